@@ -2,25 +2,53 @@ package main
 
 import (
 	"context"
-	"errors"
+	"crypto/tls"
 	"fmt"
 	"log"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
+	"strconv"
 	"syscall"
 	"time"
 
+	"github.com/getsentry/sentry-go"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/labstack/echo/v4"
+	"github.com/prometheus/client_golang/prometheus"
+	"google.golang.org/grpc"
 
+	"github.com/example/keepstack/apps/api/internal/activitypub"
+	"github.com/example/keepstack/apps/api/internal/captcha"
 	"github.com/example/keepstack/apps/api/internal/config"
+	"github.com/example/keepstack/apps/api/internal/db"
+	grpcapi "github.com/example/keepstack/apps/api/internal/grpc"
 	httpapi "github.com/example/keepstack/apps/api/internal/http"
 	"github.com/example/keepstack/apps/api/internal/observability"
 	"github.com/example/keepstack/apps/api/internal/queue"
+	"github.com/example/keepstack/apps/api/internal/replica"
+	"github.com/example/keepstack/apps/api/internal/resurfacer"
+	"github.com/example/keepstack/apps/api/internal/seed"
+	"github.com/example/keepstack/apps/api/internal/startup"
+	"github.com/example/keepstack/apps/api/internal/tlsconfig"
+	"github.com/example/keepstack/db/migrations"
+	"github.com/example/keepstack/health"
+	"github.com/example/keepstack/listen"
+	"github.com/example/keepstack/media"
+	"github.com/example/keepstack/observe"
 )
 
+// devDemoTag is created in dev mode so the tag picker is not empty.
+const devDemoTag = "demo"
+
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "--validate-config" {
+		os.Exit(validateConfig())
+	}
+
 	logger := log.New(os.Stdout, "keepstack-api ", log.LstdFlags|log.LUTC)
 
 	cfg, err := config.Load()
@@ -31,49 +59,328 @@ func main() {
 	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
 	defer stop()
 
-	pool, err := connectDatabase(ctx, logger, cfg.DatabaseURL)
+	if cfg.MemoryMode {
+		if err := serveMemory(ctx, logger, cfg); err != nil {
+			logger.Fatalf("memory mode: %v", err)
+		}
+		logger.Println("server stopped")
+		return
+	}
+
+	shutdownTracing, err := observe.InitTracing(ctx, "keepstack-api")
+	if err != nil {
+		logger.Fatalf("init tracing: %v", err)
+	}
+	defer func() {
+		flushCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := shutdownTracing(flushCtx); err != nil {
+			logger.Printf("tracing shutdown error: %v", err)
+		}
+	}()
+
+	errorReporter, err := observe.NewErrorReporter(sentry.ClientOptions{
+		Dsn:         cfg.SentryDSN,
+		Environment: cfg.SentryEnvironment,
+		ServerName:  "keepstack-api",
+	})
+	if err != nil {
+		logger.Fatalf("init error reporting: %v", err)
+	}
+	defer errorReporter.Flush(2 * time.Second)
+
+	metrics := observability.NewMetrics()
+	queryTracer := observe.NewQueryTracer(metrics.DBQueryDurationSeconds, cfg.DBSlowQueryThreshold, logger)
+
+	// With TLS configured the API terminates HTTPS itself, and the
+	// TLS_HTTP_LISTEN addresses redirect plain HTTP to it.
+	tlsConfig, redirect, err := tlsconfig.New(cfg.TLS())
+	if err != nil {
+		logger.Fatalf("configure tls: %v", err)
+	}
+	var redirectServer *http.Server
+	if len(cfg.TLSHTTPListen) > 0 {
+		redirectListeners, err := listen.Open(cfg.TLSHTTPListen)
+		if err != nil {
+			logger.Fatalf("listen http redirect: %v", err)
+		}
+		redirectServer = &http.Server{Handler: redirect, ReadHeaderTimeout: 10 * time.Second}
+		go func() {
+			logger.Printf("redirecting http on %s to https", listen.Names(redirectListeners))
+			if err := listen.Serve(redirectServer, redirectListeners); err != nil {
+				logger.Printf("http redirect server error: %v", err)
+			}
+		}()
+	}
+
+	e := echo.New()
+	listeners, err := listen.Open(cfg.ListenAddresses())
+	if err != nil {
+		logger.Fatalf("listen: %v", err)
+	}
+	scheme := "http"
+	if tlsConfig != nil {
+		e.Server.TLSConfig = tlsConfig
+		for i, listener := range listeners {
+			listeners[i] = tls.NewListener(listener, tlsConfig)
+		}
+		scheme = "https"
+	}
+
+	// Requests go through the gate, which the router replaces once every
+	// dependency is up. With STARTUP_GATE the listeners serve from here on,
+	// so probes see a live pod while the database, NATS, and migrations are
+	// awaited; otherwise they open once the router is ready.
+	gate := startup.NewGate()
+	e.Server.Handler = gate
+	serveDone := make(chan error, 1)
+	serve := func() {
+		logger.Printf("starting %s server on %s", scheme, listen.Names(listeners))
+		go func() { serveDone <- listen.Serve(e.Server, listeners) }()
+	}
+	if cfg.StartupGate {
+		serve()
+	}
+
+	gate.Wait("database")
+	poolCfg, err := newPoolConfig(cfg.DatabaseURL, cfg, queryTracer)
+	if err != nil {
+		logger.Fatalf("configure database pool: %v", err)
+	}
+	pool, err := connectDatabase(ctx, logger, poolCfg)
 	if err != nil {
 		logger.Fatalf("connect database: %v", err)
 	}
 	defer pool.Close()
+	prometheus.MustRegister(observe.NewPoolStatsCollector("keepstack_api", pool.Stat))
 
+	gate.Wait("nats")
 	publisher, err := connectNATS(ctx, logger, cfg.NATSURL)
 	if err != nil {
 		logger.Fatalf("connect nats: %v", err)
 	}
 	defer publisher.Close()
 
-	metrics := observability.NewMetrics()
+	if cfg.StartupGate {
+		gate.Wait("migrations")
+		if err := waitForMigrations(ctx, logger, pool, migrations.Latest()); err != nil {
+			logger.Fatalf("wait for migrations: %v", err)
+		}
+	}
 
-	e := echo.New()
+	if cfg.DevMode {
+		provisionDevUser(ctx, logger, pool, cfg.DevUserID)
+	}
+
+	weights, err := resurfacer.LoadWeightsFromEnv()
+	if err != nil {
+		logger.Fatalf("load resurfacer weights: %v", err)
+	}
+	refresher := resurfacer.New(pool)
+	refresher.WithWeights(weights)
+	refresher.WithCooldown(time.Duration(cfg.ResurfacerCooldownDays) * 24 * time.Hour)
+
+	_, err = publisher.SubscribeRecommendationsRefresh(func(ctx context.Context, userID uuid.UUID) (err error) {
+		start := time.Now()
+		defer func() { metrics.ObserveMessage(ctx, queue.RecommendationsRefreshSubject, time.Since(start), err) }()
+
+		ctx, cancel := context.WithTimeout(ctx, time.Minute)
+		defer cancel()
+
+		count, err := refresher.RebuildUser(ctx, userID, cfg.ResurfacerLimit)
+		if err != nil {
+			metrics.ResurfaceFailure.Inc()
+			errorReporter.Capture(ctx, err, observe.ErrorContext{
+				Tags: map[string]string{"subject": queue.RecommendationsRefreshSubject, "user_id": userID.String()},
+			})
+			return err
+		}
+		logger.Printf("refreshed %d recommendations for %s", count, userID)
+		if err := publisher.PublishEvent(ctx, queue.Event{Type: queue.EventRecommendationUpdated, UserID: userID.String()}); err != nil {
+			logger.Printf("publish %s event: %v", queue.EventRecommendationUpdated, err)
+		}
+		return nil
+	})
+	if err != nil {
+		logger.Fatalf("subscribe recommendations refresh: %v", err)
+	}
 
 	server := httpapi.NewServer(cfg, pool, publisher, metrics)
+	server.WithErrorReporter(errorReporter)
+	server.WithUnfurler(publisher)
+	server.WithExpander(publisher)
+	mediaStore, err := media.Open(cfg.Media())
+	if err != nil {
+		logger.Fatalf("open media storage: %v", err)
+	}
+	if mediaStore != nil {
+		server.WithMedia(mediaStore)
+	}
+	server.WithSchemaVersion(migrations.Latest())
+	if cfg.HighlightRateLimitBackend == config.RateLimitBackendPostgres {
+		server.WithSharedHighlightLimits(logger)
+		server.WithSharedPublicInboxLimits(logger)
+	}
+	if cfg.PublicInboxCaptchaProvider != "" {
+		verifier, err := captcha.New(cfg.PublicInboxCaptchaProvider, cfg.PublicInboxCaptchaSecret, &http.Client{Timeout: 10 * time.Second})
+		if err != nil {
+			logger.Fatalf("configure public inbox captcha: %v", err)
+		}
+		server.WithPublicInboxCaptcha(verifier)
+	}
+	if cfg.PublicInboxUserID != uuid.Nil {
+		logger.Printf("public inbox enabled for user %s", cfg.PublicInboxUserID)
+	}
+	if cfg.DatabaseReplicaURL != "" {
+		replicaCfg, err := newPoolConfig(cfg.DatabaseReplicaURL, cfg, queryTracer)
+		if err != nil {
+			logger.Fatalf("configure replica pool: %v", err)
+		}
+		// The pool connects lazily, so an unreachable replica does not block
+		// startup; the router falls back to the primary until it answers.
+		replicaPool, err := pgxpool.NewWithConfig(ctx, replicaCfg)
+		if err != nil {
+			logger.Fatalf("create replica pool: %v", err)
+		}
+		defer replicaPool.Close()
+		server.WithReadDB(replica.New(pool, replicaPool, logger))
+		logger.Println("routing list and search queries to the read replica")
+	}
+	if cfg.ActivityPubBaseURL != "" {
+		key, err := activitypub.ParsePrivateKey(cfg.ActivityPubPrivateKey)
+		if err != nil {
+			logger.Fatalf("parse activitypub private key: %v", err)
+		}
+		publicKey, err := activitypub.EncodePublicKey(&key.PublicKey)
+		if err != nil {
+			logger.Fatalf("encode activitypub public key: %v", err)
+		}
+		server.WithActivityPub(activitypub.NewClient(&http.Client{Timeout: 10 * time.Second}, key), publicKey)
+		logger.Printf("activitypub actors enabled at %s", cfg.ActivityPubBaseURL)
+	}
+	if _, err := publisher.SubscribeEvents(server.PublishEvent); err != nil {
+		logger.Fatalf("subscribe live update events: %v", err)
+	}
+	if _, err := publisher.SubscribeNotifications(server.RecordNotification); err != nil {
+		logger.Fatalf("subscribe notifications: %v", err)
+	}
 	server.RegisterRoutes(e)
 
+	// The internal gRPC service lets the worker report ingest progress. It is
+	// only reachable inside the cluster; the Service does not expose it
+	// through the ingress.
+	var grpcServer *grpc.Server
+	if cfg.GRPCPort > 0 {
+		listener, err := net.Listen("tcp", cfg.GRPCAddress())
+		if err != nil {
+			logger.Fatalf("listen grpc: %v", err)
+		}
+		ingestServer := grpcapi.New(db.New(pool), publisher, logger)
+		grpcServer = grpcapi.NewGRPCServer(ingestServer, metrics.GRPCRequestDurationSeconds)
+		go func() {
+			logger.Printf("starting grpc server on %s", cfg.GRPCAddress())
+			if err := grpcServer.Serve(listener); err != nil {
+				logger.Printf("grpc server error: %v", err)
+			}
+		}()
+	}
+
+	// Shutdown order: fail readiness, keep serving while load balancers catch
+	// up, stop accepting connections and wait for in-flight requests, drain
+	// NATS so refresh handlers finish, then let the deferred pool close run.
+	shutdownDone := make(chan struct{})
 	go func() {
+		defer close(shutdownDone)
 		<-ctx.Done()
-		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		// A second signal terminates immediately.
+		stop()
+
+		server.BeginDrain()
+		logger.Printf("shutdown signal received, draining for %s", cfg.ShutdownDrainDelay)
+		time.Sleep(cfg.ShutdownDrainDelay)
+
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
 		defer cancel()
 		if err := e.Shutdown(shutdownCtx); err != nil {
 			logger.Printf("server shutdown error: %v", err)
 		}
+		if redirectServer != nil {
+			_ = redirectServer.Shutdown(shutdownCtx)
+		}
+		if grpcServer != nil {
+			stopGRPC(shutdownCtx, grpcServer)
+		}
+		if err := publisher.Drain(shutdownCtx); err != nil {
+			logger.Printf("nats drain error: %v", err)
+		}
 	}()
 
-	logger.Printf("starting server on %s", cfg.Address())
-	if err := e.Start(cfg.Address()); err != nil && !errors.Is(err, http.ErrServerClosed) {
+	gate.Open(e)
+	if !cfg.StartupGate {
+		serve()
+	}
+	if err := <-serveDone; err != nil {
 		logger.Fatalf("server error: %v", err)
 	}
+	<-shutdownDone
 
 	logger.Println("server stopped")
 }
 
-func connectDatabase(ctx context.Context, logger *log.Logger, url string) (*pgxpool.Pool, error) {
+// stopGRPC waits for in-flight calls to finish, cutting them off if ctx
+// expires first.
+func stopGRPC(ctx context.Context, server *grpc.Server) {
+	stopped := make(chan struct{})
+	go func() {
+		server.GracefulStop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-ctx.Done():
+		server.Stop()
+	}
+}
+
+// newPoolConfig parses a database URL and applies the pool settings from the
+// environment. Zero values keep the pgxpool defaults.
+func newPoolConfig(url string, cfg config.Config, tracer pgx.QueryTracer) (*pgxpool.Config, error) {
+	poolCfg, err := pgxpool.ParseConfig(url)
+	if err != nil {
+		return nil, fmt.Errorf("parse database url: %w", err)
+	}
+	poolCfg.ConnConfig.Tracer = tracer
+	if cfg.DBMaxConns > 0 {
+		poolCfg.MaxConns = cfg.DBMaxConns
+	}
+	if cfg.DBMinConns > 0 {
+		poolCfg.MinConns = cfg.DBMinConns
+	}
+	if cfg.DBMaxConnLifetime > 0 {
+		poolCfg.MaxConnLifetime = cfg.DBMaxConnLifetime
+	}
+	if cfg.DBMaxConnIdleTime > 0 {
+		poolCfg.MaxConnIdleTime = cfg.DBMaxConnIdleTime
+	}
+	if cfg.DBHealthCheckPeriod > 0 {
+		poolCfg.HealthCheckPeriod = cfg.DBHealthCheckPeriod
+	}
+	if cfg.DBStatementTimeout > 0 {
+		poolCfg.ConnConfig.RuntimeParams["statement_timeout"] = strconv.FormatInt(cfg.DBStatementTimeout.Milliseconds(), 10)
+	}
+	if poolCfg.MinConns > poolCfg.MaxConns {
+		return nil, fmt.Errorf("DB_MIN_CONNS (%d) exceeds DB_MAX_CONNS (%d)", poolCfg.MinConns, poolCfg.MaxConns)
+	}
+	return poolCfg, nil
+}
+
+func connectDatabase(ctx context.Context, logger *log.Logger, poolCfg *pgxpool.Config) (*pgxpool.Pool, error) {
 	backoff := time.Second
 	var lastErr error
 
 	for attempts := 1; ; attempts++ {
 		attemptCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
-		pool, err := pgxpool.New(attemptCtx, url)
+		pool, err := pgxpool.NewWithConfig(attemptCtx, poolCfg)
 		cancel()
 		if err == nil {
 			logger.Printf("database connection established after %d attempt(s)", attempts)
@@ -105,7 +412,59 @@ func connectDatabase(ctx context.Context, logger *log.Logger, url string) (*pgxp
 	return nil, fmt.Errorf("connect to database: %w", lastErr)
 }
 
-func connectNATS(ctx context.Context, logger *log.Logger, url string) (queue.Publisher, error) {
+// provisionDevUser creates the dev user and the demo tag when missing. A
+// failure, e.g. because migrations have not run yet, is logged rather than
+// fatal; make seed-db creates the same rows later.
+func provisionDevUser(ctx context.Context, logger *log.Logger, pool *pgxpool.Pool, userID uuid.UUID) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	created, err := seed.EnsureDevUser(ctx, pool, userID)
+	if err != nil {
+		logger.Printf("dev mode: provision dev user: %v", err)
+		return
+	}
+	if created {
+		logger.Printf("dev mode: created dev user %s", userID)
+	}
+	if created, err := seed.EnsureTag(ctx, pool, userID, devDemoTag); err != nil {
+		logger.Printf("dev mode: provision demo tag: %v", err)
+	} else if created {
+		logger.Printf("dev mode: created tag %q", devDemoTag)
+	}
+}
+
+// waitForMigrations polls until goose has applied migration expected, so a
+// gated API does not open its routes against a schema the migrate Job is
+// still building.
+func waitForMigrations(ctx context.Context, logger *log.Logger, pool *pgxpool.Pool, expected int64) error {
+	check := health.SchemaVersion(pool, expected)
+	backoff := time.Second
+
+	for attempts := 1; ; attempts++ {
+		attemptCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+		err := check.Run(attemptCtx)
+		cancel()
+		if err == nil {
+			logger.Printf("database schema at version %d after %d attempt(s)", expected, attempts)
+			return nil
+		}
+
+		logger.Printf("waiting for migrations (attempt %d): %v", attempts, err)
+
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return fmt.Errorf("context cancelled while waiting for migrations: %w", ctx.Err())
+		}
+
+		if backoff < 8*time.Second {
+			backoff *= 2
+		}
+	}
+}
+
+func connectNATS(ctx context.Context, logger *log.Logger, url string) (*queue.NATS, error) {
 	backoff := time.Second
 	var lastErr error
 
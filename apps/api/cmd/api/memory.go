@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+
+	"github.com/example/keepstack/apps/api/internal/config"
+	httpapi "github.com/example/keepstack/apps/api/internal/http"
+	"github.com/example/keepstack/apps/api/internal/memstore"
+	"github.com/example/keepstack/apps/api/internal/observability"
+	"github.com/example/keepstack/apps/api/internal/queue"
+	"github.com/example/keepstack/apps/api/internal/seed"
+	"github.com/example/keepstack/listen"
+)
+
+// memoryIngestDelay is how long a link saved in memory mode stays pending
+// before it is marked archived, so the web app's pending state shows.
+const memoryIngestDelay = 2 * time.Second
+
+// serveMemory runs the API against an in-memory store and publisher until
+// ctx is cancelled, so the web app can be developed without Postgres, NATS,
+// or the worker. Saved links are marked archived shortly after they are
+// saved, standing in for the worker.
+func serveMemory(ctx context.Context, logger *log.Logger, cfg config.Config) error {
+	store := memstore.New(cfg.DevUserID)
+	if cfg.MemorySeed != "" {
+		dataset, err := seed.Lookup(cfg.MemorySeed)
+		if err != nil {
+			return fmt.Errorf("MEMORY_SEED: %w", err)
+		}
+		if err := store.Seed(dataset, cfg.DevUserID); err != nil {
+			return err
+		}
+		logger.Printf("memory mode: seeded %d link(s) from the %q dataset", len(dataset.Links), dataset.Name)
+	}
+
+	publisher := queue.NewMemory()
+	server := httpapi.NewMemoryServer(cfg, store, publisher, observability.NewMetrics())
+	publisher.SubscribeEvents(server.PublishEvent)
+	publisher.OnLinkSaved(func(_ context.Context, linkID uuid.UUID) {
+		time.AfterFunc(memoryIngestDelay, func() {
+			userID, err := store.Archive(context.Background(), linkID)
+			if err != nil {
+				// The link was deleted before it was archived.
+				logger.Printf("memory mode: archive %s: %v", linkID, err)
+				return
+			}
+			event := queue.Event{Type: queue.EventLinkIngested, UserID: userID.String(), LinkID: linkID.String()}
+			_ = publisher.PublishEvent(context.Background(), event)
+		})
+	})
+
+	e := echo.New()
+	server.RegisterRoutes(e)
+	listeners, err := listen.Open(cfg.ListenAddresses())
+	if err != nil {
+		return fmt.Errorf("listen: %w", err)
+	}
+	logger.Printf("memory mode: starting http server on %s; nothing is kept after it stops", listen.Names(listeners))
+	serveDone := make(chan error, 1)
+	go func() { serveDone <- listen.Serve(e.Server, listeners) }()
+
+	select {
+	case err := <-serveDone:
+		return err
+	case <-ctx.Done():
+	}
+	server.BeginDrain()
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+	defer cancel()
+	if err := e.Shutdown(shutdownCtx); err != nil {
+		logger.Printf("server shutdown error: %v", err)
+	}
+	return <-serveDone
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"context"
+	"os"
+	"time"
+
+	"github.com/example/keepstack/apps/api/internal/config"
+	"github.com/example/keepstack/apps/api/internal/digest"
+	"github.com/example/keepstack/apps/api/internal/tlsconfig"
+	"github.com/example/keepstack/configcheck"
+)
+
+const validateTimeout = 10 * time.Second
+
+// validateConfig prints the API's effective configuration and checks it
+// without starting the server, returning the exit code. On-demand digests
+// are checked only when SMTP_URL is set.
+func validateConfig() int {
+	cfg, err := config.Load()
+	if err != nil {
+		return report(nil, []configcheck.Check{configcheck.Failed("load configuration", err)})
+	}
+
+	settings := configcheck.Settings(cfg)
+	// Memory mode uses neither, so they are only checked when set.
+	backendsRequired := !cfg.MemoryMode
+	checks := []configcheck.Check{
+		configcheck.DatabaseURL("DATABASE_URL", cfg.DatabaseURL, backendsRequired),
+		configcheck.DatabaseURL("DATABASE_REPLICA_URL", cfg.DatabaseReplicaURL, false),
+		configcheck.URL("NATS_URL", cfg.NATSURL, backendsRequired, "nats", "tls", "ws", "wss"),
+		configcheck.URL("ACTIVITYPUB_BASE_URL", cfg.ActivityPubBaseURL, false, "https"),
+		configcheck.URL("SENTRY_DSN", cfg.SentryDSN, false, "http", "https"),
+	}
+
+	if cfg.TLSCertFile != "" {
+		checks = append(checks, configcheck.Check{Name: "TLS certificate", Run: func(context.Context) error {
+			_, _, err := tlsconfig.New(cfg.TLS())
+			return err
+		}})
+	}
+
+	if os.Getenv("SMTP_URL") != "" {
+		digestCfg, err := digest.LoadConfig()
+		if err != nil {
+			checks = append(checks, configcheck.Failed("digest configuration", err))
+		} else {
+			settings = append(settings, configcheck.Settings(digestCfg)...)
+			checks = append(checks, configcheck.Check{Name: "SMTP connectivity", Run: func(ctx context.Context) error {
+				return digest.CheckTransport(ctx, digestCfg.Transport)
+			}})
+		}
+	}
+
+	return report(settings, checks)
+}
+
+func report(settings []configcheck.Setting, checks []configcheck.Check) int {
+	if !configcheck.Run(context.Background(), os.Stdout, settings, checks, validateTimeout) {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"os"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"github.com/example/keepstack/apps/api/internal/db"
+)
+
+const (
+	cronRunSuccess = "success"
+	cronRunFailure = "failure"
+	cronRunSkipped = "skipped"
+)
+
+// cronRun is a row in cron_runs for the current execution. History is best
+// effort: when the database is unreachable the run proceeds unrecorded and a
+// nil *cronRun ignores finish.
+type cronRun struct {
+	logger *log.Logger
+	conn   *pgx.Conn
+	id     pgtype.UUID
+}
+
+func startCronRun(logger *log.Logger, subcommand string) *cronRun {
+	databaseURL := os.Getenv("DATABASE_URL")
+	if databaseURL == "" {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	conn, err := pgx.Connect(ctx, databaseURL)
+	if err != nil {
+		logger.Printf("warn: record cron run: %v", err)
+		return nil
+	}
+
+	run, err := db.New(conn).CreateCronRun(ctx, subcommand)
+	if err != nil {
+		logger.Printf("warn: record cron run: %v", err)
+		conn.Close(ctx)
+		return nil
+	}
+
+	return &cronRun{logger: logger, conn: conn, id: run.ID}
+}
+
+// finish stores the outcome and closes the history connection.
+func (r *cronRun) finish(status string, duration time.Duration, counts map[string]int64, runErr error) {
+	if r == nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	defer r.conn.Close(ctx)
+
+	if counts == nil {
+		counts = map[string]int64{}
+	}
+	encoded, err := json.Marshal(counts)
+	if err != nil {
+		r.logger.Printf("warn: encode cron run counts: %v", err)
+		encoded = []byte("{}")
+	}
+
+	params := db.FinishCronRunParams{
+		Status:     status,
+		DurationMs: pgtype.Int8{Int64: duration.Milliseconds(), Valid: status != cronRunSkipped},
+		Counts:     encoded,
+		ID:         r.id,
+	}
+	if runErr != nil {
+		params.Error = pgtype.Text{String: runErr.Error(), Valid: true}
+	}
+	if err := db.New(r.conn).FinishCronRun(ctx, params); err != nil {
+		r.logger.Printf("warn: record cron run result: %v", err)
+	}
+}
@@ -1,65 +1,201 @@
 package main
 
 import (
-	"compress/gzip"
 	"context"
+	"encoding/json"
 	"errors"
+	"flag"
 	"fmt"
 	"log"
+	"net/http"
 	"os"
-	"os/exec"
-	"path/filepath"
-	"sort"
 	"strconv"
 	"strings"
 	"time"
 
-	"github.com/aws/aws-sdk-go-v2/aws"
-	awsconfig "github.com/aws/aws-sdk-go-v2/config"
-	"github.com/aws/aws-sdk-go-v2/credentials"
-	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/jackc/pgx/v5/pgxpool"
 
+	"github.com/example/keepstack/apps/api/internal/backup"
 	"github.com/example/keepstack/apps/api/internal/config"
+	"github.com/example/keepstack/apps/api/internal/cronlock"
+	"github.com/example/keepstack/apps/api/internal/db"
 	"github.com/example/keepstack/apps/api/internal/digest"
+	"github.com/example/keepstack/apps/api/internal/integrity"
+	"github.com/example/keepstack/apps/api/internal/observability"
+	"github.com/example/keepstack/apps/api/internal/queue"
+	"github.com/example/keepstack/apps/api/internal/resanitize"
 	"github.com/example/keepstack/apps/api/internal/resurfacer"
 	"github.com/example/keepstack/apps/api/internal/schema"
+	"github.com/example/keepstack/apps/api/internal/searchindex"
+	"github.com/example/keepstack/apps/api/internal/vacuum"
+	"github.com/example/keepstack/apps/api/internal/wordcount"
+	"github.com/example/keepstack/messages"
 )
 
 func main() {
 	logger := log.New(os.Stdout, "keepstack-cron ", log.LstdFlags|log.LUTC)
 
 	if len(os.Args) < 2 {
-		logger.Fatalf("expected subcommand (available: digest)")
+		logger.Fatalf("expected subcommand (available: digest, verify-schema, backup, backup-prune, restore, resurface, archive-vacuum, search-reindex, wordcount-backfill, archive-resanitize, archive-verify) or --validate-config [subcommand]; verify-schema accepts --fix [--apply]")
 	}
 
-	switch os.Args[1] {
+	// Subcommands read DATABASE_URL and their own settings straight from
+	// the environment, so resolve secret files and Vault up front.
+	if err := config.LoadSecrets(); err != nil {
+		logger.Fatalf("load secrets: %v", err)
+	}
+
+	if os.Args[1] == "--validate-config" {
+		subcommand := ""
+		if len(os.Args) > 2 {
+			subcommand = os.Args[2]
+		}
+		os.Exit(validateConfig(subcommand))
+	}
+
+	subcommand := os.Args[1]
+	metrics := observability.NewCronMetrics(subcommand)
+
+	history := startCronRun(logger, subcommand)
+
+	lock, err := acquireRunLock(logger, subcommand)
+	if errors.Is(err, cronlock.ErrHeld) {
+		logger.Printf("another %s run holds the lock, skipping", subcommand)
+		history.finish(cronRunSkipped, 0, nil, nil)
+		return
+	}
+	if err != nil {
+		history.finish(cronRunFailure, 0, nil, err)
+		logger.Fatalf("%s failed: %v", subcommand, err)
+	}
+
+	counts := make(map[string]int64)
+	start := time.Now()
+	err = runSubcommand(logger, subcommand, metrics, counts)
+	finishedAt := time.Now()
+	if releaseErr := lock.Release(context.Background()); releaseErr != nil {
+		logger.Printf("warning: %v", releaseErr)
+	}
+	status := cronRunSuccess
+	if err != nil {
+		status = cronRunFailure
+	}
+	history.finish(status, finishedAt.Sub(start), counts, err)
+	metrics.ObserveRun(finishedAt, finishedAt.Sub(start), err)
+	exportCronMetrics(logger, metrics)
+
+	if err != nil {
+		logger.Fatalf("%s failed: %v", subcommand, err)
+	}
+}
+
+// runSubcommand dispatches to the named subcommand. Subcommands report what
+// they processed in counts, which is stored with the run history.
+func runSubcommand(logger *log.Logger, subcommand string, metrics *observability.CronMetrics, counts map[string]int64) error {
+	switch subcommand {
 	case "digest":
-		if err := runDigest(logger); err != nil {
+		if err := runDigest(logger, counts); err != nil {
 			if errors.Is(err, digest.ErrNoUnreadLinks) {
 				logger.Println("no unread links, skipping digest dispatch")
-				return
+				return nil
 			}
-			logger.Fatalf("digest run failed: %v", err)
+			return fmt.Errorf("digest run: %w", err)
 		}
 	case "verify-schema":
-		if err := runVerifySchema(logger); err != nil {
-			logger.Fatalf("schema verification failed: %v", err)
+		if err := runVerifySchema(logger, os.Args[2:]); err != nil {
+			return fmt.Errorf("schema verification: %w", err)
 		}
 	case "backup":
-		if err := runBackup(logger); err != nil {
-			logger.Fatalf("backup failed: %v", err)
+		if err := runBackup(logger, counts); err != nil {
+			return fmt.Errorf("backup: %w", err)
+		}
+	case "backup-prune":
+		if err := runBackupPrune(logger); err != nil {
+			return fmt.Errorf("backup prune: %w", err)
+		}
+	case "restore":
+		if err := runRestore(logger, restoreTarget()); err != nil {
+			return fmt.Errorf("restore: %w", err)
 		}
 	case "resurface":
-		if err := runResurface(logger); err != nil {
-			logger.Fatalf("resurface run failed: %v", err)
+		if err := runResurface(logger, metrics, counts); err != nil {
+			return fmt.Errorf("resurface run: %w", err)
+		}
+	case "archive-vacuum":
+		if err := runArchiveVacuum(logger, metrics, counts); err != nil {
+			return fmt.Errorf("archive vacuum: %w", err)
+		}
+	case "search-reindex":
+		if err := runSearchReindex(logger, metrics, counts); err != nil {
+			return fmt.Errorf("search reindex: %w", err)
+		}
+	case "wordcount-backfill":
+		if err := runWordCountBackfill(logger, metrics, counts); err != nil {
+			return fmt.Errorf("word count backfill: %w", err)
+		}
+	case "archive-resanitize":
+		if err := runArchiveResanitize(logger, metrics, counts); err != nil {
+			return fmt.Errorf("archive resanitize: %w", err)
+		}
+	case "archive-verify":
+		if err := runArchiveVerify(logger, metrics, counts); err != nil {
+			return fmt.Errorf("archive verify: %w", err)
 		}
 	default:
-		logger.Fatalf("unknown subcommand %q", os.Args[1])
+		return fmt.Errorf("unknown subcommand %q", subcommand)
+	}
+	return nil
+}
+
+// acquireRunLock takes the per-subcommand advisory lock so overlapping pods
+// or manual jobs do not run the same work twice. Skipped runs do not push
+// metrics. Without DATABASE_URL there is nothing to lock against.
+func acquireRunLock(logger *log.Logger, subcommand string) (*cronlock.Lock, error) {
+	databaseURL := os.Getenv("DATABASE_URL")
+	if databaseURL == "" {
+		logger.Printf("warning: DATABASE_URL not set, running %s without a lock", subcommand)
+		return nil, nil
 	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	return cronlock.Acquire(ctx, databaseURL, subcommand)
 }
 
-func runDigest(logger *log.Logger) error {
+// exportCronMetrics hands run metrics to whichever sinks are configured:
+// the Pushgateway at CRON_PUSHGATEWAY_URL, a text dump at CRON_METRICS_FILE
+// ("{subcommand}" in the path is replaced), or a POST of the same text to
+// CRON_METRICS_URL. Failures are logged rather than failing the run.
+func exportCronMetrics(logger *log.Logger, metrics *observability.CronMetrics) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if gatewayURL := getEnvDefault("CRON_PUSHGATEWAY_URL", ""); gatewayURL != "" {
+		if err := metrics.Push(ctx, gatewayURL); err != nil {
+			logger.Printf("warn: %v", err)
+		} else {
+			logger.Printf("pushed %s metrics to %s", metrics.Subcommand, gatewayURL)
+		}
+	}
+	if path := getEnvDefault("CRON_METRICS_FILE", ""); path != "" {
+		path = strings.ReplaceAll(path, "{subcommand}", metrics.Subcommand)
+		if err := metrics.WriteFile(path); err != nil {
+			logger.Printf("warn: %v", err)
+		} else {
+			logger.Printf("wrote %s metrics to %s", metrics.Subcommand, path)
+		}
+	}
+	if metricsURL := getEnvDefault("CRON_METRICS_URL", ""); metricsURL != "" {
+		if err := metrics.PostText(ctx, http.DefaultClient, metricsURL); err != nil {
+			logger.Printf("warn: %v", err)
+		} else {
+			logger.Printf("posted %s metrics to %s", metrics.Subcommand, metricsURL)
+		}
+	}
+}
+
+func runDigest(logger *log.Logger, counts map[string]int64) error {
 	cfg, err := config.Load()
 	if err != nil {
 		return err
@@ -88,12 +224,57 @@ func runDigest(logger *log.Logger) error {
 	if err != nil {
 		return err
 	}
+	counts["links"] = int64(count)
 
 	logger.Printf("sent digest with %d unread links", count)
+	publishEvent(logger, cfg.NATSURL, queue.EventDigestSent, cfg.DevUserID.String(), messages.DigestSent{Links: count})
 	return nil
 }
 
-func runVerifySchema(logger *log.Logger) error {
+// publishEvent sends a domain event for the API to record as an in-app
+// notification. A lost notification does not fail the run, so errors are
+// logged.
+func publishEvent(logger *log.Logger, natsURL, eventType, userID string, data any) {
+	if natsURL == "" {
+		return
+	}
+	raw, err := json.Marshal(data)
+	if err != nil {
+		logger.Printf("warn: publish %s event: %v", eventType, err)
+		return
+	}
+	publisher, err := queue.New(natsURL)
+	if err != nil {
+		logger.Printf("warn: publish %s event: %v", eventType, err)
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if err := publisher.PublishEvent(ctx, queue.Event{Type: eventType, UserID: userID, Data: raw}); err != nil {
+		logger.Printf("warn: publish %s event: %v", eventType, err)
+	}
+	// Draining flushes the buffered publish before the process exits.
+	if err := publisher.Drain(ctx); err != nil {
+		logger.Printf("warn: %v", err)
+	}
+}
+
+// runVerifySchema compares the database with the embedded migrations.
+// With --fix it prints the DDL that would repair each difference, and with
+// --apply it also runs that DDL, for environments that drifted from the
+// migrations after a hand-run restore or a manual hotfix.
+func runVerifySchema(logger *log.Logger, args []string) error {
+	flags := flag.NewFlagSet("verify-schema", flag.ContinueOnError)
+	fix := flags.Bool("fix", false, "print the DDL that repairs each difference")
+	apply := flags.Bool("apply", false, "with --fix, run the DDL in one transaction")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *apply && !*fix {
+		return fmt.Errorf("--apply requires --fix")
+	}
+
 	cfg, err := config.Load()
 	if err != nil {
 		return err
@@ -108,158 +289,520 @@ func runVerifySchema(logger *log.Logger) error {
 	}
 	defer pool.Close()
 
-	if err := schema.Verify(ctx, pool); err != nil {
+	if !*fix {
+		if err := schema.Verify(ctx, pool); err != nil {
+			return err
+		}
+		logger.Println("database schema verified")
+		return nil
+	}
+
+	problems, err := schema.Diff(ctx, pool)
+	if err != nil {
 		return err
 	}
+	if len(problems) == 0 {
+		logger.Println("database schema verified")
+		return nil
+	}
+
+	manual := 0
+	for _, problem := range problems {
+		logger.Printf("drift: %s", problem.Message)
+		if problem.Fix == "" {
+			manual++
+			fmt.Printf("-- %s: no automatic fix\n", problem.Message)
+			continue
+		}
+		fmt.Printf("-- %s\n%s\n", problem.Message, problem.Fix)
+	}
 
-	logger.Println("database schema verified")
+	if !*apply {
+		return fmt.Errorf("%d difference(s) found; rerun with --fix --apply to run the DDL above", len(problems))
+	}
+	if err := schema.Apply(ctx, pool, problems); err != nil {
+		return fmt.Errorf("apply fixes: %w", err)
+	}
+	logger.Printf("applied %d fix(es)", len(problems)-manual)
+	if manual > 0 {
+		return fmt.Errorf("%d difference(s) need a manual fix", manual)
+	}
 	return nil
 }
 
-func runBackup(logger *log.Logger) error {
+func runBackup(logger *log.Logger, counts map[string]int64) error {
 	cfg, err := config.Load()
 	if err != nil {
 		return err
 	}
 
-	destDir := getEnvDefault("BACKUP_DIR", "/backups")
-	if err := os.MkdirAll(destDir, 0o755); err != nil {
-		return fmt.Errorf("create backup directory: %w", err)
+	backupCfg, err := backup.LoadConfig()
+	if err != nil {
+		return err
 	}
 
-	retention := getEnvInt("BACKUP_RETENTION", 7)
-	storage := strings.ToLower(getEnvDefault("BACKUP_STORAGE", "pvc"))
-	now := time.Now().UTC()
-	fileName := fmt.Sprintf("keepstack-%s.sql.gz", now.Format("20060102-150405"))
-	path := filepath.Join(destDir, fileName)
-
-	file, err := os.Create(path)
+	notifier, err := backup.NewNotifier(backupCfg)
 	if err != nil {
-		return fmt.Errorf("create dump file: %w", err)
+		return err
 	}
-	gzipWriter := gzip.NewWriter(file)
 
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
 	defer cancel()
 
-	cmd := exec.CommandContext(ctx, "pg_dump", cfg.DatabaseURL)
-	cmd.Stdout = gzipWriter
-	cmd.Stderr = os.Stderr
+	pool, err := pgxpool.New(ctx, cfg.DatabaseURL)
+	if err != nil {
+		return err
+	}
+	defer pool.Close()
+	queries := db.New(pool)
 
-	if err := cmd.Run(); err != nil {
-		return fmt.Errorf("pg_dump: %w", err)
+	start := time.Now()
+	run, recordErr := queries.CreateBackupRun(ctx, db.CreateBackupRunParams{Kind: backupCfg.Mode, Storage: backupCfg.Storage})
+	if recordErr != nil {
+		logger.Printf("warn: record backup run: %v", recordErr)
 	}
 
-	if err := gzipWriter.Close(); err != nil {
-		return fmt.Errorf("close gzip writer: %w", err)
+	result, err := backup.NewRunner(backupCfg, cfg.DatabaseURL, logger).Run(ctx)
+	report := backupReport(result, time.Since(start), err)
+	if recordErr == nil {
+		finishBackupRun(logger, queries, run.ID, backupCfg.Mode, report)
 	}
-	if err := file.Close(); err != nil {
-		return fmt.Errorf("close dump file: %w", err)
+	notifyBackup(logger, notifier, report)
+	if err != nil {
+		kind := report.Kind
+		if kind == "" {
+			kind = backupCfg.Mode
+		}
+		// Backups belong to the instance, so admins are notified rather
+		// than one user.
+		publishEvent(logger, cfg.NATSURL, queue.EventBackupFailed, "", messages.BackupFailed{Kind: kind, Error: report.Error})
+		return err
+	}
+	counts["size_bytes"] = report.SizeBytes
+	if len(result.Exports) > 0 {
+		counts["user_exports"] = int64(len(result.Exports))
 	}
 
-	if storage == "s3" {
-		if err := uploadBackupToS3(ctx, path, fileName); err != nil {
-			return err
-		}
-		if strings.ToLower(getEnvDefault("BACKUP_KEEP_LOCAL", "false")) != "true" {
-			if err := os.Remove(path); err != nil {
-				logger.Printf("warn: failed to remove local backup after upload: %v", err)
-			}
+	logger.Printf("%s backup written to %s", result.Manifest.Kind, result.Destination)
+	return nil
+}
+
+func backupReport(result backup.Result, duration time.Duration, runErr error) backup.Report {
+	report := backup.Report{
+		Status:      backup.StatusSuccess,
+		Kind:        result.Manifest.Kind,
+		File:        result.Manifest.File,
+		Destination: result.Destination,
+		SizeBytes:   result.Manifest.SizeBytes,
+		SHA256:      result.Manifest.SHA256,
+		Duration:    duration,
+		FinishedAt:  time.Now().UTC(),
+	}
+	if len(result.Exports) > 0 {
+		report.File = fmt.Sprintf("%d user exports", len(result.Exports))
+	}
+	if runErr != nil {
+		report.Status = backup.StatusFailure
+		report.Error = runErr.Error()
+	}
+	return report
+}
+
+// finishBackupRun stores the outcome in backup_runs. It uses a fresh context
+// so a run that hit its deadline is still recorded as failed.
+func finishBackupRun(logger *log.Logger, queries *db.Queries, id pgtype.UUID, mode string, report backup.Report) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	kind := report.Kind
+	if kind == "" {
+		kind = mode
+	}
+	err := queries.FinishBackupRun(ctx, db.FinishBackupRunParams{
+		Status:      report.Status,
+		Kind:        kind,
+		File:        pgtype.Text{String: report.File, Valid: report.File != ""},
+		Destination: pgtype.Text{String: report.Destination, Valid: report.Destination != ""},
+		SizeBytes:   pgtype.Int8{Int64: report.SizeBytes, Valid: report.Status == backup.StatusSuccess},
+		Checksum:    pgtype.Text{String: report.SHA256, Valid: report.SHA256 != ""},
+		Error:       pgtype.Text{String: report.Error, Valid: report.Error != ""},
+		ID:          id,
+	})
+	if err != nil {
+		logger.Printf("warn: record backup result: %v", err)
+	}
+}
+
+// notifyBackup reports the run outcome. Delivery failures are logged so a
+// broken webhook does not mask the backup result.
+func notifyBackup(logger *log.Logger, notifier *backup.Notifier, report backup.Report) {
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	if err := notifier.Notify(ctx, report); err != nil {
+		logger.Printf("warn: %v", err)
+	}
+}
+
+// runBackupPrune enforces backup retention on its own. Passing --dry-run (or
+// setting BACKUP_PRUNE_DRY_RUN) lists what would be removed.
+func runBackupPrune(logger *log.Logger) error {
+	backupCfg, err := backup.LoadConfig()
+	if err != nil {
+		return err
+	}
+	for _, arg := range os.Args[2:] {
+		if arg == "--dry-run" {
+			backupCfg.DryRun = true
 		}
 	}
 
-	if retention > 0 {
-		pattern := filepath.Join(destDir, "keepstack-*.sql.gz")
-		files, err := filepath.Glob(pattern)
-		if err == nil {
-			sort.Strings(files)
-			for len(files) > retention {
-				old := files[0]
-				if rmErr := os.Remove(old); rmErr != nil {
-					logger.Printf("warn: failed to remove old backup %s: %v", old, rmErr)
-				}
-				files = files[1:]
-			}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	if err := backup.NewRunner(backupCfg, "", logger).Prune(ctx); err != nil {
+		return err
+	}
+
+	logger.Println("backup retention enforced")
+	return nil
+}
+
+func runRestore(logger *log.Logger, target string) error {
+	cfg, err := config.Load()
+	if err != nil {
+		return err
+	}
+
+	backupCfg, err := backup.LoadConfig()
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Minute)
+	defer cancel()
+
+	if err := backup.Restore(ctx, backupCfg.Dir, target, cfg.DatabaseURL, logger); err != nil {
+		return err
+	}
+
+	logger.Println("restore complete")
+	return nil
+}
+
+func runResurface(logger *log.Logger, metrics *observability.CronMetrics, counts map[string]int64) error {
+	cfg, err := config.Load()
+	if err != nil {
+		return err
+	}
+
+	limit := getEnvInt("RESURFACER_LIMIT", 20)
+	batchSize := getEnvInt("RESURFACER_BATCH_SIZE", resurfacer.DefaultBatchSize)
+	weights, err := resurfacer.LoadWeightsFromEnv()
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+
+	pool, err := pgxpool.New(ctx, cfg.DatabaseURL)
+	if err != nil {
+		return err
+	}
+	defer pool.Close()
+
+	svc := resurfacer.New(pool)
+	svc.WithBatchSize(batchSize)
+	svc.WithWeights(weights)
+	svc.WithCooldown(time.Duration(getEnvInt("RESURFACER_COOLDOWN_DAYS", 0)) * 24 * time.Hour)
+	stats, err := svc.Rebuild(ctx, limit)
+	metrics.ResurfacerUsers.Set(float64(stats.Users))
+	metrics.ResurfacerWritten.Set(float64(stats.Recommendations))
+	metrics.ResurfacerRebuildSeconds.Set(stats.Duration.Seconds())
+	counts["users"] = int64(stats.Users)
+	counts["recommendations"] = int64(stats.Recommendations)
+	if err != nil {
+		return err
+	}
+
+	logger.Printf("refreshed %d recommendations for %d users in %s", stats.Recommendations, stats.Users, stats.Duration.Round(time.Millisecond))
+	return nil
+}
+
+// runArchiveVacuum removes orphaned archives and clears raw HTML older than
+// ARCHIVE_HTML_RETENTION_DAYS. Passing --dry-run (or setting
+// ARCHIVE_VACUUM_DRY_RUN) reports what would be removed.
+func runArchiveVacuum(logger *log.Logger, metrics *observability.CronMetrics, counts map[string]int64) error {
+	cfg, err := config.Load()
+	if err != nil {
+		return err
+	}
+
+	opts := vacuum.Options{
+		HTMLRetention: time.Duration(getEnvInt("ARCHIVE_HTML_RETENTION_DAYS", 90)) * 24 * time.Hour,
+		BatchSize:     getEnvInt("ARCHIVE_VACUUM_BATCH_SIZE", vacuum.DefaultBatchSize),
+		DryRun:        getEnvDefault("ARCHIVE_VACUUM_DRY_RUN", "false") == "true",
+	}
+	for _, arg := range os.Args[2:] {
+		if arg == "--dry-run" {
+			opts.DryRun = true
 		}
 	}
 
-	logger.Printf("backup written to %s", path)
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Minute)
+	defer cancel()
+
+	pool, err := pgxpool.New(ctx, cfg.DatabaseURL)
+	if err != nil {
+		return err
+	}
+	defer pool.Close()
+
+	stats, err := vacuum.New(pool).Run(ctx, opts)
+	metrics.ArchiveOrphansRemoved.Set(float64(stats.OrphansRemoved))
+	metrics.ArchiveHTMLCleared.Set(float64(stats.HTMLCleared))
+	metrics.ArchiveReclaimedBytes.Set(float64(stats.ReclaimedBytes()))
+	counts["orphans_removed"] = stats.OrphansRemoved
+	counts["html_cleared"] = stats.HTMLCleared
+	counts["reclaimed_bytes"] = stats.ReclaimedBytes()
+	if err != nil {
+		return err
+	}
+
+	if opts.DryRun {
+		logger.Printf("dry run: would remove %d orphaned archives and clear HTML from %d archives (%d bytes)",
+			stats.OrphansRemoved, stats.HTMLCleared, stats.ReclaimedBytes())
+		return nil
+	}
+	logger.Printf("removed %d orphaned archives and cleared HTML from %d archives, reclaiming %d bytes (archives table %d -> %d bytes) in %s",
+		stats.OrphansRemoved, stats.HTMLCleared, stats.ReclaimedBytes(), stats.TableBytesBefore, stats.TableBytesAfter, stats.Duration.Round(time.Millisecond))
 	return nil
 }
 
-func uploadBackupToS3(ctx context.Context, path, fileName string) error {
-	bucket := getEnvDefault("BACKUP_S3_BUCKET", "")
-	accessKey := getEnvDefault("BACKUP_S3_ACCESS_KEY", "")
-	secretKey := getEnvDefault("BACKUP_S3_SECRET_KEY", "")
-	region := getEnvDefault("BACKUP_S3_REGION", "us-east-1")
-	endpoint := getEnvDefault("BACKUP_S3_ENDPOINT", "")
-	prefix := strings.TrimSuffix(getEnvDefault("BACKUP_S3_PREFIX", ""), "/")
+// searchReindexProgressInterval is how often a search-reindex,
+// wordcount-backfill, archive-resanitize, or archive-verify run logs how far
+// it has got.
+const searchReindexProgressInterval = 10 * time.Second
 
-	if bucket == "" || accessKey == "" || secretKey == "" {
-		return fmt.Errorf("missing S3 configuration")
+// runSearchReindex checks the search triggers and index and rebuilds stale
+// search vectors, for after a bulk import that bypassed the triggers.
+// Passing --dry-run (or setting SEARCH_REINDEX_DRY_RUN) reports what would
+// change.
+func runSearchReindex(logger *log.Logger, metrics *observability.CronMetrics, counts map[string]int64) error {
+	cfg, err := config.Load()
+	if err != nil {
+		return err
 	}
 
-	key := fileName
-	if prefix != "" {
-		key = fmt.Sprintf("%s/%s", prefix, fileName)
+	opts := searchindex.Options{
+		BatchSize: getEnvInt("SEARCH_REINDEX_BATCH_SIZE", searchindex.DefaultBatchSize),
+		DryRun:    getEnvDefault("SEARCH_REINDEX_DRY_RUN", "false") == "true",
+	}
+	for _, arg := range os.Args[2:] {
+		if arg == "--dry-run" {
+			opts.DryRun = true
+		}
+	}
+	lastReport := time.Now()
+	opts.Progress = func(progress searchindex.Progress) {
+		if time.Since(lastReport) < searchReindexProgressInterval {
+			return
+		}
+		lastReport = time.Now()
+		percent := int64(100)
+		if progress.Total > progress.Checked {
+			percent = progress.Checked * 100 / progress.Total
+		}
+		logger.Printf("checked %d of %d links (%d%%), %d stale so far", progress.Checked, progress.Total, percent, progress.Stale)
 	}
 
-	file, err := os.Open(path)
+	// Rebuilding every vector of a large library after an import takes a
+	// while; the batches keep each statement short.
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Hour)
+	defer cancel()
+
+	pool, err := pgxpool.New(ctx, cfg.DatabaseURL)
 	if err != nil {
-		return fmt.Errorf("open backup for upload: %w", err)
+		return err
 	}
-	defer file.Close()
+	defer pool.Close()
 
-	cfg, err := awsConfig(ctx, region, accessKey, secretKey, endpoint)
+	stats, err := searchindex.New(pool).Run(ctx, opts)
+	metrics.SearchIndexChecked.Set(float64(stats.Checked))
+	metrics.SearchIndexStale.Set(float64(stats.Stale))
+	metrics.SearchIndexTriggers.Set(float64(stats.TriggersEnabled))
+	counts["links_checked"] = stats.Checked
+	counts["stale_vectors"] = stats.Stale
+	counts["triggers_enabled"] = int64(stats.TriggersEnabled)
 	if err != nil {
-		return fmt.Errorf("configure s3 client: %w", err)
+		return err
 	}
 
-	client := s3.NewFromConfig(cfg, func(o *s3.Options) {
-		o.UsePathStyle = true
-	})
+	if opts.DryRun {
+		logger.Printf("dry run: %d of %d links have stale search vectors; %d search trigger(s) are disabled",
+			stats.Stale, stats.Checked, stats.TriggersEnabled)
+		return nil
+	}
+	logger.Printf("rebuilt %d stale search vectors out of %d links and enabled %d search trigger(s) in %s",
+		stats.Stale, stats.Checked, stats.TriggersEnabled, stats.Duration.Round(time.Millisecond))
+	return nil
+}
 
-	_, err = client.PutObject(ctx, &s3.PutObjectInput{
-		Bucket:      aws.String(bucket),
-		Key:         aws.String(key),
-		Body:        file,
-		ContentType: aws.String("application/gzip"),
-	})
+// runWordCountBackfill fills in word counts and languages for archives
+// ingested before the worker recorded them. Passing --dry-run (or setting
+// WORDCOUNT_BACKFILL_DRY_RUN) reports how many archives would change.
+func runWordCountBackfill(logger *log.Logger, metrics *observability.CronMetrics, counts map[string]int64) error {
+	cfg, err := config.Load()
 	if err != nil {
-		return fmt.Errorf("upload backup: %w", err)
+		return err
 	}
 
+	opts := wordcount.Options{
+		BatchSize: getEnvInt("WORDCOUNT_BACKFILL_BATCH_SIZE", wordcount.DefaultBatchSize),
+		DryRun:    getEnvDefault("WORDCOUNT_BACKFILL_DRY_RUN", "false") == "true",
+	}
+	for _, arg := range os.Args[2:] {
+		if arg == "--dry-run" {
+			opts.DryRun = true
+		}
+	}
+	lastReport := time.Now()
+	opts.Progress = func(progress wordcount.Progress) {
+		if time.Since(lastReport) < searchReindexProgressInterval {
+			return
+		}
+		lastReport = time.Now()
+		percent := int64(100)
+		if progress.Total > progress.Checked {
+			percent = progress.Checked * 100 / progress.Total
+		}
+		logger.Printf("checked %d of %d archives (%d%%), %d updated so far", progress.Checked, progress.Total, percent, progress.Updated)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Hour)
+	defer cancel()
+
+	pool, err := pgxpool.New(ctx, cfg.DatabaseURL)
+	if err != nil {
+		return err
+	}
+	defer pool.Close()
+
+	stats, err := wordcount.New(pool).Run(ctx, opts)
+	metrics.WordCountChecked.Set(float64(stats.Checked))
+	metrics.WordCountUpdated.Set(float64(stats.Updated))
+	counts["archives_checked"] = stats.Checked
+	counts["archives_updated"] = stats.Updated
+	counts["words_added"] = stats.Words
+	if err != nil {
+		return err
+	}
+
+	if opts.DryRun {
+		logger.Printf("dry run: %d of %d archives missing a word count or language would be updated, adding %d words",
+			stats.Updated, stats.Checked, stats.Words)
+		return nil
+	}
+	logger.Printf("updated %d of %d archives missing a word count or language, adding %d words, in %s",
+		stats.Updated, stats.Checked, stats.Words, stats.Duration.Round(time.Millisecond))
 	return nil
 }
 
-func awsConfig(ctx context.Context, region, accessKey, secretKey, endpoint string) (aws.Config, error) {
-	opts := []func(*awsconfig.LoadOptions) error{
-		awsconfig.WithRegion(region),
-		awsconfig.WithCredentialsProvider(credentials.NewStaticCredentialsProvider(accessKey, secretKey, "")),
+// runArchiveResanitize applies the SANITIZE_* settings to archive HTML
+// stored under earlier ones. Passing --dry-run (or setting
+// ARCHIVE_RESANITIZE_DRY_RUN) reports how many archives would change.
+func runArchiveResanitize(logger *log.Logger, metrics *observability.CronMetrics, counts map[string]int64) error {
+	cfg, err := config.Load()
+	if err != nil {
+		return err
 	}
 
-	if endpoint != "" {
-		resolver := aws.EndpointResolverWithOptionsFunc(func(service, region string, _ ...interface{}) (aws.Endpoint, error) {
-			return aws.Endpoint{
-				URL:               endpoint,
-				HostnameImmutable: true,
-			}, nil
-		})
-		opts = append(opts, awsconfig.WithEndpointResolverWithOptions(resolver))
+	opts := resanitize.Options{
+		BatchSize: getEnvInt("ARCHIVE_RESANITIZE_BATCH_SIZE", resanitize.DefaultBatchSize),
+		DryRun:    getEnvDefault("ARCHIVE_RESANITIZE_DRY_RUN", "false") == "true",
+	}
+	for _, arg := range os.Args[2:] {
+		if arg == "--dry-run" {
+			opts.DryRun = true
+		}
+	}
+	lastReport := time.Now()
+	opts.Progress = func(progress resanitize.Progress) {
+		if time.Since(lastReport) < searchReindexProgressInterval {
+			return
+		}
+		lastReport = time.Now()
+		percent := int64(100)
+		if progress.Total > progress.Checked {
+			percent = progress.Checked * 100 / progress.Total
+		}
+		logger.Printf("checked %d of %d archives (%d%%), %d rewritten so far", progress.Checked, progress.Total, percent, progress.Updated)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Hour)
+	defer cancel()
+
+	pool, err := pgxpool.New(ctx, cfg.DatabaseURL)
+	if err != nil {
+		return err
+	}
+	defer pool.Close()
+
+	stats, err := resanitize.New(pool, cfg.Sanitize().Policy()).Run(ctx, opts)
+	metrics.ResanitizeChecked.Set(float64(stats.Checked))
+	metrics.ResanitizeUpdated.Set(float64(stats.Updated))
+	counts["archives_checked"] = stats.Checked
+	counts["archives_updated"] = stats.Updated
+	counts["bytes_removed"] = stats.Bytes
+	if err != nil {
+		return err
 	}
 
-	return awsconfig.LoadDefaultConfig(ctx, opts...)
+	if opts.DryRun {
+		logger.Printf("dry run: %d of %d archives would be rewritten with features %v, removing %d bytes",
+			stats.Updated, stats.Checked, cfg.SanitizeFeatures, stats.Bytes)
+		return nil
+	}
+	logger.Printf("rewrote %d of %d archives with features %v, removing %d bytes, in %s",
+		stats.Updated, stats.Checked, cfg.SanitizeFeatures, stats.Bytes, stats.Duration.Round(time.Millisecond))
+	return nil
 }
 
-func runResurface(logger *log.Logger) error {
+// runArchiveVerify checks every archive against the digests recorded at
+// ingest and records digests for archives saved before them. Any mismatch
+// fails the run after the full pass, so it alerts like any failed cron job.
+// Passing --dry-run (or setting ARCHIVE_VERIFY_DRY_RUN) checks without
+// recording digests.
+func runArchiveVerify(logger *log.Logger, metrics *observability.CronMetrics, counts map[string]int64) error {
 	cfg, err := config.Load()
 	if err != nil {
 		return err
 	}
 
-	limit := getEnvInt("RESURFACER_LIMIT", 20)
+	opts := integrity.Options{
+		BatchSize: getEnvInt("ARCHIVE_VERIFY_BATCH_SIZE", integrity.DefaultBatchSize),
+		DryRun:    getEnvDefault("ARCHIVE_VERIFY_DRY_RUN", "false") == "true",
+	}
+	for _, arg := range os.Args[2:] {
+		if arg == "--dry-run" {
+			opts.DryRun = true
+		}
+	}
+	lastReport := time.Now()
+	opts.Progress = func(progress integrity.Progress) {
+		if time.Since(lastReport) < searchReindexProgressInterval {
+			return
+		}
+		lastReport = time.Now()
+		percent := int64(100)
+		if progress.Total > progress.Checked {
+			percent = progress.Checked * 100 / progress.Total
+		}
+		logger.Printf("checked %d of %d archives (%d%%), %d mismatched so far", progress.Checked, progress.Total, percent, progress.Mismatched)
+	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Hour)
 	defer cancel()
 
 	pool, err := pgxpool.New(ctx, cfg.DatabaseURL)
@@ -268,16 +811,48 @@ func runResurface(logger *log.Logger) error {
 	}
 	defer pool.Close()
 
-	svc := resurfacer.New(pool)
-	count, err := svc.Rebuild(ctx, limit)
+	stats, err := integrity.New(pool).Run(ctx, opts)
+	metrics.IntegrityChecked.Set(float64(stats.Checked))
+	metrics.IntegrityMismatched.Set(float64(stats.Mismatched))
+	metrics.IntegrityHashed.Set(float64(stats.Hashed))
+	counts["archives_checked"] = stats.Checked
+	counts["archives_mismatched"] = stats.Mismatched
+	counts["archives_hashed"] = stats.Hashed
+	for _, mismatch := range stats.Mismatches {
+		var parts []string
+		if mismatch.HTML {
+			parts = append(parts, "html")
+		}
+		if mismatch.Text {
+			parts = append(parts, "extracted text")
+		}
+		logger.Printf("archive for link %s: %s no longer matches the digest recorded at ingest", mismatch.LinkID, strings.Join(parts, " and "))
+	}
 	if err != nil {
 		return err
 	}
 
-	logger.Printf("refreshed %d recommendations", count)
+	verb := "recorded"
+	if opts.DryRun {
+		verb = "would record"
+	}
+	logger.Printf("checked %d archives, %d mismatched, %s digests for %d, in %s",
+		stats.Checked, stats.Mismatched, verb, stats.Hashed, stats.Duration.Round(time.Millisecond))
+	if stats.Mismatched > 0 {
+		return fmt.Errorf("%d archive(s) do not match their digests; ingest the affected links again to replace them", stats.Mismatched)
+	}
 	return nil
 }
 
+// restoreTarget returns the backup named on the command line or via
+// BACKUP_PATH; empty means the newest manifest.
+func restoreTarget() string {
+	if len(os.Args) > 2 {
+		return os.Args[2]
+	}
+	return getEnvDefault("BACKUP_PATH", "")
+}
+
 func getEnvDefault(key, fallback string) string {
 	if value := os.Getenv(key); value != "" {
 		return value
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/example/keepstack/apps/api/internal/backup"
+	"github.com/example/keepstack/apps/api/internal/config"
+	"github.com/example/keepstack/apps/api/internal/digest"
+	"github.com/example/keepstack/apps/api/internal/resurfacer"
+	"github.com/example/keepstack/configcheck"
+	"github.com/example/keepstack/db/migrations"
+	"github.com/example/keepstack/health"
+)
+
+// cronSubcommands lists what --validate-config checks when no subcommand is
+// named.
+var cronSubcommands = []string{"digest", "verify-schema", "backup", "backup-prune", "restore", "resurface", "archive-vacuum", "search-reindex", "wordcount-backfill", "archive-resanitize", "archive-verify"}
+
+// validateConfig prints the configuration subcommand would run with and
+// checks it without doing any work, returning the exit code. An empty
+// subcommand checks all of them.
+func validateConfig(subcommand string) int {
+	subcommands := cronSubcommands
+	if subcommand != "" {
+		subcommands = []string{subcommand}
+	}
+
+	var (
+		settings []configcheck.Setting
+		checks   []configcheck.Check
+	)
+	cfg, err := config.Load()
+	if err != nil {
+		checks = append(checks, configcheck.Failed("load configuration", err))
+	} else {
+		settings = append(settings, configcheck.Settings(cfg)...)
+		checks = append(checks,
+			configcheck.DatabaseURL("DATABASE_URL", cfg.DatabaseURL, true),
+			databaseCheck(cfg.DatabaseURL),
+		)
+	}
+	checks = append(checks,
+		configcheck.URL("CRON_PUSHGATEWAY_URL", os.Getenv("CRON_PUSHGATEWAY_URL"), false, "http", "https"),
+		configcheck.URL("CRON_METRICS_URL", os.Getenv("CRON_METRICS_URL"), false, "http", "https"),
+	)
+
+	backupChecked := false
+	for _, name := range subcommands {
+		switch name {
+		case "digest":
+			digestCfg, err := digest.LoadConfig()
+			if err != nil {
+				checks = append(checks, configcheck.Failed("digest configuration", err))
+				continue
+			}
+			settings = append(settings, configcheck.Settings(digestCfg)...)
+			checks = append(checks, configcheck.Check{Name: "SMTP connectivity", Run: func(ctx context.Context) error {
+				return digest.CheckTransport(ctx, digestCfg.Transport)
+			}})
+		case "backup", "backup-prune", "restore":
+			if backupChecked {
+				continue
+			}
+			backupChecked = true
+			backupCfg, err := backup.LoadConfig()
+			if err != nil {
+				checks = append(checks, configcheck.Failed("backup configuration", err))
+				continue
+			}
+			settings = append(settings, configcheck.Settings(backupCfg)...)
+			checks = append(checks, configcheck.Check{Name: "S3 credentials", Run: func(ctx context.Context) error {
+				return backup.CheckS3(ctx, backupCfg)
+			}})
+		case "resurface":
+			if _, err := resurfacer.LoadWeightsFromEnv(); err != nil {
+				checks = append(checks, configcheck.Failed("resurfacer weights", err))
+			}
+		case "verify-schema", "archive-vacuum", "search-reindex", "wordcount-backfill", "archive-resanitize", "archive-verify":
+		default:
+			checks = append(checks, configcheck.Failed("subcommand", fmt.Errorf("unknown subcommand %q", name)))
+		}
+	}
+
+	if !configcheck.Run(context.Background(), os.Stdout, settings, checks, 10*time.Second) {
+		return 1
+	}
+	return 0
+}
+
+// databaseCheck connects to the database every subcommand uses and confirms
+// it has every migration this build shipped with.
+func databaseCheck(databaseURL string) configcheck.Check {
+	return configcheck.Check{Name: "database and schema version", Run: func(ctx context.Context) error {
+		pool, err := pgxpool.New(ctx, databaseURL)
+		if err != nil {
+			return err
+		}
+		defer pool.Close()
+
+		_, err = health.First(ctx, []health.Check{
+			health.Postgres(pool),
+			health.SchemaVersion(pool, migrations.Latest()),
+		})
+		return err
+	}}
+}
@@ -0,0 +1,24 @@
+// Command dashgen writes the Grafana RED dashboard generated from the metric
+// definitions in internal/observability.
+package main
+
+import (
+	"flag"
+	"log"
+	"os"
+
+	"github.com/example/keepstack/apps/api/internal/observability"
+)
+
+func main() {
+	out := flag.String("out", "deploy/charts/keepstack/dashboards/keepstack-red.json", "path to write the dashboard JSON")
+	flag.Parse()
+
+	dashboard, err := observability.RenderREDDashboard()
+	if err != nil {
+		log.Fatalf("render dashboard: %v", err)
+	}
+	if err := os.WriteFile(*out, dashboard, 0o644); err != nil {
+		log.Fatalf("write dashboard: %v", err)
+	}
+}
@@ -0,0 +1,259 @@
+// Command keepstackctl administers a keepstack deployment through its API.
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/example/keepstack/apps/api/internal/adminclient"
+)
+
+const defaultServer = "http://localhost:8080"
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if err := newRootCommand().ExecuteContext(ctx); err != nil {
+		os.Exit(1)
+	}
+}
+
+func newRootCommand() *cobra.Command {
+	var server, token string
+	var timeout time.Duration
+
+	root := &cobra.Command{
+		Use:          "keepstackctl",
+		Short:        "Administer a keepstack deployment through its API",
+		SilenceUsage: true,
+	}
+	root.PersistentFlags().StringVar(&server, "server", envOr("KEEPSTACK_SERVER", defaultServer), "API base URL (KEEPSTACK_SERVER)")
+	root.PersistentFlags().StringVar(&token, "token", os.Getenv("KEEPSTACK_ADMIN_TOKEN"), "admin token from ADMIN_TOKENS (KEEPSTACK_ADMIN_TOKEN)")
+	root.PersistentFlags().DurationVar(&timeout, "timeout", 30*time.Second, "timeout for each API request")
+
+	client := func() *adminclient.Client {
+		return adminclient.New(server, token, &http.Client{Timeout: timeout})
+	}
+
+	root.AddCommand(
+		newUsersCommand(client),
+		newExportCommand(client),
+		newImportCommand(client),
+		newJobsCommand(client),
+	)
+	return root
+}
+
+func newUsersCommand(client func() *adminclient.Client) *cobra.Command {
+	users := &cobra.Command{Use: "users", Short: "Manage user accounts"}
+
+	var email, password string
+	create := &cobra.Command{
+		Use:   "create",
+		Short: "Create a user; a password is generated when none is given",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			generated := password == ""
+			if generated {
+				var err error
+				if password, err = generatePassword(); err != nil {
+					return err
+				}
+			}
+
+			user, err := client().CreateUser(cmd.Context(), email, password)
+			if err != nil {
+				return explain(err)
+			}
+			out := cmd.OutOrStdout()
+			fmt.Fprintf(out, "created user %s (%s)\n", user.Email, user.ID)
+			if generated {
+				fmt.Fprintf(out, "password: %s\n", password)
+			}
+			return nil
+		},
+	}
+	create.Flags().StringVar(&email, "email", "", "email address to sign in with")
+	create.Flags().StringVar(&password, "password", "", "password, at least 8 characters")
+	create.MarkFlagRequired("email")
+
+	users.AddCommand(create)
+	return users
+}
+
+func newExportCommand(client func() *adminclient.Client) *cobra.Command {
+	var format, output string
+	var includeText bool
+	cmd := &cobra.Command{
+		Use:   "export",
+		Short: "Export saved links as JSON or CSV",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			out := cmd.OutOrStdout()
+			if output != "" && output != "-" {
+				file, err := os.Create(output)
+				if err != nil {
+					return err
+				}
+				defer file.Close()
+				out = file
+			}
+			return explain(client().Export(cmd.Context(), out, format, includeText))
+		},
+	}
+	cmd.Flags().StringVar(&format, "format", "json", "json or csv")
+	cmd.Flags().BoolVar(&includeText, "include-text", false, "include extracted article text")
+	cmd.Flags().StringVarP(&output, "output", "o", "-", "file to write, - for stdout")
+	return cmd
+}
+
+func newImportCommand(client func() *adminclient.Client) *cobra.Command {
+	var batch int
+	cmd := &cobra.Command{
+		Use:   "import FILE",
+		Short: "Import links from a JSON export; - reads stdin",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var in io.Reader = cmd.InOrStdin()
+			if args[0] != "-" {
+				file, err := os.Open(args[0])
+				if err != nil {
+					return err
+				}
+				defer file.Close()
+				in = file
+			}
+
+			result, err := client().Import(cmd.Context(), in, batch)
+			out := cmd.OutOrStdout()
+			for _, failure := range result.Failed {
+				fmt.Fprintf(out, "failed %s: %s\n", failure.URL, failure.Error)
+			}
+			fmt.Fprintf(out, "created %d, already saved %d, failed %d\n", result.Created, result.Existing, len(result.Failed))
+			return explain(err)
+		},
+	}
+	cmd.Flags().IntVar(&batch, "batch", adminclient.DefaultImportBatch, "links per request")
+	return cmd
+}
+
+func newJobsCommand(client func() *adminclient.Client) *cobra.Command {
+	jobs := &cobra.Command{Use: "jobs", Short: "Run and inspect cron jobs"}
+
+	run := &cobra.Command{
+		Use:       "run digest|resurface",
+		Short:     "Start a job now instead of waiting for its schedule",
+		Args:      cobra.ExactArgs(1),
+		ValidArgs: []string{"digest", "resurface"},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			run, err := client().RunJob(cmd.Context(), args[0])
+			if err != nil {
+				return explain(err)
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "started %s run %s; follow it with keepstackctl jobs list --subcommand %s\n", run.Subcommand, run.ID, run.Subcommand)
+			return nil
+		},
+	}
+
+	var filter adminclient.JobFilter
+	list := &cobra.Command{
+		Use:   "list",
+		Short: "List recent job runs, newest first",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			runs, err := client().ListJobs(cmd.Context(), filter)
+			if err != nil {
+				return explain(err)
+			}
+			w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 4, 2, ' ', 0)
+			fmt.Fprintln(w, "STARTED\tJOB\tSTATUS\tDURATION\tERROR")
+			for _, run := range runs {
+				writeRun(w, run)
+			}
+			return w.Flush()
+		},
+	}
+	list.Flags().StringVar(&filter.Subcommand, "subcommand", "", "only runs of this job")
+	list.Flags().StringVar(&filter.Status, "status", "", "only runs with this status: running, success, failure, or skipped")
+	list.Flags().IntVar(&filter.Limit, "limit", 20, "runs to show, at most 100")
+
+	var follow bool
+	var interval time.Duration
+	tail := &cobra.Command{
+		Use:   "tail",
+		Short: "Print failed job runs, optionally waiting for new ones",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if !follow {
+				interval = 0
+			}
+			w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 4, 2, ' ', 0)
+			err := client().FollowJobs(cmd.Context(), adminclient.JobFilter{Status: "failure", Limit: 100}, interval, func(run adminclient.JobRun) {
+				writeRun(w, run)
+				w.Flush()
+			})
+			return explain(err)
+		},
+	}
+	tail.Flags().BoolVarP(&follow, "follow", "f", false, "keep polling for new failures")
+	tail.Flags().DurationVar(&interval, "interval", 30*time.Second, "how often to poll with --follow")
+
+	jobs.AddCommand(run, list, tail)
+	return jobs
+}
+
+func writeRun(w io.Writer, run adminclient.JobRun) {
+	duration := "-"
+	if run.DurationMs != nil {
+		duration = (time.Duration(*run.DurationMs) * time.Millisecond).String()
+	}
+	errText := run.Error
+	if errText == "" {
+		errText = "-"
+	}
+	fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", run.StartedAt.Local().Format(time.DateTime), run.Subcommand, run.Status, duration, strings.ReplaceAll(errText, "\n", " "))
+}
+
+// explain adds a hint to errors operators commonly hit.
+func explain(err error) error {
+	var apiErr *adminclient.Error
+	if !errors.As(err, &apiErr) {
+		return err
+	}
+	switch {
+	case apiErr.Status == http.StatusNotFound && apiErr.Message == "Not Found":
+		return fmt.Errorf("%w (is ADMIN_TOKENS set on the API?)", err)
+	case apiErr.Status == http.StatusUnauthorized:
+		return fmt.Errorf("%w (pass --token or set KEEPSTACK_ADMIN_TOKEN)", err)
+	}
+	return err
+}
+
+func generatePassword() (string, error) {
+	buf := make([]byte, 18)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("generate password: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+func envOr(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
+}
@@ -3,29 +3,37 @@ package main
 import (
 	"context"
 	"database/sql"
+	"flag"
+	"fmt"
+	"io/fs"
 	"log"
 	"os"
+	"strings"
 
 	_ "github.com/jackc/pgx/v5/stdlib"
 	"github.com/pressly/goose/v3"
 
 	"github.com/example/keepstack/apps/api/internal/config"
+	"github.com/example/keepstack/apps/api/internal/schema"
+	"github.com/example/keepstack/db/migrations"
 )
 
-const defaultMigrationsDir = "db/migrations"
-
 func main() {
 	logger := log.New(os.Stdout, "keepstack-migrate ", log.LstdFlags|log.LUTC)
 
+	subcommand := "up"
+	args := os.Args[1:]
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		subcommand, args = args[0], args[1:]
+	}
+
 	cfg, err := config.Load()
 	if err != nil {
 		logger.Fatalf("load config: %v", err)
 	}
 
-	migrationsDir := os.Getenv("MIGRATIONS_DIR")
-	if migrationsDir == "" {
-		migrationsDir = defaultMigrationsDir
-	}
+	source, sourceName := migrationSource()
+	goose.SetBaseFS(source)
 
 	db, err := sql.Open("pgx", cfg.DatabaseURL)
 	if err != nil {
@@ -41,9 +49,98 @@ func main() {
 		logger.Fatalf("set goose dialect: %v", err)
 	}
 
-	if err := goose.Up(db, migrationsDir); err != nil {
-		logger.Fatalf("apply migrations: %v", err)
+	if err := run(logger, cfg, db, source, subcommand, args); err != nil {
+		logger.Fatalf("%s: %v", subcommand, err)
 	}
 
-	logger.Printf("migrations applied from %s", migrationsDir)
+	if subcommand == "up" {
+		logger.Printf("migrations applied from %s", sourceName)
+	}
+}
+
+// migrationSource returns the migrations built into the binary, or the
+// directory named by MIGRATIONS_DIR when set, e.g. to test a migration
+// without rebuilding the image.
+func migrationSource() (fs.FS, string) {
+	if dir := os.Getenv("MIGRATIONS_DIR"); dir != "" {
+		return os.DirFS(dir), dir
+	}
+	return migrations.FS, "embedded migrations"
+}
+
+func run(logger *log.Logger, cfg config.Config, db *sql.DB, source fs.FS, subcommand string, args []string) error {
+	switch subcommand {
+	case "up":
+		flags := flag.NewFlagSet("up", flag.ContinueOnError)
+		allowUnsafe := flags.Bool("allow-unsafe", false, "apply migrations that are unsafe while the previous release is serving")
+		if err := flags.Parse(args); err != nil {
+			return err
+		}
+		findings, err := preflight(db, source)
+		if err != nil {
+			return err
+		}
+		if len(findings) > 0 {
+			reportFindings(logger, findings)
+			if !*allowUnsafe {
+				return fmt.Errorf("%d unsafe statement(s) in pending migrations; rerun with --allow-unsafe during a maintenance window", len(findings))
+			}
+			logger.Printf("applying %d unsafe statement(s) because --allow-unsafe is set", len(findings))
+		}
+		return goose.Up(db, ".")
+	case "check":
+		findings, err := preflight(db, source)
+		if err != nil {
+			return err
+		}
+		if len(findings) > 0 {
+			reportFindings(logger, findings)
+			return fmt.Errorf("%d unsafe statement(s) in pending migrations", len(findings))
+		}
+		logger.Printf("pending migrations are safe for a rolling deploy")
+		return nil
+	case "status":
+		return goose.Status(db, ".")
+	case "down":
+		flags := flag.NewFlagSet("down", flag.ContinueOnError)
+		to := flags.Int64("to", -1, "version to roll back to; 0 reverts every migration")
+		if err := flags.Parse(args); err != nil {
+			return err
+		}
+		if *to < 0 {
+			return fmt.Errorf("--to is required")
+		}
+		return goose.DownTo(db, ".", *to)
+	case "seed":
+		return runSeed(logger, cfg, args)
+	default:
+		return fmt.Errorf("unknown subcommand %q (available: up [--allow-unsafe], check, status, down --to <version>, seed [--env <name>] [--reset])", subcommand)
+	}
+}
+
+// preflight classifies the migrations goose up would apply next.
+func preflight(db *sql.DB, source fs.FS) ([]schema.Finding, error) {
+	current, err := goose.EnsureDBVersion(db)
+	if err != nil {
+		return nil, fmt.Errorf("read database version: %w", err)
+	}
+	collected, err := goose.CollectMigrations(".", current, goose.MaxVersion)
+	if err != nil {
+		return nil, fmt.Errorf("collect migrations: %w", err)
+	}
+	pending := make([]schema.Migration, 0, len(collected))
+	for _, migration := range collected {
+		data, err := fs.ReadFile(source, migration.Source)
+		if err != nil {
+			return nil, fmt.Errorf("read %s: %w", migration.Source, err)
+		}
+		pending = append(pending, schema.Migration{Name: migration.Source, SQL: string(data)})
+	}
+	return schema.Preflight(pending), nil
+}
+
+func reportFindings(logger *log.Logger, findings []schema.Finding) {
+	for _, finding := range findings {
+		logger.Printf("unsafe: %s: %s: %s", finding.Migration, finding.Statement, finding.Reason)
+	}
 }
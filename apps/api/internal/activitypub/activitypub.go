@@ -0,0 +1,204 @@
+// Package activitypub holds the ActivityPub vocabulary, HTTP signatures, and
+// the client keepstack uses to federate a user's public links.
+package activitypub
+
+import (
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"time"
+)
+
+const (
+	// ContentType is the media type ActivityPub documents are served and
+	// delivered with.
+	ContentType = "application/activity+json"
+	// PublicCollection addresses an activity to everyone.
+	PublicCollection = "https://www.w3.org/ns/activitystreams#Public"
+
+	activityStreamsContext = "https://www.w3.org/ns/activitystreams"
+	securityContext        = "https://w3id.org/security/v1"
+)
+
+// Context is the @context for documents that only use ActivityStreams terms.
+var Context any = activityStreamsContext
+
+// ActorContext is the @context for actors, which also carry a public key.
+var ActorContext any = []string{activityStreamsContext, securityContext}
+
+// PublicKey is the key remote servers use to verify an actor's signatures.
+type PublicKey struct {
+	ID           string `json:"id"`
+	Owner        string `json:"owner"`
+	PublicKeyPem string `json:"publicKeyPem"`
+}
+
+// Endpoints lists an actor's optional server-wide endpoints.
+type Endpoints struct {
+	SharedInbox string `json:"sharedInbox,omitempty"`
+}
+
+// Actor is a Person, both as served for local users and as fetched from
+// remote servers.
+type Actor struct {
+	Context                   any        `json:"@context,omitempty"`
+	ID                        string     `json:"id"`
+	Type                      string     `json:"type"`
+	PreferredUsername         string     `json:"preferredUsername,omitempty"`
+	Name                      string     `json:"name,omitempty"`
+	Summary                   string     `json:"summary,omitempty"`
+	Inbox                     string     `json:"inbox"`
+	Outbox                    string     `json:"outbox,omitempty"`
+	Followers                 string     `json:"followers,omitempty"`
+	ManuallyApprovesFollowers bool       `json:"manuallyApprovesFollowers"`
+	Endpoints                 *Endpoints `json:"endpoints,omitempty"`
+	PublicKey                 PublicKey  `json:"publicKey"`
+}
+
+// DeliveryInbox is where activities for this actor's followers go: the shared
+// inbox when the server offers one, otherwise the actor's own.
+func (a Actor) DeliveryInbox() string {
+	if a.Endpoints != nil && a.Endpoints.SharedInbox != "" {
+		return a.Endpoints.SharedInbox
+	}
+	return a.Inbox
+}
+
+// Note is a saved link as followers see it.
+type Note struct {
+	Context      any       `json:"@context,omitempty"`
+	ID           string    `json:"id"`
+	Type         string    `json:"type"`
+	AttributedTo string    `json:"attributedTo"`
+	Content      string    `json:"content"`
+	URL          string    `json:"url"`
+	Published    time.Time `json:"published"`
+	To           []string  `json:"to"`
+	Cc           []string  `json:"cc,omitempty"`
+}
+
+// Tombstone replaces a Note that is no longer public.
+type Tombstone struct {
+	ID   string `json:"id"`
+	Type string `json:"type"`
+}
+
+// Activity is an outgoing activity such as Create, Delete, or Accept.
+type Activity struct {
+	Context   any        `json:"@context,omitempty"`
+	ID        string     `json:"id"`
+	Type      string     `json:"type"`
+	Actor     string     `json:"actor"`
+	Object    any        `json:"object"`
+	Published *time.Time `json:"published,omitempty"`
+	To        []string   `json:"to,omitempty"`
+	Cc        []string   `json:"cc,omitempty"`
+}
+
+// OrderedCollection is an outbox or followers collection. Items are served
+// through First rather than inline.
+type OrderedCollection struct {
+	Context    any    `json:"@context,omitempty"`
+	ID         string `json:"id"`
+	Type       string `json:"type"`
+	TotalItems int64  `json:"totalItems"`
+	First      string `json:"first,omitempty"`
+}
+
+// OrderedCollectionPage is one page of an outbox, newest first.
+type OrderedCollectionPage struct {
+	Context      any    `json:"@context,omitempty"`
+	ID           string `json:"id"`
+	Type         string `json:"type"`
+	PartOf       string `json:"partOf"`
+	Next         string `json:"next,omitempty"`
+	OrderedItems []any  `json:"orderedItems"`
+}
+
+// IncomingActivity is an activity posted to an inbox. Object is kept raw
+// because it may be a URI or an embedded object.
+type IncomingActivity struct {
+	ID     string          `json:"id"`
+	Type   string          `json:"type"`
+	Actor  string          `json:"actor"`
+	Object json.RawMessage `json:"object"`
+}
+
+// ObjectID returns the id of the activity's object, whether it was sent as a
+// bare URI or embedded.
+func (a IncomingActivity) ObjectID() string {
+	var id string
+	if json.Unmarshal(a.Object, &id) == nil {
+		return id
+	}
+	var object struct {
+		ID string `json:"id"`
+	}
+	if json.Unmarshal(a.Object, &object) == nil {
+		return object.ID
+	}
+	return ""
+}
+
+// EmbeddedActivity decodes an embedded object as an activity, as in
+// Undo{Follow}. It returns false when the object is only a URI.
+func (a IncomingActivity) EmbeddedActivity() (IncomingActivity, bool) {
+	var inner IncomingActivity
+	if err := json.Unmarshal(a.Object, &inner); err != nil || inner.Type == "" {
+		return IncomingActivity{}, false
+	}
+	return inner, true
+}
+
+// ParsePrivateKey decodes a PEM-encoded RSA key in PKCS#1 or PKCS#8 form.
+func ParsePrivateKey(data string) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode([]byte(data))
+	if block == nil {
+		return nil, errors.New("no PEM block found")
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("parse private key: %w", err)
+	}
+	key, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("private key is not RSA")
+	}
+	return key, nil
+}
+
+// ParsePublicKey decodes a PEM-encoded RSA public key as published in an
+// actor's publicKeyPem.
+func ParsePublicKey(data string) (*rsa.PublicKey, error) {
+	block, _ := pem.Decode([]byte(data))
+	if block == nil {
+		return nil, errors.New("no PEM block found")
+	}
+	parsed, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		if key, pkcs1Err := x509.ParsePKCS1PublicKey(block.Bytes); pkcs1Err == nil {
+			return key, nil
+		}
+		return nil, fmt.Errorf("parse public key: %w", err)
+	}
+	key, ok := parsed.(*rsa.PublicKey)
+	if !ok {
+		return nil, errors.New("public key is not RSA")
+	}
+	return key, nil
+}
+
+// EncodePublicKey returns key as PEM for an actor's publicKeyPem.
+func EncodePublicKey(key *rsa.PublicKey) (string, error) {
+	der, err := x509.MarshalPKIXPublicKey(key)
+	if err != nil {
+		return "", fmt.Errorf("marshal public key: %w", err)
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})), nil
+}
@@ -0,0 +1,106 @@
+package activitypub
+
+import (
+	"bytes"
+	"context"
+	"crypto/rsa"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// maxResponseBytes bounds how much of a remote document is read.
+const maxResponseBytes = 1 << 20
+
+// Client fetches remote actors and delivers activities, signing every request
+// with the instance key so servers with authorized fetch accept it.
+type Client struct {
+	http *http.Client
+	key  *rsa.PrivateKey
+	now  func() time.Time
+}
+
+// NewClient returns a Client that signs with key and sends requests through
+// httpClient.
+func NewClient(httpClient *http.Client, key *rsa.PrivateKey) *Client {
+	return &Client{http: httpClient, key: key, now: time.Now}
+}
+
+// FetchActor retrieves the actor at uri, signing as keyID.
+func (c *Client) FetchActor(ctx context.Context, keyID, uri string) (Actor, error) {
+	if err := checkRemoteURL(uri); err != nil {
+		return Actor{}, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, uri, nil)
+	if err != nil {
+		return Actor{}, fmt.Errorf("build request: %w", err)
+	}
+	req.Header.Set("Accept", ContentType)
+	if err := Sign(req, keyID, c.key, nil, c.now()); err != nil {
+		return Actor{}, err
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return Actor{}, fmt.Errorf("fetch actor %s: %w", uri, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return Actor{}, fmt.Errorf("fetch actor %s: unexpected status %d", uri, resp.StatusCode)
+	}
+
+	var actor Actor
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxResponseBytes)).Decode(&actor); err != nil {
+		return Actor{}, fmt.Errorf("decode actor %s: %w", uri, err)
+	}
+	if actor.ID == "" || actor.Inbox == "" {
+		return Actor{}, fmt.Errorf("actor %s has no id or inbox", uri)
+	}
+	return actor, nil
+}
+
+// Deliver posts activity to inbox, signing as keyID.
+func (c *Client) Deliver(ctx context.Context, keyID, inbox string, activity any) error {
+	if err := checkRemoteURL(inbox); err != nil {
+		return err
+	}
+	body, err := json.Marshal(activity)
+	if err != nil {
+		return fmt.Errorf("encode activity: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, inbox, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("build request: %w", err)
+	}
+	req.Header.Set("Content-Type", ContentType)
+	if err := Sign(req, keyID, c.key, body, c.now()); err != nil {
+		return err
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return fmt.Errorf("deliver to %s: %w", inbox, err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, maxResponseBytes))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("deliver to %s: unexpected status %d", inbox, resp.StatusCode)
+	}
+	return nil
+}
+
+// checkRemoteURL only lets requests go to https URLs, since the addresses
+// come from remote servers.
+func checkRemoteURL(raw string) error {
+	parsed, err := url.Parse(raw)
+	if err != nil {
+		return fmt.Errorf("parse url %q: %w", raw, err)
+	}
+	if parsed.Scheme != "https" || parsed.Host == "" {
+		return fmt.Errorf("url %q is not an https url", raw)
+	}
+	return nil
+}
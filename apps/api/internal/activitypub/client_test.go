@@ -0,0 +1,119 @@
+package activitypub
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestKey(t *testing.T) *rsa.PrivateKey {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	return key
+}
+
+func TestDeliverSignsRequests(t *testing.T) {
+	t.Parallel()
+
+	key := newTestKey(t)
+	received := make(chan error, 1)
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if r.Header.Get("Content-Type") != ContentType {
+			received <- io.ErrUnexpectedEOF
+		}
+		keyID, err := SignatureKeyID(r)
+		if err == nil && keyID != "https://keepstack.example/api/ap/users/ada#main-key" {
+			err = io.ErrUnexpectedEOF
+		}
+		if err == nil {
+			err = Verify(r, body, &key.PublicKey, time.Now())
+		}
+		received <- err
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	t.Cleanup(server.Close)
+
+	client := NewClient(server.Client(), key)
+	activity := Activity{ID: "https://keepstack.example/a/1", Type: "Create", Actor: "https://keepstack.example/api/ap/users/ada"}
+	if err := client.Deliver(context.Background(), "https://keepstack.example/api/ap/users/ada#main-key", server.URL+"/inbox", activity); err != nil {
+		t.Fatalf("deliver: %v", err)
+	}
+	if err := <-received; err != nil {
+		t.Fatalf("inbox could not verify the delivery: %v", err)
+	}
+}
+
+func TestFetchActorRejectsNonHTTPS(t *testing.T) {
+	t.Parallel()
+
+	client := NewClient(http.DefaultClient, newTestKey(t))
+	if _, err := client.FetchActor(context.Background(), "key", "http://10.0.0.1/actor"); err == nil {
+		t.Fatalf("expected plain http actor url to be rejected")
+	}
+	if err := client.Deliver(context.Background(), "key", "file:///etc/passwd", Activity{}); err == nil {
+		t.Fatalf("expected non-https inbox to be rejected")
+	}
+}
+
+func TestVerifyRejectsTampering(t *testing.T) {
+	t.Parallel()
+
+	key := newTestKey(t)
+	now := time.Now()
+	body := []byte(`{"type":"Follow"}`)
+	sign := func() *http.Request {
+		req := httptest.NewRequest(http.MethodPost, "https://keepstack.example/api/ap/users/ada/inbox", strings.NewReader(string(body)))
+		if err := Sign(req, "https://remote.example/users/bob#main-key", key, body, now); err != nil {
+			t.Fatalf("sign: %v", err)
+		}
+		return req
+	}
+
+	if err := Verify(sign(), body, &key.PublicKey, now); err != nil {
+		t.Fatalf("expected signature to verify: %v", err)
+	}
+	if err := Verify(sign(), []byte(`{"type":"Undo"}`), &key.PublicKey, now); err == nil {
+		t.Fatalf("expected a changed body to fail the digest check")
+	}
+	if err := Verify(sign(), body, &newTestKey(t).PublicKey, now); err == nil {
+		t.Fatalf("expected another key to fail verification")
+	}
+	if err := Verify(sign(), body, &key.PublicKey, now.Add(MaxClockSkew+time.Minute)); err == nil {
+		t.Fatalf("expected a stale date to be rejected")
+	}
+	moved := sign()
+	moved.URL.Path = "/api/ap/users/eve/inbox"
+	if err := Verify(moved, body, &key.PublicKey, now); err == nil {
+		t.Fatalf("expected a different request target to fail verification")
+	}
+}
+
+func TestIncomingActivityObject(t *testing.T) {
+	t.Parallel()
+
+	var undo IncomingActivity
+	if err := json.Unmarshal([]byte(`{"type":"Undo","actor":"https://remote.example/users/bob","object":{"id":"https://remote.example/f/1","type":"Follow","object":"https://keepstack.example/api/ap/users/ada"}}`), &undo); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	follow, ok := undo.EmbeddedActivity()
+	if !ok || follow.Type != "Follow" || follow.ObjectID() != "https://keepstack.example/api/ap/users/ada" {
+		t.Fatalf("unexpected embedded activity: %+v", follow)
+	}
+	if undo.ObjectID() != "https://remote.example/f/1" {
+		t.Fatalf("unexpected object id %q", undo.ObjectID())
+	}
+	if _, ok := follow.EmbeddedActivity(); ok {
+		t.Fatalf("expected a bare uri object not to decode as an activity")
+	}
+}
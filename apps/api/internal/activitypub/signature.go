@@ -0,0 +1,142 @@
+package activitypub
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+)
+
+// MaxClockSkew is how far a signed request's Date may be from now. It matches
+// Mastodon's window so requests from slow queues still verify.
+const MaxClockSkew = 12 * time.Hour
+
+// Sign adds Host, Date, and, when body is non-nil, Digest headers to req and
+// signs them with key in the draft-cavage HTTP Signatures scheme Mastodon and
+// most ActivityPub servers require.
+func Sign(req *http.Request, keyID string, key *rsa.PrivateKey, body []byte, now time.Time) error {
+	req.Header.Set("Date", now.UTC().Format(http.TimeFormat))
+	headers := []string{"(request-target)", "host", "date"}
+	if body != nil {
+		req.Header.Set("Digest", digest(body))
+		headers = append(headers, "digest")
+	}
+
+	hashed := sha256.Sum256([]byte(signingString(req, headers)))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, hashed[:])
+	if err != nil {
+		return fmt.Errorf("sign request: %w", err)
+	}
+	req.Header.Set("Signature", fmt.Sprintf(`keyId="%s",algorithm="rsa-sha256",headers="%s",signature="%s"`,
+		keyID, strings.Join(headers, " "), base64.StdEncoding.EncodeToString(signature)))
+	return nil
+}
+
+// SignatureKeyID returns the keyId of req's Signature header, which names the
+// key, and through it the actor, that signed the request.
+func SignatureKeyID(req *http.Request) (string, error) {
+	params, err := parseSignature(req.Header.Get("Signature"))
+	if err != nil {
+		return "", err
+	}
+	return params["keyId"], nil
+}
+
+// Verify checks req's Signature header against key. The signature must cover
+// the request target, host, and date, and for requests with a body the
+// digest, which must match body.
+func Verify(req *http.Request, body []byte, key *rsa.PublicKey, now time.Time) error {
+	params, err := parseSignature(req.Header.Get("Signature"))
+	if err != nil {
+		return err
+	}
+	if algorithm := params["algorithm"]; algorithm != "" && algorithm != "rsa-sha256" && algorithm != "hs2019" {
+		return fmt.Errorf("unsupported signature algorithm %q", algorithm)
+	}
+
+	headers := strings.Fields(strings.ToLower(params["headers"]))
+	if len(headers) == 0 {
+		headers = []string{"date"}
+	}
+	required := []string{"(request-target)", "host", "date"}
+	if len(body) > 0 {
+		required = append(required, "digest")
+	}
+	for _, header := range required {
+		if !slices.Contains(headers, header) {
+			return fmt.Errorf("signature does not cover %s", header)
+		}
+	}
+
+	date, err := http.ParseTime(req.Header.Get("Date"))
+	if err != nil {
+		return fmt.Errorf("parse date: %w", err)
+	}
+	if skew := now.Sub(date); skew > MaxClockSkew || skew < -MaxClockSkew {
+		return fmt.Errorf("date %s is outside the allowed clock skew", date.Format(time.RFC3339))
+	}
+	if len(body) > 0 && req.Header.Get("Digest") != digest(body) {
+		return errors.New("digest does not match body")
+	}
+
+	signature, err := base64.StdEncoding.DecodeString(params["signature"])
+	if err != nil {
+		return fmt.Errorf("decode signature: %w", err)
+	}
+	hashed := sha256.Sum256([]byte(signingString(req, headers)))
+	if err := rsa.VerifyPKCS1v15(key, crypto.SHA256, hashed[:], signature); err != nil {
+		return errors.New("signature does not verify")
+	}
+	return nil
+}
+
+func digest(body []byte) string {
+	sum := sha256.Sum256(body)
+	return "SHA-256=" + base64.StdEncoding.EncodeToString(sum[:])
+}
+
+func signingString(req *http.Request, headers []string) string {
+	lines := make([]string, 0, len(headers))
+	for _, header := range headers {
+		var value string
+		switch header {
+		case "(request-target)":
+			value = strings.ToLower(req.Method) + " " + req.URL.RequestURI()
+		case "host":
+			value = req.Host
+			if value == "" {
+				value = req.URL.Host
+			}
+		default:
+			value = strings.Join(req.Header.Values(header), ", ")
+		}
+		lines = append(lines, header+": "+value)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// parseSignature splits a Signature header into its quoted parameters.
+func parseSignature(header string) (map[string]string, error) {
+	if header == "" {
+		return nil, errors.New("request is not signed")
+	}
+	params := make(map[string]string)
+	for _, part := range strings.Split(header, ",") {
+		name, value, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok {
+			return nil, fmt.Errorf("malformed signature parameter %q", part)
+		}
+		params[name] = strings.Trim(value, `"`)
+	}
+	if params["keyId"] == "" || params["signature"] == "" {
+		return nil, errors.New("signature is missing keyId or signature")
+	}
+	return params, nil
+}
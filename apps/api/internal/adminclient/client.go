@@ -0,0 +1,296 @@
+// Package adminclient calls the keepstack admin API on behalf of
+// keepstackctl.
+package adminclient
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultImportBatch is how many links Import sends per request, matching the
+// API's per-request cap.
+const DefaultImportBatch = 500
+
+// maxRetries bounds how often a rate limited request is retried.
+const maxRetries = 5
+
+// Error is a non-2xx response from the API.
+type Error struct {
+	Status  int
+	Message string
+	// Code is the API's machine-readable error code, such as "not_found".
+	// It is empty for responses from a proxy in front of the API.
+	Code      string
+	RequestID string
+}
+
+func (e *Error) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("api returned %d", e.Status)
+	}
+	if e.RequestID != "" {
+		return fmt.Sprintf("api returned %d: %s (request %s)", e.Status, e.Message, e.RequestID)
+	}
+	return fmt.Sprintf("api returned %d: %s", e.Status, e.Message)
+}
+
+// User is an account created through CreateUser.
+type User struct {
+	ID        string    `json:"id"`
+	Email     string    `json:"email"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// JobRun is a cron_runs entry, whether started by the CronJob or by RunJob.
+type JobRun struct {
+	ID         string           `json:"id"`
+	Subcommand string           `json:"subcommand"`
+	Status     string           `json:"status"`
+	StartedAt  time.Time        `json:"started_at"`
+	FinishedAt *time.Time       `json:"finished_at,omitempty"`
+	DurationMs *int64           `json:"duration_ms,omitempty"`
+	Counts     map[string]int64 `json:"counts"`
+	Error      string           `json:"error,omitempty"`
+}
+
+// JobFilter narrows ListJobs. Empty fields match everything.
+type JobFilter struct {
+	Subcommand string
+	Status     string
+	Limit      int
+}
+
+// ImportFailure is a link the API could not import.
+type ImportFailure struct {
+	URL   string `json:"url"`
+	Error string `json:"error"`
+}
+
+// ImportResult totals the batches of an Import.
+type ImportResult struct {
+	Created  int             `json:"created"`
+	Existing int             `json:"existing"`
+	Failed   []ImportFailure `json:"failed"`
+}
+
+// Client talks to one keepstack API with an admin token.
+type Client struct {
+	baseURL string
+	token   string
+	http    *http.Client
+	sleep   func(context.Context, time.Duration) error
+}
+
+// New returns a Client for the API at baseURL, e.g. https://keepstack.example.
+func New(baseURL, token string, httpClient *http.Client) *Client {
+	return &Client{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		token:   token,
+		http:    httpClient,
+		sleep:   sleep,
+	}
+}
+
+// CreateUser registers an account.
+func (c *Client) CreateUser(ctx context.Context, email, password string) (User, error) {
+	var user User
+	err := c.doJSON(ctx, http.MethodPost, "/api/admin/users", map[string]string{"email": email, "password": password}, &user)
+	return user, err
+}
+
+// RunJob starts a cron job, such as digest or resurface, and returns its run
+// without waiting for it to finish.
+func (c *Client) RunJob(ctx context.Context, name string) (JobRun, error) {
+	var run JobRun
+	err := c.doJSON(ctx, http.MethodPost, "/api/admin/jobs/"+url.PathEscape(name), nil, &run)
+	return run, err
+}
+
+// ListJobs returns job runs, newest first.
+func (c *Client) ListJobs(ctx context.Context, filter JobFilter) ([]JobRun, error) {
+	query := url.Values{}
+	if filter.Subcommand != "" {
+		query.Set("subcommand", filter.Subcommand)
+	}
+	if filter.Status != "" {
+		query.Set("status", filter.Status)
+	}
+	if filter.Limit > 0 {
+		query.Set("limit", strconv.Itoa(filter.Limit))
+	}
+	path := "/api/admin/jobs"
+	if len(query) > 0 {
+		path += "?" + query.Encode()
+	}
+
+	var page struct {
+		Items []JobRun `json:"items"`
+	}
+	err := c.doJSON(ctx, http.MethodGet, path, nil, &page)
+	return page.Items, err
+}
+
+// FollowJobs calls fn for each run matching filter, oldest first, then polls
+// every interval and calls it for runs it has not seen until ctx is done.
+// A zero interval returns after the first listing.
+func (c *Client) FollowJobs(ctx context.Context, filter JobFilter, interval time.Duration, fn func(JobRun)) error {
+	seen := make(map[string]struct{})
+	for {
+		runs, err := c.ListJobs(ctx, filter)
+		if err != nil {
+			return err
+		}
+		for i := len(runs) - 1; i >= 0; i-- {
+			if _, ok := seen[runs[i].ID]; ok {
+				continue
+			}
+			seen[runs[i].ID] = struct{}{}
+			fn(runs[i])
+		}
+
+		if interval <= 0 {
+			return nil
+		}
+		if err := c.sleep(ctx, interval); err != nil {
+			return nil
+		}
+	}
+}
+
+// Export writes the library as JSON or CSV to w.
+func (c *Client) Export(ctx context.Context, w io.Writer, format string, includeText bool) error {
+	query := url.Values{"format": {format}}
+	if includeText {
+		query.Set("include_text", "true")
+	}
+	resp, err := c.do(ctx, http.MethodGet, "/api/links/export?"+query.Encode(), nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if _, err := io.Copy(w, resp.Body); err != nil {
+		return fmt.Errorf("read export: %w", err)
+	}
+	return nil
+}
+
+// Import sends the links of a JSON export read from r in batches of
+// batchSize and totals the results.
+func (c *Client) Import(ctx context.Context, r io.Reader, batchSize int) (ImportResult, error) {
+	if batchSize <= 0 || batchSize > DefaultImportBatch {
+		batchSize = DefaultImportBatch
+	}
+	var links []json.RawMessage
+	if err := json.NewDecoder(r).Decode(&links); err != nil {
+		return ImportResult{}, fmt.Errorf("read import file: %w", err)
+	}
+
+	total := ImportResult{Failed: []ImportFailure{}}
+	for start := 0; start < len(links); start += batchSize {
+		end := min(start+batchSize, len(links))
+		var batch ImportResult
+		if err := c.doJSON(ctx, http.MethodPost, "/api/admin/import", links[start:end], &batch); err != nil {
+			return total, fmt.Errorf("import links %d-%d: %w", start+1, end, err)
+		}
+		total.Created += batch.Created
+		total.Existing += batch.Existing
+		total.Failed = append(total.Failed, batch.Failed...)
+	}
+	return total, nil
+}
+
+func (c *Client) doJSON(ctx context.Context, method, path string, body, out any) error {
+	var payload []byte
+	if body != nil {
+		encoded, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("encode request: %w", err)
+		}
+		payload = encoded
+	}
+	resp, err := c.do(ctx, method, path, payload)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("decode response: %w", err)
+	}
+	return nil
+}
+
+// do sends a request, waiting out 429s for as long as Retry-After asks, and
+// turns other non-2xx responses into an *Error.
+func (c *Client) do(ctx context.Context, method, path string, body []byte) (*http.Response, error) {
+	for attempt := 0; ; attempt++ {
+		req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, bytes.NewReader(body))
+		if err != nil {
+			return nil, fmt.Errorf("build request: %w", err)
+		}
+		if body != nil {
+			req.Header.Set("Content-Type", "application/json")
+		}
+		if c.token != "" {
+			req.Header.Set("Authorization", "Bearer "+c.token)
+		}
+
+		resp, err := c.http.Do(req)
+		if err != nil {
+			return nil, fmt.Errorf("%s %s: %w", method, path, err)
+		}
+		if resp.StatusCode >= 200 && resp.StatusCode <= 299 {
+			return resp, nil
+		}
+
+		apiErr := readError(resp)
+		if resp.StatusCode != http.StatusTooManyRequests || attempt >= maxRetries {
+			return nil, apiErr
+		}
+		wait := time.Second
+		if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && seconds > 0 {
+			wait = time.Duration(seconds) * time.Second
+		}
+		if err := c.sleep(ctx, wait); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// readError reads the API's error envelope. Bodies that are not one, such
+// as a proxy's error page, become the message as they are.
+func readError(resp *http.Response) *Error {
+	defer resp.Body.Close()
+	var body struct {
+		Code      string `json:"code"`
+		Message   string `json:"message"`
+		RequestID string `json:"request_id"`
+	}
+	raw, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	apiErr := &Error{Status: resp.StatusCode, Message: strings.TrimSpace(string(raw))}
+	if err := json.Unmarshal(raw, &body); err == nil {
+		apiErr.Message = cmp.Or(body.Message, apiErr.Message)
+		apiErr.Code = body.Code
+		apiErr.RequestID = body.RequestID
+	}
+	return apiErr
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
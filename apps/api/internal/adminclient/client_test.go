@@ -0,0 +1,145 @@
+package adminclient
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func newTestClient(t *testing.T, handler http.HandlerFunc) *Client {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	client := New(server.URL+"/", "secret", server.Client())
+	client.sleep = func(ctx context.Context, d time.Duration) error { return ctx.Err() }
+	return client
+}
+
+func TestImportSendsBatches(t *testing.T) {
+	t.Parallel()
+
+	var sizes []int
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/admin/import" || r.Header.Get("Authorization") != "Bearer secret" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		var links []map[string]any
+		json.NewDecoder(r.Body).Decode(&links)
+		sizes = append(sizes, len(links))
+		fmt.Fprintf(w, `{"created":%d,"existing":0,"failed":[{"url":"u%d","error":"invalid url"}]}`, len(links)-1, len(sizes))
+	})
+
+	result, err := client.Import(context.Background(), strings.NewReader(`[{"url":"a"},{"url":"b"},{"url":"c"},{"url":"d"},{"url":"e"}]`), 2)
+	if err != nil {
+		t.Fatalf("import: %v", err)
+	}
+	if fmt.Sprint(sizes) != "[2 2 1]" {
+		t.Fatalf("expected batches of 2, 2, and 1, got %v", sizes)
+	}
+	if result.Created != 2 || len(result.Failed) != 3 {
+		t.Fatalf("unexpected totals: %+v", result)
+	}
+}
+
+func TestRetriesRateLimitedRequests(t *testing.T) {
+	t.Parallel()
+
+	var calls atomic.Int32
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) < 3 {
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		fmt.Fprint(w, `{"id":"run-1","subcommand":"digest","status":"running"}`)
+	})
+
+	run, err := client.RunJob(context.Background(), "digest")
+	if err != nil {
+		t.Fatalf("run job: %v", err)
+	}
+	if run.ID != "run-1" || calls.Load() != 3 {
+		t.Fatalf("expected a run after 3 calls, got %+v after %d", run, calls.Load())
+	}
+}
+
+func TestErrorsCarryTheAPIMessage(t *testing.T) {
+	t.Parallel()
+
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/api/admin/users" {
+			w.WriteHeader(http.StatusConflict)
+			fmt.Fprint(w, `{"code":"conflict","message":"email is already registered","request_id":"req-1"}`)
+			return
+		}
+		if r.URL.Path == "/api/admin/jobs/digest" {
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"code":"not_found","message":"Not Found"}`)
+			return
+		}
+		w.WriteHeader(http.StatusBadGateway)
+		fmt.Fprint(w, `bad gateway`)
+	})
+
+	_, err := client.CreateUser(context.Background(), "ada@example.com", "correct horse")
+	var apiErr *Error
+	if !errors.As(err, &apiErr) || apiErr.Status != http.StatusConflict || apiErr.Message != "email is already registered" || apiErr.Code != "conflict" || apiErr.RequestID != "req-1" {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	_, err = client.RunJob(context.Background(), "digest")
+	if !errors.As(err, &apiErr) || apiErr.Status != http.StatusNotFound || apiErr.Message != "Not Found" || apiErr.Code != "not_found" {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	_, err = client.ListJobs(context.Background(), JobFilter{})
+	if !errors.As(err, &apiErr) || apiErr.Status != http.StatusBadGateway || apiErr.Message != "bad gateway" || apiErr.Code != "" {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestFollowJobsReportsEachRunOnce(t *testing.T) {
+	t.Parallel()
+
+	pages := []string{
+		`{"items":[{"id":"b","status":"failure"},{"id":"a","status":"failure"}]}`,
+		`{"items":[{"id":"c","status":"failure"},{"id":"b","status":"failure"},{"id":"a","status":"failure"}]}`,
+	}
+	var calls atomic.Int32
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("status") != "failure" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		fmt.Fprint(w, pages[min(int(calls.Add(1))-1, len(pages)-1)])
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	polls := 0
+	client.sleep = func(ctx context.Context, d time.Duration) error {
+		if polls++; polls == 2 {
+			cancel()
+		}
+		return ctx.Err()
+	}
+
+	var seen []string
+	err := client.FollowJobs(ctx, JobFilter{Status: "failure"}, time.Second, func(run JobRun) {
+		seen = append(seen, run.ID)
+	})
+	if err != nil {
+		t.Fatalf("follow: %v", err)
+	}
+	if strings.Join(seen, ",") != "a,b,c" {
+		t.Fatalf("expected each run once, oldest first, got %v", seen)
+	}
+}
@@ -0,0 +1,358 @@
+// Package backup writes full and incremental database backups along with the
+// manifests needed to restore them.
+package backup
+
+import (
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Result summarises a completed backup run.
+type Result struct {
+	Manifest    Manifest
+	Path        string
+	Destination string
+	// Exports lists the per-user exports written in user mode.
+	Exports []Manifest
+}
+
+// Runner produces backups into the configured directory and storage.
+type Runner struct {
+	cfg         Config
+	databaseURL string
+	logger      *log.Logger
+	now         func() time.Time
+}
+
+// NewRunner constructs a Runner for the given database.
+func NewRunner(cfg Config, databaseURL string, logger *log.Logger) *Runner {
+	return &Runner{cfg: cfg, databaseURL: databaseURL, logger: logger, now: time.Now}
+}
+
+// WithNow overrides the clock used for backup names.
+func (r *Runner) WithNow(now func() time.Time) {
+	if now != nil {
+		r.now = now
+	}
+}
+
+// Run writes a backup and its manifest, uploads both when S3 storage is
+// configured, and prunes expired backups. Incremental mode falls back to a
+// full backup when no previous manifest exists; user mode writes one export
+// per account.
+func (r *Runner) Run(ctx context.Context) (Result, error) {
+	if err := os.MkdirAll(r.cfg.Dir, 0o755); err != nil {
+		return Result{}, fmt.Errorf("create backup directory: %w", err)
+	}
+
+	if r.cfg.Mode == ModeIncremental && r.cfg.Storage == StorageS3 {
+		fetched, err := fetchManifestsFromS3(ctx, r.cfg)
+		if err != nil {
+			return Result{}, err
+		}
+		if fetched > 0 {
+			r.logger.Printf("fetched %d backup manifests from s3", fetched)
+		}
+	}
+
+	manifests, err := ListManifests(r.cfg.Dir)
+	if err != nil {
+		return Result{}, err
+	}
+
+	conn, err := pgx.Connect(ctx, r.databaseURL)
+	if err != nil {
+		return Result{}, fmt.Errorf("connect database: %w", err)
+	}
+	defer conn.Close(ctx)
+
+	if r.cfg.Mode == ModeUser {
+		return r.runUserExports(ctx, conn, manifests)
+	}
+
+	mode := r.cfg.Mode
+	var parent *Manifest
+	if mode == ModeIncremental {
+		parent = latestChainManifest(manifests)
+		if parent == nil {
+			r.logger.Println("no previous backup manifest found, taking a full backup")
+			mode = ModeFull
+		}
+	}
+
+	createdAt := r.now().UTC()
+	stamp := createdAt.Format("20060102-150405")
+	manifest := Manifest{Kind: mode, CreatedAt: createdAt, ExcludedTables: r.cfg.ExcludeTables}
+	var stats fileStats
+
+	if mode == ModeFull {
+		manifest.File = fmt.Sprintf("keepstack-%s%s", stamp, fullSuffix)
+		pgDump, err := r.selectDumper(ctx, conn)
+		if err != nil {
+			return Result{}, err
+		}
+		manifest.Dumper = DumperLogical
+		if pgDump != "" {
+			manifest.Dumper = DumperPGDump
+			if manifest.IncludedTables, err = dumpedTables(ctx, conn, r.cfg.ExcludeTables); err != nil {
+				return Result{}, err
+			}
+		}
+		stats, err = r.writeFile(ctx, manifest.File, func(w io.Writer) error {
+			if pgDump == "" {
+				snapshot, tables, err := writeLogical(ctx, conn, w, r.cfg.ExcludeTables)
+				manifest.SnapshotAt = snapshot
+				manifest.Tables = tables
+				return err
+			}
+			// Read the watermark before dumping so the next incremental
+			// backup overlaps rather than misses concurrent writes.
+			snapshot, err := snapshotWatermark(ctx, conn)
+			if err != nil {
+				return err
+			}
+			manifest.SnapshotAt = snapshot
+			return r.dump(ctx, pgDump, w)
+		})
+	} else {
+		since := parent.SnapshotAt
+		manifest.File = fmt.Sprintf("keepstack-%s%s", stamp, incrementalSuffix)
+		manifest.Parent = parent.Name()
+		manifest.Since = &since
+		stats, err = r.writeFile(ctx, manifest.File, func(w io.Writer) error {
+			snapshot, tables, err := writeIncremental(ctx, conn, w, since, r.cfg.ExcludeTables)
+			manifest.SnapshotAt = snapshot
+			manifest.Tables = tables
+			return err
+		})
+	}
+	if err != nil {
+		return Result{}, err
+	}
+
+	destination, err := r.store(ctx, &manifest, stats)
+	if err != nil {
+		return Result{}, err
+	}
+
+	r.prune(append(manifests, manifest))
+	if r.cfg.Storage == StorageS3 {
+		if err := r.pruneS3(ctx); err != nil {
+			r.logger.Printf("warn: %v", err)
+		}
+	}
+
+	return Result{Manifest: manifest, Path: filepath.Join(r.cfg.Dir, manifest.File), Destination: destination}, nil
+}
+
+// store records the file stats in manifest, writes it next to the backup,
+// and uploads both when S3 storage is configured. It returns where the
+// backup ended up.
+func (r *Runner) store(ctx context.Context, manifest *Manifest, stats fileStats) (string, error) {
+	path := filepath.Join(r.cfg.Dir, manifest.File)
+	manifest.SizeBytes = stats.size
+	manifest.SHA256 = stats.sha256
+
+	if err := WriteManifest(r.cfg.Dir, *manifest); err != nil {
+		return "", err
+	}
+
+	if r.cfg.Storage != StorageS3 {
+		return path, nil
+	}
+
+	if !r.cfg.S3Stream {
+		if err := uploadToS3(ctx, r.cfg, path, manifest.File, "application/gzip"); err != nil {
+			return "", err
+		}
+	}
+	if err := uploadToS3(ctx, r.cfg, filepath.Join(r.cfg.Dir, manifest.Name()), manifest.Name(), "application/json"); err != nil {
+		return "", err
+	}
+	// Manifests stay on disk so later incremental runs can find their parent.
+	if !r.cfg.KeepLocal && !r.cfg.S3Stream {
+		if err := os.Remove(path); err != nil {
+			r.logger.Printf("warn: failed to remove local backup after upload: %v", err)
+		}
+	}
+	return fmt.Sprintf("s3://%s/%s", r.cfg.S3Bucket, s3Key(r.cfg, manifest.File)), nil
+}
+
+type fileStats struct {
+	size   int64
+	sha256 string
+}
+
+type countingWriter struct {
+	n int64
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	w.n += int64(len(p))
+	return len(p), nil
+}
+
+// writeFile gzips the output of write into name and returns the size and
+// SHA-256 of the compressed stream. With BACKUP_S3_STREAM the stream goes
+// straight into a multipart S3 upload and only touches disk when
+// BACKUP_KEEP_LOCAL is also set.
+func (r *Runner) writeFile(ctx context.Context, name string, write func(io.Writer) error) (fileStats, error) {
+	streaming := r.cfg.Storage == StorageS3 && r.cfg.S3Stream
+	hash := sha256.New()
+	counter := &countingWriter{}
+	writers := []io.Writer{hash, counter}
+
+	var file *os.File
+	path := filepath.Join(r.cfg.Dir, name)
+	if !streaming || r.cfg.KeepLocal {
+		created, err := os.Create(path)
+		if err != nil {
+			return fileStats{}, fmt.Errorf("create dump file: %w", err)
+		}
+		file = created
+		writers = append(writers, file)
+	}
+
+	var upload *multipartUpload
+	if streaming {
+		client, err := newS3Client(ctx, r.cfg)
+		if err == nil {
+			upload, err = startMultipartUpload(ctx, client, r.cfg.S3Bucket, s3Key(r.cfg, name), "application/gzip", r.cfg.S3PartSize<<20)
+		}
+		if err != nil {
+			if file != nil {
+				file.Close()
+				os.Remove(path)
+			}
+			return fileStats{}, err
+		}
+		writers = append(writers, upload)
+	}
+
+	abort := func() {
+		if file != nil {
+			file.Close()
+			os.Remove(path)
+		}
+		if upload != nil {
+			upload.Abort()
+		}
+	}
+
+	gzipWriter := gzip.NewWriter(io.MultiWriter(writers...))
+	if err := write(gzipWriter); err != nil {
+		gzipWriter.Close()
+		abort()
+		return fileStats{}, err
+	}
+
+	if err := gzipWriter.Close(); err != nil {
+		abort()
+		return fileStats{}, fmt.Errorf("close gzip writer: %w", err)
+	}
+	if file != nil {
+		if err := file.Close(); err != nil {
+			if upload != nil {
+				upload.Abort()
+			}
+			os.Remove(path)
+			return fileStats{}, fmt.Errorf("close dump file: %w", err)
+		}
+	}
+	if upload != nil {
+		if err := upload.Close(); err != nil {
+			upload.Abort()
+			if file != nil {
+				os.Remove(path)
+			}
+			return fileStats{}, err
+		}
+	}
+
+	return fileStats{size: counter.n, sha256: hex.EncodeToString(hash.Sum(nil))}, nil
+}
+
+func (r *Runner) dump(ctx context.Context, pgDump string, w io.Writer) error {
+	cmd := exec.CommandContext(ctx, pgDump, pgDumpArgs(r.databaseURL, r.cfg.ExcludeTables)...)
+	cmd.Stdout = w
+	cmd.Stderr = os.Stderr
+
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("pg_dump: %w", err)
+	}
+	return nil
+}
+
+// Prune enforces retention without taking a backup: local files are pruned
+// by their manifests and, with S3 storage, remote objects under the prefix.
+// BACKUP_PRUNE_DRY_RUN logs what would be removed instead of deleting it.
+func (r *Runner) Prune(ctx context.Context) error {
+	manifests, err := ListManifests(r.cfg.Dir)
+	if err != nil {
+		return err
+	}
+	r.prune(manifests)
+
+	if r.cfg.Storage == StorageS3 {
+		return r.pruneS3(ctx)
+	}
+	return nil
+}
+
+// prune removes backups older than the newest BACKUP_RETENTION full backups
+// and all but the newest BACKUP_RETENTION exports per user. Dumps written
+// before manifests existed are pruned by the full-backup cutoff.
+func (r *Runner) prune(manifests []Manifest) {
+	expired, cutoff := expiredManifests(manifests, r.cfg.Retention)
+	for _, m := range expired {
+		r.remove(filepath.Join(r.cfg.Dir, m.File))
+		r.remove(filepath.Join(r.cfg.Dir, m.Name()))
+	}
+	if cutoff.IsZero() {
+		return
+	}
+
+	known := make(map[string]struct{}, len(manifests))
+	for _, m := range manifests {
+		known[m.File] = struct{}{}
+	}
+	files, err := filepath.Glob(filepath.Join(r.cfg.Dir, "keepstack-*"+fullSuffix))
+	if err != nil {
+		return
+	}
+	sort.Strings(files)
+	for _, path := range files {
+		if _, ok := known[filepath.Base(path)]; ok {
+			continue
+		}
+		info, err := os.Stat(path)
+		if err != nil || !info.ModTime().Before(cutoff) {
+			continue
+		}
+		r.remove(path)
+	}
+}
+
+func (r *Runner) remove(path string) {
+	if r.cfg.DryRun {
+		if _, err := os.Stat(path); err == nil {
+			r.logger.Printf("dry-run: would remove %s", path)
+		}
+		return
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		r.logger.Printf("warn: failed to remove old backup %s: %v", path, err)
+	}
+}
@@ -0,0 +1,400 @@
+package backup
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestChainResolvesFromFullBackup(t *testing.T) {
+	base := time.Date(2024, time.March, 1, 3, 0, 0, 0, time.UTC)
+	full := Manifest{Kind: ModeFull, File: "keepstack-20240301-030000.sql.gz", CreatedAt: base}
+	first := Manifest{
+		Kind:      ModeIncremental,
+		File:      "keepstack-20240302-030000.incr.sql.gz",
+		CreatedAt: base.Add(24 * time.Hour),
+		Parent:    full.Name(),
+	}
+	second := Manifest{
+		Kind:      ModeIncremental,
+		File:      "keepstack-20240303-030000.incr.sql.gz",
+		CreatedAt: base.Add(48 * time.Hour),
+		Parent:    first.Name(),
+	}
+
+	chain, err := Chain([]Manifest{full, first, second}, second)
+	if err != nil {
+		t.Fatalf("Chain returned error: %v", err)
+	}
+
+	want := []string{full.File, first.File, second.File}
+	if len(chain) != len(want) {
+		t.Fatalf("expected %d manifests, got %d", len(want), len(chain))
+	}
+	for i, m := range chain {
+		if m.File != want[i] {
+			t.Fatalf("chain[%d] = %s, want %s", i, m.File, want[i])
+		}
+	}
+
+	if _, err := Chain([]Manifest{first, second}, second); err == nil {
+		t.Fatalf("expected error when the full backup is missing")
+	}
+}
+
+func TestParsePGDumpMajor(t *testing.T) {
+	cases := map[string]int{
+		"pg_dump (PostgreSQL) 16.2\n":                    16,
+		"pg_dump (PostgreSQL) 15.6 (Debian 15.6-1.pgdg)": 15,
+		"pg_dump (PostgreSQL) 17beta1":                   17,
+	}
+	for output, want := range cases {
+		got, err := parsePGDumpMajor(output)
+		if err != nil {
+			t.Fatalf("parsePGDumpMajor(%q) returned error: %v", output, err)
+		}
+		if got != want {
+			t.Fatalf("parsePGDumpMajor(%q) = %d, want %d", output, got, want)
+		}
+	}
+
+	if _, err := parsePGDumpMajor("command not found"); err == nil {
+		t.Fatalf("expected error for unrecognised output")
+	}
+}
+
+func TestCompatiblePGDump(t *testing.T) {
+	if err := compatiblePGDump(16, 16); err != nil {
+		t.Fatalf("expected matching versions to be compatible: %v", err)
+	}
+	if err := compatiblePGDump(17, 16); err != nil {
+		t.Fatalf("expected newer pg_dump to be compatible: %v", err)
+	}
+	err := compatiblePGDump(15, 16)
+	if err == nil || !strings.Contains(err.Error(), "postgresql-client-16") {
+		t.Fatalf("expected an install hint for older pg_dump, got %v", err)
+	}
+}
+
+func TestLogicalTablesReplaceEveryTable(t *testing.T) {
+	specs := logicalTables()
+	if len(specs) != len(incrementalTables) {
+		t.Fatalf("expected %d tables, got %d", len(incrementalTables), len(specs))
+	}
+	for i, spec := range specs {
+		if spec.strategy != StrategyReplace {
+			t.Fatalf("%s: expected replace strategy, got %s", spec.name, spec.strategy)
+		}
+		if spec.name != incrementalTables[i].name {
+			t.Fatalf("expected restore order to match incremental tables")
+		}
+	}
+	if incrementalTables[2].strategy != StrategyUpsert {
+		t.Fatalf("logicalTables must not modify incrementalTables")
+	}
+}
+
+func TestExcludedTables(t *testing.T) {
+	args := pgDumpArgs("postgres://db", []string{"archives", "public.claims"})
+	want := []string{"--exclude-table=archives", "--exclude-table=public.claims", "postgres://db"}
+	if strings.Join(args, " ") != strings.Join(want, " ") {
+		t.Fatalf("pgDumpArgs = %v, want %v", args, want)
+	}
+
+	specs := withoutTables(incrementalTables, []string{"archives", "public.claims"})
+	for _, spec := range specs {
+		if spec.name == "archives" || spec.name == "claims" {
+			t.Fatalf("expected %s to be excluded", spec.name)
+		}
+	}
+	if len(specs) != len(incrementalTables)-2 {
+		t.Fatalf("expected two tables to be dropped, got %d of %d", len(specs), len(incrementalTables))
+	}
+}
+
+func TestLoadConfigValidatesExcludedTables(t *testing.T) {
+	t.Setenv("BACKUP_EXCLUDE_TABLES", " Archives , public.claims,")
+	cfg, err := LoadConfig()
+	if err != nil {
+		t.Fatalf("LoadConfig returned error: %v", err)
+	}
+	if strings.Join(cfg.ExcludeTables, ",") != "archives,public.claims" {
+		t.Fatalf("unexpected excluded tables: %v", cfg.ExcludeTables)
+	}
+
+	t.Setenv("BACKUP_EXCLUDE_TABLES", "archives; DROP TABLE links")
+	if _, err := LoadConfig(); err == nil {
+		t.Fatalf("expected invalid table names to be rejected")
+	}
+}
+
+func TestChainFilesStopOnErrorForScripts(t *testing.T) {
+	chain := []Manifest{
+		{Kind: ModeFull, File: "keepstack-20240301-030000.sql.gz", Dumper: DumperPGDump},
+		{Kind: ModeFull, File: "keepstack-20240302-030000.sql.gz", Dumper: DumperLogical},
+		{Kind: ModeIncremental, File: "keepstack-20240303-030000.incr.sql.gz"},
+	}
+	files, err := chainFiles("/backups", chain)
+	if err != nil {
+		t.Fatalf("chainFiles returned error: %v", err)
+	}
+	want := []bool{false, true, true}
+	for i, file := range files {
+		if file.script != want[i] {
+			t.Fatalf("%s: script = %t, want %t", file.path, file.script, want[i])
+		}
+	}
+}
+
+func TestManifestName(t *testing.T) {
+	cases := map[string]string{
+		"keepstack-20240301-030000.sql.gz":                                            "keepstack-20240301-030000.manifest.json",
+		"keepstack-20240302-030000.incr.sql.gz":                                       "keepstack-20240302-030000.incr.manifest.json",
+		"keepstack-user-7f1c2f8e-2f44-4c4f-9d0b-3c1f1f1f1f1f-20240301-030000.json.gz": "keepstack-user-7f1c2f8e-2f44-4c4f-9d0b-3c1f1f1f1f1f-20240301-030000.manifest.json",
+	}
+	for file, want := range cases {
+		if got := (Manifest{File: file}).Name(); got != want {
+			t.Fatalf("Name(%s) = %s, want %s", file, got, want)
+		}
+	}
+}
+
+func TestExpiredManifestsKeepsIncrementalsOfRetainedFulls(t *testing.T) {
+	base := time.Date(2024, time.March, 1, 3, 0, 0, 0, time.UTC)
+	manifests := []Manifest{
+		{Kind: ModeFull, File: "a.sql.gz", CreatedAt: base},
+		{Kind: ModeIncremental, File: "b.incr.sql.gz", CreatedAt: base.Add(time.Hour)},
+		{Kind: ModeFull, File: "c.sql.gz", CreatedAt: base.Add(2 * time.Hour)},
+		{Kind: ModeIncremental, File: "d.incr.sql.gz", CreatedAt: base.Add(3 * time.Hour)},
+		{Kind: ModeFull, File: "e.sql.gz", CreatedAt: base.Add(4 * time.Hour)},
+	}
+
+	expired, cutoff := expiredManifests(manifests, 2)
+	if !cutoff.Equal(base.Add(2 * time.Hour)) {
+		t.Fatalf("unexpected cutoff %s", cutoff)
+	}
+	if len(expired) != 2 || expired[0].File != "a.sql.gz" || expired[1].File != "b.incr.sql.gz" {
+		t.Fatalf("unexpected expired manifests: %+v", expired)
+	}
+
+	if expired, _ := expiredManifests(manifests, 3); len(expired) != 0 {
+		t.Fatalf("expected nothing to expire, got %+v", expired)
+	}
+}
+
+func TestMergeSQL(t *testing.T) {
+	upsert := mergeSQL(tableSpec{name: "archives", key: []string{"link_id"}, strategy: StrategyUpsert}, []string{"link_id", "html", "updated_at"})
+	for _, fragment := range []string{
+		"INSERT INTO archives (link_id, html, updated_at)",
+		"FROM keepstack_restore_archives",
+		"ON CONFLICT (link_id) DO UPDATE SET",
+		"html = EXCLUDED.html",
+	} {
+		if !strings.Contains(upsert, fragment) {
+			t.Fatalf("upsert SQL missing %q:\n%s", fragment, upsert)
+		}
+	}
+	if strings.Contains(upsert, "link_id = EXCLUDED.link_id") {
+		t.Fatalf("upsert SQL should not update key columns:\n%s", upsert)
+	}
+
+	joinOnly := mergeSQL(tableSpec{name: "link_tags", key: []string{"link_id", "tag_id"}, strategy: StrategyUpsert}, []string{"link_id", "tag_id"})
+	if !strings.Contains(joinOnly, "ON CONFLICT (link_id, tag_id) DO NOTHING") {
+		t.Fatalf("expected DO NOTHING for key-only tables:\n%s", joinOnly)
+	}
+
+	replace := mergeSQL(tableSpec{name: "tags", key: []string{"id"}, strategy: StrategyReplace, serial: "id"}, []string{"id", "name"})
+	if !strings.HasPrefix(replace, "DELETE FROM tags;") || !strings.Contains(replace, "setval(pg_get_serial_sequence('tags', 'id')") {
+		t.Fatalf("unexpected replace SQL:\n%s", replace)
+	}
+}
+
+func TestExportQueryFiltersChangedRows(t *testing.T) {
+	since := time.Date(2024, time.March, 1, 3, 0, 0, 0, time.UTC)
+
+	where := incrementalFilter(since)
+	links := tableSpec{name: "links", strategy: StrategyUpsert, changedAt: "updated_at"}
+	query := exportQuery(links, []string{"id", "url"}, where(links))
+	want := "COPY (SELECT id, url FROM links WHERE updated_at >= '2024-03-01T03:00:00Z'::timestamptz) TO STDOUT"
+	if query != want {
+		t.Fatalf("exportQuery = %s, want %s", query, want)
+	}
+
+	tags := tableSpec{name: "tags", strategy: StrategyReplace}
+	full := exportQuery(tags, []string{"id", "name"}, where(tags))
+	if strings.Contains(full, "WHERE") {
+		t.Fatalf("replace tables should export every row: %s", full)
+	}
+}
+
+func TestUserExportScopesRowsToAccount(t *testing.T) {
+	userID := uuid.MustParse("7f1c2f8e-2f44-4c4f-9d0b-3c1f1f1f1f1f")
+
+	var links, highlights tableSpec
+	for _, spec := range userTables {
+		switch spec.name {
+		case "links":
+			links = spec
+		case "highlights":
+			highlights = spec
+		}
+		if spec.filter == "" {
+			t.Fatalf("user table %s exports every row", spec.name)
+		}
+	}
+
+	query := exportQuery(links, []string{"id", "user_id"}, userFilter(links, userID))
+	if query != "COPY (SELECT id, user_id FROM links WHERE user_id = '7f1c2f8e-2f44-4c4f-9d0b-3c1f1f1f1f1f') TO STDOUT" {
+		t.Fatalf("unexpected links export: %s", query)
+	}
+
+	merge := mergeSQL(highlights, []string{"id", "link_id", "quote"})
+	if !strings.HasPrefix(merge, "DELETE FROM highlights WHERE link_id IN (SELECT id FROM keepstack_restore_links);") {
+		t.Fatalf("highlights replace should be scoped to exported links:\n%s", merge)
+	}
+
+	insert := mergeSQL(tableSpec{name: "claims", strategy: StrategyInsert}, []string{"id", "link_id"})
+	if !strings.HasSuffix(insert, "ON CONFLICT DO NOTHING;\n") {
+		t.Fatalf("unexpected insert SQL:\n%s", insert)
+	}
+}
+
+func TestExpiredManifestsKeepsNewestUserExports(t *testing.T) {
+	base := time.Date(2024, time.March, 1, 3, 0, 0, 0, time.UTC)
+	manifests := []Manifest{
+		{Kind: ModeUser, UserID: "a", File: "a1.sql.gz", CreatedAt: base},
+		{Kind: ModeUser, UserID: "b", File: "b1.sql.gz", CreatedAt: base},
+		{Kind: ModeUser, UserID: "a", File: "a2.sql.gz", CreatedAt: base.Add(time.Hour)},
+		{Kind: ModeUser, UserID: "a", File: "a3.sql.gz", CreatedAt: base.Add(2 * time.Hour)},
+		{Kind: ModeFull, File: "full.sql.gz", CreatedAt: base.Add(3 * time.Hour)},
+	}
+
+	expired, cutoff := expiredManifests(manifests, 2)
+	if !cutoff.IsZero() {
+		t.Fatalf("no full backup should expire, got cutoff %s", cutoff)
+	}
+	if len(expired) != 1 || expired[0].File != "a1.sql.gz" {
+		t.Fatalf("unexpected expired manifests: %+v", expired)
+	}
+
+	if latest := latestChainManifest(manifests); latest == nil || latest.File != "full.sql.gz" {
+		t.Fatalf("incremental parent should ignore user exports, got %+v", latest)
+	}
+	if chain, err := Chain(manifests, manifests[0]); err != nil || len(chain) != 1 {
+		t.Fatalf("user exports should restore on their own, got %v, %v", chain, err)
+	}
+}
+
+func TestExpiredObjectKeys(t *testing.T) {
+	keys := []string{
+		"nightly/keepstack-20240301-030000.sql.gz",
+		"nightly/keepstack-20240301-030000.manifest.json",
+		"nightly/keepstack-20240302-030000.incr.sql.gz",
+		"nightly/keepstack-20240302-030000.incr.manifest.json",
+		"nightly/keepstack-20240303-030000.sql.gz",
+		"nightly/keepstack-20240304-030000.incr.sql.gz",
+		"nightly/keepstack-20240305-030000.sql.gz",
+		"nightly/keepstack-notes.txt",
+		"nightly/keepstack-user-7f1c2f8e-2f44-4c4f-9d0b-3c1f1f1f1f1f-20240301-030000.json.gz",
+		"nightly/keepstack-user-7f1c2f8e-2f44-4c4f-9d0b-3c1f1f1f1f1f-20240302-030000.json.gz",
+		"nightly/keepstack-user-7f1c2f8e-2f44-4c4f-9d0b-3c1f1f1f1f1f-20240303-030000.json.gz",
+	}
+
+	expired := expiredObjectKeys(keys, 2)
+	want := []string{
+		"nightly/keepstack-20240301-030000.manifest.json",
+		"nightly/keepstack-20240301-030000.sql.gz",
+		"nightly/keepstack-20240302-030000.incr.manifest.json",
+		"nightly/keepstack-20240302-030000.incr.sql.gz",
+		"nightly/keepstack-user-7f1c2f8e-2f44-4c4f-9d0b-3c1f1f1f1f1f-20240301-030000.json.gz",
+	}
+	if strings.Join(expired, ",") != strings.Join(want, ",") {
+		t.Fatalf("expiredObjectKeys = %v, want %v", expired, want)
+	}
+
+	if expired := expiredObjectKeys(keys, 0); len(expired) != 0 {
+		t.Fatalf("retention 0 should keep everything, got %v", expired)
+	}
+}
+
+func TestLifecycleHint(t *testing.T) {
+	hint := lifecycleHint(Config{S3Prefix: "nightly", TransitionDays: 30, TransitionClass: "GLACIER"})
+	for _, fragment := range []string{`"Prefix":"nightly/keepstack-"`, `"Days":30`, `"StorageClass":"GLACIER"`} {
+		if !strings.Contains(hint, fragment) {
+			t.Fatalf("lifecycle hint missing %s: %s", fragment, hint)
+		}
+	}
+}
+
+func TestNotifierPostsWebhookReport(t *testing.T) {
+	var received Report
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := json.NewDecoder(r.Body).Decode(&received); err != nil {
+			t.Errorf("decode payload: %v", err)
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	notifier, err := NewNotifier(Config{NotifyURL: server.URL, NotifyOn: NotifyAlways})
+	if err != nil {
+		t.Fatalf("NewNotifier returned error: %v", err)
+	}
+
+	report := Report{
+		Status:      StatusSuccess,
+		Kind:        ModeFull,
+		File:        "keepstack-20240301-030000.sql.gz",
+		Destination: "s3://backups/keepstack-20240301-030000.sql.gz",
+		SizeBytes:   2048,
+		Duration:    90 * time.Second,
+	}
+	if err := notifier.Notify(context.Background(), report); err != nil {
+		t.Fatalf("Notify returned error: %v", err)
+	}
+
+	if received.Status != StatusSuccess || received.Destination != report.Destination || received.DurationSec != 90 {
+		t.Fatalf("unexpected webhook payload: %+v", received)
+	}
+}
+
+func TestNotifierFailureOnlySkipsSuccess(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+	}))
+	defer server.Close()
+
+	notifier, err := NewNotifier(Config{NotifyURL: server.URL, NotifyOn: NotifyFailure})
+	if err != nil {
+		t.Fatalf("NewNotifier returned error: %v", err)
+	}
+
+	if err := notifier.Notify(context.Background(), Report{Status: StatusSuccess}); err != nil {
+		t.Fatalf("Notify returned error: %v", err)
+	}
+	if err := notifier.Notify(context.Background(), Report{Status: StatusFailure, Error: "pg_dump: exit status 1"}); err != nil {
+		t.Fatalf("Notify returned error: %v", err)
+	}
+	if calls != 1 {
+		t.Fatalf("expected only the failure to be sent, got %d calls", calls)
+	}
+}
+
+func TestReportSummary(t *testing.T) {
+	success := Report{Status: StatusSuccess, Kind: ModeIncremental, File: "a.incr.sql.gz", Destination: "/backups/a.incr.sql.gz", SizeBytes: 3 * 1024 * 1024, Duration: 2 * time.Second}
+	if got := success.Summary(); !strings.Contains(got, "incremental backup succeeded in 2s") || !strings.Contains(got, "3.0 MiB") {
+		t.Fatalf("unexpected success summary: %s", got)
+	}
+
+	failure := Report{Status: StatusFailure, Error: "connect database: refused", Duration: time.Second}
+	if got := failure.Summary(); got != "Keepstack backup failed after 1s: connect database: refused" {
+		t.Fatalf("unexpected failure summary: %s", got)
+	}
+}
@@ -0,0 +1,142 @@
+package backup
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/kelseyhightower/envconfig"
+)
+
+const (
+	// ModeFull writes a complete snapshot of the database (see Dumper).
+	ModeFull = "full"
+	// ModeIncremental writes only rows changed since the previous backup.
+	ModeIncremental = "incremental"
+	// ModeUser writes a logical export per account.
+	ModeUser = "user"
+
+	// DumperAuto uses pg_dump when a compatible binary is available and
+	// falls back to the built-in logical export otherwise.
+	DumperAuto = "auto"
+	// DumperPGDump requires pg_dump and fails the backup without it.
+	DumperPGDump = "pg_dump"
+	// DumperLogical exports the core tables over the database connection
+	// without any client binaries.
+	DumperLogical = "logical"
+
+	// StoragePVC keeps backups on the mounted volume only.
+	StoragePVC = "pvc"
+	// StorageS3 uploads backups to an S3-compatible bucket.
+	StorageS3 = "s3"
+)
+
+// tableNamePattern accepts plain or schema-qualified table names so excluded
+// tables can be passed to pg_dump and interpolated into export queries.
+var tableNamePattern = regexp.MustCompile(`^([a-z_][a-z0-9_]*\.)?[a-z_][a-z0-9_]*$`)
+
+// Config captures runtime configuration for the backup subcommand.
+type Config struct {
+	Dir       string `envconfig:"BACKUP_DIR" default:"/backups"`
+	Retention int    `envconfig:"BACKUP_RETENTION" default:"7"`
+	Storage   string `envconfig:"BACKUP_STORAGE" default:"pvc"`
+	KeepLocal bool   `envconfig:"BACKUP_KEEP_LOCAL" default:"false"`
+	Mode      string `envconfig:"BACKUP_MODE" default:"full"`
+	DryRun    bool   `envconfig:"BACKUP_PRUNE_DRY_RUN" default:"false"`
+
+	Dumper        string   `envconfig:"BACKUP_DUMPER" default:"auto"`
+	PGDumpPath    string   `envconfig:"BACKUP_PG_DUMP_PATH"`
+	ExcludeTables []string `envconfig:"BACKUP_EXCLUDE_TABLES"`
+
+	UserID     string `envconfig:"BACKUP_USER_ID"`
+	UserFormat string `envconfig:"BACKUP_USER_FORMAT" default:"sql"`
+
+	S3Bucket    string `envconfig:"BACKUP_S3_BUCKET"`
+	S3AccessKey string `envconfig:"BACKUP_S3_ACCESS_KEY"`
+	S3SecretKey string `envconfig:"BACKUP_S3_SECRET_KEY"`
+	S3Region    string `envconfig:"BACKUP_S3_REGION" default:"us-east-1"`
+	S3Endpoint  string `envconfig:"BACKUP_S3_ENDPOINT"`
+	S3Prefix    string `envconfig:"BACKUP_S3_PREFIX"`
+	S3Stream    bool   `envconfig:"BACKUP_S3_STREAM" default:"false"`
+	S3PartSize  int    `envconfig:"BACKUP_S3_PART_SIZE_MB" default:"16"`
+
+	TransitionDays  int    `envconfig:"BACKUP_S3_TRANSITION_DAYS" default:"0"`
+	TransitionClass string `envconfig:"BACKUP_S3_TRANSITION_CLASS" default:"GLACIER"`
+
+	NotifyURL       string `envconfig:"BACKUP_NOTIFY_URL"`
+	NotifyOn        string `envconfig:"BACKUP_NOTIFY_ON" default:"always"`
+	NotifySender    string `envconfig:"BACKUP_NOTIFY_SENDER"`
+	NotifyRecipient string `envconfig:"BACKUP_NOTIFY_RECIPIENT"`
+}
+
+// LoadConfig reads backup configuration from the environment.
+func LoadConfig() (Config, error) {
+	var cfg Config
+	if err := envconfig.Process("", &cfg); err != nil {
+		return Config{}, fmt.Errorf("load backup config: %w", err)
+	}
+
+	cfg.Storage = strings.ToLower(strings.TrimSpace(cfg.Storage))
+	cfg.Mode = strings.ToLower(strings.TrimSpace(cfg.Mode))
+	cfg.S3Prefix = strings.Trim(cfg.S3Prefix, "/")
+	if cfg.Retention < 0 {
+		cfg.Retention = 0
+	}
+	if cfg.TransitionDays < 0 {
+		cfg.TransitionDays = 0
+	}
+	cfg.TransitionClass = strings.ToUpper(strings.TrimSpace(cfg.TransitionClass))
+
+	switch cfg.Mode {
+	case ModeFull, ModeIncremental, ModeUser:
+	default:
+		return Config{}, fmt.Errorf("unsupported BACKUP_MODE %q", cfg.Mode)
+	}
+
+	cfg.Dumper = strings.ToLower(strings.TrimSpace(cfg.Dumper))
+	switch cfg.Dumper {
+	case DumperAuto, DumperPGDump, DumperLogical:
+	default:
+		return Config{}, fmt.Errorf("unsupported BACKUP_DUMPER %q", cfg.Dumper)
+	}
+	cfg.PGDumpPath = strings.TrimSpace(cfg.PGDumpPath)
+
+	excluded := make([]string, 0, len(cfg.ExcludeTables))
+	for _, table := range cfg.ExcludeTables {
+		table = strings.ToLower(strings.TrimSpace(table))
+		if table == "" {
+			continue
+		}
+		if !tableNamePattern.MatchString(table) {
+			return Config{}, fmt.Errorf("invalid table %q in BACKUP_EXCLUDE_TABLES", table)
+		}
+		excluded = append(excluded, table)
+	}
+	cfg.ExcludeTables = excluded
+
+	cfg.UserID = strings.TrimSpace(cfg.UserID)
+	cfg.UserFormat = strings.ToLower(strings.TrimSpace(cfg.UserFormat))
+	switch cfg.UserFormat {
+	case FormatSQL, FormatJSON:
+	default:
+		return Config{}, fmt.Errorf("unsupported BACKUP_USER_FORMAT %q", cfg.UserFormat)
+	}
+
+	cfg.NotifyURL = strings.TrimSpace(cfg.NotifyURL)
+	cfg.NotifyOn = strings.ToLower(strings.TrimSpace(cfg.NotifyOn))
+	switch cfg.NotifyOn {
+	case NotifyAlways, NotifyFailure:
+	default:
+		return Config{}, fmt.Errorf("unsupported BACKUP_NOTIFY_ON %q", cfg.NotifyOn)
+	}
+	// Email notifications default to the digest addresses.
+	if cfg.NotifySender == "" {
+		cfg.NotifySender = os.Getenv("DIGEST_SENDER")
+	}
+	if cfg.NotifyRecipient == "" {
+		cfg.NotifyRecipient = os.Getenv("DIGEST_RECIPIENT")
+	}
+
+	return cfg, nil
+}
@@ -0,0 +1,255 @@
+package backup
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+const (
+	// StrategyUpsert copies rows and merges them on the table key.
+	StrategyUpsert = "upsert"
+	// StrategyReplace deletes the rows in scope (the whole table when no
+	// scope is set) before inserting the exported rows, so deletions carry
+	// over.
+	StrategyReplace = "replace"
+	// StrategyInsert adds exported rows and skips any that already exist.
+	StrategyInsert = "insert"
+)
+
+// tableSpec describes how a table is captured in a logical backup script.
+type tableSpec struct {
+	name      string
+	key       []string
+	strategy  string
+	changedAt string
+	serial    string
+	// filter restricts exported rows; %s is replaced with the user id.
+	filter string
+	// scope restricts the rows StrategyReplace deletes before inserting.
+	scope string
+}
+
+// incrementalTables lists the tables in restore order so foreign keys resolve
+// as rows are merged. Deleted links and highlights are not captured; restore
+// from a full backup to drop them.
+var incrementalTables = []tableSpec{
+	{name: "users", key: []string{"id"}, strategy: StrategyUpsert},
+	{name: "tags", key: []string{"id"}, strategy: StrategyReplace, serial: "id"},
+	{name: "links", key: []string{"id"}, strategy: StrategyUpsert, changedAt: "updated_at"},
+	{name: "archives", key: []string{"link_id"}, strategy: StrategyUpsert, changedAt: "updated_at"},
+	{name: "link_tags", key: []string{"link_id", "tag_id"}, strategy: StrategyReplace},
+	{name: "link_media", key: []string{"link_id", "hash"}, strategy: StrategyReplace},
+	{name: "highlights", key: []string{"id"}, strategy: StrategyUpsert, changedAt: "updated_at"},
+	{name: "claims", key: []string{"id"}, strategy: StrategyReplace},
+	{name: "recommendations", key: []string{"link_id"}, strategy: StrategyReplace},
+	{name: "resurfacer_weights", key: []string{"user_id"}, strategy: StrategyUpsert, changedAt: "updated_at"},
+}
+
+// writeIncremental streams a psql script containing the rows changed since
+// the given time. The snapshot timestamp is returned for the next backup in
+// the chain.
+func writeIncremental(ctx context.Context, conn *pgx.Conn, w io.Writer, since time.Time, exclude []string) (time.Time, []TableManifest, error) {
+	header := func(snapshot time.Time) string {
+		return fmt.Sprintf("-- keepstack incremental backup\n-- since %s\n-- snapshot %s\n",
+			since.UTC().Format(time.RFC3339Nano), snapshot.UTC().Format(time.RFC3339Nano))
+	}
+	return writeTableScript(ctx, conn, w, withoutTables(incrementalTables, exclude), header, incrementalFilter(since))
+}
+
+// withoutTables drops excluded tables, matching either the bare or the
+// schema-qualified name.
+func withoutTables(specs []tableSpec, exclude []string) []tableSpec {
+	kept := make([]tableSpec, 0, len(specs))
+	for _, spec := range specs {
+		if !tableExcluded(spec.name, exclude) {
+			kept = append(kept, spec)
+		}
+	}
+	return kept
+}
+
+func tableExcluded(name string, exclude []string) bool {
+	for _, table := range exclude {
+		if table == name || strings.HasSuffix(table, "."+name) {
+			return true
+		}
+	}
+	return false
+}
+
+// incrementalFilter limits upserted tables to rows changed at or after since;
+// replaced tables are exported whole. Since is the parent backup's watermark,
+// which can predate rows the parent already holds, so the upsert merges them
+// again rather than duplicating them.
+func incrementalFilter(since time.Time) func(tableSpec) string {
+	return func(spec tableSpec) string {
+		if spec.strategy != StrategyUpsert || spec.changedAt == "" {
+			return ""
+		}
+		return fmt.Sprintf("%s >= '%s'::timestamptz", spec.changedAt, since.UTC().Format(time.RFC3339Nano))
+	}
+}
+
+// writeTableScript streams a psql script that stages each table with COPY and
+// merges it according to its strategy. The export runs in a single
+// repeatable-read transaction so every table reflects the same snapshot.
+func writeTableScript(ctx context.Context, conn *pgx.Conn, w io.Writer, specs []tableSpec, header func(time.Time) string, where func(tableSpec) string) (time.Time, []TableManifest, error) {
+	tx, snapshot, err := beginSnapshot(ctx, conn)
+	if err != nil {
+		return time.Time{}, nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := io.WriteString(w, header(snapshot)+"\nBEGIN;\n\n"); err != nil {
+		return time.Time{}, nil, err
+	}
+
+	tables := make([]TableManifest, 0, len(specs))
+	for _, spec := range specs {
+		columns, err := tableColumns(ctx, tx, spec.name)
+		if err != nil {
+			return time.Time{}, nil, err
+		}
+
+		if _, err := io.WriteString(w, stagingSQL(spec, columns)); err != nil {
+			return time.Time{}, nil, err
+		}
+
+		tag, err := tx.Conn().PgConn().CopyTo(ctx, w, exportQuery(spec, columns, where(spec)))
+		if err != nil {
+			return time.Time{}, nil, fmt.Errorf("export %s: %w", spec.name, err)
+		}
+
+		if _, err := io.WriteString(w, "\\.\n"+mergeSQL(spec, columns)+"\n"); err != nil {
+			return time.Time{}, nil, err
+		}
+
+		tables = append(tables, TableManifest{Name: spec.name, Strategy: spec.strategy, Rows: tag.RowsAffected()})
+	}
+
+	if _, err := io.WriteString(w, "COMMIT;\n"); err != nil {
+		return time.Time{}, nil, err
+	}
+
+	return snapshot, tables, nil
+}
+
+// beginSnapshot opens the read-only transaction an export reads from and
+// returns it with the snapshot's watermark.
+func beginSnapshot(ctx context.Context, conn *pgx.Conn) (pgx.Tx, time.Time, error) {
+	// Read the watermark before the snapshot exists: a transaction running
+	// when the snapshot is taken either shows up here or starts afterwards.
+	snapshot, err := snapshotWatermark(ctx, conn)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+
+	tx, err := conn.BeginTx(ctx, pgx.TxOptions{IsoLevel: pgx.RepeatableRead, AccessMode: pgx.ReadOnly})
+	if err != nil {
+		return nil, time.Time{}, fmt.Errorf("begin snapshot: %w", err)
+	}
+	return tx, snapshot, nil
+}
+
+// watermarkSQL reads the start of the oldest transaction still running, or
+// the current time when there is none. Rows get updated_at from now(), the
+// start of the transaction writing them, so a transaction that commits after
+// a snapshot was taken stamps its rows no earlier than this.
+const watermarkSQL = `SELECT LEAST(now(), COALESCE(MIN(xact_start), now()))
+FROM pg_stat_activity
+WHERE datname = current_database()
+  AND pid <> pg_backend_pid()
+  AND xact_start IS NOT NULL`
+
+// snapshotWatermark returns the time a following incremental backup must
+// export changes from so it also picks up writes still uncommitted when this
+// backup's snapshot was taken. It trails the snapshot by the longest
+// transaction open at the time.
+func snapshotWatermark(ctx context.Context, conn *pgx.Conn) (time.Time, error) {
+	var watermark time.Time
+	if err := conn.QueryRow(ctx, watermarkSQL).Scan(&watermark); err != nil {
+		return time.Time{}, fmt.Errorf("read snapshot time: %w", err)
+	}
+	return watermark, nil
+}
+
+func tableColumns(ctx context.Context, tx pgx.Tx, table string) ([]string, error) {
+	rows, err := tx.Query(ctx, `
+SELECT column_name
+FROM information_schema.columns
+WHERE table_schema = current_schema() AND table_name = $1
+ORDER BY ordinal_position`, table)
+	if err != nil {
+		return nil, fmt.Errorf("list %s columns: %w", table, err)
+	}
+	columns, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return nil, fmt.Errorf("list %s columns: %w", table, err)
+	}
+	if len(columns) == 0 {
+		return nil, fmt.Errorf("table %s not found", table)
+	}
+	return columns, nil
+}
+
+func stagingTable(spec tableSpec) string {
+	return "keepstack_restore_" + spec.name
+}
+
+func stagingSQL(spec tableSpec, columns []string) string {
+	staging := stagingTable(spec)
+	return fmt.Sprintf("CREATE TEMP TABLE %s (LIKE %s) ON COMMIT DROP;\nCOPY %s (%s) FROM stdin;\n",
+		staging, spec.name, staging, strings.Join(columns, ", "))
+}
+
+func exportQuery(spec tableSpec, columns []string, where string) string {
+	query := fmt.Sprintf("SELECT %s FROM %s", strings.Join(columns, ", "), spec.name)
+	if where != "" {
+		query += " WHERE " + where
+	}
+	return fmt.Sprintf("COPY (%s) TO STDOUT", query)
+}
+
+func mergeSQL(spec tableSpec, columns []string) string {
+	list := strings.Join(columns, ", ")
+	insert := fmt.Sprintf("INSERT INTO %s (%s)\nSELECT %s FROM %s", spec.name, list, list, stagingTable(spec))
+
+	switch spec.strategy {
+	case StrategyReplace:
+		remove := fmt.Sprintf("DELETE FROM %s", spec.name)
+		if spec.scope != "" {
+			remove += " WHERE " + spec.scope
+		}
+		merge := fmt.Sprintf("%s;\n%s;\n", remove, insert)
+		if spec.serial != "" {
+			merge += fmt.Sprintf("SELECT setval(pg_get_serial_sequence('%s', '%s'), COALESCE(MAX(%s), 1)) FROM %s;\n",
+				spec.name, spec.serial, spec.serial, spec.name)
+		}
+		return merge
+	case StrategyInsert:
+		return fmt.Sprintf("%s\nON CONFLICT DO NOTHING;\n", insert)
+	}
+
+	keys := make(map[string]struct{}, len(spec.key))
+	for _, key := range spec.key {
+		keys[key] = struct{}{}
+	}
+	updates := make([]string, 0, len(columns))
+	for _, column := range columns {
+		if _, ok := keys[column]; ok {
+			continue
+		}
+		updates = append(updates, fmt.Sprintf("%s = EXCLUDED.%s", column, column))
+	}
+
+	conflict := fmt.Sprintf("ON CONFLICT (%s) DO NOTHING", strings.Join(spec.key, ", "))
+	if len(updates) > 0 {
+		conflict = fmt.Sprintf("ON CONFLICT (%s) DO UPDATE SET\n    %s", strings.Join(spec.key, ", "), strings.Join(updates, ",\n    "))
+	}
+	return fmt.Sprintf("%s\n%s;\n", insert, conflict)
+}
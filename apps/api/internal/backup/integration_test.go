@@ -0,0 +1,68 @@
+//go:build integration
+
+package backup
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/example/keepstack/testenv"
+)
+
+// TestIntegrationIncrementalKeepsInFlightWrites saves a link in a transaction
+// that starts before one incremental backup and commits after it. The link
+// is invisible to that backup's snapshot, but its updated_at predates the
+// snapshot, so the next backup must still export it.
+func TestIntegrationIncrementalKeepsInFlightWrites(t *testing.T) {
+	databaseURL := testenv.Postgres(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+
+	backupConn, err := pgx.Connect(ctx, databaseURL)
+	if err != nil {
+		t.Fatalf("connect postgres: %v", err)
+	}
+	defer backupConn.Close(ctx)
+	writerConn, err := pgx.Connect(ctx, databaseURL)
+	if err != nil {
+		t.Fatalf("connect postgres: %v", err)
+	}
+	defer writerConn.Close(ctx)
+
+	const url = "https://example.com/in-flight"
+	writer, err := writerConn.Begin(ctx)
+	if err != nil {
+		t.Fatalf("begin writer: %v", err)
+	}
+	defer writer.Rollback(ctx)
+	if _, err := writer.Exec(ctx, `INSERT INTO links (user_id, url) VALUES ('00000000-0000-0000-0000-000000000001', $1)`, url); err != nil {
+		t.Fatalf("insert link: %v", err)
+	}
+
+	var first bytes.Buffer
+	watermark, _, err := writeIncremental(ctx, backupConn, &first, time.Now().Add(-time.Hour), nil)
+	if err != nil {
+		t.Fatalf("first incremental: %v", err)
+	}
+	if strings.Contains(first.String(), url) {
+		t.Fatalf("first incremental exported an uncommitted link")
+	}
+
+	if err := writer.Commit(ctx); err != nil {
+		t.Fatalf("commit writer: %v", err)
+	}
+
+	var second bytes.Buffer
+	if _, _, err := writeIncremental(ctx, backupConn, &second, watermark, nil); err != nil {
+		t.Fatalf("second incremental: %v", err)
+	}
+	if !strings.Contains(second.String(), url) {
+		t.Fatalf("second incremental since %s missed the link committed after the first snapshot", watermark)
+	}
+}
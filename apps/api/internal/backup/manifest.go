@@ -0,0 +1,191 @@
+package backup
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	manifestSuffix    = ".manifest.json"
+	fullSuffix        = ".sql.gz"
+	incrementalSuffix = ".incr.sql.gz"
+	jsonSuffix        = ".json.gz"
+)
+
+// Manifest describes a single backup file and, for incremental backups, the
+// backup it builds on. Restores replay a chain of manifests from the most
+// recent full backup forward.
+type Manifest struct {
+	Kind       string          `json:"kind"`
+	File       string          `json:"file"`
+	CreatedAt  time.Time       `json:"created_at"`
+	SnapshotAt time.Time       `json:"snapshot_at"`
+	Since      *time.Time      `json:"since,omitempty"`
+	Dumper     string          `json:"dumper,omitempty"`
+	Parent     string          `json:"parent,omitempty"`
+	UserID     string          `json:"user_id,omitempty"`
+	Format     string          `json:"format,omitempty"`
+	SizeBytes  int64           `json:"size_bytes"`
+	SHA256     string          `json:"sha256,omitempty"`
+	Tables     []TableManifest `json:"tables,omitempty"`
+	// IncludedTables lists the tables a pg_dump backup contains; logical
+	// backups list theirs in Tables.
+	IncludedTables []string `json:"included_tables,omitempty"`
+	ExcludedTables []string `json:"excluded_tables,omitempty"`
+}
+
+// TableManifest records how a table was captured in a logical backup script.
+type TableManifest struct {
+	Name     string `json:"name"`
+	Strategy string `json:"strategy"`
+	Rows     int64  `json:"rows"`
+}
+
+// Name returns the manifest file name for m.
+func (m Manifest) Name() string {
+	return manifestName(m.File)
+}
+
+func manifestName(file string) string {
+	stem := strings.TrimSuffix(strings.TrimSuffix(file, fullSuffix), jsonSuffix)
+	return stem + manifestSuffix
+}
+
+// WriteManifest stores m next to its backup file in dir.
+func WriteManifest(dir string, m Manifest) error {
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encode manifest: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, m.Name()), data, 0o644); err != nil {
+		return fmt.Errorf("write manifest: %w", err)
+	}
+	return nil
+}
+
+// ReadManifest loads a manifest from path.
+func ReadManifest(path string) (Manifest, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Manifest{}, fmt.Errorf("read manifest: %w", err)
+	}
+	var m Manifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return Manifest{}, fmt.Errorf("decode manifest %s: %w", path, err)
+	}
+	return m, nil
+}
+
+// ListManifests returns every manifest in dir ordered oldest first.
+func ListManifests(dir string) ([]Manifest, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "keepstack-*"+manifestSuffix))
+	if err != nil {
+		return nil, err
+	}
+
+	manifests := make([]Manifest, 0, len(paths))
+	for _, path := range paths {
+		m, err := ReadManifest(path)
+		if err != nil {
+			return nil, err
+		}
+		manifests = append(manifests, m)
+	}
+
+	sort.Slice(manifests, func(i, j int) bool {
+		return manifests[i].CreatedAt.Before(manifests[j].CreatedAt)
+	})
+	return manifests, nil
+}
+
+// Chain resolves the manifests needed to restore target, starting with the
+// full backup it descends from. Full backups and user exports stand alone.
+func Chain(manifests []Manifest, target Manifest) ([]Manifest, error) {
+	byName := make(map[string]Manifest, len(manifests))
+	for _, m := range manifests {
+		byName[m.Name()] = m
+	}
+
+	chain := []Manifest{target}
+	current := target
+	for current.Kind == ModeIncremental {
+		if current.Parent == "" {
+			return nil, fmt.Errorf("incremental backup %s has no parent", current.File)
+		}
+		parent, ok := byName[current.Parent]
+		if !ok {
+			return nil, fmt.Errorf("backup %s depends on missing manifest %s", current.File, current.Parent)
+		}
+		if len(chain) > len(manifests) {
+			return nil, errors.New("backup manifests form a cycle")
+		}
+		chain = append(chain, parent)
+		current = parent
+	}
+
+	for i, j := 0, len(chain)-1; i < j; i, j = i+1, j-1 {
+		chain[i], chain[j] = chain[j], chain[i]
+	}
+	return chain, nil
+}
+
+// latestChainManifest returns the newest full or incremental manifest, which
+// the next incremental backup builds on.
+func latestChainManifest(manifests []Manifest) *Manifest {
+	for i := len(manifests) - 1; i >= 0; i-- {
+		if manifests[i].Kind != ModeUser {
+			latest := manifests[i]
+			return &latest
+		}
+	}
+	return nil
+}
+
+// expiredManifests returns the manifests that fall outside retention along
+// with the full-backup cutoff time. Incremental backups are kept as long as
+// the full backup they build on is retained, and each user keeps their
+// newest retention exports. The cutoff is zero when no full backup expired.
+func expiredManifests(manifests []Manifest, retention int) ([]Manifest, time.Time) {
+	if retention <= 0 {
+		return nil, time.Time{}
+	}
+
+	var fulls []Manifest
+	perUser := make(map[string]int)
+	for _, m := range manifests {
+		switch m.Kind {
+		case ModeFull:
+			fulls = append(fulls, m)
+		case ModeUser:
+			perUser[m.UserID]++
+		}
+	}
+
+	var cutoff time.Time
+	if len(fulls) > retention {
+		cutoff = fulls[len(fulls)-retention].CreatedAt
+	}
+
+	var expired []Manifest
+	for _, m := range manifests {
+		if m.Kind == ModeUser {
+			// Manifests are ordered oldest first, so the first exports seen
+			// for a user are the ones beyond retention.
+			if perUser[m.UserID] > retention {
+				expired = append(expired, m)
+				perUser[m.UserID]--
+			}
+			continue
+		}
+		if !cutoff.IsZero() && m.CreatedAt.Before(cutoff) {
+			expired = append(expired, m)
+		}
+	}
+	return expired, cutoff
+}
@@ -0,0 +1,137 @@
+package backup
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+const (
+	// minPartSize is the smallest part S3 accepts other than the last one.
+	minPartSize = 5 << 20
+	// maxParts is the most parts a single multipart upload may contain.
+	maxParts = 10000
+)
+
+// multipartClient is the subset of the S3 API used for streaming uploads.
+type multipartClient interface {
+	CreateMultipartUpload(context.Context, *s3.CreateMultipartUploadInput, ...func(*s3.Options)) (*s3.CreateMultipartUploadOutput, error)
+	UploadPart(context.Context, *s3.UploadPartInput, ...func(*s3.Options)) (*s3.UploadPartOutput, error)
+	CompleteMultipartUpload(context.Context, *s3.CompleteMultipartUploadInput, ...func(*s3.Options)) (*s3.CompleteMultipartUploadOutput, error)
+	AbortMultipartUpload(context.Context, *s3.AbortMultipartUploadInput, ...func(*s3.Options)) (*s3.AbortMultipartUploadOutput, error)
+}
+
+// multipartUpload is an io.Writer that streams into an S3 multipart upload,
+// buffering at most one part in memory.
+type multipartUpload struct {
+	ctx      context.Context
+	client   multipartClient
+	bucket   string
+	key      string
+	uploadID *string
+	partSize int
+	buf      []byte
+	parts    []types.CompletedPart
+}
+
+func startMultipartUpload(ctx context.Context, client multipartClient, bucket, key, contentType string, partSize int) (*multipartUpload, error) {
+	if partSize < minPartSize {
+		partSize = minPartSize
+	}
+
+	out, err := client.CreateMultipartUpload(ctx, &s3.CreateMultipartUploadInput{
+		Bucket:      aws.String(bucket),
+		Key:         aws.String(key),
+		ContentType: aws.String(contentType),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("start multipart upload: %w", err)
+	}
+
+	return &multipartUpload{
+		ctx:      ctx,
+		client:   client,
+		bucket:   bucket,
+		key:      key,
+		uploadID: out.UploadId,
+		partSize: partSize,
+		buf:      make([]byte, 0, partSize),
+	}, nil
+}
+
+func (u *multipartUpload) Write(p []byte) (int, error) {
+	written := 0
+	for len(p) > 0 {
+		n := min(u.partSize-len(u.buf), len(p))
+		u.buf = append(u.buf, p[:n]...)
+		p = p[n:]
+		written += n
+
+		if len(u.buf) == u.partSize {
+			if err := u.flush(); err != nil {
+				return written, err
+			}
+		}
+	}
+	return written, nil
+}
+
+func (u *multipartUpload) flush() error {
+	if len(u.parts) >= maxParts {
+		return fmt.Errorf("multipart upload exceeds %d parts; raise BACKUP_S3_PART_SIZE_MB", maxParts)
+	}
+
+	number := int32(len(u.parts) + 1)
+	out, err := u.client.UploadPart(u.ctx, &s3.UploadPartInput{
+		Bucket:     aws.String(u.bucket),
+		Key:        aws.String(u.key),
+		UploadId:   u.uploadID,
+		PartNumber: aws.Int32(number),
+		Body:       bytes.NewReader(u.buf),
+	})
+	if err != nil {
+		return fmt.Errorf("upload part %d: %w", number, err)
+	}
+
+	u.parts = append(u.parts, types.CompletedPart{ETag: out.ETag, PartNumber: aws.Int32(number)})
+	u.buf = u.buf[:0]
+	return nil
+}
+
+// Close uploads the final part and completes the upload.
+func (u *multipartUpload) Close() error {
+	if len(u.buf) > 0 || len(u.parts) == 0 {
+		if err := u.flush(); err != nil {
+			return err
+		}
+	}
+
+	_, err := u.client.CompleteMultipartUpload(u.ctx, &s3.CompleteMultipartUploadInput{
+		Bucket:          aws.String(u.bucket),
+		Key:             aws.String(u.key),
+		UploadId:        u.uploadID,
+		MultipartUpload: &types.CompletedMultipartUpload{Parts: u.parts},
+	})
+	if err != nil {
+		return fmt.Errorf("complete multipart upload: %w", err)
+	}
+	return nil
+}
+
+// Abort discards uploaded parts so failed runs do not leave billable
+// fragments behind. It uses its own context because the run's may be done.
+func (u *multipartUpload) Abort() {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	_, _ = u.client.AbortMultipartUpload(ctx, &s3.AbortMultipartUploadInput{
+		Bucket:   aws.String(u.bucket),
+		Key:      aws.String(u.key),
+		UploadId: u.uploadID,
+	})
+}
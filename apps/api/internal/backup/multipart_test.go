@@ -0,0 +1,105 @@
+package backup
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+type fakeMultipartClient struct {
+	parts     [][]byte
+	completed int
+	aborted   bool
+	failPart  int
+}
+
+func (f *fakeMultipartClient) CreateMultipartUpload(ctx context.Context, in *s3.CreateMultipartUploadInput, _ ...func(*s3.Options)) (*s3.CreateMultipartUploadOutput, error) {
+	return &s3.CreateMultipartUploadOutput{UploadId: aws.String("upload-1")}, nil
+}
+
+func (f *fakeMultipartClient) UploadPart(ctx context.Context, in *s3.UploadPartInput, _ ...func(*s3.Options)) (*s3.UploadPartOutput, error) {
+	if f.failPart != 0 && int(aws.ToInt32(in.PartNumber)) == f.failPart {
+		return nil, errors.New("boom")
+	}
+	data, err := io.ReadAll(in.Body)
+	if err != nil {
+		return nil, err
+	}
+	f.parts = append(f.parts, data)
+	return &s3.UploadPartOutput{ETag: aws.String("etag")}, nil
+}
+
+func (f *fakeMultipartClient) CompleteMultipartUpload(ctx context.Context, in *s3.CompleteMultipartUploadInput, _ ...func(*s3.Options)) (*s3.CompleteMultipartUploadOutput, error) {
+	f.completed = len(in.MultipartUpload.Parts)
+	return &s3.CompleteMultipartUploadOutput{}, nil
+}
+
+func (f *fakeMultipartClient) AbortMultipartUpload(ctx context.Context, in *s3.AbortMultipartUploadInput, _ ...func(*s3.Options)) (*s3.AbortMultipartUploadOutput, error) {
+	f.aborted = true
+	return &s3.AbortMultipartUploadOutput{}, nil
+}
+
+func TestMultipartUploadSplitsParts(t *testing.T) {
+	client := &fakeMultipartClient{}
+	upload, err := startMultipartUpload(context.Background(), client, "backups", "keepstack.sql.gz", "application/gzip", minPartSize)
+	if err != nil {
+		t.Fatalf("startMultipartUpload returned error: %v", err)
+	}
+
+	payload := bytes.Repeat([]byte("k"), 2*minPartSize+123)
+	for chunk := payload; len(chunk) > 0; {
+		n := min(len(chunk), 1<<20)
+		if _, err := upload.Write(chunk[:n]); err != nil {
+			t.Fatalf("Write returned error: %v", err)
+		}
+		chunk = chunk[n:]
+	}
+	if err := upload.Close(); err != nil {
+		t.Fatalf("Close returned error: %v", err)
+	}
+
+	if len(client.parts) != 3 || client.completed != 3 {
+		t.Fatalf("expected 3 parts, got %d uploaded and %d completed", len(client.parts), client.completed)
+	}
+	if len(client.parts[0]) != minPartSize || len(client.parts[2]) != 123 {
+		t.Fatalf("unexpected part sizes: %d, %d, %d", len(client.parts[0]), len(client.parts[1]), len(client.parts[2]))
+	}
+	if !bytes.Equal(bytes.Join(client.parts, nil), payload) {
+		t.Fatalf("uploaded bytes do not match payload")
+	}
+}
+
+func TestMultipartUploadEmptyStreamUploadsOnePart(t *testing.T) {
+	client := &fakeMultipartClient{}
+	upload, err := startMultipartUpload(context.Background(), client, "backups", "empty.sql.gz", "application/gzip", 0)
+	if err != nil {
+		t.Fatalf("startMultipartUpload returned error: %v", err)
+	}
+	if err := upload.Close(); err != nil {
+		t.Fatalf("Close returned error: %v", err)
+	}
+	if len(client.parts) != 1 || client.completed != 1 {
+		t.Fatalf("expected a single empty part, got %d", len(client.parts))
+	}
+}
+
+func TestMultipartUploadPartFailure(t *testing.T) {
+	client := &fakeMultipartClient{failPart: 1}
+	upload, err := startMultipartUpload(context.Background(), client, "backups", "keepstack.sql.gz", "application/gzip", minPartSize)
+	if err != nil {
+		t.Fatalf("startMultipartUpload returned error: %v", err)
+	}
+
+	if _, err := upload.Write(make([]byte, minPartSize)); err == nil {
+		t.Fatalf("expected part upload failure")
+	}
+	upload.Abort()
+	if !client.aborted {
+		t.Fatalf("expected upload to be aborted")
+	}
+}
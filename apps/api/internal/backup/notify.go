@@ -0,0 +1,157 @@
+package backup
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/example/keepstack/apps/api/internal/digest"
+)
+
+const (
+	// NotifyAlways sends a notification after every backup run.
+	NotifyAlways = "always"
+	// NotifyFailure only notifies when a backup run fails.
+	NotifyFailure = "failure"
+
+	// StatusSuccess marks a completed backup run.
+	StatusSuccess = "success"
+	// StatusFailure marks a backup run that returned an error.
+	StatusFailure = "failure"
+)
+
+// Report summarises a backup run for notifications.
+type Report struct {
+	Status      string        `json:"status"`
+	Kind        string        `json:"kind,omitempty"`
+	File        string        `json:"file,omitempty"`
+	Destination string        `json:"destination,omitempty"`
+	SizeBytes   int64         `json:"size_bytes"`
+	SHA256      string        `json:"sha256,omitempty"`
+	Duration    time.Duration `json:"-"`
+	DurationSec float64       `json:"duration_seconds"`
+	FinishedAt  time.Time     `json:"finished_at"`
+	Error       string        `json:"error,omitempty"`
+}
+
+// Summary renders a one-line description of the run.
+func (r Report) Summary() string {
+	duration := r.Duration.Round(time.Second)
+	if r.Status == StatusFailure {
+		return fmt.Sprintf("Keepstack backup failed after %s: %s", duration, r.Error)
+	}
+	return fmt.Sprintf("Keepstack %s backup succeeded in %s: %s (%s) stored at %s",
+		r.Kind, duration, r.File, formatBytes(r.SizeBytes), r.Destination)
+}
+
+// Notifier delivers backup reports over the digest mail transport, a Slack
+// incoming webhook, or a generic JSON webhook depending on BACKUP_NOTIFY_URL.
+type Notifier struct {
+	cfg       Config
+	target    string
+	transport digest.Transport
+	client    *http.Client
+}
+
+// NewNotifier builds a Notifier from cfg. It returns nil when notifications
+// are not configured. smtp:// and log:// URLs send email through the digest
+// transport; https://hooks.slack.com URLs post Slack messages; any other
+// http(s) URL receives the report as JSON.
+func NewNotifier(cfg Config) (*Notifier, error) {
+	if cfg.NotifyURL == "" {
+		return nil, nil
+	}
+
+	parsed, err := url.Parse(cfg.NotifyURL)
+	if err != nil {
+		return nil, fmt.Errorf("parse BACKUP_NOTIFY_URL: %w", err)
+	}
+
+	n := &Notifier{cfg: cfg, client: &http.Client{Timeout: 10 * time.Second}}
+	switch strings.ToLower(parsed.Scheme) {
+	case "smtp", "log":
+		transport, err := digest.ParseSMTPURL(cfg.NotifyURL)
+		if err != nil {
+			return nil, err
+		}
+		if cfg.NotifySender == "" || cfg.NotifyRecipient == "" {
+			return nil, fmt.Errorf("email backup notifications require BACKUP_NOTIFY_SENDER and BACKUP_NOTIFY_RECIPIENT")
+		}
+		n.target = "email"
+		n.transport = transport
+	case "http", "https":
+		n.target = "webhook"
+		if strings.EqualFold(parsed.Hostname(), "hooks.slack.com") {
+			n.target = "slack"
+		}
+	default:
+		return nil, fmt.Errorf("unsupported BACKUP_NOTIFY_URL scheme %q", parsed.Scheme)
+	}
+	return n, nil
+}
+
+// Notify sends report unless BACKUP_NOTIFY_ON filters it out.
+func (n *Notifier) Notify(ctx context.Context, report Report) error {
+	if n == nil {
+		return nil
+	}
+	if n.cfg.NotifyOn == NotifyFailure && report.Status != StatusFailure {
+		return nil
+	}
+	report.DurationSec = report.Duration.Seconds()
+
+	switch n.target {
+	case "email":
+		subject := "Keepstack backup succeeded"
+		if report.Status == StatusFailure {
+			subject = "Keepstack backup FAILED"
+		}
+		return digest.SendMail(n.transport, n.cfg.NotifySender, n.cfg.NotifyRecipient, subject, "text/plain", report.Summary()+"\n")
+	case "slack":
+		return n.post(ctx, map[string]string{"text": report.Summary()})
+	default:
+		return n.post(ctx, report)
+	}
+}
+
+func (n *Notifier) post(ctx context.Context, payload any) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("encode backup notification: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.cfg.NotifyURL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("build backup notification: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("send backup notification: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("send backup notification: unexpected status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+func formatBytes(size int64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%d B", size)
+	}
+	div, exp := int64(unit), 0
+	for n := size / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(size)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,157 @@
+package backup
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+var pgDumpVersionPattern = regexp.MustCompile(`\(PostgreSQL\)\s+(\d+)`)
+
+// selectDumper decides how a full backup is taken. It returns the pg_dump
+// binary to run, or an empty string when the logical export should be used
+// instead. With BACKUP_DUMPER=auto a missing or too-old pg_dump is logged and
+// the logical export takes over; with BACKUP_DUMPER=pg_dump it is an error.
+func (r *Runner) selectDumper(ctx context.Context, conn *pgx.Conn) (string, error) {
+	if r.cfg.Dumper == DumperLogical {
+		return "", nil
+	}
+
+	bin, err := checkPGDump(ctx, conn, r.cfg.PGDumpPath)
+	if err == nil {
+		return bin, nil
+	}
+	if r.cfg.Dumper == DumperPGDump {
+		return "", err
+	}
+	r.logger.Printf("warn: %v; falling back to the logical export", err)
+	return "", nil
+}
+
+// checkPGDump resolves the pg_dump binary and confirms it can dump the
+// connected server. pg_dump refuses servers with a newer major version, so
+// the mismatch is caught before a partial dump is written.
+func checkPGDump(ctx context.Context, conn *pgx.Conn, path string) (string, error) {
+	serverMajor, err := serverMajorVersion(ctx, conn)
+	if err != nil {
+		return "", err
+	}
+
+	name := path
+	if name == "" {
+		name = "pg_dump"
+	}
+	bin, err := exec.LookPath(name)
+	if err != nil {
+		return "", fmt.Errorf("pg_dump not found (%v); install postgresql-client-%d or set BACKUP_PG_DUMP_PATH", err, serverMajor)
+	}
+
+	out, err := exec.CommandContext(ctx, bin, "--version").Output()
+	if err != nil {
+		return "", fmt.Errorf("%s --version: %w", bin, err)
+	}
+	clientMajor, err := parsePGDumpMajor(string(out))
+	if err != nil {
+		return "", err
+	}
+	if err := compatiblePGDump(clientMajor, serverMajor); err != nil {
+		return "", fmt.Errorf("%s: %w", bin, err)
+	}
+	return bin, nil
+}
+
+// parsePGDumpMajor extracts the major version from `pg_dump --version`
+// output such as "pg_dump (PostgreSQL) 16.2".
+func parsePGDumpMajor(output string) (int, error) {
+	match := pgDumpVersionPattern.FindStringSubmatch(output)
+	if match == nil {
+		return 0, fmt.Errorf("unrecognised pg_dump version %q", strings.TrimSpace(output))
+	}
+	return strconv.Atoi(match[1])
+}
+
+func compatiblePGDump(clientMajor, serverMajor int) error {
+	if clientMajor < serverMajor {
+		return fmt.Errorf("pg_dump %d cannot dump a PostgreSQL %d server; install postgresql-client-%d or set BACKUP_PG_DUMP_PATH", clientMajor, serverMajor, serverMajor)
+	}
+	return nil
+}
+
+func serverMajorVersion(ctx context.Context, conn *pgx.Conn) (int, error) {
+	var raw string
+	if err := conn.QueryRow(ctx, "SHOW server_version_num").Scan(&raw); err != nil {
+		return 0, fmt.Errorf("read server version: %w", err)
+	}
+	num, err := strconv.Atoi(strings.TrimSpace(raw))
+	if err != nil {
+		return 0, fmt.Errorf("parse server version %q: %w", raw, err)
+	}
+	if num <= 0 {
+		return 0, errors.New("server reported an empty version")
+	}
+	return num / 10000, nil
+}
+
+// logicalTables captures every core table whole; each is replaced on restore
+// so the result matches the snapshot exactly.
+func logicalTables() []tableSpec {
+	specs := make([]tableSpec, len(incrementalTables))
+	for i, spec := range incrementalTables {
+		spec.strategy = StrategyReplace
+		specs[i] = spec
+	}
+	return specs
+}
+
+// writeLogical streams a full psql script of the core tables using COPY over
+// the existing connection, for images that ship without pg_dump. The schema
+// itself is not included; restore into a database with migrations applied.
+func writeLogical(ctx context.Context, conn *pgx.Conn, w io.Writer, exclude []string) (time.Time, []TableManifest, error) {
+	header := func(snapshot time.Time) string {
+		return fmt.Sprintf("-- keepstack logical backup\n-- snapshot %s\n", snapshot.UTC().Format(time.RFC3339Nano))
+	}
+	return writeTableScript(ctx, conn, w, withoutTables(logicalTables(), exclude), header, func(tableSpec) string { return "" })
+}
+
+// pgDumpArgs builds the pg_dump command line, skipping excluded tables
+// entirely (schema and data).
+func pgDumpArgs(databaseURL string, exclude []string) []string {
+	args := make([]string, 0, len(exclude)+1)
+	for _, table := range exclude {
+		args = append(args, "--exclude-table="+table)
+	}
+	return append(args, databaseURL)
+}
+
+// dumpedTables lists the tables in the current schema that a pg_dump backup
+// includes, for the manifest.
+func dumpedTables(ctx context.Context, conn *pgx.Conn, exclude []string) ([]string, error) {
+	rows, err := conn.Query(ctx, `
+SELECT table_name
+FROM information_schema.tables
+WHERE table_schema = current_schema() AND table_type = 'BASE TABLE'
+ORDER BY table_name`)
+	if err != nil {
+		return nil, fmt.Errorf("list tables: %w", err)
+	}
+	names, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return nil, fmt.Errorf("list tables: %w", err)
+	}
+
+	included := make([]string, 0, len(names))
+	for _, name := range names {
+		if !tableExcluded(name, exclude) {
+			included = append(included, name)
+		}
+	}
+	return included, nil
+}
@@ -0,0 +1,131 @@
+package backup
+
+import (
+	"compress/gzip"
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// Restore replays a backup into the database at databaseURL using psql. When
+// target names a manifest (or a backup file with one alongside it) the full
+// backup it descends from is restored first, followed by each incremental in
+// order; per-user SQL exports replay on their own. An empty target restores
+// the newest full or incremental backup in dir.
+func Restore(ctx context.Context, dir, target, databaseURL string, logger *log.Logger) error {
+	manifests, err := ListManifests(dir)
+	if err != nil {
+		return err
+	}
+
+	var files []restoreFile
+	switch {
+	case target == "":
+		latest := latestChainManifest(manifests)
+		if latest == nil {
+			return fmt.Errorf("no backup manifests found in %s", dir)
+		}
+		chain, err := Chain(manifests, *latest)
+		if err != nil {
+			return err
+		}
+		files, err = chainFiles(dir, chain)
+		if err != nil {
+			return err
+		}
+	default:
+		name := filepath.Base(target)
+		if !strings.HasSuffix(name, manifestSuffix) {
+			name = manifestName(name)
+		}
+		manifestPath := filepath.Join(dir, name)
+		if _, err := os.Stat(manifestPath); os.IsNotExist(err) {
+			// Dumps written before manifests existed restore on their own.
+			files = []restoreFile{{path: resolvePath(dir, target)}}
+			break
+		}
+		m, err := ReadManifest(manifestPath)
+		if err != nil {
+			return err
+		}
+		chain, err := Chain(manifests, m)
+		if err != nil {
+			return err
+		}
+		files, err = chainFiles(dir, chain)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, file := range files {
+		logger.Printf("restoring %s", file.path)
+		if err := replay(ctx, file, databaseURL); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// restoreFile is a backup to replay. Scripts written by keepstack itself run
+// in a single transaction and stop at the first error; pg_dump output is
+// replayed leniently as before.
+type restoreFile struct {
+	path   string
+	script bool
+}
+
+func chainFiles(dir string, chain []Manifest) ([]restoreFile, error) {
+	files := make([]restoreFile, 0, len(chain))
+	for _, m := range chain {
+		if m.Format == FormatJSON {
+			return nil, fmt.Errorf("%s is a JSON export and cannot be replayed; use BACKUP_USER_FORMAT=sql", m.File)
+		}
+		script := m.Kind != ModeFull || m.Dumper == DumperLogical
+		files = append(files, restoreFile{path: filepath.Join(dir, m.File), script: script})
+	}
+	return files, nil
+}
+
+func resolvePath(dir, target string) string {
+	if filepath.IsAbs(target) || strings.Contains(target, string(filepath.Separator)) {
+		return target
+	}
+	return filepath.Join(dir, target)
+}
+
+func replay(ctx context.Context, backup restoreFile, databaseURL string) error {
+	path := backup.path
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("open backup: %w", err)
+	}
+	defer file.Close()
+
+	reader, err := gzip.NewReader(file)
+	if err != nil {
+		return fmt.Errorf("read backup %s: %w", path, err)
+	}
+	defer reader.Close()
+
+	args := []string{"--quiet", databaseURL}
+	if backup.script || strings.HasSuffix(path, incrementalSuffix) {
+		// Keepstack scripts run in one transaction; stop at the first error
+		// rather than replaying the rest against an aborted transaction.
+		args = append([]string{"-v", "ON_ERROR_STOP=1"}, args...)
+	}
+
+	cmd := exec.CommandContext(ctx, "psql", args...)
+	cmd.Stdin = reader
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("psql %s: %w", filepath.Base(path), err)
+	}
+	return nil
+}
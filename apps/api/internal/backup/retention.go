@@ -0,0 +1,170 @@
+package backup
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path"
+	"regexp"
+	"sort"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// s3DeleteBatch is the most keys DeleteObjects accepts per request.
+const s3DeleteBatch = 1000
+
+var backupObjectPattern = regexp.MustCompile(`^keepstack-(?:user-([0-9a-f-]{36})-)?(\d{8}-\d{6})(\.incr)?\.(sql\.gz|json\.gz|manifest\.json)$`)
+
+// expiredObjectKeys applies the local retention rule to object keys: the
+// newest retention full backups are kept along with everything written after
+// the oldest of them, so incremental chains stay intact, and each user keeps
+// their newest retention exports. Keys that do not look like keepstack
+// backups are never returned.
+func expiredObjectKeys(keys []string, retention int) []string {
+	if retention <= 0 {
+		return nil
+	}
+
+	stamps := make(map[string]string, len(keys))
+	users := make(map[string]string)
+	userStamps := make(map[string]map[string]struct{})
+	var fulls []string
+	for _, key := range keys {
+		match := backupObjectPattern.FindStringSubmatch(path.Base(key))
+		if match == nil {
+			continue
+		}
+		userID, stamp := match[1], match[2]
+		stamps[key] = stamp
+		if userID != "" {
+			users[key] = userID
+			if userStamps[userID] == nil {
+				userStamps[userID] = make(map[string]struct{})
+			}
+			userStamps[userID][stamp] = struct{}{}
+			continue
+		}
+		if match[3] == "" && match[4] == "sql.gz" {
+			fulls = append(fulls, stamp)
+		}
+	}
+
+	var cutoff string
+	if len(fulls) > retention {
+		sort.Strings(fulls)
+		cutoff = fulls[len(fulls)-retention]
+	}
+
+	userCutoffs := make(map[string]string, len(userStamps))
+	for userID, set := range userStamps {
+		if len(set) <= retention {
+			continue
+		}
+		sorted := make([]string, 0, len(set))
+		for stamp := range set {
+			sorted = append(sorted, stamp)
+		}
+		sort.Strings(sorted)
+		userCutoffs[userID] = sorted[len(sorted)-retention]
+	}
+
+	var expired []string
+	for key, stamp := range stamps {
+		if userID, ok := users[key]; ok {
+			if userCutoff, ok := userCutoffs[userID]; ok && stamp < userCutoff {
+				expired = append(expired, key)
+			}
+			continue
+		}
+		if cutoff != "" && stamp < cutoff {
+			expired = append(expired, key)
+		}
+	}
+	sort.Strings(expired)
+	return expired
+}
+
+func (r *Runner) pruneS3(ctx context.Context) error {
+	client, err := newS3Client(ctx, r.cfg)
+	if err != nil {
+		return err
+	}
+
+	if r.cfg.TransitionDays > 0 {
+		r.logger.Printf("hint: apply this lifecycle rule to transition backups: %s", lifecycleHint(r.cfg))
+	}
+
+	var keys []string
+	paginator := s3.NewListObjectsV2Paginator(client, &s3.ListObjectsV2Input{
+		Bucket: aws.String(r.cfg.S3Bucket),
+		Prefix: aws.String(s3Key(r.cfg, "keepstack-")),
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return fmt.Errorf("list s3 backups: %w", err)
+		}
+		for _, object := range page.Contents {
+			keys = append(keys, aws.ToString(object.Key))
+		}
+	}
+
+	expired := expiredObjectKeys(keys, r.cfg.Retention)
+	if len(expired) == 0 {
+		return nil
+	}
+
+	if r.cfg.DryRun {
+		for _, key := range expired {
+			r.logger.Printf("dry-run: would delete s3://%s/%s", r.cfg.S3Bucket, key)
+		}
+		return nil
+	}
+
+	for start := 0; start < len(expired); start += s3DeleteBatch {
+		end := min(start+s3DeleteBatch, len(expired))
+		objects := make([]types.ObjectIdentifier, 0, end-start)
+		for _, key := range expired[start:end] {
+			objects = append(objects, types.ObjectIdentifier{Key: aws.String(key)})
+		}
+
+		out, err := client.DeleteObjects(ctx, &s3.DeleteObjectsInput{
+			Bucket: aws.String(r.cfg.S3Bucket),
+			Delete: &types.Delete{Objects: objects, Quiet: aws.Bool(true)},
+		})
+		if err != nil {
+			return fmt.Errorf("delete expired s3 backups: %w", err)
+		}
+		for _, failed := range out.Errors {
+			r.logger.Printf("warn: failed to delete s3://%s/%s: %s", r.cfg.S3Bucket, aws.ToString(failed.Key), aws.ToString(failed.Message))
+		}
+	}
+
+	r.logger.Printf("pruned %d expired s3 backup objects", len(expired))
+	return nil
+}
+
+// lifecycleHint renders an S3 lifecycle configuration that transitions
+// backups under the prefix to a colder storage class. It is logged rather
+// than applied because bucket lifecycle rules replace any existing ones.
+func lifecycleHint(cfg Config) string {
+	rule := map[string]any{
+		"Rules": []map[string]any{{
+			"ID":     "keepstack-backup-transition",
+			"Status": "Enabled",
+			"Filter": map[string]string{"Prefix": s3Key(cfg, "keepstack-")},
+			"Transitions": []map[string]any{{
+				"Days":         cfg.TransitionDays,
+				"StorageClass": cfg.TransitionClass,
+			}},
+		}},
+	}
+	data, err := json.Marshal(rule)
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
@@ -0,0 +1,165 @@
+package backup
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+
+	"github.com/example/keepstack/health"
+)
+
+func newS3Client(ctx context.Context, cfg Config) (*s3.Client, error) {
+	if cfg.S3Bucket == "" || cfg.S3AccessKey == "" || cfg.S3SecretKey == "" {
+		return nil, fmt.Errorf("missing S3 configuration")
+	}
+
+	awsCfg, err := awsConfig(ctx, cfg.S3Region, cfg.S3AccessKey, cfg.S3SecretKey, cfg.S3Endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("configure s3 client: %w", err)
+	}
+
+	return s3.NewFromConfig(awsCfg, func(o *s3.Options) {
+		o.UsePathStyle = true
+	}), nil
+}
+
+// CheckS3 confirms the configured credentials can reach the backup bucket.
+// It does nothing unless backups go to S3.
+func CheckS3(ctx context.Context, cfg Config) error {
+	if cfg.Storage != StorageS3 {
+		return nil
+	}
+	client, err := newS3Client(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	return health.S3(bucketHeader{client: client}, cfg.S3Bucket).Run(ctx)
+}
+
+// bucketHeader adapts an S3 client to health.BucketHeader.
+type bucketHeader struct {
+	client *s3.Client
+}
+
+func (b bucketHeader) HeadBucket(ctx context.Context, bucket string) error {
+	_, err := b.client.HeadBucket(ctx, &s3.HeadBucketInput{Bucket: aws.String(bucket)})
+	return err
+}
+
+func s3Key(cfg Config, fileName string) string {
+	if cfg.S3Prefix == "" {
+		return fileName
+	}
+	return fmt.Sprintf("%s/%s", cfg.S3Prefix, fileName)
+}
+
+func uploadToS3(ctx context.Context, cfg Config, path, fileName, contentType string) error {
+	client, err := newS3Client(ctx, cfg)
+	if err != nil {
+		return err
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("open backup for upload: %w", err)
+	}
+	defer file.Close()
+
+	_, err = client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket:      aws.String(cfg.S3Bucket),
+		Key:         aws.String(s3Key(cfg, fileName)),
+		Body:        file,
+		ContentType: aws.String(contentType),
+	})
+	if err != nil {
+		return fmt.Errorf("upload backup: %w", err)
+	}
+
+	return nil
+}
+
+// fetchManifestsFromS3 downloads manifests missing from dir so incremental
+// runs on ephemeral volumes can still find their parent backup.
+func fetchManifestsFromS3(ctx context.Context, cfg Config) (int, error) {
+	client, err := newS3Client(ctx, cfg)
+	if err != nil {
+		return 0, err
+	}
+
+	fetched := 0
+	paginator := s3.NewListObjectsV2Paginator(client, &s3.ListObjectsV2Input{
+		Bucket: aws.String(cfg.S3Bucket),
+		Prefix: aws.String(s3Key(cfg, "keepstack-")),
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return fetched, fmt.Errorf("list s3 backups: %w", err)
+		}
+		for _, object := range page.Contents {
+			key := aws.ToString(object.Key)
+			name := filepath.Base(key)
+			if !strings.HasSuffix(name, manifestSuffix) {
+				continue
+			}
+			dest := filepath.Join(cfg.Dir, name)
+			if _, err := os.Stat(dest); err == nil {
+				continue
+			}
+			if err := downloadFromS3(ctx, client, cfg.S3Bucket, key, dest); err != nil {
+				return fetched, err
+			}
+			fetched++
+		}
+	}
+	return fetched, nil
+}
+
+func downloadFromS3(ctx context.Context, client *s3.Client, bucket, key, dest string) error {
+	out, err := client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return fmt.Errorf("download %s: %w", key, err)
+	}
+	defer out.Body.Close()
+
+	file, err := os.Create(dest)
+	if err != nil {
+		return fmt.Errorf("create %s: %w", dest, err)
+	}
+	if _, err := io.Copy(file, out.Body); err != nil {
+		file.Close()
+		os.Remove(dest)
+		return fmt.Errorf("download %s: %w", key, err)
+	}
+	return file.Close()
+}
+
+func awsConfig(ctx context.Context, region, accessKey, secretKey, endpoint string) (aws.Config, error) {
+	opts := []func(*awsconfig.LoadOptions) error{
+		awsconfig.WithRegion(region),
+		awsconfig.WithCredentialsProvider(credentials.NewStaticCredentialsProvider(accessKey, secretKey, "")),
+	}
+
+	if endpoint != "" {
+		resolver := aws.EndpointResolverWithOptionsFunc(func(service, region string, _ ...interface{}) (aws.Endpoint, error) {
+			return aws.Endpoint{
+				URL:               endpoint,
+				HostnameImmutable: true,
+			}, nil
+		})
+		opts = append(opts, awsconfig.WithEndpointResolverWithOptions(resolver))
+	}
+
+	return awsconfig.LoadDefaultConfig(ctx, opts...)
+}
@@ -0,0 +1,212 @@
+package backup
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+const (
+	// FormatSQL writes per-user exports as replayable psql scripts.
+	FormatSQL = "sql"
+	// FormatJSON writes per-user exports as a JSON document of table rows.
+	FormatJSON = "json"
+)
+
+const userLinksFilter = "link_id IN (SELECT id FROM links WHERE user_id = '%s')"
+
+// userTables lists the rows that belong to a single account, in restore
+// order. Links are merged rather than replaced so restoring an export never
+// deletes links saved afterwards; tags and highlights on the exported links
+// are reset to the exported state.
+var userTables = []tableSpec{
+	{name: "users", key: []string{"id"}, strategy: StrategyUpsert, filter: "id = '%s'"},
+	{
+		name:     "tags",
+		key:      []string{"id"},
+		strategy: StrategyInsert,
+		filter:   "user_id = '%s'",
+	},
+	{name: "links", key: []string{"id"}, strategy: StrategyUpsert, filter: "user_id = '%s'"},
+	{name: "archives", key: []string{"link_id"}, strategy: StrategyUpsert, filter: userLinksFilter},
+	{
+		name:     "link_tags",
+		key:      []string{"link_id", "tag_id"},
+		strategy: StrategyReplace,
+		filter:   userLinksFilter,
+		scope:    "link_id IN (SELECT id FROM keepstack_restore_links)",
+	},
+	{
+		name:     "link_media",
+		key:      []string{"link_id", "hash"},
+		strategy: StrategyReplace,
+		filter:   userLinksFilter,
+		scope:    "link_id IN (SELECT id FROM keepstack_restore_links)",
+	},
+	{
+		name:     "highlights",
+		key:      []string{"id"},
+		strategy: StrategyReplace,
+		filter:   userLinksFilter,
+		scope:    "link_id IN (SELECT id FROM keepstack_restore_links)",
+	},
+	{name: "claims", key: []string{"id"}, strategy: StrategyInsert, filter: "user_id = '%s'"},
+	{name: "resurfacer_weights", key: []string{"user_id"}, strategy: StrategyUpsert, filter: "user_id = '%s'"},
+}
+
+// runUserExports writes one export per user: BACKUP_USER_ID when set,
+// otherwise every account.
+func (r *Runner) runUserExports(ctx context.Context, conn *pgx.Conn, manifests []Manifest) (Result, error) {
+	userIDs, err := r.exportUserIDs(ctx, conn)
+	if err != nil {
+		return Result{}, err
+	}
+
+	createdAt := r.now().UTC()
+	stamp := createdAt.Format("20060102-150405")
+	suffix := fullSuffix
+	if r.cfg.UserFormat == FormatJSON {
+		suffix = jsonSuffix
+	}
+
+	result := Result{Manifest: Manifest{Kind: ModeUser, CreatedAt: createdAt}, Destination: r.cfg.Dir}
+	if r.cfg.Storage == StorageS3 {
+		result.Destination = fmt.Sprintf("s3://%s/%s", r.cfg.S3Bucket, s3Key(r.cfg, ""))
+	}
+
+	for _, userID := range userIDs {
+		manifest := Manifest{
+			Kind:      ModeUser,
+			File:      fmt.Sprintf("keepstack-user-%s-%s%s", userID, stamp, suffix),
+			CreatedAt: createdAt,
+			UserID:    userID.String(),
+			Format:    r.cfg.UserFormat,
+		}
+
+		stats, err := r.writeFile(ctx, manifest.File, func(w io.Writer) error {
+			var (
+				snapshot time.Time
+				tables   []TableManifest
+				err      error
+			)
+			if r.cfg.UserFormat == FormatJSON {
+				snapshot, tables, err = writeUserJSON(ctx, conn, w, userID)
+			} else {
+				snapshot, tables, err = writeUserSQL(ctx, conn, w, userID)
+			}
+			manifest.SnapshotAt = snapshot
+			manifest.Tables = tables
+			return err
+		})
+		if err != nil {
+			return Result{}, fmt.Errorf("export user %s: %w", userID, err)
+		}
+
+		if _, err := r.store(ctx, &manifest, stats); err != nil {
+			return Result{}, err
+		}
+		result.Exports = append(result.Exports, manifest)
+		result.Manifest.SizeBytes += manifest.SizeBytes
+		manifests = append(manifests, manifest)
+	}
+
+	r.prune(manifests)
+	if r.cfg.Storage == StorageS3 {
+		if err := r.pruneS3(ctx); err != nil {
+			r.logger.Printf("warn: %v", err)
+		}
+	}
+
+	return result, nil
+}
+
+func (r *Runner) exportUserIDs(ctx context.Context, conn *pgx.Conn) ([]uuid.UUID, error) {
+	if r.cfg.UserID != "" {
+		id, err := uuid.Parse(r.cfg.UserID)
+		if err != nil {
+			return nil, fmt.Errorf("invalid BACKUP_USER_ID: %w", err)
+		}
+		return []uuid.UUID{id}, nil
+	}
+
+	rows, err := conn.Query(ctx, "SELECT id FROM users ORDER BY created_at, id")
+	if err != nil {
+		return nil, fmt.Errorf("list users: %w", err)
+	}
+	ids, err := pgx.CollectRows(rows, pgx.RowTo[uuid.UUID])
+	if err != nil {
+		return nil, fmt.Errorf("list users: %w", err)
+	}
+	return ids, nil
+}
+
+// writeUserSQL streams a psql script that restores a single account.
+func writeUserSQL(ctx context.Context, conn *pgx.Conn, w io.Writer, userID uuid.UUID) (time.Time, []TableManifest, error) {
+	header := func(snapshot time.Time) string {
+		return fmt.Sprintf("-- keepstack user export\n-- user %s\n-- snapshot %s\n",
+			userID, snapshot.UTC().Format(time.RFC3339Nano))
+	}
+	where := func(spec tableSpec) string {
+		return userFilter(spec, userID)
+	}
+	return writeTableScript(ctx, conn, w, userTables, header, where)
+}
+
+// writeUserJSON writes a portable JSON document holding the account's rows
+// keyed by table name.
+func writeUserJSON(ctx context.Context, conn *pgx.Conn, w io.Writer, userID uuid.UUID) (time.Time, []TableManifest, error) {
+	tx, snapshot, err := beginSnapshot(ctx, conn)
+	if err != nil {
+		return time.Time{}, nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	head, err := json.Marshal(map[string]string{
+		"user_id":     userID.String(),
+		"snapshot_at": snapshot.UTC().Format(time.RFC3339Nano),
+	})
+	if err != nil {
+		return time.Time{}, nil, err
+	}
+	// Reopen the header object so the tables can be streamed into it.
+	if _, err := io.WriteString(w, strings.TrimSuffix(string(head), "}")+`,"tables":{`); err != nil {
+		return time.Time{}, nil, err
+	}
+
+	tables := make([]TableManifest, 0, len(userTables))
+	for i, spec := range userTables {
+		var (
+			rowsJSON string
+			count    int64
+		)
+		query := fmt.Sprintf("SELECT COALESCE(json_agg(t), '[]'::json)::text, COUNT(*) FROM (SELECT * FROM %s WHERE %s) t",
+			spec.name, userFilter(spec, userID))
+		if err := tx.QueryRow(ctx, query).Scan(&rowsJSON, &count); err != nil {
+			return time.Time{}, nil, fmt.Errorf("export %s: %w", spec.name, err)
+		}
+
+		separator := ","
+		if i == 0 {
+			separator = ""
+		}
+		if _, err := fmt.Fprintf(w, "%s%q:%s", separator, spec.name, rowsJSON); err != nil {
+			return time.Time{}, nil, err
+		}
+		tables = append(tables, TableManifest{Name: spec.name, Strategy: spec.strategy, Rows: count})
+	}
+
+	if _, err := io.WriteString(w, "}}\n"); err != nil {
+		return time.Time{}, nil, err
+	}
+	return snapshot, tables, nil
+}
+
+func userFilter(spec tableSpec, userID uuid.UUID) string {
+	return strings.ReplaceAll(spec.filter, "%s", userID.String())
+}
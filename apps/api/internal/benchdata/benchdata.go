@@ -0,0 +1,304 @@
+// Package benchdata generates large, reproducible libraries for the
+// benchmarks behind the "integration" build tag: users with thousands of
+// links each, archives whose text gives the search index realistic term
+// frequencies, tags, and highlights. The same Config always yields the same
+// rows, IDs and timestamps included, so numbers from two runs compare.
+package benchdata
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Epoch anchors every generated timestamp. Links are created in the year
+// before it, so benchmarks that score by age should treat Epoch as now.
+var Epoch = time.Date(2025, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+// namespace derives the fixed IDs of generated rows.
+var namespace = uuid.MustParse("0c5e2d7a-91b4-5f3e-8a6d-2b7c4e1f9d30")
+
+// Config sizes a dataset.
+type Config struct {
+	// Seed selects the dataset; the same seed and sizes give the same rows.
+	Seed uint64
+	// Users is the number of users, each with LinksPerUser links.
+	Users        int
+	LinksPerUser int
+	// ArchivedPercent of links have an archive; the rest look pending.
+	ArchivedPercent int
+	// MaxHighlights bounds the highlights on one archived link.
+	MaxHighlights int
+	// Tags is the number of tag names links draw from. Every user has a
+	// tag of each name.
+	Tags int
+}
+
+// DefaultConfig is the dataset the benchmarks load unless the environment
+// overrides it: big enough that index choices show, small enough to load in
+// well under a minute.
+func DefaultConfig() Config {
+	return Config{
+		Seed:            1,
+		Users:           5,
+		LinksPerUser:    2000,
+		ArchivedPercent: 80,
+		MaxHighlights:   3,
+		Tags:            40,
+	}
+}
+
+// ConfigFromEnv returns DefaultConfig with KEEPSTACK_BENCH_SEED,
+// KEEPSTACK_BENCH_USERS, and KEEPSTACK_BENCH_LINKS (links per user) applied,
+// so a baseline for a 100k-link library needs no code change.
+func ConfigFromEnv() (Config, error) {
+	cfg := DefaultConfig()
+	if raw := os.Getenv("KEEPSTACK_BENCH_SEED"); raw != "" {
+		seed, err := strconv.ParseUint(raw, 10, 64)
+		if err != nil {
+			return cfg, fmt.Errorf("KEEPSTACK_BENCH_SEED: %w", err)
+		}
+		cfg.Seed = seed
+	}
+	for _, setting := range []struct {
+		env    string
+		target *int
+	}{
+		{"KEEPSTACK_BENCH_USERS", &cfg.Users},
+		{"KEEPSTACK_BENCH_LINKS", &cfg.LinksPerUser},
+	} {
+		raw := os.Getenv(setting.env)
+		if raw == "" {
+			continue
+		}
+		value, err := strconv.Atoi(raw)
+		if err != nil || value < 1 {
+			return cfg, fmt.Errorf("%s must be a positive integer, got %q", setting.env, raw)
+		}
+		*setting.target = value
+	}
+	return cfg, nil
+}
+
+// Dataset is a generated library.
+type Dataset struct {
+	Config Config
+	Users  []User
+	Tags   []string
+	Links  []Link
+}
+
+// User is a generated user.
+type User struct {
+	ID    uuid.UUID
+	Email string
+}
+
+// Link is a generated link. A nil Archive leaves it unarchived.
+type Link struct {
+	ID           uuid.UUID
+	UserID       uuid.UUID
+	URL          string
+	Title        string
+	SourceDomain string
+	Favorite     bool
+	ReadAt       *time.Time
+	CreatedAt    time.Time
+	Archive      *Archive
+	Tags         []string
+	Highlights   []Highlight
+}
+
+// Archive is the extracted content of a generated link.
+type Archive struct {
+	Title     string
+	Byline    string
+	Lang      string
+	Text      string
+	WordCount int
+}
+
+// Highlight is a generated highlight; Quote is taken from the archive text.
+type Highlight struct {
+	ID         uuid.UUID
+	Quote      string
+	Annotation string
+	CreatedAt  time.Time
+}
+
+// CommonTerm and RareTerm are the most and least frequent words in generated
+// titles and text, for benchmarking searches that match most of a library
+// and almost none of it.
+var (
+	CommonTerm = vocabulary[0]
+	RareTerm   = vocabulary[len(vocabulary)-1]
+)
+
+// Generate builds the dataset cfg describes.
+func Generate(cfg Config) Dataset {
+	g := &generator{
+		cfg: cfg,
+		rng: rand.New(rand.NewPCG(cfg.Seed, cfg.Seed^0x9e3779b97f4a7c15)),
+	}
+	// Zipf-distributed draws make a few words and tags very common and the
+	// long tail rare, as in a real library.
+	g.words = rand.NewZipf(g.rng, 1.1, 1, uint64(len(vocabulary)-1))
+	if cfg.Tags > 0 {
+		g.tags = rand.NewZipf(g.rng, 1.2, 1, uint64(cfg.Tags-1))
+	}
+
+	dataset := Dataset{Config: cfg}
+	for i := range cfg.Tags {
+		dataset.Tags = append(dataset.Tags, fmt.Sprintf("topic-%03d", i))
+	}
+	dataset.Links = make([]Link, 0, cfg.Users*cfg.LinksPerUser)
+	for u := range cfg.Users {
+		user := User{
+			ID:    g.id("user", u),
+			Email: fmt.Sprintf("bench+%d-%d@example.com", cfg.Seed, u),
+		}
+		dataset.Users = append(dataset.Users, user)
+		for range cfg.LinksPerUser {
+			dataset.Links = append(dataset.Links, g.link(user.ID, len(dataset.Links), dataset.Tags))
+		}
+	}
+	return dataset
+}
+
+type generator struct {
+	cfg   Config
+	rng   *rand.Rand
+	words *rand.Zipf
+	tags  *rand.Zipf
+}
+
+func (g *generator) id(kind string, n int) uuid.UUID {
+	return uuid.NewSHA1(namespace, fmt.Appendf(nil, "%d:%s:%d", g.cfg.Seed, kind, n))
+}
+
+func (g *generator) link(userID uuid.UUID, n int, tags []string) Link {
+	domain := domains[g.rng.IntN(len(domains))]
+	title := g.sentence(4 + g.rng.IntN(6))
+	link := Link{
+		ID:           g.id("link", n),
+		UserID:       userID,
+		URL:          fmt.Sprintf("https://%s/%s-%d", domain, strings.ReplaceAll(strings.ToLower(title), " ", "-"), n),
+		Title:        title,
+		SourceDomain: domain,
+		Favorite:     g.rng.IntN(10) == 0,
+		CreatedAt:    Epoch.Add(-time.Duration(g.rng.Int64N(int64(365 * 24 * time.Hour)))).Truncate(time.Second),
+	}
+	if g.rng.IntN(10) < 4 {
+		readAt := link.CreatedAt.Add(time.Duration(1+g.rng.IntN(72)) * time.Hour)
+		link.ReadAt = &readAt
+	}
+
+	if g.tags != nil {
+		for range g.rng.IntN(4) {
+			if name := tags[g.tags.Uint64()]; !slices.Contains(link.Tags, name) {
+				link.Tags = append(link.Tags, name)
+			}
+		}
+	}
+
+	if g.rng.IntN(100) >= g.cfg.ArchivedPercent {
+		return link
+	}
+	words := g.draw(g.wordCount())
+	link.Archive = &Archive{
+		Title:     title,
+		Byline:    bylines[g.rng.IntN(len(bylines))],
+		Lang:      "en",
+		Text:      strings.Join(words, " "),
+		WordCount: len(words),
+	}
+	if g.cfg.MaxHighlights > 0 {
+		for h := range g.rng.IntN(g.cfg.MaxHighlights + 1) {
+			start := g.rng.IntN(len(words))
+			end := min(start+5+g.rng.IntN(8), len(words))
+			highlight := Highlight{
+				ID:        g.id(fmt.Sprintf("highlight:%d", n), h),
+				Quote:     strings.Join(words[start:end], " "),
+				CreatedAt: link.CreatedAt.Add(time.Duration(1+h) * time.Hour),
+			}
+			if g.rng.IntN(10) < 3 {
+				highlight.Annotation = g.sentence(3 + g.rng.IntN(8))
+			}
+			link.Highlights = append(link.Highlights, highlight)
+		}
+	}
+	return link
+}
+
+// wordCount spreads archive lengths across the resurfacer's word-count tiers:
+// mostly short reads with a tail of long ones.
+func (g *generator) wordCount() int {
+	switch roll := g.rng.IntN(100); {
+	case roll < 60:
+		return 80 + g.rng.IntN(620)
+	case roll < 85:
+		return 800 + g.rng.IntN(700)
+	case roll < 95:
+		return 1500 + g.rng.IntN(1000)
+	default:
+		return 2500 + g.rng.IntN(1000)
+	}
+}
+
+func (g *generator) draw(n int) []string {
+	words := make([]string, n)
+	for i := range words {
+		words[i] = vocabulary[g.words.Uint64()]
+	}
+	return words
+}
+
+func (g *generator) sentence(n int) string {
+	words := g.draw(n)
+	words[0] = strings.ToUpper(words[0][:1]) + words[0][1:]
+	return strings.Join(words, " ")
+}
+
+var domains = []string{
+	"blog.example.com", "news.example.org", "docs.example.dev", "engineering.example.io",
+	"research.example.edu", "notes.example.net", "weekly.example.com", "magazine.example.org",
+	"wiki.example.org", "forum.example.net", "papers.example.edu", "changelog.example.dev",
+}
+
+var bylines = []string{
+	"Ada Byron", "Grace Hopper", "Edsger Dijkstra", "Barbara Liskov", "Ken Thompson",
+	"Frances Allen", "Donald Knuth", "Radia Perlman", "Leslie Lamport", "Margaret Hamilton",
+}
+
+// vocabulary is ordered by how often generated text uses each word, most
+// frequent first. None are English stop words, so every one reaches the
+// search index.
+var vocabulary = []string{
+	"database", "latency", "cluster", "release", "migration", "schema", "index", "query",
+	"cache", "service", "deploy", "kernel", "network", "storage", "compiler", "runtime",
+	"garbage", "collector", "scheduler", "thread", "channel", "pipeline", "backup", "replica",
+	"partition", "consensus", "protocol", "packet", "router", "gateway", "container", "image",
+	"registry", "volume", "snapshot", "rollback", "incident", "postmortem", "alert", "dashboard",
+	"metric", "trace", "sampling", "histogram", "budget", "capacity", "throughput", "bottleneck",
+	"profiler", "allocation", "pointer", "interface", "generic", "closure", "iterator", "parser",
+	"grammar", "token", "lexer", "bytecode", "interpreter", "optimizer", "vectorized", "columnar",
+	"transaction", "isolation", "deadlock", "vacuum", "autovacuum", "checkpoint", "journal", "ledger",
+	"encryption", "certificate", "handshake", "rotation", "secret", "vault", "identity", "session",
+	"cookie", "browser", "renderer", "layout", "typography", "accessibility", "keyboard", "gesture",
+	"animation", "shader", "texture", "sprite", "physics", "collision", "navigation", "heuristic",
+	"gradient", "embedding", "tokenizer", "benchmark", "regression", "bisect", "flaky", "fixture",
+	"mock", "harness", "coverage", "fuzzing", "sanitizer", "linker", "symbol", "debugger",
+	"breakpoint", "watchpoint", "assembly", "register", "firmware", "bootloader", "hypervisor", "enclave",
+	"quorum", "gossip", "lease", "fencing", "idempotent", "backpressure", "jitter", "retry",
+	"circuit", "bulkhead", "sidecar", "mesh", "ingress", "egress", "firewall", "bastion",
+	"terraform", "manifest", "helm", "operator", "reconcile", "controller", "webhook", "admission",
+	"telescope", "nebula", "glacier", "estuary", "archipelago", "savanna", "tundra", "monsoon",
+	"sourdough", "espresso", "marmalade", "croissant", "saffron", "cardamom", "tamarind", "pomegranate",
+	"harpsichord", "bassoon", "marimba", "theremin", "zither", "ocarina", "didgeridoo", "glockenspiel",
+}
@@ -0,0 +1,108 @@
+package benchdata
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestGenerateIsDeterministic(t *testing.T) {
+	cfg := Config{Seed: 7, Users: 3, LinksPerUser: 200, ArchivedPercent: 75, MaxHighlights: 3, Tags: 12}
+
+	first := Generate(cfg)
+	if !reflect.DeepEqual(first, Generate(cfg)) {
+		t.Fatal("expected the same config to generate the same dataset")
+	}
+	cfg.Seed++
+	if other := Generate(cfg); reflect.DeepEqual(first.Links[0], other.Links[0]) {
+		t.Fatal("expected another seed to generate different links")
+	}
+}
+
+func TestGenerateShape(t *testing.T) {
+	cfg := Config{Seed: 1, Users: 2, LinksPerUser: 500, ArchivedPercent: 80, MaxHighlights: 2, Tags: 10}
+	dataset := Generate(cfg)
+
+	if len(dataset.Users) != 2 || len(dataset.Links) != 1000 || len(dataset.Tags) != 10 {
+		t.Fatalf("expected 2 users, 1000 links, and 10 tags, got %d, %d, and %d", len(dataset.Users), len(dataset.Links), len(dataset.Tags))
+	}
+
+	ids := make(map[string]bool)
+	var archived, read, common, longReads int
+	for i, link := range dataset.Links {
+		if want := dataset.Users[i/cfg.LinksPerUser].ID; link.UserID != want {
+			t.Fatalf("link %d: expected user %s, got %s", i, want, link.UserID)
+		}
+		if ids[link.ID.String()] {
+			t.Fatalf("link %d: duplicate ID %s", i, link.ID)
+		}
+		ids[link.ID.String()] = true
+		if !link.CreatedAt.Before(Epoch) {
+			t.Fatalf("link %d: created %s, not before the epoch", i, link.CreatedAt)
+		}
+		if link.ReadAt != nil {
+			read++
+		}
+		if len(link.Tags) > 3 {
+			t.Fatalf("link %d: expected at most 3 tags, got %v", i, link.Tags)
+		}
+
+		archive := link.Archive
+		if archive == nil {
+			if len(link.Highlights) != 0 {
+				t.Fatalf("link %d: highlights without an archive", i)
+			}
+			continue
+		}
+		archived++
+		if got := len(strings.Fields(archive.Text)); got != archive.WordCount {
+			t.Fatalf("link %d: word count %d, text has %d words", i, archive.WordCount, got)
+		}
+		if archive.WordCount >= 2500 {
+			longReads++
+		}
+		if strings.Contains(archive.Text, CommonTerm) {
+			common++
+		}
+		if len(link.Highlights) > cfg.MaxHighlights {
+			t.Fatalf("link %d: expected at most %d highlights, got %d", i, cfg.MaxHighlights, len(link.Highlights))
+		}
+		for _, highlight := range link.Highlights {
+			if !strings.Contains(archive.Text, highlight.Quote) {
+				t.Fatalf("link %d: highlight %q is not in the archive text", i, highlight.Quote)
+			}
+		}
+	}
+
+	// Proportions are loose: they only guard against a generator that
+	// ignores its config.
+	if archived < 700 || archived > 900 {
+		t.Fatalf("expected about 800 archived links, got %d", archived)
+	}
+	if read == 0 || read == len(dataset.Links) {
+		t.Fatalf("expected a mix of read and unread links, got %d read", read)
+	}
+	if longReads == 0 {
+		t.Fatal("expected some archives in the longest word-count tier")
+	}
+	if common < archived*9/10 {
+		t.Fatalf("expected %q in nearly every archive, found it in %d of %d", CommonTerm, common, archived)
+	}
+}
+
+func TestConfigFromEnv(t *testing.T) {
+	t.Setenv("KEEPSTACK_BENCH_SEED", "42")
+	t.Setenv("KEEPSTACK_BENCH_LINKS", "20000")
+	cfg, err := ConfigFromEnv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Seed != 42 || cfg.LinksPerUser != 20000 || cfg.Users != DefaultConfig().Users {
+		t.Fatalf("unexpected config %+v", cfg)
+	}
+
+	t.Setenv("KEEPSTACK_BENCH_USERS", "0")
+	if _, err := ConfigFromEnv(); err == nil {
+		t.Fatal("expected zero users to be rejected")
+	}
+}
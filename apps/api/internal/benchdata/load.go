@@ -0,0 +1,154 @@
+package benchdata
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// passwordHash is the dev user's hash from migration 000005. Nobody signs in
+// as a generated user, but the column is required.
+const passwordHash = "$2a$12$w29oCFhGu3E7yBRLBXjg5eQqr8RP4eAbOeXtfLOcAfeUeawuO/HEa"
+
+// Load copies dataset into the database pool points at. Rows go in through
+// COPY with triggers and foreign key checks switched off for the
+// transaction, which needs a superuser such as the testenv container's. The
+// search tsvectors are then built in one pass by the links trigger itself,
+// so they match what the API would have stored, and the tables are analyzed
+// so the planner sees the loaded sizes.
+func Load(ctx context.Context, pool *pgxpool.Pool, dataset Dataset) error {
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("begin: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, `SET LOCAL session_replication_role = replica`); err != nil {
+		return fmt.Errorf("disable triggers: %w", err)
+	}
+
+	if _, err := tx.CopyFrom(ctx, pgx.Identifier{"users"}, []string{"id", "email", "password_hash"},
+		pgx.CopyFromSlice(len(dataset.Users), func(i int) ([]any, error) {
+			user := dataset.Users[i]
+			return []any{user.ID, user.Email, passwordHash}, nil
+		})); err != nil {
+		return fmt.Errorf("copy users: %w", err)
+	}
+
+	tagIDs, err := loadTags(ctx, tx, dataset.Users, dataset.Tags)
+	if err != nil {
+		return err
+	}
+
+	linkIDs := make([]uuid.UUID, len(dataset.Links))
+	var archived []Link
+	var linkTags, highlights [][]any
+	for i, link := range dataset.Links {
+		linkIDs[i] = link.ID
+		if link.Archive != nil {
+			archived = append(archived, link)
+		}
+		for _, name := range link.Tags {
+			linkTags = append(linkTags, []any{link.ID, tagIDs[tagKey{userID: link.UserID, name: name}]})
+		}
+		for _, highlight := range link.Highlights {
+			var annotation *string
+			if highlight.Annotation != "" {
+				annotation = &highlight.Annotation
+			}
+			highlights = append(highlights, []any{highlight.ID, link.ID, highlight.Quote, annotation, highlight.CreatedAt, highlight.CreatedAt})
+		}
+	}
+
+	if _, err := tx.CopyFrom(ctx, pgx.Identifier{"links"},
+		[]string{"id", "user_id", "url", "title", "source_domain", "favorite", "read_at", "created_at", "updated_at"},
+		pgx.CopyFromSlice(len(dataset.Links), func(i int) ([]any, error) {
+			link := dataset.Links[i]
+			return []any{link.ID, link.UserID, link.URL, link.Title, link.SourceDomain, link.Favorite, link.ReadAt, link.CreatedAt, link.CreatedAt}, nil
+		})); err != nil {
+		return fmt.Errorf("copy links: %w", err)
+	}
+
+	if _, err := tx.CopyFrom(ctx, pgx.Identifier{"archives"},
+		[]string{"link_id", "html", "extracted_text", "title", "byline", "lang", "word_count", "updated_at"},
+		pgx.CopyFromSlice(len(archived), func(i int) ([]any, error) {
+			link := archived[i]
+			archive := link.Archive
+			return []any{link.ID, "<article><p>" + archive.Text + "</p></article>", archive.Text, archive.Title, archive.Byline, archive.Lang, archive.WordCount, link.CreatedAt}, nil
+		})); err != nil {
+		return fmt.Errorf("copy archives: %w", err)
+	}
+	if _, err := tx.CopyFrom(ctx, pgx.Identifier{"link_ingest_status"}, []string{"link_id", "status"},
+		pgx.CopyFromSlice(len(archived), func(i int) ([]any, error) {
+			return []any{archived[i].ID, "ingested"}, nil
+		})); err != nil {
+		return fmt.Errorf("copy ingest status: %w", err)
+	}
+
+	if _, err := tx.CopyFrom(ctx, pgx.Identifier{"link_tags"}, []string{"link_id", "tag_id"}, pgx.CopyFromRows(linkTags)); err != nil {
+		return fmt.Errorf("copy link tags: %w", err)
+	}
+	if _, err := tx.CopyFrom(ctx, pgx.Identifier{"highlights"},
+		[]string{"id", "link_id", "quote", "annotation", "created_at", "updated_at"}, pgx.CopyFromRows(highlights)); err != nil {
+		return fmt.Errorf("copy highlights: %w", err)
+	}
+
+	// A no-op update runs links_search_tsv_update for every row, now that
+	// the archives it reads are in place. It also stamps updated_at with the
+	// load time, which none of the benchmarked queries read.
+	if _, err := tx.Exec(ctx, `SET LOCAL session_replication_role = origin`); err != nil {
+		return fmt.Errorf("enable triggers: %w", err)
+	}
+	if _, err := tx.Exec(ctx, `UPDATE links SET title = title WHERE id = ANY($1)`, linkIDs); err != nil {
+		return fmt.Errorf("build search index: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("commit: %w", err)
+	}
+	if _, err := pool.Exec(ctx, `ANALYZE users, links, archives, tags, link_tags, highlights, link_ingest_status`); err != nil {
+		return fmt.Errorf("analyze: %w", err)
+	}
+	return nil
+}
+
+// tagKey names one user's tag.
+type tagKey struct {
+	userID uuid.UUID
+	name   string
+}
+
+// loadTags gives every user a tag of each name and returns their IDs.
+func loadTags(ctx context.Context, tx pgx.Tx, users []User, names []string) (map[tagKey]int32, error) {
+	userIDs := make([]uuid.UUID, len(users))
+	for i, user := range users {
+		userIDs[i] = user.ID
+	}
+	if _, err := tx.Exec(ctx, `INSERT INTO tags (user_id, name)
+SELECT u, n FROM unnest($1::uuid[]) AS u CROSS JOIN unnest($2::text[]) AS n
+ON CONFLICT (user_id, name) DO NOTHING`, userIDs, names); err != nil {
+		return nil, fmt.Errorf("insert tags: %w", err)
+	}
+	rows, err := tx.Query(ctx, `SELECT id, user_id, name FROM tags WHERE user_id = ANY($1) AND name = ANY($2)`, userIDs, names)
+	if err != nil {
+		return nil, fmt.Errorf("look up tags: %w", err)
+	}
+	defer rows.Close()
+
+	ids := make(map[tagKey]int32, len(users)*len(names))
+	for rows.Next() {
+		var id int32
+		var key tagKey
+		if err := rows.Scan(&id, &key.userID, &key.name); err != nil {
+			return nil, fmt.Errorf("scan tag: %w", err)
+		}
+		ids[key] = id
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("look up tags: %w", err)
+	}
+	return ids, nil
+}
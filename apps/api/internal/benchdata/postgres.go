@@ -0,0 +1,38 @@
+package benchdata
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/example/keepstack/testenv"
+)
+
+// Postgres starts a migrated Postgres container through testenv, loads the
+// dataset ConfigFromEnv describes, and returns a pool connected to it. Call
+// it from the outer benchmark and measure in sub-benchmarks: those rerun
+// with a growing b.N, while the outer one runs once, so the container and
+// data are set up once per benchmark.
+func Postgres(tb testing.TB) (*pgxpool.Pool, Dataset) {
+	tb.Helper()
+
+	cfg, err := ConfigFromEnv()
+	if err != nil {
+		tb.Fatal(err)
+	}
+	databaseURL := testenv.Postgres(tb)
+
+	ctx := context.Background()
+	pool, err := pgxpool.New(ctx, databaseURL)
+	if err != nil {
+		tb.Fatalf("benchdata: connect postgres: %v", err)
+	}
+	tb.Cleanup(pool.Close)
+
+	dataset := Generate(cfg)
+	if err := Load(ctx, pool, dataset); err != nil {
+		tb.Fatalf("benchdata: load %d link(s): %v", len(dataset.Links), err)
+	}
+	return pool, dataset
+}
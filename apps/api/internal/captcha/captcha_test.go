@@ -0,0 +1,70 @@
+package captcha
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestVerifySendsSecretAndResponse(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		ok := r.PostForm.Get("secret") == "shh" && r.PostForm.Get("response") == "good" && r.PostForm.Get("remoteip") == "203.0.113.7"
+		fmt.Fprintf(w, `{"success":%t}`, ok)
+	}))
+	t.Cleanup(server.Close)
+
+	verifier, err := New(ProviderTurnstile, "shh", server.Client())
+	if err != nil {
+		t.Fatalf("new verifier: %v", err)
+	}
+	verifier.endpoint = server.URL
+
+	if err := verifier.Verify(context.Background(), "good", "203.0.113.7"); err != nil {
+		t.Fatalf("expected the response to pass, got %v", err)
+	}
+	for _, response := range []string{"bad", ""} {
+		if err := verifier.Verify(context.Background(), response, "203.0.113.7"); !errors.Is(err, ErrRejected) {
+			t.Fatalf("response %q: expected ErrRejected, got %v", response, err)
+		}
+	}
+}
+
+func TestVerifyReportsProviderFailures(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	t.Cleanup(server.Close)
+
+	verifier, err := New(ProviderHCaptcha, "shh", server.Client())
+	if err != nil {
+		t.Fatalf("new verifier: %v", err)
+	}
+	verifier.endpoint = server.URL
+
+	err = verifier.Verify(context.Background(), "token", "")
+	if err == nil || errors.Is(err, ErrRejected) {
+		t.Fatalf("expected an availability error, got %v", err)
+	}
+}
+
+func TestNewRejectsUnknownProvidersAndMissingSecrets(t *testing.T) {
+	t.Parallel()
+
+	if _, err := New("friendly", "shh", http.DefaultClient); err == nil {
+		t.Fatalf("expected an unknown provider to be rejected")
+	}
+	if _, err := New(ProviderReCAPTCHA, "", http.DefaultClient); err == nil {
+		t.Fatalf("expected a missing secret to be rejected")
+	}
+}
@@ -2,28 +2,274 @@ package config
 
 import (
     "fmt"
+    "regexp"
+    "strings"
+    "time"
 
     "github.com/kelseyhightower/envconfig"
     "github.com/google/uuid"
+
+    "github.com/example/keepstack/apps/api/internal/captcha"
+    "github.com/example/keepstack/apps/api/internal/tlsconfig"
+    "github.com/example/keepstack/media"
+    "github.com/example/keepstack/sanitize"
 )
 
 const defaultDevUserID = "00000000-0000-0000-0000-000000000001"
 
+// minJWTSecretLength is the shortest JWT_SECRET accepted: 32 bytes, the
+// output size of the HS256 hash.
+const minJWTSecretLength = 32
+
+// Rate limit backends for HIGHLIGHT_RATE_LIMIT_BACKEND.
+const (
+    RateLimitBackendMemory   = "memory"
+    RateLimitBackendPostgres = "postgres"
+)
+
+// trackingParamRulePattern matches a TRACKING_PARAMS_* entry: a parameter
+// name or prefix ending in *, optionally scoped to a domain.
+var trackingParamRulePattern = regexp.MustCompile(`^([a-z0-9-]+(\.[a-z0-9-]+)*:)?[^\s:*=&]+\*?$`)
+
 // Config captures runtime configuration for the API service.
 type Config struct {
-    DatabaseURL string    `envconfig:"DATABASE_URL" required:"true"`
-    NATSURL     string    `envconfig:"NATS_URL" required:"true"`
+    // DatabaseURL and NATSURL are required unless MemoryMode is set.
+    DatabaseURL string    `envconfig:"DATABASE_URL" default:""`
+    // DatabaseReplicaURL optionally points list, search, and count queries at
+    // a read replica. Reads fall back to the primary while it is unreachable.
+    DatabaseReplicaURL string `envconfig:"DATABASE_REPLICA_URL" default:""`
+    NATSURL     string    `envconfig:"NATS_URL" default:""`
     Port        int       `envconfig:"PORT" default:"8080"`
+    // HTTPListen lists addresses, comma separated, the HTTP server listens on
+    // instead of PORT: TCP addresses such as "127.0.0.1:8080" or "[::1]:8080"
+    // and Unix sockets such as "unix:/run/keepstack/api.sock".
+    HTTPListen  []string  `envconfig:"HTTP_LISTEN" default:""`
+    // GRPCPort serves the internal ingest service the worker reports status
+    // through. Zero disables it.
+    GRPCPort    int       `envconfig:"GRPC_PORT" default:"9090"`
     DevUserID   uuid.UUID `env:"-"`
     DevUserRaw  string    `envconfig:"DEV_USER_ID" default:""`
+    // DevMode creates the DevUserID user and a demo tag at startup when they
+    // are missing, so a fresh local database needs no manual setup.
+    DevMode     bool      `envconfig:"DEV_MODE" default:"false"`
+    // MemoryMode serves the API from an in-memory store with no database or
+    // NATS, for frontend development. Data is lost on restart. MemorySeed
+    // names the seed dataset it starts with; empty starts it empty.
+    MemoryMode  bool      `envconfig:"MEMORY_MODE" default:"false"`
+    MemorySeed  string    `envconfig:"MEMORY_SEED" default:"dev"`
+
+    // ResurfacerLimit caps recommendations written by on-demand refreshes.
+    ResurfacerLimit int `envconfig:"RESURFACER_LIMIT" default:"20"`
+    // ResurfacerCooldownDays skips links surfaced within this many days when
+    // refreshing recommendations on demand.
+    ResurfacerCooldownDays int `envconfig:"RESURFACER_COOLDOWN_DAYS" default:"0"`
+    // RecommendationRefreshThreshold is how many links must be marked read in
+    // one request before a recommendation refresh is queued. Zero disables it.
+    RecommendationRefreshThreshold int `envconfig:"RECOMMENDATION_REFRESH_THRESHOLD" default:"10"`
+    // HealthBackupMaxAge flags /healthz as degraded when the newest successful
+    // backup is older than this. Zero disables the check.
+    HealthBackupMaxAge time.Duration `envconfig:"HEALTH_BACKUP_MAX_AGE" default:"0"`
+    // DBSlowQueryThreshold logs database queries that take at least this long.
+    // Zero disables slow query logging; durations are always recorded.
+    DBSlowQueryThreshold time.Duration `envconfig:"DB_SLOW_QUERY_THRESHOLD" default:"500ms"`
+    // Connection pool settings. Zero keeps the pgxpool default.
+    DBMaxConns          int32         `envconfig:"DB_MAX_CONNS" default:"0"`
+    DBMinConns          int32         `envconfig:"DB_MIN_CONNS" default:"0"`
+    DBMaxConnLifetime   time.Duration `envconfig:"DB_MAX_CONN_LIFETIME" default:"0"`
+    DBMaxConnIdleTime   time.Duration `envconfig:"DB_MAX_CONN_IDLE_TIME" default:"0"`
+    DBHealthCheckPeriod time.Duration `envconfig:"DB_HEALTH_CHECK_PERIOD" default:"0"`
+    // DBStatementTimeout makes Postgres cancel any single statement that runs
+    // longer, so one slow query cannot hold a pooled connection. Zero leaves
+    // the server default.
+    DBStatementTimeout time.Duration `envconfig:"DB_STATEMENT_TIMEOUT" default:"10s"`
+
+    // HTTPCompressionLevel is the gzip level (1-9, or -1 for the library
+    // default) used for API responses. Zero disables compression.
+    HTTPCompressionLevel int `envconfig:"HTTP_COMPRESSION_LEVEL" default:"5"`
+    // HTTPMaxBodyBytes caps request bodies; larger requests get a 413. Zero
+    // disables the limit.
+    HTTPMaxBodyBytes int64 `envconfig:"HTTP_MAX_BODY_BYTES" default:"1048576"`
+    // HTTPStrictJSON rejects JSON bodies carrying fields the endpoint does
+    // not accept with a 422 instead of ignoring them.
+    HTTPStrictJSON bool `envconfig:"HTTP_STRICT_JSON" default:"false"`
+    // HTTPRequestTimeout bounds how long a request may spend in its handler;
+    // queries still running are cancelled and the client gets a 504. Live
+    // event streams and exports are exempt. Zero disables it.
+    HTTPRequestTimeout time.Duration `envconfig:"HTTP_REQUEST_TIMEOUT" default:"30s"`
+
+    // CORSAllowedOrigins lists origins, comma separated, that browsers may
+    // call the API from, e.g. the web app host or a chrome-extension:// URL.
+    // Empty disables CORS.
+    CORSAllowedOrigins []string `envconfig:"CORS_ALLOWED_ORIGINS" default:""`
+    // ExtensionTokens lists bearer tokens, comma separated, accepted by the
+    // /api/ext endpoints browser extensions call. Empty disables them. With
+    // AuthSessions the endpoints take session tokens instead.
+    ExtensionTokens []string `envconfig:"EXTENSION_TOKENS" default:""`
+    // MicropubTokens lists bearer tokens, comma separated, accepted by the
+    // /api/micropub endpoint. Empty disables it. With AuthSessions the
+    // endpoint takes session tokens instead.
+    MicropubTokens []string `envconfig:"MICROPUB_TOKENS" default:""`
+    // AdminTokens lists bearer tokens, comma separated, required on the
+    // /api/admin endpoints keepstackctl calls. Empty leaves the read-only
+    // admin endpoints open and disables the rest.
+    AdminTokens []string `envconfig:"ADMIN_TOKENS" default:""`
+    // AuthSessions makes the API's own routes require a session token from
+    // /api/auth/login and act as its user. Without it every request is
+    // attributed to DEV_USER_ID.
+    AuthSessions bool `envconfig:"AUTH_SESSIONS" default:"false"`
+    // JWTSecret signs session tokens. Required with AuthSessions.
+    JWTSecret string `envconfig:"JWT_SECRET" default:""`
+    // AuthTokenTTL is how long a session token stays valid.
+    AuthTokenTTL time.Duration `envconfig:"AUTH_TOKEN_TTL" default:"24h"`
+    // AuthSignup lets anyone create an account through /api/auth/signup.
+    // Without it accounts are created through /api/admin/users.
+    AuthSignup bool `envconfig:"AUTH_SIGNUP" default:"false"`
+    // ActivityPubBaseURL is the public https origin remote servers reach the
+    // API at, e.g. https://keepstack.example.com. Empty disables ActivityPub.
+    ActivityPubBaseURL string `envconfig:"ACTIVITYPUB_BASE_URL" default:""`
+    // ActivityPubPrivateKey is the PEM-encoded RSA key federation requests
+    // are signed with. Required when ActivityPubBaseURL is set.
+    ActivityPubPrivateKey string `envconfig:"ACTIVITYPUB_PRIVATE_KEY" default:""`
+    // PublicInboxUserRaw is the id of the user anonymous suggestions posted
+    // to /api/public/inbox are saved for. Empty disables the endpoint.
+    PublicInboxUserRaw string    `envconfig:"PUBLIC_INBOX_USER_ID" default:""`
+    PublicInboxUserID  uuid.UUID `ignored:"true"`
+    // PublicInboxTag is added to every anonymous suggestion. Empty adds none.
+    PublicInboxTag string `envconfig:"PUBLIC_INBOX_TAG" default:"suggested"`
+    // PublicInboxClientPerHour and PublicInboxPerHour cap suggestions per
+    // client address and across all clients.
+    PublicInboxClientPerHour int `envconfig:"PUBLIC_INBOX_CLIENT_PER_HOUR" default:"5"`
+    PublicInboxPerHour       int `envconfig:"PUBLIC_INBOX_PER_HOUR" default:"60"`
+    // PublicInboxCaptchaProvider requires a solved captcha with every
+    // suggestion: "hcaptcha", "turnstile", or "recaptcha". Empty requires
+    // none.
+    PublicInboxCaptchaProvider string `envconfig:"PUBLIC_INBOX_CAPTCHA_PROVIDER" default:""`
+    PublicInboxCaptchaSecret   string `envconfig:"PUBLIC_INBOX_CAPTCHA_SECRET" default:""`
+    // PublicInboxCaptchaSiteKey is handed to kiosk pages so they can render
+    // the provider's widget.
+    PublicInboxCaptchaSiteKey string `envconfig:"PUBLIC_INBOX_CAPTCHA_SITE_KEY" default:""`
+    // UnfurlTimeout bounds how long GET /api/unfurl waits for a worker to
+    // fetch and parse a page.
+    UnfurlTimeout time.Duration `envconfig:"UNFURL_TIMEOUT" default:"20s"`
+    // UnfurlCacheTTL and UnfurlCacheSize bound the per-pod cache of unfurl
+    // results. A zero TTL or size disables caching.
+    UnfurlCacheTTL  time.Duration `envconfig:"UNFURL_CACHE_TTL" default:"1h"`
+    UnfurlCacheSize int           `envconfig:"UNFURL_CACHE_SIZE" default:"1000"`
+    // TrackingParamsStrip lists extra query parameters, comma separated, that
+    // URL normalization drops before links are stored and deduplicated:
+    // "share_id" everywhere, "amazon.com:tag" on a domain and its
+    // subdomains, or "pk_*" for every parameter with a prefix.
+    TrackingParamsStrip []string `envconfig:"TRACKING_PARAMS_STRIP" default:""`
+    // TrackingParamsKeep lists parameters, in the same form, that are kept
+    // even though the built-in list drops them, e.g. "github.com:ref".
+    TrackingParamsKeep []string `envconfig:"TRACKING_PARAMS_KEEP" default:""`
+    // SanitizeFeatures, SanitizeImageProxy, and SanitizeEmbedHosts select
+    // what archived HTML keeps beyond text, as the worker's settings of the
+    // same names do. The reader page sanitizes with them again, and
+    // archive-resanitize applies them to archives stored under older
+    // settings.
+    SanitizeFeatures   []string `envconfig:"SANITIZE_FEATURES" default:"tables,code,images"`
+    SanitizeImageProxy string   `envconfig:"SANITIZE_IMAGE_PROXY" default:""`
+    SanitizeEmbedHosts []string `envconfig:"SANITIZE_EMBED_HOSTS" default:""`
+    // JobRetention is how long finished import, export, and reparse jobs,
+    // and the files they produced, are kept for clients to fetch.
+    JobRetention time.Duration `envconfig:"JOB_RETENTION" default:"24h"`
+    // MediaStorage and the MEDIA_* settings after it name the "dir" or "s3"
+    // storage the worker mirrors archived images to, which GET
+    // /api/media/:hash serves. Empty disables the endpoint.
+    MediaStorage     string `envconfig:"MEDIA_STORAGE" default:""`
+    MediaDir         string `envconfig:"MEDIA_DIR" default:""`
+    MediaS3Bucket    string `envconfig:"MEDIA_S3_BUCKET" default:""`
+    MediaS3AccessKey string `envconfig:"MEDIA_S3_ACCESS_KEY" default:""`
+    MediaS3SecretKey string `envconfig:"MEDIA_S3_SECRET_KEY" default:""`
+    MediaS3Region    string `envconfig:"MEDIA_S3_REGION" default:"us-east-1"`
+    MediaS3Endpoint  string `envconfig:"MEDIA_S3_ENDPOINT" default:""`
+    MediaS3Prefix    string `envconfig:"MEDIA_S3_PREFIX" default:""`
+    // ContentSecurityPolicy is sent on every response. Empty omits it.
+    ContentSecurityPolicy string `envconfig:"CONTENT_SECURITY_POLICY" default:"default-src 'none'; frame-ancestors 'none'"`
+    // HSTSMaxAge sets Strict-Transport-Security, in seconds, on HTTPS
+    // requests. Zero omits it.
+    HSTSMaxAge int `envconfig:"HSTS_MAX_AGE" default:"0"`
+
+    // TLSCertFile and TLSKeyFile make the HTTP server speak HTTPS with a PEM
+    // certificate and key, re-read when they change. Empty serves plain HTTP,
+    // for deployments where a proxy or ingress terminates TLS.
+    TLSCertFile string `envconfig:"TLS_CERT_FILE" default:""`
+    TLSKeyFile  string `envconfig:"TLS_KEY_FILE" default:""`
+    // TLSAutocertDomains lists hosts, comma separated, to obtain certificates
+    // for from an ACME CA instead of reading files. Issued certificates are
+    // kept in TLSAutocertCacheDir.
+    TLSAutocertDomains      []string `envconfig:"TLS_AUTOCERT_DOMAINS" default:""`
+    TLSAutocertCacheDir     string   `envconfig:"TLS_AUTOCERT_CACHE_DIR" default:""`
+    TLSAutocertEmail        string   `envconfig:"TLS_AUTOCERT_EMAIL" default:""`
+    TLSAutocertDirectoryURL string   `envconfig:"TLS_AUTOCERT_DIRECTORY_URL" default:""`
+    // TLSHTTPListen lists plain HTTP addresses, e.g. ":80", that redirect to
+    // HTTPS and answer ACME HTTP-01 challenges. Requires TLS.
+    TLSHTTPListen []string `envconfig:"TLS_HTTP_LISTEN" default:""`
+
+    // StartupGate starts serving before the database, NATS, and migrations
+    // are available: /livez answers ok, /healthz reports starting, and other
+    // routes answer 503 until every dependency is up. Without it the API
+    // listens only once connected.
+    StartupGate bool `envconfig:"STARTUP_GATE" default:"false"`
+
+    // ShutdownDrainDelay is how long the API keeps serving after SIGTERM with
+    // /healthz reporting draining, giving load balancers time to stop routing
+    // new requests to the pod.
+    ShutdownDrainDelay time.Duration `envconfig:"SHUTDOWN_DRAIN_DELAY" default:"5s"`
+    // ShutdownTimeout bounds how long in-flight requests and queue handlers
+    // may take to finish once the listener closes.
+    ShutdownTimeout time.Duration `envconfig:"SHUTDOWN_TIMEOUT" default:"20s"`
+
+    // Rate limits are token buckets per client and route class, expressed as
+    // requests per second with a burst allowance. A zero rate disables the
+    // class.
+    RateLimitReadRPS    float64 `envconfig:"RATE_LIMIT_READ_RPS" default:"20"`
+    RateLimitReadBurst  int     `envconfig:"RATE_LIMIT_READ_BURST" default:"40"`
+    RateLimitWriteRPS   float64 `envconfig:"RATE_LIMIT_WRITE_RPS" default:"5"`
+    RateLimitWriteBurst int     `envconfig:"RATE_LIMIT_WRITE_BURST" default:"20"`
+    RateLimitAdminRPS   float64 `envconfig:"RATE_LIMIT_ADMIN_RPS" default:"1"`
+    RateLimitAdminBurst int     `envconfig:"RATE_LIMIT_ADMIN_BURST" default:"5"`
+    // HighlightRateLimitBackend selects where per-user highlight buckets,
+    // and the public inbox's buckets, live: "memory" keeps them per pod,
+    // "postgres" shares them across replicas.
+    HighlightRateLimitBackend string `envconfig:"HIGHLIGHT_RATE_LIMIT_BACKEND" default:"memory"`
+    // QuotaLinksPerDay and QuotaHighlightsPerDay cap how many links and
+    // highlights each user may create per UTC day, independent of the burst
+    // limits above. Zero leaves that kind unlimited.
+    QuotaLinksPerDay      int `envconfig:"QUOTA_LINKS_PER_DAY" default:"0"`
+    QuotaHighlightsPerDay int `envconfig:"QUOTA_HIGHLIGHTS_PER_DAY" default:"0"`
+    // PreserveUserTitles keeps a title given at save time instead of letting
+    // ingestion replace it with the extracted one, unless the save says
+    // otherwise with preserve_title.
+    PreserveUserTitles bool `envconfig:"PRESERVE_USER_TITLES" default:"false"`
+
+    // SentryDSN enables error reporting for panics and 5xx responses. Empty
+    // disables it.
+    SentryDSN string `envconfig:"SENTRY_DSN" default:""`
+    // SentryEnvironment tags reported events, e.g. "production".
+    SentryEnvironment string `envconfig:"SENTRY_ENVIRONMENT" default:""`
 }
 
-// Load reads configuration values from the environment.
+// Load reads configuration values from the environment, after filling it
+// from any secret files or Vault (see LoadSecrets).
 func Load() (Config, error) {
+    if err := LoadSecrets(); err != nil {
+        return Config{}, fmt.Errorf("load secrets: %w", err)
+    }
+
     var cfg Config
     if err := envconfig.Process("", &cfg); err != nil {
         return Config{}, fmt.Errorf("load config: %w", err)
     }
+    if !cfg.MemoryMode {
+        if cfg.DatabaseURL == "" {
+            return Config{}, fmt.Errorf("load config: required key DATABASE_URL missing value")
+        }
+        if cfg.NATSURL == "" {
+            return Config{}, fmt.Errorf("load config: required key NATS_URL missing value")
+        }
+    }
 
     raw := cfg.DevUserRaw
     if raw == "" {
@@ -35,6 +281,79 @@ func Load() (Config, error) {
         return Config{}, fmt.Errorf("parse dev user id: %w", err)
     }
     cfg.DevUserID = id
+
+    switch cfg.HighlightRateLimitBackend {
+    case RateLimitBackendMemory, RateLimitBackendPostgres:
+    default:
+        return Config{}, fmt.Errorf("HIGHLIGHT_RATE_LIMIT_BACKEND must be %q or %q", RateLimitBackendMemory, RateLimitBackendPostgres)
+    }
+
+    if cfg.QuotaLinksPerDay < 0 || cfg.QuotaHighlightsPerDay < 0 {
+        return Config{}, fmt.Errorf("QUOTA_LINKS_PER_DAY and QUOTA_HIGHLIGHTS_PER_DAY must not be negative")
+    }
+
+    for _, rule := range append(append([]string{}, cfg.TrackingParamsStrip...), cfg.TrackingParamsKeep...) {
+        if !trackingParamRulePattern.MatchString(strings.ToLower(strings.TrimSpace(rule))) {
+            return Config{}, fmt.Errorf("TRACKING_PARAMS_STRIP and TRACKING_PARAMS_KEEP entries must be a parameter, optionally ending in * and prefixed with domain:, got %q", rule)
+        }
+    }
+
+    if err := cfg.Sanitize().Validate(); err != nil {
+        return Config{}, fmt.Errorf("SANITIZE_* settings: %w", err)
+    }
+    if cfg.JobRetention <= 0 {
+        return Config{}, fmt.Errorf("JOB_RETENTION must be positive")
+    }
+    if err := cfg.Media().Validate(); err != nil {
+        return Config{}, fmt.Errorf("MEDIA_* settings: %w", err)
+    }
+
+    if cfg.AuthSessions {
+        if len(cfg.JWTSecret) < minJWTSecretLength {
+            return Config{}, fmt.Errorf("JWT_SECRET must be at least %d characters when AUTH_SESSIONS is set", minJWTSecretLength)
+        }
+        if cfg.AuthTokenTTL <= 0 {
+            return Config{}, fmt.Errorf("AUTH_TOKEN_TTL must be positive")
+        }
+    } else if cfg.AuthSignup {
+        return Config{}, fmt.Errorf("AUTH_SIGNUP requires AUTH_SESSIONS")
+    }
+
+    cfg.ActivityPubBaseURL = strings.TrimRight(cfg.ActivityPubBaseURL, "/")
+    if cfg.ActivityPubBaseURL != "" {
+        if !strings.HasPrefix(cfg.ActivityPubBaseURL, "https://") {
+            return Config{}, fmt.Errorf("ACTIVITYPUB_BASE_URL must be an https URL")
+        }
+        if cfg.ActivityPubPrivateKey == "" {
+            return Config{}, fmt.Errorf("ACTIVITYPUB_PRIVATE_KEY is required when ACTIVITYPUB_BASE_URL is set")
+        }
+    }
+
+    if err := cfg.TLS().Validate(); err != nil {
+        return Config{}, err
+    }
+    if len(cfg.TLSHTTPListen) > 0 && !cfg.TLS().Enabled() {
+        return Config{}, fmt.Errorf("TLS_HTTP_LISTEN requires TLS_CERT_FILE or TLS_AUTOCERT_DOMAINS")
+    }
+
+    if cfg.PublicInboxUserRaw != "" {
+        inboxUser, err := uuid.Parse(cfg.PublicInboxUserRaw)
+        if err != nil {
+            return Config{}, fmt.Errorf("parse public inbox user id: %w", err)
+        }
+        cfg.PublicInboxUserID = inboxUser
+        if cfg.PublicInboxClientPerHour <= 0 || cfg.PublicInboxPerHour <= 0 {
+            return Config{}, fmt.Errorf("PUBLIC_INBOX_CLIENT_PER_HOUR and PUBLIC_INBOX_PER_HOUR must be positive")
+        }
+    }
+    if cfg.PublicInboxCaptchaProvider != "" {
+        if !captcha.Valid(cfg.PublicInboxCaptchaProvider) {
+            return Config{}, fmt.Errorf("PUBLIC_INBOX_CAPTCHA_PROVIDER must be %q, %q, or %q", captcha.ProviderHCaptcha, captcha.ProviderTurnstile, captcha.ProviderReCAPTCHA)
+        }
+        if cfg.PublicInboxCaptchaSecret == "" {
+            return Config{}, fmt.Errorf("PUBLIC_INBOX_CAPTCHA_SECRET is required when PUBLIC_INBOX_CAPTCHA_PROVIDER is set")
+        }
+    }
     return cfg, nil
 }
 
@@ -42,3 +361,52 @@ func Load() (Config, error) {
 func (c Config) Address() string {
     return fmt.Sprintf(":%d", c.Port)
 }
+
+// TLS returns the options the HTTP server's TLS configuration is built from.
+func (c Config) TLS() tlsconfig.Options {
+    return tlsconfig.Options{
+        CertFile:             c.TLSCertFile,
+        KeyFile:              c.TLSKeyFile,
+        AutocertDomains:      c.TLSAutocertDomains,
+        AutocertCacheDir:     c.TLSAutocertCacheDir,
+        AutocertEmail:        c.TLSAutocertEmail,
+        AutocertDirectoryURL: c.TLSAutocertDirectoryURL,
+    }
+}
+
+// Sanitize returns the options archived HTML is sanitized with.
+func (c Config) Sanitize() sanitize.Options {
+    return sanitize.Options{
+        Features:   c.SanitizeFeatures,
+        ImageProxy: c.SanitizeImageProxy,
+        EmbedHosts: c.SanitizeEmbedHosts,
+    }
+}
+
+// Media returns the storage archived images are served from.
+func (c Config) Media() media.Options {
+    return media.Options{
+        Storage:     c.MediaStorage,
+        Dir:         c.MediaDir,
+        S3Bucket:    c.MediaS3Bucket,
+        S3AccessKey: c.MediaS3AccessKey,
+        S3SecretKey: c.MediaS3SecretKey,
+        S3Region:    c.MediaS3Region,
+        S3Endpoint:  c.MediaS3Endpoint,
+        S3Prefix:    c.MediaS3Prefix,
+    }
+}
+
+// ListenAddresses returns the addresses the HTTP server listens on:
+// HTTPListen when set, otherwise Address.
+func (c Config) ListenAddresses() []string {
+    if len(c.HTTPListen) > 0 {
+        return c.HTTPListen
+    }
+    return []string{c.Address()}
+}
+
+// GRPCAddress returns the TCP listen address for the internal gRPC server.
+func (c Config) GRPCAddress() string {
+    return fmt.Sprintf(":%d", c.GRPCPort)
+}
@@ -0,0 +1,44 @@
+package config
+
+import (
+	"context"
+	"sync"
+
+	"github.com/example/keepstack/secrets"
+)
+
+// secretVariables may be read from the file named by the same variable with
+// a _FILE suffix, e.g. DATABASE_URL_FILE, so Kubernetes Secrets can be
+// mounted as files instead of exposed in the environment.
+var secretVariables = []string{
+	"DATABASE_URL",
+	"DATABASE_REPLICA_URL",
+	"NATS_URL",
+	"SMTP_URL",
+	"ADMIN_TOKENS",
+	"EXTENSION_TOKENS",
+	"MICROPUB_TOKENS",
+	"JWT_SECRET",
+	"ACTIVITYPUB_PRIVATE_KEY",
+	"PUBLIC_INBOX_CAPTCHA_SECRET",
+	"SENTRY_DSN",
+	"BACKUP_S3_ACCESS_KEY",
+	"BACKUP_S3_SECRET_KEY",
+	"BACKUP_NOTIFY_URL",
+}
+
+var (
+	secretsOnce sync.Once
+	secretsErr  error
+)
+
+// LoadSecrets fills unset environment variables from secret files, a
+// dotenv file, or Vault, as secrets.Load describes. Load calls LoadSecrets;
+// binaries that read the environment before loading configuration call it
+// first. Only the first call does any work.
+func LoadSecrets() error {
+	secretsOnce.Do(func() {
+		secretsErr = secrets.Load(context.Background(), secretVariables)
+	})
+	return secretsErr
+}
@@ -0,0 +1,64 @@
+// Package cronlock keeps cron subcommands from running concurrently across
+// pods by holding a Postgres session-level advisory lock for the run.
+package cronlock
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// ErrHeld is returned when another run already holds the lock.
+var ErrHeld = errors.New("lock held by another run")
+
+// Lock is an acquired advisory lock. It stays held until Release is called
+// or the connection drops, so a crashed pod never leaves it stuck.
+type Lock struct {
+	conn *pgx.Conn
+	key  int64
+}
+
+// Key derives the advisory lock key for a named job.
+func Key(name string) int64 {
+	h := fnv.New64a()
+	h.Write([]byte("keepstack-cron:" + name))
+	return int64(h.Sum64())
+}
+
+// Acquire takes the lock for name without waiting. It returns ErrHeld when
+// another session holds it.
+func Acquire(ctx context.Context, databaseURL, name string) (*Lock, error) {
+	conn, err := pgx.Connect(ctx, databaseURL)
+	if err != nil {
+		return nil, fmt.Errorf("connect for advisory lock: %w", err)
+	}
+
+	key := Key(name)
+	var acquired bool
+	if err := conn.QueryRow(ctx, "SELECT pg_try_advisory_lock($1)", key).Scan(&acquired); err != nil {
+		conn.Close(ctx)
+		return nil, fmt.Errorf("acquire advisory lock: %w", err)
+	}
+	if !acquired {
+		conn.Close(ctx)
+		return nil, ErrHeld
+	}
+
+	return &Lock{conn: conn, key: key}, nil
+}
+
+// Release unlocks and closes the lock connection.
+func (l *Lock) Release(ctx context.Context) error {
+	if l == nil {
+		return nil
+	}
+	defer l.conn.Close(ctx)
+
+	if _, err := l.conn.Exec(ctx, "SELECT pg_advisory_unlock($1)", l.key); err != nil {
+		return fmt.Errorf("release advisory lock: %w", err)
+	}
+	return nil
+}
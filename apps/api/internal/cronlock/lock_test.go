@@ -0,0 +1,12 @@
+package cronlock
+
+import "testing"
+
+func TestKeyIsStablePerSubcommand(t *testing.T) {
+	if Key("digest") != Key("digest") {
+		t.Fatalf("expected the same key for the same subcommand")
+	}
+	if Key("digest") == Key("backup") {
+		t.Fatalf("expected different subcommands to use different keys")
+	}
+}
@@ -0,0 +1,95 @@
+//go:build integration
+
+package db_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"github.com/example/keepstack/apps/api/internal/benchdata"
+	"github.com/example/keepstack/apps/api/internal/db"
+)
+
+// BenchmarkListLinks pages through one user's library the way the web app
+// does: the first page, a page deep in the list, and favorite and tag
+// filters.
+func BenchmarkListLinks(b *testing.B) {
+	pool, dataset := benchdata.Postgres(b)
+	queries := db.New(pool)
+	user := pgtype.UUID{Bytes: dataset.Users[0].ID, Valid: true}
+	tagID := tagIDByName(b, queries, user, dataset.Tags[0])
+
+	cases := []struct {
+		name   string
+		params db.ListLinksParams
+	}{
+		{"first-page", db.ListLinksParams{UserID: user, PageLimit: 50}},
+		{"deep-page", db.ListLinksParams{UserID: user, PageLimit: 50, PageOffset: int32(dataset.Config.LinksPerUser / 2)}},
+		{"favorites", db.ListLinksParams{UserID: user, Favorite: pgtype.Bool{Bool: true, Valid: true}, PageLimit: 50}},
+	}
+	for _, tc := range cases {
+		b.Run(tc.name, func(b *testing.B) {
+			for b.Loop() {
+				if _, err := queries.ListLinks(context.Background(), tc.params); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+	b.Run("tag", func(b *testing.B) {
+		params := db.ListLinksWithTagsParams{UserID: user, TagIds: []int32{tagID}, PageLimit: 50}
+		for b.Loop() {
+			if _, err := queries.ListLinksWithTags(context.Background(), params); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+// BenchmarkSearchLinks runs the list and count queries a search issues, for
+// a term in nearly every link, a term in few, a term in none, and the URL
+// substring fallback used when the full-text query does not parse.
+func BenchmarkSearchLinks(b *testing.B) {
+	pool, dataset := benchdata.Postgres(b)
+	queries := db.New(pool)
+	user := pgtype.UUID{Bytes: dataset.Users[0].ID, Valid: true}
+
+	cases := []struct {
+		name     string
+		query    string
+		fullText bool
+	}{
+		{"common-term", benchdata.CommonTerm, true},
+		{"rare-term", benchdata.RareTerm, true},
+		{"no-match", "zeitgeist", true},
+		{"url-substring", "docs.example", false},
+	}
+	for _, tc := range cases {
+		b.Run(tc.name, func(b *testing.B) {
+			query := pgtype.Text{String: tc.query, Valid: true}
+			for b.Loop() {
+				if _, err := queries.ListLinks(context.Background(), db.ListLinksParams{
+					UserID: user, Query: query, EnableFullText: tc.fullText, PageLimit: 50,
+				}); err != nil {
+					b.Fatal(err)
+				}
+				if _, err := queries.CountLinks(context.Background(), db.CountLinksParams{
+					UserID: user, Query: query, EnableFullText: tc.fullText,
+				}); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func tagIDByName(b *testing.B, queries *db.Queries, user pgtype.UUID, name string) int32 {
+	b.Helper()
+	tag, err := queries.GetTagByName(context.Background(), db.GetTagByNameParams{UserID: user, Name: name})
+	if err != nil {
+		b.Fatalf("look up tag %q: %v", name, err)
+	}
+	return tag.ID
+}
@@ -13,19 +13,24 @@ import (
 
 const createClaim = `-- name: CreateClaim :one
 WITH upsert AS (
-    INSERT INTO claims (link_id, user_id)
-    VALUES ($1, $2)
+    INSERT INTO claims (link_id, user_id, expires_at)
+    VALUES ($1, $2, $3)
     ON CONFLICT (link_id, user_id) DO UPDATE
-        SET claimed_at = claims.claimed_at
-    RETURNING id, link_id, user_id, claimed_at, xmax = 0 AS inserted
+        SET claimed_at = CASE
+                WHEN claims.expires_at IS NOT NULL AND claims.expires_at <= NOW() THEN NOW()
+                ELSE claims.claimed_at
+            END,
+            expires_at = EXCLUDED.expires_at
+    RETURNING id, link_id, user_id, claimed_at, expires_at, xmax = 0 AS inserted
 )
-SELECT id, link_id, user_id, claimed_at, inserted
+SELECT id, link_id, user_id, claimed_at, expires_at, inserted
 FROM upsert
 `
 
 type CreateClaimParams struct {
-	LinkID pgtype.UUID
-	UserID pgtype.UUID
+	LinkID    pgtype.UUID
+	UserID    pgtype.UUID
+	ExpiresAt pgtype.Timestamptz
 }
 
 type CreateClaimRow struct {
@@ -33,18 +38,96 @@ type CreateClaimRow struct {
 	LinkID    pgtype.UUID
 	UserID    pgtype.UUID
 	ClaimedAt pgtype.Timestamptz
+	ExpiresAt pgtype.Timestamptz
 	Inserted  bool
 }
 
+// CreateClaim claims a link for a user. Claiming it again replaces the
+// expiry, and a claim that had already expired starts over from now.
 func (q *Queries) CreateClaim(ctx context.Context, arg CreateClaimParams) (CreateClaimRow, error) {
-	row := q.db.QueryRow(ctx, createClaim, arg.LinkID, arg.UserID)
+	row := q.db.QueryRow(ctx, createClaim, arg.LinkID, arg.UserID, arg.ExpiresAt)
 	var i CreateClaimRow
 	err := row.Scan(
 		&i.ID,
 		&i.LinkID,
 		&i.UserID,
 		&i.ClaimedAt,
+		&i.ExpiresAt,
 		&i.Inserted,
 	)
 	return i, err
 }
+
+const deleteClaim = `-- name: DeleteClaim :execrows
+DELETE FROM claims
+WHERE id = $1
+  AND user_id = $2
+`
+
+type DeleteClaimParams struct {
+	ID     pgtype.UUID
+	UserID pgtype.UUID
+}
+
+func (q *Queries) DeleteClaim(ctx context.Context, arg DeleteClaimParams) (int64, error) {
+	result, err := q.db.Exec(ctx, deleteClaim, arg.ID, arg.UserID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const listClaimsForUser = `-- name: ListClaimsForUser :many
+SELECT c.id,
+       c.link_id,
+       c.claimed_at,
+       c.expires_at,
+       l.url,
+       l.title,
+       l.user_id AS owner_id
+FROM claims c
+JOIN links l ON l.id = c.link_id
+WHERE c.user_id = $1
+  AND (c.expires_at IS NULL OR c.expires_at > NOW())
+ORDER BY c.claimed_at DESC, c.id
+`
+
+type ListClaimsForUserRow struct {
+	ID        pgtype.UUID
+	LinkID    pgtype.UUID
+	ClaimedAt pgtype.Timestamptz
+	ExpiresAt pgtype.Timestamptz
+	Url       string
+	Title     pgtype.Text
+	OwnerID   pgtype.UUID
+}
+
+// ListClaimsForUser lists a user's unexpired claims, newest first, with the
+// links they are on.
+func (q *Queries) ListClaimsForUser(ctx context.Context, userID pgtype.UUID) ([]ListClaimsForUserRow, error) {
+	rows, err := q.db.Query(ctx, listClaimsForUser, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListClaimsForUserRow
+	for rows.Next() {
+		var i ListClaimsForUserRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.LinkID,
+			&i.ClaimedAt,
+			&i.ExpiresAt,
+			&i.Url,
+			&i.Title,
+			&i.OwnerID,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
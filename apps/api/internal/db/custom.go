@@ -2,18 +2,21 @@ package db
 
 import (
 	"context"
+
+	"github.com/jackc/pgx/v5/pgtype"
 )
 
 type UpdateTagParams struct {
-	ID   int32
-	Name string
+	ID     int32
+	UserID pgtype.UUID
+	Name   string
 }
 
-const updateTagQuery = `UPDATE tags SET name = $1 WHERE id = $2 RETURNING id, name`
+const updateTagQuery = `UPDATE tags SET name = $1 WHERE id = $2 AND user_id = $3 RETURNING id, name, user_id`
 
 func (q *Queries) UpdateTag(ctx context.Context, arg UpdateTagParams) (Tag, error) {
-	row := q.db.QueryRow(ctx, updateTagQuery, arg.Name, arg.ID)
+	row := q.db.QueryRow(ctx, updateTagQuery, arg.Name, arg.ID, arg.UserID)
 	var tag Tag
-	err := row.Scan(&tag.ID, &tag.Name)
+	err := row.Scan(&tag.ID, &tag.Name, &tag.UserID)
 	return tag, err
 }
@@ -27,6 +27,61 @@ func (q *Queries) AddTagToLink(ctx context.Context, arg AddTagToLinkParams) erro
 	return err
 }
 
+const applyTagToMatchingLinks = `-- name: ApplyTagToMatchingLinks :one
+WITH matched AS (
+    SELECT l.id
+    FROM links l
+    WHERE l.user_id = $1
+      AND (
+        $2::text IS NULL
+        OR l.search_tsv @@ plainto_tsquery('english', $2::text)
+        OR l.url ILIKE '%' || $2::text || '%'
+      )
+      AND ($3::text IS NULL OR domain_covers($3::text, url_host(l.url)))
+      AND ($4::timestamptz IS NULL OR l.created_at >= $4::timestamptz)
+      AND ($5::timestamptz IS NULL OR l.created_at < $5::timestamptz)
+), tagged AS (
+    INSERT INTO link_tags (link_id, tag_id)
+    SELECT matched.id, $6
+    FROM matched
+    ON CONFLICT DO NOTHING
+    RETURNING link_id
+)
+SELECT (SELECT COUNT(*) FROM matched)::bigint AS matched,
+       (SELECT COUNT(*) FROM tagged)::bigint AS tagged
+`
+
+type ApplyTagToMatchingLinksParams struct {
+	UserID        pgtype.UUID
+	Query         pgtype.Text
+	Domain        pgtype.Text
+	CreatedAfter  pgtype.Timestamptz
+	CreatedBefore pgtype.Timestamptz
+	TagID         int32
+}
+
+type ApplyTagToMatchingLinksRow struct {
+	Matched int64
+	Tagged  int64
+}
+
+// ApplyTagToMatchingLinks tags every one of a user's links matching the
+// filter in one statement. Any filter left null matches everything; domain
+// covers its subdomains. tagged counts only links that lacked the tag.
+func (q *Queries) ApplyTagToMatchingLinks(ctx context.Context, arg ApplyTagToMatchingLinksParams) (ApplyTagToMatchingLinksRow, error) {
+	row := q.db.QueryRow(ctx, applyTagToMatchingLinks,
+		arg.UserID,
+		arg.Query,
+		arg.Domain,
+		arg.CreatedAfter,
+		arg.CreatedBefore,
+		arg.TagID,
+	)
+	var i ApplyTagToMatchingLinksRow
+	err := row.Scan(&i.Matched, &i.Tagged)
+	return i, err
+}
+
 const countLinks = `-- name: CountLinks :one
 SELECT COUNT(*)
 FROM links l
@@ -45,6 +100,24 @@ WHERE l.user_id = $2
     END
     OR l.url ILIKE '%' || $4::text || '%'
   )
+  AND (
+    $6::uuid IS NULL
+    OR EXISTS (
+        SELECT 1
+        FROM claims c
+        WHERE c.link_id = l.id
+          AND c.user_id = $6::uuid
+          AND (c.expires_at IS NULL OR c.expires_at > NOW())
+    )
+  )
+  AND (
+    $7::boolean IS NULL
+    OR EXISTS (
+        SELECT 1
+        FROM highlights h
+        WHERE h.link_id = l.id
+    ) = $7::boolean
+  )
 `
 
 type CountLinksParams struct {
@@ -53,6 +126,8 @@ type CountLinksParams struct {
 	Favorite       pgtype.Bool
 	Query          pgtype.Text
 	EnableFullText bool
+	ClaimedBy      pgtype.UUID
+	HasHighlights  pgtype.Bool
 }
 
 func (q *Queries) CountLinks(ctx context.Context, arg CountLinksParams) (int64, error) {
@@ -62,6 +137,8 @@ func (q *Queries) CountLinks(ctx context.Context, arg CountLinksParams) (int64,
 		arg.Favorite,
 		arg.Query,
 		arg.EnableFullText,
+		arg.ClaimedBy,
+		arg.HasHighlights,
 	)
 	var count int64
 	err := row.Scan(&count)
@@ -98,6 +175,24 @@ WHERE l.user_id = $2
     filter_params.tag_ids IS NULL
     OR COALESCE(tag_matches.match_count, 0) = filter_params.tag_count
   )
+  AND (
+    $6::uuid IS NULL
+    OR EXISTS (
+        SELECT 1
+        FROM claims c
+        WHERE c.link_id = l.id
+          AND c.user_id = $6::uuid
+          AND (c.expires_at IS NULL OR c.expires_at > NOW())
+    )
+  )
+  AND (
+    $7::boolean IS NULL
+    OR EXISTS (
+        SELECT 1
+        FROM highlights h
+        WHERE h.link_id = l.id
+    ) = $7::boolean
+  )
 `
 
 type CountLinksWithTagsParams struct {
@@ -106,6 +201,8 @@ type CountLinksWithTagsParams struct {
 	Favorite       pgtype.Bool
 	Query          pgtype.Text
 	EnableFullText bool
+	ClaimedBy      pgtype.UUID
+	HasHighlights  pgtype.Bool
 }
 
 func (q *Queries) CountLinksWithTags(ctx context.Context, arg CountLinksWithTagsParams) (int64, error) {
@@ -115,6 +212,8 @@ func (q *Queries) CountLinksWithTags(ctx context.Context, arg CountLinksWithTags
 		arg.Favorite,
 		arg.Query,
 		arg.EnableFullText,
+		arg.ClaimedBy,
+		arg.HasHighlights,
 	)
 	var count int64
 	err := row.Scan(&count)
@@ -169,23 +268,28 @@ INSERT INTO links (
     user_id,
     url,
     title,
-    favorite
+    favorite,
+    original_title,
+    preserve_title
 ) VALUES (
     $1,
     $2,
     $3,
     $4,
-    COALESCE($5, FALSE)
+    COALESCE($5, FALSE),
+    $4,
+    COALESCE($6, FALSE)
 )
 RETURNING id, user_id, url, title, created_at, read_at, favorite
 `
 
 type CreateLinkParams struct {
-	ID       pgtype.UUID
-	UserID   pgtype.UUID
-	Url      string
-	Title    pgtype.Text
-	Favorite interface{}
+	ID            pgtype.UUID
+	UserID        pgtype.UUID
+	Url           string
+	Title         pgtype.Text
+	Favorite      interface{}
+	PreserveTitle interface{}
 }
 
 type CreateLinkRow struct {
@@ -205,6 +309,7 @@ func (q *Queries) CreateLink(ctx context.Context, arg CreateLinkParams) (CreateL
 		arg.Url,
 		arg.Title,
 		arg.Favorite,
+		arg.PreserveTitle,
 	)
 	var i CreateLinkRow
 	err := row.Scan(
@@ -220,15 +325,20 @@ func (q *Queries) CreateLink(ctx context.Context, arg CreateLinkParams) (CreateL
 }
 
 const createTag = `-- name: CreateTag :one
-INSERT INTO tags (name)
-VALUES ($1)
-RETURNING id, name
+INSERT INTO tags (user_id, name)
+VALUES ($1, $2)
+RETURNING id, name, user_id
 `
 
-func (q *Queries) CreateTag(ctx context.Context, name string) (Tag, error) {
-	row := q.db.QueryRow(ctx, createTag, name)
+type CreateTagParams struct {
+	UserID pgtype.UUID
+	Name   string
+}
+
+func (q *Queries) CreateTag(ctx context.Context, arg CreateTagParams) (Tag, error) {
+	row := q.db.QueryRow(ctx, createTag, arg.UserID, arg.Name)
 	var i Tag
-	err := row.Scan(&i.ID, &i.Name)
+	err := row.Scan(&i.ID, &i.Name, &i.UserID)
 	return i, err
 }
 
@@ -245,10 +355,16 @@ func (q *Queries) DeleteHighlight(ctx context.Context, id pgtype.UUID) error {
 const deleteTag = `-- name: DeleteTag :exec
 DELETE FROM tags
 WHERE id = $1
+  AND user_id = $2
 `
 
-func (q *Queries) DeleteTag(ctx context.Context, id int32) error {
-	_, err := q.db.Exec(ctx, deleteTag, id)
+type DeleteTagParams struct {
+	ID     int32
+	UserID pgtype.UUID
+}
+
+func (q *Queries) DeleteTag(ctx context.Context, arg DeleteTagParams) error {
+	_, err := q.db.Exec(ctx, deleteTag, arg.ID, arg.UserID)
 	return err
 }
 
@@ -290,28 +406,40 @@ func (q *Queries) GetLink(ctx context.Context, id pgtype.UUID) (GetLinkRow, erro
 }
 
 const getTag = `-- name: GetTag :one
-SELECT id, name
+SELECT id, name, user_id
 FROM tags
 WHERE id = $1
+  AND user_id = $2
 `
 
-func (q *Queries) GetTag(ctx context.Context, id int32) (Tag, error) {
-	row := q.db.QueryRow(ctx, getTag, id)
+type GetTagParams struct {
+	ID     int32
+	UserID pgtype.UUID
+}
+
+func (q *Queries) GetTag(ctx context.Context, arg GetTagParams) (Tag, error) {
+	row := q.db.QueryRow(ctx, getTag, arg.ID, arg.UserID)
 	var i Tag
-	err := row.Scan(&i.ID, &i.Name)
+	err := row.Scan(&i.ID, &i.Name, &i.UserID)
 	return i, err
 }
 
 const getTagByName = `-- name: GetTagByName :one
-SELECT id, name
+SELECT id, name, user_id
 FROM tags
-WHERE name = $1
+WHERE user_id = $1
+  AND name = $2
 `
 
-func (q *Queries) GetTagByName(ctx context.Context, name string) (Tag, error) {
-	row := q.db.QueryRow(ctx, getTagByName, name)
+type GetTagByNameParams struct {
+	UserID pgtype.UUID
+	Name   string
+}
+
+func (q *Queries) GetTagByName(ctx context.Context, arg GetTagByNameParams) (Tag, error) {
+	row := q.db.QueryRow(ctx, getTagByName, arg.UserID, arg.Name)
 	var i Tag
-	err := row.Scan(&i.ID, &i.Name)
+	err := row.Scan(&i.ID, &i.Name, &i.UserID)
 	return i, err
 }
 
@@ -358,11 +486,14 @@ SELECT l.id,
        l.created_at,
        l.read_at,
        l.favorite,
+       l.original_title,
+       l.preserve_title,
        COALESCE(a.title, '') AS archive_title,
        COALESCE(a.byline, '') AS archive_byline,
        COALESCE(a.lang, '') AS lang,
        COALESCE(a.word_count, 0) AS word_count,
        COALESCE(a.extracted_text, '') AS extracted_text,
+       COALESCE(a.paywalled, FALSE) AS paywalled,
        COALESCE(tag_data.tag_ids, '{}'::INTEGER[]) AS tag_ids,
        COALESCE(tag_data.tag_names, '{}'::TEXT[]) AS tag_names,
        COALESCE(highlight_data.highlights, '[]'::JSON)::text AS highlights
@@ -405,8 +536,33 @@ WHERE l.user_id = $2
     END
     OR l.url ILIKE '%' || $4::text || '%'
   )
-ORDER BY l.created_at DESC
-LIMIT $7::int OFFSET $6::int
+  AND (
+    $6::uuid IS NULL
+    OR EXISTS (
+        SELECT 1
+        FROM claims c
+        WHERE c.link_id = l.id
+          AND c.user_id = $6::uuid
+          AND (c.expires_at IS NULL OR c.expires_at > NOW())
+    )
+  )
+  AND (
+    $7::boolean IS NULL
+    OR EXISTS (
+        SELECT 1
+        FROM highlights h
+        WHERE h.link_id = l.id
+    ) = $7::boolean
+  )
+ORDER BY CASE
+        WHEN $8::boolean
+             AND $5::boolean
+             AND $4::text IS NOT NULL
+        THEN ts_rank_cd(l.search_tsv, plainto_tsquery('english', $4::text))
+             * (1 + 1 / (1 + EXTRACT(EPOCH FROM NOW() - l.created_at) / 2592000))
+    END DESC NULLS LAST,
+    l.created_at DESC
+LIMIT $10::int OFFSET $9::int
 `
 
 type ListLinksParams struct {
@@ -415,6 +571,9 @@ type ListLinksParams struct {
 	Favorite       pgtype.Bool
 	Query          pgtype.Text
 	EnableFullText bool
+	ClaimedBy      pgtype.UUID
+	HasHighlights  pgtype.Bool
+	SortRelevance  bool
 	PageOffset     int32
 	PageLimit      int32
 }
@@ -428,11 +587,14 @@ type ListLinksRow struct {
 	CreatedAt     pgtype.Timestamptz
 	ReadAt        pgtype.Timestamptz
 	Favorite      bool
+	OriginalTitle pgtype.Text
+	PreserveTitle bool
 	ArchiveTitle  string
 	ArchiveByline string
 	Lang          string
 	WordCount     int32
 	ExtractedText string
+	Paywalled     bool
 	TagIds        interface{}
 	TagNames      interface{}
 	Highlights    string
@@ -445,6 +607,9 @@ func (q *Queries) ListLinks(ctx context.Context, arg ListLinksParams) ([]ListLin
 		arg.Favorite,
 		arg.Query,
 		arg.EnableFullText,
+		arg.ClaimedBy,
+		arg.HasHighlights,
+		arg.SortRelevance,
 		arg.PageOffset,
 		arg.PageLimit,
 	)
@@ -464,11 +629,14 @@ func (q *Queries) ListLinks(ctx context.Context, arg ListLinksParams) ([]ListLin
 			&i.CreatedAt,
 			&i.ReadAt,
 			&i.Favorite,
+			&i.OriginalTitle,
+			&i.PreserveTitle,
 			&i.ArchiveTitle,
 			&i.ArchiveByline,
 			&i.Lang,
 			&i.WordCount,
 			&i.ExtractedText,
+			&i.Paywalled,
 			&i.TagIds,
 			&i.TagNames,
 			&i.Highlights,
@@ -492,11 +660,14 @@ SELECT l.id,
        l.created_at,
        l.read_at,
        l.favorite,
+       l.original_title,
+       l.preserve_title,
        COALESCE(a.title, '') AS archive_title,
        COALESCE(a.byline, '') AS archive_byline,
        COALESCE(a.lang, '') AS lang,
        COALESCE(a.word_count, 0) AS word_count,
        COALESCE(a.extracted_text, '') AS extracted_text,
+       COALESCE(a.paywalled, FALSE) AS paywalled,
        COALESCE(tag_data.tag_ids, '{}'::INTEGER[]) AS tag_ids,
        COALESCE(tag_data.tag_names, '{}'::TEXT[]) AS tag_names,
        COALESCE(highlight_data.highlights, '[]'::JSON)::text AS highlights
@@ -551,8 +722,33 @@ WHERE l.user_id = $2
     filter_params.tag_ids IS NULL
     OR COALESCE(tag_matches.match_count, 0) = filter_params.tag_count
   )
-ORDER BY l.created_at DESC
-LIMIT $7::int OFFSET $6::int
+  AND (
+    $6::uuid IS NULL
+    OR EXISTS (
+        SELECT 1
+        FROM claims c
+        WHERE c.link_id = l.id
+          AND c.user_id = $6::uuid
+          AND (c.expires_at IS NULL OR c.expires_at > NOW())
+    )
+  )
+  AND (
+    $7::boolean IS NULL
+    OR EXISTS (
+        SELECT 1
+        FROM highlights h
+        WHERE h.link_id = l.id
+    ) = $7::boolean
+  )
+ORDER BY CASE
+        WHEN $8::boolean
+             AND $5::boolean
+             AND $4::text IS NOT NULL
+        THEN ts_rank_cd(l.search_tsv, plainto_tsquery('english', $4::text))
+             * (1 + 1 / (1 + EXTRACT(EPOCH FROM NOW() - l.created_at) / 2592000))
+    END DESC NULLS LAST,
+    l.created_at DESC
+LIMIT $10::int OFFSET $9::int
 `
 
 type ListLinksWithTagsParams struct {
@@ -561,6 +757,9 @@ type ListLinksWithTagsParams struct {
 	Favorite       pgtype.Bool
 	Query          pgtype.Text
 	EnableFullText bool
+	ClaimedBy      pgtype.UUID
+	HasHighlights  pgtype.Bool
+	SortRelevance  bool
 	PageOffset     int32
 	PageLimit      int32
 }
@@ -574,11 +773,14 @@ type ListLinksWithTagsRow struct {
 	CreatedAt     pgtype.Timestamptz
 	ReadAt        pgtype.Timestamptz
 	Favorite      bool
+	OriginalTitle pgtype.Text
+	PreserveTitle bool
 	ArchiveTitle  string
 	ArchiveByline string
 	Lang          string
 	WordCount     int32
 	ExtractedText string
+	Paywalled     bool
 	TagIds        interface{}
 	TagNames      interface{}
 	Highlights    string
@@ -591,6 +793,9 @@ func (q *Queries) ListLinksWithTags(ctx context.Context, arg ListLinksWithTagsPa
 		arg.Favorite,
 		arg.Query,
 		arg.EnableFullText,
+		arg.ClaimedBy,
+		arg.HasHighlights,
+		arg.SortRelevance,
 		arg.PageOffset,
 		arg.PageLimit,
 	)
@@ -610,11 +815,14 @@ func (q *Queries) ListLinksWithTags(ctx context.Context, arg ListLinksWithTagsPa
 			&i.CreatedAt,
 			&i.ReadAt,
 			&i.Favorite,
+			&i.OriginalTitle,
+			&i.PreserveTitle,
 			&i.ArchiveTitle,
 			&i.ArchiveByline,
 			&i.Lang,
 			&i.WordCount,
 			&i.ExtractedText,
+			&i.Paywalled,
 			&i.TagIds,
 			&i.TagNames,
 			&i.Highlights,
@@ -629,12 +837,72 @@ func (q *Queries) ListLinksWithTags(ctx context.Context, arg ListLinksWithTagsPa
 	return items, nil
 }
 
+const listSimilarLinks = `-- name: ListSimilarLinks :many
+SELECT l.id, l.url, l.title, l.created_at
+FROM links l
+WHERE l.user_id = $1
+  AND (
+    lower(substring(l.url from '^[a-zA-Z][a-zA-Z0-9+.-]*://(?:www\.)?([^/:?#]+)')) = $2::text
+    OR regexp_split_to_array(lower(COALESCE(l.title, '')), '[^[:alnum:]]+') && $3::text[]
+  )
+ORDER BY l.created_at DESC
+LIMIT $4::int
+`
+
+type ListSimilarLinksParams struct {
+	UserID     pgtype.UUID
+	Host       string
+	TitleWords []string
+	PageLimit  int32
+}
+
+type ListSimilarLinksRow struct {
+	ID        pgtype.UUID
+	Url       string
+	Title     pgtype.Text
+	CreatedAt pgtype.Timestamptz
+}
+
+// Candidates for the near-duplicate warning on create: the user's links on
+// the same host (ignoring a leading www.), or whose title shares a word with
+// title_words. The caller does the actual scoring.
+func (q *Queries) ListSimilarLinks(ctx context.Context, arg ListSimilarLinksParams) ([]ListSimilarLinksRow, error) {
+	rows, err := q.db.Query(ctx, listSimilarLinks,
+		arg.UserID,
+		arg.Host,
+		arg.TitleWords,
+		arg.PageLimit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListSimilarLinksRow
+	for rows.Next() {
+		var i ListSimilarLinksRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Url,
+			&i.Title,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const listTagLinkCounts = `-- name: ListTagLinkCounts :many
 SELECT t.id,
        t.name,
        COUNT(lt.link_id)::INT AS link_count
 FROM tags t
 LEFT JOIN link_tags lt ON lt.tag_id = t.id
+WHERE t.user_id = $1
 GROUP BY t.id, t.name
 ORDER BY t.name
 `
@@ -645,8 +913,8 @@ type ListTagLinkCountsRow struct {
 	LinkCount int32
 }
 
-func (q *Queries) ListTagLinkCounts(ctx context.Context) ([]ListTagLinkCountsRow, error) {
-	rows, err := q.db.Query(ctx, listTagLinkCounts)
+func (q *Queries) ListTagLinkCounts(ctx context.Context, userID pgtype.UUID) ([]ListTagLinkCountsRow, error) {
+	rows, err := q.db.Query(ctx, listTagLinkCounts, userID)
 	if err != nil {
 		return nil, err
 	}
@@ -666,13 +934,14 @@ func (q *Queries) ListTagLinkCounts(ctx context.Context) ([]ListTagLinkCountsRow
 }
 
 const listTags = `-- name: ListTags :many
-SELECT id, name
+SELECT id, name, user_id
 FROM tags
+WHERE user_id = $1
 ORDER BY name
 `
 
-func (q *Queries) ListTags(ctx context.Context) ([]Tag, error) {
-	rows, err := q.db.Query(ctx, listTags)
+func (q *Queries) ListTags(ctx context.Context, userID pgtype.UUID) ([]Tag, error) {
+	rows, err := q.db.Query(ctx, listTags, userID)
 	if err != nil {
 		return nil, err
 	}
@@ -680,7 +949,7 @@ func (q *Queries) ListTags(ctx context.Context) ([]Tag, error) {
 	var items []Tag
 	for rows.Next() {
 		var i Tag
-		if err := rows.Scan(&i.ID, &i.Name); err != nil {
+		if err := rows.Scan(&i.ID, &i.Name, &i.UserID); err != nil {
 			return nil, err
 		}
 		items = append(items, i)
@@ -692,7 +961,7 @@ func (q *Queries) ListTags(ctx context.Context) ([]Tag, error) {
 }
 
 const listTagsForLink = `-- name: ListTagsForLink :many
-SELECT t.id, t.name
+SELECT t.id, t.name, t.user_id
 FROM tags t
 JOIN link_tags lt ON lt.tag_id = t.id
 WHERE lt.link_id = $1
@@ -708,7 +977,7 @@ func (q *Queries) ListTagsForLink(ctx context.Context, linkID pgtype.UUID) ([]Ta
 	var items []Tag
 	for rows.Next() {
 		var i Tag
-		if err := rows.Scan(&i.ID, &i.Name); err != nil {
+		if err := rows.Scan(&i.ID, &i.Name, &i.UserID); err != nil {
 			return nil, err
 		}
 		items = append(items, i)
@@ -719,6 +988,78 @@ func (q *Queries) ListTagsForLink(ctx context.Context, linkID pgtype.UUID) ([]Ta
 	return items, nil
 }
 
+const markLinksRead = `-- name: MarkLinksRead :many
+UPDATE links
+SET read_at = NOW()
+WHERE user_id = $1
+  AND id = ANY($2::uuid[])
+  AND read_at IS NULL
+RETURNING id
+`
+
+type MarkLinksReadParams struct {
+	UserID pgtype.UUID
+	Ids    []pgtype.UUID
+}
+
+func (q *Queries) MarkLinksRead(ctx context.Context, arg MarkLinksReadParams) ([]pgtype.UUID, error) {
+	rows, err := q.db.Query(ctx, markLinksRead, arg.UserID, arg.Ids)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []pgtype.UUID
+	for rows.Next() {
+		var id pgtype.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		items = append(items, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const markLinksReadAt = `-- name: MarkLinksReadAt :many
+UPDATE links AS l
+SET read_at = r.read_at
+FROM unnest($1::uuid[], $2::timestamptz[]) AS r(id, read_at)
+WHERE l.id = r.id
+  AND l.user_id = $3
+  AND l.read_at IS NULL
+RETURNING l.id
+`
+
+type MarkLinksReadAtParams struct {
+	Ids     []pgtype.UUID
+	ReadAts []pgtype.Timestamptz
+	UserID  pgtype.UUID
+}
+
+// MarkLinksReadAt marks each link in ids read at the time at the same
+// position in read_ats. Links already read keep their read time.
+func (q *Queries) MarkLinksReadAt(ctx context.Context, arg MarkLinksReadAtParams) ([]pgtype.UUID, error) {
+	rows, err := q.db.Query(ctx, markLinksReadAt, arg.Ids, arg.ReadAts, arg.UserID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []pgtype.UUID
+	for rows.Next() {
+		var id pgtype.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		items = append(items, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const removeTagFromLink = `-- name: RemoveTagFromLink :exec
 DELETE FROM link_tags
 WHERE link_id = $1
@@ -776,7 +1117,9 @@ WITH updated AS (
               l.source_domain,
               l.created_at,
               l.read_at,
-              l.favorite
+              l.favorite,
+              l.original_title,
+              l.preserve_title
 )
 SELECT u.id,
        u.user_id,
@@ -786,11 +1129,14 @@ SELECT u.id,
        u.created_at,
        u.read_at,
        u.favorite,
+       u.original_title,
+       u.preserve_title,
        COALESCE(a.title, '') AS archive_title,
        COALESCE(a.byline, '') AS archive_byline,
        COALESCE(a.lang, '') AS lang,
        COALESCE(a.word_count, 0) AS word_count,
        COALESCE(a.extracted_text, '') AS extracted_text,
+       COALESCE(a.paywalled, FALSE) AS paywalled,
        COALESCE(tag_data.tag_ids, '{}'::INTEGER[]) AS tag_ids,
        COALESCE(tag_data.tag_names, '{}'::TEXT[]) AS tag_names,
        COALESCE(highlight_data.highlights, '[]'::JSON)::text AS highlights
@@ -834,11 +1180,14 @@ type UpdateLinkFavoriteRow struct {
 	CreatedAt     pgtype.Timestamptz
 	ReadAt        pgtype.Timestamptz
 	Favorite      bool
+	OriginalTitle pgtype.Text
+	PreserveTitle bool
 	ArchiveTitle  string
 	ArchiveByline string
 	Lang          string
 	WordCount     int32
 	ExtractedText string
+	Paywalled     bool
 	TagIds        interface{}
 	TagNames      interface{}
 	Highlights    string
@@ -856,11 +1205,14 @@ func (q *Queries) UpdateLinkFavorite(ctx context.Context, arg UpdateLinkFavorite
 		&i.CreatedAt,
 		&i.ReadAt,
 		&i.Favorite,
+		&i.OriginalTitle,
+		&i.PreserveTitle,
 		&i.ArchiveTitle,
 		&i.ArchiveByline,
 		&i.Lang,
 		&i.WordCount,
 		&i.ExtractedText,
+		&i.Paywalled,
 		&i.TagIds,
 		&i.TagNames,
 		&i.Highlights,
@@ -868,6 +1220,22 @@ func (q *Queries) UpdateLinkFavorite(ctx context.Context, arg UpdateLinkFavorite
 	return i, err
 }
 
+const updateLinkSnooze = `-- name: UpdateLinkSnooze :exec
+UPDATE links
+SET snoozed_until = $1
+WHERE id = $2
+`
+
+type UpdateLinkSnoozeParams struct {
+	SnoozedUntil pgtype.Timestamptz
+	ID           pgtype.UUID
+}
+
+func (q *Queries) UpdateLinkSnooze(ctx context.Context, arg UpdateLinkSnoozeParams) error {
+	_, err := q.db.Exec(ctx, updateLinkSnooze, arg.SnoozedUntil, arg.ID)
+	return err
+}
+
 const updateLinkSourceDomain = `-- name: UpdateLinkSourceDomain :exec
 UPDATE links
 SET source_domain = $1
@@ -888,6 +1256,7 @@ const updateLinkTitle = `-- name: UpdateLinkTitle :exec
 UPDATE links
 SET title = $1
 WHERE id = $2
+  AND NOT preserve_title
 `
 
 type UpdateLinkTitleParams struct {
@@ -908,7 +1277,9 @@ INSERT INTO archives (
     word_count,
     lang,
     title,
-    byline
+    byline,
+    html_sha256,
+    text_sha256
 ) VALUES (
     $1,
     $2,
@@ -916,7 +1287,9 @@ INSERT INTO archives (
     $4,
     $5,
     $6,
-    $7
+    $7,
+    archive_content_sha256($2),
+    archive_content_sha256(COALESCE($3, ''))
 )
 ON CONFLICT (link_id) DO UPDATE
 SET html = EXCLUDED.html,
@@ -924,7 +1297,9 @@ SET html = EXCLUDED.html,
     word_count = EXCLUDED.word_count,
     lang = EXCLUDED.lang,
     title = EXCLUDED.title,
-    byline = EXCLUDED.byline
+    byline = EXCLUDED.byline,
+    html_sha256 = EXCLUDED.html_sha256,
+    text_sha256 = EXCLUDED.text_sha256
 `
 
 type UpsertArchiveParams struct {
@@ -8,6 +8,21 @@ import (
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
+type ActivitypubActor struct {
+	UserID      pgtype.UUID
+	Username    string
+	DisplayName pgtype.Text
+	Summary     pgtype.Text
+	CreatedAt   pgtype.Timestamptz
+}
+
+type ActivitypubFollower struct {
+	UserID    pgtype.UUID
+	ActorUri  string
+	Inbox     string
+	CreatedAt pgtype.Timestamptz
+}
+
 type Archive struct {
 	LinkID        pgtype.UUID
 	Html          pgtype.Text
@@ -16,6 +31,36 @@ type Archive struct {
 	Byline        pgtype.Text
 	Lang          pgtype.Text
 	WordCount     pgtype.Int4
+	UpdatedAt     pgtype.Timestamptz
+	Paywalled     bool
+	HtmlSha256    pgtype.Text
+	TextSha256    pgtype.Text
+	Etag          pgtype.Text
+	LastModified  pgtype.Text
+}
+
+type ArchiveVersion struct {
+	ID            int64
+	LinkID        pgtype.UUID
+	Title         pgtype.Text
+	ExtractedText string
+	WordCount     pgtype.Int4
+	ArchivedAt    pgtype.Timestamptz
+	ReplacedAt    pgtype.Timestamptz
+}
+
+type BackupRun struct {
+	ID          pgtype.UUID
+	StartedAt   pgtype.Timestamptz
+	FinishedAt  pgtype.Timestamptz
+	Status      string
+	Kind        string
+	Storage     string
+	File        pgtype.Text
+	Destination pgtype.Text
+	SizeBytes   pgtype.Int8
+	Checksum    pgtype.Text
+	Error       pgtype.Text
 }
 
 type Claim struct {
@@ -23,6 +68,29 @@ type Claim struct {
 	LinkID    pgtype.UUID
 	UserID    pgtype.UUID
 	ClaimedAt pgtype.Timestamptz
+	ExpiresAt pgtype.Timestamptz
+}
+
+type CronRun struct {
+	ID         pgtype.UUID
+	Subcommand string
+	StartedAt  pgtype.Timestamptz
+	FinishedAt pgtype.Timestamptz
+	Status     string
+	DurationMs pgtype.Int8
+	Counts     []byte
+	Error      pgtype.Text
+}
+
+type DomainPreference struct {
+	UserID         pgtype.UUID
+	Domain         string
+	AlwaysFavorite bool
+	DefaultTags    []string
+	NeverResurface bool
+	FetchHeaders   []byte
+	CreatedAt      pgtype.Timestamptz
+	UpdatedAt      pgtype.Timestamptz
 }
 
 type Highlight struct {
@@ -34,16 +102,92 @@ type Highlight struct {
 	UpdatedAt  pgtype.Timestamptz
 }
 
+type InboundHook struct {
+	ID          pgtype.UUID
+	UserID      pgtype.UUID
+	Name        string
+	TokenHash   string
+	UrlField    string
+	TitleField  string
+	TagsField   string
+	DefaultTags []string
+	CreatedAt   pgtype.Timestamptz
+	LastUsedAt  pgtype.Timestamptz
+}
+
+type Job struct {
+	ID         pgtype.UUID
+	UserID     pgtype.UUID
+	Type       string
+	State      string
+	Done       int32
+	Total      int32
+	Result     []byte
+	Error      pgtype.Text
+	CreatedAt  pgtype.Timestamptz
+	UpdatedAt  pgtype.Timestamptz
+	FinishedAt pgtype.Timestamptz
+}
+
+type JobFile struct {
+	JobID       pgtype.UUID
+	Name        string
+	ContentType string
+	Body        []byte
+}
+
 type Link struct {
-	ID           pgtype.UUID
-	UserID       pgtype.UUID
-	Url          string
-	Title        pgtype.Text
-	CreatedAt    pgtype.Timestamptz
-	ReadAt       pgtype.Timestamptz
-	Favorite     bool
-	SearchTsv    interface{}
-	SourceDomain pgtype.Text
+	ID             pgtype.UUID
+	UserID         pgtype.UUID
+	Url            string
+	Title          pgtype.Text
+	CreatedAt      pgtype.Timestamptz
+	ReadAt         pgtype.Timestamptz
+	Favorite       bool
+	SearchTsv      interface{}
+	SourceDomain   pgtype.Text
+	LastSurfacedAt pgtype.Timestamptz
+	SnoozedUntil   pgtype.Timestamptz
+	UpdatedAt      pgtype.Timestamptz
+	PublicAt       pgtype.Timestamptz
+	OriginalTitle  pgtype.Text
+	PreserveTitle  bool
+}
+
+type LinkCapture struct {
+	LinkID     pgtype.UUID
+	Html       string
+	CapturedAt pgtype.Timestamptz
+}
+
+type LinkIngestStatus struct {
+	LinkID        pgtype.UUID
+	Status        string
+	FailedStage   pgtype.Text
+	Error         pgtype.Text
+	UpdatedAt     pgtype.Timestamptz
+	FinalUrl      pgtype.Text
+	FetchBytes    pgtype.Int8
+	FetchMs       pgtype.Int4
+	ParseMs       pgtype.Int4
+	Lang          pgtype.Text
+	LangDetected  pgtype.Bool
+	WordCount     pgtype.Int4
+	DiagnosticsAt pgtype.Timestamptz
+}
+
+type LinkMedium struct {
+	LinkID    pgtype.UUID
+	Hash      string
+	CreatedAt pgtype.Timestamptz
+}
+
+type LinkShare struct {
+	LinkID     pgtype.UUID
+	UserID     pgtype.UUID
+	Permission string
+	ExpiresAt  pgtype.Timestamptz
+	CreatedAt  pgtype.Timestamptz
 }
 
 type LinkTag struct {
@@ -51,15 +195,85 @@ type LinkTag struct {
 	TagID  int32
 }
 
+type Notification struct {
+	ID        pgtype.UUID
+	UserID    pgtype.UUID
+	Kind      string
+	LinkID    pgtype.UUID
+	Message   string
+	ReadAt    pgtype.Timestamptz
+	CreatedAt pgtype.Timestamptz
+}
+
+type PublicProfile struct {
+	UserID      pgtype.UUID
+	Username    string
+	DisplayName pgtype.Text
+	CreatedAt   pgtype.Timestamptz
+}
+
+type RateLimitBucket struct {
+	Key       string
+	Tokens    float64
+	UpdatedAt pgtype.Timestamptz
+}
+
+type ReadingGoal struct {
+	UserID       pgtype.UUID
+	Metric       string
+	WeeklyTarget int32
+	CreatedAt    pgtype.Timestamptz
+	UpdatedAt    pgtype.Timestamptz
+}
+
 type Recommendation struct {
 	LinkID    pgtype.UUID
 	Score     int32
 	UpdatedAt pgtype.Timestamptz
+	Strategy  string
+}
+
+type RecommendationImpression struct {
+	ID       int64
+	UserID   pgtype.UUID
+	LinkID   pgtype.UUID
+	Strategy string
+	Surface  string
+	Position int32
+	ShownAt  pgtype.Timestamptz
+	Action   pgtype.Text
+	ActedAt  pgtype.Timestamptz
+}
+
+type ResurfacerWeight struct {
+	UserID         pgtype.UUID
+	FavoriteBonus  pgtype.Int4
+	AgeCapDays     pgtype.Int4
+	WordCountTiers pgtype.Text
+	UpdatedAt      pgtype.Timestamptz
+}
+
+type SyncChange struct {
+	Seq       int64
+	UserID    pgtype.UUID
+	Entity    string
+	EntityID  pgtype.UUID
+	Deleted   bool
+	TxID      interface{}
+	ChangedAt pgtype.Timestamptz
 }
 
 type Tag struct {
-	ID   int32
-	Name string
+	ID     int32
+	Name   string
+	UserID pgtype.UUID
+}
+
+type UsageQuota struct {
+	UserID pgtype.UUID
+	Kind   string
+	Day    pgtype.Date
+	Used   int32
 }
 
 type User struct {
@@ -67,4 +281,5 @@ type User struct {
 	Email        string
 	PasswordHash string
 	CreatedAt    pgtype.Timestamptz
+	IsAdmin      bool
 }
@@ -23,7 +23,33 @@ func (q *Queries) ClearRecommendationsForUser(ctx context.Context, userID pgtype
 	return err
 }
 
-const listRecommendationsForUser = `-- name: ListRecommendationsForUser :many
+const getResurfacerWeightsForUser = `-- name: GetResurfacerWeightsForUser :one
+SELECT user_id, favorite_bonus, age_cap_days, word_count_tiers, updated_at
+FROM resurfacer_weights
+WHERE user_id = $1
+`
+
+func (q *Queries) GetResurfacerWeightsForUser(ctx context.Context, userID pgtype.UUID) (ResurfacerWeight, error) {
+	row := q.db.QueryRow(ctx, getResurfacerWeightsForUser, userID)
+	var i ResurfacerWeight
+	err := row.Scan(
+		&i.UserID,
+		&i.FavoriteBonus,
+		&i.AgeCapDays,
+		&i.WordCountTiers,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const listColdStartLinksForUser = `-- name: ListColdStartLinksForUser :many
+WITH recent AS (
+    SELECT id
+    FROM links
+    WHERE user_id = $1
+    ORDER BY created_at DESC
+    LIMIT $2::int
+)
 SELECT
     l.id,
     l.url,
@@ -37,22 +63,25 @@ SELECT
     a.lang,
     COALESCE(a.word_count, 0) AS word_count,
     COALESCE(a.extracted_text, '') AS extracted_text,
-    r.score,
-    r.updated_at
-FROM recommendations r
-JOIN links l ON l.id = r.link_id
+    COALESCE(a.paywalled, FALSE) AS paywalled
+FROM recent
+JOIN links l ON l.id = recent.id
 LEFT JOIN archives a ON a.link_id = l.id
-WHERE l.user_id = $1
-ORDER BY r.score DESC, r.updated_at DESC
-LIMIT $2
+ORDER BY
+    l.read_at IS NOT NULL,
+    COALESCE(a.word_count, 0) = 0,
+    COALESCE(a.word_count, 0),
+    l.created_at DESC
+LIMIT $3::int
 `
 
-type ListRecommendationsForUserParams struct {
-	UserID pgtype.UUID
-	Limit  int32
+type ListColdStartLinksForUserParams struct {
+	UserID       pgtype.UUID
+	RecentWindow int32
+	RowLimit     int32
 }
 
-type ListRecommendationsForUserRow struct {
+type ListColdStartLinksForUserRow struct {
 	ID            pgtype.UUID
 	Url           string
 	Title         pgtype.Text
@@ -65,19 +94,18 @@ type ListRecommendationsForUserRow struct {
 	Lang          pgtype.Text
 	WordCount     int32
 	ExtractedText string
-	Score         int32
-	UpdatedAt     pgtype.Timestamptz
+	Paywalled     bool
 }
 
-func (q *Queries) ListRecommendationsForUser(ctx context.Context, arg ListRecommendationsForUserParams) ([]ListRecommendationsForUserRow, error) {
-	rows, err := q.db.Query(ctx, listRecommendationsForUser, arg.UserID, arg.Limit)
+func (q *Queries) ListColdStartLinksForUser(ctx context.Context, arg ListColdStartLinksForUserParams) ([]ListColdStartLinksForUserRow, error) {
+	rows, err := q.db.Query(ctx, listColdStartLinksForUser, arg.UserID, arg.RecentWindow, arg.RowLimit)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	var items []ListRecommendationsForUserRow
+	var items []ListColdStartLinksForUserRow
 	for rows.Next() {
-		var i ListRecommendationsForUserRow
+		var i ListColdStartLinksForUserRow
 		if err := rows.Scan(
 			&i.ID,
 			&i.Url,
@@ -91,8 +119,7 @@ func (q *Queries) ListRecommendationsForUser(ctx context.Context, arg ListRecomm
 			&i.Lang,
 			&i.WordCount,
 			&i.ExtractedText,
-			&i.Score,
-			&i.UpdatedAt,
+			&i.Paywalled,
 		); err != nil {
 			return nil, err
 		}
@@ -104,60 +131,343 @@ func (q *Queries) ListRecommendationsForUser(ctx context.Context, arg ListRecomm
 	return items, nil
 }
 
-const listUnreadLinksForUser = `-- name: ListUnreadLinksForUser :many
+const listOnThisDayLinksForUser = `-- name: ListOnThisDayLinksForUser :many
 SELECT
     l.id,
-    l.user_id,
     l.url,
     l.title,
+    l.source_domain,
+    l.favorite,
     l.created_at,
+    l.read_at,
+    a.title AS archive_title,
+    a.byline,
+    a.lang,
+    COALESCE(a.word_count, 0) AS word_count,
+    COALESCE(a.extracted_text, '') AS extracted_text,
+    COALESCE(a.paywalled, FALSE) AS paywalled,
+    y.years_ago::int AS years_ago
+FROM unnest($1::int[]) AS y(years_ago)
+JOIN links l
+  ON (l.created_at AT TIME ZONE 'UTC')::date = ($2::date - make_interval(years => y.years_ago))::date
+LEFT JOIN archives a ON a.link_id = l.id
+WHERE l.user_id = $3
+ORDER BY y.years_ago, l.created_at
+LIMIT $4::int
+`
+
+type ListOnThisDayLinksForUserParams struct {
+	Years    []int32
+	OnDate   pgtype.Date
+	UserID   pgtype.UUID
+	RowLimit int32
+}
+
+type ListOnThisDayLinksForUserRow struct {
+	ID            pgtype.UUID
+	Url           string
+	Title         pgtype.Text
+	SourceDomain  pgtype.Text
+	Favorite      bool
+	CreatedAt     pgtype.Timestamptz
+	ReadAt        pgtype.Timestamptz
+	ArchiveTitle  pgtype.Text
+	Byline        pgtype.Text
+	Lang          pgtype.Text
+	WordCount     int32
+	ExtractedText string
+	Paywalled     bool
+	YearsAgo      int32
+}
+
+func (q *Queries) ListOnThisDayLinksForUser(ctx context.Context, arg ListOnThisDayLinksForUserParams) ([]ListOnThisDayLinksForUserRow, error) {
+	rows, err := q.db.Query(ctx, listOnThisDayLinksForUser,
+		arg.Years,
+		arg.OnDate,
+		arg.UserID,
+		arg.RowLimit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListOnThisDayLinksForUserRow
+	for rows.Next() {
+		var i ListOnThisDayLinksForUserRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Url,
+			&i.Title,
+			&i.SourceDomain,
+			&i.Favorite,
+			&i.CreatedAt,
+			&i.ReadAt,
+			&i.ArchiveTitle,
+			&i.Byline,
+			&i.Lang,
+			&i.WordCount,
+			&i.ExtractedText,
+			&i.Paywalled,
+			&i.YearsAgo,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listRecommendationImpressionStats = `-- name: ListRecommendationImpressionStats :many
+SELECT
+    strategy,
+    surface,
+    COUNT(*)::int AS impressions,
+    COUNT(acted_at)::int AS acted,
+    COUNT(*) FILTER (WHERE action = 'open')::int AS opened,
+    COUNT(*) FILTER (WHERE action = 'read')::int AS read,
+    COUNT(*) FILTER (WHERE action = 'favorite')::int AS favorited
+FROM recommendation_impressions
+WHERE user_id = $1
+  AND shown_at >= $2::timestamptz
+GROUP BY strategy, surface
+ORDER BY strategy, surface
+`
+
+type ListRecommendationImpressionStatsParams struct {
+	UserID pgtype.UUID
+	Since  pgtype.Timestamptz
+}
+
+type ListRecommendationImpressionStatsRow struct {
+	Strategy    string
+	Surface     string
+	Impressions int32
+	Acted       int32
+	Opened      int32
+	Read        int32
+	Favorited   int32
+}
+
+func (q *Queries) ListRecommendationImpressionStats(ctx context.Context, arg ListRecommendationImpressionStatsParams) ([]ListRecommendationImpressionStatsRow, error) {
+	rows, err := q.db.Query(ctx, listRecommendationImpressionStats, arg.UserID, arg.Since)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListRecommendationImpressionStatsRow
+	for rows.Next() {
+		var i ListRecommendationImpressionStatsRow
+		if err := rows.Scan(
+			&i.Strategy,
+			&i.Surface,
+			&i.Impressions,
+			&i.Acted,
+			&i.Opened,
+			&i.Read,
+			&i.Favorited,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listRecommendationsForUser = `-- name: ListRecommendationsForUser :many
+SELECT
+    l.id,
+    l.url,
+    l.title,
+    l.source_domain,
     l.favorite,
+    l.created_at,
+    l.read_at,
     a.title AS archive_title,
     a.byline,
     a.lang,
     COALESCE(a.word_count, 0) AS word_count,
-    COALESCE(a.extracted_text, '') AS extracted_text
-FROM links l
+    COALESCE(a.extracted_text, '') AS extracted_text,
+    COALESCE(a.paywalled, FALSE) AS paywalled,
+    r.score,
+    r.updated_at,
+    r.strategy
+FROM recommendations r
+JOIN links l ON l.id = r.link_id
 LEFT JOIN archives a ON a.link_id = l.id
 WHERE l.user_id = $1
-  AND l.read_at IS NULL
+  AND ($2::text IS NULL OR l.source_domain = $2::text)
+  AND (
+    $3::int4[] IS NULL
+    OR (
+        SELECT COUNT(DISTINCT lt.tag_id)
+        FROM link_tags lt
+        WHERE lt.link_id = l.id
+          AND lt.tag_id = ANY($3::int4[])
+    ) = cardinality($3::int4[])
+  )
+  AND (
+    $4::int IS NULL
+    OR (r.score, r.updated_at, r.link_id) < (
+        $4::int,
+        $5::timestamptz,
+        $6::uuid
+    )
+  )
+ORDER BY r.score DESC, r.updated_at DESC, r.link_id DESC
+LIMIT $7::int OFFSET $8::int
 `
 
-type ListUnreadLinksForUserRow struct {
+type ListRecommendationsForUserParams struct {
+	UserID          pgtype.UUID
+	Domain          pgtype.Text
+	TagIds          []int32
+	CursorScore     pgtype.Int4
+	CursorUpdatedAt pgtype.Timestamptz
+	CursorLinkID    pgtype.UUID
+	PageLimit       int32
+	PageOffset      int32
+}
+
+type ListRecommendationsForUserRow struct {
 	ID            pgtype.UUID
-	UserID        pgtype.UUID
 	Url           string
 	Title         pgtype.Text
-	CreatedAt     pgtype.Timestamptz
+	SourceDomain  pgtype.Text
 	Favorite      bool
+	CreatedAt     pgtype.Timestamptz
+	ReadAt        pgtype.Timestamptz
 	ArchiveTitle  pgtype.Text
 	Byline        pgtype.Text
 	Lang          pgtype.Text
 	WordCount     int32
 	ExtractedText string
+	Paywalled     bool
+	Score         int32
+	UpdatedAt     pgtype.Timestamptz
+	Strategy      string
 }
 
-func (q *Queries) ListUnreadLinksForUser(ctx context.Context, userID pgtype.UUID) ([]ListUnreadLinksForUserRow, error) {
-	rows, err := q.db.Query(ctx, listUnreadLinksForUser, userID)
+func (q *Queries) ListRecommendationsForUser(ctx context.Context, arg ListRecommendationsForUserParams) ([]ListRecommendationsForUserRow, error) {
+	rows, err := q.db.Query(ctx, listRecommendationsForUser,
+		arg.UserID,
+		arg.Domain,
+		arg.TagIds,
+		arg.CursorScore,
+		arg.CursorUpdatedAt,
+		arg.CursorLinkID,
+		arg.PageLimit,
+		arg.PageOffset,
+	)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	var items []ListUnreadLinksForUserRow
+	var items []ListRecommendationsForUserRow
 	for rows.Next() {
-		var i ListUnreadLinksForUserRow
+		var i ListRecommendationsForUserRow
 		if err := rows.Scan(
 			&i.ID,
-			&i.UserID,
 			&i.Url,
 			&i.Title,
-			&i.CreatedAt,
+			&i.SourceDomain,
 			&i.Favorite,
+			&i.CreatedAt,
+			&i.ReadAt,
 			&i.ArchiveTitle,
 			&i.Byline,
 			&i.Lang,
 			&i.WordCount,
 			&i.ExtractedText,
+			&i.Paywalled,
+			&i.Score,
+			&i.UpdatedAt,
+			&i.Strategy,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listUnreadLinkBatchForUser = `-- name: ListUnreadLinkBatchForUser :many
+SELECT
+    l.id,
+    l.created_at,
+    l.favorite,
+    COALESCE(a.word_count, 0) AS word_count
+FROM links l
+LEFT JOIN archives a ON a.link_id = l.id
+WHERE l.user_id = $1
+  AND l.read_at IS NULL
+  AND (l.snoozed_until IS NULL OR l.snoozed_until <= $2::timestamptz)
+  AND (
+    $3::timestamptz IS NULL
+    OR l.last_surfaced_at IS NULL
+    OR l.last_surfaced_at < $3::timestamptz
+  )
+  AND NOT COALESCE((
+    SELECT dp.never_resurface
+    FROM domain_preferences dp
+    WHERE dp.user_id = l.user_id
+      AND domain_covers(dp.domain, url_host(l.url))
+    ORDER BY length(dp.domain) DESC
+    LIMIT 1
+  ), FALSE)
+  AND (
+    $4::timestamptz IS NULL
+    OR (l.created_at, l.id) > ($4::timestamptz, $5::uuid)
+  )
+ORDER BY l.created_at, l.id
+LIMIT $6::int
+`
+
+type ListUnreadLinkBatchForUserParams struct {
+	UserID         pgtype.UUID
+	Now            pgtype.Timestamptz
+	SurfacedBefore pgtype.Timestamptz
+	AfterCreatedAt pgtype.Timestamptz
+	AfterID        pgtype.UUID
+	BatchSize      int32
+}
+
+type ListUnreadLinkBatchForUserRow struct {
+	ID        pgtype.UUID
+	CreatedAt pgtype.Timestamptz
+	Favorite  bool
+	WordCount int32
+}
+
+func (q *Queries) ListUnreadLinkBatchForUser(ctx context.Context, arg ListUnreadLinkBatchForUserParams) ([]ListUnreadLinkBatchForUserRow, error) {
+	rows, err := q.db.Query(ctx, listUnreadLinkBatchForUser,
+		arg.UserID,
+		arg.Now,
+		arg.SurfacedBefore,
+		arg.AfterCreatedAt,
+		arg.AfterID,
+		arg.BatchSize,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListUnreadLinkBatchForUserRow
+	for rows.Next() {
+		var i ListUnreadLinkBatchForUserRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.Favorite,
+			&i.WordCount,
 		); err != nil {
 			return nil, err
 		}
@@ -195,21 +505,131 @@ func (q *Queries) ListUsersWithUnread(ctx context.Context) ([]pgtype.UUID, error
 	return items, nil
 }
 
+const markLinksSurfaced = `-- name: MarkLinksSurfaced :exec
+UPDATE links
+SET last_surfaced_at = $1::timestamptz
+WHERE id = ANY($2::uuid[])
+`
+
+type MarkLinksSurfacedParams struct {
+	SurfacedAt pgtype.Timestamptz
+	Ids        []pgtype.UUID
+}
+
+func (q *Queries) MarkLinksSurfaced(ctx context.Context, arg MarkLinksSurfacedParams) error {
+	_, err := q.db.Exec(ctx, markLinksSurfaced, arg.SurfacedAt, arg.Ids)
+	return err
+}
+
+const markRecommendationImpressionsActed = `-- name: MarkRecommendationImpressionsActed :many
+UPDATE recommendation_impressions
+SET action = $1,
+    acted_at = $2::timestamptz
+WHERE user_id = $3
+  AND link_id = ANY($4::uuid[])
+  AND acted_at IS NULL
+  AND shown_at > $5::timestamptz
+RETURNING strategy
+`
+
+type MarkRecommendationImpressionsActedParams struct {
+	Action     pgtype.Text
+	ActedAt    pgtype.Timestamptz
+	UserID     pgtype.UUID
+	LinkIds    []pgtype.UUID
+	ShownAfter pgtype.Timestamptz
+}
+
+func (q *Queries) MarkRecommendationImpressionsActed(ctx context.Context, arg MarkRecommendationImpressionsActedParams) ([]string, error) {
+	rows, err := q.db.Query(ctx, markRecommendationImpressionsActed,
+		arg.Action,
+		arg.ActedAt,
+		arg.UserID,
+		arg.LinkIds,
+		arg.ShownAfter,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []string
+	for rows.Next() {
+		var strategy string
+		if err := rows.Scan(&strategy); err != nil {
+			return nil, err
+		}
+		items = append(items, strategy)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const recordRecommendationImpressions = `-- name: RecordRecommendationImpressions :execrows
+INSERT INTO recommendation_impressions (user_id, link_id, strategy, surface, position, shown_at)
+SELECT $1, shown.link_id, $2, $3, shown.position, $4::timestamptz
+FROM unnest($5::uuid[], $6::int[]) AS shown(link_id, position)
+WHERE NOT EXISTS (
+    SELECT 1
+    FROM recommendation_impressions ri
+    WHERE ri.user_id = $1
+      AND ri.link_id = shown.link_id
+      AND ri.strategy = $2
+      AND ri.surface = $3
+      AND ri.acted_at IS NULL
+      AND ri.shown_at > $7::timestamptz
+)
+`
+
+type RecordRecommendationImpressionsParams struct {
+	UserID      pgtype.UUID
+	Strategy    string
+	Surface     string
+	ShownAt     pgtype.Timestamptz
+	LinkIds     []pgtype.UUID
+	Positions   []int32
+	RepeatAfter pgtype.Timestamptz
+}
+
+func (q *Queries) RecordRecommendationImpressions(ctx context.Context, arg RecordRecommendationImpressionsParams) (int64, error) {
+	result, err := q.db.Exec(ctx, recordRecommendationImpressions,
+		arg.UserID,
+		arg.Strategy,
+		arg.Surface,
+		arg.ShownAt,
+		arg.LinkIds,
+		arg.Positions,
+		arg.RepeatAfter,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
 const upsertRecommendation = `-- name: UpsertRecommendation :exec
-INSERT INTO recommendations (link_id, score, updated_at)
-VALUES ($1, $2, $3)
+INSERT INTO recommendations (link_id, score, strategy, updated_at)
+VALUES ($1, $2, $3, $4)
 ON CONFLICT (link_id) DO UPDATE
 SET score = EXCLUDED.score,
+    strategy = EXCLUDED.strategy,
     updated_at = EXCLUDED.updated_at
 `
 
 type UpsertRecommendationParams struct {
 	LinkID    pgtype.UUID
 	Score     int32
+	Strategy  string
 	UpdatedAt pgtype.Timestamptz
 }
 
 func (q *Queries) UpsertRecommendation(ctx context.Context, arg UpsertRecommendationParams) error {
-	_, err := q.db.Exec(ctx, upsertRecommendation, arg.LinkID, arg.Score, arg.UpdatedAt)
+	_, err := q.db.Exec(ctx, upsertRecommendation,
+		arg.LinkID,
+		arg.Score,
+		arg.Strategy,
+		arg.UpdatedAt,
+	)
 	return err
 }
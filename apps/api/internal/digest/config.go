@@ -1,7 +1,11 @@
 package digest
 
 import (
+	"context"
+	"crypto/tls"
 	"fmt"
+	"net"
+	"net/smtp"
 	"net/url"
 	"strconv"
 	"strings"
@@ -15,6 +19,7 @@ type Config struct {
 	Sender    string `envconfig:"DIGEST_SENDER" required:"true"`
 	Recipient string `envconfig:"DIGEST_RECIPIENT" required:"true"`
 	SMTPURL   string `envconfig:"SMTP_URL" required:"true"`
+	OnThisDay bool   `envconfig:"DIGEST_ON_THIS_DAY" default:"false"`
 
 	Transport Transport
 }
@@ -102,3 +107,43 @@ func ParseSMTPURL(raw string) (Transport, error) {
 		return Transport{}, fmt.Errorf("unsupported SMTP_URL scheme %q", parsed.Scheme)
 	}
 }
+
+// CheckTransport connects to the SMTP server and authenticates the way
+// sending a digest would, then quits without sending anything. The log
+// transport always passes.
+func CheckTransport(ctx context.Context, transport Transport) error {
+	if transport.Scheme != "smtp" {
+		return nil
+	}
+
+	addr := net.JoinHostPort(transport.Host, strconv.Itoa(transport.Port))
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return fmt.Errorf("connect to %s: %w", addr, err)
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	client, err := smtp.NewClient(conn, transport.Host)
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("smtp handshake with %s: %w", addr, err)
+	}
+	defer client.Close()
+
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		if err := client.StartTLS(&tls.Config{ServerName: transport.Host}); err != nil {
+			return fmt.Errorf("starttls with %s: %w", addr, err)
+		}
+	}
+	if transport.Username != "" {
+		if ok, _ := client.Extension("AUTH"); ok {
+			if err := client.Auth(smtp.PlainAuth("", transport.Username, transport.Password, transport.Host)); err != nil {
+				return fmt.Errorf("authenticate with %s: %w", addr, err)
+			}
+		}
+	}
+	return client.Quit()
+}
@@ -13,11 +13,18 @@ import (
 
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/example/keepstack/apps/api/internal/db"
+	"github.com/example/keepstack/apps/api/internal/goals"
 )
 
 // ErrNoUnreadLinks is returned when there are no unread links to include in the digest.
 var ErrNoUnreadLinks = errors.New("no unread links")
 
+// OnThisDayYears lists how many years back the "On this day" view looks for
+// links saved on the same calendar day.
+var OnThisDayYears = []int{1, 2, 5}
+
 // Service encapsulates the logic required to build and dispatch the digest email.
 type Service struct {
 	pool   *pgxpool.Pool
@@ -54,7 +61,23 @@ func (s *Service) Send(ctx context.Context, userID uuid.UUID) (int, string, erro
 		return 0, "", ErrNoUnreadLinks
 	}
 
-	htmlBody, err := s.renderHTML(links)
+	var memories []onThisDayLink
+	if s.config.OnThisDay {
+		memories, err = s.fetchOnThisDayLinks(ctx, userID, time.Now().UTC())
+		if err != nil {
+			return 0, "", fmt.Errorf("fetch on this day links: %w", err)
+		}
+	}
+
+	// The stats block is a nicety; a digest still goes out without it.
+	var stats *goals.Summary
+	if summary, err := goals.Summarize(ctx, db.New(s.pool), userID, time.Now()); err != nil {
+		log.Printf("keepstack digest: read reading stats: %v", err)
+	} else {
+		stats = &summary
+	}
+
+	htmlBody, err := s.renderHTML(links, memories, stats)
 	if err != nil {
 		return 0, "", fmt.Errorf("render digest: %w", err)
 	}
@@ -63,24 +86,41 @@ func (s *Service) Send(ctx context.Context, userID uuid.UUID) (int, string, erro
 		return 0, "", fmt.Errorf("send digest email: %w", err)
 	}
 
+	if err := s.markSurfaced(ctx, links); err != nil {
+		log.Printf("keepstack digest: record surfaced links: %v", err)
+	}
+	if err := s.recordImpressions(ctx, userID, links); err != nil {
+		log.Printf("keepstack digest: record impressions: %v", err)
+	}
+
 	return len(links), htmlBody, nil
 }
 
 type digestLink struct {
+	ID        uuid.UUID
 	Title     string
 	URL       string
 	Source    string
 	Byline    string
 	CreatedAt time.Time
+	// Paywalled is set when the link's archive was cut off by a paywall.
+	Paywalled bool
+}
+
+type onThisDayLink struct {
+	digestLink
+	YearsAgo int
 }
 
 const unreadLinksQuery = `
 SELECT
+    l.id,
     l.url,
     COALESCE(NULLIF(l.title, ''), COALESCE(NULLIF(a.title, ''), 'Untitled')) AS title,
     COALESCE(l.source_domain, '') AS source,
     COALESCE(a.byline, '') AS byline,
-    l.created_at
+    l.created_at,
+    COALESCE(a.paywalled, FALSE) AS paywalled
 FROM links l
 LEFT JOIN archives a ON a.link_id = l.id
 WHERE l.user_id = $1
@@ -99,7 +139,84 @@ func (s *Service) fetchUnreadLinks(ctx context.Context, userID uuid.UUID) ([]dig
 	var links []digestLink
 	for rows.Next() {
 		var link digestLink
-		if err := rows.Scan(&link.URL, &link.Title, &link.Source, &link.Byline, &link.CreatedAt); err != nil {
+		if err := rows.Scan(&link.ID, &link.URL, &link.Title, &link.Source, &link.Byline, &link.CreatedAt, &link.Paywalled); err != nil {
+			return nil, err
+		}
+		links = append(links, link)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return links, nil
+}
+
+const markSurfacedQuery = `
+UPDATE links
+SET last_surfaced_at = NOW()
+WHERE id = ANY($1::uuid[]);
+`
+
+// markSurfaced records that links went out in a digest so the resurfacer can
+// apply its cool-down to them.
+func (s *Service) markSurfaced(ctx context.Context, links []digestLink) error {
+	ids := make([]string, 0, len(links))
+	for _, link := range links {
+		ids = append(ids, link.ID.String())
+	}
+	_, err := s.pool.Exec(ctx, markSurfacedQuery, ids)
+	return err
+}
+
+// digestStrategy names how the digest picks links, the oldest unread first,
+// in recommendation impression stats.
+const digestStrategy = "oldest_unread"
+
+const recordImpressionsQuery = `
+INSERT INTO recommendation_impressions (user_id, link_id, strategy, surface, position)
+SELECT $1, shown.link_id, $2, 'digest', shown.position
+FROM unnest($3::uuid[]) WITH ORDINALITY AS shown(link_id, position);
+`
+
+// recordImpressions records the links as shown in a digest, so their read
+// rate can be compared with recommendations served by the API.
+func (s *Service) recordImpressions(ctx context.Context, userID uuid.UUID, links []digestLink) error {
+	ids := make([]string, 0, len(links))
+	for _, link := range links {
+		ids = append(ids, link.ID.String())
+	}
+	_, err := s.pool.Exec(ctx, recordImpressionsQuery, userID, digestStrategy, ids)
+	return err
+}
+
+const onThisDayQuery = `
+SELECT
+    l.url,
+    COALESCE(NULLIF(l.title, ''), COALESCE(NULLIF(a.title, ''), 'Untitled')) AS title,
+    COALESCE(l.source_domain, '') AS source,
+    COALESCE(a.byline, '') AS byline,
+    l.created_at,
+    COALESCE(a.paywalled, FALSE) AS paywalled,
+    y.years_ago
+FROM unnest($2::int[]) AS y(years_ago)
+JOIN links l
+  ON (l.created_at AT TIME ZONE 'UTC')::date = ($3::date - make_interval(years => y.years_ago))::date
+LEFT JOIN archives a ON a.link_id = l.id
+WHERE l.user_id = $1
+ORDER BY y.years_ago, l.created_at
+LIMIT $4;
+`
+
+func (s *Service) fetchOnThisDayLinks(ctx context.Context, userID uuid.UUID, day time.Time) ([]onThisDayLink, error) {
+	rows, err := s.pool.Query(ctx, onThisDayQuery, userID, OnThisDayYears, day.Format(time.DateOnly), s.config.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var links []onThisDayLink
+	for rows.Next() {
+		var link onThisDayLink
+		if err := rows.Scan(&link.URL, &link.Title, &link.Source, &link.Byline, &link.CreatedAt, &link.Paywalled, &link.YearsAgo); err != nil {
 			return nil, err
 		}
 		links = append(links, link)
@@ -110,15 +227,19 @@ func (s *Service) fetchUnreadLinks(ctx context.Context, userID uuid.UUID) ([]dig
 	return links, nil
 }
 
-func (s *Service) renderHTML(links []digestLink) (string, error) {
+func (s *Service) renderHTML(links []digestLink, onThisDay []onThisDayLink, stats *goals.Summary) (string, error) {
 	data := struct {
 		GeneratedAt time.Time
 		Links       []digestLink
 		Count       int
+		OnThisDay   []onThisDayLink
+		Stats       *goals.Summary
 	}{
 		GeneratedAt: time.Now().UTC(),
 		Links:       links,
 		Count:       len(links),
+		OnThisDay:   onThisDay,
+		Stats:       stats,
 	}
 
 	var buf bytes.Buffer
@@ -130,30 +251,37 @@ func (s *Service) renderHTML(links []digestLink) (string, error) {
 
 func (s *Service) dispatch(htmlBody string, count int) error {
 	subject := fmt.Sprintf("Keepstack Digest (%d links)", count)
+	return SendMail(s.config.Transport, s.config.Sender, s.config.Recipient, subject, "text/html", htmlBody)
+}
+
+// SendMail delivers a single message through the transport parsed from an
+// SMTP_URL-style string. Other jobs reuse it so every email keepstack sends
+// honours the same log/smtp configuration.
+func SendMail(transport Transport, sender, recipient, subject, contentType, body string) error {
 	msg := bytes.Buffer{}
-	msg.WriteString(fmt.Sprintf("From: %s\r\n", s.config.Sender))
-	msg.WriteString(fmt.Sprintf("To: %s\r\n", s.config.Recipient))
+	msg.WriteString(fmt.Sprintf("From: %s\r\n", sender))
+	msg.WriteString(fmt.Sprintf("To: %s\r\n", recipient))
 	msg.WriteString(fmt.Sprintf("Subject: %s\r\n", subject))
 	msg.WriteString("MIME-Version: 1.0\r\n")
-	msg.WriteString("Content-Type: text/html; charset=UTF-8\r\n")
+	msg.WriteString(fmt.Sprintf("Content-Type: %s; charset=UTF-8\r\n", contentType))
 	msg.WriteString("\r\n")
-	msg.WriteString(htmlBody)
+	msg.WriteString(body)
 
-	switch s.config.Transport.Scheme {
+	switch transport.Scheme {
 	case "log":
 		encoded := base64.StdEncoding.EncodeToString(msg.Bytes())
-		log.Printf("keepstack digest log transport: subject=%q recipient=%s payload_base64=%s", subject, s.config.Recipient, encoded)
+		log.Printf("keepstack mail log transport: subject=%q recipient=%s payload_base64=%s", subject, recipient, encoded)
 		return nil
 	case "smtp":
 		var auth smtp.Auth
-		if s.config.Transport.Username != "" {
-			auth = smtp.PlainAuth("", s.config.Transport.Username, s.config.Transport.Password, s.config.Transport.Host)
+		if transport.Username != "" {
+			auth = smtp.PlainAuth("", transport.Username, transport.Password, transport.Host)
 		}
 
-		addr := fmt.Sprintf("%s:%d", s.config.Transport.Host, s.config.Transport.Port)
-		return smtp.SendMail(addr, auth, s.config.Sender, []string{s.config.Recipient}, msg.Bytes())
+		addr := fmt.Sprintf("%s:%d", transport.Host, transport.Port)
+		return smtp.SendMail(addr, auth, sender, []string{recipient}, msg.Bytes())
 	default:
-		return fmt.Errorf("unsupported transport %q", s.config.Transport.Scheme)
+		return fmt.Errorf("unsupported transport %q", transport.Scheme)
 	}
 }
 
@@ -166,7 +294,8 @@ const digestTemplate = `<!DOCTYPE html>
 body { font-family: -apple-system, BlinkMacSystemFont, "Segoe UI", sans-serif; color: #1f2933; background-color: #f9fafb; margin: 0; padding: 24px; }
 .container { max-width: 640px; margin: 0 auto; background-color: #ffffff; border-radius: 12px; padding: 24px; box-shadow: 0 10px 30px rgba(15, 23, 42, 0.08); }
 h1 { margin-top: 0; font-size: 24px; }
-ol { padding-left: 20px; }
+h2 { font-size: 18px; margin-top: 32px; }
+ol, ul { padding-left: 20px; }
 li { margin-bottom: 18px; }
 a { color: #2563eb; text-decoration: none; }
 a:hover { text-decoration: underline; }
@@ -182,10 +311,37 @@ a:hover { text-decoration: underline; }
     <li>
       <div><a href="{{ .URL }}">{{ .Title }}</a></div>
       {{- if .Byline }}<div class="meta">{{ .Byline }}</div>{{ end }}
-      <div class="meta">Saved {{ formatDate .CreatedAt }}{{ if .Source }} • {{ .Source }}{{ end }}</div>
+      <div class="meta">Saved {{ formatDate .CreatedAt }}{{ if .Source }} • {{ .Source }}{{ end }}{{ if .Paywalled }} • Paywalled, partial archive{{ end }}</div>
     </li>
   {{- end }}
   </ol>
+  {{- with .Stats }}
+  {{- if or .Articles .Goals .Streak.Longest }}
+  <h2>Your reading</h2>
+  <p>This week you read {{ .Articles }} link{{ if ne .Articles 1 }}s{{ end }} ({{ .Minutes }} minute{{ if ne .Minutes 1 }}s{{ end }}).</p>
+  {{- if .Goals }}
+  <ul>
+  {{- range .Goals }}
+    <li>{{ .Progress }} of {{ .WeeklyTarget }} {{ .Metric }} this week{{ if .Met }} — goal met{{ end }}</li>
+  {{- end }}
+  </ul>
+  {{- end }}
+  {{- if .Streak.Longest }}
+  <p class="meta">Reading streak: {{ .Streak.Current }} day{{ if ne .Streak.Current 1 }}s{{ end }} (longest {{ .Streak.Longest }}).</p>
+  {{- end }}
+  {{- end }}
+  {{- end }}
+  {{- if .OnThisDay }}
+  <h2>On this day</h2>
+  <ul>
+  {{- range .OnThisDay }}
+    <li>
+      <div><a href="{{ .URL }}">{{ .Title }}</a></div>
+      <div class="meta">Saved {{ .YearsAgo }} year{{ if ne .YearsAgo 1 }}s{{ end }} ago{{ if .Source }} • {{ .Source }}{{ end }}{{ if .Paywalled }} • Paywalled, partial archive{{ end }}</div>
+    </li>
+  {{- end }}
+  </ul>
+  {{- end }}
   <p class="meta">Generated at {{ formatDate .GeneratedAt }}.</p>
 </div>
 </body>
@@ -4,6 +4,8 @@ import (
 	"strings"
 	"testing"
 	"time"
+
+	"github.com/example/keepstack/apps/api/internal/goals"
 )
 
 func TestRenderHTML(t *testing.T) {
@@ -33,10 +35,11 @@ func TestRenderHTML(t *testing.T) {
 			URL:       "https://another.test",
 			Source:    "another.test",
 			CreatedAt: time.Date(2024, time.January, 2, 8, 30, 0, 0, time.UTC),
+			Paywalled: true,
 		},
 	}
 
-	html, err := svc.renderHTML(links)
+	html, err := svc.renderHTML(links, nil, nil)
 	if err != nil {
 		t.Fatalf("render html: %v", err)
 	}
@@ -46,9 +49,88 @@ func TestRenderHTML(t *testing.T) {
 		"https://example.com",
 		"Editor",
 		"another.test",
+		"Paywalled, partial archive",
+	} {
+		if !strings.Contains(html, expected) {
+			t.Fatalf("expected html to contain %q", expected)
+		}
+	}
+	if strings.Count(html, "Paywalled, partial archive") != 1 {
+		t.Fatalf("expected only the paywalled link to be flagged")
+	}
+	if strings.Contains(html, "On this day") {
+		t.Fatalf("expected on this day section to be omitted")
+	}
+}
+
+func TestRenderHTMLOnThisDay(t *testing.T) {
+	svc, err := New(nil, Config{Limit: 5, Transport: Transport{Scheme: "log"}})
+	if err != nil {
+		t.Fatalf("new service: %v", err)
+	}
+
+	links := []digestLink{{Title: "Unread", URL: "https://unread.test", CreatedAt: time.Date(2024, time.May, 1, 9, 0, 0, 0, time.UTC)}}
+	memories := []onThisDayLink{
+		{digestLink: digestLink{Title: "Memory", URL: "https://memory.test", Source: "memory.test"}, YearsAgo: 1},
+		{digestLink: digestLink{Title: "Older", URL: "https://older.test"}, YearsAgo: 5},
+	}
+
+	html, err := svc.renderHTML(links, memories, nil)
+	if err != nil {
+		t.Fatalf("render html: %v", err)
+	}
+
+	for _, expected := range []string{
+		"On this day",
+		"https://memory.test",
+		"Saved 1 year ago",
+		"Saved 5 years ago",
+	} {
+		if !strings.Contains(html, expected) {
+			t.Fatalf("expected html to contain %q", expected)
+		}
+	}
+}
+
+func TestRenderHTMLStats(t *testing.T) {
+	svc, err := New(nil, Config{Limit: 5, Transport: Transport{Scheme: "log"}})
+	if err != nil {
+		t.Fatalf("new service: %v", err)
+	}
+
+	links := []digestLink{{Title: "Unread", URL: "https://unread.test", CreatedAt: time.Date(2024, time.May, 1, 9, 0, 0, 0, time.UTC)}}
+	stats := &goals.Summary{
+		Articles: 4,
+		Minutes:  37,
+		Goals: []goals.Goal{
+			{Metric: goals.MetricArticles, WeeklyTarget: 3, Progress: 4, Met: true},
+			{Metric: goals.MetricMinutes, WeeklyTarget: 60, Progress: 37},
+		},
+		Streak: goals.Streak{Current: 1, Longest: 6},
+	}
+
+	html, err := svc.renderHTML(links, nil, stats)
+	if err != nil {
+		t.Fatalf("render html: %v", err)
+	}
+	for _, expected := range []string{
+		"Your reading",
+		"This week you read 4 links (37 minutes)",
+		"4 of 3 articles this week — goal met",
+		"37 of 60 minutes this week</li>",
+		"Reading streak: 1 day (longest 6)",
 	} {
 		if !strings.Contains(html, expected) {
 			t.Fatalf("expected html to contain %q", expected)
 		}
 	}
+
+	// Someone who has never read anything gets no stats block.
+	html, err = svc.renderHTML(links, nil, &goals.Summary{})
+	if err != nil {
+		t.Fatalf("render html: %v", err)
+	}
+	if strings.Contains(html, "Your reading") {
+		t.Fatalf("expected the stats block to be omitted")
+	}
 }
@@ -0,0 +1,150 @@
+// Package goals tracks reading against a user's weekly goals. Progress and
+// streaks are counted from links.read_at: a link read this week counts
+// towards the articles goal, and its estimated reading time towards the
+// minutes goal. Weeks start on Monday and days are UTC days.
+package goals
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"github.com/example/keepstack/apps/api/internal/db"
+)
+
+// Metrics a goal can be set on, as stored in reading_goals.metric.
+const (
+	MetricArticles = "articles"
+	MetricMinutes  = "minutes"
+)
+
+// WordsPerMinute is the reading speed behind reading time estimates.
+const WordsPerMinute = 230
+
+// Queries is what Summarize reads from; *db.Queries satisfies it.
+type Queries interface {
+	ListReadingGoals(context.Context, pgtype.UUID) ([]db.ReadingGoal, error)
+	GetReadingProgress(context.Context, db.GetReadingProgressParams) (db.GetReadingProgressRow, error)
+	ListReadDays(context.Context, pgtype.UUID) ([]pgtype.Date, error)
+}
+
+// Goal is a weekly goal and how far this week's reading has got towards it.
+type Goal struct {
+	Metric       string `json:"metric"`
+	WeeklyTarget int    `json:"weekly_target"`
+	Progress     int    `json:"progress"`
+	Met          bool   `json:"met"`
+}
+
+// Streak counts consecutive days with at least one link read. Current
+// includes today once something is read, and until then still counts the
+// run that ended yesterday.
+type Streak struct {
+	Current    int        `json:"current"`
+	Longest    int        `json:"longest"`
+	LastReadOn *time.Time `json:"last_read_on,omitempty"`
+}
+
+// Summary is a user's reading this week, their goals, and their streak.
+type Summary struct {
+	WeekStart time.Time `json:"week_start"`
+	Articles  int       `json:"articles"`
+	Minutes   int       `json:"minutes"`
+	Goals     []Goal    `json:"goals"`
+	Streak    Streak    `json:"streak"`
+}
+
+// Summarize reads userID's goals and reading as of now.
+func Summarize(ctx context.Context, q Queries, userID uuid.UUID, now time.Time) (Summary, error) {
+	pgUserID := pgtype.UUID{Bytes: userID, Valid: true}
+	weekStart := WeekStart(now)
+
+	progress, err := q.GetReadingProgress(ctx, db.GetReadingProgressParams{
+		WordsPerMinute: WordsPerMinute,
+		UserID:         pgUserID,
+		Since:          pgtype.Timestamptz{Time: weekStart, Valid: true},
+	})
+	if err != nil {
+		return Summary{}, fmt.Errorf("read progress: %w", err)
+	}
+	rows, err := q.ListReadingGoals(ctx, pgUserID)
+	if err != nil {
+		return Summary{}, fmt.Errorf("list goals: %w", err)
+	}
+	days, err := q.ListReadDays(ctx, pgUserID)
+	if err != nil {
+		return Summary{}, fmt.Errorf("list read days: %w", err)
+	}
+
+	summary := Summary{
+		WeekStart: weekStart,
+		Articles:  int(progress.Articles),
+		Minutes:   int(progress.Minutes),
+		Goals:     make([]Goal, 0, len(rows)),
+		Streak:    Streaks(days, now),
+	}
+	for _, row := range rows {
+		goal := Goal{Metric: row.Metric, WeeklyTarget: int(row.WeeklyTarget)}
+		switch row.Metric {
+		case MetricArticles:
+			goal.Progress = summary.Articles
+		case MetricMinutes:
+			goal.Progress = summary.Minutes
+		}
+		goal.Met = goal.Progress >= goal.WeeklyTarget
+		summary.Goals = append(summary.Goals, goal)
+	}
+	return summary, nil
+}
+
+// WeekStart returns midnight UTC on the Monday of t's week.
+func WeekStart(t time.Time) time.Time {
+	day := startOfDay(t)
+	offset := (int(day.Weekday()) + 6) % 7
+	return day.AddDate(0, 0, -offset)
+}
+
+// Streaks works out the current and longest streaks from the days a user
+// read something, given newest first as ListReadDays returns them.
+func Streaks(days []pgtype.Date, now time.Time) Streak {
+	var streak Streak
+	if len(days) == 0 {
+		return streak
+	}
+	last := startOfDay(days[0].Time)
+	streak.LastReadOn = &last
+
+	// The first run of consecutive days is the current streak, unless it
+	// ended before yesterday.
+	run, first := 0, true
+	var prev time.Time
+	for i, day := range days {
+		current := startOfDay(day.Time)
+		if i > 0 && current.Equal(prev.AddDate(0, 0, -1)) {
+			run++
+		} else {
+			if i > 0 && first {
+				streak.Current = run
+				first = false
+			}
+			run = 1
+		}
+		streak.Longest = max(streak.Longest, run)
+		prev = current
+	}
+	if first {
+		streak.Current = run
+	}
+	if last.Before(startOfDay(now).AddDate(0, 0, -1)) {
+		streak.Current = 0
+	}
+	return streak
+}
+
+func startOfDay(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
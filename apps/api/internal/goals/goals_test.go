@@ -0,0 +1,122 @@
+package goals
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"github.com/example/keepstack/apps/api/internal/db"
+)
+
+// now is a Wednesday afternoon.
+var now = time.Date(2025, time.June, 11, 15, 30, 0, 0, time.UTC)
+
+func daysAgo(offsets ...int) []pgtype.Date {
+	days := make([]pgtype.Date, 0, len(offsets))
+	for _, offset := range offsets {
+		days = append(days, pgtype.Date{Time: startOfDay(now).AddDate(0, 0, -offset), Valid: true})
+	}
+	return days
+}
+
+func TestStreaks(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		days    []pgtype.Date
+		current int
+		longest int
+	}{
+		{name: "never read"},
+		{name: "read today", days: daysAgo(0), current: 1, longest: 1},
+		{name: "run through today", days: daysAgo(0, 1, 2), current: 3, longest: 3},
+		{name: "run ending yesterday still counts", days: daysAgo(1, 2), current: 2, longest: 2},
+		{name: "run ended two days ago", days: daysAgo(2, 3, 4), current: 0, longest: 3},
+		{name: "longer run earlier", days: daysAgo(0, 1, 5, 6, 7, 8), current: 2, longest: 4},
+		{name: "gap breaks the current run", days: daysAgo(0, 2, 3, 4), current: 1, longest: 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got := Streaks(tt.days, now)
+			if got.Current != tt.current || got.Longest != tt.longest {
+				t.Fatalf("expected current %d longest %d, got %+v", tt.current, tt.longest, got)
+			}
+			if (got.LastReadOn != nil) != (len(tt.days) > 0) {
+				t.Fatalf("unexpected last read day %v", got.LastReadOn)
+			}
+		})
+	}
+}
+
+func TestWeekStart(t *testing.T) {
+	t.Parallel()
+
+	want := time.Date(2025, time.June, 9, 0, 0, 0, 0, time.UTC)
+	for _, day := range []time.Time{
+		want,
+		now,
+		time.Date(2025, time.June, 15, 23, 59, 0, 0, time.UTC),
+	} {
+		if got := WeekStart(day); !got.Equal(want) {
+			t.Fatalf("week of %s: expected %s, got %s", day, want, got)
+		}
+	}
+}
+
+type fakeQueries struct {
+	goals    []db.ReadingGoal
+	progress db.GetReadingProgressRow
+	days     []pgtype.Date
+	since    time.Time
+}
+
+func (f *fakeQueries) ListReadingGoals(context.Context, pgtype.UUID) ([]db.ReadingGoal, error) {
+	return f.goals, nil
+}
+
+func (f *fakeQueries) GetReadingProgress(_ context.Context, arg db.GetReadingProgressParams) (db.GetReadingProgressRow, error) {
+	f.since = arg.Since.Time
+	return f.progress, nil
+}
+
+func (f *fakeQueries) ListReadDays(context.Context, pgtype.UUID) ([]pgtype.Date, error) {
+	return f.days, nil
+}
+
+func TestSummarize(t *testing.T) {
+	t.Parallel()
+
+	q := &fakeQueries{
+		goals: []db.ReadingGoal{
+			{Metric: MetricArticles, WeeklyTarget: 5},
+			{Metric: MetricMinutes, WeeklyTarget: 45},
+		},
+		progress: db.GetReadingProgressRow{Articles: 5, Minutes: 30},
+		days:     daysAgo(0, 1),
+	}
+
+	summary, err := Summarize(context.Background(), q, uuid.New(), now)
+	if err != nil {
+		t.Fatalf("summarize: %v", err)
+	}
+	if !q.since.Equal(WeekStart(now)) || !summary.WeekStart.Equal(q.since) {
+		t.Fatalf("expected progress since the start of the week, got %s", q.since)
+	}
+	want := []Goal{
+		{Metric: MetricArticles, WeeklyTarget: 5, Progress: 5, Met: true},
+		{Metric: MetricMinutes, WeeklyTarget: 45, Progress: 30},
+	}
+	if len(summary.Goals) != len(want) || summary.Goals[0] != want[0] || summary.Goals[1] != want[1] {
+		t.Fatalf("expected goals %+v, got %+v", want, summary.Goals)
+	}
+	if summary.Streak.Current != 2 {
+		t.Fatalf("expected a 2 day streak, got %+v", summary.Streak)
+	}
+}
@@ -0,0 +1,166 @@
+package grpcapi
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"path"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgerrcode"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/prometheus/client_golang/prometheus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/example/keepstack/apps/api/internal/db"
+	"github.com/example/keepstack/apps/api/internal/queue"
+	"github.com/example/keepstack/messages"
+	ingestv1 "github.com/example/keepstack/proto/ingest/v1"
+)
+
+// statusStore persists the progress the worker reports.
+type statusStore interface {
+	UpsertLinkIngestStatus(context.Context, db.UpsertLinkIngestStatusParams) (pgtype.UUID, error)
+	UpsertLinkIngestDiagnostics(context.Context, db.UpsertLinkIngestDiagnosticsParams) error
+}
+
+// Server implements the internal IngestService. The API owns the status
+// records, so the worker reports progress without knowing how it is stored.
+type Server struct {
+	ingestv1.UnimplementedIngestServiceServer
+
+	store     statusStore
+	publisher queue.Publisher
+	logger    *log.Logger
+}
+
+// New builds a Server. A nil publisher skips relaying status changes to live
+// update streams.
+func New(store statusStore, publisher queue.Publisher, logger *log.Logger) *Server {
+	return &Server{store: store, publisher: publisher, logger: logger}
+}
+
+// NewGRPCServer returns a gRPC server with the ingest service registered and
+// request durations recorded in duration.
+func NewGRPCServer(srv *Server, duration *prometheus.HistogramVec) *grpc.Server {
+	server := grpc.NewServer(grpc.ChainUnaryInterceptor(UnaryMetrics(duration)))
+	ingestv1.RegisterIngestServiceServer(server, srv)
+	return server
+}
+
+// UnaryMetrics records each call's duration labelled by method and status
+// code.
+func UnaryMetrics(duration *prometheus.HistogramVec) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		start := time.Now()
+		resp, err := handler(ctx, req)
+		duration.WithLabelValues(path.Base(info.FullMethod), status.Code(err).String()).Observe(time.Since(start).Seconds())
+		return resp, err
+	}
+}
+
+// statusNames maps reported statuses to the values stored and returned by
+// GET /api/links/:id/status.
+var statusNames = map[ingestv1.LinkStatus]string{
+	ingestv1.LinkStatus_LINK_STATUS_FETCHING:   "fetching",
+	ingestv1.LinkStatus_LINK_STATUS_PARSING:    "parsing",
+	ingestv1.LinkStatus_LINK_STATUS_PERSISTING: "persisting",
+	ingestv1.LinkStatus_LINK_STATUS_INGESTED:   "ingested",
+	ingestv1.LinkStatus_LINK_STATUS_FAILED:     "failed",
+}
+
+// linkStatusEvent is the data carried by link.status events.
+type linkStatusEvent = messages.LinkStatus
+
+// UpdateLinkStatus records the stage a link has reached and relays it to the
+// owner's live update streams.
+func (s *Server) UpdateLinkStatus(ctx context.Context, req *ingestv1.UpdateLinkStatusRequest) (*ingestv1.UpdateLinkStatusResponse, error) {
+	linkID, err := parseLinkID(req.GetLinkId())
+	if err != nil {
+		return nil, err
+	}
+	name, ok := statusNames[req.GetStatus()]
+	if !ok {
+		return nil, status.Errorf(codes.InvalidArgument, "unsupported status %s", req.GetStatus())
+	}
+
+	userID, err := s.store.UpsertLinkIngestStatus(ctx, db.UpsertLinkIngestStatusParams{
+		LinkID:      linkID,
+		Status:      name,
+		FailedStage: optionalText(req.GetFailedStage()),
+		Error:       optionalText(req.GetError()),
+	})
+	if err != nil {
+		return nil, storeError(err, "update link status")
+	}
+
+	if s.publisher != nil && userID.Valid {
+		data, _ := json.Marshal(linkStatusEvent{Status: name, FailedStage: req.GetFailedStage(), Error: req.GetError()})
+		event := queue.Event{
+			Type:   queue.EventLinkStatus,
+			UserID: uuid.UUID(userID.Bytes).String(),
+			LinkID: req.GetLinkId(),
+			Data:   data,
+		}
+		// The status is stored; a lost live update only delays clients
+		// until their next refresh.
+		if err := s.publisher.PublishEvent(ctx, event); err != nil {
+			s.logger.Printf("publish %s event: %v", queue.EventLinkStatus, err)
+		}
+	}
+	return &ingestv1.UpdateLinkStatusResponse{}, nil
+}
+
+// ReportDiagnostics records fetch and parse measurements for a link.
+func (s *Server) ReportDiagnostics(ctx context.Context, req *ingestv1.ReportDiagnosticsRequest) (*ingestv1.ReportDiagnosticsResponse, error) {
+	linkID, err := parseLinkID(req.GetLinkId())
+	if err != nil {
+		return nil, err
+	}
+
+	params := db.UpsertLinkIngestDiagnosticsParams{
+		LinkID:       linkID,
+		FinalUrl:     optionalText(req.GetFinalUrl()),
+		FetchBytes:   pgtype.Int8{Int64: req.GetFetchBytes(), Valid: true},
+		Lang:         optionalText(req.GetLang()),
+		LangDetected: pgtype.Bool{Bool: req.GetLangDetected(), Valid: true},
+		WordCount:    pgtype.Int4{Int32: req.GetWordCount(), Valid: true},
+	}
+	if d := req.GetFetchDuration(); d != nil {
+		params.FetchMs = pgtype.Int4{Int32: int32(d.AsDuration().Milliseconds()), Valid: true}
+	}
+	if d := req.GetParseDuration(); d != nil {
+		params.ParseMs = pgtype.Int4{Int32: int32(d.AsDuration().Milliseconds()), Valid: true}
+	}
+	if err := s.store.UpsertLinkIngestDiagnostics(ctx, params); err != nil {
+		return nil, storeError(err, "report diagnostics")
+	}
+	return &ingestv1.ReportDiagnosticsResponse{}, nil
+}
+
+func parseLinkID(raw string) (pgtype.UUID, error) {
+	id, err := uuid.Parse(raw)
+	if err != nil {
+		return pgtype.UUID{}, status.Error(codes.InvalidArgument, "invalid link id")
+	}
+	return pgtype.UUID{Bytes: id, Valid: true}, nil
+}
+
+func optionalText(value string) pgtype.Text {
+	return pgtype.Text{String: value, Valid: value != ""}
+}
+
+// storeError maps a write that references a missing link, typically one
+// deleted mid-ingest, to NotFound.
+func storeError(err error, action string) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == pgerrcode.ForeignKeyViolation {
+		return status.Error(codes.NotFound, "link not found")
+	}
+	return status.Errorf(codes.Internal, "%s: %v", action, err)
+}
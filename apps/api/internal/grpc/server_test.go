@@ -0,0 +1,220 @@
+package grpcapi
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgerrcode"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/types/known/durationpb"
+
+	"github.com/example/keepstack/apps/api/internal/db"
+	"github.com/example/keepstack/apps/api/internal/queue"
+	ingestv1 "github.com/example/keepstack/proto/ingest/v1"
+)
+
+type stubStore struct {
+	statuses    []db.UpsertLinkIngestStatusParams
+	diagnostics []db.UpsertLinkIngestDiagnosticsParams
+	userID      uuid.UUID
+	err         error
+}
+
+func (s *stubStore) UpsertLinkIngestStatus(ctx context.Context, params db.UpsertLinkIngestStatusParams) (pgtype.UUID, error) {
+	if s.err != nil {
+		return pgtype.UUID{}, s.err
+	}
+	s.statuses = append(s.statuses, params)
+	return pgtype.UUID{Bytes: s.userID, Valid: true}, nil
+}
+
+func (s *stubStore) UpsertLinkIngestDiagnostics(ctx context.Context, params db.UpsertLinkIngestDiagnosticsParams) error {
+	if s.err != nil {
+		return s.err
+	}
+	s.diagnostics = append(s.diagnostics, params)
+	return nil
+}
+
+type stubPublisher struct {
+	events []queue.Event
+}
+
+func (p *stubPublisher) PublishLinkSaved(ctx context.Context, linkID uuid.UUID) error { return nil }
+
+func (p *stubPublisher) PublishLinkReparse(ctx context.Context, linkID uuid.UUID, force bool) error {
+	return nil
+}
+
+func (p *stubPublisher) PublishLinkDeleted(ctx context.Context, linkID uuid.UUID, mediaHashes []string) error {
+	return nil
+}
+
+func (p *stubPublisher) PublishRecommendationsRefresh(ctx context.Context, userID uuid.UUID) error {
+	return nil
+}
+
+func (p *stubPublisher) PublishEvent(ctx context.Context, event queue.Event) error {
+	p.events = append(p.events, event)
+	return nil
+}
+
+func (p *stubPublisher) Close() {}
+
+// dial serves srv over an in-memory listener and returns a client for it.
+func dial(t *testing.T, srv *Server, duration *prometheus.HistogramVec) ingestv1.IngestServiceClient {
+	t.Helper()
+
+	listener := bufconn.Listen(1 << 20)
+	server := NewGRPCServer(srv, duration)
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return ingestv1.NewIngestServiceClient(conn)
+}
+
+func newTestDuration() *prometheus.HistogramVec {
+	return prometheus.NewHistogramVec(prometheus.HistogramOpts{Name: "test_grpc_request_duration_seconds"}, []string{"method", "code"})
+}
+
+func TestUpdateLinkStatusStoresAndPublishes(t *testing.T) {
+	t.Parallel()
+
+	store := &stubStore{userID: uuid.New()}
+	publisher := &stubPublisher{}
+	duration := newTestDuration()
+	client := dial(t, New(store, publisher, log.New(io.Discard, "", 0)), duration)
+	linkID := uuid.New()
+
+	_, err := client.UpdateLinkStatus(context.Background(), &ingestv1.UpdateLinkStatusRequest{
+		LinkId:      linkID.String(),
+		Status:      ingestv1.LinkStatus_LINK_STATUS_FAILED,
+		FailedStage: "fetch",
+		Error:       "status 500",
+	})
+	if err != nil {
+		t.Fatalf("update status: %v", err)
+	}
+
+	if len(store.statuses) != 1 {
+		t.Fatalf("expected one stored status, got %d", len(store.statuses))
+	}
+	stored := store.statuses[0]
+	if uuid.UUID(stored.LinkID.Bytes) != linkID || stored.Status != "failed" || stored.FailedStage.String != "fetch" || stored.Error.String != "status 500" {
+		t.Fatalf("unexpected stored status: %+v", stored)
+	}
+
+	if len(publisher.events) != 1 {
+		t.Fatalf("expected one event, got %d", len(publisher.events))
+	}
+	event := publisher.events[0]
+	if event.Type != queue.EventLinkStatus || event.UserID != store.userID.String() || event.LinkID != linkID.String() {
+		t.Fatalf("unexpected event: %+v", event)
+	}
+	var data linkStatusEvent
+	if err := json.Unmarshal(event.Data, &data); err != nil {
+		t.Fatalf("decode event data: %v", err)
+	}
+	if data.Status != "failed" || data.FailedStage != "fetch" {
+		t.Fatalf("unexpected event data: %+v", data)
+	}
+
+	if got := testutil.CollectAndCount(duration); got != 1 {
+		t.Fatalf("expected one recorded request duration series, got %d", got)
+	}
+}
+
+func TestReportDiagnosticsConvertsDurations(t *testing.T) {
+	t.Parallel()
+
+	store := &stubStore{}
+	client := dial(t, New(store, nil, log.New(io.Discard, "", 0)), newTestDuration())
+
+	_, err := client.ReportDiagnostics(context.Background(), &ingestv1.ReportDiagnosticsRequest{
+		LinkId:        uuid.NewString(),
+		FinalUrl:      "https://example.com/final",
+		FetchBytes:    4096,
+		FetchDuration: durationpb.New(1500 * time.Millisecond),
+		Lang:          "en",
+		LangDetected:  true,
+		WordCount:     250,
+	})
+	if err != nil {
+		t.Fatalf("report diagnostics: %v", err)
+	}
+
+	if len(store.diagnostics) != 1 {
+		t.Fatalf("expected one stored report, got %d", len(store.diagnostics))
+	}
+	got := store.diagnostics[0]
+	if got.FetchMs.Int32 != 1500 || !got.FetchMs.Valid {
+		t.Fatalf("expected fetch duration of 1500ms, got %+v", got.FetchMs)
+	}
+	// No parse duration was reported, so none is stored.
+	if got.ParseMs.Valid {
+		t.Fatalf("expected parse duration to be unset, got %+v", got.ParseMs)
+	}
+	if got.FinalUrl.String != "https://example.com/final" || got.FetchBytes.Int64 != 4096 || got.WordCount.Int32 != 250 || !got.LangDetected.Bool {
+		t.Fatalf("unexpected stored report: %+v", got)
+	}
+}
+
+func TestIngestServiceErrorCodes(t *testing.T) {
+	t.Parallel()
+
+	missing := &stubStore{err: &pgconn.PgError{Code: pgerrcode.ForeignKeyViolation}}
+	client := dial(t, New(missing, nil, log.New(io.Discard, "", 0)), newTestDuration())
+	ctx := context.Background()
+
+	cases := []struct {
+		name string
+		call func() error
+		want codes.Code
+	}{
+		{"invalid link id", func() error {
+			_, err := client.UpdateLinkStatus(ctx, &ingestv1.UpdateLinkStatusRequest{LinkId: "nope", Status: ingestv1.LinkStatus_LINK_STATUS_FETCHING})
+			return err
+		}, codes.InvalidArgument},
+		{"unspecified status", func() error {
+			_, err := client.UpdateLinkStatus(ctx, &ingestv1.UpdateLinkStatusRequest{LinkId: uuid.NewString()})
+			return err
+		}, codes.InvalidArgument},
+		{"deleted link", func() error {
+			_, err := client.UpdateLinkStatus(ctx, &ingestv1.UpdateLinkStatusRequest{LinkId: uuid.NewString(), Status: ingestv1.LinkStatus_LINK_STATUS_PARSING})
+			return err
+		}, codes.NotFound},
+		{"diagnostics for deleted link", func() error {
+			_, err := client.ReportDiagnostics(ctx, &ingestv1.ReportDiagnosticsRequest{LinkId: uuid.NewString()})
+			return err
+		}, codes.NotFound},
+	}
+	for _, tc := range cases {
+		if got := status.Code(tc.call()); got != tc.want {
+			t.Fatalf("%s: expected %s, got %s", tc.name, tc.want, got)
+		}
+	}
+}
@@ -0,0 +1,597 @@
+package httpapi
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	stdhttp "net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgerrcode"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/labstack/echo/v4"
+
+	"github.com/example/keepstack/apps/api/internal/activitypub"
+	"github.com/example/keepstack/apps/api/internal/db"
+)
+
+const (
+	activityPubPageSize = 20
+	// activityPubDeliveryTimeout bounds one fan-out to every follower inbox.
+	activityPubDeliveryTimeout = time.Minute
+)
+
+// activityPubUsernamePattern keeps usernames to what Mastodon accepts in a
+// handle.
+var activityPubUsernamePattern = regexp.MustCompile(`^[a-z0-9_]{1,30}$`)
+
+// activityPubClient talks to remote ActivityPub servers.
+type activityPubClient interface {
+	FetchActor(ctx context.Context, keyID, uri string) (activitypub.Actor, error)
+	Deliver(ctx context.Context, keyID, inbox string, activity any) error
+}
+
+type activityPubActorResponse struct {
+	Username    string  `json:"username"`
+	DisplayName *string `json:"display_name,omitempty"`
+	Summary     *string `json:"summary,omitempty"`
+	Handle      string  `json:"handle"`
+	ActorURL    string  `json:"actor_url"`
+	Followers   int64   `json:"followers"`
+}
+
+type putActivityPubActorRequest struct {
+	Username    string  `json:"username"`
+	DisplayName *string `json:"display_name"`
+	Summary     *string `json:"summary"`
+}
+
+// WithActivityPub enables ActivityPub actors for users who opt in. client
+// reaches remote servers and publicKeyPEM is published on every actor.
+func (s *Server) WithActivityPub(client activityPubClient, publicKeyPEM string) {
+	s.activityPub = client
+	s.activityPubKey = publicKeyPEM
+}
+
+// registerActivityPubRoutes adds WebFinger, the federation endpoints remote
+// servers call, and the owner endpoints for the actor and public links.
+func (s *Server) registerActivityPubRoutes(e *echo.Echo, api, user *echo.Group) {
+	if s.activityPub == nil || s.cfg.ActivityPubBaseURL == "" {
+		return
+	}
+
+	e.GET("/.well-known/webfinger", s.handleWebfinger)
+
+	ap := api.Group("/ap")
+	ap.GET("/users/:username", s.handleActivityPubActor)
+	ap.GET("/users/:username/outbox", s.handleActivityPubOutbox)
+	ap.GET("/users/:username/followers", s.handleActivityPubFollowers)
+	ap.POST("/users/:username/inbox", s.handleActivityPubInbox)
+	ap.GET("/links/:id", s.handleActivityPubNote)
+
+	user.GET("/activitypub/actor", s.handleGetActivityPubActor)
+	user.PUT("/activitypub/actor", s.handlePutActivityPubActor)
+	user.DELETE("/activitypub/actor", s.handleDeleteActivityPubActor)
+	user.PUT("/links/:id/public", s.handlePublishLink)
+	user.DELETE("/links/:id/public", s.handleUnpublishLink)
+}
+
+func (s *Server) handleWebfinger(c echo.Context) error {
+	resource := c.QueryParam("resource")
+	if resource == "" {
+		return respondError(c, stdhttp.StatusBadRequest, "resource is required")
+	}
+
+	var username string
+	if account, ok := strings.CutPrefix(resource, "acct:"); ok {
+		name, host, ok := strings.Cut(account, "@")
+		if !ok || !strings.EqualFold(host, s.activityPubHost()) {
+			return respondError(c, stdhttp.StatusNotFound, "unknown resource")
+		}
+		username = name
+	} else if name, ok := strings.CutPrefix(resource, s.actorURL("")); ok {
+		username = name
+	}
+
+	actor, err := s.queries.GetActivityPubActorByUsername(c.Request().Context(), strings.ToLower(username))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return respondError(c, stdhttp.StatusNotFound, "unknown resource")
+		}
+		return respondError(c, stdhttp.StatusInternalServerError, "failed to look up actor")
+	}
+
+	actorURL := s.actorURL(actor.Username)
+	return blobJSON(c, stdhttp.StatusOK, "application/jrd+json", map[string]any{
+		"subject": "acct:" + actor.Username + "@" + s.activityPubHost(),
+		"aliases": []string{actorURL},
+		"links": []map[string]string{
+			{"rel": "self", "type": activitypub.ContentType, "href": actorURL},
+		},
+	})
+}
+
+func (s *Server) handleActivityPubActor(c echo.Context) error {
+	actor, err := s.lookupActivityPubActor(c)
+	if err != nil {
+		return respondWithError(c, err)
+	}
+
+	actorURL := s.actorURL(actor.Username)
+	return blobJSON(c, stdhttp.StatusOK, activitypub.ContentType, activitypub.Actor{
+		Context:           activitypub.ActorContext,
+		ID:                actorURL,
+		Type:              "Person",
+		PreferredUsername: actor.Username,
+		Name:              actor.DisplayName.String,
+		Summary:           html.EscapeString(actor.Summary.String),
+		Inbox:             actorURL + "/inbox",
+		Outbox:            actorURL + "/outbox",
+		Followers:         actorURL + "/followers",
+		PublicKey: activitypub.PublicKey{
+			ID:           s.actorKeyID(actor.Username),
+			Owner:        actorURL,
+			PublicKeyPem: s.activityPubKey,
+		},
+	})
+}
+
+// handleActivityPubOutbox serves the user's public links as Create
+// activities, newest first. Without ?page it returns the collection summary
+// that points at the first page.
+func (s *Server) handleActivityPubOutbox(c echo.Context) error {
+	actor, err := s.lookupActivityPubActor(c)
+	if err != nil {
+		return respondWithError(c, err)
+	}
+	ctx := c.Request().Context()
+	outboxURL := s.actorURL(actor.Username) + "/outbox"
+
+	rawPage := c.QueryParam("page")
+	if rawPage == "" {
+		total, err := s.queries.CountPublicLinks(ctx, actor.UserID)
+		if err != nil {
+			c.Logger().Errorf("activitypub outbox: count public links failed: %v", err)
+			return respondError(c, stdhttp.StatusInternalServerError, "failed to load outbox")
+		}
+		return blobJSON(c, stdhttp.StatusOK, activitypub.ContentType, activitypub.OrderedCollection{
+			Context:    activitypub.Context,
+			ID:         outboxURL,
+			Type:       "OrderedCollection",
+			TotalItems: total,
+			First:      outboxURL + "?page=1",
+		})
+	}
+
+	page, err := strconv.Atoi(rawPage)
+	if err != nil || page < 1 {
+		return respondError(c, stdhttp.StatusBadRequest, "page must be a positive integer")
+	}
+	// One extra row tells whether there is a next page.
+	rows, err := s.queries.ListPublicLinks(ctx, db.ListPublicLinksParams{
+		UserID:     actor.UserID,
+		PageLimit:  activityPubPageSize + 1,
+		PageOffset: int32((page - 1) * activityPubPageSize),
+	})
+	if err != nil {
+		c.Logger().Errorf("activitypub outbox: list public links failed: %v", err)
+		return respondError(c, stdhttp.StatusInternalServerError, "failed to load outbox")
+	}
+
+	result := activitypub.OrderedCollectionPage{
+		Context:      activitypub.Context,
+		ID:           fmt.Sprintf("%s?page=%d", outboxURL, page),
+		Type:         "OrderedCollectionPage",
+		PartOf:       outboxURL,
+		OrderedItems: make([]any, 0, len(rows)),
+	}
+	if len(rows) > activityPubPageSize {
+		rows = rows[:activityPubPageSize]
+		result.Next = fmt.Sprintf("%s?page=%d", outboxURL, page+1)
+	}
+	for _, row := range rows {
+		note := s.linkNote(actor.Username, uuidFromPg(row.ID), row.Url, row.Title, row.PublicAt.Time)
+		result.OrderedItems = append(result.OrderedItems, createActivity(note))
+	}
+	return blobJSON(c, stdhttp.StatusOK, activitypub.ContentType, result)
+}
+
+// handleActivityPubFollowers reports how many followers a user has without
+// listing them.
+func (s *Server) handleActivityPubFollowers(c echo.Context) error {
+	actor, err := s.lookupActivityPubActor(c)
+	if err != nil {
+		return respondWithError(c, err)
+	}
+	total, err := s.queries.CountActivityPubFollowers(c.Request().Context(), actor.UserID)
+	if err != nil {
+		c.Logger().Errorf("activitypub followers: count failed: %v", err)
+		return respondError(c, stdhttp.StatusInternalServerError, "failed to count followers")
+	}
+	return blobJSON(c, stdhttp.StatusOK, activitypub.ContentType, activitypub.OrderedCollection{
+		Context:    activitypub.Context,
+		ID:         s.actorURL(actor.Username) + "/followers",
+		Type:       "OrderedCollection",
+		TotalItems: total,
+	})
+}
+
+func (s *Server) handleActivityPubNote(c echo.Context) error {
+	linkID, err := parseUUIDParam(c.Param("id"))
+	if err != nil {
+		return respondError(c, stdhttp.StatusBadRequest, "invalid link id")
+	}
+	ctx := c.Request().Context()
+	link, err := s.queries.GetPublicLink(ctx, uuidToPg(linkID))
+	if err == nil {
+		var actor db.ActivitypubActor
+		actor, err = s.queries.GetActivityPubActor(ctx, link.UserID)
+		if err == nil {
+			note := s.linkNote(actor.Username, linkID, link.Url, link.Title, link.PublicAt.Time)
+			note.Context = activitypub.Context
+			return blobJSON(c, stdhttp.StatusOK, activitypub.ContentType, note)
+		}
+	}
+	if errors.Is(err, pgx.ErrNoRows) {
+		return respondError(c, stdhttp.StatusNotFound, "link not found")
+	}
+	c.Logger().Errorf("activitypub note: lookup %s failed: %v", linkID, err)
+	return respondError(c, stdhttp.StatusInternalServerError, "failed to load link")
+}
+
+// handleActivityPubInbox accepts Follow and Undo{Follow} from remote actors.
+// Every request must carry an HTTP signature from the actor it claims to
+// come from. Other activities are acknowledged and dropped.
+func (s *Server) handleActivityPubInbox(c echo.Context) error {
+	actor, err := s.lookupActivityPubActor(c)
+	if err != nil {
+		return respondWithError(c, err)
+	}
+
+	req := c.Request()
+	body, err := io.ReadAll(req.Body)
+	if err != nil {
+		var maxBytesErr *stdhttp.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			return respondError(c, stdhttp.StatusRequestEntityTooLarge, bodyTooLargeMessage(maxBytesErr.Limit))
+		}
+		return respondError(c, stdhttp.StatusBadRequest, "failed to read body")
+	}
+	var activity activitypub.IncomingActivity
+	if err := json.Unmarshal(body, &activity); err != nil || activity.Type == "" || activity.Actor == "" {
+		return respondError(c, stdhttp.StatusBadRequest, "invalid activity")
+	}
+
+	ctx := req.Context()
+	localKeyID := s.actorKeyID(actor.Username)
+	keyID, err := activitypub.SignatureKeyID(req)
+	if err != nil {
+		return respondError(c, stdhttp.StatusUnauthorized, err.Error())
+	}
+	remote, err := s.activityPub.FetchActor(ctx, localKeyID, activity.Actor)
+	if err != nil {
+		c.Logger().Warnf("activitypub inbox: fetch actor %s failed: %v", activity.Actor, err)
+		return respondError(c, stdhttp.StatusUnauthorized, "could not fetch the signing actor")
+	}
+	if remote.ID != activity.Actor || remote.PublicKey.ID != keyID {
+		return respondError(c, stdhttp.StatusUnauthorized, "request is not signed by the activity's actor")
+	}
+	publicKey, err := activitypub.ParsePublicKey(remote.PublicKey.PublicKeyPem)
+	if err != nil {
+		return respondError(c, stdhttp.StatusUnauthorized, "actor has no usable public key")
+	}
+	if err := activitypub.Verify(req, body, publicKey, time.Now()); err != nil {
+		return respondError(c, stdhttp.StatusUnauthorized, err.Error())
+	}
+
+	actorURL := s.actorURL(actor.Username)
+	switch activity.Type {
+	case "Follow":
+		if activity.ObjectID() != actorURL {
+			return respondError(c, stdhttp.StatusBadRequest, "follow is not for this actor")
+		}
+		if err := s.queries.AddActivityPubFollower(ctx, db.AddActivityPubFollowerParams{
+			UserID:   actor.UserID,
+			ActorUri: remote.ID,
+			Inbox:    remote.DeliveryInbox(),
+		}); err != nil {
+			c.Logger().Errorf("activitypub inbox: store follower %s failed: %v", remote.ID, err)
+			return respondError(c, stdhttp.StatusInternalServerError, "failed to store follower")
+		}
+		s.deliverActivity(c, actor.Username, []string{remote.Inbox}, activitypub.Activity{
+			Context: activitypub.Context,
+			ID:      actorURL + "#accepts/" + uuid.NewString(),
+			Type:    "Accept",
+			Actor:   actorURL,
+			Object:  json.RawMessage(body),
+		})
+	case "Undo":
+		if inner, ok := activity.EmbeddedActivity(); ok && inner.Type == "Follow" {
+			if err := s.queries.RemoveActivityPubFollower(ctx, db.RemoveActivityPubFollowerParams{
+				UserID:   actor.UserID,
+				ActorUri: remote.ID,
+			}); err != nil {
+				c.Logger().Errorf("activitypub inbox: remove follower %s failed: %v", remote.ID, err)
+				return respondError(c, stdhttp.StatusInternalServerError, "failed to remove follower")
+			}
+		}
+	}
+	return c.NoContent(stdhttp.StatusAccepted)
+}
+
+func (s *Server) handleGetActivityPubActor(c echo.Context) error {
+	ctx := c.Request().Context()
+	actor, err := s.queries.GetActivityPubActor(ctx, uuidToPg(s.userID(ctx)))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return respondError(c, stdhttp.StatusNotFound, "activitypub is not enabled for this user")
+		}
+		return respondError(c, stdhttp.StatusInternalServerError, "failed to load actor")
+	}
+	followers, err := s.queries.CountActivityPubFollowers(ctx, actor.UserID)
+	if err != nil {
+		return respondError(c, stdhttp.StatusInternalServerError, "failed to count followers")
+	}
+	return c.JSON(stdhttp.StatusOK, s.toActivityPubActorResponse(actor, followers))
+}
+
+// handlePutActivityPubActor creates or renames the signed-in user's actor.
+// Renaming breaks existing follows, since remote servers key them by the
+// actor URL.
+func (s *Server) handlePutActivityPubActor(c echo.Context) error {
+	var req putActivityPubActorRequest
+	if err := c.Bind(&req); err != nil {
+		return respondBindError(c, err)
+	}
+	username := strings.TrimSpace(req.Username)
+	if !activityPubUsernamePattern.MatchString(username) {
+		return respondError(c, stdhttp.StatusBadRequest, "username must be 1-30 lowercase letters, digits, or underscores")
+	}
+
+	ctx := c.Request().Context()
+	actor, err := s.queries.UpsertActivityPubActor(ctx, db.UpsertActivityPubActorParams{
+		UserID:      uuidToPg(s.userID(ctx)),
+		Username:    username,
+		DisplayName: optionalText(req.DisplayName),
+		Summary:     optionalText(req.Summary),
+	})
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == pgerrcode.UniqueViolation {
+			return respondError(c, stdhttp.StatusConflict, "username is taken")
+		}
+		c.Logger().Errorf("activitypub actor: upsert failed: %v", err)
+		return respondError(c, stdhttp.StatusInternalServerError, "failed to save actor")
+	}
+	followers, err := s.queries.CountActivityPubFollowers(ctx, actor.UserID)
+	if err != nil {
+		return respondError(c, stdhttp.StatusInternalServerError, "failed to count followers")
+	}
+	return c.JSON(stdhttp.StatusOK, s.toActivityPubActorResponse(actor, followers))
+}
+
+// handleDeleteActivityPubActor removes the signed-in user's actor and its
+// followers. Links stay marked public and reappear if an actor is created
+// again.
+func (s *Server) handleDeleteActivityPubActor(c echo.Context) error {
+	if err := s.queries.DeleteActivityPubActor(c.Request().Context(), uuidToPg(s.currentUser(c))); err != nil {
+		c.Logger().Errorf("activitypub actor: delete failed: %v", err)
+		return respondError(c, stdhttp.StatusInternalServerError, "failed to delete actor")
+	}
+	return c.NoContent(stdhttp.StatusNoContent)
+}
+
+// handlePublishLink marks a link public, adding it to the user's outbox and
+// sending it to their followers.
+func (s *Server) handlePublishLink(c echo.Context) error {
+	return s.setLinkPublic(c, true)
+}
+
+// handleUnpublishLink makes a link private again and asks followers'
+// servers to delete it.
+func (s *Server) handleUnpublishLink(c echo.Context) error {
+	return s.setLinkPublic(c, false)
+}
+
+func (s *Server) setLinkPublic(c echo.Context, public bool) error {
+	linkID, err := parseUUIDParam(c.Param("id"))
+	if err != nil {
+		s.metrics.LinkUpdateFailure.Inc()
+		return respondError(c, stdhttp.StatusBadRequest, "invalid link id")
+	}
+
+	ctx := c.Request().Context()
+	if _, err := s.ensureLinkAccess(ctx, linkID, linkPermissionOwner); err != nil {
+		s.metrics.LinkUpdateFailure.Inc()
+		return respondWithError(c, err)
+	}
+
+	row, err := s.queries.SetLinkPublic(ctx, db.SetLinkPublicParams{Public: public, ID: uuidToPg(linkID)})
+	if err != nil {
+		s.metrics.LinkUpdateFailure.Inc()
+		c.Logger().Errorf("set link public: update %s failed: %v", linkID, err)
+		return respondError(c, stdhttp.StatusInternalServerError, "failed to update link")
+	}
+	s.metrics.LinkUpdateSuccess.Inc()
+
+	// Only changes are announced, so repeating a request is harmless.
+	if public != row.PreviousPublicAt.Valid {
+		s.announceLink(c, linkID, public)
+	}
+
+	if !public {
+		return c.NoContent(stdhttp.StatusNoContent)
+	}
+	return c.JSON(stdhttp.StatusOK, map[string]any{
+		"id":        linkID.String(),
+		"public_at": row.PublicAt.Time,
+	})
+}
+
+// announceLink sends followers a Create for a link that became public or a
+// Delete for one that stopped being public. Users without an actor or
+// followers are skipped, and failures are logged rather than failing the
+// request that changed the link.
+func (s *Server) announceLink(c echo.Context, linkID uuid.UUID, public bool) {
+	ctx := c.Request().Context()
+	actor, err := s.queries.GetActivityPubActor(ctx, uuidToPg(s.userID(ctx)))
+	if err != nil {
+		if !errors.Is(err, pgx.ErrNoRows) {
+			c.Logger().Errorf("activitypub announce: load actor failed: %v", err)
+		}
+		return
+	}
+	inboxes, err := s.queries.ListActivityPubFollowerInboxes(ctx, actor.UserID)
+	if err != nil {
+		c.Logger().Errorf("activitypub announce: list followers failed: %v", err)
+		return
+	}
+	if len(inboxes) == 0 {
+		return
+	}
+
+	actorURL := s.actorURL(actor.Username)
+	var activity activitypub.Activity
+	if public {
+		link, err := s.queries.GetPublicLink(ctx, uuidToPg(linkID))
+		if err != nil {
+			c.Logger().Errorf("activitypub announce: load link %s failed: %v", linkID, err)
+			return
+		}
+		activity = createActivity(s.linkNote(actor.Username, linkID, link.Url, link.Title, link.PublicAt.Time))
+	} else {
+		noteURL := s.noteURL(linkID)
+		activity = activitypub.Activity{
+			ID:     noteURL + "#delete",
+			Type:   "Delete",
+			Actor:  actorURL,
+			Object: activitypub.Tombstone{ID: noteURL, Type: "Tombstone"},
+			To:     []string{activitypub.PublicCollection},
+		}
+	}
+	activity.Context = activitypub.Context
+	s.deliverActivity(c, actor.Username, inboxes, activity)
+}
+
+// deliverActivity posts activity to each inbox in the background, signed as
+// username, so slow remote servers do not hold up the request.
+func (s *Server) deliverActivity(c echo.Context, username string, inboxes []string, activity activitypub.Activity) {
+	logger := c.Logger()
+	keyID := s.actorKeyID(username)
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), activityPubDeliveryTimeout)
+		defer cancel()
+		for _, inbox := range inboxes {
+			if err := s.activityPub.Deliver(ctx, keyID, inbox, activity); err != nil {
+				logger.Warnf("activitypub: deliver %s to %s failed: %v", activity.Type, inbox, err)
+			}
+		}
+	}()
+}
+
+func (s *Server) lookupActivityPubActor(c echo.Context) (db.ActivitypubActor, error) {
+	actor, err := s.queries.GetActivityPubActorByUsername(c.Request().Context(), c.Param("username"))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return db.ActivitypubActor{}, apiError{Code: stdhttp.StatusNotFound, Message: "actor not found"}
+		}
+		c.Logger().Errorf("activitypub: look up actor %q failed: %v", c.Param("username"), err)
+		return db.ActivitypubActor{}, apiError{Code: stdhttp.StatusInternalServerError, Message: "failed to look up actor"}
+	}
+	return actor, nil
+}
+
+// linkNote renders a public link as the Note followers see: the title linked
+// to the page.
+func (s *Server) linkNote(username string, linkID uuid.UUID, linkURL, title string, publishedAt time.Time) activitypub.Note {
+	actorURL := s.actorURL(username)
+	return activitypub.Note{
+		ID:           s.noteURL(linkID),
+		Type:         "Note",
+		AttributedTo: actorURL,
+		Content:      fmt.Sprintf(`<p><a href="%s">%s</a></p>`, html.EscapeString(linkURL), html.EscapeString(title)),
+		URL:          linkURL,
+		Published:    publishedAt.UTC(),
+		To:           []string{activitypub.PublicCollection},
+		Cc:           []string{actorURL + "/followers"},
+	}
+}
+
+func createActivity(note activitypub.Note) activitypub.Activity {
+	published := note.Published
+	return activitypub.Activity{
+		ID:        note.ID + "#create",
+		Type:      "Create",
+		Actor:     note.AttributedTo,
+		Object:    note,
+		Published: &published,
+		To:        note.To,
+		Cc:        note.Cc,
+	}
+}
+
+func (s *Server) toActivityPubActorResponse(actor db.ActivitypubActor, followers int64) activityPubActorResponse {
+	return activityPubActorResponse{
+		Username:    actor.Username,
+		DisplayName: textPtr(actor.DisplayName),
+		Summary:     textPtr(actor.Summary),
+		Handle:      "@" + actor.Username + "@" + s.activityPubHost(),
+		ActorURL:    s.actorURL(actor.Username),
+		Followers:   followers,
+	}
+}
+
+func (s *Server) actorURL(username string) string {
+	return s.cfg.ActivityPubBaseURL + "/api/ap/users/" + username
+}
+
+func (s *Server) actorKeyID(username string) string {
+	return s.actorURL(username) + "#main-key"
+}
+
+func (s *Server) noteURL(linkID uuid.UUID) string {
+	return s.cfg.ActivityPubBaseURL + "/api/ap/links/" + linkID.String()
+}
+
+func (s *Server) activityPubHost() string {
+	parsed, err := url.Parse(s.cfg.ActivityPubBaseURL)
+	if err != nil {
+		return ""
+	}
+	return parsed.Host
+}
+
+// blobJSON writes v as JSON with contentType, which c.JSON would replace with
+// application/json.
+func blobJSON(c echo.Context, status int, contentType string, v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return c.Blob(status, contentType, data)
+}
+
+func optionalText(value *string) pgtype.Text {
+	if value == nil {
+		return pgtype.Text{}
+	}
+	trimmed := strings.TrimSpace(*value)
+	return pgtype.Text{String: trimmed, Valid: trimmed != ""}
+}
+
+func textPtr(value pgtype.Text) *string {
+	if !value.Valid {
+		return nil
+	}
+	return &value.String
+}
@@ -0,0 +1,429 @@
+package httpapi
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgerrcode"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/labstack/echo/v4"
+
+	"github.com/example/keepstack/apps/api/internal/activitypub"
+	"github.com/example/keepstack/apps/api/internal/config"
+	"github.com/example/keepstack/apps/api/internal/db"
+)
+
+const testActivityPubBase = "https://keepstack.example"
+
+type delivery struct {
+	keyID    string
+	inbox    string
+	activity activitypub.Activity
+}
+
+type fakeActivityPubClient struct {
+	actors     map[string]activitypub.Actor
+	deliveries chan delivery
+}
+
+func newFakeActivityPubClient() *fakeActivityPubClient {
+	return &fakeActivityPubClient{actors: map[string]activitypub.Actor{}, deliveries: make(chan delivery, 10)}
+}
+
+func (f *fakeActivityPubClient) FetchActor(ctx context.Context, keyID, uri string) (activitypub.Actor, error) {
+	actor, ok := f.actors[uri]
+	if !ok {
+		return activitypub.Actor{}, pgx.ErrNoRows
+	}
+	return actor, nil
+}
+
+func (f *fakeActivityPubClient) Deliver(ctx context.Context, keyID, inbox string, activity any) error {
+	f.deliveries <- delivery{keyID: keyID, inbox: inbox, activity: activity.(activitypub.Activity)}
+	return nil
+}
+
+func (f *fakeActivityPubClient) next(t *testing.T) delivery {
+	t.Helper()
+	select {
+	case d := <-f.deliveries:
+		return d
+	case <-time.After(2 * time.Second):
+		t.Fatalf("timed out waiting for a delivery")
+		return delivery{}
+	}
+}
+
+var testActivityPubActor = db.ActivitypubActor{
+	UserID:      uuidToPg(uuid.MustParse("aaaaaaaa-aaaa-aaaa-aaaa-aaaaaaaaaaaa")),
+	Username:    "ada",
+	DisplayName: pgtype.Text{String: "Ada", Valid: true},
+}
+
+func newActivityPubTestServer(queries *mockQueries, client *fakeActivityPubClient) *echo.Echo {
+	if queries.getActivityPubActorByUsernameFn == nil {
+		queries.getActivityPubActorByUsernameFn = func(ctx context.Context, username string) (db.ActivitypubActor, error) {
+			if username != testActivityPubActor.Username {
+				return db.ActivitypubActor{}, pgx.ErrNoRows
+			}
+			return testActivityPubActor, nil
+		}
+	}
+	cfg := config.Config{
+		DevUserID:          uuid.MustParse("aaaaaaaa-aaaa-aaaa-aaaa-aaaaaaaaaaaa"),
+		ActivityPubBaseURL: testActivityPubBase,
+	}
+	srv := &Server{cfg: cfg, queries: queries, publisher: &stubPublisher{}, metrics: newTestMetrics()}
+	srv.WithActivityPub(client, "-----BEGIN PUBLIC KEY-----\ntest\n-----END PUBLIC KEY-----\n")
+	e := echo.New()
+	srv.RegisterRoutes(e)
+	return e
+}
+
+func TestActivityPubDisabledWithoutClient(t *testing.T) {
+	t.Parallel()
+
+	srv := &Server{cfg: config.Config{ActivityPubBaseURL: testActivityPubBase}, queries: &mockQueries{}, metrics: newTestMetrics()}
+	e := echo.New()
+	srv.RegisterRoutes(e)
+
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/.well-known/webfinger?resource=acct:ada@keepstack.example", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("expected status %d, got %d", http.StatusNotFound, rec.Code)
+	}
+}
+
+func TestWebfingerAndActor(t *testing.T) {
+	t.Parallel()
+
+	e := newActivityPubTestServer(&mockQueries{}, newFakeActivityPubClient())
+
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/.well-known/webfinger?resource=acct:ada@keepstack.example", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+	}
+	if got := rec.Header().Get(echo.HeaderContentType); got != "application/jrd+json" {
+		t.Fatalf("unexpected content type %q", got)
+	}
+	var jrd struct {
+		Subject string `json:"subject"`
+		Links   []struct {
+			Rel  string `json:"rel"`
+			Href string `json:"href"`
+		} `json:"links"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &jrd); err != nil {
+		t.Fatalf("decode webfinger: %v", err)
+	}
+	if jrd.Subject != "acct:ada@keepstack.example" || len(jrd.Links) != 1 || jrd.Links[0].Href != testActivityPubBase+"/api/ap/users/ada" {
+		t.Fatalf("unexpected webfinger response: %+v", jrd)
+	}
+
+	for _, resource := range []string{"acct:ada@elsewhere.example", "acct:bob@keepstack.example"} {
+		rec = httptest.NewRecorder()
+		e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/.well-known/webfinger?resource="+resource, nil))
+		if rec.Code != http.StatusNotFound {
+			t.Fatalf("%s: expected status %d, got %d", resource, http.StatusNotFound, rec.Code)
+		}
+	}
+
+	rec = httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/ap/users/ada", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, rec.Code)
+	}
+	if got := rec.Header().Get(echo.HeaderContentType); got != activitypub.ContentType {
+		t.Fatalf("unexpected content type %q", got)
+	}
+	var actor activitypub.Actor
+	if err := json.Unmarshal(rec.Body.Bytes(), &actor); err != nil {
+		t.Fatalf("decode actor: %v", err)
+	}
+	if actor.ID != testActivityPubBase+"/api/ap/users/ada" || actor.Inbox != actor.ID+"/inbox" || actor.PublicKey.ID != actor.ID+"#main-key" || actor.PublicKey.Owner != actor.ID {
+		t.Fatalf("unexpected actor: %+v", actor)
+	}
+}
+
+func TestActivityPubOutboxPages(t *testing.T) {
+	t.Parallel()
+
+	publicAt := time.Date(2024, 6, 1, 9, 0, 0, 0, time.UTC)
+	var offsets []int32
+	queries := &mockQueries{
+		countPublicLinksFn: func(ctx context.Context, id pgtype.UUID) (int64, error) {
+			return activityPubPageSize + 1, nil
+		},
+		listPublicLinksFn: func(ctx context.Context, arg db.ListPublicLinksParams) ([]db.ListPublicLinksRow, error) {
+			offsets = append(offsets, arg.PageOffset)
+			rows := make([]db.ListPublicLinksRow, 0, arg.PageLimit)
+			for i := int32(0); i < arg.PageLimit && arg.PageOffset+i <= activityPubPageSize; i++ {
+				rows = append(rows, db.ListPublicLinksRow{
+					ID:       uuidToPg(uuid.New()),
+					Url:      "https://example.com/?a=1&b=2",
+					Title:    "<script>",
+					PublicAt: pgtype.Timestamptz{Time: publicAt, Valid: true},
+				})
+			}
+			return rows, nil
+		},
+	}
+	e := newActivityPubTestServer(queries, newFakeActivityPubClient())
+	outbox := testActivityPubBase + "/api/ap/users/ada/outbox"
+
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/ap/users/ada/outbox", nil))
+	var collection activitypub.OrderedCollection
+	if err := json.Unmarshal(rec.Body.Bytes(), &collection); err != nil {
+		t.Fatalf("decode outbox: %v", err)
+	}
+	if collection.TotalItems != activityPubPageSize+1 || collection.First != outbox+"?page=1" {
+		t.Fatalf("unexpected outbox: %+v", collection)
+	}
+
+	var page struct {
+		Next         string `json:"next"`
+		OrderedItems []struct {
+			Type   string           `json:"type"`
+			Object activitypub.Note `json:"object"`
+		} `json:"orderedItems"`
+	}
+	rec = httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/ap/users/ada/outbox?page=1", nil))
+	if err := json.Unmarshal(rec.Body.Bytes(), &page); err != nil {
+		t.Fatalf("decode page: %v", err)
+	}
+	if len(page.OrderedItems) != activityPubPageSize || page.Next != outbox+"?page=2" {
+		t.Fatalf("expected a full first page with a next link, got %d items and next %q", len(page.OrderedItems), page.Next)
+	}
+	item := page.OrderedItems[0]
+	if item.Type != "Create" || !item.Object.Published.Equal(publicAt) {
+		t.Fatalf("unexpected item: %+v", item)
+	}
+	if want := `<p><a href="https://example.com/?a=1&amp;b=2">&lt;script&gt;</a></p>`; item.Object.Content != want {
+		t.Fatalf("expected escaped content %q, got %q", want, item.Object.Content)
+	}
+
+	page.Next = ""
+	rec = httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/ap/users/ada/outbox?page=2", nil))
+	if err := json.Unmarshal(rec.Body.Bytes(), &page); err != nil {
+		t.Fatalf("decode page: %v", err)
+	}
+	if len(page.OrderedItems) != 1 || page.Next != "" {
+		t.Fatalf("expected a last page with one item, got %d items and next %q", len(page.OrderedItems), page.Next)
+	}
+	if len(offsets) != 2 || offsets[1] != activityPubPageSize {
+		t.Fatalf("unexpected page offsets %v", offsets)
+	}
+}
+
+func TestPublishLinkAnnouncesOnlyChanges(t *testing.T) {
+	t.Parallel()
+
+	linkID := uuid.New()
+	publicAt := time.Date(2024, 6, 1, 9, 0, 0, 0, time.UTC)
+	previous := pgtype.Timestamptz{}
+	queries := &mockQueries{
+		getLinkFn: func(ctx context.Context, id pgtype.UUID) (db.GetLinkRow, error) {
+			return db.GetLinkRow{ID: id, UserID: testActivityPubActor.UserID}, nil
+		},
+		setLinkPublicFn: func(ctx context.Context, arg db.SetLinkPublicParams) (db.SetLinkPublicRow, error) {
+			row := db.SetLinkPublicRow{PreviousPublicAt: previous}
+			if arg.Public {
+				row.PublicAt = pgtype.Timestamptz{Time: publicAt, Valid: true}
+			}
+			previous = row.PublicAt
+			return row, nil
+		},
+		getActivityPubActorFn: func(ctx context.Context, id pgtype.UUID) (db.ActivitypubActor, error) {
+			return testActivityPubActor, nil
+		},
+		listActivityPubFollowerInboxesFn: func(ctx context.Context, id pgtype.UUID) ([]string, error) {
+			return []string{"https://mastodon.example/inbox"}, nil
+		},
+		getPublicLinkFn: func(ctx context.Context, id pgtype.UUID) (db.GetPublicLinkRow, error) {
+			return db.GetPublicLinkRow{ID: id, UserID: testActivityPubActor.UserID, Url: "https://example.com/a", Title: "A", PublicAt: pgtype.Timestamptz{Time: publicAt, Valid: true}}, nil
+		},
+	}
+	client := newFakeActivityPubClient()
+	e := newActivityPubTestServer(queries, client)
+	target := "/api/links/" + linkID.String() + "/public"
+
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodPut, target, nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+	}
+	created := client.next(t)
+	if created.activity.Type != "Create" || created.inbox != "https://mastodon.example/inbox" || created.keyID != testActivityPubBase+"/api/ap/users/ada#main-key" {
+		t.Fatalf("unexpected delivery: %+v", created)
+	}
+	if note, ok := created.activity.Object.(activitypub.Note); !ok || note.URL != "https://example.com/a" {
+		t.Fatalf("unexpected created object: %+v", created.activity.Object)
+	}
+
+	// Publishing again changes nothing, so nothing is sent.
+	rec = httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodPut, target, nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodDelete, target, nil))
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("expected status %d, got %d", http.StatusNoContent, rec.Code)
+	}
+	deleted := client.next(t)
+	if deleted.activity.Type != "Delete" {
+		t.Fatalf("expected the second delivery to be a Delete, got %s", deleted.activity.Type)
+	}
+	if tombstone, ok := deleted.activity.Object.(activitypub.Tombstone); !ok || tombstone.ID != testActivityPubBase+"/api/ap/links/"+linkID.String() {
+		t.Fatalf("unexpected deleted object: %+v", deleted.activity.Object)
+	}
+}
+
+func TestActivityPubInboxFollow(t *testing.T) {
+	t.Parallel()
+
+	remoteKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	remotePEM, err := activitypub.EncodePublicKey(&remoteKey.PublicKey)
+	if err != nil {
+		t.Fatalf("encode key: %v", err)
+	}
+	const remoteID = "https://mastodon.example/users/bob"
+	client := newFakeActivityPubClient()
+	client.actors[remoteID] = activitypub.Actor{
+		ID:        remoteID,
+		Type:      "Person",
+		Inbox:     remoteID + "/inbox",
+		Endpoints: &activitypub.Endpoints{SharedInbox: "https://mastodon.example/inbox"},
+		PublicKey: activitypub.PublicKey{ID: remoteID + "#main-key", Owner: remoteID, PublicKeyPem: remotePEM},
+	}
+
+	var followers []db.AddActivityPubFollowerParams
+	var removed []db.RemoveActivityPubFollowerParams
+	queries := &mockQueries{
+		addActivityPubFollowerFn: func(ctx context.Context, arg db.AddActivityPubFollowerParams) error {
+			followers = append(followers, arg)
+			return nil
+		},
+		removeActivityPubFollowerFn: func(ctx context.Context, arg db.RemoveActivityPubFollowerParams) error {
+			removed = append(removed, arg)
+			return nil
+		},
+	}
+	e := newActivityPubTestServer(queries, client)
+	actorURL := testActivityPubBase + "/api/ap/users/ada"
+
+	post := func(body string, key *rsa.PrivateKey) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/ap/users/ada/inbox", strings.NewReader(body))
+		req.Header.Set(echo.HeaderContentType, activitypub.ContentType)
+		if key != nil {
+			if err := activitypub.Sign(req, remoteID+"#main-key", key, []byte(body), time.Now()); err != nil {
+				t.Fatalf("sign: %v", err)
+			}
+		}
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		return rec
+	}
+
+	follow := `{"id":"https://mastodon.example/f/1","type":"Follow","actor":"` + remoteID + `","object":"` + actorURL + `"}`
+	if rec := post(follow, nil); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected unsigned follow to be rejected with %d, got %d", http.StatusUnauthorized, rec.Code)
+	}
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	if rec := post(follow, otherKey); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected follow signed with another key to be rejected with %d, got %d", http.StatusUnauthorized, rec.Code)
+	}
+	if len(followers) != 0 {
+		t.Fatalf("expected no follower to be stored from rejected requests")
+	}
+
+	if rec := post(follow, remoteKey); rec.Code != http.StatusAccepted {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusAccepted, rec.Code, rec.Body.String())
+	}
+	if len(followers) != 1 || followers[0].ActorUri != remoteID || followers[0].Inbox != "https://mastodon.example/inbox" {
+		t.Fatalf("unexpected stored followers: %+v", followers)
+	}
+	accept := client.next(t)
+	if accept.activity.Type != "Accept" || accept.inbox != remoteID+"/inbox" || accept.activity.Actor != actorURL {
+		t.Fatalf("unexpected accept delivery: %+v", accept)
+	}
+
+	undo := `{"id":"https://mastodon.example/u/1","type":"Undo","actor":"` + remoteID + `","object":` + follow + `}`
+	if rec := post(undo, remoteKey); rec.Code != http.StatusAccepted {
+		t.Fatalf("expected status %d, got %d", http.StatusAccepted, rec.Code)
+	}
+	if len(removed) != 1 || removed[0].ActorUri != remoteID {
+		t.Fatalf("unexpected removed followers: %+v", removed)
+	}
+}
+
+func TestPutActivityPubActor(t *testing.T) {
+	t.Parallel()
+
+	var saved db.UpsertActivityPubActorParams
+	queries := &mockQueries{
+		upsertActivityPubActorFn: func(ctx context.Context, arg db.UpsertActivityPubActorParams) (db.ActivitypubActor, error) {
+			if arg.Username == "taken" {
+				return db.ActivitypubActor{}, &pgconn.PgError{Code: pgerrcode.UniqueViolation}
+			}
+			saved = arg
+			return db.ActivitypubActor{UserID: arg.UserID, Username: arg.Username, DisplayName: arg.DisplayName, Summary: arg.Summary}, nil
+		},
+		countActivityPubFollowersFn: func(ctx context.Context, id pgtype.UUID) (int64, error) {
+			return 3, nil
+		},
+	}
+	e := newActivityPubTestServer(queries, newFakeActivityPubClient())
+
+	put := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPut, "/api/activitypub/actor", strings.NewReader(body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := put(`{"username":"ada","display_name":" Ada ","summary":""}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+	}
+	var resp activityPubActorResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if resp.Handle != "@ada@keepstack.example" || resp.Followers != 3 || resp.DisplayName == nil || *resp.DisplayName != "Ada" || resp.Summary != nil {
+		t.Fatalf("unexpected response: %+v", resp)
+	}
+	if saved.Summary.Valid {
+		t.Fatalf("expected a blank summary to be stored as NULL")
+	}
+
+	if rec := put(`{"username":"Ada Lovelace"}`); rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected status %d for an invalid username, got %d", http.StatusBadRequest, rec.Code)
+	}
+	if rec := put(`{"username":"taken"}`); rec.Code != http.StatusConflict {
+		t.Fatalf("expected status %d for a taken username, got %d", http.StatusConflict, rec.Code)
+	}
+}
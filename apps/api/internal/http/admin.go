@@ -0,0 +1,329 @@
+package httpapi
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/mail"
+	"strings"
+	"time"
+
+	stdhttp "net/http"
+
+	"github.com/jackc/pgerrcode"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/labstack/echo/v4"
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/example/keepstack/apps/api/internal/cronlock"
+	"github.com/example/keepstack/apps/api/internal/db"
+	"github.com/example/keepstack/apps/api/internal/digest"
+	"github.com/example/keepstack/apps/api/internal/resurfacer"
+)
+
+const (
+	// maxImportLinks caps one import request; keepstackctl sends larger
+	// files in batches.
+	maxImportLinks = 500
+
+	minPasswordLength = 8
+	passwordHashCost  = 12
+
+	// Statuses recorded in cron_runs, matching the cron binary.
+	jobRunSuccess = "success"
+	jobRunFailure = "failure"
+
+	// defaultResurfaceLimit matches the cron's RESURFACER_LIMIT default.
+	defaultResurfaceLimit = 20
+)
+
+// adminJob is a cron subcommand operators can start through the API. It runs
+// in the API process under the same advisory lock and history as the cron.
+type adminJob struct {
+	timeout time.Duration
+	run     func(context.Context) (map[string]int64, error)
+}
+
+type createUserRequest struct {
+	Email    string `json:"email"`
+	Password string `json:"password"`
+}
+
+type userResponse struct {
+	ID        string    `json:"id"`
+	Email     string    `json:"email"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// importLink is one entry of a JSON export. Fields import does not restore,
+// such as the archive, are ignored.
+type importLink struct {
+	URL      string     `json:"url"`
+	Title    string     `json:"title"`
+	Favorite bool       `json:"favorite"`
+	ReadAt   *time.Time `json:"read_at"`
+	Tags     []string   `json:"tags"`
+}
+
+type importFailure struct {
+	URL   string `json:"url"`
+	Error string `json:"error"`
+}
+
+type importResponse struct {
+	Created       int             `json:"created"`
+	Existing      int             `json:"existing"`
+	Failed        []importFailure `json:"failed"`
+	RefreshQueued bool            `json:"refresh_queued"`
+}
+
+// registerAdminRoutes adds the /api/admin endpoints. When admin tokens are
+// configured every admin endpoint requires one, and the endpoints that change
+// data are only registered then.
+func (s *Server) registerAdminRoutes(api *echo.Group) {
+	tokens := configuredTokens(s.cfg.AdminTokens)
+
+	admin := api.Group("/admin")
+	if len(tokens) > 0 {
+		admin.Use(AdminAuthMiddleware(tokens))
+	}
+	admin.GET("/backups", s.handleListBackups)
+	admin.GET("/jobs", s.handleListJobs)
+	if len(tokens) == 0 {
+		return
+	}
+
+	admin.POST("/users", s.handleCreateUser)
+	admin.POST("/import", s.handleImportLinks)
+	admin.POST("/jobs/:name", s.handleRunJob)
+}
+
+// AdminAuthMiddleware requires one of tokens as a bearer token.
+func AdminAuthMiddleware(tokens []string) echo.MiddlewareFunc {
+	return bearerAuthMiddleware(tokens)
+}
+
+// defaultAdminJobs returns the jobs keepstackctl can trigger.
+func (s *Server) defaultAdminJobs(pool *pgxpool.Pool) map[string]adminJob {
+	return map[string]adminJob{
+		"digest":    {timeout: 30 * time.Second, run: s.runDigestJob},
+		"resurface": {timeout: 2 * time.Minute, run: resurfaceJob(pool)},
+	}
+}
+
+func (s *Server) runDigestJob(ctx context.Context) (map[string]int64, error) {
+	cfg, err := s.digestConfigLoader()
+	if err != nil {
+		return nil, err
+	}
+	svc, err := s.digestServiceFactory(cfg)
+	if err != nil {
+		return nil, err
+	}
+	count, _, err := svc.Send(ctx, s.cfg.DevUserID)
+	if errors.Is(err, digest.ErrNoUnreadLinks) {
+		return map[string]int64{"links": 0}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return map[string]int64{"links": int64(count)}, nil
+}
+
+// resurfaceJob rebuilds recommendations with the resurfacer's default
+// weights and batch size.
+func resurfaceJob(pool *pgxpool.Pool) func(context.Context) (map[string]int64, error) {
+	return func(ctx context.Context) (map[string]int64, error) {
+		stats, err := resurfacer.New(pool).Rebuild(ctx, defaultResurfaceLimit)
+		return map[string]int64{
+			"users":           int64(stats.Users),
+			"recommendations": int64(stats.Recommendations),
+		}, err
+	}
+}
+
+func (s *Server) handleCreateUser(c echo.Context) error {
+	var req createUserRequest
+	if err := c.Bind(&req); err != nil {
+		return respondBindError(c, err)
+	}
+	user, err := s.createUser(c, req)
+	if err != nil {
+		return respondWithError(c, err)
+	}
+	return c.JSON(stdhttp.StatusCreated, user)
+}
+
+// createUser validates req and creates the account it describes.
+func (s *Server) createUser(c echo.Context, req createUserRequest) (userResponse, error) {
+	email := strings.TrimSpace(req.Email)
+	if addr, err := mail.ParseAddress(email); err != nil || addr.Address != email {
+		return userResponse{}, apiError{Code: stdhttp.StatusBadRequest, Message: "email is invalid"}
+	}
+	if len(req.Password) < minPasswordLength {
+		return userResponse{}, apiError{Code: stdhttp.StatusBadRequest, Message: "password must be at least 8 characters"}
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(req.Password), passwordHashCost)
+	if err != nil {
+		// bcrypt rejects passwords longer than 72 bytes.
+		return userResponse{}, apiError{Code: stdhttp.StatusBadRequest, Message: "password is too long"}
+	}
+
+	user, err := s.queries.CreateUser(c.Request().Context(), db.CreateUserParams{
+		Email:        email,
+		PasswordHash: string(hash),
+	})
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == pgerrcode.UniqueViolation {
+			return userResponse{}, apiError{Code: stdhttp.StatusConflict, Message: "email is already registered"}
+		}
+		c.Logger().Errorf("create user failed: %v", err)
+		return userResponse{}, apiError{Code: stdhttp.StatusInternalServerError, Message: "failed to create user"}
+	}
+
+	return userResponse{
+		ID:        uuidFromPg(user.ID).String(),
+		Email:     user.Email,
+		CreatedAt: user.CreatedAt.Time,
+	}, nil
+}
+
+// handleImportLinks saves the links of a JSON export. Links already saved are
+// left as they are; new ones are queued for the worker with their tags, and
+// favorite and read state restored. One bad entry does not stop the rest.
+func (s *Server) handleImportLinks(c echo.Context) error {
+	// The body is decoded directly rather than bound so export files, which
+	// carry fields import ignores, are accepted in strict JSON mode.
+	var links []importLink
+	if err := json.NewDecoder(c.Request().Body).Decode(&links); err != nil {
+		return respondError(c, stdhttp.StatusBadRequest, "body must be a JSON array of links")
+	}
+	if len(links) > maxImportLinks {
+		return respondError(c, stdhttp.StatusRequestEntityTooLarge, "import at most 500 links per request")
+	}
+
+	// Admin tokens name no user, so imports go to DevUserID's library, as
+	// the digest job's mail does.
+	req := c.Request()
+	c.SetRequest(req.WithContext(withSessionUser(req.Context(), s.cfg.DevUserID)))
+	resp, err := s.importLinks(c, links, nil)
+	if err != nil {
+		return respondError(c, stdhttp.StatusInternalServerError, "failed to restore read state")
+	}
+	return c.JSON(stdhttp.StatusOK, resp)
+}
+
+// importLinks saves links for handleImportLinks and import jobs, counting
+// each one against progress when it is not nil. Only failing to restore read
+// state, which is done for all links at the end, is returned as an error.
+func (s *Server) importLinks(c echo.Context, links []importLink, progress *jobProgress) (importResponse, error) {
+	ctx := c.Request().Context()
+	resp := importResponse{Failed: []importFailure{}}
+	var read []pgtype.UUID
+	for _, link := range links {
+		progress.Add(ctx, 1)
+		result, err := s.quickSave(c, quickSaveInput{URL: link.URL, Title: link.Title, Tags: link.Tags})
+		if err != nil {
+			resp.Failed = append(resp.Failed, importFailure{URL: link.URL, Error: importErrorMessage(err)})
+			continue
+		}
+		if !result.Created {
+			resp.Existing++
+			continue
+		}
+		resp.Created++
+
+		if link.Favorite {
+			if _, err := s.queries.UpdateLinkFavorite(ctx, db.UpdateLinkFavoriteParams{Favorite: true, ID: uuidToPg(result.ID)}); err != nil {
+				c.Logger().Errorf("import: favorite %s failed: %v", result.ID, err)
+				resp.Failed = append(resp.Failed, importFailure{URL: link.URL, Error: "failed to restore favorite"})
+			}
+		}
+		if link.ReadAt != nil {
+			read = append(read, uuidToPg(result.ID))
+		}
+	}
+
+	if len(read) > 0 {
+		if _, err := s.queries.MarkLinksRead(ctx, db.MarkLinksReadParams{UserID: uuidToPg(s.userID(ctx)), Ids: read}); err != nil {
+			c.Logger().Errorf("import: mark %d links read failed: %v", len(read), err)
+			return resp, apiError{Code: stdhttp.StatusInternalServerError, Message: "failed to restore read state"}
+		}
+	}
+	resp.RefreshQueued = s.queueRecommendationsRefresh(c, s.userID(ctx), resp.Created)
+	return resp, nil
+}
+
+func importErrorMessage(err error) string {
+	var apiErr apiError
+	if errors.As(err, &apiErr) {
+		return apiErr.Message
+	}
+	return "failed to save link"
+}
+
+// handleRunJob starts a cron job in the background and returns its cron_runs
+// entry, which /api/admin/jobs reports on once the job finishes.
+func (s *Server) handleRunJob(c echo.Context) error {
+	name := c.Param("name")
+	job, ok := s.adminJobs[name]
+	if !ok {
+		return respondError(c, stdhttp.StatusNotFound, "unknown job")
+	}
+
+	ctx := c.Request().Context()
+	lock, err := s.lockJob(ctx, name)
+	if errors.Is(err, cronlock.ErrHeld) {
+		return respondError(c, stdhttp.StatusConflict, "job is already running")
+	}
+	if err != nil {
+		c.Logger().Errorf("run job %s: lock failed: %v", name, err)
+		return respondError(c, stdhttp.StatusInternalServerError, "failed to lock job")
+	}
+
+	run, err := s.queries.CreateCronRun(ctx, name)
+	if err != nil {
+		lock.Release(context.Background())
+		c.Logger().Errorf("run job %s: record run failed: %v", name, err)
+		return respondError(c, stdhttp.StatusInternalServerError, "failed to record job")
+	}
+
+	go s.finishJob(c.Logger(), name, job, lock, run.ID)
+	return c.JSON(stdhttp.StatusAccepted, toCronRunResponse(run))
+}
+
+// finishJob runs job, records the outcome, and releases its lock.
+func (s *Server) finishJob(logger echo.Logger, name string, job adminJob, lock *cronlock.Lock, runID pgtype.UUID) {
+	ctx, cancel := context.WithTimeout(context.Background(), job.timeout)
+	defer cancel()
+
+	start := time.Now()
+	counts, runErr := job.run(ctx)
+	if counts == nil {
+		counts = map[string]int64{}
+	}
+
+	params := db.FinishCronRunParams{
+		Status:     jobRunSuccess,
+		DurationMs: pgtype.Int8{Int64: time.Since(start).Milliseconds(), Valid: true},
+		ID:         runID,
+	}
+	if runErr != nil {
+		logger.Errorf("job %s failed: %v", name, runErr)
+		params.Status = jobRunFailure
+		params.Error = pgtype.Text{String: runErr.Error(), Valid: true}
+	}
+	params.Counts, _ = json.Marshal(counts)
+
+	recordCtx, recordCancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer recordCancel()
+	if err := s.queries.FinishCronRun(recordCtx, params); err != nil {
+		logger.Errorf("job %s: record result failed: %v", name, err)
+	}
+	if err := lock.Release(recordCtx); err != nil {
+		logger.Errorf("job %s: %v", name, err)
+	}
+}
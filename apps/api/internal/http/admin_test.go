@@ -0,0 +1,342 @@
+package httpapi
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgerrcode"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/labstack/echo/v4"
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/example/keepstack/apps/api/internal/config"
+	"github.com/example/keepstack/apps/api/internal/cronlock"
+	"github.com/example/keepstack/apps/api/internal/db"
+)
+
+const testAdminToken = "admin-secret"
+
+func newAdminTestServer(queries *mockQueries, publisher *stubPublisher, jobs map[string]adminJob) *echo.Echo {
+	cfg := config.Config{
+		DevUserID:   uuid.MustParse("aaaaaaaa-aaaa-aaaa-aaaa-aaaaaaaaaaaa"),
+		AdminTokens: []string{testAdminToken},
+	}
+	srv := &Server{
+		cfg:       cfg,
+		queries:   queries,
+		publisher: publisher,
+		metrics:   newTestMetrics(),
+		adminJobs: jobs,
+		lockJob: func(context.Context, string) (*cronlock.Lock, error) {
+			return nil, nil
+		},
+	}
+	e := echo.New()
+	srv.RegisterRoutes(e)
+	return e
+}
+
+func adminRequest(method, target, body string) *http.Request {
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	if body != "" {
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	}
+	req.Header.Set(echo.HeaderAuthorization, "Bearer "+testAdminToken)
+	return req
+}
+
+func TestAdminRoutesRequireTokenWhenConfigured(t *testing.T) {
+	t.Parallel()
+
+	e := newAdminTestServer(&mockQueries{}, &stubPublisher{}, nil)
+	for _, target := range []string{"/api/admin/jobs", "/api/admin/backups"} {
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		req.Header.Set(echo.HeaderAuthorization, "Bearer wrong")
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		if rec.Code != http.StatusUnauthorized {
+			t.Fatalf("%s: expected status %d, got %d", target, http.StatusUnauthorized, rec.Code)
+		}
+	}
+}
+
+func TestAdminWriteRoutesDisabledWithoutTokens(t *testing.T) {
+	t.Parallel()
+
+	queries := &mockQueries{
+		listCronRunsFn: func(ctx context.Context, arg db.ListCronRunsParams) ([]db.CronRun, error) {
+			return nil, nil
+		},
+	}
+	srv := &Server{cfg: config.Config{}, queries: queries, metrics: newTestMetrics()}
+	e := echo.New()
+	srv.RegisterRoutes(e)
+
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/admin/jobs", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected read-only admin routes to stay open, got %d", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	e.ServeHTTP(rec, adminRequest(http.MethodPost, "/api/admin/users", `{"email":"a@example.com","password":"correct horse"}`))
+	if rec.Code != http.StatusNotFound && rec.Code != http.StatusMethodNotAllowed {
+		t.Fatalf("expected user creation to be unavailable, got %d", rec.Code)
+	}
+}
+
+func TestAdminCreateUser(t *testing.T) {
+	t.Parallel()
+
+	var stored db.CreateUserParams
+	queries := &mockQueries{
+		createUserFn: func(ctx context.Context, arg db.CreateUserParams) (db.User, error) {
+			if arg.Email == "taken@example.com" {
+				return db.User{}, &pgconn.PgError{Code: pgerrcode.UniqueViolation}
+			}
+			stored = arg
+			return db.User{
+				ID:           uuidToPg(uuid.New()),
+				Email:        arg.Email,
+				PasswordHash: arg.PasswordHash,
+				CreatedAt:    pgtype.Timestamptz{Time: time.Now(), Valid: true},
+			}, nil
+		},
+	}
+	e := newAdminTestServer(queries, &stubPublisher{}, nil)
+
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, adminRequest(http.MethodPost, "/api/admin/users", `{"email":" ada@example.com ","password":"correct horse"}`))
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusCreated, rec.Code, rec.Body.String())
+	}
+	if strings.Contains(rec.Body.String(), "password") {
+		t.Fatalf("expected the response not to include the password hash: %s", rec.Body.String())
+	}
+	if stored.Email != "ada@example.com" {
+		t.Fatalf("expected trimmed email, got %q", stored.Email)
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(stored.PasswordHash), []byte("correct horse")); err != nil {
+		t.Fatalf("expected stored hash to match the password: %v", err)
+	}
+
+	cases := map[string]int{
+		`{"email":"not an email","password":"correct horse"}`:      http.StatusBadRequest,
+		`{"email":"bob@example.com","password":"short"}`:           http.StatusBadRequest,
+		`{"email":"taken@example.com","password":"correct horse"}`: http.StatusConflict,
+	}
+	for body, want := range cases {
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, adminRequest(http.MethodPost, "/api/admin/users", body))
+		if rec.Code != want {
+			t.Fatalf("%s: expected status %d, got %d", body, want, rec.Code)
+		}
+	}
+}
+
+func TestAdminImportLinks(t *testing.T) {
+	t.Parallel()
+
+	existingID := uuid.New()
+	var created []db.CreateLinkParams
+	var favorites []pgtype.UUID
+	var read db.MarkLinksReadParams
+	queries := &mockQueries{
+		findLinkByURLHashFn: func(ctx context.Context, arg db.FindLinkByURLHashParams) (db.FindLinkByURLHashRow, error) {
+			if arg.UrlHash == urlHash("https://example.com/old") {
+				return db.FindLinkByURLHashRow{ID: uuidToPg(existingID)}, nil
+			}
+			return db.FindLinkByURLHashRow{}, pgx.ErrNoRows
+		},
+		createLinkFn: func(ctx context.Context, arg db.CreateLinkParams) (db.CreateLinkRow, error) {
+			created = append(created, arg)
+			return db.CreateLinkRow{ID: arg.ID, UserID: arg.UserID, Url: arg.Url, Title: arg.Title}, nil
+		},
+		getTagByNameFn: func(ctx context.Context, arg db.GetTagByNameParams) (db.Tag, error) {
+			return db.Tag{ID: 7, Name: arg.Name}, nil
+		},
+		addTagToLinkFn: func(ctx context.Context, arg db.AddTagToLinkParams) error {
+			return nil
+		},
+		updateLinkFavoriteFn: func(ctx context.Context, arg db.UpdateLinkFavoriteParams) (db.UpdateLinkFavoriteRow, error) {
+			favorites = append(favorites, arg.ID)
+			return db.UpdateLinkFavoriteRow{ID: arg.ID, Favorite: arg.Favorite}, nil
+		},
+		markLinksReadFn: func(ctx context.Context, arg db.MarkLinksReadParams) ([]pgtype.UUID, error) {
+			read = arg
+			return arg.Ids, nil
+		},
+	}
+	publisher := &stubPublisher{}
+	e := newAdminTestServer(queries, publisher, nil)
+
+	body := `[
+		{"id":"x","url":"https://example.com/new","title":"New","favorite":true,"read_at":"2024-01-02T03:04:05Z","tags":["go"],"word_count":10},
+		{"url":"https://example.com/old"},
+		{"url":"https://"}
+	]`
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, adminRequest(http.MethodPost, "/api/admin/import", body))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+	}
+
+	var resp importResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if resp.Created != 1 || resp.Existing != 1 || len(resp.Failed) != 1 || resp.Failed[0].URL != "https://" {
+		t.Fatalf("unexpected import result: %+v", resp)
+	}
+	if len(created) != 1 || created[0].Title.String != "New" || !publisher.called {
+		t.Fatalf("expected the new link to be stored and queued, got %+v", created)
+	}
+	if len(favorites) != 1 || favorites[0] != created[0].ID {
+		t.Fatalf("expected the favorite to be restored, got %v", favorites)
+	}
+	if len(read.Ids) != 1 || read.Ids[0] != created[0].ID {
+		t.Fatalf("expected the link to be marked read, got %+v", read)
+	}
+}
+
+func TestAdminImportRejectsOversizedBatches(t *testing.T) {
+	t.Parallel()
+
+	e := newAdminTestServer(&mockQueries{}, &stubPublisher{}, nil)
+	links := make([]importLink, maxImportLinks+1)
+	body, _ := json.Marshal(links)
+
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, adminRequest(http.MethodPost, "/api/admin/import", string(body)))
+	if rec.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("expected status %d, got %d", http.StatusRequestEntityTooLarge, rec.Code)
+	}
+}
+
+func TestAdminRunJob(t *testing.T) {
+	t.Parallel()
+
+	runID := uuid.New()
+	finished := make(chan db.FinishCronRunParams, 1)
+	queries := &mockQueries{
+		createCronRunFn: func(ctx context.Context, subcommand string) (db.CronRun, error) {
+			return db.CronRun{
+				ID:         uuidToPg(runID),
+				Subcommand: subcommand,
+				Status:     "running",
+				StartedAt:  pgtype.Timestamptz{Time: time.Now(), Valid: true},
+			}, nil
+		},
+		finishCronRunFn: func(ctx context.Context, arg db.FinishCronRunParams) error {
+			finished <- arg
+			return nil
+		},
+	}
+	jobs := map[string]adminJob{
+		"resurface": {timeout: time.Second, run: func(ctx context.Context) (map[string]int64, error) {
+			return map[string]int64{"users": 2}, nil
+		}},
+		"digest": {timeout: time.Second, run: func(ctx context.Context) (map[string]int64, error) {
+			return nil, errors.New("smtp unreachable")
+		}},
+	}
+	e := newAdminTestServer(queries, &stubPublisher{}, jobs)
+
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, adminRequest(http.MethodPost, "/api/admin/jobs/resurface", ""))
+	if rec.Code != http.StatusAccepted {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusAccepted, rec.Code, rec.Body.String())
+	}
+	var run cronRunResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &run); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if run.ID != runID.String() || run.Status != "running" {
+		t.Fatalf("unexpected run: %+v", run)
+	}
+
+	result := waitForFinish(t, finished)
+	if result.Status != jobRunSuccess || string(result.Counts) != `{"users":2}` || result.ID != uuidToPg(runID) {
+		t.Fatalf("unexpected recorded result: %+v", result)
+	}
+
+	rec = httptest.NewRecorder()
+	e.ServeHTTP(rec, adminRequest(http.MethodPost, "/api/admin/jobs/digest", ""))
+	if rec.Code != http.StatusAccepted {
+		t.Fatalf("expected status %d, got %d", http.StatusAccepted, rec.Code)
+	}
+	result = waitForFinish(t, finished)
+	if result.Status != jobRunFailure || result.Error.String != "smtp unreachable" || string(result.Counts) != `{}` {
+		t.Fatalf("unexpected recorded failure: %+v", result)
+	}
+
+	rec = httptest.NewRecorder()
+	e.ServeHTTP(rec, adminRequest(http.MethodPost, "/api/admin/jobs/backup", ""))
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("expected status %d for a job that cannot be triggered, got %d", http.StatusNotFound, rec.Code)
+	}
+}
+
+func TestAdminRunJobAlreadyRunning(t *testing.T) {
+	t.Parallel()
+
+	srv := &Server{
+		cfg:       config.Config{AdminTokens: []string{testAdminToken}},
+		queries:   &mockQueries{},
+		metrics:   newTestMetrics(),
+		adminJobs: map[string]adminJob{"digest": {timeout: time.Second}},
+		lockJob: func(context.Context, string) (*cronlock.Lock, error) {
+			return nil, cronlock.ErrHeld
+		},
+	}
+	e := echo.New()
+	srv.RegisterRoutes(e)
+
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, adminRequest(http.MethodPost, "/api/admin/jobs/digest", ""))
+	if rec.Code != http.StatusConflict {
+		t.Fatalf("expected status %d, got %d", http.StatusConflict, rec.Code)
+	}
+}
+
+func TestAdminListJobsFiltersByStatus(t *testing.T) {
+	t.Parallel()
+
+	var captured db.ListCronRunsParams
+	queries := &mockQueries{
+		listCronRunsFn: func(ctx context.Context, arg db.ListCronRunsParams) ([]db.CronRun, error) {
+			captured = arg
+			return nil, nil
+		},
+	}
+	e := newAdminTestServer(queries, &stubPublisher{}, nil)
+
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, adminRequest(http.MethodGet, "/api/admin/jobs?status=failure", ""))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, rec.Code)
+	}
+	if !captured.Status.Valid || captured.Status.String != "failure" || captured.Subcommand.Valid {
+		t.Fatalf("unexpected filter: %+v", captured)
+	}
+}
+
+func waitForFinish(t *testing.T, finished <-chan db.FinishCronRunParams) db.FinishCronRunParams {
+	t.Helper()
+	select {
+	case result := <-finished:
+		return result
+	case <-time.After(2 * time.Second):
+		t.Fatalf("timed out waiting for the job to finish")
+		return db.FinishCronRunParams{}
+	}
+}
@@ -0,0 +1,188 @@
+package httpapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	stdhttp "net/http"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/labstack/echo/v4"
+	"github.com/pmezard/go-difflib/difflib"
+
+	"github.com/example/keepstack/apps/api/internal/db"
+)
+
+// currentArchiveVersion names the text a link's archive has now, as opposed
+// to the previous versions kept in archive_versions.
+const currentArchiveVersion = "current"
+
+// archiveDiffContext is the number of unchanged lines shown around each
+// change in a diff.
+const archiveDiffContext = 3
+
+type archiveVersionResponse struct {
+	ID         string     `json:"id"`
+	Title      string     `json:"title"`
+	WordCount  int        `json:"word_count"`
+	ArchivedAt time.Time  `json:"archived_at"`
+	ReplacedAt *time.Time `json:"replaced_at,omitempty"`
+	// HTMLSHA256 and TextSHA256 are the digests the worker recorded at
+	// ingest. Only the current version has them, and only once recorded.
+	HTMLSHA256 string `json:"html_sha256,omitempty"`
+	TextSHA256 string `json:"text_sha256,omitempty"`
+}
+
+// archiveText is one version of an archive's extracted text.
+type archiveText struct {
+	id         string
+	text       string
+	archivedAt time.Time
+}
+
+// handleListArchiveVersions lists the versions of a link's archive, newest
+// first. The first item is the current text; the rest were replaced when
+// the link was ingested again.
+func (s *Server) handleListArchiveVersions(c echo.Context) error {
+	linkID, err := parseUUIDParam(c.Param("id"))
+	if err != nil {
+		return respondError(c, stdhttp.StatusBadRequest, "invalid link id")
+	}
+	ctx := c.Request().Context()
+	link, err := s.ensureLinkAccess(ctx, linkID, linkPermissionRead)
+	if err != nil {
+		return respondWithError(c, err)
+	}
+
+	current, err := s.queries.GetCurrentArchiveVersion(ctx, link.ID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return respondError(c, stdhttp.StatusNotFound, "archive not available yet")
+		}
+		c.Logger().Errorf("list archive versions: load archive failed: %v", err)
+		return respondError(c, stdhttp.StatusInternalServerError, "failed to load archive")
+	}
+	previous, err := s.queries.ListArchiveVersions(ctx, link.ID)
+	if err != nil {
+		c.Logger().Errorf("list archive versions: %v", err)
+		return respondError(c, stdhttp.StatusInternalServerError, "failed to list archive versions")
+	}
+
+	items := make([]archiveVersionResponse, 0, len(previous)+1)
+	items = append(items, archiveVersionResponse{
+		ID:         currentArchiveVersion,
+		Title:      current.Title,
+		WordCount:  int(current.WordCount),
+		ArchivedAt: current.UpdatedAt.Time,
+		HTMLSHA256: current.HtmlSha256,
+		TextSHA256: current.TextSha256,
+	})
+	for _, version := range previous {
+		replacedAt := version.ReplacedAt.Time
+		items = append(items, archiveVersionResponse{
+			ID:         strconv.FormatInt(version.ID, 10),
+			Title:      version.Title,
+			WordCount:  int(version.WordCount),
+			ArchivedAt: version.ArchivedAt.Time,
+			ReplacedAt: &replacedAt,
+		})
+	}
+	return c.JSON(stdhttp.StatusOK, map[string]any{"items": items})
+}
+
+// handleDiffArchiveVersions returns a unified diff of a link's extracted
+// text between two versions. from defaults to the newest previous version
+// and to defaults to the current text, so a bare request shows what the
+// last re-ingest changed.
+func (s *Server) handleDiffArchiveVersions(c echo.Context) error {
+	linkID, err := parseUUIDParam(c.Param("id"))
+	if err != nil {
+		return respondError(c, stdhttp.StatusBadRequest, "invalid link id")
+	}
+	ctx := c.Request().Context()
+	link, err := s.ensureLinkAccess(ctx, linkID, linkPermissionRead)
+	if err != nil {
+		return respondWithError(c, err)
+	}
+
+	fromID := strings.TrimSpace(c.QueryParam("from"))
+	if fromID == "" {
+		previous, err := s.queries.ListArchiveVersions(ctx, link.ID)
+		if err != nil {
+			c.Logger().Errorf("diff archive versions: list failed: %v", err)
+			return respondError(c, stdhttp.StatusInternalServerError, "failed to list archive versions")
+		}
+		if len(previous) == 0 {
+			return respondError(c, stdhttp.StatusNotFound, "archive has no previous versions")
+		}
+		fromID = strconv.FormatInt(previous[0].ID, 10)
+	}
+	toID := strings.TrimSpace(c.QueryParam("to"))
+	if toID == "" {
+		toID = currentArchiveVersion
+	}
+
+	from, err := s.loadArchiveText(ctx, link.ID, fromID)
+	if err != nil {
+		return respondArchiveVersionError(c, err)
+	}
+	to, err := s.loadArchiveText(ctx, link.ID, toID)
+	if err != nil {
+		return respondArchiveVersionError(c, err)
+	}
+
+	diff, err := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        difflib.SplitLines(from.text),
+		B:        difflib.SplitLines(to.text),
+		FromFile: "version " + from.id,
+		FromDate: from.archivedAt.Format(time.RFC3339),
+		ToFile:   "version " + to.id,
+		ToDate:   to.archivedAt.Format(time.RFC3339),
+		Context:  archiveDiffContext,
+	})
+	if err != nil {
+		c.Logger().Errorf("diff archive versions: %v", err)
+		return respondError(c, stdhttp.StatusInternalServerError, "failed to diff archive versions")
+	}
+	return c.Blob(stdhttp.StatusOK, "text/x-diff; charset=utf-8", []byte(diff))
+}
+
+// loadArchiveText loads version id of a link's archive, which is either
+// currentArchiveVersion or the ID of a previous version.
+func (s *Server) loadArchiveText(ctx context.Context, linkID pgtype.UUID, id string) (archiveText, error) {
+	if id == currentArchiveVersion {
+		current, err := s.queries.GetCurrentArchiveVersion(ctx, linkID)
+		if err != nil {
+			return archiveText{}, err
+		}
+		return archiveText{id: id, text: current.ExtractedText, archivedAt: current.UpdatedAt.Time}, nil
+	}
+
+	versionID, err := strconv.ParseInt(id, 10, 64)
+	if err != nil || versionID < 1 {
+		return archiveText{}, apiError{Code: stdhttp.StatusBadRequest, Message: fmt.Sprintf("version must be %s or a version id", currentArchiveVersion)}
+	}
+	version, err := s.queries.GetArchiveVersion(ctx, db.GetArchiveVersionParams{LinkID: linkID, ID: versionID})
+	if err != nil {
+		return archiveText{}, err
+	}
+	return archiveText{id: id, text: version.ExtractedText, archivedAt: version.ArchivedAt.Time}, nil
+}
+
+func respondArchiveVersionError(c echo.Context, err error) error {
+	var apiErr apiError
+	switch {
+	case errors.As(err, &apiErr):
+		return respondWithError(c, err)
+	case errors.Is(err, pgx.ErrNoRows):
+		return respondError(c, stdhttp.StatusNotFound, "archive version not found")
+	default:
+		c.Logger().Errorf("diff archive versions: load version failed: %v", err)
+		return respondError(c, stdhttp.StatusInternalServerError, "failed to load archive version")
+	}
+}
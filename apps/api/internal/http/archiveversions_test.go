@@ -0,0 +1,145 @@
+package httpapi
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/labstack/echo/v4"
+
+	"github.com/example/keepstack/apps/api/internal/config"
+	"github.com/example/keepstack/apps/api/internal/db"
+)
+
+func newArchiveVersionsServer(t *testing.T, versions []db.ArchiveVersion) (*echo.Echo, uuid.UUID) {
+	t.Helper()
+
+	cfg := config.Config{DevUserID: uuid.MustParse("a5a5a5a5-a5a5-a5a5-a5a5-a5a5a5a5a5a5")}
+	linkID := uuid.New()
+	updated := pgtype.Timestamptz{Time: time.Date(2024, time.June, 3, 0, 0, 0, 0, time.UTC), Valid: true}
+	queries := &mockQueries{
+		getLinkFn: func(ctx context.Context, id pgtype.UUID) (db.GetLinkRow, error) {
+			if uuidFromPg(id) != linkID {
+				return db.GetLinkRow{}, pgx.ErrNoRows
+			}
+			return db.GetLinkRow{ID: id, UserID: uuidToPg(cfg.DevUserID), Url: "https://example.com/post"}, nil
+		},
+		getCurrentArchiveVersionFn: func(ctx context.Context, id pgtype.UUID) (db.GetCurrentArchiveVersionRow, error) {
+			return db.GetCurrentArchiveVersionRow{Title: "Post", ExtractedText: "one\ntwo changed\nthree", WordCount: 4, UpdatedAt: updated, HtmlSha256: "aa11", TextSha256: "bb22"}, nil
+		},
+		listArchiveVersionsFn: func(ctx context.Context, id pgtype.UUID) ([]db.ListArchiveVersionsRow, error) {
+			rows := make([]db.ListArchiveVersionsRow, 0, len(versions))
+			for i := len(versions) - 1; i >= 0; i-- {
+				v := versions[i]
+				rows = append(rows, db.ListArchiveVersionsRow{ID: v.ID, Title: v.Title.String, WordCount: v.WordCount.Int32, ArchivedAt: v.ArchivedAt, ReplacedAt: v.ReplacedAt})
+			}
+			return rows, nil
+		},
+		getArchiveVersionFn: func(ctx context.Context, arg db.GetArchiveVersionParams) (db.ArchiveVersion, error) {
+			for _, v := range versions {
+				if v.ID == arg.ID {
+					return v, nil
+				}
+			}
+			return db.ArchiveVersion{}, pgx.ErrNoRows
+		},
+	}
+	srv := &Server{cfg: cfg, queries: queries, metrics: newTestMetrics()}
+	e := echo.New()
+	srv.RegisterRoutes(e)
+	return e, linkID
+}
+
+func TestHandleListArchiveVersions(t *testing.T) {
+	t.Parallel()
+
+	archived := pgtype.Timestamptz{Time: time.Date(2024, time.June, 1, 0, 0, 0, 0, time.UTC), Valid: true}
+	e, linkID := newArchiveVersionsServer(t, []db.ArchiveVersion{
+		{ID: 4, Title: pgtype.Text{String: "Post", Valid: true}, ExtractedText: "one\ntwo\nthree", WordCount: pgtype.Int4{Int32: 3, Valid: true}, ArchivedAt: archived, ReplacedAt: archived},
+	})
+
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/links/"+linkID.String()+"/archive/versions", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+	}
+	var resp struct {
+		Items []archiveVersionResponse `json:"items"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if len(resp.Items) != 2 || resp.Items[0].ID != "current" || resp.Items[0].ReplacedAt != nil || resp.Items[1].ID != "4" || resp.Items[1].ReplacedAt == nil {
+		t.Fatalf("expected the current text then version 4, got %+v", resp.Items)
+	}
+	if resp.Items[0].HTMLSHA256 != "aa11" || resp.Items[0].TextSHA256 != "bb22" || resp.Items[1].TextSHA256 != "" {
+		t.Fatalf("expected digests on the current version only, got %+v", resp.Items)
+	}
+
+	rec = httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/links/"+uuid.NewString()+"/archive/versions", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("expected status %d for an unknown link, got %d", http.StatusNotFound, rec.Code)
+	}
+}
+
+func TestHandleDiffArchiveVersions(t *testing.T) {
+	t.Parallel()
+
+	archived := pgtype.Timestamptz{Time: time.Date(2024, time.June, 1, 0, 0, 0, 0, time.UTC), Valid: true}
+	e, linkID := newArchiveVersionsServer(t, []db.ArchiveVersion{
+		{ID: 4, ExtractedText: "one\ntwo\nthree", ArchivedAt: archived},
+		{ID: 6, ExtractedText: "one\ntwo\nthree\nfour", ArchivedAt: archived},
+	})
+	path := "/api/links/" + linkID.String() + "/archive/diff"
+
+	tests := []struct {
+		name  string
+		query string
+		want  int
+		diff  []string
+	}{
+		{name: "newest previous to current", want: http.StatusOK, diff: []string{"--- version 6\t2024-06-01T00:00:00Z", "+++ version current\t2024-06-03T00:00:00Z", "-two\n", "+two changed\n", "-four\n"}},
+		{name: "between versions", query: "?from=4&to=6", want: http.StatusOK, diff: []string{"--- version 4", "+++ version 6", "+four\n"}},
+		{name: "unknown version", query: "?from=5", want: http.StatusNotFound},
+		{name: "invalid version", query: "?to=latest", want: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path+tt.query, nil))
+			if rec.Code != tt.want {
+				t.Fatalf("expected status %d, got %d: %s", tt.want, rec.Code, rec.Body.String())
+			}
+			if tt.want != http.StatusOK {
+				return
+			}
+			if got := rec.Header().Get(echo.HeaderContentType); !strings.HasPrefix(got, "text/x-diff") {
+				t.Fatalf("expected a diff content type, got %q", got)
+			}
+			for _, part := range tt.diff {
+				if !strings.Contains(rec.Body.String(), part) {
+					t.Fatalf("expected the diff to contain %q, got:\n%s", part, rec.Body.String())
+				}
+			}
+		})
+	}
+}
+
+func TestHandleDiffArchiveVersionsWithoutHistory(t *testing.T) {
+	t.Parallel()
+
+	e, linkID := newArchiveVersionsServer(t, nil)
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/links/"+linkID.String()+"/archive/diff", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusNotFound, rec.Code, rec.Body.String())
+	}
+}
@@ -0,0 +1,239 @@
+package httpapi
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	stdhttp "net/http"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/labstack/echo/v4"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// sessionIssuer is the iss claim of the session tokens the API issues.
+const sessionIssuer = "keepstack"
+
+// readerAudience is the aud claim of reader tokens, which open one link's
+// /read page and nothing else. Session tokens carry no audience.
+const readerAudience = "keepstack-reader"
+
+// unknownUserHash is compared against when a login names no account, so an
+// unknown email takes as long to reject as a wrong password.
+var unknownUserHash = sync.OnceValue(func() []byte {
+	hash, _ := bcrypt.GenerateFromPassword([]byte("keepstack-unknown-user"), passwordHashCost)
+	return hash
+})
+
+type sessionUserKey struct{}
+
+type loginRequest struct {
+	Email    string `json:"email"`
+	Password string `json:"password"`
+}
+
+type sessionResponse struct {
+	Token     string       `json:"token"`
+	ExpiresAt time.Time    `json:"expires_at"`
+	User      userResponse `json:"user"`
+}
+
+// withSessionUser returns ctx carrying userID as the authenticated user.
+func withSessionUser(ctx context.Context, userID uuid.UUID) context.Context {
+	return context.WithValue(ctx, sessionUserKey{}, userID)
+}
+
+// userID is the user a request acts as: the one its session token names, or
+// DevUserID when sessions are disabled. With sessions enabled, a request no
+// token names a user for acts as uuid.Nil, which owns nothing, rather than
+// falling back to DevUserID.
+func (s *Server) userID(ctx context.Context) uuid.UUID {
+	if id, ok := ctx.Value(sessionUserKey{}).(uuid.UUID); ok {
+		return id
+	}
+	if s.cfg.AuthSessions {
+		return uuid.Nil
+	}
+	return s.cfg.DevUserID
+}
+
+// currentUser is userID for c's request.
+func (s *Server) currentUser(c echo.Context) uuid.UUID {
+	return s.userID(c.Request().Context())
+}
+
+// registerAuthRoutes adds the /api/auth endpoints when sessions are enabled
+// and returns the group the API's own routes belong to: one requiring a
+// session token, or api itself when sessions are disabled.
+func (s *Server) registerAuthRoutes(api *echo.Group) *echo.Group {
+	if !s.cfg.AuthSessions {
+		return api
+	}
+
+	api.POST("/auth/login", s.handleLogin)
+	if s.cfg.AuthSignup {
+		api.POST("/auth/signup", s.handleSignup)
+	}
+
+	return api.Group("", SessionMiddleware([]byte(s.cfg.JWTSecret)))
+}
+
+// SessionMiddleware answers 401 with a Bearer challenge unless the request
+// carries a session token signed with secret, and otherwise runs the handler
+// as the token's user.
+func SessionMiddleware(secret []byte) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			userID, err := parseSessionToken(secret, bearerToken(c.Request()))
+			if err != nil {
+				c.Response().Header().Set(echo.HeaderWWWAuthenticate, `Bearer realm="keepstack"`)
+				return respondError(c, stdhttp.StatusUnauthorized, "invalid or missing session token")
+			}
+			req := c.Request()
+			c.SetRequest(req.WithContext(withSessionUser(req.Context(), userID)))
+			return next(c)
+		}
+	}
+}
+
+// issueSessionToken signs an HS256 token naming userID that expires after
+// ttl.
+func issueSessionToken(secret []byte, userID uuid.UUID, now time.Time, ttl time.Duration) (string, time.Time, error) {
+	expiresAt := now.Add(ttl).Truncate(time.Second)
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.RegisteredClaims{
+		Subject:   userID.String(),
+		Issuer:    sessionIssuer,
+		IssuedAt:  jwt.NewNumericDate(now),
+		ExpiresAt: jwt.NewNumericDate(expiresAt),
+	})
+	signed, err := token.SignedString(secret)
+	return signed, expiresAt, err
+}
+
+// parseSessionToken verifies raw and returns the user it names. Tokens with
+// an audience, such as reader tokens, are not session tokens.
+func parseSessionToken(secret []byte, raw string) (uuid.UUID, error) {
+	if raw == "" {
+		return uuid.Nil, errors.New("missing token")
+	}
+	var claims jwt.RegisteredClaims
+	keyFunc := func(*jwt.Token) (any, error) { return secret, nil }
+	_, err := jwt.ParseWithClaims(raw, &claims, keyFunc,
+		jwt.WithValidMethods([]string{"HS256"}),
+		jwt.WithIssuer(sessionIssuer),
+		jwt.WithExpirationRequired(),
+	)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	if len(claims.Audience) > 0 {
+		return uuid.Nil, errors.New("not a session token")
+	}
+	return uuid.Parse(claims.Subject)
+}
+
+// readerClaims are the claims of a reader token: the user it acts as and
+// the one link whose page it opens.
+type readerClaims struct {
+	jwt.RegisteredClaims
+	LinkID string `json:"link_id"`
+}
+
+// issueReaderToken signs an HS256 token that opens linkID's /read page as
+// userID until ttl passes.
+func issueReaderToken(secret []byte, userID, linkID uuid.UUID, now time.Time, ttl time.Duration) (string, time.Time, error) {
+	expiresAt := now.Add(ttl).Truncate(time.Second)
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, readerClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   userID.String(),
+			Issuer:    sessionIssuer,
+			Audience:  jwt.ClaimStrings{readerAudience},
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+		},
+		LinkID: linkID.String(),
+	})
+	signed, err := token.SignedString(secret)
+	return signed, expiresAt, err
+}
+
+// parseReaderToken verifies raw as a reader token for linkID and returns the
+// user it names.
+func parseReaderToken(secret []byte, raw string, linkID uuid.UUID) (uuid.UUID, error) {
+	if raw == "" {
+		return uuid.Nil, errors.New("missing token")
+	}
+	var claims readerClaims
+	keyFunc := func(*jwt.Token) (any, error) { return secret, nil }
+	_, err := jwt.ParseWithClaims(raw, &claims, keyFunc,
+		jwt.WithValidMethods([]string{"HS256"}),
+		jwt.WithIssuer(sessionIssuer),
+		jwt.WithAudience(readerAudience),
+		jwt.WithExpirationRequired(),
+	)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	if claims.LinkID != linkID.String() {
+		return uuid.Nil, errors.New("token is for another link")
+	}
+	return uuid.Parse(claims.Subject)
+}
+
+func (s *Server) handleLogin(c echo.Context) error {
+	var req loginRequest
+	if err := c.Bind(&req); err != nil {
+		return respondBindError(c, err)
+	}
+
+	user, err := s.queries.GetUserByEmail(c.Request().Context(), strings.TrimSpace(req.Email))
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		c.Logger().Errorf("login: load user failed: %v", err)
+		return respondError(c, stdhttp.StatusInternalServerError, "failed to log in")
+	}
+	hash := []byte(user.PasswordHash)
+	if err != nil {
+		hash = unknownUserHash()
+	}
+	if bcrypt.CompareHashAndPassword(hash, []byte(req.Password)) != nil || err != nil {
+		return respondError(c, stdhttp.StatusUnauthorized, "invalid email or password")
+	}
+
+	return s.respondSession(c, stdhttp.StatusOK, userResponse{
+		ID:        uuidFromPg(user.ID).String(),
+		Email:     user.Email,
+		CreatedAt: user.CreatedAt.Time,
+	})
+}
+
+// handleSignup creates an account, as POST /api/admin/users does, and logs
+// it in.
+func (s *Server) handleSignup(c echo.Context) error {
+	var req createUserRequest
+	if err := c.Bind(&req); err != nil {
+		return respondBindError(c, err)
+	}
+	user, err := s.createUser(c, req)
+	if err != nil {
+		return respondWithError(c, err)
+	}
+	return s.respondSession(c, stdhttp.StatusCreated, user)
+}
+
+func (s *Server) respondSession(c echo.Context, status int, user userResponse) error {
+	id, err := uuid.Parse(user.ID)
+	if err != nil {
+		return respondError(c, stdhttp.StatusInternalServerError, "failed to issue session")
+	}
+	token, expiresAt, err := issueSessionToken([]byte(s.cfg.JWTSecret), id, time.Now(), s.cfg.AuthTokenTTL)
+	if err != nil {
+		c.Logger().Errorf("issue session token failed: %v", err)
+		return respondError(c, stdhttp.StatusInternalServerError, "failed to issue session")
+	}
+	return c.JSON(status, sessionResponse{Token: token, ExpiresAt: expiresAt, User: user})
+}
@@ -0,0 +1,254 @@
+package httpapi
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+
+	"github.com/example/keepstack/apps/api/internal/config"
+	"github.com/example/keepstack/apps/api/internal/memstore"
+	"github.com/example/keepstack/apps/api/internal/queue"
+)
+
+const testJWTSecret = "0123456789abcdef0123456789abcdef"
+
+// sessionRequest sends one request to a session test server, with token as
+// its bearer token when set and body as JSON when set.
+type sessionRequest func(method, path, token, body string) *httptest.ResponseRecorder
+
+// newSessionTestServer serves an in-memory instance with sessions and
+// signup enabled.
+func newSessionTestServer(t *testing.T) (*Server, sessionRequest) {
+	t.Helper()
+	t.Setenv("MEMORY_MODE", "true")
+	t.Setenv("DATABASE_URL", "")
+	t.Setenv("NATS_URL", "")
+	t.Setenv("AUTH_SESSIONS", "true")
+	t.Setenv("JWT_SECRET", testJWTSecret)
+	t.Setenv("AUTH_SIGNUP", "true")
+	cfg, err := config.Load()
+	if err != nil {
+		t.Fatalf("load config: %v", err)
+	}
+
+	srv := NewMemoryServer(cfg, memstore.New(cfg.DevUserID), queue.NewMemory(), newTestMetrics())
+	e := echo.New()
+	srv.RegisterRoutes(e)
+
+	return srv, func(method, path, token, body string) *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		if body != "" {
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		}
+		if token != "" {
+			req.Header.Set(echo.HeaderAuthorization, "Bearer "+token)
+		}
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		return rec
+	}
+}
+
+// decodeSession checks rec answered want with a session and returns it.
+func decodeSession(t *testing.T, rec *httptest.ResponseRecorder, want int) sessionResponse {
+	t.Helper()
+	if rec.Code != want {
+		t.Fatalf("expected status %d, got %d: %s", want, rec.Code, rec.Body)
+	}
+	var resp sessionResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode session: %v", err)
+	}
+	if resp.Token == "" || resp.User.ID == "" {
+		t.Fatalf("expected a token and user, got %+v", resp)
+	}
+	return resp
+}
+
+// TestSessionsScopeLinksPerUser signs up two users against the in-memory
+// store and checks each only sees the links they saved.
+func TestSessionsScopeLinksPerUser(t *testing.T) {
+	_, do := newSessionTestServer(t)
+	session := func(rec *httptest.ResponseRecorder, want int) sessionResponse {
+		t.Helper()
+		return decodeSession(t, rec, want)
+	}
+
+	if rec := do(http.MethodGet, "/api/links", "", ""); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401 without a token, got %d", rec.Code)
+	}
+	if rec := do(http.MethodGet, "/api/healthz", "", ""); rec.Code != http.StatusOK {
+		t.Fatalf("expected health checks to stay open, got %d", rec.Code)
+	}
+	if rec := do(http.MethodGet, "/api/media/"+strings.Repeat("a", 64), "", ""); rec.Code == http.StatusUnauthorized {
+		t.Fatalf("expected mirrored images to load without a token, got %d", rec.Code)
+	}
+
+	alice := session(do(http.MethodPost, "/api/auth/signup", "", `{"email":"alice@example.com","password":"correct horse"}`), http.StatusCreated)
+	bob := session(do(http.MethodPost, "/api/auth/signup", "", `{"email":"bob@example.com","password":"battery staple"}`), http.StatusCreated)
+
+	if rec := do(http.MethodPost, "/api/auth/login", "", `{"email":"alice@example.com","password":"wrong password"}`); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401 for a wrong password, got %d", rec.Code)
+	}
+	if rec := do(http.MethodPost, "/api/auth/login", "", `{"email":"nobody@example.com","password":"correct horse"}`); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401 for an unknown email, got %d", rec.Code)
+	}
+	login := session(do(http.MethodPost, "/api/auth/login", "", `{"email":"ALICE@example.com","password":"correct horse"}`), http.StatusOK)
+	if login.User.ID != alice.User.ID {
+		t.Fatalf("expected login as %s, got %s", alice.User.ID, login.User.ID)
+	}
+
+	if rec := do(http.MethodPost, "/api/links", login.Token, `{"url":"https://example.com/alice"}`); rec.Code != http.StatusCreated {
+		t.Fatalf("alice create link: status %d: %s", rec.Code, rec.Body)
+	}
+	rec := do(http.MethodPost, "/api/links", bob.Token, `{"url":"https://example.com/bob"}`)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("bob create link: status %d: %s", rec.Code, rec.Body)
+	}
+	var bobLink struct {
+		ID string `json:"id"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &bobLink); err != nil {
+		t.Fatalf("decode link: %v", err)
+	}
+
+	for _, tc := range []struct {
+		token string
+		want  string
+	}{{alice.Token, "https://example.com/alice"}, {bob.Token, "https://example.com/bob"}} {
+		rec := do(http.MethodGet, "/api/links", tc.token, "")
+		if rec.Code != http.StatusOK {
+			t.Fatalf("list links: status %d: %s", rec.Code, rec.Body)
+		}
+		var page struct {
+			Items []struct {
+				URL string `json:"url"`
+			} `json:"items"`
+		}
+		if err := json.Unmarshal(rec.Body.Bytes(), &page); err != nil {
+			t.Fatalf("decode links: %v", err)
+		}
+		if len(page.Items) != 1 || page.Items[0].URL != tc.want {
+			t.Fatalf("expected only %s, got %+v", tc.want, page.Items)
+		}
+	}
+
+	if rec := do(http.MethodDelete, "/api/links/"+bobLink.ID, alice.Token, ""); rec.Code != http.StatusNotFound {
+		t.Fatalf("expected alice to be unable to delete bob's link, got %d", rec.Code)
+	}
+}
+
+// TestSessionsGuardTokenRoutes checks that with sessions nothing runs as
+// DEV_USER_ID: the reader page needs a token for its link, and the
+// extension and Micropub endpoints take session tokens, not static ones.
+func TestSessionsGuardTokenRoutes(t *testing.T) {
+	t.Setenv("EXTENSION_TOKENS", "ext-token")
+	t.Setenv("MICROPUB_TOKENS", "micropub-token")
+	srv, do := newSessionTestServer(t)
+
+	if got := srv.userID(context.Background()); got != uuid.Nil {
+		t.Fatalf("expected no fallback user with sessions, got %s", got)
+	}
+
+	alice := decodeSession(t, do(http.MethodPost, "/api/auth/signup", "", `{"email":"alice@example.com","password":"correct horse"}`), http.StatusCreated)
+	bob := decodeSession(t, do(http.MethodPost, "/api/auth/signup", "", `{"email":"bob@example.com","password":"battery staple"}`), http.StatusCreated)
+	var links [2]struct {
+		ID string `json:"id"`
+	}
+	for i, url := range []string{"https://example.com/one", "https://example.com/two"} {
+		rec := do(http.MethodPost, "/api/links", alice.Token, `{"url":"`+url+`"}`)
+		if rec.Code != http.StatusCreated {
+			t.Fatalf("create link: status %d: %s", rec.Code, rec.Body)
+		}
+		if err := json.Unmarshal(rec.Body.Bytes(), &links[i]); err != nil {
+			t.Fatalf("decode link: %v", err)
+		}
+	}
+	readPath := "/read/" + links[0].ID
+
+	if rec := do(http.MethodGet, readPath, "", ""); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401 reading without a token, got %d", rec.Code)
+	}
+	if rec := do(http.MethodPost, "/api/links/"+links[0].ID+"/reader", bob.Token, ""); rec.Code != http.StatusNotFound {
+		t.Fatalf("expected bob to get no reader token for alice's link, got %d", rec.Code)
+	}
+	rec := do(http.MethodPost, "/api/links/"+links[0].ID+"/reader", alice.Token, "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("reader token: status %d: %s", rec.Code, rec.Body)
+	}
+	var reader readerTokenResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &reader); err != nil {
+		t.Fatalf("decode reader token: %v", err)
+	}
+	if !strings.HasPrefix(reader.URL, readPath+"?token=") {
+		t.Fatalf("unexpected reader url %q", reader.URL)
+	}
+
+	// The link has no archive yet, so getting past the token is a 404.
+	if rec := do(http.MethodGet, reader.URL, "", ""); rec.Code != http.StatusNotFound {
+		t.Fatalf("reader url: expected the archive lookup, got %d: %s", rec.Code, rec.Body)
+	}
+	if rec := do(http.MethodGet, readPath, alice.Token, ""); rec.Code != http.StatusNotFound {
+		t.Fatalf("session token: expected the archive lookup, got %d: %s", rec.Code, rec.Body)
+	}
+	if rec := do(http.MethodGet, "/read/"+links[1].ID+"?token="+reader.Token, "", ""); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected a reader token to open only its link, got %d", rec.Code)
+	}
+	if rec := do(http.MethodGet, "/api/links", reader.Token, ""); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected a reader token to be refused as a session, got %d", rec.Code)
+	}
+
+	for _, tc := range []struct {
+		path   string
+		static string
+		denied int
+	}{
+		{"/api/ext/tags", "ext-token", http.StatusUnauthorized},
+		{"/api/micropub?q=config", "micropub-token", http.StatusForbidden},
+	} {
+		if rec := do(http.MethodGet, tc.path, tc.static, ""); rec.Code != tc.denied {
+			t.Fatalf("%s: expected %d for a static token, got %d", tc.path, tc.denied, rec.Code)
+		}
+		if rec := do(http.MethodGet, tc.path, alice.Token, ""); rec.Code != http.StatusOK {
+			t.Fatalf("%s: expected a session token accepted, got %d: %s", tc.path, rec.Code, rec.Body)
+		}
+	}
+}
+
+func TestParseSessionTokenRejectsBadTokens(t *testing.T) {
+	t.Parallel()
+
+	userID := uuid.New()
+	now := time.Now()
+	valid, _, err := issueSessionToken([]byte(testJWTSecret), userID, now, time.Hour)
+	if err != nil {
+		t.Fatalf("issue token: %v", err)
+	}
+	if got, err := parseSessionToken([]byte(testJWTSecret), valid); err != nil || got != userID {
+		t.Fatalf("expected %s, got %s (%v)", userID, got, err)
+	}
+
+	expired, _, _ := issueSessionToken([]byte(testJWTSecret), userID, now.Add(-2*time.Hour), time.Hour)
+	for name, token := range map[string]string{
+		"empty":        "",
+		"garbage":      "not-a-token",
+		"wrong secret": valid,
+		"expired":      expired,
+	} {
+		secret := []byte(testJWTSecret)
+		if name == "wrong secret" {
+			secret = []byte(strings.Repeat("x", len(testJWTSecret)))
+		}
+		if _, err := parseSessionToken(secret, token); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
@@ -0,0 +1,111 @@
+package httpapi
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	stdhttp "net/http"
+	"reflect"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+// jsonSerializer decodes request bodies for c.Bind. In strict mode fields the
+// target struct does not declare are rejected instead of silently dropped.
+type jsonSerializer struct {
+	echo.DefaultJSONSerializer
+	strict bool
+}
+
+// Deserialize decodes the request body into i. Errors are returned as-is so
+// respondBindError can classify them; Echo's binder wraps them in a 400.
+func (s jsonSerializer) Deserialize(c echo.Context, i interface{}) error {
+	dec := json.NewDecoder(c.Request().Body)
+	if s.strict {
+		dec.DisallowUnknownFields()
+	}
+	return dec.Decode(i)
+}
+
+// BodyLimitMiddleware rejects requests whose body exceeds maxBytes with a 413.
+// Bodies without a Content-Length are cut off while reading, which surfaces
+// through c.Bind and respondBindError. A maxBytes of zero disables the limit.
+func BodyLimitMiddleware(maxBytes int64) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		if maxBytes <= 0 {
+			return next
+		}
+		return func(c echo.Context) error {
+			req := c.Request()
+			if req.ContentLength > maxBytes {
+				return respondError(c, stdhttp.StatusRequestEntityTooLarge, bodyTooLargeMessage(maxBytes))
+			}
+			if req.Body != nil {
+				req.Body = stdhttp.MaxBytesReader(c.Response(), req.Body, maxBytes)
+			}
+			return next(c)
+		}
+	}
+}
+
+// respondBindError writes the response for a failed c.Bind so every handler
+// rejects bad payloads the same way: 413 for oversized bodies, 415 for
+// bodies that are not JSON, 422 for well-formed JSON with unknown fields or
+// values of the wrong type, and 400 for anything that does not parse.
+func respondBindError(c echo.Context, err error) error {
+	var (
+		maxBytesErr *stdhttp.MaxBytesError
+		typeErr     *json.UnmarshalTypeError
+	)
+	switch {
+	case errors.As(err, &maxBytesErr):
+		return respondError(c, stdhttp.StatusRequestEntityTooLarge, bodyTooLargeMessage(maxBytesErr.Limit))
+	case errors.Is(err, echo.ErrUnsupportedMediaType):
+		return respondError(c, stdhttp.StatusUnsupportedMediaType, "request body must be JSON")
+	case errors.As(err, &typeErr):
+		return respondErrorCode(c, stdhttp.StatusUnprocessableEntity, errCodeInvalidFieldType,
+			fmt.Sprintf("field %s must be %s", typeErr.Field, jsonTypeName(typeErr.Type.Kind())),
+			map[string]any{"field": typeErr.Field})
+	}
+	if field, ok := unknownField(err); ok {
+		return respondErrorCode(c, stdhttp.StatusUnprocessableEntity, errCodeUnknownField,
+			fmt.Sprintf("unknown field %s", field),
+			map[string]any{"field": field})
+	}
+	return respondError(c, stdhttp.StatusBadRequest, "invalid payload")
+}
+
+func bodyTooLargeMessage(limit int64) string {
+	return fmt.Sprintf("request body exceeds %d bytes", limit)
+}
+
+// unknownField extracts the field name from encoding/json's unexported
+// unknown field error.
+func unknownField(err error) (string, bool) {
+	for ; err != nil; err = errors.Unwrap(err) {
+		if field, ok := strings.CutPrefix(err.Error(), `json: unknown field "`); ok {
+			return strings.TrimSuffix(field, `"`), true
+		}
+	}
+	return "", false
+}
+
+func jsonTypeName(kind reflect.Kind) string {
+	switch kind {
+	case reflect.String:
+		return "a string"
+	case reflect.Bool:
+		return "a boolean"
+	case reflect.Slice, reflect.Array:
+		return "an array"
+	case reflect.Map, reflect.Struct:
+		return "an object"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return "a number"
+	default:
+		return "a valid value"
+	}
+}
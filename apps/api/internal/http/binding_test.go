@@ -0,0 +1,134 @@
+package httpapi
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/example/keepstack/apps/api/internal/config"
+)
+
+func TestCreateTagRejectsBadPayloads(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name        string
+		cfg         config.Config
+		body        io.Reader
+		contentType string
+		chunked     bool
+		wantStatus  int
+		wantCode    string
+		wantField   string
+	}{
+		{
+			name:        "oversized by content length",
+			cfg:         config.Config{HTTPMaxBodyBytes: 16},
+			body:        strings.NewReader(`{"name":"` + strings.Repeat("a", 32) + `"}`),
+			contentType: echo.MIMEApplicationJSON,
+			wantStatus:  http.StatusRequestEntityTooLarge,
+			wantCode:    errCodePayloadTooLarge,
+		},
+		{
+			name:        "oversized while streaming",
+			cfg:         config.Config{HTTPMaxBodyBytes: 16},
+			body:        strings.NewReader(`{"name":"` + strings.Repeat("a", 32) + `"}`),
+			contentType: echo.MIMEApplicationJSON,
+			chunked:     true,
+			wantStatus:  http.StatusRequestEntityTooLarge,
+			wantCode:    errCodePayloadTooLarge,
+		},
+		{
+			name:        "unknown field in strict mode",
+			cfg:         config.Config{HTTPStrictJSON: true},
+			body:        strings.NewReader(`{"name":"alpha","colour":"red"}`),
+			contentType: echo.MIMEApplicationJSON,
+			wantStatus:  http.StatusUnprocessableEntity,
+			wantCode:    errCodeUnknownField,
+			wantField:   "colour",
+		},
+		{
+			name:        "wrong type",
+			body:        strings.NewReader(`{"name":42}`),
+			contentType: echo.MIMEApplicationJSON,
+			wantStatus:  http.StatusUnprocessableEntity,
+			wantCode:    errCodeInvalidFieldType,
+			wantField:   "name",
+		},
+		{
+			name:        "malformed json",
+			body:        strings.NewReader(`{"name":`),
+			contentType: echo.MIMEApplicationJSON,
+			wantStatus:  http.StatusBadRequest,
+			wantCode:    errCodeInvalidRequest,
+		},
+		{
+			name:        "not json",
+			body:        strings.NewReader(`name=alpha`),
+			contentType: echo.MIMETextPlain,
+			wantStatus:  http.StatusUnsupportedMediaType,
+			wantCode:    errCodeUnsupportedMediaType,
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			queries := &mockQueries{}
+			srv := &Server{cfg: tc.cfg, queries: queries, metrics: newTestMetrics()}
+			e := echo.New()
+			srv.RegisterRoutes(e)
+
+			req := httptest.NewRequest(http.MethodPost, "/api/tags", tc.body)
+			req.Header.Set(echo.HeaderContentType, tc.contentType)
+			if tc.chunked {
+				req.ContentLength = -1
+			}
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+
+			if rec.Code != tc.wantStatus {
+				t.Fatalf("expected status %d, got %d: %s", tc.wantStatus, rec.Code, rec.Body.String())
+			}
+			var payload errorResponse
+			if err := json.Unmarshal(rec.Body.Bytes(), &payload); err != nil {
+				t.Fatalf("decode response: %v", err)
+			}
+			if payload.Code != tc.wantCode || payload.Message == "" || payload.RequestID == "" {
+				t.Fatalf("expected code %q with a message and request id, got %+v", tc.wantCode, payload)
+			}
+			field, _ := payload.Details["field"].(string)
+			if field != tc.wantField {
+				t.Fatalf("expected field %q, got %q", tc.wantField, field)
+			}
+			if queries.createTagCalled {
+				t.Fatalf("expected create tag not to be called")
+			}
+		})
+	}
+}
+
+func TestCreateTagAllowsUnknownFieldsByDefault(t *testing.T) {
+	t.Parallel()
+
+	srv := &Server{cfg: config.Config{}, queries: &mockQueries{}, metrics: newTestMetrics()}
+	e := echo.New()
+	srv.RegisterRoutes(e)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/tags", strings.NewReader(`{"name":"","colour":"red"}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+
+	// The empty name fails validation, proving the unknown field was ignored.
+	if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), "name is required") {
+		t.Fatalf("expected name validation error, got %d: %s", rec.Code, rec.Body.String())
+	}
+}
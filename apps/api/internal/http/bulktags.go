@@ -0,0 +1,107 @@
+package httpapi
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	stdhttp "net/http"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/labstack/echo/v4"
+
+	"github.com/example/keepstack/apps/api/internal/db"
+)
+
+// applyTagRequest selects the links a tag is applied to. From and To are
+// inclusive YYYY-MM-DD dates in UTC.
+type applyTagRequest struct {
+	Query  string `json:"query"`
+	Domain string `json:"domain"`
+	From   string `json:"from"`
+	To     string `json:"to"`
+}
+
+type applyTagResponse struct {
+	TagID   int32 `json:"tag_id"`
+	Matched int64 `json:"matched"`
+	Tagged  int64 `json:"tagged"`
+}
+
+// handleApplyTag tags every link matching a filter in one statement, so
+// clients don't loop over PUT /links/:id/tags. At least one filter is
+// required; tagging the whole library by accident is hard to undo.
+func (s *Server) handleApplyTag(c echo.Context) error {
+	id, err := parseInt32Param(c.Param("id"))
+	if err != nil {
+		s.metrics.LinkTagMutateFailure.Inc()
+		return respondError(c, stdhttp.StatusBadRequest, "invalid tag id")
+	}
+
+	var req applyTagRequest
+	if err := c.Bind(&req); err != nil {
+		s.metrics.LinkTagMutateFailure.Inc()
+		return respondBindError(c, err)
+	}
+
+	params := db.ApplyTagToMatchingLinksParams{UserID: uuidToPg(s.currentUser(c)), TagID: id}
+	if query := strings.TrimSpace(req.Query); query != "" {
+		params.Query = pgtype.Text{String: query, Valid: true}
+	}
+	if strings.TrimSpace(req.Domain) != "" {
+		domain, err := normalizeDomain(req.Domain)
+		if err != nil {
+			s.metrics.LinkTagMutateFailure.Inc()
+			return respondError(c, stdhttp.StatusBadRequest, err.Error())
+		}
+		params.Domain = pgtype.Text{String: domain, Valid: true}
+	}
+	if req.From != "" {
+		from, err := time.Parse(time.DateOnly, req.From)
+		if err != nil {
+			s.metrics.LinkTagMutateFailure.Inc()
+			return respondError(c, stdhttp.StatusBadRequest, "from must be formatted as YYYY-MM-DD")
+		}
+		params.CreatedAfter = pgtype.Timestamptz{Time: from, Valid: true}
+	}
+	if req.To != "" {
+		to, err := time.Parse(time.DateOnly, req.To)
+		if err != nil {
+			s.metrics.LinkTagMutateFailure.Inc()
+			return respondError(c, stdhttp.StatusBadRequest, "to must be formatted as YYYY-MM-DD")
+		}
+		params.CreatedBefore = pgtype.Timestamptz{Time: to.AddDate(0, 0, 1), Valid: true}
+	}
+	if params.CreatedAfter.Valid && params.CreatedBefore.Valid && !params.CreatedAfter.Time.Before(params.CreatedBefore.Time) {
+		s.metrics.LinkTagMutateFailure.Inc()
+		return respondError(c, stdhttp.StatusBadRequest, "from must not be after to")
+	}
+	if !params.Query.Valid && !params.Domain.Valid && !params.CreatedAfter.Valid && !params.CreatedBefore.Valid {
+		s.metrics.LinkTagMutateFailure.Inc()
+		return respondError(c, stdhttp.StatusBadRequest, "at least one of query, domain, from or to is required")
+	}
+
+	ctx := c.Request().Context()
+	if _, err := s.queries.GetTag(ctx, db.GetTagParams{ID: id, UserID: params.UserID}); err != nil {
+		s.metrics.LinkTagMutateFailure.Inc()
+		if errors.Is(err, pgx.ErrNoRows) {
+			return respondError(c, stdhttp.StatusNotFound, "tag not found")
+		}
+		c.Logger().Errorf("get tag: %v", err)
+		return respondError(c, stdhttp.StatusInternalServerError, "failed to load tag")
+	}
+
+	result, err := s.queries.ApplyTagToMatchingLinks(ctx, params)
+	if err != nil {
+		s.metrics.LinkTagMutateFailure.Inc()
+		if params.Query.Valid && isFullTextParseError(err) {
+			return respondError(c, stdhttp.StatusBadRequest, "invalid search query")
+		}
+		c.Logger().Errorf("apply tag: %v", err)
+		return respondError(c, stdhttp.StatusInternalServerError, "failed to apply tag")
+	}
+
+	s.metrics.LinkTagMutateSuccess.Inc()
+	return c.JSON(stdhttp.StatusOK, applyTagResponse{TagID: id, Matched: result.Matched, Tagged: result.Tagged})
+}
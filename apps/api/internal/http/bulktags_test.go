@@ -0,0 +1,92 @@
+package httpapi
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/labstack/echo/v4"
+
+	"github.com/example/keepstack/apps/api/internal/config"
+	"github.com/example/keepstack/apps/api/internal/db"
+)
+
+func TestHandleApplyTag(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		tag  string
+		body string
+		want int
+	}{
+		{name: "filters", tag: "7", body: `{"query":" go ","domain":"www.Example.com","from":"2024-06-01","to":"2024-06-30"}`, want: http.StatusOK},
+		{name: "no filter", tag: "7", body: `{"query":"  "}`, want: http.StatusBadRequest},
+		{name: "bad domain", tag: "7", body: `{"domain":"bad_domain"}`, want: http.StatusBadRequest},
+		{name: "bad date", tag: "7", body: `{"from":"06/01/2024"}`, want: http.StatusBadRequest},
+		{name: "inverted range", tag: "7", body: `{"from":"2024-07-01","to":"2024-06-01"}`, want: http.StatusBadRequest},
+		{name: "invalid tag", tag: "abc", body: `{"query":"go"}`, want: http.StatusBadRequest},
+		{name: "missing tag", tag: "8", body: `{"query":"go"}`, want: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			var applied *db.ApplyTagToMatchingLinksParams
+			queries := &mockQueries{
+				getTagFn: func(ctx context.Context, arg db.GetTagParams) (db.Tag, error) {
+					if arg.ID != 7 {
+						return db.Tag{}, pgx.ErrNoRows
+					}
+					return db.Tag{ID: arg.ID, Name: "go"}, nil
+				},
+				applyTagToMatchingLinksFn: func(ctx context.Context, arg db.ApplyTagToMatchingLinksParams) (db.ApplyTagToMatchingLinksRow, error) {
+					applied = &arg
+					return db.ApplyTagToMatchingLinksRow{Matched: 3, Tagged: 2}, nil
+				},
+			}
+			cfg := config.Config{DevUserID: uuid.MustParse("abababab-abab-abab-abab-abababababab")}
+			srv := &Server{cfg: cfg, queries: queries, metrics: newTestMetrics()}
+			e := echo.New()
+			srv.RegisterRoutes(e)
+
+			req := httptest.NewRequest(http.MethodPost, "/api/tags/"+tt.tag+"/apply", strings.NewReader(tt.body))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+
+			if rec.Code != tt.want {
+				t.Fatalf("expected status %d, got %d: %s", tt.want, rec.Code, rec.Body.String())
+			}
+			if tt.want != http.StatusOK {
+				if applied != nil {
+					t.Fatalf("expected no links to be tagged, got %+v", applied)
+				}
+				return
+			}
+
+			var resp applyTagResponse
+			if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("decode response: %v", err)
+			}
+			if resp != (applyTagResponse{TagID: 7, Matched: 3, Tagged: 2}) {
+				t.Fatalf("unexpected response %+v", resp)
+			}
+			if applied.Query.String != "go" || applied.Domain.String != "example.com" || applied.UserID != uuidToPg(cfg.DevUserID) {
+				t.Fatalf("unexpected filter %+v", applied)
+			}
+			from := time.Date(2024, time.June, 1, 0, 0, 0, 0, time.UTC)
+			until := time.Date(2024, time.July, 1, 0, 0, 0, 0, time.UTC)
+			if !applied.CreatedAfter.Time.Equal(from) || !applied.CreatedBefore.Time.Equal(until) {
+				t.Fatalf("expected the range to cover June, got %v to %v", applied.CreatedAfter.Time, applied.CreatedBefore.Time)
+			}
+		})
+	}
+}
@@ -0,0 +1,131 @@
+package httpapi
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
+
+	"github.com/example/keepstack/media"
+)
+
+// revalidateCacheControl lets browsers keep a copy of per-user responses but
+// forces them to check the ETag before reuse, so edits show up immediately.
+const revalidateCacheControl = "private, no-cache"
+
+// compressMinLength skips gzip for bodies too small to benefit from it.
+const compressMinLength = 1024
+
+// CompressMiddleware gzips responses for clients that accept it. Level 0
+// disables compression. /metrics is skipped because the Prometheus handler
+// negotiates its own encoding.
+func CompressMiddleware(level int) echo.MiddlewareFunc {
+	if level == 0 {
+		return func(next echo.HandlerFunc) echo.HandlerFunc { return next }
+	}
+	return middleware.GzipWithConfig(middleware.GzipConfig{
+		Level:     level,
+		MinLength: compressMinLength,
+		Skipper: func(c echo.Context) bool {
+			// Event streams must reach the client as each event is flushed,
+			// and mirrored images are already compressed.
+			path := c.Request().URL.Path
+			return path == "/metrics" || path == "/api/events" || strings.HasPrefix(path, media.PathPrefix)
+		},
+	})
+}
+
+// conditionalGET buffers successful GET responses, tags them with a weak ETag
+// derived from the body, and answers 304 Not Modified when the client already
+// holds that version. It suits small, frequently polled endpoints such as tag
+// lists; large or streamed responses should not use it.
+func conditionalGET(cacheControl string) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			req := c.Request()
+			if req.Method != http.MethodGet && req.Method != http.MethodHead {
+				return next(c)
+			}
+
+			res := c.Response()
+			original := res.Writer
+			buffered := &bufferedResponseWriter{header: original.Header()}
+			res.Writer = buffered
+			err := next(c)
+			res.Writer = original
+			if buffered.status == 0 {
+				// Nothing was written; echo's error handler responds instead.
+				return err
+			}
+			if err != nil || buffered.status != http.StatusOK {
+				original.WriteHeader(buffered.status)
+				if _, writeErr := original.Write(buffered.body.Bytes()); writeErr != nil && err == nil {
+					err = writeErr
+				}
+				return err
+			}
+
+			sum := sha256.Sum256(buffered.body.Bytes())
+			etag := `W/"` + hex.EncodeToString(sum[:16]) + `"`
+			header := original.Header()
+			header.Set("ETag", etag)
+			header.Set("Cache-Control", cacheControl)
+
+			if etagMatches(req.Header.Get("If-None-Match"), etag) {
+				header.Del(echo.HeaderContentLength)
+				header.Del(echo.HeaderContentType)
+				res.Status = http.StatusNotModified
+				res.Size = 0
+				original.WriteHeader(http.StatusNotModified)
+				return nil
+			}
+
+			original.WriteHeader(http.StatusOK)
+			_, err = original.Write(buffered.body.Bytes())
+			return err
+		}
+	}
+}
+
+// etagMatches reports whether an If-None-Match header lists etag, using the
+// weak comparison RFC 9110 prescribes for conditional GETs.
+func etagMatches(ifNoneMatch, etag string) bool {
+	if ifNoneMatch == "" {
+		return false
+	}
+	want := strings.TrimPrefix(etag, "W/")
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == want {
+			return true
+		}
+	}
+	return false
+}
+
+type bufferedResponseWriter struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func (w *bufferedResponseWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *bufferedResponseWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+}
+
+func (w *bufferedResponseWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	return w.body.Write(b)
+}
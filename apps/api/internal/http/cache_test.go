@@ -0,0 +1,124 @@
+package httpapi
+
+import (
+	"compress/gzip"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/labstack/echo/v4"
+
+	"github.com/example/keepstack/apps/api/internal/config"
+	"github.com/example/keepstack/apps/api/internal/db"
+)
+
+func TestTagListConditionalGET(t *testing.T) {
+	t.Parallel()
+
+	queries := &mockQueries{
+		listTagLinkCountsFn: func(ctx context.Context, userID pgtype.UUID) ([]db.ListTagLinkCountsRow, error) {
+			return []db.ListTagLinkCountsRow{{ID: 1, Name: "alpha", LinkCount: 3}}, nil
+		},
+	}
+	srv := &Server{cfg: config.Config{}, queries: queries, metrics: newTestMetrics()}
+	e := echo.New()
+	srv.RegisterRoutes(e)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/tags", nil)
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, rec.Code)
+	}
+	etag := rec.Header().Get("ETag")
+	if !strings.HasPrefix(etag, `W/"`) {
+		t.Fatalf("expected weak ETag, got %q", etag)
+	}
+	if got := rec.Header().Get("Cache-Control"); got != revalidateCacheControl {
+		t.Fatalf("expected Cache-Control %q, got %q", revalidateCacheControl, got)
+	}
+	if !strings.Contains(rec.Body.String(), "alpha") {
+		t.Fatalf("expected tag payload, got %s", rec.Body.String())
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/api/tags", nil)
+	req.Header.Set("If-None-Match", etag)
+	rec = httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusNotModified {
+		t.Fatalf("expected status %d, got %d", http.StatusNotModified, rec.Code)
+	}
+	if rec.Body.Len() != 0 {
+		t.Fatalf("expected empty body, got %q", rec.Body.String())
+	}
+}
+
+func TestConditionalGETPassesErrorsThrough(t *testing.T) {
+	t.Parallel()
+
+	e := echo.New()
+	e.GET("/missing", func(c echo.Context) error {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "not found"})
+	}, conditionalGET(revalidateCacheControl))
+
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/missing", nil))
+
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("expected status %d, got %d", http.StatusNotFound, rec.Code)
+	}
+	if rec.Header().Get("ETag") != "" {
+		t.Fatalf("expected no ETag on error responses, got %q", rec.Header().Get("ETag"))
+	}
+	if !strings.Contains(rec.Body.String(), "not found") {
+		t.Fatalf("expected error body, got %q", rec.Body.String())
+	}
+}
+
+func TestCompressMiddleware(t *testing.T) {
+	t.Parallel()
+
+	large := strings.Repeat("keepstack extracted text ", 200)
+	e := echo.New()
+	e.Use(CompressMiddleware(5))
+	e.GET("/large", func(c echo.Context) error { return c.String(http.StatusOK, large) })
+	e.GET("/small", func(c echo.Context) error { return c.String(http.StatusOK, "ok") })
+
+	req := httptest.NewRequest(http.MethodGet, "/large", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+
+	if got := rec.Header().Get("Content-Encoding"); got != "gzip" {
+		t.Fatalf("expected gzip encoding, got %q", got)
+	}
+	reader, err := gzip.NewReader(rec.Body)
+	if err != nil {
+		t.Fatalf("open gzip body: %v", err)
+	}
+	body, err := io.ReadAll(reader)
+	if err != nil {
+		t.Fatalf("read gzip body: %v", err)
+	}
+	if string(body) != large {
+		t.Fatalf("decompressed body mismatch")
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/small", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	rec = httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+
+	if got := rec.Header().Get("Content-Encoding"); got != "" {
+		t.Fatalf("expected small body to stay uncompressed, got %q", got)
+	}
+	if rec.Body.String() != "ok" {
+		t.Fatalf("unexpected body %q", rec.Body.String())
+	}
+}
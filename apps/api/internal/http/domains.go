@@ -0,0 +1,215 @@
+package httpapi
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	stdhttp "net/http"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/labstack/echo/v4"
+
+	"github.com/example/keepstack/apps/api/internal/db"
+)
+
+const (
+	maxDomainLength     = 253
+	maxDomainTags       = 20
+	maxFetchHeaders     = 10
+	maxFetchHeaderValue = 1024
+)
+
+var (
+	domainLabelPattern = regexp.MustCompile(`^[a-z0-9]([a-z0-9-]*[a-z0-9])?$`)
+	headerNamePattern  = regexp.MustCompile("^[!#$%&'*+.^_`|~0-9A-Za-z-]+$")
+)
+
+// reservedFetchHeaders are managed by the worker's HTTP client. Overriding
+// Accept-Encoding, for one, would stop responses being decompressed.
+var reservedFetchHeaders = map[string]struct{}{
+	"Accept-Encoding":   {},
+	"Connection":        {},
+	"Content-Length":    {},
+	"Host":              {},
+	"Keep-Alive":        {},
+	"Te":                {},
+	"Trailer":           {},
+	"Transfer-Encoding": {},
+	"Upgrade":           {},
+}
+
+type domainPreferenceRequest struct {
+	AlwaysFavorite bool              `json:"always_favorite"`
+	DefaultTags    []string          `json:"default_tags"`
+	NeverResurface bool              `json:"never_resurface"`
+	FetchHeaders   map[string]string `json:"fetch_headers"`
+}
+
+type domainPreferenceResponse struct {
+	Domain         string            `json:"domain"`
+	AlwaysFavorite bool              `json:"always_favorite"`
+	DefaultTags    []string          `json:"default_tags"`
+	NeverResurface bool              `json:"never_resurface"`
+	FetchHeaders   map[string]string `json:"fetch_headers"`
+	CreatedAt      time.Time         `json:"created_at"`
+	UpdatedAt      time.Time         `json:"updated_at"`
+}
+
+// handleListDomainPreferences lists the caller's domain preferences.
+func (s *Server) handleListDomainPreferences(c echo.Context) error {
+	prefs, err := s.queries.ListDomainPreferences(c.Request().Context(), uuidToPg(s.currentUser(c)))
+	if err != nil {
+		c.Logger().Errorf("list domain preferences: %v", err)
+		return respondError(c, stdhttp.StatusInternalServerError, "failed to list domain preferences")
+	}
+	items := make([]domainPreferenceResponse, 0, len(prefs))
+	for _, pref := range prefs {
+		items = append(items, toDomainPreferenceResponse(pref))
+	}
+	return c.JSON(stdhttp.StatusOK, map[string]any{"items": items})
+}
+
+// handleSetDomainPreference creates or replaces the caller's preference for
+// a domain.
+func (s *Server) handleSetDomainPreference(c echo.Context) error {
+	domain, err := normalizeDomain(c.Param("domain"))
+	if err != nil {
+		return respondError(c, stdhttp.StatusBadRequest, err.Error())
+	}
+	var req domainPreferenceRequest
+	if err := c.Bind(&req); err != nil {
+		return respondBindError(c, err)
+	}
+	params, err := validateDomainPreference(req)
+	if err != nil {
+		return respondError(c, stdhttp.StatusBadRequest, err.Error())
+	}
+	params.UserID = uuidToPg(s.currentUser(c))
+	params.Domain = domain
+
+	pref, err := s.queries.UpsertDomainPreference(c.Request().Context(), params)
+	if err != nil {
+		c.Logger().Errorf("set domain preference: upsert failed: %v", err)
+		return respondError(c, stdhttp.StatusInternalServerError, "failed to save domain preference")
+	}
+	return c.JSON(stdhttp.StatusOK, toDomainPreferenceResponse(pref))
+}
+
+// handleDeleteDomainPreference removes the caller's preference for a domain.
+func (s *Server) handleDeleteDomainPreference(c echo.Context) error {
+	domain, err := normalizeDomain(c.Param("domain"))
+	if err != nil {
+		return respondError(c, stdhttp.StatusBadRequest, err.Error())
+	}
+	deleted, err := s.queries.DeleteDomainPreference(c.Request().Context(), db.DeleteDomainPreferenceParams{
+		UserID: uuidToPg(s.currentUser(c)),
+		Domain: domain,
+	})
+	if err != nil {
+		c.Logger().Errorf("delete domain preference: delete failed: %v", err)
+		return respondError(c, stdhttp.StatusInternalServerError, "failed to delete domain preference")
+	}
+	if deleted == 0 {
+		return respondError(c, stdhttp.StatusNotFound, "domain preference not found")
+	}
+	return c.NoContent(stdhttp.StatusNoContent)
+}
+
+// domainPreference returns userID's most specific preference covering
+// rawURL's host. Preferences only adjust a save, so a failed lookup is
+// logged and treated as having none rather than failing the save.
+func (s *Server) domainPreference(c echo.Context, userID uuid.UUID, rawURL string) (db.DomainPreference, bool) {
+	pref, err := s.queries.MatchDomainPreference(c.Request().Context(), db.MatchDomainPreferenceParams{
+		UserID: uuidToPg(userID),
+		Url:    rawURL,
+	})
+	if err != nil {
+		if !errors.Is(err, pgx.ErrNoRows) {
+			c.Logger().Warnf("domain preference lookup for %s failed: %v", rawURL, err)
+		}
+		return db.DomainPreference{}, false
+	}
+	return pref, true
+}
+
+// normalizeDomain lower-cases a domain and drops a leading www., so that a
+// preference set for www.example.com covers example.com too.
+func normalizeDomain(raw string) (string, error) {
+	domain := strings.TrimSuffix(strings.ToLower(strings.TrimSpace(raw)), ".")
+	domain = strings.TrimPrefix(domain, "www.")
+	if domain == "" || len(domain) > maxDomainLength {
+		return "", fmt.Errorf("domain must be a host name such as example.com")
+	}
+	for _, label := range strings.Split(domain, ".") {
+		if len(label) > 63 || !domainLabelPattern.MatchString(label) {
+			return "", fmt.Errorf("domain must be a host name such as example.com")
+		}
+	}
+	return domain, nil
+}
+
+// validateDomainPreference checks a preference request and encodes its
+// fetch headers, with canonical header names, for storage.
+func validateDomainPreference(req domainPreferenceRequest) (db.UpsertDomainPreferenceParams, error) {
+	params := db.UpsertDomainPreferenceParams{
+		AlwaysFavorite: req.AlwaysFavorite,
+		NeverResurface: req.NeverResurface,
+		DefaultTags:    []string{},
+	}
+	for _, tag := range req.DefaultTags {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			params.DefaultTags = append(params.DefaultTags, tag)
+		}
+	}
+	if len(params.DefaultTags) > maxDomainTags {
+		return params, fmt.Errorf("at most %d default tags are allowed", maxDomainTags)
+	}
+
+	if len(req.FetchHeaders) > maxFetchHeaders {
+		return params, fmt.Errorf("at most %d fetch headers are allowed", maxFetchHeaders)
+	}
+	headers := make(map[string]string, len(req.FetchHeaders))
+	for name, value := range req.FetchHeaders {
+		if !headerNamePattern.MatchString(name) {
+			return params, fmt.Errorf("fetch header %q is not a valid header name", name)
+		}
+		name = stdhttp.CanonicalHeaderKey(name)
+		if _, reserved := reservedFetchHeaders[name]; reserved {
+			return params, fmt.Errorf("fetch header %s cannot be overridden", name)
+		}
+		if len(value) > maxFetchHeaderValue || strings.ContainsAny(value, "\r\n\x00") {
+			return params, fmt.Errorf("fetch header %s has an invalid value", name)
+		}
+		headers[name] = value
+	}
+	encoded, err := json.Marshal(headers)
+	if err != nil {
+		return params, err
+	}
+	params.FetchHeaders = encoded
+	return params, nil
+}
+
+func toDomainPreferenceResponse(pref db.DomainPreference) domainPreferenceResponse {
+	resp := domainPreferenceResponse{
+		Domain:         pref.Domain,
+		AlwaysFavorite: pref.AlwaysFavorite,
+		DefaultTags:    pref.DefaultTags,
+		NeverResurface: pref.NeverResurface,
+		FetchHeaders:   map[string]string{},
+		CreatedAt:      pref.CreatedAt.Time,
+		UpdatedAt:      pref.UpdatedAt.Time,
+	}
+	if resp.DefaultTags == nil {
+		resp.DefaultTags = []string{}
+	}
+	if len(pref.FetchHeaders) > 0 {
+		_ = json.Unmarshal(pref.FetchHeaders, &resp.FetchHeaders)
+	}
+	return resp
+}
@@ -0,0 +1,200 @@
+package httpapi
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/labstack/echo/v4"
+
+	"github.com/example/keepstack/apps/api/internal/config"
+	"github.com/example/keepstack/apps/api/internal/db"
+)
+
+func TestNormalizeDomain(t *testing.T) {
+	t.Parallel()
+
+	valid := map[string]string{
+		"Example.COM":      "example.com",
+		"www.example.com.": "example.com",
+		"blog.example.com": "blog.example.com",
+		"localhost":        "localhost",
+	}
+	for raw, want := range valid {
+		got, err := normalizeDomain(raw)
+		if err != nil || got != want {
+			t.Fatalf("normalizeDomain(%q) = %q, %v; expected %q", raw, got, err, want)
+		}
+	}
+	for _, raw := range []string{"", "https://example.com", "exa mple.com", "-bad.com", "example..com", "ex_ample.com"} {
+		if _, err := normalizeDomain(raw); err == nil {
+			t.Fatalf("expected %q to be rejected", raw)
+		}
+	}
+}
+
+func TestHandleSetDomainPreference(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name   string
+		domain string
+		body   string
+		want   int
+	}{
+		{name: "valid", domain: "www.Example.com", body: `{"always_favorite":true,"default_tags":[" go ",""],"fetch_headers":{"cookie":"a=b"}}`, want: http.StatusOK},
+		{name: "invalid domain", domain: "bad_domain", body: `{}`, want: http.StatusBadRequest},
+		{name: "reserved header", domain: "example.com", body: `{"fetch_headers":{"host":"evil.test"}}`, want: http.StatusBadRequest},
+		{name: "header injection", domain: "example.com", body: `{"fetch_headers":{"X-Token":"a\r\nb"}}`, want: http.StatusBadRequest},
+		{name: "bad header name", domain: "example.com", body: `{"fetch_headers":{"bad header":"x"}}`, want: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			var stored *db.UpsertDomainPreferenceParams
+			queries := &mockQueries{
+				upsertDomainPreferenceFn: func(ctx context.Context, arg db.UpsertDomainPreferenceParams) (db.DomainPreference, error) {
+					stored = &arg
+					return db.DomainPreference{
+						UserID:         arg.UserID,
+						Domain:         arg.Domain,
+						AlwaysFavorite: arg.AlwaysFavorite,
+						DefaultTags:    arg.DefaultTags,
+						NeverResurface: arg.NeverResurface,
+						FetchHeaders:   arg.FetchHeaders,
+					}, nil
+				},
+			}
+			cfg := config.Config{DevUserID: uuid.MustParse("eeeeeeee-eeee-eeee-eeee-eeeeeeeeeeee")}
+			srv := &Server{cfg: cfg, queries: queries, metrics: newTestMetrics()}
+			e := echo.New()
+			srv.RegisterRoutes(e)
+
+			req := httptest.NewRequest(http.MethodPut, "/api/domain-preferences/"+tt.domain, strings.NewReader(tt.body))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+
+			if rec.Code != tt.want {
+				t.Fatalf("expected status %d, got %d: %s", tt.want, rec.Code, rec.Body.String())
+			}
+			if tt.want != http.StatusOK {
+				if stored != nil {
+					t.Fatalf("expected nothing to be stored, got %+v", stored)
+				}
+				return
+			}
+			var resp domainPreferenceResponse
+			if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("decode response: %v", err)
+			}
+			if resp.Domain != "example.com" || !resp.AlwaysFavorite || len(resp.DefaultTags) != 1 || resp.DefaultTags[0] != "go" {
+				t.Fatalf("unexpected preference %+v", resp)
+			}
+			if resp.FetchHeaders["Cookie"] != "a=b" {
+				t.Fatalf("expected a canonical Cookie header, got %v", resp.FetchHeaders)
+			}
+		})
+	}
+}
+
+func TestHandleDeleteDomainPreference(t *testing.T) {
+	t.Parallel()
+
+	cfg := config.Config{DevUserID: uuid.MustParse("eeeeeeee-eeee-eeee-eeee-eeeeeeeeeeef")}
+	deleted := map[string]bool{}
+	queries := &mockQueries{
+		deleteDomainPreferenceFn: func(ctx context.Context, arg db.DeleteDomainPreferenceParams) (int64, error) {
+			if arg.Domain != "example.com" || deleted[arg.Domain] {
+				return 0, nil
+			}
+			deleted[arg.Domain] = true
+			return 1, nil
+		},
+	}
+	srv := &Server{cfg: cfg, queries: queries, metrics: newTestMetrics()}
+	e := echo.New()
+	srv.RegisterRoutes(e)
+
+	for _, want := range []int{http.StatusNoContent, http.StatusNotFound} {
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, httptest.NewRequest(http.MethodDelete, "/api/domain-preferences/www.example.com", nil))
+		if rec.Code != want {
+			t.Fatalf("expected status %d, got %d", want, rec.Code)
+		}
+	}
+}
+
+func TestHandleCreateLinkAppliesDomainPreference(t *testing.T) {
+	t.Parallel()
+
+	cfg := config.Config{DevUserID: uuid.MustParse("efefefef-efef-efef-efef-efefefefefef")}
+	tests := []struct {
+		name         string
+		body         string
+		pref         bool
+		wantFavorite bool
+		wantTags     int
+	}{
+		{name: "no preference", body: `{"url":"https://other.org/a"}`},
+		{name: "preference applies", body: `{"url":"https://blog.example.com/a"}`, pref: true, wantFavorite: true, wantTags: 2},
+		{name: "explicit favorite wins", body: `{"url":"https://blog.example.com/a","favorite":false}`, pref: true, wantTags: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			var created db.CreateLinkParams
+			tagged := 0
+			queries := &mockQueries{
+				listSimilarLinksFn: func(ctx context.Context, arg db.ListSimilarLinksParams) ([]db.ListSimilarLinksRow, error) {
+					return nil, nil
+				},
+				matchDomainPreferenceFn: func(ctx context.Context, arg db.MatchDomainPreferenceParams) (db.DomainPreference, error) {
+					if !tt.pref {
+						return db.DomainPreference{}, pgx.ErrNoRows
+					}
+					return db.DomainPreference{Domain: "example.com", AlwaysFavorite: true, DefaultTags: []string{"reading", "tech"}}, nil
+				},
+				createLinkFn: func(ctx context.Context, params db.CreateLinkParams) (db.CreateLinkRow, error) {
+					created = params
+					return db.CreateLinkRow{ID: params.ID, UserID: params.UserID, Url: params.Url}, nil
+				},
+				getTagByNameFn: func(ctx context.Context, arg db.GetTagByNameParams) (db.Tag, error) {
+					return db.Tag{ID: int32(len(arg.Name)), Name: arg.Name}, nil
+				},
+				addTagToLinkFn: func(ctx context.Context, arg db.AddTagToLinkParams) error {
+					tagged++
+					return nil
+				},
+			}
+			srv := &Server{cfg: cfg, queries: queries, publisher: &stubPublisher{}, metrics: newTestMetrics()}
+			e := echo.New()
+			srv.RegisterRoutes(e)
+
+			req := httptest.NewRequest(http.MethodPost, "/api/links", strings.NewReader(tt.body))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+
+			if rec.Code != http.StatusCreated {
+				t.Fatalf("expected status %d, got %d: %s", http.StatusCreated, rec.Code, rec.Body.String())
+			}
+			if favorite, _ := created.Favorite.(pgtype.Bool); favorite.Bool != tt.wantFavorite {
+				t.Fatalf("expected favorite %v, got %+v", tt.wantFavorite, created.Favorite)
+			}
+			if tagged != tt.wantTags {
+				t.Fatalf("expected %d default tags, got %d", tt.wantTags, tagged)
+			}
+		})
+	}
+}
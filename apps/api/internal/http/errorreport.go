@@ -0,0 +1,77 @@
+package httpapi
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/getsentry/sentry-go"
+	"github.com/labstack/echo/v4"
+
+	"github.com/example/keepstack/observe"
+)
+
+// ErrorReportingMiddleware reports requests that end in a 5xx status. Most
+// handlers write their own 500 response and return nil, so the status is
+// reported even when no error reaches Echo. A nil reporter disables it.
+func ErrorReportingMiddleware(reporter *observe.ErrorReporter) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		if reporter == nil {
+			return next
+		}
+		return func(c echo.Context) error {
+			err := next(c)
+
+			status := c.Response().Status
+			if err != nil {
+				var httpErr *echo.HTTPError
+				if errors.As(err, &httpErr) {
+					status = httpErr.Code
+				} else {
+					status = http.StatusInternalServerError
+				}
+			}
+			if status < http.StatusInternalServerError {
+				return err
+			}
+
+			reported := err
+			if reported == nil {
+				reported = fmt.Errorf("%s %s responded %d", c.Request().Method, routeOf(c), status)
+			}
+			reporter.Capture(c.Request().Context(), reported, requestErrorContext(c, status))
+			return err
+		}
+	}
+}
+
+// reportPanic is the Recover middleware's LogErrorFunc. It logs the panic the
+// way the default Recover does and forwards it to the error reporter.
+func (s *Server) reportPanic(c echo.Context, err error, stack []byte) error {
+	c.Logger().Print(fmt.Sprintf("[PANIC RECOVER] %v %s\n", err, stack))
+
+	ec := requestErrorContext(c, http.StatusInternalServerError)
+	ec.Level = sentry.LevelFatal
+	ec.Tags["panic"] = "true"
+	s.errorReporter.Capture(c.Request().Context(), fmt.Errorf("panic: %w", err), ec)
+	return err
+}
+
+func requestErrorContext(c echo.Context, status int) observe.ErrorContext {
+	return observe.ErrorContext{
+		Request: c.Request(),
+		Tags: map[string]string{
+			"route":  routeOf(c),
+			"method": c.Request().Method,
+			"status": strconv.Itoa(status),
+		},
+	}
+}
+
+func routeOf(c echo.Context) string {
+	if route := c.Path(); route != "" {
+		return route
+	}
+	return c.Request().URL.Path
+}
@@ -0,0 +1,138 @@
+package httpapi
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/getsentry/sentry-go"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/labstack/echo/v4"
+
+	"github.com/example/keepstack/apps/api/internal/config"
+	"github.com/example/keepstack/apps/api/internal/db"
+	"github.com/example/keepstack/observe"
+)
+
+type recordingTransport struct {
+	mu     sync.Mutex
+	events []*sentry.Event
+}
+
+func (t *recordingTransport) Configure(sentry.ClientOptions) {}
+
+func (t *recordingTransport) Flush(time.Duration) bool { return true }
+
+func (t *recordingTransport) SendEvent(event *sentry.Event) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.events = append(t.events, event)
+}
+
+func (t *recordingTransport) Events() []*sentry.Event {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return append([]*sentry.Event(nil), t.events...)
+}
+
+func newRecordingReporter(t *testing.T) (*observe.ErrorReporter, *recordingTransport) {
+	t.Helper()
+	transport := &recordingTransport{}
+	reporter, err := observe.NewErrorReporter(sentry.ClientOptions{
+		Dsn:       "https://public@sentry.invalid/1",
+		Transport: transport,
+	})
+	if err != nil {
+		t.Fatalf("new reporter: %v", err)
+	}
+	return reporter, transport
+}
+
+func TestErrorReportingCaptures5xxWithRequestContext(t *testing.T) {
+	t.Parallel()
+
+	reporter, transport := newRecordingReporter(t)
+	queries := &mockQueries{
+		listTagLinkCountsFn: func(ctx context.Context, userID pgtype.UUID) ([]db.ListTagLinkCountsRow, error) {
+			return nil, errors.New("boom")
+		},
+	}
+	srv := &Server{cfg: config.Config{}, queries: queries, metrics: newTestMetrics()}
+	srv.WithErrorReporter(reporter)
+	e := echo.New()
+	srv.RegisterRoutes(e)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/tags?sort=name", nil)
+	req.Header.Set("Authorization", "Bearer secret")
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusInternalServerError {
+		t.Fatalf("expected status %d, got %d", http.StatusInternalServerError, rec.Code)
+	}
+	events := transport.Events()
+	if len(events) != 1 {
+		t.Fatalf("expected 1 event, got %d", len(events))
+	}
+	event := events[0]
+	if event.Tags["route"] != "/api/tags" || event.Tags["status"] != "500" || event.Tags["method"] != http.MethodGet {
+		t.Fatalf("unexpected tags: %v", event.Tags)
+	}
+	if event.Request == nil || event.Request.QueryString != "sort=name" {
+		t.Fatalf("expected request context, got %+v", event.Request)
+	}
+	if _, ok := event.Request.Headers["Authorization"]; ok {
+		t.Fatalf("expected Authorization header to be stripped")
+	}
+}
+
+func TestErrorReportingIgnoresClientErrors(t *testing.T) {
+	t.Parallel()
+
+	reporter, transport := newRecordingReporter(t)
+	srv := &Server{cfg: config.Config{}, queries: &mockQueries{}, metrics: newTestMetrics()}
+	srv.WithErrorReporter(reporter)
+	e := echo.New()
+	srv.RegisterRoutes(e)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/tags/not-a-number", nil)
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected status %d, got %d", http.StatusBadRequest, rec.Code)
+	}
+	if events := transport.Events(); len(events) != 0 {
+		t.Fatalf("expected no events, got %d", len(events))
+	}
+}
+
+func TestErrorReportingCapturesPanics(t *testing.T) {
+	t.Parallel()
+
+	reporter, transport := newRecordingReporter(t)
+	srv := &Server{cfg: config.Config{}, queries: &mockQueries{}, metrics: newTestMetrics()}
+	srv.WithErrorReporter(reporter)
+	e := echo.New()
+	srv.RegisterRoutes(e)
+	e.GET("/api/panic", func(echo.Context) error { panic("kaboom") })
+
+	req := httptest.NewRequest(http.MethodGet, "/api/panic", nil)
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusInternalServerError {
+		t.Fatalf("expected status %d, got %d", http.StatusInternalServerError, rec.Code)
+	}
+	events := transport.Events()
+	if len(events) != 1 {
+		t.Fatalf("expected 1 event, got %d", len(events))
+	}
+	if events[0].Level != sentry.LevelFatal || events[0].Tags["panic"] != "true" {
+		t.Fatalf("expected fatal panic event, got level %q tags %v", events[0].Level, events[0].Tags)
+	}
+}
@@ -0,0 +1,129 @@
+package httpapi
+
+import (
+	"errors"
+	"fmt"
+
+	stdhttp "net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+// Error codes sent in the code field of error responses. Clients branch on
+// these; messages are for people and may change. README.md lists them.
+const (
+	errCodeInvalidRequest       = "invalid_request"
+	errCodeUnauthorized         = "unauthorized"
+	errCodeForbidden            = "forbidden"
+	errCodeNotFound             = "not_found"
+	errCodeMethodNotAllowed     = "method_not_allowed"
+	errCodeConflict             = "conflict"
+	errCodeGone                 = "gone"
+	errCodePayloadTooLarge      = "payload_too_large"
+	errCodeUnsupportedMediaType = "unsupported_media_type"
+	errCodeValidationFailed     = "validation_failed"
+	errCodeUnknownField         = "unknown_field"
+	errCodeInvalidFieldType     = "invalid_field_type"
+	errCodeRateLimited          = "rate_limited"
+	errCodeQuotaExceeded        = "quota_exceeded"
+	errCodeInternal             = "internal_error"
+	errCodeUpstream             = "upstream_error"
+	errCodeUnavailable          = "unavailable"
+	errCodeMigrationPending     = "migration_pending"
+	errCodeTimeout              = "timeout"
+)
+
+// statusErrorCodes gives the code for an error response that does not name
+// a more specific one.
+var statusErrorCodes = map[int]string{
+	stdhttp.StatusBadRequest:            errCodeInvalidRequest,
+	stdhttp.StatusUnauthorized:          errCodeUnauthorized,
+	stdhttp.StatusForbidden:             errCodeForbidden,
+	stdhttp.StatusNotFound:              errCodeNotFound,
+	stdhttp.StatusMethodNotAllowed:      errCodeMethodNotAllowed,
+	stdhttp.StatusConflict:              errCodeConflict,
+	stdhttp.StatusGone:                  errCodeGone,
+	stdhttp.StatusRequestEntityTooLarge: errCodePayloadTooLarge,
+	stdhttp.StatusUnsupportedMediaType:  errCodeUnsupportedMediaType,
+	stdhttp.StatusUnprocessableEntity:   errCodeValidationFailed,
+	stdhttp.StatusTooManyRequests:       errCodeRateLimited,
+	stdhttp.StatusInternalServerError:   errCodeInternal,
+	stdhttp.StatusBadGateway:            errCodeUpstream,
+	stdhttp.StatusServiceUnavailable:    errCodeUnavailable,
+	stdhttp.StatusGatewayTimeout:        errCodeTimeout,
+}
+
+// errorResponse is the body of every JSON error response.
+type errorResponse struct {
+	Code      string         `json:"code"`
+	Message   string         `json:"message"`
+	Details   map[string]any `json:"details,omitempty"`
+	RequestID string         `json:"request_id,omitempty"`
+}
+
+// errorCodeForStatus returns the default code for status.
+func errorCodeForStatus(status int) string {
+	if code, ok := statusErrorCodes[status]; ok {
+		return code
+	}
+	if status >= stdhttp.StatusInternalServerError {
+		return errCodeInternal
+	}
+	return errCodeInvalidRequest
+}
+
+// respondError writes an error response with the default code for status.
+func respondError(c echo.Context, status int, message string) error {
+	return respondErrorCode(c, status, errorCodeForStatus(status), message, nil)
+}
+
+// respondErrorCode writes an error response with a specific code and
+// optional details.
+func respondErrorCode(c echo.Context, status int, code, message string, details map[string]any) error {
+	return c.JSON(status, errorResponse{
+		Code:      code,
+		Message:   message,
+		Details:   details,
+		RequestID: c.Response().Header().Get(echo.HeaderXRequestID),
+	})
+}
+
+// handleHTTPError replaces Echo's default error handler so errors raised by
+// the router and middleware, such as an unknown route, use the same body as
+// handlers.
+func (s *Server) handleHTTPError(err error, c echo.Context) {
+	if c.Response().Committed {
+		return
+	}
+
+	status, code, message := stdhttp.StatusInternalServerError, errCodeInternal, "internal server error"
+	var (
+		apiErr  apiError
+		httpErr *echo.HTTPError
+	)
+	switch {
+	case errors.As(err, &apiErr):
+		status, code, message = apiErr.Code, apiErr.code(), apiErr.Message
+	case errors.As(err, &httpErr):
+		status = httpErr.Code
+		code = errorCodeForStatus(status)
+		message = stdhttp.StatusText(status)
+		if text, ok := httpErr.Message.(string); ok && text != "" {
+			message = text
+		} else if httpErr.Message != nil {
+			message = fmt.Sprint(httpErr.Message)
+		}
+	}
+	if status >= stdhttp.StatusInternalServerError {
+		c.Logger().Errorf("%s %s: %v", c.Request().Method, c.Path(), err)
+	}
+
+	if c.Request().Method == stdhttp.MethodHead {
+		err = c.NoContent(status)
+	} else {
+		err = respondErrorCode(c, status, code, message, nil)
+	}
+	if err != nil {
+		c.Logger().Errorf("write error response: %v", err)
+	}
+}
@@ -0,0 +1,88 @@
+package httpapi
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/labstack/echo/v4"
+
+	"github.com/example/keepstack/apps/api/internal/config"
+	"github.com/example/keepstack/apps/api/internal/db"
+)
+
+func TestErrorResponsesUseEnvelope(t *testing.T) {
+	t.Parallel()
+
+	cfg := config.Config{DevUserID: uuid.MustParse("d8d8d8d8-d8d8-d8d8-d8d8-d8d8d8d8d8d8")}
+	queries := &mockQueries{
+		getLinkFn: func(ctx context.Context, id pgtype.UUID) (db.GetLinkRow, error) {
+			return db.GetLinkRow{}, pgx.ErrNoRows
+		},
+	}
+	srv := &Server{cfg: cfg, queries: queries, metrics: newTestMetrics()}
+	e := echo.New()
+	srv.RegisterRoutes(e)
+
+	cases := []struct {
+		name       string
+		method     string
+		path       string
+		requestID  string
+		wantStatus int
+		wantCode   string
+	}{
+		{name: "handler", method: http.MethodGet, path: "/api/links/not-a-uuid/archive/versions", wantStatus: http.StatusBadRequest, wantCode: errCodeInvalidRequest},
+		{name: "apiError", method: http.MethodGet, path: "/api/links/" + uuid.NewString() + "/archive/versions", wantStatus: http.StatusNotFound, wantCode: errCodeNotFound},
+		{name: "unknown route", method: http.MethodGet, path: "/api/nope", wantStatus: http.StatusNotFound, wantCode: errCodeNotFound},
+		{name: "method", method: http.MethodPatch, path: "/api/tags", wantStatus: http.StatusMethodNotAllowed, wantCode: errCodeMethodNotAllowed},
+		{name: "client request id", method: http.MethodGet, path: "/api/nope", requestID: "trace-123", wantStatus: http.StatusNotFound, wantCode: errCodeNotFound},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(tc.method, tc.path, nil)
+			if tc.requestID != "" {
+				req.Header.Set(echo.HeaderXRequestID, tc.requestID)
+			}
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+			if rec.Code != tc.wantStatus {
+				t.Fatalf("expected status %d, got %d: %s", tc.wantStatus, rec.Code, rec.Body.String())
+			}
+
+			var resp errorResponse
+			if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("decode response: %v", err)
+			}
+			if resp.Code != tc.wantCode || resp.Message == "" {
+				t.Fatalf("expected code %q with a message, got %+v", tc.wantCode, resp)
+			}
+			header := rec.Header().Get(echo.HeaderXRequestID)
+			if header == "" || resp.RequestID != header {
+				t.Fatalf("expected request id %q in the body, got %q", header, resp.RequestID)
+			}
+			if tc.requestID != "" && resp.RequestID != tc.requestID {
+				t.Fatalf("expected the client's request id %q, got %q", tc.requestID, resp.RequestID)
+			}
+		})
+	}
+}
+
+func TestAPIErrorReason(t *testing.T) {
+	t.Parallel()
+
+	if got := (apiError{Code: http.StatusTooManyRequests, Reason: errCodeQuotaExceeded}).code(); got != errCodeQuotaExceeded {
+		t.Fatalf("expected the reason to win, got %q", got)
+	}
+	if got := (apiError{Code: http.StatusConflict}).code(); got != errCodeConflict {
+		t.Fatalf("expected the status default, got %q", got)
+	}
+	if got := errorCodeForStatus(http.StatusTeapot); got != errCodeInvalidRequest {
+		t.Fatalf("expected unknown 4xx statuses to be invalid_request, got %q", got)
+	}
+}
@@ -0,0 +1,167 @@
+package httpapi
+
+import (
+	"encoding/json"
+	"fmt"
+	stdhttp "net/http"
+	"sync"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/example/keepstack/apps/api/internal/queue"
+)
+
+const (
+	// eventStreamBuffer is how many events a stream may fall behind before
+	// new ones are dropped for it.
+	eventStreamBuffer = 32
+	// eventStreamKeepAlive keeps idle streams under proxy read timeouts.
+	eventStreamKeepAlive = 25 * time.Second
+	// eventStreamRetry tells browsers how long to wait before reconnecting.
+	eventStreamRetry = 3 * time.Second
+)
+
+// EventBroker fans live update events out to the event streams open on this
+// replica. Every replica receives every event from NATS and delivers it to its
+// own streams for the event's user.
+type EventBroker struct {
+	mu      sync.Mutex
+	streams map[chan queue.Event]string
+	closed  bool
+}
+
+// NewEventBroker returns a broker with no open streams.
+func NewEventBroker() *EventBroker {
+	return &EventBroker{streams: make(map[chan queue.Event]string)}
+}
+
+// Publish delivers event to the user's open streams. A stream whose buffer is
+// full misses the event rather than blocking the others.
+func (b *EventBroker) Publish(event queue.Event) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for ch, userID := range b.streams {
+		if userID != event.UserID {
+			continue
+		}
+		select {
+		case ch <- event:
+		default:
+		}
+	}
+}
+
+// Close ends every open stream and refuses new ones, so shutdown does not
+// wait on long-lived connections.
+func (b *EventBroker) Close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.closed {
+		return
+	}
+	b.closed = true
+	for ch := range b.streams {
+		close(ch)
+		delete(b.streams, ch)
+	}
+}
+
+// subscribe opens a stream for userID. It reports false once the broker is
+// closed.
+func (b *EventBroker) subscribe(userID string) (<-chan queue.Event, func(), bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.closed {
+		return nil, nil, false
+	}
+	ch := make(chan queue.Event, eventStreamBuffer)
+	b.streams[ch] = userID
+
+	unsubscribe := func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		if _, ok := b.streams[ch]; ok {
+			delete(b.streams, ch)
+			close(ch)
+		}
+	}
+	return ch, unsubscribe, true
+}
+
+// PublishEvent hands an event received from NATS to this replica's streams.
+func (s *Server) PublishEvent(event queue.Event) {
+	if s.events != nil {
+		s.events.Publish(event)
+	}
+}
+
+// publishEvent broadcasts a live update through NATS so clients connected to
+// any replica see it. Failures only cost a live update, so they are logged.
+func (s *Server) publishEvent(c echo.Context, eventType, linkID string, data any) {
+	if s.publisher == nil {
+		return
+	}
+	event := queue.Event{Type: eventType, UserID: s.currentUser(c).String(), LinkID: linkID}
+	if data != nil {
+		raw, err := json.Marshal(data)
+		if err != nil {
+			c.Logger().Errorf("publish %s event: %v", eventType, err)
+			return
+		}
+		event.Data = raw
+	}
+	if err := s.publisher.PublishEvent(c.Request().Context(), event); err != nil {
+		c.Logger().Errorf("publish %s event: %v", eventType, err)
+	}
+}
+
+// handleEvents streams live updates as Server-Sent Events. Each event's SSE
+// name is its type and its data is the JSON-encoded event.
+func (s *Server) handleEvents(c echo.Context) error {
+	if s.events == nil {
+		return respondError(c, stdhttp.StatusServiceUnavailable, "live updates are unavailable")
+	}
+	events, unsubscribe, ok := s.events.subscribe(s.currentUser(c).String())
+	if !ok {
+		return respondError(c, stdhttp.StatusServiceUnavailable, "server is shutting down")
+	}
+	defer unsubscribe()
+
+	res := c.Response()
+	res.Header().Set(echo.HeaderContentType, "text/event-stream")
+	res.Header().Set(echo.HeaderCacheControl, "no-cache")
+	// Stops nginx-based ingresses from buffering the stream.
+	res.Header().Set("X-Accel-Buffering", "no")
+	res.WriteHeader(stdhttp.StatusOK)
+	fmt.Fprintf(res, "retry: %d\n\n", eventStreamRetry.Milliseconds())
+	res.Flush()
+
+	keepAlive := time.NewTicker(eventStreamKeepAlive)
+	defer keepAlive.Stop()
+
+	ctx := c.Request().Context()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case event, ok := <-events:
+			if !ok {
+				return nil
+			}
+			data, err := json.Marshal(event)
+			if err != nil {
+				c.Logger().Errorf("events: encode %s event: %v", event.Type, err)
+				continue
+			}
+			fmt.Fprintf(res, "event: %s\ndata: %s\n\n", event.Type, data)
+			res.Flush()
+		case <-keepAlive.C:
+			fmt.Fprint(res, ": keep-alive\n\n")
+			res.Flush()
+		}
+	}
+}
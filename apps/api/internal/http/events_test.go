@@ -0,0 +1,126 @@
+package httpapi
+
+import (
+	"bufio"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+
+	"github.com/example/keepstack/apps/api/internal/config"
+	"github.com/example/keepstack/apps/api/internal/queue"
+)
+
+func TestEventBrokerDeliversToMatchingUser(t *testing.T) {
+	t.Parallel()
+
+	broker := NewEventBroker()
+	mine, unsubscribe, ok := broker.subscribe("user-a")
+	if !ok {
+		t.Fatalf("expected subscribe to succeed")
+	}
+	defer unsubscribe()
+	other, unsubscribeOther, _ := broker.subscribe("user-b")
+	defer unsubscribeOther()
+
+	broker.Publish(queue.Event{Type: queue.EventLinkIngested, UserID: "user-a", LinkID: "1"})
+
+	select {
+	case event := <-mine:
+		if event.LinkID != "1" {
+			t.Fatalf("unexpected event: %+v", event)
+		}
+	default:
+		t.Fatalf("expected event for user-a")
+	}
+	select {
+	case event := <-other:
+		t.Fatalf("expected no event for user-b, got %+v", event)
+	default:
+	}
+
+	// A stream that stops reading loses events instead of blocking Publish.
+	for i := 0; i < eventStreamBuffer+5; i++ {
+		broker.Publish(queue.Event{Type: queue.EventLinkIngested, UserID: "user-a"})
+	}
+	if len(mine) != eventStreamBuffer {
+		t.Fatalf("expected a full buffer of %d, got %d", eventStreamBuffer, len(mine))
+	}
+
+	broker.Close()
+	if _, _, ok := broker.subscribe("user-a"); ok {
+		t.Fatalf("expected subscribe to fail after close")
+	}
+}
+
+func TestEventStream(t *testing.T) {
+	t.Parallel()
+
+	userID := uuid.MustParse("ffffffff-ffff-ffff-ffff-ffffffffffff")
+	srv := &Server{
+		cfg:     config.Config{DevUserID: userID, HTTPCompressionLevel: 5},
+		queries: &mockQueries{},
+		metrics: newTestMetrics(),
+		events:  NewEventBroker(),
+	}
+	e := echo.New()
+	srv.RegisterRoutes(e)
+	ts := httptest.NewServer(e)
+	defer ts.Close()
+
+	req, err := http.NewRequest(http.MethodGet, ts.URL+"/api/events", nil)
+	if err != nil {
+		t.Fatalf("build request: %v", err)
+	}
+	req.Header.Set(echo.HeaderAcceptEncoding, "gzip")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("open stream: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if got := resp.Header.Get(echo.HeaderContentType); got != "text/event-stream" {
+		t.Fatalf("expected event stream content type, got %q", got)
+	}
+	if got := resp.Header.Get(echo.HeaderContentEncoding); got != "" {
+		t.Fatalf("expected an uncompressed stream, got %q", got)
+	}
+
+	reader := bufio.NewReader(resp.Body)
+	readFrame := func() string {
+		t.Helper()
+		var frame strings.Builder
+		for {
+			line, err := reader.ReadString('\n')
+			if err != nil {
+				t.Fatalf("read stream: %v", err)
+			}
+			if line == "\n" {
+				return frame.String()
+			}
+			frame.WriteString(line)
+		}
+	}
+
+	if frame := readFrame(); frame != "retry: 3000\n" {
+		t.Fatalf("unexpected first frame %q", frame)
+	}
+
+	srv.PublishEvent(queue.Event{Type: queue.EventLinkIngested, UserID: uuid.NewString(), LinkID: "other"})
+	srv.PublishEvent(queue.Event{Type: queue.EventLinkIngested, UserID: userID.String(), LinkID: "mine"})
+
+	want := "event: link.ingested\ndata: {\"type\":\"link.ingested\",\"user_id\":\"" + userID.String() + "\",\"link_id\":\"mine\"}\n"
+	if frame := readFrame(); frame != want {
+		t.Fatalf("expected frame %q, got %q", want, frame)
+	}
+
+	// Draining ends open streams so shutdown does not wait on them.
+	srv.BeginDrain()
+	if rest, err := io.ReadAll(reader); err != nil || len(rest) != 0 {
+		t.Fatalf("expected stream to end cleanly, got %q (%v)", rest, err)
+	}
+}
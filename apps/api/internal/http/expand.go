@@ -0,0 +1,109 @@
+package httpapi
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	stdhttp "net/http"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/labstack/echo/v4"
+
+	"github.com/example/keepstack/apps/api/internal/db"
+	"github.com/example/keepstack/apps/api/internal/queue"
+)
+
+// expander lists the article links on an index page without saving it.
+type expander interface {
+	Expand(ctx context.Context, target string) (queue.ExpandReply, error)
+}
+
+type expandRequest struct {
+	URL string `json:"url"`
+}
+
+type expandedLinkResponse struct {
+	URL    string `json:"url"`
+	Title  string `json:"title"`
+	Saved  bool   `json:"saved"`
+	LinkID string `json:"link_id,omitempty"`
+}
+
+type expandResponse struct {
+	URL   string                 `json:"url"`
+	Title string                 `json:"title"`
+	Links []expandedLinkResponse `json:"links"`
+}
+
+// WithExpander answers POST /api/links/expand through e, typically the
+// workers over NATS.
+func (s *Server) WithExpander(e expander) {
+	s.expander = e
+}
+
+// handleExpandLink lists the articles an index page such as a newsletter
+// issue links to, as suggested saves in place of the page itself. Nothing
+// is saved; each suggestion is normalized the way a save would be and
+// marked when the user already has it.
+func (s *Server) handleExpandLink(c echo.Context) error {
+	var req expandRequest
+	if err := c.Bind(&req); err != nil {
+		return respondError(c, stdhttp.StatusBadRequest, "invalid request body")
+	}
+	target, err := s.normalizeLinkURL(strings.TrimSpace(req.URL))
+	if err != nil {
+		return respondError(c, stdhttp.StatusBadRequest, "invalid url")
+	}
+	if s.expander == nil {
+		return respondError(c, stdhttp.StatusServiceUnavailable, "expanding links is not available")
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request().Context(), s.cfg.UnfurlTimeout)
+	defer cancel()
+	reply, err := s.expander.Expand(ctx, target)
+	if err != nil {
+		var expandErr *queue.ExpandError
+		switch {
+		case errors.As(err, &expandErr):
+			c.Logger().Warnf("expand %s: %v", target, err)
+			return respondError(c, stdhttp.StatusBadGateway, "failed to fetch or parse the page")
+		case errors.Is(err, queue.ErrExpandUnavailable):
+			return respondError(c, stdhttp.StatusServiceUnavailable, "expanding links is not available")
+		case errors.Is(err, context.DeadlineExceeded):
+			return respondError(c, stdhttp.StatusGatewayTimeout, "timed out fetching the page")
+		}
+		c.Logger().Errorf("expand %s: %v", target, err)
+		return respondError(c, stdhttp.StatusInternalServerError, "failed to expand url")
+	}
+
+	resp := expandResponse{
+		URL:   firstNonBlank(reply.URL, target),
+		Title: reply.Title,
+		Links: make([]expandedLinkResponse, 0, len(reply.Links)),
+	}
+	seen := map[string]bool{target: true}
+	for _, link := range reply.Links {
+		normalized, err := s.normalizeLinkURL(link.URL)
+		if err != nil || seen[normalized] {
+			continue
+		}
+		seen[normalized] = true
+
+		suggestion := expandedLinkResponse{URL: normalized, Title: link.Title}
+		row, err := s.queries.FindLinkByURLHash(c.Request().Context(), db.FindLinkByURLHashParams{
+			UserID:  uuidToPg(s.userID(ctx)),
+			UrlHash: urlHash(normalized),
+		})
+		switch {
+		case err == nil:
+			suggestion.Saved = true
+			suggestion.LinkID = uuidFromPg(row.ID).String()
+		case !errors.Is(err, pgx.ErrNoRows):
+			c.Logger().Errorf("expand %s: look up %s: %v", target, normalized, err)
+			return respondError(c, stdhttp.StatusInternalServerError, "failed to look up links")
+		}
+		resp.Links = append(resp.Links, suggestion)
+	}
+	return c.JSON(stdhttp.StatusOK, resp)
+}
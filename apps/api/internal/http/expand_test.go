@@ -0,0 +1,134 @@
+package httpapi
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/labstack/echo/v4"
+
+	"github.com/example/keepstack/apps/api/internal/config"
+	"github.com/example/keepstack/apps/api/internal/db"
+	"github.com/example/keepstack/apps/api/internal/queue"
+)
+
+type fakeExpander struct {
+	target string
+	reply  queue.ExpandReply
+	err    error
+}
+
+func (f *fakeExpander) Expand(ctx context.Context, target string) (queue.ExpandReply, error) {
+	f.target = target
+	return f.reply, f.err
+}
+
+func newExpandTestServer(x expander, mock *mockQueries) *echo.Echo {
+	if mock == nil {
+		mock = &mockQueries{}
+	}
+	srv := &Server{
+		cfg:     config.Config{UnfurlTimeout: time.Second},
+		queries: mock,
+		metrics: newTestMetrics(),
+	}
+	if x != nil {
+		srv.WithExpander(x)
+	}
+	e := echo.New()
+	srv.RegisterRoutes(e)
+	return e
+}
+
+func expandRequestFor(target string) *http.Request {
+	req := httptest.NewRequest(http.MethodPost, "/api/links/expand", strings.NewReader(`{"url":"`+target+`"}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	return req
+}
+
+func TestExpandLinkSuggestsSaves(t *testing.T) {
+	t.Parallel()
+
+	savedID := uuid.New()
+	x := &fakeExpander{reply: queue.ExpandReply{
+		URL:   "https://picks.example.com/issues/42",
+		Title: "Weekly Picks #42",
+		Links: []queue.ExpandedLink{
+			{URL: "https://blog.example.org/a-post?utm_source=picks", Title: "A post"},
+			{URL: "https://blog.example.org/a-post", Title: "A post, again"},
+			{URL: "https://news.example.net/2024/05/story", Title: "A story"},
+			{URL: "https://picks.example.com/issues/42", Title: "This issue"},
+		},
+	}}
+	var lookedUp []string
+	mock := &mockQueries{
+		findLinkByURLHashFn: func(ctx context.Context, arg db.FindLinkByURLHashParams) (db.FindLinkByURLHashRow, error) {
+			lookedUp = append(lookedUp, arg.UrlHash)
+			if arg.UrlHash == urlHash("https://news.example.net/2024/05/story") {
+				return db.FindLinkByURLHashRow{ID: uuidToPg(savedID)}, nil
+			}
+			return db.FindLinkByURLHashRow{}, pgx.ErrNoRows
+		},
+	}
+	e := newExpandTestServer(x, mock)
+
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, expandRequestFor("picks.example.com/issues/42"))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+	}
+	if x.target != "https://picks.example.com/issues/42" {
+		t.Fatalf("expected the normalized url to be expanded, got %q", x.target)
+	}
+
+	var resp expandResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if resp.Title != "Weekly Picks #42" || len(resp.Links) != 2 || len(lookedUp) != 2 {
+		t.Fatalf("expected two deduplicated suggestions, got %+v", resp)
+	}
+	if got := resp.Links[0]; got.URL != "https://blog.example.org/a-post" || got.Title != "A post" || got.Saved {
+		t.Fatalf("unexpected first suggestion: %+v", got)
+	}
+	if got := resp.Links[1]; !got.Saved || got.LinkID != savedID.String() {
+		t.Fatalf("expected the saved story to be marked, got %+v", got)
+	}
+}
+
+func TestExpandLinkErrors(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name     string
+		expander expander
+		target   string
+		want     int
+	}{
+		{name: "invalid url", expander: &fakeExpander{}, target: "not a url", want: http.StatusBadRequest},
+		{name: "no expander", target: "https://example.com", want: http.StatusServiceUnavailable},
+		{name: "no workers", expander: &fakeExpander{err: queue.ErrExpandUnavailable}, target: "https://example.com", want: http.StatusServiceUnavailable},
+		{name: "worker failed", expander: &fakeExpander{err: &queue.ExpandError{Message: "fetch: unexpected status 404"}}, target: "https://example.com", want: http.StatusBadGateway},
+		{name: "timeout", expander: &fakeExpander{err: context.DeadlineExceeded}, target: "https://example.com", want: http.StatusGatewayTimeout},
+		{name: "other", expander: &fakeExpander{err: errors.New("boom")}, target: "https://example.com", want: http.StatusInternalServerError},
+	}
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			rec := httptest.NewRecorder()
+			newExpandTestServer(tc.expander, nil).ServeHTTP(rec, expandRequestFor(tc.target))
+			if rec.Code != tc.want {
+				t.Fatalf("expected status %d, got %d: %s", tc.want, rec.Code, rec.Body.String())
+			}
+		})
+	}
+}
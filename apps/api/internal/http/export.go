@@ -0,0 +1,279 @@
+package httpapi
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	stdhttp "net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/labstack/echo/v4"
+)
+
+// exportFlushEvery bounds how many rows are buffered before the response is
+// flushed to the client.
+const exportFlushEvery = 200
+
+// exportLinksQuery is run with pool.Query rather than through sqlc so rows
+// can be streamed as they arrive instead of collected into a slice.
+const exportLinksQuery = `-- name: ExportLinks :many
+SELECT
+    l.id,
+    l.url,
+    COALESCE(l.title, ''),
+    COALESCE(l.source_domain, ''),
+    l.favorite,
+    l.created_at,
+    l.read_at,
+    COALESCE(a.title, ''),
+    COALESCE(a.byline, ''),
+    COALESCE(a.lang, ''),
+    COALESCE(a.word_count, 0),
+    CASE WHEN $2::boolean THEN COALESCE(a.extracted_text, '') ELSE '' END,
+    COALESCE((
+        SELECT array_agg(t.name ORDER BY t.name)
+        FROM link_tags lt
+        JOIN tags t ON t.id = lt.tag_id
+        WHERE lt.link_id = l.id
+    ), '{}')::text[]
+FROM links l
+LEFT JOIN archives a ON a.link_id = l.id
+WHERE l.user_id = $1
+ORDER BY l.created_at, l.id`
+
+type exportLink struct {
+	ID            string     `json:"id"`
+	URL           string     `json:"url"`
+	Title         string     `json:"title"`
+	SourceDomain  string     `json:"source_domain"`
+	Favorite      bool       `json:"favorite"`
+	CreatedAt     time.Time  `json:"created_at"`
+	ReadAt        *time.Time `json:"read_at,omitempty"`
+	ArchiveTitle  string     `json:"archive_title"`
+	Byline        string     `json:"byline"`
+	Lang          string     `json:"lang"`
+	WordCount     int32      `json:"word_count"`
+	ExtractedText string     `json:"extracted_text,omitempty"`
+	Tags          []string   `json:"tags"`
+}
+
+// linkExporter writes links to a response one row at a time.
+type linkExporter interface {
+	Begin() error
+	Write(exportLink) error
+	End() error
+}
+
+// handleExportLinks streams every link the user saved as a JSON array or CSV
+// file. Rows are encoded straight from the database cursor and flushed in
+// batches, so memory use does not grow with the size of the library.
+func (s *Server) handleExportLinks(c echo.Context) error {
+	if s.pool == nil {
+		return respondError(c, stdhttp.StatusServiceUnavailable, "export is unavailable in memory mode")
+	}
+
+	format := strings.ToLower(strings.TrimSpace(c.QueryParam("format")))
+	if format == "" {
+		format = "json"
+	}
+	if format != "json" && format != "csv" {
+		return respondError(c, stdhttp.StatusBadRequest, "format must be json or csv")
+	}
+
+	includeText := false
+	if raw := c.QueryParam("include_text"); raw != "" {
+		parsed, err := strconv.ParseBool(raw)
+		if err != nil {
+			return respondError(c, stdhttp.StatusBadRequest, "include_text must be a boolean")
+		}
+		includeText = parsed
+	}
+
+	ctx := c.Request().Context()
+	rows, err := s.pool.Query(ctx, exportLinksQuery, uuidToPg(s.userID(ctx)), includeText)
+	if err != nil {
+		s.metrics.LinkExportFailure.Inc()
+		c.Logger().Errorf("export links: %v", err)
+		return respondError(c, stdhttp.StatusInternalServerError, "failed to export links")
+	}
+	defer rows.Close()
+
+	res := c.Response()
+	filename := fmt.Sprintf("keepstack-links-%s.%s", time.Now().UTC().Format("20060102"), format)
+	res.Header().Set(echo.HeaderContentDisposition, fmt.Sprintf("attachment; filename=%q", filename))
+	res.Header().Set("Cache-Control", "no-store")
+
+	var exporter linkExporter
+	if format == "csv" {
+		res.Header().Set(echo.HeaderContentType, "text/csv; charset=utf-8")
+		exporter = newCSVLinkExporter(res, includeText)
+	} else {
+		res.Header().Set(echo.HeaderContentType, echo.MIMEApplicationJSONCharsetUTF8)
+		exporter = newJSONLinkExporter(res)
+	}
+	res.WriteHeader(stdhttp.StatusOK)
+
+	// The status is already sent, so failures from here on can only be
+	// logged; the client sees a truncated file.
+	fail := func(err error) error {
+		s.metrics.LinkExportFailure.Inc()
+		c.Logger().Errorf("export links: %v", err)
+		return nil
+	}
+
+	if err := exporter.Begin(); err != nil {
+		return fail(err)
+	}
+	count := 0
+	for rows.Next() {
+		link, err := scanExportLink(rows)
+		if err != nil {
+			return fail(err)
+		}
+		if err := exporter.Write(link); err != nil {
+			return fail(err)
+		}
+		count++
+		if count%exportFlushEvery == 0 {
+			res.Flush()
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fail(err)
+	}
+	if err := exporter.End(); err != nil {
+		return fail(err)
+	}
+	res.Flush()
+
+	s.metrics.LinkExportSuccess.Inc()
+	return nil
+}
+
+func scanExportLink(row pgx.Row) (exportLink, error) {
+	var (
+		id        pgtype.UUID
+		createdAt pgtype.Timestamptz
+		readAt    pgtype.Timestamptz
+		link      exportLink
+	)
+	if err := row.Scan(
+		&id,
+		&link.URL,
+		&link.Title,
+		&link.SourceDomain,
+		&link.Favorite,
+		&createdAt,
+		&readAt,
+		&link.ArchiveTitle,
+		&link.Byline,
+		&link.Lang,
+		&link.WordCount,
+		&link.ExtractedText,
+		&link.Tags,
+	); err != nil {
+		return exportLink{}, fmt.Errorf("scan link: %w", err)
+	}
+	link.ID = uuidFromPg(id).String()
+	if createdAt.Valid {
+		link.CreatedAt = createdAt.Time
+	}
+	if readAt.Valid {
+		t := readAt.Time
+		link.ReadAt = &t
+	}
+	if link.Tags == nil {
+		link.Tags = []string{}
+	}
+	return link, nil
+}
+
+type jsonLinkExporter struct {
+	w     io.Writer
+	enc   *json.Encoder
+	count int
+}
+
+func newJSONLinkExporter(w io.Writer) *jsonLinkExporter {
+	return &jsonLinkExporter{w: w, enc: json.NewEncoder(w)}
+}
+
+func (e *jsonLinkExporter) Begin() error {
+	_, err := io.WriteString(e.w, "[")
+	return err
+}
+
+func (e *jsonLinkExporter) Write(link exportLink) error {
+	if e.count > 0 {
+		if _, err := io.WriteString(e.w, ","); err != nil {
+			return err
+		}
+	}
+	e.count++
+	// Encode appends a newline, which keeps the array valid and lets
+	// line-oriented tools follow along.
+	return e.enc.Encode(link)
+}
+
+func (e *jsonLinkExporter) End() error {
+	_, err := io.WriteString(e.w, "]\n")
+	return err
+}
+
+type csvLinkExporter struct {
+	w           *csv.Writer
+	includeText bool
+}
+
+func newCSVLinkExporter(w io.Writer, includeText bool) *csvLinkExporter {
+	return &csvLinkExporter{w: csv.NewWriter(w), includeText: includeText}
+}
+
+func (e *csvLinkExporter) Begin() error {
+	header := []string{"id", "url", "title", "source_domain", "favorite", "created_at", "read_at", "archive_title", "byline", "lang", "word_count", "tags"}
+	if e.includeText {
+		header = append(header, "extracted_text")
+	}
+	return e.w.Write(header)
+}
+
+func (e *csvLinkExporter) Write(link exportLink) error {
+	readAt := ""
+	if link.ReadAt != nil {
+		readAt = link.ReadAt.UTC().Format(time.RFC3339)
+	}
+	record := []string{
+		link.ID,
+		link.URL,
+		link.Title,
+		link.SourceDomain,
+		strconv.FormatBool(link.Favorite),
+		link.CreatedAt.UTC().Format(time.RFC3339),
+		readAt,
+		link.ArchiveTitle,
+		link.Byline,
+		link.Lang,
+		strconv.Itoa(int(link.WordCount)),
+		strings.Join(link.Tags, ";"),
+	}
+	if e.includeText {
+		record = append(record, link.ExtractedText)
+	}
+	if err := e.w.Write(record); err != nil {
+		return err
+	}
+	// csv.Writer buffers internally; push rows through so the response
+	// flushes see them.
+	e.w.Flush()
+	return e.w.Error()
+}
+
+func (e *csvLinkExporter) End() error {
+	e.w.Flush()
+	return e.w.Error()
+}
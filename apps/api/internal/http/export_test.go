@@ -0,0 +1,176 @@
+package httpapi
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/labstack/echo/v4"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"github.com/example/keepstack/apps/api/internal/config"
+)
+
+// exportPool answers the export query with fixed rows and otherwise behaves
+// like stubHealthPool.
+type exportPool struct {
+	stubHealthPool
+	rows     [][]any
+	err      error
+	gotQuery string
+	gotArgs  []any
+}
+
+func (p *exportPool) Query(ctx context.Context, query string, args ...any) (pgx.Rows, error) {
+	p.gotQuery = query
+	p.gotArgs = args
+	if p.err != nil {
+		return nil, p.err
+	}
+	return &exportRows{rows: p.rows, index: -1}, nil
+}
+
+type exportRows struct {
+	stubRows
+	rows  [][]any
+	index int
+}
+
+func (r *exportRows) Next() bool {
+	r.index++
+	return r.index < len(r.rows)
+}
+
+func (r *exportRows) Scan(dest ...any) error {
+	row := r.rows[r.index]
+	for i, value := range row {
+		switch d := dest[i].(type) {
+		case *pgtype.UUID:
+			*d = value.(pgtype.UUID)
+		case *pgtype.Timestamptz:
+			*d = value.(pgtype.Timestamptz)
+		case *string:
+			*d = value.(string)
+		case *bool:
+			*d = value.(bool)
+		case *int32:
+			*d = value.(int32)
+		case *[]string:
+			*d = value.([]string)
+		default:
+			return errors.New("unexpected scan destination")
+		}
+	}
+	return nil
+}
+
+func exportRow(id uuid.UUID, url string, tags []string, text string) []any {
+	created := pgtype.Timestamptz{Time: time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC), Valid: true}
+	return []any{
+		pgtype.UUID{Bytes: id, Valid: true},
+		url, "Title", "example.com", true, created, pgtype.Timestamptz{},
+		"Archive title", "Ada", "en", int32(420), text, tags,
+	}
+}
+
+func TestHandleExportLinks(t *testing.T) {
+	t.Parallel()
+
+	first := uuid.MustParse("11111111-1111-1111-1111-111111111111")
+	second := uuid.MustParse("22222222-2222-2222-2222-222222222222")
+
+	t.Run("json", func(t *testing.T) {
+		t.Parallel()
+
+		pool := &exportPool{rows: [][]any{
+			exportRow(first, "https://example.com/a", []string{"go", "db"}, ""),
+			exportRow(second, "https://example.com/b", nil, ""),
+		}}
+		metrics := newTestMetrics()
+		srv := &Server{cfg: config.Config{}, pool: pool, metrics: metrics}
+		e := echo.New()
+		srv.RegisterRoutes(e)
+
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/links/export", nil))
+
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected status %d, got %d", http.StatusOK, rec.Code)
+		}
+		if got := rec.Header().Get(echo.HeaderContentDisposition); !strings.Contains(got, "attachment") || !strings.HasSuffix(got, `.json"`) {
+			t.Fatalf("unexpected content disposition %q", got)
+		}
+		var links []exportLink
+		if err := json.Unmarshal(rec.Body.Bytes(), &links); err != nil {
+			t.Fatalf("decode export: %v\n%s", err, rec.Body.String())
+		}
+		if len(links) != 2 || links[0].ID != first.String() || links[1].URL != "https://example.com/b" {
+			t.Fatalf("unexpected export: %+v", links)
+		}
+		if len(links[0].Tags) != 2 || links[1].Tags == nil {
+			t.Fatalf("unexpected tags: %+v", links)
+		}
+		if pool.gotArgs[1] != false {
+			t.Fatalf("expected extracted text to be excluded by default, got %v", pool.gotArgs[1])
+		}
+		if got := testutil.ToFloat64(metrics.LinkExportSuccess); got != 1 {
+			t.Fatalf("expected 1 successful export, got %v", got)
+		}
+	})
+
+	t.Run("csv with text", func(t *testing.T) {
+		t.Parallel()
+
+		pool := &exportPool{rows: [][]any{
+			exportRow(first, "https://example.com/a", []string{"go", "db"}, "body, with comma"),
+		}}
+		srv := &Server{cfg: config.Config{}, pool: pool, metrics: newTestMetrics()}
+		e := echo.New()
+		srv.RegisterRoutes(e)
+
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/links/export?format=csv&include_text=true", nil))
+
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected status %d, got %d", http.StatusOK, rec.Code)
+		}
+		records, err := csv.NewReader(rec.Body).ReadAll()
+		if err != nil {
+			t.Fatalf("parse csv: %v", err)
+		}
+		if len(records) != 2 {
+			t.Fatalf("expected header and one row, got %d records", len(records))
+		}
+		header, row := records[0], records[1]
+		if header[len(header)-1] != "extracted_text" || row[len(row)-1] != "body, with comma" {
+			t.Fatalf("unexpected text column: %v / %v", header, row)
+		}
+		if row[11] != "go;db" {
+			t.Fatalf("expected joined tags, got %q", row[11])
+		}
+	})
+
+	t.Run("invalid format", func(t *testing.T) {
+		t.Parallel()
+
+		srv := &Server{cfg: config.Config{}, pool: &exportPool{}, metrics: newTestMetrics()}
+		e := echo.New()
+		srv.RegisterRoutes(e)
+
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/links/export?format=xml", nil))
+
+		if rec.Code != http.StatusBadRequest {
+			t.Fatalf("expected status %d, got %d", http.StatusBadRequest, rec.Code)
+		}
+	})
+}
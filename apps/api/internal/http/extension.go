@@ -0,0 +1,380 @@
+package httpapi
+
+import (
+	"cmp"
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	stdhttp "net/http"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/labstack/echo/v4"
+
+	"github.com/example/keepstack/apps/api/internal/db"
+)
+
+// extensionPrefix is where the browser extension endpoints live.
+const extensionPrefix = "/api/ext"
+
+const (
+	defaultExtensionTagLimit = 10
+	maxExtensionTagLimit     = 50
+)
+
+var urlHashPattern = regexp.MustCompile(`^[0-9a-f]{64}$`)
+
+// likeEscaper escapes LIKE wildcards so a tag prefix matches literally.
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+type extensionSavedResponse struct {
+	Saved     bool       `json:"saved"`
+	ID        string     `json:"id,omitempty"`
+	URL       string     `json:"url,omitempty"`
+	CreatedAt *time.Time `json:"created_at,omitempty"`
+}
+
+type extensionSaveRequest struct {
+	URL   string  `json:"url"`
+	Title *string `json:"title"`
+	// HTML is the page as the browser rendered it. When set, the worker
+	// archives it instead of fetching the URL, which keeps pages behind a
+	// login or paywall readable.
+	HTML string   `json:"html"`
+	Tags []string `json:"tags"`
+	// PreserveTitle keeps Title through ingestion. It defaults to the
+	// server's PRESERVE_USER_TITLES setting.
+	PreserveTitle *bool `json:"preserve_title"`
+}
+
+type extensionSaveResponse struct {
+	ID      string        `json:"id"`
+	URL     string        `json:"url"`
+	URLHash string        `json:"url_hash"`
+	Created bool          `json:"created"`
+	Tags    []tagResponse `json:"tags"`
+}
+
+// urlHash is the hex SHA-256 of a normalized URL, which extensions use to
+// ask whether a page is saved without sending its address.
+func urlHash(normalizedURL string) string {
+	sum := sha256.Sum256([]byte(normalizedURL))
+	return hex.EncodeToString(sum[:])
+}
+
+// registerExtensionRoutes adds the /api/ext endpoints. With sessions enabled
+// they take the user's session token, so saves land in that user's library;
+// otherwise they are added when at least one extension token is configured
+// and act as DevUserID.
+func (s *Server) registerExtensionRoutes(api *echo.Group) {
+	auth := SessionMiddleware([]byte(s.cfg.JWTSecret))
+	if !s.cfg.AuthSessions {
+		tokens := configuredTokens(s.cfg.ExtensionTokens)
+		if len(tokens) == 0 {
+			return
+		}
+		auth = ExtensionAuthMiddleware(tokens)
+	}
+
+	ext := api.Group("/ext", auth)
+	ext.GET("/saved", s.handleExtensionSaved)
+	ext.POST("/save", s.handleExtensionSave)
+	ext.GET("/tags", s.handleExtensionTags)
+}
+
+// ExtensionAuthMiddleware rejects requests that do not carry one of tokens as
+// an Authorization bearer token.
+func ExtensionAuthMiddleware(tokens []string) echo.MiddlewareFunc {
+	return bearerAuthMiddleware(tokens)
+}
+
+// bearerAuthMiddleware answers 401 with a Bearer challenge unless the request
+// carries one of tokens.
+func bearerAuthMiddleware(tokens []string) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if tokenAllowed(tokens, bearerToken(c.Request())) {
+				return next(c)
+			}
+			c.Response().Header().Set(echo.HeaderWWWAuthenticate, `Bearer realm="keepstack"`)
+			return respondError(c, stdhttp.StatusUnauthorized, "invalid or missing token")
+		}
+	}
+}
+
+// configuredTokens drops blank entries from a token list read from the
+// environment.
+func configuredTokens(raw []string) []string {
+	tokens := make([]string, 0, len(raw))
+	for _, token := range raw {
+		if token = strings.TrimSpace(token); token != "" {
+			tokens = append(tokens, token)
+		}
+	}
+	return tokens
+}
+
+func bearerToken(req *stdhttp.Request) string {
+	token, ok := strings.CutPrefix(req.Header.Get(echo.HeaderAuthorization), "Bearer ")
+	if !ok {
+		return ""
+	}
+	return token
+}
+
+// tokenAllowed reports whether presented is one of tokens, comparing in
+// constant time.
+func tokenAllowed(tokens []string, presented string) bool {
+	if presented == "" {
+		return false
+	}
+	for _, token := range tokens {
+		if subtle.ConstantTimeCompare([]byte(presented), []byte(token)) == 1 {
+			return true
+		}
+	}
+	return false
+}
+
+// handleExtensionSaved reports whether a page is saved. Extensions pass hash,
+// the hex SHA-256 of the normalized URL, or the url itself.
+func (s *Server) handleExtensionSaved(c echo.Context) error {
+	hash := strings.ToLower(strings.TrimSpace(c.QueryParam("hash")))
+	if hash == "" {
+		raw := strings.TrimSpace(c.QueryParam("url"))
+		if raw == "" {
+			return respondError(c, stdhttp.StatusBadRequest, "hash or url is required")
+		}
+		normalized, err := s.normalizeLinkURL(raw)
+		if err != nil {
+			return respondError(c, stdhttp.StatusBadRequest, "invalid url")
+		}
+		hash = urlHash(normalized)
+	} else if !urlHashPattern.MatchString(hash) {
+		return respondError(c, stdhttp.StatusBadRequest, "hash must be a hex-encoded SHA-256")
+	}
+
+	row, err := s.queries.FindLinkByURLHash(c.Request().Context(), db.FindLinkByURLHashParams{
+		UserID:  uuidToPg(s.currentUser(c)),
+		UrlHash: hash,
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return c.JSON(stdhttp.StatusOK, extensionSavedResponse{Saved: false})
+		}
+		c.Logger().Errorf("extension saved: lookup failed: %v", err)
+		return respondError(c, stdhttp.StatusInternalServerError, "failed to look up link")
+	}
+
+	createdAt := row.CreatedAt.Time
+	return c.JSON(stdhttp.StatusOK, extensionSavedResponse{
+		Saved:     true,
+		ID:        uuidFromPg(row.ID).String(),
+		URL:       row.Url,
+		CreatedAt: &createdAt,
+	})
+}
+
+// handleExtensionSave saves a page in one call, with optional captured HTML
+// and tag names.
+func (s *Server) handleExtensionSave(c echo.Context) error {
+	var req extensionSaveRequest
+	if err := c.Bind(&req); err != nil {
+		s.metrics.LinkCreateFailure.Inc()
+		return respondBindError(c, err)
+	}
+
+	title := ""
+	if req.Title != nil {
+		title = *req.Title
+	}
+	saved, err := s.quickSave(c, quickSaveInput{URL: req.URL, Title: title, HTML: req.HTML, Tags: req.Tags, PreserveTitle: req.PreserveTitle})
+	if err != nil {
+		return respondWithError(c, err)
+	}
+
+	status := stdhttp.StatusOK
+	if saved.Created {
+		status = stdhttp.StatusCreated
+	}
+	return c.JSON(status, extensionSaveResponse{
+		ID:      saved.ID.String(),
+		URL:     saved.URL,
+		URLHash: urlHash(saved.URL),
+		Created: saved.Created,
+		Tags:    saved.Tags,
+	})
+}
+
+type quickSaveInput struct {
+	// UserID owns the link. Zero means the request's user.
+	UserID uuid.UUID
+	URL    string
+	Title  string
+	HTML   string
+	Tags   []string
+	// PreserveTitle overrides the server default for keeping Title.
+	PreserveTitle *bool
+}
+
+type quickSaveResult struct {
+	ID      uuid.UUID
+	URL     string
+	Created bool
+	Tags    []tagResponse
+}
+
+// quickSave saves a link for clients that send everything in one request:
+// the extension and Micropub endpoints. Tags that do not exist yet are
+// created. Saving a URL that is already saved only adds the tags and reports
+// Created false. Failures are returned as apiError.
+func (s *Server) quickSave(c echo.Context, in quickSaveInput) (quickSaveResult, error) {
+	normalizedURL, err := s.normalizeLinkURL(strings.TrimSpace(in.URL))
+	if err != nil {
+		s.metrics.LinkCreateFailure.Inc()
+		return quickSaveResult{}, apiError{Code: stdhttp.StatusBadRequest, Message: "invalid url"}
+	}
+
+	ctx := c.Request().Context()
+	userID := cmp.Or(in.UserID, s.userID(ctx))
+	result := quickSaveResult{URL: normalizedURL}
+
+	existing, err := s.queries.FindLinkByURLHash(ctx, db.FindLinkByURLHashParams{
+		UserID:  uuidToPg(userID),
+		UrlHash: urlHash(normalizedURL),
+	})
+	switch {
+	case err == nil:
+		result.ID = uuidFromPg(existing.ID)
+	case errors.Is(err, pgx.ErrNoRows):
+		result.ID = uuid.New()
+		result.Created = true
+	default:
+		s.metrics.LinkCreateFailure.Inc()
+		c.Logger().Errorf("quick save: lookup failed: %v", err)
+		return quickSaveResult{}, apiError{Code: stdhttp.StatusInternalServerError, Message: "failed to look up link"}
+	}
+
+	tags := in.Tags
+	if result.Created {
+		if err := s.takeQuota(c, quotaLinks, userID); err != nil {
+			s.metrics.LinkCreateFailure.Inc()
+			return quickSaveResult{}, err
+		}
+		favorite := pgtype.Bool{}
+		if pref, ok := s.domainPreference(c, userID, normalizedURL); ok {
+			favorite = pgtype.Bool{Bool: pref.AlwaysFavorite, Valid: pref.AlwaysFavorite}
+			tags = append(append([]string{}, pref.DefaultTags...), tags...)
+		}
+		title := pgtype.Text{}
+		if trimmed := strings.TrimSpace(in.Title); trimmed != "" {
+			title = pgtype.Text{String: trimmed, Valid: true}
+		}
+		if _, err := s.queries.CreateLink(ctx, db.CreateLinkParams{
+			ID:            uuidToPg(result.ID),
+			UserID:        uuidToPg(userID),
+			Url:           normalizedURL,
+			Title:         title,
+			Favorite:      favorite,
+			PreserveTitle: s.preserveTitle(title, in.PreserveTitle),
+		}); err != nil {
+			s.metrics.LinkCreateFailure.Inc()
+			c.Logger().Errorf("quick save: store link failed: %v", err)
+			return quickSaveResult{}, apiError{Code: stdhttp.StatusInternalServerError, Message: "failed to store link"}
+		}
+		// The capture must be stored before the worker is told about the
+		// link, or it would fetch the URL instead.
+		if strings.TrimSpace(in.HTML) != "" {
+			if err := s.queries.UpsertLinkCapture(ctx, db.UpsertLinkCaptureParams{LinkID: uuidToPg(result.ID), Html: in.HTML}); err != nil {
+				s.metrics.LinkCreateFailure.Inc()
+				c.Logger().Errorf("quick save: store capture failed: %v", err)
+				return quickSaveResult{}, apiError{Code: stdhttp.StatusInternalServerError, Message: "failed to store page html"}
+			}
+		}
+	}
+
+	result.Tags, err = s.addTagsByName(ctx, userID, result.ID, tags)
+	if err != nil {
+		s.metrics.LinkCreateFailure.Inc()
+		return quickSaveResult{}, err
+	}
+
+	if !result.Created {
+		return result, nil
+	}
+
+	if err := s.publisher.PublishLinkSaved(ctx, result.ID); err != nil {
+		s.metrics.LinkCreateFailure.Inc()
+		c.Logger().Errorf("quick save: publish link saved failed: %v", err)
+		return quickSaveResult{}, apiError{Code: stdhttp.StatusInternalServerError, Message: "failed to enqueue link"}
+	}
+	s.metrics.LinkCreateSuccess.Inc()
+	return result, nil
+}
+
+// addTagsByName attaches userID's tags with the given names to a link,
+// creating any that do not exist, and returns them.
+func (s *Server) addTagsByName(ctx context.Context, userID, linkID uuid.UUID, names []string) ([]tagResponse, error) {
+	responses := make([]tagResponse, 0, len(names))
+	seen := make(map[string]struct{}, len(names))
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		if _, ok := seen[name]; ok {
+			continue
+		}
+		seen[name] = struct{}{}
+
+		tag, err := s.queries.GetTagByName(ctx, db.GetTagByNameParams{UserID: uuidToPg(userID), Name: name})
+		if errors.Is(err, pgx.ErrNoRows) {
+			tag, err = s.queries.CreateTag(ctx, db.CreateTagParams{UserID: uuidToPg(userID), Name: name})
+		}
+		if err != nil {
+			return nil, apiError{Code: stdhttp.StatusInternalServerError, Message: "failed to resolve tag"}
+		}
+		if err := s.queries.AddTagToLink(ctx, db.AddTagToLinkParams{LinkID: uuidToPg(linkID), TagID: tag.ID}); err != nil {
+			return nil, apiError{Code: stdhttp.StatusInternalServerError, Message: "failed to assign tag"}
+		}
+		responses = append(responses, tagResponse{ID: tag.ID, Name: tag.Name})
+	}
+	return responses, nil
+}
+
+// handleExtensionTags returns tags whose names start with prefix, ignoring
+// case, for autocomplete.
+func (s *Server) handleExtensionTags(c echo.Context) error {
+	limit := defaultExtensionTagLimit
+	if raw := strings.TrimSpace(c.QueryParam("limit")); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed <= 0 {
+			return respondError(c, stdhttp.StatusBadRequest, "limit must be a positive integer")
+		}
+		limit = min(parsed, maxExtensionTagLimit)
+	}
+
+	tags, err := s.queries.SearchTagsByPrefix(c.Request().Context(), db.SearchTagsByPrefixParams{
+		UserID:    uuidToPg(s.currentUser(c)),
+		Prefix:    likeEscaper.Replace(strings.TrimSpace(c.QueryParam("prefix"))),
+		PageLimit: int32(limit),
+	})
+	if err != nil {
+		c.Logger().Errorf("extension tags: search failed: %v", err)
+		return respondError(c, stdhttp.StatusInternalServerError, "failed to search tags")
+	}
+
+	responses := make([]tagResponse, 0, len(tags))
+	for _, tag := range tags {
+		responses = append(responses, tagResponse{ID: tag.ID, Name: tag.Name})
+	}
+	return c.JSON(stdhttp.StatusOK, responses)
+}
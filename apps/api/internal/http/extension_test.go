@@ -0,0 +1,308 @@
+package httpapi
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/labstack/echo/v4"
+
+	"github.com/example/keepstack/apps/api/internal/config"
+	"github.com/example/keepstack/apps/api/internal/db"
+)
+
+const testExtensionToken = "ext-secret"
+
+// withExtensionToken accepts testExtensionToken, next to a blank entry that
+// must not match an empty bearer token.
+func withExtensionToken(cfg *config.Config) {
+	cfg.ExtensionTokens = []string{" ", testExtensionToken}
+}
+
+func extensionRequest(method, target, body string) *http.Request {
+	var req *http.Request
+	if body == "" {
+		req = httptest.NewRequest(method, target, nil)
+	} else {
+		req = httptest.NewRequest(method, target, strings.NewReader(body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	}
+	req.Header.Set(echo.HeaderAuthorization, "Bearer "+testExtensionToken)
+	return req
+}
+
+func TestExtensionRoutesRequireToken(t *testing.T) {
+	t.Parallel()
+
+	e := newRouteTestServer(&mockQueries{}, &stubPublisher{}, withExtensionToken)
+
+	for _, header := range []string{"", "Bearer wrong", testExtensionToken} {
+		req := httptest.NewRequest(http.MethodGet, "/api/ext/tags", nil)
+		req.Header.Set(echo.HeaderOrigin, "chrome-extension://abcdef")
+		if header != "" {
+			req.Header.Set(echo.HeaderAuthorization, header)
+		}
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusUnauthorized {
+			t.Fatalf("authorization %q: expected status %d, got %d", header, http.StatusUnauthorized, rec.Code)
+		}
+		if rec.Header().Get(echo.HeaderWWWAuthenticate) == "" {
+			t.Fatalf("authorization %q: expected WWW-Authenticate header", header)
+		}
+		// Without CORS headers the extension could not read the 401.
+		if got := rec.Header().Get(echo.HeaderAccessControlAllowOrigin); got != "*" {
+			t.Fatalf("authorization %q: expected allow origin *, got %q", header, got)
+		}
+	}
+}
+
+func TestExtensionRoutesDisabledWithoutTokens(t *testing.T) {
+	t.Parallel()
+
+	srv := &Server{cfg: config.Config{ExtensionTokens: []string{""}}, queries: &mockQueries{}, metrics: newTestMetrics()}
+	e := echo.New()
+	srv.RegisterRoutes(e)
+
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, extensionRequest(http.MethodGet, "/api/ext/tags", ""))
+
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("expected status %d, got %d", http.StatusNotFound, rec.Code)
+	}
+}
+
+func TestExtensionPreflightAllowsAnyOrigin(t *testing.T) {
+	t.Parallel()
+
+	e := newRouteTestServer(&mockQueries{}, &stubPublisher{}, withExtensionToken)
+
+	req := httptest.NewRequest(http.MethodOptions, "/api/ext/save", nil)
+	req.Header.Set(echo.HeaderOrigin, "moz-extension://1234")
+	req.Header.Set(echo.HeaderAccessControlRequestMethod, http.MethodPost)
+	req.Header.Set(echo.HeaderAccessControlRequestHeaders, "authorization,content-type")
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("expected status %d, got %d", http.StatusNoContent, rec.Code)
+	}
+	if got := rec.Header().Get(echo.HeaderAccessControlAllowOrigin); got != "*" {
+		t.Fatalf("expected allow origin *, got %q", got)
+	}
+	if got := rec.Header().Get(echo.HeaderAccessControlAllowHeaders); !strings.Contains(got, echo.HeaderAuthorization) {
+		t.Fatalf("expected Authorization to be allowed, got %q", got)
+	}
+
+	// The rest of the API keeps its configured origins.
+	req = httptest.NewRequest(http.MethodOptions, "/api/links", nil)
+	req.Header.Set(echo.HeaderOrigin, "moz-extension://1234")
+	req.Header.Set(echo.HeaderAccessControlRequestMethod, http.MethodPost)
+	rec = httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+
+	if got := rec.Header().Get(echo.HeaderAccessControlAllowOrigin); got != "" {
+		t.Fatalf("expected no allow origin outside /api/ext, got %q", got)
+	}
+}
+
+func TestExtensionSavedLookup(t *testing.T) {
+	t.Parallel()
+
+	linkID := uuid.New()
+	savedURL := "https://example.com/post"
+	createdAt := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	queries := &mockQueries{
+		findLinkByURLHashFn: func(ctx context.Context, arg db.FindLinkByURLHashParams) (db.FindLinkByURLHashRow, error) {
+			if arg.UrlHash != urlHash(savedURL) {
+				return db.FindLinkByURLHashRow{}, pgx.ErrNoRows
+			}
+			return db.FindLinkByURLHashRow{
+				ID:        uuidToPg(linkID),
+				Url:       savedURL,
+				CreatedAt: pgtype.Timestamptz{Time: createdAt, Valid: true},
+			}, nil
+		},
+	}
+	e := newRouteTestServer(queries, &stubPublisher{}, withExtensionToken)
+
+	cases := []struct {
+		name   string
+		target string
+		code   int
+		saved  bool
+	}{
+		{"by hash", "/api/ext/saved?hash=" + strings.ToUpper(urlHash(savedURL)), http.StatusOK, true},
+		{"by normalized url", "/api/ext/saved?url=Example.com/post?utm_source=x", http.StatusOK, true},
+		{"not saved", "/api/ext/saved?url=https://example.com/other", http.StatusOK, false},
+		{"malformed hash", "/api/ext/saved?hash=abc", http.StatusBadRequest, false},
+		{"missing params", "/api/ext/saved", http.StatusBadRequest, false},
+	}
+	for _, tc := range cases {
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, extensionRequest(http.MethodGet, tc.target, ""))
+
+		if rec.Code != tc.code {
+			t.Fatalf("%s: expected status %d, got %d", tc.name, tc.code, rec.Code)
+		}
+		if tc.code != http.StatusOK {
+			continue
+		}
+		var resp extensionSavedResponse
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("%s: decode response: %v", tc.name, err)
+		}
+		if resp.Saved != tc.saved {
+			t.Fatalf("%s: expected saved %t, got %t", tc.name, tc.saved, resp.Saved)
+		}
+		if tc.saved && (resp.ID != linkID.String() || resp.CreatedAt == nil || !resp.CreatedAt.Equal(createdAt)) {
+			t.Fatalf("%s: unexpected response %+v", tc.name, resp)
+		}
+	}
+}
+
+func TestExtensionSaveStoresCaptureAndTags(t *testing.T) {
+	t.Parallel()
+
+	var (
+		created  db.CreateLinkParams
+		capture  db.UpsertLinkCaptureParams
+		assigned []int32
+	)
+	queries := &mockQueries{
+		findLinkByURLHashFn: func(ctx context.Context, arg db.FindLinkByURLHashParams) (db.FindLinkByURLHashRow, error) {
+			return db.FindLinkByURLHashRow{}, pgx.ErrNoRows
+		},
+		createLinkFn: func(ctx context.Context, params db.CreateLinkParams) (db.CreateLinkRow, error) {
+			created = params
+			return db.CreateLinkRow{ID: params.ID, Url: params.Url}, nil
+		},
+		upsertLinkCaptureFn: func(ctx context.Context, arg db.UpsertLinkCaptureParams) error {
+			capture = arg
+			return nil
+		},
+		getTagByNameFn: func(ctx context.Context, arg db.GetTagByNameParams) (db.Tag, error) {
+			if arg.Name == "go" {
+				return db.Tag{ID: 1, Name: "go"}, nil
+			}
+			return db.Tag{}, pgx.ErrNoRows
+		},
+		createTagFn: func(ctx context.Context, arg db.CreateTagParams) (db.Tag, error) {
+			return db.Tag{ID: 2, Name: arg.Name}, nil
+		},
+		addTagToLinkFn: func(ctx context.Context, arg db.AddTagToLinkParams) error {
+			assigned = append(assigned, arg.TagID)
+			return nil
+		},
+	}
+	publisher := &stubPublisher{}
+	e := newRouteTestServer(queries, publisher, withExtensionToken)
+
+	body := `{"url":"example.com/post?utm_medium=social","title":" Post ","html":"<html><body>hi</body></html>","tags":["go"," reading ","go",""]}`
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, extensionRequest(http.MethodPost, "/api/ext/save", body))
+
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusCreated, rec.Code, rec.Body.String())
+	}
+	var resp extensionSaveResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if !resp.Created || resp.URL != "https://example.com/post" || resp.URLHash != urlHash("https://example.com/post") {
+		t.Fatalf("unexpected response: %+v", resp)
+	}
+	if created.Url != "https://example.com/post" || created.Title.String != "Post" {
+		t.Fatalf("unexpected created link: %+v", created)
+	}
+	if uuidFromPg(capture.LinkID).String() != resp.ID || capture.Html != "<html><body>hi</body></html>" {
+		t.Fatalf("unexpected capture: %+v", capture)
+	}
+	if len(assigned) != 2 || assigned[0] != 1 || assigned[1] != 2 {
+		t.Fatalf("expected tags 1 and 2 to be assigned, got %v", assigned)
+	}
+	if len(resp.Tags) != 2 || resp.Tags[1].Name != "reading" {
+		t.Fatalf("unexpected tags: %+v", resp.Tags)
+	}
+	if !publisher.called || publisher.lastID.String() != resp.ID {
+		t.Fatalf("expected link to be enqueued")
+	}
+}
+
+func TestExtensionSaveExistingLinkOnlyAddsTags(t *testing.T) {
+	t.Parallel()
+
+	linkID := uuid.New()
+	var assigned []db.AddTagToLinkParams
+	queries := &mockQueries{
+		findLinkByURLHashFn: func(ctx context.Context, arg db.FindLinkByURLHashParams) (db.FindLinkByURLHashRow, error) {
+			return db.FindLinkByURLHashRow{ID: uuidToPg(linkID), Url: "https://example.com/post"}, nil
+		},
+		getTagByNameFn: func(ctx context.Context, arg db.GetTagByNameParams) (db.Tag, error) {
+			return db.Tag{ID: 7, Name: arg.Name}, nil
+		},
+		addTagToLinkFn: func(ctx context.Context, arg db.AddTagToLinkParams) error {
+			assigned = append(assigned, arg)
+			return nil
+		},
+	}
+	publisher := &stubPublisher{}
+	e := newRouteTestServer(queries, publisher, withExtensionToken)
+
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, extensionRequest(http.MethodPost, "/api/ext/save", `{"url":"https://example.com/post","html":"<p>x</p>","tags":["later"]}`))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+	}
+	if queries.createLinkCalled || publisher.called {
+		t.Fatalf("expected existing link not to be created or enqueued")
+	}
+	if len(assigned) != 1 || uuidFromPg(assigned[0].LinkID) != linkID {
+		t.Fatalf("expected tag to be assigned to existing link, got %+v", assigned)
+	}
+}
+
+func TestExtensionTagsPrefixSearch(t *testing.T) {
+	t.Parallel()
+
+	var got db.SearchTagsByPrefixParams
+	queries := &mockQueries{
+		searchTagsByPrefixFn: func(ctx context.Context, arg db.SearchTagsByPrefixParams) ([]db.Tag, error) {
+			got = arg
+			return []db.Tag{{ID: 3, Name: "go_lang"}}, nil
+		},
+	}
+	e := newRouteTestServer(queries, &stubPublisher{}, withExtensionToken)
+
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, extensionRequest(http.MethodGet, "/api/ext/tags?prefix=go_&limit=500", ""))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, rec.Code)
+	}
+	if got.Prefix != `go\_` || got.PageLimit != maxExtensionTagLimit {
+		t.Fatalf("unexpected search params: %+v", got)
+	}
+	var tags []tagResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &tags); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if len(tags) != 1 || tags[0].Name != "go_lang" {
+		t.Fatalf("unexpected tags: %+v", tags)
+	}
+
+	rec = httptest.NewRecorder()
+	e.ServeHTTP(rec, extensionRequest(http.MethodGet, "/api/ext/tags?limit=0", ""))
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected status %d for invalid limit, got %d", http.StatusBadRequest, rec.Code)
+	}
+}
@@ -0,0 +1,289 @@
+package httpapi
+
+import (
+	"encoding/xml"
+	"errors"
+	"html/template"
+	"regexp"
+	"strings"
+	"time"
+
+	stdhttp "net/http"
+
+	"github.com/jackc/pgerrcode"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/labstack/echo/v4"
+
+	"github.com/example/keepstack/apps/api/internal/db"
+)
+
+// publicFavoritesLimit caps how many favorites the public page and feed
+// list.
+const publicFavoritesLimit = 50
+
+// publicFavoritesCacheControl lets shared caches hold the page briefly, so a
+// popular link blog does not reach Postgres on every request.
+const publicFavoritesCacheControl = "public, max-age=300"
+
+// profileUsernamePattern keeps usernames safe to put in a URL path as is.
+var profileUsernamePattern = regexp.MustCompile(`^[a-z0-9_]{1,30}$`)
+
+var favoritesTemplate = template.Must(template.New("favorites").Parse(favoritesPage))
+
+// favoritesCSP lets the page load its own stylesheet and nothing else.
+var favoritesCSP = "default-src 'none'; style-src '" + styleHash(readerStyle) + "'; " +
+	"base-uri 'none'; form-action 'none'; frame-ancestors 'none'"
+
+type publicProfileRequest struct {
+	Username    string  `json:"username"`
+	DisplayName *string `json:"display_name"`
+}
+
+type publicProfileResponse struct {
+	Username     string    `json:"username"`
+	DisplayName  *string   `json:"display_name,omitempty"`
+	FavoritesURL string    `json:"favorites_url"`
+	FeedURL      string    `json:"feed_url"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+type favoritesPageData struct {
+	Name    string
+	FeedURL string
+	Items   []db.ListPublicFavoritesRow
+}
+
+type atomFeed struct {
+	XMLName xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	ID      string      `xml:"id"`
+	Title   string      `xml:"title"`
+	Updated string      `xml:"updated"`
+	Author  atomPerson  `xml:"author"`
+	Links   []atomLink  `xml:"link"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type atomPerson struct {
+	Name string `xml:"name"`
+}
+
+type atomLink struct {
+	Rel  string `xml:"rel,attr,omitempty"`
+	Type string `xml:"type,attr,omitempty"`
+	Href string `xml:"href,attr"`
+}
+
+type atomEntry struct {
+	ID      string   `xml:"id"`
+	Title   string   `xml:"title"`
+	Updated string   `xml:"updated"`
+	Link    atomLink `xml:"link"`
+	Summary string   `xml:"summary"`
+}
+
+// registerPublicFavoritesRoutes adds the public favorites page and feed and
+// the owner endpoints that opt in to them.
+func (s *Server) registerPublicFavoritesRoutes(e *echo.Echo, user *echo.Group) {
+	e.GET("/u/:username/favorites", s.handlePublicFavorites)
+	e.GET("/u/:username/favorites.atom", s.handlePublicFavoritesFeed)
+
+	user.GET("/profile/public", s.handleGetPublicProfile)
+	user.PUT("/profile/public", s.handlePutPublicProfile)
+	user.DELETE("/profile/public", s.handleDeletePublicProfile)
+}
+
+func (s *Server) handleGetPublicProfile(c echo.Context) error {
+	profile, err := s.queries.GetPublicProfile(c.Request().Context(), uuidToPg(s.currentUser(c)))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return respondError(c, stdhttp.StatusNotFound, "public profile not enabled")
+		}
+		c.Logger().Errorf("get public profile: %v", err)
+		return respondError(c, stdhttp.StatusInternalServerError, "failed to load public profile")
+	}
+	return c.JSON(stdhttp.StatusOK, toPublicProfileResponse(profile))
+}
+
+// handlePutPublicProfile opts the caller in to a public favorites page, or
+// renames it. The old URL stops working on a rename.
+func (s *Server) handlePutPublicProfile(c echo.Context) error {
+	var req publicProfileRequest
+	if err := c.Bind(&req); err != nil {
+		return respondBindError(c, err)
+	}
+	username := strings.ToLower(strings.TrimSpace(req.Username))
+	if !profileUsernamePattern.MatchString(username) {
+		return respondError(c, stdhttp.StatusBadRequest, "username must be 1-30 lowercase letters, digits, or underscores")
+	}
+
+	profile, err := s.queries.UpsertPublicProfile(c.Request().Context(), db.UpsertPublicProfileParams{
+		UserID:      uuidToPg(s.currentUser(c)),
+		Username:    username,
+		DisplayName: optionalText(req.DisplayName),
+	})
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == pgerrcode.UniqueViolation {
+			return respondError(c, stdhttp.StatusConflict, "username is taken")
+		}
+		c.Logger().Errorf("put public profile: %v", err)
+		return respondError(c, stdhttp.StatusInternalServerError, "failed to save public profile")
+	}
+	return c.JSON(stdhttp.StatusOK, toPublicProfileResponse(profile))
+}
+
+func (s *Server) handleDeletePublicProfile(c echo.Context) error {
+	deleted, err := s.queries.DeletePublicProfile(c.Request().Context(), uuidToPg(s.currentUser(c)))
+	if err != nil {
+		c.Logger().Errorf("delete public profile: %v", err)
+		return respondError(c, stdhttp.StatusInternalServerError, "failed to delete public profile")
+	}
+	if deleted == 0 {
+		return respondError(c, stdhttp.StatusNotFound, "public profile not enabled")
+	}
+	return c.NoContent(stdhttp.StatusNoContent)
+}
+
+// handlePublicFavorites renders a user's favorites as a plain HTML link
+// blog: titles and domains only, with no JavaScript.
+func (s *Server) handlePublicFavorites(c echo.Context) error {
+	profile, items, err := s.loadPublicFavorites(c)
+	if err != nil {
+		var apiErr apiError
+		if errors.As(err, &apiErr) {
+			return c.String(apiErr.Code, apiErr.Message)
+		}
+		return err
+	}
+
+	var out strings.Builder
+	if err := favoritesTemplate.Execute(&out, favoritesPageData{
+		Name:    profileName(profile),
+		FeedURL: "/u/" + profile.Username + "/favorites.atom",
+		Items:   items,
+	}); err != nil {
+		c.Logger().Errorf("public favorites: render failed: %v", err)
+		return c.String(stdhttp.StatusInternalServerError, "failed to render favorites")
+	}
+	c.Response().Header().Set("Content-Security-Policy", favoritesCSP)
+	c.Response().Header().Set(echo.HeaderCacheControl, publicFavoritesCacheControl)
+	return c.HTML(stdhttp.StatusOK, out.String())
+}
+
+// handlePublicFavoritesFeed serves the same favorites as an Atom feed.
+func (s *Server) handlePublicFavoritesFeed(c echo.Context) error {
+	profile, items, err := s.loadPublicFavorites(c)
+	if err != nil {
+		var apiErr apiError
+		if errors.As(err, &apiErr) {
+			return c.String(apiErr.Code, apiErr.Message)
+		}
+		return err
+	}
+
+	origin := c.Scheme() + "://" + c.Request().Host
+	pageURL := origin + "/u/" + profile.Username + "/favorites"
+	feed := atomFeed{
+		ID:      pageURL,
+		Title:   profileName(profile) + "'s favorites",
+		Updated: profile.CreatedAt.Time.UTC().Format(time.RFC3339),
+		Author:  atomPerson{Name: profileName(profile)},
+		Links: []atomLink{
+			{Rel: "self", Type: "application/atom+xml", Href: pageURL + ".atom"},
+			{Rel: "alternate", Type: "text/html", Href: pageURL},
+		},
+	}
+	for i, item := range items {
+		updated := item.CreatedAt.Time.UTC().Format(time.RFC3339)
+		if i == 0 {
+			feed.Updated = updated
+		}
+		feed.Entries = append(feed.Entries, atomEntry{
+			ID:      item.Url,
+			Title:   item.Title,
+			Updated: updated,
+			Link:    atomLink{Href: item.Url},
+			Summary: item.Domain,
+		})
+	}
+
+	body, err := xml.MarshalIndent(feed, "", "  ")
+	if err != nil {
+		c.Logger().Errorf("public favorites: encode feed failed: %v", err)
+		return c.String(stdhttp.StatusInternalServerError, "failed to render feed")
+	}
+	c.Response().Header().Set(echo.HeaderCacheControl, publicFavoritesCacheControl)
+	return c.Blob(stdhttp.StatusOK, "application/atom+xml; charset=utf-8", append([]byte(xml.Header), body...))
+}
+
+// loadPublicFavorites looks up the profile named in the path and its
+// favorites. Users without a profile have no page.
+func (s *Server) loadPublicFavorites(c echo.Context) (db.PublicProfile, []db.ListPublicFavoritesRow, error) {
+	ctx := c.Request().Context()
+	username := strings.ToLower(c.Param("username"))
+	if !profileUsernamePattern.MatchString(username) {
+		return db.PublicProfile{}, nil, apiError{Code: stdhttp.StatusNotFound, Message: "profile not found"}
+	}
+	profile, err := s.queries.GetPublicProfileByUsername(ctx, username)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return db.PublicProfile{}, nil, apiError{Code: stdhttp.StatusNotFound, Message: "profile not found"}
+		}
+		c.Logger().Errorf("public favorites: look up %q failed: %v", username, err)
+		return db.PublicProfile{}, nil, apiError{Code: stdhttp.StatusInternalServerError, Message: "failed to load profile"}
+	}
+	items, err := s.queries.ListPublicFavorites(ctx, db.ListPublicFavoritesParams{
+		UserID:    profile.UserID,
+		PageLimit: publicFavoritesLimit,
+	})
+	if err != nil {
+		c.Logger().Errorf("public favorites: list failed: %v", err)
+		return db.PublicProfile{}, nil, apiError{Code: stdhttp.StatusInternalServerError, Message: "failed to load favorites"}
+	}
+	return profile, items, nil
+}
+
+func toPublicProfileResponse(profile db.PublicProfile) publicProfileResponse {
+	base := "/u/" + profile.Username + "/favorites"
+	return publicProfileResponse{
+		Username:     profile.Username,
+		DisplayName:  textPtr(profile.DisplayName),
+		FavoritesURL: base,
+		FeedURL:      base + ".atom",
+		CreatedAt:    profile.CreatedAt.Time,
+	}
+}
+
+func profileName(profile db.PublicProfile) string {
+	return firstNonBlank(profile.DisplayName.String, profile.Username)
+}
+
+const favoritesPage = `<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{.Name}}'s favorites</title>
+<link rel="alternate" type="application/atom+xml" href="{{.FeedURL}}">
+<style>` + readerStyle + `</style>
+</head>
+<body>
+<main>
+<header>
+<h1>{{.Name}}'s favorites</h1>
+<div class="meta"><a href="{{.FeedURL}}">Atom feed</a></div>
+</header>
+{{- if .Items}}
+<ul>
+{{- range .Items}}
+<li><a href="{{.Url}}" rel="noreferrer">{{.Title}}</a>{{if .Domain}} <span class="meta">{{.Domain}}</span>{{end}}</li>
+{{- end}}
+</ul>
+{{- else}}
+<p class="meta">No favorites yet.</p>
+{{- end}}
+</main>
+</body>
+</html>
+`
@@ -0,0 +1,208 @@
+package httpapi
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgerrcode"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/labstack/echo/v4"
+
+	"github.com/example/keepstack/apps/api/internal/config"
+	"github.com/example/keepstack/apps/api/internal/db"
+)
+
+func newPublicFavoritesServer(t *testing.T, queries *mockQueries) *echo.Echo {
+	t.Helper()
+
+	cfg := config.Config{DevUserID: uuid.MustParse("b6b6b6b6-b6b6-b6b6-b6b6-b6b6b6b6b6b6")}
+	srv := &Server{cfg: cfg, queries: queries, metrics: newTestMetrics()}
+	e := echo.New()
+	srv.RegisterRoutes(e)
+	return e
+}
+
+func publicFavoritesQueries() *mockQueries {
+	created := pgtype.Timestamptz{Time: time.Date(2024, time.May, 1, 0, 0, 0, 0, time.UTC), Valid: true}
+	profile := db.PublicProfile{
+		UserID:      pgtype.UUID{Bytes: [16]byte{1}, Valid: true},
+		Username:    "alice",
+		DisplayName: pgtype.Text{String: "Alice", Valid: true},
+		CreatedAt:   created,
+	}
+	return &mockQueries{
+		getPublicProfileByUsernameFn: func(ctx context.Context, username string) (db.PublicProfile, error) {
+			if username != profile.Username {
+				return db.PublicProfile{}, pgx.ErrNoRows
+			}
+			return profile, nil
+		},
+		listPublicFavoritesFn: func(ctx context.Context, arg db.ListPublicFavoritesParams) ([]db.ListPublicFavoritesRow, error) {
+			if arg.UserID != profile.UserID || arg.PageLimit != publicFavoritesLimit {
+				return nil, pgx.ErrNoRows
+			}
+			return []db.ListPublicFavoritesRow{
+				{Url: "https://example.com/a", Title: "A <b>bold</b> post", Domain: "example.com", CreatedAt: pgtype.Timestamptz{Time: time.Date(2024, time.June, 2, 0, 0, 0, 0, time.UTC), Valid: true}},
+				{Url: "https://blog.test/b", Title: "Second", Domain: "blog.test", CreatedAt: created},
+			}, nil
+		},
+	}
+}
+
+func TestHandlePublicFavoritesPage(t *testing.T) {
+	t.Parallel()
+
+	e := newPublicFavoritesServer(t, publicFavoritesQueries())
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/u/Alice/favorites", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+	}
+	body := rec.Body.String()
+	for _, want := range []string{
+		"<h1>Alice's favorites</h1>",
+		`href="https://example.com/a"`,
+		"A &lt;b&gt;bold&lt;/b&gt; post",
+		"blog.test",
+		`type="application/atom+xml" href="/u/alice/favorites.atom"`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Fatalf("expected page to contain %q, got %s", want, body)
+		}
+	}
+	if got := rec.Header().Get(echo.HeaderCacheControl); got != publicFavoritesCacheControl {
+		t.Fatalf("expected cache control %q, got %q", publicFavoritesCacheControl, got)
+	}
+	if got := rec.Header().Get("Content-Security-Policy"); got != favoritesCSP {
+		t.Fatalf("expected favorites CSP, got %q", got)
+	}
+}
+
+func TestHandlePublicFavoritesFeed(t *testing.T) {
+	t.Parallel()
+
+	e := newPublicFavoritesServer(t, publicFavoritesQueries())
+	req := httptest.NewRequest(http.MethodGet, "/u/alice/favorites.atom", nil)
+	req.Host = "keep.example"
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+	}
+	if got := rec.Header().Get(echo.HeaderContentType); !strings.HasPrefix(got, "application/atom+xml") {
+		t.Fatalf("expected an Atom content type, got %q", got)
+	}
+
+	var feed atomFeed
+	if err := xml.Unmarshal(rec.Body.Bytes(), &feed); err != nil {
+		t.Fatalf("decode feed: %v", err)
+	}
+	if feed.ID != "http://keep.example/u/alice/favorites" || feed.Updated != "2024-06-02T00:00:00Z" {
+		t.Fatalf("unexpected feed header: %+v", feed)
+	}
+	if len(feed.Entries) != 2 || feed.Entries[0].ID != "https://example.com/a" || feed.Entries[1].Summary != "blog.test" {
+		t.Fatalf("unexpected entries: %+v", feed.Entries)
+	}
+}
+
+func TestHandlePublicFavoritesUnknownUser(t *testing.T) {
+	t.Parallel()
+
+	e := newPublicFavoritesServer(t, publicFavoritesQueries())
+	for _, path := range []string{"/u/bob/favorites", "/u/bob/favorites.atom", "/u/not%20valid/favorites"} {
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		if rec.Code != http.StatusNotFound {
+			t.Fatalf("%s: expected status %d, got %d", path, http.StatusNotFound, rec.Code)
+		}
+	}
+}
+
+func TestHandlePutPublicProfile(t *testing.T) {
+	t.Parallel()
+
+	var got db.UpsertPublicProfileParams
+	e := newPublicFavoritesServer(t, &mockQueries{
+		upsertPublicProfileFn: func(ctx context.Context, arg db.UpsertPublicProfileParams) (db.PublicProfile, error) {
+			got = arg
+			return db.PublicProfile{UserID: arg.UserID, Username: arg.Username, DisplayName: arg.DisplayName}, nil
+		},
+	})
+
+	req := httptest.NewRequest(http.MethodPut, "/api/profile/public", strings.NewReader(`{"username":" Alice_1 ","display_name":"Alice"}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+	}
+	if got.Username != "alice_1" || got.DisplayName.String != "Alice" {
+		t.Fatalf("unexpected upsert params: %+v", got)
+	}
+	var resp publicProfileResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if resp.FavoritesURL != "/u/alice_1/favorites" || resp.FeedURL != "/u/alice_1/favorites.atom" {
+		t.Fatalf("unexpected response: %+v", resp)
+	}
+}
+
+func TestHandlePutPublicProfileRejectsBadUsernames(t *testing.T) {
+	t.Parallel()
+
+	e := newPublicFavoritesServer(t, &mockQueries{
+		upsertPublicProfileFn: func(ctx context.Context, arg db.UpsertPublicProfileParams) (db.PublicProfile, error) {
+			return db.PublicProfile{}, &pgconn.PgError{Code: pgerrcode.UniqueViolation}
+		},
+	})
+
+	cases := []struct {
+		body string
+		code int
+	}{
+		{body: `{"username":""}`, code: http.StatusBadRequest},
+		{body: `{"username":"has space"}`, code: http.StatusBadRequest},
+		{body: `{"username":"` + strings.Repeat("a", 31) + `"}`, code: http.StatusBadRequest},
+		{body: `{"username":"taken"}`, code: http.StatusConflict},
+	}
+	for _, tc := range cases {
+		req := httptest.NewRequest(http.MethodPut, "/api/profile/public", strings.NewReader(tc.body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		if rec.Code != tc.code {
+			t.Fatalf("%s: expected status %d, got %d: %s", tc.body, tc.code, rec.Code, rec.Body.String())
+		}
+	}
+}
+
+func TestHandleDeletePublicProfile(t *testing.T) {
+	t.Parallel()
+
+	deleted := int64(1)
+	e := newPublicFavoritesServer(t, &mockQueries{
+		deletePublicProfileFn: func(ctx context.Context, userID pgtype.UUID) (int64, error) {
+			n := deleted
+			deleted = 0
+			return n, nil
+		},
+	})
+
+	for _, want := range []int{http.StatusNoContent, http.StatusNotFound} {
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, httptest.NewRequest(http.MethodDelete, "/api/profile/public", nil))
+		if rec.Code != want {
+			t.Fatalf("expected status %d, got %d", want, rec.Code)
+		}
+	}
+}
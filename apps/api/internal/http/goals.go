@@ -0,0 +1,98 @@
+package httpapi
+
+import (
+	"time"
+
+	stdhttp "net/http"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/example/keepstack/apps/api/internal/db"
+	"github.com/example/keepstack/apps/api/internal/goals"
+)
+
+// maxWeeklyTarget caps a goal well above anything reachable in a week.
+const maxWeeklyTarget = 100000
+
+type setGoalRequest struct {
+	WeeklyTarget int `json:"weekly_target"`
+}
+
+// handleListGoals lists the caller's weekly goals with this week's progress.
+func (s *Server) handleListGoals(c echo.Context) error {
+	summary, err := goals.Summarize(c.Request().Context(), s.queries, s.currentUser(c), time.Now())
+	if err != nil {
+		c.Logger().Errorf("list goals: %v", err)
+		return respondError(c, stdhttp.StatusInternalServerError, "failed to list goals")
+	}
+	return c.JSON(stdhttp.StatusOK, map[string]any{
+		"week_start": summary.WeekStart,
+		"items":      summary.Goals,
+	})
+}
+
+// handleSetGoal sets the caller's weekly target for a metric, replacing any
+// earlier target, and returns the goal with this week's progress.
+func (s *Server) handleSetGoal(c echo.Context) error {
+	metric := c.Param("metric")
+	if metric != goals.MetricArticles && metric != goals.MetricMinutes {
+		return respondError(c, stdhttp.StatusBadRequest, "metric must be articles or minutes")
+	}
+	var req setGoalRequest
+	if err := c.Bind(&req); err != nil {
+		return respondBindError(c, err)
+	}
+	if req.WeeklyTarget < 1 || req.WeeklyTarget > maxWeeklyTarget {
+		return respondError(c, stdhttp.StatusBadRequest, "weekly_target must be between 1 and 100000")
+	}
+
+	ctx := c.Request().Context()
+	if _, err := s.queries.UpsertReadingGoal(ctx, db.UpsertReadingGoalParams{
+		UserID:       uuidToPg(s.userID(ctx)),
+		Metric:       metric,
+		WeeklyTarget: int32(req.WeeklyTarget),
+	}); err != nil {
+		c.Logger().Errorf("set goal: upsert failed: %v", err)
+		return respondError(c, stdhttp.StatusInternalServerError, "failed to set goal")
+	}
+
+	summary, err := goals.Summarize(ctx, s.queries, s.userID(ctx), time.Now())
+	if err != nil {
+		c.Logger().Errorf("set goal: %v", err)
+		return respondError(c, stdhttp.StatusInternalServerError, "failed to set goal")
+	}
+	for _, goal := range summary.Goals {
+		if goal.Metric == metric {
+			return c.JSON(stdhttp.StatusOK, goal)
+		}
+	}
+	// The goal was deleted between the upsert and the read.
+	return respondError(c, stdhttp.StatusNotFound, "goal not found")
+}
+
+// handleDeleteGoal removes the caller's goal for a metric.
+func (s *Server) handleDeleteGoal(c echo.Context) error {
+	deleted, err := s.queries.DeleteReadingGoal(c.Request().Context(), db.DeleteReadingGoalParams{
+		UserID: uuidToPg(s.currentUser(c)),
+		Metric: c.Param("metric"),
+	})
+	if err != nil {
+		c.Logger().Errorf("delete goal: delete failed: %v", err)
+		return respondError(c, stdhttp.StatusInternalServerError, "failed to delete goal")
+	}
+	if deleted == 0 {
+		return respondError(c, stdhttp.StatusNotFound, "goal not found")
+	}
+	return c.NoContent(stdhttp.StatusNoContent)
+}
+
+// handleStats reports the caller's reading this week, their goals, and
+// their reading streak.
+func (s *Server) handleStats(c echo.Context) error {
+	summary, err := goals.Summarize(c.Request().Context(), s.queries, s.currentUser(c), time.Now())
+	if err != nil {
+		c.Logger().Errorf("stats: %v", err)
+		return respondError(c, stdhttp.StatusInternalServerError, "failed to load stats")
+	}
+	return c.JSON(stdhttp.StatusOK, summary)
+}
@@ -0,0 +1,152 @@
+package httpapi
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/labstack/echo/v4"
+
+	"github.com/example/keepstack/apps/api/internal/config"
+	"github.com/example/keepstack/apps/api/internal/db"
+	"github.com/example/keepstack/apps/api/internal/goals"
+)
+
+func newGoalsServer(stored map[string]int32) *echo.Echo {
+	cfg := config.Config{DevUserID: uuid.MustParse("dddddddd-dddd-dddd-dddd-dddddddddddd")}
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	queries := &mockQueries{
+		upsertReadingGoalFn: func(ctx context.Context, arg db.UpsertReadingGoalParams) (db.ReadingGoal, error) {
+			stored[arg.Metric] = arg.WeeklyTarget
+			return db.ReadingGoal{UserID: arg.UserID, Metric: arg.Metric, WeeklyTarget: arg.WeeklyTarget}, nil
+		},
+		listReadingGoalsFn: func(ctx context.Context, userID pgtype.UUID) ([]db.ReadingGoal, error) {
+			var rows []db.ReadingGoal
+			for _, metric := range []string{goals.MetricArticles, goals.MetricMinutes} {
+				if target, ok := stored[metric]; ok {
+					rows = append(rows, db.ReadingGoal{UserID: userID, Metric: metric, WeeklyTarget: target})
+				}
+			}
+			return rows, nil
+		},
+		deleteReadingGoalFn: func(ctx context.Context, arg db.DeleteReadingGoalParams) (int64, error) {
+			if _, ok := stored[arg.Metric]; !ok {
+				return 0, nil
+			}
+			delete(stored, arg.Metric)
+			return 1, nil
+		},
+		getReadingProgressFn: func(ctx context.Context, arg db.GetReadingProgressParams) (db.GetReadingProgressRow, error) {
+			return db.GetReadingProgressRow{Articles: 3, Minutes: 42}, nil
+		},
+		listReadDaysFn: func(ctx context.Context, userID pgtype.UUID) ([]pgtype.Date, error) {
+			return []pgtype.Date{
+				{Time: today, Valid: true},
+				{Time: today.AddDate(0, 0, -1), Valid: true},
+			}, nil
+		},
+	}
+	srv := &Server{cfg: cfg, queries: queries, metrics: newTestMetrics()}
+	e := echo.New()
+	srv.RegisterRoutes(e)
+	return e
+}
+
+func TestHandleSetGoal(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name   string
+		metric string
+		body   string
+		want   int
+	}{
+		{name: "articles", metric: "articles", body: `{"weekly_target":5}`, want: http.StatusOK},
+		{name: "minutes", metric: "minutes", body: `{"weekly_target":30}`, want: http.StatusOK},
+		{name: "unknown metric", metric: "pages", body: `{"weekly_target":5}`, want: http.StatusBadRequest},
+		{name: "zero target", metric: "articles", body: `{"weekly_target":0}`, want: http.StatusBadRequest},
+		{name: "huge target", metric: "articles", body: `{"weekly_target":1000000}`, want: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			stored := map[string]int32{}
+			e := newGoalsServer(stored)
+			req := httptest.NewRequest(http.MethodPut, "/api/goals/"+tt.metric, strings.NewReader(tt.body))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+
+			if rec.Code != tt.want {
+				t.Fatalf("expected status %d, got %d: %s", tt.want, rec.Code, rec.Body.String())
+			}
+			if tt.want != http.StatusOK {
+				if len(stored) != 0 {
+					t.Fatalf("expected no goal to be stored, got %v", stored)
+				}
+				return
+			}
+			var goal goals.Goal
+			if err := json.Unmarshal(rec.Body.Bytes(), &goal); err != nil {
+				t.Fatalf("decode response: %v", err)
+			}
+			if goal.Metric != tt.metric || int32(goal.WeeklyTarget) != stored[tt.metric] || goal.Progress == 0 {
+				t.Fatalf("unexpected goal %+v", goal)
+			}
+		})
+	}
+}
+
+func TestHandleGoalsAndStats(t *testing.T) {
+	t.Parallel()
+
+	stored := map[string]int32{goals.MetricArticles: 3}
+	e := newGoalsServer(stored)
+
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/goals", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, rec.Code)
+	}
+	var list struct {
+		Items []goals.Goal `json:"items"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &list); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if len(list.Items) != 1 || !list.Items[0].Met || list.Items[0].Progress != 3 {
+		t.Fatalf("expected the articles goal met, got %+v", list.Items)
+	}
+
+	rec = httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/stats", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, rec.Code)
+	}
+	var stats goals.Summary
+	if err := json.Unmarshal(rec.Body.Bytes(), &stats); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if stats.Articles != 3 || stats.Minutes != 42 || stats.Streak.Current != 2 || stats.Streak.Longest != 2 {
+		t.Fatalf("unexpected stats %+v", stats)
+	}
+
+	rec = httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodDelete, "/api/goals/articles", nil))
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("expected status %d, got %d", http.StatusNoContent, rec.Code)
+	}
+	rec = httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodDelete, "/api/goals/articles", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("expected status %d, got %d", http.StatusNotFound, rec.Code)
+	}
+}
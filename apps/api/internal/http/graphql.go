@@ -0,0 +1,457 @@
+package httpapi
+
+import (
+	"context"
+	_ "embed"
+	"errors"
+	"log"
+	stdhttp "net/http"
+	"strconv"
+	"strings"
+
+	graphql "github.com/graph-gophers/graphql-go"
+	graphqlotel "github.com/graph-gophers/graphql-go/trace/otel"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/labstack/echo/v4"
+
+	"github.com/example/keepstack/apps/api/internal/db"
+)
+
+//go:embed graphql.graphqls
+var graphQLSchema string
+
+// graphQLRequest is the GraphQL-over-HTTP POST body.
+type graphQLRequest struct {
+	Query         string         `json:"query"`
+	OperationName string         `json:"operationName"`
+	Variables     map[string]any `json:"variables"`
+}
+
+// graphQLHandler serves read-only queries over links, tags, highlights, and
+// recommendations. Resolvers run only for selected fields, so a client that
+// skips highlights or totalCount skips their queries too.
+func (s *Server) graphQLHandler() echo.HandlerFunc {
+	schema := graphql.MustParseSchema(graphQLSchema, &graphQLResolver{s: s},
+		graphql.Tracer(graphqlotel.DefaultTracer()))
+
+	return func(c echo.Context) error {
+		var req graphQLRequest
+		if err := c.Bind(&req); err != nil {
+			return respondBindError(c, err)
+		}
+		if strings.TrimSpace(req.Query) == "" {
+			return respondError(c, stdhttp.StatusBadRequest, "query is required")
+		}
+
+		// GraphQL reports resolver errors in the body next to partial data.
+		return c.JSON(stdhttp.StatusOK, schema.Exec(c.Request().Context(), req.Query, req.OperationName, req.Variables))
+	}
+}
+
+// graphQLInternalError logs err and returns a message safe to show clients.
+func graphQLInternalError(action string, err error) error {
+	log.Printf("graphql: %s: %v", action, err)
+	return errors.New("failed to " + action)
+}
+
+type graphQLResolver struct {
+	s *Server
+}
+
+type graphQLLinksArgs struct {
+	Limit         int32
+	Offset        int32
+	Favorite      *bool
+	Query         *string
+	Tags          *[]string
+	HasHighlights *bool
+}
+
+func (r *graphQLResolver) Links(ctx context.Context, args graphQLLinksArgs) (*linkPageResolver, error) {
+	limit, offset, err := graphQLPagination(args.Limit, args.Offset)
+	if err != nil {
+		return nil, err
+	}
+	tagIDs, err := r.s.graphQLTagFilter(ctx, args.Tags)
+	if err != nil {
+		return nil, err
+	}
+
+	filter := linkFilter{tagIDs: tagIDs}
+	if args.Favorite != nil {
+		filter.favorite = pgtype.Bool{Bool: *args.Favorite, Valid: true}
+	}
+	if args.Query != nil {
+		if query := strings.TrimSpace(*args.Query); query != "" {
+			filter.query = pgtype.Text{String: query, Valid: true}
+		}
+	}
+	if args.HasHighlights != nil {
+		filter.hasHighlights = pgtype.Bool{Bool: *args.HasHighlights, Valid: true}
+	}
+	return &linkPageResolver{s: r.s, filter: filter, limit: limit, offset: offset}, nil
+}
+
+func (r *graphQLResolver) Tags(ctx context.Context) ([]*tagResolver, error) {
+	items, err := r.s.reads().ListTagLinkCounts(ctx, uuidToPg(r.s.userID(ctx)))
+	if err != nil {
+		return nil, graphQLInternalError("list tags", err)
+	}
+
+	tags := make([]*tagResolver, 0, len(items))
+	for _, item := range items {
+		count := item.LinkCount
+		tags = append(tags, &tagResolver{tag: tagResponse{ID: item.ID, Name: item.Name, LinkCount: &count}})
+	}
+	return tags, nil
+}
+
+type graphQLRecommendationsArgs struct {
+	Limit  int32
+	Cursor *string
+	Domain *string
+	Tags   *[]string
+}
+
+func (r *graphQLResolver) Recommendations(ctx context.Context, args graphQLRecommendationsArgs) (*recommendationPageResolver, error) {
+	limit, _, err := graphQLPagination(args.Limit, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	params := db.ListRecommendationsForUserParams{
+		UserID:    uuidToPg(r.s.userID(ctx)),
+		PageLimit: int32(limit + 1),
+	}
+	if args.Cursor != nil && strings.TrimSpace(*args.Cursor) != "" {
+		cursor, err := decodeRecommendationCursor(strings.TrimSpace(*args.Cursor))
+		if err != nil {
+			return nil, errors.New("invalid cursor")
+		}
+		params.CursorScore = pgtype.Int4{Int32: cursor.score, Valid: true}
+		params.CursorUpdatedAt = pgtype.Timestamptz{Time: cursor.updatedAt, Valid: true}
+		params.CursorLinkID = uuidToPg(cursor.linkID)
+	}
+	if args.Domain != nil {
+		if domain := strings.ToLower(strings.TrimSpace(*args.Domain)); domain != "" {
+			params.Domain = pgtype.Text{String: domain, Valid: true}
+		}
+	}
+	if params.TagIds, err = r.s.graphQLTagFilter(ctx, args.Tags); err != nil {
+		return nil, err
+	}
+
+	rows, source, nextCursor, err := r.s.loadRecommendations(ctx, params, limit)
+	if err != nil {
+		return nil, graphQLInternalError("load recommendations", err)
+	}
+	if err := r.s.recordImpressions(ctx, impressionSurfaceAPI, rows, 0); err != nil {
+		log.Printf("graphql: %v", err)
+	}
+
+	items := make([]*linkResolver, 0, len(rows))
+	for _, row := range rows {
+		items = append(items, &linkResolver{s: r.s, id: row.ID, link: toRecommendationLinkResponse(row)})
+	}
+	page := &recommendationPageResolver{items: items, source: source}
+	if nextCursor != "" {
+		page.nextCursor = &nextCursor
+	}
+	return page, nil
+}
+
+// graphQLPagination applies the REST limit and offset rules.
+func graphQLPagination(limit, offset int32) (int, int, error) {
+	return parsePagination(strconv.Itoa(int(limit)), strconv.Itoa(int(offset)))
+}
+
+func (s *Server) graphQLTagFilter(ctx context.Context, names *[]string) ([]int32, error) {
+	if names == nil {
+		return nil, nil
+	}
+	tagIDs, err := s.resolveTagFilter(ctx, strings.Join(*names, ","))
+	if err != nil {
+		var unknown unknownTagError
+		if errors.As(err, &unknown) {
+			return nil, unknown
+		}
+		return nil, graphQLInternalError("resolve tags", err)
+	}
+	return tagIDs, nil
+}
+
+// linkFilter holds the link list filters shared by the list and count
+// queries.
+type linkFilter struct {
+	favorite      pgtype.Bool
+	query         pgtype.Text
+	tagIDs        []int32
+	hasHighlights pgtype.Bool
+}
+
+// listLinkRows runs the list query for filter, retrying without full-text
+// parsing when the search text is not valid tsquery syntax.
+func (s *Server) listLinkRows(ctx context.Context, filter linkFilter, limit, offset int) ([]db.ListLinksRow, error) {
+	list := func(fullText bool) ([]db.ListLinksRow, error) {
+		if len(filter.tagIDs) == 0 {
+			return s.reads().ListLinks(ctx, db.ListLinksParams{
+				UserID:         uuidToPg(s.userID(ctx)),
+				Favorite:       filter.favorite,
+				Query:          filter.query,
+				EnableFullText: fullText,
+				HasHighlights:  filter.hasHighlights,
+				PageLimit:      int32(limit),
+				PageOffset:     int32(offset),
+			})
+		}
+		rows, err := s.reads().ListLinksWithTags(ctx, db.ListLinksWithTagsParams{
+			TagIds:         filter.tagIDs,
+			UserID:         uuidToPg(s.userID(ctx)),
+			Favorite:       filter.favorite,
+			Query:          filter.query,
+			EnableFullText: fullText,
+			HasHighlights:  filter.hasHighlights,
+			PageOffset:     int32(offset),
+			PageLimit:      int32(limit),
+		})
+		return convertListLinksWithTagsRows(rows), err
+	}
+
+	rows, err := list(true)
+	if err != nil && isFullTextParseError(err) {
+		rows, err = list(false)
+	}
+	return rows, err
+}
+
+// countLinkRows counts the links matching filter with the same full-text
+// fallback as listLinkRows.
+func (s *Server) countLinkRows(ctx context.Context, filter linkFilter) (int64, error) {
+	count := func(fullText bool) (int64, error) {
+		if len(filter.tagIDs) == 0 {
+			return s.reads().CountLinks(ctx, db.CountLinksParams{
+				UserID:         uuidToPg(s.userID(ctx)),
+				Favorite:       filter.favorite,
+				Query:          filter.query,
+				EnableFullText: fullText,
+				HasHighlights:  filter.hasHighlights,
+			})
+		}
+		return s.reads().CountLinksWithTags(ctx, db.CountLinksWithTagsParams{
+			TagIds:         filter.tagIDs,
+			UserID:         uuidToPg(s.userID(ctx)),
+			Favorite:       filter.favorite,
+			Query:          filter.query,
+			EnableFullText: fullText,
+			HasHighlights:  filter.hasHighlights,
+		})
+	}
+
+	total, err := count(true)
+	if err != nil && isFullTextParseError(err) {
+		total, err = count(false)
+	}
+	return total, err
+}
+
+type linkPageResolver struct {
+	s      *Server
+	filter linkFilter
+	limit  int
+	offset int
+}
+
+func (p *linkPageResolver) Items(ctx context.Context) ([]*linkResolver, error) {
+	rows, err := p.s.listLinkRows(ctx, p.filter, p.limit, p.offset)
+	if err != nil {
+		return nil, graphQLInternalError("fetch links", err)
+	}
+
+	items := make([]*linkResolver, 0, len(rows))
+	for _, row := range rows {
+		link, err := toLinkResponse(row)
+		if err != nil {
+			return nil, graphQLInternalError("format links", err)
+		}
+		items = append(items, &linkResolver{s: p.s, id: row.ID, link: link, loaded: true})
+	}
+	return items, nil
+}
+
+func (p *linkPageResolver) TotalCount(ctx context.Context) (int32, error) {
+	total, err := p.s.countLinkRows(ctx, p.filter)
+	if err != nil {
+		return 0, graphQLInternalError("count links", err)
+	}
+	return int32(total), nil
+}
+
+func (p *linkPageResolver) Limit() int32 {
+	return int32(p.limit)
+}
+
+func (p *linkPageResolver) Offset() int32 {
+	return int32(p.offset)
+}
+
+type recommendationPageResolver struct {
+	items      []*linkResolver
+	nextCursor *string
+	source     string
+}
+
+func (p *recommendationPageResolver) Items() []*linkResolver {
+	return p.items
+}
+
+func (p *recommendationPageResolver) NextCursor() *string {
+	return p.nextCursor
+}
+
+func (p *recommendationPageResolver) Source() string {
+	return p.source
+}
+
+type linkResolver struct {
+	s    *Server
+	id   pgtype.UUID
+	link linkResponse
+	// loaded reports whether link already carries its tags and highlights.
+	// Recommendation rows do not, so they are fetched only when selected.
+	loaded bool
+}
+
+func (l *linkResolver) ID() graphql.ID {
+	return graphql.ID(l.link.ID)
+}
+
+func (l *linkResolver) URL() string {
+	return l.link.URL
+}
+
+func (l *linkResolver) Title() string {
+	return l.link.Title
+}
+
+func (l *linkResolver) SourceDomain() string {
+	return l.link.SourceDomain
+}
+
+func (l *linkResolver) Favorite() bool {
+	return l.link.Favorite
+}
+
+func (l *linkResolver) CreatedAt() graphql.Time {
+	return graphql.Time{Time: l.link.CreatedAt}
+}
+
+func (l *linkResolver) ReadAt() *graphql.Time {
+	if l.link.ReadAt == nil {
+		return nil
+	}
+	return &graphql.Time{Time: *l.link.ReadAt}
+}
+
+func (l *linkResolver) ArchiveTitle() string {
+	return l.link.ArchiveTitle
+}
+
+func (l *linkResolver) Byline() string {
+	return l.link.Byline
+}
+
+func (l *linkResolver) Lang() string {
+	return l.link.Lang
+}
+
+func (l *linkResolver) WordCount() int32 {
+	return int32(l.link.WordCount)
+}
+
+func (l *linkResolver) ExtractedText() string {
+	return l.link.ExtractedText
+}
+
+func (l *linkResolver) Paywalled() bool {
+	return l.link.Paywalled
+}
+
+func (l *linkResolver) Tags(ctx context.Context) ([]*tagResolver, error) {
+	tags := l.link.Tags
+	if !l.loaded {
+		items, err := l.s.reads().ListTagsForLink(ctx, l.id)
+		if err != nil {
+			return nil, graphQLInternalError("list link tags", err)
+		}
+		tags = make([]tagResponse, 0, len(items))
+		for _, item := range items {
+			tags = append(tags, tagResponse{ID: item.ID, Name: item.Name})
+		}
+	}
+
+	resolvers := make([]*tagResolver, 0, len(tags))
+	for _, tag := range tags {
+		resolvers = append(resolvers, &tagResolver{tag: tag})
+	}
+	return resolvers, nil
+}
+
+func (l *linkResolver) Highlights(ctx context.Context) ([]*highlightResolver, error) {
+	highlights := l.link.Highlights
+	if !l.loaded {
+		items, err := l.s.reads().ListHighlightsByLink(ctx, l.id)
+		if err != nil {
+			return nil, graphQLInternalError("list highlights", err)
+		}
+		highlights = make([]highlightResponse, 0, len(items))
+		for _, item := range items {
+			highlights = append(highlights, toHighlightResponse(item))
+		}
+	}
+
+	resolvers := make([]*highlightResolver, 0, len(highlights))
+	for _, highlight := range highlights {
+		resolvers = append(resolvers, &highlightResolver{highlight: highlight})
+	}
+	return resolvers, nil
+}
+
+type tagResolver struct {
+	tag tagResponse
+}
+
+func (t *tagResolver) ID() int32 {
+	return t.tag.ID
+}
+
+func (t *tagResolver) Name() string {
+	return t.tag.Name
+}
+
+func (t *tagResolver) LinkCount() *int32 {
+	return t.tag.LinkCount
+}
+
+type highlightResolver struct {
+	highlight highlightResponse
+}
+
+func (h *highlightResolver) ID() graphql.ID {
+	return graphql.ID(h.highlight.ID)
+}
+
+func (h *highlightResolver) Text() string {
+	return h.highlight.Text
+}
+
+func (h *highlightResolver) Note() *string {
+	return h.highlight.Note
+}
+
+func (h *highlightResolver) CreatedAt() graphql.Time {
+	return graphql.Time{Time: h.highlight.CreatedAt}
+}
+
+func (h *highlightResolver) UpdatedAt() graphql.Time {
+	return graphql.Time{Time: h.highlight.UpdatedAt}
+}
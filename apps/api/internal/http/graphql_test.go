@@ -0,0 +1,184 @@
+package httpapi
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/labstack/echo/v4"
+
+	"github.com/example/keepstack/apps/api/internal/config"
+	"github.com/example/keepstack/apps/api/internal/db"
+)
+
+type graphQLTestResponse struct {
+	Data   json.RawMessage `json:"data"`
+	Errors []struct {
+		Message string `json:"message"`
+	} `json:"errors"`
+}
+
+func execGraphQL(t *testing.T, srv *Server, query string) graphQLTestResponse {
+	t.Helper()
+
+	e := echo.New()
+	srv.RegisterRoutes(e)
+
+	body, err := json.Marshal(map[string]string{"query": query})
+	if err != nil {
+		t.Fatalf("encode request: %v", err)
+	}
+	req := httptest.NewRequest(http.MethodPost, "/api/graphql", strings.NewReader(string(body)))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+	}
+	var resp graphQLTestResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	return resp
+}
+
+func TestGraphQLLinksWithNestedSelection(t *testing.T) {
+	t.Parallel()
+
+	cfg := config.Config{DevUserID: uuid.MustParse("cccccccc-cccc-cccc-cccc-cccccccccccc")}
+	linkID := uuid.MustParse("dddddddd-dddd-dddd-dddd-dddddddddddd")
+	queries := &mockQueries{
+		getTagByNameFn: func(ctx context.Context, arg db.GetTagByNameParams) (db.Tag, error) {
+			return db.Tag{ID: 7, Name: arg.Name}, nil
+		},
+		listLinksWithTagsFn: func(ctx context.Context, params db.ListLinksWithTagsParams) ([]db.ListLinksWithTagsRow, error) {
+			if len(params.TagIds) != 1 || params.TagIds[0] != 7 {
+				t.Fatalf("expected tag filter [7], got %v", params.TagIds)
+			}
+			if params.PageLimit != 5 || !params.Favorite.Valid || !params.Favorite.Bool {
+				t.Fatalf("unexpected params: %+v", params)
+			}
+			return []db.ListLinksWithTagsRow{{
+				ID:         uuidToPg(linkID),
+				UserID:     uuidToPg(cfg.DevUserID),
+				Url:        "https://example.com/a",
+				Title:      pgtype.Text{String: "Example", Valid: true},
+				CreatedAt:  pgtype.Timestamptz{Time: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC), Valid: true},
+				Favorite:   true,
+				TagIds:     []int32{7},
+				TagNames:   []string{"go"},
+				Highlights: `[{"id":"h1","text":"quoted","note":null,"created_at":"2024-01-02T03:04:05Z","updated_at":"2024-01-02T03:04:05Z"}]`,
+			}}, nil
+		},
+	}
+	srv := &Server{cfg: cfg, queries: queries, metrics: newTestMetrics()}
+
+	// totalCount is not selected, so the count query must not run.
+	resp := execGraphQL(t, srv, `{
+		links(limit: 5, favorite: true, tags: ["go"]) {
+			limit
+			items { id title createdAt tags { id name } highlights { text note } }
+		}
+	}`)
+	if len(resp.Errors) > 0 {
+		t.Fatalf("unexpected errors: %+v", resp.Errors)
+	}
+
+	var data struct {
+		Links struct {
+			Limit int `json:"limit"`
+			Items []struct {
+				ID         string `json:"id"`
+				Title      string `json:"title"`
+				CreatedAt  string `json:"createdAt"`
+				Tags       []tagResponse
+				Highlights []struct {
+					Text string  `json:"text"`
+					Note *string `json:"note"`
+				} `json:"highlights"`
+			} `json:"items"`
+		} `json:"links"`
+	}
+	if err := json.Unmarshal(resp.Data, &data); err != nil {
+		t.Fatalf("decode data: %v", err)
+	}
+	if data.Links.Limit != 5 || len(data.Links.Items) != 1 {
+		t.Fatalf("unexpected page: %+v", data.Links)
+	}
+	item := data.Links.Items[0]
+	if item.ID != linkID.String() || item.Title != "Example" || item.CreatedAt != "2024-01-02T03:04:05Z" {
+		t.Fatalf("unexpected link: %+v", item)
+	}
+	if len(item.Tags) != 1 || item.Tags[0].ID != 7 || item.Tags[0].Name != "go" {
+		t.Fatalf("unexpected tags: %+v", item.Tags)
+	}
+	if len(item.Highlights) != 1 || item.Highlights[0].Text != "quoted" || item.Highlights[0].Note != nil {
+		t.Fatalf("unexpected highlights: %+v", item.Highlights)
+	}
+}
+
+func TestGraphQLRecommendationsLoadNestedFieldsOnDemand(t *testing.T) {
+	t.Parallel()
+
+	linkID := uuid.MustParse("eeeeeeee-eeee-eeee-eeee-eeeeeeeeeeee")
+	var tagLookups int
+	queries := &mockQueries{
+		listRecommendationsForUserFn: func(ctx context.Context, params db.ListRecommendationsForUserParams) ([]db.ListRecommendationsForUserRow, error) {
+			return []db.ListRecommendationsForUserRow{{
+				ID:    uuidToPg(linkID),
+				Url:   "https://example.com/b",
+				Score: 3,
+			}}, nil
+		},
+		listTagsForLinkFn: func(ctx context.Context, id pgtype.UUID) ([]db.Tag, error) {
+			tagLookups++
+			return []db.Tag{{ID: 2, Name: "read-later"}}, nil
+		},
+	}
+	srv := &Server{cfg: config.Config{}, queries: queries, metrics: newTestMetrics()}
+
+	// highlights are not selected, so ListHighlightsByLink must not run.
+	resp := execGraphQL(t, srv, `{ recommendations { source nextCursor items { url tags { name } } } }`)
+	if len(resp.Errors) > 0 {
+		t.Fatalf("unexpected errors: %+v", resp.Errors)
+	}
+	want := `{"recommendations":{"source":"resurfacer","nextCursor":null,"items":[{"url":"https://example.com/b","tags":[{"name":"read-later"}]}]}}`
+	if string(resp.Data) != want {
+		t.Fatalf("expected %s, got %s", want, resp.Data)
+	}
+	if tagLookups != 1 {
+		t.Fatalf("expected one tag lookup, got %d", tagLookups)
+	}
+}
+
+func TestGraphQLReportsInvalidArguments(t *testing.T) {
+	t.Parallel()
+
+	queries := &mockQueries{
+		getTagByNameFn: func(ctx context.Context, arg db.GetTagByNameParams) (db.Tag, error) {
+			return db.Tag{}, pgx.ErrNoRows
+		},
+	}
+	srv := &Server{cfg: config.Config{}, queries: queries, metrics: newTestMetrics()}
+
+	cases := map[string]string{
+		`{ links(limit: 0) { limit } }`:                  "limit must be a positive integer",
+		`{ links(tags: ["missing"]) { limit } }`:         unknownTagError{name: "missing"}.Error(),
+		`{ recommendations(cursor: "nope") { source } }`: "invalid cursor",
+		`{ links { items { id } } nope }`:                `Cannot query field "nope" on type "Query".`,
+	}
+	for query, want := range cases {
+		resp := execGraphQL(t, srv, query)
+		if len(resp.Errors) != 1 || resp.Errors[0].Message != want {
+			t.Fatalf("query %s: expected error %q, got %+v", query, want, resp.Errors)
+		}
+	}
+}
@@ -2,6 +2,7 @@ package httpapi
 
 import (
 	"context"
+	"encoding/base64"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -14,6 +15,7 @@ import (
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 	"unicode/utf8"
 
@@ -25,14 +27,20 @@ import (
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/echo/v4/middleware"
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"golang.org/x/sync/singleflight"
 	"golang.org/x/time/rate"
 
 	"github.com/example/keepstack/apps/api/internal/config"
+	"github.com/example/keepstack/apps/api/internal/cronlock"
 	"github.com/example/keepstack/apps/api/internal/db"
 	"github.com/example/keepstack/apps/api/internal/digest"
 	"github.com/example/keepstack/apps/api/internal/observability"
 	"github.com/example/keepstack/apps/api/internal/queue"
+	"github.com/example/keepstack/health"
+	"github.com/example/keepstack/media"
+	"github.com/example/keepstack/observe"
 )
 
 // Server wires together HTTP handlers and dependencies.
@@ -43,22 +51,116 @@ type queryProvider interface {
 	CountLinks(context.Context, db.CountLinksParams) (int64, error)
 	CountLinksWithTags(context.Context, db.CountLinksWithTagsParams) (int64, error)
 	UpdateLinkFavorite(context.Context, db.UpdateLinkFavoriteParams) (db.UpdateLinkFavoriteRow, error)
+	MarkLinksRead(context.Context, db.MarkLinksReadParams) ([]pgtype.UUID, error)
+	MarkLinksReadAt(context.Context, db.MarkLinksReadAtParams) ([]pgtype.UUID, error)
+	UpdateLinkSnooze(context.Context, db.UpdateLinkSnoozeParams) error
 	ListRecommendationsForUser(context.Context, db.ListRecommendationsForUserParams) ([]db.ListRecommendationsForUserRow, error)
+	ListOnThisDayLinksForUser(context.Context, db.ListOnThisDayLinksForUserParams) ([]db.ListOnThisDayLinksForUserRow, error)
+	ListColdStartLinksForUser(context.Context, db.ListColdStartLinksForUserParams) ([]db.ListColdStartLinksForUserRow, error)
 	CreateClaim(context.Context, db.CreateClaimParams) (db.CreateClaimRow, error)
-	GetTagByName(context.Context, string) (db.Tag, error)
-	ListTagLinkCounts(context.Context) ([]db.ListTagLinkCountsRow, error)
-	CreateTag(context.Context, string) (db.Tag, error)
-	GetTag(context.Context, int32) (db.Tag, error)
+	ListClaimsForUser(context.Context, pgtype.UUID) ([]db.ListClaimsForUserRow, error)
+	DeleteClaim(context.Context, db.DeleteClaimParams) (int64, error)
+	GetTagByName(context.Context, db.GetTagByNameParams) (db.Tag, error)
+	ListTagLinkCounts(context.Context, pgtype.UUID) ([]db.ListTagLinkCountsRow, error)
+	CreateTag(context.Context, db.CreateTagParams) (db.Tag, error)
+	GetTag(context.Context, db.GetTagParams) (db.Tag, error)
 	UpdateTag(context.Context, db.UpdateTagParams) (db.Tag, error)
-	DeleteTag(context.Context, int32) error
+	DeleteTag(context.Context, db.DeleteTagParams) error
 	ListTagsForLink(context.Context, pgtype.UUID) ([]db.Tag, error)
 	AddTagToLink(context.Context, db.AddTagToLinkParams) error
 	RemoveTagFromLink(context.Context, db.RemoveTagFromLinkParams) error
 	GetLink(context.Context, pgtype.UUID) (db.GetLinkRow, error)
+	GetReaderArchive(context.Context, pgtype.UUID) (db.GetReaderArchiveRow, error)
+	ListLinkMediaHashes(context.Context, pgtype.UUID) ([]string, error)
 	ListHighlightsByLink(context.Context, pgtype.UUID) ([]db.Highlight, error)
 	CreateHighlight(context.Context, db.CreateHighlightParams) (db.Highlight, error)
 	UpdateHighlight(context.Context, db.UpdateHighlightParams) (db.Highlight, error)
 	DeleteHighlight(context.Context, pgtype.UUID) error
+	ListBackupRuns(context.Context, db.ListBackupRunsParams) ([]db.BackupRun, error)
+	ListCronRuns(context.Context, db.ListCronRunsParams) ([]db.CronRun, error)
+	CreateCronRun(context.Context, string) (db.CronRun, error)
+	FinishCronRun(context.Context, db.FinishCronRunParams) error
+	CreateUser(context.Context, db.CreateUserParams) (db.User, error)
+	GetLinkIngestStatus(context.Context, pgtype.UUID) (db.LinkIngestStatus, error)
+	FindLinkByURLHash(context.Context, db.FindLinkByURLHashParams) (db.FindLinkByURLHashRow, error)
+	UpsertLinkCapture(context.Context, db.UpsertLinkCaptureParams) error
+	SearchTagsByPrefix(context.Context, db.SearchTagsByPrefixParams) ([]db.Tag, error)
+	GetActivityPubActor(context.Context, pgtype.UUID) (db.ActivitypubActor, error)
+	GetActivityPubActorByUsername(context.Context, string) (db.ActivitypubActor, error)
+	UpsertActivityPubActor(context.Context, db.UpsertActivityPubActorParams) (db.ActivitypubActor, error)
+	DeleteActivityPubActor(context.Context, pgtype.UUID) error
+	AddActivityPubFollower(context.Context, db.AddActivityPubFollowerParams) error
+	RemoveActivityPubFollower(context.Context, db.RemoveActivityPubFollowerParams) error
+	CountActivityPubFollowers(context.Context, pgtype.UUID) (int64, error)
+	ListActivityPubFollowerInboxes(context.Context, pgtype.UUID) ([]string, error)
+	SetLinkPublic(context.Context, db.SetLinkPublicParams) (db.SetLinkPublicRow, error)
+	GetPublicLink(context.Context, pgtype.UUID) (db.GetPublicLinkRow, error)
+	ListPublicLinks(context.Context, db.ListPublicLinksParams) ([]db.ListPublicLinksRow, error)
+	CountPublicLinks(context.Context, pgtype.UUID) (int64, error)
+	ListSyncChanges(context.Context, db.ListSyncChangesParams) ([]db.ListSyncChangesRow, error)
+	GetSyncChangeSince(context.Context, db.GetSyncChangeSinceParams) (bool, error)
+	ListSyncLinks(context.Context, db.ListSyncLinksParams) ([]db.ListSyncLinksRow, error)
+	ListSyncHighlights(context.Context, db.ListSyncHighlightsParams) ([]db.Highlight, error)
+	UpdateSyncLink(context.Context, db.UpdateSyncLinkParams) (int64, error)
+	DeleteLink(context.Context, db.DeleteLinkParams) (int64, error)
+	CreateInboundHook(context.Context, db.CreateInboundHookParams) (db.InboundHook, error)
+	ListInboundHooks(context.Context, pgtype.UUID) ([]db.InboundHook, error)
+	GetInboundHookByTokenHash(context.Context, string) (db.InboundHook, error)
+	TouchInboundHook(context.Context, pgtype.UUID) error
+	DeleteInboundHook(context.Context, db.DeleteInboundHookParams) (int64, error)
+	GetUserIDByEmail(context.Context, string) (pgtype.UUID, error)
+	GetUserByEmail(context.Context, string) (db.User, error)
+	UpsertLinkShare(context.Context, db.UpsertLinkShareParams) (db.LinkShare, error)
+	ListLinkShares(context.Context, pgtype.UUID) ([]db.ListLinkSharesRow, error)
+	DeleteLinkShare(context.Context, db.DeleteLinkShareParams) (int64, error)
+	GetLinkSharePermission(context.Context, db.GetLinkSharePermissionParams) (string, error)
+	ListLinksSharedWithUser(context.Context, pgtype.UUID) ([]db.ListLinksSharedWithUserRow, error)
+	CreateNotification(context.Context, db.CreateNotificationParams) (db.Notification, error)
+	CreateAdminNotifications(context.Context, db.CreateAdminNotificationsParams) (int64, error)
+	ListNotifications(context.Context, db.ListNotificationsParams) ([]db.Notification, error)
+	CountUnreadNotifications(context.Context, pgtype.UUID) (int64, error)
+	MarkNotificationRead(context.Context, db.MarkNotificationReadParams) (int64, error)
+	MarkAllNotificationsRead(context.Context, pgtype.UUID) (int64, error)
+	UpsertReadingGoal(context.Context, db.UpsertReadingGoalParams) (db.ReadingGoal, error)
+	ListReadingGoals(context.Context, pgtype.UUID) ([]db.ReadingGoal, error)
+	DeleteReadingGoal(context.Context, db.DeleteReadingGoalParams) (int64, error)
+	GetReadingProgress(context.Context, db.GetReadingProgressParams) (db.GetReadingProgressRow, error)
+	ListReadDays(context.Context, pgtype.UUID) ([]pgtype.Date, error)
+	ListSimilarLinks(context.Context, db.ListSimilarLinksParams) ([]db.ListSimilarLinksRow, error)
+	UpsertDomainPreference(context.Context, db.UpsertDomainPreferenceParams) (db.DomainPreference, error)
+	ListDomainPreferences(context.Context, pgtype.UUID) ([]db.DomainPreference, error)
+	MatchDomainPreference(context.Context, db.MatchDomainPreferenceParams) (db.DomainPreference, error)
+	DeleteDomainPreference(context.Context, db.DeleteDomainPreferenceParams) (int64, error)
+	TakeDailyQuota(context.Context, db.TakeDailyQuotaParams) (int32, error)
+	DeleteUsageQuotasBefore(context.Context, pgtype.Date) (int64, error)
+	ApplyTagToMatchingLinks(context.Context, db.ApplyTagToMatchingLinksParams) (db.ApplyTagToMatchingLinksRow, error)
+	ListArchiveVersions(context.Context, pgtype.UUID) ([]db.ListArchiveVersionsRow, error)
+	GetArchiveVersion(context.Context, db.GetArchiveVersionParams) (db.ArchiveVersion, error)
+	GetCurrentArchiveVersion(context.Context, pgtype.UUID) (db.GetCurrentArchiveVersionRow, error)
+	GetPublicProfile(context.Context, pgtype.UUID) (db.PublicProfile, error)
+	GetPublicProfileByUsername(context.Context, string) (db.PublicProfile, error)
+	UpsertPublicProfile(context.Context, db.UpsertPublicProfileParams) (db.PublicProfile, error)
+	DeletePublicProfile(context.Context, pgtype.UUID) (int64, error)
+	ListPublicFavorites(context.Context, db.ListPublicFavoritesParams) ([]db.ListPublicFavoritesRow, error)
+	RecordRecommendationImpressions(context.Context, db.RecordRecommendationImpressionsParams) (int64, error)
+	MarkRecommendationImpressionsActed(context.Context, db.MarkRecommendationImpressionsActedParams) ([]string, error)
+	ListRecommendationImpressionStats(context.Context, db.ListRecommendationImpressionStatsParams) ([]db.ListRecommendationImpressionStatsRow, error)
+	CreateJob(context.Context, db.CreateJobParams) (db.Job, error)
+	GetJob(context.Context, db.GetJobParams) (db.Job, error)
+	UpdateJobProgress(context.Context, db.UpdateJobProgressParams) error
+	FinishJob(context.Context, db.FinishJobParams) error
+	SaveJobFile(context.Context, db.SaveJobFileParams) error
+	GetJobFile(context.Context, db.GetJobFileParams) (db.JobFile, error)
+	DeleteJobsFinishedBefore(context.Context, pgtype.Timestamptz) (int64, error)
+}
+
+// readinessColumns lists, per table, columns added by migrations the API
+// cannot serve without, so a pod running ahead of its schema fails
+// readiness instead of failing requests.
+var readinessColumns = map[string][]string{
+	"links":      {"source_domain"},
+	"archives":   {"title", "byline", "lang", "word_count"},
+	"highlights": nil,
 }
 
 type healthPool interface {
@@ -75,13 +177,73 @@ type Server struct {
 	publisher queue.Publisher
 	metrics   *observability.Metrics
 
-	highlightLimiters  map[uuid.UUID]*rate.Limiter
-	highlightLimiterMu sync.Mutex
-	highlightRate      rate.Limit
-	highlightBurst     int
+	// readQueries serves list, search, and count queries that tolerate
+	// replication lag. Nil means everything goes through queries.
+	readQueries queryProvider
+
+	// errorReporter receives panics and 5xx responses. Nil disables reporting.
+	errorReporter *observe.ErrorReporter
+
+	// highlightLimiter throttles highlight creation per user. Nil disables
+	// the limit.
+	highlightLimiter Limiter
+
+	// quotaSweptOn is the UTC day this replica last deleted past days'
+	// quota counters.
+	quotaSweepMu sync.Mutex
+	quotaSweptOn time.Time
+
+	// publicInboxClientLimiter and publicInboxLimiter throttle anonymous
+	// suggestions per client address and overall. captcha, when set, must
+	// accept each one.
+	publicInboxClientLimiter Limiter
+	publicInboxLimiter       Limiter
+	captcha                  captchaVerifier
+
+	// unfurler previews pages through the workers; unfurls caches its
+	// results and unfurlGroup merges concurrent requests for one URL. A nil
+	// unfurler disables GET /api/unfurl.
+	unfurler    unfurler
+	unfurls     *unfurlCache
+	unfurlGroup singleflight.Group
+
+	// expander lists the links on index pages through the workers. A nil
+	// expander disables POST /api/links/expand.
+	expander expander
+
+	// trackingRules adds the configured tracking parameter rules to the
+	// built-in list. Nil uses the built-in list alone.
+	trackingRules *trackingRules
+
+	// reader sanitizes archives for the reader page with the configured
+	// policy. Nil uses sanitize.DefaultFeatures.
+	reader *readerSanitizer
+
+	// media holds the images the worker mirrored out of archives. Nil
+	// disables GET /api/media/:hash.
+	media media.Store
+
+	// schemaVersion is the newest migration this build ships. Readiness
+	// fails while the database is behind it. Zero skips the check.
+	schemaVersion int64
+
+	// events feeds the live update streams open on this replica.
+	events *EventBroker
+
+	// activityPub federates public links to remote followers. Nil disables
+	// ActivityPub.
+	activityPub    activityPubClient
+	activityPubKey string
 
 	digestConfigLoader   func() (digest.Config, error)
 	digestServiceFactory func(digest.Config) (digestService, error)
+
+	// adminJobs are the cron jobs /api/admin/jobs/:name can start, each
+	// under the advisory lock lockJob takes.
+	adminJobs map[string]adminJob
+	lockJob   func(context.Context, string) (*cronlock.Lock, error)
+
+	draining atomic.Bool
 }
 
 type digestService interface {
@@ -90,167 +252,276 @@ type digestService interface {
 
 // NewServer builds a Server instance.
 func NewServer(cfg config.Config, pool *pgxpool.Pool, publisher queue.Publisher, metrics *observability.Metrics) *Server {
-	return &Server{
+	s := &Server{
 		cfg:                cfg,
 		pool:               pool,
 		queries:            db.New(pool),
 		publisher:          publisher,
 		metrics:            metrics,
-		highlightLimiters:  make(map[uuid.UUID]*rate.Limiter),
-		highlightRate:      rate.Every(time.Minute / 20),
-		highlightBurst:     10,
+		highlightLimiter:   NewLocalLimiter(highlightRateLimit, rateLimiterIdleTTL),
+		unfurls:            newUnfurlCache(cfg.UnfurlCacheSize, cfg.UnfurlCacheTTL),
+		events:             NewEventBroker(),
+		trackingRules:      newTrackingRules(cfg.TrackingParamsStrip, cfg.TrackingParamsKeep),
+		reader:             newReaderSanitizer(cfg.Sanitize()),
 		digestConfigLoader: digest.LoadConfig,
 		digestServiceFactory: func(cfg digest.Config) (digestService, error) {
 			return digest.New(pool, cfg)
 		},
+		lockJob: func(ctx context.Context, name string) (*cronlock.Lock, error) {
+			return cronlock.Acquire(ctx, cfg.DatabaseURL, name)
+		},
 	}
+	s.adminJobs = s.defaultAdminJobs(pool)
+	if cfg.PublicInboxUserID != uuid.Nil {
+		client, global := s.publicInboxRules()
+		s.publicInboxClientLimiter = NewLocalLimiter(client, rateLimiterIdleTTL)
+		s.publicInboxLimiter = NewLocalLimiter(global, rateLimiterIdleTTL)
+	}
+	return s
+}
+
+// WithReadDB routes list, search, and count queries through dbtx, typically
+// a replica.Router, while writes and read-after-write lookups stay on the
+// primary.
+func (s *Server) WithReadDB(dbtx db.DBTX) {
+	s.readQueries = db.New(dbtx)
+}
+
+// WithSchemaVersion makes /healthz report unready until the database has
+// applied migration version, so an image never serves an older schema.
+func (s *Server) WithSchemaVersion(version int64) {
+	s.schemaVersion = version
+}
+
+// WithErrorReporter sends recovered panics and 5xx responses to reporter.
+func (s *Server) WithErrorReporter(reporter *observe.ErrorReporter) {
+	s.errorReporter = reporter
+}
+
+// WithSharedHighlightLimits keeps highlight rate limit buckets in Postgres so
+// the per-user limit holds across API replicas.
+func (s *Server) WithSharedHighlightLimits(logger *log.Logger) {
+	s.highlightLimiter = NewPostgresLimiter(db.New(s.pool), "highlight:", highlightRateLimit, rateLimiterIdleTTL, logger)
+}
+
+func (s *Server) reads() queryProvider {
+	if s.readQueries != nil {
+		return s.readQueries
+	}
+	return s.queries
+}
+
+// metricsHandler serves the default registry and negotiates OpenMetrics, the
+// only format that carries exemplars.
+func metricsHandler() stdhttp.Handler {
+	return promhttp.InstrumentMetricHandler(prometheus.DefaultRegisterer,
+		promhttp.HandlerFor(prometheus.DefaultGatherer, promhttp.HandlerOpts{EnableOpenMetrics: true}))
 }
 
 // RegisterRoutes attaches routes to the provided Echo router.
 func (s *Server) RegisterRoutes(e *echo.Echo) {
 	e.HideBanner = true
-	e.Use(middleware.Recover())
+	e.JSONSerializer = jsonSerializer{strict: s.cfg.HTTPStrictJSON}
+	e.HTTPErrorHandler = s.handleHTTPError
+	e.Use(middleware.RequestID())
+	e.Use(middleware.RecoverWithConfig(middleware.RecoverConfig{LogErrorFunc: s.reportPanic}))
 	e.Use(middleware.Logger())
+	e.Use(TracingMiddleware())
+	e.Use(SecurityHeadersMiddleware(s.securityConfig()))
+	e.Use(CORSMiddleware(s.securityConfig()))
+	e.Use(ExtensionCORSMiddleware())
+	e.Use(ErrorReportingMiddleware(s.errorReporter))
 	e.Use(MetricsMiddleware(s.metrics))
+	e.Use(RateLimitMiddleware(s.rateLimitConfig(), s.metrics))
+	e.Use(RequestTimeoutMiddleware(s.cfg.HTTPRequestTimeout, s.metrics))
+	e.Use(BodyLimitMiddleware(s.cfg.HTTPMaxBodyBytes))
+	e.Use(CompressMiddleware(s.cfg.HTTPCompressionLevel))
 
 	e.GET("/healthz", s.handleHealthz)
 	e.GET("/livez", s.handleLivez)
-	e.GET("/metrics", echo.WrapHandler(promhttp.Handler()))
+	e.GET("/metrics", echo.WrapHandler(metricsHandler()))
 
 	api := e.Group("/api")
 	api.GET("/healthz", s.handleHealthz)
 	api.GET("/livez", s.handleLivez)
-	api.POST("/links", s.handleCreateLink)
-	api.GET("/links", s.handleListLinks)
-	api.PATCH("/links/:id", s.handleUpdateLink)
-	api.GET("/recommendations", s.handleListRecommendations)
-	api.POST("/claims", s.handleCreateClaim)
-	api.POST("/digest/:user", s.handleDigestDryRun)
-
-	api.GET("/tags", s.handleListTags)
-	api.POST("/tags", s.handleCreateTag)
-	api.GET("/tags/:id", s.handleGetTag)
-	api.PUT("/tags/:id", s.handleUpdateTag)
-	api.DELETE("/tags/:id", s.handleDeleteTag)
-
-	api.GET("/links/:id/tags", s.handleListLinkTags)
-	api.POST("/links/:id/tags", s.handleAddLinkTag)
-	api.PUT("/links/:id/tags", s.handleReplaceLinkTags)
-	api.DELETE("/links/:id/tags", s.handleClearLinkTags)
-
-	api.GET("/links/:id/highlights", s.handleListHighlights)
-	api.POST("/links/:id/highlights", s.handleCreateHighlight)
-	api.PUT("/links/:id/highlights/:highlightID", s.handleUpdateHighlight)
-	api.DELETE("/links/:id/highlights/:highlightID", s.handleDeleteHighlight)
+
+	user := s.registerAuthRoutes(api)
+	user.POST("/links", s.handleCreateLink)
+	user.GET("/links", s.handleListLinks)
+	user.GET("/links/export", s.handleExportLinks)
+	user.POST("/links/export", s.handleCreateExportJob)
+	user.POST("/links/import", s.handleCreateImportJob)
+	user.POST("/links/reparse", s.handleCreateReparseJob)
+	user.GET("/links/stream", s.handleStreamLinks)
+	user.PATCH("/links/:id", s.handleUpdateLink)
+	user.DELETE("/links/:id", s.handleDeleteLink)
+	user.POST("/links/read", s.handleMarkLinksRead)
+	user.POST("/links/expand", s.handleExpandLink)
+	user.POST("/links/:id/snooze", s.handleSnoozeLink)
+	user.DELETE("/links/:id/snooze", s.handleUnsnoozeLink)
+	user.GET("/links/:id/status", s.handleGetLinkStatus)
+	user.GET("/links/:id/archive/versions", s.handleListArchiveVersions)
+	user.GET("/links/:id/archive/diff", s.handleDiffArchiveVersions)
+	user.GET("/recommendations", s.handleListRecommendations)
+	user.GET("/recommendations/on-this-day", s.handleListOnThisDay)
+	user.GET("/claims", s.handleListClaims)
+	user.POST("/claims", s.handleCreateClaim)
+	user.DELETE("/claims/:id", s.handleReleaseClaim)
+	user.POST("/digest/:user", s.handleDigestDryRun)
+	user.POST("/graphql", s.graphQLHandler())
+	user.GET("/events", s.handleEvents)
+	user.GET("/unfurl", s.handleUnfurl)
+	user.GET("/urls/normalize", s.handlePreviewNormalizedURL)
+	user.GET("/jobs/:id", s.handleGetJob)
+	user.GET("/jobs/:id/result", s.handleGetJobResult)
+	api.GET("/media/:hash", s.handleGetMedia)
+
+	revalidate := conditionalGET(revalidateCacheControl)
+	user.GET("/tags", s.handleListTags, revalidate)
+	user.POST("/tags", s.handleCreateTag)
+	user.GET("/tags/:id", s.handleGetTag, revalidate)
+	user.PUT("/tags/:id", s.handleUpdateTag)
+	user.DELETE("/tags/:id", s.handleDeleteTag)
+	user.POST("/tags/:id/apply", s.handleApplyTag)
+
+	user.GET("/links/:id/tags", s.handleListLinkTags, revalidate)
+	user.POST("/links/:id/tags", s.handleAddLinkTag)
+	user.PUT("/links/:id/tags", s.handleReplaceLinkTags)
+	user.DELETE("/links/:id/tags", s.handleClearLinkTags)
+
+	user.GET("/links/:id/highlights", s.handleListHighlights)
+	user.POST("/links/:id/highlights", s.handleCreateHighlight)
+	user.PUT("/links/:id/highlights/:highlightID", s.handleUpdateHighlight)
+	user.DELETE("/links/:id/highlights/:highlightID", s.handleDeleteHighlight)
+
+	user.GET("/links/:id/shares", s.handleListLinkShares)
+	user.POST("/links/:id/shares", s.handleShareLink)
+	user.DELETE("/links/:id/shares/:userID", s.handleUnshareLink)
+	user.GET("/shared", s.handleListSharedWithMe)
+
+	user.GET("/notifications", s.handleListNotifications)
+	user.GET("/notifications/unread-count", s.handleCountUnreadNotifications)
+	user.POST("/notifications/read", s.handleMarkAllNotificationsRead)
+	user.POST("/notifications/:id/read", s.handleMarkNotificationRead)
+
+	user.GET("/goals", s.handleListGoals)
+	user.PUT("/goals/:metric", s.handleSetGoal)
+	user.DELETE("/goals/:metric", s.handleDeleteGoal)
+	user.GET("/stats", s.handleStats)
+
+	user.GET("/domain-preferences", s.handleListDomainPreferences)
+	user.PUT("/domain-preferences/:domain", s.handleSetDomainPreference)
+	user.DELETE("/domain-preferences/:domain", s.handleDeleteDomainPreference)
+
+	s.registerReaderRoutes(e, user)
+	s.registerExtensionRoutes(api)
+	s.registerMicropubRoutes(api)
+	s.registerActivityPubRoutes(e, api, user)
+	s.registerAdminRoutes(api)
+	s.registerSyncRoutes(user)
+	s.registerHookRoutes(api, user)
+	s.registerPublicInboxRoutes(api)
+	s.registerPublicFavoritesRoutes(e, user)
+	s.registerImpressionRoutes(user)
+}
+
+// BeginDrain makes /healthz report the pod as not ready so Kubernetes stops
+// routing traffic while in-flight requests finish, and ends live update
+// streams so clients reconnect elsewhere. Liveness is unaffected.
+func (s *Server) BeginDrain() {
+	s.draining.Store(true)
+	if s.events != nil {
+		s.events.Close()
+	}
 }
 
 func (s *Server) handleHealthz(c echo.Context) error {
+	if s.draining.Load() {
+		return c.JSON(stdhttp.StatusServiceUnavailable, map[string]string{"status": "draining"})
+	}
+
+	if s.pool == nil {
+		return c.JSON(stdhttp.StatusOK, map[string]string{"status": "ok", "mode": "memory"})
+	}
+
 	ctx, cancel := context.WithTimeout(c.Request().Context(), 2*time.Second)
 	defer cancel()
 
-	checks := []struct {
-		name string
-		run  func(context.Context) error
-	}{
-		{
-			name: "postgres connectivity",
-			run: func(ctx context.Context) error {
-				_, err := s.pool.Exec(ctx, "SELECT 1")
-				return err
-			},
-		},
-		{
-			name: "links table",
-			run: func(ctx context.Context) error {
-				var count int
-				if err := s.pool.QueryRow(ctx, "SELECT COUNT(1) FROM links").Scan(&count); err != nil {
-					return err
-				}
-				return nil
-			},
-		},
-		{
-			name: "links source_domain column",
-			run: func(ctx context.Context) error {
-				return runReadinessQuery(ctx, s.pool, "SELECT source_domain FROM links LIMIT 0")
-			},
-		},
-		{
-			name: "archives metadata columns",
-			run: func(ctx context.Context) error {
-				return runReadinessQuery(ctx, s.pool, "SELECT title, byline, lang, word_count FROM archives LIMIT 0")
-			},
-		},
-		{
-			name: "highlights table",
-			run: func(ctx context.Context) error {
-				return runReadinessQuery(ctx, s.pool, "SELECT 1 FROM highlights LIMIT 1")
-			},
-		},
+	checks := []health.Check{
+		health.Postgres(s.pool),
+		health.Schema(s.pool, readinessColumns),
+	}
+	if s.schemaVersion > 0 {
+		checks = append(checks, health.SchemaVersion(s.pool, s.schemaVersion))
 	}
 
-	for _, check := range checks {
-		if err := check.run(ctx); err != nil {
-			s.metrics.ReadinessFailure.Inc()
+	if check, err := health.First(ctx, checks); err != nil {
+		s.metrics.ReadinessFailure.Inc()
 
-			message, migrationGap := classifyReadinessError(err)
-			if migrationGap {
-				s.metrics.ReadinessMigrationGap.Inc()
-			}
+		message, migrationGap := classifyReadinessError(err)
+		if migrationGap {
+			s.metrics.ReadinessMigrationGap.Inc()
+		}
 
-			c.Logger().Errorf("readiness check: %s failed: %v", check.name, err)
+		c.Logger().Errorf("readiness check: %s failed: %v", check.Name, err)
 
-			response := map[string]string{
-				"status": "unhealthy",
-				"error":  message,
-			}
-			if migrationGap {
-				response["hint"] = "apply outstanding database migrations"
-			}
+		response := map[string]string{
+			"status": "unhealthy",
+			"error":  message,
+		}
+		if migrationGap {
+			response["hint"] = "apply outstanding database migrations"
+		}
 
-			return c.JSON(stdhttp.StatusServiceUnavailable, response)
+		return c.JSON(stdhttp.StatusServiceUnavailable, response)
+	}
+
+	if s.cfg.HealthBackupMaxAge > 0 {
+		if warning := s.checkBackupFreshness(ctx); warning != "" {
+			return c.JSON(stdhttp.StatusOK, map[string]string{
+				"status":  "degraded",
+				"warning": warning,
+			})
 		}
 	}
 
 	return c.JSON(stdhttp.StatusOK, map[string]string{"status": "ok"})
 }
 
-func runReadinessQuery(ctx context.Context, pool healthPool, query string, args ...any) error {
-	rows, err := pool.Query(ctx, query, args...)
+// checkBackupFreshness returns a warning when the newest successful database
+// backup is older than the configured maximum age. A stale backup does not
+// make the API unready, since pulling every pod would not fix it.
+func (s *Server) checkBackupFreshness(ctx context.Context) string {
+	var latest pgtype.Timestamptz
+	err := s.pool.QueryRow(ctx, "SELECT MAX(finished_at) FROM backup_runs WHERE status = 'success' AND kind <> 'user'").Scan(&latest)
 	if err != nil {
-		return err
+		return fmt.Sprintf("backup freshness unknown: %v", err)
+	}
+	if !latest.Valid {
+		return "no successful backup recorded"
 	}
-	rows.Close()
-	return rows.Err()
+	if time.Since(latest.Time) > s.cfg.HealthBackupMaxAge {
+		return fmt.Sprintf("backup older than %s", formatHours(s.cfg.HealthBackupMaxAge))
+	}
+	return ""
 }
 
-func classifyReadinessError(err error) (string, bool) {
-	var pgErr *pgconn.PgError
-	if errors.As(err, &pgErr) {
-		switch pgErr.Code {
-		case pgerrcode.UndefinedTable:
-			table := pgErr.TableName
-			if table == "" {
-				table = "required"
-			}
-			return fmt.Sprintf("database schema missing table %q", table), true
-		case pgerrcode.UndefinedColumn:
-			column := pgErr.ColumnName
-			if column == "" {
-				column = "required"
-			}
-			table := pgErr.TableName
-			if table != "" {
-				return fmt.Sprintf("database schema missing column %q on table %q", column, table), true
-			}
-			return fmt.Sprintf("database schema missing column %q", column), true
-		}
-	}
+// formatHours renders a duration without trailing zero units, e.g. "48h".
+func formatHours(d time.Duration) string {
+	return strings.TrimSuffix(strings.TrimSuffix(d.String(), "0s"), "0m")
+}
 
-	return err.Error(), false
+// classifyReadinessError returns the message to report for err and whether
+// it means a migration has not been applied.
+func classifyReadinessError(err error) (string, bool) {
+	err = health.Classify(err)
+	return err.Error(), health.IsSchemaError(err)
 }
 
 func (s *Server) handleLivez(c echo.Context) error {
+	if s.pool == nil {
+		return c.JSON(stdhttp.StatusOK, map[string]string{"status": "ok"})
+	}
 	ctx, cancel := context.WithTimeout(c.Request().Context(), 2*time.Second)
 	defer cancel()
 	if err := s.pool.Ping(ctx); err != nil {
@@ -260,9 +531,10 @@ func (s *Server) handleLivez(c echo.Context) error {
 }
 
 type createLinkRequest struct {
-	URL      string  `json:"url"`
-	Title    *string `json:"title"`
-	Favorite *bool   `json:"favorite"`
+	URL           string  `json:"url"`
+	Title         *string `json:"title"`
+	Favorite      *bool   `json:"favorite"`
+	PreserveTitle *bool   `json:"preserve_title"`
 }
 
 type updateLinkRequest struct {
@@ -270,21 +542,38 @@ type updateLinkRequest struct {
 	Tags     []string `json:"tags"`
 }
 
+type snoozeLinkRequest struct {
+	Days *int `json:"days"`
+}
+
+type markLinksReadRequest struct {
+	IDs []string `json:"ids"`
+}
+
 type linkResponse struct {
-	ID            string              `json:"id"`
-	URL           string              `json:"url"`
-	Title         string              `json:"title"`
-	SourceDomain  string              `json:"source_domain"`
-	Favorite      bool                `json:"favorite"`
-	CreatedAt     time.Time           `json:"created_at"`
-	ReadAt        *time.Time          `json:"read_at,omitempty"`
-	ArchiveTitle  string              `json:"archive_title"`
-	Byline        string              `json:"byline"`
-	Lang          string              `json:"lang"`
-	WordCount     int                 `json:"word_count"`
-	ExtractedText string              `json:"extracted_text"`
-	Tags          []tagResponse       `json:"tags"`
-	Highlights    []highlightResponse `json:"highlights"`
+	ID             string              `json:"id"`
+	URL            string              `json:"url"`
+	Title          string              `json:"title"`
+	SourceDomain   string              `json:"source_domain"`
+	Favorite       bool                `json:"favorite"`
+	CreatedAt      time.Time           `json:"created_at"`
+	ReadAt         *time.Time          `json:"read_at,omitempty"`
+	OriginalTitle  string              `json:"original_title,omitempty"`
+	PreserveTitle  bool                `json:"preserve_title"`
+	ArchiveTitle   string              `json:"archive_title"`
+	Byline         string              `json:"byline"`
+	Lang           string              `json:"lang"`
+	WordCount      int                 `json:"word_count"`
+	ExtractedText  string              `json:"extracted_text"`
+	Paywalled      bool                `json:"paywalled"`
+	Tags           []tagResponse       `json:"tags"`
+	Highlights     []highlightResponse `json:"highlights"`
+	HighlightCount int                 `json:"highlight_count"`
+}
+
+type onThisDayResponse struct {
+	linkResponse
+	YearsAgo int `json:"years_ago"`
 }
 
 type tagResponse struct {
@@ -301,16 +590,75 @@ type highlightResponse struct {
 	UpdatedAt time.Time `json:"updated_at"`
 }
 
+type backupRunResponse struct {
+	ID          string     `json:"id"`
+	Status      string     `json:"status"`
+	Kind        string     `json:"kind"`
+	Storage     string     `json:"storage"`
+	StartedAt   time.Time  `json:"started_at"`
+	FinishedAt  *time.Time `json:"finished_at,omitempty"`
+	File        string     `json:"file,omitempty"`
+	Destination string     `json:"destination,omitempty"`
+	SizeBytes   *int64     `json:"size_bytes,omitempty"`
+	Checksum    string     `json:"checksum,omitempty"`
+	Error       string     `json:"error,omitempty"`
+}
+
+type cronRunResponse struct {
+	ID         string          `json:"id"`
+	Subcommand string          `json:"subcommand"`
+	Status     string          `json:"status"`
+	StartedAt  time.Time       `json:"started_at"`
+	FinishedAt *time.Time      `json:"finished_at,omitempty"`
+	DurationMs *int64          `json:"duration_ms,omitempty"`
+	Counts     json.RawMessage `json:"counts"`
+	Error      string          `json:"error,omitempty"`
+}
+
+// linkStatusResponse reports how far the worker got ingesting a link, as
+// reported over the internal gRPC service.
+type linkStatusResponse struct {
+	LinkID      string                     `json:"link_id"`
+	Status      string                     `json:"status"`
+	FailedStage string                     `json:"failed_stage,omitempty"`
+	Error       string                     `json:"error,omitempty"`
+	UpdatedAt   time.Time                  `json:"updated_at"`
+	Diagnostics *ingestDiagnosticsResponse `json:"diagnostics,omitempty"`
+}
+
+type ingestDiagnosticsResponse struct {
+	FinalURL     string    `json:"final_url,omitempty"`
+	FetchBytes   int64     `json:"fetch_bytes"`
+	FetchMs      int32     `json:"fetch_ms"`
+	ParseMs      int32     `json:"parse_ms"`
+	Lang         string    `json:"lang,omitempty"`
+	LangDetected bool      `json:"lang_detected"`
+	WordCount    int32     `json:"word_count"`
+	ReportedAt   time.Time `json:"reported_at"`
+}
+
 type createClaimRequest struct {
-	LinkID string `json:"link_id"`
+	LinkID    string     `json:"link_id"`
+	ExpiresAt *time.Time `json:"expires_at"`
 }
 
 type claimResponse struct {
-	ID        string    `json:"id"`
-	LinkID    string    `json:"link_id"`
-	UserID    string    `json:"user_id"`
-	ClaimedAt time.Time `json:"claimed_at"`
-	Created   bool      `json:"created"`
+	ID        string     `json:"id"`
+	LinkID    string     `json:"link_id"`
+	UserID    string     `json:"user_id"`
+	ClaimedAt time.Time  `json:"claimed_at"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	Created   bool       `json:"created"`
+}
+
+type claimListItem struct {
+	ID        string     `json:"id"`
+	LinkID    string     `json:"link_id"`
+	URL       string     `json:"url"`
+	Title     string     `json:"title"`
+	Shared    bool       `json:"shared"`
+	ClaimedAt time.Time  `json:"claimed_at"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
 }
 
 type listLinksResponse struct {
@@ -354,14 +702,14 @@ func (s *Server) handleCreateLink(c echo.Context) error {
 	if err := c.Bind(&req); err != nil {
 		s.metrics.LinkCreateFailure.Inc()
 		c.Logger().Warnf("create link: bind payload failed: %v", err)
-		return c.JSON(stdhttp.StatusBadRequest, map[string]string{"error": "invalid payload"})
+		return respondBindError(c, err)
 	}
 
-	normalizedURL, err := normalizeURL(strings.TrimSpace(req.URL))
+	normalizedURL, err := s.normalizeLinkURL(strings.TrimSpace(req.URL))
 	if err != nil {
 		s.metrics.LinkCreateFailure.Inc()
 		c.Logger().Warnf("create link: invalid url %q: %v", req.URL, err)
-		return c.JSON(stdhttp.StatusBadRequest, map[string]string{"error": "invalid url"})
+		return respondError(c, stdhttp.StatusBadRequest, "invalid url")
 	}
 
 	linkID := uuid.New()
@@ -373,63 +721,102 @@ func (s *Server) handleCreateLink(c echo.Context) error {
 		}
 	}
 
+	pref, hasPref := s.domainPreference(c, s.currentUser(c), normalizedURL)
 	favorite := pgtype.Bool{}
 	if req.Favorite != nil {
 		favorite = pgtype.Bool{Bool: *req.Favorite, Valid: true}
+	} else if hasPref && pref.AlwaysFavorite {
+		favorite = pgtype.Bool{Bool: true, Valid: true}
 	}
 
 	params := db.CreateLinkParams{
-		ID:       uuidToPg(linkID),
-		UserID:   uuidToPg(s.cfg.DevUserID),
-		Url:      normalizedURL,
-		Title:    title,
-		Favorite: favorite,
+		ID:            uuidToPg(linkID),
+		UserID:        uuidToPg(s.currentUser(c)),
+		Url:           normalizedURL,
+		Title:         title,
+		Favorite:      favorite,
+		PreserveTitle: s.preserveTitle(title, req.PreserveTitle),
+	}
+
+	if err := s.takeQuota(c, quotaLinks, s.currentUser(c)); err != nil {
+		s.metrics.LinkCreateFailure.Inc()
+		return respondWithError(c, err)
 	}
 
 	ctx := c.Request().Context()
+	// Look for near-duplicates before storing so the new link never matches
+	// itself. The check is advisory: a failure only leaves the list empty.
+	similar, err := s.findSimilarLinks(ctx, s.userID(ctx), normalizedURL, title.String)
+	if err != nil {
+		c.Logger().Warnf("create link: find similar links failed: %v", err)
+		similar = []similarLinkResponse{}
+	}
+
 	if _, err := s.queries.CreateLink(ctx, params); err != nil {
 		s.metrics.LinkCreateFailure.Inc()
 		c.Logger().Errorf("create link: store link failed: %v", err)
-		return c.JSON(stdhttp.StatusInternalServerError, map[string]string{"error": "failed to store link"})
+		return respondError(c, stdhttp.StatusInternalServerError, "failed to store link")
+	}
+	if hasPref {
+		if _, err := s.addTagsByName(ctx, s.userID(ctx), linkID, pref.DefaultTags); err != nil {
+			s.metrics.LinkCreateFailure.Inc()
+			c.Logger().Errorf("create link: apply default tags for %s failed: %v", pref.Domain, err)
+			return respondWithError(c, err)
+		}
 	}
 
 	if err := s.publisher.PublishLinkSaved(ctx, linkID); err != nil {
 		s.metrics.LinkCreateFailure.Inc()
 		c.Logger().Errorf("create link: publish link saved failed: %v", err)
-		return c.JSON(stdhttp.StatusInternalServerError, map[string]string{"error": "failed to enqueue link"})
+		return respondError(c, stdhttp.StatusInternalServerError, "failed to enqueue link")
 	}
 
 	s.metrics.LinkCreateSuccess.Inc()
 	c.Logger().Infof("create link: created link %s for %s", linkID, normalizedURL)
-	return c.JSON(stdhttp.StatusCreated, map[string]string{
-		"id":  linkID.String(),
-		"url": normalizedURL,
+	return c.JSON(stdhttp.StatusCreated, map[string]any{
+		"id":            linkID.String(),
+		"url":           normalizedURL,
+		"similar_links": similar,
 	})
 }
 
+// preserveTitle reports whether a link saved with title keeps it through
+// ingestion, as requested or else by the server default. A link saved
+// without a title has nothing to keep.
+func (s *Server) preserveTitle(title pgtype.Text, requested *bool) pgtype.Bool {
+	if !title.Valid {
+		return pgtype.Bool{}
+	}
+	preserve := s.cfg.PreserveUserTitles
+	if requested != nil {
+		preserve = *requested
+	}
+	return pgtype.Bool{Bool: preserve, Valid: true}
+}
+
 func (s *Server) handleDigestDryRun(c echo.Context) error {
 	if s.digestConfigLoader == nil || s.digestServiceFactory == nil {
 		c.Logger().Error("digest dry-run: service factory unavailable")
-		return c.JSON(stdhttp.StatusServiceUnavailable, map[string]string{"error": "digest not configured"})
+		return respondError(c, stdhttp.StatusServiceUnavailable, "digest not configured")
 	}
 
 	var req digestDryRunRequest
 	if err := c.Bind(&req); err != nil && !errors.Is(err, echo.ErrUnsupportedMediaType) && !errors.Is(err, io.EOF) {
 		c.Logger().Warnf("digest dry-run: bind payload failed: %v", err)
-		return c.JSON(stdhttp.StatusBadRequest, map[string]string{"error": "invalid payload"})
+		return respondBindError(c, err)
 	}
 
 	cfg, err := s.digestConfigLoader()
 	if err != nil {
 		c.Logger().Errorf("digest dry-run: load config failed: %v", err)
-		return c.JSON(stdhttp.StatusInternalServerError, map[string]string{"error": "failed to load digest config"})
+		return respondError(c, stdhttp.StatusInternalServerError, "failed to load digest config")
 	}
 
 	if req.Transport != "" {
 		transport, err := digest.ParseSMTPURL(req.Transport)
 		if err != nil {
 			c.Logger().Warnf("digest dry-run: invalid transport override %q: %v", req.Transport, err)
-			return c.JSON(stdhttp.StatusBadRequest, map[string]string{"error": "invalid transport"})
+			return respondError(c, stdhttp.StatusBadRequest, "invalid transport")
 		}
 		cfg.Transport = transport
 		cfg.SMTPURL = req.Transport
@@ -438,20 +825,20 @@ func (s *Server) handleDigestDryRun(c echo.Context) error {
 	svc, err := s.digestServiceFactory(cfg)
 	if err != nil {
 		c.Logger().Errorf("digest dry-run: instantiate service failed: %v", err)
-		return c.JSON(stdhttp.StatusInternalServerError, map[string]string{"error": "failed to prepare digest service"})
+		return respondError(c, stdhttp.StatusInternalServerError, "failed to prepare digest service")
 	}
 
 	ctx, cancel := context.WithTimeout(c.Request().Context(), 30*time.Second)
 	defer cancel()
 
-	count, htmlBody, err := svc.Send(ctx, s.cfg.DevUserID)
+	count, htmlBody, err := svc.Send(ctx, s.userID(ctx))
 	if err != nil {
 		if errors.Is(err, digest.ErrNoUnreadLinks) {
 			c.Logger().Info("digest dry-run: no unread links for dev user")
 			return c.JSON(stdhttp.StatusOK, map[string]string{"status": "no unread links"})
 		}
 		c.Logger().Errorf("digest dry-run: send failed: %v", err)
-		return c.JSON(stdhttp.StatusInternalServerError, map[string]string{"error": "failed to build digest"})
+		return respondError(c, stdhttp.StatusInternalServerError, "failed to build digest")
 	}
 
 	c.Logger().Infof("digest dry-run: generated digest with %d unread links", count)
@@ -463,21 +850,21 @@ func (s *Server) handleUpdateLink(c echo.Context) error {
 	linkID, err := parseUUIDParam(c.Param("id"))
 	if err != nil {
 		s.metrics.LinkUpdateFailure.Inc()
-		return c.JSON(stdhttp.StatusBadRequest, map[string]string{"error": "invalid link id"})
+		return respondError(c, stdhttp.StatusBadRequest, "invalid link id")
 	}
 
 	var req updateLinkRequest
 	if err := c.Bind(&req); err != nil {
 		s.metrics.LinkUpdateFailure.Inc()
-		return c.JSON(stdhttp.StatusBadRequest, map[string]string{"error": "invalid payload"})
+		return respondBindError(c, err)
 	}
 
 	if req.Favorite == nil {
 		s.metrics.LinkUpdateFailure.Inc()
-		return c.JSON(stdhttp.StatusBadRequest, map[string]string{"error": "favorite is required"})
+		return respondError(c, stdhttp.StatusBadRequest, "favorite is required")
 	}
 
-	if _, err := s.ensureLinkAccess(c.Request().Context(), linkID); err != nil {
+	if _, err := s.ensureLinkAccess(c.Request().Context(), linkID, linkPermissionOwner); err != nil {
 		s.metrics.LinkUpdateFailure.Inc()
 		return respondWithError(c, err)
 	}
@@ -489,10 +876,10 @@ func (s *Server) handleUpdateLink(c echo.Context) error {
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			s.metrics.LinkUpdateFailure.Inc()
-			return c.JSON(stdhttp.StatusNotFound, map[string]string{"error": "link not found"})
+			return respondError(c, stdhttp.StatusNotFound, "link not found")
 		}
 		s.metrics.LinkUpdateFailure.Inc()
-		return c.JSON(stdhttp.StatusInternalServerError, map[string]string{"error": "failed to update link"})
+		return respondError(c, stdhttp.StatusInternalServerError, "failed to update link")
 	}
 
 	response, err := toLinkResponse(db.ListLinksRow{
@@ -504,57 +891,271 @@ func (s *Server) handleUpdateLink(c echo.Context) error {
 		CreatedAt:     row.CreatedAt,
 		ReadAt:        row.ReadAt,
 		Favorite:      row.Favorite,
+		OriginalTitle: row.OriginalTitle,
+		PreserveTitle: row.PreserveTitle,
 		ArchiveTitle:  row.ArchiveTitle,
 		ArchiveByline: row.ArchiveByline,
 		Lang:          row.Lang,
 		WordCount:     row.WordCount,
 		ExtractedText: row.ExtractedText,
+		Paywalled:     row.Paywalled,
 		TagIds:        row.TagIds,
 		TagNames:      row.TagNames,
 		Highlights:    row.Highlights,
 	})
 	if err != nil {
 		s.metrics.LinkUpdateFailure.Inc()
-		return c.JSON(stdhttp.StatusInternalServerError, map[string]string{"error": "failed to format link"})
+		return respondError(c, stdhttp.StatusInternalServerError, "failed to format link")
 	}
 
+	if row.Favorite {
+		s.recordRecommendationAction(c, []pgtype.UUID{row.ID}, impressionActionFavorite)
+	}
 	s.metrics.LinkUpdateSuccess.Inc()
 	return c.JSON(stdhttp.StatusOK, response)
 }
 
+// handleDeleteLink removes a link along with its archive, tags, and
+// highlights.
+func (s *Server) handleDeleteLink(c echo.Context) error {
+	linkID, err := parseUUIDParam(c.Param("id"))
+	if err != nil {
+		s.metrics.LinkDeleteFailure.Inc()
+		return respondError(c, stdhttp.StatusBadRequest, "invalid link id")
+	}
+
+	deleted, err := s.deleteLink(c, linkID)
+	if err != nil {
+		s.metrics.LinkDeleteFailure.Inc()
+		c.Logger().Errorf("delete link %s: %v", linkID, err)
+		return respondError(c, stdhttp.StatusInternalServerError, "failed to delete link")
+	}
+	if !deleted {
+		s.metrics.LinkDeleteFailure.Inc()
+		return respondError(c, stdhttp.StatusNotFound, "link not found")
+	}
+
+	s.metrics.LinkDeleteSuccess.Inc()
+	s.publishEvent(c, queue.EventLinkDeleted, linkID.String(), nil)
+	return c.NoContent(stdhttp.StatusNoContent)
+}
+
+// deleteLink removes one of the current user's links and reports whether
+// there was one to remove. The worker is then told which mirrored images the
+// link referenced, so it can drop those no other link references; a failed
+// publish only leaves them in media storage, so it is logged.
+func (s *Server) deleteLink(c echo.Context, linkID uuid.UUID) (bool, error) {
+	ctx := c.Request().Context()
+	mediaHashes, err := s.queries.ListLinkMediaHashes(ctx, uuidToPg(linkID))
+	if err != nil {
+		return false, fmt.Errorf("list link media: %w", err)
+	}
+
+	deleted, err := s.queries.DeleteLink(ctx, db.DeleteLinkParams{
+		ID:     uuidToPg(linkID),
+		UserID: uuidToPg(s.userID(ctx)),
+	})
+	if err != nil {
+		return false, fmt.Errorf("delete link: %w", err)
+	}
+	if deleted == 0 {
+		return false, nil
+	}
+
+	if s.publisher != nil {
+		if err := s.publisher.PublishLinkDeleted(ctx, linkID, mediaHashes); err != nil {
+			c.Logger().Errorf("publish link deleted %s: %v", linkID, err)
+		}
+	}
+	return true, nil
+}
+
+// defaultSnoozeDays is used when a snooze request does not specify a duration.
+const defaultSnoozeDays = 7
+
+// handleSnoozeLink hides a link from the resurfacer for the requested number of days.
+func (s *Server) handleSnoozeLink(c echo.Context) error {
+	linkID, err := parseUUIDParam(c.Param("id"))
+	if err != nil {
+		s.metrics.LinkUpdateFailure.Inc()
+		return respondError(c, stdhttp.StatusBadRequest, "invalid link id")
+	}
+
+	var req snoozeLinkRequest
+	if err := c.Bind(&req); err != nil && !errors.Is(err, io.EOF) {
+		s.metrics.LinkUpdateFailure.Inc()
+		return respondBindError(c, err)
+	}
+
+	days := defaultSnoozeDays
+	if req.Days != nil {
+		days = *req.Days
+	}
+	if days < 1 || days > 365 {
+		s.metrics.LinkUpdateFailure.Inc()
+		return respondError(c, stdhttp.StatusBadRequest, "days must be between 1 and 365")
+	}
+
+	ctx := c.Request().Context()
+	if _, err := s.ensureLinkAccess(ctx, linkID, linkPermissionOwner); err != nil {
+		s.metrics.LinkUpdateFailure.Inc()
+		return respondWithError(c, err)
+	}
+
+	until := time.Now().UTC().AddDate(0, 0, days)
+	if err := s.queries.UpdateLinkSnooze(ctx, db.UpdateLinkSnoozeParams{
+		SnoozedUntil: pgtype.Timestamptz{Time: until, Valid: true},
+		ID:           uuidToPg(linkID),
+	}); err != nil {
+		s.metrics.LinkUpdateFailure.Inc()
+		c.Logger().Errorf("snooze link: update %s failed: %v", linkID, err)
+		return respondError(c, stdhttp.StatusInternalServerError, "failed to snooze link")
+	}
+
+	s.metrics.LinkUpdateSuccess.Inc()
+	return c.JSON(stdhttp.StatusOK, map[string]any{
+		"id":            linkID.String(),
+		"snoozed_until": until,
+	})
+}
+
+func (s *Server) handleUnsnoozeLink(c echo.Context) error {
+	linkID, err := parseUUIDParam(c.Param("id"))
+	if err != nil {
+		s.metrics.LinkUpdateFailure.Inc()
+		return respondError(c, stdhttp.StatusBadRequest, "invalid link id")
+	}
+
+	ctx := c.Request().Context()
+	if _, err := s.ensureLinkAccess(ctx, linkID, linkPermissionOwner); err != nil {
+		s.metrics.LinkUpdateFailure.Inc()
+		return respondWithError(c, err)
+	}
+
+	if err := s.queries.UpdateLinkSnooze(ctx, db.UpdateLinkSnoozeParams{ID: uuidToPg(linkID)}); err != nil {
+		s.metrics.LinkUpdateFailure.Inc()
+		c.Logger().Errorf("unsnooze link: update %s failed: %v", linkID, err)
+		return respondError(c, stdhttp.StatusInternalServerError, "failed to clear snooze")
+	}
+
+	s.metrics.LinkUpdateSuccess.Inc()
+	return c.NoContent(stdhttp.StatusNoContent)
+}
+
+// maxMarkReadBatch bounds how many links a single mark-read request may touch.
+const maxMarkReadBatch = 500
+
+func (s *Server) handleMarkLinksRead(c echo.Context) error {
+	var req markLinksReadRequest
+	if err := c.Bind(&req); err != nil {
+		s.metrics.LinkUpdateFailure.Inc()
+		return respondBindError(c, err)
+	}
+
+	if len(req.IDs) == 0 {
+		s.metrics.LinkUpdateFailure.Inc()
+		return respondError(c, stdhttp.StatusBadRequest, "ids are required")
+	}
+	if len(req.IDs) > maxMarkReadBatch {
+		s.metrics.LinkUpdateFailure.Inc()
+		return respondError(c, stdhttp.StatusBadRequest, fmt.Sprintf("at most %d ids may be marked read at once", maxMarkReadBatch))
+	}
+
+	ids := make([]pgtype.UUID, 0, len(req.IDs))
+	for _, raw := range req.IDs {
+		id, err := parseUUIDParam(raw)
+		if err != nil {
+			s.metrics.LinkUpdateFailure.Inc()
+			return respondError(c, stdhttp.StatusBadRequest, fmt.Sprintf("invalid link id: %s", raw))
+		}
+		ids = append(ids, uuidToPg(id))
+	}
+
+	ctx := c.Request().Context()
+	updated, err := s.queries.MarkLinksRead(ctx, db.MarkLinksReadParams{
+		UserID: uuidToPg(s.userID(ctx)),
+		Ids:    ids,
+	})
+	if err != nil {
+		s.metrics.LinkUpdateFailure.Inc()
+		c.Logger().Errorf("mark links read: update failed: %v", err)
+		return respondError(c, stdhttp.StatusInternalServerError, "failed to mark links read")
+	}
+
+	s.recordRecommendationAction(c, updated, impressionActionRead)
+	queued := s.queueRecommendationsRefresh(c, s.userID(ctx), len(updated))
+
+	s.metrics.LinkUpdateSuccess.Inc()
+	return c.JSON(stdhttp.StatusOK, map[string]any{
+		"updated":        len(updated),
+		"refresh_queued": queued,
+	})
+}
+
+// queueRecommendationsRefresh publishes a single-user resurfacer job once a
+// request has changed at least RecommendationRefreshThreshold links. Publish
+// failures are logged only; the nightly resurfacer still catches up.
+func (s *Server) queueRecommendationsRefresh(c echo.Context, userID uuid.UUID, changed int) bool {
+	threshold := s.cfg.RecommendationRefreshThreshold
+	if threshold <= 0 || changed < threshold || s.publisher == nil {
+		return false
+	}
+
+	if err := s.publisher.PublishRecommendationsRefresh(c.Request().Context(), userID); err != nil {
+		s.metrics.ResurfaceFailure.Inc()
+		c.Logger().Warnf("recommendations refresh: publish for %s failed: %v", userID, err)
+		return false
+	}
+
+	s.metrics.ResurfaceQueued.Inc()
+	c.Logger().Infof("recommendations refresh: queued for %s after %d links changed", userID, changed)
+	return true
+}
+
 func (s *Server) handleCreateClaim(c echo.Context) error {
 	var req createClaimRequest
 	if err := c.Bind(&req); err != nil {
 		s.metrics.ClaimCreateFailure.Inc()
-		return c.JSON(stdhttp.StatusBadRequest, map[string]string{"error": "invalid payload"})
+		return respondBindError(c, err)
 	}
 
 	linkIDRaw := strings.TrimSpace(req.LinkID)
 	if linkIDRaw == "" {
 		s.metrics.ClaimCreateFailure.Inc()
-		return c.JSON(stdhttp.StatusBadRequest, map[string]string{"error": "link_id is required"})
+		return respondError(c, stdhttp.StatusBadRequest, "link_id is required")
 	}
 
 	linkID, err := uuid.Parse(linkIDRaw)
 	if err != nil {
 		s.metrics.ClaimCreateFailure.Inc()
-		return c.JSON(stdhttp.StatusBadRequest, map[string]string{"error": "invalid link_id"})
+		return respondError(c, stdhttp.StatusBadRequest, "invalid link_id")
 	}
 
+	var expiresAt pgtype.Timestamptz
+	if req.ExpiresAt != nil {
+		if !req.ExpiresAt.After(time.Now()) {
+			s.metrics.ClaimCreateFailure.Inc()
+			return respondError(c, stdhttp.StatusBadRequest, "expires_at must be in the future")
+		}
+		expiresAt = pgtype.Timestamptz{Time: req.ExpiresAt.UTC(), Valid: true}
+	}
+
+	// Anyone a link is shared with may claim it, so a shared queue can show
+	// who is reading what.
 	ctx := c.Request().Context()
-	link, err := s.ensureLinkAccess(ctx, linkID)
-	if err != nil {
+	if _, err := s.ensureLinkAccess(ctx, linkID, linkPermissionRead); err != nil {
 		s.metrics.ClaimCreateFailure.Inc()
 		return respondWithError(c, err)
 	}
 
 	result, err := s.queries.CreateClaim(ctx, db.CreateClaimParams{
-		LinkID: uuidToPg(linkID),
-		UserID: link.UserID,
+		LinkID:    uuidToPg(linkID),
+		UserID:    uuidToPg(s.userID(ctx)),
+		ExpiresAt: expiresAt,
 	})
 	if err != nil {
 		s.metrics.ClaimCreateFailure.Inc()
-		return c.JSON(stdhttp.StatusInternalServerError, map[string]string{"error": "failed to record claim"})
+		return respondError(c, stdhttp.StatusInternalServerError, "failed to record claim")
 	}
 
 	claimedAt := time.Now()
@@ -567,6 +1168,7 @@ func (s *Server) handleCreateClaim(c echo.Context) error {
 		LinkID:    uuidFromPg(result.LinkID).String(),
 		UserID:    uuidFromPg(result.UserID).String(),
 		ClaimedAt: claimedAt,
+		ExpiresAt: timestampPtr(result.ExpiresAt),
 		Created:   result.Inserted,
 	}
 
@@ -579,6 +1181,49 @@ func (s *Server) handleCreateClaim(c echo.Context) error {
 	return c.JSON(status, response)
 }
 
+// handleListClaims lists the caller's unexpired claims, newest first,
+// including claims on links other users share with them.
+func (s *Server) handleListClaims(c echo.Context) error {
+	rows, err := s.queries.ListClaimsForUser(c.Request().Context(), uuidToPg(s.currentUser(c)))
+	if err != nil {
+		c.Logger().Errorf("list claims: query failed: %v", err)
+		return respondError(c, stdhttp.StatusInternalServerError, "failed to list claims")
+	}
+	items := make([]claimListItem, 0, len(rows))
+	for _, row := range rows {
+		items = append(items, claimListItem{
+			ID:        uuidFromPg(row.ID).String(),
+			LinkID:    uuidFromPg(row.LinkID).String(),
+			URL:       row.Url,
+			Title:     row.Title.String,
+			Shared:    uuidFromPg(row.OwnerID) != s.currentUser(c),
+			ClaimedAt: row.ClaimedAt.Time,
+			ExpiresAt: timestampPtr(row.ExpiresAt),
+		})
+	}
+	return c.JSON(stdhttp.StatusOK, map[string]any{"items": items})
+}
+
+// handleReleaseClaim releases one of the caller's claims.
+func (s *Server) handleReleaseClaim(c echo.Context) error {
+	id, err := parseUUIDParam(c.Param("id"))
+	if err != nil {
+		return respondError(c, stdhttp.StatusBadRequest, "invalid claim id")
+	}
+	deleted, err := s.queries.DeleteClaim(c.Request().Context(), db.DeleteClaimParams{
+		ID:     uuidToPg(id),
+		UserID: uuidToPg(s.currentUser(c)),
+	})
+	if err != nil {
+		c.Logger().Errorf("release claim: delete failed: %v", err)
+		return respondError(c, stdhttp.StatusInternalServerError, "failed to release claim")
+	}
+	if deleted == 0 {
+		return respondError(c, stdhttp.StatusNotFound, "claim not found")
+	}
+	return c.NoContent(stdhttp.StatusNoContent)
+}
+
 func (s *Server) handleListLinks(c echo.Context) error {
 	ctx := c.Request().Context()
 	rawLimit := c.QueryParam("limit")
@@ -587,7 +1232,7 @@ func (s *Server) handleListLinks(c echo.Context) error {
 	if err != nil {
 		s.metrics.LinkListFailure.Inc()
 		c.Logger().Errorf("list links: failed to parse pagination (limit=%q offset=%q): %v", rawLimit, rawOffset, err)
-		return c.JSON(stdhttp.StatusBadRequest, map[string]string{"error": err.Error()})
+		return respondError(c, stdhttp.StatusBadRequest, err.Error())
 	}
 
 	favoriteParam := strings.TrimSpace(c.QueryParam("favorite"))
@@ -598,7 +1243,7 @@ func (s *Server) handleListLinks(c echo.Context) error {
 		if err != nil {
 			s.metrics.LinkListFailure.Inc()
 			c.Logger().Errorf("list links: invalid favorite filter %q: %v", favoriteParam, err)
-			return c.JSON(stdhttp.StatusBadRequest, map[string]string{"error": "favorite must be boolean"})
+			return respondError(c, stdhttp.StatusBadRequest, "favorite must be boolean")
 		}
 		favoriteFilter = pgtype.Bool{Bool: parsed, Valid: true}
 		favoriteLogValue = strconv.FormatBool(parsed)
@@ -610,49 +1255,69 @@ func (s *Server) handleListLinks(c echo.Context) error {
 		queryFilter = pgtype.Text{String: queryText, Valid: true}
 	}
 
-	tagsParam := strings.TrimSpace(c.QueryParam("tags"))
-	var tagIDs []int32
-	if tagsParam != "" {
-		seen := make(map[string]struct{})
-		for _, part := range strings.Split(tagsParam, ",") {
-			name := strings.TrimSpace(part)
-			if name == "" {
-				continue
-			}
-			if _, ok := seen[strings.ToLower(name)]; ok {
-				continue
-			}
-			seen[strings.ToLower(name)] = struct{}{}
+	highlightsParam := strings.TrimSpace(c.QueryParam("has_highlights"))
+	highlightsFilter := pgtype.Bool{}
+	if highlightsParam != "" {
+		parsed, err := strconv.ParseBool(highlightsParam)
+		if err != nil {
+			s.metrics.LinkListFailure.Inc()
+			return respondError(c, stdhttp.StatusBadRequest, "has_highlights must be boolean")
+		}
+		highlightsFilter = pgtype.Bool{Bool: parsed, Valid: true}
+	}
 
-			tag, err := s.queries.GetTagByName(ctx, name)
-			if err != nil {
-				if errors.Is(err, pgx.ErrNoRows) {
-					s.metrics.LinkListFailure.Inc()
-					c.Logger().Errorf("list links: unknown tag %q in filter (limit=%d offset=%d favorite=%s query=%q)", name, limit, offset, favoriteParam, queryText)
-					return c.JSON(stdhttp.StatusBadRequest, map[string]string{"error": fmt.Sprintf("unknown tag: %s", name)})
-				}
-				s.metrics.LinkListFailure.Inc()
-				c.Logger().Errorf("list links: failed to resolve tag %q (limit=%d offset=%d favorite=%s query=%q tags=%q): %v", name, limit, offset, favoriteParam, queryText, tagsParam, err)
-				return c.JSON(stdhttp.StatusInternalServerError, map[string]string{"error": "failed to resolve tags"})
-			}
-			tagIDs = append(tagIDs, tag.ID)
+	claimedFilter := pgtype.UUID{}
+	switch claimedParam := strings.TrimSpace(c.QueryParam("claimed")); claimedParam {
+	case "":
+	case "me":
+		claimedFilter = uuidToPg(s.userID(ctx))
+	default:
+		s.metrics.LinkListFailure.Inc()
+		return respondError(c, stdhttp.StatusBadRequest, "claimed must be me")
+	}
+
+	sortRelevance := false
+	switch sortParam := strings.TrimSpace(c.QueryParam("sort")); sortParam {
+	case "", "created_at":
+	case "relevance":
+		sortRelevance = true
+	default:
+		s.metrics.LinkListFailure.Inc()
+		return respondError(c, stdhttp.StatusBadRequest, "sort must be created_at or relevance")
+	}
+
+	tagsParam := strings.TrimSpace(c.QueryParam("tags"))
+	tagIDs, err := s.resolveTagFilter(ctx, tagsParam)
+	if err != nil {
+		s.metrics.LinkListFailure.Inc()
+		var unknown unknownTagError
+		if errors.As(err, &unknown) {
+			c.Logger().Errorf("list links: unknown tag %q in filter (limit=%d offset=%d favorite=%s query=%q)", unknown.name, limit, offset, favoriteParam, queryText)
+			return respondError(c, stdhttp.StatusBadRequest, unknown.Error())
 		}
+		c.Logger().Errorf("list links: failed to resolve tags (limit=%d offset=%d favorite=%s query=%q tags=%q): %v", limit, offset, favoriteParam, queryText, tagsParam, err)
+		return respondError(c, stdhttp.StatusInternalServerError, "failed to resolve tags")
 	}
 
 	listParams := db.ListLinksParams{
-		UserID:         uuidToPg(s.cfg.DevUserID),
+		UserID:         uuidToPg(s.userID(ctx)),
 		Favorite:       favoriteFilter,
 		Query:          queryFilter,
 		EnableFullText: true,
+		ClaimedBy:      claimedFilter,
+		HasHighlights:  highlightsFilter,
+		SortRelevance:  sortRelevance,
 		PageLimit:      int32(limit),
 		PageOffset:     int32(offset),
 	}
 
 	countParams := db.CountLinksParams{
-		UserID:         uuidToPg(s.cfg.DevUserID),
+		UserID:         uuidToPg(s.userID(ctx)),
 		Favorite:       favoriteFilter,
 		Query:          queryFilter,
 		EnableFullText: true,
+		ClaimedBy:      claimedFilter,
+		HasHighlights:  highlightsFilter,
 	}
 
 	var (
@@ -661,7 +1326,7 @@ func (s *Server) handleListLinks(c echo.Context) error {
 	)
 
 	if len(tagIDs) == 0 {
-		items, err := s.queries.ListLinks(ctx, listParams)
+		items, err := s.reads().ListLinks(ctx, listParams)
 		if err != nil {
 			if listParams.EnableFullText && isFullTextParseError(err) {
 				c.Logger().Warnf(
@@ -670,7 +1335,7 @@ func (s *Server) handleListLinks(c echo.Context) error {
 				)
 				listParams.EnableFullText = false
 				countParams.EnableFullText = false
-				items, err = s.queries.ListLinks(ctx, listParams)
+				items, err = s.reads().ListLinks(ctx, listParams)
 			}
 
 			if err != nil {
@@ -692,19 +1357,18 @@ func (s *Server) handleListLinks(c echo.Context) error {
 				c.Logger().Errorf(logTemplate, logArgs...)
 
 				if migrationGap {
-					return c.JSON(stdhttp.StatusServiceUnavailable, map[string]string{
-						"error": migrationMessage,
-						"hint":  "apply outstanding database migrations",
+					return respondErrorCode(c, stdhttp.StatusServiceUnavailable, errCodeMigrationPending, migrationMessage, map[string]any{
+						"hint": "apply outstanding database migrations",
 					})
 				}
 
-				return c.JSON(stdhttp.StatusInternalServerError, map[string]string{"error": "failed to fetch links"})
+				return respondError(c, stdhttp.StatusInternalServerError, "failed to fetch links")
 			}
 		}
 
 		linkRows = items
 
-		count, err = s.queries.CountLinks(ctx, countParams)
+		count, err = s.reads().CountLinks(ctx, countParams)
 		if err != nil {
 			if countParams.EnableFullText && isFullTextParseError(err) {
 				c.Logger().Warnf(
@@ -712,7 +1376,7 @@ func (s *Server) handleListLinks(c echo.Context) error {
 					queryText, err,
 				)
 				countParams.EnableFullText = false
-				count, err = s.queries.CountLinks(ctx, countParams)
+				count, err = s.reads().CountLinks(ctx, countParams)
 			}
 
 			if err != nil {
@@ -734,35 +1398,39 @@ func (s *Server) handleListLinks(c echo.Context) error {
 				c.Logger().Errorf(logTemplate, logArgs...)
 
 				if migrationGap {
-					return c.JSON(stdhttp.StatusServiceUnavailable, map[string]string{
-						"error": migrationMessage,
-						"hint":  "apply outstanding database migrations",
+					return respondErrorCode(c, stdhttp.StatusServiceUnavailable, errCodeMigrationPending, migrationMessage, map[string]any{
+						"hint": "apply outstanding database migrations",
 					})
 				}
 
-				return c.JSON(stdhttp.StatusInternalServerError, map[string]string{"error": "failed to count links"})
+				return respondError(c, stdhttp.StatusInternalServerError, "failed to count links")
 			}
 		}
 	} else {
 		listWithTagsParams := db.ListLinksWithTagsParams{
 			TagIds:         tagIDs,
-			UserID:         uuidToPg(s.cfg.DevUserID),
+			UserID:         uuidToPg(s.userID(ctx)),
 			Favorite:       favoriteFilter,
 			Query:          queryFilter,
 			EnableFullText: true,
+			ClaimedBy:      claimedFilter,
+			HasHighlights:  highlightsFilter,
+			SortRelevance:  sortRelevance,
 			PageOffset:     int32(offset),
 			PageLimit:      int32(limit),
 		}
 
 		countWithTagsParams := db.CountLinksWithTagsParams{
 			TagIds:         tagIDs,
-			UserID:         uuidToPg(s.cfg.DevUserID),
+			UserID:         uuidToPg(s.userID(ctx)),
 			Favorite:       favoriteFilter,
 			Query:          queryFilter,
 			EnableFullText: true,
+			ClaimedBy:      claimedFilter,
+			HasHighlights:  highlightsFilter,
 		}
 
-		items, err := s.queries.ListLinksWithTags(ctx, listWithTagsParams)
+		items, err := s.reads().ListLinksWithTags(ctx, listWithTagsParams)
 		if err != nil {
 			if listWithTagsParams.EnableFullText && isFullTextParseError(err) {
 				c.Logger().Warnf(
@@ -771,7 +1439,7 @@ func (s *Server) handleListLinks(c echo.Context) error {
 				)
 				listWithTagsParams.EnableFullText = false
 				countWithTagsParams.EnableFullText = false
-				items, err = s.queries.ListLinksWithTags(ctx, listWithTagsParams)
+				items, err = s.reads().ListLinksWithTags(ctx, listWithTagsParams)
 			}
 
 			if err != nil {
@@ -793,19 +1461,18 @@ func (s *Server) handleListLinks(c echo.Context) error {
 				c.Logger().Errorf(logTemplate, logArgs...)
 
 				if migrationGap {
-					return c.JSON(stdhttp.StatusServiceUnavailable, map[string]string{
-						"error": migrationMessage,
-						"hint":  "apply outstanding database migrations",
+					return respondErrorCode(c, stdhttp.StatusServiceUnavailable, errCodeMigrationPending, migrationMessage, map[string]any{
+						"hint": "apply outstanding database migrations",
 					})
 				}
 
-				return c.JSON(stdhttp.StatusInternalServerError, map[string]string{"error": "failed to fetch links"})
+				return respondError(c, stdhttp.StatusInternalServerError, "failed to fetch links")
 			}
 		}
 
 		linkRows = convertListLinksWithTagsRows(items)
 
-		count, err = s.queries.CountLinksWithTags(ctx, countWithTagsParams)
+		count, err = s.reads().CountLinksWithTags(ctx, countWithTagsParams)
 		if err != nil {
 			if countWithTagsParams.EnableFullText && isFullTextParseError(err) {
 				c.Logger().Warnf(
@@ -813,7 +1480,7 @@ func (s *Server) handleListLinks(c echo.Context) error {
 					queryText, err,
 				)
 				countWithTagsParams.EnableFullText = false
-				count, err = s.queries.CountLinksWithTags(ctx, countWithTagsParams)
+				count, err = s.reads().CountLinksWithTags(ctx, countWithTagsParams)
 			}
 
 			if err != nil {
@@ -835,13 +1502,12 @@ func (s *Server) handleListLinks(c echo.Context) error {
 				c.Logger().Errorf(logTemplate, logArgs...)
 
 				if migrationGap {
-					return c.JSON(stdhttp.StatusServiceUnavailable, map[string]string{
-						"error": migrationMessage,
-						"hint":  "apply outstanding database migrations",
+					return respondErrorCode(c, stdhttp.StatusServiceUnavailable, errCodeMigrationPending, migrationMessage, map[string]any{
+						"hint": "apply outstanding database migrations",
 					})
 				}
 
-				return c.JSON(stdhttp.StatusInternalServerError, map[string]string{"error": "failed to count links"})
+				return respondError(c, stdhttp.StatusInternalServerError, "failed to count links")
 			}
 		}
 	}
@@ -855,7 +1521,7 @@ func (s *Server) handleListLinks(c echo.Context) error {
 				"list links: toLinkResponse failed for link %s (limit=%d offset=%d favorite=%v query=%q tags=%q tagIDs=%v): %v",
 				uuidFromPg(item.ID), limit, offset, item.Favorite, queryText, tagsParam, tagIDs, err,
 			)
-			return c.JSON(stdhttp.StatusInternalServerError, map[string]string{"error": "failed to format response"})
+			return respondError(c, stdhttp.StatusInternalServerError, "failed to format response")
 		}
 		responses = append(responses, resp)
 	}
@@ -870,6 +1536,174 @@ func (s *Server) handleListLinks(c echo.Context) error {
 }
 
 func (s *Server) handleListRecommendations(c echo.Context) error {
+	limit, offset, err := parsePagination(strings.TrimSpace(c.QueryParam("limit")), strings.TrimSpace(c.QueryParam("offset")))
+	if err != nil {
+		s.metrics.LinkListFailure.Inc()
+		return respondError(c, stdhttp.StatusBadRequest, err.Error())
+	}
+
+	ctx := c.Request().Context()
+	params := db.ListRecommendationsForUserParams{
+		UserID:     uuidToPg(s.userID(ctx)),
+		PageLimit:  int32(limit + 1),
+		PageOffset: int32(offset),
+	}
+
+	if raw := strings.TrimSpace(c.QueryParam("cursor")); raw != "" {
+		if offset > 0 {
+			s.metrics.LinkListFailure.Inc()
+			return respondError(c, stdhttp.StatusBadRequest, "cursor and offset cannot be combined")
+		}
+		cursor, err := decodeRecommendationCursor(raw)
+		if err != nil {
+			s.metrics.LinkListFailure.Inc()
+			return respondError(c, stdhttp.StatusBadRequest, "invalid cursor")
+		}
+		params.CursorScore = pgtype.Int4{Int32: cursor.score, Valid: true}
+		params.CursorUpdatedAt = pgtype.Timestamptz{Time: cursor.updatedAt, Valid: true}
+		params.CursorLinkID = uuidToPg(cursor.linkID)
+	}
+
+	if domain := strings.ToLower(strings.TrimSpace(c.QueryParam("domain"))); domain != "" {
+		params.Domain = pgtype.Text{String: domain, Valid: true}
+	}
+
+	tagsParam := strings.TrimSpace(c.QueryParam("tags"))
+	tagIDs, err := s.resolveTagFilter(ctx, tagsParam)
+	if err != nil {
+		s.metrics.LinkListFailure.Inc()
+		var unknown unknownTagError
+		if errors.As(err, &unknown) {
+			return respondError(c, stdhttp.StatusBadRequest, unknown.Error())
+		}
+		c.Logger().Errorf("list recommendations: failed to resolve tags %q: %v", tagsParam, err)
+		return respondError(c, stdhttp.StatusInternalServerError, "failed to resolve tags")
+	}
+	params.TagIds = tagIDs
+
+	rows, source, nextCursor, err := s.loadRecommendations(ctx, params, limit)
+	if err != nil {
+		s.metrics.LinkListFailure.Inc()
+		c.Logger().Errorf("list recommendations: %v", err)
+		return respondError(c, stdhttp.StatusInternalServerError, "failed to load recommendations")
+	}
+
+	responses := make([]linkResponse, 0, len(rows))
+	for _, row := range rows {
+		resp, err := s.buildRecommendationResponse(ctx, row)
+		if err != nil {
+			s.metrics.LinkListFailure.Inc()
+			return respondError(c, stdhttp.StatusInternalServerError, "failed to expand recommendations")
+		}
+		responses = append(responses, resp)
+	}
+
+	if err := s.recordImpressions(ctx, impressionSurfaceAPI, rows, offset); err != nil {
+		c.Logger().Warnf("list recommendations: %v", err)
+	}
+	s.metrics.LinkListSuccess.Inc()
+
+	body := map[string]any{
+		"items":  responses,
+		"limit":  limit,
+		"offset": offset,
+		"count":  len(responses),
+		"source": source,
+	}
+	if nextCursor != "" {
+		body["next_cursor"] = nextCursor
+	}
+	return c.JSON(stdhttp.StatusOK, body)
+}
+
+// loadRecommendations runs params, which must ask for one row past limit,
+// and trims the result to limit. An unfiltered first page with no resurfaced
+// links falls back to recent saves. It returns the rows, their source
+// ("resurfacer" or "cold_start"), and the cursor for the next page, if any.
+func (s *Server) loadRecommendations(ctx context.Context, params db.ListRecommendationsForUserParams, limit int) ([]db.ListRecommendationsForUserRow, string, string, error) {
+	rows, err := s.reads().ListRecommendationsForUser(ctx, params)
+	if err != nil {
+		return nil, "", "", err
+	}
+
+	source := "resurfacer"
+	firstUnfilteredPage := params.PageOffset == 0 && !params.CursorScore.Valid && !params.Domain.Valid && len(params.TagIds) == 0
+	if len(rows) == 0 && firstUnfilteredPage {
+		coldStart, err := s.reads().ListColdStartLinksForUser(ctx, db.ListColdStartLinksForUserParams{
+			UserID:       params.UserID,
+			RecentWindow: coldStartWindow,
+			RowLimit:     int32(limit),
+		})
+		if err != nil {
+			return nil, "", "", fmt.Errorf("cold start lookup failed: %w", err)
+		}
+		rows = make([]db.ListRecommendationsForUserRow, 0, len(coldStart))
+		for _, row := range coldStart {
+			rows = append(rows, convertColdStartRow(row))
+		}
+		source = "cold_start"
+	}
+
+	nextCursor := ""
+	if len(rows) > limit {
+		rows = rows[:limit]
+		last := rows[len(rows)-1]
+		nextCursor = encodeRecommendationCursor(recommendationCursor{
+			score:     last.Score,
+			updatedAt: last.UpdatedAt.Time,
+			linkID:    uuidFromPg(last.ID),
+		})
+	}
+	return rows, source, nextCursor, nil
+}
+
+// coldStartWindow is how many of a user's most recent saves are considered when
+// the resurfacer has not produced any recommendations yet.
+const coldStartWindow = 50
+
+// recommendationCursor marks the last recommendation returned on a page using
+// the same (score, updated_at, link_id) ordering as ListRecommendationsForUser.
+type recommendationCursor struct {
+	score     int32
+	updatedAt time.Time
+	linkID    uuid.UUID
+}
+
+func encodeRecommendationCursor(cursor recommendationCursor) string {
+	raw := fmt.Sprintf("%d|%s|%s", cursor.score, cursor.updatedAt.UTC().Format(time.RFC3339Nano), cursor.linkID)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func decodeRecommendationCursor(value string) (recommendationCursor, error) {
+	decoded, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return recommendationCursor{}, err
+	}
+
+	parts := strings.Split(string(decoded), "|")
+	if len(parts) != 3 {
+		return recommendationCursor{}, fmt.Errorf("malformed cursor")
+	}
+
+	score, err := strconv.ParseInt(parts[0], 10, 32)
+	if err != nil {
+		return recommendationCursor{}, fmt.Errorf("parse cursor score: %w", err)
+	}
+	updatedAt, err := time.Parse(time.RFC3339Nano, parts[1])
+	if err != nil {
+		return recommendationCursor{}, fmt.Errorf("parse cursor timestamp: %w", err)
+	}
+	linkID, err := uuid.Parse(parts[2])
+	if err != nil {
+		return recommendationCursor{}, fmt.Errorf("parse cursor link id: %w", err)
+	}
+
+	return recommendationCursor{score: int32(score), updatedAt: updatedAt, linkID: linkID}, nil
+}
+
+// handleListOnThisDay returns links saved on this calendar day one, two, and
+// five years ago. An optional date=YYYY-MM-DD parameter overrides "today".
+func (s *Server) handleListOnThisDay(c echo.Context) error {
 	limit := 20
 	if raw := strings.TrimSpace(c.QueryParam("limit")); raw != "" {
 		if value, err := strconv.Atoi(raw); err == nil && value > 0 {
@@ -880,35 +1714,211 @@ func (s *Server) handleListRecommendations(c echo.Context) error {
 		limit = 100
 	}
 
+	day := time.Now().UTC()
+	if raw := strings.TrimSpace(c.QueryParam("date")); raw != "" {
+		parsed, err := time.Parse(time.DateOnly, raw)
+		if err != nil {
+			return respondError(c, stdhttp.StatusBadRequest, "date must be formatted as YYYY-MM-DD")
+		}
+		day = parsed
+	}
+
+	years := make([]int32, 0, len(digest.OnThisDayYears))
+	for _, value := range digest.OnThisDayYears {
+		years = append(years, int32(value))
+	}
+
 	ctx := c.Request().Context()
-	rows, err := s.queries.ListRecommendationsForUser(ctx, db.ListRecommendationsForUserParams{
-		UserID: uuidToPg(s.cfg.DevUserID),
-		Limit:  int32(limit),
+	rows, err := s.reads().ListOnThisDayLinksForUser(ctx, db.ListOnThisDayLinksForUserParams{
+		Years:    years,
+		OnDate:   pgtype.Date{Time: day, Valid: true},
+		UserID:   uuidToPg(s.userID(ctx)),
+		RowLimit: int32(limit),
 	})
 	if err != nil {
 		s.metrics.LinkListFailure.Inc()
-		return c.JSON(stdhttp.StatusInternalServerError, map[string]string{"error": "failed to load recommendations"})
+		c.Logger().Errorf("on this day: list links failed: %v", err)
+		return respondError(c, stdhttp.StatusInternalServerError, "failed to load on this day links")
 	}
 
-	responses := make([]linkResponse, 0, len(rows))
+	responses := make([]onThisDayResponse, 0, len(rows))
+	shown := make([]db.ListRecommendationsForUserRow, 0, len(rows))
 	for _, row := range rows {
-		resp, err := s.buildRecommendationResponse(ctx, row)
+		converted := convertOnThisDayRow(row)
+		resp, err := s.buildRecommendationResponse(ctx, converted)
 		if err != nil {
 			s.metrics.LinkListFailure.Inc()
-			return c.JSON(stdhttp.StatusInternalServerError, map[string]string{"error": "failed to expand recommendations"})
+			c.Logger().Errorf("on this day: expand link failed: %v", err)
+			return respondError(c, stdhttp.StatusInternalServerError, "failed to expand on this day links")
 		}
-		responses = append(responses, resp)
+		responses = append(responses, onThisDayResponse{linkResponse: resp, YearsAgo: int(row.YearsAgo)})
+		shown = append(shown, converted)
+	}
+
+	if err := s.recordImpressions(ctx, impressionSurfaceAPI, shown, 0); err != nil {
+		c.Logger().Warnf("on this day: %v", err)
 	}
 
 	s.metrics.LinkListSuccess.Inc()
 
 	return c.JSON(stdhttp.StatusOK, map[string]any{
+		"date":  day.Format(time.DateOnly),
 		"items": responses,
 		"limit": limit,
 		"count": len(responses),
 	})
 }
 
+func (s *Server) handleListBackups(c echo.Context) error {
+	limit, offset, err := parsePagination(c.QueryParam("limit"), c.QueryParam("offset"))
+	if err != nil {
+		return respondError(c, stdhttp.StatusBadRequest, err.Error())
+	}
+
+	runs, err := s.queries.ListBackupRuns(c.Request().Context(), db.ListBackupRunsParams{
+		PageLimit:  int32(limit),
+		PageOffset: int32(offset),
+	})
+	if err != nil {
+		c.Logger().Errorf("list backup runs failed: %v", err)
+		return respondError(c, stdhttp.StatusInternalServerError, "failed to list backups")
+	}
+
+	items := make([]backupRunResponse, 0, len(runs))
+	for _, run := range runs {
+		items = append(items, toBackupRunResponse(run))
+	}
+
+	return c.JSON(stdhttp.StatusOK, map[string]any{
+		"items":  items,
+		"limit":  limit,
+		"offset": offset,
+	})
+}
+
+func toBackupRunResponse(run db.BackupRun) backupRunResponse {
+	resp := backupRunResponse{
+		ID:          uuidFromPg(run.ID).String(),
+		Status:      run.Status,
+		Kind:        run.Kind,
+		Storage:     run.Storage,
+		StartedAt:   run.StartedAt.Time,
+		File:        run.File.String,
+		Destination: run.Destination.String,
+		Checksum:    run.Checksum.String,
+		Error:       run.Error.String,
+	}
+	if run.FinishedAt.Valid {
+		finished := run.FinishedAt.Time
+		resp.FinishedAt = &finished
+	}
+	if run.SizeBytes.Valid {
+		size := run.SizeBytes.Int64
+		resp.SizeBytes = &size
+	}
+	return resp
+}
+
+func toLinkStatusResponse(row db.LinkIngestStatus) linkStatusResponse {
+	resp := linkStatusResponse{
+		LinkID:      uuidFromPg(row.LinkID).String(),
+		Status:      row.Status,
+		FailedStage: row.FailedStage.String,
+		Error:       row.Error.String,
+		UpdatedAt:   row.UpdatedAt.Time,
+	}
+	if row.DiagnosticsAt.Valid {
+		resp.Diagnostics = &ingestDiagnosticsResponse{
+			FinalURL:     row.FinalUrl.String,
+			FetchBytes:   row.FetchBytes.Int64,
+			FetchMs:      row.FetchMs.Int32,
+			ParseMs:      row.ParseMs.Int32,
+			Lang:         row.Lang.String,
+			LangDetected: row.LangDetected.Bool,
+			WordCount:    row.WordCount.Int32,
+			ReportedAt:   row.DiagnosticsAt.Time,
+		}
+	}
+	return resp
+}
+
+// handleGetLinkStatus returns the ingest status the worker last reported for
+// a link. Links saved before status reporting existed have none.
+func (s *Server) handleGetLinkStatus(c echo.Context) error {
+	linkID, err := parseUUIDParam(c.Param("id"))
+	if err != nil {
+		return respondError(c, stdhttp.StatusBadRequest, "invalid link id")
+	}
+
+	link, err := s.ensureLinkAccess(c.Request().Context(), linkID, linkPermissionRead)
+	if err != nil {
+		return respondWithError(c, err)
+	}
+
+	row, err := s.queries.GetLinkIngestStatus(c.Request().Context(), link.ID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return respondError(c, stdhttp.StatusNotFound, "no ingest status recorded")
+		}
+		return respondError(c, stdhttp.StatusInternalServerError, "failed to load ingest status")
+	}
+	return c.JSON(stdhttp.StatusOK, toLinkStatusResponse(row))
+}
+
+func (s *Server) handleListJobs(c echo.Context) error {
+	limit, offset, err := parsePagination(c.QueryParam("limit"), c.QueryParam("offset"))
+	if err != nil {
+		return respondError(c, stdhttp.StatusBadRequest, err.Error())
+	}
+
+	subcommand := strings.TrimSpace(c.QueryParam("subcommand"))
+	status := strings.TrimSpace(c.QueryParam("status"))
+	runs, err := s.queries.ListCronRuns(c.Request().Context(), db.ListCronRunsParams{
+		Subcommand: pgtype.Text{String: subcommand, Valid: subcommand != ""},
+		Status:     pgtype.Text{String: status, Valid: status != ""},
+		PageLimit:  int32(limit),
+		PageOffset: int32(offset),
+	})
+	if err != nil {
+		c.Logger().Errorf("list cron runs failed: %v", err)
+		return respondError(c, stdhttp.StatusInternalServerError, "failed to list jobs")
+	}
+
+	items := make([]cronRunResponse, 0, len(runs))
+	for _, run := range runs {
+		items = append(items, toCronRunResponse(run))
+	}
+
+	return c.JSON(stdhttp.StatusOK, map[string]any{
+		"items":  items,
+		"limit":  limit,
+		"offset": offset,
+	})
+}
+
+func toCronRunResponse(run db.CronRun) cronRunResponse {
+	resp := cronRunResponse{
+		ID:         uuidFromPg(run.ID).String(),
+		Subcommand: run.Subcommand,
+		Status:     run.Status,
+		StartedAt:  run.StartedAt.Time,
+		Counts:     json.RawMessage(run.Counts),
+		Error:      run.Error.String,
+	}
+	if len(resp.Counts) == 0 {
+		resp.Counts = json.RawMessage("{}")
+	}
+	if run.FinishedAt.Valid {
+		finished := run.FinishedAt.Time
+		resp.FinishedAt = &finished
+	}
+	if run.DurationMs.Valid {
+		duration := run.DurationMs.Int64
+		resp.DurationMs = &duration
+	}
+	return resp
+}
+
 // isFullTextParseError reports whether a PostgreSQL error was caused by
 // parsing a user supplied search term. These errors are considered retryable
 // because the handler can safely fall back to the slower, non full text
@@ -952,6 +1962,16 @@ func (s *Server) buildRecommendationResponse(ctx context.Context, row db.ListRec
 		highlightResponses = append(highlightResponses, toHighlightResponse(item))
 	}
 
+	resp := toRecommendationLinkResponse(row)
+	resp.Tags = tagResponses
+	resp.Highlights = highlightResponses
+	resp.HighlightCount = len(highlightResponses)
+	return resp, nil
+}
+
+// toRecommendationLinkResponse converts a recommendation row without its tags
+// or highlights, which the row does not carry.
+func toRecommendationLinkResponse(row db.ListRecommendationsForUserRow) linkResponse {
 	var readAt *time.Time
 	if row.ReadAt.Valid {
 		t := row.ReadAt.Time
@@ -996,17 +2016,16 @@ func (s *Server) buildRecommendationResponse(ctx context.Context, row db.ListRec
 		Lang:          lang,
 		WordCount:     int(row.WordCount),
 		ExtractedText: row.ExtractedText,
-		Tags:          tagResponses,
-		Highlights:    highlightResponses,
-	}, nil
+		Paywalled:     row.Paywalled,
+	}
 }
 
 func (s *Server) handleListTags(c echo.Context) error {
 	ctx := c.Request().Context()
-	items, err := s.queries.ListTagLinkCounts(ctx)
+	items, err := s.reads().ListTagLinkCounts(ctx, uuidToPg(s.userID(ctx)))
 	if err != nil {
 		s.metrics.TagListFailure.Inc()
-		return c.JSON(stdhttp.StatusInternalServerError, map[string]string{"error": "failed to list tags"})
+		return respondError(c, stdhttp.StatusInternalServerError, "failed to list tags")
 	}
 
 	responses := make([]tagResponse, 0, len(items))
@@ -1023,31 +2042,32 @@ func (s *Server) handleCreateTag(c echo.Context) error {
 	var req createTagRequest
 	if err := c.Bind(&req); err != nil {
 		s.metrics.TagCreateFailure.Inc()
-		return c.JSON(stdhttp.StatusBadRequest, map[string]string{"error": "invalid payload"})
+		return respondBindError(c, err)
 	}
 
 	name := strings.TrimSpace(req.Name)
 	if name == "" {
 		s.metrics.TagCreateFailure.Inc()
-		return c.JSON(stdhttp.StatusBadRequest, map[string]string{"error": "name is required"})
+		return respondError(c, stdhttp.StatusBadRequest, "name is required")
 	}
 
 	ctx := c.Request().Context()
-	tag, err := s.queries.GetTagByName(ctx, name)
+	userID := uuidToPg(s.userID(ctx))
+	tag, err := s.queries.GetTagByName(ctx, db.GetTagByNameParams{UserID: userID, Name: name})
 	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
 		s.metrics.TagCreateFailure.Inc()
-		return c.JSON(stdhttp.StatusInternalServerError, map[string]string{"error": "failed to resolve tag"})
+		return respondError(c, stdhttp.StatusInternalServerError, "failed to resolve tag")
 	}
 
 	if err == nil {
 		s.metrics.TagCreateFailure.Inc()
-		return c.JSON(stdhttp.StatusConflict, map[string]string{"error": "tag already exists"})
+		return respondError(c, stdhttp.StatusConflict, "tag already exists")
 	}
 
-	tag, err = s.queries.CreateTag(ctx, name)
+	tag, err = s.queries.CreateTag(ctx, db.CreateTagParams{UserID: userID, Name: name})
 	if err != nil {
 		s.metrics.TagCreateFailure.Inc()
-		return c.JSON(stdhttp.StatusInternalServerError, map[string]string{"error": "failed to create tag"})
+		return respondError(c, stdhttp.StatusInternalServerError, "failed to create tag")
 	}
 
 	s.metrics.TagCreateSuccess.Inc()
@@ -1059,18 +2079,18 @@ func (s *Server) handleGetTag(c echo.Context) error {
 	id, err := parseInt32Param(c.Param("id"))
 	if err != nil {
 		s.metrics.TagReadFailure.Inc()
-		return c.JSON(stdhttp.StatusBadRequest, map[string]string{"error": "invalid tag id"})
+		return respondError(c, stdhttp.StatusBadRequest, "invalid tag id")
 	}
 
 	ctx := c.Request().Context()
-	tag, err := s.queries.GetTag(ctx, id)
+	tag, err := s.queries.GetTag(ctx, db.GetTagParams{ID: id, UserID: uuidToPg(s.userID(ctx))})
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			s.metrics.TagReadFailure.Inc()
-			return c.JSON(stdhttp.StatusNotFound, map[string]string{"error": "tag not found"})
+			return respondError(c, stdhttp.StatusNotFound, "tag not found")
 		}
 		s.metrics.TagReadFailure.Inc()
-		return c.JSON(stdhttp.StatusInternalServerError, map[string]string{"error": "failed to load tag"})
+		return respondError(c, stdhttp.StatusInternalServerError, "failed to load tag")
 	}
 
 	s.metrics.TagReadSuccess.Inc()
@@ -1081,30 +2101,30 @@ func (s *Server) handleUpdateTag(c echo.Context) error {
 	id, err := parseInt32Param(c.Param("id"))
 	if err != nil {
 		s.metrics.TagUpdateFailure.Inc()
-		return c.JSON(stdhttp.StatusBadRequest, map[string]string{"error": "invalid tag id"})
+		return respondError(c, stdhttp.StatusBadRequest, "invalid tag id")
 	}
 
 	var req updateTagRequest
 	if err := c.Bind(&req); err != nil {
 		s.metrics.TagUpdateFailure.Inc()
-		return c.JSON(stdhttp.StatusBadRequest, map[string]string{"error": "invalid payload"})
+		return respondBindError(c, err)
 	}
 
 	name := strings.TrimSpace(req.Name)
 	if name == "" {
 		s.metrics.TagUpdateFailure.Inc()
-		return c.JSON(stdhttp.StatusBadRequest, map[string]string{"error": "name is required"})
+		return respondError(c, stdhttp.StatusBadRequest, "name is required")
 	}
 
 	ctx := c.Request().Context()
-	tag, err := s.queries.UpdateTag(ctx, db.UpdateTagParams{ID: id, Name: name})
+	tag, err := s.queries.UpdateTag(ctx, db.UpdateTagParams{ID: id, UserID: uuidToPg(s.userID(ctx)), Name: name})
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			s.metrics.TagUpdateFailure.Inc()
-			return c.JSON(stdhttp.StatusNotFound, map[string]string{"error": "tag not found"})
+			return respondError(c, stdhttp.StatusNotFound, "tag not found")
 		}
 		s.metrics.TagUpdateFailure.Inc()
-		return c.JSON(stdhttp.StatusInternalServerError, map[string]string{"error": "failed to update tag"})
+		return respondError(c, stdhttp.StatusInternalServerError, "failed to update tag")
 	}
 
 	s.metrics.TagUpdateSuccess.Inc()
@@ -1115,22 +2135,23 @@ func (s *Server) handleDeleteTag(c echo.Context) error {
 	id, err := parseInt32Param(c.Param("id"))
 	if err != nil {
 		s.metrics.TagDeleteFailure.Inc()
-		return c.JSON(stdhttp.StatusBadRequest, map[string]string{"error": "invalid tag id"})
+		return respondError(c, stdhttp.StatusBadRequest, "invalid tag id")
 	}
 
 	ctx := c.Request().Context()
-	if _, err := s.queries.GetTag(ctx, id); err != nil {
+	params := db.DeleteTagParams{ID: id, UserID: uuidToPg(s.userID(ctx))}
+	if _, err := s.queries.GetTag(ctx, db.GetTagParams{ID: params.ID, UserID: params.UserID}); err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			s.metrics.TagDeleteFailure.Inc()
-			return c.JSON(stdhttp.StatusNotFound, map[string]string{"error": "tag not found"})
+			return respondError(c, stdhttp.StatusNotFound, "tag not found")
 		}
 		s.metrics.TagDeleteFailure.Inc()
-		return c.JSON(stdhttp.StatusInternalServerError, map[string]string{"error": "failed to load tag"})
+		return respondError(c, stdhttp.StatusInternalServerError, "failed to load tag")
 	}
 
-	if err := s.queries.DeleteTag(ctx, id); err != nil {
+	if err := s.queries.DeleteTag(ctx, params); err != nil {
 		s.metrics.TagDeleteFailure.Inc()
-		return c.JSON(stdhttp.StatusInternalServerError, map[string]string{"error": "failed to delete tag"})
+		return respondError(c, stdhttp.StatusInternalServerError, "failed to delete tag")
 	}
 
 	s.metrics.TagDeleteSuccess.Inc()
@@ -1141,10 +2162,10 @@ func (s *Server) handleListLinkTags(c echo.Context) error {
 	linkID, err := parseUUIDParam(c.Param("id"))
 	if err != nil {
 		s.metrics.LinkTagReadFailure.Inc()
-		return c.JSON(stdhttp.StatusBadRequest, map[string]string{"error": "invalid link id"})
+		return respondError(c, stdhttp.StatusBadRequest, "invalid link id")
 	}
 
-	if _, err := s.ensureLinkAccess(c.Request().Context(), linkID); err != nil {
+	if _, err := s.ensureLinkAccess(c.Request().Context(), linkID, linkPermissionRead); err != nil {
 		s.metrics.LinkTagReadFailure.Inc()
 		return respondWithError(c, err)
 	}
@@ -1153,7 +2174,7 @@ func (s *Server) handleListLinkTags(c echo.Context) error {
 	tags, err := s.queries.ListTagsForLink(ctx, uuidToPg(linkID))
 	if err != nil {
 		s.metrics.LinkTagReadFailure.Inc()
-		return c.JSON(stdhttp.StatusInternalServerError, map[string]string{"error": "failed to list tags"})
+		return respondError(c, stdhttp.StatusInternalServerError, "failed to list tags")
 	}
 
 	responses := make([]tagResponse, 0, len(tags))
@@ -1169,10 +2190,10 @@ func (s *Server) handleAddLinkTag(c echo.Context) error {
 	linkID, err := parseUUIDParam(c.Param("id"))
 	if err != nil {
 		s.metrics.LinkTagMutateFailure.Inc()
-		return c.JSON(stdhttp.StatusBadRequest, map[string]string{"error": "invalid link id"})
+		return respondError(c, stdhttp.StatusBadRequest, "invalid link id")
 	}
 
-	if _, err := s.ensureLinkAccess(c.Request().Context(), linkID); err != nil {
+	if _, err := s.ensureLinkAccess(c.Request().Context(), linkID, linkPermissionOwner); err != nil {
 		s.metrics.LinkTagMutateFailure.Inc()
 		return respondWithError(c, err)
 	}
@@ -1180,7 +2201,7 @@ func (s *Server) handleAddLinkTag(c echo.Context) error {
 	var req linkTagsRequest
 	if err := c.Bind(&req); err != nil {
 		s.metrics.LinkTagMutateFailure.Inc()
-		return c.JSON(stdhttp.StatusBadRequest, map[string]string{"error": "invalid payload"})
+		return respondBindError(c, err)
 	}
 
 	ctx := c.Request().Context()
@@ -1198,10 +2219,10 @@ func (s *Server) handleReplaceLinkTags(c echo.Context) error {
 	linkID, err := parseUUIDParam(c.Param("id"))
 	if err != nil {
 		s.metrics.LinkTagMutateFailure.Inc()
-		return c.JSON(stdhttp.StatusBadRequest, map[string]string{"error": "invalid link id"})
+		return respondError(c, stdhttp.StatusBadRequest, "invalid link id")
 	}
 
-	if _, err := s.ensureLinkAccess(c.Request().Context(), linkID); err != nil {
+	if _, err := s.ensureLinkAccess(c.Request().Context(), linkID, linkPermissionOwner); err != nil {
 		s.metrics.LinkTagMutateFailure.Inc()
 		return respondWithError(c, err)
 	}
@@ -1209,7 +2230,7 @@ func (s *Server) handleReplaceLinkTags(c echo.Context) error {
 	var req linkTagsRequest
 	if err := c.Bind(&req); err != nil {
 		s.metrics.LinkTagMutateFailure.Inc()
-		return c.JSON(stdhttp.StatusBadRequest, map[string]string{"error": "invalid payload"})
+		return respondBindError(c, err)
 	}
 
 	ctx := c.Request().Context()
@@ -1227,10 +2248,10 @@ func (s *Server) handleClearLinkTags(c echo.Context) error {
 	linkID, err := parseUUIDParam(c.Param("id"))
 	if err != nil {
 		s.metrics.LinkTagMutateFailure.Inc()
-		return c.JSON(stdhttp.StatusBadRequest, map[string]string{"error": "invalid link id"})
+		return respondError(c, stdhttp.StatusBadRequest, "invalid link id")
 	}
 
-	if _, err := s.ensureLinkAccess(c.Request().Context(), linkID); err != nil {
+	if _, err := s.ensureLinkAccess(c.Request().Context(), linkID, linkPermissionOwner); err != nil {
 		s.metrics.LinkTagMutateFailure.Inc()
 		return respondWithError(c, err)
 	}
@@ -1249,10 +2270,10 @@ func (s *Server) handleListHighlights(c echo.Context) error {
 	linkID, err := parseUUIDParam(c.Param("id"))
 	if err != nil {
 		s.metrics.HighlightListFailure.Inc()
-		return c.JSON(stdhttp.StatusBadRequest, map[string]string{"error": "invalid link id"})
+		return respondError(c, stdhttp.StatusBadRequest, "invalid link id")
 	}
 
-	link, err := s.ensureLinkAccess(c.Request().Context(), linkID)
+	link, err := s.ensureLinkAccess(c.Request().Context(), linkID, linkPermissionRead)
 	if err != nil {
 		s.metrics.HighlightListFailure.Inc()
 		return respondWithError(c, err)
@@ -1262,7 +2283,7 @@ func (s *Server) handleListHighlights(c echo.Context) error {
 	items, err := s.queries.ListHighlightsByLink(ctx, link.ID)
 	if err != nil {
 		s.metrics.HighlightListFailure.Inc()
-		return c.JSON(stdhttp.StatusInternalServerError, map[string]string{"error": "failed to list highlights"})
+		return respondError(c, stdhttp.StatusInternalServerError, "failed to list highlights")
 	}
 
 	responses := make([]highlightResponse, 0, len(items))
@@ -1278,10 +2299,10 @@ func (s *Server) handleCreateHighlight(c echo.Context) error {
 	linkID, err := parseUUIDParam(c.Param("id"))
 	if err != nil {
 		s.metrics.HighlightCreateFailure.Inc()
-		return c.JSON(stdhttp.StatusBadRequest, map[string]string{"error": "invalid link id"})
+		return respondError(c, stdhttp.StatusBadRequest, "invalid link id")
 	}
 
-	link, err := s.ensureLinkAccess(c.Request().Context(), linkID)
+	link, err := s.ensureLinkAccess(c.Request().Context(), linkID, linkPermissionAnnotate)
 	if err != nil {
 		s.metrics.HighlightCreateFailure.Inc()
 		return respondWithError(c, err)
@@ -1290,19 +2311,22 @@ func (s *Server) handleCreateHighlight(c echo.Context) error {
 	var req highlightRequest
 	if err := c.Bind(&req); err != nil {
 		s.metrics.HighlightCreateFailure.Inc()
-		return c.JSON(stdhttp.StatusBadRequest, map[string]string{"error": "invalid payload"})
+		return respondBindError(c, err)
 	}
 
 	text, note, err := validateHighlightPayload(req)
 	if err != nil {
 		s.metrics.HighlightCreateFailure.Inc()
-		return c.JSON(stdhttp.StatusBadRequest, map[string]string{"error": err.Error()})
+		return respondError(c, stdhttp.StatusBadRequest, err.Error())
 	}
 
-	limiter := s.highlightLimiterForUser(uuidFromPg(link.UserID))
-	if limiter != nil && !limiter.Allow() {
+	if s.highlightLimiter != nil && !s.highlightLimiter.Allow(c.Request().Context(), uuidFromPg(link.UserID).String()) {
 		s.metrics.HighlightRateLimited.Inc()
-		return c.JSON(stdhttp.StatusTooManyRequests, map[string]string{"error": "highlight rate limit exceeded"})
+		return respondError(c, stdhttp.StatusTooManyRequests, "highlight rate limit exceeded")
+	}
+	if err := s.takeQuota(c, quotaHighlights, s.currentUser(c)); err != nil {
+		s.metrics.HighlightCreateFailure.Inc()
+		return respondWithError(c, err)
 	}
 
 	noteText := pgtype.Text{}
@@ -1319,22 +2343,24 @@ func (s *Server) handleCreateHighlight(c echo.Context) error {
 	})
 	if err != nil {
 		s.metrics.HighlightCreateFailure.Inc()
-		return c.JSON(stdhttp.StatusInternalServerError, map[string]string{"error": "failed to create highlight"})
+		return respondError(c, stdhttp.StatusInternalServerError, "failed to create highlight")
 	}
 	s.metrics.HighlightProcessingSeconds.Observe(time.Since(start).Seconds())
 
 	s.metrics.HighlightCreateSuccess.Inc()
-	return c.JSON(stdhttp.StatusCreated, toHighlightResponse(highlight))
+	resp := toHighlightResponse(highlight)
+	s.publishEvent(c, queue.EventHighlightCreated, linkID.String(), resp)
+	return c.JSON(stdhttp.StatusCreated, resp)
 }
 
 func (s *Server) handleUpdateHighlight(c echo.Context) error {
 	linkID, err := parseUUIDParam(c.Param("id"))
 	if err != nil {
 		s.metrics.HighlightUpdateFailure.Inc()
-		return c.JSON(stdhttp.StatusBadRequest, map[string]string{"error": "invalid link id"})
+		return respondError(c, stdhttp.StatusBadRequest, "invalid link id")
 	}
 
-	if _, err := s.ensureLinkAccess(c.Request().Context(), linkID); err != nil {
+	if _, err := s.ensureLinkAccess(c.Request().Context(), linkID, linkPermissionAnnotate); err != nil {
 		s.metrics.HighlightUpdateFailure.Inc()
 		return respondWithError(c, err)
 	}
@@ -1342,19 +2368,19 @@ func (s *Server) handleUpdateHighlight(c echo.Context) error {
 	highlightID, err := parseUUIDParam(c.Param("highlightID"))
 	if err != nil {
 		s.metrics.HighlightUpdateFailure.Inc()
-		return c.JSON(stdhttp.StatusBadRequest, map[string]string{"error": "invalid highlight id"})
+		return respondError(c, stdhttp.StatusBadRequest, "invalid highlight id")
 	}
 
 	var req highlightRequest
 	if err := c.Bind(&req); err != nil {
 		s.metrics.HighlightUpdateFailure.Inc()
-		return c.JSON(stdhttp.StatusBadRequest, map[string]string{"error": "invalid payload"})
+		return respondBindError(c, err)
 	}
 
 	text, note, err := validateHighlightPayload(req)
 	if err != nil {
 		s.metrics.HighlightUpdateFailure.Inc()
-		return c.JSON(stdhttp.StatusBadRequest, map[string]string{"error": err.Error()})
+		return respondError(c, stdhttp.StatusBadRequest, err.Error())
 	}
 
 	noteText := pgtype.Text{}
@@ -1371,15 +2397,15 @@ func (s *Server) handleUpdateHighlight(c echo.Context) error {
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			s.metrics.HighlightUpdateFailure.Inc()
-			return c.JSON(stdhttp.StatusNotFound, map[string]string{"error": "highlight not found"})
+			return respondError(c, stdhttp.StatusNotFound, "highlight not found")
 		}
 		s.metrics.HighlightUpdateFailure.Inc()
-		return c.JSON(stdhttp.StatusInternalServerError, map[string]string{"error": "failed to update highlight"})
+		return respondError(c, stdhttp.StatusInternalServerError, "failed to update highlight")
 	}
 
 	if uuidFromPg(highlight.LinkID) != linkID {
 		s.metrics.HighlightUpdateFailure.Inc()
-		return c.JSON(stdhttp.StatusNotFound, map[string]string{"error": "highlight not found"})
+		return respondError(c, stdhttp.StatusNotFound, "highlight not found")
 	}
 
 	s.metrics.HighlightUpdateSuccess.Inc()
@@ -1390,10 +2416,10 @@ func (s *Server) handleDeleteHighlight(c echo.Context) error {
 	linkID, err := parseUUIDParam(c.Param("id"))
 	if err != nil {
 		s.metrics.HighlightDeleteFailure.Inc()
-		return c.JSON(stdhttp.StatusBadRequest, map[string]string{"error": "invalid link id"})
+		return respondError(c, stdhttp.StatusBadRequest, "invalid link id")
 	}
 
-	if _, err := s.ensureLinkAccess(c.Request().Context(), linkID); err != nil {
+	if _, err := s.ensureLinkAccess(c.Request().Context(), linkID, linkPermissionAnnotate); err != nil {
 		s.metrics.HighlightDeleteFailure.Inc()
 		return respondWithError(c, err)
 	}
@@ -1401,14 +2427,14 @@ func (s *Server) handleDeleteHighlight(c echo.Context) error {
 	highlightID, err := parseUUIDParam(c.Param("highlightID"))
 	if err != nil {
 		s.metrics.HighlightDeleteFailure.Inc()
-		return c.JSON(stdhttp.StatusBadRequest, map[string]string{"error": "invalid highlight id"})
+		return respondError(c, stdhttp.StatusBadRequest, "invalid highlight id")
 	}
 
 	ctx := c.Request().Context()
 	existing, err := s.queries.ListHighlightsByLink(ctx, uuidToPg(linkID))
 	if err != nil {
 		s.metrics.HighlightDeleteFailure.Inc()
-		return c.JSON(stdhttp.StatusInternalServerError, map[string]string{"error": "failed to load highlights"})
+		return respondError(c, stdhttp.StatusInternalServerError, "failed to load highlights")
 	}
 
 	found := false
@@ -1420,12 +2446,12 @@ func (s *Server) handleDeleteHighlight(c echo.Context) error {
 	}
 	if !found {
 		s.metrics.HighlightDeleteFailure.Inc()
-		return c.JSON(stdhttp.StatusNotFound, map[string]string{"error": "highlight not found"})
+		return respondError(c, stdhttp.StatusNotFound, "highlight not found")
 	}
 
 	if err := s.queries.DeleteHighlight(ctx, uuidToPg(highlightID)); err != nil {
 		s.metrics.HighlightDeleteFailure.Inc()
-		return c.JSON(stdhttp.StatusInternalServerError, map[string]string{"error": "failed to delete highlight"})
+		return respondError(c, stdhttp.StatusInternalServerError, "failed to delete highlight")
 	}
 
 	s.metrics.HighlightDeleteSuccess.Inc()
@@ -1444,11 +2470,14 @@ func convertListLinksWithTagsRows(rows []db.ListLinksWithTagsRow) []db.ListLinks
 			CreatedAt:     row.CreatedAt,
 			ReadAt:        row.ReadAt,
 			Favorite:      row.Favorite,
+			OriginalTitle: row.OriginalTitle,
+			PreserveTitle: row.PreserveTitle,
 			ArchiveTitle:  row.ArchiveTitle,
 			ArchiveByline: row.ArchiveByline,
 			Lang:          row.Lang,
 			WordCount:     row.WordCount,
 			ExtractedText: row.ExtractedText,
+			Paywalled:     row.Paywalled,
 			TagIds:        row.TagIds,
 			TagNames:      row.TagNames,
 			Highlights:    row.Highlights,
@@ -1457,6 +2486,44 @@ func convertListLinksWithTagsRows(rows []db.ListLinksWithTagsRow) []db.ListLinks
 	return converted
 }
 
+func convertColdStartRow(row db.ListColdStartLinksForUserRow) db.ListRecommendationsForUserRow {
+	return db.ListRecommendationsForUserRow{
+		ID:            row.ID,
+		Url:           row.Url,
+		Title:         row.Title,
+		SourceDomain:  row.SourceDomain,
+		Favorite:      row.Favorite,
+		CreatedAt:     row.CreatedAt,
+		ReadAt:        row.ReadAt,
+		ArchiveTitle:  row.ArchiveTitle,
+		Byline:        row.Byline,
+		Lang:          row.Lang,
+		WordCount:     row.WordCount,
+		ExtractedText: row.ExtractedText,
+		Paywalled:     row.Paywalled,
+		Strategy:      strategyColdStart,
+	}
+}
+
+func convertOnThisDayRow(row db.ListOnThisDayLinksForUserRow) db.ListRecommendationsForUserRow {
+	return db.ListRecommendationsForUserRow{
+		ID:            row.ID,
+		Url:           row.Url,
+		Title:         row.Title,
+		SourceDomain:  row.SourceDomain,
+		Favorite:      row.Favorite,
+		CreatedAt:     row.CreatedAt,
+		ReadAt:        row.ReadAt,
+		ArchiveTitle:  row.ArchiveTitle,
+		Byline:        row.Byline,
+		Lang:          row.Lang,
+		WordCount:     row.WordCount,
+		ExtractedText: row.ExtractedText,
+		Paywalled:     row.Paywalled,
+		Strategy:      strategyOnThisDay,
+	}
+}
+
 func toLinkResponse(row db.ListLinksRow) (linkResponse, error) {
 	var readAt *time.Time
 	if row.ReadAt.Valid {
@@ -1481,41 +2548,59 @@ func toLinkResponse(row db.ListLinksRow) (linkResponse, error) {
 	}
 
 	return linkResponse{
-		ID:            uuidFromPg(row.ID).String(),
-		URL:           row.Url,
-		Title:         title,
-		SourceDomain:  sourceDomain,
-		Favorite:      row.Favorite,
-		CreatedAt:     row.CreatedAt.Time,
-		ReadAt:        readAt,
-		ArchiveTitle:  row.ArchiveTitle,
-		Byline:        row.ArchiveByline,
-		Lang:          row.Lang,
-		WordCount:     int(row.WordCount),
-		ExtractedText: row.ExtractedText,
-		Tags:          tags,
-		Highlights:    highlights,
+		ID:             uuidFromPg(row.ID).String(),
+		URL:            row.Url,
+		Title:          title,
+		SourceDomain:   sourceDomain,
+		Favorite:       row.Favorite,
+		CreatedAt:      row.CreatedAt.Time,
+		ReadAt:         readAt,
+		OriginalTitle:  row.OriginalTitle.String,
+		PreserveTitle:  row.PreserveTitle,
+		ArchiveTitle:   row.ArchiveTitle,
+		Byline:         row.ArchiveByline,
+		Lang:           row.Lang,
+		WordCount:      int(row.WordCount),
+		ExtractedText:  row.ExtractedText,
+		Paywalled:      row.Paywalled,
+		Tags:           tags,
+		Highlights:     highlights,
+		HighlightCount: len(highlights),
 	}, nil
 }
 
+// apiError is an error a handler reports to the client. Code is the HTTP
+// status; Reason is the error code sent with it, empty for the status's
+// default.
 type apiError struct {
 	Code    int
 	Message string
+	Reason  string
 }
 
 func (e apiError) Error() string {
 	return e.Message
 }
 
+func (e apiError) code() string {
+	if e.Reason != "" {
+		return e.Reason
+	}
+	return errorCodeForStatus(e.Code)
+}
+
 func respondWithError(c echo.Context, err error) error {
 	var apiErr apiError
 	if errors.As(err, &apiErr) {
-		return c.JSON(apiErr.Code, map[string]string{"error": apiErr.Message})
+		return respondErrorCode(c, apiErr.Code, apiErr.code(), apiErr.Message, nil)
 	}
 	return err
 }
 
-func (s *Server) ensureLinkAccess(ctx context.Context, linkID uuid.UUID) (db.GetLinkRow, error) {
+// ensureLinkAccess loads a link the caller may act on with need. The owner
+// may do anything; anyone else only what an unexpired share grants them.
+// Links the caller cannot see at all are reported as not found.
+func (s *Server) ensureLinkAccess(ctx context.Context, linkID uuid.UUID, need linkPermission) (db.GetLinkRow, error) {
 	link, err := s.queries.GetLink(ctx, uuidToPg(linkID))
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
@@ -1523,9 +2608,26 @@ func (s *Server) ensureLinkAccess(ctx context.Context, linkID uuid.UUID) (db.Get
 		}
 		return db.GetLinkRow{}, apiError{Code: stdhttp.StatusInternalServerError, Message: "failed to load link"}
 	}
-	if uuidFromPg(link.UserID) != s.cfg.DevUserID {
+	if uuidFromPg(link.UserID) == s.userID(ctx) {
+		return link, nil
+	}
+	if need == linkPermissionOwner {
 		return db.GetLinkRow{}, apiError{Code: stdhttp.StatusNotFound, Message: "link not found"}
 	}
+
+	granted, err := s.queries.GetLinkSharePermission(ctx, db.GetLinkSharePermissionParams{
+		LinkID: link.ID,
+		UserID: uuidToPg(s.userID(ctx)),
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return db.GetLinkRow{}, apiError{Code: stdhttp.StatusNotFound, Message: "link not found"}
+		}
+		return db.GetLinkRow{}, apiError{Code: stdhttp.StatusInternalServerError, Message: "failed to load link"}
+	}
+	if need == linkPermissionAnnotate && granted != sharePermissionAnnotate {
+		return db.GetLinkRow{}, apiError{Code: stdhttp.StatusForbidden, Message: "link is shared read-only"}
+	}
 	return link, nil
 }
 
@@ -1541,6 +2643,46 @@ func parseInt32Param(raw string) (int32, error) {
 	return int32(value), nil
 }
 
+// unknownTagError reports a tag filter that names a tag which does not exist.
+type unknownTagError struct {
+	name string
+}
+
+func (e unknownTagError) Error() string {
+	return fmt.Sprintf("unknown tag: %s", e.name)
+}
+
+// resolveTagFilter converts a comma-separated list of tag names into tag IDs,
+// skipping blanks and case-insensitive duplicates.
+func (s *Server) resolveTagFilter(ctx context.Context, raw string) ([]int32, error) {
+	if raw == "" {
+		return nil, nil
+	}
+
+	var tagIDs []int32
+	seen := make(map[string]struct{})
+	for _, part := range strings.Split(raw, ",") {
+		name := strings.TrimSpace(part)
+		if name == "" {
+			continue
+		}
+		if _, ok := seen[strings.ToLower(name)]; ok {
+			continue
+		}
+		seen[strings.ToLower(name)] = struct{}{}
+
+		tag, err := s.queries.GetTagByName(ctx, db.GetTagByNameParams{UserID: uuidToPg(s.userID(ctx)), Name: name})
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return nil, unknownTagError{name: name}
+			}
+			return nil, fmt.Errorf("resolve tag %q: %w", name, err)
+		}
+		tagIDs = append(tagIDs, tag.ID)
+	}
+	return tagIDs, nil
+}
+
 func (s *Server) setLinkTags(ctx context.Context, linkID uuid.UUID, ids []int32) ([]tagResponse, error) {
 	unique := make([]int32, 0, len(ids))
 	seen := make(map[int32]struct{}, len(ids))
@@ -1568,7 +2710,7 @@ func (s *Server) setLinkTags(ctx context.Context, linkID uuid.UUID, ids []int32)
 
 	desiredTags := make(map[int32]db.Tag, len(unique))
 	for _, id := range unique {
-		tag, err := s.queries.GetTag(ctx, id)
+		tag, err := s.queries.GetTag(ctx, db.GetTagParams{ID: id, UserID: uuidToPg(s.userID(ctx))})
 		if err != nil {
 			if errors.Is(err, pgx.ErrNoRows) {
 				return nil, apiError{Code: stdhttp.StatusBadRequest, Message: "tag not found"}
@@ -1651,20 +2793,20 @@ func toHighlightResponse(item db.Highlight) highlightResponse {
 	}
 }
 
-func (s *Server) highlightLimiterForUser(userID uuid.UUID) *rate.Limiter {
-	if s.highlightRate == 0 {
-		return nil
+func (s *Server) rateLimitConfig() RateLimitConfig {
+	return RateLimitConfig{
+		Read:  RateLimitRule{Limit: rate.Limit(s.cfg.RateLimitReadRPS), Burst: s.cfg.RateLimitReadBurst},
+		Write: RateLimitRule{Limit: rate.Limit(s.cfg.RateLimitWriteRPS), Burst: s.cfg.RateLimitWriteBurst},
+		Admin: RateLimitRule{Limit: rate.Limit(s.cfg.RateLimitAdminRPS), Burst: s.cfg.RateLimitAdminBurst},
 	}
+}
 
-	s.highlightLimiterMu.Lock()
-	defer s.highlightLimiterMu.Unlock()
-
-	limiter, ok := s.highlightLimiters[userID]
-	if !ok {
-		limiter = rate.NewLimiter(s.highlightRate, s.highlightBurst)
-		s.highlightLimiters[userID] = limiter
+func (s *Server) securityConfig() SecurityConfig {
+	return SecurityConfig{
+		AllowedOrigins:        s.cfg.CORSAllowedOrigins,
+		ContentSecurityPolicy: s.cfg.ContentSecurityPolicy,
+		HSTSMaxAge:            s.cfg.HSTSMaxAge,
 	}
-	return limiter
 }
 
 var trackingParameters = map[string]struct{}{
@@ -1880,27 +3022,46 @@ func parsePagination(limitRaw, offsetRaw string) (int, int, error) {
 }
 
 func normalizeURL(raw string) (string, error) {
+	normalized, _, err := normalizeURLWithRules(raw, nil)
+	return normalized, err
+}
+
+// normalizeURLWithRules normalizes raw: it unwraps redirectors, AMP, and
+// mobile editions, then drops the query parameters rules match. The report
+// lists each step. Nil rules drop only the built-in tracking parameters.
+func normalizeURLWithRules(raw string, rules *trackingRules) (string, urlNormalization, error) {
+	var report urlNormalization
 	if raw == "" {
-		return "", fmt.Errorf("url is required")
+		return "", report, fmt.Errorf("url is required")
 	}
 	parsed, err := url.Parse(raw)
 	if err != nil {
-		return "", err
+		return "", report, err
 	}
 	if parsed.Scheme == "" {
 		parsed, err = url.Parse("https://" + raw)
 		if err != nil {
-			return "", err
+			return "", report, err
 		}
 	}
 	if parsed.Host == "" {
-		return "", fmt.Errorf("url missing host")
+		return "", report, fmt.Errorf("url missing host")
 	}
 	parsed.Fragment = ""
 
+	for i := 0; i < maxURLUnwraps; i++ {
+		next, rule, ok := unwrapURL(parsed)
+		if !ok {
+			break
+		}
+		report.Rewrites = append(report.Rewrites, urlRewrite{Rule: rule, From: parsed.String()})
+		parsed = next
+		parsed.Fragment = ""
+	}
+
 	host := strings.ToLower(parsed.Hostname())
 	if host == "" {
-		return "", fmt.Errorf("url missing host")
+		return "", report, fmt.Errorf("url missing host")
 	}
 
 	port := parsed.Port()
@@ -1917,17 +3078,19 @@ func normalizeURL(raw string) (string, error) {
 
 	query := parsed.Query()
 	for key := range query {
-		if isTrackingParam(key) {
+		if rule, ok := rules.strips(host, key); ok {
+			report.Removed = append(report.Removed, removedParam{Param: key, Rule: rule})
 			query.Del(key)
 		}
 	}
+	sort.Slice(report.Removed, func(i, j int) bool { return report.Removed[i].Param < report.Removed[j].Param })
 	if len(query) == 0 {
 		parsed.RawQuery = ""
 	} else {
 		parsed.RawQuery = query.Encode()
 	}
 
-	return parsed.String(), nil
+	return parsed.String(), report, nil
 }
 
 func uuidToPg(id uuid.UUID) pgtype.UUID {
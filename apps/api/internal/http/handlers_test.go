@@ -26,8 +26,24 @@ import (
 	"github.com/example/keepstack/apps/api/internal/digest"
 	"github.com/example/keepstack/apps/api/internal/observability"
 	"github.com/example/keepstack/apps/api/internal/queue"
+	"github.com/example/keepstack/media"
+	"github.com/example/keepstack/messages"
 )
 
+// newRouteTestServer registers every route on a Server reading from queries
+// and publishing to publisher, with DevUserID as its user. options adjust the
+// config first, e.g. to configure tokens.
+func newRouteTestServer(queries *mockQueries, publisher *stubPublisher, options ...func(*config.Config)) *echo.Echo {
+	cfg := config.Config{DevUserID: uuid.MustParse("aaaaaaaa-aaaa-aaaa-aaaa-aaaaaaaaaaaa")}
+	for _, option := range options {
+		option(&cfg)
+	}
+	srv := &Server{cfg: cfg, queries: queries, publisher: publisher, metrics: newTestMetrics()}
+	e := echo.New()
+	srv.RegisterRoutes(e)
+	return e
+}
+
 func TestHandleCreateLink(t *testing.T) {
 	t.Parallel()
 
@@ -70,6 +86,56 @@ func TestHandleCreateLink(t *testing.T) {
 	}
 }
 
+func TestHandleCreateLinkPreserveTitle(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		body     string
+		byConfig bool
+		want     pgtype.Bool
+	}{
+		{name: "default", body: `{"url":"https://example.com","title":"Mine"}`, want: pgtype.Bool{Valid: true}},
+		{name: "requested", body: `{"url":"https://example.com","title":"Mine","preserve_title":true}`, want: pgtype.Bool{Bool: true, Valid: true}},
+		{name: "config default", body: `{"url":"https://example.com","title":"Mine"}`, byConfig: true, want: pgtype.Bool{Bool: true, Valid: true}},
+		{name: "opt out", body: `{"url":"https://example.com","title":"Mine","preserve_title":false}`, byConfig: true, want: pgtype.Bool{Valid: true}},
+		{name: "no title", body: `{"url":"https://example.com","preserve_title":true}`, want: pgtype.Bool{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			var created db.CreateLinkParams
+			queries := &mockQueries{
+				createLinkFn: func(ctx context.Context, params db.CreateLinkParams) (db.CreateLinkRow, error) {
+					created = params
+					return db.CreateLinkRow{ID: params.ID, UserID: params.UserID, Url: params.Url}, nil
+				},
+			}
+			cfg := config.Config{
+				DevUserID:          uuid.MustParse("abcabcab-cabc-abca-bcab-cabcabcabcab"),
+				PreserveUserTitles: tt.byConfig,
+			}
+			srv := &Server{cfg: cfg, queries: queries, publisher: &stubPublisher{}, metrics: newTestMetrics()}
+			e := echo.New()
+			srv.RegisterRoutes(e)
+
+			req := httptest.NewRequest(http.MethodPost, "/api/links", strings.NewReader(tt.body))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+
+			if rec.Code != http.StatusCreated {
+				t.Fatalf("expected status %d, got %d: %s", http.StatusCreated, rec.Code, rec.Body.String())
+			}
+			if got, _ := created.PreserveTitle.(pgtype.Bool); got != tt.want {
+				t.Fatalf("expected preserve_title %+v, got %+v", tt.want, created.PreserveTitle)
+			}
+		})
+	}
+}
+
 func TestHandleCreateLinkInvalidURL(t *testing.T) {
 	t.Parallel()
 
@@ -402,7 +468,7 @@ func TestHandleListLinksSearchQuery(t *testing.T) {
 	e := echo.New()
 	srv.RegisterRoutes(e)
 
-	req := httptest.NewRequest(http.MethodGet, "/api/links?q=  time  ", nil)
+	req := httptest.NewRequest(http.MethodGet, "/api/links?q=%20%20time%20%20", nil)
 	rec := httptest.NewRecorder()
 
 	e.ServeHTTP(rec, req)
@@ -471,6 +537,7 @@ func TestHandleListLinksWithRFC3339NanoHighlights(t *testing.T) {
 					Lang:          "en",
 					WordCount:     250,
 					ExtractedText: "Body",
+					Paywalled:     true,
 					TagIds:        nil,
 					TagNames:      nil,
 					Highlights:    string(highlightJSON),
@@ -506,6 +573,9 @@ func TestHandleListLinksWithRFC3339NanoHighlights(t *testing.T) {
 	if len(resp.Items[0].Highlights) != 1 {
 		t.Fatalf("expected 1 highlight, got %d", len(resp.Items[0].Highlights))
 	}
+	if !resp.Items[0].Paywalled {
+		t.Fatalf("expected the paywalled flag to be returned")
+	}
 
 	highlight := resp.Items[0].Highlights[0]
 	wantCreated, err := time.Parse(time.RFC3339Nano, highlightCreatedStr)
@@ -994,6 +1064,69 @@ func TestHandleUpdateLinkFavoriteNotFound(t *testing.T) {
 	}
 }
 
+func TestHandleGetLinkStatus(t *testing.T) {
+	t.Parallel()
+
+	cfg := config.Config{DevUserID: uuid.MustParse("dddddddd-dddd-dddd-dddd-dddddddddddd")}
+	withStatus := uuid.New()
+	withoutStatus := uuid.New()
+	reportedAt := time.Unix(1_700_000_000, 0).UTC()
+
+	queries := &mockQueries{
+		getLinkFn: func(ctx context.Context, id pgtype.UUID) (db.GetLinkRow, error) {
+			return db.GetLinkRow{ID: id, UserID: uuidToPg(cfg.DevUserID), Url: "https://example.com"}, nil
+		},
+		getLinkIngestStatusFn: func(ctx context.Context, id pgtype.UUID) (db.LinkIngestStatus, error) {
+			if uuidFromPg(id) != withStatus {
+				return db.LinkIngestStatus{}, pgx.ErrNoRows
+			}
+			return db.LinkIngestStatus{
+				LinkID:        id,
+				Status:        "ingested",
+				UpdatedAt:     pgtype.Timestamptz{Time: reportedAt, Valid: true},
+				FinalUrl:      pgtype.Text{String: "https://example.com/final", Valid: true},
+				FetchBytes:    pgtype.Int8{Int64: 2048, Valid: true},
+				FetchMs:       pgtype.Int4{Int32: 120, Valid: true},
+				ParseMs:       pgtype.Int4{Int32: 15, Valid: true},
+				Lang:          pgtype.Text{String: "en", Valid: true},
+				LangDetected:  pgtype.Bool{Bool: true, Valid: true},
+				WordCount:     pgtype.Int4{Int32: 900, Valid: true},
+				DiagnosticsAt: pgtype.Timestamptz{Time: reportedAt, Valid: true},
+			}, nil
+		},
+	}
+	srv := &Server{cfg: cfg, queries: queries, metrics: newTestMetrics()}
+
+	e := echo.New()
+	srv.RegisterRoutes(e)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/links/"+withStatus.String()+"/status", nil)
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+	}
+	var resp linkStatusResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if resp.Status != "ingested" || resp.FailedStage != "" || resp.Diagnostics == nil {
+		t.Fatalf("unexpected response: %+v", resp)
+	}
+	if resp.Diagnostics.FinalURL != "https://example.com/final" || resp.Diagnostics.FetchMs != 120 || resp.Diagnostics.WordCount != 900 {
+		t.Fatalf("unexpected diagnostics: %+v", resp.Diagnostics)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/api/links/"+withoutStatus.String()+"/status", nil)
+	rec = httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("expected status %d, got %d", http.StatusNotFound, rec.Code)
+	}
+}
+
 func TestHandleCreateClaim(t *testing.T) {
 	t.Parallel()
 
@@ -1139,6 +1272,240 @@ func TestHandleCreateClaimInvalidLinkID(t *testing.T) {
 	}
 }
 
+func TestHandleCreateClaimOnSharedLink(t *testing.T) {
+	t.Parallel()
+
+	cfg := config.Config{DevUserID: uuid.MustParse("dddddddd-dddd-dddd-dddd-dddddddddddd")}
+	owner := uuid.New()
+	linkID := uuid.New()
+	expiresAt := time.Now().Add(time.Hour).UTC().Truncate(time.Second)
+
+	var claimed db.CreateClaimParams
+	queries := &mockQueries{
+		getLinkFn: func(ctx context.Context, id pgtype.UUID) (db.GetLinkRow, error) {
+			return db.GetLinkRow{ID: id, UserID: uuidToPg(owner)}, nil
+		},
+		getLinkSharePermissionFn: func(ctx context.Context, arg db.GetLinkSharePermissionParams) (string, error) {
+			return sharePermissionRead, nil
+		},
+		createClaimFn: func(ctx context.Context, params db.CreateClaimParams) (db.CreateClaimRow, error) {
+			claimed = params
+			return db.CreateClaimRow{
+				ID:        uuidToPg(uuid.New()),
+				LinkID:    params.LinkID,
+				UserID:    params.UserID,
+				ClaimedAt: pgtype.Timestamptz{Time: time.Now(), Valid: true},
+				ExpiresAt: params.ExpiresAt,
+				Inserted:  true,
+			}, nil
+		},
+	}
+	srv := &Server{cfg: cfg, queries: queries, metrics: newTestMetrics()}
+	e := echo.New()
+	srv.RegisterRoutes(e)
+
+	body := fmt.Sprintf(`{"link_id":"%s","expires_at":"%s"}`, linkID, expiresAt.Format(time.RFC3339))
+	req := httptest.NewRequest(http.MethodPost, "/api/claims", strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusCreated, rec.Code, rec.Body.String())
+	}
+	if uuidFromPg(claimed.UserID) != cfg.DevUserID {
+		t.Fatalf("expected the claim to belong to the caller, got %s", uuidFromPg(claimed.UserID))
+	}
+	var resp claimResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if resp.ExpiresAt == nil || !resp.ExpiresAt.Equal(expiresAt) {
+		t.Fatalf("expected expires_at %s, got %v", expiresAt, resp.ExpiresAt)
+	}
+
+	past := time.Now().Add(-time.Minute).UTC().Format(time.RFC3339)
+	req = httptest.NewRequest(http.MethodPost, "/api/claims", strings.NewReader(fmt.Sprintf(`{"link_id":"%s","expires_at":"%s"}`, linkID, past)))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec = httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected a past expiry to be rejected, got %d", rec.Code)
+	}
+}
+
+func TestHandleListAndReleaseClaims(t *testing.T) {
+	t.Parallel()
+
+	cfg := config.Config{DevUserID: uuid.MustParse("dddddddd-dddd-dddd-dddd-dddddddddddd")}
+	claimID := uuid.New()
+	queries := &mockQueries{
+		listClaimsForUserFn: func(ctx context.Context, userID pgtype.UUID) ([]db.ListClaimsForUserRow, error) {
+			return []db.ListClaimsForUserRow{
+				{ID: uuidToPg(claimID), LinkID: uuidToPg(uuid.New()), Url: "https://example.com/mine", OwnerID: userID},
+				{ID: uuidToPg(uuid.New()), LinkID: uuidToPg(uuid.New()), Url: "https://example.com/shared", OwnerID: uuidToPg(uuid.New())},
+			}, nil
+		},
+		deleteClaimFn: func(ctx context.Context, arg db.DeleteClaimParams) (int64, error) {
+			if uuidFromPg(arg.ID) != claimID || uuidFromPg(arg.UserID) != cfg.DevUserID {
+				return 0, nil
+			}
+			return 1, nil
+		},
+	}
+	srv := &Server{cfg: cfg, queries: queries, metrics: newTestMetrics()}
+	e := echo.New()
+	srv.RegisterRoutes(e)
+
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/claims", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, rec.Code)
+	}
+	var list struct {
+		Items []claimListItem `json:"items"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&list); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if len(list.Items) != 2 || list.Items[0].Shared || !list.Items[1].Shared {
+		t.Fatalf("expected one own and one shared claim, got %+v", list.Items)
+	}
+
+	rec = httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodDelete, "/api/claims/"+claimID.String(), nil))
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("expected status %d, got %d", http.StatusNoContent, rec.Code)
+	}
+	rec = httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodDelete, "/api/claims/"+uuid.NewString(), nil))
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("expected status %d for someone else's claim, got %d", http.StatusNotFound, rec.Code)
+	}
+}
+
+func TestHandleListLinksClaimedFilter(t *testing.T) {
+	t.Parallel()
+
+	cfg := config.Config{DevUserID: uuid.MustParse("dddddddd-dddd-dddd-dddd-dddddddddddd")}
+	var listed db.ListLinksParams
+	var counted db.CountLinksParams
+	queries := &mockQueries{
+		listLinksFn: func(ctx context.Context, params db.ListLinksParams) ([]db.ListLinksRow, error) {
+			listed = params
+			return nil, nil
+		},
+		countLinksFn: func(ctx context.Context, params db.CountLinksParams) (int64, error) {
+			counted = params
+			return 0, nil
+		},
+	}
+	srv := &Server{cfg: cfg, queries: queries, metrics: newTestMetrics()}
+	e := echo.New()
+	srv.RegisterRoutes(e)
+
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/links?claimed=me", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+	}
+	if uuidFromPg(listed.ClaimedBy) != cfg.DevUserID || uuidFromPg(counted.ClaimedBy) != cfg.DevUserID {
+		t.Fatalf("expected list and count filtered by the caller's claims, got %v / %v", listed.ClaimedBy, counted.ClaimedBy)
+	}
+
+	rec = httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/links?claimed=someone", nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected status %d, got %d", http.StatusBadRequest, rec.Code)
+	}
+}
+
+func TestHandleListLinksHighlightsFilter(t *testing.T) {
+	t.Parallel()
+
+	cfg := config.Config{DevUserID: uuid.MustParse("d1d1d1d1-d1d1-d1d1-d1d1-d1d1d1d1d1d1")}
+	var listed db.ListLinksParams
+	var counted db.CountLinksParams
+	queries := &mockQueries{
+		listLinksFn: func(ctx context.Context, params db.ListLinksParams) ([]db.ListLinksRow, error) {
+			listed = params
+			return []db.ListLinksRow{{
+				ID:         uuidToPg(uuid.New()),
+				Url:        "https://example.com/annotated",
+				Highlights: `[{"id":"h1","text":"one","created_at":"2024-01-02T03:04:05Z","updated_at":"2024-01-02T03:04:05Z"},{"id":"h2","text":"two","created_at":"2024-01-02T03:04:05Z","updated_at":"2024-01-02T03:04:05Z"}]`,
+			}}, nil
+		},
+		countLinksFn: func(ctx context.Context, params db.CountLinksParams) (int64, error) {
+			counted = params
+			return 1, nil
+		},
+	}
+	srv := &Server{cfg: cfg, queries: queries, metrics: newTestMetrics()}
+	e := echo.New()
+	srv.RegisterRoutes(e)
+
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/links?has_highlights=true", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+	}
+	want := pgtype.Bool{Bool: true, Valid: true}
+	if listed.HasHighlights != want || counted.HasHighlights != want {
+		t.Fatalf("expected list and count filtered to annotated links, got %v / %v", listed.HasHighlights, counted.HasHighlights)
+	}
+	var resp struct {
+		Items []linkResponse `json:"items"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if len(resp.Items) != 1 || resp.Items[0].HighlightCount != 2 {
+		t.Fatalf("expected one link with 2 highlights, got %+v", resp.Items)
+	}
+
+	rec = httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/links?has_highlights=maybe", nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected status %d, got %d", http.StatusBadRequest, rec.Code)
+	}
+}
+
+func TestHandleListLinksSortsByRelevance(t *testing.T) {
+	t.Parallel()
+
+	cfg := config.Config{DevUserID: uuid.MustParse("d1d1d1d1-d1d1-d1d1-d1d1-d1d1d1d1d1d1")}
+	var listed []db.ListLinksParams
+	queries := &mockQueries{
+		listLinksFn: func(ctx context.Context, params db.ListLinksParams) ([]db.ListLinksRow, error) {
+			listed = append(listed, params)
+			return nil, nil
+		},
+		countLinksFn: func(ctx context.Context, params db.CountLinksParams) (int64, error) {
+			return 0, nil
+		},
+	}
+	srv := &Server{cfg: cfg, queries: queries, metrics: newTestMetrics()}
+	e := echo.New()
+	srv.RegisterRoutes(e)
+
+	for _, target := range []string{"/api/links?q=go&sort=relevance", "/api/links?q=go&sort=created_at", "/api/links?q=go"} {
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%s: expected status %d, got %d: %s", target, http.StatusOK, rec.Code, rec.Body.String())
+		}
+	}
+	if len(listed) != 3 || !listed[0].SortRelevance || listed[1].SortRelevance || listed[2].SortRelevance {
+		t.Fatalf("expected only sort=relevance to rank by relevance, got %+v", listed)
+	}
+
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/links?sort=oldest", nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected status %d, got %d", http.StatusBadRequest, rec.Code)
+	}
+}
+
 func TestNormalizeURL(t *testing.T) {
 	t.Parallel()
 
@@ -1183,9 +1550,9 @@ func TestHandleListLinksTagFilter(t *testing.T) {
 	requestedTags := make([]string, 0)
 
 	queries := &mockQueries{
-		getTagByNameFn: func(ctx context.Context, name string) (db.Tag, error) {
-			requestedTags = append(requestedTags, name)
-			switch name {
+		getTagByNameFn: func(ctx context.Context, arg db.GetTagByNameParams) (db.Tag, error) {
+			requestedTags = append(requestedTags, arg.Name)
+			switch arg.Name {
 			case "news":
 				return db.Tag{ID: 2, Name: "news"}, nil
 			case "tech":
@@ -1194,9 +1561,9 @@ func TestHandleListLinksTagFilter(t *testing.T) {
 				return db.Tag{}, pgx.ErrNoRows
 			}
 		},
-		listLinksFn: func(ctx context.Context, params db.ListLinksParams) ([]db.ListLinksRow, error) {
+		listLinksWithTagsFn: func(ctx context.Context, params db.ListLinksWithTagsParams) ([]db.ListLinksWithTagsRow, error) {
 			capturedTagIDs = params.TagIds
-			return []db.ListLinksRow{{
+			return []db.ListLinksWithTagsRow{{
 				ID:           uuidToPg(linkID),
 				UserID:       uuidToPg(cfg.DevUserID),
 				Url:          "https://example.com",
@@ -1207,7 +1574,7 @@ func TestHandleListLinksTagFilter(t *testing.T) {
 				Highlights:   "[]",
 			}}, nil
 		},
-		countLinksFn: func(ctx context.Context, params db.CountLinksParams) (int64, error) {
+		countLinksWithTagsFn: func(ctx context.Context, params db.CountLinksWithTagsParams) (int64, error) {
 			return 1, nil
 		},
 	}
@@ -1238,19 +1605,23 @@ func TestHandleListLinksTagFilter(t *testing.T) {
 func TestHandleCreateTag(t *testing.T) {
 	t.Parallel()
 
+	cfg := config.Config{DevUserID: uuid.MustParse("abababab-abab-abab-abab-abababababab")}
 	queries := &mockQueries{
-		getTagByNameFn: func(ctx context.Context, name string) (db.Tag, error) {
+		getTagByNameFn: func(ctx context.Context, arg db.GetTagByNameParams) (db.Tag, error) {
+			if arg.UserID != uuidToPg(cfg.DevUserID) {
+				t.Fatalf("expected the lookup scoped to the user, got %+v", arg)
+			}
 			return db.Tag{}, pgx.ErrNoRows
 		},
-		createTagFn: func(ctx context.Context, name string) (db.Tag, error) {
-			if name != "alpha" {
-				t.Fatalf("unexpected tag name: %s", name)
+		createTagFn: func(ctx context.Context, arg db.CreateTagParams) (db.Tag, error) {
+			if arg.Name != "alpha" || arg.UserID != uuidToPg(cfg.DevUserID) {
+				t.Fatalf("unexpected params: %+v", arg)
 			}
-			return db.Tag{ID: 1, Name: name}, nil
+			return db.Tag{ID: 1, Name: arg.Name, UserID: arg.UserID}, nil
 		},
 	}
 
-	srv := &Server{cfg: config.Config{}, queries: queries, metrics: newTestMetrics()}
+	srv := &Server{cfg: cfg, queries: queries, metrics: newTestMetrics()}
 	e := echo.New()
 	srv.RegisterRoutes(e)
 
@@ -1268,11 +1639,11 @@ func TestHandleCreateTagDuplicate(t *testing.T) {
 	t.Parallel()
 
 	queries := &mockQueries{
-		getTagByNameFn: func(ctx context.Context, name string) (db.Tag, error) {
-			if name != "alpha" {
-				t.Fatalf("unexpected tag name lookup: %s", name)
+		getTagByNameFn: func(ctx context.Context, arg db.GetTagByNameParams) (db.Tag, error) {
+			if arg.Name != "alpha" {
+				t.Fatalf("unexpected tag name lookup: %s", arg.Name)
 			}
-			return db.Tag{ID: 1, Name: name}, nil
+			return db.Tag{ID: 1, Name: arg.Name}, nil
 		},
 	}
 
@@ -1297,7 +1668,7 @@ func TestHandleListTags(t *testing.T) {
 	t.Parallel()
 
 	queries := &mockQueries{
-		listTagLinkCountsFn: func(ctx context.Context) ([]db.ListTagLinkCountsRow, error) {
+		listTagLinkCountsFn: func(ctx context.Context, userID pgtype.UUID) ([]db.ListTagLinkCountsRow, error) {
 			return []db.ListTagLinkCountsRow{{ID: 1, Name: "alpha", LinkCount: 3}}, nil
 		},
 	}
@@ -1330,15 +1701,41 @@ func TestHandleListTags(t *testing.T) {
 	}
 }
 
+func TestHandleListTagsUsesReadQueries(t *testing.T) {
+	t.Parallel()
+
+	reads := &mockQueries{
+		listTagLinkCountsFn: func(ctx context.Context, userID pgtype.UUID) ([]db.ListTagLinkCountsRow, error) {
+			return []db.ListTagLinkCountsRow{{ID: 2, Name: "replica", LinkCount: 1}}, nil
+		},
+	}
+
+	// The primary mock has no list function and would fail the request.
+	srv := &Server{cfg: config.Config{}, queries: &mockQueries{}, readQueries: reads, metrics: newTestMetrics()}
+	e := echo.New()
+	srv.RegisterRoutes(e)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/tags", nil)
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+	}
+	if !strings.Contains(rec.Body.String(), "replica") {
+		t.Fatalf("expected tags from read queries, got %s", rec.Body.String())
+	}
+}
+
 func TestHandleGetTag(t *testing.T) {
 	t.Parallel()
 
 	queries := &mockQueries{
-		getTagFn: func(ctx context.Context, id int32) (db.Tag, error) {
-			if id != 7 {
-				t.Fatalf("unexpected tag id: %d", id)
+		getTagFn: func(ctx context.Context, arg db.GetTagParams) (db.Tag, error) {
+			if arg.ID != 7 {
+				t.Fatalf("unexpected tag id: %d", arg.ID)
 			}
-			return db.Tag{ID: id, Name: "alpha"}, nil
+			return db.Tag{ID: arg.ID, Name: "alpha"}, nil
 		},
 	}
 
@@ -1384,19 +1781,20 @@ func TestHandleUpdateTag(t *testing.T) {
 func TestHandleDeleteTag(t *testing.T) {
 	t.Parallel()
 
+	cfg := config.Config{DevUserID: uuid.MustParse("cdcdcdcd-cdcd-cdcd-cdcd-cdcdcdcdcdcd")}
 	queries := &mockQueries{
-		getTagFn: func(ctx context.Context, id int32) (db.Tag, error) {
-			return db.Tag{ID: id, Name: "alpha"}, nil
+		getTagFn: func(ctx context.Context, arg db.GetTagParams) (db.Tag, error) {
+			return db.Tag{ID: arg.ID, Name: "alpha", UserID: arg.UserID}, nil
 		},
-		deleteTagFn: func(ctx context.Context, id int32) error {
-			if id != 9 {
-				t.Fatalf("unexpected delete id: %d", id)
+		deleteTagFn: func(ctx context.Context, arg db.DeleteTagParams) error {
+			if arg.ID != 9 || arg.UserID != uuidToPg(cfg.DevUserID) {
+				t.Fatalf("unexpected delete params: %+v", arg)
 			}
 			return nil
 		},
 	}
 
-	srv := &Server{cfg: config.Config{}, queries: queries, metrics: newTestMetrics()}
+	srv := &Server{cfg: cfg, queries: queries, metrics: newTestMetrics()}
 	e := echo.New()
 	srv.RegisterRoutes(e)
 
@@ -1409,33 +1807,91 @@ func TestHandleDeleteTag(t *testing.T) {
 	}
 }
 
-func TestHandleReplaceLinkTags(t *testing.T) {
+func TestHandleDeleteLink(t *testing.T) {
 	t.Parallel()
 
 	cfg := config.Config{DevUserID: uuid.MustParse("dddddddd-dddd-dddd-dddd-dddddddddddd")}
 	linkID := uuid.New()
+	metrics := newTestMetrics()
 
-	currentTags := []db.Tag{{ID: 1, Name: "alpha"}, {ID: 2, Name: "beta"}}
-	added := make([]int32, 0)
-	removed := make([]int32, 0)
+	imageHash := media.Hash([]byte("image"))
 
 	queries := &mockQueries{
-		getLinkFn: func(ctx context.Context, id pgtype.UUID) (db.GetLinkRow, error) {
-			return db.GetLinkRow{ID: id, UserID: uuidToPg(cfg.DevUserID)}, nil
-		},
-		listTagsForLinkFn: func(ctx context.Context, id pgtype.UUID) ([]db.Tag, error) {
-			copyTags := make([]db.Tag, len(currentTags))
-			copy(copyTags, currentTags)
-			return copyTags, nil
+		listLinkMediaHashesFn: func(ctx context.Context, id pgtype.UUID) ([]string, error) {
+			if uuidFromPg(id) != linkID {
+				return nil, nil
+			}
+			return []string{imageHash}, nil
 		},
-		getTagFn: func(ctx context.Context, id int32) (db.Tag, error) {
-			switch id {
-			case 2:
-				return db.Tag{ID: 2, Name: "beta"}, nil
+		deleteLinkFn: func(ctx context.Context, params db.DeleteLinkParams) (int64, error) {
+			if uuidFromPg(params.UserID) != cfg.DevUserID {
+				t.Fatalf("expected delete scoped to the dev user, got %s", uuidFromPg(params.UserID))
+			}
+			if uuidFromPg(params.ID) == linkID {
+				return 1, nil
+			}
+			return 0, nil
+		},
+	}
+
+	publisher := &stubPublisher{}
+	srv := &Server{cfg: cfg, queries: queries, publisher: publisher, metrics: metrics}
+	e := echo.New()
+	srv.RegisterRoutes(e)
+
+	for path, want := range map[string]int{
+		"/api/links/" + linkID.String():  http.StatusNoContent,
+		"/api/links/" + uuid.NewString(): http.StatusNotFound,
+		"/api/links/not-a-uuid":          http.StatusBadRequest,
+	} {
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, httptest.NewRequest(http.MethodDelete, path, nil))
+		if rec.Code != want {
+			t.Fatalf("DELETE %s: expected status %d, got %d", path, want, rec.Code)
+		}
+	}
+	if got := testutil.ToFloat64(metrics.LinkDeleteSuccess); got != 1 {
+		t.Fatalf("unexpected success metric: got %v want 1", got)
+	}
+	if got := testutil.ToFloat64(metrics.LinkDeleteFailure); got != 2 {
+		t.Fatalf("unexpected failure metric: got %v want 2", got)
+	}
+	if len(publisher.events) != 1 || publisher.events[0].Type != queue.EventLinkDeleted || publisher.events[0].LinkID != linkID.String() {
+		t.Fatalf("expected one link.deleted event, got %+v", publisher.events)
+	}
+	if len(publisher.deleted) != 1 || publisher.deleted[0].LinkID != linkID.String() ||
+		len(publisher.deleted[0].MediaHashes) != 1 || publisher.deleted[0].MediaHashes[0] != imageHash {
+		t.Fatalf("expected the worker told to clean up %s's image, got %+v", linkID, publisher.deleted)
+	}
+}
+
+func TestHandleReplaceLinkTags(t *testing.T) {
+	t.Parallel()
+
+	cfg := config.Config{DevUserID: uuid.MustParse("dddddddd-dddd-dddd-dddd-dddddddddddd")}
+	linkID := uuid.New()
+
+	currentTags := []db.Tag{{ID: 1, Name: "alpha"}, {ID: 2, Name: "beta"}}
+	added := make([]int32, 0)
+	removed := make([]int32, 0)
+
+	queries := &mockQueries{
+		getLinkFn: func(ctx context.Context, id pgtype.UUID) (db.GetLinkRow, error) {
+			return db.GetLinkRow{ID: id, UserID: uuidToPg(cfg.DevUserID)}, nil
+		},
+		listTagsForLinkFn: func(ctx context.Context, id pgtype.UUID) ([]db.Tag, error) {
+			copyTags := make([]db.Tag, len(currentTags))
+			copy(copyTags, currentTags)
+			return copyTags, nil
+		},
+		getTagFn: func(ctx context.Context, arg db.GetTagParams) (db.Tag, error) {
+			switch arg.ID {
+			case 2:
+				return db.Tag{ID: 2, Name: "beta"}, nil
 			case 3:
 				return db.Tag{ID: 3, Name: "gamma"}, nil
 			default:
-				return db.Tag{}, fmt.Errorf("unexpected tag lookup: %d", id)
+				return db.Tag{}, fmt.Errorf("unexpected tag lookup: %d", arg.ID)
 			}
 		},
 		addTagToLinkFn: func(ctx context.Context, params db.AddTagToLinkParams) error {
@@ -1490,14 +1946,14 @@ func TestHandleReplaceLinkTagsIdempotent(t *testing.T) {
 		listTagsForLinkFn: func(ctx context.Context, id pgtype.UUID) ([]db.Tag, error) {
 			return []db.Tag{{ID: 1, Name: "alpha"}, {ID: 2, Name: "beta"}}, nil
 		},
-		getTagFn: func(ctx context.Context, id int32) (db.Tag, error) {
-			switch id {
+		getTagFn: func(ctx context.Context, arg db.GetTagParams) (db.Tag, error) {
+			switch arg.ID {
 			case 1:
 				return db.Tag{ID: 1, Name: "alpha"}, nil
 			case 2:
 				return db.Tag{ID: 2, Name: "beta"}, nil
 			default:
-				return db.Tag{}, fmt.Errorf("unexpected tag lookup: %d", id)
+				return db.Tag{}, fmt.Errorf("unexpected tag lookup: %d", arg.ID)
 			}
 		},
 		addTagToLinkFn: func(ctx context.Context, params db.AddTagToLinkParams) error {
@@ -1544,12 +2000,12 @@ func TestHandleAddLinkTagsReplacesAll(t *testing.T) {
 			copy(copyTags, currentTags)
 			return copyTags, nil
 		},
-		getTagFn: func(ctx context.Context, id int32) (db.Tag, error) {
-			switch id {
+		getTagFn: func(ctx context.Context, arg db.GetTagParams) (db.Tag, error) {
+			switch arg.ID {
 			case 6:
 				return db.Tag{ID: 6, Name: "new"}, nil
 			default:
-				return db.Tag{}, fmt.Errorf("unexpected tag lookup: %d", id)
+				return db.Tag{}, fmt.Errorf("unexpected tag lookup: %d", arg.ID)
 			}
 		},
 		addTagToLinkFn: func(ctx context.Context, params db.AddTagToLinkParams) error {
@@ -1668,7 +2124,8 @@ func TestHighlightLifecycle(t *testing.T) {
 		},
 	}
 
-	srv := &Server{cfg: cfg, queries: queries, metrics: newTestMetrics()}
+	publisher := &stubPublisher{}
+	srv := &Server{cfg: cfg, queries: queries, metrics: newTestMetrics(), publisher: publisher}
 	e := echo.New()
 	srv.RegisterRoutes(e)
 
@@ -1689,6 +2146,9 @@ func TestHighlightLifecycle(t *testing.T) {
 	if created.ID == "" {
 		t.Fatalf("expected highlight id in response")
 	}
+	if len(publisher.events) != 1 || publisher.events[0].Type != queue.EventHighlightCreated || publisher.events[0].LinkID != linkID.String() {
+		t.Fatalf("expected a highlight.created event, got %+v", publisher.events)
+	}
 
 	// Update highlight
 	updateBody := `{"text":"updated","note":"note"}`
@@ -1733,8 +2193,9 @@ func TestHighlightRateLimit(t *testing.T) {
 		},
 	}
 
-	srv := &Server{cfg: cfg, queries: queries, metrics: newTestMetrics(), highlightLimiters: make(map[uuid.UUID]*rate.Limiter), highlightRate: rate.Every(time.Minute), highlightBurst: 1}
-	srv.highlightLimiters[cfg.DevUserID] = rateLimiterZero()
+	limiter := NewLocalLimiter(RateLimitRule{Limit: rate.Every(time.Hour), Burst: 1}, time.Minute)
+	limiter.Allow(context.Background(), cfg.DevUserID.String())
+	srv := &Server{cfg: cfg, queries: queries, metrics: newTestMetrics(), highlightLimiter: limiter}
 
 	e := echo.New()
 	srv.RegisterRoutes(e)
@@ -1766,213 +2227,1668 @@ func TestParsePagination(t *testing.T) {
 	}
 }
 
-// --- Helpers ---
-
-type mockQueries struct {
-	createLinkFn                 func(context.Context, db.CreateLinkParams) (db.CreateLinkRow, error)
-	listLinksFn                  func(context.Context, db.ListLinksParams) ([]db.ListLinksRow, error)
-	countLinksFn                 func(context.Context, db.CountLinksParams) (int64, error)
-	updateLinkFavoriteFn         func(context.Context, db.UpdateLinkFavoriteParams) (db.UpdateLinkFavoriteRow, error)
-	listRecommendationsForUserFn func(context.Context, db.ListRecommendationsForUserParams) ([]db.ListRecommendationsForUserRow, error)
-	createClaimFn                func(context.Context, db.CreateClaimParams) (db.CreateClaimRow, error)
-	getTagByNameFn               func(context.Context, string) (db.Tag, error)
-	listTagLinkCountsFn          func(context.Context) ([]db.ListTagLinkCountsRow, error)
-	createTagFn                  func(context.Context, string) (db.Tag, error)
-	getTagFn                     func(context.Context, int32) (db.Tag, error)
-	updateTagFn                  func(context.Context, db.UpdateTagParams) (db.Tag, error)
-	deleteTagFn                  func(context.Context, int32) error
-	listTagsForLinkFn            func(context.Context, pgtype.UUID) ([]db.Tag, error)
-	addTagToLinkFn               func(context.Context, db.AddTagToLinkParams) error
-	removeTagFromLinkFn          func(context.Context, db.RemoveTagFromLinkParams) error
-	getLinkFn                    func(context.Context, pgtype.UUID) (db.GetLinkRow, error)
-	listHighlightsByLinkFn       func(context.Context, pgtype.UUID) ([]db.Highlight, error)
-	createHighlightFn            func(context.Context, db.CreateHighlightParams) (db.Highlight, error)
-	updateHighlightFn            func(context.Context, db.UpdateHighlightParams) (db.Highlight, error)
-	deleteHighlightFn            func(context.Context, pgtype.UUID) error
-
-	createLinkCalled         bool
-	createClaimCalled        bool
-	createTagCalled          bool
-	createHighlightCalled    bool
-	updateLinkFavoriteCalled bool
-}
+func TestHandleListRecommendationsFiltersAndCursor(t *testing.T) {
+	t.Parallel()
 
-func (m *mockQueries) CreateLink(ctx context.Context, params db.CreateLinkParams) (db.CreateLinkRow, error) {
-	m.createLinkCalled = true
-	if m.createLinkFn == nil {
-		return db.CreateLinkRow{}, fmt.Errorf("unexpected CreateLink call")
+	devUser := uuid.MustParse("11111111-1111-1111-1111-111111111111")
+	updatedAt := time.Date(2024, time.June, 1, 2, 0, 0, 0, time.UTC)
+	ids := []uuid.UUID{
+		uuid.MustParse("aaaaaaaa-aaaa-aaaa-aaaa-aaaaaaaaaaaa"),
+		uuid.MustParse("bbbbbbbb-bbbb-bbbb-bbbb-bbbbbbbbbbbb"),
+		uuid.MustParse("cccccccc-cccc-cccc-cccc-cccccccccccc"),
 	}
-	return m.createLinkFn(ctx, params)
-}
 
-func (m *mockQueries) ListLinks(ctx context.Context, params db.ListLinksParams) ([]db.ListLinksRow, error) {
-	if m.listLinksFn == nil {
-		return nil, fmt.Errorf("unexpected ListLinks call")
+	var calls []db.ListRecommendationsForUserParams
+	mock := &mockQueries{
+		getTagByNameFn: func(ctx context.Context, arg db.GetTagByNameParams) (db.Tag, error) {
+			if arg.Name != "golang" {
+				return db.Tag{}, pgx.ErrNoRows
+			}
+			return db.Tag{ID: 7, Name: arg.Name}, nil
+		},
+		listRecommendationsForUserFn: func(ctx context.Context, params db.ListRecommendationsForUserParams) ([]db.ListRecommendationsForUserRow, error) {
+			calls = append(calls, params)
+			rows := make([]db.ListRecommendationsForUserRow, 0, len(ids))
+			for i, id := range ids {
+				rows = append(rows, db.ListRecommendationsForUserRow{
+					ID:        uuidToPg(id),
+					Url:       "https://example.com/" + id.String(),
+					Score:     int32(30 - i),
+					UpdatedAt: pgtype.Timestamptz{Time: updatedAt, Valid: true},
+				})
+			}
+			return rows, nil
+		},
+		listTagsForLinkFn: func(ctx context.Context, id pgtype.UUID) ([]db.Tag, error) {
+			return nil, nil
+		},
+		listHighlightsByLinkFn: func(ctx context.Context, id pgtype.UUID) ([]db.Highlight, error) {
+			return nil, nil
+		},
 	}
-	return m.listLinksFn(ctx, params)
-}
 
-func (m *mockQueries) ListRecommendationsForUser(ctx context.Context, params db.ListRecommendationsForUserParams) ([]db.ListRecommendationsForUserRow, error) {
-	if m.listRecommendationsForUserFn == nil {
-		return nil, fmt.Errorf("unexpected ListRecommendationsForUser call")
-	}
-	return m.listRecommendationsForUserFn(ctx, params)
-}
+	srv := &Server{cfg: config.Config{DevUserID: devUser}, queries: mock, metrics: newTestMetrics()}
+	e := echo.New()
+	srv.RegisterRoutes(e)
 
-func (m *mockQueries) CountLinks(ctx context.Context, params db.CountLinksParams) (int64, error) {
-	if m.countLinksFn == nil {
-		return 0, fmt.Errorf("unexpected CountLinks call")
-	}
-	return m.countLinksFn(ctx, params)
-}
+	req := httptest.NewRequest(http.MethodGet, "/api/recommendations?limit=2&tags=golang&domain=Example.com", nil)
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
 
-func (m *mockQueries) UpdateLinkFavorite(ctx context.Context, params db.UpdateLinkFavoriteParams) (db.UpdateLinkFavoriteRow, error) {
-	m.updateLinkFavoriteCalled = true
-	if m.updateLinkFavoriteFn == nil {
-		return db.UpdateLinkFavoriteRow{}, fmt.Errorf("unexpected UpdateLinkFavorite call")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d -- %s", http.StatusOK, rec.Code, rec.Body.String())
 	}
-	return m.updateLinkFavoriteFn(ctx, params)
-}
-
-func (m *mockQueries) CreateClaim(ctx context.Context, params db.CreateClaimParams) (db.CreateClaimRow, error) {
-	m.createClaimCalled = true
-	if m.createClaimFn == nil {
-		return db.CreateClaimRow{}, fmt.Errorf("unexpected CreateClaim call")
+	if len(calls) != 1 {
+		t.Fatalf("expected one query, got %d", len(calls))
 	}
-	return m.createClaimFn(ctx, params)
-}
-
-func (m *mockQueries) GetTagByName(ctx context.Context, name string) (db.Tag, error) {
-	if m.getTagByNameFn == nil {
-		return db.Tag{}, fmt.Errorf("unexpected GetTagByName call")
+	first := calls[0]
+	if first.PageLimit != 3 || first.PageOffset != 0 {
+		t.Fatalf("expected limit+1 lookahead, got limit=%d offset=%d", first.PageLimit, first.PageOffset)
 	}
-	return m.getTagByNameFn(ctx, name)
-}
-
-func (m *mockQueries) ListTagLinkCounts(ctx context.Context) ([]db.ListTagLinkCountsRow, error) {
-	if m.listTagLinkCountsFn == nil {
-		return nil, fmt.Errorf("unexpected ListTagLinkCounts call")
+	if len(first.TagIds) != 1 || first.TagIds[0] != 7 {
+		t.Fatalf("unexpected tag ids: %v", first.TagIds)
 	}
-	return m.listTagLinkCountsFn(ctx)
-}
-
-func (m *mockQueries) CreateTag(ctx context.Context, name string) (db.Tag, error) {
-	m.createTagCalled = true
-	if m.createTagFn == nil {
-		return db.Tag{}, fmt.Errorf("unexpected CreateTag call")
+	if !first.Domain.Valid || first.Domain.String != "example.com" {
+		t.Fatalf("unexpected domain filter: %+v", first.Domain)
 	}
-	return m.createTagFn(ctx, name)
-}
-
-func (m *mockQueries) GetTag(ctx context.Context, id int32) (db.Tag, error) {
-	if m.getTagFn == nil {
-		return db.Tag{}, fmt.Errorf("unexpected GetTag call")
+	if first.CursorScore.Valid {
+		t.Fatalf("expected no cursor on first page")
 	}
-	return m.getTagFn(ctx, id)
-}
 
-func (m *mockQueries) UpdateTag(ctx context.Context, params db.UpdateTagParams) (db.Tag, error) {
-	if m.updateTagFn == nil {
-		return db.Tag{}, fmt.Errorf("unexpected UpdateTag call")
+	var payload struct {
+		Items      []linkResponse `json:"items"`
+		Count      int            `json:"count"`
+		NextCursor string         `json:"next_cursor"`
 	}
-	return m.updateTagFn(ctx, params)
-}
-
-func (m *mockQueries) DeleteTag(ctx context.Context, id int32) error {
-	if m.deleteTagFn == nil {
-		return fmt.Errorf("unexpected DeleteTag call")
+	if err := json.Unmarshal(rec.Body.Bytes(), &payload); err != nil {
+		t.Fatalf("decode response: %v", err)
 	}
-	return m.deleteTagFn(ctx, id)
-}
-
-func (m *mockQueries) ListTagsForLink(ctx context.Context, id pgtype.UUID) ([]db.Tag, error) {
-	if m.listTagsForLinkFn == nil {
-		return nil, fmt.Errorf("unexpected ListTagsForLink call")
+	if payload.Count != 2 || len(payload.Items) != 2 {
+		t.Fatalf("expected 2 items, got %d", len(payload.Items))
+	}
+	if payload.NextCursor == "" {
+		t.Fatalf("expected next cursor when more results exist")
 	}
-	return m.listTagsForLinkFn(ctx, id)
-}
 
-func (m *mockQueries) AddTagToLink(ctx context.Context, params db.AddTagToLinkParams) error {
-	if m.addTagToLinkFn == nil {
-		return fmt.Errorf("unexpected AddTagToLink call")
+	req = httptest.NewRequest(http.MethodGet, "/api/recommendations?limit=2&cursor="+payload.NextCursor, nil)
+	rec = httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d -- %s", http.StatusOK, rec.Code, rec.Body.String())
+	}
+	second := calls[1]
+	if !second.CursorScore.Valid || second.CursorScore.Int32 != 29 {
+		t.Fatalf("unexpected cursor score: %+v", second.CursorScore)
+	}
+	if !second.CursorUpdatedAt.Time.Equal(updatedAt) {
+		t.Fatalf("unexpected cursor timestamp: %v", second.CursorUpdatedAt.Time)
+	}
+	if uuidFromPg(second.CursorLinkID) != ids[1] {
+		t.Fatalf("unexpected cursor link id: %s", uuidFromPg(second.CursorLinkID))
 	}
-	return m.addTagToLinkFn(ctx, params)
 }
 
-func (m *mockQueries) RemoveTagFromLink(ctx context.Context, params db.RemoveTagFromLinkParams) error {
-	if m.removeTagFromLinkFn == nil {
-		return fmt.Errorf("unexpected RemoveTagFromLink call")
+func TestHandleListRecommendationsColdStart(t *testing.T) {
+	t.Parallel()
+
+	linkID := uuid.MustParse("dddddddd-dddd-dddd-dddd-dddddddddddd")
+	var captured db.ListColdStartLinksForUserParams
+	mock := &mockQueries{
+		listRecommendationsForUserFn: func(ctx context.Context, params db.ListRecommendationsForUserParams) ([]db.ListRecommendationsForUserRow, error) {
+			return nil, nil
+		},
+		listColdStartLinksForUserFn: func(ctx context.Context, params db.ListColdStartLinksForUserParams) ([]db.ListColdStartLinksForUserRow, error) {
+			captured = params
+			return []db.ListColdStartLinksForUserRow{{
+				ID:        uuidToPg(linkID),
+				Url:       "https://example.com/quick-read",
+				WordCount: 400,
+			}}, nil
+		},
+		listTagsForLinkFn: func(ctx context.Context, id pgtype.UUID) ([]db.Tag, error) {
+			return nil, nil
+		},
+		listHighlightsByLinkFn: func(ctx context.Context, id pgtype.UUID) ([]db.Highlight, error) {
+			return nil, nil
+		},
 	}
-	return m.removeTagFromLinkFn(ctx, params)
-}
 
-func (m *mockQueries) GetLink(ctx context.Context, id pgtype.UUID) (db.GetLinkRow, error) {
-	if m.getLinkFn == nil {
-		return db.GetLinkRow{}, fmt.Errorf("unexpected GetLink call")
+	srv := &Server{queries: mock, metrics: newTestMetrics()}
+	e := echo.New()
+	srv.RegisterRoutes(e)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/recommendations?limit=5", nil)
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d -- %s", http.StatusOK, rec.Code, rec.Body.String())
+	}
+	if captured.RowLimit != 5 || captured.RecentWindow != coldStartWindow {
+		t.Fatalf("unexpected cold start params: %+v", captured)
 	}
-	return m.getLinkFn(ctx, id)
-}
 
-func (m *mockQueries) ListHighlightsByLink(ctx context.Context, id pgtype.UUID) ([]db.Highlight, error) {
-	if m.listHighlightsByLinkFn == nil {
-		return nil, fmt.Errorf("unexpected ListHighlightsByLink call")
+	var payload struct {
+		Items  []linkResponse `json:"items"`
+		Source string         `json:"source"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &payload); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if payload.Source != "cold_start" {
+		t.Fatalf("expected cold_start source, got %q", payload.Source)
+	}
+	if len(payload.Items) != 1 || payload.Items[0].ID != linkID.String() || payload.Items[0].WordCount != 400 {
+		t.Fatalf("unexpected items: %+v", payload.Items)
 	}
-	return m.listHighlightsByLinkFn(ctx, id)
-}
 
-func (m *mockQueries) CreateHighlight(ctx context.Context, params db.CreateHighlightParams) (db.Highlight, error) {
-	m.createHighlightCalled = true
-	if m.createHighlightFn == nil {
-		return db.Highlight{}, fmt.Errorf("unexpected CreateHighlight call")
+	// Filtered pages stay empty rather than falling back.
+	mock.listColdStartLinksForUserFn = nil
+	req = httptest.NewRequest(http.MethodGet, "/api/recommendations?domain=example.com", nil)
+	rec = httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d for filtered page, got %d -- %s", http.StatusOK, rec.Code, rec.Body.String())
 	}
-	return m.createHighlightFn(ctx, params)
 }
 
-func (m *mockQueries) UpdateHighlight(ctx context.Context, params db.UpdateHighlightParams) (db.Highlight, error) {
-	if m.updateHighlightFn == nil {
-		return db.Highlight{}, fmt.Errorf("unexpected UpdateHighlight call")
+func TestHandleListRecommendationsInvalidFilters(t *testing.T) {
+	t.Parallel()
+
+	mock := &mockQueries{
+		getTagByNameFn: func(ctx context.Context, arg db.GetTagByNameParams) (db.Tag, error) {
+			return db.Tag{}, pgx.ErrNoRows
+		},
 	}
-	return m.updateHighlightFn(ctx, params)
-}
+	srv := &Server{queries: mock, metrics: newTestMetrics()}
+	e := echo.New()
+	srv.RegisterRoutes(e)
 
-func (m *mockQueries) DeleteHighlight(ctx context.Context, id pgtype.UUID) error {
-	if m.deleteHighlightFn == nil {
-		return fmt.Errorf("unexpected DeleteHighlight call")
+	for _, target := range []string{
+		"/api/recommendations?tags=missing",
+		"/api/recommendations?cursor=not-a-cursor",
+		"/api/recommendations?offset=5&cursor=" + encodeRecommendationCursor(recommendationCursor{score: 1, linkID: uuid.New()}),
+		"/api/recommendations?limit=abc",
+	} {
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusBadRequest {
+			t.Fatalf("%s: expected status %d, got %d", target, http.StatusBadRequest, rec.Code)
+		}
 	}
-	return m.deleteHighlightFn(ctx, id)
 }
 
-var _ queryProvider = (*mockQueries)(nil)
+func TestHandleMarkLinksReadQueuesRefresh(t *testing.T) {
+	t.Parallel()
 
-type stubPublisher struct {
-	called bool
-	lastID uuid.UUID
-}
+	devUser := uuid.MustParse("11111111-1111-1111-1111-111111111111")
+	first := uuid.MustParse("aaaaaaaa-aaaa-aaaa-aaaa-aaaaaaaaaaaa")
+	second := uuid.MustParse("bbbbbbbb-bbbb-bbbb-bbbb-bbbbbbbbbbbb")
 
-func (s *stubPublisher) PublishLinkSaved(ctx context.Context, linkID uuid.UUID) error {
-	s.called = true
-	s.lastID = linkID
-	return nil
-}
+	var captured db.MarkLinksReadParams
+	mock := &mockQueries{
+		markLinksReadFn: func(ctx context.Context, params db.MarkLinksReadParams) ([]pgtype.UUID, error) {
+			captured = params
+			return params.Ids, nil
+		},
+	}
+	publisher := &stubPublisher{}
+	metrics := newTestMetrics()
+	srv := &Server{
+		cfg:       config.Config{DevUserID: devUser, RecommendationRefreshThreshold: 2},
+		queries:   mock,
+		publisher: publisher,
+		metrics:   metrics,
+	}
 
-func (s *stubPublisher) Close() {}
+	e := echo.New()
+	srv.RegisterRoutes(e)
 
-var _ queue.Publisher = (*stubPublisher)(nil)
+	body := fmt.Sprintf(`{"ids":[%q,%q]}`, first, second)
+	req := httptest.NewRequest(http.MethodPost, "/api/links/read", strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
 
-type digestServiceFunc func(context.Context, uuid.UUID) (int, string, error)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d -- %s", http.StatusOK, rec.Code, rec.Body.String())
+	}
+	if uuidFromPg(captured.UserID) != devUser || len(captured.Ids) != 2 {
+		t.Fatalf("unexpected mark read params: %+v", captured)
+	}
+	if !publisher.refreshCalled || publisher.refreshUserID != devUser {
+		t.Fatalf("expected refresh to be queued for dev user")
+	}
+	if got := testutil.ToFloat64(metrics.ResurfaceQueued); got != 1 {
+		t.Fatalf("expected refresh queued metric 1, got %v", got)
+	}
 
-func (f digestServiceFunc) Send(ctx context.Context, userID uuid.UUID) (int, string, error) {
-	return f(ctx, userID)
+	var payload struct {
+		Updated       int  `json:"updated"`
+		RefreshQueued bool `json:"refresh_queued"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &payload); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if payload.Updated != 2 || !payload.RefreshQueued {
+		t.Fatalf("unexpected response: %+v", payload)
+	}
 }
 
-func newTestMetrics() *observability.Metrics {
-	return &observability.Metrics{
-		LinkCreateSuccess:          prometheus.NewCounter(prometheus.CounterOpts{Name: "test_link_create_success_total", Help: ""}),
-		LinkCreateFailure:          prometheus.NewCounter(prometheus.CounterOpts{Name: "test_link_create_failure_total", Help: ""}),
+func TestHandleMarkLinksReadBelowThreshold(t *testing.T) {
+	t.Parallel()
+
+	mock := &mockQueries{
+		markLinksReadFn: func(ctx context.Context, params db.MarkLinksReadParams) ([]pgtype.UUID, error) {
+			return params.Ids, nil
+		},
+	}
+	publisher := &stubPublisher{}
+	srv := &Server{
+		cfg:       config.Config{RecommendationRefreshThreshold: 10},
+		queries:   mock,
+		publisher: publisher,
+		metrics:   newTestMetrics(),
+	}
+
+	e := echo.New()
+	srv.RegisterRoutes(e)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/links/read", strings.NewReader(fmt.Sprintf(`{"ids":[%q]}`, uuid.New())))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d -- %s", http.StatusOK, rec.Code, rec.Body.String())
+	}
+	if publisher.refreshCalled {
+		t.Fatalf("did not expect refresh below threshold")
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/api/links/read", strings.NewReader(`{"ids":["nope"]}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec = httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected status %d for invalid id, got %d", http.StatusBadRequest, rec.Code)
+	}
+}
+
+func TestHandleSnoozeLink(t *testing.T) {
+	t.Parallel()
+
+	devUser := uuid.MustParse("11111111-1111-1111-1111-111111111111")
+	linkID := uuid.MustParse("33333333-3333-3333-3333-333333333333")
+
+	var snoozes []db.UpdateLinkSnoozeParams
+	mock := &mockQueries{
+		getLinkFn: func(ctx context.Context, id pgtype.UUID) (db.GetLinkRow, error) {
+			return db.GetLinkRow{ID: id, UserID: uuidToPg(devUser)}, nil
+		},
+		updateLinkSnoozeFn: func(ctx context.Context, params db.UpdateLinkSnoozeParams) error {
+			snoozes = append(snoozes, params)
+			return nil
+		},
+	}
+
+	srv := &Server{cfg: config.Config{DevUserID: devUser}, queries: mock, metrics: newTestMetrics()}
+	e := echo.New()
+	srv.RegisterRoutes(e)
+
+	before := time.Now().UTC()
+	req := httptest.NewRequest(http.MethodPost, "/api/links/"+linkID.String()+"/snooze", strings.NewReader(`{"days":3}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d -- %s", http.StatusOK, rec.Code, rec.Body.String())
+	}
+	if len(snoozes) != 1 || !snoozes[0].SnoozedUntil.Valid {
+		t.Fatalf("expected snooze to be stored, got %+v", snoozes)
+	}
+	if until := snoozes[0].SnoozedUntil.Time; until.Before(before.AddDate(0, 0, 3)) || until.After(time.Now().UTC().AddDate(0, 0, 3)) {
+		t.Fatalf("unexpected snooze deadline: %v", until)
+	}
+
+	req = httptest.NewRequest(http.MethodDelete, "/api/links/"+linkID.String()+"/snooze", nil)
+	rec = httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("expected status %d, got %d -- %s", http.StatusNoContent, rec.Code, rec.Body.String())
+	}
+	if len(snoozes) != 2 || snoozes[1].SnoozedUntil.Valid {
+		t.Fatalf("expected snooze to be cleared, got %+v", snoozes)
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/api/links/"+linkID.String()+"/snooze", strings.NewReader(`{"days":0}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec = httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected status %d for zero days, got %d", http.StatusBadRequest, rec.Code)
+	}
+}
+
+func TestHandleListOnThisDay(t *testing.T) {
+	t.Parallel()
+
+	devUser := uuid.MustParse("11111111-1111-1111-1111-111111111111")
+	linkID := uuid.MustParse("22222222-2222-2222-2222-222222222222")
+	savedAt := time.Date(2023, time.June, 15, 10, 0, 0, 0, time.UTC)
+
+	var captured db.ListOnThisDayLinksForUserParams
+	mock := &mockQueries{
+		listOnThisDayLinksForUserFn: func(ctx context.Context, params db.ListOnThisDayLinksForUserParams) ([]db.ListOnThisDayLinksForUserRow, error) {
+			captured = params
+			return []db.ListOnThisDayLinksForUserRow{{
+				ID:        uuidToPg(linkID),
+				Url:       "https://example.com/memory",
+				Title:     pgtype.Text{String: "Memory", Valid: true},
+				CreatedAt: pgtype.Timestamptz{Time: savedAt, Valid: true},
+				YearsAgo:  1,
+			}}, nil
+		},
+		listTagsForLinkFn: func(ctx context.Context, id pgtype.UUID) ([]db.Tag, error) {
+			return nil, nil
+		},
+		listHighlightsByLinkFn: func(ctx context.Context, id pgtype.UUID) ([]db.Highlight, error) {
+			return nil, nil
+		},
+	}
+
+	srv := &Server{cfg: config.Config{DevUserID: devUser}, queries: mock, metrics: newTestMetrics()}
+	e := echo.New()
+	srv.RegisterRoutes(e)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/recommendations/on-this-day?date=2024-06-15&limit=5", nil)
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d -- %s", http.StatusOK, rec.Code, rec.Body.String())
+	}
+	if uuidFromPg(captured.UserID) != devUser {
+		t.Fatalf("expected query for dev user, got %s", uuidFromPg(captured.UserID))
+	}
+	if got := captured.OnDate.Time.Format(time.DateOnly); got != "2024-06-15" {
+		t.Fatalf("expected on date 2024-06-15, got %s", got)
+	}
+	if captured.RowLimit != 5 {
+		t.Fatalf("expected limit 5, got %d", captured.RowLimit)
+	}
+	if len(captured.Years) != 3 || captured.Years[0] != 1 || captured.Years[1] != 2 || captured.Years[2] != 5 {
+		t.Fatalf("unexpected years: %v", captured.Years)
+	}
+
+	var payload struct {
+		Date  string `json:"date"`
+		Items []struct {
+			ID       string `json:"id"`
+			Title    string `json:"title"`
+			YearsAgo int    `json:"years_ago"`
+		} `json:"items"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &payload); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if payload.Date != "2024-06-15" {
+		t.Fatalf("unexpected date: %s", payload.Date)
+	}
+	if len(payload.Items) != 1 || payload.Items[0].ID != linkID.String() || payload.Items[0].YearsAgo != 1 || payload.Items[0].Title != "Memory" {
+		t.Fatalf("unexpected items: %+v", payload.Items)
+	}
+}
+
+func TestHandleListOnThisDayInvalidDate(t *testing.T) {
+	t.Parallel()
+
+	srv := &Server{queries: &mockQueries{}, metrics: newTestMetrics()}
+	e := echo.New()
+	srv.RegisterRoutes(e)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/recommendations/on-this-day?date=yesterday", nil)
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected status %d, got %d", http.StatusBadRequest, rec.Code)
+	}
+}
+
+func TestHandleListBackups(t *testing.T) {
+	t.Parallel()
+
+	started := time.Date(2024, time.March, 1, 3, 0, 0, 0, time.UTC)
+	finishedID := uuid.New()
+	runningID := uuid.New()
+	var captured db.ListBackupRunsParams
+	queries := &mockQueries{
+		listBackupRunsFn: func(ctx context.Context, arg db.ListBackupRunsParams) ([]db.BackupRun, error) {
+			captured = arg
+			return []db.BackupRun{
+				{
+					ID:        uuidToPg(runningID),
+					StartedAt: pgtype.Timestamptz{Time: started.Add(24 * time.Hour), Valid: true},
+					Status:    "running",
+					Kind:      "incremental",
+					Storage:   "s3",
+				},
+				{
+					ID:          uuidToPg(finishedID),
+					StartedAt:   pgtype.Timestamptz{Time: started, Valid: true},
+					FinishedAt:  pgtype.Timestamptz{Time: started.Add(time.Minute), Valid: true},
+					Status:      "success",
+					Kind:        "full",
+					Storage:     "s3",
+					File:        pgtype.Text{String: "keepstack-20240301-030000.sql.gz", Valid: true},
+					Destination: pgtype.Text{String: "s3://backups/keepstack-20240301-030000.sql.gz", Valid: true},
+					SizeBytes:   pgtype.Int8{Int64: 4096, Valid: true},
+					Checksum:    pgtype.Text{String: "abc123", Valid: true},
+				},
+			}, nil
+		},
+	}
+
+	srv := &Server{queries: queries, metrics: newTestMetrics()}
+	e := echo.New()
+	srv.RegisterRoutes(e)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/admin/backups?limit=5&offset=10", nil)
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, rec.Code)
+	}
+	if captured.PageLimit != 5 || captured.PageOffset != 10 {
+		t.Fatalf("unexpected pagination: %+v", captured)
+	}
+
+	var payload struct {
+		Items []backupRunResponse `json:"items"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &payload); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if len(payload.Items) != 2 {
+		t.Fatalf("expected 2 backup runs, got %d", len(payload.Items))
+	}
+
+	running := payload.Items[0]
+	if running.ID != runningID.String() || running.FinishedAt != nil || running.SizeBytes != nil {
+		t.Fatalf("unexpected running backup: %+v", running)
+	}
+
+	finished := payload.Items[1]
+	if finished.Status != "success" || finished.FinishedAt == nil || finished.SizeBytes == nil || *finished.SizeBytes != 4096 {
+		t.Fatalf("unexpected finished backup: %+v", finished)
+	}
+	if finished.Checksum != "abc123" || finished.Destination != "s3://backups/keepstack-20240301-030000.sql.gz" {
+		t.Fatalf("unexpected backup metadata: %+v", finished)
+	}
+}
+
+func TestHandleListJobs(t *testing.T) {
+	t.Parallel()
+
+	started := time.Date(2024, time.March, 1, 6, 0, 0, 0, time.UTC)
+	runID := uuid.New()
+	var captured db.ListCronRunsParams
+	queries := &mockQueries{
+		listCronRunsFn: func(ctx context.Context, arg db.ListCronRunsParams) ([]db.CronRun, error) {
+			captured = arg
+			return []db.CronRun{
+				{
+					ID:         uuidToPg(runID),
+					Subcommand: "resurface",
+					StartedAt:  pgtype.Timestamptz{Time: started, Valid: true},
+					FinishedAt: pgtype.Timestamptz{Time: started.Add(3 * time.Second), Valid: true},
+					Status:     "success",
+					DurationMs: pgtype.Int8{Int64: 3000, Valid: true},
+					Counts:     []byte(`{"users": 2, "recommendations": 40}`),
+				},
+				{
+					ID:         uuidToPg(uuid.New()),
+					Subcommand: "resurface",
+					StartedAt:  pgtype.Timestamptz{Time: started.Add(-time.Hour), Valid: true},
+					FinishedAt: pgtype.Timestamptz{Time: started.Add(-time.Hour), Valid: true},
+					Status:     "skipped",
+				},
+			}, nil
+		},
+	}
+
+	srv := &Server{queries: queries, metrics: newTestMetrics()}
+	e := echo.New()
+	srv.RegisterRoutes(e)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/admin/jobs?subcommand=resurface&limit=2", nil)
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, rec.Code)
+	}
+	if !captured.Subcommand.Valid || captured.Subcommand.String != "resurface" || captured.PageLimit != 2 {
+		t.Fatalf("unexpected query params: %+v", captured)
+	}
+
+	var payload struct {
+		Items []struct {
+			ID         string           `json:"id"`
+			Status     string           `json:"status"`
+			DurationMs *int64           `json:"duration_ms"`
+			Counts     map[string]int64 `json:"counts"`
+		} `json:"items"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &payload); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if len(payload.Items) != 2 {
+		t.Fatalf("expected 2 runs, got %d", len(payload.Items))
+	}
+
+	run := payload.Items[0]
+	if run.ID != runID.String() || run.DurationMs == nil || *run.DurationMs != 3000 {
+		t.Fatalf("unexpected run: %+v", run)
+	}
+	if run.Counts["recommendations"] != 40 || run.Counts["users"] != 2 {
+		t.Fatalf("unexpected counts: %+v", run.Counts)
+	}
+
+	skipped := payload.Items[1]
+	if skipped.Status != "skipped" || skipped.DurationMs != nil || skipped.Counts == nil {
+		t.Fatalf("unexpected skipped run: %+v", skipped)
+	}
+}
+
+func TestHandleHealthzBackupFreshness(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name        string
+		latest      pgtype.Timestamptz
+		wantStatus  string
+		wantWarning string
+	}{
+		{
+			name:       "recent backup",
+			latest:     pgtype.Timestamptz{Time: time.Now().Add(-time.Hour), Valid: true},
+			wantStatus: "ok",
+		},
+		{
+			name:        "stale backup",
+			latest:      pgtype.Timestamptz{Time: time.Now().Add(-72 * time.Hour), Valid: true},
+			wantStatus:  "degraded",
+			wantWarning: "backup older than 48h",
+		},
+		{
+			name:        "no backups",
+			wantStatus:  "degraded",
+			wantWarning: "no successful backup recorded",
+		},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			srv := &Server{
+				cfg:     config.Config{HealthBackupMaxAge: 48 * time.Hour},
+				metrics: newTestMetrics(),
+				pool:    backupFreshnessPool{latest: tc.latest},
+			}
+			e := echo.New()
+			srv.RegisterRoutes(e)
+
+			req := httptest.NewRequest(http.MethodGet, "/healthz", nil)
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+
+			if rec.Code != http.StatusOK {
+				t.Fatalf("expected status %d, got %d", http.StatusOK, rec.Code)
+			}
+
+			var payload map[string]string
+			if err := json.Unmarshal(rec.Body.Bytes(), &payload); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+			if payload["status"] != tc.wantStatus || payload["warning"] != tc.wantWarning {
+				t.Fatalf("unexpected payload: %+v", payload)
+			}
+		})
+	}
+}
+
+func TestHandleHealthzDraining(t *testing.T) {
+	t.Parallel()
+
+	srv := &Server{cfg: config.Config{}, metrics: newTestMetrics(), pool: stubHealthPool{}}
+	srv.BeginDrain()
+	e := echo.New()
+	srv.RegisterRoutes(e)
+
+	req := httptest.NewRequest(http.MethodGet, "/healthz", nil)
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected status %d, got %d", http.StatusServiceUnavailable, rec.Code)
+	}
+	if !strings.Contains(rec.Body.String(), `"draining"`) {
+		t.Fatalf("expected draining status, got %s", rec.Body.String())
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/livez", nil)
+	rec = httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected liveness to stay %d while draining, got %d", http.StatusOK, rec.Code)
+	}
+}
+
+func TestHandleHealthzSchemaVersion(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name       string
+		applied    int64
+		wantStatus int
+	}{
+		{name: "database matches build", applied: 18, wantStatus: http.StatusOK},
+		{name: "database ahead of build", applied: 19, wantStatus: http.StatusOK},
+		{name: "database behind build", applied: 17, wantStatus: http.StatusServiceUnavailable},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			metrics := newTestMetrics()
+			srv := &Server{cfg: config.Config{}, metrics: metrics, pool: schemaVersionPool{applied: tc.applied}}
+			srv.WithSchemaVersion(18)
+			e := echo.New()
+			srv.RegisterRoutes(e)
+
+			req := httptest.NewRequest(http.MethodGet, "/healthz", nil)
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+
+			if rec.Code != tc.wantStatus {
+				t.Fatalf("expected status %d, got %d: %s", tc.wantStatus, rec.Code, rec.Body.String())
+			}
+			gaps := testutil.ToFloat64(metrics.ReadinessMigrationGap)
+			if tc.wantStatus == http.StatusServiceUnavailable {
+				if !strings.Contains(rec.Body.String(), "apply outstanding database migrations") {
+					t.Fatalf("expected a migration hint, got %s", rec.Body.String())
+				}
+				if gaps != 1 {
+					t.Fatalf("expected a migration gap to be counted, got %v", gaps)
+				}
+			} else if gaps != 0 {
+				t.Fatalf("expected no migration gap, got %v", gaps)
+			}
+		})
+	}
+}
+
+// --- Helpers ---
+
+type mockQueries struct {
+	createLinkFn                         func(context.Context, db.CreateLinkParams) (db.CreateLinkRow, error)
+	listLinksFn                          func(context.Context, db.ListLinksParams) ([]db.ListLinksRow, error)
+	listLinksWithTagsFn                  func(context.Context, db.ListLinksWithTagsParams) ([]db.ListLinksWithTagsRow, error)
+	countLinksFn                         func(context.Context, db.CountLinksParams) (int64, error)
+	countLinksWithTagsFn                 func(context.Context, db.CountLinksWithTagsParams) (int64, error)
+	updateLinkFavoriteFn                 func(context.Context, db.UpdateLinkFavoriteParams) (db.UpdateLinkFavoriteRow, error)
+	markLinksReadFn                      func(context.Context, db.MarkLinksReadParams) ([]pgtype.UUID, error)
+	markLinksReadAtFn                    func(context.Context, db.MarkLinksReadAtParams) ([]pgtype.UUID, error)
+	updateLinkSnoozeFn                   func(context.Context, db.UpdateLinkSnoozeParams) error
+	listRecommendationsForUserFn         func(context.Context, db.ListRecommendationsForUserParams) ([]db.ListRecommendationsForUserRow, error)
+	listOnThisDayLinksForUserFn          func(context.Context, db.ListOnThisDayLinksForUserParams) ([]db.ListOnThisDayLinksForUserRow, error)
+	listColdStartLinksForUserFn          func(context.Context, db.ListColdStartLinksForUserParams) ([]db.ListColdStartLinksForUserRow, error)
+	createClaimFn                        func(context.Context, db.CreateClaimParams) (db.CreateClaimRow, error)
+	listClaimsForUserFn                  func(context.Context, pgtype.UUID) ([]db.ListClaimsForUserRow, error)
+	deleteClaimFn                        func(context.Context, db.DeleteClaimParams) (int64, error)
+	getTagByNameFn                       func(context.Context, db.GetTagByNameParams) (db.Tag, error)
+	listTagLinkCountsFn                  func(context.Context, pgtype.UUID) ([]db.ListTagLinkCountsRow, error)
+	createTagFn                          func(context.Context, db.CreateTagParams) (db.Tag, error)
+	getTagFn                             func(context.Context, db.GetTagParams) (db.Tag, error)
+	updateTagFn                          func(context.Context, db.UpdateTagParams) (db.Tag, error)
+	deleteTagFn                          func(context.Context, db.DeleteTagParams) error
+	listTagsForLinkFn                    func(context.Context, pgtype.UUID) ([]db.Tag, error)
+	addTagToLinkFn                       func(context.Context, db.AddTagToLinkParams) error
+	removeTagFromLinkFn                  func(context.Context, db.RemoveTagFromLinkParams) error
+	getLinkFn                            func(context.Context, pgtype.UUID) (db.GetLinkRow, error)
+	getReaderArchiveFn                   func(context.Context, pgtype.UUID) (db.GetReaderArchiveRow, error)
+	listLinkMediaHashesFn                func(context.Context, pgtype.UUID) ([]string, error)
+	listHighlightsByLinkFn               func(context.Context, pgtype.UUID) ([]db.Highlight, error)
+	createHighlightFn                    func(context.Context, db.CreateHighlightParams) (db.Highlight, error)
+	updateHighlightFn                    func(context.Context, db.UpdateHighlightParams) (db.Highlight, error)
+	deleteHighlightFn                    func(context.Context, pgtype.UUID) error
+	listBackupRunsFn                     func(context.Context, db.ListBackupRunsParams) ([]db.BackupRun, error)
+	listCronRunsFn                       func(context.Context, db.ListCronRunsParams) ([]db.CronRun, error)
+	createCronRunFn                      func(context.Context, string) (db.CronRun, error)
+	finishCronRunFn                      func(context.Context, db.FinishCronRunParams) error
+	createUserFn                         func(context.Context, db.CreateUserParams) (db.User, error)
+	getLinkIngestStatusFn                func(context.Context, pgtype.UUID) (db.LinkIngestStatus, error)
+	findLinkByURLHashFn                  func(context.Context, db.FindLinkByURLHashParams) (db.FindLinkByURLHashRow, error)
+	upsertLinkCaptureFn                  func(context.Context, db.UpsertLinkCaptureParams) error
+	searchTagsByPrefixFn                 func(context.Context, db.SearchTagsByPrefixParams) ([]db.Tag, error)
+	getActivityPubActorFn                func(context.Context, pgtype.UUID) (db.ActivitypubActor, error)
+	getActivityPubActorByUsernameFn      func(context.Context, string) (db.ActivitypubActor, error)
+	upsertActivityPubActorFn             func(context.Context, db.UpsertActivityPubActorParams) (db.ActivitypubActor, error)
+	deleteActivityPubActorFn             func(context.Context, pgtype.UUID) error
+	addActivityPubFollowerFn             func(context.Context, db.AddActivityPubFollowerParams) error
+	removeActivityPubFollowerFn          func(context.Context, db.RemoveActivityPubFollowerParams) error
+	countActivityPubFollowersFn          func(context.Context, pgtype.UUID) (int64, error)
+	listActivityPubFollowerInboxesFn     func(context.Context, pgtype.UUID) ([]string, error)
+	setLinkPublicFn                      func(context.Context, db.SetLinkPublicParams) (db.SetLinkPublicRow, error)
+	getPublicLinkFn                      func(context.Context, pgtype.UUID) (db.GetPublicLinkRow, error)
+	listPublicLinksFn                    func(context.Context, db.ListPublicLinksParams) ([]db.ListPublicLinksRow, error)
+	countPublicLinksFn                   func(context.Context, pgtype.UUID) (int64, error)
+	listSyncChangesFn                    func(context.Context, db.ListSyncChangesParams) ([]db.ListSyncChangesRow, error)
+	getSyncChangeSinceFn                 func(context.Context, db.GetSyncChangeSinceParams) (bool, error)
+	listSyncLinksFn                      func(context.Context, db.ListSyncLinksParams) ([]db.ListSyncLinksRow, error)
+	listSyncHighlightsFn                 func(context.Context, db.ListSyncHighlightsParams) ([]db.Highlight, error)
+	updateSyncLinkFn                     func(context.Context, db.UpdateSyncLinkParams) (int64, error)
+	deleteLinkFn                         func(context.Context, db.DeleteLinkParams) (int64, error)
+	createInboundHookFn                  func(context.Context, db.CreateInboundHookParams) (db.InboundHook, error)
+	listInboundHooksFn                   func(context.Context, pgtype.UUID) ([]db.InboundHook, error)
+	getInboundHookByTokenHashFn          func(context.Context, string) (db.InboundHook, error)
+	touchInboundHookFn                   func(context.Context, pgtype.UUID) error
+	deleteInboundHookFn                  func(context.Context, db.DeleteInboundHookParams) (int64, error)
+	getUserIDByEmailFn                   func(context.Context, string) (pgtype.UUID, error)
+	getUserByEmailFn                     func(context.Context, string) (db.User, error)
+	upsertLinkShareFn                    func(context.Context, db.UpsertLinkShareParams) (db.LinkShare, error)
+	listLinkSharesFn                     func(context.Context, pgtype.UUID) ([]db.ListLinkSharesRow, error)
+	deleteLinkShareFn                    func(context.Context, db.DeleteLinkShareParams) (int64, error)
+	getLinkSharePermissionFn             func(context.Context, db.GetLinkSharePermissionParams) (string, error)
+	listLinksSharedWithUserFn            func(context.Context, pgtype.UUID) ([]db.ListLinksSharedWithUserRow, error)
+	createNotificationFn                 func(context.Context, db.CreateNotificationParams) (db.Notification, error)
+	createAdminNotificationsFn           func(context.Context, db.CreateAdminNotificationsParams) (int64, error)
+	listNotificationsFn                  func(context.Context, db.ListNotificationsParams) ([]db.Notification, error)
+	countUnreadNotificationsFn           func(context.Context, pgtype.UUID) (int64, error)
+	markNotificationReadFn               func(context.Context, db.MarkNotificationReadParams) (int64, error)
+	markAllNotificationsReadFn           func(context.Context, pgtype.UUID) (int64, error)
+	upsertReadingGoalFn                  func(context.Context, db.UpsertReadingGoalParams) (db.ReadingGoal, error)
+	listReadingGoalsFn                   func(context.Context, pgtype.UUID) ([]db.ReadingGoal, error)
+	deleteReadingGoalFn                  func(context.Context, db.DeleteReadingGoalParams) (int64, error)
+	getReadingProgressFn                 func(context.Context, db.GetReadingProgressParams) (db.GetReadingProgressRow, error)
+	listReadDaysFn                       func(context.Context, pgtype.UUID) ([]pgtype.Date, error)
+	listSimilarLinksFn                   func(context.Context, db.ListSimilarLinksParams) ([]db.ListSimilarLinksRow, error)
+	upsertDomainPreferenceFn             func(context.Context, db.UpsertDomainPreferenceParams) (db.DomainPreference, error)
+	listDomainPreferencesFn              func(context.Context, pgtype.UUID) ([]db.DomainPreference, error)
+	matchDomainPreferenceFn              func(context.Context, db.MatchDomainPreferenceParams) (db.DomainPreference, error)
+	deleteDomainPreferenceFn             func(context.Context, db.DeleteDomainPreferenceParams) (int64, error)
+	takeDailyQuotaFn                     func(context.Context, db.TakeDailyQuotaParams) (int32, error)
+	deleteUsageQuotasBeforeFn            func(context.Context, pgtype.Date) (int64, error)
+	applyTagToMatchingLinksFn            func(context.Context, db.ApplyTagToMatchingLinksParams) (db.ApplyTagToMatchingLinksRow, error)
+	listArchiveVersionsFn                func(context.Context, pgtype.UUID) ([]db.ListArchiveVersionsRow, error)
+	getArchiveVersionFn                  func(context.Context, db.GetArchiveVersionParams) (db.ArchiveVersion, error)
+	getCurrentArchiveVersionFn           func(context.Context, pgtype.UUID) (db.GetCurrentArchiveVersionRow, error)
+	getPublicProfileFn                   func(context.Context, pgtype.UUID) (db.PublicProfile, error)
+	getPublicProfileByUsernameFn         func(context.Context, string) (db.PublicProfile, error)
+	upsertPublicProfileFn                func(context.Context, db.UpsertPublicProfileParams) (db.PublicProfile, error)
+	deletePublicProfileFn                func(context.Context, pgtype.UUID) (int64, error)
+	listPublicFavoritesFn                func(context.Context, db.ListPublicFavoritesParams) ([]db.ListPublicFavoritesRow, error)
+	recordRecommendationImpressionsFn    func(context.Context, db.RecordRecommendationImpressionsParams) (int64, error)
+	markRecommendationImpressionsActedFn func(context.Context, db.MarkRecommendationImpressionsActedParams) ([]string, error)
+	listRecommendationImpressionStatsFn  func(context.Context, db.ListRecommendationImpressionStatsParams) ([]db.ListRecommendationImpressionStatsRow, error)
+	createJobFn                          func(context.Context, db.CreateJobParams) (db.Job, error)
+	getJobFn                             func(context.Context, db.GetJobParams) (db.Job, error)
+	updateJobProgressFn                  func(context.Context, db.UpdateJobProgressParams) error
+	finishJobFn                          func(context.Context, db.FinishJobParams) error
+	saveJobFileFn                        func(context.Context, db.SaveJobFileParams) error
+	getJobFileFn                         func(context.Context, db.GetJobFileParams) (db.JobFile, error)
+	deleteJobsFinishedBeforeFn           func(context.Context, pgtype.Timestamptz) (int64, error)
+
+	createLinkCalled         bool
+	createClaimCalled        bool
+	createTagCalled          bool
+	createHighlightCalled    bool
+	updateLinkFavoriteCalled bool
+}
+
+func (m *mockQueries) CreateLink(ctx context.Context, params db.CreateLinkParams) (db.CreateLinkRow, error) {
+	m.createLinkCalled = true
+	if m.createLinkFn == nil {
+		return db.CreateLinkRow{}, fmt.Errorf("unexpected CreateLink call")
+	}
+	return m.createLinkFn(ctx, params)
+}
+
+func (m *mockQueries) ListLinks(ctx context.Context, params db.ListLinksParams) ([]db.ListLinksRow, error) {
+	if m.listLinksFn == nil {
+		return nil, fmt.Errorf("unexpected ListLinks call")
+	}
+	return m.listLinksFn(ctx, params)
+}
+
+func (m *mockQueries) ListLinksWithTags(ctx context.Context, params db.ListLinksWithTagsParams) ([]db.ListLinksWithTagsRow, error) {
+	if m.listLinksWithTagsFn == nil {
+		return nil, fmt.Errorf("unexpected ListLinksWithTags call")
+	}
+	return m.listLinksWithTagsFn(ctx, params)
+}
+
+func (m *mockQueries) ListRecommendationsForUser(ctx context.Context, params db.ListRecommendationsForUserParams) ([]db.ListRecommendationsForUserRow, error) {
+	if m.listRecommendationsForUserFn == nil {
+		return nil, fmt.Errorf("unexpected ListRecommendationsForUser call")
+	}
+	return m.listRecommendationsForUserFn(ctx, params)
+}
+
+func (m *mockQueries) ListOnThisDayLinksForUser(ctx context.Context, params db.ListOnThisDayLinksForUserParams) ([]db.ListOnThisDayLinksForUserRow, error) {
+	if m.listOnThisDayLinksForUserFn == nil {
+		return nil, fmt.Errorf("unexpected ListOnThisDayLinksForUser call")
+	}
+	return m.listOnThisDayLinksForUserFn(ctx, params)
+}
+
+func (m *mockQueries) ListColdStartLinksForUser(ctx context.Context, params db.ListColdStartLinksForUserParams) ([]db.ListColdStartLinksForUserRow, error) {
+	if m.listColdStartLinksForUserFn == nil {
+		return nil, fmt.Errorf("unexpected ListColdStartLinksForUser call")
+	}
+	return m.listColdStartLinksForUserFn(ctx, params)
+}
+
+func (m *mockQueries) CountLinks(ctx context.Context, params db.CountLinksParams) (int64, error) {
+	if m.countLinksFn == nil {
+		return 0, fmt.Errorf("unexpected CountLinks call")
+	}
+	return m.countLinksFn(ctx, params)
+}
+
+func (m *mockQueries) CountLinksWithTags(ctx context.Context, params db.CountLinksWithTagsParams) (int64, error) {
+	if m.countLinksWithTagsFn == nil {
+		return 0, fmt.Errorf("unexpected CountLinksWithTags call")
+	}
+	return m.countLinksWithTagsFn(ctx, params)
+}
+
+func (m *mockQueries) UpdateLinkFavorite(ctx context.Context, params db.UpdateLinkFavoriteParams) (db.UpdateLinkFavoriteRow, error) {
+	m.updateLinkFavoriteCalled = true
+	if m.updateLinkFavoriteFn == nil {
+		return db.UpdateLinkFavoriteRow{}, fmt.Errorf("unexpected UpdateLinkFavorite call")
+	}
+	return m.updateLinkFavoriteFn(ctx, params)
+}
+
+func (m *mockQueries) MarkLinksRead(ctx context.Context, params db.MarkLinksReadParams) ([]pgtype.UUID, error) {
+	if m.markLinksReadFn == nil {
+		return nil, fmt.Errorf("unexpected MarkLinksRead call")
+	}
+	return m.markLinksReadFn(ctx, params)
+}
+
+func (m *mockQueries) MarkLinksReadAt(ctx context.Context, params db.MarkLinksReadAtParams) ([]pgtype.UUID, error) {
+	if m.markLinksReadAtFn == nil {
+		return nil, fmt.Errorf("unexpected MarkLinksReadAt call")
+	}
+	return m.markLinksReadAtFn(ctx, params)
+}
+
+func (m *mockQueries) UpdateLinkSnooze(ctx context.Context, params db.UpdateLinkSnoozeParams) error {
+	if m.updateLinkSnoozeFn == nil {
+		return fmt.Errorf("unexpected UpdateLinkSnooze call")
+	}
+	return m.updateLinkSnoozeFn(ctx, params)
+}
+
+func (m *mockQueries) CreateClaim(ctx context.Context, params db.CreateClaimParams) (db.CreateClaimRow, error) {
+	m.createClaimCalled = true
+	if m.createClaimFn == nil {
+		return db.CreateClaimRow{}, fmt.Errorf("unexpected CreateClaim call")
+	}
+	return m.createClaimFn(ctx, params)
+}
+
+func (m *mockQueries) ListClaimsForUser(ctx context.Context, userID pgtype.UUID) ([]db.ListClaimsForUserRow, error) {
+	if m.listClaimsForUserFn == nil {
+		return nil, fmt.Errorf("unexpected ListClaimsForUser call")
+	}
+	return m.listClaimsForUserFn(ctx, userID)
+}
+
+func (m *mockQueries) DeleteClaim(ctx context.Context, arg db.DeleteClaimParams) (int64, error) {
+	if m.deleteClaimFn == nil {
+		return 0, fmt.Errorf("unexpected DeleteClaim call")
+	}
+	return m.deleteClaimFn(ctx, arg)
+}
+
+func (m *mockQueries) GetTagByName(ctx context.Context, arg db.GetTagByNameParams) (db.Tag, error) {
+	if m.getTagByNameFn == nil {
+		return db.Tag{}, fmt.Errorf("unexpected GetTagByName call")
+	}
+	return m.getTagByNameFn(ctx, arg)
+}
+
+func (m *mockQueries) ListTagLinkCounts(ctx context.Context, userID pgtype.UUID) ([]db.ListTagLinkCountsRow, error) {
+	if m.listTagLinkCountsFn == nil {
+		return nil, fmt.Errorf("unexpected ListTagLinkCounts call")
+	}
+	return m.listTagLinkCountsFn(ctx, userID)
+}
+
+func (m *mockQueries) CreateTag(ctx context.Context, arg db.CreateTagParams) (db.Tag, error) {
+	m.createTagCalled = true
+	if m.createTagFn == nil {
+		return db.Tag{}, fmt.Errorf("unexpected CreateTag call")
+	}
+	return m.createTagFn(ctx, arg)
+}
+
+func (m *mockQueries) GetTag(ctx context.Context, arg db.GetTagParams) (db.Tag, error) {
+	if m.getTagFn == nil {
+		return db.Tag{}, fmt.Errorf("unexpected GetTag call")
+	}
+	return m.getTagFn(ctx, arg)
+}
+
+func (m *mockQueries) UpdateTag(ctx context.Context, params db.UpdateTagParams) (db.Tag, error) {
+	if m.updateTagFn == nil {
+		return db.Tag{}, fmt.Errorf("unexpected UpdateTag call")
+	}
+	return m.updateTagFn(ctx, params)
+}
+
+func (m *mockQueries) DeleteTag(ctx context.Context, arg db.DeleteTagParams) error {
+	if m.deleteTagFn == nil {
+		return fmt.Errorf("unexpected DeleteTag call")
+	}
+	return m.deleteTagFn(ctx, arg)
+}
+
+func (m *mockQueries) ListTagsForLink(ctx context.Context, id pgtype.UUID) ([]db.Tag, error) {
+	if m.listTagsForLinkFn == nil {
+		return nil, fmt.Errorf("unexpected ListTagsForLink call")
+	}
+	return m.listTagsForLinkFn(ctx, id)
+}
+
+func (m *mockQueries) AddTagToLink(ctx context.Context, params db.AddTagToLinkParams) error {
+	if m.addTagToLinkFn == nil {
+		return fmt.Errorf("unexpected AddTagToLink call")
+	}
+	return m.addTagToLinkFn(ctx, params)
+}
+
+func (m *mockQueries) RemoveTagFromLink(ctx context.Context, params db.RemoveTagFromLinkParams) error {
+	if m.removeTagFromLinkFn == nil {
+		return fmt.Errorf("unexpected RemoveTagFromLink call")
+	}
+	return m.removeTagFromLinkFn(ctx, params)
+}
+
+func (m *mockQueries) GetLink(ctx context.Context, id pgtype.UUID) (db.GetLinkRow, error) {
+	if m.getLinkFn == nil {
+		return db.GetLinkRow{}, fmt.Errorf("unexpected GetLink call")
+	}
+	return m.getLinkFn(ctx, id)
+}
+
+func (m *mockQueries) GetReaderArchive(ctx context.Context, linkID pgtype.UUID) (db.GetReaderArchiveRow, error) {
+	if m.getReaderArchiveFn == nil {
+		return db.GetReaderArchiveRow{}, fmt.Errorf("unexpected GetReaderArchive call")
+	}
+	return m.getReaderArchiveFn(ctx, linkID)
+}
+
+func (m *mockQueries) ListLinkMediaHashes(ctx context.Context, linkID pgtype.UUID) ([]string, error) {
+	if m.listLinkMediaHashesFn == nil {
+		return nil, fmt.Errorf("unexpected ListLinkMediaHashes call")
+	}
+	return m.listLinkMediaHashesFn(ctx, linkID)
+}
+
+func (m *mockQueries) ListHighlightsByLink(ctx context.Context, id pgtype.UUID) ([]db.Highlight, error) {
+	if m.listHighlightsByLinkFn == nil {
+		return nil, fmt.Errorf("unexpected ListHighlightsByLink call")
+	}
+	return m.listHighlightsByLinkFn(ctx, id)
+}
+
+func (m *mockQueries) CreateHighlight(ctx context.Context, params db.CreateHighlightParams) (db.Highlight, error) {
+	m.createHighlightCalled = true
+	if m.createHighlightFn == nil {
+		return db.Highlight{}, fmt.Errorf("unexpected CreateHighlight call")
+	}
+	return m.createHighlightFn(ctx, params)
+}
+
+func (m *mockQueries) UpdateHighlight(ctx context.Context, params db.UpdateHighlightParams) (db.Highlight, error) {
+	if m.updateHighlightFn == nil {
+		return db.Highlight{}, fmt.Errorf("unexpected UpdateHighlight call")
+	}
+	return m.updateHighlightFn(ctx, params)
+}
+
+func (m *mockQueries) DeleteHighlight(ctx context.Context, id pgtype.UUID) error {
+	if m.deleteHighlightFn == nil {
+		return fmt.Errorf("unexpected DeleteHighlight call")
+	}
+	return m.deleteHighlightFn(ctx, id)
+}
+
+func (m *mockQueries) ListBackupRuns(ctx context.Context, arg db.ListBackupRunsParams) ([]db.BackupRun, error) {
+	if m.listBackupRunsFn == nil {
+		return nil, fmt.Errorf("unexpected ListBackupRuns call")
+	}
+	return m.listBackupRunsFn(ctx, arg)
+}
+
+func (m *mockQueries) ListCronRuns(ctx context.Context, arg db.ListCronRunsParams) ([]db.CronRun, error) {
+	if m.listCronRunsFn == nil {
+		return nil, fmt.Errorf("unexpected ListCronRuns call")
+	}
+	return m.listCronRunsFn(ctx, arg)
+}
+
+func (m *mockQueries) CreateCronRun(ctx context.Context, subcommand string) (db.CronRun, error) {
+	if m.createCronRunFn == nil {
+		return db.CronRun{}, fmt.Errorf("unexpected CreateCronRun call")
+	}
+	return m.createCronRunFn(ctx, subcommand)
+}
+
+func (m *mockQueries) FinishCronRun(ctx context.Context, arg db.FinishCronRunParams) error {
+	if m.finishCronRunFn == nil {
+		return fmt.Errorf("unexpected FinishCronRun call")
+	}
+	return m.finishCronRunFn(ctx, arg)
+}
+
+func (m *mockQueries) CreateUser(ctx context.Context, arg db.CreateUserParams) (db.User, error) {
+	if m.createUserFn == nil {
+		return db.User{}, fmt.Errorf("unexpected CreateUser call")
+	}
+	return m.createUserFn(ctx, arg)
+}
+
+func (m *mockQueries) GetLinkIngestStatus(ctx context.Context, id pgtype.UUID) (db.LinkIngestStatus, error) {
+	if m.getLinkIngestStatusFn == nil {
+		return db.LinkIngestStatus{}, fmt.Errorf("unexpected GetLinkIngestStatus call")
+	}
+	return m.getLinkIngestStatusFn(ctx, id)
+}
+
+func (m *mockQueries) FindLinkByURLHash(ctx context.Context, arg db.FindLinkByURLHashParams) (db.FindLinkByURLHashRow, error) {
+	if m.findLinkByURLHashFn == nil {
+		return db.FindLinkByURLHashRow{}, fmt.Errorf("unexpected FindLinkByURLHash call")
+	}
+	return m.findLinkByURLHashFn(ctx, arg)
+}
+
+func (m *mockQueries) UpsertLinkCapture(ctx context.Context, arg db.UpsertLinkCaptureParams) error {
+	if m.upsertLinkCaptureFn == nil {
+		return fmt.Errorf("unexpected UpsertLinkCapture call")
+	}
+	return m.upsertLinkCaptureFn(ctx, arg)
+}
+
+func (m *mockQueries) SearchTagsByPrefix(ctx context.Context, arg db.SearchTagsByPrefixParams) ([]db.Tag, error) {
+	if m.searchTagsByPrefixFn == nil {
+		return nil, fmt.Errorf("unexpected SearchTagsByPrefix call")
+	}
+	return m.searchTagsByPrefixFn(ctx, arg)
+}
+
+func (m *mockQueries) GetActivityPubActor(ctx context.Context, id pgtype.UUID) (db.ActivitypubActor, error) {
+	if m.getActivityPubActorFn == nil {
+		return db.ActivitypubActor{}, fmt.Errorf("unexpected GetActivityPubActor call")
+	}
+	return m.getActivityPubActorFn(ctx, id)
+}
+
+func (m *mockQueries) GetActivityPubActorByUsername(ctx context.Context, username string) (db.ActivitypubActor, error) {
+	if m.getActivityPubActorByUsernameFn == nil {
+		return db.ActivitypubActor{}, fmt.Errorf("unexpected GetActivityPubActorByUsername call")
+	}
+	return m.getActivityPubActorByUsernameFn(ctx, username)
+}
+
+func (m *mockQueries) UpsertActivityPubActor(ctx context.Context, arg db.UpsertActivityPubActorParams) (db.ActivitypubActor, error) {
+	if m.upsertActivityPubActorFn == nil {
+		return db.ActivitypubActor{}, fmt.Errorf("unexpected UpsertActivityPubActor call")
+	}
+	return m.upsertActivityPubActorFn(ctx, arg)
+}
+
+func (m *mockQueries) DeleteActivityPubActor(ctx context.Context, id pgtype.UUID) error {
+	if m.deleteActivityPubActorFn == nil {
+		return fmt.Errorf("unexpected DeleteActivityPubActor call")
+	}
+	return m.deleteActivityPubActorFn(ctx, id)
+}
+
+func (m *mockQueries) AddActivityPubFollower(ctx context.Context, arg db.AddActivityPubFollowerParams) error {
+	if m.addActivityPubFollowerFn == nil {
+		return fmt.Errorf("unexpected AddActivityPubFollower call")
+	}
+	return m.addActivityPubFollowerFn(ctx, arg)
+}
+
+func (m *mockQueries) RemoveActivityPubFollower(ctx context.Context, arg db.RemoveActivityPubFollowerParams) error {
+	if m.removeActivityPubFollowerFn == nil {
+		return fmt.Errorf("unexpected RemoveActivityPubFollower call")
+	}
+	return m.removeActivityPubFollowerFn(ctx, arg)
+}
+
+func (m *mockQueries) CountActivityPubFollowers(ctx context.Context, id pgtype.UUID) (int64, error) {
+	if m.countActivityPubFollowersFn == nil {
+		return 0, fmt.Errorf("unexpected CountActivityPubFollowers call")
+	}
+	return m.countActivityPubFollowersFn(ctx, id)
+}
+
+func (m *mockQueries) ListActivityPubFollowerInboxes(ctx context.Context, id pgtype.UUID) ([]string, error) {
+	if m.listActivityPubFollowerInboxesFn == nil {
+		return nil, fmt.Errorf("unexpected ListActivityPubFollowerInboxes call")
+	}
+	return m.listActivityPubFollowerInboxesFn(ctx, id)
+}
+
+func (m *mockQueries) SetLinkPublic(ctx context.Context, arg db.SetLinkPublicParams) (db.SetLinkPublicRow, error) {
+	if m.setLinkPublicFn == nil {
+		return db.SetLinkPublicRow{}, fmt.Errorf("unexpected SetLinkPublic call")
+	}
+	return m.setLinkPublicFn(ctx, arg)
+}
+
+func (m *mockQueries) GetPublicLink(ctx context.Context, id pgtype.UUID) (db.GetPublicLinkRow, error) {
+	if m.getPublicLinkFn == nil {
+		return db.GetPublicLinkRow{}, fmt.Errorf("unexpected GetPublicLink call")
+	}
+	return m.getPublicLinkFn(ctx, id)
+}
+
+func (m *mockQueries) ListPublicLinks(ctx context.Context, arg db.ListPublicLinksParams) ([]db.ListPublicLinksRow, error) {
+	if m.listPublicLinksFn == nil {
+		return nil, fmt.Errorf("unexpected ListPublicLinks call")
+	}
+	return m.listPublicLinksFn(ctx, arg)
+}
+
+func (m *mockQueries) CountPublicLinks(ctx context.Context, id pgtype.UUID) (int64, error) {
+	if m.countPublicLinksFn == nil {
+		return 0, fmt.Errorf("unexpected CountPublicLinks call")
+	}
+	return m.countPublicLinksFn(ctx, id)
+}
+
+func (m *mockQueries) ListSyncChanges(ctx context.Context, arg db.ListSyncChangesParams) ([]db.ListSyncChangesRow, error) {
+	if m.listSyncChangesFn == nil {
+		return nil, fmt.Errorf("unexpected ListSyncChanges call")
+	}
+	return m.listSyncChangesFn(ctx, arg)
+}
+
+func (m *mockQueries) GetSyncChangeSince(ctx context.Context, arg db.GetSyncChangeSinceParams) (bool, error) {
+	if m.getSyncChangeSinceFn == nil {
+		return false, fmt.Errorf("unexpected GetSyncChangeSince call")
+	}
+	return m.getSyncChangeSinceFn(ctx, arg)
+}
+
+func (m *mockQueries) ListSyncLinks(ctx context.Context, arg db.ListSyncLinksParams) ([]db.ListSyncLinksRow, error) {
+	if m.listSyncLinksFn == nil {
+		return nil, fmt.Errorf("unexpected ListSyncLinks call")
+	}
+	return m.listSyncLinksFn(ctx, arg)
+}
+
+func (m *mockQueries) ListSyncHighlights(ctx context.Context, arg db.ListSyncHighlightsParams) ([]db.Highlight, error) {
+	if m.listSyncHighlightsFn == nil {
+		return nil, fmt.Errorf("unexpected ListSyncHighlights call")
+	}
+	return m.listSyncHighlightsFn(ctx, arg)
+}
+
+func (m *mockQueries) UpdateSyncLink(ctx context.Context, arg db.UpdateSyncLinkParams) (int64, error) {
+	if m.updateSyncLinkFn == nil {
+		return 0, fmt.Errorf("unexpected UpdateSyncLink call")
+	}
+	return m.updateSyncLinkFn(ctx, arg)
+}
+
+func (m *mockQueries) DeleteLink(ctx context.Context, arg db.DeleteLinkParams) (int64, error) {
+	if m.deleteLinkFn == nil {
+		return 0, fmt.Errorf("unexpected DeleteLink call")
+	}
+	return m.deleteLinkFn(ctx, arg)
+}
+
+func (m *mockQueries) CreateInboundHook(ctx context.Context, arg db.CreateInboundHookParams) (db.InboundHook, error) {
+	if m.createInboundHookFn == nil {
+		return db.InboundHook{}, fmt.Errorf("unexpected CreateInboundHook call")
+	}
+	return m.createInboundHookFn(ctx, arg)
+}
+
+func (m *mockQueries) ListInboundHooks(ctx context.Context, userID pgtype.UUID) ([]db.InboundHook, error) {
+	if m.listInboundHooksFn == nil {
+		return nil, fmt.Errorf("unexpected ListInboundHooks call")
+	}
+	return m.listInboundHooksFn(ctx, userID)
+}
+
+func (m *mockQueries) GetInboundHookByTokenHash(ctx context.Context, tokenHash string) (db.InboundHook, error) {
+	if m.getInboundHookByTokenHashFn == nil {
+		return db.InboundHook{}, fmt.Errorf("unexpected GetInboundHookByTokenHash call")
+	}
+	return m.getInboundHookByTokenHashFn(ctx, tokenHash)
+}
+
+func (m *mockQueries) TouchInboundHook(ctx context.Context, id pgtype.UUID) error {
+	if m.touchInboundHookFn == nil {
+		return fmt.Errorf("unexpected TouchInboundHook call")
+	}
+	return m.touchInboundHookFn(ctx, id)
+}
+
+func (m *mockQueries) DeleteInboundHook(ctx context.Context, arg db.DeleteInboundHookParams) (int64, error) {
+	if m.deleteInboundHookFn == nil {
+		return 0, fmt.Errorf("unexpected DeleteInboundHook call")
+	}
+	return m.deleteInboundHookFn(ctx, arg)
+}
+
+func (m *mockQueries) GetUserIDByEmail(ctx context.Context, email string) (pgtype.UUID, error) {
+	if m.getUserIDByEmailFn == nil {
+		return pgtype.UUID{}, fmt.Errorf("unexpected GetUserIDByEmail call")
+	}
+	return m.getUserIDByEmailFn(ctx, email)
+}
+
+func (m *mockQueries) GetUserByEmail(ctx context.Context, email string) (db.User, error) {
+	if m.getUserByEmailFn == nil {
+		return db.User{}, fmt.Errorf("unexpected GetUserByEmail call")
+	}
+	return m.getUserByEmailFn(ctx, email)
+}
+
+func (m *mockQueries) UpsertLinkShare(ctx context.Context, arg db.UpsertLinkShareParams) (db.LinkShare, error) {
+	if m.upsertLinkShareFn == nil {
+		return db.LinkShare{}, fmt.Errorf("unexpected UpsertLinkShare call")
+	}
+	return m.upsertLinkShareFn(ctx, arg)
+}
+
+func (m *mockQueries) ListLinkShares(ctx context.Context, linkID pgtype.UUID) ([]db.ListLinkSharesRow, error) {
+	if m.listLinkSharesFn == nil {
+		return nil, fmt.Errorf("unexpected ListLinkShares call")
+	}
+	return m.listLinkSharesFn(ctx, linkID)
+}
+
+func (m *mockQueries) DeleteLinkShare(ctx context.Context, arg db.DeleteLinkShareParams) (int64, error) {
+	if m.deleteLinkShareFn == nil {
+		return 0, fmt.Errorf("unexpected DeleteLinkShare call")
+	}
+	return m.deleteLinkShareFn(ctx, arg)
+}
+
+func (m *mockQueries) GetLinkSharePermission(ctx context.Context, arg db.GetLinkSharePermissionParams) (string, error) {
+	if m.getLinkSharePermissionFn == nil {
+		return "", fmt.Errorf("unexpected GetLinkSharePermission call")
+	}
+	return m.getLinkSharePermissionFn(ctx, arg)
+}
+
+func (m *mockQueries) ListLinksSharedWithUser(ctx context.Context, userID pgtype.UUID) ([]db.ListLinksSharedWithUserRow, error) {
+	if m.listLinksSharedWithUserFn == nil {
+		return nil, fmt.Errorf("unexpected ListLinksSharedWithUser call")
+	}
+	return m.listLinksSharedWithUserFn(ctx, userID)
+}
+
+func (m *mockQueries) CreateNotification(ctx context.Context, arg db.CreateNotificationParams) (db.Notification, error) {
+	if m.createNotificationFn == nil {
+		return db.Notification{}, fmt.Errorf("unexpected CreateNotification call")
+	}
+	return m.createNotificationFn(ctx, arg)
+}
+
+func (m *mockQueries) CreateAdminNotifications(ctx context.Context, arg db.CreateAdminNotificationsParams) (int64, error) {
+	if m.createAdminNotificationsFn == nil {
+		return 0, fmt.Errorf("unexpected CreateAdminNotifications call")
+	}
+	return m.createAdminNotificationsFn(ctx, arg)
+}
+
+func (m *mockQueries) ListNotifications(ctx context.Context, arg db.ListNotificationsParams) ([]db.Notification, error) {
+	if m.listNotificationsFn == nil {
+		return nil, fmt.Errorf("unexpected ListNotifications call")
+	}
+	return m.listNotificationsFn(ctx, arg)
+}
+
+func (m *mockQueries) CountUnreadNotifications(ctx context.Context, userID pgtype.UUID) (int64, error) {
+	if m.countUnreadNotificationsFn == nil {
+		return 0, fmt.Errorf("unexpected CountUnreadNotifications call")
+	}
+	return m.countUnreadNotificationsFn(ctx, userID)
+}
+
+func (m *mockQueries) MarkNotificationRead(ctx context.Context, arg db.MarkNotificationReadParams) (int64, error) {
+	if m.markNotificationReadFn == nil {
+		return 0, fmt.Errorf("unexpected MarkNotificationRead call")
+	}
+	return m.markNotificationReadFn(ctx, arg)
+}
+
+func (m *mockQueries) MarkAllNotificationsRead(ctx context.Context, userID pgtype.UUID) (int64, error) {
+	if m.markAllNotificationsReadFn == nil {
+		return 0, fmt.Errorf("unexpected MarkAllNotificationsRead call")
+	}
+	return m.markAllNotificationsReadFn(ctx, userID)
+}
+
+func (m *mockQueries) UpsertReadingGoal(ctx context.Context, arg db.UpsertReadingGoalParams) (db.ReadingGoal, error) {
+	if m.upsertReadingGoalFn == nil {
+		return db.ReadingGoal{}, fmt.Errorf("unexpected UpsertReadingGoal call")
+	}
+	return m.upsertReadingGoalFn(ctx, arg)
+}
+
+func (m *mockQueries) ListReadingGoals(ctx context.Context, userID pgtype.UUID) ([]db.ReadingGoal, error) {
+	if m.listReadingGoalsFn == nil {
+		return nil, fmt.Errorf("unexpected ListReadingGoals call")
+	}
+	return m.listReadingGoalsFn(ctx, userID)
+}
+
+func (m *mockQueries) DeleteReadingGoal(ctx context.Context, arg db.DeleteReadingGoalParams) (int64, error) {
+	if m.deleteReadingGoalFn == nil {
+		return 0, fmt.Errorf("unexpected DeleteReadingGoal call")
+	}
+	return m.deleteReadingGoalFn(ctx, arg)
+}
+
+func (m *mockQueries) GetReadingProgress(ctx context.Context, arg db.GetReadingProgressParams) (db.GetReadingProgressRow, error) {
+	if m.getReadingProgressFn == nil {
+		return db.GetReadingProgressRow{}, fmt.Errorf("unexpected GetReadingProgress call")
+	}
+	return m.getReadingProgressFn(ctx, arg)
+}
+
+func (m *mockQueries) ListReadDays(ctx context.Context, userID pgtype.UUID) ([]pgtype.Date, error) {
+	if m.listReadDaysFn == nil {
+		return nil, fmt.Errorf("unexpected ListReadDays call")
+	}
+	return m.listReadDaysFn(ctx, userID)
+}
+
+func (m *mockQueries) ListSimilarLinks(ctx context.Context, arg db.ListSimilarLinksParams) ([]db.ListSimilarLinksRow, error) {
+	if m.listSimilarLinksFn == nil {
+		return nil, fmt.Errorf("unexpected ListSimilarLinks call")
+	}
+	return m.listSimilarLinksFn(ctx, arg)
+}
+
+func (m *mockQueries) UpsertDomainPreference(ctx context.Context, arg db.UpsertDomainPreferenceParams) (db.DomainPreference, error) {
+	if m.upsertDomainPreferenceFn == nil {
+		return db.DomainPreference{}, fmt.Errorf("unexpected UpsertDomainPreference call")
+	}
+	return m.upsertDomainPreferenceFn(ctx, arg)
+}
+
+func (m *mockQueries) ListDomainPreferences(ctx context.Context, userID pgtype.UUID) ([]db.DomainPreference, error) {
+	if m.listDomainPreferencesFn == nil {
+		return nil, fmt.Errorf("unexpected ListDomainPreferences call")
+	}
+	return m.listDomainPreferencesFn(ctx, userID)
+}
+
+func (m *mockQueries) MatchDomainPreference(ctx context.Context, arg db.MatchDomainPreferenceParams) (db.DomainPreference, error) {
+	if m.matchDomainPreferenceFn == nil {
+		return db.DomainPreference{}, fmt.Errorf("unexpected MatchDomainPreference call")
+	}
+	return m.matchDomainPreferenceFn(ctx, arg)
+}
+
+func (m *mockQueries) DeleteDomainPreference(ctx context.Context, arg db.DeleteDomainPreferenceParams) (int64, error) {
+	if m.deleteDomainPreferenceFn == nil {
+		return 0, fmt.Errorf("unexpected DeleteDomainPreference call")
+	}
+	return m.deleteDomainPreferenceFn(ctx, arg)
+}
+
+func (m *mockQueries) TakeDailyQuota(ctx context.Context, arg db.TakeDailyQuotaParams) (int32, error) {
+	if m.takeDailyQuotaFn == nil {
+		return 0, fmt.Errorf("unexpected TakeDailyQuota call")
+	}
+	return m.takeDailyQuotaFn(ctx, arg)
+}
+
+func (m *mockQueries) DeleteUsageQuotasBefore(ctx context.Context, day pgtype.Date) (int64, error) {
+	if m.deleteUsageQuotasBeforeFn == nil {
+		return 0, fmt.Errorf("unexpected DeleteUsageQuotasBefore call")
+	}
+	return m.deleteUsageQuotasBeforeFn(ctx, day)
+}
+
+func (m *mockQueries) ApplyTagToMatchingLinks(ctx context.Context, arg db.ApplyTagToMatchingLinksParams) (db.ApplyTagToMatchingLinksRow, error) {
+	if m.applyTagToMatchingLinksFn == nil {
+		return db.ApplyTagToMatchingLinksRow{}, fmt.Errorf("unexpected ApplyTagToMatchingLinks call")
+	}
+	return m.applyTagToMatchingLinksFn(ctx, arg)
+}
+
+func (m *mockQueries) ListArchiveVersions(ctx context.Context, linkID pgtype.UUID) ([]db.ListArchiveVersionsRow, error) {
+	if m.listArchiveVersionsFn == nil {
+		return nil, fmt.Errorf("unexpected ListArchiveVersions call")
+	}
+	return m.listArchiveVersionsFn(ctx, linkID)
+}
+
+func (m *mockQueries) GetArchiveVersion(ctx context.Context, arg db.GetArchiveVersionParams) (db.ArchiveVersion, error) {
+	if m.getArchiveVersionFn == nil {
+		return db.ArchiveVersion{}, fmt.Errorf("unexpected GetArchiveVersion call")
+	}
+	return m.getArchiveVersionFn(ctx, arg)
+}
+
+func (m *mockQueries) GetCurrentArchiveVersion(ctx context.Context, linkID pgtype.UUID) (db.GetCurrentArchiveVersionRow, error) {
+	if m.getCurrentArchiveVersionFn == nil {
+		return db.GetCurrentArchiveVersionRow{}, fmt.Errorf("unexpected GetCurrentArchiveVersion call")
+	}
+	return m.getCurrentArchiveVersionFn(ctx, linkID)
+}
+
+func (m *mockQueries) GetPublicProfile(ctx context.Context, userID pgtype.UUID) (db.PublicProfile, error) {
+	if m.getPublicProfileFn == nil {
+		return db.PublicProfile{}, fmt.Errorf("unexpected GetPublicProfile call")
+	}
+	return m.getPublicProfileFn(ctx, userID)
+}
+
+func (m *mockQueries) GetPublicProfileByUsername(ctx context.Context, username string) (db.PublicProfile, error) {
+	if m.getPublicProfileByUsernameFn == nil {
+		return db.PublicProfile{}, fmt.Errorf("unexpected GetPublicProfileByUsername call")
+	}
+	return m.getPublicProfileByUsernameFn(ctx, username)
+}
+
+func (m *mockQueries) UpsertPublicProfile(ctx context.Context, arg db.UpsertPublicProfileParams) (db.PublicProfile, error) {
+	if m.upsertPublicProfileFn == nil {
+		return db.PublicProfile{}, fmt.Errorf("unexpected UpsertPublicProfile call")
+	}
+	return m.upsertPublicProfileFn(ctx, arg)
+}
+
+func (m *mockQueries) DeletePublicProfile(ctx context.Context, userID pgtype.UUID) (int64, error) {
+	if m.deletePublicProfileFn == nil {
+		return 0, fmt.Errorf("unexpected DeletePublicProfile call")
+	}
+	return m.deletePublicProfileFn(ctx, userID)
+}
+
+func (m *mockQueries) ListPublicFavorites(ctx context.Context, arg db.ListPublicFavoritesParams) ([]db.ListPublicFavoritesRow, error) {
+	if m.listPublicFavoritesFn == nil {
+		return nil, fmt.Errorf("unexpected ListPublicFavorites call")
+	}
+	return m.listPublicFavoritesFn(ctx, arg)
+}
+
+func (m *mockQueries) RecordRecommendationImpressions(ctx context.Context, arg db.RecordRecommendationImpressionsParams) (int64, error) {
+	if m.recordRecommendationImpressionsFn == nil {
+		return 0, fmt.Errorf("unexpected RecordRecommendationImpressions call")
+	}
+	return m.recordRecommendationImpressionsFn(ctx, arg)
+}
+
+func (m *mockQueries) MarkRecommendationImpressionsActed(ctx context.Context, arg db.MarkRecommendationImpressionsActedParams) ([]string, error) {
+	if m.markRecommendationImpressionsActedFn == nil {
+		return nil, fmt.Errorf("unexpected MarkRecommendationImpressionsActed call")
+	}
+	return m.markRecommendationImpressionsActedFn(ctx, arg)
+}
+
+func (m *mockQueries) ListRecommendationImpressionStats(ctx context.Context, arg db.ListRecommendationImpressionStatsParams) ([]db.ListRecommendationImpressionStatsRow, error) {
+	if m.listRecommendationImpressionStatsFn == nil {
+		return nil, fmt.Errorf("unexpected ListRecommendationImpressionStats call")
+	}
+	return m.listRecommendationImpressionStatsFn(ctx, arg)
+}
+
+func (m *mockQueries) CreateJob(ctx context.Context, arg db.CreateJobParams) (db.Job, error) {
+	if m.createJobFn == nil {
+		return db.Job{}, fmt.Errorf("unexpected CreateJob call")
+	}
+	return m.createJobFn(ctx, arg)
+}
+
+func (m *mockQueries) GetJob(ctx context.Context, arg db.GetJobParams) (db.Job, error) {
+	if m.getJobFn == nil {
+		return db.Job{}, fmt.Errorf("unexpected GetJob call")
+	}
+	return m.getJobFn(ctx, arg)
+}
+
+func (m *mockQueries) UpdateJobProgress(ctx context.Context, arg db.UpdateJobProgressParams) error {
+	if m.updateJobProgressFn == nil {
+		return fmt.Errorf("unexpected UpdateJobProgress call")
+	}
+	return m.updateJobProgressFn(ctx, arg)
+}
+
+func (m *mockQueries) FinishJob(ctx context.Context, arg db.FinishJobParams) error {
+	if m.finishJobFn == nil {
+		return fmt.Errorf("unexpected FinishJob call")
+	}
+	return m.finishJobFn(ctx, arg)
+}
+
+func (m *mockQueries) SaveJobFile(ctx context.Context, arg db.SaveJobFileParams) error {
+	if m.saveJobFileFn == nil {
+		return fmt.Errorf("unexpected SaveJobFile call")
+	}
+	return m.saveJobFileFn(ctx, arg)
+}
+
+func (m *mockQueries) GetJobFile(ctx context.Context, arg db.GetJobFileParams) (db.JobFile, error) {
+	if m.getJobFileFn == nil {
+		return db.JobFile{}, fmt.Errorf("unexpected GetJobFile call")
+	}
+	return m.getJobFileFn(ctx, arg)
+}
+
+func (m *mockQueries) DeleteJobsFinishedBefore(ctx context.Context, arg pgtype.Timestamptz) (int64, error) {
+	if m.deleteJobsFinishedBeforeFn == nil {
+		return 0, fmt.Errorf("unexpected DeleteJobsFinishedBefore call")
+	}
+	return m.deleteJobsFinishedBeforeFn(ctx, arg)
+}
+
+var _ queryProvider = (*mockQueries)(nil)
+
+type stubPublisher struct {
+	called bool
+	lastID uuid.UUID
+
+	refreshCalled bool
+	refreshUserID uuid.UUID
+
+	reparsed []uuid.UUID
+
+	deleted []messages.LinkDeleted
+
+	events []queue.Event
+}
+
+func (s *stubPublisher) PublishLinkSaved(ctx context.Context, linkID uuid.UUID) error {
+	s.called = true
+	s.lastID = linkID
+	return nil
+}
+
+func (s *stubPublisher) PublishLinkReparse(ctx context.Context, linkID uuid.UUID, force bool) error {
+	s.reparsed = append(s.reparsed, linkID)
+	return nil
+}
+
+func (s *stubPublisher) PublishLinkDeleted(ctx context.Context, linkID uuid.UUID, mediaHashes []string) error {
+	s.deleted = append(s.deleted, messages.LinkDeleted{LinkID: linkID.String(), MediaHashes: mediaHashes})
+	return nil
+}
+
+func (s *stubPublisher) PublishRecommendationsRefresh(ctx context.Context, userID uuid.UUID) error {
+	s.refreshCalled = true
+	s.refreshUserID = userID
+	return nil
+}
+
+func (s *stubPublisher) PublishEvent(ctx context.Context, event queue.Event) error {
+	s.events = append(s.events, event)
+	return nil
+}
+
+func (s *stubPublisher) Close() {}
+
+var _ queue.Publisher = (*stubPublisher)(nil)
+
+type digestServiceFunc func(context.Context, uuid.UUID) (int, string, error)
+
+func (f digestServiceFunc) Send(ctx context.Context, userID uuid.UUID) (int, string, error) {
+	return f(ctx, userID)
+}
+
+func newTestMetrics() *observability.Metrics {
+	return &observability.Metrics{
+		HTTPRequestDurationSeconds: prometheus.NewHistogramVec(prometheus.HistogramOpts{Name: "test_http_request_duration_seconds", Help: ""}, []string{"route", "code"}),
+		HTTPRequestTotal:           prometheus.NewCounterVec(prometheus.CounterOpts{Name: "test_http_requests_total", Help: ""}, []string{"route", "code"}),
+		HTTPRequestNon2xxTotal:     prometheus.NewCounterVec(prometheus.CounterOpts{Name: "test_http_requests_non_2xx_total", Help: ""}, []string{"route", "code"}),
+		HTTPRateLimited:            prometheus.NewCounterVec(prometheus.CounterOpts{Name: "test_http_rate_limited_total", Help: ""}, []string{"class"}),
+		HTTPRequestTimeouts:        prometheus.NewCounterVec(prometheus.CounterOpts{Name: "test_http_request_timeouts_total", Help: ""}, []string{"route"}),
+		DBQueryDurationSeconds:     prometheus.NewHistogramVec(prometheus.HistogramOpts{Name: "test_db_query_duration_seconds", Help: ""}, []string{"query", "status"}),
+		LinkCreateSuccess:          prometheus.NewCounter(prometheus.CounterOpts{Name: "test_link_create_success_total", Help: ""}),
+		LinkCreateFailure:          prometheus.NewCounter(prometheus.CounterOpts{Name: "test_link_create_failure_total", Help: ""}),
 		LinkListSuccess:            prometheus.NewCounter(prometheus.CounterOpts{Name: "test_link_list_success_total", Help: ""}),
 		LinkListFailure:            prometheus.NewCounter(prometheus.CounterOpts{Name: "test_link_list_failure_total", Help: ""}),
 		LinkUpdateSuccess:          prometheus.NewCounter(prometheus.CounterOpts{Name: "test_link_update_success_total", Help: ""}),
 		LinkUpdateFailure:          prometheus.NewCounter(prometheus.CounterOpts{Name: "test_link_update_failure_total", Help: ""}),
+		LinkDeleteSuccess:          prometheus.NewCounter(prometheus.CounterOpts{Name: "test_link_delete_success_total", Help: ""}),
+		LinkDeleteFailure:          prometheus.NewCounter(prometheus.CounterOpts{Name: "test_link_delete_failure_total", Help: ""}),
+		LinkExportSuccess:          prometheus.NewCounter(prometheus.CounterOpts{Name: "test_link_export_success_total", Help: ""}),
+		LinkExportFailure:          prometheus.NewCounter(prometheus.CounterOpts{Name: "test_link_export_failure_total", Help: ""}),
+		LinkStreamSuccess:          prometheus.NewCounter(prometheus.CounterOpts{Name: "test_link_stream_success_total", Help: ""}),
+		LinkStreamFailure:          prometheus.NewCounter(prometheus.CounterOpts{Name: "test_link_stream_failure_total", Help: ""}),
 		ClaimCreateSuccess:         prometheus.NewCounter(prometheus.CounterOpts{Name: "test_claim_create_success_total", Help: ""}),
 		ClaimCreateFailure:         prometheus.NewCounter(prometheus.CounterOpts{Name: "test_claim_create_failure_total", Help: ""}),
 		ReadinessFailure:           prometheus.NewCounter(prometheus.CounterOpts{Name: "test_readiness_failure_total", Help: ""}),
@@ -2000,13 +3916,18 @@ func newTestMetrics() *observability.Metrics {
 		HighlightDeleteSuccess:     prometheus.NewCounter(prometheus.CounterOpts{Name: "test_highlight_delete_success_total", Help: ""}),
 		HighlightDeleteFailure:     prometheus.NewCounter(prometheus.CounterOpts{Name: "test_highlight_delete_failure_total", Help: ""}),
 		HighlightRateLimited:       prometheus.NewCounter(prometheus.CounterOpts{Name: "test_highlight_rate_limited_total", Help: ""}),
+		QuotaExceeded:              prometheus.NewCounterVec(prometheus.CounterOpts{Name: "test_quota_exceeded_total", Help: ""}, []string{"kind"}),
 		HighlightProcessingSeconds: prometheus.NewHistogram(prometheus.HistogramOpts{Name: "test_highlight_processing_seconds", Help: ""}),
-	}
-}
+		ResurfaceQueued:            prometheus.NewCounter(prometheus.CounterOpts{Name: "test_recommendation_refresh_queued_total", Help: ""}),
+		ResurfaceFailure:           prometheus.NewCounter(prometheus.CounterOpts{Name: "test_recommendation_refresh_failure_total", Help: ""}),
+		RecommendationImpressions:  prometheus.NewCounterVec(prometheus.CounterOpts{Name: "test_recommendation_impressions_total", Help: ""}, []string{"strategy", "surface"}),
+		RecommendationActions:      prometheus.NewCounterVec(prometheus.CounterOpts{Name: "test_recommendation_actions_total", Help: ""}, []string{"strategy", "action"}),
+
+		QueueMessagesTotal:          prometheus.NewCounterVec(prometheus.CounterOpts{Name: "test_queue_messages_total", Help: ""}, []string{"subject", "outcome"}),
+		QueueMessageDurationSeconds: prometheus.NewHistogramVec(prometheus.HistogramOpts{Name: "test_queue_message_duration_seconds", Help: ""}, []string{"subject"}),
 
-func rateLimiterZero() *rate.Limiter {
-	limiter := rate.NewLimiter(rate.Every(time.Hour), 0)
-	return limiter
+		GRPCRequestDurationSeconds: prometheus.NewHistogramVec(prometheus.HistogramOpts{Name: "test_grpc_request_duration_seconds", Help: ""}, []string{"method", "code"}),
+	}
 }
 
 func TestClassifyReadinessError(t *testing.T) {
@@ -2074,6 +3995,54 @@ func (stubHealthPool) Ping(context.Context) error {
 	return nil
 }
 
+// backupFreshnessPool answers the backup freshness query with a fixed
+// timestamp and otherwise behaves like stubHealthPool.
+type backupFreshnessPool struct {
+	stubHealthPool
+	latest pgtype.Timestamptz
+}
+
+func (p backupFreshnessPool) QueryRow(ctx context.Context, query string, args ...any) pgx.Row {
+	if strings.Contains(query, "backup_runs") {
+		return timestampRow{value: p.latest}
+	}
+	return p.stubHealthPool.QueryRow(ctx, query, args...)
+}
+
+// schemaVersionPool reports a fixed applied goose version and otherwise
+// behaves like stubHealthPool.
+type schemaVersionPool struct {
+	stubHealthPool
+	applied int64
+}
+
+func (p schemaVersionPool) QueryRow(ctx context.Context, query string, args ...any) pgx.Row {
+	if strings.Contains(query, "goose_db_version") {
+		return versionRow(p.applied)
+	}
+	return p.stubHealthPool.QueryRow(ctx, query, args...)
+}
+
+type versionRow int64
+
+func (r versionRow) Scan(dest ...any) error {
+	if v, ok := dest[0].(*int64); ok {
+		*v = int64(r)
+	}
+	return nil
+}
+
+type timestampRow struct {
+	value pgtype.Timestamptz
+}
+
+func (r timestampRow) Scan(dest ...any) error {
+	if ts, ok := dest[0].(*pgtype.Timestamptz); ok {
+		*ts = r.value
+	}
+	return nil
+}
+
 type stubRow struct{}
 
 func (stubRow) Scan(dest ...any) error {
@@ -0,0 +1,357 @@
+package httpapi
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	stdhttp "net/http"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/labstack/echo/v4"
+
+	"github.com/example/keepstack/apps/api/internal/db"
+)
+
+const (
+	maxHookNameLength = 100
+	maxHookFieldPath  = 128
+	maxHookTags       = 20
+)
+
+// hookFieldPattern matches a payload field path: dot-separated keys, where a
+// numeric key indexes into an array.
+var hookFieldPattern = regexp.MustCompile(`^[A-Za-z0-9_-]+(\.[A-Za-z0-9_-]+)*$`)
+
+type inboundHookRequest struct {
+	Name        string   `json:"name"`
+	URLField    string   `json:"url_field"`
+	TitleField  string   `json:"title_field"`
+	TagsField   string   `json:"tags_field"`
+	DefaultTags []string `json:"default_tags"`
+}
+
+type inboundHookResponse struct {
+	ID          string     `json:"id"`
+	Name        string     `json:"name"`
+	URLField    string     `json:"url_field"`
+	TitleField  string     `json:"title_field"`
+	TagsField   string     `json:"tags_field"`
+	DefaultTags []string   `json:"default_tags"`
+	CreatedAt   time.Time  `json:"created_at"`
+	LastUsedAt  *time.Time `json:"last_used_at,omitempty"`
+	// Token and Path are only returned when the hook is created.
+	Token string `json:"token,omitempty"`
+	Path  string `json:"path,omitempty"`
+}
+
+type hookSaveResponse struct {
+	ID      string        `json:"id"`
+	URL     string        `json:"url"`
+	Created bool          `json:"created"`
+	Tags    []tagResponse `json:"tags"`
+}
+
+// registerHookRoutes adds the owner endpoints that manage hooks to user and
+// the endpoint hooks save through, which their token authenticates, to api.
+func (s *Server) registerHookRoutes(api, user *echo.Group) {
+	user.GET("/hooks", s.handleListHooks)
+	user.POST("/hooks", s.handleCreateHook)
+	user.DELETE("/hooks/:id", s.handleDeleteHook)
+	api.POST("/hooks/:token", s.handleHookSave)
+}
+
+func (s *Server) handleListHooks(c echo.Context) error {
+	hooks, err := s.queries.ListInboundHooks(c.Request().Context(), uuidToPg(s.currentUser(c)))
+	if err != nil {
+		c.Logger().Errorf("list hooks: query failed: %v", err)
+		return respondError(c, stdhttp.StatusInternalServerError, "failed to list hooks")
+	}
+	responses := make([]inboundHookResponse, 0, len(hooks))
+	for _, hook := range hooks {
+		responses = append(responses, toInboundHookResponse(hook))
+	}
+	return c.JSON(stdhttp.StatusOK, map[string]any{"items": responses})
+}
+
+// handleCreateHook creates a hook and returns its token. The token is not
+// stored and cannot be shown again; a lost token means a new hook.
+func (s *Server) handleCreateHook(c echo.Context) error {
+	var req inboundHookRequest
+	if err := c.Bind(&req); err != nil {
+		return respondBindError(c, err)
+	}
+	params, err := validateInboundHook(req)
+	if err != nil {
+		return respondError(c, stdhttp.StatusBadRequest, err.Error())
+	}
+
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		c.Logger().Errorf("create hook: generate token failed: %v", err)
+		return respondError(c, stdhttp.StatusInternalServerError, "failed to create hook")
+	}
+	token := base64.RawURLEncoding.EncodeToString(buf)
+	params.UserID = uuidToPg(s.currentUser(c))
+	params.TokenHash = hookTokenHash(token)
+
+	hook, err := s.queries.CreateInboundHook(c.Request().Context(), params)
+	if err != nil {
+		c.Logger().Errorf("create hook: insert failed: %v", err)
+		return respondError(c, stdhttp.StatusInternalServerError, "failed to create hook")
+	}
+	resp := toInboundHookResponse(hook)
+	resp.Token = token
+	resp.Path = "/api/hooks/" + token
+	return c.JSON(stdhttp.StatusCreated, resp)
+}
+
+func (s *Server) handleDeleteHook(c echo.Context) error {
+	id, err := parseUUIDParam(c.Param("id"))
+	if err != nil {
+		return respondError(c, stdhttp.StatusBadRequest, "invalid hook id")
+	}
+	deleted, err := s.queries.DeleteInboundHook(c.Request().Context(), db.DeleteInboundHookParams{
+		ID:     uuidToPg(id),
+		UserID: uuidToPg(s.currentUser(c)),
+	})
+	if err != nil {
+		c.Logger().Errorf("delete hook: delete failed: %v", err)
+		return respondError(c, stdhttp.StatusInternalServerError, "failed to delete hook")
+	}
+	if deleted == 0 {
+		return respondError(c, stdhttp.StatusNotFound, "hook not found")
+	}
+	return c.NoContent(stdhttp.StatusNoContent)
+}
+
+// handleHookSave saves the link in a webhook payload. The token in the path
+// is the only credential, so services that cannot set headers can still
+// post. JSON bodies are read through the hook's field paths; form bodies by
+// field name. Tags may be an array or a comma-separated string, and the
+// hook's default tags are always added.
+func (s *Server) handleHookSave(c echo.Context) error {
+	ctx := c.Request().Context()
+	hook, err := s.queries.GetInboundHookByTokenHash(ctx, hookTokenHash(c.Param("token")))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return respondError(c, stdhttp.StatusNotFound, "hook not found")
+		}
+		c.Logger().Errorf("hook save: lookup failed: %v", err)
+		return respondError(c, stdhttp.StatusInternalServerError, "failed to look up hook")
+	}
+
+	payload, err := parseHookPayload(c)
+	if err != nil {
+		s.metrics.LinkCreateFailure.Inc()
+		var maxBytesErr *stdhttp.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			return respondError(c, stdhttp.StatusRequestEntityTooLarge, bodyTooLargeMessage(maxBytesErr.Limit))
+		}
+		return respondError(c, stdhttp.StatusBadRequest, "payload must be a JSON object or a form")
+	}
+
+	url := hookText(lookupHookField(payload, hook.UrlField))
+	if url == "" {
+		s.metrics.LinkCreateFailure.Inc()
+		return respondError(c, stdhttp.StatusBadRequest, "payload has no url at "+hook.UrlField)
+	}
+	tags := append(append([]string{}, hook.DefaultTags...), hookList(lookupHookField(payload, hook.TagsField))...)
+
+	saved, err := s.quickSave(c, quickSaveInput{
+		UserID: uuidFromPg(hook.UserID),
+		URL:    url,
+		Title:  hookText(lookupHookField(payload, hook.TitleField)),
+		Tags:   tags,
+	})
+	if err != nil {
+		return respondWithError(c, err)
+	}
+	if err := s.queries.TouchInboundHook(ctx, hook.ID); err != nil {
+		c.Logger().Warnf("hook save: record use failed: %v", err)
+	}
+
+	status := stdhttp.StatusOK
+	if saved.Created {
+		status = stdhttp.StatusCreated
+	}
+	return c.JSON(status, hookSaveResponse{
+		ID:      saved.ID.String(),
+		URL:     saved.URL,
+		Created: saved.Created,
+		Tags:    saved.Tags,
+	})
+}
+
+// validateInboundHook checks a create request and fills in the default field
+// paths.
+func validateInboundHook(req inboundHookRequest) (db.CreateInboundHookParams, error) {
+	params := db.CreateInboundHookParams{
+		Name:        strings.TrimSpace(req.Name),
+		UrlField:    strings.TrimSpace(req.URLField),
+		TitleField:  strings.TrimSpace(req.TitleField),
+		TagsField:   strings.TrimSpace(req.TagsField),
+		DefaultTags: []string{},
+	}
+	if params.Name == "" {
+		return params, errors.New("name is required")
+	}
+	if len(params.Name) > maxHookNameLength {
+		return params, fmt.Errorf("name must be at most %d characters", maxHookNameLength)
+	}
+
+	fields := []struct {
+		name     string
+		value    *string
+		fallback string
+	}{
+		{"url_field", &params.UrlField, "url"},
+		{"title_field", &params.TitleField, "title"},
+		{"tags_field", &params.TagsField, "tags"},
+	}
+	for _, field := range fields {
+		if *field.value == "" {
+			*field.value = field.fallback
+		}
+		if len(*field.value) > maxHookFieldPath || !hookFieldPattern.MatchString(*field.value) {
+			return params, fmt.Errorf("%s must be a dot-separated path of letters, digits, '_' or '-'", field.name)
+		}
+	}
+
+	for _, tag := range req.DefaultTags {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			params.DefaultTags = append(params.DefaultTags, tag)
+		}
+	}
+	if len(params.DefaultTags) > maxHookTags {
+		return params, fmt.Errorf("at most %d default tags are allowed", maxHookTags)
+	}
+	return params, nil
+}
+
+// parseHookPayload reads a JSON object or a form into a map. Form fields
+// sent more than once, or with a [] suffix, become lists.
+func parseHookPayload(c echo.Context) (map[string]any, error) {
+	if !isFormRequest(c.Request()) {
+		decoder := json.NewDecoder(c.Request().Body)
+		decoder.UseNumber()
+		var payload map[string]any
+		if err := decoder.Decode(&payload); err != nil {
+			return nil, err
+		}
+		if payload == nil {
+			return nil, errors.New("payload is not an object")
+		}
+		return payload, nil
+	}
+
+	form, err := c.FormParams()
+	if err != nil {
+		return nil, err
+	}
+	payload := make(map[string]any, len(form))
+	for key, values := range form {
+		list, isList := strings.CutSuffix(key, "[]")
+		if !isList && len(values) == 1 {
+			payload[key] = values[0]
+			continue
+		}
+		items := make([]any, 0, len(values))
+		for _, value := range values {
+			items = append(items, value)
+		}
+		payload[list] = items
+	}
+	return payload, nil
+}
+
+// lookupHookField resolves a dotted path in a payload. A key that contains
+// the whole path, dots included, takes precedence.
+func lookupHookField(payload map[string]any, path string) any {
+	if value, ok := payload[path]; ok {
+		return value
+	}
+	var current any = payload
+	for _, key := range strings.Split(path, ".") {
+		switch node := current.(type) {
+		case map[string]any:
+			current = node[key]
+		case []any:
+			index, err := strconv.Atoi(key)
+			if err != nil || index < 0 || index >= len(node) {
+				return nil
+			}
+			current = node[index]
+		default:
+			return nil
+		}
+	}
+	return current
+}
+
+// hookText returns a payload value as trimmed text. Lists yield their first
+// element; objects yield nothing.
+func hookText(value any) string {
+	switch v := value.(type) {
+	case string:
+		return strings.TrimSpace(v)
+	case json.Number:
+		return v.String()
+	case []any:
+		if len(v) > 0 {
+			return hookText(v[0])
+		}
+	}
+	return ""
+}
+
+// hookList returns a payload value as a list of strings, splitting a single
+// string on commas.
+func hookList(value any) []string {
+	switch v := value.(type) {
+	case string:
+		return strings.Split(v, ",")
+	case []any:
+		items := make([]string, 0, len(v))
+		for _, item := range v {
+			if text := hookText(item); text != "" {
+				items = append(items, text)
+			}
+		}
+		return items
+	}
+	return nil
+}
+
+func hookTokenHash(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func toInboundHookResponse(hook db.InboundHook) inboundHookResponse {
+	resp := inboundHookResponse{
+		ID:          uuidFromPg(hook.ID).String(),
+		Name:        hook.Name,
+		URLField:    hook.UrlField,
+		TitleField:  hook.TitleField,
+		TagsField:   hook.TagsField,
+		DefaultTags: hook.DefaultTags,
+		CreatedAt:   hook.CreatedAt.Time,
+	}
+	if resp.DefaultTags == nil {
+		resp.DefaultTags = []string{}
+	}
+	if hook.LastUsedAt.Valid {
+		lastUsed := hook.LastUsedAt.Time
+		resp.LastUsedAt = &lastUsed
+	}
+	return resp
+}
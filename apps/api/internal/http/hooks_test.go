@@ -0,0 +1,224 @@
+package httpapi
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/labstack/echo/v4"
+
+	"github.com/example/keepstack/apps/api/internal/db"
+)
+
+// hookSaveQueries answers the lookups a hook save makes for a URL that is not
+// saved yet and records the tags it assigns.
+func hookSaveQueries(hook db.InboundHook, tagged *[]string) *mockQueries {
+	return &mockQueries{
+		getInboundHookByTokenHashFn: func(ctx context.Context, tokenHash string) (db.InboundHook, error) {
+			if tokenHash != hook.TokenHash {
+				return db.InboundHook{}, pgx.ErrNoRows
+			}
+			return hook, nil
+		},
+		findLinkByURLHashFn: func(ctx context.Context, arg db.FindLinkByURLHashParams) (db.FindLinkByURLHashRow, error) {
+			return db.FindLinkByURLHashRow{}, pgx.ErrNoRows
+		},
+		createLinkFn: func(ctx context.Context, params db.CreateLinkParams) (db.CreateLinkRow, error) {
+			return db.CreateLinkRow{ID: params.ID, Url: params.Url, Title: params.Title}, nil
+		},
+		getTagByNameFn: func(ctx context.Context, arg db.GetTagByNameParams) (db.Tag, error) {
+			*tagged = append(*tagged, arg.Name)
+			return db.Tag{ID: int32(len(*tagged)), Name: arg.Name}, nil
+		},
+		addTagToLinkFn: func(ctx context.Context, params db.AddTagToLinkParams) error {
+			return nil
+		},
+		touchInboundHookFn: func(ctx context.Context, id pgtype.UUID) error {
+			return nil
+		},
+	}
+}
+
+func TestHookSaveMapsJSONFields(t *testing.T) {
+	t.Parallel()
+
+	hook := db.InboundHook{
+		ID:          uuidToPg(uuid.New()),
+		UserID:      uuidToPg(uuid.New()),
+		TokenHash:   hookTokenHash("secret-token"),
+		UrlField:    "data.link",
+		TitleField:  "data.title",
+		TagsField:   "tags",
+		DefaultTags: []string{"ifttt"},
+	}
+	var tagged []string
+	var created db.CreateLinkParams
+	queries := hookSaveQueries(hook, &tagged)
+	createLink := queries.createLinkFn
+	queries.createLinkFn = func(ctx context.Context, params db.CreateLinkParams) (db.CreateLinkRow, error) {
+		created = params
+		return createLink(ctx, params)
+	}
+	publisher := &stubPublisher{}
+	e := newRouteTestServer(queries, publisher)
+
+	body := `{"data":{"link":"example.com/post","title":" A post "},"tags":"reading, rss"}`
+	req := httptest.NewRequest(http.MethodPost, "/api/hooks/secret-token", strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusCreated, rec.Code, rec.Body.String())
+	}
+	if created.Url != "https://example.com/post" || created.Title.String != "A post" {
+		t.Fatalf("unexpected link: %+v", created)
+	}
+	if created.UserID != hook.UserID {
+		t.Fatalf("expected the link saved for the hook's owner, got %+v", created.UserID)
+	}
+	if strings.Join(tagged, ",") != "ifttt,reading,rss" {
+		t.Fatalf("expected default and payload tags, got %v", tagged)
+	}
+	if !publisher.called {
+		t.Fatalf("expected the link to be queued")
+	}
+}
+
+func TestHookSaveAcceptsForms(t *testing.T) {
+	t.Parallel()
+
+	hook := db.InboundHook{
+		ID:         uuidToPg(uuid.New()),
+		TokenHash:  hookTokenHash("form-token"),
+		UrlField:   "url",
+		TitleField: "title",
+		TagsField:  "tags",
+	}
+	var tagged []string
+	e := newRouteTestServer(hookSaveQueries(hook, &tagged), &stubPublisher{})
+
+	form := url.Values{"url": {"https://example.com/form"}, "tags[]": {"one", "two"}}
+	req := httptest.NewRequest(http.MethodPost, "/api/hooks/form-token", strings.NewReader(form.Encode()))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationForm)
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusCreated, rec.Code, rec.Body.String())
+	}
+	if strings.Join(tagged, ",") != "one,two" {
+		t.Fatalf("expected tags from the list field, got %v", tagged)
+	}
+}
+
+func TestHookSaveRejectsUnknownTokensAndMissingURLs(t *testing.T) {
+	t.Parallel()
+
+	hook := db.InboundHook{ID: uuidToPg(uuid.New()), TokenHash: hookTokenHash("known"), UrlField: "link"}
+	var tagged []string
+	e := newRouteTestServer(hookSaveQueries(hook, &tagged), &stubPublisher{})
+
+	cases := []struct {
+		path string
+		body string
+		want int
+	}{
+		{"/api/hooks/unknown", `{"link":"https://example.com"}`, http.StatusNotFound},
+		{"/api/hooks/known", `{"url":"https://example.com"}`, http.StatusBadRequest},
+		{"/api/hooks/known", `["https://example.com"]`, http.StatusBadRequest},
+	}
+	for _, tc := range cases {
+		req := httptest.NewRequest(http.MethodPost, tc.path, strings.NewReader(tc.body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		if rec.Code != tc.want {
+			t.Fatalf("%s %s: expected status %d, got %d", tc.path, tc.body, tc.want, rec.Code)
+		}
+	}
+}
+
+func TestCreateHookReturnsTokenForStoredHash(t *testing.T) {
+	t.Parallel()
+
+	var stored db.CreateInboundHookParams
+	queries := &mockQueries{
+		createInboundHookFn: func(ctx context.Context, arg db.CreateInboundHookParams) (db.InboundHook, error) {
+			stored = arg
+			return db.InboundHook{
+				ID:          uuidToPg(uuid.New()),
+				Name:        arg.Name,
+				TokenHash:   arg.TokenHash,
+				UrlField:    arg.UrlField,
+				TitleField:  arg.TitleField,
+				TagsField:   arg.TagsField,
+				DefaultTags: arg.DefaultTags,
+			}, nil
+		},
+	}
+	e := newRouteTestServer(queries, &stubPublisher{})
+
+	req := httptest.NewRequest(http.MethodPost, "/api/hooks", strings.NewReader(`{"name":"zapier","url_field":"link.href","default_tags":["zapier"," "]}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusCreated, rec.Code, rec.Body.String())
+	}
+
+	var resp inboundHookResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if resp.Token == "" || hookTokenHash(resp.Token) != stored.TokenHash || resp.Path != "/api/hooks/"+resp.Token {
+		t.Fatalf("expected the token to match the stored hash, got %+v", resp)
+	}
+	if stored.UrlField != "link.href" || stored.TitleField != "title" || stored.TagsField != "tags" {
+		t.Fatalf("expected default field paths to be filled in, got %+v", stored)
+	}
+	if len(stored.DefaultTags) != 1 || stored.DefaultTags[0] != "zapier" {
+		t.Fatalf("expected blank default tags to be dropped, got %v", stored.DefaultTags)
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/api/hooks", strings.NewReader(`{"name":"bad","url_field":"data[0]"}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec = httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected status %d for an invalid path, got %d", http.StatusBadRequest, rec.Code)
+	}
+}
+
+func TestDeleteHook(t *testing.T) {
+	t.Parallel()
+
+	id := uuid.New()
+	queries := &mockQueries{
+		deleteInboundHookFn: func(ctx context.Context, arg db.DeleteInboundHookParams) (int64, error) {
+			if uuidFromPg(arg.ID) != id {
+				return 0, nil
+			}
+			return 1, nil
+		},
+	}
+	e := newRouteTestServer(queries, &stubPublisher{})
+
+	for target, want := range map[string]int{
+		"/api/hooks/" + id.String():         http.StatusNoContent,
+		"/api/hooks/" + uuid.New().String(): http.StatusNotFound,
+	} {
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, httptest.NewRequest(http.MethodDelete, target, nil))
+		if rec.Code != want {
+			t.Fatalf("%s: expected status %d, got %d", target, want, rec.Code)
+		}
+	}
+}
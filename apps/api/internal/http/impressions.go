@@ -0,0 +1,195 @@
+package httpapi
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	stdhttp "net/http"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/labstack/echo/v4"
+
+	"github.com/example/keepstack/apps/api/internal/db"
+)
+
+// Where an impression was shown. The digest records its own with surface
+// "digest".
+const impressionSurfaceAPI = "api"
+
+// Strategies for recommendations the resurfacer did not score. Resurfaced
+// rows carry the strategy named by their weights.
+const (
+	strategyColdStart = "cold_start"
+	strategyOnThisDay = "on_this_day"
+)
+
+// Actions that count an impression as acted on.
+const (
+	impressionActionOpen     = "open"
+	impressionActionRead     = "read"
+	impressionActionFavorite = "favorite"
+)
+
+// impressionRepeatWindow keeps a client that refetches recommendations from
+// recording the same link again until it has been acted on or this long has
+// passed.
+const impressionRepeatWindow = time.Hour
+
+// impressionAttributionWindow is how long after being shown an action still
+// counts towards an impression.
+const impressionAttributionWindow = 7 * 24 * time.Hour
+
+// defaultImpressionStatsDays and maxImpressionStatsDays bound the window the
+// stats endpoint totals.
+const (
+	defaultImpressionStatsDays = 30
+	maxImpressionStatsDays     = 365
+)
+
+type impressionStatsItem struct {
+	Strategy    string  `json:"strategy"`
+	Surface     string  `json:"surface"`
+	Impressions int     `json:"impressions"`
+	Acted       int     `json:"acted"`
+	Opened      int     `json:"opened"`
+	Read        int     `json:"read"`
+	Favorited   int     `json:"favorited"`
+	CTR         float64 `json:"ctr"`
+}
+
+type impressionStatsResponse struct {
+	Since time.Time             `json:"since"`
+	Days  int                   `json:"days"`
+	Items []impressionStatsItem `json:"items"`
+}
+
+// registerImpressionRoutes adds the endpoints that report recommendation
+// impressions.
+func (s *Server) registerImpressionRoutes(api *echo.Group) {
+	api.GET("/recommendations/stats", s.handleRecommendationStats)
+	api.POST("/recommendations/:id/open", s.handleOpenRecommendation)
+}
+
+// recordImpressions records rows as shown on surface. Positions count from
+// one after offset. Rows are grouped by strategy, since a page can mix rows
+// scored before and after a weight change.
+func (s *Server) recordImpressions(ctx context.Context, surface string, rows []db.ListRecommendationsForUserRow, offset int) error {
+	if len(rows) == 0 {
+		return nil
+	}
+
+	type shown struct {
+		ids       []pgtype.UUID
+		positions []int32
+	}
+	byStrategy := make(map[string]*shown)
+	var order []string
+	for i, row := range rows {
+		group := byStrategy[row.Strategy]
+		if group == nil {
+			group = &shown{}
+			byStrategy[row.Strategy] = group
+			order = append(order, row.Strategy)
+		}
+		group.ids = append(group.ids, row.ID)
+		group.positions = append(group.positions, int32(offset+i+1))
+	}
+
+	now := time.Now().UTC()
+	for _, strategy := range order {
+		group := byStrategy[strategy]
+		recorded, err := s.queries.RecordRecommendationImpressions(ctx, db.RecordRecommendationImpressionsParams{
+			UserID:      uuidToPg(s.userID(ctx)),
+			Strategy:    strategy,
+			Surface:     surface,
+			ShownAt:     pgtype.Timestamptz{Time: now, Valid: true},
+			LinkIds:     group.ids,
+			Positions:   group.positions,
+			RepeatAfter: pgtype.Timestamptz{Time: now.Add(-impressionRepeatWindow), Valid: true},
+		})
+		if err != nil {
+			return fmt.Errorf("record %s impressions: %w", strategy, err)
+		}
+		s.metrics.RecommendationImpressions.WithLabelValues(strategy, surface).Add(float64(recorded))
+	}
+	return nil
+}
+
+// recordRecommendationAction marks recent impressions of ids as acted on.
+// Failures are logged only; the action itself has already succeeded.
+func (s *Server) recordRecommendationAction(c echo.Context, ids []pgtype.UUID, action string) {
+	if len(ids) == 0 {
+		return
+	}
+	now := time.Now().UTC()
+	strategies, err := s.queries.MarkRecommendationImpressionsActed(c.Request().Context(), db.MarkRecommendationImpressionsActedParams{
+		Action:     pgtype.Text{String: action, Valid: true},
+		ActedAt:    pgtype.Timestamptz{Time: now, Valid: true},
+		UserID:     uuidToPg(s.currentUser(c)),
+		LinkIds:    ids,
+		ShownAfter: pgtype.Timestamptz{Time: now.Add(-impressionAttributionWindow), Valid: true},
+	})
+	if err != nil {
+		c.Logger().Warnf("recommendation impressions: record %s for %d links failed: %v", action, len(ids), err)
+		return
+	}
+	for _, strategy := range strategies {
+		s.metrics.RecommendationActions.WithLabelValues(strategy, action).Inc()
+	}
+}
+
+// handleOpenRecommendation records that the user opened a recommended link.
+// Clients call it when a recommendation is clicked; reads and favorites are
+// recorded without it.
+func (s *Server) handleOpenRecommendation(c echo.Context) error {
+	linkID, err := parseUUIDParam(c.Param("id"))
+	if err != nil {
+		return respondError(c, stdhttp.StatusBadRequest, "invalid link id")
+	}
+	s.recordRecommendationAction(c, []pgtype.UUID{uuidToPg(linkID)}, impressionActionOpen)
+	return c.NoContent(stdhttp.StatusNoContent)
+}
+
+// handleRecommendationStats totals impressions and the actions taken on them
+// by strategy and surface over the last days days.
+func (s *Server) handleRecommendationStats(c echo.Context) error {
+	days := defaultImpressionStatsDays
+	if raw := strings.TrimSpace(c.QueryParam("days")); raw != "" {
+		value, err := strconv.Atoi(raw)
+		if err != nil || value < 1 || value > maxImpressionStatsDays {
+			return respondError(c, stdhttp.StatusBadRequest, fmt.Sprintf("days must be between 1 and %d", maxImpressionStatsDays))
+		}
+		days = value
+	}
+
+	since := time.Now().UTC().AddDate(0, 0, -days)
+	rows, err := s.reads().ListRecommendationImpressionStats(c.Request().Context(), db.ListRecommendationImpressionStatsParams{
+		UserID: uuidToPg(s.currentUser(c)),
+		Since:  pgtype.Timestamptz{Time: since, Valid: true},
+	})
+	if err != nil {
+		c.Logger().Errorf("recommendation stats: %v", err)
+		return respondError(c, stdhttp.StatusInternalServerError, "failed to load recommendation stats")
+	}
+
+	items := make([]impressionStatsItem, 0, len(rows))
+	for _, row := range rows {
+		item := impressionStatsItem{
+			Strategy:    row.Strategy,
+			Surface:     row.Surface,
+			Impressions: int(row.Impressions),
+			Acted:       int(row.Acted),
+			Opened:      int(row.Opened),
+			Read:        int(row.Read),
+			Favorited:   int(row.Favorited),
+		}
+		if item.Impressions > 0 {
+			item.CTR = float64(item.Acted) / float64(item.Impressions)
+		}
+		items = append(items, item)
+	}
+	return c.JSON(stdhttp.StatusOK, impressionStatsResponse{Since: since, Days: days, Items: items})
+}
@@ -0,0 +1,174 @@
+package httpapi
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/labstack/echo/v4"
+
+	"github.com/example/keepstack/apps/api/internal/config"
+	"github.com/example/keepstack/apps/api/internal/db"
+)
+
+func TestListRecommendationsRecordsImpressions(t *testing.T) {
+	t.Parallel()
+
+	first, second := uuid.New(), uuid.New()
+	var recorded []db.RecordRecommendationImpressionsParams
+	mock := &mockQueries{
+		listRecommendationsForUserFn: func(ctx context.Context, params db.ListRecommendationsForUserParams) ([]db.ListRecommendationsForUserRow, error) {
+			return []db.ListRecommendationsForUserRow{
+				{ID: uuidToPg(first), Url: "https://example.com/a", Score: 9, Strategy: "resurfacer:new"},
+				{ID: uuidToPg(second), Url: "https://example.com/b", Score: 8, Strategy: "resurfacer:old"},
+			}, nil
+		},
+		listTagsForLinkFn: func(ctx context.Context, id pgtype.UUID) ([]db.Tag, error) {
+			return nil, nil
+		},
+		listHighlightsByLinkFn: func(ctx context.Context, id pgtype.UUID) ([]db.Highlight, error) {
+			return nil, nil
+		},
+		recordRecommendationImpressionsFn: func(ctx context.Context, arg db.RecordRecommendationImpressionsParams) (int64, error) {
+			recorded = append(recorded, arg)
+			return int64(len(arg.LinkIds)), nil
+		},
+	}
+	srv := &Server{queries: mock, metrics: newTestMetrics()}
+	e := echo.New()
+	srv.RegisterRoutes(e)
+
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/recommendations?offset=10", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+	}
+
+	if len(recorded) != 2 {
+		t.Fatalf("expected one impression batch per strategy, got %+v", recorded)
+	}
+	for i, want := range []struct {
+		strategy string
+		id       uuid.UUID
+		position int32
+	}{{"resurfacer:new", first, 11}, {"resurfacer:old", second, 12}} {
+		got := recorded[i]
+		if got.Strategy != want.strategy || got.Surface != impressionSurfaceAPI || len(got.LinkIds) != 1 ||
+			uuidFromPg(got.LinkIds[0]) != want.id || got.Positions[0] != want.position {
+			t.Fatalf("unexpected impression batch %d: %+v", i, got)
+		}
+		if !got.RepeatAfter.Time.Equal(got.ShownAt.Time.Add(-impressionRepeatWindow)) {
+			t.Fatalf("expected repeats within %s to be skipped, got %+v", impressionRepeatWindow, got)
+		}
+	}
+}
+
+func TestMarkLinksReadRecordsRecommendationAction(t *testing.T) {
+	t.Parallel()
+
+	linkID := uuid.New()
+	var acted db.MarkRecommendationImpressionsActedParams
+	mock := &mockQueries{
+		markLinksReadFn: func(ctx context.Context, arg db.MarkLinksReadParams) ([]pgtype.UUID, error) {
+			return arg.Ids, nil
+		},
+		markRecommendationImpressionsActedFn: func(ctx context.Context, arg db.MarkRecommendationImpressionsActedParams) ([]string, error) {
+			acted = arg
+			return []string{"resurfacer:new"}, nil
+		},
+	}
+	srv := &Server{queries: mock, metrics: newTestMetrics()}
+	e := echo.New()
+	srv.RegisterRoutes(e)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/links/read", strings.NewReader(`{"ids":["`+linkID.String()+`"]}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+	}
+
+	if acted.Action.String != impressionActionRead || len(acted.LinkIds) != 1 || uuidFromPg(acted.LinkIds[0]) != linkID {
+		t.Fatalf("expected the read to be recorded against the link, got %+v", acted)
+	}
+	if !acted.ShownAfter.Time.Equal(acted.ActedAt.Time.Add(-impressionAttributionWindow)) {
+		t.Fatalf("expected the attribution window to apply, got %+v", acted)
+	}
+}
+
+func TestOpenRecommendation(t *testing.T) {
+	t.Parallel()
+
+	var action string
+	mock := &mockQueries{
+		markRecommendationImpressionsActedFn: func(ctx context.Context, arg db.MarkRecommendationImpressionsActedParams) ([]string, error) {
+			action = arg.Action.String
+			return nil, nil
+		},
+	}
+	srv := &Server{queries: mock, metrics: newTestMetrics()}
+	e := echo.New()
+	srv.RegisterRoutes(e)
+
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/recommendations/"+uuid.NewString()+"/open", nil))
+	if rec.Code != http.StatusNoContent || action != impressionActionOpen {
+		t.Fatalf("expected the open to be recorded, got %d (%q): %s", rec.Code, action, rec.Body.String())
+	}
+
+	rec = httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/recommendations/nope/open", nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected status %d for a bad id, got %d", http.StatusBadRequest, rec.Code)
+	}
+}
+
+func TestRecommendationStats(t *testing.T) {
+	t.Parallel()
+
+	cfg := config.Config{DevUserID: uuid.New()}
+	var captured db.ListRecommendationImpressionStatsParams
+	mock := &mockQueries{
+		listRecommendationImpressionStatsFn: func(ctx context.Context, arg db.ListRecommendationImpressionStatsParams) ([]db.ListRecommendationImpressionStatsRow, error) {
+			captured = arg
+			return []db.ListRecommendationImpressionStatsRow{
+				{Strategy: "cold_start", Surface: "api", Impressions: 0},
+				{Strategy: "resurfacer:new", Surface: "api", Impressions: 8, Acted: 2, Opened: 1, Read: 1},
+			}, nil
+		},
+	}
+	srv := &Server{cfg: cfg, queries: mock, metrics: newTestMetrics()}
+	e := echo.New()
+	srv.RegisterRoutes(e)
+
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/recommendations/stats?days=7", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+	}
+
+	var payload impressionStatsResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &payload); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if payload.Days != 7 || uuidFromPg(captured.UserID) != cfg.DevUserID || !captured.Since.Time.Equal(payload.Since) {
+		t.Fatalf("unexpected window: %+v (queried %+v)", payload, captured)
+	}
+	if len(payload.Items) != 2 || payload.Items[0].CTR != 0 || payload.Items[1].CTR != 0.25 || payload.Items[1].Opened != 1 {
+		t.Fatalf("unexpected stats: %+v", payload.Items)
+	}
+
+	for _, days := range []string{"0", "366", "week"} {
+		rec = httptest.NewRecorder()
+		e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/recommendations/stats?days="+days, nil))
+		if rec.Code != http.StatusBadRequest {
+			t.Fatalf("expected status %d for days=%s, got %d", http.StatusBadRequest, days, rec.Code)
+		}
+	}
+}
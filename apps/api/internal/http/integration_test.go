@@ -0,0 +1,145 @@
+//go:build integration
+
+package httpapi
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/labstack/echo/v4"
+	"github.com/nats-io/nats.go"
+
+	"github.com/example/keepstack/apps/api/internal/config"
+	"github.com/example/keepstack/apps/api/internal/queue"
+	"github.com/example/keepstack/db/migrations"
+	"github.com/example/keepstack/messages"
+	"github.com/example/keepstack/testenv"
+)
+
+// TestIntegrationLinkLifecycle drives the real routes against migrated
+// Postgres and NATS containers: a save is stored and enqueued for the worker,
+// found by search and tag filters, and removed again.
+func TestIntegrationLinkLifecycle(t *testing.T) {
+	databaseURL := testenv.Postgres(t)
+	natsURL := testenv.NATS(t)
+
+	t.Setenv("DATABASE_URL", databaseURL)
+	t.Setenv("NATS_URL", natsURL)
+	cfg, err := config.Load()
+	if err != nil {
+		t.Fatalf("load config: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+
+	pool, err := pgxpool.New(ctx, databaseURL)
+	if err != nil {
+		t.Fatalf("connect postgres: %v", err)
+	}
+	defer pool.Close()
+
+	publisher, err := queue.New(natsURL)
+	if err != nil {
+		t.Fatalf("connect nats: %v", err)
+	}
+	defer publisher.Close()
+
+	// Listen where the worker would, so the test sees exactly what it gets.
+	conn, err := nats.Connect(natsURL)
+	if err != nil {
+		t.Fatalf("connect nats: %v", err)
+	}
+	defer conn.Close()
+	saved := make(chan *nats.Msg, 1)
+	if _, err := conn.ChanSubscribe(messages.SubjectLinksSaved, saved); err != nil {
+		t.Fatalf("subscribe: %v", err)
+	}
+	if err := conn.Flush(); err != nil {
+		t.Fatalf("flush subscription: %v", err)
+	}
+
+	srv := NewServer(cfg, pool, publisher, newTestMetrics())
+	srv.WithSchemaVersion(migrations.Latest())
+	e := echo.New()
+	srv.RegisterRoutes(e)
+
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		if body != "" {
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		}
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		return rec
+	}
+
+	if rec := do(http.MethodGet, "/healthz", ""); rec.Code != http.StatusOK {
+		t.Fatalf("healthz against a migrated database: status %d: %s", rec.Code, rec.Body)
+	}
+
+	rec := do(http.MethodPost, "/api/links", `{"url":"https://example.com/integration","title":"Integration harness notes"}`)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("create link: status %d: %s", rec.Code, rec.Body)
+	}
+	var created struct {
+		ID string `json:"id"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &created); err != nil {
+		t.Fatalf("decode create response: %v", err)
+	}
+
+	select {
+	case msg := <-saved:
+		var payload messages.LinkSaved
+		if err := json.Unmarshal(msg.Data, &payload); err != nil {
+			t.Fatalf("decode %s payload: %v", msg.Subject, err)
+		}
+		if payload.LinkID != created.ID || payload.EnqueuedAt.IsZero() {
+			t.Fatalf("unexpected %s payload %+v for link %s", msg.Subject, payload, created.ID)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatalf("no %s message for link %s", messages.SubjectLinksSaved, created.ID)
+	}
+
+	rec = do(http.MethodPost, "/api/tags", `{"name":"integration"}`)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("create tag: status %d: %s", rec.Code, rec.Body)
+	}
+	var tag tagResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &tag); err != nil {
+		t.Fatalf("decode tag: %v", err)
+	}
+	body, _ := json.Marshal(linkTagsRequest{TagIDs: []int32{tag.ID}})
+	if rec := do(http.MethodPost, "/api/links/"+created.ID+"/tags", string(body)); rec.Code != http.StatusCreated {
+		t.Fatalf("tag link: status %d: %s", rec.Code, rec.Body)
+	}
+
+	for _, query := range []string{"q=harness", "tags=integration"} {
+		rec := do(http.MethodGet, "/api/links?"+query, "")
+		if rec.Code != http.StatusOK {
+			t.Fatalf("list links (%s): status %d: %s", query, rec.Code, rec.Body)
+		}
+		var list listLinksResponse
+		if err := json.Unmarshal(rec.Body.Bytes(), &list); err != nil {
+			t.Fatalf("decode list (%s): %v", query, err)
+		}
+		if len(list.Items) != 1 || list.Items[0].ID != created.ID {
+			t.Fatalf("list links (%s): expected only %s, got %+v", query, created.ID, list.Items)
+		}
+	}
+
+	if rec := do(http.MethodDelete, "/api/links/"+created.ID, ""); rec.Code != http.StatusNoContent {
+		t.Fatalf("delete link: status %d: %s", rec.Code, rec.Body)
+	}
+	if rec := do(http.MethodDelete, "/api/links/"+created.ID, ""); rec.Code != http.StatusNotFound {
+		t.Fatalf("delete link again: expected 404, got %d", rec.Code)
+	}
+}
@@ -0,0 +1,448 @@
+package httpapi
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	stdhttp "net/http"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/labstack/echo/v4"
+
+	"github.com/example/keepstack/apps/api/internal/db"
+)
+
+// Job types and states, as stored in the jobs table.
+const (
+	jobTypeImport  = "import"
+	jobTypeExport  = "export"
+	jobTypeReparse = "reparse"
+
+	jobStateSucceeded = "succeeded"
+	jobStateFailed    = "failed"
+)
+
+const (
+	// jobProgressEvery is how many items a job handles between progress
+	// updates. The updates double as the job's heartbeat.
+	jobProgressEvery = 25
+	// jobStaleAfter is how long a job may go without an update before it
+	// is reported as interrupted, as happens when its replica restarts.
+	jobStaleAfter = 5 * time.Minute
+	// jobTimeout bounds one job.
+	jobTimeout = time.Hour
+
+	// maxImportJobLinks caps one import job. HTTP_MAX_BODY_BYTES may cap
+	// the request first.
+	maxImportJobLinks = 5000
+	// maxReparseLinks caps one reparse job.
+	maxReparseLinks = 500
+	// maxExportJobBytes caps the file an export job keeps in the database;
+	// GET /api/links/export streams libraries of any size.
+	maxExportJobBytes = 256 << 20
+)
+
+// jobFunc does a job's work. It returns the summary stored as the job's
+// result and, for jobs that produce one, a file to download.
+type jobFunc func(c echo.Context, progress *jobProgress) (any, *jobFile, error)
+
+type jobFile struct {
+	name        string
+	contentType string
+	body        []byte
+}
+
+// jobProgress records how far a job got. A nil jobProgress ignores updates,
+// so the work can also run outside a job.
+type jobProgress struct {
+	queries queryProvider
+	logger  echo.Logger
+	id      pgtype.UUID
+	total   int
+	done    int
+	flushed int
+}
+
+// Add counts n more items done, recording progress every jobProgressEvery
+// items.
+func (p *jobProgress) Add(ctx context.Context, n int) {
+	if p == nil {
+		return
+	}
+	p.done += n
+	if p.done-p.flushed >= jobProgressEvery {
+		p.flush(ctx)
+	}
+}
+
+func (p *jobProgress) flush(ctx context.Context) {
+	p.flushed = p.done
+	if err := p.queries.UpdateJobProgress(ctx, db.UpdateJobProgressParams{
+		Done:  int32(p.done),
+		Total: int32(p.total),
+		ID:    p.id,
+	}); err != nil {
+		// Progress is advisory; the job carries on.
+		p.logger.Warnf("job %s: record progress failed: %v", uuidFromPg(p.id), err)
+	}
+}
+
+type jobResponse struct {
+	ID         string          `json:"id"`
+	Type       string          `json:"type"`
+	State      string          `json:"state"`
+	Done       int32           `json:"done"`
+	Total      int32           `json:"total"`
+	Progress   float64         `json:"progress"`
+	Result     json.RawMessage `json:"result,omitempty"`
+	ResultURL  string          `json:"result_url,omitempty"`
+	Error      string          `json:"error,omitempty"`
+	CreatedAt  time.Time       `json:"created_at"`
+	UpdatedAt  time.Time       `json:"updated_at"`
+	FinishedAt *time.Time      `json:"finished_at,omitempty"`
+}
+
+type reparseRequest struct {
+	IDs []string `json:"ids"`
+	// Force parses links again even when their pages are unchanged, such
+	// as after a parser improvement.
+	Force bool `json:"force"`
+}
+
+type reparseFailure struct {
+	ID    string `json:"id"`
+	Error string `json:"error"`
+}
+
+type reparseResult struct {
+	Queued int              `json:"queued"`
+	Failed []reparseFailure `json:"failed"`
+}
+
+type exportResult struct {
+	Links int   `json:"links"`
+	Bytes int64 `json:"bytes"`
+}
+
+// toJobResponse reports job as of now. A job that stopped updating before
+// it finished is reported as failed.
+func toJobResponse(job db.Job, now time.Time) jobResponse {
+	id := uuidFromPg(job.ID).String()
+	resp := jobResponse{
+		ID:        id,
+		Type:      job.Type,
+		State:     job.State,
+		Done:      job.Done,
+		Total:     job.Total,
+		Error:     job.Error.String,
+		CreatedAt: job.CreatedAt.Time,
+		UpdatedAt: job.UpdatedAt.Time,
+	}
+	if len(job.Result) > 0 {
+		resp.Result = json.RawMessage(job.Result)
+	}
+	if job.FinishedAt.Valid {
+		finished := job.FinishedAt.Time
+		resp.FinishedAt = &finished
+	} else if now.Sub(job.UpdatedAt.Time) > jobStaleAfter {
+		resp.State = jobStateFailed
+		resp.Error = "job was interrupted before it finished"
+	}
+
+	switch {
+	case resp.State == jobStateSucceeded:
+		resp.Progress = 1
+	case job.Total > 0:
+		resp.Progress = min(1, float64(job.Done)/float64(job.Total))
+	}
+	if resp.State == jobStateSucceeded && job.Type == jobTypeExport {
+		resp.ResultURL = "/api/jobs/" + id + "/result"
+	}
+	return resp
+}
+
+// startJob records a job of jobType and runs it in the background, then
+// answers 202 with the job for the client to poll at its Location.
+func (s *Server) startJob(c echo.Context, jobType string, total int, run jobFunc) error {
+	ctx := c.Request().Context()
+	userID := uuidToPg(s.userID(ctx))
+
+	before := pgtype.Timestamptz{Time: time.Now().Add(-s.cfg.JobRetention), Valid: true}
+	if _, err := s.queries.DeleteJobsFinishedBefore(ctx, before); err != nil {
+		c.Logger().Warnf("delete expired jobs failed: %v", err)
+	}
+
+	job, err := s.queries.CreateJob(ctx, db.CreateJobParams{UserID: userID, Type: jobType, Total: int32(total)})
+	if err != nil {
+		c.Logger().Errorf("create %s job failed: %v", jobType, err)
+		return respondError(c, stdhttp.StatusInternalServerError, "failed to create job")
+	}
+
+	// The job outlives the request, so it gets its own context and a
+	// response it can write headers to without touching the real one.
+	// It keeps acting as the request's user.
+	jobCtx, cancel := context.WithTimeout(withSessionUser(context.Background(), s.userID(ctx)), jobTimeout)
+	detached := c.Echo().NewContext(c.Request().Clone(jobCtx), &bufferedResponseWriter{header: stdhttp.Header{}})
+	go func() {
+		defer cancel()
+		s.runJob(detached, job, run)
+	}()
+
+	resp := toJobResponse(job, time.Now())
+	c.Response().Header().Set(echo.HeaderLocation, "/api/jobs/"+resp.ID)
+	return c.JSON(stdhttp.StatusAccepted, resp)
+}
+
+// runJob runs a job and records how it ended.
+func (s *Server) runJob(c echo.Context, job db.Job, run jobFunc) {
+	ctx := c.Request().Context()
+	progress := &jobProgress{queries: s.queries, logger: c.Logger(), id: job.ID, total: int(job.Total)}
+	progress.flush(ctx)
+
+	result, file, err := run(c, progress)
+	if err == nil && file != nil {
+		err = s.queries.SaveJobFile(ctx, db.SaveJobFileParams{
+			JobID:       job.ID,
+			Name:        file.name,
+			ContentType: file.contentType,
+			Body:        file.body,
+		})
+	}
+
+	params := db.FinishJobParams{State: jobStateSucceeded, Done: int32(progress.done), ID: job.ID}
+	if err != nil {
+		c.Logger().Errorf("%s job %s failed: %v", job.Type, uuidFromPg(job.ID), err)
+		params.State = jobStateFailed
+		params.Error = pgtype.Text{String: jobErrorMessage(err), Valid: true}
+	}
+	if result != nil {
+		params.Result, _ = json.Marshal(result)
+	}
+
+	recordCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := s.queries.FinishJob(recordCtx, params); err != nil {
+		c.Logger().Errorf("%s job %s: record result failed: %v", job.Type, uuidFromPg(job.ID), err)
+	}
+}
+
+// jobErrorMessage is the error a failed job reports. Unexpected errors are
+// only logged.
+func jobErrorMessage(err error) string {
+	var apiErr apiError
+	if errors.As(err, &apiErr) {
+		return apiErr.Message
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return "job timed out"
+	}
+	return "job failed"
+}
+
+// handleGetJob reports a job's state and progress.
+func (s *Server) handleGetJob(c echo.Context) error {
+	id, err := parseUUIDParam(c.Param("id"))
+	if err != nil {
+		return respondError(c, stdhttp.StatusBadRequest, "invalid job id")
+	}
+	job, err := s.queries.GetJob(c.Request().Context(), db.GetJobParams{ID: uuidToPg(id), UserID: uuidToPg(s.currentUser(c))})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return respondError(c, stdhttp.StatusNotFound, "job not found")
+	}
+	if err != nil {
+		c.Logger().Errorf("get job %s failed: %v", id, err)
+		return respondError(c, stdhttp.StatusInternalServerError, "failed to load job")
+	}
+	c.Response().Header().Set("Cache-Control", "no-store")
+	return c.JSON(stdhttp.StatusOK, toJobResponse(job, time.Now()))
+}
+
+// handleGetJobResult downloads the file a finished job produced.
+func (s *Server) handleGetJobResult(c echo.Context) error {
+	id, err := parseUUIDParam(c.Param("id"))
+	if err != nil {
+		return respondError(c, stdhttp.StatusBadRequest, "invalid job id")
+	}
+	file, err := s.queries.GetJobFile(c.Request().Context(), db.GetJobFileParams{JobID: uuidToPg(id), UserID: uuidToPg(s.currentUser(c))})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return respondError(c, stdhttp.StatusNotFound, "job has no result")
+	}
+	if err != nil {
+		c.Logger().Errorf("get job %s result failed: %v", id, err)
+		return respondError(c, stdhttp.StatusInternalServerError, "failed to load job result")
+	}
+	header := c.Response().Header()
+	header.Set(echo.HeaderContentDisposition, fmt.Sprintf("attachment; filename=%q", file.Name))
+	header.Set("Cache-Control", "no-store")
+	return c.Blob(stdhttp.StatusOK, file.ContentType, file.Body)
+}
+
+// handleCreateImportJob imports a JSON export like POST /api/admin/import,
+// as a job for files too large to import in one request. With mode=read it
+// imports read history instead.
+func (s *Server) handleCreateImportJob(c echo.Context) error {
+	switch c.QueryParam("mode") {
+	case "":
+	case importModeRead:
+		return s.handleCreateReadImportJob(c)
+	default:
+		return respondError(c, stdhttp.StatusBadRequest, "mode must be read or omitted")
+	}
+
+	var links []importLink
+	if err := json.NewDecoder(c.Request().Body).Decode(&links); err != nil {
+		return respondError(c, stdhttp.StatusBadRequest, "body must be a JSON array of links")
+	}
+	if len(links) == 0 {
+		return respondError(c, stdhttp.StatusBadRequest, "no links to import")
+	}
+	if len(links) > maxImportJobLinks {
+		return respondError(c, stdhttp.StatusRequestEntityTooLarge, fmt.Sprintf("import at most %d links per job", maxImportJobLinks))
+	}
+
+	return s.startJob(c, jobTypeImport, len(links), func(c echo.Context, progress *jobProgress) (any, *jobFile, error) {
+		resp, err := s.importLinks(c, links, progress)
+		return resp, nil, err
+	})
+}
+
+// handleCreateExportJob builds the file GET /api/links/export streams, with
+// the same format and include_text parameters, as a job whose result is
+// downloaded once it finishes.
+func (s *Server) handleCreateExportJob(c echo.Context) error {
+	if s.pool == nil {
+		return respondError(c, stdhttp.StatusServiceUnavailable, "export is unavailable in memory mode")
+	}
+	format := strings.ToLower(strings.TrimSpace(c.QueryParam("format")))
+	if format == "" {
+		format = "json"
+	}
+	if format != "json" && format != "csv" {
+		return respondError(c, stdhttp.StatusBadRequest, "format must be json or csv")
+	}
+	includeText := false
+	if raw := c.QueryParam("include_text"); raw != "" {
+		parsed, err := strconv.ParseBool(raw)
+		if err != nil {
+			return respondError(c, stdhttp.StatusBadRequest, "include_text must be a boolean")
+		}
+		includeText = parsed
+	}
+
+	userID := uuidToPg(s.currentUser(c))
+	total, err := s.queries.CountLinks(c.Request().Context(), db.CountLinksParams{UserID: userID})
+	if err != nil {
+		c.Logger().Errorf("export job: count links failed: %v", err)
+		return respondError(c, stdhttp.StatusInternalServerError, "failed to count links")
+	}
+
+	return s.startJob(c, jobTypeExport, int(total), func(c echo.Context, progress *jobProgress) (any, *jobFile, error) {
+		ctx := c.Request().Context()
+		rows, err := s.pool.Query(ctx, exportLinksQuery, userID, includeText)
+		if err != nil {
+			return nil, nil, err
+		}
+		defer rows.Close()
+
+		var buf bytes.Buffer
+		out := &limitedWriter{buf: &buf, limit: maxExportJobBytes}
+		file := &jobFile{name: fmt.Sprintf("keepstack-links-%s.%s", time.Now().UTC().Format("20060102"), format)}
+		var exporter linkExporter
+		if format == "csv" {
+			file.contentType = "text/csv; charset=utf-8"
+			exporter = newCSVLinkExporter(out, includeText)
+		} else {
+			file.contentType = echo.MIMEApplicationJSONCharsetUTF8
+			exporter = newJSONLinkExporter(out)
+		}
+
+		if err := exporter.Begin(); err != nil {
+			return nil, nil, err
+		}
+		count := 0
+		for rows.Next() {
+			link, err := scanExportLink(rows)
+			if err != nil {
+				return nil, nil, err
+			}
+			if err := exporter.Write(link); err != nil {
+				return nil, nil, err
+			}
+			count++
+			progress.Add(ctx, 1)
+		}
+		if err := rows.Err(); err != nil {
+			return nil, nil, err
+		}
+		if err := exporter.End(); err != nil {
+			return nil, nil, err
+		}
+		file.body = buf.Bytes()
+		return exportResult{Links: count, Bytes: int64(len(file.body))}, file, nil
+	})
+}
+
+// handleCreateReparseJob asks the worker to ingest saved links again, for
+// instance after extraction improves. Progress counts links queued; each
+// link's own status reports its ingest.
+func (s *Server) handleCreateReparseJob(c echo.Context) error {
+	var req reparseRequest
+	if err := c.Bind(&req); err != nil {
+		return respondBindError(c, err)
+	}
+	if len(req.IDs) == 0 {
+		return respondError(c, stdhttp.StatusBadRequest, "ids are required")
+	}
+	if len(req.IDs) > maxReparseLinks {
+		return respondError(c, stdhttp.StatusBadRequest, fmt.Sprintf("at most %d links may be reparsed at once", maxReparseLinks))
+	}
+	for _, raw := range req.IDs {
+		if _, err := parseUUIDParam(raw); err != nil {
+			return respondError(c, stdhttp.StatusBadRequest, fmt.Sprintf("invalid link id: %s", raw))
+		}
+	}
+
+	return s.startJob(c, jobTypeReparse, len(req.IDs), func(c echo.Context, progress *jobProgress) (any, *jobFile, error) {
+		ctx := c.Request().Context()
+		result := reparseResult{Failed: []reparseFailure{}}
+		for _, raw := range req.IDs {
+			progress.Add(ctx, 1)
+			id, _ := parseUUIDParam(raw)
+			link, err := s.queries.GetLink(ctx, uuidToPg(id))
+			if errors.Is(err, pgx.ErrNoRows) || (err == nil && uuidFromPg(link.UserID) != s.userID(ctx)) {
+				result.Failed = append(result.Failed, reparseFailure{ID: raw, Error: "link not found"})
+				continue
+			}
+			if err == nil {
+				err = s.publisher.PublishLinkReparse(ctx, id, req.Force)
+			}
+			if err != nil {
+				c.Logger().Errorf("reparse job: queue %s failed: %v", id, err)
+				result.Failed = append(result.Failed, reparseFailure{ID: raw, Error: "failed to queue link"})
+				continue
+			}
+			result.Queued++
+		}
+		return result, nil, nil
+	})
+}
+
+// limitedWriter fails writes that would take buf past limit bytes.
+type limitedWriter struct {
+	buf   *bytes.Buffer
+	limit int
+}
+
+func (w *limitedWriter) Write(p []byte) (int, error) {
+	if w.buf.Len()+len(p) > w.limit {
+		return 0, apiError{Code: stdhttp.StatusRequestEntityTooLarge, Message: "export is too large for a job; download it from GET /api/links/export instead"}
+	}
+	return w.buf.Write(p)
+}
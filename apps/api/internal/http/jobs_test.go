@@ -0,0 +1,328 @@
+package httpapi
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/labstack/echo/v4"
+
+	"github.com/example/keepstack/apps/api/internal/config"
+	"github.com/example/keepstack/apps/api/internal/db"
+	"github.com/example/keepstack/apps/api/internal/memstore"
+	"github.com/example/keepstack/apps/api/internal/queue"
+)
+
+type jobTestServer struct {
+	t     *testing.T
+	e     *echo.Echo
+	store *memstore.Store
+	cfg   config.Config
+
+	mu     sync.Mutex
+	queued []uuid.UUID
+}
+
+func newJobTestServer(t *testing.T) *jobTestServer {
+	t.Helper()
+	t.Setenv("MEMORY_MODE", "true")
+	t.Setenv("DATABASE_URL", "")
+	t.Setenv("NATS_URL", "")
+	cfg, err := config.Load()
+	if err != nil {
+		t.Fatalf("load config: %v", err)
+	}
+
+	ts := &jobTestServer{t: t, store: memstore.New(cfg.DevUserID), cfg: cfg}
+	publisher := queue.NewMemory()
+	publisher.OnLinkSaved(func(_ context.Context, linkID uuid.UUID) {
+		ts.mu.Lock()
+		defer ts.mu.Unlock()
+		ts.queued = append(ts.queued, linkID)
+	})
+	srv := NewMemoryServer(cfg, ts.store, publisher, newTestMetrics())
+	ts.e = echo.New()
+	srv.RegisterRoutes(ts.e)
+	return ts
+}
+
+func (ts *jobTestServer) do(method, path, body string) *httptest.ResponseRecorder {
+	ts.t.Helper()
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	if body != "" {
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	}
+	rec := httptest.NewRecorder()
+	ts.e.ServeHTTP(rec, req)
+	return rec
+}
+
+// start posts to path and waits for the job it starts to finish.
+func (ts *jobTestServer) start(path, body string) jobResponse {
+	ts.t.Helper()
+	rec := ts.do(http.MethodPost, path, body)
+	if rec.Code != http.StatusAccepted {
+		ts.t.Fatalf("POST %s: expected 202, got %d: %s", path, rec.Code, rec.Body)
+	}
+	location := rec.Header().Get(echo.HeaderLocation)
+	if !strings.HasPrefix(location, "/api/jobs/") {
+		ts.t.Fatalf("POST %s: unexpected Location %q", path, location)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		rec := ts.do(http.MethodGet, location, "")
+		if rec.Code != http.StatusOK {
+			ts.t.Fatalf("GET %s: status %d: %s", location, rec.Code, rec.Body)
+		}
+		var job jobResponse
+		if err := json.Unmarshal(rec.Body.Bytes(), &job); err != nil {
+			ts.t.Fatalf("decode job: %v", err)
+		}
+		if job.FinishedAt != nil {
+			return job
+		}
+		if time.Now().After(deadline) {
+			ts.t.Fatalf("job %s did not finish: %+v", location, job)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestImportAndReparseJobs(t *testing.T) {
+	ts := newJobTestServer(t)
+
+	job := ts.start("/api/links/import", `[
+		{"url":"https://example.com/one","title":"One","favorite":true},
+		{"url":"https://example.com/two","tags":["go"]},
+		{"url":"not a url"}
+	]`)
+	if job.Type != jobTypeImport || job.State != jobStateSucceeded || job.Progress != 1 || job.Done != 3 || job.Total != 3 {
+		t.Fatalf("unexpected import job %+v", job)
+	}
+	var imported importResponse
+	if err := json.Unmarshal(job.Result, &imported); err != nil {
+		t.Fatalf("decode import result: %v", err)
+	}
+	if imported.Created != 2 || len(imported.Failed) != 1 || imported.Failed[0].URL != "not a url" {
+		t.Fatalf("unexpected import result %+v", imported)
+	}
+	if job.ResultURL != "" {
+		t.Fatalf("import jobs have no file, got result_url %q", job.ResultURL)
+	}
+
+	ts.mu.Lock()
+	saved := append([]uuid.UUID{}, ts.queued...)
+	ts.mu.Unlock()
+	if len(saved) != 2 {
+		t.Fatalf("expected both imported links queued, got %v", saved)
+	}
+
+	missing := uuid.New()
+	job = ts.start("/api/links/reparse", `{"ids":["`+saved[0].String()+`","`+missing.String()+`"]}`)
+	var reparsed reparseResult
+	if err := json.Unmarshal(job.Result, &reparsed); err != nil {
+		t.Fatalf("decode reparse result: %v", err)
+	}
+	if job.State != jobStateSucceeded || reparsed.Queued != 1 || len(reparsed.Failed) != 1 || reparsed.Failed[0].ID != missing.String() {
+		t.Fatalf("unexpected reparse job %+v with result %+v", job, reparsed)
+	}
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	if len(ts.queued) != 3 || ts.queued[2] != saved[0] {
+		t.Fatalf("expected %s queued again, got %v", saved[0], ts.queued)
+	}
+}
+
+func TestImportJobQueuesRecommendationsRefresh(t *testing.T) {
+	t.Setenv("RECOMMENDATION_REFRESH_THRESHOLD", "2")
+	ts := newJobTestServer(t)
+
+	job := ts.start("/api/links/import", `[{"url":"https://example.com/one"}]`)
+	var imported importResponse
+	if err := json.Unmarshal(job.Result, &imported); err != nil {
+		t.Fatalf("decode import result: %v", err)
+	}
+	if imported.Created != 1 || imported.RefreshQueued {
+		t.Fatalf("expected no refresh below the threshold, got %+v", imported)
+	}
+
+	job = ts.start("/api/links/import", `[{"url":"https://example.com/one"},{"url":"https://example.com/two"},{"url":"https://example.com/three"}]`)
+	if err := json.Unmarshal(job.Result, &imported); err != nil {
+		t.Fatalf("decode import result: %v", err)
+	}
+	if imported.Created != 2 || !imported.RefreshQueued {
+		t.Fatalf("expected a refresh once the import created enough links, got %+v", imported)
+	}
+}
+
+func TestReadImportJobMarksSavedLinksRead(t *testing.T) {
+	ts := newJobTestServer(t)
+	ts.start("/api/links/import", `[
+		{"url":"https://example.com/one"},
+		{"url":"https://example.com/two"},
+		{"url":"https://example.com/three"}
+	]`)
+	ts.mu.Lock()
+	saved := append([]uuid.UUID{}, ts.queued...)
+	ts.mu.Unlock()
+
+	ctx := context.Background()
+	earlier := time.Date(2023, time.March, 1, 0, 0, 0, 0, time.UTC)
+	if _, err := ts.store.MarkLinksReadAt(ctx, db.MarkLinksReadAtParams{
+		Ids:     []pgtype.UUID{uuidToPg(saved[2])},
+		ReadAts: []pgtype.Timestamptz{{Time: earlier, Valid: true}},
+		UserID:  uuidToPg(ts.cfg.DevUserID),
+	}); err != nil {
+		t.Fatalf("mark read: %v", err)
+	}
+
+	job := ts.start("/api/links/import?mode=read", "\ufefftitle,URL,visit_time\n"+
+		"One,https://example.com/one#intro,2024-05-02 10:00:00\n"+
+		"One again,https://example.com/one,2024-05-01T09:30:00Z\n"+
+		"Two,https://example.com/two,\n"+
+		"Three,https://example.com/three,2024-05-03\n"+
+		"Elsewhere,https://example.org/unsaved,2024-05-03\n"+
+		"Bad date,https://example.com/two,yesterday\n")
+	if job.Type != jobTypeImport || job.State != jobStateSucceeded || job.Done != 6 || job.Total != 6 {
+		t.Fatalf("unexpected read import job %+v", job)
+	}
+	var result readImportResponse
+	if err := json.Unmarshal(job.Result, &result); err != nil {
+		t.Fatalf("decode read import result: %v", err)
+	}
+	if result.Marked != 2 || result.AlreadyRead != 1 || result.Unmatched != 1 ||
+		len(result.Failed) != 1 || result.Failed[0].Error != "invalid read date" {
+		t.Fatalf("unexpected read import result %+v", result)
+	}
+
+	wantReadAt := map[uuid.UUID]time.Time{
+		saved[0]: time.Date(2024, time.May, 1, 9, 30, 0, 0, time.UTC),
+		saved[2]: earlier,
+	}
+	for i, id := range saved {
+		link, err := ts.store.GetLink(ctx, uuidToPg(id))
+		if err != nil {
+			t.Fatalf("get link %d: %v", i, err)
+		}
+		if !link.ReadAt.Valid {
+			t.Fatalf("expected link %d read", i)
+		}
+		if want, ok := wantReadAt[id]; ok && !link.ReadAt.Time.Equal(want) {
+			t.Errorf("link %d read at %s, want %s", i, link.ReadAt.Time, want)
+		}
+	}
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	if len(ts.queued) != len(saved) {
+		t.Fatalf("expected the read import to save nothing, got %v", ts.queued)
+	}
+}
+
+func TestParseReadHistoryWithoutHeader(t *testing.T) {
+	t.Parallel()
+
+	entries, failed, err := parseReadHistory(strings.NewReader("https://example.com/a,2024-01-02\nhttps://example.com/b\n\n,2024-01-03\nhttps://example.com/c,2999-01-01\n"), 10)
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if len(entries) != 2 || entries[0].ReadAt != time.Date(2024, time.January, 2, 0, 0, 0, 0, time.UTC) || !entries[1].ReadAt.IsZero() {
+		t.Fatalf("unexpected entries %+v", entries)
+	}
+	if len(failed) != 1 || failed[0].URL != "https://example.com/c" {
+		t.Fatalf("expected the future date refused, got %+v", failed)
+	}
+
+	if _, _, err := parseReadHistory(strings.NewReader("https://example.com/a\nhttps://example.com/b\n"), 1); err != errTooManyReadHistoryRows {
+		t.Fatalf("expected the row limit enforced, got %v", err)
+	}
+}
+
+func TestJobRequestsAreValidated(t *testing.T) {
+	ts := newJobTestServer(t)
+
+	cases := []struct {
+		method, path, body string
+		want               int
+	}{
+		{http.MethodPost, "/api/links/import", `{"url":"https://example.com"}`, http.StatusBadRequest},
+		{http.MethodPost, "/api/links/import", `[]`, http.StatusBadRequest},
+		{http.MethodPost, "/api/links/import?mode=pocket", `[]`, http.StatusBadRequest},
+		{http.MethodPost, "/api/links/import?mode=read", "url,read_at\n", http.StatusBadRequest},
+		{http.MethodPost, "/api/links/reparse", `{"ids":[]}`, http.StatusBadRequest},
+		{http.MethodPost, "/api/links/reparse", `{"ids":["nope"]}`, http.StatusBadRequest},
+		{http.MethodPost, "/api/links/export?format=json", "", http.StatusServiceUnavailable},
+		{http.MethodGet, "/api/jobs/nope", "", http.StatusBadRequest},
+		{http.MethodGet, "/api/jobs/" + uuid.NewString(), "", http.StatusNotFound},
+		{http.MethodGet, "/api/jobs/" + uuid.NewString() + "/result", "", http.StatusNotFound},
+	}
+	for _, tc := range cases {
+		if rec := ts.do(tc.method, tc.path, tc.body); rec.Code != tc.want {
+			t.Errorf("%s %s %s: expected %d, got %d: %s", tc.method, tc.path, tc.body, tc.want, rec.Code, rec.Body)
+		}
+	}
+}
+
+func TestGetJobResultDownloadsFile(t *testing.T) {
+	ts := newJobTestServer(t)
+	ctx := context.Background()
+
+	job, err := ts.store.CreateJob(ctx, db.CreateJobParams{UserID: uuidToPg(ts.cfg.DevUserID), Type: jobTypeExport, Total: 1})
+	if err != nil {
+		t.Fatalf("create job: %v", err)
+	}
+	if err := ts.store.SaveJobFile(ctx, db.SaveJobFileParams{JobID: job.ID, Name: "links.csv", ContentType: "text/csv; charset=utf-8", Body: []byte("id,url\n")}); err != nil {
+		t.Fatalf("save file: %v", err)
+	}
+	if err := ts.store.FinishJob(ctx, db.FinishJobParams{State: jobStateSucceeded, Done: 1, ID: job.ID}); err != nil {
+		t.Fatalf("finish job: %v", err)
+	}
+
+	id := uuidFromPg(job.ID).String()
+	rec := ts.do(http.MethodGet, "/api/jobs/"+id, "")
+	var got jobResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+		t.Fatalf("decode job: %v", err)
+	}
+	if got.ResultURL != "/api/jobs/"+id+"/result" {
+		t.Fatalf("expected a result_url, got %+v", got)
+	}
+
+	rec = ts.do(http.MethodGet, got.ResultURL, "")
+	if rec.Code != http.StatusOK || rec.Body.String() != "id,url\n" {
+		t.Fatalf("unexpected result %d: %q", rec.Code, rec.Body)
+	}
+	if got := rec.Header().Get(echo.HeaderContentDisposition); got != `attachment; filename="links.csv"` {
+		t.Fatalf("unexpected Content-Disposition %q", got)
+	}
+}
+
+func TestJobResponseReportsInterruptedJobs(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now()
+	job := db.Job{
+		ID:        uuidToPg(uuid.New()),
+		Type:      jobTypeImport,
+		State:     "running",
+		Done:      50,
+		Total:     200,
+		CreatedAt: pgtype.Timestamptz{Time: now.Add(-time.Hour), Valid: true},
+		UpdatedAt: pgtype.Timestamptz{Time: now.Add(-time.Minute), Valid: true},
+	}
+	if got := toJobResponse(job, now); got.State != "running" || got.Progress != 0.25 || got.Error != "" {
+		t.Fatalf("unexpected running job %+v", got)
+	}
+
+	job.UpdatedAt.Time = now.Add(-jobStaleAfter - time.Second)
+	if got := toJobResponse(job, now); got.State != jobStateFailed || got.Error == "" {
+		t.Fatalf("expected a stale job to be reported failed, got %+v", got)
+	}
+}
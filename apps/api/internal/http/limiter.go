@@ -0,0 +1,121 @@
+package httpapi
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"golang.org/x/time/rate"
+
+	"github.com/example/keepstack/apps/api/internal/db"
+)
+
+// highlightRateLimit allows 20 highlights a minute per user with a burst of 10.
+var highlightRateLimit = RateLimitRule{Limit: rate.Every(time.Minute / 20), Burst: 10}
+
+// Limiter reports whether the caller identified by key may proceed,
+// consuming a token when it may.
+type Limiter interface {
+	Allow(ctx context.Context, key string) bool
+}
+
+// LocalLimiter keeps a token bucket per key in memory. Buckets idle for
+// longer than the TTL are evicted; by then they would have refilled anyway.
+type LocalLimiter struct {
+	rule  RateLimitRule
+	store *rateLimiterStore
+}
+
+// NewLocalLimiter builds an in-memory limiter applying rule to every key.
+func NewLocalLimiter(rule RateLimitRule, ttl time.Duration) *LocalLimiter {
+	return &LocalLimiter{rule: rule, store: newRateLimiterStore(ttl)}
+}
+
+// Allow takes a token from the bucket for key.
+func (l *LocalLimiter) Allow(_ context.Context, key string) bool {
+	now := time.Now()
+	return l.store.get(key, l.rule, now).AllowN(now, 1)
+}
+
+type rateLimitQueries interface {
+	TakeRateLimitToken(context.Context, db.TakeRateLimitTokenParams) (float64, error)
+	DeleteIdleRateLimitBuckets(context.Context, pgtype.Timestamptz) (int64, error)
+}
+
+// PostgresLimiter keeps token buckets in the rate_limit_buckets table so a
+// limit holds across API replicas. Keys are namespaced with prefix. When the
+// database cannot be reached it falls back to a per-pod bucket rather than
+// failing the request or dropping the limit.
+type PostgresLimiter struct {
+	queries  rateLimitQueries
+	prefix   string
+	rule     RateLimitRule
+	ttl      time.Duration
+	fallback *LocalLimiter
+	logger   *log.Logger
+
+	mu        sync.Mutex
+	lastSweep time.Time
+}
+
+// NewPostgresLimiter builds a shared limiter. Buckets idle for longer than
+// ttl are deleted by whichever replica sweeps first.
+func NewPostgresLimiter(queries rateLimitQueries, prefix string, rule RateLimitRule, ttl time.Duration, logger *log.Logger) *PostgresLimiter {
+	return &PostgresLimiter{
+		queries:  queries,
+		prefix:   prefix,
+		rule:     rule,
+		ttl:      ttl,
+		fallback: NewLocalLimiter(rule, ttl),
+		logger:   logger,
+	}
+}
+
+// Allow takes a token from the shared bucket for key.
+func (l *PostgresLimiter) Allow(ctx context.Context, key string) bool {
+	l.sweep(ctx, time.Now())
+
+	burst := l.rule.Burst
+	if burst < 1 {
+		burst = 1
+	}
+	_, err := l.queries.TakeRateLimitToken(ctx, db.TakeRateLimitTokenParams{
+		Key:       l.prefix + key,
+		Burst:     float64(burst),
+		PerSecond: float64(l.rule.Limit),
+	})
+	switch {
+	case err == nil:
+		return true
+	case errors.Is(err, pgx.ErrNoRows):
+		return false
+	default:
+		l.logf("shared rate limit unavailable, using local bucket: %v", err)
+		return l.fallback.Allow(ctx, key)
+	}
+}
+
+func (l *PostgresLimiter) sweep(ctx context.Context, now time.Time) {
+	l.mu.Lock()
+	if now.Sub(l.lastSweep) < l.ttl {
+		l.mu.Unlock()
+		return
+	}
+	l.lastSweep = now
+	l.mu.Unlock()
+
+	cutoff := pgtype.Timestamptz{Time: now.Add(-l.ttl), Valid: true}
+	if _, err := l.queries.DeleteIdleRateLimitBuckets(ctx, cutoff); err != nil {
+		l.logf("sweep idle rate limit buckets: %v", err)
+	}
+}
+
+func (l *PostgresLimiter) logf(format string, args ...any) {
+	if l.logger != nil {
+		l.logger.Printf(format, args...)
+	}
+}
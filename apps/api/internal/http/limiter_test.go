@@ -0,0 +1,72 @@
+package httpapi
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"golang.org/x/time/rate"
+
+	"github.com/example/keepstack/apps/api/internal/db"
+)
+
+type fakeRateLimitQueries struct {
+	takeErr error
+	taken   []db.TakeRateLimitTokenParams
+	sweeps  int
+}
+
+func (f *fakeRateLimitQueries) TakeRateLimitToken(_ context.Context, arg db.TakeRateLimitTokenParams) (float64, error) {
+	f.taken = append(f.taken, arg)
+	if f.takeErr != nil {
+		return 0, f.takeErr
+	}
+	return arg.Burst - 1, nil
+}
+
+func (f *fakeRateLimitQueries) DeleteIdleRateLimitBuckets(context.Context, pgtype.Timestamptz) (int64, error) {
+	f.sweeps++
+	return 0, nil
+}
+
+func TestPostgresLimiter(t *testing.T) {
+	t.Parallel()
+
+	rule := RateLimitRule{Limit: rate.Every(time.Minute / 20), Burst: 10}
+	queries := &fakeRateLimitQueries{}
+	limiter := NewPostgresLimiter(queries, "highlight:", rule, time.Hour, nil)
+
+	if !limiter.Allow(context.Background(), "user-1") {
+		t.Fatalf("expected first request to be allowed")
+	}
+	got := queries.taken[0]
+	if got.Key != "highlight:user-1" || got.Burst != 10 || got.PerSecond != float64(rule.Limit) {
+		t.Fatalf("unexpected params: %+v", got)
+	}
+
+	queries.takeErr = pgx.ErrNoRows
+	if limiter.Allow(context.Background(), "user-1") {
+		t.Fatalf("expected empty bucket to be rejected")
+	}
+
+	if queries.sweeps != 1 {
+		t.Fatalf("expected one sweep within the TTL, got %d", queries.sweeps)
+	}
+}
+
+func TestPostgresLimiterFallsBackToLocalBucket(t *testing.T) {
+	t.Parallel()
+
+	queries := &fakeRateLimitQueries{takeErr: errors.New("connection refused")}
+	limiter := NewPostgresLimiter(queries, "highlight:", RateLimitRule{Limit: rate.Every(time.Hour), Burst: 1}, time.Hour, nil)
+
+	if !limiter.Allow(context.Background(), "user-1") {
+		t.Fatalf("expected fallback bucket to allow the first request")
+	}
+	if limiter.Allow(context.Background(), "user-1") {
+		t.Fatalf("expected fallback bucket to enforce the limit")
+	}
+}
@@ -0,0 +1,65 @@
+package httpapi
+
+import (
+	"errors"
+	"strconv"
+
+	stdhttp "net/http"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/example/keepstack/media"
+)
+
+// mediaCacheControl lets browsers and proxies keep an image for a year:
+// the hash in its path changes whenever its bytes do.
+const mediaCacheControl = "public, max-age=31536000, immutable"
+
+// WithMedia serves GET /api/media/:hash from store, the storage the worker
+// mirrors archived images to.
+func (s *Server) WithMedia(store media.Store) {
+	s.media = store
+}
+
+// handleGetMedia serves an archived image by the hash of its bytes. Paths
+// are only learned from archived HTML, so the endpoint needs no user.
+func (s *Server) handleGetMedia(c echo.Context) error {
+	if s.media == nil {
+		return respondError(c, stdhttp.StatusServiceUnavailable, "media storage is not configured")
+	}
+	hash := c.Param("hash")
+	if !media.ValidHash(hash) {
+		return respondError(c, stdhttp.StatusNotFound, "media not found")
+	}
+
+	etag := `"` + hash + `"`
+	header := c.Response().Header()
+	header.Set("Cache-Control", mediaCacheControl)
+	header.Set("ETag", etag)
+	if c.Request().Header.Get("If-None-Match") == etag {
+		return c.NoContent(stdhttp.StatusNotModified)
+	}
+
+	object, err := s.media.Get(c.Request().Context(), hash)
+	if errors.Is(err, media.ErrNotFound) {
+		header.Del("Cache-Control")
+		header.Del("ETag")
+		return respondError(c, stdhttp.StatusNotFound, "media not found")
+	}
+	if err != nil {
+		header.Del("Cache-Control")
+		header.Del("ETag")
+		c.Logger().Errorf("media: get %s: %v", hash, err)
+		return respondError(c, stdhttp.StatusInternalServerError, "failed to load media")
+	}
+	defer object.Body.Close()
+
+	contentType := object.ContentType
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	if object.Size > 0 {
+		header.Set("Content-Length", strconv.FormatInt(object.Size, 10))
+	}
+	return c.Stream(stdhttp.StatusOK, contentType, object.Body)
+}
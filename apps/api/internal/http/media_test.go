@@ -0,0 +1,92 @@
+package httpapi
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/example/keepstack/apps/api/internal/config"
+	"github.com/example/keepstack/media"
+)
+
+// testPNG is padded past compressMinLength so the gzip skip is exercised.
+var testPNG = append([]byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR"), make([]byte, 2048)...)
+
+func newMediaTestServer(store media.Store) *echo.Echo {
+	srv := &Server{
+		cfg:     config.Config{HTTPCompressionLevel: 5},
+		queries: &mockQueries{},
+		metrics: newTestMetrics(),
+	}
+	if store != nil {
+		srv.WithMedia(store)
+	}
+	e := echo.New()
+	srv.RegisterRoutes(e)
+	return e
+}
+
+func TestGetMediaServesStoredImage(t *testing.T) {
+	t.Parallel()
+
+	store := media.NewDirStore(t.TempDir())
+	hash := media.Hash(testPNG)
+	if err := store.Put(context.Background(), hash, "image/png", testPNG); err != nil {
+		t.Fatalf("store image: %v", err)
+	}
+	e := newMediaTestServer(store)
+
+	req := httptest.NewRequest(http.MethodGet, media.Path(hash), nil)
+	req.Header.Set(echo.HeaderAcceptEncoding, "gzip")
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if rec.Body.String() != string(testPNG) {
+		t.Fatalf("unexpected body %q", rec.Body.String())
+	}
+	if got := rec.Header().Get(echo.HeaderContentType); got != "image/png" {
+		t.Fatalf("expected image/png, got %q", got)
+	}
+	if got := rec.Header().Get("Cache-Control"); got != mediaCacheControl {
+		t.Fatalf("expected immutable caching, got %q", got)
+	}
+	if got := rec.Header().Get(echo.HeaderContentEncoding); got != "" {
+		t.Fatalf("expected images to skip compression, got %q", got)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, media.Path(hash), nil)
+	req.Header.Set("If-None-Match", rec.Header().Get("ETag"))
+	rec = httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	if rec.Code != http.StatusNotModified {
+		t.Fatalf("expected 304 for a matching ETag, got %d", rec.Code)
+	}
+}
+
+func TestGetMediaRejectsUnknownImages(t *testing.T) {
+	t.Parallel()
+
+	e := newMediaTestServer(media.NewDirStore(t.TempDir()))
+	for _, path := range []string{media.Path(media.Hash(testPNG)), "/api/media/not-a-hash"} {
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		if rec.Code != http.StatusNotFound {
+			t.Fatalf("%s: expected 404, got %d", path, rec.Code)
+		}
+		if rec.Header().Get("Cache-Control") == mediaCacheControl {
+			t.Fatalf("%s: a missing image must not be cached", path)
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	newMediaTestServer(nil).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, media.Path(media.Hash(testPNG)), nil))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected 503 without media storage, got %d", rec.Code)
+	}
+}
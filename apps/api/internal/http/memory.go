@@ -0,0 +1,32 @@
+package httpapi
+
+import (
+	"github.com/google/uuid"
+
+	"github.com/example/keepstack/apps/api/internal/config"
+	"github.com/example/keepstack/apps/api/internal/memstore"
+	"github.com/example/keepstack/apps/api/internal/observability"
+	"github.com/example/keepstack/apps/api/internal/queue"
+)
+
+// NewMemoryServer builds a Server backed by store instead of Postgres, for
+// MEMORY_MODE. Readiness and liveness always pass. Routes that need the
+// database itself rather than its queries are unavailable: export and
+// digests answer 503 and no admin jobs are registered.
+func NewMemoryServer(cfg config.Config, store *memstore.Store, publisher queue.Publisher, metrics *observability.Metrics) *Server {
+	s := &Server{
+		cfg:              cfg,
+		queries:          store,
+		publisher:        publisher,
+		metrics:          metrics,
+		highlightLimiter: NewLocalLimiter(highlightRateLimit, rateLimiterIdleTTL),
+		unfurls:          newUnfurlCache(cfg.UnfurlCacheSize, cfg.UnfurlCacheTTL),
+		events:           NewEventBroker(),
+	}
+	if cfg.PublicInboxUserID != uuid.Nil {
+		client, global := s.publicInboxRules()
+		s.publicInboxClientLimiter = NewLocalLimiter(client, rateLimiterIdleTTL)
+		s.publicInboxLimiter = NewLocalLimiter(global, rateLimiterIdleTTL)
+	}
+	return s
+}
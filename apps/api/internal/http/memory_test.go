@@ -0,0 +1,121 @@
+package httpapi
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+
+	"github.com/example/keepstack/apps/api/internal/config"
+	"github.com/example/keepstack/apps/api/internal/memstore"
+	"github.com/example/keepstack/apps/api/internal/queue"
+)
+
+// TestMemoryServerLinkLifecycle drives the real routes against the
+// in-memory store: a save reaches the publisher, is found by search and tag
+// filters, carries its highlight, and is removed again.
+func TestMemoryServerLinkLifecycle(t *testing.T) {
+	t.Setenv("MEMORY_MODE", "true")
+	t.Setenv("DATABASE_URL", "")
+	t.Setenv("NATS_URL", "")
+	cfg, err := config.Load()
+	if err != nil {
+		t.Fatalf("load config without DATABASE_URL or NATS_URL: %v", err)
+	}
+
+	publisher := queue.NewMemory()
+	var saved []uuid.UUID
+	publisher.OnLinkSaved(func(_ context.Context, linkID uuid.UUID) { saved = append(saved, linkID) })
+
+	srv := NewMemoryServer(cfg, memstore.New(cfg.DevUserID), publisher, newTestMetrics())
+	e := echo.New()
+	srv.RegisterRoutes(e)
+
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		if body != "" {
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		}
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		return rec
+	}
+
+	for _, path := range []string{"/healthz", "/livez"} {
+		if rec := do(http.MethodGet, path, ""); rec.Code != http.StatusOK {
+			t.Fatalf("%s in memory mode: status %d: %s", path, rec.Code, rec.Body)
+		}
+	}
+
+	rec := do(http.MethodPost, "/api/links", `{"url":"https://example.com/memory","title":"Memory mode notes"}`)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("create link: status %d: %s", rec.Code, rec.Body)
+	}
+	var created struct {
+		ID string `json:"id"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &created); err != nil {
+		t.Fatalf("decode create response: %v", err)
+	}
+	if len(saved) != 1 || saved[0].String() != created.ID {
+		t.Fatalf("expected link %s published as saved, got %v", created.ID, saved)
+	}
+	do(http.MethodPost, "/api/links", `{"url":"https://example.org/other","title":"Unrelated"}`)
+
+	rec = do(http.MethodPost, "/api/tags", `{"name":"frontend"}`)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("create tag: status %d: %s", rec.Code, rec.Body)
+	}
+	var tag tagResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &tag); err != nil {
+		t.Fatalf("decode tag: %v", err)
+	}
+	if rec := do(http.MethodPost, "/api/tags", `{"name":"frontend"}`); rec.Code != http.StatusConflict {
+		t.Fatalf("duplicate tag: expected 409, got %d: %s", rec.Code, rec.Body)
+	}
+	body, _ := json.Marshal(linkTagsRequest{TagIDs: []int32{tag.ID}})
+	if rec := do(http.MethodPost, "/api/links/"+created.ID+"/tags", string(body)); rec.Code != http.StatusCreated {
+		t.Fatalf("tag link: status %d: %s", rec.Code, rec.Body)
+	}
+	if rec := do(http.MethodPost, "/api/links/"+created.ID+"/highlights", `{"text":"worth keeping","note":"why"}`); rec.Code != http.StatusCreated {
+		t.Fatalf("create highlight: status %d: %s", rec.Code, rec.Body)
+	}
+
+	for _, query := range []string{"q=memory", "tags=frontend"} {
+		rec := do(http.MethodGet, "/api/links?"+query, "")
+		if rec.Code != http.StatusOK {
+			t.Fatalf("list links (%s): status %d: %s", query, rec.Code, rec.Body)
+		}
+		var list listLinksResponse
+		if err := json.Unmarshal(rec.Body.Bytes(), &list); err != nil {
+			t.Fatalf("decode list (%s): %v", query, err)
+		}
+		if list.TotalCount != 1 || len(list.Items) != 1 || list.Items[0].ID != created.ID {
+			t.Fatalf("list links (%s): expected only %s, got %+v", query, created.ID, list)
+		}
+		item := list.Items[0]
+		if len(item.Tags) != 1 || item.Tags[0].Name != "frontend" {
+			t.Fatalf("list links (%s): expected the frontend tag, got %+v", query, item.Tags)
+		}
+		if len(item.Highlights) != 1 || item.Highlights[0].Text != "worth keeping" {
+			t.Fatalf("list links (%s): expected the highlight, got %+v", query, item.Highlights)
+		}
+	}
+
+	if rec := do(http.MethodGet, "/api/links/export", ""); rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("export in memory mode: expected 503, got %d", rec.Code)
+	}
+
+	if rec := do(http.MethodDelete, "/api/links/"+created.ID, ""); rec.Code != http.StatusNoContent {
+		t.Fatalf("delete link: status %d: %s", rec.Code, rec.Body)
+	}
+	if rec := do(http.MethodDelete, "/api/links/"+created.ID, ""); rec.Code != http.StatusNotFound {
+		t.Fatalf("delete link again: expected 404, got %d", rec.Code)
+	}
+}
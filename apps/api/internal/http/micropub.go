@@ -0,0 +1,231 @@
+package httpapi
+
+import (
+	"encoding/json"
+	"errors"
+	stdhttp "net/http"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/example/keepstack/apps/api/internal/db"
+)
+
+// maxMicropubCategories caps the tags returned by q=category.
+const maxMicropubCategories = 100
+
+// micropubRequest is a Micropub create request in its JSON form. Form-encoded
+// requests are converted into the same shape.
+type micropubRequest struct {
+	Type       []string                     `json:"type"`
+	Action     string                       `json:"action"`
+	Properties map[string][]json.RawMessage `json:"properties"`
+}
+
+// registerMicropubRoutes adds the Micropub endpoint. With sessions enabled
+// it takes the user's session token as its access token; otherwise it is
+// added when at least one Micropub token is configured and acts as
+// DevUserID.
+func (s *Server) registerMicropubRoutes(api *echo.Group) {
+	auth := MicropubSessionMiddleware([]byte(s.cfg.JWTSecret))
+	if !s.cfg.AuthSessions {
+		tokens := configuredTokens(s.cfg.MicropubTokens)
+		if len(tokens) == 0 {
+			return
+		}
+		auth = MicropubAuthMiddleware(tokens)
+	}
+
+	api.GET("/micropub", s.handleMicropubQuery, auth)
+	api.POST("/micropub", s.handleMicropubCreate, auth)
+}
+
+// MicropubAuthMiddleware accepts one of tokens as an Authorization bearer
+// token or, for form-encoded posts, an access_token field, as the Micropub
+// spec allows.
+func MicropubAuthMiddleware(tokens []string) echo.MiddlewareFunc {
+	return micropubAuthMiddleware(func(c echo.Context, token string) bool {
+		return tokenAllowed(tokens, token)
+	})
+}
+
+// MicropubSessionMiddleware is MicropubAuthMiddleware for instances with
+// sessions: the access token must be a session token signed with secret,
+// and the request runs as its user.
+func MicropubSessionMiddleware(secret []byte) echo.MiddlewareFunc {
+	return micropubAuthMiddleware(func(c echo.Context, token string) bool {
+		userID, err := parseSessionToken(secret, token)
+		if err != nil {
+			return false
+		}
+		req := c.Request()
+		c.SetRequest(req.WithContext(withSessionUser(req.Context(), userID)))
+		return true
+	})
+}
+
+// micropubAuthMiddleware reads the access token and lets the request through
+// when allow accepts it.
+func micropubAuthMiddleware(allow func(c echo.Context, token string) bool) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			token := bearerToken(c.Request())
+			if token == "" && isFormRequest(c.Request()) {
+				token = c.FormValue("access_token")
+			}
+			if token == "" {
+				c.Response().Header().Set(echo.HeaderWWWAuthenticate, `Bearer realm="keepstack"`)
+				return micropubError(c, stdhttp.StatusUnauthorized, "unauthorized", "no access token was provided")
+			}
+			if !allow(c, token) {
+				return micropubError(c, stdhttp.StatusForbidden, "forbidden", "access token is not valid")
+			}
+			return next(c)
+		}
+	}
+}
+
+// handleMicropubQuery answers the q=config, q=syndicate-to, and q=category
+// queries clients send before posting.
+func (s *Server) handleMicropubQuery(c echo.Context) error {
+	switch c.QueryParam("q") {
+	case "config":
+		return c.JSON(stdhttp.StatusOK, map[string]any{
+			"syndicate-to": []any{},
+			"post-types":   []map[string]string{{"type": "bookmark", "name": "Bookmark"}},
+			"q":            []string{"config", "syndicate-to", "category"},
+		})
+	case "syndicate-to":
+		return c.JSON(stdhttp.StatusOK, map[string]any{"syndicate-to": []any{}})
+	case "category":
+		tags, err := s.queries.SearchTagsByPrefix(c.Request().Context(), db.SearchTagsByPrefixParams{
+			UserID:    uuidToPg(s.currentUser(c)),
+			Prefix:    likeEscaper.Replace(strings.TrimSpace(c.QueryParam("filter"))),
+			PageLimit: maxMicropubCategories,
+		})
+		if err != nil {
+			c.Logger().Errorf("micropub category: search failed: %v", err)
+			return micropubError(c, stdhttp.StatusInternalServerError, "server_error", "failed to list categories")
+		}
+		names := make([]string, 0, len(tags))
+		for _, tag := range tags {
+			names = append(names, tag.Name)
+		}
+		return c.JSON(stdhttp.StatusOK, map[string]any{"categories": names})
+	default:
+		return micropubError(c, stdhttp.StatusBadRequest, "invalid_request", "unsupported query")
+	}
+}
+
+// handleMicropubCreate saves the bookmark-of URL of an h-entry. Other post
+// types and the update and delete actions are rejected, since keepstack only
+// stores links. Posting a URL that is already saved adds its categories as
+// tags and answers with the existing link.
+func (s *Server) handleMicropubCreate(c echo.Context) error {
+	req, err := parseMicropubRequest(c)
+	if err != nil {
+		s.metrics.LinkCreateFailure.Inc()
+		var maxBytesErr *stdhttp.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			return micropubError(c, stdhttp.StatusRequestEntityTooLarge, "invalid_request", bodyTooLargeMessage(maxBytesErr.Limit))
+		}
+		return micropubError(c, stdhttp.StatusBadRequest, "invalid_request", err.Error())
+	}
+
+	if req.Action != "" {
+		s.metrics.LinkCreateFailure.Inc()
+		return micropubError(c, stdhttp.StatusBadRequest, "invalid_request", "action "+req.Action+" is not supported")
+	}
+	if len(req.Type) > 0 && req.Type[0] != "h-entry" {
+		s.metrics.LinkCreateFailure.Inc()
+		return micropubError(c, stdhttp.StatusBadRequest, "invalid_request", "only h-entry posts are supported")
+	}
+	bookmarkOf := req.stringProperty("bookmark-of")
+	if len(bookmarkOf) == 0 {
+		s.metrics.LinkCreateFailure.Inc()
+		return micropubError(c, stdhttp.StatusBadRequest, "invalid_request", "only bookmark-of posts are supported")
+	}
+
+	title := ""
+	if names := req.stringProperty("name"); len(names) > 0 {
+		title = names[0]
+	}
+	saved, err := s.quickSave(c, quickSaveInput{URL: bookmarkOf[0], Title: title, Tags: req.stringProperty("category")})
+	if err != nil {
+		var apiErr apiError
+		if errors.As(err, &apiErr) && apiErr.Code == stdhttp.StatusBadRequest {
+			return micropubError(c, apiErr.Code, "invalid_request", "bookmark-of must be a valid url")
+		}
+		return micropubError(c, stdhttp.StatusInternalServerError, "server_error", err.Error())
+	}
+
+	c.Response().Header().Set(echo.HeaderLocation, "/api/links/"+saved.ID.String()+"/status")
+	if saved.Created {
+		return c.NoContent(stdhttp.StatusCreated)
+	}
+	return c.NoContent(stdhttp.StatusOK)
+}
+
+// parseMicropubRequest reads a JSON or form-encoded Micropub request. In form
+// posts, h=entry sets the type and repeated or bracketed keys such as
+// category[] become multi-valued properties.
+func parseMicropubRequest(c echo.Context) (micropubRequest, error) {
+	if !isFormRequest(c.Request()) {
+		var req micropubRequest
+		if err := json.NewDecoder(c.Request().Body).Decode(&req); err != nil {
+			return micropubRequest{}, err
+		}
+		return req, nil
+	}
+
+	form, err := c.FormParams()
+	if err != nil {
+		return micropubRequest{}, err
+	}
+	req := micropubRequest{Action: form.Get("action"), Properties: map[string][]json.RawMessage{}}
+	if h := form.Get("h"); h != "" {
+		req.Type = []string{"h-" + h}
+	}
+	for key, values := range form {
+		switch key {
+		case "h", "action", "access_token":
+			continue
+		}
+		key = strings.TrimSuffix(key, "[]")
+		for _, value := range values {
+			raw, err := json.Marshal(value)
+			if err != nil {
+				return micropubRequest{}, err
+			}
+			req.Properties[key] = append(req.Properties[key], raw)
+		}
+	}
+	return req, nil
+}
+
+// stringProperty returns the string values of a property, trimmed and with
+// blanks dropped. Nested objects, such as an h-cite in bookmark-of, are
+// skipped.
+func (r micropubRequest) stringProperty(name string) []string {
+	values := make([]string, 0, len(r.Properties[name]))
+	for _, raw := range r.Properties[name] {
+		var value string
+		if err := json.Unmarshal(raw, &value); err != nil {
+			continue
+		}
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+func isFormRequest(req *stdhttp.Request) bool {
+	contentType := req.Header.Get(echo.HeaderContentType)
+	return strings.HasPrefix(contentType, echo.MIMEApplicationForm) || strings.HasPrefix(contentType, echo.MIMEMultipartForm)
+}
+
+// micropubError writes an error in the shape the Micropub spec defines.
+func micropubError(c echo.Context, status int, code, description string) error {
+	return c.JSON(status, map[string]string{"error": code, "error_description": description})
+}
@@ -0,0 +1,195 @@
+package httpapi
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/labstack/echo/v4"
+
+	"github.com/example/keepstack/apps/api/internal/config"
+	"github.com/example/keepstack/apps/api/internal/db"
+)
+
+const testMicropubToken = "micropub-secret"
+
+func withMicropubToken(cfg *config.Config) {
+	cfg.MicropubTokens = []string{testMicropubToken}
+}
+
+// newMicropubSaveQueries records the created link and assigned tag names.
+func newMicropubSaveQueries(created *db.CreateLinkParams, tags *[]string) *mockQueries {
+	return &mockQueries{
+		findLinkByURLHashFn: func(ctx context.Context, arg db.FindLinkByURLHashParams) (db.FindLinkByURLHashRow, error) {
+			return db.FindLinkByURLHashRow{}, pgx.ErrNoRows
+		},
+		createLinkFn: func(ctx context.Context, params db.CreateLinkParams) (db.CreateLinkRow, error) {
+			*created = params
+			return db.CreateLinkRow{ID: params.ID, Url: params.Url}, nil
+		},
+		getTagByNameFn: func(ctx context.Context, arg db.GetTagByNameParams) (db.Tag, error) {
+			*tags = append(*tags, arg.Name)
+			return db.Tag{ID: int32(len(*tags)), Name: arg.Name}, nil
+		},
+		addTagToLinkFn: func(ctx context.Context, arg db.AddTagToLinkParams) error {
+			return nil
+		},
+	}
+}
+
+func TestMicropubCreateFromForm(t *testing.T) {
+	t.Parallel()
+
+	var (
+		created db.CreateLinkParams
+		tags    []string
+	)
+	publisher := &stubPublisher{}
+	e := newRouteTestServer(newMicropubSaveQueries(&created, &tags), publisher, withMicropubToken)
+
+	form := url.Values{
+		"h":            {"entry"},
+		"bookmark-of":  {"https://example.com/post?utm_campaign=x"},
+		"name":         {"A post"},
+		"category[]":   {"go", "indieweb"},
+		"access_token": {testMicropubToken},
+	}
+	req := httptest.NewRequest(http.MethodPost, "/api/micropub", strings.NewReader(form.Encode()))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationForm)
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusCreated, rec.Code, rec.Body.String())
+	}
+	if created.Url != "https://example.com/post" || created.Title.String != "A post" {
+		t.Fatalf("unexpected created link: %+v", created)
+	}
+	if want := "/api/links/" + uuidFromPg(created.ID).String() + "/status"; rec.Header().Get(echo.HeaderLocation) != want {
+		t.Fatalf("expected Location %q, got %q", want, rec.Header().Get(echo.HeaderLocation))
+	}
+	if len(tags) != 2 || tags[0] != "go" || tags[1] != "indieweb" {
+		t.Fatalf("expected categories to become tags, got %v", tags)
+	}
+	if !publisher.called {
+		t.Fatalf("expected link to be enqueued")
+	}
+}
+
+func TestMicropubCreateFromJSON(t *testing.T) {
+	t.Parallel()
+
+	var (
+		created db.CreateLinkParams
+		tags    []string
+	)
+	e := newRouteTestServer(newMicropubSaveQueries(&created, &tags), &stubPublisher{}, withMicropubToken)
+
+	body := `{"type":["h-entry"],"properties":{"bookmark-of":["https://example.com/json"],"category":["reading"],"content":[{"html":"<p>x</p>"}]}}`
+	req := httptest.NewRequest(http.MethodPost, "/api/micropub", strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	req.Header.Set(echo.HeaderAuthorization, "Bearer "+testMicropubToken)
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusCreated, rec.Code, rec.Body.String())
+	}
+	if created.Url != "https://example.com/json" || created.Title.Valid {
+		t.Fatalf("unexpected created link: %+v", created)
+	}
+	if len(tags) != 1 || tags[0] != "reading" {
+		t.Fatalf("expected category to become a tag, got %v", tags)
+	}
+}
+
+func TestMicropubRejectsUnsupportedRequests(t *testing.T) {
+	t.Parallel()
+
+	e := newRouteTestServer(&mockQueries{}, &stubPublisher{}, withMicropubToken)
+
+	cases := []struct {
+		name        string
+		contentType string
+		body        string
+		token       string
+		code        int
+		errorCode   string
+	}{
+		{"missing token", echo.MIMEApplicationForm, "h=entry&bookmark-of=https://example.com", "", http.StatusUnauthorized, "unauthorized"},
+		{"wrong token", echo.MIMEApplicationForm, "h=entry&bookmark-of=https://example.com&access_token=nope", "", http.StatusForbidden, "forbidden"},
+		{"note", echo.MIMEApplicationForm, "h=entry&content=hello", testMicropubToken, http.StatusBadRequest, "invalid_request"},
+		{"event", echo.MIMEApplicationJSON, `{"type":["h-event"],"properties":{"bookmark-of":["https://example.com"]}}`, testMicropubToken, http.StatusBadRequest, "invalid_request"},
+		{"delete", echo.MIMEApplicationJSON, `{"action":"delete","url":"https://example.com"}`, testMicropubToken, http.StatusBadRequest, "invalid_request"},
+		{"bad url", echo.MIMEApplicationForm, "h=entry&bookmark-of=http://", testMicropubToken, http.StatusBadRequest, "invalid_request"},
+	}
+	for _, tc := range cases {
+		req := httptest.NewRequest(http.MethodPost, "/api/micropub", strings.NewReader(tc.body))
+		req.Header.Set(echo.HeaderContentType, tc.contentType)
+		if tc.token != "" {
+			req.Header.Set(echo.HeaderAuthorization, "Bearer "+tc.token)
+		}
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+
+		if rec.Code != tc.code {
+			t.Fatalf("%s: expected status %d, got %d: %s", tc.name, tc.code, rec.Code, rec.Body.String())
+		}
+		var resp map[string]string
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("%s: decode response: %v", tc.name, err)
+		}
+		if resp["error"] != tc.errorCode || resp["error_description"] == "" {
+			t.Fatalf("%s: unexpected error body %v", tc.name, resp)
+		}
+	}
+}
+
+func TestMicropubQueries(t *testing.T) {
+	t.Parallel()
+
+	var filter string
+	queries := &mockQueries{
+		searchTagsByPrefixFn: func(ctx context.Context, arg db.SearchTagsByPrefixParams) ([]db.Tag, error) {
+			filter = arg.Prefix
+			return []db.Tag{{ID: 1, Name: "golang"}}, nil
+		},
+	}
+	e := newRouteTestServer(queries, &stubPublisher{}, withMicropubToken)
+
+	get := func(target string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		req.Header.Set(echo.HeaderAuthorization, "Bearer "+testMicropubToken)
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := get("/api/micropub?q=config")
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"syndicate-to":[]`) {
+		t.Fatalf("unexpected config response %d: %s", rec.Code, rec.Body.String())
+	}
+
+	rec = get("/api/micropub?q=category&filter=go")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, rec.Code)
+	}
+	var categories struct {
+		Categories []string `json:"categories"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &categories); err != nil {
+		t.Fatalf("decode categories: %v", err)
+	}
+	if filter != "go" || len(categories.Categories) != 1 || categories.Categories[0] != "golang" {
+		t.Fatalf("unexpected categories %v for filter %q", categories.Categories, filter)
+	}
+
+	if rec = get("/api/micropub?q=source"); rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected status %d for unsupported query, got %d", http.StatusBadRequest, rec.Code)
+	}
+}
@@ -1,13 +1,20 @@
 package httpapi
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
 	"time"
 
 	"github.com/labstack/echo/v4"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
 
 	"github.com/example/keepstack/apps/api/internal/observability"
+	"github.com/example/keepstack/observe"
 )
 
 // MetricsMiddleware records request metrics for the API.
@@ -35,11 +42,61 @@ func MetricsMiddleware(metrics *observability.Metrics) echo.MiddlewareFunc {
 			code := strconv.Itoa(status)
 			durationSeconds := time.Since(start).Seconds()
 
-			metrics.HTTPRequestTotal.WithLabelValues(route, code).Inc()
-			metrics.HTTPRequestDurationSeconds.WithLabelValues(route, code).Observe(durationSeconds)
+			ctx := c.Request().Context()
+			observe.IncWithTrace(ctx, metrics.HTTPRequestTotal.WithLabelValues(route, code))
+			observe.ObserveWithTrace(ctx, metrics.HTTPRequestDurationSeconds.WithLabelValues(route, code), durationSeconds)
 
 			if status < 200 || status >= 300 {
-				metrics.HTTPRequestNon2xxTotal.WithLabelValues(route, code).Inc()
+				observe.IncWithTrace(ctx, metrics.HTTPRequestNon2xxTotal.WithLabelValues(route, code))
+			}
+
+			return err
+		}
+	}
+}
+
+const tracerName = "github.com/example/keepstack/apps/api/internal/http"
+
+// TracingMiddleware starts a server span per request, continuing any trace
+// context sent by the caller. Handlers pass the request context on, so the
+// span becomes the parent of database and NATS spans.
+func TracingMiddleware() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			req := c.Request()
+			ctx := otel.GetTextMapPropagator().Extract(req.Context(), propagation.HeaderCarrier(req.Header))
+
+			route := c.Path()
+			if route == "" {
+				route = req.URL.Path
+			}
+
+			ctx, span := otel.Tracer(tracerName).Start(ctx, req.Method+" "+route,
+				trace.WithSpanKind(trace.SpanKindServer),
+				trace.WithAttributes(
+					attribute.String("http.request.method", req.Method),
+					attribute.String("http.route", route),
+					attribute.String("url.path", req.URL.Path),
+				),
+			)
+			defer span.End()
+
+			c.SetRequest(req.WithContext(ctx))
+			err := next(c)
+
+			status := c.Response().Status
+			if err != nil {
+				span.RecordError(err)
+				var httpErr *echo.HTTPError
+				if errors.As(err, &httpErr) {
+					status = httpErr.Code
+				} else {
+					status = http.StatusInternalServerError
+				}
+			}
+			span.SetAttributes(attribute.Int("http.response.status_code", status))
+			if status >= http.StatusInternalServerError {
+				span.SetStatus(codes.Error, http.StatusText(status))
 			}
 
 			return err
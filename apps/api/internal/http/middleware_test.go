@@ -0,0 +1,65 @@
+package httpapi
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// TestTracingMiddleware swaps the global tracer provider, so it must not run
+// in parallel with other tests.
+func TestTracingMiddleware(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	prevProvider, prevPropagator := otel.GetTracerProvider(), otel.GetTextMapPropagator()
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() {
+		otel.SetTracerProvider(prevProvider)
+		otel.SetTextMapPropagator(prevPropagator)
+	})
+
+	e := echo.New()
+	e.Use(TracingMiddleware())
+	e.GET("/api/links/:id", func(c echo.Context) error {
+		return c.JSON(http.StatusBadGateway, map[string]string{"error": "upstream"})
+	})
+
+	const traceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+	req := httptest.NewRequest(http.MethodGet, "/api/links/123", nil)
+	req.Header.Set("traceparent", "00-"+traceID+"-00f067aa0ba902b7-01")
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+
+	spans := recorder.Ended()
+	if len(spans) != 1 {
+		t.Fatalf("expected 1 span, got %d", len(spans))
+	}
+	span := spans[0]
+	if span.Name() != "GET /api/links/:id" {
+		t.Fatalf("unexpected span name %q", span.Name())
+	}
+	if got := span.SpanContext().TraceID().String(); got != traceID {
+		t.Fatalf("expected span to continue trace %s, got %s", traceID, got)
+	}
+	if span.Status().Code != codes.Error {
+		t.Fatalf("expected 5xx response to mark the span as an error, got %v", span.Status().Code)
+	}
+
+	var status int64
+	for _, attr := range span.Attributes() {
+		if attr.Key == "http.response.status_code" {
+			status = attr.Value.AsInt64()
+		}
+	}
+	if status != http.StatusBadGateway {
+		t.Fatalf("expected status attribute %d, got %d", http.StatusBadGateway, status)
+	}
+}
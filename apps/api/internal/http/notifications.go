@@ -0,0 +1,219 @@
+package httpapi
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	stdhttp "net/http"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/labstack/echo/v4"
+
+	"github.com/example/keepstack/apps/api/internal/db"
+	"github.com/example/keepstack/apps/api/internal/queue"
+	"github.com/example/keepstack/messages"
+)
+
+// Notification kinds, as stored in notifications.kind.
+const (
+	notificationIngestFailed = "ingest_failed"
+	notificationDigestSent   = "digest_sent"
+	notificationBackupFailed = "backup_failed"
+)
+
+type notificationResponse struct {
+	ID        string     `json:"id"`
+	Kind      string     `json:"kind"`
+	LinkID    string     `json:"link_id,omitempty"`
+	Message   string     `json:"message"`
+	Read      bool       `json:"read"`
+	ReadAt    *time.Time `json:"read_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// RecordNotification turns a domain event into in-app notifications: a
+// failed ingest for the link's owner, a sent digest for its recipient, and
+// a failed backup for every admin. Other events are ignored. Each event must
+// reach only one replica, or it is recorded once per replica.
+func (s *Server) RecordNotification(ctx context.Context, event queue.Event) error {
+	switch event.Type {
+	case queue.EventLinkStatus:
+		var status messages.LinkStatus
+		if err := json.Unmarshal(event.Data, &status); err != nil {
+			return fmt.Errorf("decode %s event: %w", event.Type, err)
+		}
+		if status.Status != "failed" {
+			return nil
+		}
+		linkID, err := parseUUIDParam(event.LinkID)
+		if err != nil {
+			return fmt.Errorf("%s event: invalid link id %q", event.Type, event.LinkID)
+		}
+		link, err := s.queries.GetLink(ctx, uuidToPg(linkID))
+		if errors.Is(err, pgx.ErrNoRows) {
+			// The link was deleted while it was being ingested.
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("look up link %s: %w", linkID, err)
+		}
+		message := "Could not save " + link.Url
+		if status.FailedStage != "" {
+			message += " (" + status.FailedStage + " failed)"
+		}
+		if status.Error != "" {
+			message += ": " + status.Error
+		}
+		_, err = s.queries.CreateNotification(ctx, db.CreateNotificationParams{
+			UserID:  link.UserID,
+			Kind:    notificationIngestFailed,
+			LinkID:  link.ID,
+			Message: message,
+		})
+		return err
+
+	case queue.EventDigestSent:
+		var sent messages.DigestSent
+		if err := json.Unmarshal(event.Data, &sent); err != nil {
+			return fmt.Errorf("decode %s event: %w", event.Type, err)
+		}
+		userID, err := parseUUIDParam(event.UserID)
+		if err != nil {
+			return fmt.Errorf("%s event: invalid user id %q", event.Type, event.UserID)
+		}
+		noun := "links"
+		if sent.Links == 1 {
+			noun = "link"
+		}
+		_, err = s.queries.CreateNotification(ctx, db.CreateNotificationParams{
+			UserID:  uuidToPg(userID),
+			Kind:    notificationDigestSent,
+			Message: fmt.Sprintf("Your digest was sent with %d unread %s", sent.Links, noun),
+		})
+		return err
+
+	case queue.EventBackupFailed:
+		var failed messages.BackupFailed
+		if err := json.Unmarshal(event.Data, &failed); err != nil {
+			return fmt.Errorf("decode %s event: %w", event.Type, err)
+		}
+		message := "Backup failed: " + failed.Error
+		if failed.Kind != "" {
+			message = fmt.Sprintf("Backup failed (%s): %s", failed.Kind, failed.Error)
+		}
+		_, err := s.queries.CreateAdminNotifications(ctx, db.CreateAdminNotificationsParams{
+			Kind:    notificationBackupFailed,
+			Message: message,
+		})
+		return err
+	}
+	return nil
+}
+
+// handleListNotifications pages through the caller's notifications, newest
+// first, along with how many are unread. unread=true leaves out the ones
+// already read.
+func (s *Server) handleListNotifications(c echo.Context) error {
+	limit, offset, err := parsePagination(strings.TrimSpace(c.QueryParam("limit")), strings.TrimSpace(c.QueryParam("offset")))
+	if err != nil {
+		return respondError(c, stdhttp.StatusBadRequest, err.Error())
+	}
+	unreadOnly := false
+	if raw := strings.TrimSpace(c.QueryParam("unread")); raw != "" {
+		unreadOnly, err = strconv.ParseBool(raw)
+		if err != nil {
+			return respondError(c, stdhttp.StatusBadRequest, "unread must be a boolean")
+		}
+	}
+
+	ctx := c.Request().Context()
+	userID := uuidToPg(s.userID(ctx))
+	rows, err := s.queries.ListNotifications(ctx, db.ListNotificationsParams{
+		UserID:     userID,
+		UnreadOnly: unreadOnly,
+		PageLimit:  int32(limit),
+		PageOffset: int32(offset),
+	})
+	if err != nil {
+		c.Logger().Errorf("list notifications: query failed: %v", err)
+		return respondError(c, stdhttp.StatusInternalServerError, "failed to list notifications")
+	}
+	unread, err := s.queries.CountUnreadNotifications(ctx, userID)
+	if err != nil {
+		c.Logger().Errorf("list notifications: count unread failed: %v", err)
+		return respondError(c, stdhttp.StatusInternalServerError, "failed to list notifications")
+	}
+
+	items := make([]notificationResponse, 0, len(rows))
+	for _, row := range rows {
+		items = append(items, toNotificationResponse(row))
+	}
+	return c.JSON(stdhttp.StatusOK, map[string]any{
+		"items":  items,
+		"unread": unread,
+		"limit":  limit,
+		"offset": offset,
+	})
+}
+
+// handleCountUnreadNotifications returns how many notifications the caller
+// has not read, for a badge that polls without fetching the list.
+func (s *Server) handleCountUnreadNotifications(c echo.Context) error {
+	unread, err := s.queries.CountUnreadNotifications(c.Request().Context(), uuidToPg(s.currentUser(c)))
+	if err != nil {
+		c.Logger().Errorf("count unread notifications: query failed: %v", err)
+		return respondError(c, stdhttp.StatusInternalServerError, "failed to count notifications")
+	}
+	return c.JSON(stdhttp.StatusOK, map[string]int64{"unread": unread})
+}
+
+// handleMarkNotificationRead marks one of the caller's notifications read.
+func (s *Server) handleMarkNotificationRead(c echo.Context) error {
+	id, err := parseUUIDParam(c.Param("id"))
+	if err != nil {
+		return respondError(c, stdhttp.StatusBadRequest, "invalid notification id")
+	}
+	updated, err := s.queries.MarkNotificationRead(c.Request().Context(), db.MarkNotificationReadParams{
+		ID:     uuidToPg(id),
+		UserID: uuidToPg(s.currentUser(c)),
+	})
+	if err != nil {
+		c.Logger().Errorf("mark notification read: update failed: %v", err)
+		return respondError(c, stdhttp.StatusInternalServerError, "failed to mark notification read")
+	}
+	if updated == 0 {
+		return respondError(c, stdhttp.StatusNotFound, "notification not found")
+	}
+	return c.NoContent(stdhttp.StatusNoContent)
+}
+
+// handleMarkAllNotificationsRead marks every unread notification the caller
+// has read and reports how many there were.
+func (s *Server) handleMarkAllNotificationsRead(c echo.Context) error {
+	updated, err := s.queries.MarkAllNotificationsRead(c.Request().Context(), uuidToPg(s.currentUser(c)))
+	if err != nil {
+		c.Logger().Errorf("mark notifications read: update failed: %v", err)
+		return respondError(c, stdhttp.StatusInternalServerError, "failed to mark notifications read")
+	}
+	return c.JSON(stdhttp.StatusOK, map[string]int64{"updated": updated})
+}
+
+func toNotificationResponse(row db.Notification) notificationResponse {
+	resp := notificationResponse{
+		ID:        uuidFromPg(row.ID).String(),
+		Kind:      row.Kind,
+		Message:   row.Message,
+		Read:      row.ReadAt.Valid,
+		ReadAt:    timestampPtr(row.ReadAt),
+		CreatedAt: row.CreatedAt.Time,
+	}
+	if row.LinkID.Valid {
+		resp.LinkID = uuidFromPg(row.LinkID).String()
+	}
+	return resp
+}
@@ -0,0 +1,208 @@
+package httpapi
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/labstack/echo/v4"
+
+	"github.com/example/keepstack/apps/api/internal/config"
+	"github.com/example/keepstack/apps/api/internal/db"
+	"github.com/example/keepstack/apps/api/internal/queue"
+)
+
+func TestRecordNotification(t *testing.T) {
+	t.Parallel()
+
+	ownerID := uuid.New()
+	linkID := uuid.New()
+
+	tests := []struct {
+		name      string
+		event     queue.Event
+		wantKind  string
+		wantAdmin bool
+		wantText  string
+	}{
+		{
+			name:     "failed ingest",
+			event:    queue.Event{Type: queue.EventLinkStatus, UserID: ownerID.String(), LinkID: linkID.String(), Data: json.RawMessage(`{"status":"failed","failed_stage":"fetch","error":"timeout"}`)},
+			wantKind: notificationIngestFailed,
+			wantText: "Could not save https://example.com/article (fetch failed): timeout",
+		},
+		{
+			name:  "ingest progress",
+			event: queue.Event{Type: queue.EventLinkStatus, UserID: ownerID.String(), LinkID: linkID.String(), Data: json.RawMessage(`{"status":"parsing"}`)},
+		},
+		{
+			name:     "digest sent",
+			event:    queue.Event{Type: queue.EventDigestSent, UserID: ownerID.String(), Data: json.RawMessage(`{"links":1}`)},
+			wantKind: notificationDigestSent,
+			wantText: "Your digest was sent with 1 unread link",
+		},
+		{
+			name:      "backup failed",
+			event:     queue.Event{Type: queue.EventBackupFailed, Data: json.RawMessage(`{"kind":"full","error":"pg_dump: connection refused"}`)},
+			wantKind:  notificationBackupFailed,
+			wantAdmin: true,
+			wantText:  "Backup failed (full): pg_dump: connection refused",
+		},
+		{
+			name:  "unrelated event",
+			event: queue.Event{Type: queue.EventHighlightCreated, UserID: ownerID.String(), LinkID: linkID.String()},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			var created *db.CreateNotificationParams
+			var admin *db.CreateAdminNotificationsParams
+			queries := &mockQueries{
+				getLinkFn: func(ctx context.Context, id pgtype.UUID) (db.GetLinkRow, error) {
+					return db.GetLinkRow{ID: id, UserID: uuidToPg(ownerID), Url: "https://example.com/article"}, nil
+				},
+				createNotificationFn: func(ctx context.Context, arg db.CreateNotificationParams) (db.Notification, error) {
+					created = &arg
+					return db.Notification{}, nil
+				},
+				createAdminNotificationsFn: func(ctx context.Context, arg db.CreateAdminNotificationsParams) (int64, error) {
+					admin = &arg
+					return 1, nil
+				},
+			}
+			srv := &Server{cfg: config.Config{}, queries: queries, metrics: newTestMetrics()}
+
+			if err := srv.RecordNotification(context.Background(), tt.event); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			switch {
+			case tt.wantKind == "":
+				if created != nil || admin != nil {
+					t.Fatalf("expected no notification, got %+v / %+v", created, admin)
+				}
+			case tt.wantAdmin:
+				if admin == nil || admin.Kind != tt.wantKind || admin.Message != tt.wantText {
+					t.Fatalf("expected admin notification %q, got %+v", tt.wantText, admin)
+				}
+			default:
+				if created == nil || created.Kind != tt.wantKind || created.Message != tt.wantText {
+					t.Fatalf("expected notification %q, got %+v", tt.wantText, created)
+				}
+				if uuidFromPg(created.UserID) != ownerID {
+					t.Fatalf("expected the notification for %s, got %s", ownerID, uuidFromPg(created.UserID))
+				}
+				if (tt.wantKind == notificationIngestFailed) != created.LinkID.Valid {
+					t.Fatalf("unexpected link id %+v", created.LinkID)
+				}
+			}
+		})
+	}
+}
+
+func TestRecordNotificationSkipsDeletedLinks(t *testing.T) {
+	t.Parallel()
+
+	queries := &mockQueries{
+		getLinkFn: func(ctx context.Context, id pgtype.UUID) (db.GetLinkRow, error) {
+			return db.GetLinkRow{}, pgx.ErrNoRows
+		},
+	}
+	srv := &Server{cfg: config.Config{}, queries: queries, metrics: newTestMetrics()}
+
+	event := queue.Event{Type: queue.EventLinkStatus, LinkID: uuid.NewString(), Data: json.RawMessage(`{"status":"failed"}`)}
+	if err := srv.RecordNotification(context.Background(), event); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestHandleNotifications(t *testing.T) {
+	t.Parallel()
+
+	cfg := config.Config{DevUserID: uuid.MustParse("dddddddd-dddd-dddd-dddd-dddddddddddd")}
+	readID := uuid.New()
+	unreadID := uuid.New()
+	now := time.Now().UTC()
+
+	var listed db.ListNotificationsParams
+	queries := &mockQueries{
+		listNotificationsFn: func(ctx context.Context, arg db.ListNotificationsParams) ([]db.Notification, error) {
+			listed = arg
+			return []db.Notification{
+				{ID: uuidToPg(unreadID), Kind: notificationIngestFailed, LinkID: uuidToPg(uuid.New()), Message: "Could not save", CreatedAt: pgtype.Timestamptz{Time: now, Valid: true}},
+				{ID: uuidToPg(readID), Kind: notificationDigestSent, Message: "Your digest was sent", ReadAt: pgtype.Timestamptz{Time: now, Valid: true}, CreatedAt: pgtype.Timestamptz{Time: now.Add(-time.Hour), Valid: true}},
+			}, nil
+		},
+		countUnreadNotificationsFn: func(ctx context.Context, userID pgtype.UUID) (int64, error) {
+			return 1, nil
+		},
+		markNotificationReadFn: func(ctx context.Context, arg db.MarkNotificationReadParams) (int64, error) {
+			if uuidFromPg(arg.ID) == unreadID && uuidFromPg(arg.UserID) == cfg.DevUserID {
+				return 1, nil
+			}
+			return 0, nil
+		},
+		markAllNotificationsReadFn: func(ctx context.Context, userID pgtype.UUID) (int64, error) {
+			return 3, nil
+		},
+	}
+	srv := &Server{cfg: cfg, queries: queries, metrics: newTestMetrics()}
+	e := echo.New()
+	srv.RegisterRoutes(e)
+
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/notifications?unread=true&limit=5", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+	}
+	if !listed.UnreadOnly || listed.PageLimit != 5 || uuidFromPg(listed.UserID) != cfg.DevUserID {
+		t.Fatalf("unexpected list params %+v", listed)
+	}
+	var list struct {
+		Items  []notificationResponse `json:"items"`
+		Unread int64                  `json:"unread"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&list); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if list.Unread != 1 || len(list.Items) != 2 || list.Items[0].Read || !list.Items[1].Read || list.Items[0].LinkID == "" {
+		t.Fatalf("unexpected notifications %+v", list)
+	}
+
+	rec = httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/notifications?unread=maybe", nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected status %d, got %d", http.StatusBadRequest, rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/notifications/unread-count", nil))
+	if rec.Code != http.StatusOK || rec.Body.String() != "{\"unread\":1}\n" {
+		t.Fatalf("unexpected unread count %d: %s", rec.Code, rec.Body.String())
+	}
+
+	rec = httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/notifications/"+unreadID.String()+"/read", nil))
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("expected status %d, got %d", http.StatusNoContent, rec.Code)
+	}
+	rec = httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/notifications/"+uuid.NewString()+"/read", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("expected status %d, got %d", http.StatusNotFound, rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/notifications/read", nil))
+	if rec.Code != http.StatusOK || rec.Body.String() != "{\"updated\":3}\n" {
+		t.Fatalf("unexpected mark all response %d: %s", rec.Code, rec.Body.String())
+	}
+}
@@ -0,0 +1,137 @@
+package httpapi
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	stdhttp "net/http"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"golang.org/x/time/rate"
+
+	"github.com/example/keepstack/apps/api/internal/captcha"
+	"github.com/example/keepstack/apps/api/internal/db"
+)
+
+const (
+	maxPublicInboxURLLength   = 2048
+	maxPublicInboxTitleLength = 300
+	// publicInboxGlobalKey is the bucket every suggestion draws from.
+	publicInboxGlobalKey = "all"
+)
+
+// captchaVerifier checks a captcha widget's response token.
+type captchaVerifier interface {
+	Provider() string
+	Verify(ctx context.Context, response, remoteIP string) error
+}
+
+type publicInboxRequest struct {
+	URL          string `json:"url" form:"url"`
+	Title        string `json:"title" form:"title"`
+	CaptchaToken string `json:"captcha_token" form:"captcha_token"`
+}
+
+type publicInboxCaptcha struct {
+	Provider string `json:"provider"`
+	SiteKey  string `json:"site_key"`
+}
+
+type publicInboxConfigResponse struct {
+	Captcha *publicInboxCaptcha `json:"captcha"`
+}
+
+// WithPublicInboxCaptcha requires a solved captcha with every anonymous
+// suggestion.
+func (s *Server) WithPublicInboxCaptcha(verifier captchaVerifier) {
+	s.captcha = verifier
+}
+
+// WithSharedPublicInboxLimits keeps the public inbox's rate limit buckets in
+// Postgres so the limits hold across API replicas.
+func (s *Server) WithSharedPublicInboxLimits(logger *log.Logger) {
+	if s.cfg.PublicInboxUserID == uuid.Nil {
+		return
+	}
+	client, global := s.publicInboxRules()
+	queries := db.New(s.pool)
+	s.publicInboxClientLimiter = NewPostgresLimiter(queries, "public-inbox:client:", client, rateLimiterIdleTTL, logger)
+	s.publicInboxLimiter = NewPostgresLimiter(queries, "public-inbox:", global, rateLimiterIdleTTL, logger)
+}
+
+// publicInboxRules spreads each hourly allowance evenly across the hour and
+// lets a full hour's worth through at once.
+func (s *Server) publicInboxRules() (client, global RateLimitRule) {
+	perHour := func(n int) RateLimitRule {
+		return RateLimitRule{Limit: rate.Every(time.Hour / time.Duration(n)), Burst: n}
+	}
+	return perHour(s.cfg.PublicInboxClientPerHour), perHour(s.cfg.PublicInboxPerHour)
+}
+
+// registerPublicInboxRoutes adds the anonymous suggestion endpoints when a
+// public inbox user is configured.
+func (s *Server) registerPublicInboxRoutes(api *echo.Group) {
+	if s.cfg.PublicInboxUserID == uuid.Nil {
+		return
+	}
+	api.GET("/public/inbox", s.handlePublicInboxConfig)
+	api.POST("/public/inbox", s.handlePublicInboxSave)
+}
+
+// handlePublicInboxConfig tells kiosk pages which captcha widget, if any, to
+// render.
+func (s *Server) handlePublicInboxConfig(c echo.Context) error {
+	resp := publicInboxConfigResponse{}
+	if s.captcha != nil {
+		resp.Captcha = &publicInboxCaptcha{Provider: s.captcha.Provider(), SiteKey: s.cfg.PublicInboxCaptchaSiteKey}
+	}
+	return c.JSON(stdhttp.StatusOK, resp)
+}
+
+// handlePublicInboxSave saves an anonymous suggestion for the inbox user,
+// tagged with PublicInboxTag. The response is the same whether or not the
+// link was already saved, so the endpoint does not reveal the library.
+func (s *Server) handlePublicInboxSave(c echo.Context) error {
+	ctx := c.Request().Context()
+	if !s.publicInboxClientLimiter.Allow(ctx, c.RealIP()) || !s.publicInboxLimiter.Allow(ctx, publicInboxGlobalKey) {
+		s.metrics.HTTPRateLimited.WithLabelValues("public_inbox").Inc()
+		return respondError(c, stdhttp.StatusTooManyRequests, "too many suggestions, try again later")
+	}
+
+	var req publicInboxRequest
+	if err := c.Bind(&req); err != nil {
+		return respondBindError(c, err)
+	}
+	url := strings.TrimSpace(req.URL)
+	if url == "" || len(url) > maxPublicInboxURLLength {
+		return respondError(c, stdhttp.StatusBadRequest, "invalid url")
+	}
+	title := strings.TrimSpace(req.Title)
+	if utf8.RuneCountInString(title) > maxPublicInboxTitleLength {
+		return respondError(c, stdhttp.StatusBadRequest, "title exceeds maximum length")
+	}
+
+	if s.captcha != nil {
+		if err := s.captcha.Verify(ctx, req.CaptchaToken, c.RealIP()); err != nil {
+			if errors.Is(err, captcha.ErrRejected) {
+				return respondError(c, stdhttp.StatusForbidden, "captcha verification failed")
+			}
+			c.Logger().Errorf("public inbox: captcha verification failed: %v", err)
+			return respondError(c, stdhttp.StatusServiceUnavailable, "captcha verification is unavailable")
+		}
+	}
+
+	var tags []string
+	if s.cfg.PublicInboxTag != "" {
+		tags = []string{s.cfg.PublicInboxTag}
+	}
+	if _, err := s.quickSave(c, quickSaveInput{UserID: s.cfg.PublicInboxUserID, URL: url, Title: title, Tags: tags}); err != nil {
+		return respondWithError(c, err)
+	}
+	return c.JSON(stdhttp.StatusAccepted, map[string]string{"status": "received"})
+}
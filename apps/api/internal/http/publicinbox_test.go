@@ -0,0 +1,172 @@
+package httpapi
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/labstack/echo/v4"
+	"golang.org/x/time/rate"
+
+	"github.com/example/keepstack/apps/api/internal/captcha"
+	"github.com/example/keepstack/apps/api/internal/config"
+	"github.com/example/keepstack/apps/api/internal/db"
+)
+
+var testInboxUserID = uuid.MustParse("bbbbbbbb-bbbb-bbbb-bbbb-bbbbbbbbbbbb")
+
+type fakeCaptcha struct {
+	err   error
+	token string
+}
+
+func (f *fakeCaptcha) Provider() string { return captcha.ProviderTurnstile }
+
+func (f *fakeCaptcha) Verify(ctx context.Context, response, remoteIP string) error {
+	f.token = response
+	return f.err
+}
+
+func newPublicInboxTestServer(queries *mockQueries, verifier captchaVerifier, clientBurst int) *echo.Echo {
+	srv := &Server{
+		cfg: config.Config{
+			DevUserID:                 uuid.MustParse("aaaaaaaa-aaaa-aaaa-aaaa-aaaaaaaaaaaa"),
+			PublicInboxUserID:         testInboxUserID,
+			PublicInboxTag:            "suggested",
+			PublicInboxCaptchaSiteKey: "site-key",
+		},
+		queries:                  queries,
+		publisher:                &stubPublisher{},
+		metrics:                  newTestMetrics(),
+		publicInboxClientLimiter: NewLocalLimiter(RateLimitRule{Limit: rate.Every(time.Hour), Burst: clientBurst}, time.Minute),
+		publicInboxLimiter:       NewLocalLimiter(RateLimitRule{Limit: rate.Every(time.Hour), Burst: 100}, time.Minute),
+	}
+	if verifier != nil {
+		srv.captcha = verifier
+	}
+	e := echo.New()
+	srv.RegisterRoutes(e)
+	return e
+}
+
+func newPublicInboxRequest(body string) *http.Request {
+	req := httptest.NewRequest(http.MethodPost, "/api/public/inbox", strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	return req
+}
+
+func TestPublicInboxDisabledByDefault(t *testing.T) {
+	t.Parallel()
+
+	srv := &Server{cfg: config.Config{}, queries: &mockQueries{}, metrics: newTestMetrics()}
+	e := echo.New()
+	srv.RegisterRoutes(e)
+
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, newPublicInboxRequest(`{"url":"https://example.com"}`))
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("expected status %d, got %d", http.StatusNotFound, rec.Code)
+	}
+}
+
+func TestPublicInboxSavesForInboxUser(t *testing.T) {
+	t.Parallel()
+
+	var created db.CreateLinkParams
+	var tagged []string
+	queries := &mockQueries{
+		findLinkByURLHashFn: func(ctx context.Context, arg db.FindLinkByURLHashParams) (db.FindLinkByURLHashRow, error) {
+			if uuidFromPg(arg.UserID) != testInboxUserID {
+				t.Fatalf("expected the lookup to use the inbox user, got %v", uuidFromPg(arg.UserID))
+			}
+			return db.FindLinkByURLHashRow{}, pgx.ErrNoRows
+		},
+		createLinkFn: func(ctx context.Context, params db.CreateLinkParams) (db.CreateLinkRow, error) {
+			created = params
+			return db.CreateLinkRow{ID: params.ID}, nil
+		},
+		getTagByNameFn: func(ctx context.Context, arg db.GetTagByNameParams) (db.Tag, error) {
+			tagged = append(tagged, arg.Name)
+			return db.Tag{ID: 1, Name: arg.Name}, nil
+		},
+		addTagToLinkFn: func(ctx context.Context, params db.AddTagToLinkParams) error {
+			return nil
+		},
+	}
+	verifier := &fakeCaptcha{}
+	e := newPublicInboxTestServer(queries, verifier, 5)
+
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, newPublicInboxRequest(`{"url":"https://example.com/read-this","title":"Worth it","captcha_token":"solved"}`))
+	if rec.Code != http.StatusAccepted {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusAccepted, rec.Code, rec.Body.String())
+	}
+	if uuidFromPg(created.UserID) != testInboxUserID || created.Title.String != "Worth it" {
+		t.Fatalf("unexpected link: %+v", created)
+	}
+	if len(tagged) != 1 || tagged[0] != "suggested" {
+		t.Fatalf("expected the inbox tag, got %v", tagged)
+	}
+	if verifier.token != "solved" {
+		t.Fatalf("expected the captcha token to be verified, got %q", verifier.token)
+	}
+}
+
+func TestPublicInboxCaptchaFailures(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		err  error
+		want int
+	}{
+		{captcha.ErrRejected, http.StatusForbidden},
+		{errors.New("provider down"), http.StatusServiceUnavailable},
+	}
+	for _, tc := range cases {
+		e := newPublicInboxTestServer(&mockQueries{}, &fakeCaptcha{err: tc.err}, 5)
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, newPublicInboxRequest(`{"url":"https://example.com"}`))
+		if rec.Code != tc.want {
+			t.Fatalf("%v: expected status %d, got %d", tc.err, tc.want, rec.Code)
+		}
+	}
+}
+
+func TestPublicInboxRateLimitsClients(t *testing.T) {
+	t.Parallel()
+
+	e := newPublicInboxTestServer(&mockQueries{}, nil, 1)
+
+	// The first request spends the client's only token before failing
+	// validation; the second is turned away before it is read.
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, newPublicInboxRequest(`{"url":""}`))
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected status %d, got %d", http.StatusBadRequest, rec.Code)
+	}
+	rec = httptest.NewRecorder()
+	e.ServeHTTP(rec, newPublicInboxRequest(`{"url":"https://example.com"}`))
+	if rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected status %d, got %d", http.StatusTooManyRequests, rec.Code)
+	}
+}
+
+func TestPublicInboxConfigNamesCaptcha(t *testing.T) {
+	t.Parallel()
+
+	e := newPublicInboxTestServer(&mockQueries{}, &fakeCaptcha{}, 5)
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/public/inbox", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, rec.Code)
+	}
+	if body := strings.TrimSpace(rec.Body.String()); body != `{"captcha":{"provider":"turnstile","site_key":"site-key"}}` {
+		t.Fatalf("unexpected config: %s", body)
+	}
+}
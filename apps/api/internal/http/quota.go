@@ -0,0 +1,100 @@
+package httpapi
+
+import (
+	"errors"
+	"strconv"
+	"time"
+
+	stdhttp "net/http"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/labstack/echo/v4"
+
+	"github.com/example/keepstack/apps/api/internal/db"
+)
+
+// Kinds of write a daily quota applies to, as stored in usage_quotas.kind.
+const (
+	quotaLinks      = "links"
+	quotaHighlights = "highlights"
+)
+
+// Response headers describing the quota a write counted against.
+const (
+	headerQuotaLimit     = "X-Quota-Limit"
+	headerQuotaRemaining = "X-Quota-Remaining"
+	headerQuotaReset     = "X-Quota-Reset"
+)
+
+func (s *Server) quotaLimit(kind string) int {
+	switch kind {
+	case quotaLinks:
+		return s.cfg.QuotaLinksPerDay
+	case quotaHighlights:
+		return s.cfg.QuotaHighlightsPerDay
+	default:
+		return 0
+	}
+}
+
+// takeQuota counts one write of kind against userID's quota for today and
+// reports the quota in the response headers. Once the day's quota is used
+// up it returns a 429 apiError instead. Quotas guard against runaway
+// clients rather than enforce billing, so if the counter cannot be updated
+// the write is let through.
+func (s *Server) takeQuota(c echo.Context, kind string, userID uuid.UUID) error {
+	limit := s.quotaLimit(kind)
+	if limit <= 0 {
+		return nil
+	}
+
+	now := time.Now().UTC()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	s.sweepQuotas(c, today)
+
+	ctx := c.Request().Context()
+	used, err := s.queries.TakeDailyQuota(ctx, db.TakeDailyQuotaParams{
+		UserID:     uuidToPg(userID),
+		Kind:       kind,
+		Day:        pgtype.Date{Time: today, Valid: true},
+		DailyLimit: int32(limit),
+	})
+	exhausted := errors.Is(err, pgx.ErrNoRows)
+	if err != nil && !exhausted {
+		c.Logger().Warnf("%s quota unavailable, allowing write: %v", kind, err)
+		return nil
+	}
+	if exhausted {
+		used = int32(limit)
+	}
+
+	reset := max(1, int(today.AddDate(0, 0, 1).Sub(now).Seconds()))
+	header := c.Response().Header()
+	header.Set(headerQuotaLimit, strconv.Itoa(limit))
+	header.Set(headerQuotaRemaining, strconv.Itoa(max(0, limit-int(used))))
+	header.Set(headerQuotaReset, strconv.Itoa(reset))
+	if exhausted {
+		s.metrics.QuotaExceeded.WithLabelValues(kind).Inc()
+		header.Set("Retry-After", strconv.Itoa(reset))
+		return apiError{Code: stdhttp.StatusTooManyRequests, Message: "daily " + kind + " quota exceeded", Reason: errCodeQuotaExceeded}
+	}
+	return nil
+}
+
+// sweepQuotas deletes counters for past days, at most once a day per pod.
+func (s *Server) sweepQuotas(c echo.Context, today time.Time) {
+	s.quotaSweepMu.Lock()
+	if !s.quotaSweptOn.Before(today) {
+		s.quotaSweepMu.Unlock()
+		return
+	}
+	s.quotaSweptOn = today
+	s.quotaSweepMu.Unlock()
+
+	cutoff := pgtype.Date{Time: today, Valid: true}
+	if _, err := s.queries.DeleteUsageQuotasBefore(c.Request().Context(), cutoff); err != nil {
+		c.Logger().Warnf("sweep usage quotas: %v", err)
+	}
+}
@@ -0,0 +1,98 @@
+package httpapi
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+
+	"github.com/example/keepstack/apps/api/internal/config"
+	"github.com/example/keepstack/apps/api/internal/memstore"
+	"github.com/example/keepstack/apps/api/internal/queue"
+)
+
+func newQuotaServer(cfg config.Config) *echo.Echo {
+	cfg.DevUserID = uuid.MustParse("abcdabcd-abcd-abcd-abcd-abcdabcdabcd")
+	srv := NewMemoryServer(cfg, memstore.New(cfg.DevUserID), queue.NewMemory(), newTestMetrics())
+	srv.highlightLimiter = nil
+	e := echo.New()
+	srv.RegisterRoutes(e)
+	return e
+}
+
+func postJSON(e *echo.Echo, path, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	req.Header.Set(echo.HeaderAuthorization, "Bearer "+testExtensionToken)
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestLinkQuota(t *testing.T) {
+	t.Parallel()
+
+	e := newQuotaServer(config.Config{QuotaLinksPerDay: 2, ExtensionTokens: []string{testExtensionToken}})
+
+	for i, remaining := range []string{"1", "0"} {
+		rec := postJSON(e, "/api/links", `{"url":"https://example.com/`+strconv.Itoa(i)+`"}`)
+		if rec.Code != http.StatusCreated {
+			t.Fatalf("save %d: expected status %d, got %d: %s", i, http.StatusCreated, rec.Code, rec.Body.String())
+		}
+		if got := rec.Header().Get(headerQuotaLimit); got != "2" {
+			t.Fatalf("save %d: expected limit 2, got %q", i, got)
+		}
+		if got := rec.Header().Get(headerQuotaRemaining); got != remaining {
+			t.Fatalf("save %d: expected %s remaining, got %q", i, remaining, got)
+		}
+		if reset, err := strconv.Atoi(rec.Header().Get(headerQuotaReset)); err != nil || reset < 1 || reset > 86400 {
+			t.Fatalf("save %d: unexpected reset %q", i, rec.Header().Get(headerQuotaReset))
+		}
+	}
+
+	rec := postJSON(e, "/api/links", `{"url":"https://example.com/over"}`)
+	if rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusTooManyRequests, rec.Code, rec.Body.String())
+	}
+	if rec.Header().Get(headerQuotaRemaining) != "0" || rec.Header().Get("Retry-After") == "" {
+		t.Fatalf("expected an exhausted quota with Retry-After, got %v", rec.Header())
+	}
+
+	// Re-saving a known link through the extension creates nothing, so it
+	// is not counted.
+	rec = postJSON(e, "/api/ext/save", `{"url":"https://example.com/0"}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected an existing save to succeed, got %d: %s", rec.Code, rec.Body.String())
+	}
+	rec = postJSON(e, "/api/ext/save", `{"url":"https://example.com/new"}`)
+	if rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected a new extension save to be refused, got %d", rec.Code)
+	}
+}
+
+func TestHighlightQuota(t *testing.T) {
+	t.Parallel()
+
+	e := newQuotaServer(config.Config{QuotaHighlightsPerDay: 1})
+
+	rec := postJSON(e, "/api/links", `{"url":"https://example.com/a"}`)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("create link: status %d", rec.Code)
+	}
+	if rec.Header().Get(headerQuotaLimit) != "" {
+		t.Fatalf("expected no quota headers for unlimited links, got %v", rec.Header())
+	}
+	id := strings.Split(strings.Split(rec.Body.String(), `"id":"`)[1], `"`)[0]
+
+	if rec := postJSON(e, "/api/links/"+id+"/highlights", `{"text":"first"}`); rec.Code != http.StatusCreated {
+		t.Fatalf("first highlight: status %d: %s", rec.Code, rec.Body.String())
+	}
+	rec = postJSON(e, "/api/links/"+id+"/highlights", `{"text":"second"}`)
+	if rec.Code != http.StatusTooManyRequests || !strings.Contains(rec.Body.String(), "highlights quota") {
+		t.Fatalf("second highlight: expected the quota to be exhausted, got %d: %s", rec.Code, rec.Body.String())
+	}
+}
@@ -0,0 +1,164 @@
+package httpapi
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"golang.org/x/time/rate"
+
+	"github.com/example/keepstack/apps/api/internal/observability"
+)
+
+// Route classes used to pick a rate limit rule and label rejections.
+const (
+	rateClassRead  = "read"
+	rateClassWrite = "write"
+	rateClassAdmin = "admin"
+)
+
+// rateLimiterIdleTTL is how long an unused bucket is kept before it is swept.
+const rateLimiterIdleTTL = 10 * time.Minute
+
+// RateLimitRule describes a token bucket. A zero Limit disables the class.
+type RateLimitRule struct {
+	Limit rate.Limit
+	Burst int
+}
+
+// RateLimitConfig holds one rule per route class.
+type RateLimitConfig struct {
+	Read  RateLimitRule
+	Write RateLimitRule
+	Admin RateLimitRule
+}
+
+func (c RateLimitConfig) rule(class string) RateLimitRule {
+	switch class {
+	case rateClassAdmin:
+		return c.Admin
+	case rateClassWrite:
+		return c.Write
+	default:
+		return c.Read
+	}
+}
+
+// RateLimitMiddleware applies a token bucket per client and route class to
+// /api routes. Until requests carry an authenticated user, clients are keyed
+// by IP address. Rejected requests get a 429 with a Retry-After header.
+// Health probes and /metrics are never limited.
+func RateLimitMiddleware(cfg RateLimitConfig, metrics *observability.Metrics) echo.MiddlewareFunc {
+	store := newRateLimiterStore(rateLimiterIdleTTL)
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			req := c.Request()
+			class, ok := rateLimitClass(req.Method, req.URL.Path)
+			if !ok {
+				return next(c)
+			}
+
+			rule := cfg.rule(class)
+			if rule.Limit == 0 {
+				return next(c)
+			}
+
+			now := time.Now()
+			limiter := store.get(class+":"+c.RealIP(), rule, now)
+			reservation := limiter.ReserveN(now, 1)
+			delay := reservation.DelayFrom(now)
+			if delay == 0 {
+				return next(c)
+			}
+			reservation.CancelAt(now)
+
+			metrics.HTTPRateLimited.WithLabelValues(class).Inc()
+			retryAfter := int(math.Ceil(delay.Seconds()))
+			if retryAfter < 1 {
+				retryAfter = 1
+			}
+			c.Response().Header().Set("Retry-After", strconv.Itoa(retryAfter))
+			return respondError(c, http.StatusTooManyRequests, "rate limit exceeded")
+		}
+	}
+}
+
+// rateLimitClass maps a request onto a route class. The second return value
+// is false for routes that are exempt from rate limiting.
+func rateLimitClass(method, path string) (string, bool) {
+	if strings.HasPrefix(path, "/read/") {
+		return rateClassRead, true
+	}
+	if !strings.HasPrefix(path, "/api/") {
+		return "", false
+	}
+	switch path {
+	case "/api/healthz", "/api/livez":
+		return "", false
+	}
+	if strings.HasPrefix(path, "/api/admin/") || path == "/api/admin" {
+		return rateClassAdmin, true
+	}
+	if path == "/api/graphql" {
+		// The schema only has queries, so POSTs here are reads.
+		return rateClassRead, true
+	}
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return rateClassRead, true
+	default:
+		return rateClassWrite, true
+	}
+}
+
+type rateLimiterEntry struct {
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
+
+// rateLimiterStore keeps one limiter per key and drops buckets that have been
+// idle for longer than ttl so the map does not grow with every client seen.
+type rateLimiterStore struct {
+	mu        sync.Mutex
+	ttl       time.Duration
+	entries   map[string]*rateLimiterEntry
+	lastSweep time.Time
+}
+
+func newRateLimiterStore(ttl time.Duration) *rateLimiterStore {
+	return &rateLimiterStore{
+		ttl:     ttl,
+		entries: make(map[string]*rateLimiterEntry),
+	}
+}
+
+func (s *rateLimiterStore) get(key string, rule RateLimitRule, now time.Time) *rate.Limiter {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if now.Sub(s.lastSweep) >= s.ttl {
+		for k, entry := range s.entries {
+			if now.Sub(entry.lastSeen) >= s.ttl {
+				delete(s.entries, k)
+			}
+		}
+		s.lastSweep = now
+	}
+
+	entry, ok := s.entries[key]
+	if !ok {
+		burst := rule.Burst
+		if burst < 1 {
+			burst = 1
+		}
+		entry = &rateLimiterEntry{limiter: rate.NewLimiter(rule.Limit, burst)}
+		s.entries[key] = entry
+	}
+	entry.lastSeen = now
+	return entry.limiter
+}
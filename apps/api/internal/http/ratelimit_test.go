@@ -0,0 +1,112 @@
+package httpapi
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"golang.org/x/time/rate"
+)
+
+func TestRateLimitMiddleware(t *testing.T) {
+	t.Parallel()
+
+	metrics := newTestMetrics()
+	cfg := RateLimitConfig{
+		Read:  RateLimitRule{Limit: rate.Every(time.Hour), Burst: 2},
+		Write: RateLimitRule{Limit: rate.Every(time.Minute), Burst: 1},
+	}
+
+	e := echo.New()
+	e.Use(RateLimitMiddleware(cfg, metrics))
+	ok := func(c echo.Context) error { return c.NoContent(http.StatusNoContent) }
+	e.GET("/api/links", ok)
+	e.POST("/api/links", ok)
+	e.GET("/api/healthz", ok)
+	e.GET("/api/admin/jobs", ok)
+
+	do := func(method, path, ip string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, nil)
+		req.RemoteAddr = ip + ":1234"
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		return rec
+	}
+
+	if rec := do(http.MethodPost, "/api/links", "10.0.0.1"); rec.Code != http.StatusNoContent {
+		t.Fatalf("expected first write to pass, got %d", rec.Code)
+	}
+	rec := do(http.MethodPost, "/api/links", "10.0.0.1")
+	if rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected second write to be limited, got %d", rec.Code)
+	}
+	if got := rec.Header().Get("Retry-After"); got != "60" {
+		t.Fatalf("expected Retry-After 60, got %q", got)
+	}
+
+	if rec := do(http.MethodGet, "/api/links", "10.0.0.1"); rec.Code != http.StatusNoContent {
+		t.Fatalf("expected reads to use a separate bucket, got %d", rec.Code)
+	}
+	if rec := do(http.MethodPost, "/api/links", "10.0.0.2"); rec.Code != http.StatusNoContent {
+		t.Fatalf("expected another client to have its own bucket, got %d", rec.Code)
+	}
+
+	for i := 0; i < 5; i++ {
+		if rec := do(http.MethodGet, "/api/healthz", "10.0.0.1"); rec.Code != http.StatusNoContent {
+			t.Fatalf("expected health probes to be exempt, got %d", rec.Code)
+		}
+		if rec := do(http.MethodGet, "/api/admin/jobs", "10.0.0.1"); rec.Code != http.StatusNoContent {
+			t.Fatalf("expected disabled admin class to pass, got %d", rec.Code)
+		}
+	}
+
+	if got := testutil.ToFloat64(metrics.HTTPRateLimited.WithLabelValues(rateClassWrite)); got != 1 {
+		t.Fatalf("expected 1 rejected write, got %v", got)
+	}
+}
+
+func TestRateLimitClass(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		method string
+		path   string
+		class  string
+		ok     bool
+	}{
+		{http.MethodGet, "/api/links", rateClassRead, true},
+		{http.MethodPatch, "/api/links/1", rateClassWrite, true},
+		{http.MethodGet, "/api/admin/backups", rateClassAdmin, true},
+		{http.MethodGet, "/read/1", rateClassRead, true},
+		{http.MethodGet, "/api/livez", "", false},
+		{http.MethodGet, "/metrics", "", false},
+		{http.MethodGet, "/healthz", "", false},
+	}
+	for _, tc := range cases {
+		class, ok := rateLimitClass(tc.method, tc.path)
+		if class != tc.class || ok != tc.ok {
+			t.Fatalf("%s %s: expected (%q, %v), got (%q, %v)", tc.method, tc.path, tc.class, tc.ok, class, ok)
+		}
+	}
+}
+
+func TestRateLimiterStoreSweepsIdleBuckets(t *testing.T) {
+	t.Parallel()
+
+	store := newRateLimiterStore(time.Minute)
+	rule := RateLimitRule{Limit: 1, Burst: 1}
+	now := time.Now()
+	store.get("read:10.0.0.1", rule, now)
+	store.get("read:10.0.0.2", rule, now.Add(30*time.Second))
+	store.get("read:10.0.0.3", rule, now.Add(70*time.Second))
+
+	if _, ok := store.entries["read:10.0.0.1"]; ok {
+		t.Fatal("expected idle bucket to be swept")
+	}
+	if len(store.entries) != 2 {
+		t.Fatalf("expected 2 buckets, got %d", len(store.entries))
+	}
+}
@@ -0,0 +1,397 @@
+package httpapi
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"html/template"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	stdhttp "net/http"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/labstack/echo/v4"
+	"github.com/microcosm-cc/bluemonday"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+
+	"github.com/example/keepstack/apps/api/internal/db"
+	"github.com/example/keepstack/apps/api/internal/goals"
+	"github.com/example/keepstack/sanitize"
+)
+
+// readerWordsPerMinute is the reading speed behind reading time estimates,
+// shared with reading goals so both agree on how long a link takes.
+const readerWordsPerMinute = goals.WordsPerMinute
+
+// readerTokenTTL is how long a reader token opens its page. The page needs
+// nothing else authenticated once loaded, so a link is opened, not kept.
+const readerTokenTTL = 15 * time.Minute
+
+// readerSanitizer sanitizes archive HTML again before it is served from the
+// API's origin, with the policy the worker stored it with. This keeps a row
+// written any other way from running script next to the API. csp allows what
+// the policy keeps: proxied images and embed hosts.
+type readerSanitizer struct {
+	policy *bluemonday.Policy
+	csp    string
+}
+
+// defaultReaderSanitizer is used when the server has none configured.
+var defaultReaderSanitizer = newReaderSanitizer(sanitize.Options{Features: sanitize.DefaultFeatures})
+
+// paragraphBreak separates paragraphs in extracted text.
+var paragraphBreak = regexp.MustCompile(`\n\s*\n`)
+
+var readerTemplate = template.Must(template.New("reader").Parse(readerPage))
+
+func newReaderSanitizer(opts sanitize.Options) *readerSanitizer {
+	return &readerSanitizer{policy: opts.Policy(), csp: readerCSP(opts)}
+}
+
+// readerCSP lets the page load its own stylesheet, the article's images,
+// through the image proxy when there is one, images mirrored to
+// /api/media, and frames from embed hosts, and nothing else.
+func readerCSP(opts sanitize.Options) string {
+	images := "'self' https: data:"
+	if origin := opts.ProxyOrigin(); origin != "" {
+		images = "'self' " + origin + " data:"
+	}
+	csp := "default-src 'none'; img-src " + images + "; style-src '" + styleHash(readerStyle) + "'; "
+	if hosts := opts.Hosts(); len(hosts) > 0 {
+		csp += "frame-src https://" + strings.Join(hosts, " https://") + "; "
+	}
+	return csp + "base-uri 'none'; form-action 'none'; frame-ancestors 'none'"
+}
+
+type readerPageData struct {
+	Title      string
+	URL        string
+	Byline     string
+	Lang       string
+	Minutes    int
+	Partial    bool
+	Body       template.HTML
+	Highlights []readerHighlight
+}
+
+type readerTokenResponse struct {
+	URL       string    `json:"url"`
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+type readerHighlight struct {
+	ID         string
+	Quote      string
+	Annotation string
+	// Anchored is set when the quote was found and marked in the text.
+	Anchored bool
+}
+
+// registerReaderRoutes adds /read/:id. With sessions enabled the page needs
+// a token: browsers open it without an Authorization header, so
+// POST /api/links/:id/reader hands out a short-lived token for the page's
+// token query parameter.
+func (s *Server) registerReaderRoutes(e *echo.Echo, user *echo.Group) {
+	if !s.cfg.AuthSessions {
+		e.GET("/read/:id", s.handleReader)
+		return
+	}
+	e.GET("/read/:id", s.handleReader, ReaderAuthMiddleware([]byte(s.cfg.JWTSecret)))
+	user.POST("/links/:id/reader", s.handleCreateReaderToken)
+}
+
+// ReaderAuthMiddleware answers 401 unless the request carries a reader token
+// for its link in the token query parameter, or a session token, signed with
+// secret, and otherwise runs the handler as the token's user.
+func ReaderAuthMiddleware(secret []byte) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			linkID, err := parseUUIDParam(c.Param("id"))
+			if err != nil {
+				return c.String(stdhttp.StatusBadRequest, "invalid link id")
+			}
+			userID, err := parseReaderToken(secret, c.QueryParam("token"), linkID)
+			if err != nil {
+				userID, err = parseSessionToken(secret, bearerToken(c.Request()))
+			}
+			if err != nil {
+				c.Response().Header().Set(echo.HeaderWWWAuthenticate, `Bearer realm="keepstack"`)
+				return c.String(stdhttp.StatusUnauthorized, "invalid or missing reader token")
+			}
+			req := c.Request()
+			c.SetRequest(req.WithContext(withSessionUser(req.Context(), userID)))
+			return next(c)
+		}
+	}
+}
+
+// handleCreateReaderToken returns the /read URL of a link the user can read,
+// with a token that opens that page alone for readerTokenTTL.
+func (s *Server) handleCreateReaderToken(c echo.Context) error {
+	linkID, err := parseUUIDParam(c.Param("id"))
+	if err != nil {
+		return respondError(c, stdhttp.StatusBadRequest, "invalid link id")
+	}
+	ctx := c.Request().Context()
+	if _, err := s.ensureLinkAccess(ctx, linkID, linkPermissionRead); err != nil {
+		return respondWithError(c, err)
+	}
+
+	token, expiresAt, err := issueReaderToken([]byte(s.cfg.JWTSecret), s.userID(ctx), linkID, time.Now(), readerTokenTTL)
+	if err != nil {
+		c.Logger().Errorf("reader: issue token failed: %v", err)
+		return respondError(c, stdhttp.StatusInternalServerError, "failed to issue reader token")
+	}
+	return c.JSON(stdhttp.StatusOK, readerTokenResponse{
+		URL:       "/read/" + linkID.String() + "?token=" + url.QueryEscape(token),
+		Token:     token,
+		ExpiresAt: expiresAt,
+	})
+}
+
+// handleReader renders a link's archive as a standalone page, so saved
+// articles stay readable without the web app. Highlights are marked where
+// their quote appears in the text and listed after it, each linking to its
+// mark. Archives whose HTML has been vacuumed fall back to the extracted
+// text.
+func (s *Server) handleReader(c echo.Context) error {
+	linkID, err := parseUUIDParam(c.Param("id"))
+	if err != nil {
+		return c.String(stdhttp.StatusBadRequest, "invalid link id")
+	}
+	ctx := c.Request().Context()
+	link, err := s.ensureLinkAccess(ctx, linkID, linkPermissionRead)
+	if err != nil {
+		var apiErr apiError
+		if errors.As(err, &apiErr) {
+			return c.String(apiErr.Code, apiErr.Message)
+		}
+		return err
+	}
+
+	archive, err := s.queries.GetReaderArchive(ctx, link.ID)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		c.Logger().Errorf("reader: load archive failed: %v", err)
+		return c.String(stdhttp.StatusInternalServerError, "failed to load archive")
+	}
+	sanitizer := s.reader
+	if sanitizer == nil {
+		sanitizer = defaultReaderSanitizer
+	}
+	body := sanitizer.policy.Sanitize(archive.Html)
+	if strings.TrimSpace(body) == "" {
+		body = textParagraphs(archive.ExtractedText)
+	}
+	if body == "" {
+		return c.String(stdhttp.StatusNotFound, "archive not available yet")
+	}
+
+	highlights, err := s.queries.ListHighlightsByLink(ctx, link.ID)
+	if err != nil {
+		c.Logger().Errorf("reader: list highlights failed: %v", err)
+		return c.String(stdhttp.StatusInternalServerError, "failed to load highlights")
+	}
+	body, anchored := anchorHighlights(body, highlights)
+
+	data := readerPageData{
+		Title:   firstNonBlank(archive.Title, link.Title.String, link.Url),
+		URL:     link.Url,
+		Byline:  archive.Byline,
+		Lang:    firstNonBlank(archive.Lang, "en"),
+		Body:    template.HTML(body),
+		Partial: archive.Paywalled,
+	}
+	data.Minutes = readingMinutes(int(archive.WordCount))
+	for _, h := range highlights {
+		id := uuidFromPg(h.ID).String()
+		data.Highlights = append(data.Highlights, readerHighlight{
+			ID:         id,
+			Quote:      h.Quote,
+			Annotation: h.Annotation.String,
+			Anchored:   anchored[id],
+		})
+	}
+
+	var out strings.Builder
+	if err := readerTemplate.Execute(&out, data); err != nil {
+		c.Logger().Errorf("reader: render failed: %v", err)
+		return c.String(stdhttp.StatusInternalServerError, "failed to render archive")
+	}
+	c.Response().Header().Set("Content-Security-Policy", sanitizer.csp)
+	c.Response().Header().Set(echo.HeaderCacheControl, "private, no-cache")
+	return c.HTML(stdhttp.StatusOK, out.String())
+}
+
+// readingMinutes estimates reading time, rounding up.
+func readingMinutes(words int) int {
+	if words <= 0 {
+		return 0
+	}
+	return (words + readerWordsPerMinute - 1) / readerWordsPerMinute
+}
+
+// textParagraphs turns extracted text into escaped paragraphs, splitting on
+// blank lines.
+func textParagraphs(text string) string {
+	var b strings.Builder
+	for _, para := range paragraphBreak.Split(text, -1) {
+		if para = strings.TrimSpace(para); para != "" {
+			b.WriteString("<p>")
+			b.WriteString(html.EscapeString(para))
+			b.WriteString("</p>\n")
+		}
+	}
+	return b.String()
+}
+
+// anchorHighlights wraps the first occurrence of each highlight's quote in a
+// <mark> with the highlight's id. Quotes match across differences in
+// whitespace but not across element boundaries; quotes that cannot be found
+// are left out of the returned set.
+func anchorHighlights(fragment string, highlights []db.Highlight) (string, map[string]bool) {
+	anchored := make(map[string]bool, len(highlights))
+	if len(highlights) == 0 {
+		return fragment, anchored
+	}
+	container := &html.Node{Type: html.ElementNode, Data: "div", DataAtom: atom.Div}
+	nodes, err := html.ParseFragment(strings.NewReader(fragment), container)
+	if err != nil {
+		return fragment, anchored
+	}
+	for _, node := range nodes {
+		container.AppendChild(node)
+	}
+
+	for _, h := range highlights {
+		words := strings.Fields(h.Quote)
+		if len(words) == 0 {
+			continue
+		}
+		for i, word := range words {
+			words[i] = regexp.QuoteMeta(word)
+		}
+		pattern := regexp.MustCompile(strings.Join(words, `\s+`))
+		id := uuidFromPg(h.ID).String()
+		anchored[id] = markFirst(container, pattern, "highlight-"+id)
+	}
+
+	var b strings.Builder
+	for node := container.FirstChild; node != nil; node = node.NextSibling {
+		if err := html.Render(&b, node); err != nil {
+			return fragment, map[string]bool{}
+		}
+	}
+	return b.String(), anchored
+}
+
+// markFirst splits the first text node matching pattern around the match
+// and wraps the match in a <mark>. Text already inside a mark is skipped so
+// overlapping highlights do not nest.
+func markFirst(node *html.Node, pattern *regexp.Regexp, id string) bool {
+	for child := node.FirstChild; child != nil; child = child.NextSibling {
+		switch {
+		case child.Type == html.TextNode:
+			loc := pattern.FindStringIndex(child.Data)
+			if loc == nil {
+				continue
+			}
+			mark := &html.Node{Type: html.ElementNode, Data: "mark", DataAtom: atom.Mark, Attr: []html.Attribute{{Key: "id", Val: id}}}
+			mark.AppendChild(&html.Node{Type: html.TextNode, Data: child.Data[loc[0]:loc[1]]})
+			if loc[0] > 0 {
+				node.InsertBefore(&html.Node{Type: html.TextNode, Data: child.Data[:loc[0]]}, child)
+			}
+			node.InsertBefore(mark, child)
+			if loc[1] < len(child.Data) {
+				node.InsertBefore(&html.Node{Type: html.TextNode, Data: child.Data[loc[1]:]}, child)
+			}
+			node.RemoveChild(child)
+			return true
+		case child.Type == html.ElementNode && child.DataAtom != atom.Mark:
+			if markFirst(child, pattern, id) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// firstNonBlank returns the first value that is not blank, trimmed.
+func firstNonBlank(values ...string) string {
+	for _, value := range values {
+		if value = strings.TrimSpace(value); value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+func styleHash(style string) string {
+	sum := sha256.Sum256([]byte(style))
+	return "sha256-" + base64.StdEncoding.EncodeToString(sum[:])
+}
+
+const readerStyle = `
+:root { color-scheme: light dark; --text: #1f2933; --muted: #616e7c; --bg: #fdfcf9; --rule: #e4e7eb; --link: #2563eb; --mark: #fde68a; }
+@media (prefers-color-scheme: dark) {
+  :root { --text: #e4e7eb; --muted: #9aa5b1; --bg: #16181d; --rule: #323f4b; --link: #93c5fd; --mark: #854d0e; }
+}
+body { margin: 0; background: var(--bg); color: var(--text); font: 1.125rem/1.7 Charter, "Bitstream Charter", "Sitka Text", Cambria, Georgia, serif; }
+main { max-width: 38rem; margin: 0 auto; padding: 3rem 1.25rem 5rem; }
+header { margin-bottom: 2.5rem; font-family: -apple-system, BlinkMacSystemFont, "Segoe UI", sans-serif; }
+h1 { font-size: 2rem; line-height: 1.25; margin: 0 0 0.75rem; }
+.meta { color: var(--muted); font-size: 0.9rem; }
+a { color: var(--link); }
+img, video { max-width: 100%; height: auto; }
+pre { overflow-x: auto; font-size: 0.9rem; }
+blockquote { margin-left: 0; padding-left: 1rem; border-left: 3px solid var(--rule); color: var(--muted); }
+mark { background: var(--mark); color: inherit; }
+aside { margin-top: 3rem; padding-top: 1.5rem; border-top: 1px solid var(--rule); font-size: 1rem; }
+aside li { margin-bottom: 1rem; }
+aside .note { color: var(--muted); }
+`
+
+const readerPage = `<!DOCTYPE html>
+<html lang="{{.Lang}}">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<meta name="robots" content="noindex">
+<title>{{.Title}}</title>
+<style>` + readerStyle + `</style>
+</head>
+<body>
+<main>
+<header>
+<h1>{{.Title}}</h1>
+<div class="meta">
+{{- if .Byline}}{{.Byline}} · {{end -}}
+<a href="{{.URL}}" rel="noreferrer">Original</a>
+{{- if .Minutes}} · {{.Minutes}} min read{{end}}
+</div>
+{{- if .Partial}}
+<p class="meta">This archive is partial. The page is behind a paywall, so only its opening was saved.</p>
+{{- end}}
+</header>
+<article>
+{{.Body}}
+</article>
+{{- if .Highlights}}
+<aside>
+<h2>Highlights</h2>
+<ul>
+{{- range .Highlights}}
+<li>{{if .Anchored}}<a href="#highlight-{{.ID}}">{{.Quote}}</a>{{else}}{{.Quote}}{{end}}
+{{- if .Annotation}}<div class="note">{{.Annotation}}</div>{{end}}</li>
+{{- end}}
+</ul>
+</aside>
+{{- end}}
+</main>
+</body>
+</html>
+`
@@ -0,0 +1,172 @@
+package httpapi
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/labstack/echo/v4"
+
+	"github.com/example/keepstack/apps/api/internal/config"
+	"github.com/example/keepstack/apps/api/internal/db"
+	"github.com/example/keepstack/sanitize"
+)
+
+func newReaderTestServer(linkID uuid.UUID, archive db.GetReaderArchiveRow, archiveErr error, highlights []db.Highlight) *echo.Echo {
+	return newReaderTestServerWithPolicy(nil, linkID, archive, archiveErr, highlights)
+}
+
+func newReaderTestServerWithPolicy(reader *readerSanitizer, linkID uuid.UUID, archive db.GetReaderArchiveRow, archiveErr error, highlights []db.Highlight) *echo.Echo {
+	cfg := config.Config{DevUserID: uuid.MustParse("aaaaaaaa-aaaa-aaaa-aaaa-aaaaaaaaaaaa")}
+	queries := &mockQueries{
+		getLinkFn: func(ctx context.Context, id pgtype.UUID) (db.GetLinkRow, error) {
+			if uuidFromPg(id) != linkID {
+				return db.GetLinkRow{}, pgx.ErrNoRows
+			}
+			return db.GetLinkRow{ID: id, UserID: uuidToPg(cfg.DevUserID), Url: "https://example.com/post"}, nil
+		},
+		getReaderArchiveFn: func(ctx context.Context, id pgtype.UUID) (db.GetReaderArchiveRow, error) {
+			return archive, archiveErr
+		},
+		listHighlightsByLinkFn: func(ctx context.Context, id pgtype.UUID) ([]db.Highlight, error) {
+			return highlights, nil
+		},
+	}
+	srv := &Server{cfg: cfg, queries: queries, publisher: &stubPublisher{}, metrics: newTestMetrics(), reader: reader}
+	e := echo.New()
+	srv.RegisterRoutes(e)
+	return e
+}
+
+func TestReaderRendersArchiveWithHighlightAnchors(t *testing.T) {
+	t.Parallel()
+
+	linkID := uuid.New()
+	anchoredID := uuid.New()
+	missingID := uuid.New()
+	archive := db.GetReaderArchiveRow{
+		Title:     "A post",
+		Byline:    "Ada",
+		WordCount: 460,
+		Html:      "<p>The quick brown\n fox jumps.</p><script>alert(1)</script>",
+	}
+	highlights := []db.Highlight{
+		{ID: uuidToPg(anchoredID), Quote: "quick brown fox", Annotation: pgtype.Text{String: "nice", Valid: true}},
+		{ID: uuidToPg(missingID), Quote: "not in the text"},
+	}
+	e := newReaderTestServer(linkID, archive, nil, highlights)
+
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/read/"+linkID.String(), nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+	}
+	body := rec.Body.String()
+	for _, want := range []string{
+		"<title>A post</title>",
+		"Ada · ",
+		"2 min read",
+		`<mark id="highlight-` + anchoredID.String() + `">quick brown` + "\n" + ` fox</mark>`,
+		`<a href="#highlight-` + anchoredID.String() + `">quick brown fox</a>`,
+		`<div class="note">nice</div>`,
+		"<li>not in the text</li>",
+	} {
+		if !strings.Contains(body, want) {
+			t.Fatalf("expected page to contain %q, got:\n%s", want, body)
+		}
+	}
+	if strings.Contains(body, "<script>") {
+		t.Fatalf("expected scripts to be stripped, got:\n%s", body)
+	}
+	if strings.Contains(body, "This archive is partial.") {
+		t.Fatalf("expected a full archive not to be flagged, got:\n%s", body)
+	}
+	if csp := rec.Header().Get("Content-Security-Policy"); !strings.Contains(csp, "style-src 'sha256-") || !strings.Contains(csp, "default-src 'none'") {
+		t.Fatalf("unexpected content security policy: %q", csp)
+	}
+}
+
+func TestReaderFallsBackToExtractedText(t *testing.T) {
+	t.Parallel()
+
+	linkID := uuid.New()
+	archive := db.GetReaderArchiveRow{ExtractedText: "First <para>.\n\n\nSecond para.", Paywalled: true}
+	e := newReaderTestServer(linkID, archive, nil, nil)
+
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/read/"+linkID.String(), nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, rec.Code)
+	}
+	body := rec.Body.String()
+	if !strings.Contains(body, "<p>First &lt;para&gt;.</p>\n<p>Second para.</p>") {
+		t.Fatalf("expected escaped paragraphs, got:\n%s", body)
+	}
+	if !strings.Contains(body, "<title>https://example.com/post</title>") {
+		t.Fatalf("expected the url as the title, got:\n%s", body)
+	}
+	if !strings.Contains(body, "This archive is partial.") {
+		t.Fatalf("expected the paywalled archive to be flagged, got:\n%s", body)
+	}
+}
+
+func TestReaderAppliesConfiguredPolicy(t *testing.T) {
+	t.Parallel()
+
+	linkID := uuid.New()
+	archive := db.GetReaderArchiveRow{
+		Html: `<p>Watch:</p><iframe src="https://player.vimeo.com/video/1" width="640"></iframe>` +
+			`<iframe src="https://ads.example.net/frame"></iframe><img src="https://example.com/a.png">`,
+	}
+	reader := newReaderSanitizer(sanitize.Options{
+		Features:   []string{sanitize.FeatureImages, sanitize.FeatureEmbeds},
+		ImageProxy: "https://images.example.org/p?url={url}",
+	})
+	e := newReaderTestServerWithPolicy(reader, linkID, archive, nil, nil)
+
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/read/"+linkID.String(), nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+	}
+	body := rec.Body.String()
+	for _, want := range []string{
+		`<iframe src="https://player.vimeo.com/video/1" width="640">`,
+		`<img src="https://images.example.org/p?url=https%3A%2F%2Fexample.com%2Fa.png">`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Fatalf("expected page to contain %q, got:\n%s", want, body)
+		}
+	}
+	if strings.Contains(body, "ads.example.net") {
+		t.Fatalf("expected frames from other hosts to be stripped, got:\n%s", body)
+	}
+	csp := rec.Header().Get("Content-Security-Policy")
+	if !strings.Contains(csp, "img-src 'self' https://images.example.org data:;") || !strings.Contains(csp, "frame-src https://www.youtube.com https://www.youtube-nocookie.com https://player.vimeo.com;") {
+		t.Fatalf("expected the policy to allow the proxy and embed hosts, got %q", csp)
+	}
+}
+
+func TestReaderNotFound(t *testing.T) {
+	t.Parallel()
+
+	linkID := uuid.New()
+	e := newReaderTestServer(linkID, db.GetReaderArchiveRow{}, pgx.ErrNoRows, nil)
+
+	for target, want := range map[string]int{
+		"/read/" + linkID.String():     http.StatusNotFound,
+		"/read/" + uuid.New().String(): http.StatusNotFound,
+		"/read/not-a-uuid":             http.StatusBadRequest,
+	} {
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+		if rec.Code != want {
+			t.Fatalf("%s: expected status %d, got %d", target, want, rec.Code)
+		}
+	}
+}
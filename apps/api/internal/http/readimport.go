@@ -0,0 +1,227 @@
+package httpapi
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	stdhttp "net/http"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/labstack/echo/v4"
+
+	"github.com/example/keepstack/apps/api/internal/db"
+)
+
+// importModeRead selects the read history import of POST /api/links/import.
+const importModeRead = "read"
+
+// readHistoryDateColumns are the header names a read history CSV's date
+// column may have, in order of preference.
+var readHistoryDateColumns = []string{"read_at", "read_date", "date", "visited_at", "visit_time"}
+
+// readHistoryDateLayouts are the formats read dates are accepted in. Dates
+// without an offset are UTC.
+var readHistoryDateLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05",
+	time.DateTime,
+	time.DateOnly,
+}
+
+// readHistoryEntry is one row of a read history CSV. A zero ReadAt means the
+// row gave no date.
+type readHistoryEntry struct {
+	URL    string
+	ReadAt time.Time
+}
+
+type readImportResponse struct {
+	// Marked counts the links marked read, AlreadyRead the matching links
+	// that were read before, and Unmatched the rows naming no saved link.
+	Marked        int             `json:"marked"`
+	AlreadyRead   int             `json:"already_read"`
+	Unmatched     int             `json:"unmatched"`
+	Failed        []importFailure `json:"failed"`
+	RefreshQueued bool            `json:"refresh_queued"`
+}
+
+// handleCreateReadImportJob marks saved links read from a CSV of URLs and
+// read dates, such as a browser history export, as a job. Rows naming links
+// that are not saved are counted and skipped; nothing is saved.
+func (s *Server) handleCreateReadImportJob(c echo.Context) error {
+	entries, failed, err := parseReadHistory(c.Request().Body, maxImportJobLinks)
+	if errors.Is(err, errTooManyReadHistoryRows) {
+		return respondError(c, stdhttp.StatusRequestEntityTooLarge, fmt.Sprintf("import at most %d rows per job", maxImportJobLinks))
+	}
+	if err != nil {
+		return respondError(c, stdhttp.StatusBadRequest, "body must be a CSV of urls and read dates")
+	}
+	if len(entries)+len(failed) == 0 {
+		return respondError(c, stdhttp.StatusBadRequest, "no rows to import")
+	}
+
+	return s.startJob(c, jobTypeImport, len(entries)+len(failed), func(c echo.Context, progress *jobProgress) (any, *jobFile, error) {
+		progress.Add(c.Request().Context(), len(failed))
+		resp, err := s.importReadHistory(c, entries, progress)
+		resp.Failed = append(failed, resp.Failed...)
+		return resp, nil, err
+	})
+}
+
+var errTooManyReadHistoryRows = errors.New("too many rows")
+
+// parseReadHistory reads a CSV whose rows hold a URL and, optionally, the
+// date it was read. A first row with a "url" column is a header naming the
+// columns, so exports with more columns work; otherwise the URL is the
+// first column and the date the second. Rows with an unreadable or future
+// date are returned as failures.
+func parseReadHistory(r io.Reader, maxRows int) ([]readHistoryEntry, []importFailure, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+	reader.TrimLeadingSpace = true
+
+	urlColumn, dateColumn := 0, 1
+	var entries []readHistoryEntry
+	failed := []importFailure{}
+	now := time.Now()
+	for first := true; ; first = false {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		if first {
+			record[0] = strings.TrimPrefix(record[0], "\ufeff")
+			if column := headerColumn(record, "url"); column >= 0 {
+				urlColumn, dateColumn = column, -1
+				for _, name := range readHistoryDateColumns {
+					if column := headerColumn(record, name); column >= 0 {
+						dateColumn = column
+						break
+					}
+				}
+				continue
+			}
+		}
+
+		if len(entries)+len(failed) >= maxRows {
+			return nil, nil, errTooManyReadHistoryRows
+		}
+		if urlColumn >= len(record) || strings.TrimSpace(record[urlColumn]) == "" {
+			continue
+		}
+		entry := readHistoryEntry{URL: strings.TrimSpace(record[urlColumn])}
+		if dateColumn >= 0 && dateColumn < len(record) && strings.TrimSpace(record[dateColumn]) != "" {
+			readAt, err := parseReadDate(record[dateColumn])
+			if err != nil {
+				failed = append(failed, importFailure{URL: entry.URL, Error: "invalid read date"})
+				continue
+			}
+			if readAt.After(now) {
+				failed = append(failed, importFailure{URL: entry.URL, Error: "read date is in the future"})
+				continue
+			}
+			entry.ReadAt = readAt
+		}
+		entries = append(entries, entry)
+	}
+	return entries, failed, nil
+}
+
+func headerColumn(record []string, name string) int {
+	for i, field := range record {
+		if strings.EqualFold(strings.TrimSpace(field), name) {
+			return i
+		}
+	}
+	return -1
+}
+
+func parseReadDate(raw string) (time.Time, error) {
+	raw = strings.TrimSpace(raw)
+	var lastErr error
+	for _, layout := range readHistoryDateLayouts {
+		t, err := time.Parse(layout, raw)
+		if err == nil {
+			return t, nil
+		}
+		lastErr = err
+	}
+	return time.Time{}, lastErr
+}
+
+// importReadHistory marks the saved links entries name read, each at the
+// earliest date given for it, or now when none was. Links are matched by
+// normalized URL, as saving one is, and links already read keep their read
+// time.
+func (s *Server) importReadHistory(c echo.Context, entries []readHistoryEntry, progress *jobProgress) (readImportResponse, error) {
+	ctx := c.Request().Context()
+	userID := s.userID(ctx)
+	resp := readImportResponse{Failed: []importFailure{}}
+
+	now := time.Now()
+	readAt := map[pgtype.UUID]time.Time{}
+	var ids []pgtype.UUID
+	for _, entry := range entries {
+		progress.Add(ctx, 1)
+		normalizedURL, err := s.normalizeLinkURL(entry.URL)
+		if err != nil {
+			resp.Failed = append(resp.Failed, importFailure{URL: entry.URL, Error: "invalid url"})
+			continue
+		}
+		link, err := s.queries.FindLinkByURLHash(ctx, db.FindLinkByURLHashParams{
+			UserID:  uuidToPg(userID),
+			UrlHash: urlHash(normalizedURL),
+		})
+		if errors.Is(err, pgx.ErrNoRows) {
+			resp.Unmatched++
+			continue
+		}
+		if err != nil {
+			c.Logger().Errorf("read import: lookup failed: %v", err)
+			resp.Failed = append(resp.Failed, importFailure{URL: entry.URL, Error: "failed to look up link"})
+			continue
+		}
+
+		at := entry.ReadAt
+		if at.IsZero() {
+			at = now
+		}
+		previous, seen := readAt[link.ID]
+		if !seen {
+			ids = append(ids, link.ID)
+		}
+		if !seen || at.Before(previous) {
+			readAt[link.ID] = at
+		}
+	}
+	if len(ids) == 0 {
+		return resp, nil
+	}
+
+	readAts := make([]pgtype.Timestamptz, len(ids))
+	for i, id := range ids {
+		readAts[i] = pgtype.Timestamptz{Time: readAt[id], Valid: true}
+	}
+	marked, err := s.queries.MarkLinksReadAt(ctx, db.MarkLinksReadAtParams{
+		Ids:     ids,
+		ReadAts: readAts,
+		UserID:  uuidToPg(userID),
+	})
+	if err != nil {
+		c.Logger().Errorf("read import: mark %d links read failed: %v", len(ids), err)
+		return resp, apiError{Code: stdhttp.StatusInternalServerError, Message: "failed to mark links read"}
+	}
+	resp.Marked = len(marked)
+	resp.AlreadyRead = len(ids) - len(marked)
+	resp.RefreshQueued = s.queueRecommendationsRefresh(c, userID, len(marked))
+	return resp, nil
+}
@@ -0,0 +1,86 @@
+package httpapi
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
+)
+
+// corsMaxAge is how long, in seconds, browsers may cache a preflight result.
+const corsMaxAge = 600
+
+// SecurityConfig controls which browser origins may call the API and the
+// security headers sent on every response.
+type SecurityConfig struct {
+	// AllowedOrigins lists exact origins allowed to make cross-origin
+	// requests, such as the web app's host or
+	// "chrome-extension://<extension id>". Empty disables CORS.
+	AllowedOrigins []string
+	// ContentSecurityPolicy is sent verbatim. Empty omits the header.
+	ContentSecurityPolicy string
+	// HSTSMaxAge sets Strict-Transport-Security on HTTPS requests. Zero
+	// omits the header.
+	HSTSMaxAge int
+}
+
+// CORSMiddleware answers preflight requests and adds CORS headers for the
+// configured origins. Requests from other origins get no CORS headers, so the
+// browser blocks them. With no origins configured it does nothing. The
+// extension endpoints under /api/ext apply ExtensionCORSMiddleware instead.
+func CORSMiddleware(cfg SecurityConfig) echo.MiddlewareFunc {
+	origins := make([]string, 0, len(cfg.AllowedOrigins))
+	for _, origin := range cfg.AllowedOrigins {
+		if origin = strings.TrimRight(strings.TrimSpace(origin), "/"); origin != "" {
+			origins = append(origins, origin)
+		}
+	}
+	if len(origins) == 0 {
+		return func(next echo.HandlerFunc) echo.HandlerFunc { return next }
+	}
+
+	return middleware.CORSWithConfig(middleware.CORSConfig{
+		Skipper:      isExtensionRoute,
+		AllowOrigins: origins,
+		AllowMethods: []string{http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete},
+		AllowHeaders: []string{echo.HeaderContentType, echo.HeaderAuthorization, "If-None-Match", "traceparent", "tracestate"},
+		ExposeHeaders: []string{
+			echo.HeaderContentDisposition,
+			echo.HeaderRetryAfter,
+			"ETag",
+		},
+		MaxAge: corsMaxAge,
+	})
+}
+
+// ExtensionCORSMiddleware allows the extension endpoints to be called from
+// any origin. They authenticate with a bearer token rather than cookies, so a
+// page on another origin gains nothing without the token. Other routes pass
+// through untouched.
+func ExtensionCORSMiddleware() echo.MiddlewareFunc {
+	return middleware.CORSWithConfig(middleware.CORSConfig{
+		Skipper:      func(c echo.Context) bool { return !isExtensionRoute(c) },
+		AllowOrigins: []string{"*"},
+		AllowMethods: []string{http.MethodGet, http.MethodPost},
+		AllowHeaders: []string{echo.HeaderContentType, echo.HeaderAuthorization},
+		MaxAge:       corsMaxAge,
+	})
+}
+
+func isExtensionRoute(c echo.Context) bool {
+	path := c.Request().URL.Path
+	return path == extensionPrefix || strings.HasPrefix(path, extensionPrefix+"/")
+}
+
+// SecurityHeadersMiddleware sets standard hardening headers. The API only
+// serves JSON and downloads, so framing is denied and referrers are dropped.
+func SecurityHeadersMiddleware(cfg SecurityConfig) echo.MiddlewareFunc {
+	return middleware.SecureWithConfig(middleware.SecureConfig{
+		ContentTypeNosniff:    "nosniff",
+		XFrameOptions:         "DENY",
+		ReferrerPolicy:        "no-referrer",
+		HSTSMaxAge:            cfg.HSTSMaxAge,
+		ContentSecurityPolicy: cfg.ContentSecurityPolicy,
+	})
+}
@@ -0,0 +1,88 @@
+package httpapi
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/labstack/echo/v4"
+
+	"github.com/example/keepstack/apps/api/internal/config"
+	"github.com/example/keepstack/apps/api/internal/db"
+)
+
+func newSecurityTestServer(cfg config.Config) *echo.Echo {
+	queries := &mockQueries{
+		listTagLinkCountsFn: func(ctx context.Context, userID pgtype.UUID) ([]db.ListTagLinkCountsRow, error) {
+			return nil, nil
+		},
+	}
+	srv := &Server{cfg: cfg, queries: queries, metrics: newTestMetrics()}
+	e := echo.New()
+	srv.RegisterRoutes(e)
+	return e
+}
+
+func TestCORSAllowsConfiguredOrigins(t *testing.T) {
+	t.Parallel()
+
+	e := newSecurityTestServer(config.Config{
+		CORSAllowedOrigins: []string{"https://keepstack.example.com/", "chrome-extension://abcdef"},
+	})
+
+	req := httptest.NewRequest(http.MethodOptions, "/api/links", nil)
+	req.Header.Set(echo.HeaderOrigin, "chrome-extension://abcdef")
+	req.Header.Set(echo.HeaderAccessControlRequestMethod, http.MethodPost)
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("expected preflight status %d, got %d", http.StatusNoContent, rec.Code)
+	}
+	if got := rec.Header().Get(echo.HeaderAccessControlAllowOrigin); got != "chrome-extension://abcdef" {
+		t.Fatalf("expected extension origin to be allowed, got %q", got)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/api/tags", nil)
+	req.Header.Set(echo.HeaderOrigin, "https://keepstack.example.com")
+	rec = httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+
+	if got := rec.Header().Get(echo.HeaderAccessControlAllowOrigin); got != "https://keepstack.example.com" {
+		t.Fatalf("expected web origin to be allowed, got %q", got)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/api/tags", nil)
+	req.Header.Set(echo.HeaderOrigin, "https://evil.example.com")
+	rec = httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+
+	if got := rec.Header().Get(echo.HeaderAccessControlAllowOrigin); got != "" {
+		t.Fatalf("expected unknown origin to get no CORS headers, got %q", got)
+	}
+}
+
+func TestSecurityHeaders(t *testing.T) {
+	t.Parallel()
+
+	e := newSecurityTestServer(config.Config{ContentSecurityPolicy: "default-src 'none'"})
+
+	req := httptest.NewRequest(http.MethodGet, "/api/tags", nil)
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+
+	want := map[string]string{
+		echo.HeaderXContentTypeOptions:      "nosniff",
+		echo.HeaderXFrameOptions:            "DENY",
+		echo.HeaderReferrerPolicy:           "no-referrer",
+		echo.HeaderContentSecurityPolicy:    "default-src 'none'",
+		echo.HeaderAccessControlAllowOrigin: "",
+	}
+	for header, value := range want {
+		if got := rec.Header().Get(header); got != value {
+			t.Fatalf("expected %s %q, got %q", header, value, got)
+		}
+	}
+}
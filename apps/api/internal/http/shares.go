@@ -0,0 +1,223 @@
+package httpapi
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	stdhttp "net/http"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/labstack/echo/v4"
+
+	"github.com/example/keepstack/apps/api/internal/db"
+)
+
+// linkPermission is what a request needs to do with a link.
+type linkPermission int
+
+const (
+	// linkPermissionRead opens the link, its archive, tags, and highlights.
+	linkPermissionRead linkPermission = iota
+	// linkPermissionAnnotate also adds, edits, and deletes highlights.
+	linkPermissionAnnotate
+	// linkPermissionOwner covers everything else: editing, tagging,
+	// publishing, and sharing the link. Shares never grant it.
+	linkPermissionOwner
+)
+
+// Permissions a share grants, as stored in link_shares.permission.
+const (
+	sharePermissionRead     = "read"
+	sharePermissionAnnotate = "annotate"
+)
+
+type shareLinkRequest struct {
+	Email      string     `json:"email"`
+	Permission string     `json:"permission"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+}
+
+type linkShareResponse struct {
+	UserID     string     `json:"user_id"`
+	Email      string     `json:"email,omitempty"`
+	Permission string     `json:"permission"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	Expired    bool       `json:"expired"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+type sharedLinkResponse struct {
+	ID         string     `json:"id"`
+	URL        string     `json:"url"`
+	Title      string     `json:"title"`
+	OwnerEmail string     `json:"owner_email"`
+	Permission string     `json:"permission"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	SharedAt   time.Time  `json:"shared_at"`
+}
+
+// handleListLinkShares lists everyone the caller's link is shared with,
+// expired shares included so they can be renewed or removed.
+func (s *Server) handleListLinkShares(c echo.Context) error {
+	linkID, err := parseUUIDParam(c.Param("id"))
+	if err != nil {
+		return respondError(c, stdhttp.StatusBadRequest, "invalid link id")
+	}
+	ctx := c.Request().Context()
+	if _, err := s.ensureLinkAccess(ctx, linkID, linkPermissionOwner); err != nil {
+		return respondWithError(c, err)
+	}
+
+	shares, err := s.queries.ListLinkShares(ctx, uuidToPg(linkID))
+	if err != nil {
+		c.Logger().Errorf("list link shares: query failed: %v", err)
+		return respondError(c, stdhttp.StatusInternalServerError, "failed to list shares")
+	}
+	now := time.Now()
+	responses := make([]linkShareResponse, 0, len(shares))
+	for _, share := range shares {
+		responses = append(responses, toLinkShareResponse(db.LinkShare{
+			LinkID:     share.LinkID,
+			UserID:     share.UserID,
+			Permission: share.Permission,
+			ExpiresAt:  share.ExpiresAt,
+			CreatedAt:  share.CreatedAt,
+		}, share.Email, now))
+	}
+	return c.JSON(stdhttp.StatusOK, map[string]any{"items": responses})
+}
+
+// handleShareLink shares the caller's link with another user on the
+// instance, found by email. Sharing again with the same user replaces the
+// permission and expiry.
+func (s *Server) handleShareLink(c echo.Context) error {
+	linkID, err := parseUUIDParam(c.Param("id"))
+	if err != nil {
+		return respondError(c, stdhttp.StatusBadRequest, "invalid link id")
+	}
+	var req shareLinkRequest
+	if err := c.Bind(&req); err != nil {
+		return respondBindError(c, err)
+	}
+
+	email := strings.TrimSpace(req.Email)
+	if email == "" {
+		return respondError(c, stdhttp.StatusBadRequest, "email is required")
+	}
+	permission := strings.ToLower(strings.TrimSpace(req.Permission))
+	if permission == "" {
+		permission = sharePermissionRead
+	}
+	if permission != sharePermissionRead && permission != sharePermissionAnnotate {
+		return respondError(c, stdhttp.StatusBadRequest, "permission must be read or annotate")
+	}
+	var expiresAt pgtype.Timestamptz
+	if req.ExpiresAt != nil {
+		if !req.ExpiresAt.After(time.Now()) {
+			return respondError(c, stdhttp.StatusBadRequest, "expires_at must be in the future")
+		}
+		expiresAt = pgtype.Timestamptz{Time: req.ExpiresAt.UTC(), Valid: true}
+	}
+
+	ctx := c.Request().Context()
+	if _, err := s.ensureLinkAccess(ctx, linkID, linkPermissionOwner); err != nil {
+		return respondWithError(c, err)
+	}
+
+	userID, err := s.queries.GetUserIDByEmail(ctx, email)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return respondError(c, stdhttp.StatusNotFound, "user not found")
+		}
+		c.Logger().Errorf("share link: look up user failed: %v", err)
+		return respondError(c, stdhttp.StatusInternalServerError, "failed to share link")
+	}
+	if uuidFromPg(userID) == s.userID(ctx) {
+		return respondError(c, stdhttp.StatusBadRequest, "cannot share a link with yourself")
+	}
+
+	share, err := s.queries.UpsertLinkShare(ctx, db.UpsertLinkShareParams{
+		LinkID:     uuidToPg(linkID),
+		UserID:     userID,
+		Permission: permission,
+		ExpiresAt:  expiresAt,
+	})
+	if err != nil {
+		c.Logger().Errorf("share link: upsert failed: %v", err)
+		return respondError(c, stdhttp.StatusInternalServerError, "failed to share link")
+	}
+	return c.JSON(stdhttp.StatusOK, toLinkShareResponse(share, email, time.Now()))
+}
+
+// handleUnshareLink stops sharing the caller's link with a user.
+func (s *Server) handleUnshareLink(c echo.Context) error {
+	linkID, err := parseUUIDParam(c.Param("id"))
+	if err != nil {
+		return respondError(c, stdhttp.StatusBadRequest, "invalid link id")
+	}
+	userID, err := parseUUIDParam(c.Param("userID"))
+	if err != nil {
+		return respondError(c, stdhttp.StatusBadRequest, "invalid user id")
+	}
+	ctx := c.Request().Context()
+	if _, err := s.ensureLinkAccess(ctx, linkID, linkPermissionOwner); err != nil {
+		return respondWithError(c, err)
+	}
+
+	deleted, err := s.queries.DeleteLinkShare(ctx, db.DeleteLinkShareParams{
+		LinkID: uuidToPg(linkID),
+		UserID: uuidToPg(userID),
+	})
+	if err != nil {
+		c.Logger().Errorf("unshare link: delete failed: %v", err)
+		return respondError(c, stdhttp.StatusInternalServerError, "failed to remove share")
+	}
+	if deleted == 0 {
+		return respondError(c, stdhttp.StatusNotFound, "share not found")
+	}
+	return c.NoContent(stdhttp.StatusNoContent)
+}
+
+// handleListSharedWithMe lists the links other users share with the caller,
+// most recently shared first. Expired shares are left out.
+func (s *Server) handleListSharedWithMe(c echo.Context) error {
+	rows, err := s.queries.ListLinksSharedWithUser(c.Request().Context(), uuidToPg(s.currentUser(c)))
+	if err != nil {
+		c.Logger().Errorf("list shared links: query failed: %v", err)
+		return respondError(c, stdhttp.StatusInternalServerError, "failed to list shared links")
+	}
+	responses := make([]sharedLinkResponse, 0, len(rows))
+	for _, row := range rows {
+		responses = append(responses, sharedLinkResponse{
+			ID:         uuidFromPg(row.ID).String(),
+			URL:        row.Url,
+			Title:      row.Title.String,
+			OwnerEmail: row.OwnerEmail,
+			Permission: row.Permission,
+			ExpiresAt:  timestampPtr(row.ExpiresAt),
+			SharedAt:   row.CreatedAt.Time,
+		})
+	}
+	return c.JSON(stdhttp.StatusOK, map[string]any{"items": responses})
+}
+
+func toLinkShareResponse(share db.LinkShare, email string, now time.Time) linkShareResponse {
+	return linkShareResponse{
+		UserID:     uuidFromPg(share.UserID).String(),
+		Email:      email,
+		Permission: share.Permission,
+		ExpiresAt:  timestampPtr(share.ExpiresAt),
+		Expired:    share.ExpiresAt.Valid && !share.ExpiresAt.Time.After(now),
+		CreatedAt:  share.CreatedAt.Time,
+	}
+}
+
+func timestampPtr(ts pgtype.Timestamptz) *time.Time {
+	if !ts.Valid {
+		return nil
+	}
+	t := ts.Time
+	return &t
+}
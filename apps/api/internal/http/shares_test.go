@@ -0,0 +1,162 @@
+package httpapi
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/labstack/echo/v4"
+
+	"github.com/example/keepstack/apps/api/internal/config"
+	"github.com/example/keepstack/apps/api/internal/db"
+)
+
+var (
+	shareOwnerID  = uuid.MustParse("aaaaaaaa-aaaa-aaaa-aaaa-aaaaaaaaaaaa")
+	shareViewerID = uuid.MustParse("bbbbbbbb-bbbb-bbbb-bbbb-bbbbbbbbbbbb")
+	sharedLinkID  = uuid.MustParse("cccccccc-cccc-cccc-cccc-cccccccccccc")
+)
+
+func TestEnsureLinkAccessHonoursShares(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		viewer  uuid.UUID
+		granted string // empty means no unexpired share
+		need    linkPermission
+		want    int
+	}{
+		{name: "owner edits", viewer: shareOwnerID, need: linkPermissionOwner, want: http.StatusOK},
+		{name: "no share", viewer: shareViewerID, need: linkPermissionRead, want: http.StatusNotFound},
+		{name: "read share reads", viewer: shareViewerID, granted: sharePermissionRead, need: linkPermissionRead, want: http.StatusOK},
+		{name: "read share cannot annotate", viewer: shareViewerID, granted: sharePermissionRead, need: linkPermissionAnnotate, want: http.StatusForbidden},
+		{name: "annotate share annotates", viewer: shareViewerID, granted: sharePermissionAnnotate, need: linkPermissionAnnotate, want: http.StatusOK},
+		{name: "shares never grant ownership", viewer: shareViewerID, granted: sharePermissionAnnotate, need: linkPermissionOwner, want: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			queries := &mockQueries{
+				getLinkFn: func(ctx context.Context, id pgtype.UUID) (db.GetLinkRow, error) {
+					return db.GetLinkRow{ID: id, UserID: uuidToPg(shareOwnerID)}, nil
+				},
+				getLinkSharePermissionFn: func(ctx context.Context, arg db.GetLinkSharePermissionParams) (string, error) {
+					if uuidFromPg(arg.UserID) != tt.viewer {
+						t.Errorf("share looked up for %s, want %s", uuidFromPg(arg.UserID), tt.viewer)
+					}
+					if tt.granted == "" {
+						return "", pgx.ErrNoRows
+					}
+					return tt.granted, nil
+				},
+			}
+			srv := &Server{cfg: config.Config{DevUserID: tt.viewer}, queries: queries, metrics: newTestMetrics()}
+
+			_, err := srv.ensureLinkAccess(context.Background(), sharedLinkID, tt.need)
+			got := http.StatusOK
+			var apiErr apiError
+			if errors.As(err, &apiErr) {
+				got = apiErr.Code
+			} else if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Fatalf("expected %d, got %d (%v)", tt.want, got, err)
+			}
+		})
+	}
+}
+
+func TestHandleShareLink(t *testing.T) {
+	t.Parallel()
+
+	newServer := func(upserted *db.UpsertLinkShareParams) *echo.Echo {
+		queries := &mockQueries{
+			getLinkFn: func(ctx context.Context, id pgtype.UUID) (db.GetLinkRow, error) {
+				return db.GetLinkRow{ID: id, UserID: uuidToPg(shareOwnerID)}, nil
+			},
+			getUserIDByEmailFn: func(ctx context.Context, email string) (pgtype.UUID, error) {
+				switch strings.ToLower(email) {
+				case "viewer@example.com":
+					return uuidToPg(shareViewerID), nil
+				case "owner@example.com":
+					return uuidToPg(shareOwnerID), nil
+				}
+				return pgtype.UUID{}, pgx.ErrNoRows
+			},
+			upsertLinkShareFn: func(ctx context.Context, arg db.UpsertLinkShareParams) (db.LinkShare, error) {
+				*upserted = arg
+				return db.LinkShare{
+					LinkID:     arg.LinkID,
+					UserID:     arg.UserID,
+					Permission: arg.Permission,
+					ExpiresAt:  arg.ExpiresAt,
+					CreatedAt:  pgtype.Timestamptz{Time: time.Now(), Valid: true},
+				}, nil
+			},
+		}
+		srv := &Server{cfg: config.Config{DevUserID: shareOwnerID}, queries: queries, metrics: newTestMetrics()}
+		e := echo.New()
+		srv.RegisterRoutes(e)
+		return e
+	}
+
+	expires := time.Now().Add(48 * time.Hour).UTC().Format(time.RFC3339)
+	past := time.Now().Add(-time.Hour).UTC().Format(time.RFC3339)
+	tests := []struct {
+		name string
+		body string
+		want int
+	}{
+		{name: "read by default", body: `{"email":"Viewer@example.com"}`, want: http.StatusOK},
+		{name: "annotate with expiry", body: `{"email":"viewer@example.com","permission":"annotate","expires_at":"` + expires + `"}`, want: http.StatusOK},
+		{name: "unknown permission", body: `{"email":"viewer@example.com","permission":"write"}`, want: http.StatusBadRequest},
+		{name: "expiry in the past", body: `{"email":"viewer@example.com","expires_at":"` + past + `"}`, want: http.StatusBadRequest},
+		{name: "unknown user", body: `{"email":"nobody@example.com"}`, want: http.StatusNotFound},
+		{name: "yourself", body: `{"email":"owner@example.com"}`, want: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			var upserted db.UpsertLinkShareParams
+			e := newServer(&upserted)
+			req := httptest.NewRequest(http.MethodPost, "/api/links/"+sharedLinkID.String()+"/shares", strings.NewReader(tt.body))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+
+			if rec.Code != tt.want {
+				t.Fatalf("expected status %d, got %d: %s", tt.want, rec.Code, rec.Body.String())
+			}
+			if tt.want != http.StatusOK {
+				if upserted.LinkID.Valid {
+					t.Fatalf("expected no share to be stored")
+				}
+				return
+			}
+			var resp linkShareResponse
+			if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("decode response: %v", err)
+			}
+			if resp.UserID != shareViewerID.String() || resp.Permission != upserted.Permission || resp.Expired {
+				t.Fatalf("unexpected share %+v for %+v", resp, upserted)
+			}
+			if upserted.ExpiresAt.Valid != (resp.ExpiresAt != nil) {
+				t.Fatalf("expiry not passed through: %+v", upserted)
+			}
+		})
+	}
+}
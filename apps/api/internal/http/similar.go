@@ -0,0 +1,164 @@
+package httpapi
+
+import (
+	"context"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/google/uuid"
+
+	"github.com/example/keepstack/apps/api/internal/db"
+)
+
+const (
+	// maxSimilarLinks caps the similar_links array in a create response.
+	maxSimilarLinks = 5
+	// similarCandidateLimit bounds how many existing links are scored.
+	similarCandidateLimit = 200
+	// titleSimilarityThreshold is the share of distinct title words two
+	// titles must have in common to count as similar.
+	titleSimilarityThreshold = 0.6
+)
+
+// Reasons a saved link is reported as similar.
+const (
+	similarReasonURL   = "url"
+	similarReasonTitle = "title"
+)
+
+// titleStopWords are left out of title comparisons so that two titles do
+// not look alike just for sharing them.
+var titleStopWords = map[string]struct{}{
+	"and": {}, "are": {}, "for": {}, "from": {}, "how": {}, "into": {},
+	"not": {}, "that": {}, "the": {}, "this": {}, "what": {}, "why": {},
+	"with": {}, "you": {}, "your": {},
+}
+
+type similarLinkResponse struct {
+	ID        string    `json:"id"`
+	URL       string    `json:"url"`
+	Title     string    `json:"title"`
+	CreatedAt time.Time `json:"created_at"`
+	Reason    string    `json:"reason"`
+	Score     float64   `json:"score"`
+}
+
+// findSimilarLinks returns the caller's saved links that look like a near
+// duplicate of normalizedURL or title: the same page once scheme, www.,
+// trailing slashes and query order are ignored, or a title sharing most of
+// its words. URL matches come first, then titles by score.
+func (s *Server) findSimilarLinks(ctx context.Context, userID uuid.UUID, normalizedURL, title string) ([]similarLinkResponse, error) {
+	canonical, host := canonicalURL(normalizedURL)
+	words := titleWords(title)
+	rows, err := s.queries.ListSimilarLinks(ctx, db.ListSimilarLinksParams{
+		UserID:     uuidToPg(userID),
+		Host:       host,
+		TitleWords: words,
+		PageLimit:  similarCandidateLimit,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	similar := make([]similarLinkResponse, 0)
+	for _, row := range rows {
+		item := similarLinkResponse{
+			ID:        uuidFromPg(row.ID).String(),
+			URL:       row.Url,
+			Title:     row.Title.String,
+			CreatedAt: row.CreatedAt.Time,
+		}
+		// Links saved before normalization was tightened are compared as
+		// they would be stored today.
+		existing := row.Url
+		if normalized, err := s.normalizeLinkURL(existing); err == nil {
+			existing = normalized
+		}
+		if other, _ := canonicalURL(existing); canonical != "" && other == canonical {
+			item.Reason, item.Score = similarReasonURL, 1
+		} else if score := titleSimilarity(words, titleWords(row.Title.String)); score >= titleSimilarityThreshold {
+			item.Reason, item.Score = similarReasonTitle, score
+		} else {
+			continue
+		}
+		similar = append(similar, item)
+	}
+
+	sort.SliceStable(similar, func(i, j int) bool {
+		if (similar[i].Reason == similarReasonURL) != (similar[j].Reason == similarReasonURL) {
+			return similar[i].Reason == similarReasonURL
+		}
+		return similar[i].Score > similar[j].Score
+	})
+	if len(similar) > maxSimilarLinks {
+		similar = similar[:maxSimilarLinks]
+	}
+	return similar, nil
+}
+
+// canonicalURL reduces a normalized URL to the parts that identify a page:
+// host without www., path without a trailing slash, and the sorted query.
+// It also returns the host on its own, as ListSimilarLinks matches it.
+func canonicalURL(normalized string) (string, string) {
+	parsed, err := url.Parse(normalized)
+	if err != nil || parsed.Host == "" {
+		return "", ""
+	}
+	host := strings.TrimPrefix(strings.ToLower(parsed.Hostname()), "www.")
+	canonical := host
+	if port := parsed.Port(); port != "" {
+		canonical += ":" + port
+	}
+	canonical += strings.TrimRight(parsed.EscapedPath(), "/")
+	if query := parsed.Query().Encode(); query != "" {
+		canonical += "?" + query
+	}
+	return canonical, host
+}
+
+// titleWords splits a title into its distinct lower-case words, dropping
+// stop words and anything shorter than three characters. The split matches
+// the one ListSimilarLinks applies to stored titles.
+func titleWords(title string) []string {
+	fields := strings.FieldsFunc(strings.ToLower(title), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	words := make([]string, 0, len(fields))
+	seen := make(map[string]struct{}, len(fields))
+	for _, field := range fields {
+		if len([]rune(field)) < 3 {
+			continue
+		}
+		if _, stop := titleStopWords[field]; stop {
+			continue
+		}
+		if _, dup := seen[field]; dup {
+			continue
+		}
+		seen[field] = struct{}{}
+		words = append(words, field)
+	}
+	return words
+}
+
+// titleSimilarity is the Jaccard index of two word sets: the words they
+// share over the words in either.
+func titleSimilarity(a, b []string) float64 {
+	if len(a) == 0 || len(b) == 0 {
+		return 0
+	}
+	set := make(map[string]struct{}, len(a))
+	for _, word := range a {
+		set[word] = struct{}{}
+	}
+	shared := 0
+	for _, word := range b {
+		if _, ok := set[word]; ok {
+			shared++
+		}
+	}
+	return float64(shared) / float64(len(a)+len(b)-shared)
+}
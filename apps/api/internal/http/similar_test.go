@@ -0,0 +1,151 @@
+package httpapi
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/labstack/echo/v4"
+
+	"github.com/example/keepstack/apps/api/internal/config"
+	"github.com/example/keepstack/apps/api/internal/db"
+)
+
+func TestCanonicalURL(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		a, b string
+		same bool
+	}{
+		{a: "https://example.com/post/", b: "http://www.example.com/post", same: true},
+		{a: "https://example.com/?b=2&a=1", b: "https://example.com?a=1&b=2", same: true},
+		{a: "https://example.com/post", b: "https://example.com/Post", same: false},
+		{a: "https://example.com:8080/post", b: "https://example.com/post", same: false},
+		{a: "https://example.com/post?id=1", b: "https://example.com/post?id=2", same: false},
+	}
+
+	for _, tt := range tests {
+		a, _ := canonicalURL(tt.a)
+		b, _ := canonicalURL(tt.b)
+		if (a == b) != tt.same {
+			t.Fatalf("canonicalURL(%q) = %q, canonicalURL(%q) = %q, expected same=%v", tt.a, a, tt.b, b, tt.same)
+		}
+	}
+	if _, host := canonicalURL("https://www.Example.com:8080/x"); host != "example.com" {
+		t.Fatalf("expected host example.com, got %q", host)
+	}
+}
+
+func TestTitleSimilarity(t *testing.T) {
+	t.Parallel()
+
+	words := titleWords("The Go Memory Model, explained!")
+	if strings.Join(words, " ") != "memory model explained" {
+		t.Fatalf("unexpected title words %v", words)
+	}
+	if score := titleSimilarity(words, titleWords("Go memory model explained")); score != 1 {
+		t.Fatalf("expected identical word sets to score 1, got %v", score)
+	}
+	if score := titleSimilarity(words, titleWords("Memory model explained for beginners")); score < titleSimilarityThreshold {
+		t.Fatalf("expected a near match, got %v", score)
+	}
+	if score := titleSimilarity(words, titleWords("A model railway")); score >= titleSimilarityThreshold {
+		t.Fatalf("expected a weak match, got %v", score)
+	}
+	if score := titleSimilarity(nil, words); score != 0 {
+		t.Fatalf("expected no score without a title, got %v", score)
+	}
+}
+
+func TestHandleCreateLinkSimilarLinks(t *testing.T) {
+	t.Parallel()
+
+	cfg := config.Config{DevUserID: uuid.MustParse("abababab-abab-abab-abab-abababababab")}
+	urlMatch := uuid.New()
+	titleMatch := uuid.New()
+	created := time.Now().Add(-48 * time.Hour)
+
+	var captured db.ListSimilarLinksParams
+	queries := &mockQueries{
+		listSimilarLinksFn: func(ctx context.Context, arg db.ListSimilarLinksParams) ([]db.ListSimilarLinksRow, error) {
+			captured = arg
+			return []db.ListSimilarLinksRow{
+				{ID: uuidToPg(titleMatch), Url: "https://mirror.example.org/copy", Title: pgtype.Text{String: "Memory model explained", Valid: true}, CreatedAt: pgtype.Timestamptz{Time: created, Valid: true}},
+				{ID: uuidToPg(uuid.New()), Url: "https://blog.example.com/other", Title: pgtype.Text{String: "Something else", Valid: true}},
+				{ID: uuidToPg(urlMatch), Url: "http://www.blog.example.com/post/", CreatedAt: pgtype.Timestamptz{Time: created, Valid: true}},
+			}, nil
+		},
+		createLinkFn: func(ctx context.Context, params db.CreateLinkParams) (db.CreateLinkRow, error) {
+			return db.CreateLinkRow{ID: params.ID, UserID: params.UserID, Url: params.Url}, nil
+		},
+	}
+	publisher := &stubPublisher{}
+	srv := &Server{cfg: cfg, queries: queries, publisher: publisher, metrics: newTestMetrics()}
+	e := echo.New()
+	srv.RegisterRoutes(e)
+
+	body := `{"url":"https://blog.example.com/post?utm_source=x","title":"The memory model, explained"}`
+	req := httptest.NewRequest(http.MethodPost, "/api/links", strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusCreated, rec.Code, rec.Body.String())
+	}
+	if captured.Host != "blog.example.com" || strings.Join(captured.TitleWords, ",") != "memory,model,explained" {
+		t.Fatalf("unexpected candidate lookup %+v", captured)
+	}
+	var resp struct {
+		ID           string                `json:"id"`
+		SimilarLinks []similarLinkResponse `json:"similar_links"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if len(resp.SimilarLinks) != 2 {
+		t.Fatalf("expected 2 similar links, got %+v", resp.SimilarLinks)
+	}
+	if first := resp.SimilarLinks[0]; first.ID != urlMatch.String() || first.Reason != similarReasonURL {
+		t.Fatalf("expected the url match first, got %+v", first)
+	}
+	if second := resp.SimilarLinks[1]; second.ID != titleMatch.String() || second.Reason != similarReasonTitle || second.Score != 1 {
+		t.Fatalf("expected the title match second, got %+v", second)
+	}
+	if !publisher.called {
+		t.Fatalf("expected the link to be created despite the warning")
+	}
+}
+
+func TestHandleCreateLinkSimilarLookupFailure(t *testing.T) {
+	t.Parallel()
+
+	cfg := config.Config{DevUserID: uuid.MustParse("bcbcbcbc-bcbc-bcbc-bcbc-bcbcbcbcbcbc")}
+	queries := &mockQueries{
+		createLinkFn: func(ctx context.Context, params db.CreateLinkParams) (db.CreateLinkRow, error) {
+			return db.CreateLinkRow{ID: params.ID, UserID: params.UserID, Url: params.Url}, nil
+		},
+	}
+	srv := &Server{cfg: cfg, queries: queries, publisher: &stubPublisher{}, metrics: newTestMetrics()}
+	e := echo.New()
+	srv.RegisterRoutes(e)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/links", strings.NewReader(`{"url":"https://example.com"}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("expected status %d, got %d", http.StatusCreated, rec.Code)
+	}
+	if !strings.Contains(rec.Body.String(), `"similar_links":[]`) {
+		t.Fatalf("expected an empty similar_links array, got %s", rec.Body.String())
+	}
+}
@@ -0,0 +1,194 @@
+package httpapi
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	stdhttp "net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/labstack/echo/v4"
+)
+
+// streamBatchSize is how many links each keyset query reads. Every batch is
+// its own short statement, so a slow client never holds a connection or a
+// snapshot open for the length of the stream.
+const streamBatchSize = 500
+
+// streamLinksQuery pages through a user's links in export order, resuming
+// after the (created_at, id) of the previous batch's last row.
+const streamLinksQuery = `-- name: StreamLinks :many
+SELECT
+    l.id,
+    l.url,
+    COALESCE(l.title, ''),
+    COALESCE(l.source_domain, ''),
+    l.favorite,
+    l.created_at,
+    l.read_at,
+    COALESCE(a.title, ''),
+    COALESCE(a.byline, ''),
+    COALESCE(a.lang, ''),
+    COALESCE(a.word_count, 0),
+    CASE WHEN $2::boolean THEN COALESCE(a.extracted_text, '') ELSE '' END,
+    COALESCE((
+        SELECT array_agg(t.name ORDER BY t.name)
+        FROM link_tags lt
+        JOIN tags t ON t.id = lt.tag_id
+        WHERE lt.link_id = l.id
+    ), '{}')::text[]
+FROM links l
+LEFT JOIN archives a ON a.link_id = l.id
+WHERE l.user_id = $1
+  AND ($3::timestamptz IS NULL OR (l.created_at, l.id) > ($3::timestamptz, $4::uuid))
+ORDER BY l.created_at, l.id
+LIMIT $5`
+
+// streamLink is one NDJSON line: an exported link and the cursor that
+// resumes the stream after it.
+type streamLink struct {
+	exportLink
+	Cursor string `json:"cursor"`
+}
+
+// streamCursor marks the last link sent using the (created_at, id) ordering
+// of streamLinksQuery.
+type streamCursor struct {
+	createdAt time.Time
+	linkID    uuid.UUID
+}
+
+func encodeStreamCursor(cursor streamCursor) string {
+	raw := fmt.Sprintf("%s|%s", cursor.createdAt.UTC().Format(time.RFC3339Nano), cursor.linkID)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func decodeStreamCursor(value string) (streamCursor, error) {
+	decoded, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return streamCursor{}, err
+	}
+	createdPart, idPart, ok := strings.Cut(string(decoded), "|")
+	if !ok {
+		return streamCursor{}, errors.New("malformed cursor")
+	}
+	createdAt, err := time.Parse(time.RFC3339Nano, createdPart)
+	if err != nil {
+		return streamCursor{}, fmt.Errorf("parse cursor timestamp: %w", err)
+	}
+	linkID, err := uuid.Parse(idPart)
+	if err != nil {
+		return streamCursor{}, fmt.Errorf("parse cursor link id: %w", err)
+	}
+	return streamCursor{createdAt: createdAt, linkID: linkID}, nil
+}
+
+// handleStreamLinks writes every link the user saved as newline-delimited
+// JSON, oldest first. The server pages through the library itself, so a
+// client reads 100k links from one response; each line carries a cursor, and
+// a client whose connection drops resumes by passing the last one it read as
+// after.
+func (s *Server) handleStreamLinks(c echo.Context) error {
+	if s.pool == nil {
+		return respondError(c, stdhttp.StatusServiceUnavailable, "link stream is unavailable in memory mode")
+	}
+
+	includeText := false
+	if raw := c.QueryParam("include_text"); raw != "" {
+		parsed, err := strconv.ParseBool(raw)
+		if err != nil {
+			return respondError(c, stdhttp.StatusBadRequest, "include_text must be a boolean")
+		}
+		includeText = parsed
+	}
+
+	var after *streamCursor
+	if raw := strings.TrimSpace(c.QueryParam("after")); raw != "" {
+		cursor, err := decodeStreamCursor(raw)
+		if err != nil {
+			return respondError(c, stdhttp.StatusBadRequest, "invalid cursor")
+		}
+		after = &cursor
+	}
+
+	ctx := c.Request().Context()
+	userID := uuidToPg(s.userID(ctx))
+	fetch := func(after *streamCursor) ([]exportLink, error) {
+		var createdAt pgtype.Timestamptz
+		var linkID pgtype.UUID
+		if after != nil {
+			createdAt = pgtype.Timestamptz{Time: after.createdAt, Valid: true}
+			linkID = uuidToPg(after.linkID)
+		}
+		rows, err := s.pool.Query(ctx, streamLinksQuery, userID, includeText, createdAt, linkID, streamBatchSize)
+		if err != nil {
+			return nil, err
+		}
+		defer rows.Close()
+
+		links := make([]exportLink, 0, streamBatchSize)
+		for rows.Next() {
+			link, err := scanExportLink(rows)
+			if err != nil {
+				return nil, err
+			}
+			links = append(links, link)
+		}
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+		return links, nil
+	}
+
+	// The first batch is read before the status is sent so a failing query
+	// still gets a 500.
+	batch, err := fetch(after)
+	if err != nil {
+		s.metrics.LinkStreamFailure.Inc()
+		c.Logger().Errorf("stream links: %v", err)
+		return respondError(c, stdhttp.StatusInternalServerError, "failed to stream links")
+	}
+
+	res := c.Response()
+	res.Header().Set(echo.HeaderContentType, "application/x-ndjson")
+	res.Header().Set("Cache-Control", "no-store")
+	res.WriteHeader(stdhttp.StatusOK)
+
+	// The status is already sent, so failures from here on can only be
+	// logged; the client resumes from the last cursor it read.
+	fail := func(err error) error {
+		s.metrics.LinkStreamFailure.Inc()
+		c.Logger().Errorf("stream links: %v", err)
+		return nil
+	}
+
+	enc := json.NewEncoder(res)
+	for {
+		for _, link := range batch {
+			linkID, err := uuid.Parse(link.ID)
+			if err != nil {
+				return fail(err)
+			}
+			cursor := streamCursor{createdAt: link.CreatedAt, linkID: linkID}
+			if err := enc.Encode(streamLink{exportLink: link, Cursor: encodeStreamCursor(cursor)}); err != nil {
+				return fail(err)
+			}
+			after = &cursor
+		}
+		res.Flush()
+		if len(batch) < streamBatchSize {
+			break
+		}
+		if batch, err = fetch(after); err != nil {
+			return fail(err)
+		}
+	}
+
+	s.metrics.LinkStreamSuccess.Inc()
+	return nil
+}
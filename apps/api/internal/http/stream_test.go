@@ -0,0 +1,174 @@
+package httpapi
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/labstack/echo/v4"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"github.com/example/keepstack/apps/api/internal/config"
+)
+
+// streamPool pages through rows the way streamLinksQuery would, honouring
+// the cursor and limit arguments.
+type streamPool struct {
+	stubHealthPool
+	rows    [][]any
+	err     error
+	queries int
+}
+
+func (p *streamPool) Query(ctx context.Context, query string, args ...any) (pgx.Rows, error) {
+	p.queries++
+	if p.err != nil {
+		return nil, p.err
+	}
+	start := 0
+	if after := args[3].(pgtype.UUID); after.Valid {
+		for i, row := range p.rows {
+			if row[0].(pgtype.UUID) == after {
+				start = i + 1
+				break
+			}
+		}
+	}
+	end := min(start+args[4].(int), len(p.rows))
+	return &exportRows{rows: p.rows[start:end], index: -1}, nil
+}
+
+func streamRows(n int) [][]any {
+	base := time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC)
+	rows := make([][]any, n)
+	for i := range rows {
+		created := pgtype.Timestamptz{Time: base.Add(time.Duration(i) * time.Second), Valid: true}
+		rows[i] = []any{
+			pgtype.UUID{Bytes: uuid.New(), Valid: true},
+			"https://example.com/" + uuid.NewString(), "Title", "example.com", false, created, pgtype.Timestamptz{},
+			"", "", "", int32(0), "", []string(nil),
+		}
+	}
+	return rows
+}
+
+func readStream(t *testing.T, rec *httptest.ResponseRecorder) []streamLink {
+	t.Helper()
+	var links []streamLink
+	scanner := bufio.NewScanner(rec.Body)
+	for scanner.Scan() {
+		var link streamLink
+		if err := json.Unmarshal(scanner.Bytes(), &link); err != nil {
+			t.Fatalf("decode line %d: %v", len(links)+1, err)
+		}
+		links = append(links, link)
+	}
+	return links
+}
+
+func TestHandleStreamLinks(t *testing.T) {
+	t.Parallel()
+
+	t.Run("pages through the library", func(t *testing.T) {
+		t.Parallel()
+
+		rows := streamRows(streamBatchSize*2 + 3)
+		pool := &streamPool{rows: rows}
+		metrics := newTestMetrics()
+		srv := &Server{cfg: config.Config{}, pool: pool, metrics: metrics}
+		e := echo.New()
+		srv.RegisterRoutes(e)
+
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/links/stream", nil))
+
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected status %d, got %d", http.StatusOK, rec.Code)
+		}
+		if got := rec.Header().Get(echo.HeaderContentType); got != "application/x-ndjson" {
+			t.Fatalf("unexpected content type %q", got)
+		}
+		links := readStream(t, rec)
+		if len(links) != len(rows) {
+			t.Fatalf("expected %d links, got %d", len(rows), len(links))
+		}
+		if pool.queries != 3 {
+			t.Fatalf("expected 3 batches, got %d", pool.queries)
+		}
+		for i, link := range links {
+			if link.ID != uuidFromPg(rows[i][0].(pgtype.UUID)).String() {
+				t.Fatalf("link %d out of order", i)
+			}
+			if link.Tags == nil || link.Cursor == "" {
+				t.Fatalf("link %d missing tags or cursor: %+v", i, link)
+			}
+		}
+		if got := testutil.ToFloat64(metrics.LinkStreamSuccess); got != 1 {
+			t.Fatalf("expected 1 completed stream, got %v", got)
+		}
+
+		// Resuming from a line's cursor sends everything after it.
+		resume := links[len(links)-5]
+		rec = httptest.NewRecorder()
+		e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/links/stream?after="+resume.Cursor, nil))
+		rest := readStream(t, rec)
+		if len(rest) != 4 || rest[0].ID != links[len(links)-4].ID {
+			t.Fatalf("expected the last 4 links after resuming, got %d", len(rest))
+		}
+	})
+
+	t.Run("invalid cursor", func(t *testing.T) {
+		t.Parallel()
+
+		srv := &Server{cfg: config.Config{}, pool: &streamPool{}, metrics: newTestMetrics()}
+		e := echo.New()
+		srv.RegisterRoutes(e)
+
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/links/stream?after=not-a-cursor", nil))
+
+		if rec.Code != http.StatusBadRequest {
+			t.Fatalf("expected status %d, got %d", http.StatusBadRequest, rec.Code)
+		}
+	})
+
+	t.Run("query failure before the first line", func(t *testing.T) {
+		t.Parallel()
+
+		metrics := newTestMetrics()
+		srv := &Server{cfg: config.Config{}, pool: &streamPool{err: errors.New("boom")}, metrics: metrics}
+		e := echo.New()
+		srv.RegisterRoutes(e)
+
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/links/stream", nil))
+
+		if rec.Code != http.StatusInternalServerError {
+			t.Fatalf("expected status %d, got %d", http.StatusInternalServerError, rec.Code)
+		}
+		if got := testutil.ToFloat64(metrics.LinkStreamFailure); got != 1 {
+			t.Fatalf("expected 1 failed stream, got %v", got)
+		}
+	})
+}
+
+func TestStreamCursorRoundTrip(t *testing.T) {
+	t.Parallel()
+
+	want := streamCursor{createdAt: time.Date(2024, 3, 1, 9, 0, 0, 123456000, time.UTC), linkID: uuid.New()}
+	got, err := decodeStreamCursor(encodeStreamCursor(want))
+	if err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if !got.createdAt.Equal(want.createdAt) || got.linkID != want.linkID {
+		t.Fatalf("expected %+v, got %+v", want, got)
+	}
+}
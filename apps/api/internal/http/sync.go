@@ -0,0 +1,588 @@
+package httpapi
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	stdhttp "net/http"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgerrcode"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/labstack/echo/v4"
+
+	"github.com/example/keepstack/apps/api/internal/db"
+)
+
+const (
+	syncEntityLink      = "link"
+	syncEntityHighlight = "highlight"
+
+	defaultSyncPageSize = 500
+	maxSyncPageSize     = 1000
+	// maxSyncPushChanges caps one push; clients with more queued changes
+	// send them in several requests.
+	maxSyncPushChanges = 500
+)
+
+// Reasons a pushed change was not applied.
+const (
+	// syncConflictServerChanged means the server changed or deleted the
+	// entity after the client's cursor. The server copy wins and is returned.
+	syncConflictServerChanged = "server_changed"
+	// syncConflictDuplicateURL means a link the client created offline is
+	// already saved under another id. The server copy is returned so the
+	// client can merge its local record into it.
+	syncConflictDuplicateURL = "duplicate_url"
+	syncConflictInvalid      = "invalid"
+)
+
+// syncCursor is a position in the sync_changes log. Clients treat it as
+// opaque; the zero cursor is the start of the log.
+type syncCursor struct {
+	tx  int64
+	seq int64
+}
+
+func parseSyncCursor(raw string) (syncCursor, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return syncCursor{}, nil
+	}
+	txPart, seqPart, ok := strings.Cut(raw, "-")
+	if !ok {
+		return syncCursor{}, errors.New("invalid cursor")
+	}
+	tx, err := strconv.ParseInt(txPart, 10, 64)
+	if err != nil || tx < 0 {
+		return syncCursor{}, errors.New("invalid cursor")
+	}
+	seq, err := strconv.ParseInt(seqPart, 10, 64)
+	if err != nil || seq < 0 {
+		return syncCursor{}, errors.New("invalid cursor")
+	}
+	return syncCursor{tx: tx, seq: seq}, nil
+}
+
+func (c syncCursor) String() string {
+	return fmt.Sprintf("%d-%d", c.tx, c.seq)
+}
+
+// syncLinkData is a link as sync clients see it. Pushes send the full record;
+// url is only read when the link is created.
+type syncLinkData struct {
+	URL       string     `json:"url"`
+	Title     *string    `json:"title"`
+	Favorite  bool       `json:"favorite"`
+	ReadAt    *time.Time `json:"read_at"`
+	Tags      []string   `json:"tags"`
+	CreatedAt *time.Time `json:"created_at,omitempty"`
+	UpdatedAt *time.Time `json:"updated_at,omitempty"`
+}
+
+// syncHighlightData is a highlight as sync clients see it. link_id is only
+// read when the highlight is created.
+type syncHighlightData struct {
+	LinkID    string     `json:"link_id"`
+	Text      string     `json:"text"`
+	Note      *string    `json:"note"`
+	CreatedAt *time.Time `json:"created_at,omitempty"`
+	UpdatedAt *time.Time `json:"updated_at,omitempty"`
+}
+
+// syncChange is one entity in a pull response, or the server copy in a
+// conflict. Deleted changes are tombstones and carry no data.
+type syncChange struct {
+	Entity  string `json:"entity"`
+	ID      string `json:"id"`
+	Deleted bool   `json:"deleted"`
+	Data    any    `json:"data,omitempty"`
+}
+
+type syncPullResponse struct {
+	Changes []syncChange `json:"changes"`
+	Cursor  string       `json:"cursor"`
+	HasMore bool         `json:"has_more"`
+}
+
+type syncPushChange struct {
+	Entity  string          `json:"entity"`
+	ID      string          `json:"id"`
+	Deleted bool            `json:"deleted"`
+	Data    json.RawMessage `json:"data"`
+}
+
+type syncPushRequest struct {
+	Cursor  string           `json:"cursor"`
+	Changes []syncPushChange `json:"changes"`
+}
+
+type syncConflict struct {
+	Entity string      `json:"entity"`
+	ID     string      `json:"id"`
+	Reason string      `json:"reason"`
+	Error  string      `json:"error,omitempty"`
+	Server *syncChange `json:"server,omitempty"`
+}
+
+type syncPushResponse struct {
+	Applied   int            `json:"applied"`
+	Conflicts []syncConflict `json:"conflicts"`
+}
+
+func (s *Server) registerSyncRoutes(api *echo.Group) {
+	api.GET("/sync/changes", s.handleSyncPull)
+	api.POST("/sync/changes", s.handleSyncPush)
+}
+
+// handleSyncPull returns the links and highlights that changed after cursor,
+// oldest first, in their current state. An entity that changed several times
+// within a page appears once, and one that no longer exists appears as a
+// tombstone. Clients repeat the call with the returned cursor until has_more
+// is false.
+func (s *Server) handleSyncPull(c echo.Context) error {
+	cursor, err := parseSyncCursor(c.QueryParam("cursor"))
+	if err != nil {
+		return respondError(c, stdhttp.StatusBadRequest, err.Error())
+	}
+	limit := defaultSyncPageSize
+	if raw := strings.TrimSpace(c.QueryParam("limit")); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed <= 0 {
+			return respondError(c, stdhttp.StatusBadRequest, "limit must be a positive integer")
+		}
+		limit = min(parsed, maxSyncPageSize)
+	}
+
+	ctx := c.Request().Context()
+	rows, err := s.queries.ListSyncChanges(ctx, db.ListSyncChangesParams{
+		UserID:    uuidToPg(s.userID(ctx)),
+		CursorTx:  cursor.tx,
+		CursorSeq: cursor.seq,
+		PageLimit: int32(limit),
+	})
+	if err != nil {
+		c.Logger().Errorf("sync pull: list changes failed: %v", err)
+		return respondError(c, stdhttp.StatusInternalServerError, "failed to list changes")
+	}
+
+	next := cursor
+	last := make(map[string]int, len(rows))
+	var linkIDs, highlightIDs []pgtype.UUID
+	for i, row := range rows {
+		next = syncCursor{tx: row.TxID, seq: row.Seq}
+		key := syncKey(row.Entity, uuidFromPg(row.EntityID))
+		if _, seen := last[key]; !seen {
+			switch row.Entity {
+			case syncEntityLink:
+				linkIDs = append(linkIDs, row.EntityID)
+			case syncEntityHighlight:
+				highlightIDs = append(highlightIDs, row.EntityID)
+			}
+		}
+		last[key] = i
+	}
+
+	current, err := s.loadSyncEntities(ctx, linkIDs, highlightIDs)
+	if err != nil {
+		c.Logger().Errorf("sync pull: load entities failed: %v", err)
+		return respondError(c, stdhttp.StatusInternalServerError, "failed to load changes")
+	}
+
+	changes := make([]syncChange, 0, len(last))
+	for i, row := range rows {
+		id := uuidFromPg(row.EntityID)
+		key := syncKey(row.Entity, id)
+		if last[key] != i {
+			continue
+		}
+		change, ok := current[key]
+		if !ok {
+			change = syncChange{Entity: row.Entity, ID: id.String(), Deleted: true}
+		}
+		changes = append(changes, change)
+	}
+
+	return c.JSON(stdhttp.StatusOK, syncPullResponse{
+		Changes: changes,
+		Cursor:  next.String(),
+		HasMore: len(rows) == limit,
+	})
+}
+
+// handleSyncPush applies changes a client made since it last pulled at
+// cursor. Conflicts are resolved per entity:
+//
+//   - a deletion always wins, whatever happened on the server;
+//   - otherwise, if the server changed the entity after cursor, the server
+//     copy wins and is returned in the conflict;
+//   - a link created offline whose URL is already saved is not duplicated,
+//     and the existing link is returned instead.
+//
+// Changes are applied in order and the rest of the batch continues past a
+// conflict. Clients pull afterwards to pick up their own changes and the
+// server's winners.
+func (s *Server) handleSyncPush(c echo.Context) error {
+	var req syncPushRequest
+	if err := c.Bind(&req); err != nil {
+		return respondBindError(c, err)
+	}
+	cursor, err := parseSyncCursor(req.Cursor)
+	if err != nil {
+		return respondError(c, stdhttp.StatusBadRequest, err.Error())
+	}
+	if len(req.Changes) > maxSyncPushChanges {
+		return respondError(c, stdhttp.StatusRequestEntityTooLarge, fmt.Sprintf("push at most %d changes per request", maxSyncPushChanges))
+	}
+
+	resp := syncPushResponse{Conflicts: []syncConflict{}}
+	// An entity changed twice in one push must not conflict with itself.
+	pushed := make(map[string]struct{}, len(req.Changes))
+	for _, change := range req.Changes {
+		conflict, err := s.applySyncChange(c, cursor, change, pushed)
+		if err != nil {
+			c.Logger().Errorf("sync push: apply %s %s failed: %v", change.Entity, change.ID, err)
+			return respondError(c, stdhttp.StatusInternalServerError, "failed to apply changes")
+		}
+		if conflict != nil {
+			resp.Conflicts = append(resp.Conflicts, *conflict)
+			continue
+		}
+		resp.Applied++
+	}
+	return c.JSON(stdhttp.StatusOK, resp)
+}
+
+// applySyncChange applies one pushed change, or reports why it was not
+// applied. Errors are reserved for storage failures.
+func (s *Server) applySyncChange(c echo.Context, cursor syncCursor, change syncPushChange, pushed map[string]struct{}) (*syncConflict, error) {
+	invalid := func(message string) *syncConflict {
+		return &syncConflict{Entity: change.Entity, ID: change.ID, Reason: syncConflictInvalid, Error: message}
+	}
+	if change.Entity != syncEntityLink && change.Entity != syncEntityHighlight {
+		return invalid("entity must be link or highlight"), nil
+	}
+	id, err := parseUUIDParam(change.ID)
+	if err != nil {
+		return invalid("invalid id"), nil
+	}
+
+	ctx := c.Request().Context()
+	key := syncKey(change.Entity, id)
+	if change.Deleted {
+		pushed[key] = struct{}{}
+		return nil, s.deleteSyncEntity(c, change.Entity, id)
+	}
+
+	if _, ok := pushed[key]; !ok {
+		_, err := s.queries.GetSyncChangeSince(ctx, db.GetSyncChangeSinceParams{
+			UserID:    uuidToPg(s.userID(ctx)),
+			Entity:    change.Entity,
+			EntityID:  uuidToPg(id),
+			CursorTx:  cursor.tx,
+			CursorSeq: cursor.seq,
+		})
+		switch {
+		case err == nil:
+			return s.syncConflictWithServer(ctx, change.Entity, id, id, syncConflictServerChanged)
+		case !errors.Is(err, pgx.ErrNoRows):
+			return nil, fmt.Errorf("check server changes: %w", err)
+		}
+	}
+
+	var conflict *syncConflict
+	switch change.Entity {
+	case syncEntityLink:
+		var data syncLinkData
+		if err := json.Unmarshal(change.Data, &data); err != nil {
+			return invalid("data must be a link"), nil
+		}
+		conflict, err = s.upsertSyncLink(c, id, data)
+	case syncEntityHighlight:
+		var data syncHighlightData
+		if err := json.Unmarshal(change.Data, &data); err != nil {
+			return invalid("data must be a highlight"), nil
+		}
+		conflict, err = s.upsertSyncHighlight(ctx, id, data)
+	}
+	if err != nil {
+		var apiErr apiError
+		if errors.As(err, &apiErr) && apiErr.Code < stdhttp.StatusInternalServerError {
+			return invalid(apiErr.Message), nil
+		}
+		return nil, err
+	}
+	if conflict != nil {
+		conflict.Entity, conflict.ID = change.Entity, change.ID
+		return conflict, nil
+	}
+	pushed[key] = struct{}{}
+	return nil, nil
+}
+
+// upsertSyncLink updates a link to match data, creating it under id if it
+// does not exist yet.
+func (s *Server) upsertSyncLink(c echo.Context, id uuid.UUID, data syncLinkData) (*syncConflict, error) {
+	ctx := c.Request().Context()
+	params := db.UpdateSyncLinkParams{
+		Favorite: data.Favorite,
+		ID:       uuidToPg(id),
+		UserID:   uuidToPg(s.userID(ctx)),
+	}
+	if data.Title != nil && strings.TrimSpace(*data.Title) != "" {
+		params.Title = pgtype.Text{String: strings.TrimSpace(*data.Title), Valid: true}
+	}
+	if data.ReadAt != nil {
+		params.ReadAt = pgtype.Timestamptz{Time: *data.ReadAt, Valid: true}
+	}
+
+	updated, err := s.queries.UpdateSyncLink(ctx, params)
+	if err != nil {
+		return nil, fmt.Errorf("update link: %w", err)
+	}
+	if updated > 0 {
+		return nil, s.replaceTagsByName(ctx, id, data.Tags)
+	}
+
+	normalizedURL, err := s.normalizeLinkURL(strings.TrimSpace(data.URL))
+	if err != nil {
+		return nil, apiError{Code: stdhttp.StatusBadRequest, Message: "invalid url"}
+	}
+	existing, err := s.queries.FindLinkByURLHash(ctx, db.FindLinkByURLHashParams{
+		UserID:  uuidToPg(s.userID(ctx)),
+		UrlHash: urlHash(normalizedURL),
+	})
+	switch {
+	case err == nil:
+		return s.syncConflictWithServer(ctx, syncEntityLink, id, uuidFromPg(existing.ID), syncConflictDuplicateURL)
+	case !errors.Is(err, pgx.ErrNoRows):
+		return nil, fmt.Errorf("look up url: %w", err)
+	}
+
+	if _, err := s.queries.CreateLink(ctx, db.CreateLinkParams{
+		ID:       uuidToPg(id),
+		UserID:   uuidToPg(s.userID(ctx)),
+		Url:      normalizedURL,
+		Title:    params.Title,
+		Favorite: data.Favorite,
+	}); err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == pgerrcode.UniqueViolation {
+			return nil, apiError{Code: stdhttp.StatusConflict, Message: "id is already in use"}
+		}
+		return nil, fmt.Errorf("create link: %w", err)
+	}
+	if params.ReadAt.Valid {
+		if _, err := s.queries.UpdateSyncLink(ctx, params); err != nil {
+			return nil, fmt.Errorf("restore read state: %w", err)
+		}
+	}
+	if err := s.replaceTagsByName(ctx, id, data.Tags); err != nil {
+		return nil, err
+	}
+	if err := s.publisher.PublishLinkSaved(ctx, id); err != nil {
+		return nil, fmt.Errorf("publish link saved: %w", err)
+	}
+	s.metrics.LinkCreateSuccess.Inc()
+	return nil, nil
+}
+
+// upsertSyncHighlight updates a highlight's text and note, creating it under
+// id if it does not exist yet.
+func (s *Server) upsertSyncHighlight(ctx context.Context, id uuid.UUID, data syncHighlightData) (*syncConflict, error) {
+	text, note, err := validateHighlightPayload(highlightRequest{Text: data.Text, Note: data.Note})
+	if err != nil {
+		return nil, apiError{Code: stdhttp.StatusBadRequest, Message: err.Error()}
+	}
+	noteText := pgtype.Text{}
+	if note != nil {
+		noteText = pgtype.Text{String: *note, Valid: true}
+	}
+
+	existing, err := s.queries.ListSyncHighlights(ctx, db.ListSyncHighlightsParams{
+		UserID: uuidToPg(s.userID(ctx)),
+		Ids:    []pgtype.UUID{uuidToPg(id)},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("load highlight: %w", err)
+	}
+	if len(existing) > 0 {
+		if _, err := s.queries.UpdateHighlight(ctx, db.UpdateHighlightParams{Text: text, Note: noteText, ID: uuidToPg(id)}); err != nil {
+			return nil, fmt.Errorf("update highlight: %w", err)
+		}
+		return nil, nil
+	}
+
+	linkID, err := parseUUIDParam(data.LinkID)
+	if err != nil {
+		return nil, apiError{Code: stdhttp.StatusBadRequest, Message: "invalid link id"}
+	}
+	link, err := s.ensureLinkAccess(ctx, linkID, linkPermissionOwner)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.queries.CreateHighlight(ctx, db.CreateHighlightParams{
+		ID:     uuidToPg(id),
+		LinkID: link.ID,
+		Text:   text,
+		Note:   noteText,
+	}); err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == pgerrcode.UniqueViolation {
+			return nil, apiError{Code: stdhttp.StatusConflict, Message: "id is already in use"}
+		}
+		return nil, fmt.Errorf("create highlight: %w", err)
+	}
+	return nil, nil
+}
+
+// deleteSyncEntity removes a link or highlight. Deleting one that is already
+// gone succeeds.
+func (s *Server) deleteSyncEntity(c echo.Context, entity string, id uuid.UUID) error {
+	if entity == syncEntityLink {
+		if _, err := s.deleteLink(c, id); err != nil {
+			return err
+		}
+		return nil
+	}
+
+	ctx := c.Request().Context()
+	existing, err := s.queries.ListSyncHighlights(ctx, db.ListSyncHighlightsParams{
+		UserID: uuidToPg(s.userID(ctx)),
+		Ids:    []pgtype.UUID{uuidToPg(id)},
+	})
+	if err != nil {
+		return fmt.Errorf("load highlight: %w", err)
+	}
+	if len(existing) == 0 {
+		return nil
+	}
+	if err := s.queries.DeleteHighlight(ctx, uuidToPg(id)); err != nil {
+		return fmt.Errorf("delete highlight: %w", err)
+	}
+	return nil
+}
+
+// replaceTagsByName makes names the link's full set of tags, creating any
+// that do not exist.
+func (s *Server) replaceTagsByName(ctx context.Context, linkID uuid.UUID, names []string) error {
+	current, err := s.queries.ListTagsForLink(ctx, uuidToPg(linkID))
+	if err != nil {
+		return fmt.Errorf("list tags: %w", err)
+	}
+	wanted := make(map[string]struct{}, len(names))
+	for _, name := range names {
+		if name = strings.TrimSpace(name); name != "" {
+			wanted[name] = struct{}{}
+		}
+	}
+
+	for _, tag := range current {
+		if _, ok := wanted[tag.Name]; ok {
+			delete(wanted, tag.Name)
+			continue
+		}
+		if err := s.queries.RemoveTagFromLink(ctx, db.RemoveTagFromLinkParams{LinkID: uuidToPg(linkID), TagID: tag.ID}); err != nil {
+			return fmt.Errorf("remove tag: %w", err)
+		}
+	}
+	missing := make([]string, 0, len(wanted))
+	for name := range wanted {
+		missing = append(missing, name)
+	}
+	slices.Sort(missing)
+	_, err = s.addTagsByName(ctx, s.userID(ctx), linkID, missing)
+	return err
+}
+
+// syncConflictWithServer reports a conflict on entity id together with the
+// server's current copy of serverID, which is a tombstone if it is gone.
+func (s *Server) syncConflictWithServer(ctx context.Context, entity string, id, serverID uuid.UUID, reason string) (*syncConflict, error) {
+	var linkIDs, highlightIDs []pgtype.UUID
+	if entity == syncEntityLink {
+		linkIDs = []pgtype.UUID{uuidToPg(serverID)}
+	} else {
+		highlightIDs = []pgtype.UUID{uuidToPg(serverID)}
+	}
+	current, err := s.loadSyncEntities(ctx, linkIDs, highlightIDs)
+	if err != nil {
+		return nil, err
+	}
+	server, ok := current[syncKey(entity, serverID)]
+	if !ok {
+		server = syncChange{Entity: entity, ID: serverID.String(), Deleted: true}
+	}
+	return &syncConflict{Entity: entity, ID: id.String(), Reason: reason, Server: &server}, nil
+}
+
+// loadSyncEntities returns the current state of the given links and
+// highlights keyed by syncKey. Missing ones are left out.
+func (s *Server) loadSyncEntities(ctx context.Context, linkIDs, highlightIDs []pgtype.UUID) (map[string]syncChange, error) {
+	entities := make(map[string]syncChange, len(linkIDs)+len(highlightIDs))
+	if len(linkIDs) > 0 {
+		links, err := s.queries.ListSyncLinks(ctx, db.ListSyncLinksParams{UserID: uuidToPg(s.userID(ctx)), Ids: linkIDs})
+		if err != nil {
+			return nil, fmt.Errorf("list links: %w", err)
+		}
+		for _, link := range links {
+			id := uuidFromPg(link.ID)
+			entities[syncKey(syncEntityLink, id)] = syncChange{Entity: syncEntityLink, ID: id.String(), Data: toSyncLinkData(link)}
+		}
+	}
+	if len(highlightIDs) > 0 {
+		highlights, err := s.queries.ListSyncHighlights(ctx, db.ListSyncHighlightsParams{UserID: uuidToPg(s.userID(ctx)), Ids: highlightIDs})
+		if err != nil {
+			return nil, fmt.Errorf("list highlights: %w", err)
+		}
+		for _, highlight := range highlights {
+			id := uuidFromPg(highlight.ID)
+			entities[syncKey(syncEntityHighlight, id)] = syncChange{Entity: syncEntityHighlight, ID: id.String(), Data: toSyncHighlightData(highlight)}
+		}
+	}
+	return entities, nil
+}
+
+func toSyncLinkData(link db.ListSyncLinksRow) syncLinkData {
+	data := syncLinkData{
+		URL:       link.Url,
+		Favorite:  link.Favorite,
+		Tags:      link.TagNames,
+		CreatedAt: &link.CreatedAt.Time,
+		UpdatedAt: &link.UpdatedAt.Time,
+	}
+	if data.Tags == nil {
+		data.Tags = []string{}
+	}
+	if link.Title.Valid {
+		data.Title = &link.Title.String
+	}
+	if link.ReadAt.Valid {
+		data.ReadAt = &link.ReadAt.Time
+	}
+	return data
+}
+
+func toSyncHighlightData(highlight db.Highlight) syncHighlightData {
+	data := syncHighlightData{
+		LinkID:    uuidFromPg(highlight.LinkID).String(),
+		Text:      highlight.Quote,
+		CreatedAt: &highlight.CreatedAt.Time,
+		UpdatedAt: &highlight.UpdatedAt.Time,
+	}
+	if highlight.Annotation.Valid {
+		data.Note = &highlight.Annotation.String
+	}
+	return data
+}
+
+func syncKey(entity string, id uuid.UUID) string {
+	return entity + ":" + id.String()
+}
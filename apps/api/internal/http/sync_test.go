@@ -0,0 +1,282 @@
+package httpapi
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/labstack/echo/v4"
+
+	"github.com/example/keepstack/apps/api/internal/db"
+)
+
+func TestSyncPullReturnsLatestStateAndTombstones(t *testing.T) {
+	t.Parallel()
+
+	linkA := uuid.New()
+	linkB := uuid.New()
+	highlight := uuid.New()
+	now := time.Now().UTC().Truncate(time.Second)
+
+	var gotCursor db.ListSyncChangesParams
+	queries := &mockQueries{
+		listSyncChangesFn: func(ctx context.Context, arg db.ListSyncChangesParams) ([]db.ListSyncChangesRow, error) {
+			gotCursor = arg
+			return []db.ListSyncChangesRow{
+				{Seq: 11, TxID: 900, Entity: syncEntityLink, EntityID: uuidToPg(linkA)},
+				{Seq: 12, TxID: 900, Entity: syncEntityHighlight, EntityID: uuidToPg(highlight)},
+				{Seq: 10, TxID: 901, Entity: syncEntityLink, EntityID: uuidToPg(linkA)},
+				{Seq: 14, TxID: 902, Entity: syncEntityLink, EntityID: uuidToPg(linkB)},
+			}, nil
+		},
+		listSyncLinksFn: func(ctx context.Context, arg db.ListSyncLinksParams) ([]db.ListSyncLinksRow, error) {
+			if len(arg.Ids) != 2 {
+				t.Fatalf("expected each link to be loaded once, got %d ids", len(arg.Ids))
+			}
+			return []db.ListSyncLinksRow{{
+				ID:        uuidToPg(linkA),
+				Url:       "https://example.com/a",
+				Title:     pgtype.Text{String: "A", Valid: true},
+				Favorite:  true,
+				CreatedAt: pgtype.Timestamptz{Time: now, Valid: true},
+				UpdatedAt: pgtype.Timestamptz{Time: now, Valid: true},
+				TagNames:  []string{"go"},
+			}}, nil
+		},
+		listSyncHighlightsFn: func(ctx context.Context, arg db.ListSyncHighlightsParams) ([]db.Highlight, error) {
+			return []db.Highlight{{ID: uuidToPg(highlight), LinkID: uuidToPg(linkA), Quote: "quoted"}}, nil
+		},
+	}
+	e := newRouteTestServer(queries, &stubPublisher{})
+
+	req := httptest.NewRequest(http.MethodGet, "/api/sync/changes?cursor=899-40&limit=4", nil)
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+	}
+	if gotCursor.CursorTx != 899 || gotCursor.CursorSeq != 40 || gotCursor.PageLimit != 4 {
+		t.Fatalf("unexpected query params: %+v", gotCursor)
+	}
+
+	var resp struct {
+		Changes []struct {
+			Entity  string          `json:"entity"`
+			ID      string          `json:"id"`
+			Deleted bool            `json:"deleted"`
+			Data    json.RawMessage `json:"data"`
+		} `json:"changes"`
+		Cursor  string `json:"cursor"`
+		HasMore bool   `json:"has_more"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if resp.Cursor != "902-14" || !resp.HasMore {
+		t.Fatalf("expected cursor 902-14 with more pages, got %q has_more=%v", resp.Cursor, resp.HasMore)
+	}
+	if len(resp.Changes) != 3 {
+		t.Fatalf("expected 3 changes, got %d", len(resp.Changes))
+	}
+	if resp.Changes[0].ID != highlight.String() || resp.Changes[1].ID != linkA.String() {
+		t.Fatalf("expected changes ordered by their latest entry, got %+v", resp.Changes)
+	}
+	var link syncLinkData
+	if err := json.Unmarshal(resp.Changes[1].Data, &link); err != nil {
+		t.Fatalf("decode link: %v", err)
+	}
+	if link.URL != "https://example.com/a" || !link.Favorite || len(link.Tags) != 1 {
+		t.Fatalf("unexpected link data: %+v", link)
+	}
+	if tomb := resp.Changes[2]; tomb.ID != linkB.String() || !tomb.Deleted || tomb.Data != nil {
+		t.Fatalf("expected a tombstone for the missing link, got %+v", tomb)
+	}
+}
+
+func TestSyncPullRejectsInvalidCursor(t *testing.T) {
+	t.Parallel()
+
+	e := newRouteTestServer(&mockQueries{}, &stubPublisher{})
+	for _, cursor := range []string{"abc", "12", "-1-3"} {
+		req := httptest.NewRequest(http.MethodGet, "/api/sync/changes?cursor="+cursor, nil)
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		if rec.Code != http.StatusBadRequest {
+			t.Fatalf("cursor %q: expected status %d, got %d", cursor, http.StatusBadRequest, rec.Code)
+		}
+	}
+}
+
+func TestSyncPushAppliesConflictRules(t *testing.T) {
+	t.Parallel()
+
+	clean := uuid.New()
+	changedOnServer := uuid.New()
+	deletedHighlight := uuid.New()
+	offline := uuid.New()
+	existing := uuid.New()
+
+	var updated []uuid.UUID
+	var removedTags, deletedHighlights int
+	queries := &mockQueries{
+		getSyncChangeSinceFn: func(ctx context.Context, arg db.GetSyncChangeSinceParams) (bool, error) {
+			if arg.CursorTx != 5 || arg.CursorSeq != 7 {
+				t.Fatalf("unexpected cursor: %+v", arg)
+			}
+			if uuidFromPg(arg.EntityID) == changedOnServer {
+				return false, nil
+			}
+			return false, pgx.ErrNoRows
+		},
+		updateSyncLinkFn: func(ctx context.Context, arg db.UpdateSyncLinkParams) (int64, error) {
+			id := uuidFromPg(arg.ID)
+			if id == offline {
+				return 0, nil
+			}
+			updated = append(updated, id)
+			if !arg.Favorite || arg.Title.String != "Renamed" || arg.ReadAt.Valid {
+				t.Fatalf("unexpected update: %+v", arg)
+			}
+			return 1, nil
+		},
+		listTagsForLinkFn: func(ctx context.Context, id pgtype.UUID) ([]db.Tag, error) {
+			return []db.Tag{{ID: 1, Name: "keep"}, {ID: 2, Name: "drop"}}, nil
+		},
+		removeTagFromLinkFn: func(ctx context.Context, params db.RemoveTagFromLinkParams) error {
+			if params.TagID != 2 {
+				t.Fatalf("expected only the dropped tag to be removed, got %d", params.TagID)
+			}
+			removedTags++
+			return nil
+		},
+		listSyncLinksFn: func(ctx context.Context, arg db.ListSyncLinksParams) ([]db.ListSyncLinksRow, error) {
+			return []db.ListSyncLinksRow{{ID: arg.Ids[0], Url: "https://example.com/server"}}, nil
+		},
+		listSyncHighlightsFn: func(ctx context.Context, arg db.ListSyncHighlightsParams) ([]db.Highlight, error) {
+			return []db.Highlight{{ID: arg.Ids[0]}}, nil
+		},
+		deleteHighlightFn: func(ctx context.Context, id pgtype.UUID) error {
+			deletedHighlights++
+			return nil
+		},
+		findLinkByURLHashFn: func(ctx context.Context, arg db.FindLinkByURLHashParams) (db.FindLinkByURLHashRow, error) {
+			return db.FindLinkByURLHashRow{ID: uuidToPg(existing)}, nil
+		},
+	}
+	publisher := &stubPublisher{}
+	e := newRouteTestServer(queries, publisher)
+
+	body := `{"cursor":"5-7","changes":[
+		{"entity":"link","id":"` + clean.String() + `","data":{"title":"Renamed","favorite":true,"tags":["keep"]}},
+		{"entity":"link","id":"` + changedOnServer.String() + `","data":{"title":"Renamed","favorite":true}},
+		{"entity":"highlight","id":"` + deletedHighlight.String() + `","deleted":true},
+		{"entity":"link","id":"` + offline.String() + `","data":{"url":"https://example.com/server"}},
+		{"entity":"tag","id":"` + uuid.NewString() + `"}
+	]}`
+	req := httptest.NewRequest(http.MethodPost, "/api/sync/changes", strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+	}
+
+	var resp syncPushResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if resp.Applied != 2 || len(resp.Conflicts) != 3 {
+		t.Fatalf("expected 2 applied and 3 conflicts, got %+v", resp)
+	}
+	if len(updated) != 1 || updated[0] != clean || removedTags != 1 || deletedHighlights != 1 {
+		t.Fatalf("unexpected writes: updated=%v removedTags=%d deletedHighlights=%d", updated, removedTags, deletedHighlights)
+	}
+
+	changed := resp.Conflicts[0]
+	if changed.ID != changedOnServer.String() || changed.Reason != syncConflictServerChanged || changed.Server == nil || changed.Server.Deleted {
+		t.Fatalf("expected the server copy to win, got %+v", changed)
+	}
+	duplicate := resp.Conflicts[1]
+	if duplicate.ID != offline.String() || duplicate.Reason != syncConflictDuplicateURL || duplicate.Server.ID != existing.String() {
+		t.Fatalf("expected the offline link to map to the saved one, got %+v", duplicate)
+	}
+	if resp.Conflicts[2].Reason != syncConflictInvalid {
+		t.Fatalf("expected an unknown entity to be rejected, got %+v", resp.Conflicts[2])
+	}
+	if publisher.called {
+		t.Fatalf("expected no link to be queued")
+	}
+}
+
+func TestSyncPushCreatesLinkWithClientID(t *testing.T) {
+	t.Parallel()
+
+	id := uuid.New()
+	readAt := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	var updates int
+	var created db.CreateLinkParams
+	var tagged []int32
+	queries := &mockQueries{
+		getSyncChangeSinceFn: func(ctx context.Context, arg db.GetSyncChangeSinceParams) (bool, error) {
+			return false, pgx.ErrNoRows
+		},
+		updateSyncLinkFn: func(ctx context.Context, arg db.UpdateSyncLinkParams) (int64, error) {
+			updates++
+			if updates == 1 {
+				return 0, nil
+			}
+			if !arg.ReadAt.Valid || !arg.ReadAt.Time.Equal(readAt) {
+				t.Fatalf("expected read state to be restored, got %+v", arg.ReadAt)
+			}
+			return 1, nil
+		},
+		findLinkByURLHashFn: func(ctx context.Context, arg db.FindLinkByURLHashParams) (db.FindLinkByURLHashRow, error) {
+			return db.FindLinkByURLHashRow{}, pgx.ErrNoRows
+		},
+		createLinkFn: func(ctx context.Context, params db.CreateLinkParams) (db.CreateLinkRow, error) {
+			created = params
+			return db.CreateLinkRow{ID: params.ID}, nil
+		},
+		listTagsForLinkFn: func(ctx context.Context, id pgtype.UUID) ([]db.Tag, error) {
+			return nil, nil
+		},
+		getTagByNameFn: func(ctx context.Context, arg db.GetTagByNameParams) (db.Tag, error) {
+			return db.Tag{ID: 3, Name: arg.Name}, nil
+		},
+		addTagToLinkFn: func(ctx context.Context, params db.AddTagToLinkParams) error {
+			tagged = append(tagged, params.TagID)
+			return nil
+		},
+	}
+	publisher := &stubPublisher{}
+	e := newRouteTestServer(queries, publisher)
+
+	body := `{"changes":[{"entity":"link","id":"` + id.String() + `","data":{"url":"example.com/offline","favorite":true,"read_at":"2026-03-01T12:00:00Z","tags":["later"]}}]}`
+	req := httptest.NewRequest(http.MethodPost, "/api/sync/changes", strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+	}
+	if !strings.Contains(rec.Body.String(), `"applied":1`) {
+		t.Fatalf("expected the link to be applied, got %s", rec.Body.String())
+	}
+	if uuidFromPg(created.ID) != id || created.Url != "https://example.com/offline" || created.Favorite != true {
+		t.Fatalf("unexpected create params: %+v", created)
+	}
+	if updates != 2 || len(tagged) != 1 {
+		t.Fatalf("expected read state and tags to be restored, got %d updates and tags %v", updates, tagged)
+	}
+	if !publisher.called || publisher.lastID != id {
+		t.Fatalf("expected the new link to be queued for the worker")
+	}
+}
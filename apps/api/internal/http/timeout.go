@@ -0,0 +1,61 @@
+package httpapi
+
+import (
+	"context"
+	"errors"
+	stdhttp "net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/example/keepstack/apps/api/internal/observability"
+)
+
+// untimedRoutes stream until the client goes away, so a request deadline
+// would cut them off mid-response.
+var untimedRoutes = map[string]bool{
+	"/api/events":       true,
+	"/api/links/export": true,
+	"/api/links/stream": true,
+}
+
+// RequestTimeoutMiddleware bounds each request's context by timeout, so every
+// query a handler runs is cancelled once the request's budget is spent instead
+// of holding a pooled connection. Handlers answer a failed query with a 500;
+// when the deadline caused it the status becomes 504 so clients know a retry
+// may succeed. A timeout of zero disables the middleware.
+func RequestTimeoutMiddleware(timeout time.Duration, metrics *observability.Metrics) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		if timeout <= 0 {
+			return next
+		}
+		return func(c echo.Context) error {
+			if untimedRoutes[c.Path()] {
+				return next(c)
+			}
+
+			ctx, cancel := context.WithTimeout(c.Request().Context(), timeout)
+			defer cancel()
+			c.SetRequest(c.Request().WithContext(ctx))
+
+			res := c.Response()
+			timedOut := false
+			res.Before(func() {
+				if res.Status == stdhttp.StatusInternalServerError && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+					res.Status = stdhttp.StatusGatewayTimeout
+					timedOut = true
+				}
+			})
+
+			err := next(c)
+			if !res.Committed && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				err = respondError(c, stdhttp.StatusGatewayTimeout, "request timed out")
+				timedOut = true
+			}
+			if timedOut {
+				metrics.HTTPRequestTimeouts.WithLabelValues(c.Path()).Inc()
+			}
+			return err
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package httpapi
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestRequestTimeoutMiddleware(t *testing.T) {
+	t.Parallel()
+
+	metrics := newTestMetrics()
+	e := echo.New()
+	e.Use(RequestTimeoutMiddleware(20*time.Millisecond, metrics))
+
+	// slowQuery stands in for a handler whose query is cancelled by the
+	// deadline and that reports the failure as usual.
+	slowQuery := func(c echo.Context) error {
+		<-c.Request().Context().Done()
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "failed to list links"})
+	}
+	e.GET("/api/links", slowQuery)
+	e.GET("/api/tags", func(c echo.Context) error {
+		<-c.Request().Context().Done()
+		return c.Request().Context().Err()
+	})
+	e.GET("/api/events", func(c echo.Context) error {
+		if _, ok := c.Request().Context().Deadline(); ok {
+			t.Error("expected live events to run without a deadline")
+		}
+		return c.NoContent(http.StatusNoContent)
+	})
+	e.GET("/api/healthz", func(c echo.Context) error {
+		return c.NoContent(http.StatusNoContent)
+	})
+
+	do := func(path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		return rec
+	}
+
+	if rec := do("/api/links"); rec.Code != http.StatusGatewayTimeout {
+		t.Fatalf("expected a cancelled query to answer 504, got %d", rec.Code)
+	}
+	rec := do("/api/tags")
+	if rec.Code != http.StatusGatewayTimeout {
+		t.Fatalf("expected an unanswered request to get 504, got %d", rec.Code)
+	}
+	if body := rec.Body.String(); body != "{\"code\":\"timeout\",\"message\":\"request timed out\"}\n" {
+		t.Fatalf("unexpected body %q", body)
+	}
+	if rec := do("/api/events"); rec.Code != http.StatusNoContent {
+		t.Fatalf("expected events to pass, got %d", rec.Code)
+	}
+	if rec := do("/api/healthz"); rec.Code != http.StatusNoContent {
+		t.Fatalf("expected fast requests to pass, got %d", rec.Code)
+	}
+
+	if got := testutil.ToFloat64(metrics.HTTPRequestTimeouts.WithLabelValues("/api/links")); got != 1 {
+		t.Fatalf("expected 1 timeout on /api/links, got %v", got)
+	}
+	if got := testutil.ToFloat64(metrics.HTTPRequestTimeouts.WithLabelValues("/api/healthz")); got != 0 {
+		t.Fatalf("expected no timeout on /api/healthz, got %v", got)
+	}
+}
+
+func TestRequestTimeoutMiddlewareDisabled(t *testing.T) {
+	t.Parallel()
+
+	e := echo.New()
+	e.Use(RequestTimeoutMiddleware(0, nil))
+	e.GET("/api/links", func(c echo.Context) error {
+		if _, ok := c.Request().Context().Deadline(); ok {
+			t.Error("expected no deadline when the timeout is zero")
+		}
+		return c.NoContent(http.StatusNoContent)
+	})
+
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/links", nil))
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("expected 204, got %d", rec.Code)
+	}
+}
@@ -0,0 +1,143 @@
+package httpapi
+
+import (
+	"sort"
+	"strings"
+
+	stdhttp "net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+// builtInTrackingRule names the rule for parameters dropped by the built-in
+// list rather than TRACKING_PARAMS_STRIP.
+const builtInTrackingRule = "built-in"
+
+// trackingRule strips or keeps query parameters named name, or starting with
+// it when prefix is set, on domain and its subdomains. An empty domain
+// applies everywhere.
+type trackingRule struct {
+	domain string
+	name   string
+	prefix bool
+	keep   bool
+	source string
+}
+
+// trackingRules extends the built-in tracking parameter list with the
+// configured TRACKING_PARAMS_STRIP and TRACKING_PARAMS_KEEP entries. The
+// rule for the most specific domain wins, and a keep beats a strip for the
+// same domain.
+type trackingRules struct {
+	rules []trackingRule
+}
+
+// removedParam is a query parameter normalization dropped and the rule that
+// dropped it.
+type removedParam struct {
+	Param string `json:"param"`
+	Rule  string `json:"rule"`
+}
+
+// urlNormalization reports what normalizing a URL changed.
+type urlNormalization struct {
+	Rewrites []urlRewrite
+	Removed  []removedParam
+}
+
+type normalizePreviewResponse struct {
+	URL        string         `json:"url"`
+	Normalized string         `json:"normalized"`
+	Rewrites   []urlRewrite   `json:"rewrites"`
+	Removed    []removedParam `json:"removed"`
+}
+
+// newTrackingRules parses entries of the form "param", "param*",
+// "domain:param", or "domain:param*". Config validates them at startup;
+// malformed entries are skipped.
+func newTrackingRules(strip, keep []string) *trackingRules {
+	var rules []trackingRule
+	add := func(entries []string, keep bool) {
+		for _, entry := range entries {
+			entry = strings.ToLower(strings.TrimSpace(entry))
+			rule := trackingRule{keep: keep, source: entry}
+			if domain, name, scoped := strings.Cut(entry, ":"); scoped {
+				rule.domain, entry = domain, name
+			}
+			rule.name = strings.TrimSuffix(entry, "*")
+			rule.prefix = rule.name != entry
+			if rule.name == "" {
+				continue
+			}
+			rules = append(rules, rule)
+		}
+	}
+	add(keep, true)
+	add(strip, false)
+	if len(rules) == 0 {
+		return nil
+	}
+	sort.SliceStable(rules, func(i, j int) bool { return len(rules[i].domain) > len(rules[j].domain) })
+	return &trackingRules{rules: rules}
+}
+
+// strips reports whether parameter name is dropped from URLs on host, and
+// the rule that drops it.
+func (r *trackingRules) strips(host, name string) (string, bool) {
+	key := strings.ToLower(name)
+	if r != nil {
+		for _, rule := range r.rules {
+			if !rule.matches(host, key) {
+				continue
+			}
+			if rule.keep {
+				return "", false
+			}
+			return rule.source, true
+		}
+	}
+	if isTrackingParam(key) {
+		return builtInTrackingRule, true
+	}
+	return "", false
+}
+
+func (rule trackingRule) matches(host, key string) bool {
+	if rule.domain != "" && host != rule.domain && !strings.HasSuffix(host, "."+rule.domain) {
+		return false
+	}
+	if rule.prefix {
+		return strings.HasPrefix(key, rule.name)
+	}
+	return key == rule.name
+}
+
+// normalizeLinkURL normalizes raw as links are stored, with this instance's
+// tracking parameter rules.
+func (s *Server) normalizeLinkURL(raw string) (string, error) {
+	normalized, _, err := normalizeURLWithRules(raw, s.trackingRules)
+	return normalized, err
+}
+
+// handlePreviewNormalizedURL shows how a URL would be stored without saving
+// it, listing each rewrite applied and each parameter dropped with the rule
+// that dropped it, so TRACKING_PARAMS_* changes can be checked before links
+// pile up.
+func (s *Server) handlePreviewNormalizedURL(c echo.Context) error {
+	raw := strings.TrimSpace(c.QueryParam("url"))
+	if raw == "" {
+		return respondError(c, stdhttp.StatusBadRequest, "url is required")
+	}
+	normalized, report, err := normalizeURLWithRules(raw, s.trackingRules)
+	if err != nil {
+		return respondError(c, stdhttp.StatusBadRequest, "invalid url")
+	}
+	resp := normalizePreviewResponse{URL: raw, Normalized: normalized, Rewrites: report.Rewrites, Removed: report.Removed}
+	if resp.Rewrites == nil {
+		resp.Rewrites = []urlRewrite{}
+	}
+	if resp.Removed == nil {
+		resp.Removed = []removedParam{}
+	}
+	return c.JSON(stdhttp.StatusOK, resp)
+}
@@ -0,0 +1,89 @@
+package httpapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+
+	"github.com/example/keepstack/apps/api/internal/config"
+)
+
+func TestNormalizeURLWithTrackingRules(t *testing.T) {
+	t.Parallel()
+
+	rules := newTrackingRules(
+		[]string{"share_id", "amazon.com:tag", "pk_*"},
+		[]string{"ref", "docs.amazon.com:tag"},
+	)
+
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{name: "extra param", input: "https://example.com/a?share_id=1&id=2", want: "https://example.com/a?id=2"},
+		{name: "prefix", input: "https://example.com/a?pk_campaign=x&pkg=y", want: "https://example.com/a?pkg=y"},
+		{name: "domain rule", input: "https://www.amazon.com/dp/1?tag=aff-20&th=1", want: "https://www.amazon.com/dp/1?th=1"},
+		{name: "domain rule elsewhere", input: "https://example.com/a?tag=go", want: "https://example.com/a?tag=go"},
+		{name: "more specific keep", input: "https://docs.amazon.com/a?tag=go", want: "https://docs.amazon.com/a?tag=go"},
+		{name: "kept built-in", input: "https://example.com/a?ref=home&utm_source=x", want: "https://example.com/a?ref=home"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, _, err := normalizeURLWithRules(tc.input, rules)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tc.want {
+				t.Fatalf("expected %q, got %q", tc.want, got)
+			}
+		})
+	}
+
+	if got := newTrackingRules(nil, []string{" ", "*"}); got != nil {
+		t.Fatalf("expected blank entries to add no rules, got %+v", got)
+	}
+}
+
+func TestHandlePreviewNormalizedURL(t *testing.T) {
+	t.Parallel()
+
+	cfg := config.Config{
+		DevUserID:           uuid.MustParse("c7c7c7c7-c7c7-c7c7-c7c7-c7c7c7c7c7c7"),
+		TrackingParamsStrip: []string{"amazon.com:tag"},
+	}
+	srv := &Server{cfg: cfg, queries: &mockQueries{}, metrics: newTestMetrics(), trackingRules: newTrackingRules(cfg.TrackingParamsStrip, nil)}
+	e := echo.New()
+	srv.RegisterRoutes(e)
+
+	target := "https://Amazon.com/dp/1?tag=aff-20&utm_medium=email&th=1#reviews"
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/urls/normalize?url="+url.QueryEscape(target), nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+	}
+	var resp normalizePreviewResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if resp.URL != target || resp.Normalized != "https://amazon.com/dp/1?th=1" {
+		t.Fatalf("unexpected preview: %+v", resp)
+	}
+	want := []removedParam{{Param: "tag", Rule: "amazon.com:tag"}, {Param: "utm_medium", Rule: builtInTrackingRule}}
+	if len(resp.Removed) != len(want) || resp.Removed[0] != want[0] || resp.Removed[1] != want[1] {
+		t.Fatalf("expected removed %+v, got %+v", want, resp.Removed)
+	}
+
+	for _, query := range []string{"", "?url=" + url.QueryEscape("https://")} {
+		rec = httptest.NewRecorder()
+		e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/urls/normalize"+query, nil))
+		if rec.Code != http.StatusBadRequest {
+			t.Fatalf("%q: expected status %d, got %d", query, http.StatusBadRequest, rec.Code)
+		}
+	}
+}
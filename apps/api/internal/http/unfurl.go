@@ -0,0 +1,151 @@
+package httpapi
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	stdhttp "net/http"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/example/keepstack/apps/api/internal/queue"
+)
+
+// unfurler fetches and parses a page without saving it.
+type unfurler interface {
+	Unfurl(ctx context.Context, target string) (queue.UnfurlReply, error)
+}
+
+type unfurlResponse struct {
+	URL            string `json:"url"`
+	Title          string `json:"title"`
+	Description    string `json:"description"`
+	Image          string `json:"image"`
+	WordCount      int    `json:"word_count"`
+	ReadingMinutes int    `json:"reading_minutes"`
+}
+
+// WithUnfurler answers GET /api/unfurl through u, typically the workers over
+// NATS.
+func (s *Server) WithUnfurler(u unfurler) {
+	s.unfurler = u
+}
+
+// handleUnfurl previews a page for chat apps or a save dialog. Results are
+// cached per URL, and concurrent requests for the same URL share one
+// fetch. The fetch continues if the client that started it disconnects,
+// so the others still get an answer.
+func (s *Server) handleUnfurl(c echo.Context) error {
+	target, err := s.normalizeLinkURL(strings.TrimSpace(c.QueryParam("url")))
+	if err != nil {
+		return respondError(c, stdhttp.StatusBadRequest, "invalid url")
+	}
+	if s.unfurler == nil {
+		return respondError(c, stdhttp.StatusServiceUnavailable, "unfurling is not available")
+	}
+	if cached, ok := s.unfurls.get(target, time.Now()); ok {
+		return c.JSON(stdhttp.StatusOK, cached)
+	}
+
+	value, err, _ := s.unfurlGroup.Do(target, func() (any, error) {
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(c.Request().Context()), s.cfg.UnfurlTimeout)
+		defer cancel()
+		reply, err := s.unfurler.Unfurl(ctx, target)
+		if err != nil {
+			return unfurlResponse{}, err
+		}
+		resp := unfurlResponse{
+			URL:            firstNonBlank(reply.URL, target),
+			Title:          reply.Title,
+			Description:    reply.Description,
+			Image:          reply.Image,
+			WordCount:      reply.WordCount,
+			ReadingMinutes: readingMinutes(reply.WordCount),
+		}
+		s.unfurls.put(target, resp, time.Now())
+		return resp, nil
+	})
+	if err != nil {
+		var unfurlErr *queue.UnfurlError
+		switch {
+		case errors.As(err, &unfurlErr):
+			c.Logger().Warnf("unfurl %s: %v", target, err)
+			return respondError(c, stdhttp.StatusBadGateway, "failed to fetch or parse the page")
+		case errors.Is(err, queue.ErrUnfurlUnavailable):
+			return respondError(c, stdhttp.StatusServiceUnavailable, "unfurling is not available")
+		case errors.Is(err, context.DeadlineExceeded):
+			return respondError(c, stdhttp.StatusGatewayTimeout, "timed out fetching the page")
+		}
+		c.Logger().Errorf("unfurl %s: %v", target, err)
+		return respondError(c, stdhttp.StatusInternalServerError, "failed to unfurl url")
+	}
+	return c.JSON(stdhttp.StatusOK, value.(unfurlResponse))
+}
+
+// unfurlCache keeps recent unfurl results in memory, evicting the least
+// recently used once it holds size entries. A nil cache stores nothing.
+type unfurlCache struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	size    int
+	order   *list.List // most recently used first
+	entries map[string]*list.Element
+}
+
+type unfurlCacheEntry struct {
+	key     string
+	value   unfurlResponse
+	expires time.Time
+}
+
+// newUnfurlCache returns nil, disabling the cache, unless size and ttl are
+// both positive.
+func newUnfurlCache(size int, ttl time.Duration) *unfurlCache {
+	if size <= 0 || ttl <= 0 {
+		return nil
+	}
+	return &unfurlCache{ttl: ttl, size: size, order: list.New(), entries: make(map[string]*list.Element)}
+}
+
+func (c *unfurlCache) get(key string, now time.Time) (unfurlResponse, bool) {
+	if c == nil {
+		return unfurlResponse{}, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	elem, ok := c.entries[key]
+	if !ok {
+		return unfurlResponse{}, false
+	}
+	entry := elem.Value.(*unfurlCacheEntry)
+	if !now.Before(entry.expires) {
+		c.order.Remove(elem)
+		delete(c.entries, key)
+		return unfurlResponse{}, false
+	}
+	c.order.MoveToFront(elem)
+	return entry.value, true
+}
+
+func (c *unfurlCache) put(key string, value unfurlResponse, now time.Time) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if elem, ok := c.entries[key]; ok {
+		elem.Value = &unfurlCacheEntry{key: key, value: value, expires: now.Add(c.ttl)}
+		c.order.MoveToFront(elem)
+		return
+	}
+	c.entries[key] = c.order.PushFront(&unfurlCacheEntry{key: key, value: value, expires: now.Add(c.ttl)})
+	for c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*unfurlCacheEntry).key)
+	}
+}
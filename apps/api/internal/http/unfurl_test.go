@@ -0,0 +1,130 @@
+package httpapi
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/example/keepstack/apps/api/internal/config"
+	"github.com/example/keepstack/apps/api/internal/queue"
+)
+
+type fakeUnfurler struct {
+	calls atomic.Int32
+	reply queue.UnfurlReply
+	err   error
+}
+
+func (f *fakeUnfurler) Unfurl(ctx context.Context, target string) (queue.UnfurlReply, error) {
+	f.calls.Add(1)
+	return f.reply, f.err
+}
+
+func newUnfurlTestServer(u unfurler) *echo.Echo {
+	srv := &Server{
+		cfg:       config.Config{UnfurlTimeout: time.Second},
+		queries:   &mockQueries{},
+		publisher: &stubPublisher{},
+		metrics:   newTestMetrics(),
+		unfurls:   newUnfurlCache(10, time.Minute),
+	}
+	if u != nil {
+		srv.WithUnfurler(u)
+	}
+	e := echo.New()
+	srv.RegisterRoutes(e)
+	return e
+}
+
+func unfurlRequest(target string) *http.Request {
+	return httptest.NewRequest(http.MethodGet, "/api/unfurl?url="+url.QueryEscape(target), nil)
+}
+
+func TestUnfurlReturnsAndCachesPreview(t *testing.T) {
+	t.Parallel()
+
+	u := &fakeUnfurler{reply: queue.UnfurlReply{
+		URL:         "https://example.com/post",
+		Title:       "A post",
+		Description: "About things",
+		Image:       "https://example.com/cover.png",
+		WordCount:   461,
+	}}
+	e := newUnfurlTestServer(u)
+
+	for i := 0; i < 2; i++ {
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, unfurlRequest("example.com/post#section"))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected status %d, got %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+		}
+		var resp unfurlResponse
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("decode response: %v", err)
+		}
+		if resp.Title != "A post" || resp.Image != "https://example.com/cover.png" || resp.ReadingMinutes != 3 {
+			t.Fatalf("unexpected preview: %+v", resp)
+		}
+	}
+	if calls := u.calls.Load(); calls != 1 {
+		t.Fatalf("expected the second request to be served from cache, got %d unfurls", calls)
+	}
+}
+
+func TestUnfurlErrors(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name string
+		u    unfurler
+		url  string
+		want int
+	}{
+		{"invalid url", &fakeUnfurler{}, "", http.StatusBadRequest},
+		{"not configured", nil, "https://example.com", http.StatusServiceUnavailable},
+		{"no workers", &fakeUnfurler{err: queue.ErrUnfurlUnavailable}, "https://example.com", http.StatusServiceUnavailable},
+		{"page failed", &fakeUnfurler{err: &queue.UnfurlError{Message: "unexpected status 404"}}, "https://example.com", http.StatusBadGateway},
+		{"timeout", &fakeUnfurler{err: context.DeadlineExceeded}, "https://example.com", http.StatusGatewayTimeout},
+		{"other", &fakeUnfurler{err: errors.New("boom")}, "https://example.com", http.StatusInternalServerError},
+	}
+	for _, tc := range cases {
+		e := newUnfurlTestServer(tc.u)
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, unfurlRequest(tc.url))
+		if rec.Code != tc.want {
+			t.Fatalf("%s: expected status %d, got %d", tc.name, tc.want, rec.Code)
+		}
+	}
+}
+
+func TestUnfurlCacheEvictsAndExpires(t *testing.T) {
+	t.Parallel()
+
+	cache := newUnfurlCache(2, time.Minute)
+	now := time.Now()
+	cache.put("a", unfurlResponse{Title: "a"}, now)
+	cache.put("b", unfurlResponse{Title: "b"}, now)
+	cache.get("a", now)
+	cache.put("c", unfurlResponse{Title: "c"}, now)
+
+	if _, ok := cache.get("b", now); ok {
+		t.Fatal("expected the least recently used entry to be evicted")
+	}
+	if _, ok := cache.get("a", now); !ok {
+		t.Fatal("expected a recently read entry to be kept")
+	}
+	if _, ok := cache.get("c", now.Add(time.Minute)); ok {
+		t.Fatal("expected entries to expire after the ttl")
+	}
+	if newUnfurlCache(0, time.Minute) != nil {
+		t.Fatal("expected a zero size to disable the cache")
+	}
+}
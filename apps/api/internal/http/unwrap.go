@@ -0,0 +1,207 @@
+package httpapi
+
+import (
+	"bytes"
+	"encoding/base64"
+	"net"
+	"net/url"
+	"strings"
+)
+
+// maxURLUnwraps bounds how many rewrites normalization applies to one URL,
+// so a redirector pointing at itself cannot loop.
+const maxURLUnwraps = 5
+
+// Rules reported for each rewrite normalization applies.
+const (
+	unwrapRuleRedirect = "redirect"
+	unwrapRuleAMP      = "amp"
+	unwrapRuleMobile   = "mobile"
+)
+
+// urlRedirector is a link wrapper that carries its target in a query
+// parameter.
+type urlRedirector struct {
+	path   string
+	params []string
+}
+
+// urlRedirectors are keyed by host.
+var urlRedirectors = map[string]urlRedirector{
+	"l.facebook.com":  {path: "/l.php", params: []string{"u"}},
+	"lm.facebook.com": {path: "/l.php", params: []string{"u"}},
+	"l.messenger.com": {path: "/l.php", params: []string{"u"}},
+	"l.instagram.com": {path: "/", params: []string{"u"}},
+	"google.com":      {path: "/url", params: []string{"q", "url"}},
+	"www.google.com":  {path: "/url", params: []string{"q", "url"}},
+	"www.youtube.com": {path: "/redirect", params: []string{"q"}},
+	"slack-redir.net": {path: "/link", params: []string{"url"}},
+}
+
+// mobileHostLabels mark the mobile edition of a site, as in m.example.com
+// or en.m.wikipedia.org.
+var mobileHostLabels = map[string]struct{}{
+	"m":      {},
+	"mobile": {},
+}
+
+// urlRewrite is one step normalization took to reach a canonical URL.
+type urlRewrite struct {
+	Rule string `json:"rule"`
+	From string `json:"from"`
+}
+
+// unwrapURL applies the first rewrite that leads from u towards the page it
+// stands for: following a redirector, leaving an AMP edition, or leaving a
+// mobile subdomain. It reports false when u is already canonical.
+func unwrapURL(u *url.URL) (*url.URL, string, bool) {
+	host := strings.ToLower(u.Hostname())
+	if next, ok := redirectTarget(u, host); ok {
+		return next, unwrapRuleRedirect, true
+	}
+	if next, ok := ampTarget(u, host); ok {
+		return next, unwrapRuleAMP, true
+	}
+	if next, ok := mobileTarget(u, host); ok {
+		return next, unwrapRuleMobile, true
+	}
+	return nil, "", false
+}
+
+func redirectTarget(u *url.URL, host string) (*url.URL, bool) {
+	if host == "news.google.com" {
+		path := strings.TrimPrefix(u.Path, "/rss")
+		if id, ok := strings.CutPrefix(path, "/articles/"); ok {
+			id, _, _ = strings.Cut(id, "/")
+			return googleNewsTarget(id)
+		}
+		return nil, false
+	}
+
+	redirector, ok := urlRedirectors[host]
+	if !ok {
+		return nil, false
+	}
+	path := u.Path
+	if path == "" {
+		path = "/"
+	}
+	if path != redirector.path {
+		return nil, false
+	}
+	query := u.Query()
+	for _, param := range redirector.params {
+		if next, ok := parseUnwrapTarget(query.Get(param)); ok {
+			return next, true
+		}
+	}
+	return nil, false
+}
+
+// googleNewsTarget decodes a news.google.com article id. Older ids are
+// base64 protobufs holding the article URL; newer ones can only be resolved
+// by asking Google and are left alone.
+func googleNewsTarget(id string) (*url.URL, bool) {
+	decoded, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(id, "="))
+	if err != nil {
+		return nil, false
+	}
+	start := bytes.Index(decoded, []byte("http"))
+	if start < 0 {
+		return nil, false
+	}
+	end := start
+	for end < len(decoded) && decoded[end] > ' ' && decoded[end] < 0x7f {
+		end++
+	}
+	return parseUnwrapTarget(string(decoded[start:end]))
+}
+
+func ampTarget(u *url.URL, host string) (*url.URL, bool) {
+	// The Google AMP viewer and the AMP cache embed the origin URL in their
+	// path: /amp/s/example.com/a and /c/s/example.com/a are https, without
+	// the s they are http.
+	viewerPrefix := ""
+	switch {
+	case host == "google.com" || host == "www.google.com":
+		viewerPrefix = "/amp/"
+	case strings.HasSuffix(host, ".cdn.ampproject.org"):
+		viewerPrefix = "/c/"
+		if strings.HasPrefix(u.Path, "/v/") {
+			viewerPrefix = "/v/"
+		}
+	}
+	if viewerPrefix != "" {
+		rest, ok := strings.CutPrefix(u.EscapedPath(), viewerPrefix)
+		if !ok {
+			return nil, false
+		}
+		scheme := "http://"
+		if secure, ok := strings.CutPrefix(rest, "s/"); ok {
+			scheme, rest = "https://", secure
+		}
+		target := scheme + rest
+		if u.RawQuery != "" {
+			target += "?" + u.RawQuery
+		}
+		return parseUnwrapTarget(target)
+	}
+
+	if rest, ok := strings.CutPrefix(host, "amp."); ok && strings.Contains(rest, ".") {
+		next := *u
+		next.Host = withPort(rest, u.Port())
+		return &next, true
+	}
+
+	trimmed := strings.TrimSuffix(u.Path, "/")
+	if page, ok := strings.CutSuffix(trimmed, "/amp"); ok && page != "" {
+		next := *u
+		next.Path, next.RawPath = page, ""
+		return &next, true
+	}
+
+	query := u.Query()
+	if values, ok := query["amp"]; ok && len(values) == 1 && (values[0] == "" || values[0] == "1" || values[0] == "true") {
+		query.Del("amp")
+		next := *u
+		next.RawQuery = query.Encode()
+		return &next, true
+	}
+	return nil, false
+}
+
+// mobileTarget drops a mobile label from the first two labels of host,
+// leaving at least a registrable name behind.
+func mobileTarget(u *url.URL, host string) (*url.URL, bool) {
+	labels := strings.Split(host, ".")
+	for i := 0; i < 2 && i < len(labels)-2; i++ {
+		if _, ok := mobileHostLabels[labels[i]]; !ok {
+			continue
+		}
+		labels = append(labels[:i], labels[i+1:]...)
+		next := *u
+		next.Host = withPort(strings.Join(labels, "."), u.Port())
+		return &next, true
+	}
+	return nil, false
+}
+
+// parseUnwrapTarget accepts an unwrapped URL only if it is an absolute
+// http or https URL, so a wrapper around anything else is kept as saved.
+func parseUnwrapTarget(raw string) (*url.URL, bool) {
+	if raw == "" {
+		return nil, false
+	}
+	parsed, err := url.Parse(raw)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || !strings.Contains(parsed.Hostname(), ".") {
+		return nil, false
+	}
+	return parsed, true
+}
+
+func withPort(host, port string) string {
+	if port == "" {
+		return host
+	}
+	return net.JoinHostPort(host, port)
+}
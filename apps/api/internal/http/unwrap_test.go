@@ -0,0 +1,71 @@
+package httpapi
+
+import (
+	"encoding/base64"
+	"net/url"
+	"testing"
+)
+
+func TestNormalizeURLUnwraps(t *testing.T) {
+	t.Parallel()
+
+	newsID := base64.RawURLEncoding.EncodeToString(append([]byte("\x08\x13\x22\x1ahttps://example.com/story"), 0xd2, 0x01, 0x00))
+
+	tests := []struct {
+		name  string
+		input string
+		want  string
+		rules []string
+	}{
+		{name: "amp subdomain", input: "https://amp.example.com/news/a", want: "https://example.com/news/a", rules: []string{"amp"}},
+		{name: "amp suffix", input: "https://example.com/news/a/amp/", want: "https://example.com/news/a", rules: []string{"amp"}},
+		{name: "amp query", input: "https://example.com/a?amp=1&id=2", want: "https://example.com/a?id=2", rules: []string{"amp"}},
+		{name: "amp page kept", input: "https://example.com/amp", want: "https://example.com/amp"},
+		{name: "amp apex kept", input: "https://amp.dev/docs", want: "https://amp.dev/docs"},
+		{name: "google amp viewer", input: "https://www.google.com/amp/s/example.com/a/amp", want: "https://example.com/a", rules: []string{"amp", "amp"}},
+		{name: "amp cache", input: "https://example-com.cdn.ampproject.org/c/example.com/a?x=1", want: "http://example.com/a?x=1", rules: []string{"amp"}},
+		{name: "mobile subdomain", input: "https://m.example.com/a", want: "https://example.com/a", rules: []string{"mobile"}},
+		{name: "wikipedia mobile", input: "https://en.m.wikipedia.org/wiki/Go", want: "https://en.wikipedia.org/wiki/Go", rules: []string{"mobile"}},
+		{name: "short host kept", input: "https://m.me/page", want: "https://m.me/page"},
+		{name: "facebook", input: "https://l.facebook.com/l.php?u=" + url.QueryEscape("https://m.example.com/a?utm_source=fb") + "&h=AT0", want: "https://example.com/a", rules: []string{"redirect", "mobile"}},
+		{name: "google redirect", input: "https://www.google.com/url?q=https://example.com/a&sa=D", want: "https://example.com/a", rules: []string{"redirect"}},
+		{name: "google news", input: "https://news.google.com/rss/articles/" + newsID + "?oc=5", want: "https://example.com/story", rules: []string{"redirect"}},
+		{name: "opaque google news", input: "https://news.google.com/articles/AU_yqLNotDecodable", want: "https://news.google.com/articles/AU_yqLNotDecodable"},
+		{name: "non-http target kept", input: "https://l.facebook.com/l.php?u=javascript:alert(1)", want: "https://l.facebook.com/l.php?u=javascript%3Aalert%281%29"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, report, err := normalizeURLWithRules(tc.input, nil)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tc.want {
+				t.Fatalf("expected %q, got %q", tc.want, got)
+			}
+			if len(report.Rewrites) != len(tc.rules) {
+				t.Fatalf("expected rewrites %v, got %+v", tc.rules, report.Rewrites)
+			}
+			for i, rule := range tc.rules {
+				if report.Rewrites[i].Rule != rule {
+					t.Fatalf("expected rewrites %v, got %+v", tc.rules, report.Rewrites)
+				}
+			}
+		})
+	}
+}
+
+func TestNormalizeURLUnwrapLimit(t *testing.T) {
+	t.Parallel()
+
+	target := "https://example.com/a"
+	for i := 0; i < maxURLUnwraps+1; i++ {
+		target = "https://www.google.com/url?q=" + url.QueryEscape(target)
+	}
+	_, report, err := normalizeURLWithRules(target, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(report.Rewrites) != maxURLUnwraps {
+		t.Fatalf("expected %d rewrites, got %d", maxURLUnwraps, len(report.Rewrites))
+	}
+}
@@ -0,0 +1,159 @@
+// Package integrity checks stored archives against the SHA-256 digests the
+// worker records at ingest. An archive whose HTML or extracted text no longer
+// hashes to its digest was changed outside the ingest path, by corruption or
+// by hand; ingesting the link again stores fresh content and digests.
+// Archives saved before the digests existed have none, and Service records
+// them from what is stored so later runs can check those too.
+package integrity
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/example/keepstack/apps/api/internal/db"
+)
+
+// DefaultBatchSize is the number of archives hashed per statement. Hashing
+// happens in Postgres, so only the results cross the wire.
+const DefaultBatchSize = 500
+
+// maxReportedMismatches caps how many mismatched archives Stats lists; the
+// count covers every one.
+const maxReportedMismatches = 100
+
+type queries interface {
+	CountArchivesForIntegrity(context.Context) (int64, error)
+	CheckArchiveIntegrityBatch(context.Context, db.CheckArchiveIntegrityBatchParams) ([]db.CheckArchiveIntegrityBatchRow, error)
+	RecordArchiveHashes(context.Context, []pgtype.UUID) (int64, error)
+}
+
+// Options controls a run.
+type Options struct {
+	// BatchSize caps how many archives are hashed per statement.
+	BatchSize int
+	// DryRun checks archives without recording digests for the ones that
+	// have none.
+	DryRun bool
+	// Progress, when set, is called after each batch.
+	Progress func(Progress)
+}
+
+// Progress reports how far a run has got. Total is the number of archives
+// when the run started.
+type Progress struct {
+	Checked    int64
+	Total      int64
+	Mismatched int64
+}
+
+// Mismatch is an archive whose stored content no longer matches its
+// digests.
+type Mismatch struct {
+	LinkID uuid.UUID
+	HTML   bool
+	Text   bool
+}
+
+// Stats summarises a run. In a dry run Hashed counts the archives that
+// would have had digests recorded.
+type Stats struct {
+	Checked    int64
+	Mismatched int64
+	Hashed     int64
+	// Mismatches lists the first mismatched archives, by link id.
+	Mismatches []Mismatch
+	Duration   time.Duration
+}
+
+// Service verifies archive digests against Postgres.
+type Service struct {
+	queries queries
+	now     func() time.Time
+}
+
+// New constructs a Service using the provided connection pool.
+func New(pool *pgxpool.Pool) *Service {
+	return &Service{queries: db.New(pool), now: time.Now}
+}
+
+// WithNow overrides the time source. Intended for tests.
+func (s *Service) WithNow(now func() time.Time) {
+	s.now = now
+}
+
+// Run walks every archive by link id, compares its HTML and extracted text
+// with the stored digests, and records digests for archives that have
+// none. Mismatches are reported, never repaired: the stored digest is the
+// only record of what was ingested.
+func (s *Service) Run(ctx context.Context, opts Options) (Stats, error) {
+	start := s.now()
+	var stats Stats
+
+	if opts.BatchSize < 1 {
+		opts.BatchSize = DefaultBatchSize
+	}
+
+	total, err := s.queries.CountArchivesForIntegrity(ctx)
+	if err != nil {
+		stats.Duration = s.now().Sub(start)
+		return stats, fmt.Errorf("count archives: %w", err)
+	}
+
+	var after pgtype.UUID
+	for {
+		rows, err := s.queries.CheckArchiveIntegrityBatch(ctx, db.CheckArchiveIntegrityBatchParams{AfterID: after, BatchSize: int32(opts.BatchSize)})
+		if err != nil {
+			stats.Duration = s.now().Sub(start)
+			return stats, fmt.Errorf("check archives: %w", err)
+		}
+
+		var unhashed []pgtype.UUID
+		for _, row := range rows {
+			stats.Checked++
+			if !row.Hashed {
+				unhashed = append(unhashed, row.LinkID)
+				continue
+			}
+			if row.HtmlMatches && row.TextMatches {
+				continue
+			}
+			stats.Mismatched++
+			if len(stats.Mismatches) < maxReportedMismatches {
+				stats.Mismatches = append(stats.Mismatches, Mismatch{
+					LinkID: uuid.UUID(row.LinkID.Bytes),
+					HTML:   !row.HtmlMatches,
+					Text:   !row.TextMatches,
+				})
+			}
+		}
+
+		if len(unhashed) > 0 {
+			if opts.DryRun {
+				stats.Hashed += int64(len(unhashed))
+			} else {
+				recorded, err := s.queries.RecordArchiveHashes(ctx, unhashed)
+				if err != nil {
+					stats.Duration = s.now().Sub(start)
+					return stats, fmt.Errorf("record archive digests: %w", err)
+				}
+				stats.Hashed += recorded
+			}
+		}
+
+		if opts.Progress != nil && len(rows) > 0 {
+			opts.Progress(Progress{Checked: stats.Checked, Total: total, Mismatched: stats.Mismatched})
+		}
+		if len(rows) < opts.BatchSize {
+			break
+		}
+		after = rows[len(rows)-1].LinkID
+	}
+
+	stats.Duration = s.now().Sub(start)
+	return stats, nil
+}
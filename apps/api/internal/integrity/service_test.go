@@ -0,0 +1,119 @@
+package integrity
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"github.com/example/keepstack/apps/api/internal/db"
+)
+
+type fakeQueries struct {
+	batches [][]db.CheckArchiveIntegrityBatchRow
+	total   int64
+
+	afters   []pgtype.UUID
+	recorded [][]pgtype.UUID
+}
+
+func (f *fakeQueries) CountArchivesForIntegrity(context.Context) (int64, error) {
+	return f.total, nil
+}
+
+func (f *fakeQueries) CheckArchiveIntegrityBatch(_ context.Context, arg db.CheckArchiveIntegrityBatchParams) ([]db.CheckArchiveIntegrityBatchRow, error) {
+	f.afters = append(f.afters, arg.AfterID)
+	if len(f.batches) == 0 {
+		return nil, nil
+	}
+	rows := f.batches[0]
+	f.batches = f.batches[1:]
+	return rows, nil
+}
+
+func (f *fakeQueries) RecordArchiveHashes(_ context.Context, ids []pgtype.UUID) (int64, error) {
+	f.recorded = append(f.recorded, ids)
+	return int64(len(ids)), nil
+}
+
+func row(hashed, html, text bool) db.CheckArchiveIntegrityBatchRow {
+	return db.CheckArchiveIntegrityBatchRow{
+		LinkID:      pgtype.UUID{Bytes: uuid.New(), Valid: true},
+		Hashed:      hashed,
+		HtmlMatches: html,
+		TextMatches: text,
+	}
+}
+
+func newTestService(q *fakeQueries) *Service {
+	now := time.Date(2024, time.June, 1, 0, 0, 0, 0, time.UTC)
+	return &Service{queries: q, now: func() time.Time { return now }}
+}
+
+func TestRunReportsMismatchesAndRecordsMissingDigests(t *testing.T) {
+	first := []db.CheckArchiveIntegrityBatchRow{row(true, true, true), row(true, false, true)}
+	second := []db.CheckArchiveIntegrityBatchRow{row(false, true, true)}
+	q := &fakeQueries{total: 3, batches: [][]db.CheckArchiveIntegrityBatchRow{first, second}}
+
+	var progress []Progress
+	stats, err := newTestService(q).Run(context.Background(), Options{
+		BatchSize: 2,
+		Progress:  func(p Progress) { progress = append(progress, p) },
+	})
+	if err != nil {
+		t.Fatalf("Run returned error: %v", err)
+	}
+	if stats.Checked != 3 || stats.Mismatched != 1 || stats.Hashed != 1 {
+		t.Fatalf("unexpected stats: %+v", stats)
+	}
+	want := Mismatch{LinkID: uuid.UUID(first[1].LinkID.Bytes), HTML: true}
+	if len(stats.Mismatches) != 1 || stats.Mismatches[0] != want {
+		t.Fatalf("expected %+v reported, got %+v", want, stats.Mismatches)
+	}
+	if len(q.afters) != 2 || q.afters[0].Valid || q.afters[1] != first[1].LinkID {
+		t.Fatalf("expected the second batch to continue after the first, got %v", q.afters)
+	}
+	if len(q.recorded) != 1 || len(q.recorded[0]) != 1 || q.recorded[0][0] != second[0].LinkID {
+		t.Fatalf("expected digests recorded for the unhashed archive only, got %v", q.recorded)
+	}
+	wantProgress := []Progress{{Checked: 2, Total: 3, Mismatched: 1}, {Checked: 3, Total: 3, Mismatched: 1}}
+	if len(progress) != len(wantProgress) || progress[0] != wantProgress[0] || progress[1] != wantProgress[1] {
+		t.Fatalf("expected progress %+v, got %+v", wantProgress, progress)
+	}
+}
+
+func TestRunDryRunRecordsNothing(t *testing.T) {
+	q := &fakeQueries{
+		total:   2,
+		batches: [][]db.CheckArchiveIntegrityBatchRow{{row(false, true, true), row(true, true, false)}},
+	}
+
+	stats, err := newTestService(q).Run(context.Background(), Options{DryRun: true})
+	if err != nil {
+		t.Fatalf("Run returned error: %v", err)
+	}
+	if stats.Checked != 2 || stats.Hashed != 1 || stats.Mismatched != 1 || !stats.Mismatches[0].Text {
+		t.Fatalf("unexpected stats: %+v", stats)
+	}
+	if len(q.recorded) != 0 {
+		t.Fatalf("dry run recorded digests for %v", q.recorded)
+	}
+}
+
+func TestRunCapsReportedMismatches(t *testing.T) {
+	rows := make([]db.CheckArchiveIntegrityBatchRow, maxReportedMismatches+5)
+	for i := range rows {
+		rows[i] = row(true, false, false)
+	}
+	q := &fakeQueries{total: int64(len(rows)), batches: [][]db.CheckArchiveIntegrityBatchRow{rows}}
+
+	stats, err := newTestService(q).Run(context.Background(), Options{BatchSize: len(rows) + 1})
+	if err != nil {
+		t.Fatalf("Run returned error: %v", err)
+	}
+	if stats.Mismatched != int64(len(rows)) || len(stats.Mismatches) != maxReportedMismatches {
+		t.Fatalf("expected %d mismatches with %d listed, got %d with %d", len(rows), maxReportedMismatches, stats.Mismatched, len(stats.Mismatches))
+	}
+}
@@ -0,0 +1,213 @@
+package memstore
+
+import (
+	"context"
+	"sort"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"github.com/example/keepstack/apps/api/internal/db"
+)
+
+// GetActivityPubActor returns a user's actor.
+func (s *Store) GetActivityPubActor(ctx context.Context, userID pgtype.UUID) (db.ActivitypubActor, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	actor, ok := s.actors[userID.Bytes]
+	if !ok {
+		return db.ActivitypubActor{}, pgx.ErrNoRows
+	}
+	return actor, nil
+}
+
+// GetActivityPubActorByUsername returns the actor with a username.
+func (s *Store) GetActivityPubActorByUsername(ctx context.Context, username string) (db.ActivitypubActor, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, actor := range s.actors {
+		if actor.Username == username {
+			return actor, nil
+		}
+	}
+	return db.ActivitypubActor{}, pgx.ErrNoRows
+}
+
+// UpsertActivityPubActor creates or updates a user's actor. Usernames are
+// unique.
+func (s *Store) UpsertActivityPubActor(ctx context.Context, arg db.UpsertActivityPubActorParams) (db.ActivitypubActor, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for userID, actor := range s.actors {
+		if actor.Username == arg.Username && userID != arg.UserID.Bytes {
+			return db.ActivitypubActor{}, uniqueViolation("activitypub_actors_username_key")
+		}
+	}
+	actor, ok := s.actors[arg.UserID.Bytes]
+	if !ok {
+		if _, ok := s.users[arg.UserID.Bytes]; !ok {
+			return db.ActivitypubActor{}, foreignKeyViolation("activitypub_actors_user_id_fkey")
+		}
+		actor = db.ActivitypubActor{UserID: arg.UserID, CreatedAt: s.timestamp()}
+	}
+	actor.Username = arg.Username
+	actor.DisplayName = arg.DisplayName
+	actor.Summary = arg.Summary
+	s.actors[arg.UserID.Bytes] = actor
+	return actor, nil
+}
+
+// DeleteActivityPubActor removes a user's actor and its followers.
+func (s *Store) DeleteActivityPubActor(ctx context.Context, userID pgtype.UUID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.actors, userID.Bytes)
+	delete(s.followers, userID.Bytes)
+	return nil
+}
+
+// AddActivityPubFollower records a follower, updating the inbox of one
+// already following.
+func (s *Store) AddActivityPubFollower(ctx context.Context, arg db.AddActivityPubFollowerParams) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.actors[arg.UserID.Bytes]; !ok {
+		return foreignKeyViolation("activitypub_followers_user_id_fkey")
+	}
+	followers := s.followers[arg.UserID.Bytes]
+	if followers == nil {
+		followers = make(map[string]db.ActivitypubFollower)
+		s.followers[arg.UserID.Bytes] = followers
+	}
+	follower, ok := followers[arg.ActorUri]
+	if !ok {
+		follower = db.ActivitypubFollower{UserID: arg.UserID, ActorUri: arg.ActorUri, CreatedAt: s.timestamp()}
+	}
+	follower.Inbox = arg.Inbox
+	followers[arg.ActorUri] = follower
+	return nil
+}
+
+// RemoveActivityPubFollower forgets a follower.
+func (s *Store) RemoveActivityPubFollower(ctx context.Context, arg db.RemoveActivityPubFollowerParams) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.followers[arg.UserID.Bytes], arg.ActorUri)
+	return nil
+}
+
+// CountActivityPubFollowers counts a user's followers.
+func (s *Store) CountActivityPubFollowers(ctx context.Context, userID pgtype.UUID) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return int64(len(s.followers[userID.Bytes])), nil
+}
+
+// ListActivityPubFollowerInboxes lists the distinct inboxes of a user's
+// followers, sorted.
+func (s *Store) ListActivityPubFollowerInboxes(ctx context.Context, userID pgtype.UUID) ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	seen := make(map[string]bool)
+	var inboxes []string
+	for _, follower := range s.followers[userID.Bytes] {
+		if !seen[follower.Inbox] {
+			seen[follower.Inbox] = true
+			inboxes = append(inboxes, follower.Inbox)
+		}
+	}
+	sort.Strings(inboxes)
+	return inboxes, nil
+}
+
+// SetLinkPublic publishes or unpublishes a link, keeping the original
+// publish time when it is already public.
+func (s *Store) SetLinkPublic(ctx context.Context, arg db.SetLinkPublicParams) (db.SetLinkPublicRow, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	link, ok := s.links[arg.ID.Bytes]
+	if !ok {
+		return db.SetLinkPublicRow{}, pgx.ErrNoRows
+	}
+	previous := link.PublicAt
+	switch {
+	case !arg.Public:
+		link.PublicAt = pgtype.Timestamptz{}
+	case !previous.Valid:
+		link.PublicAt = s.timestamp()
+	}
+	return db.SetLinkPublicRow{PublicAt: link.PublicAt, PreviousPublicAt: previous}, nil
+}
+
+// GetPublicLink returns a link if it is public.
+func (s *Store) GetPublicLink(ctx context.Context, id pgtype.UUID) (db.GetPublicLinkRow, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	link, ok := s.links[id.Bytes]
+	if !ok || !link.PublicAt.Valid {
+		return db.GetPublicLinkRow{}, pgx.ErrNoRows
+	}
+	return db.GetPublicLinkRow{ID: link.ID, UserID: link.UserID, Url: link.Url, Title: s.publicTitle(link), PublicAt: link.PublicAt}, nil
+}
+
+// ListPublicLinks pages through a user's public links, most recently
+// published first.
+func (s *Store) ListPublicLinks(ctx context.Context, arg db.ListPublicLinksParams) ([]db.ListPublicLinksRow, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	links := s.publicLinks(arg.UserID)
+	sort.Slice(links, func(i, j int) bool {
+		a, b := links[i], links[j]
+		if !a.PublicAt.Time.Equal(b.PublicAt.Time) {
+			return a.PublicAt.Time.After(b.PublicAt.Time)
+		}
+		return lessUUID(b.ID, a.ID)
+	})
+	links = page(links, arg.PageOffset, arg.PageLimit)
+	rows := make([]db.ListPublicLinksRow, 0, len(links))
+	for _, link := range links {
+		rows = append(rows, db.ListPublicLinksRow{ID: link.ID, Url: link.Url, Title: s.publicTitle(link), PublicAt: link.PublicAt})
+	}
+	return rows, nil
+}
+
+// CountPublicLinks counts a user's public links.
+func (s *Store) CountPublicLinks(ctx context.Context, userID pgtype.UUID) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return int64(len(s.publicLinks(userID))), nil
+}
+
+func (s *Store) publicLinks(userID pgtype.UUID) []*db.Link {
+	var links []*db.Link
+	for _, link := range s.links {
+		if link.UserID == userID && link.PublicAt.Valid {
+			links = append(links, link)
+		}
+	}
+	return links
+}
+
+// publicTitle falls back from the saved title to the archived one to the
+// URL.
+func (s *Store) publicTitle(link *db.Link) string {
+	if link.Title.Valid {
+		return link.Title.String
+	}
+	if archive := s.archives[link.ID.Bytes]; archive.Title.Valid {
+		return archive.Title.String
+	}
+	return link.Url
+}
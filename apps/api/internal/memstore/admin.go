@@ -0,0 +1,175 @@
+package memstore
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"github.com/example/keepstack/apps/api/internal/db"
+)
+
+// CreateUser adds a user. Emails are unique.
+func (s *Store) CreateUser(ctx context.Context, arg db.CreateUserParams) (db.User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, user := range s.users {
+		if user.Email == arg.Email {
+			return db.User{}, uniqueViolation("users_email_key")
+		}
+	}
+	user := db.User{ID: newUUID(), Email: arg.Email, PasswordHash: arg.PasswordHash, CreatedAt: s.timestamp()}
+	s.users[user.ID.Bytes] = user
+	return user, nil
+}
+
+// GetUserByEmail finds a user by email, ignoring case.
+func (s *Store) GetUserByEmail(ctx context.Context, email string) (db.User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, user := range s.users {
+		if strings.EqualFold(user.Email, email) {
+			return user, nil
+		}
+	}
+	return db.User{}, pgx.ErrNoRows
+}
+
+// ListBackupRuns lists no runs: nothing backs up an in-memory store.
+func (s *Store) ListBackupRuns(ctx context.Context, arg db.ListBackupRunsParams) ([]db.BackupRun, error) {
+	return []db.BackupRun{}, nil
+}
+
+// CreateCronRun records a job starting.
+func (s *Store) CreateCronRun(ctx context.Context, subcommand string) (db.CronRun, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	run := db.CronRun{ID: newUUID(), Subcommand: subcommand, StartedAt: s.timestamp(), Status: "running"}
+	s.cronRuns[run.ID.Bytes] = run
+	return run, nil
+}
+
+// FinishCronRun records how a job ended.
+func (s *Store) FinishCronRun(ctx context.Context, arg db.FinishCronRunParams) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	run, ok := s.cronRuns[arg.ID.Bytes]
+	if !ok {
+		return nil
+	}
+	run.FinishedAt = s.timestamp()
+	run.Status = arg.Status
+	run.DurationMs = arg.DurationMs
+	run.Counts = arg.Counts
+	run.Error = arg.Error
+	s.cronRuns[arg.ID.Bytes] = run
+	return nil
+}
+
+// ListCronRuns pages through job runs, newest first.
+func (s *Store) ListCronRuns(ctx context.Context, arg db.ListCronRunsParams) ([]db.CronRun, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var runs []db.CronRun
+	for _, run := range s.cronRuns {
+		if arg.Subcommand.Valid && run.Subcommand != arg.Subcommand.String {
+			continue
+		}
+		if arg.Status.Valid && run.Status != arg.Status.String {
+			continue
+		}
+		runs = append(runs, run)
+	}
+	sort.Slice(runs, func(i, j int) bool { return runs[i].StartedAt.Time.After(runs[j].StartedAt.Time) })
+	return page(runs, arg.PageOffset, arg.PageLimit), nil
+}
+
+// CreateInboundHook adds a webhook that saves links. Token hashes are
+// unique.
+func (s *Store) CreateInboundHook(ctx context.Context, arg db.CreateInboundHookParams) (db.InboundHook, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.users[arg.UserID.Bytes]; !ok {
+		return db.InboundHook{}, foreignKeyViolation("inbound_hooks_user_id_fkey")
+	}
+	for _, hook := range s.hooks {
+		if hook.TokenHash == arg.TokenHash {
+			return db.InboundHook{}, uniqueViolation("inbound_hooks_token_hash_key")
+		}
+	}
+	hook := db.InboundHook{
+		ID:          newUUID(),
+		UserID:      arg.UserID,
+		Name:        arg.Name,
+		TokenHash:   arg.TokenHash,
+		UrlField:    arg.UrlField,
+		TitleField:  arg.TitleField,
+		TagsField:   arg.TagsField,
+		DefaultTags: arg.DefaultTags,
+		CreatedAt:   s.timestamp(),
+	}
+	s.hooks[hook.ID.Bytes] = hook
+	return hook, nil
+}
+
+// ListInboundHooks lists a user's webhooks, oldest first.
+func (s *Store) ListInboundHooks(ctx context.Context, userID pgtype.UUID) ([]db.InboundHook, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var hooks []db.InboundHook
+	for _, hook := range s.hooks {
+		if hook.UserID == userID {
+			hooks = append(hooks, hook)
+		}
+	}
+	sort.Slice(hooks, func(i, j int) bool { return hooks[i].CreatedAt.Time.Before(hooks[j].CreatedAt.Time) })
+	return hooks, nil
+}
+
+// GetInboundHookByTokenHash returns the webhook a token belongs to.
+func (s *Store) GetInboundHookByTokenHash(ctx context.Context, tokenHash string) (db.InboundHook, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, hook := range s.hooks {
+		if hook.TokenHash == tokenHash {
+			return hook, nil
+		}
+	}
+	return db.InboundHook{}, pgx.ErrNoRows
+}
+
+// TouchInboundHook records that a webhook was just used.
+func (s *Store) TouchInboundHook(ctx context.Context, id pgtype.UUID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if hook, ok := s.hooks[id.Bytes]; ok {
+		hook.LastUsedAt = s.timestamp()
+		s.hooks[id.Bytes] = hook
+	}
+	return nil
+}
+
+// DeleteInboundHook removes one of the user's webhooks and reports how many
+// it removed.
+func (s *Store) DeleteInboundHook(ctx context.Context, arg db.DeleteInboundHookParams) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	hook, ok := s.hooks[arg.ID.Bytes]
+	if !ok || hook.UserID != arg.UserID {
+		return 0, nil
+	}
+	delete(s.hooks, arg.ID.Bytes)
+	return 1, nil
+}
@@ -0,0 +1,97 @@
+package memstore
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"github.com/example/keepstack/apps/api/internal/db"
+)
+
+// maxArchiveVersions mirrors the number of previous versions migration
+// 000026's trigger keeps per link.
+const maxArchiveVersions = 20
+
+// putArchive stores archive, keeping the text it replaces as a previous
+// version the way migration 000026's trigger does.
+func (s *Store) putArchive(archive db.Archive) {
+	old, ok := s.archives[archive.LinkID.Bytes]
+	if ok && old.ExtractedText.String != "" && old.ExtractedText != archive.ExtractedText {
+		s.nextArchiveVersion++
+		versions := append(s.archiveVersions[archive.LinkID.Bytes], db.ArchiveVersion{
+			ID:            s.nextArchiveVersion,
+			LinkID:        old.LinkID,
+			Title:         old.Title,
+			ExtractedText: old.ExtractedText.String,
+			WordCount:     old.WordCount,
+			ArchivedAt:    old.UpdatedAt,
+			ReplacedAt:    s.timestamp(),
+		})
+		if len(versions) > maxArchiveVersions {
+			versions = versions[len(versions)-maxArchiveVersions:]
+		}
+		s.archiveVersions[archive.LinkID.Bytes] = versions
+	}
+	s.archives[archive.LinkID.Bytes] = archive
+}
+
+// contentSHA256 digests archive content the way the worker does at ingest.
+func contentSHA256(content string) pgtype.Text {
+	sum := sha256.Sum256([]byte(content))
+	return text(hex.EncodeToString(sum[:]))
+}
+
+// GetCurrentArchiveVersion returns the text a link's archive has now.
+func (s *Store) GetCurrentArchiveVersion(ctx context.Context, linkID pgtype.UUID) (db.GetCurrentArchiveVersionRow, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	archive, ok := s.archives[linkID.Bytes]
+	if !ok {
+		return db.GetCurrentArchiveVersionRow{}, pgx.ErrNoRows
+	}
+	return db.GetCurrentArchiveVersionRow{
+		Title:         archive.Title.String,
+		ExtractedText: archive.ExtractedText.String,
+		WordCount:     archive.WordCount.Int32,
+		UpdatedAt:     archive.UpdatedAt,
+		HtmlSha256:    archive.HtmlSha256.String,
+		TextSha256:    archive.TextSha256.String,
+	}, nil
+}
+
+// ListArchiveVersions lists a link's previous archive versions, newest
+// first.
+func (s *Store) ListArchiveVersions(ctx context.Context, linkID pgtype.UUID) ([]db.ListArchiveVersionsRow, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	versions := s.archiveVersions[linkID.Bytes]
+	rows := make([]db.ListArchiveVersionsRow, 0, len(versions))
+	for i := len(versions) - 1; i >= 0; i-- {
+		rows = append(rows, db.ListArchiveVersionsRow{
+			ID:         versions[i].ID,
+			Title:      versions[i].Title.String,
+			WordCount:  versions[i].WordCount.Int32,
+			ArchivedAt: versions[i].ArchivedAt,
+			ReplacedAt: versions[i].ReplacedAt,
+		})
+	}
+	return rows, nil
+}
+
+// GetArchiveVersion returns one of a link's previous archive versions.
+func (s *Store) GetArchiveVersion(ctx context.Context, arg db.GetArchiveVersionParams) (db.ArchiveVersion, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, version := range s.archiveVersions[arg.LinkID.Bytes] {
+		if version.ID == arg.ID {
+			return version, nil
+		}
+	}
+	return db.ArchiveVersion{}, pgx.ErrNoRows
+}
@@ -0,0 +1,104 @@
+package memstore
+
+import (
+	"context"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"github.com/example/keepstack/apps/api/internal/db"
+)
+
+// domainKey identifies a user's preference for one domain.
+type domainKey struct {
+	userID [16]byte
+	domain string
+}
+
+// fullHostPattern mirrors the url_host SQL function.
+var fullHostPattern = regexp.MustCompile(`^[a-zA-Z][a-zA-Z0-9+.-]*://(?:[^/?#@]*@)?([^/:?#]+)`)
+
+// UpsertDomainPreference sets a user's preference for a domain, replacing an
+// existing one.
+func (s *Store) UpsertDomainPreference(ctx context.Context, arg db.UpsertDomainPreferenceParams) (db.DomainPreference, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.users[arg.UserID.Bytes]; !ok {
+		return db.DomainPreference{}, foreignKeyViolation("domain_preferences_user_id_fkey")
+	}
+	key := domainKey{userID: arg.UserID.Bytes, domain: arg.Domain}
+	pref, ok := s.domainPrefs[key]
+	if !ok {
+		pref = db.DomainPreference{UserID: arg.UserID, Domain: arg.Domain, CreatedAt: s.timestamp()}
+	}
+	pref.AlwaysFavorite = arg.AlwaysFavorite
+	pref.DefaultTags = append([]string{}, arg.DefaultTags...)
+	pref.NeverResurface = arg.NeverResurface
+	pref.FetchHeaders = append([]byte(nil), arg.FetchHeaders...)
+	pref.UpdatedAt = s.timestamp()
+	s.domainPrefs[key] = pref
+	return pref, nil
+}
+
+// ListDomainPreferences lists a user's domain preferences by domain.
+func (s *Store) ListDomainPreferences(ctx context.Context, userID pgtype.UUID) ([]db.DomainPreference, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var rows []db.DomainPreference
+	for key, pref := range s.domainPrefs {
+		if key.userID == userID.Bytes {
+			rows = append(rows, pref)
+		}
+	}
+	sort.Slice(rows, func(i, j int) bool { return rows[i].Domain < rows[j].Domain })
+	return rows, nil
+}
+
+// MatchDomainPreference returns the user's most specific preference whose
+// domain is arg.Url's host or a parent of it.
+func (s *Store) MatchDomainPreference(ctx context.Context, arg db.MatchDomainPreferenceParams) (db.DomainPreference, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	host := ""
+	if match := fullHostPattern.FindStringSubmatch(arg.Url); match != nil {
+		host = strings.ToLower(match[1])
+	}
+	var found *db.DomainPreference
+	for key, pref := range s.domainPrefs {
+		if key.userID != arg.UserID.Bytes || !domainCovers(pref.Domain, host) {
+			continue
+		}
+		if found == nil || len(pref.Domain) > len(found.Domain) {
+			found = &pref
+		}
+	}
+	if found == nil {
+		return db.DomainPreference{}, pgx.ErrNoRows
+	}
+	return *found, nil
+}
+
+// DeleteDomainPreference removes a user's preference for a domain and
+// reports how many it removed.
+func (s *Store) DeleteDomainPreference(ctx context.Context, arg db.DeleteDomainPreferenceParams) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := domainKey{userID: arg.UserID.Bytes, domain: arg.Domain}
+	if _, ok := s.domainPrefs[key]; !ok {
+		return 0, nil
+	}
+	delete(s.domainPrefs, key)
+	return 1, nil
+}
+
+// domainCovers mirrors the domain_covers SQL function.
+func domainCovers(domain, host string) bool {
+	return host == domain || strings.HasSuffix(host, "."+domain)
+}
@@ -0,0 +1,109 @@
+package memstore
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"github.com/example/keepstack/apps/api/internal/db"
+)
+
+// goalKey identifies a user's goal for one metric.
+type goalKey struct {
+	userID [16]byte
+	metric string
+}
+
+// UpsertReadingGoal sets a user's weekly target for a metric, replacing an
+// existing target.
+func (s *Store) UpsertReadingGoal(ctx context.Context, arg db.UpsertReadingGoalParams) (db.ReadingGoal, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.users[arg.UserID.Bytes]; !ok {
+		return db.ReadingGoal{}, foreignKeyViolation("reading_goals_user_id_fkey")
+	}
+	key := goalKey{userID: arg.UserID.Bytes, metric: arg.Metric}
+	goal, ok := s.goals[key]
+	if !ok {
+		goal = db.ReadingGoal{UserID: arg.UserID, Metric: arg.Metric, CreatedAt: s.timestamp()}
+	}
+	goal.WeeklyTarget = arg.WeeklyTarget
+	goal.UpdatedAt = s.timestamp()
+	s.goals[key] = goal
+	return goal, nil
+}
+
+// ListReadingGoals lists a user's goals by metric.
+func (s *Store) ListReadingGoals(ctx context.Context, userID pgtype.UUID) ([]db.ReadingGoal, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var rows []db.ReadingGoal
+	for key, goal := range s.goals {
+		if key.userID == userID.Bytes {
+			rows = append(rows, goal)
+		}
+	}
+	sort.Slice(rows, func(i, j int) bool { return rows[i].Metric < rows[j].Metric })
+	return rows, nil
+}
+
+// DeleteReadingGoal removes a user's goal for a metric and reports how many
+// it removed.
+func (s *Store) DeleteReadingGoal(ctx context.Context, arg db.DeleteReadingGoalParams) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := goalKey{userID: arg.UserID.Bytes, metric: arg.Metric}
+	if _, ok := s.goals[key]; !ok {
+		return 0, nil
+	}
+	delete(s.goals, key)
+	return 1, nil
+}
+
+// GetReadingProgress counts the links a user read since a time and the
+// minutes they took, rounding each link's reading time up.
+func (s *Store) GetReadingProgress(ctx context.Context, arg db.GetReadingProgressParams) (db.GetReadingProgressRow, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var row db.GetReadingProgressRow
+	for id, link := range s.links {
+		if link.UserID != arg.UserID || !link.ReadAt.Valid || link.ReadAt.Time.Before(arg.Since.Time) {
+			continue
+		}
+		row.Articles++
+		words := int64(s.archives[id].WordCount.Int32)
+		wpm := int64(arg.WordsPerMinute)
+		row.Minutes += (words + wpm - 1) / wpm
+	}
+	return row, nil
+}
+
+// ListReadDays lists the UTC days a user read at least one link, newest
+// first.
+func (s *Store) ListReadDays(ctx context.Context, userID pgtype.UUID) ([]pgtype.Date, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	seen := make(map[time.Time]struct{})
+	var days []pgtype.Date
+	for _, link := range s.links {
+		if link.UserID != userID || !link.ReadAt.Valid {
+			continue
+		}
+		t := link.ReadAt.Time.UTC()
+		day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+		if _, ok := seen[day]; ok {
+			continue
+		}
+		seen[day] = struct{}{}
+		days = append(days, pgtype.Date{Time: day, Valid: true})
+	}
+	sort.Slice(days, func(i, j int) bool { return days[i].Time.After(days[j].Time) })
+	return days, nil
+}
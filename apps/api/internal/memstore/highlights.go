@@ -0,0 +1,109 @@
+package memstore
+
+import (
+	"context"
+	"sort"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"github.com/example/keepstack/apps/api/internal/db"
+)
+
+// CreateHighlight adds a highlight to a link. A nil ID gets a random one.
+func (s *Store) CreateHighlight(ctx context.Context, arg db.CreateHighlightParams) (db.Highlight, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	id := newUUID()
+	if given, ok := arg.ID.(pgtype.UUID); ok && given.Valid {
+		id = given
+	}
+	if _, ok := s.highlights[id.Bytes]; ok {
+		return db.Highlight{}, uniqueViolation("highlights_pkey")
+	}
+	link, ok := s.links[arg.LinkID.Bytes]
+	if !ok {
+		return db.Highlight{}, foreignKeyViolation("highlights_link_id_fkey")
+	}
+	now := s.timestamp()
+	highlight := db.Highlight{
+		ID:         id,
+		LinkID:     arg.LinkID,
+		Quote:      arg.Text,
+		Annotation: arg.Note,
+		CreatedAt:  now,
+		UpdatedAt:  now,
+	}
+	s.highlights[id.Bytes] = highlight
+	s.record(s.begin(), link.UserID, "highlight", id, false)
+	return highlight, nil
+}
+
+// UpdateHighlight replaces a highlight's text and note.
+func (s *Store) UpdateHighlight(ctx context.Context, arg db.UpdateHighlightParams) (db.Highlight, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	highlight, ok := s.highlights[arg.ID.Bytes]
+	if !ok {
+		return db.Highlight{}, pgx.ErrNoRows
+	}
+	highlight.Quote = arg.Text
+	highlight.Annotation = arg.Note
+	highlight.UpdatedAt = s.timestamp()
+	s.highlights[arg.ID.Bytes] = highlight
+	s.record(s.begin(), s.links[highlight.LinkID.Bytes].UserID, "highlight", highlight.ID, false)
+	return highlight, nil
+}
+
+// DeleteHighlight removes a highlight.
+func (s *Store) DeleteHighlight(ctx context.Context, id pgtype.UUID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	highlight, ok := s.highlights[id.Bytes]
+	if !ok {
+		return nil
+	}
+	delete(s.highlights, id.Bytes)
+	s.record(s.begin(), s.links[highlight.LinkID.Bytes].UserID, "highlight", highlight.ID, true)
+	return nil
+}
+
+// ListHighlightsByLink lists a link's highlights, newest first.
+func (s *Store) ListHighlightsByLink(ctx context.Context, linkID pgtype.UUID) ([]db.Highlight, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.linkHighlights(linkID.Bytes), nil
+}
+
+// ListSyncHighlights returns the highlights among Ids on the user's links.
+func (s *Store) ListSyncHighlights(ctx context.Context, arg db.ListSyncHighlightsParams) ([]db.Highlight, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var highlights []db.Highlight
+	for _, id := range arg.Ids {
+		highlight, ok := s.highlights[id.Bytes]
+		if !ok || s.links[highlight.LinkID.Bytes].UserID != arg.UserID {
+			continue
+		}
+		highlights = append(highlights, highlight)
+	}
+	return highlights, nil
+}
+
+func (s *Store) linkHighlights(linkID [16]byte) []db.Highlight {
+	var highlights []db.Highlight
+	for _, highlight := range s.highlights {
+		if highlight.LinkID.Bytes == linkID {
+			highlights = append(highlights, highlight)
+		}
+	}
+	sort.Slice(highlights, func(i, j int) bool {
+		return highlights[i].CreatedAt.Time.After(highlights[j].CreatedAt.Time)
+	})
+	return highlights
+}
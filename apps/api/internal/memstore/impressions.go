@@ -0,0 +1,128 @@
+package memstore
+
+import (
+	"context"
+	"sort"
+
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"github.com/example/keepstack/apps/api/internal/db"
+)
+
+// RecordRecommendationImpressions records that the links were shown, skipping
+// any still waiting on an action from a showing after RepeatAfter.
+func (s *Store) RecordRecommendationImpressions(ctx context.Context, arg db.RecordRecommendationImpressionsParams) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, linkID := range arg.LinkIds {
+		if _, ok := s.links[linkID.Bytes]; !ok {
+			return 0, foreignKeyViolation("recommendation_impressions_link_id_fkey")
+		}
+	}
+
+	var recorded int64
+	for i, linkID := range arg.LinkIds {
+		if s.recentImpression(arg, linkID) {
+			continue
+		}
+		s.nextImpressionID++
+		impression := db.RecommendationImpression{
+			ID:       s.nextImpressionID,
+			UserID:   arg.UserID,
+			LinkID:   linkID,
+			Strategy: arg.Strategy,
+			Surface:  arg.Surface,
+			ShownAt:  arg.ShownAt,
+		}
+		if i < len(arg.Positions) {
+			impression.Position = arg.Positions[i]
+		}
+		s.impressions = append(s.impressions, impression)
+		recorded++
+	}
+	return recorded, nil
+}
+
+func (s *Store) recentImpression(arg db.RecordRecommendationImpressionsParams, linkID pgtype.UUID) bool {
+	for _, impression := range s.impressions {
+		if impression.UserID == arg.UserID && impression.LinkID == linkID &&
+			impression.Strategy == arg.Strategy && impression.Surface == arg.Surface &&
+			!impression.ActedAt.Valid && impression.ShownAt.Time.After(arg.RepeatAfter.Time) {
+			return true
+		}
+	}
+	return false
+}
+
+// MarkRecommendationImpressionsActed records an action against the links'
+// unacted impressions shown after ShownAfter and returns their strategies.
+func (s *Store) MarkRecommendationImpressionsActed(ctx context.Context, arg db.MarkRecommendationImpressionsActedParams) ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ids := make(map[[16]byte]struct{}, len(arg.LinkIds))
+	for _, id := range arg.LinkIds {
+		ids[id.Bytes] = struct{}{}
+	}
+	var strategies []string
+	for i := range s.impressions {
+		impression := &s.impressions[i]
+		if _, ok := ids[impression.LinkID.Bytes]; !ok || impression.UserID != arg.UserID {
+			continue
+		}
+		if impression.ActedAt.Valid || !impression.ShownAt.Time.After(arg.ShownAfter.Time) {
+			continue
+		}
+		impression.Action = arg.Action
+		impression.ActedAt = arg.ActedAt
+		strategies = append(strategies, impression.Strategy)
+	}
+	return strategies, nil
+}
+
+// ListRecommendationImpressionStats totals a user's impressions and actions
+// since Since by strategy and surface.
+func (s *Store) ListRecommendationImpressionStats(ctx context.Context, arg db.ListRecommendationImpressionStatsParams) ([]db.ListRecommendationImpressionStatsRow, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	type statsKey struct{ strategy, surface string }
+	totals := make(map[statsKey]*db.ListRecommendationImpressionStatsRow)
+	for _, impression := range s.impressions {
+		if impression.UserID != arg.UserID || impression.ShownAt.Time.Before(arg.Since.Time) {
+			continue
+		}
+		key := statsKey{impression.Strategy, impression.Surface}
+		row := totals[key]
+		if row == nil {
+			row = &db.ListRecommendationImpressionStatsRow{Strategy: key.strategy, Surface: key.surface}
+			totals[key] = row
+		}
+		row.Impressions++
+		if !impression.ActedAt.Valid {
+			continue
+		}
+		row.Acted++
+		switch impression.Action.String {
+		case "open":
+			row.Opened++
+		case "read":
+			row.Read++
+		case "favorite":
+			row.Favorited++
+		}
+	}
+
+	rows := make([]db.ListRecommendationImpressionStatsRow, 0, len(totals))
+	for _, row := range totals {
+		rows = append(rows, *row)
+	}
+	sort.Slice(rows, func(i, j int) bool {
+		if rows[i].Strategy != rows[j].Strategy {
+			return rows[i].Strategy < rows[j].Strategy
+		}
+		return rows[i].Surface < rows[j].Surface
+	})
+	return rows, nil
+}
@@ -0,0 +1,126 @@
+package memstore
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"github.com/example/keepstack/apps/api/internal/db"
+)
+
+// CreateJob records a queued job for a user.
+func (s *Store) CreateJob(ctx context.Context, arg db.CreateJobParams) (db.Job, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.users[arg.UserID.Bytes]; !ok {
+		return db.Job{}, foreignKeyViolation("jobs_user_id_fkey")
+	}
+	now := s.timestamp()
+	job := db.Job{
+		ID:        newUUID(),
+		UserID:    arg.UserID,
+		Type:      arg.Type,
+		State:     "queued",
+		Total:     arg.Total,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	s.jobs[job.ID.Bytes] = job
+	return job, nil
+}
+
+// GetJob returns one of the user's jobs.
+func (s *Store) GetJob(ctx context.Context, arg db.GetJobParams) (db.Job, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	job, ok := s.jobs[arg.ID.Bytes]
+	if !ok || job.UserID != arg.UserID {
+		return db.Job{}, pgx.ErrNoRows
+	}
+	return job, nil
+}
+
+// UpdateJobProgress marks a job running and records how far it got.
+func (s *Store) UpdateJobProgress(ctx context.Context, arg db.UpdateJobProgressParams) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if job, ok := s.jobs[arg.ID.Bytes]; ok {
+		job.State = "running"
+		job.Done = arg.Done
+		job.Total = arg.Total
+		job.UpdatedAt = s.timestamp()
+		s.jobs[arg.ID.Bytes] = job
+	}
+	return nil
+}
+
+// FinishJob records how a job ended.
+func (s *Store) FinishJob(ctx context.Context, arg db.FinishJobParams) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if job, ok := s.jobs[arg.ID.Bytes]; ok {
+		job.State = arg.State
+		job.Done = arg.Done
+		job.Result = arg.Result
+		job.Error = arg.Error
+		job.UpdatedAt = s.timestamp()
+		job.FinishedAt = job.UpdatedAt
+		s.jobs[arg.ID.Bytes] = job
+	}
+	return nil
+}
+
+// SaveJobFile stores the file a job produced, replacing any earlier one.
+func (s *Store) SaveJobFile(ctx context.Context, arg db.SaveJobFileParams) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.jobs[arg.JobID.Bytes]; !ok {
+		return foreignKeyViolation("job_files_job_id_fkey")
+	}
+	s.jobFiles[arg.JobID.Bytes] = db.JobFile{
+		JobID:       arg.JobID,
+		Name:        arg.Name,
+		ContentType: arg.ContentType,
+		Body:        arg.Body,
+	}
+	return nil
+}
+
+// GetJobFile returns the file one of the user's jobs produced.
+func (s *Store) GetJobFile(ctx context.Context, arg db.GetJobFileParams) (db.JobFile, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	job, ok := s.jobs[arg.JobID.Bytes]
+	if !ok || job.UserID != arg.UserID {
+		return db.JobFile{}, pgx.ErrNoRows
+	}
+	file, ok := s.jobFiles[arg.JobID.Bytes]
+	if !ok {
+		return db.JobFile{}, pgx.ErrNoRows
+	}
+	return file, nil
+}
+
+// DeleteJobsFinishedBefore removes finished jobs, and their files, older
+// than before.
+func (s *Store) DeleteJobsFinishedBefore(ctx context.Context, before pgtype.Timestamptz) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var deleted int64
+	for id, job := range s.jobs {
+		if job.FinishedAt.Valid && job.FinishedAt.Time.Before(before.Time) {
+			delete(s.jobs, id)
+			delete(s.jobFiles, id)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
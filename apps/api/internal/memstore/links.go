@@ -0,0 +1,676 @@
+package memstore
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"github.com/example/keepstack/apps/api/internal/db"
+)
+
+// CreateLink stores a new link.
+func (s *Store) CreateLink(ctx context.Context, arg db.CreateLinkParams) (db.CreateLinkRow, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.links[arg.ID.Bytes]; ok {
+		return db.CreateLinkRow{}, uniqueViolation("links_pkey")
+	}
+	if _, ok := s.users[arg.UserID.Bytes]; !ok {
+		return db.CreateLinkRow{}, foreignKeyViolation("links_user_id_fkey")
+	}
+	now := s.timestamp()
+	link := &db.Link{
+		ID:            arg.ID,
+		UserID:        arg.UserID,
+		Url:           arg.Url,
+		Title:         arg.Title,
+		CreatedAt:     now,
+		Favorite:      boolValue(arg.Favorite),
+		UpdatedAt:     now,
+		OriginalTitle: arg.Title,
+		PreserveTitle: boolValue(arg.PreserveTitle),
+	}
+	s.links[arg.ID.Bytes] = link
+	s.record(s.begin(), link.UserID, "link", link.ID, false)
+	return db.CreateLinkRow{
+		ID:        link.ID,
+		UserID:    link.UserID,
+		Url:       link.Url,
+		Title:     link.Title,
+		CreatedAt: link.CreatedAt,
+		ReadAt:    link.ReadAt,
+		Favorite:  link.Favorite,
+	}, nil
+}
+
+// GetLink returns a link.
+func (s *Store) GetLink(ctx context.Context, id pgtype.UUID) (db.GetLinkRow, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	link, ok := s.links[id.Bytes]
+	if !ok {
+		return db.GetLinkRow{}, pgx.ErrNoRows
+	}
+	return db.GetLinkRow{
+		ID:        link.ID,
+		UserID:    link.UserID,
+		Url:       link.Url,
+		Title:     link.Title,
+		CreatedAt: link.CreatedAt,
+		ReadAt:    link.ReadAt,
+		Favorite:  link.Favorite,
+	}, nil
+}
+
+// ListLinks pages through a user's links, newest first or, with
+// SortRelevance and a query, best match first. Like the SQL query, it ignores
+// TagIds.
+func (s *Store) ListLinks(ctx context.Context, arg db.ListLinksParams) ([]db.ListLinksRow, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	links := s.filterLinks(arg.UserID, arg.Favorite, arg.Query, arg.EnableFullText, nil, arg.ClaimedBy, arg.HasHighlights)
+	if arg.SortRelevance && arg.EnableFullText && arg.Query.Valid {
+		s.sortByRelevance(links, arg.Query.String)
+	}
+	links = page(links, arg.PageOffset, arg.PageLimit)
+	rows := make([]db.ListLinksRow, 0, len(links))
+	for _, link := range links {
+		rows = append(rows, s.linkRow(link))
+	}
+	return rows, nil
+}
+
+// ListLinksWithTags pages through a user's links carrying every tag in
+// TagIds, ordered as ListLinks orders them.
+func (s *Store) ListLinksWithTags(ctx context.Context, arg db.ListLinksWithTagsParams) ([]db.ListLinksWithTagsRow, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	links := s.filterLinks(arg.UserID, arg.Favorite, arg.Query, arg.EnableFullText, arg.TagIds, arg.ClaimedBy, arg.HasHighlights)
+	if arg.SortRelevance && arg.EnableFullText && arg.Query.Valid {
+		s.sortByRelevance(links, arg.Query.String)
+	}
+	links = page(links, arg.PageOffset, arg.PageLimit)
+	rows := make([]db.ListLinksWithTagsRow, 0, len(links))
+	for _, link := range links {
+		rows = append(rows, db.ListLinksWithTagsRow(s.linkRow(link)))
+	}
+	return rows, nil
+}
+
+// CountLinks counts the links ListLinks pages through.
+func (s *Store) CountLinks(ctx context.Context, arg db.CountLinksParams) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return int64(len(s.filterLinks(arg.UserID, arg.Favorite, arg.Query, arg.EnableFullText, nil, arg.ClaimedBy, arg.HasHighlights))), nil
+}
+
+// CountLinksWithTags counts the links ListLinksWithTags pages through.
+func (s *Store) CountLinksWithTags(ctx context.Context, arg db.CountLinksWithTagsParams) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return int64(len(s.filterLinks(arg.UserID, arg.Favorite, arg.Query, arg.EnableFullText, arg.TagIds, arg.ClaimedBy, arg.HasHighlights))), nil
+}
+
+// UpdateLinkFavorite sets a link's favorite flag and returns it as listed.
+func (s *Store) UpdateLinkFavorite(ctx context.Context, arg db.UpdateLinkFavoriteParams) (db.UpdateLinkFavoriteRow, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	link, ok := s.links[arg.ID.Bytes]
+	if !ok {
+		return db.UpdateLinkFavoriteRow{}, pgx.ErrNoRows
+	}
+	if link.Favorite != arg.Favorite {
+		link.Favorite = arg.Favorite
+		s.touch(s.begin(), link)
+	}
+	return db.UpdateLinkFavoriteRow(s.linkRow(link)), nil
+}
+
+// MarkLinksRead marks the user's unread links among Ids read and returns
+// the ones it changed.
+func (s *Store) MarkLinksRead(ctx context.Context, arg db.MarkLinksReadParams) ([]pgtype.UUID, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tx := s.begin()
+	var marked []pgtype.UUID
+	for _, id := range arg.Ids {
+		link, ok := s.links[id.Bytes]
+		if !ok || link.UserID != arg.UserID || link.ReadAt.Valid {
+			continue
+		}
+		link.ReadAt = s.timestamp()
+		s.touch(tx, link)
+		marked = append(marked, link.ID)
+	}
+	return marked, nil
+}
+
+// MarkLinksReadAt marks each unread link in arg.Ids read at the matching
+// time in arg.ReadAts.
+func (s *Store) MarkLinksReadAt(ctx context.Context, arg db.MarkLinksReadAtParams) ([]pgtype.UUID, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tx := s.begin()
+	var marked []pgtype.UUID
+	for i, id := range arg.Ids {
+		link, ok := s.links[id.Bytes]
+		if !ok || link.UserID != arg.UserID || link.ReadAt.Valid || i >= len(arg.ReadAts) {
+			continue
+		}
+		link.ReadAt = arg.ReadAts[i]
+		s.touch(tx, link)
+		marked = append(marked, link.ID)
+	}
+	return marked, nil
+}
+
+// UpdateLinkSnooze sets or clears when a link may be recommended again.
+func (s *Store) UpdateLinkSnooze(ctx context.Context, arg db.UpdateLinkSnoozeParams) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if link, ok := s.links[arg.ID.Bytes]; ok {
+		link.SnoozedUntil = arg.SnoozedUntil
+	}
+	return nil
+}
+
+// UpdateSyncLink applies a sync client's edit to one of the user's links and
+// reports how many links it changed.
+func (s *Store) UpdateSyncLink(ctx context.Context, arg db.UpdateSyncLinkParams) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	link, ok := s.links[arg.ID.Bytes]
+	if !ok || link.UserID != arg.UserID {
+		return 0, nil
+	}
+	changed := link.Title != arg.Title || link.Favorite != arg.Favorite || !sameTime(link.ReadAt, arg.ReadAt)
+	link.Title = arg.Title
+	link.Favorite = arg.Favorite
+	link.ReadAt = arg.ReadAt
+	if changed {
+		s.touch(s.begin(), link)
+	}
+	return 1, nil
+}
+
+// DeleteLink removes one of the user's links along with everything that
+// references it, and reports how many links it removed.
+func (s *Store) DeleteLink(ctx context.Context, arg db.DeleteLinkParams) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	link, ok := s.links[arg.ID.Bytes]
+	if !ok || link.UserID != arg.UserID {
+		return 0, nil
+	}
+	id := arg.ID.Bytes
+	delete(s.links, id)
+	delete(s.archives, id)
+	delete(s.archiveVersions, id)
+	delete(s.statuses, id)
+	delete(s.captures, id)
+	delete(s.recommendations, id)
+	delete(s.linkTags, id)
+	for key, claim := range s.claims {
+		if claim.LinkID.Bytes == id {
+			delete(s.claims, key)
+		}
+	}
+	for key, share := range s.shares {
+		if share.LinkID.Bytes == id {
+			delete(s.shares, key)
+		}
+	}
+	for key, notification := range s.notifications {
+		if notification.LinkID.Valid && notification.LinkID.Bytes == id {
+			delete(s.notifications, key)
+		}
+	}
+	impressions := s.impressions[:0]
+	for _, impression := range s.impressions {
+		if impression.LinkID.Bytes != id {
+			impressions = append(impressions, impression)
+		}
+	}
+	s.impressions = impressions
+	// Highlights go with their link without tombstones of their own.
+	for key, highlight := range s.highlights {
+		if highlight.LinkID.Bytes == id {
+			delete(s.highlights, key)
+		}
+	}
+	s.record(s.begin(), link.UserID, "link", link.ID, true)
+	return 1, nil
+}
+
+// GetReaderArchive returns a link's archived article.
+func (s *Store) GetReaderArchive(ctx context.Context, linkID pgtype.UUID) (db.GetReaderArchiveRow, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	archive, ok := s.archives[linkID.Bytes]
+	if !ok {
+		return db.GetReaderArchiveRow{}, pgx.ErrNoRows
+	}
+	return db.GetReaderArchiveRow{
+		Title:         archive.Title.String,
+		Byline:        archive.Byline.String,
+		Lang:          archive.Lang.String,
+		WordCount:     archive.WordCount.Int32,
+		Html:          archive.Html.String,
+		ExtractedText: archive.ExtractedText.String,
+		Paywalled:     archive.Paywalled,
+	}, nil
+}
+
+// ListLinkMediaHashes returns no images: nothing is mirrored in memory
+// mode.
+func (s *Store) ListLinkMediaHashes(ctx context.Context, linkID pgtype.UUID) ([]string, error) {
+	return nil, nil
+}
+
+// GetLinkIngestStatus returns how far ingestion of a link got.
+func (s *Store) GetLinkIngestStatus(ctx context.Context, linkID pgtype.UUID) (db.LinkIngestStatus, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	status, ok := s.statuses[linkID.Bytes]
+	if !ok {
+		return db.LinkIngestStatus{}, pgx.ErrNoRows
+	}
+	return status, nil
+}
+
+// FindLinkByURLHash returns the user's newest link whose URL has the given
+// hex SHA-256.
+func (s *Store) FindLinkByURLHash(ctx context.Context, arg db.FindLinkByURLHashParams) (db.FindLinkByURLHashRow, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var found *db.Link
+	for _, link := range s.links {
+		if link.UserID != arg.UserID {
+			continue
+		}
+		sum := sha256.Sum256([]byte(link.Url))
+		if hex.EncodeToString(sum[:]) != arg.UrlHash {
+			continue
+		}
+		if found == nil || link.CreatedAt.Time.After(found.CreatedAt.Time) {
+			found = link
+		}
+	}
+	if found == nil {
+		return db.FindLinkByURLHashRow{}, pgx.ErrNoRows
+	}
+	return db.FindLinkByURLHashRow{ID: found.ID, Url: found.Url, CreatedAt: found.CreatedAt}, nil
+}
+
+// ListSimilarLinks returns the user's links on host, ignoring a leading
+// www., or whose title shares a word with arg.TitleWords, newest first.
+func (s *Store) ListSimilarLinks(ctx context.Context, arg db.ListSimilarLinksParams) ([]db.ListSimilarLinksRow, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	words := make(map[string]struct{}, len(arg.TitleWords))
+	for _, word := range arg.TitleWords {
+		words[word] = struct{}{}
+	}
+	var matches []*db.Link
+	for _, link := range s.links {
+		if link.UserID != arg.UserID {
+			continue
+		}
+		if urlHost(link.Url) == arg.Host || sharesWord(link.Title.String, words) {
+			matches = append(matches, link)
+		}
+	}
+	sortNewestFirst(matches)
+	matches = page(matches, 0, arg.PageLimit)
+
+	rows := make([]db.ListSimilarLinksRow, 0, len(matches))
+	for _, link := range matches {
+		rows = append(rows, db.ListSimilarLinksRow{ID: link.ID, Url: link.Url, Title: link.Title, CreatedAt: link.CreatedAt})
+	}
+	return rows, nil
+}
+
+// UpsertLinkCapture stores the page HTML a browser captured for a link.
+func (s *Store) UpsertLinkCapture(ctx context.Context, arg db.UpsertLinkCaptureParams) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.links[arg.LinkID.Bytes]; !ok {
+		return foreignKeyViolation("link_captures_link_id_fkey")
+	}
+	s.captures[arg.LinkID.Bytes] = db.LinkCapture{LinkID: arg.LinkID, Html: arg.Html, CapturedAt: s.timestamp()}
+	return nil
+}
+
+// CreateClaim records that a user claimed a link, returning the existing
+// claim with Inserted unset when there already was one. Claiming again
+// replaces the expiry and restarts a claim that had expired.
+func (s *Store) CreateClaim(ctx context.Context, arg db.CreateClaimParams) (db.CreateClaimRow, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var key [32]byte
+	copy(key[:16], arg.LinkID.Bytes[:])
+	copy(key[16:], arg.UserID.Bytes[:])
+	if claim, ok := s.claims[key]; ok {
+		if !s.claimActive(claim) {
+			claim.ClaimedAt = s.timestamp()
+		}
+		claim.ExpiresAt = arg.ExpiresAt
+		s.claims[key] = claim
+		return db.CreateClaimRow{ID: claim.ID, LinkID: claim.LinkID, UserID: claim.UserID, ClaimedAt: claim.ClaimedAt, ExpiresAt: claim.ExpiresAt}, nil
+	}
+	if _, ok := s.links[arg.LinkID.Bytes]; !ok {
+		return db.CreateClaimRow{}, foreignKeyViolation("claims_link_id_fkey")
+	}
+	if _, ok := s.users[arg.UserID.Bytes]; !ok {
+		return db.CreateClaimRow{}, foreignKeyViolation("claims_user_id_fkey")
+	}
+	claim := db.Claim{ID: newUUID(), LinkID: arg.LinkID, UserID: arg.UserID, ClaimedAt: s.timestamp(), ExpiresAt: arg.ExpiresAt}
+	s.claims[key] = claim
+	return db.CreateClaimRow{ID: claim.ID, LinkID: claim.LinkID, UserID: claim.UserID, ClaimedAt: claim.ClaimedAt, ExpiresAt: claim.ExpiresAt, Inserted: true}, nil
+}
+
+// ListClaimsForUser lists a user's unexpired claims, newest first.
+func (s *Store) ListClaimsForUser(ctx context.Context, userID pgtype.UUID) ([]db.ListClaimsForUserRow, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var rows []db.ListClaimsForUserRow
+	for _, claim := range s.claims {
+		if claim.UserID != userID || !s.claimActive(claim) {
+			continue
+		}
+		link := s.links[claim.LinkID.Bytes]
+		rows = append(rows, db.ListClaimsForUserRow{
+			ID:        claim.ID,
+			LinkID:    claim.LinkID,
+			ClaimedAt: claim.ClaimedAt,
+			ExpiresAt: claim.ExpiresAt,
+			Url:       link.Url,
+			Title:     link.Title,
+			OwnerID:   link.UserID,
+		})
+	}
+	sort.Slice(rows, func(i, j int) bool {
+		if !rows[i].ClaimedAt.Time.Equal(rows[j].ClaimedAt.Time) {
+			return rows[i].ClaimedAt.Time.After(rows[j].ClaimedAt.Time)
+		}
+		return lessUUID(rows[i].ID, rows[j].ID)
+	})
+	return rows, nil
+}
+
+// DeleteClaim releases one of the user's claims and reports how many it
+// removed.
+func (s *Store) DeleteClaim(ctx context.Context, arg db.DeleteClaimParams) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for key, claim := range s.claims {
+		if claim.ID == arg.ID && claim.UserID == arg.UserID {
+			delete(s.claims, key)
+			return 1, nil
+		}
+	}
+	return 0, nil
+}
+
+func (s *Store) claimActive(claim db.Claim) bool {
+	return !claim.ExpiresAt.Valid || claim.ExpiresAt.Time.After(s.now())
+}
+
+// filterLinks returns the user's links matching the list filters, newest
+// first. Every tag in tagIDs must be on a link for it to match, a valid
+// claimedBy keeps only links that user has an unexpired claim on, and a
+// valid hasHighlights keeps links with, or without, highlights.
+func (s *Store) filterLinks(userID pgtype.UUID, favorite pgtype.Bool, query pgtype.Text, fullText bool, tagIDs []int32, claimedBy pgtype.UUID, hasHighlights pgtype.Bool) []*db.Link {
+	var links []*db.Link
+	for _, link := range s.links {
+		if link.UserID != userID {
+			continue
+		}
+		if favorite.Valid && link.Favorite != favorite.Bool {
+			continue
+		}
+		if query.Valid && !s.matchesQuery(link, query.String, fullText) {
+			continue
+		}
+		if !s.hasTags(link.ID.Bytes, tagIDs) {
+			continue
+		}
+		if claimedBy.Valid && !s.claimedBy(link.ID, claimedBy) {
+			continue
+		}
+		if hasHighlights.Valid && (len(s.linkHighlights(link.ID.Bytes)) > 0) != hasHighlights.Bool {
+			continue
+		}
+		links = append(links, link)
+	}
+	sortNewestFirst(links)
+	return links
+}
+
+// matchesQuery approximates the search the SQL queries run: the URL
+// contains query, or, with full text enabled, every word of query appears in
+// the link's title, byline, or archived text.
+func (s *Store) matchesQuery(link *db.Link, query string, fullText bool) bool {
+	if strings.Contains(strings.ToLower(link.Url), strings.ToLower(query)) {
+		return true
+	}
+	if !fullText {
+		return false
+	}
+	words := strings.Fields(strings.ToLower(query))
+	if len(words) == 0 {
+		return false
+	}
+	archive := s.archives[link.ID.Bytes]
+	document := strings.ToLower(strings.Join([]string{link.Title.String, archive.Title.String, archive.Byline.String, archive.ExtractedText.String}, " "))
+	return containsAll(document, words)
+}
+
+// sortByRelevance approximates the weighted ranking the SQL queries use when
+// sorting by relevance: links whose titles contain every word of query come
+// first, then those with a byline match, newest first within each group.
+func (s *Store) sortByRelevance(links []*db.Link, query string) {
+	words := strings.Fields(strings.ToLower(query))
+	weight := func(link *db.Link) int {
+		archive := s.archives[link.ID.Bytes]
+		switch {
+		case containsAll(strings.ToLower(link.Title.String+" "+archive.Title.String), words):
+			return 2
+		case containsAll(strings.ToLower(archive.Byline.String), words):
+			return 1
+		}
+		return 0
+	}
+	sort.SliceStable(links, func(i, j int) bool {
+		return weight(links[i]) > weight(links[j])
+	})
+}
+
+func containsAll(document string, words []string) bool {
+	for _, word := range words {
+		if !strings.Contains(document, word) {
+			return false
+		}
+	}
+	return true
+}
+
+func (s *Store) claimedBy(linkID, userID pgtype.UUID) bool {
+	var key [32]byte
+	copy(key[:16], linkID.Bytes[:])
+	copy(key[16:], userID.Bytes[:])
+	claim, ok := s.claims[key]
+	return ok && s.claimActive(claim)
+}
+
+func (s *Store) hasTags(linkID [16]byte, tagIDs []int32) bool {
+	for _, id := range tagIDs {
+		if _, ok := s.linkTags[linkID][id]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// linkRow builds a link as the list queries return it, with its archive,
+// tags ordered by name, and highlights newest first as JSON.
+func (s *Store) linkRow(link *db.Link) db.ListLinksRow {
+	archive := s.archives[link.ID.Bytes]
+	tags := s.sortedTags(link.ID.Bytes)
+	tagIDs := make([]int32, 0, len(tags))
+	tagNames := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		tagIDs = append(tagIDs, tag.ID)
+		tagNames = append(tagNames, tag.Name)
+	}
+	return db.ListLinksRow{
+		ID:            link.ID,
+		UserID:        link.UserID,
+		Url:           link.Url,
+		Title:         link.Title,
+		SourceDomain:  link.SourceDomain,
+		CreatedAt:     link.CreatedAt,
+		ReadAt:        link.ReadAt,
+		Favorite:      link.Favorite,
+		OriginalTitle: link.OriginalTitle,
+		PreserveTitle: link.PreserveTitle,
+		ArchiveTitle:  archive.Title.String,
+		ArchiveByline: archive.Byline.String,
+		Lang:          archive.Lang.String,
+		WordCount:     archive.WordCount.Int32,
+		ExtractedText: archive.ExtractedText.String,
+		Paywalled:     archive.Paywalled,
+		TagIds:        tagIDs,
+		TagNames:      tagNames,
+		Highlights:    s.highlightsJSON(link.ID.Bytes),
+	}
+}
+
+type highlightJSON struct {
+	ID        string  `json:"id"`
+	LinkID    string  `json:"link_id"`
+	Text      string  `json:"text"`
+	Note      *string `json:"note"`
+	CreatedAt string  `json:"created_at"`
+	UpdatedAt string  `json:"updated_at"`
+}
+
+func (s *Store) highlightsJSON(linkID [16]byte) string {
+	highlights := s.linkHighlights(linkID)
+	items := make([]highlightJSON, 0, len(highlights))
+	for _, h := range highlights {
+		item := highlightJSON{
+			ID:        formatUUID(h.ID),
+			LinkID:    formatUUID(h.LinkID),
+			Text:      h.Quote,
+			CreatedAt: h.CreatedAt.Time.Format(time.RFC3339Nano),
+			UpdatedAt: h.UpdatedAt.Time.Format(time.RFC3339Nano),
+		}
+		if h.Annotation.Valid {
+			note := h.Annotation.String
+			item.Note = &note
+		}
+		items = append(items, item)
+	}
+	data, _ := json.Marshal(items)
+	return string(data)
+}
+
+// touch records a change to a field sync clients see.
+func (s *Store) touch(tx int64, link *db.Link) {
+	link.UpdatedAt = s.timestamp()
+	s.record(tx, link.UserID, "link", link.ID, false)
+}
+
+// urlHostPattern mirrors the host expression in ListSimilarLinks.
+var urlHostPattern = regexp.MustCompile(`^[a-zA-Z][a-zA-Z0-9+.-]*://(?:www\.)?([^/:?#]+)`)
+
+func urlHost(raw string) string {
+	match := urlHostPattern.FindStringSubmatch(raw)
+	if match == nil {
+		return ""
+	}
+	return strings.ToLower(match[1])
+}
+
+// sharesWord reports whether title, split on anything but letters and
+// digits as ListSimilarLinks splits it, contains one of words.
+func sharesWord(title string, words map[string]struct{}) bool {
+	fields := strings.FieldsFunc(strings.ToLower(title), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	for _, field := range fields {
+		if _, ok := words[field]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+func sortNewestFirst(links []*db.Link) {
+	sort.Slice(links, func(i, j int) bool {
+		if !links[i].CreatedAt.Time.Equal(links[j].CreatedAt.Time) {
+			return links[i].CreatedAt.Time.After(links[j].CreatedAt.Time)
+		}
+		return lessUUID(links[j].ID, links[i].ID)
+	})
+}
+
+// page applies OFFSET and LIMIT.
+func page[T any](items []T, offset, limit int32) []T {
+	if int(offset) >= len(items) {
+		return nil
+	}
+	items = items[offset:]
+	if limit >= 0 && int(limit) < len(items) {
+		items = items[:limit]
+	}
+	return items
+}
+
+// boolValue reads the COALESCE($5, FALSE) argument CreateLink takes.
+func boolValue(value interface{}) bool {
+	switch v := value.(type) {
+	case bool:
+		return v
+	case *bool:
+		return v != nil && *v
+	case pgtype.Bool:
+		return v.Valid && v.Bool
+	default:
+		return false
+	}
+}
+
+func sameTime(a, b pgtype.Timestamptz) bool {
+	return a.Valid == b.Valid && (!a.Valid || a.Time.Equal(b.Time))
+}
@@ -0,0 +1,318 @@
+// Package memstore keeps the API's data in process memory for MEMORY_MODE,
+// so the API binary runs with no Postgres or NATS behind it. Store answers
+// the same queries the sqlc package does, with the constraints the schema
+// enforces reported as the same Postgres errors, so handlers behave as they
+// do against a database. Nothing survives a restart.
+//
+// Full-text search is approximated: a query matches when the URL contains
+// it, or, with full text enabled, when every word appears in the title or
+// archived text.
+package memstore
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgerrcode"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"github.com/example/keepstack/apps/api/internal/db"
+	"github.com/example/keepstack/apps/api/internal/seed"
+)
+
+// archivePlaceholder is the text Archive stores in place of a fetched page.
+const archivePlaceholder = "This link was archived in memory mode, so its page was not fetched. Run the worker against Postgres and NATS to see extracted articles."
+
+// Store is an in-memory stand-in for the database. It is safe for
+// concurrent use.
+type Store struct {
+	mu  sync.Mutex
+	now func() time.Time
+
+	users           map[[16]byte]db.User
+	links           map[[16]byte]*db.Link
+	archives        map[[16]byte]db.Archive
+	archiveVersions map[[16]byte][]db.ArchiveVersion
+	statuses        map[[16]byte]db.LinkIngestStatus
+	captures        map[[16]byte]db.LinkCapture
+	recommendations map[[16]byte]db.Recommendation
+	claims          map[[32]byte]db.Claim
+	tags            map[int32]db.Tag
+	linkTags        map[[16]byte]map[int32]struct{}
+	highlights      map[[16]byte]db.Highlight
+	cronRuns        map[[16]byte]db.CronRun
+	actors          map[[16]byte]db.ActivitypubActor
+	followers       map[[16]byte]map[string]db.ActivitypubFollower
+	hooks           map[[16]byte]db.InboundHook
+	shares          map[[32]byte]db.LinkShare
+	notifications   map[[16]byte]db.Notification
+	goals           map[goalKey]db.ReadingGoal
+	domainPrefs     map[domainKey]db.DomainPreference
+	profiles        map[[16]byte]db.PublicProfile
+	quotas          map[quotaKey]int32
+	impressions     []db.RecommendationImpression
+	jobs            map[[16]byte]db.Job
+	jobFiles        map[[16]byte]db.JobFile
+	changes         []syncChange
+
+	nextTagID          int32
+	nextArchiveVersion int64
+	nextImpressionID   int64
+	txID               int64
+}
+
+// New returns an empty store holding only the user devUserID, which the
+// API saves links for.
+func New(devUserID uuid.UUID) *Store {
+	s := &Store{
+		now:             func() time.Time { return time.Now().UTC() },
+		users:           make(map[[16]byte]db.User),
+		links:           make(map[[16]byte]*db.Link),
+		archives:        make(map[[16]byte]db.Archive),
+		archiveVersions: make(map[[16]byte][]db.ArchiveVersion),
+		statuses:        make(map[[16]byte]db.LinkIngestStatus),
+		captures:        make(map[[16]byte]db.LinkCapture),
+		recommendations: make(map[[16]byte]db.Recommendation),
+		claims:          make(map[[32]byte]db.Claim),
+		tags:            make(map[int32]db.Tag),
+		linkTags:        make(map[[16]byte]map[int32]struct{}),
+		highlights:      make(map[[16]byte]db.Highlight),
+		cronRuns:        make(map[[16]byte]db.CronRun),
+		actors:          make(map[[16]byte]db.ActivitypubActor),
+		followers:       make(map[[16]byte]map[string]db.ActivitypubFollower),
+		hooks:           make(map[[16]byte]db.InboundHook),
+		shares:          make(map[[32]byte]db.LinkShare),
+		notifications:   make(map[[16]byte]db.Notification),
+		goals:           make(map[goalKey]db.ReadingGoal),
+		domainPrefs:     make(map[domainKey]db.DomainPreference),
+		profiles:        make(map[[16]byte]db.PublicProfile),
+		quotas:          make(map[quotaKey]int32),
+		jobs:            make(map[[16]byte]db.Job),
+		jobFiles:        make(map[[16]byte]db.JobFile),
+	}
+	s.users[devUserID] = db.User{
+		ID:        pgUUID(devUserID),
+		Email:     seed.DevUserEmail,
+		CreatedAt: s.timestamp(),
+		IsAdmin:   true,
+	}
+	return s
+}
+
+// Seed adds dataset's links for userID the way seed.Run inserts them, so
+// memory mode starts with something to look at. Links already in the store
+// are left alone.
+func (s *Store) Seed(dataset seed.Dataset, userID uuid.UUID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.users[userID]; !ok {
+		return fmt.Errorf("seed %s: user %s does not exist", dataset.Name, userID)
+	}
+	tx := s.begin()
+	now := s.now()
+	for _, item := range dataset.Links {
+		id := seed.LinkID(item.URL)
+		if _, ok := s.links[id]; ok {
+			continue
+		}
+		createdAt := pgtype.Timestamptz{Time: now.Add(-item.Age), Valid: true}
+		link := &db.Link{
+			ID:           pgUUID(id),
+			UserID:       pgUUID(userID),
+			Url:          item.URL,
+			Title:        text(item.Title),
+			CreatedAt:    createdAt,
+			Favorite:     item.Favorite,
+			SourceDomain: text(seed.SourceDomain(item.URL)),
+			UpdatedAt:    createdAt,
+		}
+		if item.Read {
+			link.ReadAt = createdAt
+		}
+		s.links[id] = link
+		s.record(tx, link.UserID, "link", link.ID, false)
+
+		if archive := item.Archive; archive != nil {
+			s.archives[id] = db.Archive{
+				LinkID:        link.ID,
+				Html:          text("<article><p>" + archive.Text + "</p></article>"),
+				ExtractedText: text(archive.Text),
+				Title:         text(archive.Title),
+				Byline:        text(archive.Byline),
+				Lang:          text(archive.Lang),
+				WordCount:     pgtype.Int4{Int32: int32(len(strings.Fields(archive.Text))), Valid: true},
+				UpdatedAt:     createdAt,
+			}
+			s.statuses[id] = db.LinkIngestStatus{LinkID: link.ID, Status: "ingested", UpdatedAt: createdAt}
+		}
+
+		for _, name := range item.Tags {
+			tag, ok := s.tagByName(link.UserID, name)
+			if !ok {
+				s.nextTagID++
+				tag = db.Tag{ID: s.nextTagID, Name: name, UserID: link.UserID}
+				s.tags[tag.ID] = tag
+			}
+			s.tagLink(tx, id, tag.ID)
+		}
+
+		for _, h := range item.Highlights {
+			highlight := db.Highlight{
+				ID:         pgUUID(uuid.New()),
+				LinkID:     link.ID,
+				Quote:      h.Quote,
+				Annotation: text(h.Annotation),
+				CreatedAt:  createdAt,
+				UpdatedAt:  createdAt,
+			}
+			s.highlights[highlight.ID.Bytes] = highlight
+			s.record(tx, link.UserID, "highlight", highlight.ID, false)
+		}
+
+		if item.Score > 0 {
+			s.recommendations[id] = db.Recommendation{LinkID: link.ID, Score: int32(item.Score), UpdatedAt: createdAt, Strategy: "resurfacer"}
+		}
+	}
+	return nil
+}
+
+// Archive marks a saved link as ingested, as the worker would once it had
+// fetched the page, and returns the ID of the user who saved it. The page
+// is not fetched; the archive holds a placeholder text.
+func (s *Store) Archive(ctx context.Context, linkID uuid.UUID) (uuid.UUID, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	link, ok := s.links[linkID]
+	if !ok {
+		return uuid.Nil, fmt.Errorf("archive link %s: %w", linkID, pgx.ErrNoRows)
+	}
+	now := s.timestamp()
+	title := link.Title
+	if !title.Valid {
+		title = text(link.Url)
+	}
+	html := "<article><p>" + archivePlaceholder + "</p></article>"
+	s.putArchive(db.Archive{
+		LinkID:        link.ID,
+		Html:          text(html),
+		ExtractedText: text(archivePlaceholder),
+		Title:         title,
+		Lang:          text("en"),
+		WordCount:     pgtype.Int4{Int32: int32(len(strings.Fields(archivePlaceholder))), Valid: true},
+		UpdatedAt:     now,
+		HtmlSha256:    contentSHA256(html),
+		TextSha256:    contentSHA256(archivePlaceholder),
+	})
+	s.statuses[linkID] = db.LinkIngestStatus{LinkID: link.ID, Status: "ingested", UpdatedAt: now}
+	link.SourceDomain = text(seed.SourceDomain(link.Url))
+	return link.UserID.Bytes, nil
+}
+
+// begin starts a transaction for the sync change log. Every change a call
+// records shares its ID, as the changes a statement's triggers record do.
+func (s *Store) begin() int64 {
+	s.txID++
+	return s.txID
+}
+
+// record appends to the sync change log what migration 000017's triggers
+// would.
+func (s *Store) record(tx int64, userID pgtype.UUID, entity string, entityID pgtype.UUID, deleted bool) {
+	s.changes = append(s.changes, syncChange{
+		userID: userID,
+		row: db.ListSyncChangesRow{
+			Seq:       int64(len(s.changes) + 1),
+			TxID:      tx,
+			Entity:    entity,
+			EntityID:  entityID,
+			Deleted:   deleted,
+			ChangedAt: s.timestamp(),
+		},
+	})
+}
+
+func (s *Store) timestamp() pgtype.Timestamptz {
+	return pgtype.Timestamptz{Time: s.now(), Valid: true}
+}
+
+func (s *Store) tagByName(userID pgtype.UUID, name string) (db.Tag, bool) {
+	for _, tag := range s.tags {
+		if tag.UserID == userID && tag.Name == name {
+			return tag, true
+		}
+	}
+	return db.Tag{}, false
+}
+
+// tagLink attaches tagID to linkID and reports whether it was not already.
+func (s *Store) tagLink(tx int64, linkID [16]byte, tagID int32) bool {
+	tags := s.linkTags[linkID]
+	if tags == nil {
+		tags = make(map[int32]struct{})
+		s.linkTags[linkID] = tags
+	}
+	if _, ok := tags[tagID]; ok {
+		return false
+	}
+	tags[tagID] = struct{}{}
+	s.record(tx, s.links[linkID].UserID, "link", s.links[linkID].ID, false)
+	return true
+}
+
+// sortedTags returns the tags on linkID ordered by name.
+func (s *Store) sortedTags(linkID [16]byte) []db.Tag {
+	tags := make([]db.Tag, 0, len(s.linkTags[linkID]))
+	for id := range s.linkTags[linkID] {
+		tags = append(tags, s.tags[id])
+	}
+	sort.Slice(tags, func(i, j int) bool { return tags[i].Name < tags[j].Name })
+	return tags
+}
+
+func uniqueViolation(constraint string) error {
+	return &pgconn.PgError{
+		Severity:       "ERROR",
+		Code:           pgerrcode.UniqueViolation,
+		Message:        fmt.Sprintf("duplicate key value violates unique constraint %q", constraint),
+		ConstraintName: constraint,
+	}
+}
+
+func foreignKeyViolation(constraint string) error {
+	return &pgconn.PgError{
+		Severity:       "ERROR",
+		Code:           pgerrcode.ForeignKeyViolation,
+		Message:        fmt.Sprintf("insert or update violates foreign key constraint %q", constraint),
+		ConstraintName: constraint,
+	}
+}
+
+func pgUUID(id uuid.UUID) pgtype.UUID {
+	return pgtype.UUID{Bytes: id, Valid: true}
+}
+
+func newUUID() pgtype.UUID {
+	return pgUUID(uuid.New())
+}
+
+func formatUUID(id pgtype.UUID) string {
+	return uuid.UUID(id.Bytes).String()
+}
+
+func text(value string) pgtype.Text {
+	return pgtype.Text{String: value, Valid: value != ""}
+}
+
+func lessUUID(a, b pgtype.UUID) bool {
+	return bytes.Compare(a.Bytes[:], b.Bytes[:]) < 0
+}
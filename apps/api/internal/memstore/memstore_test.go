@@ -0,0 +1,634 @@
+package memstore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgerrcode"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"github.com/example/keepstack/apps/api/internal/db"
+	"github.com/example/keepstack/apps/api/internal/seed"
+)
+
+var devUserID = uuid.MustParse("00000000-0000-0000-0000-000000000001")
+
+func TestSeedMatchesDataset(t *testing.T) {
+	ctx := context.Background()
+	dataset, err := seed.Lookup("dev")
+	if err != nil {
+		t.Fatal(err)
+	}
+	store := New(devUserID)
+	if err := store.Seed(dataset, devUserID); err != nil {
+		t.Fatalf("seed: %v", err)
+	}
+	// Seeding again leaves existing links alone.
+	if err := store.Seed(dataset, devUserID); err != nil {
+		t.Fatalf("seed again: %v", err)
+	}
+
+	user := pgUUID(devUserID)
+	count, err := store.CountLinks(ctx, db.CountLinksParams{UserID: user})
+	if err != nil || count != int64(len(dataset.Links)) {
+		t.Fatalf("expected %d links, got %d (%v)", len(dataset.Links), count, err)
+	}
+
+	var scored int
+	for _, link := range dataset.Links {
+		if link.Score > 0 {
+			scored++
+		}
+	}
+	recs, err := store.ListRecommendationsForUser(ctx, db.ListRecommendationsForUserParams{UserID: user, PageLimit: 100})
+	if err != nil || len(recs) != scored {
+		t.Fatalf("expected %d recommendations, got %d (%v)", scored, len(recs), err)
+	}
+	for i := 1; i < len(recs); i++ {
+		if recs[i].Score > recs[i-1].Score {
+			t.Fatalf("recommendations out of order: %d after %d", recs[i].Score, recs[i-1].Score)
+		}
+	}
+
+	// Paging by cursor picks up after the last row of the previous page.
+	first := recs[0]
+	rest, err := store.ListRecommendationsForUser(ctx, db.ListRecommendationsForUserParams{
+		UserID:          user,
+		CursorScore:     pgtype.Int4{Int32: first.Score, Valid: true},
+		CursorUpdatedAt: first.UpdatedAt,
+		CursorLinkID:    first.ID,
+		PageLimit:       100,
+	})
+	if err != nil || len(rest) != scored-1 || rest[0].ID != recs[1].ID {
+		t.Fatalf("expected the page after %v to start at %v, got %d rows (%v)", first.ID, recs[1].ID, len(rest), err)
+	}
+}
+
+func TestConstraintsReportPostgresErrors(t *testing.T) {
+	ctx := context.Background()
+	store := New(devUserID)
+
+	tag := db.CreateTagParams{UserID: pgUUID(devUserID), Name: "go"}
+	if _, err := store.CreateTag(ctx, tag); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.CreateTag(ctx, tag); !isPgError(err, pgerrcode.UniqueViolation) {
+		t.Fatalf("duplicate tag: expected a unique violation, got %v", err)
+	}
+
+	missing := pgUUID(uuid.New())
+	if err := store.AddTagToLink(ctx, db.AddTagToLinkParams{LinkID: missing, TagID: 1}); !isPgError(err, pgerrcode.ForeignKeyViolation) {
+		t.Fatalf("tagging a missing link: expected a foreign key violation, got %v", err)
+	}
+	if _, err := store.CreateLink(ctx, db.CreateLinkParams{ID: missing, UserID: pgUUID(uuid.New()), Url: "https://example.com"}); !isPgError(err, pgerrcode.ForeignKeyViolation) {
+		t.Fatalf("link for a missing user: expected a foreign key violation, got %v", err)
+	}
+	if _, err := store.GetLink(ctx, missing); !errors.Is(err, pgx.ErrNoRows) {
+		t.Fatalf("missing link: expected pgx.ErrNoRows, got %v", err)
+	}
+}
+
+func TestTagsBelongToTheirUser(t *testing.T) {
+	ctx := context.Background()
+	store := New(devUserID)
+	mine, theirs := pgUUID(devUserID), pgUUID(uuid.New())
+
+	tag, err := store.CreateTag(ctx, db.CreateTagParams{UserID: mine, Name: "go"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := store.CreateTag(ctx, db.CreateTagParams{UserID: theirs, Name: "go"})
+	if err != nil {
+		t.Fatalf("expected another user to get their own go tag, got %v", err)
+	}
+
+	if found, err := store.GetTagByName(ctx, db.GetTagByNameParams{UserID: mine, Name: "go"}); err != nil || found.ID != tag.ID {
+		t.Fatalf("expected my tag %d by name, got %+v (%v)", tag.ID, found, err)
+	}
+	if _, err := store.GetTag(ctx, db.GetTagParams{ID: other.ID, UserID: mine}); !errors.Is(err, pgx.ErrNoRows) {
+		t.Fatalf("another user's tag: expected pgx.ErrNoRows, got %v", err)
+	}
+	if _, err := store.UpdateTag(ctx, db.UpdateTagParams{ID: other.ID, UserID: mine, Name: "mine"}); !errors.Is(err, pgx.ErrNoRows) {
+		t.Fatalf("renaming another user's tag: expected pgx.ErrNoRows, got %v", err)
+	}
+	if err := store.DeleteTag(ctx, db.DeleteTagParams{ID: other.ID, UserID: mine}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.GetTag(ctx, db.GetTagParams{ID: other.ID, UserID: theirs}); err != nil {
+		t.Fatalf("expected deleting as another user to leave the tag, got %v", err)
+	}
+
+	rows, err := store.ListTagLinkCounts(ctx, mine)
+	if err != nil || len(rows) != 1 || rows[0].ID != tag.ID {
+		t.Fatalf("expected only my tag listed, got %+v (%v)", rows, err)
+	}
+}
+
+func TestSyncChangesFollowWrites(t *testing.T) {
+	ctx := context.Background()
+	store := New(devUserID)
+	user := pgUUID(devUserID)
+	linkID := pgUUID(uuid.New())
+
+	if _, err := store.CreateLink(ctx, db.CreateLinkParams{ID: linkID, UserID: user, Url: "https://example.com/sync"}); err != nil {
+		t.Fatal(err)
+	}
+	changes, err := store.ListSyncChanges(ctx, db.ListSyncChangesParams{UserID: user, PageLimit: 100})
+	if err != nil || len(changes) != 1 {
+		t.Fatalf("expected the insert recorded, got %d changes (%v)", len(changes), err)
+	}
+	cursor := changes[0]
+
+	// Snoozing is bookkeeping sync clients do not see; favoriting is not.
+	if err := store.UpdateLinkSnooze(ctx, db.UpdateLinkSnoozeParams{ID: linkID}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.UpdateLinkFavorite(ctx, db.UpdateLinkFavoriteParams{ID: linkID, Favorite: true}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.DeleteLink(ctx, db.DeleteLinkParams{ID: linkID, UserID: user}); err != nil {
+		t.Fatal(err)
+	}
+
+	changes, err = store.ListSyncChanges(ctx, db.ListSyncChangesParams{UserID: user, CursorTx: cursor.TxID, CursorSeq: cursor.Seq, PageLimit: 100})
+	if err != nil || len(changes) != 2 || changes[0].Deleted || !changes[1].Deleted {
+		t.Fatalf("expected a favorite change then a tombstone, got %+v (%v)", changes, err)
+	}
+	deleted, err := store.GetSyncChangeSince(ctx, db.GetSyncChangeSinceParams{
+		UserID: user, Entity: "link", EntityID: linkID, CursorTx: cursor.TxID, CursorSeq: cursor.Seq,
+	})
+	if err != nil || !deleted {
+		t.Fatalf("expected the newest change to be the deletion, got deleted=%t (%v)", deleted, err)
+	}
+}
+
+func TestArchiveMarksLinkIngested(t *testing.T) {
+	ctx := context.Background()
+	store := New(devUserID)
+	linkID := uuid.New()
+	if _, err := store.CreateLink(ctx, db.CreateLinkParams{ID: pgUUID(linkID), UserID: pgUUID(devUserID), Url: "https://www.Example.com/page"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.GetLinkIngestStatus(ctx, pgUUID(linkID)); !errors.Is(err, pgx.ErrNoRows) {
+		t.Fatalf("expected no status before archiving, got %v", err)
+	}
+
+	userID, err := store.Archive(ctx, linkID)
+	if err != nil || userID != devUserID {
+		t.Fatalf("archive: user %s, %v", userID, err)
+	}
+	status, err := store.GetLinkIngestStatus(ctx, pgUUID(linkID))
+	if err != nil || status.Status != "ingested" {
+		t.Fatalf("expected status ingested, got %q (%v)", status.Status, err)
+	}
+	rows, err := store.ListLinks(ctx, db.ListLinksParams{UserID: pgUUID(devUserID), PageLimit: 10})
+	if err != nil || len(rows) != 1 || rows[0].SourceDomain.String != "example.com" || rows[0].WordCount == 0 {
+		t.Fatalf("expected the archived link listed with its domain and word count, got %+v (%v)", rows, err)
+	}
+}
+
+func TestSharesExpire(t *testing.T) {
+	ctx := context.Background()
+	store := New(devUserID)
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	store.now = func() time.Time { return now }
+
+	viewer, err := store.CreateUser(ctx, db.CreateUserParams{Email: "viewer@example.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id, err := store.GetUserIDByEmail(ctx, "Viewer@Example.com"); err != nil || id != viewer.ID {
+		t.Fatalf("expected email lookup to ignore case, got %v (%v)", id, err)
+	}
+	linkID := pgUUID(uuid.New())
+	if _, err := store.CreateLink(ctx, db.CreateLinkParams{ID: linkID, UserID: pgUUID(devUserID), Url: "https://example.com/shared"}); err != nil {
+		t.Fatal(err)
+	}
+
+	expires := pgtype.Timestamptz{Time: now.Add(time.Hour), Valid: true}
+	if _, err := store.UpsertLinkShare(ctx, db.UpsertLinkShareParams{LinkID: linkID, UserID: viewer.ID, Permission: "read", ExpiresAt: expires}); err != nil {
+		t.Fatal(err)
+	}
+	lookup := db.GetLinkSharePermissionParams{LinkID: linkID, UserID: viewer.ID}
+	if permission, err := store.GetLinkSharePermission(ctx, lookup); err != nil || permission != "read" {
+		t.Fatalf("expected a read share, got %q (%v)", permission, err)
+	}
+	if shared, err := store.ListLinksSharedWithUser(ctx, viewer.ID); err != nil || len(shared) != 1 || shared[0].OwnerEmail != seed.DevUserEmail {
+		t.Fatalf("expected the link listed as shared by the dev user, got %+v (%v)", shared, err)
+	}
+
+	now = now.Add(2 * time.Hour)
+	if _, err := store.GetLinkSharePermission(ctx, lookup); !errors.Is(err, pgx.ErrNoRows) {
+		t.Fatalf("expected an expired share to grant nothing, got %v", err)
+	}
+	if shares, err := store.ListLinkShares(ctx, linkID); err != nil || len(shares) != 1 {
+		t.Fatalf("expected the owner to still see the expired share, got %d (%v)", len(shares), err)
+	}
+}
+
+func TestClaimsExpireAndFilterLinks(t *testing.T) {
+	ctx := context.Background()
+	store := New(devUserID)
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	store.now = func() time.Time { return now }
+
+	userID := pgUUID(devUserID)
+	claimedID := pgUUID(uuid.New())
+	for _, id := range []pgtype.UUID{claimedID, pgUUID(uuid.New())} {
+		if _, err := store.CreateLink(ctx, db.CreateLinkParams{ID: id, UserID: userID, Url: "https://example.com/" + uuid.UUID(id.Bytes).String()}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	expires := pgtype.Timestamptz{Time: now.Add(time.Hour), Valid: true}
+	claim, err := store.CreateClaim(ctx, db.CreateClaimParams{LinkID: claimedID, UserID: userID, ExpiresAt: expires})
+	if err != nil || !claim.Inserted {
+		t.Fatalf("expected a new claim, got %+v (%v)", claim, err)
+	}
+	filter := db.ListLinksParams{UserID: userID, ClaimedBy: userID, PageLimit: 50}
+	if links, err := store.ListLinks(ctx, filter); err != nil || len(links) != 1 || links[0].ID != claimedID {
+		t.Fatalf("expected only the claimed link, got %d (%v)", len(links), err)
+	}
+	if claims, err := store.ListClaimsForUser(ctx, userID); err != nil || len(claims) != 1 {
+		t.Fatalf("expected one claim, got %d (%v)", len(claims), err)
+	}
+
+	now = now.Add(2 * time.Hour)
+	if links, err := store.ListLinks(ctx, filter); err != nil || len(links) != 0 {
+		t.Fatalf("expected an expired claim to match nothing, got %d (%v)", len(links), err)
+	}
+	if claims, err := store.ListClaimsForUser(ctx, userID); err != nil || len(claims) != 0 {
+		t.Fatalf("expected expired claims to be hidden, got %d (%v)", len(claims), err)
+	}
+
+	renewed, err := store.CreateClaim(ctx, db.CreateClaimParams{LinkID: claimedID, UserID: userID})
+	if err != nil || renewed.Inserted || renewed.ID != claim.ID || !renewed.ClaimedAt.Time.Equal(now) {
+		t.Fatalf("expected claiming again to restart the claim, got %+v (%v)", renewed, err)
+	}
+	if deleted, err := store.DeleteClaim(ctx, db.DeleteClaimParams{ID: claim.ID, UserID: userID}); err != nil || deleted != 1 {
+		t.Fatalf("expected the claim to be released, got %d (%v)", deleted, err)
+	}
+}
+
+func TestFilterLinksByHighlights(t *testing.T) {
+	ctx := context.Background()
+	store := New(devUserID)
+	userID := pgUUID(devUserID)
+
+	annotated := pgUUID(uuid.New())
+	for _, id := range []pgtype.UUID{annotated, pgUUID(uuid.New())} {
+		if _, err := store.CreateLink(ctx, db.CreateLinkParams{ID: id, UserID: userID, Url: "https://example.com/" + uuid.UUID(id.Bytes).String()}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := store.CreateHighlight(ctx, db.CreateHighlightParams{LinkID: annotated, Text: "quoted"}); err != nil {
+		t.Fatal(err)
+	}
+
+	filter := db.ListLinksParams{UserID: userID, HasHighlights: pgtype.Bool{Bool: true, Valid: true}, PageLimit: 50}
+	if links, err := store.ListLinks(ctx, filter); err != nil || len(links) != 1 || links[0].ID != annotated {
+		t.Fatalf("expected only the annotated link, got %d (%v)", len(links), err)
+	}
+	filter.HasHighlights.Bool = false
+	if links, err := store.ListLinks(ctx, filter); err != nil || len(links) != 1 || links[0].ID == annotated {
+		t.Fatalf("expected only the link without highlights, got %d (%v)", len(links), err)
+	}
+}
+
+func TestListLinksSortsByRelevance(t *testing.T) {
+	ctx := context.Background()
+	store := New(devUserID)
+	userID := pgUUID(devUserID)
+
+	titled, mentioned := pgUUID(uuid.New()), pgUUID(uuid.New())
+	if _, err := store.CreateLink(ctx, db.CreateLinkParams{ID: titled, UserID: userID, Url: "https://example.com/titled", Title: text("Go generics in practice")}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.CreateLink(ctx, db.CreateLinkParams{ID: mentioned, UserID: userID, Url: "https://example.com/mentioned", Title: text("Release notes")}); err != nil {
+		t.Fatal(err)
+	}
+	store.putArchive(db.Archive{LinkID: mentioned, ExtractedText: text("This release adds go generics support.")})
+
+	filter := db.ListLinksParams{UserID: userID, Query: text("generics"), EnableFullText: true, PageLimit: 10}
+	if links, err := store.ListLinks(ctx, filter); err != nil || len(links) != 2 || links[0].ID != mentioned {
+		t.Fatalf("expected the newest match first, got %+v (%v)", links, err)
+	}
+	filter.SortRelevance = true
+	if links, err := store.ListLinks(ctx, filter); err != nil || len(links) != 2 || links[0].ID != titled {
+		t.Fatalf("expected the title match first, got %+v (%v)", links, err)
+	}
+}
+
+func TestArchiveKeepsPreviousVersions(t *testing.T) {
+	ctx := context.Background()
+	store := New(devUserID)
+	linkID := pgUUID(uuid.New())
+	if _, err := store.CreateLink(ctx, db.CreateLinkParams{ID: linkID, UserID: pgUUID(devUserID), Url: "https://example.com/a"}); err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < maxArchiveVersions+3; i++ {
+		store.putArchive(db.Archive{LinkID: linkID, ExtractedText: text(fmt.Sprintf("revision %d", i))})
+	}
+	// The same text again is not a new version.
+	store.putArchive(db.Archive{LinkID: linkID, ExtractedText: text(fmt.Sprintf("revision %d", maxArchiveVersions+2))})
+
+	versions, err := store.ListArchiveVersions(ctx, linkID)
+	if err != nil || len(versions) != maxArchiveVersions {
+		t.Fatalf("expected %d versions, got %d (%v)", maxArchiveVersions, len(versions), err)
+	}
+	newest, err := store.GetArchiveVersion(ctx, db.GetArchiveVersionParams{LinkID: linkID, ID: versions[0].ID})
+	if err != nil || newest.ExtractedText != fmt.Sprintf("revision %d", maxArchiveVersions+1) {
+		t.Fatalf("expected the newest version to be the text replaced last, got %q (%v)", newest.ExtractedText, err)
+	}
+	if current, err := store.GetCurrentArchiveVersion(ctx, linkID); err != nil || current.ExtractedText != fmt.Sprintf("revision %d", maxArchiveVersions+2) {
+		t.Fatalf("unexpected current text %q (%v)", current.ExtractedText, err)
+	}
+}
+
+func TestNotificationsReachAdmins(t *testing.T) {
+	ctx := context.Background()
+	store := New(devUserID)
+
+	member, err := store.CreateUser(ctx, db.CreateUserParams{Email: "member@example.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	created, err := store.CreateAdminNotifications(ctx, db.CreateAdminNotificationsParams{Kind: "backup_failed", Message: "Backup failed"})
+	if err != nil || created != 1 {
+		t.Fatalf("expected only the dev user to be an admin, got %d (%v)", created, err)
+	}
+	if count, err := store.CountUnreadNotifications(ctx, member.ID); err != nil || count != 0 {
+		t.Fatalf("expected no notifications for a member, got %d (%v)", count, err)
+	}
+
+	userID := pgUUID(devUserID)
+	if _, err := store.CreateNotification(ctx, db.CreateNotificationParams{UserID: userID, Kind: "digest_sent", Message: "Digest sent"}); err != nil {
+		t.Fatal(err)
+	}
+	unread, err := store.ListNotifications(ctx, db.ListNotificationsParams{UserID: userID, UnreadOnly: true, PageLimit: 10})
+	if err != nil || len(unread) != 2 {
+		t.Fatalf("expected 2 unread notifications, got %d (%v)", len(unread), err)
+	}
+	if updated, err := store.MarkNotificationRead(ctx, db.MarkNotificationReadParams{ID: unread[0].ID, UserID: member.ID}); err != nil || updated != 0 {
+		t.Fatalf("expected another user's notification to be untouched, got %d (%v)", updated, err)
+	}
+	if updated, err := store.MarkNotificationRead(ctx, db.MarkNotificationReadParams{ID: unread[0].ID, UserID: userID}); err != nil || updated != 1 {
+		t.Fatalf("expected the notification to be marked read, got %d (%v)", updated, err)
+	}
+	if updated, err := store.MarkAllNotificationsRead(ctx, userID); err != nil || updated != 1 {
+		t.Fatalf("expected one notification left to mark read, got %d (%v)", updated, err)
+	}
+	if count, err := store.CountUnreadNotifications(ctx, userID); err != nil || count != 0 {
+		t.Fatalf("expected everything read, got %d (%v)", count, err)
+	}
+}
+
+func TestReadingProgressAndDays(t *testing.T) {
+	ctx := context.Background()
+	store := New(devUserID)
+	now := time.Date(2025, 6, 11, 12, 0, 0, 0, time.UTC)
+	store.now = func() time.Time { return now }
+
+	userID := pgUUID(devUserID)
+	var ids []pgtype.UUID
+	for range 3 {
+		id := pgUUID(uuid.New())
+		if _, err := store.CreateLink(ctx, db.CreateLinkParams{ID: id, UserID: userID, Url: "https://example.com/" + uuid.UUID(id.Bytes).String()}); err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, id)
+	}
+	store.links[ids[0].Bytes].ReadAt = pgtype.Timestamptz{Time: now.Add(-time.Hour), Valid: true}
+	store.links[ids[1].Bytes].ReadAt = pgtype.Timestamptz{Time: now.Add(-2 * time.Hour), Valid: true}
+	store.links[ids[2].Bytes].ReadAt = pgtype.Timestamptz{Time: now.AddDate(0, 0, -10), Valid: true}
+	store.archives[ids[0].Bytes] = db.Archive{LinkID: ids[0], WordCount: pgtype.Int4{Int32: 231, Valid: true}}
+
+	progress, err := store.GetReadingProgress(ctx, db.GetReadingProgressParams{
+		WordsPerMinute: 230,
+		UserID:         userID,
+		Since:          pgtype.Timestamptz{Time: now.AddDate(0, 0, -2), Valid: true},
+	})
+	if err != nil || progress.Articles != 2 || progress.Minutes != 2 {
+		t.Fatalf("expected 2 links and 2 minutes, got %+v (%v)", progress, err)
+	}
+
+	days, err := store.ListReadDays(ctx, userID)
+	if err != nil || len(days) != 2 || !days[0].Time.After(days[1].Time) {
+		t.Fatalf("expected 2 distinct days newest first, got %+v (%v)", days, err)
+	}
+}
+
+func TestListSimilarLinks(t *testing.T) {
+	ctx := context.Background()
+	store := New(devUserID)
+	userID := pgUUID(devUserID)
+
+	saved := []struct {
+		url   string
+		title string
+	}{
+		{url: "https://www.example.com/a", title: "First post"},
+		{url: "https://other.org/b", title: "Go Generics Explained"},
+		{url: "https://other.org/c", title: "Gardening tips"},
+	}
+	for _, link := range saved {
+		if _, err := store.CreateLink(ctx, db.CreateLinkParams{
+			ID:     pgUUID(uuid.New()),
+			UserID: userID,
+			Url:    link.url,
+			Title:  pgtype.Text{String: link.title, Valid: true},
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	rows, err := store.ListSimilarLinks(ctx, db.ListSimilarLinksParams{
+		UserID:     userID,
+		Host:       "example.com",
+		TitleWords: []string{"generics"},
+		PageLimit:  10,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var urls []string
+	for _, row := range rows {
+		urls = append(urls, row.Url)
+	}
+	sort.Strings(urls)
+	if len(urls) != 2 || urls[0] != "https://other.org/b" || urls[1] != "https://www.example.com/a" {
+		t.Fatalf("expected the same host and the shared title word, got %v", urls)
+	}
+}
+
+func TestMatchDomainPreferencePrefersMostSpecific(t *testing.T) {
+	ctx := context.Background()
+	store := New(devUserID)
+	userID := pgUUID(devUserID)
+
+	for _, domain := range []string{"example.com", "blog.example.com"} {
+		if _, err := store.UpsertDomainPreference(ctx, db.UpsertDomainPreferenceParams{
+			UserID:         userID,
+			Domain:         domain,
+			NeverResurface: domain == "example.com",
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := map[string]string{
+		"https://example.com/a":          "example.com",
+		"https://www.example.com/a":      "example.com",
+		"https://news.example.com/a":     "example.com",
+		"https://blog.example.com:8080/": "blog.example.com",
+		"https://a.blog.example.com/":    "blog.example.com",
+	}
+	for url, want := range tests {
+		pref, err := store.MatchDomainPreference(ctx, db.MatchDomainPreferenceParams{UserID: userID, Url: url})
+		if err != nil || pref.Domain != want {
+			t.Fatalf("%s: expected %s, got %q (%v)", url, want, pref.Domain, err)
+		}
+	}
+	for _, url := range []string{"https://notexample.com/", "https://example.com.evil.test/"} {
+		if _, err := store.MatchDomainPreference(ctx, db.MatchDomainPreferenceParams{UserID: userID, Url: url}); !errors.Is(err, pgx.ErrNoRows) {
+			t.Fatalf("%s: expected no preference, got %v", url, err)
+		}
+	}
+}
+
+func TestApplyTagToMatchingLinks(t *testing.T) {
+	ctx := context.Background()
+	store := New(devUserID)
+	userID := pgUUID(devUserID)
+
+	tag, err := store.CreateTag(ctx, db.CreateTagParams{UserID: userID, Name: "bulk"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, url := range []string{"https://blog.example.com/a", "https://example.com/b", "https://other.org/c"} {
+		if _, err := store.CreateLink(ctx, db.CreateLinkParams{ID: pgUUID(uuid.New()), UserID: userID, Url: url}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	arg := db.ApplyTagToMatchingLinksParams{
+		UserID: userID,
+		Domain: pgtype.Text{String: "example.com", Valid: true},
+		TagID:  tag.ID,
+	}
+	for _, want := range []db.ApplyTagToMatchingLinksRow{{Matched: 2, Tagged: 2}, {Matched: 2, Tagged: 0}} {
+		row, err := store.ApplyTagToMatchingLinks(ctx, arg)
+		if err != nil || row != want {
+			t.Fatalf("expected %+v, got %+v (%v)", want, row, err)
+		}
+	}
+
+	arg.CreatedBefore = pgtype.Timestamptz{Time: time.Now().Add(-time.Hour), Valid: true}
+	if row, err := store.ApplyTagToMatchingLinks(ctx, arg); err != nil || row.Matched != 0 {
+		t.Fatalf("expected no links created before an hour ago, got %+v (%v)", row, err)
+	}
+
+	arg.TagID = tag.ID + 100
+	if _, err := store.ApplyTagToMatchingLinks(ctx, arg); !isPgError(err, pgerrcode.ForeignKeyViolation) {
+		t.Fatalf("expected a foreign key violation for a missing tag, got %v", err)
+	}
+}
+
+func isPgError(err error, code string) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == code
+}
+
+func TestPublicProfilesAndFavorites(t *testing.T) {
+	ctx := context.Background()
+	store := New(devUserID)
+	userID := pgUUID(devUserID)
+
+	if _, err := store.UpsertPublicProfile(ctx, db.UpsertPublicProfileParams{UserID: userID, Username: "alice"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.UpsertPublicProfile(ctx, db.UpsertPublicProfileParams{UserID: pgUUID(uuid.New()), Username: "alice"}); err == nil {
+		t.Fatal("expected a taken username to be rejected")
+	}
+	profile, err := store.GetPublicProfileByUsername(ctx, "alice")
+	if err != nil || profile.UserID != userID {
+		t.Fatalf("expected alice to be the dev user, got %+v (%v)", profile, err)
+	}
+
+	for i, favorite := range []bool{true, false} {
+		url := fmt.Sprintf("https://www.example.com/%d", i)
+		if _, err := store.CreateLink(ctx, db.CreateLinkParams{ID: pgUUID(uuid.New()), UserID: userID, Url: url, Favorite: favorite}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	favorites, err := store.ListPublicFavorites(ctx, db.ListPublicFavoritesParams{UserID: userID, PageLimit: 50})
+	if err != nil || len(favorites) != 1 || favorites[0].Url != "https://www.example.com/0" || favorites[0].Domain == "" {
+		t.Fatalf("expected only the favorite with its domain, got %+v (%v)", favorites, err)
+	}
+
+	if n, err := store.DeletePublicProfile(ctx, userID); err != nil || n != 1 {
+		t.Fatalf("expected the profile to be deleted, got %d (%v)", n, err)
+	}
+	if _, err := store.GetPublicProfileByUsername(ctx, "alice"); err == nil {
+		t.Fatal("expected the deleted profile to be gone")
+	}
+}
+
+func TestRecommendationImpressions(t *testing.T) {
+	ctx := context.Background()
+	store := New(devUserID)
+	userID := pgUUID(devUserID)
+
+	ids := make([]pgtype.UUID, 2)
+	for i := range ids {
+		ids[i] = pgUUID(uuid.New())
+		if _, err := store.CreateLink(ctx, db.CreateLinkParams{ID: ids[i], UserID: userID, Url: fmt.Sprintf("https://example.com/%d", i)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	now := time.Now().UTC()
+	record := db.RecordRecommendationImpressionsParams{
+		UserID:      userID,
+		Strategy:    "resurfacer",
+		Surface:     "api",
+		ShownAt:     pgtype.Timestamptz{Time: now, Valid: true},
+		LinkIds:     ids,
+		Positions:   []int32{1, 2},
+		RepeatAfter: pgtype.Timestamptz{Time: now.Add(-time.Hour), Valid: true},
+	}
+	if n, err := store.RecordRecommendationImpressions(ctx, record); err != nil || n != 2 {
+		t.Fatalf("expected two impressions, got %d (%v)", n, err)
+	}
+	if n, err := store.RecordRecommendationImpressions(ctx, record); err != nil || n != 0 {
+		t.Fatalf("expected a repeat showing to be skipped, got %d (%v)", n, err)
+	}
+
+	strategies, err := store.MarkRecommendationImpressionsActed(ctx, db.MarkRecommendationImpressionsActedParams{
+		Action:     pgtype.Text{String: "read", Valid: true},
+		ActedAt:    pgtype.Timestamptz{Time: now, Valid: true},
+		UserID:     userID,
+		LinkIds:    ids[:1],
+		ShownAfter: pgtype.Timestamptz{Time: now.Add(-24 * time.Hour), Valid: true},
+	})
+	if err != nil || len(strategies) != 1 || strategies[0] != "resurfacer" {
+		t.Fatalf("expected one acted impression, got %v (%v)", strategies, err)
+	}
+
+	stats, err := store.ListRecommendationImpressionStats(ctx, db.ListRecommendationImpressionStatsParams{
+		UserID: userID,
+		Since:  pgtype.Timestamptz{Time: now.Add(-24 * time.Hour), Valid: true},
+	})
+	want := db.ListRecommendationImpressionStatsRow{Strategy: "resurfacer", Surface: "api", Impressions: 2, Acted: 1, Read: 1}
+	if err != nil || len(stats) != 1 || stats[0] != want {
+		t.Fatalf("expected %+v, got %+v (%v)", want, stats, err)
+	}
+}
@@ -0,0 +1,129 @@
+package memstore
+
+import (
+	"context"
+	"sort"
+
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"github.com/example/keepstack/apps/api/internal/db"
+)
+
+// CreateNotification records a notification for a user.
+func (s *Store) CreateNotification(ctx context.Context, arg db.CreateNotificationParams) (db.Notification, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.users[arg.UserID.Bytes]; !ok {
+		return db.Notification{}, foreignKeyViolation("notifications_user_id_fkey")
+	}
+	if arg.LinkID.Valid {
+		if _, ok := s.links[arg.LinkID.Bytes]; !ok {
+			return db.Notification{}, foreignKeyViolation("notifications_link_id_fkey")
+		}
+	}
+	notification := db.Notification{
+		ID:        newUUID(),
+		UserID:    arg.UserID,
+		Kind:      arg.Kind,
+		LinkID:    arg.LinkID,
+		Message:   arg.Message,
+		CreatedAt: s.timestamp(),
+	}
+	s.notifications[notification.ID.Bytes] = notification
+	return notification, nil
+}
+
+// CreateAdminNotifications gives every admin the same notification and
+// reports how many it created.
+func (s *Store) CreateAdminNotifications(ctx context.Context, arg db.CreateAdminNotificationsParams) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var created int64
+	for _, user := range s.users {
+		if !user.IsAdmin {
+			continue
+		}
+		notification := db.Notification{
+			ID:        newUUID(),
+			UserID:    user.ID,
+			Kind:      arg.Kind,
+			Message:   arg.Message,
+			CreatedAt: s.timestamp(),
+		}
+		s.notifications[notification.ID.Bytes] = notification
+		created++
+	}
+	return created, nil
+}
+
+// ListNotifications pages through a user's notifications, newest first.
+func (s *Store) ListNotifications(ctx context.Context, arg db.ListNotificationsParams) ([]db.Notification, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var rows []db.Notification
+	for _, notification := range s.notifications {
+		if notification.UserID != arg.UserID || (arg.UnreadOnly && notification.ReadAt.Valid) {
+			continue
+		}
+		rows = append(rows, notification)
+	}
+	sort.Slice(rows, func(i, j int) bool {
+		if !rows[i].CreatedAt.Time.Equal(rows[j].CreatedAt.Time) {
+			return rows[i].CreatedAt.Time.After(rows[j].CreatedAt.Time)
+		}
+		return lessUUID(rows[i].ID, rows[j].ID)
+	})
+	return page(rows, arg.PageOffset, arg.PageLimit), nil
+}
+
+// CountUnreadNotifications counts the user's unread notifications.
+func (s *Store) CountUnreadNotifications(ctx context.Context, userID pgtype.UUID) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var count int64
+	for _, notification := range s.notifications {
+		if notification.UserID == userID && !notification.ReadAt.Valid {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// MarkNotificationRead marks one of the user's notifications read, keeping
+// the first read time, and reports how many it matched.
+func (s *Store) MarkNotificationRead(ctx context.Context, arg db.MarkNotificationReadParams) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	notification, ok := s.notifications[arg.ID.Bytes]
+	if !ok || notification.UserID != arg.UserID {
+		return 0, nil
+	}
+	if !notification.ReadAt.Valid {
+		notification.ReadAt = s.timestamp()
+		s.notifications[arg.ID.Bytes] = notification
+	}
+	return 1, nil
+}
+
+// MarkAllNotificationsRead marks the user's unread notifications read and
+// reports how many there were.
+func (s *Store) MarkAllNotificationsRead(ctx context.Context, userID pgtype.UUID) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var updated int64
+	for key, notification := range s.notifications {
+		if notification.UserID != userID || notification.ReadAt.Valid {
+			continue
+		}
+		notification.ReadAt = s.timestamp()
+		s.notifications[key] = notification
+		updated++
+	}
+	return updated, nil
+}
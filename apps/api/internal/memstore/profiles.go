@@ -0,0 +1,103 @@
+package memstore
+
+import (
+	"context"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"github.com/example/keepstack/apps/api/internal/db"
+)
+
+// GetPublicProfile returns a user's public profile.
+func (s *Store) GetPublicProfile(ctx context.Context, userID pgtype.UUID) (db.PublicProfile, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	profile, ok := s.profiles[userID.Bytes]
+	if !ok {
+		return db.PublicProfile{}, pgx.ErrNoRows
+	}
+	return profile, nil
+}
+
+// GetPublicProfileByUsername returns the public profile with username.
+func (s *Store) GetPublicProfileByUsername(ctx context.Context, username string) (db.PublicProfile, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, profile := range s.profiles {
+		if profile.Username == username {
+			return profile, nil
+		}
+	}
+	return db.PublicProfile{}, pgx.ErrNoRows
+}
+
+// UpsertPublicProfile creates or renames a user's public profile. Usernames
+// are unique.
+func (s *Store) UpsertPublicProfile(ctx context.Context, arg db.UpsertPublicProfileParams) (db.PublicProfile, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for userID, profile := range s.profiles {
+		if profile.Username == arg.Username && userID != arg.UserID.Bytes {
+			return db.PublicProfile{}, uniqueViolation("public_profiles_username_key")
+		}
+	}
+	profile, ok := s.profiles[arg.UserID.Bytes]
+	if !ok {
+		if _, ok := s.users[arg.UserID.Bytes]; !ok {
+			return db.PublicProfile{}, foreignKeyViolation("public_profiles_user_id_fkey")
+		}
+		profile = db.PublicProfile{UserID: arg.UserID, CreatedAt: s.timestamp()}
+	}
+	profile.Username = arg.Username
+	profile.DisplayName = arg.DisplayName
+	s.profiles[arg.UserID.Bytes] = profile
+	return profile, nil
+}
+
+// DeletePublicProfile removes a user's public profile.
+func (s *Store) DeletePublicProfile(ctx context.Context, userID pgtype.UUID) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.profiles[userID.Bytes]; !ok {
+		return 0, nil
+	}
+	delete(s.profiles, userID.Bytes)
+	return 1, nil
+}
+
+// ListPublicFavorites lists a user's favorites, newest first.
+func (s *Store) ListPublicFavorites(ctx context.Context, arg db.ListPublicFavoritesParams) ([]db.ListPublicFavoritesRow, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var links []*db.Link
+	for _, link := range s.links {
+		if link.UserID == arg.UserID && link.Favorite {
+			links = append(links, link)
+		}
+	}
+	sortNewestFirst(links)
+
+	rows := make([]db.ListPublicFavoritesRow, 0, len(links))
+	for _, link := range page(links, 0, arg.PageLimit) {
+		domain := link.SourceDomain.String
+		if !link.SourceDomain.Valid {
+			if match := fullHostPattern.FindStringSubmatch(link.Url); match != nil {
+				domain = strings.ToLower(match[1])
+			}
+		}
+		rows = append(rows, db.ListPublicFavoritesRow{
+			Url:       link.Url,
+			Title:     s.publicTitle(link),
+			Domain:    domain,
+			CreatedAt: link.CreatedAt,
+		})
+	}
+	return rows, nil
+}
@@ -0,0 +1,50 @@
+package memstore
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"github.com/example/keepstack/apps/api/internal/db"
+)
+
+// quotaKey identifies a user's usage counter for one kind on one day.
+type quotaKey struct {
+	userID [16]byte
+	kind   string
+	day    int64
+}
+
+// TakeDailyQuota counts one use of arg.Kind for the user on arg.Day and
+// returns the day's total, or pgx.ErrNoRows once it has reached the limit.
+func (s *Store) TakeDailyQuota(ctx context.Context, arg db.TakeDailyQuotaParams) (int32, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.users[arg.UserID.Bytes]; !ok {
+		return 0, foreignKeyViolation("usage_quotas_user_id_fkey")
+	}
+	key := quotaKey{userID: arg.UserID.Bytes, kind: arg.Kind, day: arg.Day.Time.Unix()}
+	used, ok := s.quotas[key]
+	if ok && used >= arg.DailyLimit {
+		return 0, pgx.ErrNoRows
+	}
+	s.quotas[key] = used + 1
+	return used + 1, nil
+}
+
+// DeleteUsageQuotasBefore drops counters for days before day.
+func (s *Store) DeleteUsageQuotasBefore(ctx context.Context, day pgtype.Date) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var deleted int64
+	for key := range s.quotas {
+		if key.day < day.Time.Unix() {
+			delete(s.quotas, key)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
@@ -0,0 +1,169 @@
+package memstore
+
+import (
+	"context"
+	"sort"
+
+	"github.com/example/keepstack/apps/api/internal/db"
+)
+
+// ListRecommendationsForUser pages through a user's scored links, highest
+// score first, after the keyset cursor when one is given.
+func (s *Store) ListRecommendationsForUser(ctx context.Context, arg db.ListRecommendationsForUserParams) ([]db.ListRecommendationsForUserRow, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	cursor := db.Recommendation{Score: arg.CursorScore.Int32, UpdatedAt: arg.CursorUpdatedAt, LinkID: arg.CursorLinkID}
+	var recs []db.Recommendation
+	for id, rec := range s.recommendations {
+		link := s.links[id]
+		if link == nil || link.UserID != arg.UserID {
+			continue
+		}
+		if arg.Domain.Valid && link.SourceDomain.String != arg.Domain.String {
+			continue
+		}
+		if !s.hasTags(id, arg.TagIds) {
+			continue
+		}
+		if arg.CursorScore.Valid && !recommendationLess(rec, cursor) {
+			continue
+		}
+		recs = append(recs, rec)
+	}
+	sort.Slice(recs, func(i, j int) bool { return recommendationLess(recs[j], recs[i]) })
+
+	recs = page(recs, arg.PageOffset, arg.PageLimit)
+	rows := make([]db.ListRecommendationsForUserRow, 0, len(recs))
+	for _, rec := range recs {
+		link := s.links[rec.LinkID.Bytes]
+		archive, archived := s.archives[rec.LinkID.Bytes]
+		row := db.ListRecommendationsForUserRow{
+			ID:            link.ID,
+			Url:           link.Url,
+			Title:         link.Title,
+			SourceDomain:  link.SourceDomain,
+			Favorite:      link.Favorite,
+			CreatedAt:     link.CreatedAt,
+			ReadAt:        link.ReadAt,
+			WordCount:     archive.WordCount.Int32,
+			ExtractedText: archive.ExtractedText.String,
+			Paywalled:     archive.Paywalled,
+			Score:         rec.Score,
+			UpdatedAt:     rec.UpdatedAt,
+			Strategy:      rec.Strategy,
+		}
+		if archived {
+			row.ArchiveTitle, row.Byline, row.Lang = archive.Title, archive.Byline, archive.Lang
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+// recommendationLess compares (score, updated_at, link_id) tuples, the key
+// recommendations are listed and paged by in descending order.
+func recommendationLess(a, b db.Recommendation) bool {
+	if a.Score != b.Score {
+		return a.Score < b.Score
+	}
+	if !a.UpdatedAt.Time.Equal(b.UpdatedAt.Time) {
+		return a.UpdatedAt.Time.Before(b.UpdatedAt.Time)
+	}
+	return lessUUID(a.LinkID, b.LinkID)
+}
+
+// ListOnThisDayLinksForUser returns the user's links saved on OnDate's
+// month and day the given numbers of years ago.
+func (s *Store) ListOnThisDayLinksForUser(ctx context.Context, arg db.ListOnThisDayLinksForUserParams) ([]db.ListOnThisDayLinksForUserRow, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	years := append([]int32(nil), arg.Years...)
+	sort.Slice(years, func(i, j int) bool { return years[i] < years[j] })
+
+	var rows []db.ListOnThisDayLinksForUserRow
+	for _, yearsAgo := range years {
+		day := arg.OnDate.Time.AddDate(-int(yearsAgo), 0, 0).Format("2006-01-02")
+		var links []*db.Link
+		for _, link := range s.links {
+			if link.UserID == arg.UserID && link.CreatedAt.Time.UTC().Format("2006-01-02") == day {
+				links = append(links, link)
+			}
+		}
+		sort.Slice(links, func(i, j int) bool { return links[i].CreatedAt.Time.Before(links[j].CreatedAt.Time) })
+		for _, link := range links {
+			archive, archived := s.archives[link.ID.Bytes]
+			row := db.ListOnThisDayLinksForUserRow{
+				ID:            link.ID,
+				Url:           link.Url,
+				Title:         link.Title,
+				SourceDomain:  link.SourceDomain,
+				Favorite:      link.Favorite,
+				CreatedAt:     link.CreatedAt,
+				ReadAt:        link.ReadAt,
+				WordCount:     archive.WordCount.Int32,
+				ExtractedText: archive.ExtractedText.String,
+				Paywalled:     archive.Paywalled,
+				YearsAgo:      yearsAgo,
+			}
+			if archived {
+				row.ArchiveTitle, row.Byline, row.Lang = archive.Title, archive.Byline, archive.Lang
+			}
+			rows = append(rows, row)
+		}
+	}
+	return page(rows, 0, arg.RowLimit), nil
+}
+
+// ListColdStartLinksForUser picks from the user's most recent links for
+// someone with no recommendations yet: unread before read, archived before
+// pending, shorter reads first.
+func (s *Store) ListColdStartLinksForUser(ctx context.Context, arg db.ListColdStartLinksForUserParams) ([]db.ListColdStartLinksForUserRow, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var links []*db.Link
+	for _, link := range s.links {
+		if link.UserID == arg.UserID {
+			links = append(links, link)
+		}
+	}
+	sortNewestFirst(links)
+	links = page(links, 0, arg.RecentWindow)
+
+	words := func(link *db.Link) int32 { return s.archives[link.ID.Bytes].WordCount.Int32 }
+	sort.SliceStable(links, func(i, j int) bool {
+		a, b := links[i], links[j]
+		if a.ReadAt.Valid != b.ReadAt.Valid {
+			return !a.ReadAt.Valid
+		}
+		if (words(a) == 0) != (words(b) == 0) {
+			return words(a) != 0
+		}
+		return words(a) < words(b)
+	})
+
+	links = page(links, 0, arg.RowLimit)
+	rows := make([]db.ListColdStartLinksForUserRow, 0, len(links))
+	for _, link := range links {
+		archive, archived := s.archives[link.ID.Bytes]
+		row := db.ListColdStartLinksForUserRow{
+			ID:            link.ID,
+			Url:           link.Url,
+			Title:         link.Title,
+			SourceDomain:  link.SourceDomain,
+			Favorite:      link.Favorite,
+			CreatedAt:     link.CreatedAt,
+			ReadAt:        link.ReadAt,
+			WordCount:     archive.WordCount.Int32,
+			ExtractedText: archive.ExtractedText.String,
+			Paywalled:     archive.Paywalled,
+		}
+		if archived {
+			row.ArchiveTitle, row.Byline, row.Lang = archive.Title, archive.Byline, archive.Lang
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
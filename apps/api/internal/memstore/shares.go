@@ -0,0 +1,145 @@
+package memstore
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"github.com/example/keepstack/apps/api/internal/db"
+)
+
+// GetUserIDByEmail looks a user up by email, ignoring case.
+func (s *Store) GetUserIDByEmail(ctx context.Context, email string) (pgtype.UUID, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, user := range s.users {
+		if strings.EqualFold(user.Email, email) {
+			return user.ID, nil
+		}
+	}
+	return pgtype.UUID{}, pgx.ErrNoRows
+}
+
+// UpsertLinkShare shares a link with a user, replacing the permission and
+// expiry of an existing share.
+func (s *Store) UpsertLinkShare(ctx context.Context, arg db.UpsertLinkShareParams) (db.LinkShare, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.links[arg.LinkID.Bytes]; !ok {
+		return db.LinkShare{}, foreignKeyViolation("link_shares_link_id_fkey")
+	}
+	if _, ok := s.users[arg.UserID.Bytes]; !ok {
+		return db.LinkShare{}, foreignKeyViolation("link_shares_user_id_fkey")
+	}
+	key := shareKey(arg.LinkID, arg.UserID)
+	share, ok := s.shares[key]
+	if !ok {
+		share = db.LinkShare{LinkID: arg.LinkID, UserID: arg.UserID, CreatedAt: s.timestamp()}
+	}
+	share.Permission = arg.Permission
+	share.ExpiresAt = arg.ExpiresAt
+	s.shares[key] = share
+	return share, nil
+}
+
+// ListLinkShares lists everyone a link is shared with, expired shares
+// included, oldest first.
+func (s *Store) ListLinkShares(ctx context.Context, linkID pgtype.UUID) ([]db.ListLinkSharesRow, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var rows []db.ListLinkSharesRow
+	for _, share := range s.shares {
+		if share.LinkID != linkID {
+			continue
+		}
+		rows = append(rows, db.ListLinkSharesRow{
+			LinkID:     share.LinkID,
+			UserID:     share.UserID,
+			Email:      s.users[share.UserID.Bytes].Email,
+			Permission: share.Permission,
+			ExpiresAt:  share.ExpiresAt,
+			CreatedAt:  share.CreatedAt,
+		})
+	}
+	sort.Slice(rows, func(i, j int) bool {
+		if !rows[i].CreatedAt.Time.Equal(rows[j].CreatedAt.Time) {
+			return rows[i].CreatedAt.Time.Before(rows[j].CreatedAt.Time)
+		}
+		return rows[i].Email < rows[j].Email
+	})
+	return rows, nil
+}
+
+// DeleteLinkShare stops sharing a link with a user and reports how many
+// shares it removed.
+func (s *Store) DeleteLinkShare(ctx context.Context, arg db.DeleteLinkShareParams) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := shareKey(arg.LinkID, arg.UserID)
+	if _, ok := s.shares[key]; !ok {
+		return 0, nil
+	}
+	delete(s.shares, key)
+	return 1, nil
+}
+
+// GetLinkSharePermission returns what an unexpired share lets the user do.
+func (s *Store) GetLinkSharePermission(ctx context.Context, arg db.GetLinkSharePermissionParams) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	share, ok := s.shares[shareKey(arg.LinkID, arg.UserID)]
+	if !ok || !s.shareActive(share) {
+		return "", pgx.ErrNoRows
+	}
+	return share.Permission, nil
+}
+
+// ListLinksSharedWithUser lists the links other users share with userID
+// whose shares have not expired, most recently shared first.
+func (s *Store) ListLinksSharedWithUser(ctx context.Context, userID pgtype.UUID) ([]db.ListLinksSharedWithUserRow, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var rows []db.ListLinksSharedWithUserRow
+	for _, share := range s.shares {
+		if share.UserID != userID || !s.shareActive(share) {
+			continue
+		}
+		link := s.links[share.LinkID.Bytes]
+		rows = append(rows, db.ListLinksSharedWithUserRow{
+			ID:         link.ID,
+			Url:        link.Url,
+			Title:      link.Title,
+			OwnerEmail: s.users[link.UserID.Bytes].Email,
+			Permission: share.Permission,
+			ExpiresAt:  share.ExpiresAt,
+			CreatedAt:  share.CreatedAt,
+		})
+	}
+	sort.Slice(rows, func(i, j int) bool {
+		if !rows[i].CreatedAt.Time.Equal(rows[j].CreatedAt.Time) {
+			return rows[i].CreatedAt.Time.After(rows[j].CreatedAt.Time)
+		}
+		return lessUUID(rows[i].ID, rows[j].ID)
+	})
+	return rows, nil
+}
+
+func (s *Store) shareActive(share db.LinkShare) bool {
+	return !share.ExpiresAt.Valid || share.ExpiresAt.Time.After(s.now())
+}
+
+func shareKey(linkID, userID pgtype.UUID) [32]byte {
+	var key [32]byte
+	copy(key[:16], linkID.Bytes[:])
+	copy(key[16:], userID.Bytes[:])
+	return key
+}
@@ -0,0 +1,85 @@
+package memstore
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"github.com/example/keepstack/apps/api/internal/db"
+)
+
+// syncChange is one row of the sync change log.
+type syncChange struct {
+	userID pgtype.UUID
+	row    db.ListSyncChangesRow
+}
+
+// after reports whether the change sorts after the (tx, seq) cursor.
+func (c syncChange) after(tx, seq int64) bool {
+	return c.row.TxID > tx || (c.row.TxID == tx && c.row.Seq > seq)
+}
+
+// ListSyncChanges returns the user's changes after the cursor in the order
+// they happened. Every change in the store is committed, so none is held
+// back the way in-flight transactions are in Postgres.
+func (s *Store) ListSyncChanges(ctx context.Context, arg db.ListSyncChangesParams) ([]db.ListSyncChangesRow, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var rows []db.ListSyncChangesRow
+	for _, change := range s.changes {
+		if change.userID == arg.UserID && change.after(arg.CursorTx, arg.CursorSeq) {
+			rows = append(rows, change.row)
+		}
+	}
+	return page(rows, 0, arg.PageLimit), nil
+}
+
+// GetSyncChangeSince reports whether the newest change to an entity after
+// the cursor deleted it, or pgx.ErrNoRows when it has not changed since.
+func (s *Store) GetSyncChangeSince(ctx context.Context, arg db.GetSyncChangeSinceParams) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i := len(s.changes) - 1; i >= 0; i-- {
+		change := s.changes[i]
+		if !change.after(arg.CursorTx, arg.CursorSeq) {
+			break
+		}
+		if change.userID == arg.UserID && change.row.Entity == arg.Entity && change.row.EntityID == arg.EntityID {
+			return change.row.Deleted, nil
+		}
+	}
+	return false, pgx.ErrNoRows
+}
+
+// ListSyncLinks returns the links among Ids that belong to the user, with
+// their tag names.
+func (s *Store) ListSyncLinks(ctx context.Context, arg db.ListSyncLinksParams) ([]db.ListSyncLinksRow, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var rows []db.ListSyncLinksRow
+	for _, id := range arg.Ids {
+		link, ok := s.links[id.Bytes]
+		if !ok || link.UserID != arg.UserID {
+			continue
+		}
+		names := []string{}
+		for _, tag := range s.sortedTags(id.Bytes) {
+			names = append(names, tag.Name)
+		}
+		rows = append(rows, db.ListSyncLinksRow{
+			ID:        link.ID,
+			Url:       link.Url,
+			Title:     link.Title,
+			Favorite:  link.Favorite,
+			ReadAt:    link.ReadAt,
+			CreatedAt: link.CreatedAt,
+			UpdatedAt: link.UpdatedAt,
+			TagNames:  names,
+		})
+	}
+	return rows, nil
+}
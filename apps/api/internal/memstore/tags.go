@@ -0,0 +1,219 @@
+package memstore
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"github.com/example/keepstack/apps/api/internal/db"
+)
+
+// CreateTag adds a tag for a user. Names are unique per user.
+func (s *Store) CreateTag(ctx context.Context, arg db.CreateTagParams) (db.Tag, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.tagByName(arg.UserID, arg.Name); ok {
+		return db.Tag{}, uniqueViolation("tags_user_id_name_key")
+	}
+	s.nextTagID++
+	tag := db.Tag{ID: s.nextTagID, Name: arg.Name, UserID: arg.UserID}
+	s.tags[tag.ID] = tag
+	return tag, nil
+}
+
+// GetTag returns one of a user's tags by ID.
+func (s *Store) GetTag(ctx context.Context, arg db.GetTagParams) (db.Tag, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tag, ok := s.tags[arg.ID]
+	if !ok || tag.UserID != arg.UserID {
+		return db.Tag{}, pgx.ErrNoRows
+	}
+	return tag, nil
+}
+
+// GetTagByName returns one of a user's tags by name.
+func (s *Store) GetTagByName(ctx context.Context, arg db.GetTagByNameParams) (db.Tag, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tag, ok := s.tagByName(arg.UserID, arg.Name)
+	if !ok {
+		return db.Tag{}, pgx.ErrNoRows
+	}
+	return tag, nil
+}
+
+// UpdateTag renames a tag, which changes every link carrying it.
+func (s *Store) UpdateTag(ctx context.Context, arg db.UpdateTagParams) (db.Tag, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tag, ok := s.tags[arg.ID]
+	if !ok || tag.UserID != arg.UserID {
+		return db.Tag{}, pgx.ErrNoRows
+	}
+	if tag.Name == arg.Name {
+		return tag, nil
+	}
+	if _, taken := s.tagByName(arg.UserID, arg.Name); taken {
+		return db.Tag{}, uniqueViolation("tags_user_id_name_key")
+	}
+	tag.Name = arg.Name
+	s.tags[tag.ID] = tag
+
+	tx := s.begin()
+	for linkID, tags := range s.linkTags {
+		if _, ok := tags[tag.ID]; ok {
+			link := s.links[linkID]
+			s.record(tx, link.UserID, "link", link.ID, false)
+		}
+	}
+	return tag, nil
+}
+
+// DeleteTag removes one of a user's tags from every link and then the tag
+// itself.
+func (s *Store) DeleteTag(ctx context.Context, arg db.DeleteTagParams) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	id := arg.ID
+	if tag, ok := s.tags[id]; !ok || tag.UserID != arg.UserID {
+		return nil
+	}
+	tx := s.begin()
+	for linkID, tags := range s.linkTags {
+		if _, ok := tags[id]; ok {
+			delete(tags, id)
+			link := s.links[linkID]
+			s.record(tx, link.UserID, "link", link.ID, false)
+		}
+	}
+	delete(s.tags, id)
+	return nil
+}
+
+// ListTagLinkCounts lists a user's tags with how many links carry each, by
+// name.
+func (s *Store) ListTagLinkCounts(ctx context.Context, userID pgtype.UUID) ([]db.ListTagLinkCountsRow, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	counts := make(map[int32]int32, len(s.tags))
+	for _, tags := range s.linkTags {
+		for id := range tags {
+			counts[id]++
+		}
+	}
+	rows := make([]db.ListTagLinkCountsRow, 0, len(s.tags))
+	for _, tag := range s.sortedUserTags(userID) {
+		rows = append(rows, db.ListTagLinkCountsRow{ID: tag.ID, Name: tag.Name, LinkCount: counts[tag.ID]})
+	}
+	return rows, nil
+}
+
+// SearchTagsByPrefix lists a user's tags whose names start with Prefix,
+// ignoring case, by name.
+func (s *Store) SearchTagsByPrefix(ctx context.Context, arg db.SearchTagsByPrefixParams) ([]db.Tag, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	prefix := strings.ToLower(arg.Prefix)
+	var tags []db.Tag
+	for _, tag := range s.sortedUserTags(arg.UserID) {
+		if strings.HasPrefix(strings.ToLower(tag.Name), prefix) {
+			tags = append(tags, tag)
+		}
+	}
+	return page(tags, 0, arg.PageLimit), nil
+}
+
+// ListTagsForLink lists the tags on a link by name.
+func (s *Store) ListTagsForLink(ctx context.Context, linkID pgtype.UUID) ([]db.Tag, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.sortedTags(linkID.Bytes), nil
+}
+
+// AddTagToLink tags a link. Tagging it again is a no-op.
+func (s *Store) AddTagToLink(ctx context.Context, arg db.AddTagToLinkParams) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.links[arg.LinkID.Bytes]; !ok {
+		return foreignKeyViolation("link_tags_link_id_fkey")
+	}
+	if _, ok := s.tags[arg.TagID]; !ok {
+		return foreignKeyViolation("link_tags_tag_id_fkey")
+	}
+	s.tagLink(s.begin(), arg.LinkID.Bytes, arg.TagID)
+	return nil
+}
+
+// ApplyTagToMatchingLinks tags every one of the user's links matching the
+// filter and counts how many matched and how many were newly tagged.
+func (s *Store) ApplyTagToMatchingLinks(ctx context.Context, arg db.ApplyTagToMatchingLinksParams) (db.ApplyTagToMatchingLinksRow, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.tags[arg.TagID]; !ok {
+		return db.ApplyTagToMatchingLinksRow{}, foreignKeyViolation("link_tags_tag_id_fkey")
+	}
+	var row db.ApplyTagToMatchingLinksRow
+	tx := s.begin()
+	for _, link := range s.filterLinks(arg.UserID, pgtype.Bool{}, arg.Query, true, nil, pgtype.UUID{}, pgtype.Bool{}) {
+		if arg.Domain.Valid {
+			host := ""
+			if match := fullHostPattern.FindStringSubmatch(link.Url); match != nil {
+				host = strings.ToLower(match[1])
+			}
+			if !domainCovers(arg.Domain.String, host) {
+				continue
+			}
+		}
+		if arg.CreatedAfter.Valid && link.CreatedAt.Time.Before(arg.CreatedAfter.Time) {
+			continue
+		}
+		if arg.CreatedBefore.Valid && !link.CreatedAt.Time.Before(arg.CreatedBefore.Time) {
+			continue
+		}
+		row.Matched++
+		if s.tagLink(tx, link.ID.Bytes, arg.TagID) {
+			row.Tagged++
+		}
+	}
+	return row, nil
+}
+
+// RemoveTagFromLink untags a link.
+func (s *Store) RemoveTagFromLink(ctx context.Context, arg db.RemoveTagFromLinkParams) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tags := s.linkTags[arg.LinkID.Bytes]
+	if _, ok := tags[arg.TagID]; !ok {
+		return nil
+	}
+	delete(tags, arg.TagID)
+	link := s.links[arg.LinkID.Bytes]
+	s.record(s.begin(), link.UserID, "link", link.ID, false)
+	return nil
+}
+
+func (s *Store) sortedUserTags(userID pgtype.UUID) []db.Tag {
+	var tags []db.Tag
+	for _, tag := range s.tags {
+		if tag.UserID == userID {
+			tags = append(tags, tag)
+		}
+	}
+	sort.Slice(tags, func(i, j int) bool { return tags[i].Name < tags[j].Name })
+	return tags
+}
@@ -0,0 +1,347 @@
+package observability
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/push"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+)
+
+const cronNamespace = "keepstack_cron"
+
+// cronLastSuccessMetric is the series a failed run leaves out.
+const cronLastSuccessMetric = cronNamespace + "_last_success_timestamp_seconds"
+
+// CronMetrics captures the outcome of a single cron subcommand run. The
+// collectors live on a dedicated registry because cron pods exit before
+// Prometheus could scrape them; the values are pushed once the run finishes.
+type CronMetrics struct {
+	Registry   *prometheus.Registry
+	Subcommand string
+
+	RunDurationSeconds       prometheus.Gauge
+	RunSuccess               prometheus.Gauge
+	LastRunTimestamp         prometheus.Gauge
+	LastSuccessTimestamp     prometheus.Gauge
+	Errors                   prometheus.Counter
+	ResurfacerUsers          prometheus.Gauge
+	ResurfacerWritten        prometheus.Gauge
+	ResurfacerRebuildSeconds prometheus.Gauge
+	ArchiveOrphansRemoved    prometheus.Gauge
+	ArchiveHTMLCleared       prometheus.Gauge
+	ArchiveReclaimedBytes    prometheus.Gauge
+	SearchIndexChecked       prometheus.Gauge
+	SearchIndexStale         prometheus.Gauge
+	SearchIndexTriggers      prometheus.Gauge
+	WordCountChecked         prometheus.Gauge
+	WordCountUpdated         prometheus.Gauge
+	ResanitizeChecked        prometheus.Gauge
+	ResanitizeUpdated        prometheus.Gauge
+	IntegrityChecked         prometheus.Gauge
+	IntegrityMismatched      prometheus.Gauge
+	IntegrityHashed          prometheus.Gauge
+}
+
+// NewCronMetrics builds the collectors for the named subcommand.
+func NewCronMetrics(subcommand string) *CronMetrics {
+	registry := prometheus.NewRegistry()
+	m := &CronMetrics{
+		Registry:   registry,
+		Subcommand: subcommand,
+		RunDurationSeconds: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: cronNamespace,
+			Name:      "run_duration_seconds",
+			Help:      "Wall-clock duration of the most recent cron subcommand run.",
+		}),
+		RunSuccess: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: cronNamespace,
+			Name:      "run_success",
+			Help:      "Whether the most recent cron subcommand run succeeded (1) or failed (0).",
+		}),
+		LastRunTimestamp: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: cronNamespace,
+			Name:      "last_run_timestamp_seconds",
+			Help:      "Unix timestamp of the most recent cron subcommand run.",
+		}),
+		LastSuccessTimestamp: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: cronNamespace,
+			Name:      "last_success_timestamp_seconds",
+			Help:      "Unix timestamp of the most recent successful cron subcommand run.",
+		}),
+		Errors: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: cronNamespace,
+			Name:      "errors_total",
+			Help:      "Number of errors reported by the cron subcommand run.",
+		}),
+		ResurfacerUsers: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: cronNamespace,
+			Name:      "resurfacer_users_processed",
+			Help:      "Number of users whose recommendations were rebuilt in the most recent run.",
+		}),
+		ResurfacerWritten: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: cronNamespace,
+			Name:      "resurfacer_recommendations_written",
+			Help:      "Number of recommendations written in the most recent run.",
+		}),
+		ResurfacerRebuildSeconds: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: cronNamespace,
+			Name:      "resurfacer_rebuild_duration_seconds",
+			Help:      "Time spent rebuilding recommendations in the most recent run.",
+		}),
+		ArchiveOrphansRemoved: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: cronNamespace,
+			Name:      "archive_orphans_removed",
+			Help:      "Number of orphaned archive rows removed in the most recent run.",
+		}),
+		ArchiveHTMLCleared: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: cronNamespace,
+			Name:      "archive_html_cleared",
+			Help:      "Number of archives whose raw HTML was cleared in the most recent run.",
+		}),
+		ArchiveReclaimedBytes: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: cronNamespace,
+			Name:      "archive_reclaimed_bytes",
+			Help:      "Uncompressed bytes removed from archives in the most recent run.",
+		}),
+		SearchIndexChecked: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: cronNamespace,
+			Name:      "search_index_links_checked",
+			Help:      "Number of links whose search vector was checked in the most recent run.",
+		}),
+		SearchIndexStale: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: cronNamespace,
+			Name:      "search_index_stale_vectors",
+			Help:      "Number of stale search vectors found (and, unless a dry run, rebuilt) in the most recent run.",
+		}),
+		SearchIndexTriggers: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: cronNamespace,
+			Name:      "search_index_triggers_enabled",
+			Help:      "Number of disabled search triggers found (and, unless a dry run, enabled) in the most recent run.",
+		}),
+		WordCountChecked: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: cronNamespace,
+			Name:      "wordcount_archives_checked",
+			Help:      "Number of archives missing a word count or language checked in the most recent run.",
+		}),
+		WordCountUpdated: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: cronNamespace,
+			Name:      "wordcount_archives_updated",
+			Help:      "Number of archives whose word count or language was (or, in a dry run, would be) updated in the most recent run.",
+		}),
+		ResanitizeChecked: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: cronNamespace,
+			Name:      "resanitize_archives_checked",
+			Help:      "Number of archives whose HTML was checked against the sanitization policy in the most recent run.",
+		}),
+		ResanitizeUpdated: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: cronNamespace,
+			Name:      "resanitize_archives_updated",
+			Help:      "Number of archives whose HTML was (or, in a dry run, would be) rewritten in the most recent run.",
+		}),
+		IntegrityChecked: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: cronNamespace,
+			Name:      "archive_integrity_checked",
+			Help:      "Number of archives checked against their ingest digests in the most recent run.",
+		}),
+		IntegrityMismatched: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: cronNamespace,
+			Name:      "archive_integrity_mismatched",
+			Help:      "Number of archives whose HTML or extracted text no longer matches its ingest digest in the most recent run.",
+		}),
+		IntegrityHashed: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: cronNamespace,
+			Name:      "archive_integrity_hashed",
+			Help:      "Number of archives without digests that had them (or, in a dry run, would have them) recorded in the most recent run.",
+		}),
+	}
+
+	registry.MustRegister(
+		m.RunDurationSeconds,
+		m.RunSuccess,
+		m.LastRunTimestamp,
+		m.LastSuccessTimestamp,
+		m.Errors,
+	)
+	if subcommand == "resurface" {
+		registry.MustRegister(m.ResurfacerUsers, m.ResurfacerWritten, m.ResurfacerRebuildSeconds)
+	}
+	if subcommand == "archive-vacuum" {
+		registry.MustRegister(m.ArchiveOrphansRemoved, m.ArchiveHTMLCleared, m.ArchiveReclaimedBytes)
+	}
+	if subcommand == "search-reindex" {
+		registry.MustRegister(m.SearchIndexChecked, m.SearchIndexStale, m.SearchIndexTriggers)
+	}
+	if subcommand == "wordcount-backfill" {
+		registry.MustRegister(m.WordCountChecked, m.WordCountUpdated)
+	}
+	if subcommand == "archive-resanitize" {
+		registry.MustRegister(m.ResanitizeChecked, m.ResanitizeUpdated)
+	}
+	if subcommand == "archive-verify" {
+		registry.MustRegister(m.IntegrityChecked, m.IntegrityMismatched, m.IntegrityHashed)
+	}
+
+	return m
+}
+
+// ObserveRun records the run duration and outcome. Failed runs drop the
+// last-success gauge so a push does not overwrite the previous success time.
+func (m *CronMetrics) ObserveRun(finishedAt time.Time, duration time.Duration, err error) {
+	m.RunDurationSeconds.Set(duration.Seconds())
+	m.LastRunTimestamp.Set(float64(finishedAt.Unix()))
+	if err != nil {
+		m.RunSuccess.Set(0)
+		m.Errors.Inc()
+		m.Registry.Unregister(m.LastSuccessTimestamp)
+		return
+	}
+	m.RunSuccess.Set(1)
+	m.LastSuccessTimestamp.Set(float64(finishedAt.Unix()))
+}
+
+// Push sends the collected metrics to a Prometheus Pushgateway, grouped by
+// subcommand so each CronJob keeps its own series. Metrics missing from the
+// registry keep their previously pushed values.
+func (m *CronMetrics) Push(ctx context.Context, gatewayURL string) error {
+	pusher := push.New(gatewayURL, cronNamespace).
+		Gatherer(m.Registry).
+		Grouping("subcommand", m.Subcommand)
+	if err := pusher.AddContext(ctx); err != nil {
+		return fmt.Errorf("push cron metrics: %w", err)
+	}
+	return nil
+}
+
+// WriteText writes the collected metrics in the Prometheus text format, each
+// series labelled with the subcommand as Push groups them.
+func (m *CronMetrics) WriteText(w io.Writer) error {
+	families, err := m.gather()
+	if err != nil {
+		return err
+	}
+	return writeFamilies(w, families)
+}
+
+// WriteFile replaces path with the metrics text, for node_exporter's textfile
+// collector or a sidecar that ships the file. The dump is written beside path
+// and renamed into place so readers never see it half written. A failed run
+// carries over the last success timestamp from the previous dump, as Push
+// leaves the previously pushed value in place.
+func (m *CronMetrics) WriteFile(path string) error {
+	families, err := m.gather()
+	if err != nil {
+		return err
+	}
+	if !hasFamily(families, cronLastSuccessMetric) {
+		if previous, err := readFamilies(path); err == nil {
+			if family, ok := previous[cronLastSuccessMetric]; ok {
+				families = append(families, family)
+				sort.Slice(families, func(i, j int) bool { return families[i].GetName() < families[j].GetName() })
+			}
+		}
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".cron-metrics-*")
+	if err != nil {
+		return fmt.Errorf("write cron metrics: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if err := writeFamilies(tmp, families); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(0o644); err != nil {
+		tmp.Close()
+		return fmt.Errorf("write cron metrics: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("write cron metrics: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("write cron metrics: %w", err)
+	}
+	return nil
+}
+
+// PostText sends the metrics text to url in a single POST, for receivers
+// that accept the exposition format such as VictoriaMetrics'
+// /api/v1/import/prometheus.
+func (m *CronMetrics) PostText(ctx context.Context, client *http.Client, url string) error {
+	var body bytes.Buffer
+	if err := m.WriteText(&body); err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, &body)
+	if err != nil {
+		return fmt.Errorf("post cron metrics: %w", err)
+	}
+	req.Header.Set("Content-Type", string(expfmt.FmtText))
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("post cron metrics: %w", err)
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("post cron metrics: unexpected status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// gather collects the registry and adds the subcommand label to every
+// series.
+func (m *CronMetrics) gather() ([]*dto.MetricFamily, error) {
+	families, err := m.Registry.Gather()
+	if err != nil {
+		return nil, fmt.Errorf("gather cron metrics: %w", err)
+	}
+	name, value := "subcommand", m.Subcommand
+	for _, family := range families {
+		for _, metric := range family.Metric {
+			metric.Label = append(metric.Label, &dto.LabelPair{Name: &name, Value: &value})
+		}
+	}
+	return families, nil
+}
+
+func writeFamilies(w io.Writer, families []*dto.MetricFamily) error {
+	for _, family := range families {
+		if _, err := expfmt.MetricFamilyToText(w, family); err != nil {
+			return fmt.Errorf("encode cron metrics: %w", err)
+		}
+	}
+	return nil
+}
+
+func readFamilies(path string) (map[string]*dto.MetricFamily, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var parser expfmt.TextParser
+	families, err := parser.TextToMetricFamilies(f)
+	if err != nil {
+		return nil, fmt.Errorf("parse previous cron metrics: %w", err)
+	}
+	return families, nil
+}
+
+func hasFamily(families []*dto.MetricFamily, name string) bool {
+	for _, family := range families {
+		if family.GetName() == name {
+			return true
+		}
+	}
+	return false
+}
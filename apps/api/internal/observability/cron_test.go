@@ -0,0 +1,98 @@
+package observability
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCronMetricsWriteTextLabelsSubcommand(t *testing.T) {
+	t.Parallel()
+
+	metrics := NewCronMetrics("digest")
+	metrics.ObserveRun(time.Unix(1700000000, 0), 3*time.Second, nil)
+
+	var buf bytes.Buffer
+	if err := metrics.WriteText(&buf); err != nil {
+		t.Fatalf("WriteText: %v", err)
+	}
+	out := buf.String()
+	for _, want := range []string{
+		`keepstack_cron_run_success{subcommand="digest"} 1`,
+		`keepstack_cron_run_duration_seconds{subcommand="digest"} 3`,
+		`keepstack_cron_last_success_timestamp_seconds{subcommand="digest"} 1.7e+09`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Fatalf("expected %q in:\n%s", want, out)
+		}
+	}
+}
+
+func TestCronMetricsWriteFileKeepsLastSuccessAfterFailure(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "cron.prom")
+
+	succeeded := NewCronMetrics("backup")
+	succeeded.ObserveRun(time.Unix(1700000000, 0), time.Second, nil)
+	if err := succeeded.WriteFile(path); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+
+	failed := NewCronMetrics("backup")
+	failed.ObserveRun(time.Unix(1700003600, 0), time.Second, errors.New("boom"))
+	if err := failed.WriteFile(path); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read dump: %v", err)
+	}
+	out := string(data)
+	if !strings.Contains(out, `keepstack_cron_run_success{subcommand="backup"} 0`) {
+		t.Fatalf("expected the failed run in:\n%s", out)
+	}
+	if !strings.Contains(out, `keepstack_cron_last_success_timestamp_seconds{subcommand="backup"} 1.7e+09`) {
+		t.Fatalf("expected the previous success to be kept in:\n%s", out)
+	}
+	if entries, _ := os.ReadDir(filepath.Dir(path)); len(entries) != 1 {
+		t.Fatalf("expected no temporary files to be left, got %d entries", len(entries))
+	}
+}
+
+func TestCronMetricsPostText(t *testing.T) {
+	t.Parallel()
+
+	var received string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received = string(body)
+		if strings.HasSuffix(r.URL.Path, "/reject") {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	metrics := NewCronMetrics("resurface")
+	metrics.ObserveRun(time.Now(), time.Second, nil)
+	if err := metrics.PostText(context.Background(), srv.Client(), srv.URL+"/import"); err != nil {
+		t.Fatalf("PostText: %v", err)
+	}
+	if !strings.Contains(received, `keepstack_cron_run_success{subcommand="resurface"} 1`) {
+		t.Fatalf("unexpected body:\n%s", received)
+	}
+	if err := metrics.PostText(context.Background(), srv.Client(), srv.URL+"/reject"); err == nil {
+		t.Fatal("expected an error for a rejected post")
+	}
+}
@@ -0,0 +1,182 @@
+package observability
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// REDDashboardUID identifies the generated dashboard in Grafana.
+const REDDashboardUID = "keepstack-red"
+
+// REDSeries describes one family of rate, errors, and duration metrics that
+// share a grouping label, such as HTTP requests by route.
+type REDSeries struct {
+	Title string
+	// GroupBy is the label the panels are broken down by.
+	GroupBy string
+	// Requests is a counter of every handled unit of work.
+	Requests string
+	// ErrorMatcher selects the failed subset of Requests.
+	ErrorMatcher string
+	// Duration is the histogram the latency panel reads from.
+	Duration string
+}
+
+// REDSeriesSet lists the RED metric families exported by the API and worker.
+// The Grafana dashboard shipped with the chart is generated from it, so keep
+// it in step with NewMetrics and the worker's metric definitions.
+var REDSeriesSet = []REDSeries{
+	{
+		Title:        "API HTTP",
+		GroupBy:      "route",
+		Requests:     "keepstack_api_http_requests_total",
+		ErrorMatcher: `code=~"5.."`,
+		Duration:     "keepstack_api_http_request_duration_seconds",
+	},
+	{
+		Title:        "API queue",
+		GroupBy:      "subject",
+		Requests:     "keepstack_api_queue_messages_total",
+		ErrorMatcher: `outcome="` + OutcomeError + `"`,
+		Duration:     "keepstack_api_queue_message_duration_seconds",
+	},
+	{
+		Title:        "Worker queue",
+		GroupBy:      "subject",
+		Requests:     "keepstack_worker_queue_messages_total",
+		ErrorMatcher: `outcome="` + OutcomeError + `"`,
+		Duration:     "keepstack_worker_queue_message_duration_seconds",
+	},
+	{
+		Title:        "API database",
+		GroupBy:      "query",
+		Requests:     "keepstack_api_db_query_duration_seconds_count",
+		ErrorMatcher: `status="error"`,
+		Duration:     "keepstack_api_db_query_duration_seconds",
+	},
+	{
+		Title:        "Worker database",
+		GroupBy:      "query",
+		Requests:     "keepstack_worker_db_query_duration_seconds_count",
+		ErrorMatcher: `status="error"`,
+		Duration:     "keepstack_worker_db_query_duration_seconds",
+	},
+}
+
+type grafanaDashboard struct {
+	Title         string             `json:"title"`
+	UID           string             `json:"uid"`
+	SchemaVersion int                `json:"schemaVersion"`
+	Refresh       string             `json:"refresh"`
+	Time          grafanaTimeRange   `json:"time"`
+	Panels        []grafanaPanel     `json:"panels"`
+	Templating    grafanaTemplateSet `json:"templating"`
+}
+
+type grafanaTimeRange struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+type grafanaTemplateSet struct {
+	List []any `json:"list"`
+}
+
+type grafanaDatasource struct {
+	Type string `json:"type"`
+	UID  string `json:"uid"`
+}
+
+type grafanaGridPos struct {
+	H int `json:"h"`
+	W int `json:"w"`
+	X int `json:"x"`
+	Y int `json:"y"`
+}
+
+type grafanaTarget struct {
+	Expr         string `json:"expr"`
+	LegendFormat string `json:"legendFormat"`
+	Exemplar     bool   `json:"exemplar"`
+}
+
+type grafanaFieldConfig struct {
+	Defaults grafanaFieldDefaults `json:"defaults"`
+}
+
+type grafanaFieldDefaults struct {
+	Unit string `json:"unit"`
+}
+
+type grafanaPanel struct {
+	ID          int                 `json:"id"`
+	Type        string              `json:"type"`
+	Title       string              `json:"title"`
+	Datasource  *grafanaDatasource  `json:"datasource,omitempty"`
+	Targets     []grafanaTarget     `json:"targets,omitempty"`
+	FieldConfig *grafanaFieldConfig `json:"fieldConfig,omitempty"`
+	GridPos     grafanaGridPos      `json:"gridPos"`
+}
+
+// RenderREDDashboard builds the Grafana dashboard JSON for REDSeriesSet: one
+// row per series with request rate, error ratio, and p95 latency panels.
+// Every query has exemplars enabled so latency points link to traces.
+func RenderREDDashboard() ([]byte, error) {
+	datasource := &grafanaDatasource{Type: "prometheus", UID: "prometheus"}
+	dashboard := grafanaDashboard{
+		Title:         "Keepstack RED",
+		UID:           REDDashboardUID,
+		SchemaVersion: 38,
+		Refresh:       "30s",
+		Time:          grafanaTimeRange{From: "now-1h", To: "now"},
+		Templating:    grafanaTemplateSet{List: []any{}},
+	}
+
+	id := 1
+	y := 0
+	panel := func(title, unit, expr string, x int, series REDSeries) grafanaPanel {
+		p := grafanaPanel{
+			ID:         id,
+			Type:       "timeseries",
+			Title:      title,
+			Datasource: datasource,
+			Targets: []grafanaTarget{{
+				Expr:         expr,
+				LegendFormat: fmt.Sprintf("{{%s}}", series.GroupBy),
+				Exemplar:     true,
+			}},
+			FieldConfig: &grafanaFieldConfig{Defaults: grafanaFieldDefaults{Unit: unit}},
+			GridPos:     grafanaGridPos{H: 8, W: 8, X: x, Y: y},
+		}
+		id++
+		return p
+	}
+
+	for _, series := range REDSeriesSet {
+		dashboard.Panels = append(dashboard.Panels, grafanaPanel{
+			ID:      id,
+			Type:    "row",
+			Title:   series.Title,
+			GridPos: grafanaGridPos{H: 1, W: 24, X: 0, Y: y},
+		})
+		id++
+		y++
+
+		rate := fmt.Sprintf("sum by (%s) (rate(%s[5m]))", series.GroupBy, series.Requests)
+		errors := fmt.Sprintf("sum by (%s) (rate(%s{%s}[5m])) / %s", series.GroupBy, series.Requests, series.ErrorMatcher, rate)
+		p95 := fmt.Sprintf("histogram_quantile(0.95, sum by (%s, le) (rate(%s_bucket[5m])))", series.GroupBy, series.Duration)
+
+		dashboard.Panels = append(dashboard.Panels,
+			panel(series.Title+" rate", "reqps", rate, 0, series),
+			panel(series.Title+" error ratio", "percentunit", errors, 8, series),
+			panel(series.Title+" p95 latency", "s", p95, 16, series),
+		)
+		y += 8
+	}
+
+	out, err := json.MarshalIndent(dashboard, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("marshal dashboard: %w", err)
+	}
+	return append(out, '\n'), nil
+}
@@ -0,0 +1,26 @@
+package observability
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestREDDashboardIsUpToDate(t *testing.T) {
+	t.Parallel()
+
+	got, err := RenderREDDashboard()
+	if err != nil {
+		t.Fatalf("render dashboard: %v", err)
+	}
+
+	path := filepath.Join("..", "..", "..", "..", "deploy", "charts", "keepstack", "dashboards", "keepstack-red.json")
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read %s: %v", path, err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Fatalf("%s is stale; run make dashboards", path)
+	}
+}
@@ -0,0 +1,25 @@
+package observability
+
+import (
+	"context"
+	"time"
+
+	"github.com/example/keepstack/observe"
+)
+
+// Queue message outcomes used as the "outcome" label.
+const (
+	OutcomeSuccess = "success"
+	OutcomeError   = "error"
+)
+
+// ObserveMessage records the rate, errors, and duration of one queue message
+// handled for subject.
+func (m *Metrics) ObserveMessage(ctx context.Context, subject string, duration time.Duration, err error) {
+	outcome := OutcomeSuccess
+	if err != nil {
+		outcome = OutcomeError
+	}
+	observe.IncWithTrace(ctx, m.QueueMessagesTotal.WithLabelValues(subject, outcome))
+	observe.ObserveWithTrace(ctx, m.QueueMessageDurationSeconds.WithLabelValues(subject), duration.Seconds())
+}
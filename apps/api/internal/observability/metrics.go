@@ -10,12 +10,21 @@ type Metrics struct {
 	HTTPRequestDurationSeconds *prometheus.HistogramVec
 	HTTPRequestTotal           *prometheus.CounterVec
 	HTTPRequestNon2xxTotal     *prometheus.CounterVec
+	HTTPRateLimited            *prometheus.CounterVec
+	HTTPRequestTimeouts        *prometheus.CounterVec
+	DBQueryDurationSeconds     *prometheus.HistogramVec
 	LinkCreateSuccess          prometheus.Counter
 	LinkCreateFailure          prometheus.Counter
 	LinkListSuccess            prometheus.Counter
 	LinkListFailure            prometheus.Counter
 	LinkUpdateSuccess          prometheus.Counter
 	LinkUpdateFailure          prometheus.Counter
+	LinkDeleteSuccess          prometheus.Counter
+	LinkDeleteFailure          prometheus.Counter
+	LinkExportSuccess          prometheus.Counter
+	LinkExportFailure          prometheus.Counter
+	LinkStreamSuccess          prometheus.Counter
+	LinkStreamFailure          prometheus.Counter
 	ClaimCreateSuccess         prometheus.Counter
 	ClaimCreateFailure         prometheus.Counter
 	ReadinessFailure           prometheus.Counter
@@ -43,7 +52,17 @@ type Metrics struct {
 	HighlightDeleteSuccess     prometheus.Counter
 	HighlightDeleteFailure     prometheus.Counter
 	HighlightRateLimited       prometheus.Counter
+	QuotaExceeded              *prometheus.CounterVec
 	HighlightProcessingSeconds prometheus.Histogram
+	ResurfaceQueued            prometheus.Counter
+	ResurfaceFailure           prometheus.Counter
+	RecommendationImpressions  *prometheus.CounterVec
+	RecommendationActions      *prometheus.CounterVec
+
+	QueueMessagesTotal          *prometheus.CounterVec
+	QueueMessageDurationSeconds *prometheus.HistogramVec
+
+	GRPCRequestDurationSeconds *prometheus.HistogramVec
 }
 
 // NewMetrics registers and returns API metrics collectors.
@@ -66,6 +85,22 @@ func NewMetrics() *Metrics {
 			Name:      "http_requests_non_2xx_total",
 			Help:      "Number of HTTP requests that resulted in non-2xx responses, labelled by route and status code.",
 		}, []string{"route", "code"}),
+		HTTPRateLimited: promauto.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "http_rate_limited_total",
+			Help:      "Number of requests rejected by the rate limiter, labelled by route class.",
+		}, []string{"class"}),
+		HTTPRequestTimeouts: promauto.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "http_request_timeouts_total",
+			Help:      "Number of requests that ran past HTTP_REQUEST_TIMEOUT, labelled by route.",
+		}, []string{"route"}),
+		DBQueryDurationSeconds: promauto.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "db_query_duration_seconds",
+			Help:      "Distribution of database query durations in seconds, labelled by query name and status.",
+			Buckets:   []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5},
+		}, []string{"query", "status"}),
 		LinkCreateSuccess: promauto.NewCounter(prometheus.CounterOpts{
 			Namespace: namespace,
 			Name:      "link_create_success_total",
@@ -96,6 +131,36 @@ func NewMetrics() *Metrics {
 			Name:      "link_update_failure_total",
 			Help:      "Number of link update requests that failed.",
 		}),
+		LinkDeleteSuccess: promauto.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "link_delete_success_total",
+			Help:      "Number of link delete requests that succeeded.",
+		}),
+		LinkDeleteFailure: promauto.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "link_delete_failure_total",
+			Help:      "Number of link delete requests that failed.",
+		}),
+		LinkExportSuccess: promauto.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "link_export_success_total",
+			Help:      "Number of link exports streamed to completion.",
+		}),
+		LinkExportFailure: promauto.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "link_export_failure_total",
+			Help:      "Number of link exports that failed or were cut short.",
+		}),
+		LinkStreamSuccess: promauto.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "link_stream_success_total",
+			Help:      "Number of link streams sent to completion.",
+		}),
+		LinkStreamFailure: promauto.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "link_stream_failure_total",
+			Help:      "Number of link streams that failed or were cut short.",
+		}),
 		ClaimCreateSuccess: promauto.NewCounter(prometheus.CounterOpts{
 			Namespace: namespace,
 			Name:      "claim_create_success_total",
@@ -231,11 +296,55 @@ func NewMetrics() *Metrics {
 			Name:      "highlight_rate_limited_total",
 			Help:      "Number of highlight requests rejected due to rate limiting.",
 		}),
+		QuotaExceeded: promauto.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "quota_exceeded_total",
+			Help:      "Number of requests rejected because a user's daily quota was used up, labelled by kind.",
+		}, []string{"kind"}),
 		HighlightProcessingSeconds: promauto.NewHistogram(prometheus.HistogramOpts{
 			Namespace: namespace,
 			Name:      "highlight_processing_seconds",
 			Help:      "Distribution of highlight processing durations.",
 			Buckets:   prometheus.DefBuckets,
 		}),
+		ResurfaceQueued: promauto.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "recommendation_refresh_queued_total",
+			Help:      "Number of single-user recommendation refresh jobs published.",
+		}),
+		ResurfaceFailure: promauto.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "recommendation_refresh_failure_total",
+			Help:      "Number of recommendation refresh jobs that failed to publish or run.",
+		}),
+		RecommendationImpressions: promauto.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "recommendation_impressions_total",
+			Help:      "Number of recommendations shown, labelled by scoring strategy and surface.",
+		}, []string{"strategy", "surface"}),
+		RecommendationActions: promauto.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "recommendation_actions_total",
+			Help:      "Number of shown recommendations later opened, read, or favorited, labelled by scoring strategy and action.",
+		}, []string{"strategy", "action"}),
+
+		QueueMessagesTotal: promauto.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "queue_messages_total",
+			Help:      "Number of queue messages handled, labelled by subject and outcome.",
+		}, []string{"subject", "outcome"}),
+		QueueMessageDurationSeconds: promauto.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "queue_message_duration_seconds",
+			Help:      "Distribution of queue message handling durations in seconds, labelled by subject.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"subject"}),
+
+		GRPCRequestDurationSeconds: promauto.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "grpc_request_duration_seconds",
+			Help:      "Distribution of internal gRPC request durations in seconds, labelled by method and status code.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"method", "code"}),
 	}
 }
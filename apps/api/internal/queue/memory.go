@@ -0,0 +1,82 @@
+package queue
+
+import (
+	"context"
+	"sync"
+
+	"github.com/google/uuid"
+)
+
+// Memory is a Publisher that delivers in process instead of through NATS,
+// for MEMORY_MODE. Saved and reparsed links go to the OnLinkSaved handler in
+// place of a worker, and events go straight to the SubscribeEvents handler.
+// Recommendation refreshes and deleted links are dropped, since no resurfacer
+// runs and no media is mirrored.
+type Memory struct {
+	mu        sync.RWMutex
+	linkSaved func(context.Context, uuid.UUID)
+	events    func(Event)
+}
+
+// NewMemory returns a Memory publisher with no handlers.
+func NewMemory() *Memory {
+	return &Memory{}
+}
+
+// OnLinkSaved makes handler receive every saved link. It runs on the
+// publishing goroutine, so it should hand slow work off.
+func (m *Memory) OnLinkSaved(handler func(context.Context, uuid.UUID)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.linkSaved = handler
+}
+
+// SubscribeEvents delivers every live update event to handler.
+func (m *Memory) SubscribeEvents(handler func(Event)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.events = handler
+}
+
+// PublishLinkSaved hands linkID to the OnLinkSaved handler.
+func (m *Memory) PublishLinkSaved(ctx context.Context, linkID uuid.UUID) error {
+	m.mu.RLock()
+	handler := m.linkSaved
+	m.mu.RUnlock()
+	if handler != nil {
+		handler(ctx, linkID)
+	}
+	return nil
+}
+
+// PublishLinkReparse hands linkID to the OnLinkSaved handler, which ingests
+// it again. There is no archive to compare against, so force is ignored.
+func (m *Memory) PublishLinkReparse(ctx context.Context, linkID uuid.UUID, force bool) error {
+	return m.PublishLinkSaved(ctx, linkID)
+}
+
+// PublishLinkDeleted drops the message.
+func (m *Memory) PublishLinkDeleted(ctx context.Context, linkID uuid.UUID, mediaHashes []string) error {
+	return nil
+}
+
+// PublishRecommendationsRefresh drops the request.
+func (m *Memory) PublishRecommendationsRefresh(ctx context.Context, userID uuid.UUID) error {
+	return nil
+}
+
+// PublishEvent hands event to the SubscribeEvents handler.
+func (m *Memory) PublishEvent(ctx context.Context, event Event) error {
+	m.mu.RLock()
+	handler := m.events
+	m.mu.RUnlock()
+	if handler != nil {
+		handler(event)
+	}
+	return nil
+}
+
+// Close is a no-op.
+func (m *Memory) Close() {}
+
+var _ Publisher = (*Memory)(nil)
@@ -3,17 +3,62 @@ package queue
 import (
     "context"
     "encoding/json"
+    "errors"
     "fmt"
+    "log"
+    "net/http"
+    "time"
 
     "github.com/google/uuid"
     "github.com/nats-io/nats.go"
+    "go.opentelemetry.io/otel"
+    "go.opentelemetry.io/otel/attribute"
+    "go.opentelemetry.io/otel/codes"
+    "go.opentelemetry.io/otel/propagation"
+    "go.opentelemetry.io/otel/trace"
+
+    "github.com/example/keepstack/messages"
+)
+
+const tracerName = "github.com/example/keepstack/apps/api/internal/queue"
+
+const (
+    linkSavedSubject            = messages.SubjectLinksSaved
+    linkReparseSubject          = messages.SubjectLinksReparse
+    linkDeletedSubject          = messages.SubjectLinksDeleted
+    recommendationsRefreshGroup = "keepstack-api-resurfacer"
+    notificationsGroup          = "keepstack-api-notifications"
+)
+
+// RecommendationsRefreshSubject carries single-user recommendation refresh jobs.
+const RecommendationsRefreshSubject = messages.SubjectRecommendationsRefresh
+
+// EventsSubjectPrefix prefixes the live update subjects. The event type
+// follows it, e.g. "keepstack.events.link.ingested".
+const EventsSubjectPrefix = messages.EventsSubjectPrefix
+
+// Live update event types.
+const (
+    EventLinkIngested          = messages.EventLinkIngested
+    EventLinkStatus            = messages.EventLinkStatus
+    EventLinkDeleted           = messages.EventLinkDeleted
+    EventHighlightCreated      = messages.EventHighlightCreated
+    EventRecommendationUpdated = messages.EventRecommendationUpdated
+    EventDigestSent            = messages.EventDigestSent
+    EventBackupFailed          = messages.EventBackupFailed
 )
 
-const linkSavedSubject = "keepstack.links.saved"
+// Event is a live update for one user's clients. Data holds the
+// type-specific payload, if any.
+type Event = messages.Event
 
 // Publisher publishes domain events to NATS.
 type Publisher interface {
     PublishLinkSaved(ctx context.Context, linkID uuid.UUID) error
+    PublishLinkReparse(ctx context.Context, linkID uuid.UUID, force bool) error
+    PublishLinkDeleted(ctx context.Context, linkID uuid.UUID, mediaHashes []string) error
+    PublishRecommendationsRefresh(ctx context.Context, userID uuid.UUID) error
+    PublishEvent(ctx context.Context, event Event) error
     Close()
 }
 
@@ -31,15 +76,317 @@ func New(url string) (*NATS, error) {
     return &NATS{conn: conn}, nil
 }
 
+// LinkSavedMessage is the payload published when a link needs ingesting.
+// EnqueuedAt lets the worker measure how long the message waited.
+type LinkSavedMessage = messages.LinkSaved
+
 // PublishLinkSaved emits a message indicating a link should be processed.
 func (n *NATS) PublishLinkSaved(ctx context.Context, linkID uuid.UUID) error {
-    payload := map[string]string{"link_id": linkID.String()}
+    payload := LinkSavedMessage{LinkID: linkID.String(), EnqueuedAt: time.Now().UTC()}
     data, err := json.Marshal(payload)
     if err != nil {
         return fmt.Errorf("marshal link saved payload: %w", err)
     }
 
-    return n.conn.PublishMsg(&nats.Msg{Subject: linkSavedSubject, Data: data})
+    return n.publish(ctx, linkSavedSubject, data)
+}
+
+// PublishLinkReparse asks the worker to ingest an already saved link again.
+// Without force the worker keeps the archive when the page is unchanged.
+func (n *NATS) PublishLinkReparse(ctx context.Context, linkID uuid.UUID, force bool) error {
+    payload := messages.LinkReparse{LinkID: linkID.String(), RequestedAt: time.Now().UTC(), Force: force}
+    data, err := json.Marshal(payload)
+    if err != nil {
+        return fmt.Errorf("marshal link reparse payload: %w", err)
+    }
+
+    return n.publish(ctx, linkReparseSubject, data)
+}
+
+// PublishLinkDeleted tells the worker a link was deleted, passing the
+// mirrored images its archive showed so any no other archive shows can be
+// removed from media storage.
+func (n *NATS) PublishLinkDeleted(ctx context.Context, linkID uuid.UUID, mediaHashes []string) error {
+    payload := messages.LinkDeleted{LinkID: linkID.String(), MediaHashes: mediaHashes, DeletedAt: time.Now().UTC()}
+    data, err := json.Marshal(payload)
+    if err != nil {
+        return fmt.Errorf("marshal link deleted payload: %w", err)
+    }
+
+    return n.publish(ctx, linkDeletedSubject, data)
+}
+
+// PublishRecommendationsRefresh requests a resurfacer rebuild for a single user.
+func (n *NATS) PublishRecommendationsRefresh(ctx context.Context, userID uuid.UUID) error {
+    payload := messages.RecommendationsRefresh{UserID: userID.String()}
+    data, err := json.Marshal(payload)
+    if err != nil {
+        return fmt.Errorf("marshal recommendations refresh payload: %w", err)
+    }
+
+    return n.publish(ctx, RecommendationsRefreshSubject, data)
+}
+
+// PublishEvent broadcasts a live update to every API replica.
+func (n *NATS) PublishEvent(ctx context.Context, event Event) error {
+    data, err := json.Marshal(event)
+    if err != nil {
+        return fmt.Errorf("marshal %s event: %w", event.Type, err)
+    }
+
+    return n.publish(ctx, EventsSubjectPrefix+event.Type, data)
+}
+
+// publish sends data under a producer span and carries the trace context in
+// the message headers so consumers continue the same trace.
+func (n *NATS) publish(ctx context.Context, subject string, data []byte) error {
+    ctx, span := otel.Tracer(tracerName).Start(ctx, subject+" publish",
+        trace.WithSpanKind(trace.SpanKindProducer),
+        trace.WithAttributes(
+            attribute.String("messaging.system", "nats"),
+            attribute.String("messaging.destination.name", subject),
+        ),
+    )
+    defer span.End()
+
+    msg := &nats.Msg{Subject: subject, Data: data, Header: nats.Header{}}
+    otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(http.Header(msg.Header)))
+
+    if err := n.conn.PublishMsg(msg); err != nil {
+        span.RecordError(err)
+        span.SetStatus(codes.Error, err.Error())
+        return err
+    }
+    return nil
+}
+
+// unfurlSubject is the request subject workers answer unfurl requests on.
+const unfurlSubject = messages.SubjectLinksUnfurl
+
+// UnfurlReply is a worker's summary of a page it fetched and parsed without
+// saving. Error is set when the page could not be fetched or parsed.
+type UnfurlReply = messages.UnfurlReply
+
+// ErrUnfurlUnavailable means no worker was listening for unfurl requests.
+var ErrUnfurlUnavailable = errors.New("no worker is available to unfurl")
+
+// UnfurlError is a worker's report that it could not fetch or parse a page.
+type UnfurlError struct {
+    Message string
+}
+
+func (e *UnfurlError) Error() string {
+    return "unfurl failed: " + e.Message
+}
+
+// Unfurl asks a worker to fetch and parse target and waits for the reply
+// until ctx expires. It returns ErrUnfurlUnavailable when no worker is
+// listening and an *UnfurlError when the worker could not read the page.
+func (n *NATS) Unfurl(ctx context.Context, target string) (UnfurlReply, error) {
+    var reply UnfurlReply
+    if err := n.request(ctx, unfurlSubject, messages.UnfurlRequest{URL: target}, &reply); err != nil {
+        if errors.Is(err, nats.ErrNoResponders) {
+            return UnfurlReply{}, ErrUnfurlUnavailable
+        }
+        return UnfurlReply{}, err
+    }
+    if reply.Error != "" {
+        return UnfurlReply{}, &UnfurlError{Message: reply.Error}
+    }
+    return reply, nil
+}
+
+// expandSubject is the request subject workers answer expand requests on.
+const expandSubject = messages.SubjectLinksExpand
+
+// ExpandReply is a worker's list of the article links on an index page.
+// Error is set when the page could not be fetched or parsed.
+type ExpandReply = messages.ExpandReply
+
+// ExpandedLink is one article link in an ExpandReply.
+type ExpandedLink = messages.ExpandedLink
+
+// ErrExpandUnavailable means no worker was listening for expand requests.
+var ErrExpandUnavailable = errors.New("no worker is available to expand")
+
+// ExpandError is a worker's report that it could not fetch or parse a page.
+type ExpandError struct {
+    Message string
+}
+
+func (e *ExpandError) Error() string {
+    return "expand failed: " + e.Message
+}
+
+// Expand asks a worker to list the articles target links to and waits for
+// the reply until ctx expires. It returns ErrExpandUnavailable when no
+// worker is listening and an *ExpandError when the worker could not read
+// the page.
+func (n *NATS) Expand(ctx context.Context, target string) (ExpandReply, error) {
+    var reply ExpandReply
+    if err := n.request(ctx, expandSubject, messages.ExpandRequest{URL: target}, &reply); err != nil {
+        if errors.Is(err, nats.ErrNoResponders) {
+            return ExpandReply{}, ErrExpandUnavailable
+        }
+        return ExpandReply{}, err
+    }
+    if reply.Error != "" {
+        return ExpandReply{}, &ExpandError{Message: reply.Error}
+    }
+    return reply, nil
+}
+
+// request sends payload to subject as JSON and decodes the worker's reply
+// into reply. The span records transport failures and errors the worker
+// reports in the reply's "error" field.
+func (n *NATS) request(ctx context.Context, subject string, payload, reply any) error {
+    data, err := json.Marshal(payload)
+    if err != nil {
+        return fmt.Errorf("marshal %s request: %w", subject, err)
+    }
+
+    ctx, span := otel.Tracer(tracerName).Start(ctx, subject+" request",
+        trace.WithSpanKind(trace.SpanKindClient),
+        trace.WithAttributes(
+            attribute.String("messaging.system", "nats"),
+            attribute.String("messaging.destination.name", subject),
+        ),
+    )
+    defer span.End()
+
+    msg := &nats.Msg{Subject: subject, Data: data, Header: nats.Header{}}
+    otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(http.Header(msg.Header)))
+
+    resp, err := n.conn.RequestMsgWithContext(ctx, msg)
+    if err != nil {
+        span.RecordError(err)
+        span.SetStatus(codes.Error, err.Error())
+        return err
+    }
+    if err := json.Unmarshal(resp.Data, reply); err != nil {
+        return fmt.Errorf("decode %s reply: %w", subject, err)
+    }
+    var failure struct {
+        Error string `json:"error"`
+    }
+    if json.Unmarshal(resp.Data, &failure) == nil && failure.Error != "" {
+        span.SetStatus(codes.Error, failure.Error)
+    }
+    return nil
+}
+
+// SubscribeRecommendationsRefresh delivers refresh requests to handler. API
+// replicas share a queue group so each request is processed once.
+func (n *NATS) SubscribeRecommendationsRefresh(handler func(context.Context, uuid.UUID) error) (*nats.Subscription, error) {
+    sub, err := n.conn.QueueSubscribe(RecommendationsRefreshSubject, recommendationsRefreshGroup, func(msg *nats.Msg) {
+        var payload messages.RecommendationsRefresh
+        if err := json.Unmarshal(msg.Data, &payload); err != nil {
+            log.Printf("recommendations refresh: decode payload: %v", err)
+            return
+        }
+        userID, err := uuid.Parse(payload.UserID)
+        if err != nil {
+            log.Printf("recommendations refresh: invalid user id %q: %v", payload.UserID, err)
+            return
+        }
+        ctx := otel.GetTextMapPropagator().Extract(context.Background(), propagation.HeaderCarrier(http.Header(msg.Header)))
+        ctx, span := otel.Tracer(tracerName).Start(ctx, RecommendationsRefreshSubject+" process",
+            trace.WithSpanKind(trace.SpanKindConsumer),
+            trace.WithAttributes(
+                attribute.String("messaging.system", "nats"),
+                attribute.String("messaging.destination.name", RecommendationsRefreshSubject),
+                attribute.String("keepstack.user_id", userID.String()),
+            ),
+        )
+        defer span.End()
+
+        if err := handler(ctx, userID); err != nil {
+            span.RecordError(err)
+            span.SetStatus(codes.Error, err.Error())
+            log.Printf("recommendations refresh: user %s: %v", userID, err)
+        }
+    })
+    if err != nil {
+        return nil, fmt.Errorf("subscribe recommendations refresh: %w", err)
+    }
+    return sub, nil
+}
+
+// SubscribeEvents delivers every live update event to handler. There is no
+// queue group: each API replica needs every event for the clients connected
+// to it.
+func (n *NATS) SubscribeEvents(handler func(Event)) (*nats.Subscription, error) {
+    sub, err := n.conn.Subscribe(EventsSubjectPrefix+">", func(msg *nats.Msg) {
+        var event Event
+        if err := json.Unmarshal(msg.Data, &event); err != nil {
+            log.Printf("events: decode %s payload: %v", msg.Subject, err)
+            return
+        }
+        handler(event)
+    })
+    if err != nil {
+        return nil, fmt.Errorf("subscribe events: %w", err)
+    }
+    return sub, nil
+}
+
+// SubscribeNotifications delivers every event to handler for recording as
+// in-app notifications. Unlike SubscribeEvents, API replicas share a queue
+// group so each event is recorded once.
+func (n *NATS) SubscribeNotifications(handler func(context.Context, Event) error) (*nats.Subscription, error) {
+    sub, err := n.conn.QueueSubscribe(EventsSubjectPrefix+">", notificationsGroup, func(msg *nats.Msg) {
+        var event Event
+        if err := json.Unmarshal(msg.Data, &event); err != nil {
+            log.Printf("notifications: decode %s payload: %v", msg.Subject, err)
+            return
+        }
+        ctx := otel.GetTextMapPropagator().Extract(context.Background(), propagation.HeaderCarrier(http.Header(msg.Header)))
+        ctx, span := otel.Tracer(tracerName).Start(ctx, msg.Subject+" notify",
+            trace.WithSpanKind(trace.SpanKindConsumer),
+            trace.WithAttributes(
+                attribute.String("messaging.system", "nats"),
+                attribute.String("messaging.destination.name", msg.Subject),
+            ),
+        )
+        defer span.End()
+
+        ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+        defer cancel()
+        if err := handler(ctx, event); err != nil {
+            span.RecordError(err)
+            span.SetStatus(codes.Error, err.Error())
+            log.Printf("notifications: record %s event: %v", event.Type, err)
+        }
+    })
+    if err != nil {
+        return nil, fmt.Errorf("subscribe notifications: %w", err)
+    }
+    return sub, nil
+}
+
+// Drain unsubscribes, lets in-flight message handlers and buffered publishes
+// finish, and then closes the connection. The connection is closed outright
+// when ctx expires first.
+func (n *NATS) Drain(ctx context.Context) error {
+    if n.conn == nil || n.conn.IsClosed() {
+        return nil
+    }
+    if err := n.conn.Drain(); err != nil {
+        n.conn.Close()
+        return fmt.Errorf("drain nats: %w", err)
+    }
+
+    ticker := time.NewTicker(50 * time.Millisecond)
+    defer ticker.Stop()
+    for !n.conn.IsClosed() {
+        select {
+        case <-ctx.Done():
+            n.conn.Close()
+            return fmt.Errorf("drain nats: %w", ctx.Err())
+        case <-ticker.C:
+        }
+    }
+    return nil
 }
 
 // Close shuts down the underlying NATS connection.
@@ -0,0 +1,136 @@
+// Package replica routes read-only queries to a Postgres read replica and
+// falls back to the primary when the replica cannot be reached.
+package replica
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+
+	"github.com/example/keepstack/apps/api/internal/db"
+)
+
+// DefaultCooldown is how long reads skip the replica after it failed.
+const DefaultCooldown = 30 * time.Second
+
+// Router satisfies db.DBTX. Query and QueryRow go to the replica unless it
+// recently failed; Exec always goes to the primary. Only hand it to code paths
+// that tolerate replication lag.
+type Router struct {
+	primary  db.DBTX
+	replica  db.DBTX
+	cooldown time.Duration
+	now      func() time.Time
+	logger   *log.Logger
+
+	// downUntil holds the UnixNano time until which the replica is skipped.
+	downUntil atomic.Int64
+}
+
+// New builds a Router over the given primary and replica connections.
+func New(primary, replica db.DBTX, logger *log.Logger) *Router {
+	return &Router{
+		primary:  primary,
+		replica:  replica,
+		cooldown: DefaultCooldown,
+		now:      time.Now,
+		logger:   logger,
+	}
+}
+
+// WithCooldown overrides how long the replica is skipped after a failure.
+func (r *Router) WithCooldown(cooldown time.Duration) {
+	r.cooldown = cooldown
+}
+
+// WithNow overrides the clock, for tests.
+func (r *Router) WithNow(now func() time.Time) {
+	r.now = now
+}
+
+// Exec runs statements on the primary.
+func (r *Router) Exec(ctx context.Context, sql string, args ...interface{}) (pgconn.CommandTag, error) {
+	return r.primary.Exec(ctx, sql, args...)
+}
+
+// Query runs on the replica, retrying on the primary when the replica is
+// unavailable.
+func (r *Router) Query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error) {
+	if !r.replicaUp() {
+		return r.primary.Query(ctx, sql, args...)
+	}
+	rows, err := r.replica.Query(ctx, sql, args...)
+	if err != nil && Unavailable(err) {
+		r.markDown(err)
+		return r.primary.Query(ctx, sql, args...)
+	}
+	return rows, err
+}
+
+// QueryRow runs on the replica. Errors surface on Scan, so the fallback to
+// the primary happens there.
+func (r *Router) QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row {
+	if !r.replicaUp() {
+		return r.primary.QueryRow(ctx, sql, args...)
+	}
+	return &fallbackRow{
+		row: r.replica.QueryRow(ctx, sql, args...),
+		retry: func() pgx.Row {
+			return r.primary.QueryRow(ctx, sql, args...)
+		},
+		router: r,
+	}
+}
+
+func (r *Router) replicaUp() bool {
+	return r.now().UnixNano() >= r.downUntil.Load()
+}
+
+func (r *Router) markDown(err error) {
+	until := r.now().Add(r.cooldown)
+	previous := r.downUntil.Swap(until.UnixNano())
+	if r.logger != nil && r.now().UnixNano() >= previous {
+		r.logger.Printf("read replica unavailable, using primary for %s: %v", r.cooldown, err)
+	}
+}
+
+type fallbackRow struct {
+	row    pgx.Row
+	retry  func() pgx.Row
+	router *Router
+}
+
+func (f *fallbackRow) Scan(dest ...any) error {
+	err := f.row.Scan(dest...)
+	if err != nil && Unavailable(err) {
+		f.router.markDown(err)
+		return f.retry().Scan(dest...)
+	}
+	return err
+}
+
+// Unavailable reports whether err means the server could not be reached or
+// is refusing work, as opposed to a problem with the query itself.
+func Unavailable(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return true
+	}
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		// Class 08 is connection exceptions, class 57P covers shutdown and
+		// "cannot connect now" while a standby is starting.
+		return strings.HasPrefix(pgErr.Code, "08") || strings.HasPrefix(pgErr.Code, "57P")
+	}
+	return pgconn.SafeToRetry(err)
+}
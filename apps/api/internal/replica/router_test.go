@@ -0,0 +1,156 @@
+package replica
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+type fakeDB struct {
+	name    string
+	err     error
+	queries int
+	execs   int
+}
+
+func (f *fakeDB) Exec(context.Context, string, ...interface{}) (pgconn.CommandTag, error) {
+	f.execs++
+	return pgconn.NewCommandTag("UPDATE 1"), f.err
+}
+
+func (f *fakeDB) Query(context.Context, string, ...interface{}) (pgx.Rows, error) {
+	f.queries++
+	if f.err != nil {
+		return nil, f.err
+	}
+	return nil, nil
+}
+
+func (f *fakeDB) QueryRow(context.Context, string, ...interface{}) pgx.Row {
+	f.queries++
+	return fakeRow{name: f.name, err: f.err}
+}
+
+type fakeRow struct {
+	name string
+	err  error
+}
+
+func (r fakeRow) Scan(dest ...any) error {
+	if r.err != nil {
+		return r.err
+	}
+	*(dest[0].(*string)) = r.name
+	return nil
+}
+
+var errReplicaDown = fmt.Errorf("dial: %w", &pgconn.PgError{Code: "57P03", Message: "the database system is starting up"})
+
+func TestRouterSendsReadsToReplicaAndWritesToPrimary(t *testing.T) {
+	t.Parallel()
+
+	primary := &fakeDB{name: "primary"}
+	replicaDB := &fakeDB{name: "replica"}
+	router := New(primary, replicaDB, nil)
+
+	var got string
+	if err := router.QueryRow(context.Background(), "SELECT 1").Scan(&got); err != nil {
+		t.Fatalf("scan: %v", err)
+	}
+	if got != "replica" {
+		t.Fatalf("expected replica row, got %q", got)
+	}
+	if _, err := router.Query(context.Background(), "SELECT 1"); err != nil {
+		t.Fatalf("query: %v", err)
+	}
+	if _, err := router.Exec(context.Background(), "UPDATE links SET title = ''"); err != nil {
+		t.Fatalf("exec: %v", err)
+	}
+
+	if replicaDB.queries != 2 || primary.queries != 0 {
+		t.Fatalf("expected reads on replica, got replica=%d primary=%d", replicaDB.queries, primary.queries)
+	}
+	if primary.execs != 1 || replicaDB.execs != 0 {
+		t.Fatalf("expected exec on primary, got primary=%d replica=%d", primary.execs, replicaDB.execs)
+	}
+}
+
+func TestRouterFallsBackWhenReplicaUnavailable(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	primary := &fakeDB{name: "primary"}
+	replicaDB := &fakeDB{name: "replica", err: errReplicaDown}
+	router := New(primary, replicaDB, nil)
+	router.WithNow(func() time.Time { return now })
+
+	var got string
+	if err := router.QueryRow(context.Background(), "SELECT 1").Scan(&got); err != nil {
+		t.Fatalf("scan: %v", err)
+	}
+	if got != "primary" {
+		t.Fatalf("expected fallback to primary, got %q", got)
+	}
+
+	// The replica is skipped during the cool-down.
+	if _, err := router.Query(context.Background(), "SELECT 1"); err != nil {
+		t.Fatalf("query: %v", err)
+	}
+	if replicaDB.queries != 1 || primary.queries != 2 {
+		t.Fatalf("expected replica to be skipped, got replica=%d primary=%d", replicaDB.queries, primary.queries)
+	}
+
+	now = now.Add(DefaultCooldown)
+	replicaDB.err = nil
+	if err := router.QueryRow(context.Background(), "SELECT 1").Scan(&got); err != nil {
+		t.Fatalf("scan: %v", err)
+	}
+	if got != "replica" {
+		t.Fatalf("expected replica after cool-down, got %q", got)
+	}
+}
+
+func TestRouterKeepsQueryErrors(t *testing.T) {
+	t.Parallel()
+
+	primary := &fakeDB{name: "primary"}
+	replicaDB := &fakeDB{name: "replica", err: &pgconn.PgError{Code: "42P01", Message: "relation does not exist"}}
+	router := New(primary, replicaDB, nil)
+
+	var got string
+	err := router.QueryRow(context.Background(), "SELECT 1").Scan(&got)
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) || pgErr.Code != "42P01" {
+		t.Fatalf("expected replica query error, got %v", err)
+	}
+	if primary.queries != 0 {
+		t.Fatalf("expected no fallback for query errors, got %d primary queries", primary.queries)
+	}
+}
+
+func TestUnavailable(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		err  error
+		want bool
+	}{
+		{&pgconn.PgError{Code: "08006"}, true},
+		{&pgconn.PgError{Code: "57P01"}, true},
+		{&pgconn.PgError{Code: "23505"}, false},
+		{fmt.Errorf("failed to connect: %w", &net.OpError{Op: "dial", Err: errors.New("connection refused")}), true},
+		{context.Canceled, false},
+		{pgx.ErrNoRows, false},
+	}
+	for _, tc := range cases {
+		if got := Unavailable(tc.err); got != tc.want {
+			t.Fatalf("Unavailable(%v) = %v, want %v", tc.err, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,136 @@
+// Package resanitize applies the configured sanitization policy to archive
+// HTML stored under an earlier one. The worker sanitizes archives once, at
+// ingest, so tightening SANITIZE_FEATURES or adding an image proxy leaves
+// older archives as they were until Service rewrites them in batches.
+// Loosening the policy cannot bring back what was already stripped; those
+// links have to be ingested again.
+package resanitize
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/microcosm-cc/bluemonday"
+
+	"github.com/example/keepstack/apps/api/internal/db"
+)
+
+// DefaultBatchSize is the number of archives read per statement. Each row
+// carries its full HTML, so batches stay small.
+const DefaultBatchSize = 200
+
+type queries interface {
+	CountArchivesWithHTML(context.Context) (int64, error)
+	ListArchiveHTMLBatch(context.Context, db.ListArchiveHTMLBatchParams) ([]db.ListArchiveHTMLBatchRow, error)
+	UpdateArchiveHTML(context.Context, db.UpdateArchiveHTMLParams) error
+}
+
+// Options controls a run.
+type Options struct {
+	// BatchSize caps how many archives are read per statement.
+	BatchSize int
+	// DryRun counts the archives that would change without writing.
+	DryRun bool
+	// Progress, when set, is called after each batch.
+	Progress func(Progress)
+}
+
+// Progress reports how far a run has got. Total is the number of archives
+// with HTML when the run started.
+type Progress struct {
+	Checked int64
+	Total   int64
+	Updated int64
+}
+
+// Stats summarises a run. In a dry run Updated and Bytes count what would
+// have been written.
+type Stats struct {
+	Checked int64
+	Updated int64
+	// Bytes is how much smaller the stored HTML got; negative when proxied
+	// image URLs made it grow.
+	Bytes    int64
+	Duration time.Duration
+}
+
+// Service re-sanitizes archive HTML against Postgres.
+type Service struct {
+	queries queries
+	policy  *bluemonday.Policy
+	now     func() time.Time
+}
+
+// New constructs a Service that sanitizes with policy using the provided
+// connection pool.
+func New(pool *pgxpool.Pool, policy *bluemonday.Policy) *Service {
+	return &Service{queries: db.New(pool), policy: policy, now: time.Now}
+}
+
+// WithNow overrides the time source. Intended for tests.
+func (s *Service) WithNow(now func() time.Time) {
+	s.now = now
+}
+
+// Run walks the archives that have HTML, by link id, sanitizes each with
+// the policy, and writes back the ones that changed. Archives already
+// sanitized with the same policy are left alone, so a second run writes
+// nothing.
+func (s *Service) Run(ctx context.Context, opts Options) (Stats, error) {
+	start := s.now()
+	var stats Stats
+
+	if opts.BatchSize < 1 {
+		opts.BatchSize = DefaultBatchSize
+	}
+
+	total, err := s.queries.CountArchivesWithHTML(ctx)
+	if err != nil {
+		stats.Duration = s.now().Sub(start)
+		return stats, fmt.Errorf("count archives: %w", err)
+	}
+
+	var after pgtype.UUID
+	for {
+		rows, err := s.queries.ListArchiveHTMLBatch(ctx, db.ListArchiveHTMLBatchParams{AfterID: after, BatchSize: int32(opts.BatchSize)})
+		if err != nil {
+			stats.Duration = s.now().Sub(start)
+			return stats, fmt.Errorf("list archives: %w", err)
+		}
+
+		for _, row := range rows {
+			stats.Checked++
+			cleaned := strings.TrimSpace(s.policy.Sanitize(row.Html))
+			if cleaned == row.Html {
+				continue
+			}
+			if !opts.DryRun {
+				if err := s.queries.UpdateArchiveHTML(ctx, db.UpdateArchiveHTMLParams{
+					Html:   pgtype.Text{String: cleaned, Valid: cleaned != ""},
+					LinkID: row.LinkID,
+				}); err != nil {
+					stats.Duration = s.now().Sub(start)
+					return stats, fmt.Errorf("update archive %s: %w", uuid.UUID(row.LinkID.Bytes), err)
+				}
+			}
+			stats.Updated++
+			stats.Bytes += int64(len(row.Html)) - int64(len(cleaned))
+		}
+
+		if opts.Progress != nil && len(rows) > 0 {
+			opts.Progress(Progress{Checked: stats.Checked, Total: total, Updated: stats.Updated})
+		}
+		if len(rows) < opts.BatchSize {
+			break
+		}
+		after = rows[len(rows)-1].LinkID
+	}
+
+	stats.Duration = s.now().Sub(start)
+	return stats, nil
+}
@@ -0,0 +1,111 @@
+package resanitize
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"github.com/example/keepstack/apps/api/internal/db"
+	"github.com/example/keepstack/sanitize"
+)
+
+type fakeQueries struct {
+	batches [][]db.ListArchiveHTMLBatchRow
+	total   int64
+
+	afters  []pgtype.UUID
+	updates []db.UpdateArchiveHTMLParams
+}
+
+func (f *fakeQueries) CountArchivesWithHTML(context.Context) (int64, error) {
+	return f.total, nil
+}
+
+func (f *fakeQueries) ListArchiveHTMLBatch(_ context.Context, arg db.ListArchiveHTMLBatchParams) ([]db.ListArchiveHTMLBatchRow, error) {
+	f.afters = append(f.afters, arg.AfterID)
+	if len(f.batches) == 0 {
+		return nil, nil
+	}
+	rows := f.batches[0]
+	f.batches = f.batches[1:]
+	return rows, nil
+}
+
+func (f *fakeQueries) UpdateArchiveHTML(_ context.Context, arg db.UpdateArchiveHTMLParams) error {
+	f.updates = append(f.updates, arg)
+	return nil
+}
+
+func row(html string) db.ListArchiveHTMLBatchRow {
+	return db.ListArchiveHTMLBatchRow{
+		LinkID: pgtype.UUID{Bytes: uuid.New(), Valid: true},
+		Html:   html,
+	}
+}
+
+// newTestService sanitizes to text, links, and tables only.
+func newTestService(q *fakeQueries) *Service {
+	now := time.Date(2024, time.June, 1, 0, 0, 0, 0, time.UTC)
+	policy := sanitize.Options{Features: []string{sanitize.FeatureTables}}.Policy()
+	return &Service{queries: q, policy: policy, now: func() time.Time { return now }}
+}
+
+func TestRunRewritesChangedArchives(t *testing.T) {
+	first := []db.ListArchiveHTMLBatchRow{
+		row(`<p>Hello <img src="https://example.com/a.png"></p>`),
+		row(`<table><tbody><tr><td>kept</td></tr></tbody></table>`),
+	}
+	q := &fakeQueries{
+		total:   3,
+		batches: [][]db.ListArchiveHTMLBatchRow{first, {row(`<img src="https://example.com/b.png">`)}},
+	}
+
+	var progress []Progress
+	stats, err := newTestService(q).Run(context.Background(), Options{
+		BatchSize: 2,
+		Progress:  func(p Progress) { progress = append(progress, p) },
+	})
+	if err != nil {
+		t.Fatalf("Run returned error: %v", err)
+	}
+	if stats.Checked != 3 || stats.Updated != 2 || stats.Bytes <= 0 {
+		t.Fatalf("unexpected stats: %+v", stats)
+	}
+	if len(q.afters) != 2 || q.afters[0].Valid || q.afters[1] != first[1].LinkID {
+		t.Fatalf("expected the second batch to continue after the first, got %v", q.afters)
+	}
+
+	if update := q.updates[0]; update.Html.String != "<p>Hello </p>" || !update.Html.Valid || update.LinkID != first[0].LinkID {
+		t.Fatalf("unexpected first update %+v", update)
+	}
+	// Nothing is left of an image-only archive, so its HTML is cleared and
+	// the reader falls back to the extracted text.
+	if second := q.updates[1]; second.Html.Valid {
+		t.Fatalf("expected the emptied archive to be cleared, got %+v", second)
+	}
+	want := []Progress{{Checked: 2, Total: 3, Updated: 1}, {Checked: 3, Total: 3, Updated: 2}}
+	if len(progress) != len(want) || progress[0] != want[0] || progress[1] != want[1] {
+		t.Fatalf("expected progress %+v, got %+v", want, progress)
+	}
+}
+
+func TestRunDryRunWritesNothing(t *testing.T) {
+	q := &fakeQueries{
+		total:   1,
+		batches: [][]db.ListArchiveHTMLBatchRow{{row(`<p>Hi</p><script>alert(1)</script>`)}},
+	}
+
+	stats, err := newTestService(q).Run(context.Background(), Options{DryRun: true})
+	if err != nil {
+		t.Fatalf("Run returned error: %v", err)
+	}
+	if stats.Checked != 1 || stats.Updated != 1 {
+		t.Fatalf("expected 1 archive reported, got %+v", stats)
+	}
+	if len(q.updates) != 0 {
+		t.Fatalf("dry run wrote %d updates", len(q.updates))
+	}
+}
@@ -0,0 +1,45 @@
+//go:build integration
+
+package resurfacer
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/example/keepstack/apps/api/internal/benchdata"
+)
+
+// BenchmarkRebuild measures a nightly rebuild over every generated user and
+// a single user's rebuild after a save, at the default and a small batch
+// size.
+func BenchmarkRebuild(b *testing.B) {
+	pool, dataset := benchdata.Postgres(b)
+	const limit = 20
+
+	for _, batchSize := range []int{DefaultBatchSize, 100} {
+		service := New(pool)
+		service.WithNow(func() time.Time { return benchdata.Epoch })
+		service.WithBatchSize(batchSize)
+
+		b.Run(fmt.Sprintf("all-users/batch-%d", batchSize), func(b *testing.B) {
+			for b.Loop() {
+				stats, err := service.Rebuild(context.Background(), limit)
+				if err != nil {
+					b.Fatal(err)
+				}
+				if stats.Users != len(dataset.Users) {
+					b.Fatalf("expected %d users rebuilt, got %d", len(dataset.Users), stats.Users)
+				}
+			}
+		})
+		b.Run(fmt.Sprintf("one-user/batch-%d", batchSize), func(b *testing.B) {
+			for b.Loop() {
+				if _, err := service.RebuildUser(context.Background(), dataset.Users[0].ID, limit); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -1,6 +1,7 @@
 package resurfacer
 
 import (
+	"container/heap"
 	"context"
 	"errors"
 	"fmt"
@@ -15,19 +16,28 @@ import (
 	"github.com/example/keepstack/apps/api/internal/db"
 )
 
+// DefaultBatchSize is the number of unread links fetched per round trip when
+// scanning a user's queue.
+const DefaultBatchSize = 500
+
 // Service recalculates resurfacing recommendations for unread links.
 type Service struct {
-	pool    *pgxpool.Pool
-	queries *db.Queries
-	now     func() time.Time
+	pool      *pgxpool.Pool
+	queries   *db.Queries
+	now       func() time.Time
+	batchSize int
+	weights   Weights
+	cooldown  time.Duration
 }
 
 // New constructs a Service using the provided connection pool.
 func New(pool *pgxpool.Pool) *Service {
 	return &Service{
-		pool:    pool,
-		queries: db.New(pool),
-		now:     time.Now,
+		pool:      pool,
+		queries:   db.New(pool),
+		now:       time.Now,
+		batchSize: DefaultBatchSize,
+		weights:   DefaultWeights(),
 	}
 }
 
@@ -36,14 +46,50 @@ func (s *Service) WithNow(now func() time.Time) {
 	s.now = now
 }
 
+// WithBatchSize overrides how many unread links are loaded per query. Values
+// less than one fall back to DefaultBatchSize.
+func (s *Service) WithBatchSize(size int) {
+	if size < 1 {
+		size = DefaultBatchSize
+	}
+	s.batchSize = size
+}
+
+// WithWeights overrides the default scoring weights. Per-user overrides stored
+// in resurfacer_weights are still applied on top.
+func (s *Service) WithWeights(weights Weights) {
+	weights.WordCountTiers = append([]WordCountTier(nil), weights.WordCountTiers...)
+	sortTiers(weights.WordCountTiers)
+	s.weights = weights
+}
+
+// WithCooldown excludes links that were surfaced in a digest or recommendation
+// set within the given window. Zero disables the cool-down.
+func (s *Service) WithCooldown(cooldown time.Duration) {
+	if cooldown < 0 {
+		cooldown = 0
+	}
+	s.cooldown = cooldown
+}
+
+// RebuildStats summarises a Rebuild run.
+type RebuildStats struct {
+	Users           int
+	Recommendations int
+	Duration        time.Duration
+}
+
 // Rebuild recalculates the recommendation set for all users with unread links.
-func (s *Service) Rebuild(ctx context.Context, limit int) (int, error) {
+func (s *Service) Rebuild(ctx context.Context, limit int) (RebuildStats, error) {
+	start := time.Now()
+	var stats RebuildStats
+
 	userIDs, err := s.queries.ListUsersWithUnread(ctx)
 	if err != nil {
-		return 0, fmt.Errorf("list users: %w", err)
+		stats.Duration = time.Since(start)
+		return stats, fmt.Errorf("list users: %w", err)
 	}
 
-	total := 0
 	for _, rawUserID := range userIDs {
 		if !rawUserID.Valid {
 			continue
@@ -51,49 +97,78 @@ func (s *Service) Rebuild(ctx context.Context, limit int) (int, error) {
 		userID := uuid.UUID(rawUserID.Bytes)
 		count, err := s.rebuildForUser(ctx, userID, limit)
 		if err != nil {
-			return total, fmt.Errorf("rebuild user %s: %w", userID, err)
+			stats.Duration = time.Since(start)
+			return stats, fmt.Errorf("rebuild user %s: %w", userID, err)
 		}
-		total += count
+		stats.Users++
+		stats.Recommendations += count
 	}
 
-	return total, nil
+	stats.Duration = time.Since(start)
+	return stats, nil
+}
+
+// RebuildUser recalculates the recommendation set for a single user and
+// returns the number of recommendations written.
+func (s *Service) RebuildUser(ctx context.Context, userID uuid.UUID, limit int) (int, error) {
+	count, err := s.rebuildForUser(ctx, userID, limit)
+	if err != nil {
+		return 0, fmt.Errorf("rebuild user %s: %w", userID, err)
+	}
+	return count, nil
 }
 
 func (s *Service) rebuildForUser(ctx context.Context, userID uuid.UUID, limit int) (int, error) {
-	rows, err := s.queries.ListUnreadLinksForUser(ctx, uuidToPg(userID))
+	now := s.now().UTC()
+	top := newTopCandidates(limit)
+
+	weights, err := s.weightsForUser(ctx, userID)
 	if err != nil {
-		if errors.Is(err, context.Canceled) {
-			return 0, err
+		return 0, err
+	}
+
+	params := db.ListUnreadLinkBatchForUserParams{
+		UserID:    uuidToPg(userID),
+		Now:       pgtype.Timestamptz{Time: now, Valid: true},
+		BatchSize: int32(s.batchSize),
+	}
+	if s.cooldown > 0 {
+		params.SurfacedBefore = pgtype.Timestamptz{Time: now.Add(-s.cooldown), Valid: true}
+	}
+	for {
+		rows, err := s.queries.ListUnreadLinkBatchForUser(ctx, params)
+		if err != nil {
+			if errors.Is(err, context.Canceled) {
+				return 0, err
+			}
+			return 0, fmt.Errorf("list unread links: %w", err)
+		}
+
+		for _, row := range rows {
+			createdAt := row.CreatedAt.Time
+			top.offer(candidate{
+				linkID:    uuid.UUID(row.ID.Bytes),
+				score:     scoreLink(weights, now, createdAt, row.Favorite, int(row.WordCount)),
+				createdAt: createdAt,
+			})
+		}
+
+		if len(rows) < s.batchSize {
+			break
 		}
-		return 0, fmt.Errorf("list unread links: %w", err)
+		last := rows[len(rows)-1]
+		params.AfterCreatedAt = last.CreatedAt
+		params.AfterID = last.ID
 	}
 
-	if len(rows) == 0 {
+	if top.Len() == 0 {
 		if err := s.clearExisting(ctx, userID); err != nil {
 			return 0, err
 		}
 		return 0, nil
 	}
 
-	now := s.now().UTC()
-	candidates := make([]candidate, 0, len(rows))
-	for _, row := range rows {
-		linkID := uuid.UUID(row.ID.Bytes)
-		createdAt := row.CreatedAt.Time
-		score := scoreLink(now, createdAt, row.Favorite, int(row.WordCount))
-		candidates = append(candidates, candidate{linkID: linkID, score: score, createdAt: createdAt})
-	}
-
-	sort.SliceStable(candidates, func(i, j int) bool {
-		if candidates[i].score == candidates[j].score {
-			return candidates[i].createdAt.Before(candidates[j].createdAt)
-		}
-		return candidates[i].score > candidates[j].score
-	})
-
-	if limit > 0 && len(candidates) > limit {
-		candidates = candidates[:limit]
-	}
+	candidates := top.sorted()
 
 	tx, err := s.pool.BeginTx(ctx, pgx.TxOptions{})
 	if err != nil {
@@ -107,14 +182,25 @@ func (s *Service) rebuildForUser(ctx context.Context, userID uuid.UUID, limit in
 	}
 
 	updatedAt := pgtype.Timestamptz{Time: now, Valid: true}
+	strategy := weights.Strategy()
+	surfaced := make([]pgtype.UUID, 0, len(candidates))
 	for _, candidate := range candidates {
 		if err := qtx.UpsertRecommendation(ctx, db.UpsertRecommendationParams{
 			LinkID:    uuidToPg(candidate.linkID),
 			Score:     int32(candidate.score),
+			Strategy:  strategy,
 			UpdatedAt: updatedAt,
 		}); err != nil {
 			return 0, fmt.Errorf("upsert recommendation: %w", err)
 		}
+		surfaced = append(surfaced, uuidToPg(candidate.linkID))
+	}
+
+	if err := qtx.MarkLinksSurfaced(ctx, db.MarkLinksSurfacedParams{
+		SurfacedAt: updatedAt,
+		Ids:        surfaced,
+	}); err != nil {
+		return 0, fmt.Errorf("mark links surfaced: %w", err)
 	}
 
 	if err := tx.Commit(ctx); err != nil {
@@ -124,6 +210,21 @@ func (s *Service) rebuildForUser(ctx context.Context, userID uuid.UUID, limit in
 	return len(candidates), nil
 }
 
+func (s *Service) weightsForUser(ctx context.Context, userID uuid.UUID) (Weights, error) {
+	row, err := s.queries.GetResurfacerWeightsForUser(ctx, uuidToPg(userID))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return s.weights, nil
+		}
+		return Weights{}, fmt.Errorf("load weights: %w", err)
+	}
+	weights, err := s.weights.WithOverrides(row)
+	if err != nil {
+		return Weights{}, fmt.Errorf("load weights: %w", err)
+	}
+	return weights, nil
+}
+
 func (s *Service) clearExisting(ctx context.Context, userID uuid.UUID) error {
 	return s.queries.ClearRecommendationsForUser(ctx, uuidToPg(userID))
 }
@@ -134,7 +235,63 @@ type candidate struct {
 	createdAt time.Time
 }
 
-func scoreLink(now, created time.Time, favorite bool, wordCount int) int {
+// ranksAbove reports whether c should be recommended ahead of other. Higher
+// scores win; ties favour the link that has waited longest.
+func (c candidate) ranksAbove(other candidate) bool {
+	if c.score == other.score {
+		return c.createdAt.Before(other.createdAt)
+	}
+	return c.score > other.score
+}
+
+// topCandidates keeps the best candidates seen so far in a min-heap bounded by
+// limit, so memory stays proportional to the recommendation count rather than
+// the size of the unread queue. A non-positive limit keeps every candidate.
+type topCandidates struct {
+	items []candidate
+	limit int
+}
+
+func newTopCandidates(limit int) *topCandidates {
+	return &topCandidates{limit: limit}
+}
+
+func (t *topCandidates) Len() int { return len(t.items) }
+
+func (t *topCandidates) Less(i, j int) bool { return t.items[j].ranksAbove(t.items[i]) }
+
+func (t *topCandidates) Swap(i, j int) { t.items[i], t.items[j] = t.items[j], t.items[i] }
+
+func (t *topCandidates) Push(x any) { t.items = append(t.items, x.(candidate)) }
+
+func (t *topCandidates) Pop() any {
+	last := t.items[len(t.items)-1]
+	t.items = t.items[:len(t.items)-1]
+	return last
+}
+
+func (t *topCandidates) offer(c candidate) {
+	if t.limit <= 0 || len(t.items) < t.limit {
+		heap.Push(t, c)
+		return
+	}
+	if c.ranksAbove(t.items[0]) {
+		t.items[0] = c
+		heap.Fix(t, 0)
+	}
+}
+
+// sorted returns the retained candidates ordered best first.
+func (t *topCandidates) sorted() []candidate {
+	out := make([]candidate, len(t.items))
+	copy(out, t.items)
+	sort.SliceStable(out, func(i, j int) bool {
+		return out[i].ranksAbove(out[j])
+	})
+	return out
+}
+
+func scoreLink(weights Weights, now, created time.Time, favorite bool, wordCount int) int {
 	if now.Before(created) {
 		now = created
 	}
@@ -142,22 +299,20 @@ func scoreLink(now, created time.Time, favorite bool, wordCount int) int {
 	if daysUnread < 0 {
 		daysUnread = 0
 	}
-	if daysUnread > 30 {
-		daysUnread = 30
+	if daysUnread > weights.AgeCapDays {
+		daysUnread = weights.AgeCapDays
 	}
 
 	score := daysUnread
 	if favorite {
-		score += 10
+		score += weights.FavoriteBonus
 	}
 
-	switch {
-	case wordCount >= 2500:
-		score += 3
-	case wordCount >= 1500:
-		score += 2
-	case wordCount >= 800:
-		score += 1
+	for _, tier := range weights.WordCountTiers {
+		if wordCount >= tier.MinWords {
+			score += tier.Bonus
+			break
+		}
 	}
 
 	return score
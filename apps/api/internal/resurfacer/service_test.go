@@ -0,0 +1,144 @@
+package resurfacer
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"github.com/example/keepstack/apps/api/internal/db"
+)
+
+func TestTopCandidatesKeepsHighestScores(t *testing.T) {
+	base := time.Date(2024, time.March, 1, 0, 0, 0, 0, time.UTC)
+
+	top := newTopCandidates(3)
+	scores := []int{4, 12, 1, 9, 12, 7, 3}
+	for i, score := range scores {
+		top.offer(candidate{
+			linkID:    uuid.New(),
+			score:     score,
+			createdAt: base.Add(time.Duration(i) * time.Hour),
+		})
+	}
+
+	got := top.sorted()
+	if len(got) != 3 {
+		t.Fatalf("expected 3 candidates, got %d", len(got))
+	}
+
+	wantScores := []int{12, 12, 9}
+	for i, want := range wantScores {
+		if got[i].score != want {
+			t.Fatalf("candidate %d: expected score %d, got %d", i, want, got[i].score)
+		}
+	}
+	if !got[0].createdAt.Before(got[1].createdAt) {
+		t.Fatalf("expected older link to win score tie")
+	}
+}
+
+func TestTopCandidatesUnboundedLimit(t *testing.T) {
+	top := newTopCandidates(0)
+	for i := 0; i < 5; i++ {
+		top.offer(candidate{linkID: uuid.New(), score: i})
+	}
+
+	got := top.sorted()
+	if len(got) != 5 {
+		t.Fatalf("expected all 5 candidates, got %d", len(got))
+	}
+	if got[0].score != 4 || got[4].score != 0 {
+		t.Fatalf("unexpected ordering: first=%d last=%d", got[0].score, got[4].score)
+	}
+}
+
+func TestScoreLink(t *testing.T) {
+	now := time.Date(2024, time.March, 31, 0, 0, 0, 0, time.UTC)
+
+	cases := []struct {
+		name      string
+		created   time.Time
+		favorite  bool
+		wordCount int
+		want      int
+	}{
+		{name: "fresh", created: now, want: 0},
+		{name: "age capped", created: now.AddDate(0, -6, 0), want: 30},
+		{name: "favorite long read", created: now.AddDate(0, 0, -5), favorite: true, wordCount: 3000, want: 18},
+		{name: "future timestamp", created: now.Add(48 * time.Hour), wordCount: 900, want: 1},
+	}
+
+	for _, tc := range cases {
+		if got := scoreLink(DefaultWeights(), now, tc.created, tc.favorite, tc.wordCount); got != tc.want {
+			t.Fatalf("%s: expected score %d, got %d", tc.name, tc.want, got)
+		}
+	}
+}
+
+func TestScoreLinkCustomWeights(t *testing.T) {
+	now := time.Date(2024, time.March, 31, 0, 0, 0, 0, time.UTC)
+	weights := Weights{
+		FavoriteBonus:  2,
+		AgeCapDays:     7,
+		WordCountTiers: []WordCountTier{{MinWords: 500, Bonus: 4}},
+	}
+
+	if got := scoreLink(weights, now, now.AddDate(0, 0, -20), true, 600); got != 13 {
+		t.Fatalf("expected score 13, got %d", got)
+	}
+}
+
+func TestParseWordCountTiers(t *testing.T) {
+	tiers, err := ParseWordCountTiers(" 800:1, 2500:3,1500:2 ")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []WordCountTier{{MinWords: 2500, Bonus: 3}, {MinWords: 1500, Bonus: 2}, {MinWords: 800, Bonus: 1}}
+	if len(tiers) != len(want) {
+		t.Fatalf("expected %d tiers, got %d", len(want), len(tiers))
+	}
+	for i := range want {
+		if tiers[i] != want[i] {
+			t.Fatalf("tier %d: expected %+v, got %+v", i, want[i], tiers[i])
+		}
+	}
+
+	if _, err := ParseWordCountTiers("800"); err == nil {
+		t.Fatal("expected error for tier without bonus")
+	}
+}
+
+func TestWeightsWithOverrides(t *testing.T) {
+	row := db.ResurfacerWeight{
+		FavoriteBonus:  pgtype.Int4{Int32: 25, Valid: true},
+		WordCountTiers: pgtype.Text{String: "100:5", Valid: true},
+	}
+
+	weights, err := DefaultWeights().WithOverrides(row)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if weights.FavoriteBonus != 25 {
+		t.Fatalf("expected favorite bonus 25, got %d", weights.FavoriteBonus)
+	}
+	if weights.AgeCapDays != DefaultWeights().AgeCapDays {
+		t.Fatalf("expected default age cap, got %d", weights.AgeCapDays)
+	}
+	if len(weights.WordCountTiers) != 1 || weights.WordCountTiers[0] != (WordCountTier{MinWords: 100, Bonus: 5}) {
+		t.Fatalf("unexpected tiers: %+v", weights.WordCountTiers)
+	}
+}
+
+func TestWeightsStrategy(t *testing.T) {
+	if got, want := DefaultWeights().Strategy(), "resurfacer:fav=10,age=30,words=2500:3+1500:2+800:1"; got != want {
+		t.Fatalf("expected %q, got %q", want, got)
+	}
+
+	custom := DefaultWeights()
+	custom.FavoriteBonus = 25
+	if custom.Strategy() == DefaultWeights().Strategy() {
+		t.Fatalf("expected different weights to name a different strategy")
+	}
+}
@@ -0,0 +1,144 @@
+package resurfacer
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/example/keepstack/apps/api/internal/db"
+)
+
+// WordCountTier awards Bonus points to links with at least MinWords words.
+type WordCountTier struct {
+	MinWords int
+	Bonus    int
+}
+
+// Weights tunes how unread links are scored.
+type Weights struct {
+	// FavoriteBonus is added to the score of favourited links.
+	FavoriteBonus int
+	// AgeCapDays caps how many days of unread age count towards the score.
+	AgeCapDays int
+	// WordCountTiers rewards longer reads. Only the highest matching tier applies.
+	WordCountTiers []WordCountTier
+}
+
+// DefaultWeights returns the built-in scoring weights.
+func DefaultWeights() Weights {
+	return Weights{
+		FavoriteBonus: 10,
+		AgeCapDays:    30,
+		WordCountTiers: []WordCountTier{
+			{MinWords: 2500, Bonus: 3},
+			{MinWords: 1500, Bonus: 2},
+			{MinWords: 800, Bonus: 1},
+		},
+	}
+}
+
+// LoadWeightsFromEnv applies RESURFACER_FAVORITE_BONUS, RESURFACER_AGE_CAP_DAYS,
+// and RESURFACER_WORD_COUNT_TIERS on top of DefaultWeights.
+func LoadWeightsFromEnv() (Weights, error) {
+	weights := DefaultWeights()
+
+	if raw := strings.TrimSpace(os.Getenv("RESURFACER_FAVORITE_BONUS")); raw != "" {
+		value, err := strconv.Atoi(raw)
+		if err != nil {
+			return Weights{}, fmt.Errorf("parse RESURFACER_FAVORITE_BONUS: %w", err)
+		}
+		weights.FavoriteBonus = value
+	}
+
+	if raw := strings.TrimSpace(os.Getenv("RESURFACER_AGE_CAP_DAYS")); raw != "" {
+		value, err := strconv.Atoi(raw)
+		if err != nil || value < 0 {
+			return Weights{}, fmt.Errorf("parse RESURFACER_AGE_CAP_DAYS: must be a non-negative integer")
+		}
+		weights.AgeCapDays = value
+	}
+
+	if raw := os.Getenv("RESURFACER_WORD_COUNT_TIERS"); strings.TrimSpace(raw) != "" {
+		tiers, err := ParseWordCountTiers(raw)
+		if err != nil {
+			return Weights{}, fmt.Errorf("parse RESURFACER_WORD_COUNT_TIERS: %w", err)
+		}
+		weights.WordCountTiers = tiers
+	}
+
+	return weights, nil
+}
+
+// ParseWordCountTiers parses a comma-separated list of words:bonus pairs such
+// as "800:1,1500:2,2500:3". Tiers are returned highest threshold first.
+func ParseWordCountTiers(raw string) ([]WordCountTier, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return nil, nil
+	}
+
+	parts := strings.Split(raw, ",")
+	tiers := make([]WordCountTier, 0, len(parts))
+	for _, part := range parts {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		words, bonus, ok := strings.Cut(part, ":")
+		if !ok {
+			return nil, fmt.Errorf("word count tier %q: expected words:bonus", part)
+		}
+		minWords, err := strconv.Atoi(strings.TrimSpace(words))
+		if err != nil || minWords < 0 {
+			return nil, fmt.Errorf("word count tier %q: invalid word threshold", part)
+		}
+		points, err := strconv.Atoi(strings.TrimSpace(bonus))
+		if err != nil {
+			return nil, fmt.Errorf("word count tier %q: invalid bonus", part)
+		}
+		tiers = append(tiers, WordCountTier{MinWords: minWords, Bonus: points})
+	}
+
+	sortTiers(tiers)
+	return tiers, nil
+}
+
+// WithOverrides layers the non-null columns of a per-user override row on top
+// of w.
+func (w Weights) WithOverrides(row db.ResurfacerWeight) (Weights, error) {
+	out := w
+	if row.FavoriteBonus.Valid {
+		out.FavoriteBonus = int(row.FavoriteBonus.Int32)
+	}
+	if row.AgeCapDays.Valid {
+		out.AgeCapDays = int(row.AgeCapDays.Int32)
+	}
+	if row.WordCountTiers.Valid {
+		tiers, err := ParseWordCountTiers(row.WordCountTiers.String)
+		if err != nil {
+			return w, err
+		}
+		out.WordCountTiers = tiers
+	}
+	return out, nil
+}
+
+// Strategy names the scoring w produces, such as
+// "resurfacer:fav=10,age=30,words=2500:3+1500:2+800:1". Recommendations and
+// their impressions carry it, so a weight change shows up as a new strategy in
+// the impression stats.
+func (w Weights) Strategy() string {
+	tiers := make([]string, 0, len(w.WordCountTiers))
+	for _, tier := range w.WordCountTiers {
+		tiers = append(tiers, fmt.Sprintf("%d:%d", tier.MinWords, tier.Bonus))
+	}
+	return fmt.Sprintf("resurfacer:fav=%d,age=%d,words=%s", w.FavoriteBonus, w.AgeCapDays, strings.Join(tiers, "+"))
+}
+
+func sortTiers(tiers []WordCountTier) {
+	sort.SliceStable(tiers, func(i, j int) bool {
+		return tiers[i].MinWords > tiers[j].MinWords
+	})
+}
@@ -0,0 +1,391 @@
+package schema
+
+import (
+	"fmt"
+	"io/fs"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Expected is the schema the migrations build, found by replaying the Up
+// section of each one in order.
+type Expected struct {
+	Tables   []*Table
+	Indexes  []Object
+	Triggers []Object
+}
+
+// Table is an expected table with its columns and named constraints.
+type Table struct {
+	Name        string
+	Columns     []Column
+	Constraints []Constraint
+}
+
+// Column is an expected column. Type is the information_schema data_type
+// and Definition the column's DDL as the migration wrote it.
+type Column struct {
+	Name       string
+	Type       string
+	Definition string
+}
+
+// Constraint is an expected constraint, named the way Postgres names it
+// when the migration did not. Column is set for constraints declared as
+// part of a column definition, which recreating the column restores.
+type Constraint struct {
+	Name       string
+	Column     string
+	Definition string
+}
+
+// Object is an expected index or trigger with the statement creating it.
+type Object struct {
+	Name      string
+	Table     string
+	Statement string
+}
+
+var (
+	tableElementKeyword = regexp.MustCompile(`(?i)^(constraint|primary key|unique|foreign key|check|exclude|like)\b`)
+	namedConstraint     = regexp.MustCompile(`(?i)^constraint ([\w"]+) (.*)$`)
+	columnList          = regexp.MustCompile(`^\(([^)]*)\)`)
+	referencesClause    = regexp.MustCompile(`(?i)\breferences [\w."]+ ?\([^)]*\)(?: on (?:delete|update) (?:cascade|restrict|set null|set default|no action))*`)
+	primaryKeyClause    = regexp.MustCompile(`(?i)\bprimary key\b`)
+	uniqueClause        = regexp.MustCompile(`(?i)\bunique\b`)
+	checkClause         = regexp.MustCompile(`(?i)\bcheck ?\(`)
+	typeTerminator      = regexp.MustCompile(`(?i)^(not|null|default|primary|references|unique|check|constraint|generated|collate)$`)
+
+	addColumnAction      = regexp.MustCompile(`(?i)^add (?:column )?(?:if not exists )?(.*)$`)
+	dropColumnAction     = regexp.MustCompile(`(?i)^drop (?:column )?(?:if exists )?([\w"]+)`)
+	addConstraintAction  = regexp.MustCompile(`(?i)^add ((?:constraint|primary key|unique|foreign key|check|exclude)\b.*)$`)
+	dropConstraintAction = regexp.MustCompile(`(?i)^drop constraint (?:if exists )?([\w"]+)`)
+	dropIndexPattern     = regexp.MustCompile(`^drop index (?:concurrently )?(?:if exists )?([\w."]+)`)
+	createTriggerPattern = regexp.MustCompile(`^create (?:or replace )?trigger ([\w"]+) .*? on ([\w."]+) `)
+	dropTriggerPattern   = regexp.MustCompile(`^drop trigger (?:if exists )?([\w"]+) on ([\w."]+)`)
+	indexNamePattern     = regexp.MustCompile(`^create (?:unique )?index (?:concurrently )?(?:if not exists )?([\w"]+) on`)
+)
+
+// dataTypes maps the type names migrations use to information_schema
+// data_type values.
+var dataTypes = map[string]string{
+	"bigint":           "bigint",
+	"bigserial":        "bigint",
+	"bool":             "boolean",
+	"boolean":          "boolean",
+	"bytea":            "bytea",
+	"date":             "date",
+	"double precision": "double precision",
+	"float8":           "double precision",
+	"int":              "integer",
+	"int4":             "integer",
+	"int8":             "bigint",
+	"integer":          "integer",
+	"jsonb":            "jsonb",
+	"json":             "json",
+	"numeric":          "numeric",
+	"real":             "real",
+	"serial":           "integer",
+	"smallint":         "smallint",
+	"text":             "text",
+	"timestamp":        "timestamp without time zone",
+	"timestamptz":      "timestamp with time zone",
+	"tsvector":         "tsvector",
+	"uuid":             "uuid",
+	"varchar":          "character varying",
+	"xid8":             "xid8",
+}
+
+// ExpectedFromMigrations replays the goose migrations at the root of fsys.
+// Function bodies and data changes are skipped, as are unnamed table-level
+// CHECK constraints, whose generated names depend on their expression.
+// Renames and column type changes are not replayed; the guard in Preflight
+// already keeps them out of routine migrations.
+func ExpectedFromMigrations(fsys fs.FS) (*Expected, error) {
+	names, err := fs.Glob(fsys, "*.sql")
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(names)
+
+	expected := &Expected{}
+	for _, name := range names {
+		data, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return nil, fmt.Errorf("read %s: %w", name, err)
+		}
+		for _, statement := range upStatements(string(data)) {
+			if err := expected.apply(collapseStatement(statement)); err != nil {
+				return nil, fmt.Errorf("%s: %w", name, err)
+			}
+		}
+	}
+	return expected, nil
+}
+
+// Table returns the expected table named name, or nil.
+func (e *Expected) Table(name string) *Table {
+	for _, table := range e.Tables {
+		if table.Name == name {
+			return table
+		}
+	}
+	return nil
+}
+
+func (e *Expected) apply(stmt string) error {
+	lower := strings.ToLower(stmt)
+	switch {
+	case createTablePattern.MatchString(lower):
+		return e.createTable(stmt, lower)
+	case alterTablePattern.MatchString(lower):
+		m := alterTablePattern.FindStringSubmatch(lower)
+		table := e.Table(tableName(m[1]))
+		if table == nil {
+			return nil
+		}
+		actions := stmt[len(stmt)-len(m[2]):]
+		for _, action := range splitTopLevel(actions) {
+			table.alter(action)
+		}
+	case dropTablePattern.MatchString(lower):
+		name := tableName(dropTablePattern.FindStringSubmatch(lower)[1])
+		for i, table := range e.Tables {
+			if table.Name == name {
+				e.Tables = append(e.Tables[:i], e.Tables[i+1:]...)
+				break
+			}
+		}
+		e.Indexes = removeObjects(e.Indexes, func(o Object) bool { return o.Table == name })
+		e.Triggers = removeObjects(e.Triggers, func(o Object) bool { return o.Table == name })
+	case createIndexPattern.MatchString(lower):
+		m := indexNamePattern.FindStringSubmatch(lower)
+		if m == nil {
+			return fmt.Errorf("unnamed index: %s", firstLine(stmt))
+		}
+		table := tableName(createIndexPattern.FindStringSubmatch(lower)[2])
+		e.Indexes = append(e.Indexes, Object{Name: unquote(m[1]), Table: table, Statement: stmt})
+	case dropIndexPattern.MatchString(lower):
+		name := tableName(dropIndexPattern.FindStringSubmatch(lower)[1])
+		e.Indexes = removeObjects(e.Indexes, func(o Object) bool { return o.Name == name })
+	case createTriggerPattern.MatchString(lower):
+		m := createTriggerPattern.FindStringSubmatch(lower)
+		name, table := unquote(m[1]), tableName(m[2])
+		e.Triggers = removeObjects(e.Triggers, func(o Object) bool { return o.Name == name && o.Table == table })
+		e.Triggers = append(e.Triggers, Object{Name: name, Table: table, Statement: stmt})
+	case dropTriggerPattern.MatchString(lower):
+		m := dropTriggerPattern.FindStringSubmatch(lower)
+		name, table := unquote(m[1]), tableName(m[2])
+		e.Triggers = removeObjects(e.Triggers, func(o Object) bool { return o.Name == name && o.Table == table })
+	}
+	return nil
+}
+
+func (e *Expected) createTable(stmt, lower string) error {
+	name := tableName(createTablePattern.FindStringSubmatch(lower)[1])
+	if e.Table(name) != nil {
+		// CREATE TABLE IF NOT EXISTS on an existing table does nothing.
+		return nil
+	}
+	open := strings.Index(stmt, "(")
+	end := strings.LastIndex(stmt, ")")
+	if open < 0 || end < open {
+		return fmt.Errorf("cannot parse columns of table %q", name)
+	}
+
+	table := &Table{Name: name}
+	for _, element := range splitTopLevel(stmt[open+1 : end]) {
+		if element == "" {
+			continue
+		}
+		if tableElementKeyword.MatchString(element) {
+			table.addTableConstraint(element)
+			continue
+		}
+		table.addColumn(element)
+	}
+	e.Tables = append(e.Tables, table)
+	return nil
+}
+
+func (t *Table) alter(action string) {
+	switch {
+	case addConstraintAction.MatchString(action):
+		t.addTableConstraint(addConstraintAction.FindStringSubmatch(action)[1])
+	case dropConstraintAction.MatchString(action):
+		name := unquote(strings.ToLower(dropConstraintAction.FindStringSubmatch(action)[1]))
+		t.Constraints = removeConstraints(t.Constraints, func(c Constraint) bool { return c.Name == name })
+	case addColumnAction.MatchString(action):
+		definition := addColumnAction.FindStringSubmatch(action)[1]
+		if t.column(columnName(definition)) == nil {
+			t.addColumn(definition)
+		}
+	case dropColumnAction.MatchString(action):
+		name := unquote(strings.ToLower(dropColumnAction.FindStringSubmatch(action)[1]))
+		for i, column := range t.Columns {
+			if column.Name == name {
+				t.Columns = append(t.Columns[:i], t.Columns[i+1:]...)
+				break
+			}
+		}
+		t.Constraints = removeConstraints(t.Constraints, func(c Constraint) bool { return c.Column == name })
+	}
+}
+
+func (t *Table) column(name string) *Column {
+	for i := range t.Columns {
+		if t.Columns[i].Name == name {
+			return &t.Columns[i]
+		}
+	}
+	return nil
+}
+
+// addColumn records a column and the constraints declared with it.
+func (t *Table) addColumn(definition string) {
+	fields := strings.Fields(definition)
+	name := columnName(definition)
+	var typeParts []string
+	for _, field := range fields[1:] {
+		if typeTerminator.MatchString(field) {
+			break
+		}
+		typeParts = append(typeParts, field)
+	}
+	t.Columns = append(t.Columns, Column{Name: name, Type: dataType(strings.Join(typeParts, " ")), Definition: definition})
+
+	if primaryKeyClause.MatchString(definition) {
+		t.Constraints = append(t.Constraints, Constraint{Name: t.Name + "_pkey", Column: name, Definition: fmt.Sprintf("PRIMARY KEY (%s)", name)})
+	}
+	if uniqueClause.MatchString(definition) {
+		t.Constraints = append(t.Constraints, Constraint{Name: fmt.Sprintf("%s_%s_key", t.Name, name), Column: name, Definition: fmt.Sprintf("UNIQUE (%s)", name)})
+	}
+	if ref := referencesClause.FindString(definition); ref != "" {
+		t.Constraints = append(t.Constraints, Constraint{Name: fmt.Sprintf("%s_%s_fkey", t.Name, name), Column: name, Definition: fmt.Sprintf("FOREIGN KEY (%s) %s", name, ref)})
+	}
+	if loc := checkClause.FindStringIndex(definition); loc != nil {
+		if expr, ok := balanced(definition[loc[1]-1:]); ok {
+			t.Constraints = append(t.Constraints, Constraint{Name: fmt.Sprintf("%s_%s_check", t.Name, name), Column: name, Definition: "CHECK " + expr})
+		}
+	}
+}
+
+// addTableConstraint records a constraint declared on its own, either in
+// CREATE TABLE or by ALTER TABLE ... ADD CONSTRAINT.
+func (t *Table) addTableConstraint(element string) {
+	if m := namedConstraint.FindStringSubmatch(element); m != nil {
+		t.Constraints = append(t.Constraints, Constraint{Name: unquote(strings.ToLower(m[1])), Definition: m[2]})
+		return
+	}
+	lower := strings.ToLower(element)
+	var suffix, rest string
+	switch {
+	case strings.HasPrefix(lower, "primary key"):
+		t.Constraints = append(t.Constraints, Constraint{Name: t.Name + "_pkey", Definition: element})
+		return
+	case strings.HasPrefix(lower, "unique"):
+		suffix, rest = "key", strings.TrimSpace(element[len("unique"):])
+	case strings.HasPrefix(lower, "foreign key"):
+		suffix, rest = "fkey", strings.TrimSpace(element[len("foreign key"):])
+	default:
+		return
+	}
+	m := columnList.FindStringSubmatch(rest)
+	if m == nil {
+		return
+	}
+	var columns []string
+	for _, column := range strings.Split(m[1], ",") {
+		columns = append(columns, unquote(strings.ToLower(strings.TrimSpace(column))))
+	}
+	t.Constraints = append(t.Constraints, Constraint{
+		Name:       fmt.Sprintf("%s_%s_%s", t.Name, strings.Join(columns, "_"), suffix),
+		Definition: element,
+	})
+}
+
+// createStatement recreates the table with its current columns and
+// constraints.
+func (t *Table) createStatement() string {
+	var elements []string
+	for _, column := range t.Columns {
+		elements = append(elements, column.Definition)
+	}
+	for _, constraint := range t.Constraints {
+		if constraint.Column == "" {
+			elements = append(elements, fmt.Sprintf("CONSTRAINT %s %s", constraint.Name, constraint.Definition))
+		}
+	}
+	return fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (\n    %s\n)", t.Name, strings.Join(elements, ",\n    "))
+}
+
+func columnName(definition string) string {
+	fields := strings.Fields(definition)
+	if len(fields) == 0 {
+		return ""
+	}
+	return unquote(strings.ToLower(fields[0]))
+}
+
+func dataType(raw string) string {
+	raw = strings.ToLower(strings.TrimSpace(raw))
+	if strings.HasSuffix(raw, "[]") {
+		return "ARRAY"
+	}
+	if i := strings.Index(raw, "("); i >= 0 {
+		raw = strings.TrimSpace(raw[:i])
+	}
+	if mapped, ok := dataTypes[raw]; ok {
+		return mapped
+	}
+	return raw
+}
+
+// balanced returns the parenthesized expression s starts with.
+func balanced(s string) (string, bool) {
+	depth := 0
+	quoted := false
+	for i, r := range s {
+		if r == '\'' {
+			quoted = !quoted
+		}
+		if quoted {
+			continue
+		}
+		switch r {
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth == 0 {
+				return s[:i+1], true
+			}
+		}
+	}
+	return "", false
+}
+
+func unquote(name string) string {
+	return strings.ReplaceAll(name, `"`, "")
+}
+
+func removeObjects(objects []Object, drop func(Object) bool) []Object {
+	kept := objects[:0]
+	for _, object := range objects {
+		if !drop(object) {
+			kept = append(kept, object)
+		}
+	}
+	return kept
+}
+
+func removeConstraints(constraints []Constraint, drop func(Constraint) bool) []Constraint {
+	kept := constraints[:0]
+	for _, constraint := range constraints {
+		if !drop(constraint) {
+			kept = append(kept, constraint)
+		}
+	}
+	return kept
+}
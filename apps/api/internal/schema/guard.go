@@ -0,0 +1,229 @@
+package schema
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Migration is the SQL of a goose migration that has not been applied yet.
+type Migration struct {
+	Name string
+	SQL  string
+}
+
+// Finding is a statement that is unsafe to run while the previous release
+// is still serving traffic, because it takes a long lock, rewrites a table,
+// or breaks queries the old code still issues.
+type Finding struct {
+	Migration string
+	Statement string
+	Reason    string
+}
+
+var (
+	createTablePattern = regexp.MustCompile(`^create (?:unlogged )?table (?:if not exists )?([\w."]+)`)
+	createIndexPattern = regexp.MustCompile(`^create (?:unique )?index (concurrently )?(?:if not exists )?(?:[\w"]+ )?on (?:only )?([\w."]+)`)
+	alterTablePattern  = regexp.MustCompile(`^alter table (?:if exists )?(?:only )?([\w."]+) (.*)$`)
+	dropTablePattern   = regexp.MustCompile(`^drop table (?:if exists )?([\w."]+)`)
+	updatePattern      = regexp.MustCompile(`^update (?:only )?([\w."]+) `)
+	rewritePattern     = regexp.MustCompile(`^(?:vacuum full|cluster)\b`)
+
+	volatileDefaultPattern = regexp.MustCompile(`\bdefault (?:gen_random_uuid|uuid_generate_v[14]|random|clock_timestamp|nextval)\(`)
+	alterTypePattern       = regexp.MustCompile(`^alter (?:column )?[\w"]+ (?:set data )?type `)
+	setNotNullPattern      = regexp.MustCompile(`^alter (?:column )?[\w"]+ set not null`)
+	renamePattern          = regexp.MustCompile(`^rename (?:column |constraint )?(?:[\w"]+ )?to `)
+	addIndexedPattern      = regexp.MustCompile(`^add (?:constraint [\w"]+ )?(?:primary key|unique)`)
+	addCheckedPattern      = regexp.MustCompile(`^add (?:constraint [\w"]+ )?(?:check|foreign key)`)
+)
+
+// Preflight lists the unsafe statements in pending, which must be in the
+// order goose applies them. Tables created by a pending migration are new
+// and empty, so later statements touching them are not reported; for a
+// fresh database Preflight therefore returns nothing.
+func Preflight(pending []Migration) []Finding {
+	created := make(map[string]bool)
+	var findings []Finding
+	for _, migration := range pending {
+		for _, statement := range upStatements(migration.SQL) {
+			for _, reason := range classify(normalizeStatement(statement), created) {
+				findings = append(findings, Finding{
+					Migration: migration.Name,
+					Statement: firstLine(statement),
+					Reason:    reason,
+				})
+			}
+		}
+	}
+	return findings
+}
+
+// classify returns why stmt is unsafe, if it is, and records the tables it
+// creates.
+func classify(stmt string, created map[string]bool) []string {
+	if m := createTablePattern.FindStringSubmatch(stmt); m != nil {
+		created[tableName(m[1])] = true
+		return nil
+	}
+	if m := createIndexPattern.FindStringSubmatch(stmt); m != nil {
+		if m[1] == "" && !created[tableName(m[2])] {
+			return []string{"CREATE INDEX blocks writes until it finishes; use CREATE INDEX CONCURRENTLY in a -- +goose NO TRANSACTION migration"}
+		}
+		return nil
+	}
+	if m := dropTablePattern.FindStringSubmatch(stmt); m != nil {
+		if !created[tableName(m[1])] {
+			return []string{"dropping a table breaks the release still serving; drop it once no deployed code reads it"}
+		}
+		return nil
+	}
+	if m := updatePattern.FindStringSubmatch(stmt); m != nil {
+		if !created[tableName(m[1])] && !strings.Contains(stmt, " where ") {
+			return []string{"UPDATE without WHERE rewrites every row in one transaction; backfill in batches"}
+		}
+		return nil
+	}
+	if rewritePattern.MatchString(stmt) {
+		return []string{"rewrites the table under an exclusive lock"}
+	}
+	if m := alterTablePattern.FindStringSubmatch(stmt); m != nil {
+		if created[tableName(m[1])] {
+			return nil
+		}
+		var reasons []string
+		for _, action := range splitTopLevel(m[2]) {
+			if reason := classifyAlter(action); reason != "" {
+				reasons = append(reasons, reason)
+			}
+		}
+		return reasons
+	}
+	return nil
+}
+
+func classifyAlter(action string) string {
+	switch {
+	case addIndexedPattern.MatchString(action):
+		if !strings.Contains(action, " using index") {
+			return "adding a primary key or unique constraint builds an index under an exclusive lock; build it CONCURRENTLY and add the constraint USING INDEX"
+		}
+	case addCheckedPattern.MatchString(action):
+		if !strings.Contains(action, " not valid") {
+			return "adding a constraint scans the table under a lock; add it NOT VALID and VALIDATE it in a later migration"
+		}
+	case strings.HasPrefix(action, "add "):
+		if strings.Contains(action, "not null") && !strings.Contains(action, " default ") {
+			return "adds a NOT NULL column without a default, which fails on existing rows and rejects inserts from the release still serving"
+		}
+		if volatileDefaultPattern.MatchString(action) {
+			return "adds a column with a volatile default, which rewrites the table"
+		}
+	case alterTypePattern.MatchString(action):
+		return "changing a column type rewrites the table under an exclusive lock"
+	case setNotNullPattern.MatchString(action):
+		return "SET NOT NULL scans the table under an exclusive lock; add a CHECK (... IS NOT NULL) NOT VALID constraint and validate it first"
+	case strings.HasPrefix(action, "drop column"):
+		return "dropping a column breaks the release still serving; drop it once no deployed code reads it"
+	case renamePattern.MatchString(action):
+		return "renaming breaks the release still serving; add the new name and migrate readers first"
+	}
+	return ""
+}
+
+// upStatements splits the Up section of a goose migration into statements
+// the way goose does, at semicolons ending a line. StatementBegin and
+// StatementEnd blocks hold function bodies and DO blocks and are skipped.
+func upStatements(sql string) []string {
+	var (
+		statements []string
+		current    strings.Builder
+		inUp       bool
+		inBlock    bool
+	)
+	for _, line := range strings.Split(sql, "\n") {
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case strings.HasPrefix(trimmed, "-- +goose Up"):
+			inUp = true
+			continue
+		case strings.HasPrefix(trimmed, "-- +goose Down"):
+			return statements
+		case strings.HasPrefix(trimmed, "-- +goose StatementBegin"):
+			inBlock = true
+			continue
+		case strings.HasPrefix(trimmed, "-- +goose StatementEnd"):
+			inBlock = false
+			current.Reset()
+			continue
+		}
+		if !inUp || inBlock || trimmed == "" || strings.HasPrefix(trimmed, "--") {
+			continue
+		}
+		current.WriteString(line)
+		current.WriteByte('\n')
+		if strings.HasSuffix(trimmed, ";") {
+			statements = append(statements, strings.TrimSpace(current.String()))
+			current.Reset()
+		}
+	}
+	return statements
+}
+
+// normalizeStatement lowercases stmt and collapses whitespace, dropping
+// trailing comments and the final semicolon.
+func normalizeStatement(stmt string) string {
+	return strings.ToLower(collapseStatement(stmt))
+}
+
+// collapseStatement is normalizeStatement without the lowercasing, for
+// statements that are printed back as DDL.
+func collapseStatement(stmt string) string {
+	var lines []string
+	for _, line := range strings.Split(stmt, "\n") {
+		if i := strings.Index(line, "--"); i >= 0 {
+			line = line[:i]
+		}
+		lines = append(lines, line)
+	}
+	return strings.TrimSuffix(strings.Join(strings.Fields(strings.Join(lines, " ")), " "), ";")
+}
+
+// splitTopLevel splits ALTER TABLE actions or CREATE TABLE elements at
+// commas outside parentheses and string literals.
+func splitTopLevel(s string) []string {
+	var (
+		parts  []string
+		depth  int
+		start  int
+		quoted bool
+	)
+	for i, r := range s {
+		if r == '\'' {
+			quoted = !quoted
+		}
+		if quoted {
+			continue
+		}
+		switch r {
+		case '(':
+			depth++
+		case ')':
+			depth--
+		case ',':
+			if depth == 0 {
+				parts = append(parts, strings.TrimSpace(s[start:i]))
+				start = i + 1
+			}
+		}
+	}
+	return append(parts, strings.TrimSpace(s[start:]))
+}
+
+func tableName(name string) string {
+	name = strings.ReplaceAll(name, `"`, "")
+	return strings.TrimPrefix(name, "public.")
+}
+
+func firstLine(stmt string) string {
+	line, _, _ := strings.Cut(stmt, "\n")
+	return strings.TrimSpace(line)
+}
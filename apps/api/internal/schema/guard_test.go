@@ -0,0 +1,82 @@
+package schema
+
+import (
+	"io/fs"
+	"strings"
+	"testing"
+
+	"github.com/example/keepstack/db/migrations"
+)
+
+func TestPreflightClassifiesStatements(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name   string
+		up     string
+		unsafe string
+	}{
+		{name: "nullable column", up: "ALTER TABLE links ADD COLUMN IF NOT EXISTS note TEXT;"},
+		{name: "not null with default", up: "ALTER TABLE links ADD COLUMN pinned BOOLEAN NOT NULL DEFAULT false;"},
+		{name: "not null without default", up: "ALTER TABLE links ADD COLUMN pinned BOOLEAN NOT NULL;", unsafe: "NOT NULL column without a default"},
+		{name: "volatile default", up: "ALTER TABLE links ADD COLUMN token UUID DEFAULT gen_random_uuid();", unsafe: "volatile default"},
+		{name: "second action", up: "ALTER TABLE links\n    ADD COLUMN a TEXT,\n    ALTER COLUMN title TYPE VARCHAR(200);", unsafe: "column type"},
+		{name: "set not null", up: "ALTER TABLE links ALTER COLUMN title SET NOT NULL;", unsafe: "SET NOT NULL"},
+		{name: "drop column", up: "ALTER TABLE links DROP COLUMN IF EXISTS title;", unsafe: "dropping a column"},
+		{name: "rename column", up: "ALTER TABLE links RENAME COLUMN title TO headline;", unsafe: "renaming"},
+		{name: "foreign key", up: "ALTER TABLE links ADD CONSTRAINT links_user_fk FOREIGN KEY (user_id) REFERENCES users(id);", unsafe: "NOT VALID"},
+		{name: "foreign key not valid", up: "ALTER TABLE links ADD CONSTRAINT links_user_fk FOREIGN KEY (user_id) REFERENCES users(id) NOT VALID;"},
+		{name: "blocking index", up: "CREATE INDEX links_title_idx ON links(title);", unsafe: "CONCURRENTLY"},
+		{name: "concurrent index", up: "-- +goose NO TRANSACTION\nCREATE INDEX CONCURRENTLY IF NOT EXISTS links_title_idx ON links(title);"},
+		{name: "full update", up: "UPDATE links SET title = '';", unsafe: "backfill in batches"},
+		{name: "scoped update", up: "UPDATE links SET title = ''\nWHERE title IS NULL;"},
+		{name: "new table", up: "CREATE TABLE notes (id UUID PRIMARY KEY);\nALTER TABLE notes ADD COLUMN body TEXT NOT NULL;\nCREATE INDEX notes_body_idx ON notes(body);"},
+		{name: "function body", up: "-- +goose StatementBegin\nCREATE FUNCTION f() RETURNS void AS $$\nBEGIN\n    UPDATE links SET title = '';\nEND;\n$$ LANGUAGE plpgsql;\n-- +goose StatementEnd"},
+		{name: "down section", up: "SELECT 1;\n\n-- +goose Down\nALTER TABLE links DROP COLUMN title;"},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			findings := Preflight([]Migration{{Name: "000100_test.sql", SQL: "-- +goose Up\n" + tc.up + "\n"}})
+			if tc.unsafe == "" {
+				if len(findings) != 0 {
+					t.Fatalf("expected no findings, got %+v", findings)
+				}
+				return
+			}
+			if len(findings) != 1 || !strings.Contains(findings[0].Reason, tc.unsafe) {
+				t.Fatalf("expected one finding mentioning %q, got %+v", tc.unsafe, findings)
+			}
+			if findings[0].Migration != "000100_test.sql" {
+				t.Fatalf("unexpected migration: %q", findings[0].Migration)
+			}
+		})
+	}
+}
+
+func TestPreflightFreshDatabaseIsSafe(t *testing.T) {
+	t.Parallel()
+
+	entries, err := fs.ReadDir(migrations.FS, ".")
+	if err != nil {
+		t.Fatalf("read migrations: %v", err)
+	}
+	var pending []Migration
+	for _, entry := range entries {
+		if !strings.HasSuffix(entry.Name(), ".sql") {
+			continue
+		}
+		data, err := fs.ReadFile(migrations.FS, entry.Name())
+		if err != nil {
+			t.Fatalf("read %s: %v", entry.Name(), err)
+		}
+		pending = append(pending, Migration{Name: entry.Name(), SQL: string(data)})
+	}
+
+	if findings := Preflight(pending); len(findings) != 0 {
+		t.Fatalf("expected a fresh database to migrate without findings, got %+v", findings)
+	}
+}
@@ -4,172 +4,232 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"sort"
-	"strings"
+	"regexp"
 
 	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/example/keepstack/db/migrations"
 )
 
-type columnSpec struct {
-	name     string
-	dataType string
+// Problem is one way the database differs from the schema the migrations
+// build. Fix is the DDL that repairs it, or empty when the repair needs a
+// human, such as a column whose type changed.
+type Problem struct {
+	Message string
+	Fix     string
 }
 
-// Verify ensures the database schema matches the expected structure for v0.2 features.
-func Verify(ctx context.Context, pool *pgxpool.Pool) error {
-	var errs []error
-
-	archivesReady := true
-	if err := ensureTable(ctx, pool, "archives"); err != nil {
-		archivesReady = false
-		errs = append(errs, err)
-	} else if err := ensureColumns(ctx, pool, "archives", []columnSpec{
-		{name: "title", dataType: "text"},
-		{name: "byline", dataType: "text"},
-		{name: "lang", dataType: "text"},
-		{name: "word_count", dataType: "integer"},
-	}); err != nil {
-		errs = append(errs, err)
-	}
-
-	if archivesReady {
-		if err := ensureTriggers(ctx, pool, "archives", []string{"archives_refresh_link_search_trigger"}); err != nil {
-			errs = append(errs, err)
-		}
-	}
-
-	linksReady := true
-	if err := ensureTable(ctx, pool, "links"); err != nil {
-		linksReady = false
-		errs = append(errs, err)
-	} else {
-		if err := ensureColumns(ctx, pool, "links", []columnSpec{{name: "source_domain", dataType: "text"}}); err != nil {
-			errs = append(errs, err)
-		}
-	}
+// snapshot is the part of a live schema compare looks at.
+type snapshot struct {
+	columns     map[string]map[string]string
+	constraints map[string]map[string]bool
+	indexes     map[string]bool
+	triggers    map[string]map[string]bool
+}
 
-	if linksReady {
-		if err := ensureTriggers(ctx, pool, "links", []string{"links_search_tsv_update_trigger"}); err != nil {
-			errs = append(errs, err)
-		}
-	}
+var indexPrefix = regexp.MustCompile(`(?i)^create (unique )?index (?:concurrently )?(?:if not exists )?`)
 
-	if err := ensureTable(ctx, pool, "highlights"); err != nil {
-		errs = append(errs, err)
-	} else if err := ensureColumns(ctx, pool, "highlights", []columnSpec{
-		{name: "id", dataType: "uuid"},
-		{name: "link_id", dataType: "uuid"},
-		{name: "quote", dataType: "text"},
-		{name: "annotation", dataType: "text"},
-		{name: "created_at", dataType: "timestamp with time zone"},
-		{name: "updated_at", dataType: "timestamp with time zone"},
-	}); err != nil {
-		errs = append(errs, err)
+// Verify ensures the database has every table, column, constraint, index,
+// and trigger the embedded migrations create.
+func Verify(ctx context.Context, pool *pgxpool.Pool) error {
+	problems, err := Diff(ctx, pool)
+	if err != nil {
+		return err
 	}
-
-	if len(errs) > 0 {
-		return errors.Join(errs...)
+	errs := make([]error, 0, len(problems))
+	for _, problem := range problems {
+		errs = append(errs, errors.New(problem.Message))
 	}
-
-	return nil
+	return errors.Join(errs...)
 }
 
-func ensureTable(ctx context.Context, pool *pgxpool.Pool, table string) error {
-	const query = `SELECT EXISTS (SELECT 1 FROM information_schema.tables WHERE table_schema = 'public' AND table_name = $1)`
-
-	var exists bool
-	if err := pool.QueryRow(ctx, query, table).Scan(&exists); err != nil {
-		return fmt.Errorf("check table %q: %w", table, err)
+// Diff compares the database with the embedded migrations.
+func Diff(ctx context.Context, pool *pgxpool.Pool) ([]Problem, error) {
+	expected, err := ExpectedFromMigrations(migrations.FS)
+	if err != nil {
+		return nil, fmt.Errorf("read expected schema: %w", err)
 	}
-	if !exists {
-		return fmt.Errorf("database schema missing table %q", table)
+	actual, err := loadSnapshot(ctx, pool)
+	if err != nil {
+		return nil, err
 	}
-	return nil
+	return compare(expected, actual), nil
 }
 
-func ensureColumns(ctx context.Context, pool *pgxpool.Pool, table string, specs []columnSpec) error {
-	const query = `SELECT column_name, data_type FROM information_schema.columns WHERE table_schema = 'public' AND table_name = $1`
-
-	rows, err := pool.Query(ctx, query, table)
+// Apply runs the fixes for problems in one transaction. Problems without a
+// fix are left alone.
+func Apply(ctx context.Context, pool *pgxpool.Pool, problems []Problem) error {
+	tx, err := pool.Begin(ctx)
 	if err != nil {
-		return fmt.Errorf("query columns for table %q: %w", table, err)
+		return fmt.Errorf("begin: %w", err)
 	}
-	defer rows.Close()
+	defer tx.Rollback(ctx)
 
-	columns := map[string]string{}
-	for rows.Next() {
-		var name, dataType string
-		if err := rows.Scan(&name, &dataType); err != nil {
-			return fmt.Errorf("scan column metadata for table %q: %w", table, err)
+	for _, problem := range problems {
+		if problem.Fix == "" {
+			continue
+		}
+		if _, err := tx.Exec(ctx, problem.Fix); err != nil {
+			return fmt.Errorf("%s: %w", problem.Message, err)
 		}
-		columns[name] = dataType
-	}
-	if err := rows.Err(); err != nil {
-		return fmt.Errorf("iterate columns for table %q: %w", table, err)
 	}
+	return tx.Commit(ctx)
+}
 
-	var missing []string
-	var mismatched []string
-	for _, spec := range specs {
-		dataType, ok := columns[spec.name]
+// compare lists what actual lacks from expected, tables, columns, and
+// constraints first so the index and trigger fixes that follow can rely on
+// them. Objects the migrations do not know about are not reported.
+func compare(expected *Expected, actual snapshot) []Problem {
+	var problems []Problem
+	for _, table := range expected.Tables {
+		columns, ok := actual.columns[table.Name]
 		if !ok {
-			missing = append(missing, spec.name)
+			problems = append(problems, Problem{
+				Message: fmt.Sprintf("database schema missing table %q", table.Name),
+				Fix:     table.createStatement() + ";",
+			})
 			continue
 		}
-		if dataType != spec.dataType {
-			mismatched = append(mismatched, fmt.Sprintf("%s (expected %s, found %s)", spec.name, spec.dataType, dataType))
+
+		restored := make(map[string]bool)
+		for _, column := range table.Columns {
+			dataType, ok := columns[column.Name]
+			if !ok {
+				restored[column.Name] = true
+				problems = append(problems, Problem{
+					Message: fmt.Sprintf("database schema missing column %q on table %q", column.Name, table.Name),
+					Fix:     fmt.Sprintf("ALTER TABLE %s ADD COLUMN IF NOT EXISTS %s;", table.Name, column.Definition),
+				})
+				continue
+			}
+			if dataType != column.Type {
+				problems = append(problems, Problem{
+					Message: fmt.Sprintf("database schema mismatch on table %q (column %s: expected %s, found %s)", table.Name, column.Name, column.Type, dataType),
+				})
+			}
 		}
-	}
 
-	if len(missing) == 0 && len(mismatched) == 0 {
-		return nil
+		for _, constraint := range table.Constraints {
+			if restored[constraint.Column] || actual.constraints[table.Name][constraint.Name] {
+				continue
+			}
+			problems = append(problems, Problem{
+				Message: fmt.Sprintf("database schema missing constraint %q on table %q", constraint.Name, table.Name),
+				Fix:     fmt.Sprintf("ALTER TABLE %s ADD CONSTRAINT %s %s;", table.Name, constraint.Name, constraint.Definition),
+			})
+		}
 	}
 
-	sort.Strings(missing)
-	sort.Strings(mismatched)
-
-	var parts []string
-	if len(missing) > 0 {
-		parts = append(parts, fmt.Sprintf("missing columns: %s", strings.Join(missing, ", ")))
+	for _, index := range expected.Indexes {
+		if actual.indexes[index.Name] {
+			continue
+		}
+		problems = append(problems, Problem{
+			Message: fmt.Sprintf("database schema missing index %q on table %q", index.Name, index.Table),
+			Fix:     indexFix(index.Statement) + ";",
+		})
 	}
-	if len(mismatched) > 0 {
-		parts = append(parts, fmt.Sprintf("unexpected types: %s", strings.Join(mismatched, "; ")))
+
+	for _, trigger := range expected.Triggers {
+		if actual.triggers[trigger.Table][trigger.Name] {
+			continue
+		}
+		problems = append(problems, Problem{
+			Message: fmt.Sprintf("database schema missing trigger %q on table %q", trigger.Name, trigger.Table),
+			Fix:     trigger.Statement + ";",
+		})
 	}
+	return problems
+}
 
-	return fmt.Errorf("database schema mismatch on table %q (%s)", table, strings.Join(parts, "; "))
+// indexFix rewrites a CREATE INDEX statement so it can run inside Apply's
+// transaction and be rerun safely.
+func indexFix(stmt string) string {
+	return indexPrefix.ReplaceAllStringFunc(stmt, func(prefix string) string {
+		if indexPrefix.FindStringSubmatch(prefix)[1] != "" {
+			return "CREATE UNIQUE INDEX IF NOT EXISTS "
+		}
+		return "CREATE INDEX IF NOT EXISTS "
+	})
 }
 
-func ensureTriggers(ctx context.Context, pool *pgxpool.Pool, table string, expected []string) error {
-	const query = `SELECT tgname FROM pg_trigger WHERE NOT tgisinternal AND tgrelid = $1::regclass`
+func loadSnapshot(ctx context.Context, pool *pgxpool.Pool) (snapshot, error) {
+	actual := snapshot{
+		columns:     make(map[string]map[string]string),
+		constraints: make(map[string]map[string]bool),
+		indexes:     make(map[string]bool),
+		triggers:    make(map[string]map[string]bool),
+	}
+
+	const columnsQuery = `SELECT table_name, column_name, data_type FROM information_schema.columns WHERE table_schema = 'public'`
+	if err := scanRows(ctx, pool, columnsQuery, "columns", func(values []string) {
+		if actual.columns[values[0]] == nil {
+			actual.columns[values[0]] = make(map[string]string)
+		}
+		actual.columns[values[0]][values[1]] = values[2]
+	}, 3); err != nil {
+		return actual, err
+	}
+
+	const constraintsQuery = `SELECT c.relname, con.conname
+FROM pg_constraint con
+JOIN pg_class c ON c.oid = con.conrelid
+JOIN pg_namespace n ON n.oid = c.relnamespace
+WHERE n.nspname = 'public'`
+	if err := scanRows(ctx, pool, constraintsQuery, "constraints", func(values []string) {
+		addName(actual.constraints, values[0], values[1])
+	}, 2); err != nil {
+		return actual, err
+	}
+
+	const indexesQuery = `SELECT indexname FROM pg_indexes WHERE schemaname = 'public'`
+	if err := scanRows(ctx, pool, indexesQuery, "indexes", func(values []string) {
+		actual.indexes[values[0]] = true
+	}, 1); err != nil {
+		return actual, err
+	}
+
+	const triggersQuery = `SELECT c.relname, t.tgname
+FROM pg_trigger t
+JOIN pg_class c ON c.oid = t.tgrelid
+JOIN pg_namespace n ON n.oid = c.relnamespace
+WHERE NOT t.tgisinternal AND n.nspname = 'public'`
+	if err := scanRows(ctx, pool, triggersQuery, "triggers", func(values []string) {
+		addName(actual.triggers, values[0], values[1])
+	}, 2); err != nil {
+		return actual, err
+	}
+	return actual, nil
+}
 
-	rows, err := pool.Query(ctx, query, table)
+// scanRows runs query and passes each row's width text columns to add.
+func scanRows(ctx context.Context, pool *pgxpool.Pool, query, what string, add func([]string), width int) error {
+	rows, err := pool.Query(ctx, query)
 	if err != nil {
-		return fmt.Errorf("query triggers for table %q: %w", table, err)
+		return fmt.Errorf("query %s: %w", what, err)
 	}
 	defer rows.Close()
 
-	triggers := map[string]struct{}{}
+	values := make([]string, width)
+	targets := make([]any, width)
+	for i := range values {
+		targets[i] = &values[i]
+	}
 	for rows.Next() {
-		var name string
-		if err := rows.Scan(&name); err != nil {
-			return fmt.Errorf("scan trigger metadata for table %q: %w", table, err)
+		if err := rows.Scan(targets...); err != nil {
+			return fmt.Errorf("scan %s: %w", what, err)
 		}
-		triggers[name] = struct{}{}
+		add(values)
 	}
 	if err := rows.Err(); err != nil {
-		return fmt.Errorf("iterate triggers for table %q: %w", table, err)
+		return fmt.Errorf("iterate %s: %w", what, err)
 	}
+	return nil
+}
 
-	var missing []string
-	for _, name := range expected {
-		if _, ok := triggers[name]; !ok {
-			missing = append(missing, name)
-		}
-	}
-	if len(missing) == 0 {
-		return nil
+func addName(names map[string]map[string]bool, table, name string) {
+	if names[table] == nil {
+		names[table] = make(map[string]bool)
 	}
-
-	sort.Strings(missing)
-	return fmt.Errorf("database schema missing triggers on table %q: %s", table, strings.Join(missing, ", "))
+	names[table][name] = true
 }
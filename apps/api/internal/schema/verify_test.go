@@ -0,0 +1,170 @@
+package schema
+
+import (
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/example/keepstack/db/migrations"
+)
+
+func TestExpectedFromMigrations(t *testing.T) {
+	t.Parallel()
+
+	expected, err := ExpectedFromMigrations(migrations.FS)
+	if err != nil {
+		t.Fatalf("ExpectedFromMigrations: %v", err)
+	}
+
+	links := expected.Table("links")
+	if links == nil {
+		t.Fatal("expected links table")
+	}
+	for name, dataType := range map[string]string{
+		"id":            "uuid",
+		"source_domain": "text",
+		"search_tsv":    "tsvector",
+		"favorite":      "boolean",
+		"updated_at":    "timestamp with time zone",
+	} {
+		column := links.column(name)
+		if column == nil {
+			t.Errorf("links missing column %s", name)
+			continue
+		}
+		if column.Type != dataType {
+			t.Errorf("links.%s type = %q, want %q", name, column.Type, dataType)
+		}
+	}
+
+	assertConstraints(t, expected, "links", "links_pkey", "links_user_id_fkey")
+	assertConstraints(t, expected, "users", "users_pkey", "users_email_key")
+	assertConstraints(t, expected, "link_tags", "link_tags_pkey", "link_tags_link_id_fkey", "link_tags_tag_id_fkey")
+	assertConstraints(t, expected, "sync_changes", "sync_changes_entity_check")
+
+	if !hasObject(expected.Indexes, "highlights_link_id_idx") {
+		t.Error("expected highlights_link_id_idx index")
+	}
+	if !hasObject(expected.Triggers, "links_search_tsv_update_trigger") {
+		t.Error("expected links_search_tsv_update_trigger trigger")
+	}
+}
+
+func TestExpectedReplaysDrops(t *testing.T) {
+	t.Parallel()
+
+	fsys := fstest.MapFS{
+		"000001_a.sql": {Data: []byte("-- +goose Up\nCREATE TABLE notes (\n    id UUID PRIMARY KEY,\n    body TEXT,\n    owner UUID REFERENCES users(id)\n);\nCREATE INDEX notes_body_idx ON notes(body);\n")},
+		"000002_b.sql": {Data: []byte("-- +goose Up\nALTER TABLE notes DROP COLUMN owner, ADD COLUMN title VARCHAR(200) NOT NULL DEFAULT '';\nDROP INDEX IF EXISTS notes_body_idx;\n\n-- +goose Down\nDROP TABLE notes;\n")},
+	}
+	expected, err := ExpectedFromMigrations(fsys)
+	if err != nil {
+		t.Fatalf("ExpectedFromMigrations: %v", err)
+	}
+
+	notes := expected.Table("notes")
+	if notes == nil {
+		t.Fatal("expected notes table")
+	}
+	if notes.column("owner") != nil {
+		t.Error("dropped column owner still expected")
+	}
+	if column := notes.column("title"); column == nil || column.Type != "character varying" {
+		t.Errorf("title column = %+v, want character varying", column)
+	}
+	for _, constraint := range notes.Constraints {
+		if constraint.Name == "notes_owner_fkey" {
+			t.Error("constraint of dropped column still expected")
+		}
+	}
+	if len(expected.Indexes) != 0 {
+		t.Errorf("indexes = %+v, want none", expected.Indexes)
+	}
+}
+
+func TestCompareReportsDriftWithFixes(t *testing.T) {
+	t.Parallel()
+
+	fsys := fstest.MapFS{
+		"000001_a.sql": {Data: []byte("-- +goose Up\nCREATE TABLE notes (\n    id UUID PRIMARY KEY,\n    body TEXT NOT NULL,\n    owner UUID REFERENCES users(id),\n    rank INTEGER\n);\nCREATE TABLE pins (\n    note_id UUID NOT NULL,\n    CONSTRAINT pins_note_fk FOREIGN KEY (note_id) REFERENCES notes(id)\n);\nCREATE INDEX CONCURRENTLY notes_body_idx ON notes(body);\nCREATE TRIGGER notes_touch_trigger BEFORE UPDATE ON notes FOR EACH ROW EXECUTE FUNCTION touch();\n")},
+	}
+	expected, err := ExpectedFromMigrations(fsys)
+	if err != nil {
+		t.Fatalf("ExpectedFromMigrations: %v", err)
+	}
+
+	actual := snapshot{
+		columns:     map[string]map[string]string{"notes": {"id": "uuid", "body": "text", "rank": "bigint"}},
+		constraints: map[string]map[string]bool{"notes": {"notes_pkey": true}},
+		indexes:     map[string]bool{},
+		triggers:    map[string]map[string]bool{},
+	}
+	problems := compare(expected, actual)
+
+	want := []Problem{
+		{
+			Message: `database schema missing column "owner" on table "notes"`,
+			Fix:     "ALTER TABLE notes ADD COLUMN IF NOT EXISTS owner UUID REFERENCES users(id);",
+		},
+		{
+			Message: `database schema mismatch on table "notes" (column rank: expected integer, found bigint)`,
+		},
+		{
+			Message: `database schema missing table "pins"`,
+			Fix:     "CREATE TABLE IF NOT EXISTS pins (\n    note_id UUID NOT NULL,\n    CONSTRAINT pins_note_fk FOREIGN KEY (note_id) REFERENCES notes(id)\n);",
+		},
+		{
+			Message: `database schema missing index "notes_body_idx" on table "notes"`,
+			Fix:     "CREATE INDEX IF NOT EXISTS notes_body_idx ON notes(body);",
+		},
+		{
+			Message: `database schema missing trigger "notes_touch_trigger" on table "notes"`,
+			Fix:     "CREATE TRIGGER notes_touch_trigger BEFORE UPDATE ON notes FOR EACH ROW EXECUTE FUNCTION touch();",
+		},
+	}
+	if len(problems) != len(want) {
+		t.Fatalf("problems = %+v, want %d", problems, len(want))
+	}
+	for i := range want {
+		if problems[i] != want[i] {
+			t.Errorf("problem %d = %+v, want %+v", i, problems[i], want[i])
+		}
+	}
+
+	actual.columns["notes"]["owner"] = "uuid"
+	actual.columns["notes"]["rank"] = "integer"
+	problems = compare(expected, actual)
+	if len(problems) == 0 || !strings.Contains(problems[0].Message, `constraint "notes_owner_fkey"`) {
+		t.Fatalf("problems = %+v, want missing notes_owner_fkey first", problems)
+	}
+	if problems[0].Fix != "ALTER TABLE notes ADD CONSTRAINT notes_owner_fkey FOREIGN KEY (owner) REFERENCES users(id);" {
+		t.Errorf("fix = %q", problems[0].Fix)
+	}
+}
+
+func assertConstraints(t *testing.T, expected *Expected, table string, names ...string) {
+	t.Helper()
+	found := expected.Table(table)
+	if found == nil {
+		t.Errorf("expected %s table", table)
+		return
+	}
+	for _, name := range names {
+		ok := false
+		for _, constraint := range found.Constraints {
+			ok = ok || constraint.Name == name
+		}
+		if !ok {
+			t.Errorf("%s missing constraint %s, have %+v", table, name, found.Constraints)
+		}
+	}
+}
+
+func hasObject(objects []Object, name string) bool {
+	for _, object := range objects {
+		if object.Name == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,238 @@
+// Package searchindex keeps links.search_tsv in step with link titles and
+// archives. The search triggers maintain it row by row, which goes wrong
+// when a bulk import disables them or loads rows around them; Service checks
+// that the triggers and the search index are in place and rebuilds every
+// stale vector in batches.
+package searchindex
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/example/keepstack/apps/api/internal/db"
+	"github.com/example/keepstack/apps/api/internal/schema"
+	"github.com/example/keepstack/db/migrations"
+)
+
+// DefaultBatchSize is the number of links checked per statement, keeping
+// row locks short while stale vectors are rewritten.
+const DefaultBatchSize = 1000
+
+type queries interface {
+	CountLinksForSearchIndex(context.Context) (int64, error)
+	CheckSearchVectorBatch(context.Context, db.CheckSearchVectorBatchParams) (db.CheckSearchVectorBatchRow, error)
+	RebuildSearchVectorBatch(context.Context, db.RebuildSearchVectorBatchParams) (db.RebuildSearchVectorBatchRow, error)
+	ListTriggerStates(context.Context, []string) ([]db.ListTriggerStatesRow, error)
+	ListIndexesByName(context.Context, []string) ([]string, error)
+}
+
+type execer interface {
+	Exec(context.Context, string, ...any) (pgconn.CommandTag, error)
+}
+
+// Options controls a run.
+type Options struct {
+	// BatchSize caps how many links are checked per statement.
+	BatchSize int
+	// DryRun counts stale vectors and disabled triggers without changing
+	// anything.
+	DryRun bool
+	// Progress, when set, is called after each batch.
+	Progress func(Progress)
+}
+
+// Progress reports how far a run has got. Total is the number of links when
+// the run started, so Checked can pass it while links are being saved.
+type Progress struct {
+	Checked int64
+	Total   int64
+	Stale   int64
+}
+
+// Stats summarises a run. In a dry run Stale and TriggersEnabled count what
+// would have been rebuilt and switched back on.
+type Stats struct {
+	Checked         int64
+	Stale           int64
+	TriggersEnabled int
+	Duration        time.Duration
+}
+
+// Service checks and rebuilds the search index against Postgres.
+type Service struct {
+	pool     execer
+	queries  queries
+	expected func() (*schema.Expected, error)
+	now      func() time.Time
+}
+
+// New constructs a Service using the provided connection pool.
+func New(pool *pgxpool.Pool) *Service {
+	return &Service{
+		pool:    pool,
+		queries: db.New(pool),
+		expected: func() (*schema.Expected, error) {
+			return schema.ExpectedFromMigrations(migrations.FS)
+		},
+		now: time.Now,
+	}
+}
+
+// WithNow overrides the time source. Intended for tests.
+func (s *Service) WithNow(now func() time.Time) {
+	s.now = now
+}
+
+// Run checks the search triggers and index the migrations create, switches
+// disabled triggers back on, and rewrites every search vector that differs
+// from what the triggers would store. A missing trigger or index fails the
+// run before anything is rebuilt, since the vectors would go stale again;
+// verify-schema --fix --apply restores them.
+func (s *Service) Run(ctx context.Context, opts Options) (Stats, error) {
+	start := s.now()
+	var stats Stats
+
+	if opts.BatchSize < 1 {
+		opts.BatchSize = DefaultBatchSize
+	}
+
+	enabled, err := s.checkTriggers(ctx, opts.DryRun)
+	stats.TriggersEnabled = enabled
+	if err != nil {
+		stats.Duration = s.now().Sub(start)
+		return stats, err
+	}
+
+	total, err := s.queries.CountLinksForSearchIndex(ctx)
+	if err != nil {
+		stats.Duration = s.now().Sub(start)
+		return stats, fmt.Errorf("count links: %w", err)
+	}
+
+	var after pgtype.UUID
+	for {
+		var checked, stale int64
+		var last pgtype.UUID
+		if opts.DryRun {
+			batch, err := s.queries.CheckSearchVectorBatch(ctx, db.CheckSearchVectorBatchParams{AfterID: after, BatchSize: int32(opts.BatchSize)})
+			if err != nil {
+				stats.Duration = s.now().Sub(start)
+				return stats, fmt.Errorf("check search vectors: %w", err)
+			}
+			checked, stale, last = batch.Checked, batch.Stale, batch.LastID
+		} else {
+			batch, err := s.queries.RebuildSearchVectorBatch(ctx, db.RebuildSearchVectorBatchParams{AfterID: after, BatchSize: int32(opts.BatchSize)})
+			if err != nil {
+				stats.Duration = s.now().Sub(start)
+				return stats, fmt.Errorf("rebuild search vectors: %w", err)
+			}
+			checked, stale, last = batch.Checked, batch.Stale, batch.LastID
+		}
+
+		stats.Checked += checked
+		stats.Stale += stale
+		if opts.Progress != nil && checked > 0 {
+			opts.Progress(Progress{Checked: stats.Checked, Total: total, Stale: stats.Stale})
+		}
+		if checked < int64(opts.BatchSize) || !last.Valid {
+			break
+		}
+		after = last
+	}
+
+	stats.Duration = s.now().Sub(start)
+	return stats, nil
+}
+
+// checkTriggers confirms the search triggers and index exist and switches
+// disabled triggers back on, returning how many it enabled (or would
+// enable, in a dry run).
+func (s *Service) checkTriggers(ctx context.Context, dryRun bool) (int, error) {
+	expected, err := s.expected()
+	if err != nil {
+		return 0, fmt.Errorf("read expected schema: %w", err)
+	}
+	triggers, indexes := searchObjects(expected)
+
+	names := make([]string, 0, len(triggers))
+	for _, trigger := range triggers {
+		names = append(names, trigger.Name)
+	}
+	states, err := s.queries.ListTriggerStates(ctx, names)
+	if err != nil {
+		return 0, fmt.Errorf("list search triggers: %w", err)
+	}
+	found := make(map[string]db.ListTriggerStatesRow, len(states))
+	for _, state := range states {
+		found[state.TableName+"."+state.TriggerName] = state
+	}
+
+	indexNames := make([]string, 0, len(indexes))
+	for _, index := range indexes {
+		indexNames = append(indexNames, index.Name)
+	}
+	present, err := s.queries.ListIndexesByName(ctx, indexNames)
+	if err != nil {
+		return 0, fmt.Errorf("list search indexes: %w", err)
+	}
+
+	var missing []string
+	var disabled []schema.Object
+	for _, trigger := range triggers {
+		state, ok := found[trigger.Table+"."+trigger.Name]
+		switch {
+		case !ok:
+			missing = append(missing, fmt.Sprintf("trigger %q on table %q", trigger.Name, trigger.Table))
+		case !state.Enabled:
+			disabled = append(disabled, trigger)
+		}
+	}
+	for _, index := range indexes {
+		if !slices.Contains(present, index.Name) {
+			missing = append(missing, fmt.Sprintf("index %q on table %q", index.Name, index.Table))
+		}
+	}
+	if len(missing) > 0 {
+		return 0, fmt.Errorf("database schema missing %s; run verify-schema --fix --apply first", strings.Join(missing, ", "))
+	}
+
+	if dryRun {
+		return len(disabled), nil
+	}
+	var errs []error
+	enabled := 0
+	for _, trigger := range disabled {
+		if _, err := s.pool.Exec(ctx, fmt.Sprintf("ALTER TABLE %s ENABLE TRIGGER %s",
+			pgx.Identifier{trigger.Table}.Sanitize(), pgx.Identifier{trigger.Name}.Sanitize())); err != nil {
+			errs = append(errs, fmt.Errorf("enable trigger %q on table %q: %w", trigger.Name, trigger.Table, err))
+			continue
+		}
+		enabled++
+	}
+	return enabled, errors.Join(errs...)
+}
+
+// searchObjects picks the triggers and index that maintain and serve
+// links.search_tsv out of the schema the migrations build.
+func searchObjects(expected *schema.Expected) (triggers, indexes []schema.Object) {
+	for _, trigger := range expected.Triggers {
+		if strings.Contains(trigger.Name, "search") {
+			triggers = append(triggers, trigger)
+		}
+	}
+	for _, index := range expected.Indexes {
+		if strings.Contains(index.Name, "search") {
+			indexes = append(indexes, index)
+		}
+	}
+	return triggers, indexes
+}
@@ -0,0 +1,209 @@
+package searchindex
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"github.com/example/keepstack/apps/api/internal/db"
+	"github.com/example/keepstack/apps/api/internal/schema"
+	"github.com/example/keepstack/db/migrations"
+)
+
+type batch struct {
+	checked, stale int64
+}
+
+type fakeQueries struct {
+	links    int64
+	batches  []batch
+	triggers []db.ListTriggerStatesRow
+	indexes  []string
+
+	afters       []pgtype.UUID
+	checkCalls   int
+	rebuildCalls int
+}
+
+func (f *fakeQueries) CountLinksForSearchIndex(context.Context) (int64, error) {
+	return f.links, nil
+}
+
+func (f *fakeQueries) next(after pgtype.UUID) (int64, int64, pgtype.UUID) {
+	f.afters = append(f.afters, after)
+	if len(f.batches) == 0 {
+		return 0, 0, pgtype.UUID{}
+	}
+	b := f.batches[0]
+	f.batches = f.batches[1:]
+	return b.checked, b.stale, pgtype.UUID{Bytes: uuid.New(), Valid: b.checked > 0}
+}
+
+func (f *fakeQueries) CheckSearchVectorBatch(_ context.Context, arg db.CheckSearchVectorBatchParams) (db.CheckSearchVectorBatchRow, error) {
+	f.checkCalls++
+	checked, stale, last := f.next(arg.AfterID)
+	return db.CheckSearchVectorBatchRow{Checked: checked, Stale: stale, LastID: last}, nil
+}
+
+func (f *fakeQueries) RebuildSearchVectorBatch(_ context.Context, arg db.RebuildSearchVectorBatchParams) (db.RebuildSearchVectorBatchRow, error) {
+	f.rebuildCalls++
+	checked, stale, last := f.next(arg.AfterID)
+	return db.RebuildSearchVectorBatchRow{Checked: checked, Stale: stale, LastID: last}, nil
+}
+
+func (f *fakeQueries) ListTriggerStates(context.Context, []string) ([]db.ListTriggerStatesRow, error) {
+	return f.triggers, nil
+}
+
+func (f *fakeQueries) ListIndexesByName(context.Context, []string) ([]string, error) {
+	return f.indexes, nil
+}
+
+type fakePool struct {
+	statements []string
+}
+
+func (p *fakePool) Exec(_ context.Context, sql string, _ ...any) (pgconn.CommandTag, error) {
+	p.statements = append(p.statements, sql)
+	return pgconn.NewCommandTag("ALTER TABLE"), nil
+}
+
+func healthyQueries() *fakeQueries {
+	return &fakeQueries{
+		triggers: []db.ListTriggerStatesRow{
+			{TableName: "archives", TriggerName: "archives_refresh_link_search_trigger", Enabled: true},
+			{TableName: "links", TriggerName: "links_search_tsv_update_trigger", Enabled: true},
+		},
+		indexes: []string{"links_search_tsv_idx"},
+	}
+}
+
+func newTestService(q *fakeQueries, pool *fakePool) *Service {
+	now := time.Date(2024, time.June, 1, 0, 0, 0, 0, time.UTC)
+	return &Service{
+		pool:    pool,
+		queries: q,
+		expected: func() (*schema.Expected, error) {
+			return schema.ExpectedFromMigrations(migrations.FS)
+		},
+		now: func() time.Time { return now },
+	}
+}
+
+func TestRunRebuildsInBatches(t *testing.T) {
+	q := healthyQueries()
+	q.links = 7
+	q.batches = []batch{{checked: 3, stale: 2}, {checked: 3, stale: 0}, {checked: 1, stale: 1}}
+	pool := &fakePool{}
+
+	var progress []Progress
+	stats, err := newTestService(q, pool).Run(context.Background(), Options{
+		BatchSize: 3,
+		Progress:  func(p Progress) { progress = append(progress, p) },
+	})
+	if err != nil {
+		t.Fatalf("Run returned error: %v", err)
+	}
+	if stats.Checked != 7 || stats.Stale != 3 || stats.TriggersEnabled != 0 {
+		t.Fatalf("unexpected stats: %+v", stats)
+	}
+	if q.rebuildCalls != 3 || q.checkCalls != 0 {
+		t.Fatalf("expected 3 rebuild batches and no checks, got %d and %d", q.rebuildCalls, q.checkCalls)
+	}
+	if q.afters[0].Valid {
+		t.Fatal("expected the first batch to start from the beginning")
+	}
+	if !q.afters[1].Valid || !q.afters[2].Valid || q.afters[1] == q.afters[2] {
+		t.Fatalf("expected each batch to continue after the previous one, got %v", q.afters)
+	}
+	want := []Progress{{Checked: 3, Total: 7, Stale: 2}, {Checked: 6, Total: 7, Stale: 2}, {Checked: 7, Total: 7, Stale: 3}}
+	if len(progress) != len(want) {
+		t.Fatalf("expected %d progress reports, got %+v", len(want), progress)
+	}
+	for i := range want {
+		if progress[i] != want[i] {
+			t.Fatalf("progress %d: expected %+v, got %+v", i, want[i], progress[i])
+		}
+	}
+	if len(pool.statements) != 0 {
+		t.Fatalf("expected no DDL with healthy triggers, got %v", pool.statements)
+	}
+}
+
+func TestRunEnablesDisabledTriggers(t *testing.T) {
+	q := healthyQueries()
+	q.triggers[1].Enabled = false
+	pool := &fakePool{}
+
+	stats, err := newTestService(q, pool).Run(context.Background(), Options{})
+	if err != nil {
+		t.Fatalf("Run returned error: %v", err)
+	}
+	if stats.TriggersEnabled != 1 {
+		t.Fatalf("expected 1 trigger enabled, got %d", stats.TriggersEnabled)
+	}
+	want := `ALTER TABLE "links" ENABLE TRIGGER "links_search_tsv_update_trigger"`
+	if len(pool.statements) != 1 || pool.statements[0] != want {
+		t.Fatalf("expected %q, got %v", want, pool.statements)
+	}
+}
+
+func TestRunDryRunChangesNothing(t *testing.T) {
+	q := healthyQueries()
+	q.triggers[0].Enabled = false
+	q.batches = []batch{{checked: 2, stale: 2}}
+	pool := &fakePool{}
+
+	stats, err := newTestService(q, pool).Run(context.Background(), Options{BatchSize: 10, DryRun: true})
+	if err != nil {
+		t.Fatalf("Run returned error: %v", err)
+	}
+	if stats.Stale != 2 || stats.TriggersEnabled != 1 {
+		t.Fatalf("expected 2 stale vectors and 1 disabled trigger reported, got %+v", stats)
+	}
+	if q.rebuildCalls != 0 || q.checkCalls != 1 || len(pool.statements) != 0 {
+		t.Fatalf("dry run changed something: %d rebuilds, statements %v", q.rebuildCalls, pool.statements)
+	}
+}
+
+func TestRunFailsWhenTriggerMissing(t *testing.T) {
+	q := healthyQueries()
+	q.triggers = q.triggers[:1]
+	q.indexes = nil
+
+	_, err := newTestService(q, &fakePool{}).Run(context.Background(), Options{})
+	if err == nil {
+		t.Fatal("expected a missing trigger to fail the run")
+	}
+	for _, name := range []string{"links_search_tsv_update_trigger", "links_search_tsv_idx", "verify-schema"} {
+		if !strings.Contains(err.Error(), name) {
+			t.Fatalf("expected the error to mention %s, got %v", name, err)
+		}
+	}
+	if q.rebuildCalls != 0 {
+		t.Fatal("expected nothing rebuilt without the trigger")
+	}
+}
+
+func TestSearchObjectsMatchMigrations(t *testing.T) {
+	expected, err := schema.ExpectedFromMigrations(migrations.FS)
+	if err != nil {
+		t.Fatal(err)
+	}
+	triggers, indexes := searchObjects(expected)
+
+	var names []string
+	for _, object := range append(triggers, indexes...) {
+		names = append(names, object.Table+"."+object.Name)
+	}
+	got := strings.Join(names, " ")
+	want := "links.links_search_tsv_update_trigger archives.archives_refresh_link_search_trigger links.links_search_tsv_idx"
+	if got != want {
+		t.Fatalf("expected %s, got %s", want, got)
+	}
+}
@@ -0,0 +1,131 @@
+package seed
+
+import "time"
+
+const day = 24 * time.Hour
+
+// datasets holds the links seeded per environment. "dev" covers every view
+// the web app has: unread, read, favorite, archived, pending, highlighted,
+// and recommended links. "smoke" is the small known state the smoke suite
+// starts from.
+var datasets = map[string]Dataset{
+	"dev": {
+		Name: "dev",
+		Links: []Link{
+			{
+				URL:      "https://go.dev/blog/errors-are-values",
+				Title:    "Errors are values",
+				Favorite: true,
+				Read:     true,
+				Age:      21 * day,
+				Archive: &Archive{
+					Title:  "Errors are values",
+					Byline: "Rob Pike",
+					Lang:   "en",
+					Text:   "A common point of discussion among Go programmers, especially those new to the language, is how to handle errors. Values can be programmed, and since errors are values, errors can be programmed.",
+				},
+				Tags: []string{"go", "programming"},
+				Highlights: []Highlight{
+					{Quote: "Values can be programmed, and since errors are values, errors can be programmed.", Annotation: "The whole post in one line."},
+				},
+				Score: 40,
+			},
+			{
+				URL:   "https://www.postgresql.org/docs/current/textsearch-intro.html",
+				Title: "Introduction to full text search",
+				Age:   9 * day,
+				Archive: &Archive{
+					Title: "12.1. Introduction",
+					Lang:  "en",
+					Text:  "Full Text Searching provides the capability to identify natural-language documents that satisfy a query, and optionally to sort them by relevance to the query.",
+				},
+				Tags: []string{"postgres", "search"},
+				Highlights: []Highlight{
+					{Quote: "optionally to sort them by relevance to the query"},
+				},
+				Score: 85,
+			},
+			{
+				URL:      "https://martinfowler.com/articles/feature-toggles.html",
+				Title:    "Feature Toggles (aka Feature Flags)",
+				Favorite: true,
+				Age:      5 * day,
+				Archive: &Archive{
+					Title:  "Feature Toggles (aka Feature Flags)",
+					Byline: "Pete Hodgson",
+					Lang:   "en",
+					Text:   "Feature Toggles are a powerful technique, allowing teams to modify system behavior without changing code. They fall into various usage categories.",
+				},
+				Tags:  []string{"architecture"},
+				Score: 70,
+			},
+			{
+				URL:   "https://sre.google/sre-book/monitoring-distributed-systems/",
+				Title: "Monitoring Distributed Systems",
+				Read:  true,
+				Age:   3 * day,
+				Archive: &Archive{
+					Title:  "Monitoring Distributed Systems",
+					Byline: "Rob Ewaschuk",
+					Lang:   "en",
+					Text:   "The four golden signals of monitoring are latency, traffic, errors, and saturation. If you can only measure four metrics of your user-facing system, focus on these four.",
+				},
+				Tags: []string{"operations", "observability"},
+				Highlights: []Highlight{
+					{Quote: "latency, traffic, errors, and saturation", Annotation: "Golden signals."},
+					{Quote: "focus on these four"},
+				},
+				Score: 55,
+			},
+			{
+				URL:   "https://kubernetes.io/docs/concepts/workloads/pods/",
+				Title: "Pods",
+				Age:   day,
+				Archive: &Archive{
+					Title: "Pods",
+					Lang:  "en",
+					Text:  "Pods are the smallest deployable units of computing that you can create and manage in Kubernetes.",
+				},
+				Tags: []string{"kubernetes", "operations"},
+			},
+			{
+				URL:   "https://example.com/articles/still-fetching",
+				Title: "https://example.com/articles/still-fetching",
+				Age:   time.Hour,
+			},
+		},
+	},
+	"smoke": {
+		Name: "smoke",
+		Links: []Link{
+			{
+				URL:   "https://example.com/smoke/archived",
+				Title: "Smoke archived link",
+				Age:   2 * day,
+				Archive: &Archive{
+					Title: "Smoke archived link",
+					Lang:  "en",
+					Text:  "keepstack smoke fixture with a highlight and a recommendation.",
+				},
+				Tags: []string{"smoke"},
+				Highlights: []Highlight{
+					{Quote: "keepstack smoke fixture"},
+				},
+				Score: 50,
+			},
+			{
+				URL:      "https://example.com/smoke/favorite",
+				Title:    "Smoke favorite link",
+				Favorite: true,
+				Read:     true,
+				Age:      day,
+				Archive: &Archive{
+					Title: "Smoke favorite link",
+					Lang:  "en",
+					Text:  "keepstack smoke fixture that is read and marked favorite.",
+				},
+				Tags: []string{"smoke"},
+			},
+		},
+	},
+}
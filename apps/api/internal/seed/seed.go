@@ -0,0 +1,280 @@
+// Package seed loads a known set of links into a database: the dev user and,
+// per environment, links with archives, tags, highlights, and
+// recommendations. Rows get fixed IDs so seeding twice changes nothing and
+// --reset can remove exactly what an earlier run created.
+package seed
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// DevUserEmail and devUserPasswordHash match the user migration 000005
+// creates, so seeding a database that skipped it yields the same login.
+const (
+	DevUserEmail        = "dev@example.com"
+	devUserPasswordHash = "$2a$12$w29oCFhGu3E7yBRLBXjg5eQqr8RP4eAbOeXtfLOcAfeUeawuO/HEa"
+)
+
+var (
+	// defaultDevUserID is the ID migration 000005 gives the dev user.
+	defaultDevUserID = uuid.MustParse("00000000-0000-0000-0000-000000000001")
+	// namespace derives the fixed IDs of seeded rows.
+	namespace = uuid.MustParse("6b1f3c8e-2f4a-5d7b-9c0e-4a6f8b2d1e3c")
+)
+
+// Link is one seeded link. A nil Archive leaves the link unarchived, as if
+// the worker had not reached it yet; a zero Score gives it no
+// recommendation.
+type Link struct {
+	URL        string
+	Title      string
+	Favorite   bool
+	Read       bool
+	Age        time.Duration
+	Archive    *Archive
+	Tags       []string
+	Highlights []Highlight
+	Score      int
+}
+
+// Archive is the extracted content of a seeded link.
+type Archive struct {
+	Title  string
+	Byline string
+	Lang   string
+	Text   string
+}
+
+// Highlight is a seeded highlight.
+type Highlight struct {
+	Quote      string
+	Annotation string
+}
+
+// Dataset is the set of links seeded for one environment.
+type Dataset struct {
+	Name  string
+	Links []Link
+}
+
+// Summary counts what a run inserted. Rows that already existed are not
+// counted.
+type Summary struct {
+	Removed         int64
+	UserCreated     bool
+	Links           int64
+	Archives        int64
+	Tags            int64
+	Highlights      int64
+	Recommendations int64
+}
+
+func (s Summary) String() string {
+	return fmt.Sprintf("removed %d link(s); created user %t, %d link(s), %d archive(s), %d tag(s), %d highlight(s), %d recommendation(s)",
+		s.Removed, s.UserCreated, s.Links, s.Archives, s.Tags, s.Highlights, s.Recommendations)
+}
+
+// Options controls a run.
+type Options struct {
+	// UserID owns the seeded links. It is created as the dev user when
+	// missing.
+	UserID uuid.UUID
+	// Reset deletes the dataset's links, and with them their archives,
+	// highlights, tag assignments, and recommendations, before seeding again.
+	Reset bool
+}
+
+type execer interface {
+	Exec(context.Context, string, ...any) (pgconn.CommandTag, error)
+	QueryRow(context.Context, string, ...any) pgx.Row
+}
+
+// Lookup returns the dataset for environment name.
+func Lookup(name string) (Dataset, error) {
+	dataset, ok := datasets[name]
+	if !ok {
+		return Dataset{}, fmt.Errorf("unknown environment %q (available: %s)", name, strings.Join(Names(), ", "))
+	}
+	return dataset, nil
+}
+
+// Names lists the environments with a dataset.
+func Names() []string {
+	names := make([]string, 0, len(datasets))
+	for name := range datasets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// LinkID is the fixed ID a dataset gives the link saved from rawURL.
+func LinkID(rawURL string) uuid.UUID {
+	return uuid.NewSHA1(namespace, []byte("link:"+rawURL))
+}
+
+// Run seeds dataset. Call it inside a transaction so a failure leaves
+// nothing half seeded.
+func Run(ctx context.Context, tx execer, dataset Dataset, opts Options) (Summary, error) {
+	var summary Summary
+
+	if opts.Reset {
+		ids := make([]uuid.UUID, 0, len(dataset.Links))
+		for _, link := range dataset.Links {
+			ids = append(ids, LinkID(link.URL))
+		}
+		tag, err := tx.Exec(ctx, `DELETE FROM links WHERE id = ANY($1)`, ids)
+		if err != nil {
+			return summary, fmt.Errorf("reset links: %w", err)
+		}
+		summary.Removed = tag.RowsAffected()
+	}
+
+	created, err := EnsureDevUser(ctx, tx, opts.UserID)
+	if err != nil {
+		return summary, err
+	}
+	summary.UserCreated = created
+
+	now := time.Now().UTC()
+	for _, link := range dataset.Links {
+		if err := seedLink(ctx, tx, opts.UserID, link, now, &summary); err != nil {
+			return summary, fmt.Errorf("seed %s: %w", link.URL, err)
+		}
+	}
+	return summary, nil
+}
+
+// EnsureDevUser creates the user with userID unless it exists, so the API
+// has someone to save links for on a database nobody has set up by hand. The
+// default dev user gets DevUserEmail; any other ID gets an address derived
+// from it, since DevUserEmail already belongs to the user migration 000005
+// created.
+func EnsureDevUser(ctx context.Context, db execer, userID uuid.UUID) (bool, error) {
+	tag, err := db.Exec(ctx, `INSERT INTO users (id, email, password_hash) VALUES ($1, $2, $3) ON CONFLICT DO NOTHING`,
+		userID, devUserEmail(userID), devUserPasswordHash)
+	if err != nil {
+		return false, fmt.Errorf("create dev user: %w", err)
+	}
+	if tag.RowsAffected() == 1 {
+		return true, nil
+	}
+
+	var exists bool
+	if err := db.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM users WHERE id = $1)`, userID).Scan(&exists); err != nil {
+		return false, fmt.Errorf("look up dev user: %w", err)
+	}
+	if !exists {
+		return false, fmt.Errorf("dev user %s missing and %s belongs to another user", userID, devUserEmail(userID))
+	}
+	return false, nil
+}
+
+// EnsureTag creates userID's tag name unless it exists and reports whether
+// it did.
+func EnsureTag(ctx context.Context, db execer, userID uuid.UUID, name string) (bool, error) {
+	tag, err := db.Exec(ctx, `INSERT INTO tags (user_id, name) VALUES ($1, $2) ON CONFLICT (user_id, name) DO NOTHING`, userID, name)
+	if err != nil {
+		return false, fmt.Errorf("insert tag %q: %w", name, err)
+	}
+	return tag.RowsAffected() == 1, nil
+}
+
+func devUserEmail(userID uuid.UUID) string {
+	if userID == defaultDevUserID {
+		return DevUserEmail
+	}
+	return fmt.Sprintf("dev+%s@example.com", userID)
+}
+
+func seedLink(ctx context.Context, tx execer, userID uuid.UUID, link Link, now time.Time, summary *Summary) error {
+	id := LinkID(link.URL)
+	createdAt := now.Add(-link.Age)
+	var readAt *time.Time
+	if link.Read {
+		readAt = &createdAt
+	}
+
+	tag, err := tx.Exec(ctx, `INSERT INTO links (id, user_id, url, title, source_domain, favorite, read_at, created_at)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+ON CONFLICT (id) DO NOTHING`,
+		id, userID, link.URL, link.Title, SourceDomain(link.URL), link.Favorite, readAt, createdAt)
+	if err != nil {
+		return fmt.Errorf("insert link: %w", err)
+	}
+	summary.Links += tag.RowsAffected()
+
+	if archive := link.Archive; archive != nil {
+		tag, err := tx.Exec(ctx, `INSERT INTO archives (link_id, html, extracted_text, title, byline, lang, word_count)
+VALUES ($1, $2, $3, $4, $5, $6, $7)
+ON CONFLICT (link_id) DO NOTHING`,
+			id, "<article><p>"+archive.Text+"</p></article>", archive.Text, archive.Title, archive.Byline, archive.Lang, len(strings.Fields(archive.Text)))
+		if err != nil {
+			return fmt.Errorf("insert archive: %w", err)
+		}
+		summary.Archives += tag.RowsAffected()
+
+		if _, err := tx.Exec(ctx, `INSERT INTO link_ingest_status (link_id, status) VALUES ($1, 'ingested') ON CONFLICT (link_id) DO NOTHING`, id); err != nil {
+			return fmt.Errorf("insert ingest status: %w", err)
+		}
+	}
+
+	for _, name := range link.Tags {
+		created, err := EnsureTag(ctx, tx, userID, name)
+		if err != nil {
+			return err
+		}
+		if created {
+			summary.Tags++
+		}
+
+		if _, err := tx.Exec(ctx, `INSERT INTO link_tags (link_id, tag_id)
+SELECT $1, id FROM tags WHERE user_id = $2 AND name = $3
+ON CONFLICT DO NOTHING`, id, userID, name); err != nil {
+			return fmt.Errorf("tag link %q: %w", name, err)
+		}
+	}
+
+	for _, highlight := range link.Highlights {
+		var annotation *string
+		if highlight.Annotation != "" {
+			annotation = &highlight.Annotation
+		}
+		tag, err := tx.Exec(ctx, `INSERT INTO highlights (id, link_id, quote, annotation)
+VALUES ($1, $2, $3, $4)
+ON CONFLICT (id) DO NOTHING`,
+			uuid.NewSHA1(namespace, []byte("highlight:"+link.URL+"#"+highlight.Quote)), id, highlight.Quote, annotation)
+		if err != nil {
+			return fmt.Errorf("insert highlight: %w", err)
+		}
+		summary.Highlights += tag.RowsAffected()
+	}
+
+	if link.Score > 0 {
+		tag, err := tx.Exec(ctx, `INSERT INTO recommendations (link_id, score) VALUES ($1, $2) ON CONFLICT (link_id) DO NOTHING`, id, link.Score)
+		if err != nil {
+			return fmt.Errorf("insert recommendation: %w", err)
+		}
+		summary.Recommendations += tag.RowsAffected()
+	}
+	return nil
+}
+
+// SourceDomain mirrors what the worker stores once it ingests a link: the
+// lowercased host without a leading "www.".
+func SourceDomain(raw string) string {
+	parsed, err := url.Parse(raw)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimPrefix(strings.ToLower(parsed.Host), "www.")
+}
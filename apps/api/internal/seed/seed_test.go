@@ -0,0 +1,129 @@
+package seed
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+func TestDatasetsUseUniqueURLs(t *testing.T) {
+	t.Parallel()
+
+	for _, name := range Names() {
+		dataset, err := Lookup(name)
+		if err != nil {
+			t.Fatalf("Lookup(%q): %v", name, err)
+		}
+		if len(dataset.Links) == 0 {
+			t.Errorf("%s dataset has no links", name)
+		}
+		seen := map[uuid.UUID]string{}
+		for _, link := range dataset.Links {
+			id := LinkID(link.URL)
+			if other, ok := seen[id]; ok {
+				t.Errorf("%s dataset repeats %s (also %s)", name, link.URL, other)
+			}
+			seen[id] = link.URL
+			if SourceDomain(link.URL) == "" {
+				t.Errorf("%s dataset link %q has no host", name, link.URL)
+			}
+		}
+	}
+}
+
+func TestLookupUnknownEnvironment(t *testing.T) {
+	t.Parallel()
+
+	_, err := Lookup("prod")
+	if err == nil || !strings.Contains(err.Error(), "dev, smoke") {
+		t.Fatalf("Lookup(prod) err = %v, want one listing dev, smoke", err)
+	}
+}
+
+func TestSourceDomain(t *testing.T) {
+	t.Parallel()
+
+	for raw, want := range map[string]string{
+		"https://www.PostgreSQL.org/docs/": "postgresql.org",
+		"https://go.dev/blog":              "go.dev",
+		"not a url":                        "",
+	} {
+		if got := SourceDomain(raw); got != want {
+			t.Errorf("SourceDomain(%q) = %q, want %q", raw, got, want)
+		}
+	}
+}
+
+func TestRunSeedsEveryRowOnce(t *testing.T) {
+	t.Parallel()
+
+	dataset := Dataset{Name: "test", Links: []Link{
+		{
+			URL:        "https://example.com/a",
+			Title:      "A",
+			Archive:    &Archive{Title: "A", Lang: "en", Text: "one two three"},
+			Tags:       []string{"x", "y"},
+			Highlights: []Highlight{{Quote: "two", Annotation: "note"}},
+			Score:      10,
+		},
+		{URL: "https://example.com/b", Title: "B"},
+	}}
+	tx := &recordingTx{}
+	summary, err := Run(context.Background(), tx, dataset, Options{UserID: uuid.New(), Reset: true})
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+
+	want := Summary{Removed: 1, UserCreated: true, Links: 2, Archives: 1, Tags: 2, Highlights: 1, Recommendations: 1}
+	if summary != want {
+		t.Fatalf("summary = %+v, want %+v", summary, want)
+	}
+	if !strings.HasPrefix(tx.statements[0], "DELETE FROM links") {
+		t.Fatalf("first statement = %q, want the reset", tx.statements[0])
+	}
+	ids := tx.args[0][0].([]uuid.UUID)
+	if len(ids) != 2 || ids[0] != LinkID("https://example.com/a") || ids[1] != LinkID("https://example.com/b") {
+		t.Fatalf("reset ids = %v", ids)
+	}
+}
+
+type recordingTx struct {
+	statements []string
+	args       [][]any
+}
+
+func (r *recordingTx) Exec(_ context.Context, sql string, args ...any) (pgconn.CommandTag, error) {
+	r.statements = append(r.statements, sql)
+	r.args = append(r.args, args)
+	if strings.HasPrefix(sql, "DELETE") {
+		return pgconn.NewCommandTag("DELETE 1"), nil
+	}
+	return pgconn.NewCommandTag("INSERT 0 1"), nil
+}
+
+func (r *recordingTx) QueryRow(context.Context, string, ...any) pgx.Row {
+	panic("QueryRow called although the user was created")
+}
+
+func TestEnsureDevUserEmail(t *testing.T) {
+	t.Parallel()
+
+	custom := uuid.MustParse("11111111-2222-3333-4444-555555555555")
+	for userID, want := range map[uuid.UUID]string{
+		defaultDevUserID: DevUserEmail,
+		custom:           "dev+11111111-2222-3333-4444-555555555555@example.com",
+	} {
+		tx := &recordingTx{}
+		created, err := EnsureDevUser(context.Background(), tx, userID)
+		if err != nil || !created {
+			t.Fatalf("EnsureDevUser(%s) = %t, %v", userID, created, err)
+		}
+		if got := tx.args[0][1]; got != want {
+			t.Errorf("EnsureDevUser(%s) email = %v, want %s", userID, got, want)
+		}
+	}
+}
@@ -0,0 +1,69 @@
+// Package startup lets the API accept connections before its dependencies
+// are up. A Gate answers probes while the database, NATS, and migrations are
+// awaited, so Kubernetes sees a live pod that is not yet ready instead of a
+// crashlooping one, and hands every request to the real router once opened.
+package startup
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync/atomic"
+)
+
+// retryAfterSeconds is the Retry-After sent with requests held by the gate.
+const retryAfterSeconds = "5"
+
+// Gate is an http.Handler that serves placeholder responses until Open.
+// While closed, /livez reports ok, /healthz reports the pod as starting, and
+// every other route answers 503 naming the dependency being waited for.
+type Gate struct {
+	handler    atomic.Pointer[http.Handler]
+	waitingFor atomic.Pointer[string]
+}
+
+// NewGate returns a closed gate.
+func NewGate() *Gate {
+	g := &Gate{}
+	g.Wait("startup")
+	return g
+}
+
+// Wait records the dependency startup is blocked on, reported in the
+// placeholder responses.
+func (g *Gate) Wait(dependency string) {
+	g.waitingFor.Store(&dependency)
+}
+
+// Open sends every request from now on to handler.
+func (g *Gate) Open(handler http.Handler) {
+	g.handler.Store(&handler)
+}
+
+func (g *Gate) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if handler := g.handler.Load(); handler != nil {
+		(*handler).ServeHTTP(w, r)
+		return
+	}
+
+	waitingFor := *g.waitingFor.Load()
+	switch r.URL.Path {
+	case "/livez", "/api/livez":
+		writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+	case "/healthz", "/api/healthz":
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"status": "starting", "waiting_for": waitingFor})
+	default:
+		w.Header().Set("Retry-After", retryAfterSeconds)
+		// The API's error envelope, so clients handle this like any 503.
+		writeJSON(w, http.StatusServiceUnavailable, map[string]any{
+			"code":    "unavailable",
+			"message": "service starting",
+			"details": map[string]string{"waiting_for": waitingFor},
+		})
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
@@ -0,0 +1,48 @@
+package startup
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestGateHoldsRoutesUntilOpen(t *testing.T) {
+	t.Parallel()
+
+	gate := NewGate()
+	gate.Wait("nats")
+
+	do := func(path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		gate.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		return rec
+	}
+
+	if rec := do("/livez"); rec.Code != http.StatusOK {
+		t.Fatalf("expected /livez to pass while starting, got %d", rec.Code)
+	}
+	rec := do("/api/healthz")
+	if rec.Code != http.StatusServiceUnavailable || !strings.Contains(rec.Body.String(), `"status":"starting"`) {
+		t.Fatalf("expected /api/healthz to report starting, got %d %s", rec.Code, rec.Body)
+	}
+	rec = do("/api/links")
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected /api/links to be held, got %d", rec.Code)
+	}
+	if got := rec.Header().Get("Retry-After"); got != retryAfterSeconds {
+		t.Fatalf("expected Retry-After %s, got %q", retryAfterSeconds, got)
+	}
+	if body := rec.Body.String(); body != "{\"code\":\"unavailable\",\"details\":{\"waiting_for\":\"nats\"},\"message\":\"service starting\"}\n" {
+		t.Fatalf("unexpected body %q", body)
+	}
+
+	gate.Open(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	}))
+	for _, path := range []string{"/livez", "/api/healthz", "/api/links"} {
+		if rec := do(path); rec.Code != http.StatusTeapot {
+			t.Fatalf("expected %s to reach the router once open, got %d", path, rec.Code)
+		}
+	}
+}
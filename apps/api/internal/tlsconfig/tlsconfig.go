@@ -0,0 +1,162 @@
+// Package tlsconfig builds the TLS configuration the API serves HTTPS with,
+// for small deployments that run it without a reverse proxy in front. The
+// certificate comes either from PEM files, re-read when they change so a
+// renewed certificate is picked up without a restart, or from an ACME CA
+// such as Let's Encrypt through autocert.
+package tlsconfig
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/acme"
+	"golang.org/x/crypto/acme/autocert"
+)
+
+// Options selects where certificates come from. Set CertFile and KeyFile, or
+// AutocertDomains and AutocertCacheDir, or nothing to serve plain HTTP.
+type Options struct {
+	CertFile string
+	KeyFile  string
+
+	// AutocertDomains are the hosts certificates are requested for. The CA
+	// must reach the server on one of them at port 443, or at port 80 through
+	// the handler New returns.
+	AutocertDomains []string
+	// AutocertCacheDir keeps issued certificates across restarts so they are
+	// not requested again, which CAs rate limit.
+	AutocertCacheDir string
+	// AutocertEmail is given to the CA for expiry notices. Optional.
+	AutocertEmail string
+	// AutocertDirectoryURL overrides the CA, e.g. with Let's Encrypt's
+	// staging directory while testing. Empty uses Let's Encrypt.
+	AutocertDirectoryURL string
+}
+
+// Enabled reports whether opts configures TLS.
+func (opts Options) Enabled() bool {
+	return opts.CertFile != "" || opts.KeyFile != "" || len(opts.AutocertDomains) > 0
+}
+
+// Validate reports options that cannot be served.
+func (opts Options) Validate() error {
+	files := opts.CertFile != "" || opts.KeyFile != ""
+	switch {
+	case files && (opts.CertFile == "" || opts.KeyFile == ""):
+		return errors.New("TLS_CERT_FILE and TLS_KEY_FILE must be set together")
+	case files && len(opts.AutocertDomains) > 0:
+		return errors.New("TLS_CERT_FILE and TLS_AUTOCERT_DOMAINS are mutually exclusive")
+	case len(opts.AutocertDomains) > 0 && opts.AutocertCacheDir == "":
+		return errors.New("TLS_AUTOCERT_CACHE_DIR is required when TLS_AUTOCERT_DOMAINS is set")
+	}
+	return nil
+}
+
+// New returns the server TLS configuration and the handler to serve on plain
+// HTTP next to it: with autocert it answers the CA's HTTP-01 challenges, and
+// otherwise, as for every other request, it redirects to HTTPS. Both are nil
+// when opts does not enable TLS.
+func New(opts Options) (*tls.Config, http.Handler, error) {
+	if !opts.Enabled() {
+		return nil, nil, nil
+	}
+	if err := opts.Validate(); err != nil {
+		return nil, nil, err
+	}
+
+	if len(opts.AutocertDomains) > 0 {
+		manager := &autocert.Manager{
+			Prompt:     autocert.AcceptTOS,
+			HostPolicy: autocert.HostWhitelist(opts.AutocertDomains...),
+			Cache:      autocert.DirCache(opts.AutocertCacheDir),
+			Email:      opts.AutocertEmail,
+		}
+		if opts.AutocertDirectoryURL != "" {
+			manager.Client = &acme.Client{DirectoryURL: opts.AutocertDirectoryURL}
+		}
+		cfg := manager.TLSConfig()
+		cfg.MinVersion = tls.VersionTLS12
+		return cfg, manager.HTTPHandler(nil), nil
+	}
+
+	reloader := &fileCertificate{certFile: opts.CertFile, keyFile: opts.KeyFile}
+	if _, err := reloader.GetCertificate(nil); err != nil {
+		return nil, nil, err
+	}
+	cfg := &tls.Config{
+		MinVersion:     tls.VersionTLS12,
+		NextProtos:     []string{"h2", "http/1.1"},
+		GetCertificate: reloader.GetCertificate,
+	}
+	return cfg, http.HandlerFunc(redirectToHTTPS), nil
+}
+
+// fileCertificate loads a key pair from disk and loads it again once either
+// file's modification time changes, as when cert-manager or certbot renews
+// it. A renewal that fails to load keeps serving the previous certificate.
+type fileCertificate struct {
+	certFile string
+	keyFile  string
+
+	mu       sync.Mutex
+	cert     *tls.Certificate
+	modTimes [2]time.Time
+}
+
+func (f *fileCertificate) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	modTimes, err := f.stat()
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.cert != nil && (err != nil || modTimes == f.modTimes) {
+		return f.cert, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	cert, err := tls.LoadX509KeyPair(f.certFile, f.keyFile)
+	if err != nil {
+		if f.cert != nil {
+			return f.cert, nil
+		}
+		return nil, fmt.Errorf("load tls key pair: %w", err)
+	}
+	f.cert, f.modTimes = &cert, modTimes
+	return f.cert, nil
+}
+
+func (f *fileCertificate) stat() ([2]time.Time, error) {
+	var modTimes [2]time.Time
+	for i, path := range []string{f.certFile, f.keyFile} {
+		info, err := os.Stat(path)
+		if err != nil {
+			return modTimes, fmt.Errorf("stat %s: %w", path, err)
+		}
+		modTimes[i] = info.ModTime()
+	}
+	return modTimes, nil
+}
+
+// redirectToHTTPS sends plain HTTP requests to the same URL over HTTPS on
+// the default port.
+func redirectToHTTPS(w http.ResponseWriter, r *http.Request) {
+	host := r.Host
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+		if strings.Contains(host, ":") {
+			host = "[" + host + "]"
+		}
+	}
+	if host == "" {
+		http.Error(w, "use https", http.StatusBadRequest)
+		return
+	}
+	http.Redirect(w, r, "https://"+host+r.URL.RequestURI(), http.StatusMovedPermanently)
+}
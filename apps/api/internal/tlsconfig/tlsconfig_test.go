@@ -0,0 +1,149 @@
+package tlsconfig
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestValidate(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		opts    Options
+		wantErr bool
+	}{
+		"disabled":         {opts: Options{}},
+		"files":            {opts: Options{CertFile: "c.pem", KeyFile: "k.pem"}},
+		"autocert":         {opts: Options{AutocertDomains: []string{"a.example"}, AutocertCacheDir: "/cache"}},
+		"cert without key": {opts: Options{CertFile: "c.pem"}, wantErr: true},
+		"files and autocert": {
+			opts:    Options{CertFile: "c.pem", KeyFile: "k.pem", AutocertDomains: []string{"a.example"}, AutocertCacheDir: "/cache"},
+			wantErr: true,
+		},
+		"autocert without cache": {opts: Options{AutocertDomains: []string{"a.example"}}, wantErr: true},
+	}
+	for name, tc := range tests {
+		if err := tc.opts.Validate(); (err != nil) != tc.wantErr {
+			t.Errorf("%s: Validate() = %v, want error %t", name, err, tc.wantErr)
+		}
+	}
+}
+
+func TestNewReloadsRenewedCertificate(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "tls.crt"), filepath.Join(dir, "tls.key")
+	writeKeyPair(t, certFile, keyFile, "first.example", time.Now().Add(-time.Hour))
+
+	cfg, redirect, err := New(Options{CertFile: certFile, KeyFile: keyFile})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	if redirect == nil {
+		t.Fatal("expected a redirect handler")
+	}
+	if got := leafName(t, cfg); got != "first.example" {
+		t.Fatalf("certificate for %q, want first.example", got)
+	}
+
+	writeKeyPair(t, certFile, keyFile, "second.example", time.Now())
+	if got := leafName(t, cfg); got != "second.example" {
+		t.Fatalf("certificate for %q after renewal, want second.example", got)
+	}
+
+	if err := os.WriteFile(certFile, []byte("garbage"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if got := leafName(t, cfg); got != "second.example" {
+		t.Fatalf("certificate for %q after a broken renewal, want the previous one", got)
+	}
+}
+
+func TestNewDisabled(t *testing.T) {
+	t.Parallel()
+
+	cfg, redirect, err := New(Options{})
+	if cfg != nil || redirect != nil || err != nil {
+		t.Fatalf("New(Options{}) = %v, %v, %v; want nothing", cfg, redirect, err)
+	}
+}
+
+func TestRedirectToHTTPS(t *testing.T) {
+	t.Parallel()
+
+	for host, want := range map[string]string{
+		"keepstack.example:80": "https://keepstack.example/api/links?q=go",
+		"keepstack.example":    "https://keepstack.example/api/links?q=go",
+		"[::1]:80":             "https://[::1]/api/links?q=go",
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/api/links?q=go", nil)
+		req.Host = host
+		rec := httptest.NewRecorder()
+		redirectToHTTPS(rec, req)
+		if rec.Code != http.StatusMovedPermanently || rec.Header().Get("Location") != want {
+			t.Errorf("host %s: %d %q, want 301 %q", host, rec.Code, rec.Header().Get("Location"), want)
+		}
+	}
+}
+
+func leafName(t *testing.T, cfg *tls.Config) string {
+	t.Helper()
+	cert, err := cfg.GetCertificate(&tls.ClientHelloInfo{})
+	if err != nil {
+		t.Fatalf("GetCertificate: %v", err)
+	}
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		t.Fatalf("parse certificate: %v", err)
+	}
+	return leaf.Subject.CommonName
+}
+
+// writeKeyPair writes a self-signed certificate for name and stamps both
+// files with modTime, so successive writes differ even on coarse clocks.
+func writeKeyPair(t *testing.T, certFile, keyFile, name string, modTime time.Time) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: name},
+		DNSNames:     []string{name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range []string{certFile, keyFile} {
+		if err := os.Chtimes(path, modTime, modTime); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,167 @@
+// Package vacuum trims archive storage so the database does not grow without
+// bound: it removes archive rows whose link no longer exists and drops raw
+// HTML older than a retention window while keeping the extracted text that
+// search and digests rely on.
+package vacuum
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/example/keepstack/apps/api/internal/db"
+)
+
+// DefaultBatchSize is the number of archives whose HTML is cleared per
+// statement, keeping row locks short on large tables.
+const DefaultBatchSize = 500
+
+type queries interface {
+	CountOrphanedArchives(context.Context) (db.CountOrphanedArchivesRow, error)
+	DeleteOrphanedArchives(context.Context) (db.DeleteOrphanedArchivesRow, error)
+	CountExpiredArchiveHTML(context.Context, pgtype.Timestamptz) (db.CountExpiredArchiveHTMLRow, error)
+	ClearExpiredArchiveHTML(context.Context, db.ClearExpiredArchiveHTMLParams) (db.ClearExpiredArchiveHTMLRow, error)
+}
+
+type maintenancePool interface {
+	Exec(context.Context, string, ...any) (pgconn.CommandTag, error)
+	QueryRow(context.Context, string, ...any) pgx.Row
+}
+
+// Options controls a cleanup run.
+type Options struct {
+	// HTMLRetention clears archives.html for archives last fetched before
+	// now minus this window. Zero keeps HTML forever.
+	HTMLRetention time.Duration
+	// BatchSize caps how many archives are updated per statement.
+	BatchSize int
+	// DryRun reports what would be removed without changing anything.
+	DryRun bool
+}
+
+// Stats summarises a cleanup run. Byte counts are the uncompressed size of
+// the removed values; TableBytesBefore/After are the on-disk size of the
+// archives table (including TOAST and indexes) around the VACUUM.
+type Stats struct {
+	OrphansRemoved   int64
+	OrphanBytes      int64
+	HTMLCleared      int64
+	HTMLBytes        int64
+	TableBytesBefore int64
+	TableBytesAfter  int64
+	Duration         time.Duration
+}
+
+// ReclaimedBytes is the logical size of everything removed.
+func (s Stats) ReclaimedBytes() int64 {
+	return s.OrphanBytes + s.HTMLBytes
+}
+
+// Service runs archive cleanup against Postgres.
+type Service struct {
+	pool    maintenancePool
+	queries queries
+	now     func() time.Time
+}
+
+// New constructs a Service using the provided connection pool.
+func New(pool *pgxpool.Pool) *Service {
+	return &Service{pool: pool, queries: db.New(pool), now: time.Now}
+}
+
+// WithNow overrides the time source. Intended for tests.
+func (s *Service) WithNow(now func() time.Time) {
+	s.now = now
+}
+
+// Run removes orphaned archives, clears expired HTML, and vacuums the table
+// so the freed pages can be reused.
+func (s *Service) Run(ctx context.Context, opts Options) (Stats, error) {
+	start := s.now()
+	var stats Stats
+
+	if opts.BatchSize < 1 {
+		opts.BatchSize = DefaultBatchSize
+	}
+
+	before, err := s.tableSize(ctx)
+	if err != nil {
+		return stats, err
+	}
+	stats.TableBytesBefore = before
+
+	if opts.DryRun {
+		orphans, err := s.queries.CountOrphanedArchives(ctx)
+		if err != nil {
+			return stats, fmt.Errorf("count orphaned archives: %w", err)
+		}
+		stats.OrphansRemoved, stats.OrphanBytes = orphans.Archives, orphans.Bytes
+	} else {
+		orphans, err := s.queries.DeleteOrphanedArchives(ctx)
+		if err != nil {
+			return stats, fmt.Errorf("delete orphaned archives: %w", err)
+		}
+		stats.OrphansRemoved, stats.OrphanBytes = orphans.Archives, orphans.Bytes
+	}
+
+	if opts.HTMLRetention > 0 {
+		cutoff := pgtype.Timestamptz{Time: start.Add(-opts.HTMLRetention), Valid: true}
+		if err := s.clearHTML(ctx, cutoff, opts, &stats); err != nil {
+			return stats, err
+		}
+	}
+
+	stats.TableBytesAfter = before
+	if !opts.DryRun {
+		if _, err := s.pool.Exec(ctx, "VACUUM (ANALYZE) archives"); err != nil {
+			return stats, fmt.Errorf("vacuum archives: %w", err)
+		}
+		after, err := s.tableSize(ctx)
+		if err != nil {
+			return stats, err
+		}
+		stats.TableBytesAfter = after
+	}
+
+	stats.Duration = s.now().Sub(start)
+	return stats, nil
+}
+
+func (s *Service) clearHTML(ctx context.Context, cutoff pgtype.Timestamptz, opts Options, stats *Stats) error {
+	if opts.DryRun {
+		expired, err := s.queries.CountExpiredArchiveHTML(ctx, cutoff)
+		if err != nil {
+			return fmt.Errorf("count expired archive html: %w", err)
+		}
+		stats.HTMLCleared, stats.HTMLBytes = expired.Archives, expired.Bytes
+		return nil
+	}
+
+	for {
+		batch, err := s.queries.ClearExpiredArchiveHTML(ctx, db.ClearExpiredArchiveHTMLParams{
+			Cutoff:    cutoff,
+			BatchSize: int32(opts.BatchSize),
+		})
+		if err != nil {
+			return fmt.Errorf("clear expired archive html: %w", err)
+		}
+		stats.HTMLCleared += batch.Archives
+		stats.HTMLBytes += batch.Bytes
+		if batch.Archives < int64(opts.BatchSize) {
+			return nil
+		}
+	}
+}
+
+func (s *Service) tableSize(ctx context.Context) (int64, error) {
+	var size int64
+	if err := s.pool.QueryRow(ctx, "SELECT pg_total_relation_size('archives')").Scan(&size); err != nil {
+		return 0, fmt.Errorf("measure archives table: %w", err)
+	}
+	return size, nil
+}
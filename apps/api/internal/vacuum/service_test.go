@@ -0,0 +1,133 @@
+package vacuum
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"github.com/example/keepstack/apps/api/internal/db"
+)
+
+type fakeQueries struct {
+	orphans      int64
+	expired      []int64
+	cutoff       pgtype.Timestamptz
+	deleteCalled bool
+	clearCalls   int
+}
+
+func (f *fakeQueries) CountOrphanedArchives(context.Context) (db.CountOrphanedArchivesRow, error) {
+	return db.CountOrphanedArchivesRow{Archives: f.orphans, Bytes: f.orphans * 100}, nil
+}
+
+func (f *fakeQueries) DeleteOrphanedArchives(context.Context) (db.DeleteOrphanedArchivesRow, error) {
+	f.deleteCalled = true
+	return db.DeleteOrphanedArchivesRow{Archives: f.orphans, Bytes: f.orphans * 100}, nil
+}
+
+func (f *fakeQueries) CountExpiredArchiveHTML(_ context.Context, cutoff pgtype.Timestamptz) (db.CountExpiredArchiveHTMLRow, error) {
+	f.cutoff = cutoff
+	var total int64
+	for _, n := range f.expired {
+		total += n
+	}
+	return db.CountExpiredArchiveHTMLRow{Archives: total, Bytes: total * 1000}, nil
+}
+
+func (f *fakeQueries) ClearExpiredArchiveHTML(_ context.Context, arg db.ClearExpiredArchiveHTMLParams) (db.ClearExpiredArchiveHTMLRow, error) {
+	f.cutoff = arg.Cutoff
+	f.clearCalls++
+	if len(f.expired) == 0 {
+		return db.ClearExpiredArchiveHTMLRow{}, nil
+	}
+	n := f.expired[0]
+	f.expired = f.expired[1:]
+	return db.ClearExpiredArchiveHTMLRow{Archives: n, Bytes: n * 1000}, nil
+}
+
+type fakePool struct {
+	sizes    []int64
+	vacuumed bool
+}
+
+func (p *fakePool) Exec(context.Context, string, ...any) (pgconn.CommandTag, error) {
+	p.vacuumed = true
+	return pgconn.NewCommandTag("VACUUM"), nil
+}
+
+func (p *fakePool) QueryRow(context.Context, string, ...any) pgx.Row {
+	size := p.sizes[0]
+	p.sizes = p.sizes[1:]
+	return sizeRow(size)
+}
+
+type sizeRow int64
+
+func (r sizeRow) Scan(dest ...any) error {
+	*dest[0].(*int64) = int64(r)
+	return nil
+}
+
+func TestRunClearsHTMLInBatches(t *testing.T) {
+	now := time.Date(2024, time.June, 1, 0, 0, 0, 0, time.UTC)
+	q := &fakeQueries{orphans: 2, expired: []int64{3, 3, 1}}
+	pool := &fakePool{sizes: []int64{10_000, 4_000}}
+	svc := &Service{pool: pool, queries: q, now: func() time.Time { return now }}
+
+	stats, err := svc.Run(context.Background(), Options{HTMLRetention: 30 * 24 * time.Hour, BatchSize: 3})
+	if err != nil {
+		t.Fatalf("Run returned error: %v", err)
+	}
+
+	if !q.deleteCalled || stats.OrphansRemoved != 2 || stats.OrphanBytes != 200 {
+		t.Fatalf("unexpected orphan stats: %+v", stats)
+	}
+	if q.clearCalls != 3 || stats.HTMLCleared != 7 || stats.HTMLBytes != 7000 {
+		t.Fatalf("expected three batches clearing 7 archives, got %d calls and %+v", q.clearCalls, stats)
+	}
+	if want := now.Add(-30 * 24 * time.Hour); !q.cutoff.Time.Equal(want) {
+		t.Fatalf("unexpected cutoff: got %s want %s", q.cutoff.Time, want)
+	}
+	if !pool.vacuumed || stats.TableBytesBefore != 10_000 || stats.TableBytesAfter != 4_000 {
+		t.Fatalf("unexpected vacuum stats: %+v", stats)
+	}
+	if stats.ReclaimedBytes() != 7200 {
+		t.Fatalf("unexpected reclaimed bytes: %d", stats.ReclaimedBytes())
+	}
+}
+
+func TestRunDryRunOnlyCounts(t *testing.T) {
+	q := &fakeQueries{orphans: 1, expired: []int64{4}}
+	pool := &fakePool{sizes: []int64{5_000}}
+	svc := &Service{pool: pool, queries: q, now: time.Now}
+
+	stats, err := svc.Run(context.Background(), Options{HTMLRetention: time.Hour, DryRun: true})
+	if err != nil {
+		t.Fatalf("Run returned error: %v", err)
+	}
+
+	if q.deleteCalled || q.clearCalls != 0 || pool.vacuumed {
+		t.Fatalf("dry run modified data: delete=%t clear=%d vacuum=%t", q.deleteCalled, q.clearCalls, pool.vacuumed)
+	}
+	if stats.OrphansRemoved != 1 || stats.HTMLCleared != 4 || stats.TableBytesAfter != 5_000 {
+		t.Fatalf("unexpected dry run stats: %+v", stats)
+	}
+}
+
+func TestRunWithoutRetentionKeepsHTML(t *testing.T) {
+	q := &fakeQueries{expired: []int64{5}}
+	pool := &fakePool{sizes: []int64{1, 1}}
+	svc := &Service{pool: pool, queries: q, now: time.Now}
+
+	stats, err := svc.Run(context.Background(), Options{})
+	if err != nil {
+		t.Fatalf("Run returned error: %v", err)
+	}
+	if q.clearCalls != 0 || stats.HTMLCleared != 0 {
+		t.Fatalf("expected html to be kept, got %+v", stats)
+	}
+}
@@ -0,0 +1,159 @@
+// Package wordcount backfills archives.word_count and archives.lang. Archives
+// ingested before the worker recorded them have zeros and no language,
+// which leaves reading time estimates and the resurfacer's length tiers
+// blind to them. Service recomputes both from the stored extracted text in
+// batches, the way the worker does at ingest.
+package wordcount
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/abadojack/whatlanggo"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/example/keepstack/apps/api/internal/db"
+)
+
+// DefaultBatchSize is the number of archives read per statement. Each row
+// carries its full extracted text, so batches stay smaller than the search
+// reindex's.
+const DefaultBatchSize = 200
+
+type queries interface {
+	CountArchivesMissingTextStats(context.Context) (int64, error)
+	ListArchiveTextStatsBatch(context.Context, db.ListArchiveTextStatsBatchParams) ([]db.ListArchiveTextStatsBatchRow, error)
+	UpdateArchiveTextStats(context.Context, db.UpdateArchiveTextStatsParams) error
+}
+
+// Options controls a run.
+type Options struct {
+	// BatchSize caps how many archives are read per statement.
+	BatchSize int
+	// DryRun counts the archives that would change without writing.
+	DryRun bool
+	// Progress, when set, is called after each batch.
+	Progress func(Progress)
+}
+
+// Progress reports how far a run has got. Total is the number of archives
+// missing a word count or language when the run started.
+type Progress struct {
+	Checked int64
+	Total   int64
+	Updated int64
+}
+
+// Stats summarises a run. In a dry run Updated counts what would have been
+// written.
+type Stats struct {
+	Checked int64
+	Updated int64
+	// Words is the number of words added to word counts, which is what
+	// reading time estimates are worked out from.
+	Words    int64
+	Duration time.Duration
+}
+
+// Service recomputes archive text statistics against Postgres.
+type Service struct {
+	queries queries
+	now     func() time.Time
+}
+
+// New constructs a Service using the provided connection pool.
+func New(pool *pgxpool.Pool) *Service {
+	return &Service{queries: db.New(pool), now: time.Now}
+}
+
+// WithNow overrides the time source. Intended for tests.
+func (s *Service) WithNow(now func() time.Time) {
+	s.now = now
+}
+
+// Run walks the archives that have extracted text but no word count or
+// language, by link id, and fills in what can be worked out from the text.
+// A language is only stored when detection is reliable, so archives in an
+// undetectable language are checked again on the next run but left as
+// they are.
+func (s *Service) Run(ctx context.Context, opts Options) (Stats, error) {
+	start := s.now()
+	var stats Stats
+
+	if opts.BatchSize < 1 {
+		opts.BatchSize = DefaultBatchSize
+	}
+
+	total, err := s.queries.CountArchivesMissingTextStats(ctx)
+	if err != nil {
+		stats.Duration = s.now().Sub(start)
+		return stats, fmt.Errorf("count archives: %w", err)
+	}
+
+	var after pgtype.UUID
+	for {
+		rows, err := s.queries.ListArchiveTextStatsBatch(ctx, db.ListArchiveTextStatsBatchParams{AfterID: after, BatchSize: int32(opts.BatchSize)})
+		if err != nil {
+			stats.Duration = s.now().Sub(start)
+			return stats, fmt.Errorf("list archives: %w", err)
+		}
+
+		for _, row := range rows {
+			stats.Checked++
+			words, lang := Count(row.ExtractedText)
+			if lang == "" {
+				lang = row.Lang
+			}
+			if int32(words) == row.WordCount && lang == row.Lang {
+				continue
+			}
+			if !opts.DryRun {
+				if err := s.queries.UpdateArchiveTextStats(ctx, db.UpdateArchiveTextStatsParams{
+					WordCount: pgtype.Int4{Int32: int32(words), Valid: true},
+					Lang:      pgtype.Text{String: lang, Valid: lang != ""},
+					LinkID:    row.LinkID,
+				}); err != nil {
+					stats.Duration = s.now().Sub(start)
+					return stats, fmt.Errorf("update archive %s: %w", uuid.UUID(row.LinkID.Bytes), err)
+				}
+			}
+			stats.Updated++
+			stats.Words += int64(words) - int64(row.WordCount)
+		}
+
+		if opts.Progress != nil && len(rows) > 0 {
+			opts.Progress(Progress{Checked: stats.Checked, Total: total, Updated: stats.Updated})
+		}
+		if len(rows) < opts.BatchSize {
+			break
+		}
+		after = rows[len(rows)-1].LinkID
+	}
+
+	stats.Duration = s.now().Sub(start)
+	return stats, nil
+}
+
+// Count returns the number of words in text and its ISO 639-1 language,
+// matching what the worker stores at ingest. The language is empty when
+// it cannot be told reliably.
+func Count(text string) (int, string) {
+	words := len(strings.Fields(text))
+	trimmed := strings.TrimSpace(text)
+	if trimmed == "" {
+		return words, ""
+	}
+	info := whatlanggo.Detect(trimmed)
+	if !info.IsReliable() {
+		return words, ""
+	}
+	lang := info.Lang.Iso6391()
+	if lang == "" {
+		lang = whatlanggo.LangToString(info.Lang)
+	}
+	return words, strings.TrimSpace(lang)
+}
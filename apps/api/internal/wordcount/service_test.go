@@ -0,0 +1,132 @@
+package wordcount
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"github.com/example/keepstack/apps/api/internal/db"
+)
+
+const englishText = "The quick brown fox jumps over the lazy dog while the farmer watches from the porch and wonders whether the harvest will come in before the rain."
+
+type fakeQueries struct {
+	batches [][]db.ListArchiveTextStatsBatchRow
+	total   int64
+
+	afters  []pgtype.UUID
+	updates []db.UpdateArchiveTextStatsParams
+}
+
+func (f *fakeQueries) CountArchivesMissingTextStats(context.Context) (int64, error) {
+	return f.total, nil
+}
+
+func (f *fakeQueries) ListArchiveTextStatsBatch(_ context.Context, arg db.ListArchiveTextStatsBatchParams) ([]db.ListArchiveTextStatsBatchRow, error) {
+	f.afters = append(f.afters, arg.AfterID)
+	if len(f.batches) == 0 {
+		return nil, nil
+	}
+	rows := f.batches[0]
+	f.batches = f.batches[1:]
+	return rows, nil
+}
+
+func (f *fakeQueries) UpdateArchiveTextStats(_ context.Context, arg db.UpdateArchiveTextStatsParams) error {
+	f.updates = append(f.updates, arg)
+	return nil
+}
+
+func row(text string, words int32, lang string) db.ListArchiveTextStatsBatchRow {
+	return db.ListArchiveTextStatsBatchRow{
+		LinkID:        pgtype.UUID{Bytes: uuid.New(), Valid: true},
+		ExtractedText: text,
+		WordCount:     words,
+		Lang:          lang,
+	}
+}
+
+func newTestService(q *fakeQueries) *Service {
+	now := time.Date(2024, time.June, 1, 0, 0, 0, 0, time.UTC)
+	return &Service{queries: q, now: func() time.Time { return now }}
+}
+
+func TestCount(t *testing.T) {
+	words, lang := Count(englishText)
+	if words != 27 || lang != "en" {
+		t.Fatalf("expected 27 English words, got %d %q", words, lang)
+	}
+	if words, lang := Count("  "); words != 0 || lang != "" {
+		t.Fatalf("expected nothing from blank text, got %d %q", words, lang)
+	}
+}
+
+func TestRunBackfillsInBatches(t *testing.T) {
+	first := []db.ListArchiveTextStatsBatchRow{row(englishText, 0, ""), row("1 2 3", 0, "de")}
+	q := &fakeQueries{
+		total:   3,
+		batches: [][]db.ListArchiveTextStatsBatchRow{first, {row(englishText, 27, "")}},
+	}
+
+	var progress []Progress
+	stats, err := newTestService(q).Run(context.Background(), Options{
+		BatchSize: 2,
+		Progress:  func(p Progress) { progress = append(progress, p) },
+	})
+	if err != nil {
+		t.Fatalf("Run returned error: %v", err)
+	}
+	if stats.Checked != 3 || stats.Updated != 3 || stats.Words != 30 {
+		t.Fatalf("unexpected stats: %+v", stats)
+	}
+	if len(q.afters) != 2 || q.afters[0].Valid || q.afters[1] != first[1].LinkID {
+		t.Fatalf("expected the second batch to continue after the first, got %v", q.afters)
+	}
+
+	if update := q.updates[0]; update.WordCount.Int32 != 27 || update.Lang.String != "en" || !update.Lang.Valid {
+		t.Fatalf("unexpected first update %+v", update)
+	}
+	// Digits carry no language, so the stored one is kept.
+	if second := q.updates[1]; second.WordCount.Int32 != 3 || second.Lang.String != "de" {
+		t.Fatalf("expected the stored language to be kept, got %+v", second)
+	}
+	want := []Progress{{Checked: 2, Total: 3, Updated: 2}, {Checked: 3, Total: 3, Updated: 3}}
+	if len(progress) != len(want) || progress[0] != want[0] || progress[1] != want[1] {
+		t.Fatalf("expected progress %+v, got %+v", want, progress)
+	}
+}
+
+func TestRunDryRunWritesNothing(t *testing.T) {
+	q := &fakeQueries{
+		total:   2,
+		batches: [][]db.ListArchiveTextStatsBatchRow{{row(englishText, 0, ""), row("?!", 0, "")}},
+	}
+
+	stats, err := newTestService(q).Run(context.Background(), Options{BatchSize: 10, DryRun: true})
+	if err != nil {
+		t.Fatalf("Run returned error: %v", err)
+	}
+	if stats.Checked != 2 || stats.Updated != 2 {
+		t.Fatalf("expected 2 archives reported, got %+v", stats)
+	}
+	if len(q.updates) != 0 {
+		t.Fatalf("dry run wrote %d updates", len(q.updates))
+	}
+}
+
+func TestRunSkipsUnchangedArchives(t *testing.T) {
+	// One word and no detectable language: nothing new to store.
+	q := &fakeQueries{batches: [][]db.ListArchiveTextStatsBatchRow{{row(strings.Repeat("x", 4), 1, "")}}}
+
+	stats, err := newTestService(q).Run(context.Background(), Options{})
+	if err != nil {
+		t.Fatalf("Run returned error: %v", err)
+	}
+	if stats.Checked != 1 || stats.Updated != 0 || len(q.updates) != 0 {
+		t.Fatalf("expected the archive to be left alone, got %+v and %d updates", stats, len(q.updates))
+	}
+}
@@ -1,5 +1,5 @@
 import { afterEach, describe, expect, test, vi } from "vitest";
-import { listTags, type TagWithCount } from "./client";
+import { ApiError, listTags, type TagWithCount } from "./client";
 
 const originalFetch = global.fetch;
 
@@ -27,3 +27,24 @@ describe("listTags", () => {
     expect(mockFetch).toHaveBeenCalledWith("/api/tags", expect.objectContaining({ method: "GET" }));
   });
 });
+
+describe("request errors", () => {
+  test("throws ApiError with the envelope's code", async () => {
+    global.fetch = vi.fn().mockResolvedValue(
+      new Response(JSON.stringify({ code: "rate_limited", message: "rate limit exceeded", request_id: "abc" }), {
+        status: 429,
+        headers: { "Content-Type": "application/json" }
+      })
+    );
+
+    const error = await listTags().catch((err: unknown) => err);
+    expect(error).toBeInstanceOf(ApiError);
+    expect(error).toMatchObject({ status: 429, code: "rate_limited", message: "rate limit exceeded", requestId: "abc" });
+  });
+
+  test("keeps non-API bodies as the message", async () => {
+    global.fetch = vi.fn().mockResolvedValue(new Response("Bad Gateway", { status: 502 }));
+
+    await expect(listTags()).rejects.toThrow("Bad Gateway");
+  });
+});
@@ -25,13 +25,17 @@ export interface LinkSummary {
   favorite: boolean;
   created_at: string;
   read_at?: string | null;
+  original_title?: string;
+  preserve_title?: boolean;
   archive_title: string;
   byline: string;
   lang: string;
   word_count: number;
   extracted_text: string;
+  paywalled?: boolean;
   tags: TagSummary[];
   highlights: HighlightSummary[];
+  highlight_count?: number;
 }
 
 export interface ListLinksResponse {
@@ -51,6 +55,43 @@ const API_BASE = import.meta.env.VITE_API_BASE_URL ?? "/api";
 
 type RequestInitWithBody = RequestInit & { body?: BodyInit | null };
 
+export interface ErrorResponse {
+  code: string;
+  message: string;
+  details?: Record<string, unknown>;
+  request_id?: string;
+}
+
+// ApiError carries the API's error envelope so callers can branch on code.
+export class ApiError extends Error {
+  readonly status: number;
+  readonly code: string;
+  readonly details?: Record<string, unknown>;
+  readonly requestId?: string;
+
+  constructor(status: number, body: ErrorResponse) {
+    super(body.message);
+    this.name = "ApiError";
+    this.status = status;
+    this.code = body.code;
+    this.details = body.details;
+    this.requestId = body.request_id;
+  }
+}
+
+async function readError(response: Response): Promise<Error> {
+  const text = await response.text();
+  try {
+    const body = JSON.parse(text) as Partial<ErrorResponse>;
+    if (typeof body.code === "string" && typeof body.message === "string") {
+      return new ApiError(response.status, body as ErrorResponse);
+    }
+  } catch {
+    // Not an API error body, e.g. a proxy's error page.
+  }
+  return new Error(text || `request failed (${response.status})`);
+}
+
 async function request<T>(path: string, init?: RequestInitWithBody): Promise<T> {
   const response = await fetch(`${API_BASE}${path}`, {
     headers: {
@@ -60,8 +101,7 @@ async function request<T>(path: string, init?: RequestInitWithBody): Promise<T>
   });
 
   if (!response.ok) {
-    const message = await response.text();
-    throw new Error(message || `request failed (${response.status})`);
+    throw await readError(response);
   }
 
   if (response.status === 204) {
@@ -75,6 +115,8 @@ export interface ListLinksParams {
   q?: string;
   favorite?: boolean;
   tags?: string[];
+  hasHighlights?: boolean;
+  sort?: "created_at" | "relevance";
   limit?: number;
   offset?: number;
 }
@@ -84,6 +126,8 @@ export async function listLinks(params: ListLinksParams): Promise<ListLinksRespo
   if (params.q) query.set("q", params.q);
   if (typeof params.favorite === "boolean") query.set("favorite", String(params.favorite));
   if (params.tags && params.tags.length > 0) query.set("tags", params.tags.join(","));
+  if (typeof params.hasHighlights === "boolean") query.set("has_highlights", String(params.hasHighlights));
+  if (params.sort) query.set("sort", params.sort);
   if (typeof params.limit === "number") query.set("limit", String(params.limit));
   if (typeof params.offset === "number") query.set("offset", String(params.offset));
 
@@ -97,15 +141,31 @@ export function listRecommendations(limit: number): Promise<RecommendationsRespo
   return request<RecommendationsResponse>(`/recommendations${query}`, { method: "GET" });
 }
 
+// recordRecommendationOpen tells the API a suggested link was opened, so it
+// counts towards that recommendation strategy's click-through rate.
+export function recordRecommendationOpen(linkId: string): Promise<void> {
+  return request<void>(`/recommendations/${linkId}/open`, { method: "POST" });
+}
+
 export interface CreateLinkInput {
   url: string;
   title?: string;
   favorite?: boolean;
 }
 
+export interface SimilarLink {
+  id: string;
+  url: string;
+  title: string;
+  created_at: string;
+  reason: "url" | "title";
+  score: number;
+}
+
 export interface CreateLinkResponse {
   id: string;
   url: string;
+  similar_links: SimilarLink[];
 }
 
 export function createLink(input: CreateLinkInput): Promise<CreateLinkResponse> {
@@ -146,6 +206,26 @@ export function deleteTag(tagId: number): Promise<void> {
   return request<void>(`/tags/${tagId}`, { method: "DELETE" });
 }
 
+export interface ApplyTagFilter {
+  query?: string;
+  domain?: string;
+  from?: string;
+  to?: string;
+}
+
+export interface ApplyTagResponse {
+  tag_id: number;
+  matched: number;
+  tagged: number;
+}
+
+export function applyTag(tagId: number, filter: ApplyTagFilter): Promise<ApplyTagResponse> {
+  return request<ApplyTagResponse>(`/tags/${tagId}/apply`, {
+    method: "POST",
+    body: JSON.stringify(filter)
+  });
+}
+
 export interface CreateHighlightInput {
   text: string;
   note?: string;
@@ -169,3 +249,34 @@ export function deleteHighlight(linkId: string, highlightId: string): Promise<vo
     method: "DELETE"
   });
 }
+
+export type LiveEventType = "link.ingested" | "link.deleted" | "highlight.created" | "recommendation.updated";
+
+export interface LiveEvent {
+  type: LiveEventType;
+  user_id: string;
+  link_id?: string;
+  data?: unknown;
+}
+
+const LIVE_EVENT_TYPES: LiveEventType[] = ["link.ingested", "link.deleted", "highlight.created", "recommendation.updated"];
+
+// subscribeToEvents opens the live update stream and returns a function that
+// closes it. The browser reconnects on its own after dropped connections.
+export function subscribeToEvents(onEvent: (event: LiveEvent) => void): () => void {
+  if (typeof EventSource === "undefined") {
+    return () => {};
+  }
+  const source = new EventSource(`${API_BASE}/events`);
+  const listener = (message: MessageEvent<string>) => {
+    try {
+      onEvent(JSON.parse(message.data) as LiveEvent);
+    } catch {
+      // Ignore malformed frames; the next event refreshes the same data.
+    }
+  };
+  for (const type of LIVE_EVENT_TYPES) {
+    source.addEventListener(type, listener);
+  }
+  return () => source.close();
+}
@@ -6,6 +6,8 @@ import {
   listLinks,
   listRecommendations,
   listTags,
+  recordRecommendationOpen,
+  subscribeToEvents,
   updateLink,
   type HighlightSummary,
   type LinkSummary,
@@ -40,11 +42,25 @@ export function ListPage({ search, onSearchChange }: ListPageProps) {
   const showSuggestions = Boolean(search.suggested);
   const queryKey = useMemo<LinksQueryKey>(() => ["links", search], [search]);
   const [highlightLinkId, setHighlightLinkId] = useState<string | null>(null);
+  const queryClient = useQueryClient();
 
   useEffect(() => {
     setQuery(search.q ?? "");
   }, [search.q]);
 
+  // Refetch when the worker finishes an article or another tab changes data.
+  useEffect(
+    () =>
+      subscribeToEvents((event) => {
+        if (event.type === "recommendation.updated") {
+          queryClient.invalidateQueries({ queryKey: ["recommendations"] });
+          return;
+        }
+        queryClient.invalidateQueries({ queryKey: ["links"] });
+      }),
+    [queryClient]
+  );
+
   const {
     data,
     isLoading,
@@ -265,6 +281,7 @@ export function ListPage({ search, onSearchChange }: ListPageProps) {
               link={item}
               queryKey={queryKey}
               onOpenHighlights={() => setHighlightLinkId(item.id)}
+              onOpen={showSuggestions ? () => void recordRecommendationOpen(item.id).catch(() => undefined) : undefined}
             />
           ))}
         </section>
@@ -306,9 +323,10 @@ interface LinkCardProps {
   link: LinkSummary;
   queryKey: LinksQueryKey;
   onOpenHighlights: () => void;
+  onOpen?: () => void;
 }
 
-function LinkCard({ link, queryKey, onOpenHighlights }: LinkCardProps) {
+function LinkCard({ link, queryKey, onOpenHighlights, onOpen }: LinkCardProps) {
   const queryClient = useQueryClient();
   const { mutate: toggleFavorite, isPending } = useMutation({
     mutationFn: (nextFavorite: boolean) => updateLink(link.id, { favorite: nextFavorite }),
@@ -332,6 +350,7 @@ function LinkCard({ link, queryKey, onOpenHighlights }: LinkCardProps) {
             href={link.url}
             target="_blank"
             rel="noreferrer"
+            onClick={onOpen}
             className="text-lg font-semibold text-slate-100 hover:underline"
           >
             {link.title || link.archive_title || link.source_domain || extractDomain(link.url)}
@@ -341,6 +360,7 @@ function LinkCard({ link, queryKey, onOpenHighlights }: LinkCardProps) {
             {link.byline && <span>By {link.byline}</span>}
             {link.lang && <span className="uppercase">{link.lang}</span>}
             {link.word_count > 0 && <span>{link.word_count.toLocaleString()} words</span>}
+            {link.paywalled && <span title="The page is behind a paywall, so the archive is partial">Paywalled</span>}
           </div>
         </div>
         <div className="flex items-center gap-2">
@@ -403,7 +423,11 @@ function HighlightDrawer({ link, queryKey, onClose }: HighlightDrawerProps) {
           ...current,
           items: current.items.map((item) =>
             item.id === link.id
-              ? { ...item, highlights: [...item.highlights, newHighlight] }
+              ? {
+                  ...item,
+                  highlights: [...item.highlights, newHighlight],
+                  highlight_count: item.highlights.length + 1,
+                }
               : item
           ),
         };
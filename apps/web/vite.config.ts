@@ -10,6 +10,14 @@ export default defineConfig({
       "/api": {
         target: "http://localhost:18080",
         changeOrigin: true
+      },
+      "/read": {
+        target: "http://localhost:18080",
+        changeOrigin: true
+      },
+      "/u": {
+        target: "http://localhost:18080",
+        changeOrigin: true
       }
     }
   }
@@ -2,26 +2,50 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
 	"os"
 	"os/signal"
-	"sync/atomic"
+	"strings"
 	"syscall"
 	"time"
 
-	"github.com/google/uuid"
+	"github.com/getsentry/sentry-go"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 
 	"github.com/example/keepstack/apps/worker/internal/config"
 	"github.com/example/keepstack/apps/worker/internal/ingest"
+	"github.com/example/keepstack/apps/worker/internal/ingestclient"
+	"github.com/example/keepstack/apps/worker/internal/jobs"
 	"github.com/example/keepstack/apps/worker/internal/observability"
 	"github.com/example/keepstack/apps/worker/internal/queue"
+	"github.com/example/keepstack/health"
+	"github.com/example/keepstack/listen"
+	"github.com/example/keepstack/media"
+	"github.com/example/keepstack/observe"
 )
 
+// requiredColumns lists, per table, the columns the ingest pipeline uses, so
+// a missed migration shows up as a failing readiness probe rather than as
+// failed jobs.
+var requiredColumns = map[string][]string{
+	"links":              {"id", "user_id", "url", "created_at", "title", "source_domain", "preserve_title"},
+	"archives":           {"link_id", "html", "extracted_text", "word_count", "lang", "title", "byline"},
+	"link_captures":      {"link_id", "html"},
+	"link_media":         {"link_id", "hash"},
+	"domain_preferences": {"user_id", "domain", "fetch_headers"},
+}
+
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "--validate-config" {
+		os.Exit(validateConfig())
+	}
+
 	logger := log.New(os.Stdout, "keepstack-worker ", log.LstdFlags|log.LUTC)
 
 	cfg, err := config.Load()
@@ -32,32 +56,72 @@ func main() {
 	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
 	defer stop()
 
-	var dbReady atomic.Bool
-	var queueReady atomic.Bool
+	shutdownTracing, err := observe.InitTracing(ctx, "keepstack-worker")
+	if err != nil {
+		logger.Fatalf("init tracing: %v", err)
+	}
+	defer func() {
+		flushCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := shutdownTracing(flushCtx); err != nil {
+			logger.Printf("tracing shutdown error: %v", err)
+		}
+	}()
+
+	errorReporter, err := observe.NewErrorReporter(sentry.ClientOptions{
+		Dsn:         cfg.SentryDSN,
+		Environment: cfg.SentryEnvironment,
+		ServerName:  "keepstack-worker",
+	})
+	if err != nil {
+		logger.Fatalf("init error reporting: %v", err)
+	}
+	defer errorReporter.Flush(2 * time.Second)
+
+	metrics := observability.NewMetrics()
+	queryTracer := observe.NewQueryTracer(metrics.DBQueryDurationSeconds, cfg.SlowQueryThreshold, logger)
 
-	pool, err := connectDatabase(ctx, logger, cfg.DatabaseURL)
+	poolCfg, err := newPoolConfig(cfg, queryTracer)
+	if err != nil {
+		logger.Fatalf("configure database pool: %v", err)
+	}
+	pool, err := connectDatabase(ctx, logger, poolCfg)
 	if err != nil {
 		logger.Fatalf("connect database: %v", err)
 	}
 	defer pool.Close()
-	dbReady.Store(true)
+	prometheus.MustRegister(observe.NewPoolStatsCollector("keepstack_worker", pool.Stat))
 
-	metrics := observability.NewMetrics()
-	metricsSrv := startMetricsServer(cfg.MetricsAddress(), logger)
+	metricsSrv, err := startMetricsServer(cfg.MetricsAddresses(), logger)
+	if err != nil {
+		logger.Fatalf("start metrics server: %v", err)
+	}
 	defer func() {
 		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 		defer cancel()
 		_ = metricsSrv.Shutdown(shutdownCtx)
 	}()
 
-	healthSrv := startHealthServer(cfg.HealthAddress(), &dbReady, &queueReady, logger)
+	// subscriber is assigned before the checker first runs, from the ready
+	// callback below.
+	var subscriber *queue.Subscriber
+	checker := health.NewChecker([]health.Check{
+		health.Postgres(pool),
+		{Name: "nats", Run: func(ctx context.Context) error { return subscriber.Healthy(ctx) }},
+		health.Schema(pool, requiredColumns),
+	}, cfg.HealthCheckTimeout, metrics.DependencyUp, logger)
+
+	healthSrv, err := startHealthServer(cfg.HealthAddresses(), checker, logger)
+	if err != nil {
+		logger.Fatalf("start health server: %v", err)
+	}
 	defer func() {
 		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 		defer cancel()
 		_ = healthSrv.Shutdown(shutdownCtx)
 	}()
 
-	subscriber, err := connectNATS(ctx, logger, cfg.NATSURL)
+	subscriber, err = connectNATS(ctx, logger, cfg.NATSURL)
 	if err != nil {
 		logger.Fatalf("connect nats: %v", err)
 	}
@@ -66,27 +130,78 @@ func main() {
 	fetcher := ingest.NewFetcher(cfg.FetchTimeout)
 	store := ingest.NewStore(pool)
 	processor := ingest.NewProcessor(fetcher, store, metrics)
+	processor.WithSanitizePolicy(cfg.Sanitize().Policy())
+	mediaStore, err := media.Open(cfg.Media())
+	if err != nil {
+		logger.Fatalf("open media storage: %v", err)
+	}
+	var mediaCleaner *ingest.MediaCleaner
+	if mediaStore != nil {
+		processor.WithImageMirror(ingest.NewImageMirror(mediaStore, store, cfg.FetchTimeout, cfg.MediaMaxBytes, cfg.MediaMaxImages))
+		mediaCleaner = ingest.NewMediaCleaner(mediaStore, store)
+		logger.Printf("mirroring archived images to %s media storage", cfg.MediaStorage)
+	}
+	processor.OnIngested(func(ctx context.Context, link ingest.Link) {
+		event := queue.Event{Type: queue.EventLinkIngested, UserID: link.UserID.String(), LinkID: link.ID.String()}
+		if err := subscriber.PublishEvent(event); err != nil {
+			logger.Printf("publish %s event for %s: %v", event.Type, link.ID, err)
+		}
+	})
+	if cfg.APIGRPCAddr != "" {
+		reporter, err := ingestclient.Dial(cfg.APIGRPCAddr, cfg.StatusReportTimeout, logger)
+		if err != nil {
+			logger.Fatalf("connect api grpc: %v", err)
+		}
+		defer reporter.Close()
+		processor.WithStatusReporter(reporter)
+		logger.Printf("reporting job status to %s", cfg.APIGRPCAddr)
+	}
 
-	processJob := func(jobCtx context.Context, linkID uuid.UUID) error {
-		metrics.JobsInFlight.Inc()
-		defer metrics.JobsInFlight.Dec()
+	if _, err := subscriber.ServeUnfurl(func(ctx context.Context, target string) (queue.UnfurlReply, error) {
+		start := time.Now()
+		preview, err := processor.Unfurl(ctx, target)
+		metrics.ObserveMessage(ctx, queue.SubjectUnfurl, time.Since(start), err)
+		if err != nil {
+			return queue.UnfurlReply{}, err
+		}
+		return queue.UnfurlReply{
+			URL:         preview.URL,
+			Title:       preview.Title,
+			Description: preview.Description,
+			Image:       preview.Image,
+			WordCount:   preview.WordCount,
+		}, nil
+	}); err != nil {
+		logger.Fatalf("serve unfurl requests: %v", err)
+	}
 
-		return processor.Process(jobCtx, linkID)
+	if _, err := subscriber.ServeExpand(func(ctx context.Context, target string) (queue.ExpandReply, error) {
+		start := time.Now()
+		index, err := processor.Expand(ctx, target)
+		metrics.ObserveMessage(ctx, queue.SubjectExpand, time.Since(start), err)
+		if err != nil {
+			return queue.ExpandReply{}, err
+		}
+		links := make([]queue.ExpandedLink, 0, len(index.Links))
+		for _, link := range index.Links {
+			links = append(links, queue.ExpandedLink{URL: link.URL, Title: link.Title})
+		}
+		return queue.ExpandReply{URL: index.URL, Title: index.Title, Links: links}, nil
+	}); err != nil {
+		logger.Fatalf("serve expand requests: %v", err)
 	}
 
+	routes := jobRoutes(jobs.Deps{Processor: processor, Media: mediaCleaner, Metrics: metrics, Logger: logger}, metrics, errorReporter)
 	errCh := make(chan error, 1)
 	go func() {
-		errCh <- subscriber.Listen(ctx, func(jobCtx context.Context, linkID uuid.UUID) error {
-			if err := processJob(jobCtx, linkID); err != nil {
-				metrics.JobsFailed.Inc()
-				return err
+		errCh <- subscriber.Listen(ctx, routes, func() {
+			go checker.Run(ctx, cfg.HealthCheckInterval)
+			if cfg.RequeueWindow > 0 {
+				go requeueUnarchived(ctx, logger, store, subscriber, cfg.RequeueWindow)
 			}
-			metrics.JobsProcessed.Inc()
-			return nil
-		}, func() {
-			queueReady.Store(true)
 		})
 	}()
+	logger.Printf("handling jobs on %d subject(s)", len(routes))
 
 	select {
 	case <-ctx.Done():
@@ -100,13 +215,121 @@ func main() {
 	logger.Println("worker stopped")
 }
 
-func connectDatabase(ctx context.Context, logger *log.Logger, url string) (*pgxpool.Pool, error) {
+// requeueLimit caps how many links one startup re-enqueues.
+const requeueLimit = 1000
+
+// requeueUnarchived publishes links.saved again for links saved within
+// window that have no archive. Core NATS drops messages published while no
+// worker is subscribed, such as during a rollout with the worker scaled to
+// zero; this picks those saves back up once a worker is listening. Links
+// whose ingestion failed are retried too, and when several replicas start
+// together a link may be ingested twice, which only rewrites its archive.
+func requeueUnarchived(ctx context.Context, logger *log.Logger, store *ingest.Store, subscriber *queue.Subscriber, window time.Duration) {
+	ids, err := store.ListUnarchived(ctx, time.Now().Add(-window), requeueLimit)
+	if err != nil {
+		logger.Printf("requeue unarchived links: %v", err)
+		return
+	}
+	for _, id := range ids {
+		if err := subscriber.PublishLinkSaved(id.String()); err != nil {
+			logger.Printf("requeue link %s: %v", id, err)
+			return
+		}
+	}
+	if len(ids) > 0 {
+		logger.Printf("requeued %d unarchived link(s) saved in the last %s", len(ids), window)
+	}
+}
+
+// jobRoutes builds a queue route for every registered job type, recording
+// each job's outcome in the job metrics and reporting failures.
+func jobRoutes(deps jobs.Deps, metrics *observability.Metrics, reporter *observe.ErrorReporter) []queue.Route {
+	types := jobs.Types()
+	routes := make([]queue.Route, 0, len(types))
+	for _, t := range types {
+		subject, handle := t.Subject, t.New(deps)
+		routes = append(routes, queue.Route{
+			Subject: subject,
+			Timeout: t.Timeout,
+			Handler: func(jobCtx context.Context, payload []byte) error {
+				metrics.JobsInFlight.Inc()
+				defer metrics.JobsInFlight.Dec()
+
+				start := time.Now()
+				err := handle(jobCtx, payload)
+				metrics.ObserveMessage(jobCtx, subject, time.Since(start), err)
+				if err != nil {
+					metrics.JobsFailed.Inc()
+					reportJobFailure(jobCtx, reporter, subject, err)
+					return err
+				}
+				metrics.JobsProcessed.Inc()
+				return nil
+			},
+		})
+	}
+	return routes
+}
+
+// reportJobFailure sends a failed job to the error reporter, tagged with its
+// subject and, for ingestion, the link and pipeline stage that failed.
+func reportJobFailure(ctx context.Context, reporter *observe.ErrorReporter, subject string, err error) {
+	ec := observe.ErrorContext{
+		Tags: map[string]string{
+			"subject": subject,
+			"stage":   "unknown",
+		},
+	}
+	var linkErr *jobs.LinkError
+	if errors.As(err, &linkErr) {
+		ec.Tags["link_id"] = linkErr.LinkID.String()
+	}
+	var stageErr *ingest.StageError
+	if errors.As(err, &stageErr) {
+		ec.Tags["stage"] = stageErr.Stage
+		if stageErr.URL != "" {
+			ec.Details = map[string]any{"link_url": stageErr.URL}
+		}
+	}
+	reporter.Capture(ctx, err, ec)
+}
+
+// newPoolConfig parses the database URL and applies the pool settings from
+// the environment. Zero values keep the pgxpool defaults.
+func newPoolConfig(cfg config.Config, tracer pgx.QueryTracer) (*pgxpool.Config, error) {
+	poolCfg, err := pgxpool.ParseConfig(cfg.DatabaseURL)
+	if err != nil {
+		return nil, fmt.Errorf("parse database url: %w", err)
+	}
+	poolCfg.ConnConfig.Tracer = tracer
+	if cfg.DBMaxConns > 0 {
+		poolCfg.MaxConns = cfg.DBMaxConns
+	}
+	if cfg.DBMinConns > 0 {
+		poolCfg.MinConns = cfg.DBMinConns
+	}
+	if cfg.DBMaxConnLifetime > 0 {
+		poolCfg.MaxConnLifetime = cfg.DBMaxConnLifetime
+	}
+	if cfg.DBMaxConnIdleTime > 0 {
+		poolCfg.MaxConnIdleTime = cfg.DBMaxConnIdleTime
+	}
+	if cfg.DBHealthCheckPeriod > 0 {
+		poolCfg.HealthCheckPeriod = cfg.DBHealthCheckPeriod
+	}
+	if poolCfg.MinConns > poolCfg.MaxConns {
+		return nil, fmt.Errorf("DB_MIN_CONNS (%d) exceeds DB_MAX_CONNS (%d)", poolCfg.MinConns, poolCfg.MaxConns)
+	}
+	return poolCfg, nil
+}
+
+func connectDatabase(ctx context.Context, logger *log.Logger, poolCfg *pgxpool.Config) (*pgxpool.Pool, error) {
 	backoff := time.Second
 	var lastErr error
 
 	for attempts := 1; ; attempts++ {
 		attemptCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
-		pool, err := pgxpool.New(attemptCtx, url)
+		pool, err := pgxpool.NewWithConfig(attemptCtx, poolCfg)
 		cancel()
 		if err == nil {
 			logger.Printf("database connection established after %d attempt(s)", attempts)
@@ -174,25 +397,29 @@ func connectNATS(ctx context.Context, logger *log.Logger, url string) (*queue.Su
 	return nil, fmt.Errorf("connect to nats: %w", lastErr)
 }
 
-func startMetricsServer(addr string, logger *log.Logger) *http.Server {
+func startMetricsServer(addrs []string, logger *log.Logger) (*http.Server, error) {
 	mux := http.NewServeMux()
-	mux.Handle("/metrics", promhttp.Handler())
+	// OpenMetrics is the only exposition format that carries exemplars.
+	mux.Handle("/metrics", promhttp.InstrumentMetricHandler(prometheus.DefaultRegisterer,
+		promhttp.HandlerFor(prometheus.DefaultGatherer, promhttp.HandlerOpts{EnableOpenMetrics: true})))
 
-	srv := &http.Server{
-		Addr:    addr,
-		Handler: mux,
+	listeners, err := listen.Open(addrs)
+	if err != nil {
+		return nil, err
 	}
+	srv := &http.Server{Handler: mux}
 
 	go func() {
-		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		logger.Printf("metrics server listening on %s", listen.Names(listeners))
+		if err := listen.Serve(srv, listeners); err != nil {
 			logger.Printf("metrics server error: %v", err)
 		}
 	}()
 
-	return srv
+	return srv, nil
 }
 
-func startHealthServer(addr string, dbReady, queueReady *atomic.Bool, logger *log.Logger) *http.Server {
+func startHealthServer(addrs []string, checker *health.Checker, logger *log.Logger) (*http.Server, error) {
 	mux := http.NewServeMux()
 	mux.HandleFunc("/livez", func(w http.ResponseWriter, _ *http.Request) {
 		w.WriteHeader(http.StatusOK)
@@ -200,9 +427,9 @@ func startHealthServer(addr string, dbReady, queueReady *atomic.Bool, logger *lo
 	})
 
 	mux.HandleFunc("/healthz", func(w http.ResponseWriter, _ *http.Request) {
-		if !dbReady.Load() || !queueReady.Load() {
+		if ready, problems := checker.Ready(); !ready {
 			w.WriteHeader(http.StatusServiceUnavailable)
-			_, _ = w.Write([]byte("not ready"))
+			_, _ = w.Write([]byte("not ready\n" + strings.Join(problems, "\n")))
 			return
 		}
 
@@ -210,16 +437,18 @@ func startHealthServer(addr string, dbReady, queueReady *atomic.Bool, logger *lo
 		_, _ = w.Write([]byte("ok"))
 	})
 
-	srv := &http.Server{
-		Addr:    addr,
-		Handler: mux,
+	listeners, err := listen.Open(addrs)
+	if err != nil {
+		return nil, err
 	}
+	srv := &http.Server{Handler: mux}
 
 	go func() {
-		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		logger.Printf("health server listening on %s", listen.Names(listeners))
+		if err := listen.Serve(srv, listeners); err != nil {
 			logger.Printf("health server error: %v", err)
 		}
 	}()
 
-	return srv
+	return srv, nil
 }
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"time"
+
+	"github.com/example/keepstack/apps/worker/internal/config"
+	"github.com/example/keepstack/configcheck"
+)
+
+// validateConfig prints the worker's effective configuration and checks it
+// without connecting to anything, returning the exit code.
+func validateConfig() int {
+	var (
+		settings []configcheck.Setting
+		checks   []configcheck.Check
+	)
+	cfg, err := config.Load()
+	if err != nil {
+		checks = append(checks, configcheck.Failed("load configuration", err))
+	} else {
+		settings = configcheck.Settings(cfg)
+		checks = append(checks,
+			configcheck.DatabaseURL("DATABASE_URL", cfg.DatabaseURL, true),
+			configcheck.URL("NATS_URL", cfg.NATSURL, true, "nats", "tls", "ws", "wss"),
+			configcheck.URL("SENTRY_DSN", cfg.SentryDSN, false, "http", "https"),
+			configcheck.Check{Name: "API_GRPC_ADDR format", Run: func(context.Context) error {
+				if cfg.APIGRPCAddr == "" {
+					return nil
+				}
+				if _, _, err := net.SplitHostPort(cfg.APIGRPCAddr); err != nil {
+					return fmt.Errorf("API_GRPC_ADDR must be host:port: %w", err)
+				}
+				return nil
+			}},
+		)
+	}
+
+	if !configcheck.Run(context.Background(), os.Stdout, settings, checks, 10*time.Second) {
+		return 1
+	}
+	return 0
+}
@@ -5,26 +5,122 @@ import (
 	"time"
 
 	"github.com/kelseyhightower/envconfig"
+
+	"github.com/example/keepstack/media"
+	"github.com/example/keepstack/sanitize"
 )
 
 // Config holds runtime settings for the worker service.
 type Config struct {
-	DatabaseURL  string        `envconfig:"DATABASE_URL" required:"true"`
-	NATSURL      string        `envconfig:"NATS_URL" required:"true"`
-	MetricsPort  int           `envconfig:"PORT" default:"9090"`
-	HealthPort   int           `envconfig:"HEALTH_PORT" default:"8081"`
-	FetchTimeout time.Duration `envconfig:"FETCH_TIMEOUT" default:"15s"`
+	DatabaseURL string `envconfig:"DATABASE_URL" required:"true"`
+	NATSURL     string `envconfig:"NATS_URL" required:"true"`
+	MetricsPort int    `envconfig:"PORT" default:"9090"`
+	HealthPort  int    `envconfig:"HEALTH_PORT" default:"8081"`
+	// MetricsListen and HealthListen list addresses, comma separated, that
+	// replace PORT and HEALTH_PORT: TCP addresses such as "127.0.0.1:9090"
+	// and Unix sockets such as "unix:/run/keepstack/metrics.sock".
+	MetricsListen []string      `envconfig:"METRICS_LISTEN" default:""`
+	HealthListen  []string      `envconfig:"HEALTH_LISTEN" default:""`
+	FetchTimeout  time.Duration `envconfig:"FETCH_TIMEOUT" default:"15s"`
+	// HealthCheckInterval is how often readiness re-checks the database,
+	// NATS subscription, and schema.
+	HealthCheckInterval time.Duration `envconfig:"HEALTH_CHECK_INTERVAL" default:"15s"`
+	// HealthCheckTimeout bounds each dependency check.
+	HealthCheckTimeout time.Duration `envconfig:"HEALTH_CHECK_TIMEOUT" default:"5s"`
+	// SlowQueryThreshold logs database queries that take at least this long.
+	// Zero disables slow query logging.
+	SlowQueryThreshold time.Duration `envconfig:"DB_SLOW_QUERY_THRESHOLD" default:"500ms"`
+	// Connection pool settings. Zero keeps the pgxpool default.
+	DBMaxConns          int32         `envconfig:"DB_MAX_CONNS" default:"0"`
+	DBMinConns          int32         `envconfig:"DB_MIN_CONNS" default:"0"`
+	DBMaxConnLifetime   time.Duration `envconfig:"DB_MAX_CONN_LIFETIME" default:"0"`
+	DBMaxConnIdleTime   time.Duration `envconfig:"DB_MAX_CONN_IDLE_TIME" default:"0"`
+	DBHealthCheckPeriod time.Duration `envconfig:"DB_HEALTH_CHECK_PERIOD" default:"0"`
+	// APIGRPCAddr is the API's internal gRPC address, e.g.
+	// "keepstack-api:9090", that job status and diagnostics are reported to.
+	// Empty disables reporting.
+	APIGRPCAddr string `envconfig:"API_GRPC_ADDR" default:""`
+	// StatusReportTimeout bounds each report so a slow API cannot stall jobs.
+	StatusReportTimeout time.Duration `envconfig:"STATUS_REPORT_TIMEOUT" default:"2s"`
+	// RequeueWindow is how far back the worker looks on startup for saved
+	// links without an archive, whose links.saved message was lost because
+	// no worker was subscribed, and publishes them again. Zero disables it.
+	RequeueWindow time.Duration `envconfig:"REQUEUE_WINDOW" default:"24h"`
+	// SanitizeFeatures lists what archived HTML keeps beyond text, comma
+	// separated: "tables", "code", "images", and "embeds". The API must be
+	// given the same SANITIZE_* settings, since the reader sanitizes again.
+	SanitizeFeatures []string `envconfig:"SANITIZE_FEATURES" default:"tables,code,images"`
+	// SanitizeImageProxy rewrites image sources through a proxy, e.g.
+	// "https://images.example.com/proxy?url={url}". Empty keeps them as is.
+	SanitizeImageProxy string `envconfig:"SANITIZE_IMAGE_PROXY" default:""`
+	// SanitizeEmbedHosts lists the iframe hosts "embeds" allows. Empty allows
+	// YouTube and Vimeo players.
+	SanitizeEmbedHosts []string `envconfig:"SANITIZE_EMBED_HOSTS" default:""`
+	// MediaStorage copies the images in archived HTML to "dir" or "s3"
+	// storage, for the API to serve from /api/media/:hash. Empty leaves them
+	// linked to their sites. The API must be given the same MEDIA_* storage
+	// settings.
+	MediaStorage     string `envconfig:"MEDIA_STORAGE" default:""`
+	MediaDir         string `envconfig:"MEDIA_DIR" default:""`
+	MediaS3Bucket    string `envconfig:"MEDIA_S3_BUCKET" default:""`
+	MediaS3AccessKey string `envconfig:"MEDIA_S3_ACCESS_KEY" default:""`
+	MediaS3SecretKey string `envconfig:"MEDIA_S3_SECRET_KEY" default:""`
+	MediaS3Region    string `envconfig:"MEDIA_S3_REGION" default:"us-east-1"`
+	MediaS3Endpoint  string `envconfig:"MEDIA_S3_ENDPOINT" default:""`
+	MediaS3Prefix    string `envconfig:"MEDIA_S3_PREFIX" default:""`
+	// MediaMaxBytes and MediaMaxImages bound each mirrored image and how many
+	// one article may mirror; the rest keep their original source.
+	MediaMaxBytes  int64 `envconfig:"MEDIA_MAX_BYTES" default:"5242880"`
+	MediaMaxImages int   `envconfig:"MEDIA_MAX_IMAGES" default:"50"`
+	// SentryDSN enables error reporting for failed jobs. Empty disables it.
+	SentryDSN string `envconfig:"SENTRY_DSN" default:""`
+	// SentryEnvironment tags reported events, e.g. "production".
+	SentryEnvironment string `envconfig:"SENTRY_ENVIRONMENT" default:""`
 }
 
-// Load retrieves configuration from environment variables.
+// Load retrieves configuration from environment variables, after filling
+// them from any secret files or Vault (see LoadSecrets).
 func Load() (Config, error) {
+	if err := LoadSecrets(); err != nil {
+		return Config{}, fmt.Errorf("load secrets: %w", err)
+	}
+
 	var cfg Config
 	if err := envconfig.Process("", &cfg); err != nil {
 		return Config{}, fmt.Errorf("load config: %w", err)
 	}
+	if err := cfg.Sanitize().Validate(); err != nil {
+		return Config{}, fmt.Errorf("load config: %w", err)
+	}
+	if err := cfg.Media().Validate(); err != nil {
+		return Config{}, fmt.Errorf("load config: %w", err)
+	}
 	return cfg, nil
 }
 
+// Sanitize returns the options archived HTML is sanitized with.
+func (c Config) Sanitize() sanitize.Options {
+	return sanitize.Options{
+		Features:   c.SanitizeFeatures,
+		ImageProxy: c.SanitizeImageProxy,
+		EmbedHosts: c.SanitizeEmbedHosts,
+	}
+}
+
+// Media returns the options archived images are mirrored with.
+func (c Config) Media() media.Options {
+	return media.Options{
+		Storage:     c.MediaStorage,
+		Dir:         c.MediaDir,
+		S3Bucket:    c.MediaS3Bucket,
+		S3AccessKey: c.MediaS3AccessKey,
+		S3SecretKey: c.MediaS3SecretKey,
+		S3Region:    c.MediaS3Region,
+		S3Endpoint:  c.MediaS3Endpoint,
+		S3Prefix:    c.MediaS3Prefix,
+	}
+}
+
 // MetricsAddress returns the listen address for the metrics HTTP server.
 func (c Config) MetricsAddress() string {
 	return fmt.Sprintf(":%d", c.MetricsPort)
@@ -34,3 +130,19 @@ func (c Config) MetricsAddress() string {
 func (c Config) HealthAddress() string {
 	return fmt.Sprintf(":%d", c.HealthPort)
 }
+
+// MetricsAddresses returns MetricsListen when set, otherwise MetricsAddress.
+func (c Config) MetricsAddresses() []string {
+	if len(c.MetricsListen) > 0 {
+		return c.MetricsListen
+	}
+	return []string{c.MetricsAddress()}
+}
+
+// HealthAddresses returns HealthListen when set, otherwise HealthAddress.
+func (c Config) HealthAddresses() []string {
+	if len(c.HealthListen) > 0 {
+		return c.HealthListen
+	}
+	return []string{c.HealthAddress()}
+}
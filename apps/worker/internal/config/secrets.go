@@ -0,0 +1,32 @@
+package config
+
+import (
+	"context"
+	"sync"
+
+	"github.com/example/keepstack/secrets"
+)
+
+// secretVariables may be read from the file named by the same variable with
+// a _FILE suffix, e.g. DATABASE_URL_FILE, so Kubernetes Secrets can be
+// mounted as files instead of exposed in the environment.
+var secretVariables = []string{
+	"DATABASE_URL",
+	"NATS_URL",
+	"SENTRY_DSN",
+}
+
+var (
+	secretsOnce sync.Once
+	secretsErr  error
+)
+
+// LoadSecrets fills unset environment variables from secret files, a
+// dotenv file, or Vault, as secrets.Load describes. Load calls LoadSecrets,
+// and only the first call does any work.
+func LoadSecrets() error {
+	secretsOnce.Do(func() {
+		secretsErr = secrets.Load(context.Background(), secretVariables)
+	})
+	return secretsErr
+}
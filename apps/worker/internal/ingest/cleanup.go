@@ -0,0 +1,81 @@
+package ingest
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/example/keepstack/media"
+)
+
+// mediaReferences reports which mirrored images some link still references,
+// as recorded in link_media.
+type mediaReferences interface {
+	LockMedia(ctx context.Context, hashes []string, purge func(referenced []string)) error
+}
+
+// MediaCleaner removes mirrored images from media storage once the links
+// whose archives showed them are deleted. Images are stored by content
+// hash, so one stored image can back several archives; it is only removed
+// when no link references it any more.
+type MediaCleaner struct {
+	store media.Store
+	refs  mediaReferences
+}
+
+// NewMediaCleaner returns a MediaCleaner removing from store the images no
+// link in refs references.
+func NewMediaCleaner(store media.Store, refs *Store) *MediaCleaner {
+	return &MediaCleaner{store: store, refs: refs}
+}
+
+// PurgeStats counts what Purge did with a deleted link's images. Kept
+// images are still referenced by another link.
+type PurgeStats struct {
+	Removed int
+	Kept    int
+	Failed  int
+}
+
+// Purge removes each image in hashes that no link references. Hashes that
+// could not have come from media.Hash are ignored. It returns an error when
+// any removal failed; removing an image already gone succeeds, so the
+// message can simply be handled again. Images are removed while refs holds
+// their locks, so one being mirrored for another link meanwhile is kept.
+func (c *MediaCleaner) Purge(ctx context.Context, hashes []string) (PurgeStats, error) {
+	var stats PurgeStats
+	valid := make([]string, 0, len(hashes))
+	for _, hash := range hashes {
+		if media.ValidHash(hash) {
+			valid = append(valid, hash)
+		}
+	}
+	if len(valid) == 0 {
+		return stats, nil
+	}
+
+	var firstErr error
+	err := c.refs.LockMedia(ctx, valid, func(referenced []string) {
+		keep := make(map[string]bool, len(referenced))
+		for _, hash := range referenced {
+			keep[hash] = true
+		}
+		for _, hash := range valid {
+			if keep[hash] {
+				stats.Kept++
+				continue
+			}
+			if err := c.store.Delete(ctx, hash); err != nil {
+				stats.Failed++
+				if firstErr == nil {
+					firstErr = err
+				}
+				continue
+			}
+			stats.Removed++
+		}
+	})
+	if err != nil {
+		return stats, fmt.Errorf("check media references: %w", err)
+	}
+	return stats, firstErr
+}
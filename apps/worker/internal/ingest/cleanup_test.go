@@ -0,0 +1,84 @@
+package ingest
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/google/uuid"
+
+	"github.com/example/keepstack/media"
+)
+
+// fakeMediaReferences stands in for link_media: referenced holds the hashes
+// some link references.
+type fakeMediaReferences struct {
+	mu         sync.Mutex
+	referenced map[string]bool
+	recorded   map[uuid.UUID][]string
+	asked      []string
+}
+
+func (f *fakeMediaReferences) RecordMedia(_ context.Context, linkID uuid.UUID, hash string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.recorded == nil {
+		f.recorded = make(map[uuid.UUID][]string)
+	}
+	f.recorded[linkID] = append(f.recorded[linkID], hash)
+	return nil
+}
+
+func (f *fakeMediaReferences) LockMedia(_ context.Context, hashes []string, purge func(referenced []string)) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.asked = append(f.asked, hashes...)
+	var out []string
+	for _, hash := range hashes {
+		if f.referenced[hash] {
+			out = append(out, hash)
+		}
+	}
+	purge(out)
+	return nil
+}
+
+func TestMediaCleanerKeepsImagesOtherArchivesShow(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	store := media.NewDirStore(t.TempDir())
+	shared, only := media.Hash([]byte("shared")), media.Hash([]byte("only"))
+	for _, hash := range []string{shared, only} {
+		if err := store.Put(ctx, hash, "image/png", testPNG); err != nil {
+			t.Fatalf("Put: %v", err)
+		}
+	}
+	refs := &fakeMediaReferences{referenced: map[string]bool{shared: true}}
+	cleaner := &MediaCleaner{store: store, refs: refs}
+
+	stats, err := cleaner.Purge(ctx, []string{shared, only, "../not-a-hash"})
+	if err != nil {
+		t.Fatalf("Purge: %v", err)
+	}
+	if stats != (PurgeStats{Removed: 1, Kept: 1}) {
+		t.Fatalf("unexpected stats %+v", stats)
+	}
+	if len(refs.asked) != 2 {
+		t.Fatalf("expected only valid hashes checked, got %v", refs.asked)
+	}
+	if _, err := store.Get(ctx, only); !errors.Is(err, media.ErrNotFound) {
+		t.Fatalf("expected the unreferenced image removed, got %v", err)
+	}
+	object, err := store.Get(ctx, shared)
+	if err != nil {
+		t.Fatalf("expected the shared image kept, got %v", err)
+	}
+	object.Body.Close()
+
+	// A redelivered message finds the image already gone.
+	if stats, err := cleaner.Purge(ctx, []string{only}); err != nil || stats.Removed != 1 {
+		t.Fatalf("Purge again = %+v, %v", stats, err)
+	}
+}
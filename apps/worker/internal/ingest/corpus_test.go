@@ -0,0 +1,161 @@
+package ingest
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+var updateGolden = flag.Bool("update", false, "rewrite testdata/corpus golden files from the current parser output")
+
+const corpusDir = "testdata/corpus"
+
+// corpusURLPattern matches the comment on a fixture's first line that names
+// the page it was saved from, so relative links and images resolve the way
+// they would for the real page.
+var corpusURLPattern = regexp.MustCompile(`^<!-- url: (\S+) -->`)
+
+// TestParseCorpus parses every page under testdata/corpus and compares the
+// extracted article with the fixture's .golden file. After upgrading
+// readability or the sanitizer, rerun with -update and review the golden
+// diff to see exactly how extraction changed.
+func TestParseCorpus(t *testing.T) {
+	t.Parallel()
+
+	fixtures, err := filepath.Glob(filepath.Join(corpusDir, "*.html"))
+	if err != nil {
+		t.Fatalf("list corpus: %v", err)
+	}
+	if len(fixtures) == 0 {
+		t.Fatalf("no fixtures in %s", corpusDir)
+	}
+
+	for _, fixture := range fixtures {
+		name := strings.TrimSuffix(filepath.Base(fixture), ".html")
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			html, err := os.ReadFile(fixture)
+			if err != nil {
+				t.Fatalf("read fixture: %v", err)
+			}
+			got := renderGolden(Parse(corpusURL(html), html))
+
+			goldenPath := strings.TrimSuffix(fixture, ".html") + ".golden"
+			if *updateGolden {
+				if err := os.WriteFile(goldenPath, got, 0o644); err != nil {
+					t.Fatalf("write golden: %v", err)
+				}
+				return
+			}
+			want, err := os.ReadFile(goldenPath)
+			if errors.Is(err, fs.ErrNotExist) {
+				t.Fatalf("no golden file; run go test ./internal/ingest -run TestParseCorpus -update")
+			}
+			if err != nil {
+				t.Fatalf("read golden: %v", err)
+			}
+			if !bytes.Equal(got, want) {
+				t.Errorf("extraction changed; rerun with -update and review the golden diff\n%s", firstDifference(want, got))
+			}
+		})
+	}
+
+	// A golden file whose fixture was deleted would otherwise linger unnoticed.
+	goldens, err := filepath.Glob(filepath.Join(corpusDir, "*.golden"))
+	if err != nil {
+		t.Fatalf("list golden files: %v", err)
+	}
+	for _, golden := range goldens {
+		fixture := strings.TrimSuffix(golden, ".golden") + ".html"
+		if _, err := os.Stat(fixture); err == nil {
+			continue
+		}
+		if *updateGolden {
+			if err := os.Remove(golden); err != nil {
+				t.Errorf("remove stale golden: %v", err)
+			}
+			continue
+		}
+		t.Errorf("%s has no fixture; delete it or rerun with -update", golden)
+	}
+}
+
+// corpusURL returns the page URL recorded on the fixture's first line, or an
+// empty string when the fixture has none.
+func corpusURL(html []byte) string {
+	if match := corpusURLPattern.FindSubmatch(html); match != nil {
+		return string(match[1])
+	}
+	return ""
+}
+
+// renderGolden lays out a parse result as plain text, one field per line
+// followed by the text and HTML bodies, so golden diffs read line by line.
+func renderGolden(article Article, diagnostics ParseDiagnostics, err error) []byte {
+	var b bytes.Buffer
+	if err != nil {
+		fmt.Fprintf(&b, "error: %v\n", err)
+		return b.Bytes()
+	}
+	fmt.Fprintf(&b, "title: %s\n", article.Title)
+	fmt.Fprintf(&b, "byline: %s\n", article.Byline)
+	fmt.Fprintf(&b, "excerpt: %s\n", article.Excerpt)
+	fmt.Fprintf(&b, "image: %s\n", article.Image)
+	hinted := ""
+	if diagnostics.LangHinted {
+		hinted = ", hinted"
+	}
+	fmt.Fprintf(&b, "language: %s (detected: %t%s)\n", article.Language, diagnostics.LangDetected, hinted)
+	fmt.Fprintf(&b, "words: %d\n", article.WordCount)
+	fmt.Fprintf(&b, "\n== text ==\n%s\n", article.TextContent)
+	fmt.Fprintf(&b, "\n== html ==\n%s\n", article.HTMLContent)
+	return b.Bytes()
+}
+
+// firstDifference describes the first line where got departs from want.
+func firstDifference(want, got []byte) string {
+	wantLines := strings.Split(string(want), "\n")
+	gotLines := strings.Split(string(got), "\n")
+	for i := 0; i < len(wantLines) || i < len(gotLines); i++ {
+		var w, g string
+		if i < len(wantLines) {
+			w = wantLines[i]
+		}
+		if i < len(gotLines) {
+			g = gotLines[i]
+		}
+		if w != g {
+			return "line " + strconv.Itoa(i+1) + ":\n want " + excerptAt(w, g) + "\n  got " + excerptAt(g, w)
+		}
+	}
+	return "outputs differ only in line endings"
+}
+
+// excerptAt quotes the part of line around where it first differs from
+// other, keeping long single-line HTML readable in failure output.
+func excerptAt(line, other string) string {
+	const context = 60
+	start := 0
+	for start < len(line) && start < len(other) && line[start] == other[start] {
+		start++
+	}
+	from := max(start-context, 0)
+	to := min(start+context, len(line))
+	excerpt := strconv.Quote(line[from:to])
+	if from > 0 {
+		excerpt = "…" + excerpt
+	}
+	if to < len(line) {
+		excerpt += "…"
+	}
+	return excerpt
+}
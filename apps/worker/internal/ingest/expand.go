@@ -0,0 +1,308 @@
+package ingest
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/url"
+	"path"
+	"regexp"
+	"strings"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// maxIndexLinks caps how many links Expand returns for one page.
+const maxIndexLinks = 100
+
+// Index is the article links found on an index page, such as a newsletter
+// issue or a "best of" list, in page order.
+type Index struct {
+	URL   string
+	Title string
+	Links []IndexLink
+}
+
+// IndexLink is one article link on an index page.
+type IndexLink struct {
+	URL   string
+	Title string
+}
+
+// Expand fetches target and lists the articles it links to instead of
+// archiving it, so an index page can be offered as saves of what it points
+// at. Nothing is persisted. Failures are StageErrors for the fetch or parse
+// stage.
+func (p *Processor) Expand(ctx context.Context, target string) (index Index, err error) {
+	ctx, span := otel.Tracer(tracerName).Start(ctx, "ingest.expand",
+		trace.WithAttributes(attribute.String("url.full", target)))
+	defer func() { endSpan(span, err) }()
+
+	result, err := p.fetcher.Fetch(ctx, target)
+	if err != nil {
+		return Index{}, &StageError{Stage: StageFetch, URL: target, Err: err}
+	}
+	index, err = ExtractIndex(result.FinalURL, result.Body)
+	if err != nil {
+		p.metrics.ParseFailures.Inc()
+		return Index{}, &StageError{Stage: StageParse, URL: target, Err: err}
+	}
+	span.SetAttributes(attribute.Int("keepstack.expand.links", len(index.Links)))
+	return index, nil
+}
+
+// indexChromeElements hold site navigation and boilerplate rather than the
+// page's content, so links inside them are skipped.
+var indexChromeElements = map[atom.Atom]bool{
+	atom.Nav:      true,
+	atom.Header:   true,
+	atom.Footer:   true,
+	atom.Aside:    true,
+	atom.Form:     true,
+	atom.Script:   true,
+	atom.Style:    true,
+	atom.Noscript: true,
+	atom.Template: true,
+}
+
+// indexChromeRoles are ARIA landmark roles with the same meaning.
+var indexChromeRoles = map[string]bool{
+	"navigation":    true,
+	"banner":        true,
+	"contentinfo":   true,
+	"complementary": true,
+}
+
+// shareLinkPrefixes are host and path prefixes of "share this" and
+// submission links.
+var shareLinkPrefixes = []string{
+	"twitter.com/intent/",
+	"x.com/intent/",
+	"facebook.com/sharer",
+	"linkedin.com/sharing/",
+	"linkedin.com/shareArticle",
+	"reddit.com/submit",
+	"pinterest.com/pin/create",
+	"news.ycombinator.com/submitlink",
+	"t.me/share",
+	"wa.me/",
+}
+
+// socialHosts are only followed to individual posts; a bare profile link,
+// as found in newsletter footers, is skipped.
+var socialHosts = map[string]bool{
+	"twitter.com":     true,
+	"x.com":           true,
+	"facebook.com":    true,
+	"instagram.com":   true,
+	"linkedin.com":    true,
+	"threads.net":     true,
+	"tiktok.com":      true,
+	"youtube.com":     true,
+	"github.com":      true,
+	"mastodon.social": true,
+	"bsky.app":        true,
+}
+
+// chromePathSegments mark account, subscription, and site pages.
+var chromePathSegments = map[string]bool{
+	"about":       true,
+	"account":     true,
+	"contact":     true,
+	"feed":        true,
+	"login":       true,
+	"preferences": true,
+	"privacy":     true,
+	"register":    true,
+	"rss":         true,
+	"signin":      true,
+	"signup":      true,
+	"subscribe":   true,
+	"terms":       true,
+	"unsubscribe": true,
+}
+
+// skippedLinkExtensions are files rather than pages.
+var skippedLinkExtensions = map[string]bool{
+	".css": true, ".gif": true, ".ico": true, ".jpeg": true, ".jpg": true,
+	".js": true, ".mp3": true, ".mp4": true, ".png": true, ".svg": true,
+	".webp": true, ".xml": true, ".zip": true,
+}
+
+var yearSegment = regexp.MustCompile(`^(19|20)\d{2}$`)
+
+// ExtractIndex lists the article links in body, a page fetched from
+// pageURL. Links to other sites are kept unless they are share buttons,
+// social profiles, or account pages; links within the site are kept only
+// when their path looks like an article, since the rest are mostly
+// navigation. Links without any text are dropped.
+func ExtractIndex(pageURL string, body []byte) (Index, error) {
+	base, err := url.Parse(pageURL)
+	if err != nil {
+		return Index{}, fmt.Errorf("parse page url: %w", err)
+	}
+	doc, err := html.Parse(bytes.NewReader(body))
+	if err != nil {
+		return Index{}, fmt.Errorf("parse html: %w", err)
+	}
+
+	index := Index{URL: pageURL}
+	seen := make(map[string]int)
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode {
+			switch {
+			case n.DataAtom == atom.Title && index.Title == "":
+				index.Title = nodeText(n)
+				return
+			case n.DataAtom == atom.Base:
+				if href := attr(n, "href"); href != "" {
+					if resolved, err := base.Parse(href); err == nil {
+						base = resolved
+					}
+				}
+			case indexChromeElements[n.DataAtom] || indexChromeRoles[strings.ToLower(attr(n, "role"))]:
+				return
+			case n.DataAtom == atom.A:
+				addIndexLink(&index, seen, base, n)
+				return
+			}
+		}
+		for child := n.FirstChild; child != nil; child = child.NextSibling {
+			walk(child)
+		}
+	}
+	walk(doc)
+
+	links := index.Links[:0]
+	for _, link := range index.Links {
+		if link.Title != "" {
+			links = append(links, link)
+		}
+	}
+	if len(links) > maxIndexLinks {
+		links = links[:maxIndexLinks]
+	}
+	index.Links = links
+	return index, nil
+}
+
+// addIndexLink records the anchor n if it points at an article. A repeated
+// link keeps its first position and takes the first non-empty title.
+func addIndexLink(index *Index, seen map[string]int, base *url.URL, n *html.Node) {
+	href := strings.TrimSpace(attr(n, "href"))
+	if href == "" || strings.HasPrefix(href, "#") {
+		return
+	}
+	target, err := base.Parse(href)
+	if err != nil || !isIndexArticle(base, target) {
+		return
+	}
+	target.Fragment, target.RawFragment = "", ""
+	key := target.String()
+
+	title := nodeText(n)
+	if title == "" {
+		title = strings.TrimSpace(attr(n, "title"))
+	}
+	if i, ok := seen[key]; ok {
+		if index.Links[i].Title == "" {
+			index.Links[i].Title = title
+		}
+		return
+	}
+	seen[key] = len(index.Links)
+	index.Links = append(index.Links, IndexLink{URL: key, Title: title})
+}
+
+func isIndexArticle(page, target *url.URL) bool {
+	if target.Scheme != "http" && target.Scheme != "https" {
+		return false
+	}
+	host := strings.TrimPrefix(strings.ToLower(target.Hostname()), "www.")
+	if !strings.Contains(host, ".") {
+		return false
+	}
+	segments := strings.FieldsFunc(target.Path, func(r rune) bool { return r == '/' })
+	if len(segments) == 0 {
+		return false
+	}
+	if skippedLinkExtensions[strings.ToLower(path.Ext(target.Path))] {
+		return false
+	}
+	for _, segment := range segments {
+		if chromePathSegments[strings.ToLower(segment)] {
+			return false
+		}
+	}
+	hostPath := host + target.Path
+	for _, prefix := range shareLinkPrefixes {
+		if strings.HasPrefix(hostPath, prefix) {
+			return false
+		}
+	}
+	if socialHosts[host] && len(segments) < 2 {
+		return false
+	}
+
+	pageHost := strings.TrimPrefix(strings.ToLower(page.Hostname()), "www.")
+	if host != pageHost {
+		return true
+	}
+	if target.Path == page.Path {
+		return false
+	}
+	return articlePath(segments)
+}
+
+// articlePath reports whether a path on the index's own site looks like an
+// article: a dated path such as /2024/05/title, or a slug of at least three
+// words.
+func articlePath(segments []string) bool {
+	for _, segment := range segments {
+		if yearSegment.MatchString(segment) {
+			return true
+		}
+	}
+	slug := strings.TrimSuffix(segments[len(segments)-1], path.Ext(segments[len(segments)-1]))
+	words := strings.FieldsFunc(slug, func(r rune) bool { return r == '-' || r == '_' })
+	return len(words) >= 3
+}
+
+func attr(n *html.Node, name string) string {
+	for _, a := range n.Attr {
+		if a.Key == name {
+			return a.Val
+		}
+	}
+	return ""
+}
+
+// nodeText returns n's text with whitespace collapsed, falling back to the
+// alt text of an image when there is none.
+func nodeText(n *html.Node) string {
+	var text strings.Builder
+	var alt string
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		switch {
+		case n.Type == html.TextNode:
+			text.WriteString(n.Data)
+			text.WriteByte(' ')
+		case n.Type == html.ElementNode && n.DataAtom == atom.Img && alt == "":
+			alt = attr(n, "alt")
+		}
+		for child := n.FirstChild; child != nil; child = child.NextSibling {
+			walk(child)
+		}
+	}
+	walk(n)
+	if collapsed := strings.Join(strings.Fields(text.String()), " "); collapsed != "" {
+		return collapsed
+	}
+	return strings.Join(strings.Fields(alt), " ")
+}
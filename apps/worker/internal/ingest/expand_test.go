@@ -0,0 +1,75 @@
+package ingest
+
+import (
+	"reflect"
+	"testing"
+)
+
+const newsletterIssue = `<!doctype html>
+<html>
+<head><title>Weekly Picks #42</title></head>
+<body>
+<header><a href="https://news.example.com/2024/05/masthead-story">Masthead</a></header>
+<nav><a href="/archive/issue-41-was-great">Previous issue</a></nav>
+<main>
+  <h1>This week</h1>
+  <p><a href="https://blog.example.org/a-post#comments">  A
+     post  </a> and <a href="https://blog.example.org/a-post">again</a>.</p>
+  <p><a href="https://media.example.net/photo"><img src="x.png" alt="A photo essay"></a></p>
+  <p><a href="/2024/05/our-own-story">Our own story</a></p>
+  <p><a href="/issues/42">This issue</a> <a href="/tags">Tags</a>
+     <a href="/why-we-write-this-newsletter">Why we write</a></p>
+  <p><a href="https://twitter.com/intent/tweet?url=x">Share</a>
+     <a href="https://twitter.com/weeklypicks">Follow us</a>
+     <a href="https://github.com/example/project">A project</a></p>
+  <p><a href="mailto:hi@example.com">Mail</a> <a href="#top">Top</a>
+     <a href="https://cdn.example.net/report.png">Chart</a>
+     <a href="https://partner.example.net/">Partner</a>
+     <a href="https://notitle.example.net/some/page"></a></p>
+  <div role="navigation"><a href="https://other.example.net/nav-link">Nav</a></div>
+</main>
+<footer><a href="https://picks.example.com/unsubscribe">Unsubscribe</a></footer>
+<p><a href="https://picks.example.com/preferences/email">Preferences</a></p>
+</body>
+</html>`
+
+func TestExtractIndex(t *testing.T) {
+	t.Parallel()
+
+	index, err := ExtractIndex("https://picks.example.com/issues/42", []byte(newsletterIssue))
+	if err != nil {
+		t.Fatalf("ExtractIndex returned error: %v", err)
+	}
+	if index.Title != "Weekly Picks #42" || index.URL != "https://picks.example.com/issues/42" {
+		t.Fatalf("unexpected page: %+v", index)
+	}
+
+	want := []IndexLink{
+		{URL: "https://blog.example.org/a-post", Title: "A post"},
+		{URL: "https://media.example.net/photo", Title: "A photo essay"},
+		{URL: "https://picks.example.com/2024/05/our-own-story", Title: "Our own story"},
+		{URL: "https://picks.example.com/why-we-write-this-newsletter", Title: "Why we write"},
+		{URL: "https://github.com/example/project", Title: "A project"},
+	}
+	if !reflect.DeepEqual(index.Links, want) {
+		t.Fatalf("unexpected links:\n got %+v\nwant %+v", index.Links, want)
+	}
+}
+
+func TestExtractIndexCapsLinks(t *testing.T) {
+	t.Parallel()
+
+	body := "<html><body>"
+	for i := 0; i < maxIndexLinks+20; i++ {
+		body += `<a href="https://example.org/post/` + string(rune('a'+i%26)) + `/` + string(rune('a'+i/26)) + `">Post</a>`
+	}
+	body += "</body></html>"
+
+	index, err := ExtractIndex("https://picks.example.com/", []byte(body))
+	if err != nil {
+		t.Fatalf("ExtractIndex returned error: %v", err)
+	}
+	if len(index.Links) != maxIndexLinks {
+		t.Fatalf("expected %d links, got %d", maxIndexLinks, len(index.Links))
+	}
+}
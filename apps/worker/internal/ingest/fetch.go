@@ -14,6 +14,16 @@ const userAgent = "keepstack-worker/0.1"
 type FetchResult struct {
     Body    []byte
     FinalURL string
+    // ETag and LastModified are the response's validators, for sending back
+    // as If-None-Match and If-Modified-Since on the next fetch.
+    ETag         string
+    LastModified string
+    // ContentLanguage is the response's Content-Language header, a hint
+    // for language detection.
+    ContentLanguage string
+    // NotModified reports a 304 answer to a conditional request; Body is
+    // empty and the previously fetched copy is still current.
+    NotModified bool
 }
 
 // Fetcher retrieves HTML documents over HTTP.
@@ -30,11 +40,20 @@ func NewFetcher(timeout time.Duration) *Fetcher {
 
 // Fetch downloads the target URL.
 func (f *Fetcher) Fetch(ctx context.Context, target string) (FetchResult, error) {
+    return f.FetchWithHeaders(ctx, target, nil)
+}
+
+// FetchWithHeaders downloads the target URL, sending headers on top of the
+// defaults. A User-Agent in headers replaces the worker's own.
+func (f *Fetcher) FetchWithHeaders(ctx context.Context, target string, headers map[string]string) (FetchResult, error) {
     req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
     if err != nil {
         return FetchResult{}, fmt.Errorf("build request: %w", err)
     }
     req.Header.Set("User-Agent", userAgent)
+    for name, value := range headers {
+        req.Header.Set(name, value)
+    }
 
     resp, err := f.client.Do(req)
     if err != nil {
@@ -46,15 +65,26 @@ func (f *Fetcher) Fetch(ctx context.Context, target string) (FetchResult, error)
         return FetchResult{}, fmt.Errorf("unexpected status %d", resp.StatusCode)
     }
 
-    body, err := io.ReadAll(resp.Body)
-    if err != nil {
-        return FetchResult{}, fmt.Errorf("read response: %w", err)
-    }
-
     finalURL := target
     if resp.Request != nil && resp.Request.URL != nil {
         finalURL = resp.Request.URL.String()
     }
+    result := FetchResult{
+        FinalURL:        finalURL,
+        ETag:            resp.Header.Get("ETag"),
+        LastModified:    resp.Header.Get("Last-Modified"),
+        ContentLanguage: resp.Header.Get("Content-Language"),
+    }
+    if resp.StatusCode == http.StatusNotModified {
+        result.NotModified = true
+        return result, nil
+    }
+
+    body, err := io.ReadAll(resp.Body)
+    if err != nil {
+        return FetchResult{}, fmt.Errorf("read response: %w", err)
+    }
+    result.Body = body
 
-    return FetchResult{Body: body, FinalURL: finalURL}, nil
+    return result, nil
 }
@@ -0,0 +1,94 @@
+package ingest
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestFetchWithHeaders(t *testing.T) {
+	t.Parallel()
+
+	var got http.Header
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Clone()
+		_, _ = w.Write([]byte("<html></html>"))
+	}))
+	defer server.Close()
+
+	fetcher := NewFetcher(5 * time.Second)
+	if _, err := fetcher.Fetch(context.Background(), server.URL); err != nil {
+		t.Fatalf("fetch: %v", err)
+	}
+	if got.Get("User-Agent") != userAgent || got.Get("Cookie") != "" {
+		t.Fatalf("expected only the default headers, got %v", got)
+	}
+
+	headers := map[string]string{"Cookie": "session=abc", "User-Agent": "custom/1.0"}
+	if _, err := fetcher.FetchWithHeaders(context.Background(), server.URL, headers); err != nil {
+		t.Fatalf("fetch with headers: %v", err)
+	}
+	if got.Get("Cookie") != "session=abc" || got.Get("User-Agent") != "custom/1.0" {
+		t.Fatalf("expected the preference headers, got %v", got)
+	}
+}
+
+func TestFetchConditional(t *testing.T) {
+	t.Parallel()
+
+	const etag = `"v1"`
+	const lastModified = "Mon, 01 Jan 2024 00:00:00 GMT"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("If-None-Match") == etag {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", etag)
+		w.Header().Set("Last-Modified", lastModified)
+		_, _ = w.Write([]byte("<html></html>"))
+	}))
+	defer server.Close()
+
+	fetcher := NewFetcher(5 * time.Second)
+	first, err := fetcher.Fetch(context.Background(), server.URL)
+	if err != nil {
+		t.Fatalf("fetch: %v", err)
+	}
+	if first.NotModified || first.ETag != etag || first.LastModified != lastModified {
+		t.Fatalf("expected validators from a full response, got %+v", first)
+	}
+
+	link := Link{URL: server.URL, ETag: first.ETag, LastModified: first.LastModified}
+	second, err := fetcher.FetchWithHeaders(context.Background(), server.URL, conditionalHeaders(link))
+	if err != nil {
+		t.Fatalf("conditional fetch: %v", err)
+	}
+	if !second.NotModified || len(second.Body) != 0 {
+		t.Fatalf("expected a 304 with no body, got %+v", second)
+	}
+}
+
+func TestConditionalHeadersKeepPreferenceHeaders(t *testing.T) {
+	t.Parallel()
+
+	link := Link{
+		FetchHeaders: map[string]string{"if-none-match": "*", "Cookie": "session=abc"},
+		ETag:         `"v1"`,
+		LastModified: "Mon, 01 Jan 2024 00:00:00 GMT",
+	}
+	headers := conditionalHeaders(link)
+	if _, ok := headers["If-None-Match"]; ok || headers["if-none-match"] != "*" {
+		t.Fatalf("expected the preference If-None-Match to win, got %v", headers)
+	}
+	if headers["If-Modified-Since"] != link.LastModified || headers["Cookie"] != "session=abc" {
+		t.Fatalf("unexpected headers %v", headers)
+	}
+	if len(link.FetchHeaders) != 2 {
+		t.Fatalf("conditionalHeaders modified the link's headers: %v", link.FetchHeaders)
+	}
+	if got := conditionalHeaders(Link{FetchHeaders: link.FetchHeaders}); len(got) != 2 {
+		t.Fatalf("expected no validators without an archive, got %v", got)
+	}
+}
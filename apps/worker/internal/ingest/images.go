@@ -0,0 +1,189 @@
+package ingest
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+
+	"github.com/example/keepstack/media"
+)
+
+// DefaultMaxImageBytes and DefaultMaxImages bound what one article may
+// mirror.
+const (
+	DefaultMaxImageBytes = 5 << 20
+	DefaultMaxImages     = 50
+)
+
+// mediaRecorder records which link's archive shows a mirrored image.
+type mediaRecorder interface {
+	RecordMedia(ctx context.Context, linkID uuid.UUID, hash string) error
+}
+
+// ImageMirror copies the images in archived HTML to media storage and points
+// them at the API's /api/media/:hash, so reading an archive loads nothing
+// from the site it came from and the images outlive it.
+type ImageMirror struct {
+	store     media.Store
+	refs      mediaRecorder
+	client    *http.Client
+	maxBytes  int64
+	maxImages int
+}
+
+// NewImageMirror returns an ImageMirror storing into store and recording
+// each image's link in refs. Each download is bounded by timeout and
+// maxBytes, and at most maxImages are mirrored per article; zero picks the
+// defaults.
+func NewImageMirror(store media.Store, refs *Store, timeout time.Duration, maxBytes int64, maxImages int) *ImageMirror {
+	if maxBytes <= 0 {
+		maxBytes = DefaultMaxImageBytes
+	}
+	if maxImages <= 0 {
+		maxImages = DefaultMaxImages
+	}
+	return &ImageMirror{
+		store:     store,
+		refs:      refs,
+		client:    &http.Client{Timeout: timeout},
+		maxBytes:  maxBytes,
+		maxImages: maxImages,
+	}
+}
+
+// MirrorStats counts what Mirror did with an article's images. Skipped
+// images are past the per-article limit.
+type MirrorStats struct {
+	Stored  int
+	Failed  int
+	Skipped int
+}
+
+// Mirror downloads each absolute http or https image in content, stores it,
+// and rewrites its src to the stored copy. An image that fails to download,
+// is too large, or is not a raster format keeps its original src, so the
+// archive still shows it. Each stored image is recorded as linkID's before it
+// is stored. Images already mirrored are left alone, though recorded again.
+func (m *ImageMirror) Mirror(ctx context.Context, linkID uuid.UUID, content string) (string, MirrorStats) {
+	var stats MirrorStats
+	body := &html.Node{Type: html.ElementNode, Data: "body", DataAtom: atom.Body}
+	nodes, err := html.ParseFragment(strings.NewReader(content), body)
+	if err != nil {
+		return content, stats
+	}
+
+	mirrored := make(map[string]string)
+	changed := false
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode && n.DataAtom == atom.Img {
+			for i, a := range n.Attr {
+				if a.Key != "src" {
+					continue
+				}
+				if hash := media.HashFromPath(a.Val); hash != "" {
+					if err := m.refs.RecordMedia(ctx, linkID, hash); err != nil {
+						stats.Failed++
+					}
+					continue
+				}
+				if !mirrorable(a.Val) {
+					continue
+				}
+				path, ok := mirrored[a.Val]
+				if !ok {
+					if len(mirrored) >= m.maxImages {
+						stats.Skipped++
+						break
+					}
+					path, err = m.fetchAndStore(ctx, linkID, a.Val)
+					if err != nil {
+						stats.Failed++
+					} else {
+						stats.Stored++
+					}
+					mirrored[a.Val] = path
+				}
+				if path != "" {
+					n.Attr[i].Val = path
+					changed = true
+				}
+			}
+		}
+		for child := n.FirstChild; child != nil; child = child.NextSibling {
+			walk(child)
+		}
+	}
+	for _, node := range nodes {
+		walk(node)
+	}
+	if !changed {
+		return content, stats
+	}
+
+	var out strings.Builder
+	for _, node := range nodes {
+		if err := html.Render(&out, node); err != nil {
+			return content, stats
+		}
+	}
+	return out.String(), stats
+}
+
+// mirrorable reports whether src is an image on another site.
+func mirrorable(src string) bool {
+	parsed, err := url.Parse(src)
+	return err == nil && (parsed.Scheme == "http" || parsed.Scheme == "https") && parsed.Host != ""
+}
+
+// fetchAndStore downloads one image, records it as linkID's, and stores it,
+// returning the path it is served from.
+func (m *ImageMirror) fetchAndStore(ctx context.Context, linkID uuid.UUID, src string) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, src, nil)
+	if err != nil {
+		return "", fmt.Errorf("build request: %w", err)
+	}
+	req.Header.Set("User-Agent", userAgent)
+	req.Header.Set("Accept", "image/avif,image/webp,image/png,image/jpeg,image/gif")
+
+	resp, err := m.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("fetch image: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 400 {
+		return "", fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	if resp.ContentLength > m.maxBytes {
+		return "", fmt.Errorf("image is %d bytes, over the %d byte limit", resp.ContentLength, m.maxBytes)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, m.maxBytes+1))
+	if err != nil {
+		return "", fmt.Errorf("read image: %w", err)
+	}
+	if int64(len(body)) > m.maxBytes {
+		return "", fmt.Errorf("image is over the %d byte limit", m.maxBytes)
+	}
+	contentType, ok := media.Sniff(body)
+	if !ok {
+		return "", fmt.Errorf("unsupported image type %q", contentType)
+	}
+
+	hash := media.Hash(body)
+	if err := m.refs.RecordMedia(ctx, linkID, hash); err != nil {
+		return "", fmt.Errorf("record image: %w", err)
+	}
+	if err := m.store.Put(ctx, hash, contentType, body); err != nil {
+		return "", err
+	}
+	return media.Path(hash), nil
+}
@@ -0,0 +1,104 @@
+package ingest
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/example/keepstack/media"
+)
+
+var testPNG = []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR\x00\x00\x00\x01")
+
+func newImageServer(t *testing.T, requests *atomic.Int32) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		switch r.URL.Path {
+		case "/cat.png":
+			w.Write(testPNG)
+		case "/big.png":
+			w.Write(append(append([]byte{}, testPNG...), make([]byte, 64)...))
+		case "/logo.svg":
+			w.Header().Set("Content-Type", "image/svg+xml")
+			w.Write([]byte(`<svg xmlns="http://www.w3.org/2000/svg"></svg>`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestImageMirrorRewritesStoredImages(t *testing.T) {
+	t.Parallel()
+
+	var requests atomic.Int32
+	srv := newImageServer(t, &requests)
+	store := media.NewDirStore(t.TempDir())
+	refs := &fakeMediaReferences{}
+	mirror := NewImageMirror(store, nil, time.Second, 32, 0)
+	mirror.refs = refs
+	linkID := uuid.New()
+
+	content := `<p>Intro</p><img src="` + srv.URL + `/cat.png" alt="cat">` +
+		`<figure><img src="` + srv.URL + `/cat.png"></figure>` +
+		`<img src="` + srv.URL + `/logo.svg"><img src="` + srv.URL + `/missing.png">` +
+		`<img src="` + srv.URL + `/big.png"><img src="/relative.png">`
+	got, stats := mirror.Mirror(context.Background(), linkID, content)
+
+	path := media.Path(media.Hash(testPNG))
+	if strings.Count(got, `src="`+path+`"`) != 2 {
+		t.Fatalf("expected both copies of the png to point at %s, got %s", path, got)
+	}
+	for _, kept := range []string{srv.URL + "/logo.svg", srv.URL + "/missing.png", srv.URL + "/big.png", `src="/relative.png"`, `alt="cat"`} {
+		if !strings.Contains(got, kept) {
+			t.Fatalf("expected %q to be kept, got %s", kept, got)
+		}
+	}
+	if stats != (MirrorStats{Stored: 1, Failed: 3}) {
+		t.Fatalf("unexpected stats %+v", stats)
+	}
+	if requests.Load() != 4 {
+		t.Fatalf("expected each image to be downloaded once, got %d requests", requests.Load())
+	}
+	if _, err := store.Get(context.Background(), media.Hash(testPNG)); err != nil {
+		t.Fatalf("expected the png to be stored: %v", err)
+	}
+	if got := refs.recorded[linkID]; len(got) != 1 || got[0] != media.Hash(testPNG) {
+		t.Fatalf("expected the png recorded for the link once, got %v", got)
+	}
+
+	// Mirroring the stored HTML again downloads nothing but still records
+	// the image for the link archiving it.
+	requests.Store(0)
+	other := uuid.New()
+	again, _ := mirror.Mirror(context.Background(), other, `<img src="`+path+`">`)
+	if again != `<img src="`+path+`">` || requests.Load() != 0 {
+		t.Fatalf("expected mirrored images to be left alone, got %s after %d requests", again, requests.Load())
+	}
+	if got := refs.recorded[other]; len(got) != 1 || got[0] != media.Hash(testPNG) {
+		t.Fatalf("expected the mirrored image recorded for the other link, got %v", got)
+	}
+}
+
+func TestImageMirrorLimitsImagesPerArticle(t *testing.T) {
+	t.Parallel()
+
+	var requests atomic.Int32
+	srv := newImageServer(t, &requests)
+	mirror := NewImageMirror(media.NewDirStore(t.TempDir()), nil, time.Second, 0, 1)
+	mirror.refs = &fakeMediaReferences{}
+
+	content := `<img src="` + srv.URL + `/cat.png"><img src="` + srv.URL + `/other.png">`
+	got, stats := mirror.Mirror(context.Background(), uuid.New(), content)
+	if stats != (MirrorStats{Stored: 1, Skipped: 1}) || !strings.Contains(got, srv.URL+"/other.png") {
+		t.Fatalf("expected the second image to be skipped, got %+v: %s", stats, got)
+	}
+}
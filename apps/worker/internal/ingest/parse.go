@@ -10,8 +10,13 @@ import (
 	"github.com/abadojack/whatlanggo"
 	readability "github.com/go-shiori/go-readability"
 	"github.com/microcosm-cc/bluemonday"
+
+	"github.com/example/keepstack/sanitize"
 )
 
+// defaultPolicy sanitizes archived HTML with sanitize.DefaultFeatures.
+var defaultPolicy = sanitize.Options{Features: sanitize.DefaultFeatures}.Policy()
+
 // Article represents parsed content from a web page.
 type Article struct {
 	Title       string
@@ -20,16 +25,43 @@ type Article struct {
 	HTMLContent string
 	WordCount   int
 	Language    string
+	// Excerpt and Image come from the page's description and preview image
+	// metadata, when it has any.
+	Excerpt string
+	Image   string
+	// Paywalled is set when the text looks cut off by a paywall, so the
+	// archive is partial.
+	Paywalled bool
 }
 
 // ParseDiagnostics captures metadata generated while parsing content.
 type ParseDiagnostics struct {
 	LangDetectDuration time.Duration
 	LangDetected       bool
+	// LangHinted is set when detection was unreliable and the language
+	// the page declared was used instead.
+	LangHinted bool
 }
 
+// undeterminedLanguages are language tags that name no single language.
+var undeterminedLanguages = map[string]bool{"und": true, "mul": true, "zxx": true, "mis": true}
+
 // Parse extracts readable content from HTML bytes.
 func Parse(targetURL string, html []byte) (Article, ParseDiagnostics, error) {
+	return ParseWithPolicy(targetURL, html, defaultPolicy)
+}
+
+// ParseWithPolicy is Parse with the article HTML sanitized by policy, as
+// configured through the SANITIZE_* settings.
+func ParseWithPolicy(targetURL string, html []byte, policy *bluemonday.Policy) (Article, ParseDiagnostics, error) {
+	return ParseWithLanguage(targetURL, html, policy, "")
+}
+
+// ParseWithLanguage is ParseWithPolicy for a page served with the given
+// Content-Language header. The page's <html lang>, or failing that the
+// header, is the language used when the text is too short or mixed for
+// reliable detection.
+func ParseWithLanguage(targetURL string, html []byte, policy *bluemonday.Policy, contentLanguage string) (Article, ParseDiagnostics, error) {
 	reader := bytes.NewReader(html)
 
 	var pageURL *url.URL
@@ -47,15 +79,21 @@ func Parse(targetURL string, html []byte) (Article, ParseDiagnostics, error) {
 	title := strings.TrimSpace(extracted.Title)
 	byline := strings.TrimSpace(extracted.Byline)
 	text := strings.TrimSpace(extracted.TextContent)
-	cleanedHTML := sanitizeHTML(extracted.Content)
+	cleanedHTML := strings.TrimSpace(policy.Sanitize(extracted.Content))
 	if cleanedHTML == "" {
-		cleanedHTML = sanitizeHTML(string(html))
+		cleanedHTML = strings.TrimSpace(policy.Sanitize(string(html)))
 	}
 	if text == "" {
 		text = strings.TrimSpace(bluemonday.StrictPolicy().Sanitize(string(html)))
 	}
 
 	lang, detectDuration, langDetected := detectLanguage(text)
+	var langHinted bool
+	if !langDetected {
+		if hint := languageHint(extracted.Language, contentLanguage); hint != "" {
+			lang, langHinted = hint, true
+		}
+	}
 
 	article := Article{
 		Title:       title,
@@ -64,11 +102,15 @@ func Parse(targetURL string, html []byte) (Article, ParseDiagnostics, error) {
 		HTMLContent: cleanedHTML,
 		WordCount:   len(strings.Fields(text)),
 		Language:    lang,
+		Excerpt:     strings.TrimSpace(extracted.Excerpt),
+		Image:       resolveImage(pageURL, extracted.Image),
 	}
+	article.Paywalled = detectPaywall(html, text, article.WordCount)
 
 	diagnostics := ParseDiagnostics{
 		LangDetectDuration: detectDuration,
 		LangDetected:       langDetected,
+		LangHinted:         langHinted,
 	}
 
 	return article, diagnostics, nil
@@ -98,8 +140,58 @@ func detectLanguage(text string) (string, time.Duration, bool) {
 	return lang, duration, lang != ""
 }
 
-func sanitizeHTML(raw string) string {
-	policy := bluemonday.UGCPolicy()
-	sanitized := policy.Sanitize(raw)
-	return strings.TrimSpace(sanitized)
+// languageHint returns the language a page declares, in the form
+// detectLanguage reports: the primary subtag of its <html lang>, or of its
+// Content-Language header when that names exactly one language. Tags that
+// name no single language are ignored.
+func languageHint(htmlLang, contentLanguage string) string {
+	if hint := primaryLanguage(htmlLang); hint != "" {
+		return hint
+	}
+	if strings.Contains(contentLanguage, ",") {
+		return ""
+	}
+	return primaryLanguage(contentLanguage)
+}
+
+func primaryLanguage(tag string) string {
+	tag = strings.ToLower(strings.TrimSpace(tag))
+	if i := strings.IndexAny(tag, "-_"); i >= 0 {
+		tag = tag[:i]
+	}
+	if len(tag) < 2 || len(tag) > 3 || undeterminedLanguages[tag] {
+		return ""
+	}
+	for _, r := range tag {
+		if r < 'a' || r > 'z' {
+			return ""
+		}
+	}
+	if len(tag) == 3 {
+		// detectLanguage reports ISO 639-1 codes where one exists.
+		if code := whatlanggo.CodeToLang(tag).Iso6391(); code != "" {
+			return code
+		}
+	}
+	return tag
+}
+
+// resolveImage makes a preview image URL absolute against the page and drops
+// anything that is not http or https.
+func resolveImage(pageURL *url.URL, raw string) string {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return ""
+	}
+	image, err := url.Parse(raw)
+	if err != nil {
+		return ""
+	}
+	if pageURL != nil {
+		image = pageURL.ResolveReference(image)
+	}
+	if image.Scheme != "http" && image.Scheme != "https" {
+		return ""
+	}
+	return image.String()
 }
@@ -89,3 +89,84 @@ func readFixture(t *testing.T, name string) []byte {
 	}
 	return data
 }
+
+func TestParsePreviewMetadata(t *testing.T) {
+	t.Parallel()
+
+	html := []byte(`<!doctype html><html><head>
+<title>Preview page</title>
+<meta property="og:description" content=" A short summary. " />
+<meta property="og:image" content="/images/cover.png" />
+</head><body><article><p>Body text long enough to count as the article content for the parser.</p></article></body></html>`)
+	article, _, err := Parse("https://example.com/posts/preview", html)
+	if err != nil {
+		t.Fatalf("Parse returned error: %v", err)
+	}
+	if article.Excerpt != "A short summary." {
+		t.Fatalf("expected the og description, got %q", article.Excerpt)
+	}
+	if article.Image != "https://example.com/images/cover.png" {
+		t.Fatalf("expected the image to resolve against the page, got %q", article.Image)
+	}
+}
+
+func TestParseWithLanguageFallsBackToDeclaredLanguage(t *testing.T) {
+	t.Parallel()
+
+	page := func(lang string) []byte {
+		return []byte(`<!doctype html><html` + lang + `><head><title>Kurz</title></head>
+<body><article><p>Danke schön.</p></article></body></html>`)
+	}
+
+	cases := []struct {
+		name            string
+		html            []byte
+		contentLanguage string
+		want            string
+	}{
+		{name: "html lang", html: page(` lang="de-AT"`), want: "de"},
+		{name: "html lang wins over header", html: page(` lang="de"`), contentLanguage: "fr", want: "de"},
+		{name: "header", html: page(""), contentLanguage: "de-DE", want: "de"},
+		{name: "several header languages", html: page(""), contentLanguage: "de, fr", want: ""},
+		{name: "undetermined", html: page(` lang="und"`), want: ""},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			article, diagnostics, err := ParseWithLanguage("https://example.de/kurz", tc.html, defaultPolicy, tc.contentLanguage)
+			if err != nil {
+				t.Fatalf("ParseWithLanguage returned error: %v", err)
+			}
+			if diagnostics.LangDetected {
+				t.Fatalf("expected detection to be unreliable for %q", article.TextContent)
+			}
+			if article.Language != tc.want || diagnostics.LangHinted != (tc.want != "") {
+				t.Fatalf("expected language %q, got %q (hinted: %t)", tc.want, article.Language, diagnostics.LangHinted)
+			}
+		})
+	}
+}
+
+func TestParseWithLanguagePrefersReliableDetection(t *testing.T) {
+	t.Parallel()
+
+	html := readFixture(t, "spanish_article.html")
+	article, diagnostics, err := ParseWithLanguage("https://example.com/articulos/spanish", html, defaultPolicy, "en")
+	if err != nil {
+		t.Fatalf("ParseWithLanguage returned error: %v", err)
+	}
+	if article.Language != "es" || !diagnostics.LangDetected || diagnostics.LangHinted {
+		t.Fatalf("expected the detected language over the header, got %q (%+v)", article.Language, diagnostics)
+	}
+}
+
+func TestPrimaryLanguageMapsThreeLetterCodes(t *testing.T) {
+	t.Parallel()
+
+	for tag, want := range map[string]string{"deu": "de", "EN_gb": "en", "e1": "", "zxx": "", "": ""} {
+		if got := primaryLanguage(tag); got != want {
+			t.Errorf("primaryLanguage(%q) = %q, want %q", tag, got, want)
+		}
+	}
+}
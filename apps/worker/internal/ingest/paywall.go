@@ -0,0 +1,74 @@
+package ingest
+
+import (
+	"regexp"
+	"strings"
+)
+
+// paywallWordLimit is the longest extracted text still considered cut off
+// when a page shows paywall markup or a subscribe prompt. Longer text is
+// treated as the full article with a paywall widget beside it.
+const paywallWordLimit = 400
+
+// paywallTailChars is how much of the end of the extracted text is searched
+// for a subscribe prompt, where truncated articles put it.
+const paywallTailChars = 600
+
+// paywallNotFree matches the schema.org markup publishers add for search
+// engines when an article is not free to read.
+var paywallNotFree = regexp.MustCompile(`(?i)"isAccessibleForFree"\s*:\s*"?false"?`)
+
+// paywallContentTier matches the Open Graph article:content_tier meta tag
+// for locked or metered content, with the attributes in either order.
+var paywallContentTier = regexp.MustCompile(`(?i)<meta[^>]+(?:article:content_tier[^>]+content\s*=\s*["']?(?:locked|metered)|content\s*=\s*["']?(?:locked|metered)["']?[^>]+article:content_tier)`)
+
+// paywallMarkup matches class and id names of common paywall overlays.
+var paywallMarkup = regexp.MustCompile(`(?i)(?:class|id)\s*=\s*["'][^"']*\b(?:paywall|regwall|subscriber-only|premium-content|meteredContent|tp-modal|piano-offer)`)
+
+// paywallPrompts are phrases shown where a paywalled article stops.
+var paywallPrompts = []string{
+	"subscribe to continue reading",
+	"subscribe to keep reading",
+	"subscribe to read the full",
+	"subscribe to read more",
+	"subscribe now to continue",
+	"to continue reading, subscribe",
+	"this article is for subscribers",
+	"this content is for subscribers",
+	"available to subscribers only",
+	"exclusive to subscribers",
+	"already a subscriber",
+	"sign in to continue reading",
+	"log in to continue reading",
+	"become a member to read",
+	"you have reached your free article limit",
+	"you've reached your free article limit",
+	"create a free account to continue reading",
+}
+
+// detectPaywall reports whether the article extracted from raw looks cut off
+// by a paywall. Publisher markup saying the article is not free is enough on
+// its own. Otherwise the text must be short and either end in a subscribe
+// prompt or come from a page with paywall overlay markup.
+func detectPaywall(raw []byte, text string, wordCount int) bool {
+	if paywallNotFree.Match(raw) || paywallContentTier.Match(raw) {
+		return true
+	}
+	if wordCount > paywallWordLimit {
+		return false
+	}
+	if paywallMarkup.Match(raw) {
+		return true
+	}
+	tail := strings.ToLower(text)
+	if len(tail) > paywallTailChars {
+		tail = tail[len(tail)-paywallTailChars:]
+	}
+	tail = strings.ReplaceAll(tail, "’", "'")
+	for _, prompt := range paywallPrompts {
+		if strings.Contains(tail, prompt) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,84 @@
+package ingest
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDetectPaywall(t *testing.T) {
+	t.Parallel()
+
+	longText := strings.Repeat("word ", paywallWordLimit+1)
+	cases := []struct {
+		name string
+		raw  string
+		text string
+		want bool
+	}{
+		{
+			name: "schema.org not free",
+			raw:  `<script type="application/ld+json">{"@type":"NewsArticle","isAccessibleForFree": "False"}</script>`,
+			text: longText,
+			want: true,
+		},
+		{
+			name: "locked content tier",
+			raw:  `<meta property="article:content_tier" content="locked">`,
+			text: longText,
+			want: true,
+		},
+		{
+			name: "content tier before property",
+			raw:  `<meta content="metered" property="article:content_tier">`,
+			text: "A short teaser.",
+			want: true,
+		},
+		{
+			name: "free content tier",
+			raw:  `<meta property="article:content_tier" content="free">`,
+			text: "A short note.",
+			want: false,
+		},
+		{
+			name: "subscribe prompt at the end",
+			raw:  `<p>Teaser</p>`,
+			text: "The first paragraph of the story. You’ve reached your free article limit. Subscribe to continue reading.",
+			want: true,
+		},
+		{
+			name: "paywall overlay on short text",
+			raw:  `<div class="article-paywall">Subscribe</div>`,
+			text: "The first paragraph of the story.",
+			want: true,
+		},
+		{
+			name: "paywall markup beside a full article",
+			raw:  `<div class="paywall-hidden"></div>`,
+			text: longText + "Already a subscriber? Sign in.",
+			want: false,
+		},
+		{
+			name: "prompt quoted early in a short post",
+			raw:  `<p>Post</p>`,
+			text: "Why \"subscribe to continue reading\" banners annoy me. " + strings.Repeat("more ", 150),
+			want: false,
+		},
+		{
+			name: "plain article",
+			raw:  `<article><p>Hello</p></article>`,
+			text: "Hello there.",
+			want: false,
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := detectPaywall([]byte(tc.raw), tc.text, len(strings.Fields(tc.text))); got != tc.want {
+				t.Fatalf("detectPaywall = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
@@ -2,9 +2,13 @@ package ingest
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"net/url"
+	"slices"
 	"strings"
 	"time"
 
@@ -27,57 +31,185 @@ func NewStore(pool *pgxpool.Pool) *Store {
 // Link represents the minimal data needed for ingestion.
 type Link struct {
 	ID        uuid.UUID
+	UserID    uuid.UUID
 	URL       string
 	CreatedAt time.Time
+	// CapturedHTML is the page as a browser extension saved it. When set it
+	// is archived in place of fetching URL.
+	CapturedHTML string
+	// FetchHeaders are extra request headers from the owner's preference
+	// for the link's domain.
+	FetchHeaders map[string]string
+	// ETag and LastModified are the validators of the fetch the current
+	// archive was built from, empty when there is no archive or the server
+	// sent none.
+	ETag         string
+	LastModified string
 }
 
 // LookupLink retrieves a link record by identifier.
 func (s *Store) LookupLink(ctx context.Context, id uuid.UUID) (Link, error) {
-	row := s.pool.QueryRow(ctx, `SELECT id, url, created_at FROM links WHERE id = $1`, pgtype.UUID{Bytes: id, Valid: true})
+	row := s.pool.QueryRow(ctx, `-- name: LookupLink :one
+SELECT l.id, l.user_id, l.url, l.created_at, c.html, a.etag, a.last_modified,
+       (
+           SELECT dp.fetch_headers
+           FROM domain_preferences dp
+           WHERE dp.user_id = l.user_id
+             AND domain_covers(dp.domain, url_host(l.url))
+           ORDER BY length(dp.domain) DESC
+           LIMIT 1
+       ) AS fetch_headers
+FROM links l
+LEFT JOIN link_captures c ON c.link_id = l.id
+LEFT JOIN archives a ON a.link_id = l.id
+WHERE l.id = $1`, pgtype.UUID{Bytes: id, Valid: true})
 	var link Link
-	var idVal pgtype.UUID
+	var idVal, userID pgtype.UUID
 	var created pgtype.Timestamptz
-	if err := row.Scan(&idVal, &link.URL, &created); err != nil {
+	var captured, etag, lastModified pgtype.Text
+	var headers []byte
+	if err := row.Scan(&idVal, &userID, &link.URL, &created, &captured, &etag, &lastModified, &headers); err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return Link{}, fmt.Errorf("link not found: %w", err)
 		}
 		return Link{}, fmt.Errorf("query link: %w", err)
 	}
 	link.ID = uuid.UUID(idVal.Bytes)
+	link.UserID = uuid.UUID(userID.Bytes)
 	if created.Valid {
 		link.CreatedAt = created.Time
 	}
+	link.CapturedHTML = captured.String
+	link.ETag = etag.String
+	link.LastModified = lastModified.String
+	if len(headers) > 0 {
+		if err := json.Unmarshal(headers, &link.FetchHeaders); err != nil {
+			return Link{}, fmt.Errorf("decode fetch headers: %w", err)
+		}
+	}
 	return link, nil
 }
 
-// PersistResult writes the parsed article back to the database.
-func (s *Store) PersistResult(ctx context.Context, link Link, article Article, rawHTML []byte) error {
+// ListUnarchived returns the links created at or after since that have no
+// archive yet, oldest first and at most limit of them.
+func (s *Store) ListUnarchived(ctx context.Context, since time.Time, limit int) ([]uuid.UUID, error) {
+	rows, err := s.pool.Query(ctx, `-- name: ListUnarchivedLinks :many
+SELECT l.id
+FROM links l
+LEFT JOIN archives a ON a.link_id = l.id
+WHERE a.link_id IS NULL AND l.created_at >= $1
+ORDER BY l.created_at
+LIMIT $2`, pgtype.Timestamptz{Time: since, Valid: true}, int32(limit))
+	if err != nil {
+		return nil, fmt.Errorf("query unarchived links: %w", err)
+	}
+	ids, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (uuid.UUID, error) {
+		var id pgtype.UUID
+		err := row.Scan(&id)
+		return uuid.UUID(id.Bytes), err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("scan unarchived links: %w", err)
+	}
+	return ids, nil
+}
+
+// lockMediaSQL takes the transaction-scoped lock on one mirrored image.
+// Recording a reference and purging an unreferenced image both hold it, so
+// an image is never deleted between being referenced and being stored.
+const lockMediaSQL = `-- name: LockMedia :exec
+SELECT pg_advisory_xact_lock(hashtext('link_media'), hashtext($1))`
+
+// RecordMedia notes that the link's archive shows the mirrored image hash.
+// The image is stored afterwards, so a purge running meanwhile either
+// finished before the reference was recorded or sees it.
+func (s *Store) RecordMedia(ctx context.Context, linkID uuid.UUID, hash string) error {
+	tx, err := s.pool.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, lockMediaSQL, hash); err != nil {
+		return fmt.Errorf("lock media: %w", err)
+	}
+	if _, err := tx.Exec(ctx, `-- name: InsertLinkMedia :exec
+INSERT INTO link_media (link_id, hash) VALUES ($1, $2)
+ON CONFLICT DO NOTHING`, pgtype.UUID{Bytes: linkID, Valid: true}, hash); err != nil {
+		return fmt.Errorf("insert link media: %w", err)
+	}
+	return tx.Commit(ctx)
+}
+
+// LockMedia calls purge with the hashes in hashes that some link still
+// references, holding each image's lock so no new reference to any of them
+// is recorded until purge returns.
+func (s *Store) LockMedia(ctx context.Context, hashes []string, purge func(referenced []string)) error {
 	tx, err := s.pool.BeginTx(ctx, pgx.TxOptions{})
 	if err != nil {
 		return fmt.Errorf("begin tx: %w", err)
 	}
 	defer tx.Rollback(ctx)
 
+	// Locking in order keeps two purges of overlapping images from
+	// deadlocking.
+	for _, hash := range slices.Sorted(slices.Values(hashes)) {
+		if _, err := tx.Exec(ctx, lockMediaSQL, hash); err != nil {
+			return fmt.Errorf("lock media: %w", err)
+		}
+	}
+	rows, err := tx.Query(ctx, `-- name: ListReferencedMedia :many
+SELECT DISTINCT hash
+FROM link_media
+WHERE hash = ANY($1::text[])`, hashes)
+	if err != nil {
+		return fmt.Errorf("query referenced media: %w", err)
+	}
+	referenced, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return fmt.Errorf("scan referenced media: %w", err)
+	}
+
+	purge(referenced)
+	return tx.Commit(ctx)
+}
+
+// PersistResult writes the parsed article back to the database, along with
+// the validators of the fetch it came from.
+func (s *Store) PersistResult(ctx context.Context, link Link, article Article, fetched FetchResult) error {
+	tx, err := s.pool.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	// Links saved with preserve_title keep the title they were given; the
+	// extracted one is still stored on the archive below.
 	if article.Title != "" {
-		if _, err := tx.Exec(ctx, `UPDATE links SET title = $2 WHERE id = $1`, pgtype.UUID{Bytes: link.ID, Valid: true}, pgtype.Text{String: article.Title, Valid: true}); err != nil {
+		if _, err := tx.Exec(ctx, `-- name: UpdateLinkTitle :exec
+UPDATE links SET title = $2 WHERE id = $1 AND NOT preserve_title`, pgtype.UUID{Bytes: link.ID, Valid: true}, pgtype.Text{String: article.Title, Valid: true}); err != nil {
 			return fmt.Errorf("update title: %w", err)
 		}
 	}
 
 	if source := extractDomain(link.URL); source != "" {
-		if _, err := tx.Exec(ctx, `UPDATE links SET source_domain = $2 WHERE id = $1`, pgtype.UUID{Bytes: link.ID, Valid: true}, pgtype.Text{String: source, Valid: true}); err != nil {
+		if _, err := tx.Exec(ctx, `-- name: UpdateLinkSourceDomain :exec
+UPDATE links SET source_domain = $2 WHERE id = $1`, pgtype.UUID{Bytes: link.ID, Valid: true}, pgtype.Text{String: source, Valid: true}); err != nil {
 			return fmt.Errorf("update source_domain: %w", err)
 		}
 	}
 
 	htmlContent := article.HTMLContent
 	if htmlContent == "" {
-		htmlContent = string(rawHTML)
+		htmlContent = string(fetched.Body)
 	}
 
-	if _, err := tx.Exec(ctx, `INSERT INTO archives (link_id, html, extracted_text, word_count, lang, title, byline)
-        VALUES ($1, $2, $3, $4, $5, $6, $7)
-        ON CONFLICT (link_id) DO UPDATE SET html = EXCLUDED.html, extracted_text = EXCLUDED.extracted_text, word_count = EXCLUDED.word_count, lang = EXCLUDED.lang, title = EXCLUDED.title, byline = EXCLUDED.byline`,
+	// The digests let the archive-verify cron notice archives that changed
+	// after this point without being ingested again.
+	if _, err := tx.Exec(ctx, `-- name: UpsertArchive :exec
+INSERT INTO archives (link_id, html, extracted_text, word_count, lang, title, byline, paywalled, html_sha256, text_sha256, etag, last_modified)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+        ON CONFLICT (link_id) DO UPDATE SET html = EXCLUDED.html, extracted_text = EXCLUDED.extracted_text, word_count = EXCLUDED.word_count, lang = EXCLUDED.lang, title = EXCLUDED.title, byline = EXCLUDED.byline, paywalled = EXCLUDED.paywalled, html_sha256 = EXCLUDED.html_sha256, text_sha256 = EXCLUDED.text_sha256, etag = EXCLUDED.etag, last_modified = EXCLUDED.last_modified`,
 		pgtype.UUID{Bytes: link.ID, Valid: true},
 		pgtype.Text{String: htmlContent, Valid: true},
 		pgtype.Text{String: article.TextContent, Valid: true},
@@ -85,16 +217,36 @@ func (s *Store) PersistResult(ctx context.Context, link Link, article Article, r
 		pgtype.Text{String: article.Language, Valid: article.Language != ""},
 		pgtype.Text{String: article.Title, Valid: article.Title != ""},
 		pgtype.Text{String: article.Byline, Valid: article.Byline != ""},
+		article.Paywalled,
+		contentSHA256(htmlContent),
+		contentSHA256(article.TextContent),
+		pgtype.Text{String: fetched.ETag, Valid: fetched.ETag != ""},
+		pgtype.Text{String: fetched.LastModified, Valid: fetched.LastModified != ""},
 	); err != nil {
 		return fmt.Errorf("upsert archive: %w", err)
 	}
 
+	// A capture is only needed until it has been archived.
+	if link.CapturedHTML != "" {
+		if _, err := tx.Exec(ctx, `-- name: DeleteLinkCapture :exec
+DELETE FROM link_captures WHERE link_id = $1`, pgtype.UUID{Bytes: link.ID, Valid: true}); err != nil {
+			return fmt.Errorf("delete capture: %w", err)
+		}
+	}
+
 	if err := tx.Commit(ctx); err != nil {
 		return fmt.Errorf("commit tx: %w", err)
 	}
 	return nil
 }
 
+// contentSHA256 returns the hex SHA-256 of content's bytes, matching the
+// archive_content_sha256 SQL function.
+func contentSHA256(content string) string {
+	sum := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(sum[:])
+}
+
 func extractDomain(raw string) string {
 	parsed, err := url.Parse(raw)
 	if err != nil {
@@ -2,12 +2,20 @@ package ingest
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/microcosm-cc/bluemonday"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 
 	"github.com/example/keepstack/apps/worker/internal/observability"
+	"github.com/example/keepstack/observe"
 )
 
 // Processor ties together fetch, parse, and persist steps.
@@ -15,42 +23,186 @@ type Processor struct {
 	fetcher *Fetcher
 	store   *Store
 	metrics *observability.Metrics
+	policy  *bluemonday.Policy
+	images  *ImageMirror
+
+	onIngested func(context.Context, Link)
+	reporter   StatusReporter
 }
 
 // NewProcessor constructs a Processor.
 func NewProcessor(fetcher *Fetcher, store *Store, metrics *observability.Metrics) *Processor {
-	return &Processor{fetcher: fetcher, store: store, metrics: metrics}
+	return &Processor{fetcher: fetcher, store: store, metrics: metrics, policy: defaultPolicy}
+}
+
+// WithImageMirror copies archived images to media storage before the
+// archive is persisted.
+func (p *Processor) WithImageMirror(images *ImageMirror) {
+	p.images = images
+}
+
+// WithSanitizePolicy sanitizes archived HTML with policy instead of the
+// default one.
+func (p *Processor) WithSanitizePolicy(policy *bluemonday.Policy) {
+	p.policy = policy
+}
+
+// OnIngested registers fn to run after a link's archive is persisted, such as
+// to tell connected clients the article is ready.
+func (p *Processor) OnIngested(fn func(context.Context, Link)) {
+	p.onIngested = fn
+}
+
+// WithStatusReporter sends each job's progress and diagnostics to reporter.
+func (p *Processor) WithStatusReporter(reporter StatusReporter) {
+	p.reporter = reporter
+}
+
+// Status is a job's progress through the pipeline as seen by a
+// StatusReporter.
+type Status string
+
+// Statuses passed to a StatusReporter, in pipeline order.
+const (
+	StatusFetching   Status = "fetching"
+	StatusParsing    Status = "parsing"
+	StatusPersisting Status = "persisting"
+	StatusIngested   Status = "ingested"
+	StatusFailed     Status = "failed"
+)
+
+// Diagnostics describes how a job's fetch and parse went.
+type Diagnostics struct {
+	FinalURL      string
+	FetchBytes    int
+	FetchDuration time.Duration
+	ParseDuration time.Duration
+	Lang          string
+	LangDetected  bool
+	WordCount     int
+}
+
+// StatusReporter receives progress for each job. Reporting is best effort:
+// implementations handle their own failures and never fail the job.
+type StatusReporter interface {
+	ReportStatus(ctx context.Context, linkID uuid.UUID, status Status, failure *StageError)
+	ReportDiagnostics(ctx context.Context, linkID uuid.UUID, diagnostics Diagnostics)
 }
 
-// Process executes the ingestion pipeline for a link identifier.
-func (p *Processor) Process(ctx context.Context, linkID uuid.UUID) error {
-	link, err := p.store.LookupLink(ctx, linkID)
+const tracerName = "github.com/example/keepstack/apps/worker/internal/ingest"
+
+// Pipeline stages reported on failures.
+const (
+	StageLookup  = "lookup"
+	StageFetch   = "fetch"
+	StageParse   = "parse"
+	StagePersist = "persist"
+)
+
+// StageError records which pipeline stage failed and, once the link has been
+// loaded, its URL.
+type StageError struct {
+	Stage string
+	URL   string
+	Err   error
+}
+
+func (e *StageError) Error() string {
+	return fmt.Sprintf("%s: %v", e.Stage, e.Err)
+}
+
+func (e *StageError) Unwrap() error {
+	return e.Err
+}
+
+// Process executes the ingestion pipeline for a link identifier. Each stage
+// runs in its own span under the caller's trace so a save can be followed
+// from the API request through persistence. enqueuedAt is when the save was
+// published; when zero, queue lag is measured from the link's creation time.
+//
+// A link that already has an archive is fetched conditionally with the
+// validators stored alongside it; when the server answers 304 Not Modified
+// the archive is kept as is and parse and persist are skipped.
+func (p *Processor) Process(ctx context.Context, linkID uuid.UUID, enqueuedAt time.Time) error {
+	return p.process(ctx, linkID, enqueuedAt, false)
+}
+
+// ProcessForced is Process without the conditional fetch, for reparses that
+// must run the parser again even when the page is unchanged.
+func (p *Processor) ProcessForced(ctx context.Context, linkID uuid.UUID, enqueuedAt time.Time) error {
+	return p.process(ctx, linkID, enqueuedAt, true)
+}
+
+func (p *Processor) process(ctx context.Context, linkID uuid.UUID, enqueuedAt time.Time, force bool) (err error) {
+	ctx, span := otel.Tracer(tracerName).Start(ctx, "ingest.process",
+		trace.WithAttributes(attribute.String("keepstack.link_id", linkID.String())))
+	defer func() { endSpan(span, err) }()
+	defer func() {
+		var stageErr *StageError
+		if errors.As(err, &stageErr) {
+			p.reportStatus(ctx, linkID, StatusFailed, stageErr)
+		}
+	}()
+
+	if !enqueuedAt.IsZero() {
+		p.observeQueueLag(ctx, enqueuedAt)
+	}
+
+	lookupCtx, lookupSpan := otel.Tracer(tracerName).Start(ctx, "ingest.lookup")
+	link, err := p.store.LookupLink(lookupCtx, linkID)
+	endSpan(lookupSpan, err)
 	if err != nil {
-		return fmt.Errorf("lookup link: %w", err)
+		return &StageError{Stage: StageLookup, Err: err}
 	}
 
-	if !link.CreatedAt.IsZero() {
-		lag := time.Since(link.CreatedAt)
-		if lag < 0 {
-			lag = 0
-		}
-		p.metrics.QueueLagSeconds.Observe(lag.Seconds())
+	if enqueuedAt.IsZero() && !link.CreatedAt.IsZero() {
+		p.observeQueueLag(ctx, link.CreatedAt)
 	}
 
+	p.reportStatus(ctx, linkID, StatusFetching, nil)
 	fetchStart := time.Now()
-	result, err := p.fetcher.Fetch(ctx, link.URL)
+	fetchCtx, fetchSpan := otel.Tracer(tracerName).Start(ctx, "ingest.fetch",
+		trace.WithAttributes(attribute.String("url.full", link.URL)))
+	var result FetchResult
+	if link.CapturedHTML != "" {
+		result = FetchResult{Body: []byte(link.CapturedHTML), FinalURL: link.URL}
+		fetchSpan.SetAttributes(attribute.Bool("keepstack.fetch.captured", true))
+	} else {
+		headers := link.FetchHeaders
+		if !force {
+			headers = conditionalHeaders(link)
+		}
+		result, err = p.fetcher.FetchWithHeaders(fetchCtx, link.URL, headers)
+	}
+	if err == nil {
+		fetchSpan.SetAttributes(
+			attribute.Int("keepstack.fetch.bytes", len(result.Body)),
+			attribute.Bool("keepstack.fetch.not_modified", result.NotModified),
+		)
+	}
+	endSpan(fetchSpan, err)
 	if err != nil {
-		return fmt.Errorf("fetch: %w", err)
+		return &StageError{Stage: StageFetch, URL: link.URL, Err: err}
+	}
+	fetchDuration := time.Since(fetchStart)
+	observe.ObserveWithTrace(ctx, p.metrics.FetchLatency, fetchDuration.Seconds())
+
+	if result.NotModified {
+		p.metrics.FetchNotModified.Inc()
+		p.reportStatus(ctx, linkID, StatusIngested, nil)
+		return nil
 	}
-	p.metrics.FetchLatency.Observe(time.Since(fetchStart).Seconds())
 
+	p.reportStatus(ctx, linkID, StatusParsing, nil)
 	parseStart := time.Now()
-	article, diagnostics, err := Parse(result.FinalURL, result.Body)
+	_, parseSpan := otel.Tracer(tracerName).Start(ctx, "ingest.parse")
+	article, diagnostics, err := ParseWithLanguage(result.FinalURL, result.Body, p.policy, result.ContentLanguage)
+	endSpan(parseSpan, err)
 	parseDuration := time.Since(parseStart)
-	p.metrics.ParseLatency.Observe(parseDuration.Seconds())
+	observe.ObserveWithTrace(ctx, p.metrics.ParseLatency, parseDuration.Seconds())
 	if err != nil {
 		p.metrics.ParseFailures.Inc()
-		return fmt.Errorf("parse: %w", err)
+		return &StageError{Stage: StageParse, URL: link.URL, Err: err}
 	}
 
 	if diagnostics.LangDetectDuration > 0 {
@@ -58,15 +210,104 @@ func (p *Processor) Process(ctx context.Context, linkID uuid.UUID) error {
 	}
 	if diagnostics.LangDetected {
 		p.metrics.LangDetect.WithLabelValues(article.Language).Inc()
+	} else if diagnostics.LangHinted {
+		p.metrics.LangDetectHinted.WithLabelValues(article.Language).Inc()
 	} else if diagnostics.LangDetectDuration > 0 {
 		p.metrics.LangDetectErrors.Inc()
 	}
+	if p.reporter != nil {
+		p.reporter.ReportDiagnostics(ctx, linkID, Diagnostics{
+			FinalURL:      result.FinalURL,
+			FetchBytes:    len(result.Body),
+			FetchDuration: fetchDuration,
+			ParseDuration: parseDuration,
+			Lang:          article.Language,
+			LangDetected:  diagnostics.LangDetected,
+			WordCount:     article.WordCount,
+		})
+	}
 
+	if p.images != nil && article.HTMLContent != "" {
+		imagesCtx, imagesSpan := otel.Tracer(tracerName).Start(ctx, "ingest.images")
+		content, stats := p.images.Mirror(imagesCtx, link.ID, article.HTMLContent)
+		imagesSpan.SetAttributes(
+			attribute.Int("keepstack.images.stored", stats.Stored),
+			attribute.Int("keepstack.images.failed", stats.Failed),
+			attribute.Int("keepstack.images.skipped", stats.Skipped),
+		)
+		endSpan(imagesSpan, nil)
+		article.HTMLContent = content
+		p.metrics.ImagesMirrored.WithLabelValues("stored").Add(float64(stats.Stored))
+		p.metrics.ImagesMirrored.WithLabelValues("failed").Add(float64(stats.Failed))
+		p.metrics.ImagesMirrored.WithLabelValues("skipped").Add(float64(stats.Skipped))
+	}
+
+	p.reportStatus(ctx, linkID, StatusPersisting, nil)
 	persistStart := time.Now()
-	if err := p.store.PersistResult(ctx, link, article, result.Body); err != nil {
-		return fmt.Errorf("persist: %w", err)
+	persistCtx, persistSpan := otel.Tracer(tracerName).Start(ctx, "ingest.persist")
+	err = p.store.PersistResult(persistCtx, link, article, result)
+	endSpan(persistSpan, err)
+	if err != nil {
+		return &StageError{Stage: StagePersist, URL: link.URL, Err: err}
 	}
-	p.metrics.PersistLatency.Observe(time.Since(persistStart).Seconds())
+	observe.ObserveWithTrace(ctx, p.metrics.PersistLatency, time.Since(persistStart).Seconds())
 
+	p.reportStatus(ctx, linkID, StatusIngested, nil)
+	if p.onIngested != nil {
+		p.onIngested(ctx, link)
+	}
 	return nil
 }
+
+// conditionalHeaders returns the link's fetch headers plus If-None-Match and
+// If-Modified-Since from its archive's validators. Headers the owner set for
+// the domain win.
+func conditionalHeaders(link Link) map[string]string {
+	if link.ETag == "" && link.LastModified == "" {
+		return link.FetchHeaders
+	}
+	headers := make(map[string]string, len(link.FetchHeaders)+2)
+	for name, value := range link.FetchHeaders {
+		headers[name] = value
+	}
+	if link.ETag != "" && !hasHeader(headers, "If-None-Match") {
+		headers["If-None-Match"] = link.ETag
+	}
+	if link.LastModified != "" && !hasHeader(headers, "If-Modified-Since") {
+		headers["If-Modified-Since"] = link.LastModified
+	}
+	return headers
+}
+
+func hasHeader(headers map[string]string, name string) bool {
+	for key := range headers {
+		if strings.EqualFold(key, name) {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *Processor) reportStatus(ctx context.Context, linkID uuid.UUID, status Status, failure *StageError) {
+	if p.reporter != nil {
+		p.reporter.ReportStatus(ctx, linkID, status, failure)
+	}
+}
+
+// observeQueueLag records how long the job waited since since. Clock skew
+// between the API and worker can make it negative, which is clamped to zero.
+func (p *Processor) observeQueueLag(ctx context.Context, since time.Time) {
+	lag := time.Since(since)
+	if lag < 0 {
+		lag = 0
+	}
+	observe.ObserveWithTrace(ctx, p.metrics.QueueLagSeconds, lag.Seconds())
+}
+
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
@@ -0,0 +1,44 @@
+package ingest
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Preview is a page summary for unfurling a link that is not saved.
+type Preview struct {
+	URL         string
+	Title       string
+	Description string
+	Image       string
+	WordCount   int
+}
+
+// Unfurl fetches and parses target like Process does but persists nothing,
+// so other apps can show a page before anyone saves it. Failures are
+// StageErrors for the fetch or parse stage.
+func (p *Processor) Unfurl(ctx context.Context, target string) (preview Preview, err error) {
+	ctx, span := otel.Tracer(tracerName).Start(ctx, "ingest.unfurl",
+		trace.WithAttributes(attribute.String("url.full", target)))
+	defer func() { endSpan(span, err) }()
+
+	result, err := p.fetcher.Fetch(ctx, target)
+	if err != nil {
+		return Preview{}, &StageError{Stage: StageFetch, URL: target, Err: err}
+	}
+	article, _, err := ParseWithPolicy(result.FinalURL, result.Body, p.policy)
+	if err != nil {
+		p.metrics.ParseFailures.Inc()
+		return Preview{}, &StageError{Stage: StageParse, URL: target, Err: err}
+	}
+	return Preview{
+		URL:         result.FinalURL,
+		Title:       article.Title,
+		Description: article.Excerpt,
+		Image:       article.Image,
+		WordCount:   article.WordCount,
+	}, nil
+}
@@ -0,0 +1,105 @@
+package ingestclient
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/protobuf/types/known/durationpb"
+
+	"github.com/example/keepstack/apps/worker/internal/ingest"
+	ingestv1 "github.com/example/keepstack/proto/ingest/v1"
+)
+
+var statuses = map[ingest.Status]ingestv1.LinkStatus{
+	ingest.StatusFetching:   ingestv1.LinkStatus_LINK_STATUS_FETCHING,
+	ingest.StatusParsing:    ingestv1.LinkStatus_LINK_STATUS_PARSING,
+	ingest.StatusPersisting: ingestv1.LinkStatus_LINK_STATUS_PERSISTING,
+	ingest.StatusIngested:   ingestv1.LinkStatus_LINK_STATUS_INGESTED,
+	ingest.StatusFailed:     ingestv1.LinkStatus_LINK_STATUS_FAILED,
+}
+
+// Reporter sends job progress to the API's internal IngestService. It
+// implements ingest.StatusReporter: failed reports are logged and the job
+// carries on.
+type Reporter struct {
+	client  ingestv1.IngestServiceClient
+	conn    *grpc.ClientConn
+	timeout time.Duration
+	logger  *log.Logger
+}
+
+// Dial returns a Reporter for the API at addr. The connection is established
+// lazily, so an API that is not up yet does not block worker startup.
+func Dial(addr string, timeout time.Duration, logger *log.Logger) (*Reporter, error) {
+	conn, err := grpc.NewClient(addr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		return nil, fmt.Errorf("dial api grpc %s: %w", addr, err)
+	}
+	reporter := New(conn, timeout, logger)
+	reporter.conn = conn
+	return reporter, nil
+}
+
+// New returns a Reporter that calls the API over conn. Each report is
+// bounded by timeout; zero leaves it to the caller's context.
+func New(conn grpc.ClientConnInterface, timeout time.Duration, logger *log.Logger) *Reporter {
+	return &Reporter{client: ingestv1.NewIngestServiceClient(conn), timeout: timeout, logger: logger}
+}
+
+// Close releases the connection opened by Dial.
+func (r *Reporter) Close() error {
+	if r.conn == nil {
+		return nil
+	}
+	return r.conn.Close()
+}
+
+// ReportStatus records the stage linkID has reached. failure is set with
+// ingest.StatusFailed.
+func (r *Reporter) ReportStatus(ctx context.Context, linkID uuid.UUID, status ingest.Status, failure *ingest.StageError) {
+	req := &ingestv1.UpdateLinkStatusRequest{LinkId: linkID.String(), Status: statuses[status]}
+	if failure != nil {
+		req.FailedStage = failure.Stage
+		req.Error = failure.Err.Error()
+	}
+
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+	if _, err := r.client.UpdateLinkStatus(ctx, req); err != nil {
+		r.logger.Printf("report %s status for %s: %v", status, linkID, err)
+	}
+}
+
+// ReportDiagnostics records how linkID's fetch and parse went.
+func (r *Reporter) ReportDiagnostics(ctx context.Context, linkID uuid.UUID, diagnostics ingest.Diagnostics) {
+	req := &ingestv1.ReportDiagnosticsRequest{
+		LinkId:        linkID.String(),
+		FinalUrl:      diagnostics.FinalURL,
+		FetchBytes:    int64(diagnostics.FetchBytes),
+		FetchDuration: durationpb.New(diagnostics.FetchDuration),
+		ParseDuration: durationpb.New(diagnostics.ParseDuration),
+		Lang:          diagnostics.Lang,
+		LangDetected:  diagnostics.LangDetected,
+		WordCount:     int32(diagnostics.WordCount),
+	}
+
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+	if _, err := r.client.ReportDiagnostics(ctx, req); err != nil {
+		r.logger.Printf("report diagnostics for %s: %v", linkID, err)
+	}
+}
+
+func (r *Reporter) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if r.timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, r.timeout)
+}
+
+var _ ingest.StatusReporter = (*Reporter)(nil)
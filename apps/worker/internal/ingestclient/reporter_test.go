@@ -0,0 +1,128 @@
+package ingestclient
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	"github.com/example/keepstack/apps/worker/internal/ingest"
+	ingestv1 "github.com/example/keepstack/proto/ingest/v1"
+)
+
+type fakeIngestService struct {
+	ingestv1.UnimplementedIngestServiceServer
+
+	mu          sync.Mutex
+	statuses    []*ingestv1.UpdateLinkStatusRequest
+	diagnostics []*ingestv1.ReportDiagnosticsRequest
+	err         error
+}
+
+func (f *fakeIngestService) UpdateLinkStatus(ctx context.Context, req *ingestv1.UpdateLinkStatusRequest) (*ingestv1.UpdateLinkStatusResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.statuses = append(f.statuses, req)
+	return &ingestv1.UpdateLinkStatusResponse{}, f.err
+}
+
+func (f *fakeIngestService) ReportDiagnostics(ctx context.Context, req *ingestv1.ReportDiagnosticsRequest) (*ingestv1.ReportDiagnosticsResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.diagnostics = append(f.diagnostics, req)
+	return &ingestv1.ReportDiagnosticsResponse{}, f.err
+}
+
+func newTestReporter(t *testing.T, service *fakeIngestService, logs *bytes.Buffer) *Reporter {
+	t.Helper()
+
+	listener := bufconn.Listen(1 << 20)
+	server := grpc.NewServer()
+	ingestv1.RegisterIngestServiceServer(server, service)
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return New(conn, time.Second, log.New(logs, "", 0))
+}
+
+func TestReporterSendsStatusAndDiagnostics(t *testing.T) {
+	t.Parallel()
+
+	service := &fakeIngestService{}
+	var logs bytes.Buffer
+	reporter := newTestReporter(t, service, &logs)
+	linkID := uuid.New()
+	ctx := context.Background()
+
+	reporter.ReportStatus(ctx, linkID, ingest.StatusFetching, nil)
+	reporter.ReportStatus(ctx, linkID, ingest.StatusFailed, &ingest.StageError{Stage: ingest.StageFetch, Err: errors.New("status 500")})
+	reporter.ReportDiagnostics(ctx, linkID, ingest.Diagnostics{
+		FinalURL:      "https://example.com/final",
+		FetchBytes:    512,
+		FetchDuration: 250 * time.Millisecond,
+		ParseDuration: 30 * time.Millisecond,
+		Lang:          "en",
+		LangDetected:  true,
+		WordCount:     120,
+	})
+
+	if len(service.statuses) != 2 {
+		t.Fatalf("expected two status reports, got %d", len(service.statuses))
+	}
+	if got := service.statuses[0]; got.GetLinkId() != linkID.String() || got.GetStatus() != ingestv1.LinkStatus_LINK_STATUS_FETCHING || got.GetError() != "" {
+		t.Fatalf("unexpected fetching report: %v", got)
+	}
+	if got := service.statuses[1]; got.GetStatus() != ingestv1.LinkStatus_LINK_STATUS_FAILED || got.GetFailedStage() != "fetch" || got.GetError() != "status 500" {
+		t.Fatalf("unexpected failure report: %v", got)
+	}
+
+	if len(service.diagnostics) != 1 {
+		t.Fatalf("expected one diagnostics report, got %d", len(service.diagnostics))
+	}
+	got := service.diagnostics[0]
+	if got.GetFetchDuration().AsDuration() != 250*time.Millisecond || got.GetParseDuration().AsDuration() != 30*time.Millisecond {
+		t.Fatalf("unexpected durations: %v", got)
+	}
+	if got.GetFinalUrl() != "https://example.com/final" || got.GetFetchBytes() != 512 || got.GetWordCount() != 120 || !got.GetLangDetected() {
+		t.Fatalf("unexpected diagnostics: %v", got)
+	}
+	if logs.Len() != 0 {
+		t.Fatalf("expected no logged failures, got %q", logs.String())
+	}
+}
+
+func TestReporterLogsFailures(t *testing.T) {
+	t.Parallel()
+
+	service := &fakeIngestService{err: status.Error(codes.NotFound, "link not found")}
+	var logs bytes.Buffer
+	reporter := newTestReporter(t, service, &logs)
+
+	reporter.ReportStatus(context.Background(), uuid.New(), ingest.StatusParsing, nil)
+
+	if !strings.Contains(logs.String(), "link not found") {
+		t.Fatalf("expected failure to be logged, got %q", logs.String())
+	}
+}
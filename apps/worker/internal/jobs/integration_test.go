@@ -0,0 +1,295 @@
+//go:build integration
+
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/nats-io/nats.go"
+
+	"github.com/example/keepstack/apps/worker/internal/ingest"
+	"github.com/example/keepstack/apps/worker/internal/observability"
+	"github.com/example/keepstack/apps/worker/internal/queue"
+	"github.com/example/keepstack/media"
+	"github.com/example/keepstack/messages"
+	"github.com/example/keepstack/testenv"
+)
+
+// devUserID is the user migration 000005 seeds.
+const devUserID = "00000000-0000-0000-0000-000000000001"
+
+// testMetrics shares one set of worker metrics between tests, since
+// NewMetrics registers them with the default registry.
+var testMetrics = sync.OnceValue(observability.NewMetrics)
+
+// TestIntegrationIngestPipeline runs every registered job type behind a real
+// NATS subscription against migrated Postgres, archiving a fixture page
+// served over HTTP on save and again on reparse.
+func TestIntegrationIngestPipeline(t *testing.T) {
+	databaseURL := testenv.Postgres(t)
+	natsURL := testenv.NATS(t)
+
+	fixture, err := os.ReadFile("../ingest/testdata/english_article.html")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var hits atomic.Int32
+	site := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/article" {
+			http.NotFound(w, r)
+			return
+		}
+		hits.Add(1)
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		_, _ = w.Write(fixture)
+	}))
+	defer site.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+
+	pool, err := pgxpool.New(ctx, databaseURL)
+	if err != nil {
+		t.Fatalf("connect postgres: %v", err)
+	}
+	defer pool.Close()
+
+	subscriber, err := queue.NewSubscriber(natsURL)
+	if err != nil {
+		t.Fatalf("connect nats: %v", err)
+	}
+	defer subscriber.Close()
+
+	deps := Deps{
+		Processor: ingest.NewProcessor(ingest.NewFetcher(10*time.Second), ingest.NewStore(pool), testMetrics()),
+		Logger:    log.New(os.Stderr, "integration ", log.LstdFlags),
+	}
+	var routes []queue.Route
+	for _, typ := range Types() {
+		routes = append(routes, queue.Route{Subject: typ.Subject, Timeout: typ.Timeout, Handler: queue.Handler(typ.New(deps))})
+	}
+
+	listenCtx, stopListening := context.WithCancel(ctx)
+	ready := make(chan struct{})
+	listenErr := make(chan error, 1)
+	go func() {
+		listenErr <- subscriber.Listen(listenCtx, routes, func() { close(ready) })
+	}()
+	defer func() {
+		stopListening()
+		if err := <-listenErr; err != nil {
+			t.Errorf("listen: %v", err)
+		}
+	}()
+	select {
+	case <-ready:
+	case err := <-listenErr:
+		t.Fatalf("listen: %v", err)
+	}
+
+	linkID := insertLink(ctx, t, pool, site.URL+"/article")
+	if err := subscriber.PublishLinkSaved(linkID.String()); err != nil {
+		t.Fatalf("publish %s: %v", messages.SubjectLinksSaved, err)
+	}
+
+	var title, lang, text string
+	var words int
+	waitForRow(ctx, t, "archive", func() error {
+		return pool.QueryRow(ctx,
+			`SELECT title, lang, extracted_text, word_count FROM archives WHERE link_id = $1`, linkID,
+		).Scan(&title, &lang, &text, &words)
+	})
+	if title != "The Dawn of Readability Extraction" || lang != "en" || words == 0 {
+		t.Fatalf("unexpected archive: title=%q lang=%q words=%d", title, lang, words)
+	}
+	if !strings.Contains(text, "Readability algorithms") {
+		t.Fatalf("extracted text is missing the article body: %q", text)
+	}
+	var searchable bool
+	if err := pool.QueryRow(ctx,
+		`SELECT search_tsv @@ plainto_tsquery('english', 'readability') FROM links WHERE id = $1`, linkID,
+	).Scan(&searchable); err != nil || !searchable {
+		t.Fatalf("archived text should be searchable: matched=%v err=%v", searchable, err)
+	}
+
+	// A reparse fetches the page again and replaces the archive in place.
+	if _, err := pool.Exec(ctx, `UPDATE archives SET title = 'stale' WHERE link_id = $1`, linkID); err != nil {
+		t.Fatal(err)
+	}
+	payload, _ := json.Marshal(messages.LinkReparse{LinkID: linkID.String(), RequestedAt: time.Now()})
+	publishRaw(t, natsURL, messages.SubjectLinksReparse, payload)
+	waitForRow(ctx, t, "reparsed archive", func() error {
+		if err := pool.QueryRow(ctx, `SELECT title FROM archives WHERE link_id = $1`, linkID).Scan(&title); err != nil {
+			return err
+		}
+		if title == "stale" {
+			return pgx.ErrNoRows
+		}
+		return nil
+	})
+	if got := hits.Load(); got != 2 {
+		t.Fatalf("expected the fixture to be fetched twice, got %d", got)
+	}
+}
+
+// TestIntegrationIngestFailure checks that a page the fetch stage cannot read
+// fails the job with the link and stage attached and stores no archive.
+func TestIntegrationIngestFailure(t *testing.T) {
+	databaseURL := testenv.Postgres(t)
+
+	site := httptest.NewServer(http.NotFoundHandler())
+	defer site.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	pool, err := pgxpool.New(ctx, databaseURL)
+	if err != nil {
+		t.Fatalf("connect postgres: %v", err)
+	}
+	defer pool.Close()
+
+	processor := ingest.NewProcessor(ingest.NewFetcher(5*time.Second), ingest.NewStore(pool), testMetrics())
+
+	linkID := insertLink(ctx, t, pool, site.URL+"/missing")
+	payload, _ := json.Marshal(messages.LinkSaved{LinkID: linkID.String()})
+	err = newIngestHandler(Deps{Processor: processor})(ctx, payload)
+
+	var linkErr *LinkError
+	var stageErr *ingest.StageError
+	if !errors.As(err, &linkErr) || linkErr.LinkID != linkID {
+		t.Fatalf("expected the failure attributed to link %s, got %v", linkID, err)
+	}
+	if !errors.As(err, &stageErr) || stageErr.Stage != ingest.StageFetch {
+		t.Fatalf("expected a fetch stage failure, got %v", err)
+	}
+	var archived bool
+	if err := pool.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM archives WHERE link_id = $1)`, linkID).Scan(&archived); err != nil || archived {
+		t.Fatalf("failed ingest should store no archive: archived=%v err=%v", archived, err)
+	}
+}
+
+// TestIntegrationMediaReferences checks that a mirrored image is only purged
+// once no link references it, and that recording a new reference waits for
+// a purge of the same image to finish.
+func TestIntegrationMediaReferences(t *testing.T) {
+	databaseURL := testenv.Postgres(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+
+	pool, err := pgxpool.New(ctx, databaseURL)
+	if err != nil {
+		t.Fatalf("connect postgres: %v", err)
+	}
+	defer pool.Close()
+
+	store := ingest.NewStore(pool)
+	mediaStore := media.NewDirStore(t.TempDir())
+	cleaner := ingest.NewMediaCleaner(mediaStore, store)
+	image := []byte("\x89PNG\r\n\x1a\n")
+	hash := media.Hash(image)
+	if err := mediaStore.Put(ctx, hash, "image/png", image); err != nil {
+		t.Fatalf("put image: %v", err)
+	}
+
+	first := insertLink(ctx, t, pool, "https://example.com/first")
+	second := insertLink(ctx, t, pool, "https://example.com/second")
+	for _, linkID := range []uuid.UUID{first, second} {
+		if err := store.RecordMedia(ctx, linkID, hash); err != nil {
+			t.Fatalf("record media: %v", err)
+		}
+	}
+
+	if _, err := pool.Exec(ctx, `DELETE FROM links WHERE id = $1`, first); err != nil {
+		t.Fatalf("delete link: %v", err)
+	}
+	if stats, err := cleaner.Purge(ctx, []string{hash}); err != nil || stats != (ingest.PurgeStats{Kept: 1}) {
+		t.Fatalf("expected the image kept for the second link, got %+v, %v", stats, err)
+	}
+
+	// A reference recorded while a purge holds the image's lock lands
+	// after the purge, so the image the new link shows is stored again.
+	if _, err := pool.Exec(ctx, `DELETE FROM links WHERE id = $1`, second); err != nil {
+		t.Fatalf("delete link: %v", err)
+	}
+	third := insertLink(ctx, t, pool, "https://example.com/third")
+	recorded := make(chan error, 1)
+	err = store.LockMedia(ctx, []string{hash}, func(referenced []string) {
+		if len(referenced) != 0 {
+			t.Errorf("expected no references left, got %v", referenced)
+		}
+		go func() { recorded <- store.RecordMedia(ctx, third, hash) }()
+		select {
+		case err := <-recorded:
+			t.Errorf("reference recorded while the purge held the lock: %v", err)
+		case <-time.After(500 * time.Millisecond):
+		}
+	})
+	if err != nil {
+		t.Fatalf("lock media: %v", err)
+	}
+	if err := <-recorded; err != nil {
+		t.Fatalf("record media: %v", err)
+	}
+	if stats, err := cleaner.Purge(ctx, []string{hash}); err != nil || stats != (ingest.PurgeStats{Kept: 1}) {
+		t.Fatalf("expected the image kept for the third link, got %+v, %v", stats, err)
+	}
+}
+
+func insertLink(ctx context.Context, t *testing.T, pool *pgxpool.Pool, url string) uuid.UUID {
+	t.Helper()
+	linkID := uuid.New()
+	if _, err := pool.Exec(ctx, `INSERT INTO links (id, user_id, url) VALUES ($1, $2, $3)`, linkID, devUserID, url); err != nil {
+		t.Fatalf("insert link: %v", err)
+	}
+	return linkID
+}
+
+func publishRaw(t *testing.T, natsURL, subject string, payload []byte) {
+	t.Helper()
+	conn, err := nats.Connect(natsURL)
+	if err != nil {
+		t.Fatalf("connect nats: %v", err)
+	}
+	defer conn.Close()
+	if err := conn.Publish(subject, payload); err != nil {
+		t.Fatalf("publish %s: %v", subject, err)
+	}
+	if err := conn.Flush(); err != nil {
+		t.Fatalf("flush %s: %v", subject, err)
+	}
+}
+
+// waitForRow retries query until it stops returning pgx.ErrNoRows.
+func waitForRow(ctx context.Context, t *testing.T, what string, query func() error) {
+	t.Helper()
+	for {
+		err := query()
+		if err == nil {
+			return
+		}
+		if !errors.Is(err, pgx.ErrNoRows) {
+			t.Fatalf("query %s: %v", what, err)
+		}
+		select {
+		case <-ctx.Done():
+			t.Fatalf("timed out waiting for %s", what)
+		case <-time.After(200 * time.Millisecond):
+		}
+	}
+}
@@ -0,0 +1,96 @@
+// Package jobs is the worker's registry of background job types. A job type
+// is a NATS subject and a handler for the payloads published on it. Types
+// register themselves from init in this package, and the worker subscribes to
+// every registered subject, so adding export generation, webhook delivery, or
+// any other task is a new file here rather than a change to cmd/worker.
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/example/keepstack/apps/worker/internal/ingest"
+	"github.com/example/keepstack/apps/worker/internal/observability"
+)
+
+// DefaultTimeout bounds a job whose type sets no Timeout.
+const DefaultTimeout = 60 * time.Second
+
+// Handler runs one job from its raw message payload. Returning an error
+// leaves the message unacknowledged and counts the job as failed.
+type Handler func(ctx context.Context, payload []byte) error
+
+// Deps are the shared services job handlers are built from.
+type Deps struct {
+	Processor *ingest.Processor
+	// Media removes deleted links' mirrored images. Nil when media
+	// storage is disabled.
+	Media   *ingest.MediaCleaner
+	Metrics *observability.Metrics
+	Logger  *log.Logger
+}
+
+// Type describes one kind of background job.
+type Type struct {
+	// Subject is the NATS subject the job is published on.
+	Subject string
+	// Timeout bounds one job. Zero uses DefaultTimeout.
+	Timeout time.Duration
+	// New builds the handler once the worker's dependencies are connected.
+	New func(Deps) Handler
+}
+
+var (
+	mu    sync.Mutex
+	types = map[string]Type{}
+)
+
+// Register adds a job type. It panics if the subject is empty or already
+// registered, since both are programming errors caught at startup.
+func Register(t Type) {
+	mu.Lock()
+	defer mu.Unlock()
+	if t.Subject == "" || t.New == nil {
+		panic("jobs: Register needs a subject and a handler constructor")
+	}
+	if _, dup := types[t.Subject]; dup {
+		panic(fmt.Sprintf("jobs: Register called twice for %s", t.Subject))
+	}
+	if t.Timeout <= 0 {
+		t.Timeout = DefaultTimeout
+	}
+	types[t.Subject] = t
+}
+
+// Types returns the registered job types ordered by subject.
+func Types() []Type {
+	mu.Lock()
+	defer mu.Unlock()
+	out := make([]Type, 0, len(types))
+	for _, t := range types {
+		out = append(out, t)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Subject < out[j].Subject })
+	return out
+}
+
+// LinkError attributes a failed job to the link it was working on, so the
+// error report carries the link ID.
+type LinkError struct {
+	LinkID uuid.UUID
+	Err    error
+}
+
+func (e *LinkError) Error() string {
+	return fmt.Sprintf("link %s: %v", e.LinkID, e.Err)
+}
+
+func (e *LinkError) Unwrap() error {
+	return e.Err
+}
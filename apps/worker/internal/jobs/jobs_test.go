@@ -0,0 +1,73 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+
+	"github.com/example/keepstack/apps/worker/internal/queue"
+)
+
+func TestTypesIncludesLinkJobs(t *testing.T) {
+	t.Parallel()
+
+	got := map[string]Type{}
+	for _, typ := range Types() {
+		got[typ.Subject] = typ
+	}
+	for _, subject := range []string{queue.SubjectLinksSaved, SubjectLinksReparse, SubjectLinksDeleted} {
+		typ, ok := got[subject]
+		if !ok {
+			t.Fatalf("expected %s to be registered", subject)
+		}
+		if typ.Timeout != DefaultTimeout {
+			t.Errorf("%s timeout = %s, want the default", subject, typ.Timeout)
+		}
+	}
+}
+
+func TestRegisterRejectsDuplicates(t *testing.T) {
+	t.Parallel()
+
+	defer func() {
+		if recover() == nil {
+			t.Fatal("expected registering a subject twice to panic")
+		}
+	}()
+	Register(Type{Subject: queue.SubjectLinksSaved, New: newIngestHandler})
+}
+
+func TestLinkHandlersRejectInvalidPayloads(t *testing.T) {
+	t.Parallel()
+
+	handlers := map[string]Handler{
+		queue.SubjectLinksSaved: newIngestHandler(Deps{}),
+		SubjectLinksReparse:     newReparseHandler(Deps{}),
+		SubjectLinksDeleted:     newDeletedHandler(Deps{}),
+	}
+	for subject, handle := range handlers {
+		for _, payload := range []string{`not json`, `{"link_id":"nope"}`} {
+			err := handle(context.Background(), []byte(payload))
+			if err == nil {
+				t.Errorf("%s: expected %s to be rejected", subject, payload)
+			}
+			var linkErr *LinkError
+			if errors.As(err, &linkErr) {
+				t.Errorf("%s: a malformed payload should not be attributed to a link: %v", subject, err)
+			}
+		}
+	}
+}
+
+func TestDeletedHandlerWithoutMediaStorage(t *testing.T) {
+	t.Parallel()
+
+	handle := newDeletedHandler(Deps{})
+	payload := `{"link_id":"` + uuid.NewString() + `","media_hashes":["` + strings.Repeat("a", 64) + `"]}`
+	if err := handle(context.Background(), []byte(payload)); err != nil {
+		t.Fatalf("expected nothing to do without media storage, got %v", err)
+	}
+}
@@ -0,0 +1,111 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/example/keepstack/apps/worker/internal/queue"
+	"github.com/example/keepstack/messages"
+)
+
+// SubjectLinksReparse asks the worker to ingest an already saved link again,
+// such as after a parser improvement or to refresh a page that has changed.
+const SubjectLinksReparse = messages.SubjectLinksReparse
+
+// ReparseMessage is the payload published on SubjectLinksReparse. Queue lag
+// is measured from RequestedAt; zero measures from now.
+type ReparseMessage = messages.LinkReparse
+
+// SubjectLinksDeleted tells the worker a link was deleted, so it can remove
+// the mirrored images only that link's archive showed.
+const SubjectLinksDeleted = messages.SubjectLinksDeleted
+
+// DeletedMessage is the payload published on SubjectLinksDeleted.
+type DeletedMessage = messages.LinkDeleted
+
+func init() {
+	Register(Type{Subject: queue.SubjectLinksSaved, New: newIngestHandler})
+	Register(Type{Subject: SubjectLinksReparse, New: newReparseHandler})
+	Register(Type{Subject: SubjectLinksDeleted, New: newDeletedHandler})
+}
+
+// newIngestHandler archives a newly saved link.
+func newIngestHandler(deps Deps) Handler {
+	return func(ctx context.Context, payload []byte) error {
+		var msg queue.LinkSavedMessage
+		if err := json.Unmarshal(payload, &msg); err != nil {
+			return fmt.Errorf("invalid payload: %w", err)
+		}
+		linkID, err := uuid.Parse(msg.LinkID)
+		if err != nil {
+			return fmt.Errorf("invalid link id: %w", err)
+		}
+		if err := deps.Processor.Process(ctx, linkID, msg.EnqueuedAt); err != nil {
+			return &LinkError{LinkID: linkID, Err: err}
+		}
+		return nil
+	}
+}
+
+// newReparseHandler runs a saved link through the ingestion pipeline again,
+// replacing its archive. Unless the message forces it, an unchanged page
+// keeps its archive.
+func newReparseHandler(deps Deps) Handler {
+	return func(ctx context.Context, payload []byte) error {
+		var msg ReparseMessage
+		if err := json.Unmarshal(payload, &msg); err != nil {
+			return fmt.Errorf("invalid payload: %w", err)
+		}
+		linkID, err := uuid.Parse(msg.LinkID)
+		if err != nil {
+			return fmt.Errorf("invalid link id: %w", err)
+		}
+		// A zero time would measure lag from the link's creation, which for
+		// a reparse is long past.
+		requestedAt := msg.RequestedAt
+		if requestedAt.IsZero() {
+			requestedAt = time.Now()
+		}
+		process := deps.Processor.Process
+		if msg.Force {
+			process = deps.Processor.ProcessForced
+		}
+		if err := process(ctx, linkID, requestedAt); err != nil {
+			return &LinkError{LinkID: linkID, Err: err}
+		}
+		return nil
+	}
+}
+
+// newDeletedHandler removes a deleted link's mirrored images from media
+// storage, keeping any another archive still shows. With media storage
+// disabled there is nothing to remove.
+func newDeletedHandler(deps Deps) Handler {
+	return func(ctx context.Context, payload []byte) error {
+		var msg DeletedMessage
+		if err := json.Unmarshal(payload, &msg); err != nil {
+			return fmt.Errorf("invalid payload: %w", err)
+		}
+		linkID, err := uuid.Parse(msg.LinkID)
+		if err != nil {
+			return fmt.Errorf("invalid link id: %w", err)
+		}
+		if deps.Media == nil || len(msg.MediaHashes) == 0 {
+			return nil
+		}
+		stats, err := deps.Media.Purge(ctx, msg.MediaHashes)
+		if deps.Metrics != nil {
+			deps.Metrics.MediaPurged.WithLabelValues("removed").Add(float64(stats.Removed))
+			deps.Metrics.MediaPurged.WithLabelValues("kept").Add(float64(stats.Kept))
+			deps.Metrics.MediaPurged.WithLabelValues("failed").Add(float64(stats.Failed))
+		}
+		if err != nil {
+			return &LinkError{LinkID: linkID, Err: err}
+		}
+		return nil
+	}
+}
@@ -11,13 +11,22 @@ type Metrics struct {
 	JobsFailed        prometheus.Counter
 	JobsInFlight      prometheus.Gauge
 	FetchLatency      prometheus.Histogram
+	FetchNotModified  prometheus.Counter
 	ParseLatency      prometheus.Histogram
 	PersistLatency    prometheus.Histogram
 	ParseFailures     prometheus.Counter
 	LangDetectLatency prometheus.Histogram
 	LangDetect        *prometheus.CounterVec
+	LangDetectHinted  *prometheus.CounterVec
 	LangDetectErrors  prometheus.Counter
 	QueueLagSeconds   prometheus.Histogram
+	ImagesMirrored    *prometheus.CounterVec
+	MediaPurged       *prometheus.CounterVec
+
+	DBQueryDurationSeconds      *prometheus.HistogramVec
+	QueueMessagesTotal          *prometheus.CounterVec
+	QueueMessageDurationSeconds *prometheus.HistogramVec
+	DependencyUp                *prometheus.GaugeVec
 }
 
 // NewMetrics registers worker metrics.
@@ -27,17 +36,17 @@ func NewMetrics() *Metrics {
 		JobsProcessed: promauto.NewCounter(prometheus.CounterOpts{
 			Namespace: namespace,
 			Name:      "jobs_processed_total",
-			Help:      "Number of link ingestion jobs successfully processed.",
+			Help:      "Number of background jobs successfully processed.",
 		}),
 		JobsFailed: promauto.NewCounter(prometheus.CounterOpts{
 			Namespace: namespace,
 			Name:      "jobs_failed_total",
-			Help:      "Number of link ingestion jobs that failed.",
+			Help:      "Number of background jobs that failed.",
 		}),
 		JobsInFlight: promauto.NewGauge(prometheus.GaugeOpts{
 			Namespace: namespace,
 			Name:      "jobs_in_flight",
-			Help:      "Number of link ingestion jobs currently being processed.",
+			Help:      "Number of background jobs currently being processed.",
 		}),
 		FetchLatency: promauto.NewHistogram(prometheus.HistogramOpts{
 			Namespace: namespace,
@@ -45,6 +54,11 @@ func NewMetrics() *Metrics {
 			Help:      "Time spent fetching URLs.",
 			Buckets:   prometheus.DefBuckets,
 		}),
+		FetchNotModified: promauto.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "fetch_not_modified_total",
+			Help:      "Number of conditional refetches answered 304 Not Modified, which keep the existing archive.",
+		}),
 		ParseLatency: promauto.NewHistogram(prometheus.HistogramOpts{
 			Namespace: namespace,
 			Name:      "parse_seconds",
@@ -73,6 +87,11 @@ func NewMetrics() *Metrics {
 			Name:      "lang_detect",
 			Help:      "Number of successful language detections grouped by ISO code.",
 		}, []string{"lang"}),
+		LangDetectHinted: promauto.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "lang_detect_hinted_total",
+			Help:      "Number of articles whose language came from their <html lang> or Content-Language after unreliable detection, grouped by ISO code.",
+		}, []string{"lang"}),
 		LangDetectErrors: promauto.NewCounter(prometheus.CounterOpts{
 			Namespace: namespace,
 			Name:      "lang_detect_errors_total",
@@ -81,8 +100,40 @@ func NewMetrics() *Metrics {
 		QueueLagSeconds: promauto.NewHistogram(prometheus.HistogramOpts{
 			Namespace: namespace,
 			Name:      "queue_lag_seconds",
-			Help:      "Observed delay between link creation and worker processing.",
+			Help:      "Observed delay between the API enqueueing a link and the worker starting to process it.",
 			Buckets:   []float64{1, 5, 15, 30, 60, 120, 300, 600, 900, 1800},
 		}),
+		ImagesMirrored: promauto.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "images_mirrored_total",
+			Help:      "Number of archived images copied to media storage, labelled by outcome: stored, failed, or skipped past the per-article limit.",
+		}, []string{"outcome"}),
+		MediaPurged: promauto.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "media_purged_total",
+			Help:      "Number of mirrored images considered after their link was deleted, labelled by outcome: removed, kept because another archive shows them, or failed.",
+		}, []string{"outcome"}),
+		DBQueryDurationSeconds: promauto.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "db_query_duration_seconds",
+			Help:      "Distribution of database query durations in seconds, labelled by query name and status.",
+			Buckets:   []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5},
+		}, []string{"query", "status"}),
+		QueueMessagesTotal: promauto.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "queue_messages_total",
+			Help:      "Number of queue messages handled, labelled by subject and outcome.",
+		}, []string{"subject", "outcome"}),
+		QueueMessageDurationSeconds: promauto.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "queue_message_duration_seconds",
+			Help:      "Distribution of queue message handling durations in seconds, labelled by subject.",
+			Buckets:   []float64{.1, .25, .5, 1, 2.5, 5, 10, 15, 30, 60},
+		}, []string{"subject"}),
+		DependencyUp: promauto.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "dependency_up",
+			Help:      "Whether the last readiness check of a dependency passed (1) or failed (0).",
+		}, []string{"dependency"}),
 	}
 }
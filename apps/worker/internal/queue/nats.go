@@ -3,26 +3,54 @@ package queue
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
+	"net/http"
+	"sync/atomic"
 	"time"
 
-	"github.com/google/uuid"
 	"github.com/nats-io/nats.go"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/example/keepstack/health"
+	"github.com/example/keepstack/messages"
 )
 
+// SubjectLinksSaved is the subject the API publishes saved links on.
+const SubjectLinksSaved = messages.SubjectLinksSaved
+
 const (
-	subjectLinksSaved = "keepstack.links.saved"
-	queueGroup        = "keepstack-worker"
+	queueGroup = "keepstack-worker"
+
+	tracerName = "github.com/example/keepstack/apps/worker/internal/queue"
 )
 
 // LinkSavedMessage represents the payload emitted by the API when a link is stored.
-type LinkSavedMessage struct {
-	LinkID string `json:"link_id"`
-}
+type LinkSavedMessage = messages.LinkSaved
+
+// EventsSubjectPrefix prefixes the live update subjects the API relays to
+// connected clients. The event type follows it.
+const EventsSubjectPrefix = messages.EventsSubjectPrefix
 
-// Handler processes incoming link saved events.
-type Handler func(ctx context.Context, linkID uuid.UUID) error
+// EventLinkIngested announces that a link's archive is ready.
+const EventLinkIngested = messages.EventLinkIngested
+
+// Event is a live update for one user's clients.
+type Event = messages.Event
+
+// Handler processes one message's payload.
+type Handler func(ctx context.Context, payload []byte) error
+
+// Route sends the messages on Subject to Handler, each bounded by Timeout.
+type Route struct {
+	Subject string
+	Timeout time.Duration
+	Handler Handler
+}
 
 // ReadyCallback is invoked after the subscriber successfully registers with
 // NATS and is ready to receive messages.
@@ -31,6 +59,7 @@ type ReadyCallback func()
 // Subscriber wraps a NATS connection for consuming events.
 type Subscriber struct {
 	conn *nats.Conn
+	subs atomic.Pointer[[]*nats.Subscription]
 }
 
 // NewSubscriber connects to NATS and returns a subscriber instance.
@@ -42,25 +71,55 @@ func NewSubscriber(url string) (*Subscriber, error) {
 	return &Subscriber{conn: conn}, nil
 }
 
-// Listen subscribes to link saved events until the context is cancelled.
-func (s *Subscriber) Listen(ctx context.Context, handler Handler, ready ReadyCallback) error {
-	sub, err := s.conn.QueueSubscribe(subjectLinksSaved, queueGroup, func(msg *nats.Msg) {
-		var payload LinkSavedMessage
-		if err := json.Unmarshal(msg.Data, &payload); err != nil {
-			log.Printf("worker: invalid payload: %v", err)
-			return
-		}
-		linkID, err := uuid.Parse(payload.LinkID)
+// Listen subscribes to every route's subject until the context is
+// cancelled. A message is acknowledged once its handler succeeds.
+func (s *Subscriber) Listen(ctx context.Context, routes []Route, ready ReadyCallback) error {
+	subs := make([]*nats.Subscription, 0, len(routes))
+	for _, route := range routes {
+		sub, err := s.conn.QueueSubscribe(route.Subject, queueGroup, s.dispatch(ctx, route))
 		if err != nil {
-			log.Printf("worker: invalid link id: %v", err)
-			return
+			for _, sub := range subs {
+				_ = sub.Unsubscribe()
+			}
+			return fmt.Errorf("subscribe to %s: %w", route.Subject, err)
 		}
+		subs = append(subs, sub)
+	}
+	if err := s.conn.Flush(); err != nil {
+		return err
+	}
+	s.subs.Store(&subs)
 
-		jobCtx, cancel := context.WithTimeout(ctx, 60*time.Second)
+	if ready != nil {
+		ready()
+	}
+
+	<-ctx.Done()
+	var errs []error
+	for _, sub := range subs {
+		errs = append(errs, sub.Drain())
+	}
+	return errors.Join(errs...)
+}
+
+func (s *Subscriber) dispatch(ctx context.Context, route Route) nats.MsgHandler {
+	return func(msg *nats.Msg) {
+		jobCtx, cancel := context.WithTimeout(ctx, route.Timeout)
 		defer cancel()
 
-		if err := handler(jobCtx, linkID); err != nil {
-			log.Printf("worker: handler error for %s: %v", linkID, err)
+		// Continue the trace started by the API request that published the job.
+		jobCtx = otel.GetTextMapPropagator().Extract(jobCtx, propagation.HeaderCarrier(http.Header(msg.Header)))
+		jobCtx, span := otel.Tracer(tracerName).Start(jobCtx, route.Subject+" process",
+			trace.WithSpanKind(trace.SpanKindConsumer),
+			trace.WithAttributes(
+				attribute.String("messaging.system", "nats"),
+				attribute.String("messaging.destination.name", route.Subject),
+			),
+		)
+		defer span.End()
+
+		if err := route.Handler(jobCtx, msg.Data); err != nil {
+			log.Printf("worker: %s handler error: %v", route.Subject, err)
 			return
 		}
 
@@ -68,20 +127,138 @@ func (s *Subscriber) Listen(ctx context.Context, handler Handler, ready ReadyCal
 			// Ack only succeeds when JetStream is configured; ignore for core NATS.
 			log.Printf("worker: ack warning: %v", err)
 		}
+	}
+}
+
+// SubjectUnfurl is the request subject the API sends unfurl requests on.
+// Workers answer in the same queue group as ingestion, so one replica
+// replies to each request.
+const SubjectUnfurl = messages.SubjectLinksUnfurl
+
+// UnfurlRequest asks a worker to fetch and parse a page without saving it.
+type UnfurlRequest = messages.UnfurlRequest
+
+// UnfurlReply is a worker's answer to an UnfurlRequest. Error is set, and
+// the other fields empty, when the page could not be fetched or parsed.
+type UnfurlReply = messages.UnfurlReply
+
+// UnfurlHandler answers one unfurl request.
+type UnfurlHandler func(ctx context.Context, url string) (UnfurlReply, error)
+
+// ServeUnfurl answers unfurl requests with handler until the connection
+// closes. A handler error is sent back as the reply's Error.
+func (s *Subscriber) ServeUnfurl(handler UnfurlHandler) (*nats.Subscription, error) {
+	return serveRequests(s, SubjectUnfurl, func(ctx context.Context, req UnfurlRequest) any {
+		reply, err := handler(ctx, req.URL)
+		if err != nil {
+			return UnfurlReply{Error: err.Error()}
+		}
+		return reply
+	})
+}
+
+// SubjectExpand is the request subject the API sends expand requests on.
+// Like unfurl, one worker in the queue group replies to each request.
+const SubjectExpand = messages.SubjectLinksExpand
+
+// ExpandRequest asks a worker to list the articles an index page links to.
+type ExpandRequest = messages.ExpandRequest
+
+// ExpandReply is a worker's answer to an ExpandRequest. Error is set, and
+// the other fields empty, when the page could not be fetched or parsed.
+type ExpandReply = messages.ExpandReply
+
+// ExpandedLink is one article link in an ExpandReply.
+type ExpandedLink = messages.ExpandedLink
+
+// ExpandHandler answers one expand request.
+type ExpandHandler func(ctx context.Context, url string) (ExpandReply, error)
+
+// ServeExpand answers expand requests with handler until the connection
+// closes. A handler error is sent back as the reply's Error.
+func (s *Subscriber) ServeExpand(handler ExpandHandler) (*nats.Subscription, error) {
+	return serveRequests(s, SubjectExpand, func(ctx context.Context, req ExpandRequest) any {
+		reply, err := handler(ctx, req.URL)
+		if err != nil {
+			return ExpandReply{Error: err.Error()}
+		}
+		return reply
+	})
+}
+
+// serveRequests answers requests on subject in the worker queue group,
+// replying with the JSON encoding of answer's result. The request's trace
+// context is carried into answer.
+func serveRequests[Req any](s *Subscriber, subject string, answer func(ctx context.Context, req Req) any) (*nats.Subscription, error) {
+	sub, err := s.conn.QueueSubscribe(subject, queueGroup, func(msg *nats.Msg) {
+		var req Req
+		if err := json.Unmarshal(msg.Data, &req); err != nil {
+			log.Printf("worker: invalid %s payload: %v", subject, err)
+			return
+		}
+
+		ctx := otel.GetTextMapPropagator().Extract(context.Background(), propagation.HeaderCarrier(http.Header(msg.Header)))
+		ctx, span := otel.Tracer(tracerName).Start(ctx, subject+" process",
+			trace.WithSpanKind(trace.SpanKindConsumer),
+			trace.WithAttributes(
+				attribute.String("messaging.system", "nats"),
+				attribute.String("messaging.destination.name", subject),
+			),
+		)
+		defer span.End()
+
+		data, err := json.Marshal(answer(ctx, req))
+		if err != nil {
+			log.Printf("worker: marshal %s reply: %v", subject, err)
+			return
+		}
+		if err := msg.Respond(data); err != nil {
+			log.Printf("worker: %s reply: %v", subject, err)
+		}
 	})
 	if err != nil {
-		return fmt.Errorf("subscribe to subject: %w", err)
+		return nil, fmt.Errorf("subscribe to %s requests: %w", subject, err)
 	}
-	if err := s.conn.Flush(); err != nil {
+	return sub, nil
+}
+
+// Healthy returns an error unless the connection is up and every
+// subscription is active. Before Listen has subscribed it reports not
+// subscribed.
+func (s *Subscriber) Healthy(ctx context.Context) error {
+	if err := health.NATS(s.conn).Run(ctx); err != nil {
 		return err
 	}
+	subs := s.subs.Load()
+	if subs == nil {
+		return errors.New("not subscribed")
+	}
+	for _, sub := range *subs {
+		if !sub.IsValid() {
+			return fmt.Errorf("subscription to %s closed", sub.Subject)
+		}
+	}
+	return nil
+}
 
-	if ready != nil {
-		ready()
+// PublishLinkSaved enqueues a link for ingestion as the API does when it is
+// saved, leaving EnqueuedAt zero so queue lag is measured from the link's
+// creation.
+func (s *Subscriber) PublishLinkSaved(linkID string) error {
+	data, err := json.Marshal(LinkSavedMessage{LinkID: linkID})
+	if err != nil {
+		return fmt.Errorf("marshal link saved payload: %w", err)
 	}
+	return s.conn.Publish(SubjectLinksSaved, data)
+}
 
-	<-ctx.Done()
-	return sub.Drain()
+// PublishEvent sends a live update for the API to relay to clients.
+func (s *Subscriber) PublishEvent(event Event) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("marshal %s event: %w", event.Type, err)
+	}
+	return s.conn.Publish(EventsSubjectPrefix+event.Type, data)
 }
 
 // Close shuts down the underlying connection.
@@ -0,0 +1,185 @@
+// Package configcheck backs the --validate-config startup mode: it prints
+// the configuration a binary would run with, secrets redacted, and runs
+// checks against it so a bad value fails a one-off Pod instead of
+// crashlooping a Deployment.
+package configcheck
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/url"
+	"reflect"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Redacted replaces secret values in the effective configuration.
+const Redacted = "<redacted>"
+
+// secretMarkers flag variables whose whole value is a credential.
+var secretMarkers = []string{"PASSWORD", "SECRET", "TOKEN", "PRIVATE_KEY", "ACCESS_KEY", "DSN"}
+
+// keywordPassword matches the password in a keyword/value connection string
+// such as "host=db password=secret".
+var keywordPassword = regexp.MustCompile(`password=('[^']*'|\S+)`)
+
+// Setting is one environment variable as a binary sees it.
+type Setting struct {
+	Name  string
+	Value string
+}
+
+// Check is one validation. Run returns nil when the check passes.
+type Check struct {
+	Name string
+	Run  func(context.Context) error
+}
+
+// Settings lists the envconfig variables behind each of cfgs, which must be
+// structs or pointers to structs, with their loaded values. Variables
+// already listed by an earlier struct are skipped.
+func Settings(cfgs ...any) []Setting {
+	var settings []Setting
+	seen := make(map[string]bool)
+	for _, cfg := range cfgs {
+		value := reflect.Indirect(reflect.ValueOf(cfg))
+		if value.Kind() != reflect.Struct {
+			continue
+		}
+		for i := 0; i < value.NumField(); i++ {
+			field := value.Type().Field(i)
+			name := field.Tag.Get("envconfig")
+			if name == "" || !field.IsExported() || field.Tag.Get("ignored") == "true" || seen[name] {
+				continue
+			}
+			seen[name] = true
+			settings = append(settings, Setting{Name: name, Value: redact(name, format(value.Field(i)))})
+		}
+	}
+	return settings
+}
+
+// Run writes settings, then runs each check with timeout and writes its
+// result. It reports whether every check passed.
+func Run(ctx context.Context, w io.Writer, settings []Setting, checks []Check, timeout time.Duration) bool {
+	fmt.Fprintln(w, "effective configuration:")
+	for _, setting := range settings {
+		fmt.Fprintf(w, "  %s=%s\n", setting.Name, setting.Value)
+	}
+
+	fmt.Fprintln(w, "checks:")
+	passed := true
+	for _, check := range checks {
+		checkCtx, cancel := context.WithTimeout(ctx, timeout)
+		err := check.Run(checkCtx)
+		cancel()
+		if err != nil {
+			passed = false
+			fmt.Fprintf(w, "  FAIL %s: %v\n", check.Name, err)
+			continue
+		}
+		fmt.Fprintf(w, "  ok   %s\n", check.Name)
+	}
+	return passed
+}
+
+// Failed is a check that always fails with err, for configuration that did
+// not load at all.
+func Failed(name string, err error) Check {
+	return Check{Name: name, Run: func(context.Context) error { return err }}
+}
+
+// DatabaseURL checks that raw is a connection string pgx accepts. An empty
+// value passes unless required.
+func DatabaseURL(name, raw string, required bool) Check {
+	return Check{Name: name + " format", Run: func(context.Context) error {
+		if raw == "" {
+			if required {
+				return fmt.Errorf("%s is required", name)
+			}
+			return nil
+		}
+		if _, err := pgx.ParseConfig(raw); err != nil {
+			// pgx masks the password in its parse errors.
+			return fmt.Errorf("parse %s: %w", name, err)
+		}
+		return nil
+	}}
+}
+
+// URL checks that raw, a comma separated list when more than one server is
+// accepted, holds absolute URLs with a host and one of schemes. An empty
+// value passes unless required.
+func URL(name, raw string, required bool, schemes ...string) Check {
+	return Check{Name: name + " format", Run: func(context.Context) error {
+		if strings.TrimSpace(raw) == "" {
+			if required {
+				return fmt.Errorf("%s is required", name)
+			}
+			return nil
+		}
+		for _, part := range strings.Split(raw, ",") {
+			parsed, err := url.Parse(strings.TrimSpace(part))
+			if err != nil {
+				return fmt.Errorf("parse %s: invalid URL", name)
+			}
+			if parsed.Host == "" {
+				return fmt.Errorf("%s has no host", name)
+			}
+			if !hasScheme(parsed.Scheme, schemes) {
+				return fmt.Errorf("%s scheme must be one of %s, got %q", name, strings.Join(schemes, ", "), parsed.Scheme)
+			}
+		}
+		return nil
+	}}
+}
+
+func hasScheme(scheme string, schemes []string) bool {
+	for _, candidate := range schemes {
+		if strings.EqualFold(scheme, candidate) {
+			return true
+		}
+	}
+	return false
+}
+
+func format(value reflect.Value) string {
+	switch v := value.Interface().(type) {
+	case []string:
+		return strings.Join(v, ",")
+	case fmt.Stringer:
+		return v.String()
+	}
+	return fmt.Sprint(value.Interface())
+}
+
+// redact hides credentials: the whole value of secret variables and the
+// password of any URL or connection string.
+func redact(name, value string) string {
+	if value == "" {
+		return value
+	}
+	for _, marker := range secretMarkers {
+		if strings.Contains(name, marker) {
+			return Redacted
+		}
+	}
+	return keywordPassword.ReplaceAllString(redactURLs(value), "password=xxxxx")
+}
+
+// redactURLs masks the password of each comma separated URL in value.
+func redactURLs(value string) string {
+	parts := strings.Split(value, ",")
+	for i, part := range parts {
+		parsed, err := url.Parse(strings.TrimSpace(part))
+		if err != nil || parsed.User == nil {
+			continue
+		}
+		parts[i] = parsed.Redacted()
+	}
+	return strings.Join(parts, ",")
+}
@@ -0,0 +1,87 @@
+package configcheck
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+type testConfig struct {
+	DatabaseURL string        `envconfig:"DATABASE_URL"`
+	NATSURL     string        `envconfig:"NATS_URL"`
+	AdminTokens []string      `envconfig:"ADMIN_TOKENS"`
+	S3SecretKey string        `envconfig:"BACKUP_S3_SECRET_KEY"`
+	Timeout     time.Duration `envconfig:"TIMEOUT"`
+	Derived     string        `ignored:"true"`
+}
+
+func TestSettingsRedactsSecrets(t *testing.T) {
+	t.Parallel()
+
+	settings := Settings(
+		testConfig{
+			DatabaseURL: "postgres://keepstack:hunter2@db:5432/keepstack",
+			NATSURL:     "nats://a:4222,nats://user:pw@b:4222",
+			AdminTokens: []string{"one", "two"},
+			S3SecretKey: "abc",
+			Timeout:     1500 * time.Millisecond,
+		},
+		&testConfig{DatabaseURL: "duplicate"},
+	)
+
+	want := []Setting{
+		{Name: "DATABASE_URL", Value: "postgres://keepstack:xxxxx@db:5432/keepstack"},
+		{Name: "NATS_URL", Value: "nats://a:4222,nats://user:xxxxx@b:4222"},
+		{Name: "ADMIN_TOKENS", Value: Redacted},
+		{Name: "BACKUP_S3_SECRET_KEY", Value: Redacted},
+		{Name: "TIMEOUT", Value: "1.5s"},
+	}
+	if len(settings) != len(want) {
+		t.Fatalf("expected %d settings, got %+v", len(want), settings)
+	}
+	for i := range want {
+		if settings[i] != want[i] {
+			t.Fatalf("setting %d: expected %+v, got %+v", i, want[i], settings[i])
+		}
+	}
+
+	if got := redact("DATABASE_URL", "host=db password='se cret' user=k"); strings.Contains(got, "se cret") {
+		t.Fatalf("expected the keyword password to be redacted, got %q", got)
+	}
+}
+
+func TestRunReportsFailures(t *testing.T) {
+	t.Parallel()
+
+	checks := []Check{
+		DatabaseURL("DATABASE_URL", "postgres://db/keepstack", true),
+		DatabaseURL("DATABASE_REPLICA_URL", "", false),
+		URL("NATS_URL", "http://nats:4222", true, "nats", "tls"),
+		URL("CRON_METRICS_URL", "", true, "http", "https"),
+		Failed("digest configuration", errors.New("DIGEST_SENDER missing")),
+	}
+	var buf bytes.Buffer
+	if Run(context.Background(), &buf, nil, checks, time.Second) {
+		t.Fatal("expected failing checks to fail the run")
+	}
+
+	out := buf.String()
+	for _, want := range []string{
+		"ok   DATABASE_URL format",
+		"ok   DATABASE_REPLICA_URL format",
+		`FAIL NATS_URL format: NATS_URL scheme must be one of nats, tls, got "http"`,
+		"FAIL CRON_METRICS_URL format: CRON_METRICS_URL is required",
+		"FAIL digest configuration: DIGEST_SENDER missing",
+	} {
+		if !strings.Contains(out, want) {
+			t.Fatalf("expected %q in:\n%s", want, out)
+		}
+	}
+
+	if !Run(context.Background(), &buf, nil, checks[:2], time.Second) {
+		t.Fatal("expected passing checks to pass the run")
+	}
+}
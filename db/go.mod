@@ -0,0 +1,3 @@
+module github.com/example/keepstack/db
+
+go 1.25
@@ -0,0 +1,7 @@
+-- +goose Up
+CREATE INDEX IF NOT EXISTS links_user_unread_created_idx
+    ON links(user_id, created_at, id)
+    WHERE read_at IS NULL;
+
+-- +goose Down
+DROP INDEX IF EXISTS links_user_unread_created_idx;
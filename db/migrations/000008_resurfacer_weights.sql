@@ -0,0 +1,11 @@
+-- +goose Up
+CREATE TABLE IF NOT EXISTS resurfacer_weights (
+    user_id UUID PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    favorite_bonus INTEGER,
+    age_cap_days INTEGER CHECK (age_cap_days IS NULL OR age_cap_days >= 0),
+    word_count_tiers TEXT,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS resurfacer_weights;
@@ -0,0 +1,7 @@
+-- +goose Up
+ALTER TABLE links ADD COLUMN IF NOT EXISTS last_surfaced_at TIMESTAMPTZ;
+ALTER TABLE links ADD COLUMN IF NOT EXISTS snoozed_until TIMESTAMPTZ;
+
+-- +goose Down
+ALTER TABLE links DROP COLUMN IF EXISTS snoozed_until;
+ALTER TABLE links DROP COLUMN IF EXISTS last_surfaced_at;
@@ -0,0 +1,46 @@
+-- +goose Up
+ALTER TABLE links ADD COLUMN IF NOT EXISTS updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW();
+ALTER TABLE archives ADD COLUMN IF NOT EXISTS updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW();
+
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION set_updated_at() RETURNS TRIGGER AS $$
+BEGIN
+    NEW.updated_at := NOW();
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+CREATE TRIGGER links_set_updated_at_trigger
+BEFORE UPDATE ON links
+FOR EACH ROW EXECUTE FUNCTION set_updated_at();
+
+CREATE TRIGGER archives_set_updated_at_trigger
+BEFORE UPDATE ON archives
+FOR EACH ROW EXECUTE FUNCTION set_updated_at();
+
+CREATE TRIGGER highlights_set_updated_at_trigger
+BEFORE UPDATE ON highlights
+FOR EACH ROW EXECUTE FUNCTION set_updated_at();
+
+CREATE TRIGGER resurfacer_weights_set_updated_at_trigger
+BEFORE UPDATE ON resurfacer_weights
+FOR EACH ROW EXECUTE FUNCTION set_updated_at();
+
+CREATE INDEX IF NOT EXISTS links_updated_at_idx ON links(updated_at);
+CREATE INDEX IF NOT EXISTS archives_updated_at_idx ON archives(updated_at);
+CREATE INDEX IF NOT EXISTS highlights_updated_at_idx ON highlights(updated_at);
+
+-- +goose Down
+DROP INDEX IF EXISTS highlights_updated_at_idx;
+DROP INDEX IF EXISTS archives_updated_at_idx;
+DROP INDEX IF EXISTS links_updated_at_idx;
+
+DROP TRIGGER IF EXISTS resurfacer_weights_set_updated_at_trigger ON resurfacer_weights;
+DROP TRIGGER IF EXISTS highlights_set_updated_at_trigger ON highlights;
+DROP TRIGGER IF EXISTS archives_set_updated_at_trigger ON archives;
+DROP TRIGGER IF EXISTS links_set_updated_at_trigger ON links;
+DROP FUNCTION IF EXISTS set_updated_at();
+
+ALTER TABLE archives DROP COLUMN IF EXISTS updated_at;
+ALTER TABLE links DROP COLUMN IF EXISTS updated_at;
@@ -0,0 +1,19 @@
+-- +goose Up
+CREATE TABLE IF NOT EXISTS backup_runs (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    started_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    finished_at TIMESTAMPTZ,
+    status TEXT NOT NULL DEFAULT 'running' CHECK (status IN ('running', 'success', 'failure')),
+    kind TEXT NOT NULL,
+    storage TEXT NOT NULL,
+    file TEXT,
+    destination TEXT,
+    size_bytes BIGINT,
+    checksum TEXT,
+    error TEXT
+);
+
+CREATE INDEX IF NOT EXISTS backup_runs_started_at_idx ON backup_runs(started_at DESC);
+
+-- +goose Down
+DROP TABLE IF EXISTS backup_runs;
@@ -0,0 +1,17 @@
+-- +goose Up
+CREATE TABLE IF NOT EXISTS cron_runs (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    subcommand TEXT NOT NULL,
+    started_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    finished_at TIMESTAMPTZ,
+    status TEXT NOT NULL DEFAULT 'running' CHECK (status IN ('running', 'success', 'failure', 'skipped')),
+    duration_ms BIGINT,
+    counts JSONB NOT NULL DEFAULT '{}'::jsonb,
+    error TEXT
+);
+
+CREATE INDEX IF NOT EXISTS cron_runs_started_at_idx ON cron_runs(started_at DESC);
+CREATE INDEX IF NOT EXISTS cron_runs_subcommand_started_at_idx ON cron_runs(subcommand, started_at DESC);
+
+-- +goose Down
+DROP TABLE IF EXISTS cron_runs;
@@ -0,0 +1,19 @@
+-- +goose Up
+CREATE TABLE IF NOT EXISTS link_ingest_status (
+    link_id UUID PRIMARY KEY REFERENCES links(id) ON DELETE CASCADE,
+    status TEXT NOT NULL DEFAULT 'pending',
+    failed_stage TEXT,
+    error TEXT,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    final_url TEXT,
+    fetch_bytes BIGINT,
+    fetch_ms INTEGER,
+    parse_ms INTEGER,
+    lang TEXT,
+    lang_detected BOOLEAN,
+    word_count INTEGER,
+    diagnostics_at TIMESTAMPTZ
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS link_ingest_status;
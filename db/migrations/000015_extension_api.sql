@@ -0,0 +1,15 @@
+-- +goose Up
+-- Lets extensions check whether a page is saved by the SHA-256 of its URL.
+CREATE INDEX IF NOT EXISTS links_user_url_hash_idx ON links (user_id, encode(digest(url, 'sha256'), 'hex'));
+
+-- Page HTML captured by an extension at save time. The worker archives it in
+-- place of fetching the URL, then deletes it.
+CREATE TABLE IF NOT EXISTS link_captures (
+    link_id UUID PRIMARY KEY REFERENCES links(id) ON DELETE CASCADE,
+    html TEXT NOT NULL,
+    captured_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS link_captures;
+DROP INDEX IF EXISTS links_user_url_hash_idx;
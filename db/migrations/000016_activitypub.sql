@@ -0,0 +1,30 @@
+-- +goose Up
+-- Links a user has chosen to publish. NULL keeps a link private; the time
+-- orders the user's ActivityPub outbox.
+ALTER TABLE links ADD COLUMN IF NOT EXISTS public_at TIMESTAMPTZ;
+CREATE INDEX IF NOT EXISTS links_user_public_at_idx ON links (user_id, public_at DESC) WHERE public_at IS NOT NULL;
+
+-- A user's opt-in ActivityPub identity, reachable as @username@host.
+CREATE TABLE IF NOT EXISTS activitypub_actors (
+    user_id UUID PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    username TEXT NOT NULL UNIQUE,
+    display_name TEXT,
+    summary TEXT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- Remote actors following a user. inbox is where new public links are
+-- delivered, the shared inbox when the remote server offers one.
+CREATE TABLE IF NOT EXISTS activitypub_followers (
+    user_id UUID NOT NULL REFERENCES activitypub_actors(user_id) ON DELETE CASCADE,
+    actor_uri TEXT NOT NULL,
+    inbox TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (user_id, actor_uri)
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS activitypub_followers;
+DROP TABLE IF EXISTS activitypub_actors;
+DROP INDEX IF EXISTS links_user_public_at_idx;
+ALTER TABLE links DROP COLUMN IF EXISTS public_at;
@@ -0,0 +1,136 @@
+-- +goose Up
+-- sync_changes logs every change to a user's links and highlights so sync
+-- clients can ask for what changed since their cursor. Rows are ordered by
+-- (tx_id, seq): once every transaction below the snapshot xmin has finished,
+-- no row can later appear before that point, which a plain sequence does not
+-- guarantee when transactions commit out of order.
+CREATE TABLE sync_changes (
+    seq BIGSERIAL PRIMARY KEY,
+    user_id UUID NOT NULL,
+    entity TEXT NOT NULL CHECK (entity IN ('link', 'highlight')),
+    entity_id UUID NOT NULL,
+    deleted BOOLEAN NOT NULL DEFAULT FALSE,
+    tx_id XID8 NOT NULL DEFAULT pg_current_xact_id(),
+    changed_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX sync_changes_user_cursor_idx ON sync_changes(user_id, tx_id, seq);
+CREATE INDEX sync_changes_entity_idx ON sync_changes(entity_id, tx_id, seq);
+
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION sync_record_link_change() RETURNS TRIGGER AS $$
+BEGIN
+    IF TG_OP = 'DELETE' THEN
+        INSERT INTO sync_changes (user_id, entity, entity_id, deleted)
+        VALUES (OLD.user_id, 'link', OLD.id, TRUE);
+    ELSE
+        INSERT INTO sync_changes (user_id, entity, entity_id)
+        VALUES (NEW.user_id, 'link', NEW.id);
+    END IF;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+-- Tags travel with their link, so tagging changes are recorded as link
+-- changes. When the link itself is being deleted its row is already gone and
+-- the link's tombstone covers the tags.
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION sync_record_link_tags_change() RETURNS TRIGGER AS $$
+DECLARE
+    changed_link UUID;
+BEGIN
+    IF TG_OP = 'DELETE' THEN
+        changed_link := OLD.link_id;
+    ELSE
+        changed_link := NEW.link_id;
+    END IF;
+    INSERT INTO sync_changes (user_id, entity, entity_id)
+    SELECT user_id, 'link', id FROM links WHERE id = changed_link;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION sync_record_tag_rename() RETURNS TRIGGER AS $$
+BEGIN
+    INSERT INTO sync_changes (user_id, entity, entity_id)
+    SELECT l.user_id, 'link', l.id
+    FROM link_tags lt
+    JOIN links l ON l.id = lt.link_id
+    WHERE lt.tag_id = NEW.id;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+-- Highlights removed along with their link get no tombstone of their own;
+-- clients drop a link's highlights with the link.
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION sync_record_highlight_change() RETURNS TRIGGER AS $$
+BEGIN
+    IF TG_OP = 'DELETE' THEN
+        INSERT INTO sync_changes (user_id, entity, entity_id, deleted)
+        SELECT user_id, 'highlight', OLD.id, TRUE FROM links WHERE id = OLD.link_id;
+    ELSE
+        INSERT INTO sync_changes (user_id, entity, entity_id)
+        SELECT user_id, 'highlight', NEW.id FROM links WHERE id = NEW.link_id;
+    END IF;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+CREATE TRIGGER links_sync_insert_delete_trigger
+AFTER INSERT OR DELETE ON links
+FOR EACH ROW EXECUTE FUNCTION sync_record_link_change();
+
+-- Only fields sync clients see count; ingest and resurfacing bookkeeping
+-- does not.
+CREATE TRIGGER links_sync_update_trigger
+AFTER UPDATE ON links
+FOR EACH ROW
+WHEN (
+    OLD.url IS DISTINCT FROM NEW.url
+    OR OLD.title IS DISTINCT FROM NEW.title
+    OR OLD.favorite IS DISTINCT FROM NEW.favorite
+    OR OLD.read_at IS DISTINCT FROM NEW.read_at
+)
+EXECUTE FUNCTION sync_record_link_change();
+
+CREATE TRIGGER link_tags_sync_trigger
+AFTER INSERT OR DELETE ON link_tags
+FOR EACH ROW EXECUTE FUNCTION sync_record_link_tags_change();
+
+CREATE TRIGGER tags_sync_rename_trigger
+AFTER UPDATE OF name ON tags
+FOR EACH ROW
+WHEN (OLD.name IS DISTINCT FROM NEW.name)
+EXECUTE FUNCTION sync_record_tag_rename();
+
+CREATE TRIGGER highlights_sync_trigger
+AFTER INSERT OR UPDATE OR DELETE ON highlights
+FOR EACH ROW EXECUTE FUNCTION sync_record_highlight_change();
+
+-- Existing data gets one change each so a first sync sees everything.
+INSERT INTO sync_changes (user_id, entity, entity_id)
+SELECT user_id, 'link', id FROM links ORDER BY created_at, id;
+
+INSERT INTO sync_changes (user_id, entity, entity_id)
+SELECT l.user_id, 'highlight', h.id
+FROM highlights h
+JOIN links l ON l.id = h.link_id
+ORDER BY h.created_at, h.id;
+
+-- +goose Down
+DROP TRIGGER IF EXISTS highlights_sync_trigger ON highlights;
+DROP TRIGGER IF EXISTS tags_sync_rename_trigger ON tags;
+DROP TRIGGER IF EXISTS link_tags_sync_trigger ON link_tags;
+DROP TRIGGER IF EXISTS links_sync_update_trigger ON links;
+DROP TRIGGER IF EXISTS links_sync_insert_delete_trigger ON links;
+DROP FUNCTION IF EXISTS sync_record_highlight_change();
+DROP FUNCTION IF EXISTS sync_record_tag_rename();
+DROP FUNCTION IF EXISTS sync_record_link_tags_change();
+DROP FUNCTION IF EXISTS sync_record_link_change();
+DROP TABLE IF EXISTS sync_changes;
@@ -0,0 +1,22 @@
+-- +goose Up
+-- Inbound webhooks let services such as IFTTT, Zapier, or Shortcuts save
+-- links by posting to /api/hooks/<token>. Only a SHA-256 of the token is
+-- kept. The *_field columns name where each value sits in the payload, as a
+-- dotted path into JSON bodies or a form field name.
+CREATE TABLE IF NOT EXISTS inbound_hooks (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    token_hash TEXT NOT NULL UNIQUE,
+    url_field TEXT NOT NULL DEFAULT 'url',
+    title_field TEXT NOT NULL DEFAULT 'title',
+    tags_field TEXT NOT NULL DEFAULT 'tags',
+    default_tags TEXT[] NOT NULL DEFAULT '{}',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    last_used_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS inbound_hooks_user_idx ON inbound_hooks (user_id, created_at);
+
+-- +goose Down
+DROP TABLE IF EXISTS inbound_hooks;
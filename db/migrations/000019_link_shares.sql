@@ -0,0 +1,18 @@
+-- +goose Up
+-- A link's owner can share it with another user on the instance. read lets
+-- them open the link, its archive and its highlights; annotate also lets them
+-- add and edit highlights. A share with expires_at in the past is ignored
+-- and can be renewed by sharing again.
+CREATE TABLE IF NOT EXISTS link_shares (
+    link_id UUID NOT NULL REFERENCES links(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    permission TEXT NOT NULL CHECK (permission IN ('read', 'annotate')),
+    expires_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (link_id, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS link_shares_user_idx ON link_shares (user_id, created_at);
+
+-- +goose Down
+DROP TABLE IF EXISTS link_shares;
@@ -0,0 +1,11 @@
+-- +goose Up
+-- A claim marks a link as being read by someone, so people sharing a queue
+-- do not all pick it up. expires_at lets a claim lapse on its own; NULL
+-- keeps it until it is released.
+ALTER TABLE claims ADD COLUMN IF NOT EXISTS expires_at TIMESTAMPTZ;
+
+CREATE INDEX IF NOT EXISTS claims_user_idx ON claims (user_id, claimed_at);
+
+-- +goose Down
+DROP INDEX IF EXISTS claims_user_idx;
+ALTER TABLE claims DROP COLUMN IF EXISTS expires_at;
@@ -0,0 +1,25 @@
+-- +goose Up
+-- Admins also receive instance-wide notifications such as failed backups.
+-- The dev user runs single-user installs, so it starts as one.
+ALTER TABLE users ADD COLUMN IF NOT EXISTS is_admin BOOLEAN NOT NULL DEFAULT FALSE;
+UPDATE users SET is_admin = TRUE WHERE id = '00000000-0000-0000-0000-000000000001';
+
+-- In-app notifications, recorded from domain events. link_id is set for
+-- notifications about one link and goes with it. read_at stays NULL until
+-- the user marks the notification read.
+CREATE TABLE IF NOT EXISTS notifications (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    kind TEXT NOT NULL CHECK (kind IN ('ingest_failed', 'digest_sent', 'backup_failed')),
+    link_id UUID REFERENCES links(id) ON DELETE CASCADE,
+    message TEXT NOT NULL,
+    read_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS notifications_user_created_idx ON notifications (user_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS notifications_user_unread_idx ON notifications (user_id) WHERE read_at IS NULL;
+
+-- +goose Down
+DROP TABLE IF EXISTS notifications;
+ALTER TABLE users DROP COLUMN IF EXISTS is_admin;
@@ -0,0 +1,19 @@
+-- +goose Up
+-- Weekly reading goals: how many articles, or minutes of reading, a user
+-- means to get through each week. Progress is counted from links.read_at,
+-- so a user has at most one goal per metric.
+CREATE TABLE IF NOT EXISTS reading_goals (
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    metric TEXT NOT NULL CHECK (metric IN ('articles', 'minutes')),
+    weekly_target INTEGER NOT NULL CHECK (weekly_target > 0),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (user_id, metric)
+);
+
+-- Progress and streaks read a user's links by read time.
+CREATE INDEX IF NOT EXISTS links_user_read_at_idx ON links (user_id, read_at) WHERE read_at IS NOT NULL;
+
+-- +goose Down
+DROP INDEX IF EXISTS links_user_read_at_idx;
+DROP TABLE IF EXISTS reading_goals;
@@ -0,0 +1,38 @@
+-- +goose Up
+-- Per-user preferences for the sites links are saved from. A preference for
+-- a domain also covers its subdomains, and the most specific one wins, so
+-- example.com can be muted while blog.example.com is not. The create handler
+-- applies always_favorite and default_tags, the worker sends fetch_headers,
+-- and the resurfacer skips never_resurface domains.
+CREATE TABLE IF NOT EXISTS domain_preferences (
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    domain TEXT NOT NULL,
+    always_favorite BOOLEAN NOT NULL DEFAULT FALSE,
+    default_tags TEXT[] NOT NULL DEFAULT '{}',
+    never_resurface BOOLEAN NOT NULL DEFAULT FALSE,
+    fetch_headers JSONB NOT NULL DEFAULT '{}'::jsonb,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (user_id, domain)
+);
+
+-- url_host returns the lower-cased host of an absolute URL, without any
+-- user info or port.
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION url_host(url TEXT) RETURNS TEXT AS $$
+    SELECT lower(substring(url from '^[a-zA-Z][a-zA-Z0-9+.-]*://(?:[^/?#@]*@)?([^/:?#]+)'));
+$$ LANGUAGE sql IMMUTABLE;
+-- +goose StatementEnd
+
+-- domain_covers reports whether a preference for domain applies to host:
+-- the domain itself or any of its subdomains.
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION domain_covers(domain TEXT, host TEXT) RETURNS BOOLEAN AS $$
+    SELECT host = domain OR right(host, length(domain) + 1) = '.' || domain;
+$$ LANGUAGE sql IMMUTABLE;
+-- +goose StatementEnd
+
+-- +goose Down
+DROP FUNCTION IF EXISTS domain_covers(TEXT, TEXT);
+DROP FUNCTION IF EXISTS url_host(TEXT);
+DROP TABLE IF EXISTS domain_preferences;
@@ -0,0 +1,16 @@
+-- +goose Up
+-- Daily usage counters behind the per-user link and highlight quotas. Each
+-- row counts one kind of write for one user on one UTC day; rows for past
+-- days are only kept until the API sweeps them.
+CREATE TABLE IF NOT EXISTS usage_quotas (
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    kind TEXT NOT NULL CHECK (kind IN ('links', 'highlights')),
+    day DATE NOT NULL,
+    used INTEGER NOT NULL DEFAULT 0,
+    PRIMARY KEY (user_id, kind, day)
+);
+
+CREATE INDEX IF NOT EXISTS usage_quotas_day_idx ON usage_quotas (day);
+
+-- +goose Down
+DROP TABLE IF EXISTS usage_quotas;
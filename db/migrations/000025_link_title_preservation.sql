@@ -0,0 +1,12 @@
+-- +goose Up
+-- original_title keeps the title a link was saved with, which ingestion
+-- would otherwise overwrite with the extracted one. preserve_title stops
+-- the worker doing so.
+ALTER TABLE links
+    ADD COLUMN IF NOT EXISTS original_title TEXT,
+    ADD COLUMN IF NOT EXISTS preserve_title BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- +goose Down
+ALTER TABLE links
+    DROP COLUMN IF EXISTS preserve_title,
+    DROP COLUMN IF EXISTS original_title;
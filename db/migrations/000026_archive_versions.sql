@@ -0,0 +1,49 @@
+-- +goose Up
+-- archive_versions keeps the extracted text an archive had before a
+-- re-ingest replaced it, so changes to an article after publication can be
+-- diffed. archived_at is when that text was stored and replaced_at when it
+-- was superseded. Only the newest 20 versions of a link are kept.
+CREATE TABLE archive_versions (
+    id BIGSERIAL PRIMARY KEY,
+    link_id UUID NOT NULL REFERENCES links(id) ON DELETE CASCADE,
+    title TEXT,
+    extracted_text TEXT NOT NULL,
+    word_count INTEGER,
+    archived_at TIMESTAMPTZ NOT NULL,
+    replaced_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX archive_versions_link_id_idx ON archive_versions(link_id, id);
+
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION archives_keep_previous_version() RETURNS TRIGGER AS $$
+BEGIN
+    INSERT INTO archive_versions (link_id, title, extracted_text, word_count, archived_at)
+    VALUES (OLD.link_id, OLD.title, OLD.extracted_text, OLD.word_count, OLD.updated_at);
+
+    DELETE FROM archive_versions
+    WHERE link_id = OLD.link_id
+      AND id NOT IN (
+          SELECT id
+          FROM archive_versions
+          WHERE link_id = OLD.link_id
+          ORDER BY id DESC
+          LIMIT 20
+      );
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+-- Vacuuming archive HTML and backfilling word counts leave the text alone,
+-- so they never add a version.
+CREATE TRIGGER archives_keep_previous_version_trigger
+AFTER UPDATE OF extracted_text ON archives
+FOR EACH ROW
+WHEN (COALESCE(OLD.extracted_text, '') <> '' AND OLD.extracted_text IS DISTINCT FROM NEW.extracted_text)
+EXECUTE FUNCTION archives_keep_previous_version();
+
+-- +goose Down
+DROP TRIGGER IF EXISTS archives_keep_previous_version_trigger ON archives;
+DROP FUNCTION IF EXISTS archives_keep_previous_version();
+DROP TABLE IF EXISTS archive_versions;
@@ -0,0 +1,16 @@
+-- +goose Up
+-- public_profiles opts a user in to a public page of their favorites at
+-- /u/:username/favorites. Without a row there is no page, and deleting the
+-- row takes it down again.
+CREATE TABLE IF NOT EXISTS public_profiles (
+    user_id UUID PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    username TEXT NOT NULL UNIQUE,
+    display_name TEXT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS links_user_favorite_created_idx ON links(user_id, created_at DESC) WHERE favorite;
+
+-- +goose Down
+DROP INDEX IF EXISTS links_user_favorite_created_idx;
+DROP TABLE IF EXISTS public_profiles;
@@ -0,0 +1,27 @@
+-- +goose Up
+-- recommendations.strategy names the scoring that produced each row, so
+-- impressions can be compared across weight changes.
+ALTER TABLE recommendations ADD COLUMN IF NOT EXISTS strategy TEXT NOT NULL DEFAULT 'resurfacer';
+
+-- recommendation_impressions records each time a recommended link was shown,
+-- through the API or in a digest, and whether the user later acted on it.
+CREATE TABLE IF NOT EXISTS recommendation_impressions (
+    id BIGSERIAL PRIMARY KEY,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    link_id UUID NOT NULL REFERENCES links(id) ON DELETE CASCADE,
+    strategy TEXT NOT NULL,
+    surface TEXT NOT NULL,
+    position INT NOT NULL,
+    shown_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    action TEXT,
+    acted_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS recommendation_impressions_user_shown_idx ON recommendation_impressions(user_id, shown_at DESC);
+CREATE INDEX IF NOT EXISTS recommendation_impressions_link_idx ON recommendation_impressions(link_id, shown_at DESC) WHERE acted_at IS NULL;
+
+-- +goose Down
+DROP INDEX IF EXISTS recommendation_impressions_link_idx;
+DROP INDEX IF EXISTS recommendation_impressions_user_shown_idx;
+DROP TABLE IF EXISTS recommendation_impressions;
+ALTER TABLE recommendations DROP COLUMN IF EXISTS strategy;
@@ -0,0 +1,9 @@
+-- +goose Up
+-- paywalled marks an archive the worker judged to be cut off by a paywall,
+-- so clients can say the saved text is partial.
+ALTER TABLE archives
+    ADD COLUMN IF NOT EXISTS paywalled BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- +goose Down
+ALTER TABLE archives
+    DROP COLUMN IF EXISTS paywalled;
@@ -0,0 +1,33 @@
+-- +goose Up
+-- jobs tracks long-running imports, exports, and reparses started through
+-- the API, so clients can poll their progress instead of waiting on the
+-- request.
+CREATE TABLE IF NOT EXISTS jobs (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    type TEXT NOT NULL CHECK (type IN ('import', 'export', 'reparse')),
+    state TEXT NOT NULL DEFAULT 'queued' CHECK (state IN ('queued', 'running', 'succeeded', 'failed')),
+    done INT NOT NULL DEFAULT 0,
+    total INT NOT NULL DEFAULT 0,
+    result JSONB,
+    error TEXT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    finished_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS jobs_finished_at_idx ON jobs(finished_at) WHERE finished_at IS NOT NULL;
+
+-- job_files holds the file a job produced, such as an export, until the job
+-- is deleted.
+CREATE TABLE IF NOT EXISTS job_files (
+    job_id UUID PRIMARY KEY REFERENCES jobs(id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    content_type TEXT NOT NULL,
+    body BYTEA NOT NULL
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS job_files;
+DROP INDEX IF EXISTS jobs_finished_at_idx;
+DROP TABLE IF EXISTS jobs;
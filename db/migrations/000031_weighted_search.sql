@@ -0,0 +1,102 @@
+-- +goose Up
+-- Weight the search vector so ts_rank_cd ranks title matches (A) over
+-- byline matches (B) over body matches (D). Existing vectors keep their
+-- unweighted form until the row changes or search-reindex rewrites them.
+
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION links_search_document(
+    link_title TEXT,
+    archive_title TEXT,
+    archive_byline TEXT,
+    archive_body TEXT
+) RETURNS TSVECTOR AS $$
+    SELECT setweight(to_tsvector('english', coalesce(link_title, '') || ' ' || coalesce(archive_title, '')), 'A') ||
+           setweight(to_tsvector('english', coalesce(archive_byline, '')), 'B') ||
+           setweight(to_tsvector('english', coalesce(archive_body, '')), 'D');
+$$ LANGUAGE sql IMMUTABLE;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION links_search_tsv_update() RETURNS TRIGGER AS $$
+DECLARE
+    archive_title TEXT;
+    archive_byline TEXT;
+    archive_body TEXT;
+BEGIN
+    SELECT a.title, a.byline, a.extracted_text
+    INTO archive_title, archive_byline, archive_body
+    FROM archives a
+    WHERE a.link_id = NEW.id;
+
+    NEW.search_tsv := links_search_document(NEW.title, archive_title, archive_byline, archive_body);
+
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION archives_refresh_link_search() RETURNS TRIGGER AS $$
+DECLARE
+    link_title TEXT;
+BEGIN
+    SELECT l.title INTO link_title FROM links l WHERE l.id = NEW.link_id;
+
+    UPDATE links
+    SET search_tsv = links_search_document(link_title, NEW.title, NEW.byline, NEW.extracted_text)
+    WHERE id = NEW.link_id;
+
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION links_search_tsv_update() RETURNS TRIGGER AS $$
+DECLARE
+    archive_title TEXT;
+    archive_byline TEXT;
+    archive_body TEXT;
+BEGIN
+    SELECT a.title, a.byline, a.extracted_text
+    INTO archive_title, archive_byline, archive_body
+    FROM archives a
+    WHERE a.link_id = NEW.id;
+
+    NEW.search_tsv := to_tsvector(
+        'english',
+        coalesce(NEW.title::text, '') || ' ' ||
+        coalesce(archive_title, '') || ' ' ||
+        coalesce(archive_byline, '') || ' ' ||
+        coalesce(archive_body, '')
+    );
+
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION archives_refresh_link_search() RETURNS TRIGGER AS $$
+DECLARE
+    link_title TEXT;
+BEGIN
+    SELECT l.title INTO link_title FROM links l WHERE l.id = NEW.link_id;
+
+    UPDATE links
+    SET search_tsv = to_tsvector(
+        'english',
+        coalesce(link_title, '') || ' ' ||
+        coalesce(NEW.title, '') || ' ' ||
+        coalesce(NEW.byline, '') || ' ' ||
+        coalesce(NEW.extracted_text, '')
+    )
+    WHERE id = NEW.link_id;
+
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+DROP FUNCTION IF EXISTS links_search_document(TEXT, TEXT, TEXT, TEXT);
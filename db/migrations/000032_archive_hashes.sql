@@ -0,0 +1,23 @@
+-- +goose Up
+-- html_sha256 and text_sha256 are hex SHA-256 digests of archives.html and
+-- archives.extracted_text as written at ingest, so archive-verify can tell
+-- when stored content changed without going through the worker. Archives
+-- saved before this migration have none until archive-verify records them.
+ALTER TABLE archives
+    ADD COLUMN IF NOT EXISTS html_sha256 TEXT,
+    ADD COLUMN IF NOT EXISTS text_sha256 TEXT;
+
+-- archive_content_sha256 hashes the UTF-8 bytes of content the way the
+-- worker does, so digests computed in SQL and in Go agree.
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION archive_content_sha256(content TEXT) RETURNS TEXT AS $$
+    SELECT encode(sha256(convert_to(content, 'UTF8')), 'hex');
+$$ LANGUAGE sql IMMUTABLE STRICT;
+-- +goose StatementEnd
+
+-- +goose Down
+DROP FUNCTION IF EXISTS archive_content_sha256(TEXT);
+
+ALTER TABLE archives
+    DROP COLUMN IF EXISTS html_sha256,
+    DROP COLUMN IF EXISTS text_sha256;
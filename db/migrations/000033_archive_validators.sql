@@ -0,0 +1,12 @@
+-- +goose Up
+-- etag and last_modified hold the ETag and Last-Modified response headers
+-- from the fetch an archive was built from. Reparses send them back as
+-- If-None-Match and If-Modified-Since and keep the archive on a 304.
+ALTER TABLE archives
+    ADD COLUMN IF NOT EXISTS etag TEXT,
+    ADD COLUMN IF NOT EXISTS last_modified TEXT;
+
+-- +goose Down
+ALTER TABLE archives
+    DROP COLUMN IF EXISTS etag,
+    DROP COLUMN IF EXISTS last_modified;
@@ -0,0 +1,24 @@
+-- +goose Up
+-- link_media records which mirrored images each link's archive shows. The
+-- worker adds a row before storing an image, and removes an image from
+-- media storage only once no row names it. Rows go with their link.
+CREATE TABLE IF NOT EXISTS link_media (
+    link_id UUID NOT NULL REFERENCES links(id) ON DELETE CASCADE,
+    hash TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (link_id, hash)
+);
+
+CREATE INDEX IF NOT EXISTS link_media_hash_idx ON link_media (hash);
+
+-- Archives mirrored before this migration reference their images only in
+-- their HTML.
+INSERT INTO link_media (link_id, hash)
+SELECT DISTINCT a.link_id, m[1]
+FROM archives a
+CROSS JOIN LATERAL regexp_matches(a.html, '/api/media/([0-9a-f]{64})', 'g') AS m
+WHERE a.html IS NOT NULL
+ON CONFLICT DO NOTHING;
+
+-- +goose Down
+DROP TABLE IF EXISTS link_media;
@@ -0,0 +1,63 @@
+-- +goose Up
+-- Tags belong to the user who made them, so two users naming a tag alike get
+-- two tags, and renaming or deleting one leaves the other's links alone.
+ALTER TABLE tags ADD COLUMN IF NOT EXISTS user_id UUID REFERENCES users(id) ON DELETE CASCADE;
+ALTER TABLE tags DROP CONSTRAINT IF EXISTS tags_name_key;
+
+-- A shared tag goes to the owner of the oldest link carrying it.
+UPDATE tags t
+SET user_id = oldest.user_id
+FROM (
+    SELECT DISTINCT ON (lt.tag_id) lt.tag_id, l.user_id
+    FROM link_tags lt
+    JOIN links l ON l.id = lt.link_id
+    ORDER BY lt.tag_id, l.created_at, l.id
+) AS oldest
+WHERE t.id = oldest.tag_id;
+
+-- Every other user carrying it gets a copy, and their links move to it.
+WITH copies AS (
+    INSERT INTO tags (name, user_id)
+    SELECT DISTINCT t.name, l.user_id
+    FROM link_tags lt
+    JOIN links l ON l.id = lt.link_id
+    JOIN tags t ON t.id = lt.tag_id
+    WHERE l.user_id <> t.user_id
+    RETURNING id, name, user_id
+)
+UPDATE link_tags lt
+SET tag_id = c.id
+FROM links l, tags t, copies c
+WHERE l.id = lt.link_id
+  AND t.id = lt.tag_id
+  AND l.user_id <> t.user_id
+  AND c.user_id = l.user_id
+  AND c.name = t.name;
+
+-- Tags no link carries go to the first account, usually DEV_USER_ID.
+UPDATE tags
+SET user_id = (SELECT id FROM users ORDER BY created_at, id LIMIT 1)
+WHERE user_id IS NULL;
+
+DELETE FROM tags WHERE user_id IS NULL;
+
+ALTER TABLE tags ALTER COLUMN user_id SET NOT NULL;
+ALTER TABLE tags ADD CONSTRAINT tags_user_id_name_key UNIQUE (user_id, name);
+
+-- +goose Down
+-- Tags sharing a name merge into the oldest of them.
+UPDATE link_tags lt
+SET tag_id = keep.id
+FROM tags t, (SELECT name, MIN(id) AS id FROM tags GROUP BY name) AS keep
+WHERE t.id = lt.tag_id
+  AND keep.name = t.name
+  AND lt.tag_id <> keep.id;
+
+DELETE FROM tags t
+USING (SELECT name, MIN(id) AS id FROM tags GROUP BY name) AS keep
+WHERE t.name = keep.name
+  AND t.id <> keep.id;
+
+ALTER TABLE tags DROP CONSTRAINT IF EXISTS tags_user_id_name_key;
+ALTER TABLE tags DROP COLUMN IF EXISTS user_id;
+ALTER TABLE tags ADD CONSTRAINT tags_name_key UNIQUE (name);
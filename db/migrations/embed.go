@@ -0,0 +1,36 @@
+// Package migrations embeds the goose migrations so the migrate binary and
+// the API agree on the schema version they were built with.
+package migrations
+
+import (
+	"embed"
+	"io/fs"
+	"strconv"
+	"strings"
+)
+
+// FS holds every migration file at its root.
+//
+//go:embed *.sql
+var FS embed.FS
+
+// Latest returns the highest migration version in FS, read from the numeric
+// prefix of each file name.
+func Latest() int64 {
+	entries, err := fs.ReadDir(FS, ".")
+	if err != nil {
+		return 0
+	}
+	var latest int64
+	for _, entry := range entries {
+		prefix, _, ok := strings.Cut(entry.Name(), "_")
+		if !ok {
+			continue
+		}
+		version, err := strconv.ParseInt(prefix, 10, 64)
+		if err == nil && version > latest {
+			latest = version
+		}
+	}
+	return latest
+}
@@ -0,0 +1,82 @@
+-- name: GetActivityPubActor :one
+SELECT user_id, username, display_name, summary, created_at
+FROM activitypub_actors
+WHERE user_id = sqlc.arg('user_id');
+
+-- name: GetActivityPubActorByUsername :one
+SELECT user_id, username, display_name, summary, created_at
+FROM activitypub_actors
+WHERE username = sqlc.arg('username');
+
+-- name: UpsertActivityPubActor :one
+INSERT INTO activitypub_actors (user_id, username, display_name, summary)
+VALUES (sqlc.arg('user_id'), sqlc.arg('username'), sqlc.narg('display_name'), sqlc.narg('summary'))
+ON CONFLICT (user_id) DO UPDATE
+SET username = EXCLUDED.username,
+    display_name = EXCLUDED.display_name,
+    summary = EXCLUDED.summary
+RETURNING user_id, username, display_name, summary, created_at;
+
+-- name: DeleteActivityPubActor :exec
+DELETE FROM activitypub_actors
+WHERE user_id = sqlc.arg('user_id');
+
+-- name: AddActivityPubFollower :exec
+INSERT INTO activitypub_followers (user_id, actor_uri, inbox)
+VALUES (sqlc.arg('user_id'), sqlc.arg('actor_uri'), sqlc.arg('inbox'))
+ON CONFLICT (user_id, actor_uri) DO UPDATE
+SET inbox = EXCLUDED.inbox;
+
+-- name: RemoveActivityPubFollower :exec
+DELETE FROM activitypub_followers
+WHERE user_id = sqlc.arg('user_id')
+  AND actor_uri = sqlc.arg('actor_uri');
+
+-- name: CountActivityPubFollowers :one
+SELECT COUNT(*)::bigint
+FROM activitypub_followers
+WHERE user_id = sqlc.arg('user_id');
+
+-- name: ListActivityPubFollowerInboxes :many
+-- Followers on the same server share an inbox, so each is listed once.
+SELECT DISTINCT inbox
+FROM activitypub_followers
+WHERE user_id = sqlc.arg('user_id')
+ORDER BY inbox;
+
+-- name: SetLinkPublic :one
+-- Returns the previous value too, so callers only announce actual changes.
+WITH previous AS (
+    SELECT id, public_at
+    FROM links
+    WHERE id = sqlc.arg('id')
+    FOR UPDATE
+)
+UPDATE links l
+SET public_at = CASE WHEN sqlc.arg('public')::boolean THEN COALESCE(previous.public_at, NOW()) ELSE NULL END
+FROM previous
+WHERE l.id = previous.id
+RETURNING l.public_at, previous.public_at AS previous_public_at;
+
+-- name: GetPublicLink :one
+SELECT l.id, l.user_id, l.url, COALESCE(l.title, a.title, l.url)::text AS title, l.public_at
+FROM links l
+LEFT JOIN archives a ON a.link_id = l.id
+WHERE l.id = sqlc.arg('id')
+  AND l.public_at IS NOT NULL;
+
+-- name: ListPublicLinks :many
+SELECT l.id, l.url, COALESCE(l.title, a.title, l.url)::text AS title, l.public_at
+FROM links l
+LEFT JOIN archives a ON a.link_id = l.id
+WHERE l.user_id = sqlc.arg('user_id')
+  AND l.public_at IS NOT NULL
+ORDER BY l.public_at DESC, l.id DESC
+LIMIT sqlc.arg('page_limit')
+OFFSET sqlc.arg('page_offset');
+
+-- name: CountPublicLinks :one
+SELECT COUNT(*)::bigint
+FROM links
+WHERE user_id = sqlc.arg('user_id')
+  AND public_at IS NOT NULL;
@@ -0,0 +1,165 @@
+-- name: CountOrphanedArchives :one
+SELECT COUNT(*)::bigint AS archives,
+       COALESCE(SUM(COALESCE(octet_length(a.html), 0) + COALESCE(octet_length(a.extracted_text), 0)), 0)::bigint AS bytes
+FROM archives a
+WHERE NOT EXISTS (SELECT 1 FROM links l WHERE l.id = a.link_id);
+
+-- name: DeleteOrphanedArchives :one
+WITH deleted AS (
+    DELETE FROM archives a
+    WHERE NOT EXISTS (SELECT 1 FROM links l WHERE l.id = a.link_id)
+    RETURNING COALESCE(octet_length(a.html), 0) + COALESCE(octet_length(a.extracted_text), 0) AS bytes
+)
+SELECT COUNT(*)::bigint AS archives,
+       COALESCE(SUM(deleted.bytes), 0)::bigint AS bytes
+FROM deleted;
+
+-- name: CountArchivesWithHTML :one
+SELECT COUNT(*)::bigint
+FROM archives a
+WHERE COALESCE(a.html, '') <> '';
+
+-- name: CountExpiredArchiveHTML :one
+SELECT COUNT(*)::bigint AS archives,
+       COALESCE(SUM(octet_length(html)), 0)::bigint AS bytes
+FROM archives
+WHERE html IS NOT NULL
+  AND updated_at < sqlc.arg('cutoff');
+
+-- name: ClearExpiredArchiveHTML :one
+WITH batch AS (
+    SELECT link_id, octet_length(html) AS bytes
+    FROM archives
+    WHERE html IS NOT NULL
+      AND updated_at < sqlc.arg('cutoff')
+    ORDER BY updated_at
+    LIMIT sqlc.arg('batch_size')
+    FOR UPDATE SKIP LOCKED
+), cleared AS (
+    UPDATE archives a
+    SET html = NULL,
+        html_sha256 = NULL
+    FROM batch
+    WHERE a.link_id = batch.link_id
+    RETURNING batch.bytes
+)
+SELECT COUNT(*)::bigint AS archives,
+       COALESCE(SUM(cleared.bytes), 0)::bigint AS bytes
+FROM cleared;
+
+-- name: GetArchiveVersion :one
+SELECT id, link_id, title, extracted_text, word_count, archived_at, replaced_at
+FROM archive_versions
+WHERE link_id = sqlc.arg('link_id')
+  AND id = sqlc.arg('id');
+
+-- name: GetCurrentArchiveVersion :one
+-- GetCurrentArchiveVersion returns the text a link's archive has now, which
+-- is the newest version and not yet in archive_versions.
+SELECT COALESCE(a.title, '')::text AS title,
+       COALESCE(a.extracted_text, '')::text AS extracted_text,
+       COALESCE(a.word_count, 0)::int AS word_count,
+       a.updated_at,
+       COALESCE(a.html_sha256, '')::text AS html_sha256,
+       COALESCE(a.text_sha256, '')::text AS text_sha256
+FROM archives a
+WHERE a.link_id = sqlc.arg('link_id');
+
+-- name: GetReaderArchive :one
+-- GetReaderArchive returns the parts of a link's archive the reader page
+-- renders; html is empty once archive vacuuming has cleared it.
+SELECT COALESCE(a.title, '')::text AS title,
+       COALESCE(a.byline, '')::text AS byline,
+       COALESCE(a.lang, '')::text AS lang,
+       COALESCE(a.word_count, 0)::int AS word_count,
+       COALESCE(a.html, '')::text AS html,
+       COALESCE(a.extracted_text, '')::text AS extracted_text,
+       a.paywalled
+FROM archives a
+WHERE a.link_id = sqlc.arg('link_id');
+
+-- name: CountArchivesMissingTextStats :one
+SELECT COUNT(*)::bigint
+FROM archives a
+WHERE COALESCE(a.extracted_text, '') <> ''
+  AND (COALESCE(a.word_count, 0) = 0 OR COALESCE(a.lang, '') = '');
+
+-- name: ListArchiveHTMLBatch :many
+-- ListArchiveHTMLBatch returns the next batch of archives, by link_id after
+-- after_id, that still have HTML.
+SELECT a.link_id,
+       a.html::text AS html
+FROM archives a
+WHERE COALESCE(a.html, '') <> ''
+  AND (sqlc.narg('after_id')::uuid IS NULL OR a.link_id > sqlc.narg('after_id')::uuid)
+ORDER BY a.link_id
+LIMIT sqlc.arg('batch_size')::int;
+
+-- name: ListArchiveTextStatsBatch :many
+-- ListArchiveTextStatsBatch returns the next batch of archives, by link_id
+-- after after_id, that have extracted text but no word count or language.
+SELECT a.link_id,
+       a.extracted_text::text AS extracted_text,
+       COALESCE(a.word_count, 0)::int AS word_count,
+       COALESCE(a.lang, '')::text AS lang
+FROM archives a
+WHERE COALESCE(a.extracted_text, '') <> ''
+  AND (COALESCE(a.word_count, 0) = 0 OR COALESCE(a.lang, '') = '')
+  AND (sqlc.narg('after_id')::uuid IS NULL OR a.link_id > sqlc.narg('after_id')::uuid)
+ORDER BY a.link_id
+LIMIT sqlc.arg('batch_size')::int;
+
+-- name: ListArchiveVersions :many
+-- ListArchiveVersions lists the previous versions of a link's archive,
+-- newest first, without their text.
+SELECT id,
+       COALESCE(title, '')::text AS title,
+       COALESCE(word_count, 0)::int AS word_count,
+       archived_at,
+       replaced_at
+FROM archive_versions
+WHERE link_id = sqlc.arg('link_id')
+ORDER BY id DESC;
+
+-- name: UpdateArchiveHTML :exec
+-- UpdateArchiveHTML replaces an archive's HTML and, for an archive that has
+-- digests, the digest of it, so archive-verify accepts the rewrite.
+UPDATE archives
+SET html = sqlc.arg('html'),
+    html_sha256 = CASE
+        WHEN text_sha256 IS NULL THEN NULL
+        ELSE archive_content_sha256(sqlc.arg('html'))
+    END
+WHERE link_id = sqlc.arg('link_id');
+
+-- name: UpdateArchiveTextStats :exec
+UPDATE archives
+SET word_count = sqlc.arg('word_count'),
+    lang = sqlc.narg('lang')
+WHERE link_id = sqlc.arg('link_id');
+
+-- name: CountArchivesForIntegrity :one
+SELECT COUNT(*)::bigint FROM archives;
+
+-- name: CheckArchiveIntegrityBatch :many
+-- CheckArchiveIntegrityBatch hashes the next batch of archives, by link_id
+-- after after_id, and reports whether each matches the digests stored at
+-- ingest. html_sha256 is NULL once archive vacuuming clears the HTML, and
+-- archives without a text_sha256 predate the digests.
+SELECT a.link_id,
+       (a.text_sha256 IS NOT NULL)::boolean AS hashed,
+       (a.html_sha256 IS NULL OR a.html_sha256 = archive_content_sha256(COALESCE(a.html, '')))::boolean AS html_matches,
+       (a.text_sha256 IS NULL OR a.text_sha256 = archive_content_sha256(COALESCE(a.extracted_text, '')))::boolean AS text_matches
+FROM archives a
+WHERE sqlc.narg('after_id')::uuid IS NULL OR a.link_id > sqlc.narg('after_id')::uuid
+ORDER BY a.link_id
+LIMIT sqlc.arg('batch_size')::int;
+
+-- name: RecordArchiveHashes :execrows
+-- RecordArchiveHashes stores digests of what the listed archives hold now,
+-- for archives saved before ingest recorded them.
+UPDATE archives
+SET html_sha256 = archive_content_sha256(html),
+    text_sha256 = archive_content_sha256(COALESCE(extracted_text, ''))
+WHERE link_id = ANY(sqlc.arg('link_ids')::uuid[])
+  AND text_sha256 IS NULL;
@@ -0,0 +1,23 @@
+-- name: CreateBackupRun :one
+INSERT INTO backup_runs (kind, storage)
+VALUES (sqlc.arg('kind'), sqlc.arg('storage'))
+RETURNING id, started_at, finished_at, status, kind, storage, file, destination, size_bytes, checksum, error;
+
+-- name: FinishBackupRun :exec
+UPDATE backup_runs
+SET finished_at = NOW(),
+    status = sqlc.arg('status'),
+    kind = sqlc.arg('kind'),
+    file = sqlc.narg('file'),
+    destination = sqlc.narg('destination'),
+    size_bytes = sqlc.narg('size_bytes'),
+    checksum = sqlc.narg('checksum'),
+    error = sqlc.narg('error')
+WHERE id = sqlc.arg('id');
+
+-- name: ListBackupRuns :many
+SELECT id, started_at, finished_at, status, kind, storage, file, destination, size_bytes, checksum, error
+FROM backup_runs
+ORDER BY started_at DESC
+LIMIT sqlc.arg('page_limit')
+OFFSET sqlc.arg('page_offset');
@@ -1,10 +1,37 @@
 -- name: CreateClaim :one
+-- CreateClaim claims a link for a user. Claiming it again replaces the
+-- expiry, and a claim that had already expired starts over from now.
 WITH upsert AS (
-    INSERT INTO claims (link_id, user_id)
-    VALUES (sqlc.arg('link_id'), sqlc.arg('user_id'))
+    INSERT INTO claims (link_id, user_id, expires_at)
+    VALUES (sqlc.arg('link_id'), sqlc.arg('user_id'), sqlc.narg('expires_at'))
     ON CONFLICT (link_id, user_id) DO UPDATE
-        SET claimed_at = claims.claimed_at
-    RETURNING id, link_id, user_id, claimed_at, xmax = 0 AS inserted
+        SET claimed_at = CASE
+                WHEN claims.expires_at IS NOT NULL AND claims.expires_at <= NOW() THEN NOW()
+                ELSE claims.claimed_at
+            END,
+            expires_at = EXCLUDED.expires_at
+    RETURNING id, link_id, user_id, claimed_at, expires_at, xmax = 0 AS inserted
 )
-SELECT id, link_id, user_id, claimed_at, inserted
+SELECT id, link_id, user_id, claimed_at, expires_at, inserted
 FROM upsert;
+
+-- name: ListClaimsForUser :many
+-- ListClaimsForUser lists a user's unexpired claims, newest first, with the
+-- links they are on.
+SELECT c.id,
+       c.link_id,
+       c.claimed_at,
+       c.expires_at,
+       l.url,
+       l.title,
+       l.user_id AS owner_id
+FROM claims c
+JOIN links l ON l.id = c.link_id
+WHERE c.user_id = sqlc.arg('user_id')
+  AND (c.expires_at IS NULL OR c.expires_at > NOW())
+ORDER BY c.claimed_at DESC, c.id;
+
+-- name: DeleteClaim :execrows
+DELETE FROM claims
+WHERE id = sqlc.arg('id')
+  AND user_id = sqlc.arg('user_id');
@@ -0,0 +1,22 @@
+-- name: CreateCronRun :one
+INSERT INTO cron_runs (subcommand)
+VALUES (sqlc.arg('subcommand'))
+RETURNING id, subcommand, started_at, finished_at, status, duration_ms, counts, error;
+
+-- name: FinishCronRun :exec
+UPDATE cron_runs
+SET finished_at = NOW(),
+    status = sqlc.arg('status'),
+    duration_ms = sqlc.arg('duration_ms'),
+    counts = sqlc.arg('counts'),
+    error = sqlc.narg('error')
+WHERE id = sqlc.arg('id');
+
+-- name: ListCronRuns :many
+SELECT id, subcommand, started_at, finished_at, status, duration_ms, counts, error
+FROM cron_runs
+WHERE (sqlc.narg('subcommand')::text IS NULL OR subcommand = sqlc.narg('subcommand')::text)
+  AND (sqlc.narg('status')::text IS NULL OR status = sqlc.narg('status')::text)
+ORDER BY started_at DESC
+LIMIT sqlc.arg('page_limit')
+OFFSET sqlc.arg('page_offset');
@@ -0,0 +1,43 @@
+-- name: UpsertDomainPreference :one
+INSERT INTO domain_preferences (
+    user_id,
+    domain,
+    always_favorite,
+    default_tags,
+    never_resurface,
+    fetch_headers
+) VALUES (
+    sqlc.arg('user_id'),
+    sqlc.arg('domain'),
+    sqlc.arg('always_favorite'),
+    sqlc.arg('default_tags')::text[],
+    sqlc.arg('never_resurface'),
+    sqlc.arg('fetch_headers')
+)
+ON CONFLICT (user_id, domain) DO UPDATE
+SET always_favorite = EXCLUDED.always_favorite,
+    default_tags = EXCLUDED.default_tags,
+    never_resurface = EXCLUDED.never_resurface,
+    fetch_headers = EXCLUDED.fetch_headers,
+    updated_at = NOW()
+RETURNING user_id, domain, always_favorite, default_tags, never_resurface, fetch_headers, created_at, updated_at;
+
+-- name: ListDomainPreferences :many
+SELECT user_id, domain, always_favorite, default_tags, never_resurface, fetch_headers, created_at, updated_at
+FROM domain_preferences
+WHERE user_id = sqlc.arg('user_id')
+ORDER BY domain;
+
+-- name: MatchDomainPreference :one
+-- The most specific of the user's preferences covering url's host.
+SELECT user_id, domain, always_favorite, default_tags, never_resurface, fetch_headers, created_at, updated_at
+FROM domain_preferences
+WHERE user_id = sqlc.arg('user_id')
+  AND domain_covers(domain, url_host(sqlc.arg('url')::text))
+ORDER BY length(domain) DESC
+LIMIT 1;
+
+-- name: DeleteDomainPreference :execrows
+DELETE FROM domain_preferences
+WHERE user_id = sqlc.arg('user_id')
+  AND domain = sqlc.arg('domain');
@@ -0,0 +1,25 @@
+-- name: FindLinkByURLHash :one
+-- Matches the expression in links_user_url_hash_idx so the lookup is an
+-- index scan.
+SELECT id, url, created_at
+FROM links
+WHERE user_id = sqlc.arg('user_id')
+  AND encode(digest(url, 'sha256'), 'hex') = sqlc.arg('url_hash')::text
+ORDER BY created_at DESC
+LIMIT 1;
+
+-- name: UpsertLinkCapture :exec
+INSERT INTO link_captures (link_id, html, captured_at)
+VALUES (sqlc.arg('link_id'), sqlc.arg('html'), NOW())
+ON CONFLICT (link_id) DO UPDATE
+SET html = EXCLUDED.html,
+    captured_at = NOW();
+
+-- name: SearchTagsByPrefix :many
+-- prefix must already have LIKE wildcards escaped.
+SELECT id, name, user_id
+FROM tags
+WHERE user_id = sqlc.arg('user_id')
+  AND name ILIKE sqlc.arg('prefix')::text || '%'
+ORDER BY name
+LIMIT sqlc.arg('page_limit');
@@ -0,0 +1,37 @@
+-- name: UpsertReadingGoal :one
+INSERT INTO reading_goals (user_id, metric, weekly_target)
+VALUES (sqlc.arg('user_id'), sqlc.arg('metric'), sqlc.arg('weekly_target'))
+ON CONFLICT (user_id, metric) DO UPDATE
+SET weekly_target = EXCLUDED.weekly_target,
+    updated_at = NOW()
+RETURNING user_id, metric, weekly_target, created_at, updated_at;
+
+-- name: ListReadingGoals :many
+SELECT user_id, metric, weekly_target, created_at, updated_at
+FROM reading_goals
+WHERE user_id = sqlc.arg('user_id')
+ORDER BY metric;
+
+-- name: DeleteReadingGoal :execrows
+DELETE FROM reading_goals
+WHERE user_id = sqlc.arg('user_id')
+  AND metric = sqlc.arg('metric');
+
+-- name: GetReadingProgress :one
+-- GetReadingProgress counts the links a user read since a time and the
+-- minutes they took, rounding each link's reading time up.
+SELECT COUNT(*) AS articles,
+       COALESCE(SUM(CEIL(COALESCE(a.word_count, 0)::numeric / sqlc.arg('words_per_minute')::int)), 0)::bigint AS minutes
+FROM links l
+LEFT JOIN archives a ON a.link_id = l.id
+WHERE l.user_id = sqlc.arg('user_id')
+  AND l.read_at >= sqlc.arg('since');
+
+-- name: ListReadDays :many
+-- ListReadDays lists the UTC days a user read at least one link, newest
+-- first.
+SELECT DISTINCT (read_at AT TIME ZONE 'UTC')::date AS day
+FROM links
+WHERE user_id = sqlc.arg('user_id')
+  AND read_at IS NOT NULL
+ORDER BY day DESC;
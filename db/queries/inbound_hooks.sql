@@ -0,0 +1,40 @@
+-- name: CreateInboundHook :one
+INSERT INTO inbound_hooks (
+    user_id,
+    name,
+    token_hash,
+    url_field,
+    title_field,
+    tags_field,
+    default_tags
+) VALUES (
+    sqlc.arg('user_id'),
+    sqlc.arg('name'),
+    sqlc.arg('token_hash'),
+    sqlc.arg('url_field'),
+    sqlc.arg('title_field'),
+    sqlc.arg('tags_field'),
+    sqlc.arg('default_tags')::text[]
+)
+RETURNING id, user_id, name, token_hash, url_field, title_field, tags_field, default_tags, created_at, last_used_at;
+
+-- name: ListInboundHooks :many
+SELECT id, user_id, name, token_hash, url_field, title_field, tags_field, default_tags, created_at, last_used_at
+FROM inbound_hooks
+WHERE user_id = sqlc.arg('user_id')
+ORDER BY created_at;
+
+-- name: GetInboundHookByTokenHash :one
+SELECT id, user_id, name, token_hash, url_field, title_field, tags_field, default_tags, created_at, last_used_at
+FROM inbound_hooks
+WHERE token_hash = sqlc.arg('token_hash');
+
+-- name: TouchInboundHook :exec
+UPDATE inbound_hooks
+SET last_used_at = NOW()
+WHERE id = sqlc.arg('id');
+
+-- name: DeleteInboundHook :execrows
+DELETE FROM inbound_hooks
+WHERE id = sqlc.arg('id')
+  AND user_id = sqlc.arg('user_id');
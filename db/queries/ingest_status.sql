@@ -0,0 +1,49 @@
+-- name: UpsertLinkIngestStatus :one
+-- Records the stage a link's ingest has reached and returns the link owner
+-- so the change can be relayed to their event streams.
+INSERT INTO link_ingest_status (link_id, status, failed_stage, error, updated_at)
+VALUES (sqlc.arg('link_id'), sqlc.arg('status'), sqlc.narg('failed_stage'), sqlc.narg('error'), NOW())
+ON CONFLICT (link_id) DO UPDATE
+SET status = EXCLUDED.status,
+    failed_stage = EXCLUDED.failed_stage,
+    error = EXCLUDED.error,
+    updated_at = NOW()
+RETURNING (SELECT user_id FROM links WHERE links.id = link_ingest_status.link_id) AS user_id;
+
+-- name: UpsertLinkIngestDiagnostics :exec
+INSERT INTO link_ingest_status (
+    link_id,
+    final_url,
+    fetch_bytes,
+    fetch_ms,
+    parse_ms,
+    lang,
+    lang_detected,
+    word_count,
+    diagnostics_at
+)
+VALUES (
+    sqlc.arg('link_id'),
+    sqlc.narg('final_url'),
+    sqlc.narg('fetch_bytes'),
+    sqlc.narg('fetch_ms'),
+    sqlc.narg('parse_ms'),
+    sqlc.narg('lang'),
+    sqlc.narg('lang_detected'),
+    sqlc.narg('word_count'),
+    NOW()
+)
+ON CONFLICT (link_id) DO UPDATE
+SET final_url = EXCLUDED.final_url,
+    fetch_bytes = EXCLUDED.fetch_bytes,
+    fetch_ms = EXCLUDED.fetch_ms,
+    parse_ms = EXCLUDED.parse_ms,
+    lang = EXCLUDED.lang,
+    lang_detected = EXCLUDED.lang_detected,
+    word_count = EXCLUDED.word_count,
+    diagnostics_at = NOW();
+
+-- name: GetLinkIngestStatus :one
+SELECT link_id, status, failed_stage, error, updated_at, final_url, fetch_bytes, fetch_ms, parse_ms, lang, lang_detected, word_count, diagnostics_at
+FROM link_ingest_status
+WHERE link_id = sqlc.arg('link_id');
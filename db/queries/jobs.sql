@@ -0,0 +1,52 @@
+-- name: CreateJob :one
+INSERT INTO jobs (user_id, type, total)
+VALUES (sqlc.arg('user_id'), sqlc.arg('type'), sqlc.arg('total'))
+RETURNING id, user_id, type, state, done, total, result, error, created_at, updated_at, finished_at;
+
+-- name: GetJob :one
+SELECT id, user_id, type, state, done, total, result, error, created_at, updated_at, finished_at
+FROM jobs
+WHERE id = sqlc.arg('id')
+  AND user_id = sqlc.arg('user_id');
+
+-- name: UpdateJobProgress :exec
+-- UpdateJobProgress marks a job running and records how far it got. The
+-- update also serves as a heartbeat: a running job that stops updating was
+-- interrupted.
+UPDATE jobs
+SET state = 'running',
+    done = sqlc.arg('done'),
+    total = sqlc.arg('total'),
+    updated_at = NOW()
+WHERE id = sqlc.arg('id');
+
+-- name: FinishJob :exec
+UPDATE jobs
+SET state = sqlc.arg('state'),
+    done = sqlc.arg('done'),
+    result = sqlc.narg('result'),
+    error = sqlc.narg('error'),
+    updated_at = NOW(),
+    finished_at = NOW()
+WHERE id = sqlc.arg('id');
+
+-- name: SaveJobFile :exec
+INSERT INTO job_files (job_id, name, content_type, body)
+VALUES (sqlc.arg('job_id'), sqlc.arg('name'), sqlc.arg('content_type'), sqlc.arg('body'))
+ON CONFLICT (job_id) DO UPDATE
+SET name = EXCLUDED.name,
+    content_type = EXCLUDED.content_type,
+    body = EXCLUDED.body;
+
+-- name: GetJobFile :one
+SELECT f.job_id, f.name, f.content_type, f.body
+FROM job_files f
+JOIN jobs j ON j.id = f.job_id
+WHERE f.job_id = sqlc.arg('job_id')
+  AND j.user_id = sqlc.arg('user_id');
+
+-- name: DeleteJobsFinishedBefore :execrows
+-- DeleteJobsFinishedBefore removes finished jobs, and their files, older
+-- than the retention window.
+DELETE FROM jobs
+WHERE finished_at < sqlc.arg('before');
@@ -0,0 +1,7 @@
+-- name: ListLinkMediaHashes :many
+-- ListLinkMediaHashes returns the mirrored images a link's archive showed,
+-- as the worker recorded them when mirroring.
+SELECT hash
+FROM link_media
+WHERE link_id = $1
+ORDER BY hash;
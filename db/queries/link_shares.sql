@@ -0,0 +1,54 @@
+-- name: GetUserIDByEmail :one
+SELECT id
+FROM users
+WHERE lower(email) = lower(sqlc.arg('email'));
+
+-- name: UpsertLinkShare :one
+-- UpsertLinkShare shares a link with a user, replacing the permission and
+-- expiry when it is already shared with them.
+INSERT INTO link_shares (link_id, user_id, permission, expires_at)
+VALUES (sqlc.arg('link_id'), sqlc.arg('user_id'), sqlc.arg('permission'), sqlc.narg('expires_at'))
+ON CONFLICT (link_id, user_id) DO UPDATE
+SET permission = EXCLUDED.permission,
+    expires_at = EXCLUDED.expires_at
+RETURNING link_id, user_id, permission, expires_at, created_at;
+
+-- name: ListLinkShares :many
+SELECT s.link_id,
+       s.user_id,
+       u.email,
+       s.permission,
+       s.expires_at,
+       s.created_at
+FROM link_shares s
+JOIN users u ON u.id = s.user_id
+WHERE s.link_id = sqlc.arg('link_id')
+ORDER BY s.created_at, u.email;
+
+-- name: DeleteLinkShare :execrows
+DELETE FROM link_shares
+WHERE link_id = sqlc.arg('link_id')
+  AND user_id = sqlc.arg('user_id');
+
+-- name: GetLinkSharePermission :one
+-- GetLinkSharePermission returns what an unexpired share lets the user do.
+SELECT permission
+FROM link_shares
+WHERE link_id = sqlc.arg('link_id')
+  AND user_id = sqlc.arg('user_id')
+  AND (expires_at IS NULL OR expires_at > NOW());
+
+-- name: ListLinksSharedWithUser :many
+SELECT l.id,
+       l.url,
+       l.title,
+       u.email AS owner_email,
+       s.permission,
+       s.expires_at,
+       s.created_at
+FROM link_shares s
+JOIN links l ON l.id = s.link_id
+JOIN users u ON u.id = l.user_id
+WHERE s.user_id = sqlc.arg('user_id')
+  AND (s.expires_at IS NULL OR s.expires_at > NOW())
+ORDER BY s.created_at DESC, l.id;
@@ -4,13 +4,17 @@ INSERT INTO links (
     user_id,
     url,
     title,
-    favorite
+    favorite,
+    original_title,
+    preserve_title
 ) VALUES (
     sqlc.arg('id'),
     sqlc.arg('user_id'),
     sqlc.arg('url'),
     sqlc.narg('title'),
-    COALESCE(sqlc.narg('favorite'), FALSE)
+    COALESCE(sqlc.narg('favorite'), FALSE),
+    sqlc.narg('title'),
+    COALESCE(sqlc.narg('preserve_title'), FALSE)
 )
 RETURNING id, user_id, url, title, created_at, read_at, favorite;
 
@@ -34,11 +38,14 @@ SELECT l.id,
        l.created_at,
        l.read_at,
        l.favorite,
+       l.original_title,
+       l.preserve_title,
        COALESCE(a.title, '') AS archive_title,
        COALESCE(a.byline, '') AS archive_byline,
        COALESCE(a.lang, '') AS lang,
        COALESCE(a.word_count, 0) AS word_count,
        COALESCE(a.extracted_text, '') AS extracted_text,
+       COALESCE(a.paywalled, FALSE) AS paywalled,
        COALESCE(tag_data.tag_ids, '{}'::INTEGER[]) AS tag_ids,
        COALESCE(tag_data.tag_names, '{}'::TEXT[]) AS tag_names,
        COALESCE(highlight_data.highlights, '[]'::JSON)::text AS highlights
@@ -81,7 +88,32 @@ WHERE l.user_id = sqlc.arg('user_id')
     END
     OR l.url ILIKE '%' || sqlc.narg('query')::text || '%'
   )
-ORDER BY l.created_at DESC
+  AND (
+    sqlc.narg('claimed_by')::uuid IS NULL
+    OR EXISTS (
+        SELECT 1
+        FROM claims c
+        WHERE c.link_id = l.id
+          AND c.user_id = sqlc.narg('claimed_by')::uuid
+          AND (c.expires_at IS NULL OR c.expires_at > NOW())
+    )
+  )
+  AND (
+    sqlc.narg('has_highlights')::boolean IS NULL
+    OR EXISTS (
+        SELECT 1
+        FROM highlights h
+        WHERE h.link_id = l.id
+    ) = sqlc.narg('has_highlights')::boolean
+  )
+ORDER BY CASE
+        WHEN sqlc.arg('sort_relevance')::boolean
+             AND sqlc.arg('enable_full_text')::boolean
+             AND sqlc.narg('query')::text IS NOT NULL
+        THEN ts_rank_cd(l.search_tsv, plainto_tsquery('english', sqlc.narg('query')::text))
+             * (1 + 1 / (1 + EXTRACT(EPOCH FROM NOW() - l.created_at) / 2592000))
+    END DESC NULLS LAST,
+    l.created_at DESC
 LIMIT sqlc.arg('page_limit')::int OFFSET sqlc.arg('page_offset')::int;
 
 -- name: ListLinksWithTags :many
@@ -93,11 +125,14 @@ SELECT l.id,
        l.created_at,
        l.read_at,
        l.favorite,
+       l.original_title,
+       l.preserve_title,
        COALESCE(a.title, '') AS archive_title,
        COALESCE(a.byline, '') AS archive_byline,
        COALESCE(a.lang, '') AS lang,
        COALESCE(a.word_count, 0) AS word_count,
        COALESCE(a.extracted_text, '') AS extracted_text,
+       COALESCE(a.paywalled, FALSE) AS paywalled,
        COALESCE(tag_data.tag_ids, '{}'::INTEGER[]) AS tag_ids,
        COALESCE(tag_data.tag_names, '{}'::TEXT[]) AS tag_names,
        COALESCE(highlight_data.highlights, '[]'::JSON)::text AS highlights
@@ -152,7 +187,32 @@ WHERE l.user_id = sqlc.arg('user_id')
     filter_params.tag_ids IS NULL
     OR COALESCE(tag_matches.match_count, 0) = filter_params.tag_count
   )
-ORDER BY l.created_at DESC
+  AND (
+    sqlc.narg('claimed_by')::uuid IS NULL
+    OR EXISTS (
+        SELECT 1
+        FROM claims c
+        WHERE c.link_id = l.id
+          AND c.user_id = sqlc.narg('claimed_by')::uuid
+          AND (c.expires_at IS NULL OR c.expires_at > NOW())
+    )
+  )
+  AND (
+    sqlc.narg('has_highlights')::boolean IS NULL
+    OR EXISTS (
+        SELECT 1
+        FROM highlights h
+        WHERE h.link_id = l.id
+    ) = sqlc.narg('has_highlights')::boolean
+  )
+ORDER BY CASE
+        WHEN sqlc.arg('sort_relevance')::boolean
+             AND sqlc.arg('enable_full_text')::boolean
+             AND sqlc.narg('query')::text IS NOT NULL
+        THEN ts_rank_cd(l.search_tsv, plainto_tsquery('english', sqlc.narg('query')::text))
+             * (1 + 1 / (1 + EXTRACT(EPOCH FROM NOW() - l.created_at) / 2592000))
+    END DESC NULLS LAST,
+    l.created_at DESC
 LIMIT sqlc.arg('page_limit')::int OFFSET sqlc.arg('page_offset')::int;
 -- name: CountLinks :one
 SELECT COUNT(*)
@@ -171,6 +231,24 @@ WHERE l.user_id = sqlc.arg('user_id')
         ELSE FALSE
     END
     OR l.url ILIKE '%' || sqlc.narg('query')::text || '%'
+  )
+  AND (
+    sqlc.narg('claimed_by')::uuid IS NULL
+    OR EXISTS (
+        SELECT 1
+        FROM claims c
+        WHERE c.link_id = l.id
+          AND c.user_id = sqlc.narg('claimed_by')::uuid
+          AND (c.expires_at IS NULL OR c.expires_at > NOW())
+    )
+  )
+  AND (
+    sqlc.narg('has_highlights')::boolean IS NULL
+    OR EXISTS (
+        SELECT 1
+        FROM highlights h
+        WHERE h.link_id = l.id
+    ) = sqlc.narg('has_highlights')::boolean
   );
 
 -- name: CountLinksWithTags :one
@@ -202,8 +280,40 @@ WHERE l.user_id = sqlc.arg('user_id')
   AND (
     filter_params.tag_ids IS NULL
     OR COALESCE(tag_matches.match_count, 0) = filter_params.tag_count
+  )
+  AND (
+    sqlc.narg('claimed_by')::uuid IS NULL
+    OR EXISTS (
+        SELECT 1
+        FROM claims c
+        WHERE c.link_id = l.id
+          AND c.user_id = sqlc.narg('claimed_by')::uuid
+          AND (c.expires_at IS NULL OR c.expires_at > NOW())
+    )
+  )
+  AND (
+    sqlc.narg('has_highlights')::boolean IS NULL
+    OR EXISTS (
+        SELECT 1
+        FROM highlights h
+        WHERE h.link_id = l.id
+    ) = sqlc.narg('has_highlights')::boolean
   );
 
+-- name: ListSimilarLinks :many
+-- Candidates for the near-duplicate warning on create: the user's links on
+-- the same host (ignoring a leading www.), or whose title shares a word with
+-- title_words. The caller does the actual scoring.
+SELECT l.id, l.url, l.title, l.created_at
+FROM links l
+WHERE l.user_id = sqlc.arg('user_id')
+  AND (
+    lower(substring(l.url from '^[a-zA-Z][a-zA-Z0-9+.-]*://(?:www\.)?([^/:?#]+)')) = sqlc.arg('host')::text
+    OR regexp_split_to_array(lower(COALESCE(l.title, '')), '[^[:alnum:]]+') && sqlc.arg('title_words')::text[]
+  )
+ORDER BY l.created_at DESC
+LIMIT sqlc.arg('page_limit')::int;
+
 -- name: UpsertArchive :exec
 INSERT INTO archives (
     link_id,
@@ -212,7 +322,9 @@ INSERT INTO archives (
     word_count,
     lang,
     title,
-    byline
+    byline,
+    html_sha256,
+    text_sha256
 ) VALUES (
     sqlc.arg('link_id'),
     sqlc.narg('html'),
@@ -220,7 +332,9 @@ INSERT INTO archives (
     sqlc.narg('word_count'),
     sqlc.narg('lang'),
     sqlc.narg('title'),
-    sqlc.narg('byline')
+    sqlc.narg('byline'),
+    archive_content_sha256(sqlc.narg('html')),
+    archive_content_sha256(COALESCE(sqlc.narg('extracted_text'), ''))
 )
 ON CONFLICT (link_id) DO UPDATE
 SET html = EXCLUDED.html,
@@ -228,7 +342,14 @@ SET html = EXCLUDED.html,
     word_count = EXCLUDED.word_count,
     lang = EXCLUDED.lang,
     title = EXCLUDED.title,
-    byline = EXCLUDED.byline;
+    byline = EXCLUDED.byline,
+    html_sha256 = EXCLUDED.html_sha256,
+    text_sha256 = EXCLUDED.text_sha256;
+
+-- name: UpdateLinkSnooze :exec
+UPDATE links
+SET snoozed_until = sqlc.narg('snoozed_until')
+WHERE id = sqlc.arg('id');
 
 -- name: UpdateLinkSourceDomain :exec
 UPDATE links
@@ -238,7 +359,27 @@ WHERE id = sqlc.arg('id');
 -- name: UpdateLinkTitle :exec
 UPDATE links
 SET title = sqlc.narg('title')
-WHERE id = sqlc.arg('id');
+WHERE id = sqlc.arg('id')
+  AND NOT preserve_title;
+
+-- name: MarkLinksRead :many
+UPDATE links
+SET read_at = NOW()
+WHERE user_id = sqlc.arg('user_id')
+  AND id = ANY(sqlc.arg('ids')::uuid[])
+  AND read_at IS NULL
+RETURNING id;
+
+-- name: MarkLinksReadAt :many
+-- MarkLinksReadAt marks each link in ids read at the time at the same
+-- position in read_ats. Links already read keep their read time.
+UPDATE links AS l
+SET read_at = r.read_at
+FROM unnest(sqlc.arg('ids')::uuid[], sqlc.arg('read_ats')::timestamptz[]) AS r(id, read_at)
+WHERE l.id = r.id
+  AND l.user_id = sqlc.arg('user_id')
+  AND l.read_at IS NULL
+RETURNING l.id;
 
 -- name: UpdateLinkFavorite :one
 WITH updated AS (
@@ -252,7 +393,9 @@ WITH updated AS (
               l.source_domain,
               l.created_at,
               l.read_at,
-              l.favorite
+              l.favorite,
+              l.original_title,
+              l.preserve_title
 )
 SELECT u.id,
        u.user_id,
@@ -262,11 +405,14 @@ SELECT u.id,
        u.created_at,
        u.read_at,
        u.favorite,
+       u.original_title,
+       u.preserve_title,
        COALESCE(a.title, '') AS archive_title,
        COALESCE(a.byline, '') AS archive_byline,
        COALESCE(a.lang, '') AS lang,
        COALESCE(a.word_count, 0) AS word_count,
        COALESCE(a.extracted_text, '') AS extracted_text,
+       COALESCE(a.paywalled, FALSE) AS paywalled,
        COALESCE(tag_data.tag_ids, '{}'::INTEGER[]) AS tag_ids,
        COALESCE(tag_data.tag_names, '{}'::TEXT[]) AS tag_names,
        COALESCE(highlight_data.highlights, '[]'::JSON)::text AS highlights
@@ -296,17 +442,19 @@ LEFT JOIN LATERAL (
 ) AS highlight_data ON TRUE;
 
 -- name: CreateTag :one
-INSERT INTO tags (name)
-VALUES (sqlc.arg('name'))
-RETURNING id, name;
+INSERT INTO tags (user_id, name)
+VALUES (sqlc.arg('user_id'), sqlc.arg('name'))
+RETURNING id, name, user_id;
 
 -- name: DeleteTag :exec
 DELETE FROM tags
-WHERE id = sqlc.arg('id');
+WHERE id = sqlc.arg('id')
+  AND user_id = sqlc.arg('user_id');
 
 -- name: ListTags :many
-SELECT id, name
+SELECT id, name, user_id
 FROM tags
+WHERE user_id = sqlc.arg('user_id')
 ORDER BY name;
 
 -- name: ListTagLinkCounts :many
@@ -315,31 +463,60 @@ SELECT t.id,
        COUNT(lt.link_id)::INT AS link_count
 FROM tags t
 LEFT JOIN link_tags lt ON lt.tag_id = t.id
+WHERE t.user_id = sqlc.arg('user_id')
 GROUP BY t.id, t.name
 ORDER BY t.name;
 
 -- name: GetTag :one
-SELECT id, name
+SELECT id, name, user_id
 FROM tags
-WHERE id = sqlc.arg('id');
+WHERE id = sqlc.arg('id')
+  AND user_id = sqlc.arg('user_id');
 
 -- name: GetTagByName :one
-SELECT id, name
+SELECT id, name, user_id
 FROM tags
-WHERE name = sqlc.arg('name');
+WHERE user_id = sqlc.arg('user_id')
+  AND name = sqlc.arg('name');
 
 -- name: AddTagToLink :exec
 INSERT INTO link_tags (link_id, tag_id)
 VALUES (sqlc.arg('link_id'), sqlc.arg('tag_id'))
 ON CONFLICT DO NOTHING;
 
+-- name: ApplyTagToMatchingLinks :one
+-- ApplyTagToMatchingLinks tags every one of a user's links matching the
+-- filter in one statement. Any filter left null matches everything; domain
+-- covers its subdomains. tagged counts only links that lacked the tag.
+WITH matched AS (
+    SELECT l.id
+    FROM links l
+    WHERE l.user_id = sqlc.arg('user_id')
+      AND (
+        sqlc.narg('query')::text IS NULL
+        OR l.search_tsv @@ plainto_tsquery('english', sqlc.narg('query')::text)
+        OR l.url ILIKE '%' || sqlc.narg('query')::text || '%'
+      )
+      AND (sqlc.narg('domain')::text IS NULL OR domain_covers(sqlc.narg('domain')::text, url_host(l.url)))
+      AND (sqlc.narg('created_after')::timestamptz IS NULL OR l.created_at >= sqlc.narg('created_after')::timestamptz)
+      AND (sqlc.narg('created_before')::timestamptz IS NULL OR l.created_at < sqlc.narg('created_before')::timestamptz)
+), tagged AS (
+    INSERT INTO link_tags (link_id, tag_id)
+    SELECT matched.id, sqlc.arg('tag_id')
+    FROM matched
+    ON CONFLICT DO NOTHING
+    RETURNING link_id
+)
+SELECT (SELECT COUNT(*) FROM matched)::bigint AS matched,
+       (SELECT COUNT(*) FROM tagged)::bigint AS tagged;
+
 -- name: RemoveTagFromLink :exec
 DELETE FROM link_tags
 WHERE link_id = sqlc.arg('link_id')
   AND tag_id = sqlc.arg('tag_id');
 
 -- name: ListTagsForLink :many
-SELECT t.id, t.name
+SELECT t.id, t.name, t.user_id
 FROM tags t
 JOIN link_tags lt ON lt.tag_id = t.id
 WHERE lt.link_id = sqlc.arg('link_id')
@@ -0,0 +1,39 @@
+-- name: CreateNotification :one
+INSERT INTO notifications (user_id, kind, link_id, message)
+VALUES (sqlc.arg('user_id'), sqlc.arg('kind'), sqlc.narg('link_id'), sqlc.arg('message'))
+RETURNING id, user_id, kind, link_id, message, read_at, created_at;
+
+-- name: CreateAdminNotifications :execrows
+-- CreateAdminNotifications gives every admin the same notification.
+INSERT INTO notifications (user_id, kind, message)
+SELECT id, sqlc.arg('kind'), sqlc.arg('message')
+FROM users
+WHERE is_admin;
+
+-- name: ListNotifications :many
+SELECT id, user_id, kind, link_id, message, read_at, created_at
+FROM notifications
+WHERE user_id = sqlc.arg('user_id')
+  AND (NOT sqlc.arg('unread_only')::boolean OR read_at IS NULL)
+ORDER BY created_at DESC, id
+LIMIT sqlc.arg('page_limit')::int OFFSET sqlc.arg('page_offset')::int;
+
+-- name: CountUnreadNotifications :one
+SELECT COUNT(*)
+FROM notifications
+WHERE user_id = sqlc.arg('user_id')
+  AND read_at IS NULL;
+
+-- name: MarkNotificationRead :execrows
+-- MarkNotificationRead keeps the first read time when a notification is
+-- marked read again.
+UPDATE notifications
+SET read_at = COALESCE(read_at, NOW())
+WHERE id = sqlc.arg('id')
+  AND user_id = sqlc.arg('user_id');
+
+-- name: MarkAllNotificationsRead :execrows
+UPDATE notifications
+SET read_at = NOW()
+WHERE user_id = sqlc.arg('user_id')
+  AND read_at IS NULL;
@@ -0,0 +1,35 @@
+-- name: GetPublicProfile :one
+SELECT user_id, username, display_name, created_at
+FROM public_profiles
+WHERE user_id = sqlc.arg('user_id');
+
+-- name: GetPublicProfileByUsername :one
+SELECT user_id, username, display_name, created_at
+FROM public_profiles
+WHERE username = sqlc.arg('username');
+
+-- name: UpsertPublicProfile :one
+INSERT INTO public_profiles (user_id, username, display_name)
+VALUES (sqlc.arg('user_id'), sqlc.arg('username'), sqlc.narg('display_name'))
+ON CONFLICT (user_id) DO UPDATE
+SET username = EXCLUDED.username,
+    display_name = EXCLUDED.display_name
+RETURNING user_id, username, display_name, created_at;
+
+-- name: DeletePublicProfile :execrows
+DELETE FROM public_profiles
+WHERE user_id = sqlc.arg('user_id');
+
+-- name: ListPublicFavorites :many
+-- ListPublicFavorites lists a user's favorites, newest first, with only what
+-- the public favorites page shows.
+SELECT l.url,
+       COALESCE(l.title, a.title, l.url)::text AS title,
+       COALESCE(l.source_domain, url_host(l.url), '')::text AS domain,
+       l.created_at
+FROM links l
+LEFT JOIN archives a ON a.link_id = l.id
+WHERE l.user_id = sqlc.arg('user_id')
+  AND l.favorite
+ORDER BY l.created_at DESC, l.id DESC
+LIMIT sqlc.arg('page_limit');
@@ -0,0 +1,13 @@
+-- name: TakeDailyQuota :one
+-- Counts one use of kind for the user on day and returns the day's total.
+-- No row is returned once the total has reached daily_limit.
+INSERT INTO usage_quotas (user_id, kind, day, used)
+VALUES (sqlc.arg('user_id'), sqlc.arg('kind'), sqlc.arg('day'), 1)
+ON CONFLICT (user_id, kind, day) DO UPDATE
+SET used = usage_quotas.used + 1
+WHERE usage_quotas.used < sqlc.arg('daily_limit')::int
+RETURNING used;
+
+-- name: DeleteUsageQuotasBefore :execrows
+DELETE FROM usage_quotas
+WHERE day < sqlc.arg('day');
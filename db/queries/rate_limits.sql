@@ -0,0 +1,14 @@
+-- name: TakeRateLimitToken :one
+-- Refills the bucket for the time elapsed since it was last used and takes
+-- one token. No row is returned when the bucket is empty.
+INSERT INTO rate_limit_buckets (key, tokens, updated_at)
+VALUES (sqlc.arg('key'), sqlc.arg('burst')::double precision - 1, NOW())
+ON CONFLICT (key) DO UPDATE
+SET tokens = LEAST(sqlc.arg('burst')::double precision, rate_limit_buckets.tokens + EXTRACT(EPOCH FROM NOW() - rate_limit_buckets.updated_at) * sqlc.arg('per_second')::double precision) - 1,
+    updated_at = NOW()
+WHERE LEAST(sqlc.arg('burst')::double precision, rate_limit_buckets.tokens + EXTRACT(EPOCH FROM NOW() - rate_limit_buckets.updated_at) * sqlc.arg('per_second')::double precision) >= 1
+RETURNING tokens;
+
+-- name: DeleteIdleRateLimitBuckets :execrows
+DELETE FROM rate_limit_buckets
+WHERE updated_at < sqlc.arg('idle_before');
@@ -3,23 +3,36 @@ SELECT DISTINCT user_id
 FROM links
 WHERE read_at IS NULL;
 
--- name: ListUnreadLinksForUser :many
+-- name: ListUnreadLinkBatchForUser :many
 SELECT
     l.id,
-    l.user_id,
-    l.url,
-    l.title,
     l.created_at,
     l.favorite,
-    a.title AS archive_title,
-    a.byline,
-    a.lang,
-    COALESCE(a.word_count, 0) AS word_count,
-    COALESCE(a.extracted_text, '') AS extracted_text
+    COALESCE(a.word_count, 0) AS word_count
 FROM links l
 LEFT JOIN archives a ON a.link_id = l.id
-WHERE l.user_id = $1
-  AND l.read_at IS NULL;
+WHERE l.user_id = sqlc.arg('user_id')
+  AND l.read_at IS NULL
+  AND (l.snoozed_until IS NULL OR l.snoozed_until <= sqlc.arg('now')::timestamptz)
+  AND (
+    sqlc.narg('surfaced_before')::timestamptz IS NULL
+    OR l.last_surfaced_at IS NULL
+    OR l.last_surfaced_at < sqlc.narg('surfaced_before')::timestamptz
+  )
+  AND NOT COALESCE((
+    SELECT dp.never_resurface
+    FROM domain_preferences dp
+    WHERE dp.user_id = l.user_id
+      AND domain_covers(dp.domain, url_host(l.url))
+    ORDER BY length(dp.domain) DESC
+    LIMIT 1
+  ), FALSE)
+  AND (
+    sqlc.narg('after_created_at')::timestamptz IS NULL
+    OR (l.created_at, l.id) > (sqlc.narg('after_created_at')::timestamptz, sqlc.narg('after_id')::uuid)
+  )
+ORDER BY l.created_at, l.id
+LIMIT sqlc.arg('batch_size')::int;
 
 -- name: ClearRecommendationsForUser :exec
 DELETE FROM recommendations
@@ -28,12 +41,18 @@ WHERE link_id IN (
 );
 
 -- name: UpsertRecommendation :exec
-INSERT INTO recommendations (link_id, score, updated_at)
-VALUES ($1, $2, $3)
+INSERT INTO recommendations (link_id, score, strategy, updated_at)
+VALUES ($1, $2, $3, $4)
 ON CONFLICT (link_id) DO UPDATE
 SET score = EXCLUDED.score,
+    strategy = EXCLUDED.strategy,
     updated_at = EXCLUDED.updated_at;
 
+-- name: MarkLinksSurfaced :exec
+UPDATE links
+SET last_surfaced_at = sqlc.arg('surfaced_at')::timestamptz
+WHERE id = ANY(sqlc.arg('ids')::uuid[]);
+
 -- name: ListRecommendationsForUser :many
 SELECT
     l.id,
@@ -48,11 +67,132 @@ SELECT
     a.lang,
     COALESCE(a.word_count, 0) AS word_count,
     COALESCE(a.extracted_text, '') AS extracted_text,
+    COALESCE(a.paywalled, FALSE) AS paywalled,
     r.score,
-    r.updated_at
+    r.updated_at,
+    r.strategy
 FROM recommendations r
 JOIN links l ON l.id = r.link_id
 LEFT JOIN archives a ON a.link_id = l.id
-WHERE l.user_id = $1
-ORDER BY r.score DESC, r.updated_at DESC
-LIMIT $2;
+WHERE l.user_id = sqlc.arg('user_id')
+  AND (sqlc.narg('domain')::text IS NULL OR l.source_domain = sqlc.narg('domain')::text)
+  AND (
+    sqlc.narg('tag_ids')::int4[] IS NULL
+    OR (
+        SELECT COUNT(DISTINCT lt.tag_id)
+        FROM link_tags lt
+        WHERE lt.link_id = l.id
+          AND lt.tag_id = ANY(sqlc.narg('tag_ids')::int4[])
+    ) = cardinality(sqlc.narg('tag_ids')::int4[])
+  )
+  AND (
+    sqlc.narg('cursor_score')::int IS NULL
+    OR (r.score, r.updated_at, r.link_id) < (
+        sqlc.narg('cursor_score')::int,
+        sqlc.narg('cursor_updated_at')::timestamptz,
+        sqlc.narg('cursor_link_id')::uuid
+    )
+  )
+ORDER BY r.score DESC, r.updated_at DESC, r.link_id DESC
+LIMIT sqlc.arg('page_limit')::int OFFSET sqlc.arg('page_offset')::int;
+
+-- name: GetResurfacerWeightsForUser :one
+SELECT user_id, favorite_bonus, age_cap_days, word_count_tiers, updated_at
+FROM resurfacer_weights
+WHERE user_id = $1;
+
+-- name: ListOnThisDayLinksForUser :many
+SELECT
+    l.id,
+    l.url,
+    l.title,
+    l.source_domain,
+    l.favorite,
+    l.created_at,
+    l.read_at,
+    a.title AS archive_title,
+    a.byline,
+    a.lang,
+    COALESCE(a.word_count, 0) AS word_count,
+    COALESCE(a.extracted_text, '') AS extracted_text,
+    COALESCE(a.paywalled, FALSE) AS paywalled,
+    y.years_ago::int AS years_ago
+FROM unnest(sqlc.arg('years')::int[]) AS y(years_ago)
+JOIN links l
+  ON (l.created_at AT TIME ZONE 'UTC')::date = (sqlc.arg('on_date')::date - make_interval(years => y.years_ago))::date
+LEFT JOIN archives a ON a.link_id = l.id
+WHERE l.user_id = sqlc.arg('user_id')
+ORDER BY y.years_ago, l.created_at
+LIMIT sqlc.arg('row_limit')::int;
+
+-- name: ListColdStartLinksForUser :many
+WITH recent AS (
+    SELECT id
+    FROM links
+    WHERE user_id = sqlc.arg('user_id')
+    ORDER BY created_at DESC
+    LIMIT sqlc.arg('recent_window')::int
+)
+SELECT
+    l.id,
+    l.url,
+    l.title,
+    l.source_domain,
+    l.favorite,
+    l.created_at,
+    l.read_at,
+    a.title AS archive_title,
+    a.byline,
+    a.lang,
+    COALESCE(a.word_count, 0) AS word_count,
+    COALESCE(a.extracted_text, '') AS extracted_text,
+    COALESCE(a.paywalled, FALSE) AS paywalled
+FROM recent
+JOIN links l ON l.id = recent.id
+LEFT JOIN archives a ON a.link_id = l.id
+ORDER BY
+    l.read_at IS NOT NULL,
+    COALESCE(a.word_count, 0) = 0,
+    COALESCE(a.word_count, 0),
+    l.created_at DESC
+LIMIT sqlc.arg('row_limit')::int;
+
+-- name: RecordRecommendationImpressions :execrows
+INSERT INTO recommendation_impressions (user_id, link_id, strategy, surface, position, shown_at)
+SELECT sqlc.arg('user_id'), shown.link_id, sqlc.arg('strategy'), sqlc.arg('surface'), shown.position, sqlc.arg('shown_at')::timestamptz
+FROM unnest(sqlc.arg('link_ids')::uuid[], sqlc.arg('positions')::int[]) AS shown(link_id, position)
+WHERE NOT EXISTS (
+    SELECT 1
+    FROM recommendation_impressions ri
+    WHERE ri.user_id = sqlc.arg('user_id')
+      AND ri.link_id = shown.link_id
+      AND ri.strategy = sqlc.arg('strategy')
+      AND ri.surface = sqlc.arg('surface')
+      AND ri.acted_at IS NULL
+      AND ri.shown_at > sqlc.arg('repeat_after')::timestamptz
+);
+
+-- name: MarkRecommendationImpressionsActed :many
+UPDATE recommendation_impressions
+SET action = sqlc.arg('action'),
+    acted_at = sqlc.arg('acted_at')::timestamptz
+WHERE user_id = sqlc.arg('user_id')
+  AND link_id = ANY(sqlc.arg('link_ids')::uuid[])
+  AND acted_at IS NULL
+  AND shown_at > sqlc.arg('shown_after')::timestamptz
+RETURNING strategy;
+
+-- name: ListRecommendationImpressionStats :many
+SELECT
+    strategy,
+    surface,
+    COUNT(*)::int AS impressions,
+    COUNT(acted_at)::int AS acted,
+    COUNT(*) FILTER (WHERE action = 'open')::int AS opened,
+    COUNT(*) FILTER (WHERE action = 'read')::int AS read,
+    COUNT(*) FILTER (WHERE action = 'favorite')::int AS favorited
+FROM recommendation_impressions
+WHERE user_id = sqlc.arg('user_id')
+  AND shown_at >= sqlc.arg('since')::timestamptz
+GROUP BY strategy, surface
+ORDER BY strategy, surface;
@@ -0,0 +1,66 @@
+-- name: CountLinksForSearchIndex :one
+SELECT COUNT(*)::bigint FROM links;
+
+-- name: CheckSearchVectorBatch :one
+-- CheckSearchVectorBatch compares the search vectors of the next batch of
+-- links, by id after after_id, with what links_search_tsv_update would
+-- store, without changing them.
+WITH batch AS (
+    SELECT l.id,
+           l.search_tsv,
+           links_search_document(l.title, a.title, a.byline, a.extracted_text) AS expected
+    FROM links l
+    LEFT JOIN archives a ON a.link_id = l.id
+    WHERE sqlc.narg('after_id')::uuid IS NULL OR l.id > sqlc.narg('after_id')::uuid
+    ORDER BY l.id
+    LIMIT sqlc.arg('batch_size')::int
+)
+SELECT (SELECT COUNT(*) FROM batch)::bigint AS checked,
+       (SELECT COUNT(*) FROM batch WHERE batch.search_tsv IS DISTINCT FROM batch.expected)::bigint AS stale,
+       (SELECT batch.id FROM batch ORDER BY batch.id DESC LIMIT 1)::uuid AS last_id;
+
+-- name: RebuildSearchVectorBatch :one
+-- RebuildSearchVectorBatch rewrites the stale search vectors in the next
+-- batch of links, by id after after_id, the way CheckSearchVectorBatch finds
+-- them.
+WITH batch AS (
+    SELECT l.id,
+           l.search_tsv,
+           links_search_document(l.title, a.title, a.byline, a.extracted_text) AS expected
+    FROM links l
+    LEFT JOIN archives a ON a.link_id = l.id
+    WHERE sqlc.narg('after_id')::uuid IS NULL OR l.id > sqlc.narg('after_id')::uuid
+    ORDER BY l.id
+    LIMIT sqlc.arg('batch_size')::int
+), rebuilt AS (
+    UPDATE links l
+    SET search_tsv = batch.expected
+    FROM batch
+    WHERE l.id = batch.id
+      AND batch.search_tsv IS DISTINCT FROM batch.expected
+    RETURNING l.id
+)
+SELECT (SELECT COUNT(*) FROM batch)::bigint AS checked,
+       (SELECT COUNT(*) FROM rebuilt)::bigint AS stale,
+       (SELECT batch.id FROM batch ORDER BY batch.id DESC LIMIT 1)::uuid AS last_id;
+
+-- name: ListTriggerStates :many
+-- ListTriggerStates reports whether each named trigger exists and fires;
+-- ALTER TABLE ... DISABLE TRIGGER leaves a trigger in place but off.
+SELECT c.relname::text AS table_name,
+       t.tgname::text AS trigger_name,
+       (t.tgenabled <> 'D')::boolean AS enabled
+FROM pg_trigger t
+JOIN pg_class c ON c.oid = t.tgrelid
+JOIN pg_namespace n ON n.oid = c.relnamespace
+WHERE NOT t.tgisinternal
+  AND n.nspname = 'public'
+  AND t.tgname = ANY(sqlc.arg('names')::text[])
+ORDER BY c.relname, t.tgname;
+
+-- name: ListIndexesByName :many
+SELECT indexname::text
+FROM pg_indexes
+WHERE schemaname = 'public'
+  AND indexname = ANY(sqlc.arg('names')::text[])
+ORDER BY indexname;
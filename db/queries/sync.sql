@@ -0,0 +1,69 @@
+-- name: ListSyncChanges :many
+-- Only changes from transactions below the snapshot xmin are returned, so a
+-- slower transaction can never commit a change behind a cursor already handed
+-- out.
+SELECT seq,
+       tx_id::text::bigint AS tx_id,
+       entity,
+       entity_id,
+       deleted,
+       changed_at
+FROM sync_changes
+WHERE user_id = sqlc.arg('user_id')
+  AND (tx_id, seq) > (sqlc.arg('cursor_tx')::bigint::text::xid8, sqlc.arg('cursor_seq')::bigint)
+  AND tx_id < pg_snapshot_xmin(pg_current_snapshot())
+ORDER BY tx_id, seq
+LIMIT sqlc.arg('page_limit');
+
+-- name: GetSyncChangeSince :one
+-- Reports whether an entity changed after the cursor, and if so whether its
+-- latest change deleted it.
+SELECT deleted
+FROM sync_changes
+WHERE user_id = sqlc.arg('user_id')
+  AND entity = sqlc.arg('entity')
+  AND entity_id = sqlc.arg('entity_id')
+  AND (tx_id, seq) > (sqlc.arg('cursor_tx')::bigint::text::xid8, sqlc.arg('cursor_seq')::bigint)
+ORDER BY tx_id DESC, seq DESC
+LIMIT 1;
+
+-- name: ListSyncLinks :many
+SELECT l.id,
+       l.url,
+       l.title,
+       l.favorite,
+       l.read_at,
+       l.created_at,
+       l.updated_at,
+       COALESCE(tag_data.tag_names, '{}'::TEXT[]) AS tag_names
+FROM links l
+LEFT JOIN LATERAL (
+    SELECT ARRAY_AGG(t.name ORDER BY t.name) AS tag_names
+    FROM link_tags lt
+    JOIN tags t ON t.id = lt.tag_id
+    WHERE lt.link_id = l.id
+) AS tag_data ON TRUE
+WHERE l.user_id = sqlc.arg('user_id')
+  AND l.id = ANY(sqlc.arg('ids')::uuid[]);
+
+-- name: ListSyncHighlights :many
+SELECT h.id, h.link_id, h.quote, h.annotation, h.created_at, h.updated_at
+FROM highlights h
+JOIN links l ON l.id = h.link_id
+WHERE l.user_id = sqlc.arg('user_id')
+  AND h.id = ANY(sqlc.arg('ids')::uuid[]);
+
+-- name: UpdateSyncLink :execrows
+UPDATE links
+SET title = sqlc.narg('title'),
+    favorite = sqlc.arg('favorite'),
+    read_at = sqlc.narg('read_at')
+WHERE id = sqlc.arg('id')
+  AND user_id = sqlc.arg('user_id');
+
+-- name: DeleteLink :execrows
+-- Foreign keys cascade, so the link's archive, highlights, tags, claims, and
+-- recommendations go in the same statement.
+DELETE FROM links
+WHERE id = sqlc.arg('id')
+  AND user_id = sqlc.arg('user_id');
@@ -0,0 +1,9 @@
+-- name: CreateUser :one
+INSERT INTO users (email, password_hash)
+VALUES (sqlc.arg('email'), sqlc.arg('password_hash'))
+RETURNING id, email, password_hash, created_at;
+
+-- name: GetUserByEmail :one
+SELECT id, email, password_hash, created_at
+FROM users
+WHERE lower(email) = lower(sqlc.arg('email'));
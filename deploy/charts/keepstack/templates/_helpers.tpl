@@ -67,3 +67,100 @@ app.kubernetes.io/managed-by: {{ .Release.Service }}
 {{ printf "%s-%s" (include "keepstack.fullname" .) .Values.tls.issuer }}
 {{- end -}}
 {{- end -}}
+
+{{/*
+Connection pool settings for a component; pass its dbPool values. Unset keys
+keep the pgxpool defaults.
+*/}}
+{{- define "keepstack.dbPoolEnv" -}}
+{{- with .maxConns }}
+- name: DB_MAX_CONNS
+  value: {{ . | quote }}
+{{- end }}
+{{- with .minConns }}
+- name: DB_MIN_CONNS
+  value: {{ . | quote }}
+{{- end }}
+{{- with .maxConnLifetime }}
+- name: DB_MAX_CONN_LIFETIME
+  value: {{ . | quote }}
+{{- end }}
+{{- with .maxConnIdleTime }}
+- name: DB_MAX_CONN_IDLE_TIME
+  value: {{ . | quote }}
+{{- end }}
+{{- with .healthCheckPeriod }}
+- name: DB_HEALTH_CHECK_PERIOD
+  value: {{ . | quote }}
+{{- end }}
+{{- end -}}
+
+{{- define "keepstack.errorReportingEnv" -}}
+- name: SENTRY_DSN
+  valueFrom:
+    secretKeyRef:
+      name: {{ .Values.secrets.name }}
+      key: SENTRY_DSN
+      optional: true
+{{- with .Values.observability.errorReporting.environment }}
+- name: SENTRY_ENVIRONMENT
+  value: {{ . | quote }}
+{{- end }}
+{{- end -}}
+
+{{- define "keepstack.sanitizeEnv" -}}
+{{- with .Values.sanitize }}
+- name: SANITIZE_FEATURES
+  value: {{ join "," (.features | default list) | quote }}
+- name: SANITIZE_IMAGE_PROXY
+  value: {{ .imageProxy | default "" | quote }}
+- name: SANITIZE_EMBED_HOSTS
+  value: {{ join "," (.embedHosts | default list) | quote }}
+{{- end }}
+{{- end -}}
+
+{{- define "keepstack.mediaEnv" -}}
+{{- with .Values.media }}
+{{- if .storage }}
+- name: MEDIA_STORAGE
+  value: {{ .storage | quote }}
+- name: MEDIA_S3_BUCKET
+  value: {{ .s3.bucket | quote }}
+- name: MEDIA_S3_REGION
+  value: {{ .s3.region | default "us-east-1" | quote }}
+- name: MEDIA_S3_ENDPOINT
+  value: {{ .s3.endpoint | default "" | quote }}
+- name: MEDIA_S3_PREFIX
+  value: {{ .s3.prefix | default "" | quote }}
+- name: MEDIA_S3_ACCESS_KEY
+  valueFrom:
+    secretKeyRef:
+      name: {{ $.Values.secrets.name }}
+      key: MEDIA_S3_ACCESS_KEY
+      optional: true
+- name: MEDIA_S3_SECRET_KEY
+  valueFrom:
+    secretKeyRef:
+      name: {{ $.Values.secrets.name }}
+      key: MEDIA_S3_SECRET_KEY
+      optional: true
+- name: MEDIA_MAX_BYTES
+  value: {{ .maxBytes | default 5242880 | int64 | quote }}
+- name: MEDIA_MAX_IMAGES
+  value: {{ .maxImages | default 50 | int | quote }}
+{{- end }}
+{{- end }}
+{{- end -}}
+
+{{- define "keepstack.tracingEnv" -}}
+{{- with .Values.observability.tracing }}
+{{- if .endpoint }}
+- name: OTEL_EXPORTER_OTLP_ENDPOINT
+  value: {{ .endpoint | quote }}
+- name: OTEL_TRACES_SAMPLER
+  value: parentbased_traceidratio
+- name: OTEL_TRACES_SAMPLER_ARG
+  value: {{ .sampleRatio | default 1 | quote }}
+{{- end }}
+{{- end }}
+{{- end -}}
@@ -0,0 +1,121 @@
+// Package health holds the dependency checks behind the readiness probes of
+// the API, worker, and cron binaries. Postgres, Schema, SchemaVersion, NATS,
+// and S3 build individual checks; a Checker re-runs a set of them on an
+// interval so a probe reflects current state rather than startup state.
+package health
+
+import (
+	"context"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Checker runs a set of checks on an interval and remembers the latest
+// result of each. It reports not ready until every check has passed once.
+type Checker struct {
+	checks  []Check
+	timeout time.Duration
+	up      *prometheus.GaugeVec
+	logger  *log.Logger
+
+	mu       sync.RWMutex
+	failures map[string]string
+	checked  bool
+}
+
+// NewChecker builds a Checker. Each check gets timeout to finish; up, when
+// set, receives 1 or 0 per check name.
+func NewChecker(checks []Check, timeout time.Duration, up *prometheus.GaugeVec, logger *log.Logger) *Checker {
+	return &Checker{
+		checks:   checks,
+		timeout:  timeout,
+		up:       up,
+		logger:   logger,
+		failures: make(map[string]string),
+	}
+}
+
+// Run checks immediately and then every interval until ctx is cancelled.
+func (c *Checker) Run(ctx context.Context, interval time.Duration) {
+	c.CheckNow(ctx)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			c.CheckNow(ctx)
+		}
+	}
+}
+
+// CheckNow runs every check once and records the results.
+func (c *Checker) CheckNow(ctx context.Context) {
+	failures := make(map[string]string, len(c.checks))
+	for _, check := range c.checks {
+		checkCtx, cancel := context.WithTimeout(ctx, c.timeout)
+		err := check.Run(checkCtx)
+		cancel()
+
+		if err != nil {
+			failures[check.Name] = err.Error()
+		}
+		if c.up != nil {
+			value := 1.0
+			if err != nil {
+				value = 0
+			}
+			c.up.WithLabelValues(check.Name).Set(value)
+		}
+	}
+
+	c.mu.Lock()
+	previous := c.failures
+	c.failures = failures
+	c.checked = true
+	c.mu.Unlock()
+
+	c.logTransitions(previous, failures)
+}
+
+// Ready reports whether the last run passed. When it did not, the returned
+// slice describes each failing check as "name: error", sorted by name.
+func (c *Checker) Ready() (bool, []string) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if !c.checked {
+		return false, []string{"dependencies not checked yet"}
+	}
+	if len(c.failures) == 0 {
+		return true, nil
+	}
+	problems := make([]string, 0, len(c.failures))
+	for name, msg := range c.failures {
+		problems = append(problems, name+": "+msg)
+	}
+	sort.Strings(problems)
+	return false, problems
+}
+
+func (c *Checker) logTransitions(previous, current map[string]string) {
+	if c.logger == nil {
+		return
+	}
+	for _, check := range c.checks {
+		_, wasFailing := previous[check.Name]
+		msg, failing := current[check.Name]
+		switch {
+		case failing && !wasFailing:
+			c.logger.Printf("readiness: %s check failing: %s", check.Name, msg)
+		case !failing && wasFailing:
+			c.logger.Printf("readiness: %s check recovered", check.Name)
+		}
+	}
+}
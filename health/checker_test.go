@@ -0,0 +1,69 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestCheckerFlipsReadiness(t *testing.T) {
+	t.Parallel()
+
+	var dbErr error
+	up := prometheus.NewGaugeVec(prometheus.GaugeOpts{Name: "test_dependency_up"}, []string{"dependency"})
+	checker := NewChecker([]Check{
+		{Name: "database", Run: func(context.Context) error { return dbErr }},
+		{Name: "nats", Run: func(context.Context) error { return nil }},
+	}, time.Second, up, nil)
+
+	if ready, _ := checker.Ready(); ready {
+		t.Fatalf("expected not ready before the first check")
+	}
+
+	checker.CheckNow(context.Background())
+	if ready, problems := checker.Ready(); !ready {
+		t.Fatalf("expected ready, got %v", problems)
+	}
+
+	dbErr = errors.New("connection refused")
+	checker.CheckNow(context.Background())
+	ready, problems := checker.Ready()
+	if ready {
+		t.Fatalf("expected not ready after the database check failed")
+	}
+	if len(problems) != 1 || problems[0] != "database: connection refused" {
+		t.Fatalf("unexpected problems: %v", problems)
+	}
+	if got := testutil.ToFloat64(up.WithLabelValues("database")); got != 0 {
+		t.Fatalf("expected database gauge 0, got %v", got)
+	}
+	if got := testutil.ToFloat64(up.WithLabelValues("nats")); got != 1 {
+		t.Fatalf("expected nats gauge 1, got %v", got)
+	}
+
+	dbErr = nil
+	checker.CheckNow(context.Background())
+	if ready, problems := checker.Ready(); !ready {
+		t.Fatalf("expected recovery, got %v", problems)
+	}
+}
+
+func TestCheckerAppliesTimeout(t *testing.T) {
+	t.Parallel()
+
+	checker := NewChecker([]Check{
+		{Name: "slow", Run: func(ctx context.Context) error {
+			<-ctx.Done()
+			return ctx.Err()
+		}},
+	}, 10*time.Millisecond, nil, nil)
+
+	checker.CheckNow(context.Background())
+	if ready, _ := checker.Ready(); ready {
+		t.Fatalf("expected a timed out check to fail readiness")
+	}
+}
@@ -0,0 +1,178 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/nats-io/nats.go"
+)
+
+// Postgres error codes that mean a migration has not been applied.
+const (
+	undefinedTable  = "42P01"
+	undefinedColumn = "42703"
+)
+
+// Check probes one dependency. Run returns nil while it is usable.
+type Check struct {
+	Name string
+	Run  func(context.Context) error
+}
+
+// Pinger is the part of pgxpool.Pool the Postgres check uses.
+type Pinger interface {
+	Ping(context.Context) error
+}
+
+// Querier is the part of pgxpool.Pool the Schema check uses.
+type Querier interface {
+	Query(context.Context, string, ...any) (pgx.Rows, error)
+}
+
+// RowQuerier is the part of pgxpool.Pool the SchemaVersion check uses.
+type RowQuerier interface {
+	QueryRow(context.Context, string, ...any) pgx.Row
+}
+
+// NATSConn is the part of *nats.Conn the NATS check uses.
+type NATSConn interface {
+	Status() nats.Status
+}
+
+// BucketHeader makes an S3 HeadBucket call. Binaries that back up to S3
+// wrap their client in one, which keeps the AWS SDK out of the others.
+type BucketHeader interface {
+	HeadBucket(ctx context.Context, bucket string) error
+}
+
+// SchemaError reports that the database lacks a table, column, or migration
+// a binary needs. Applying the outstanding migrations fixes it, so callers
+// count it separately and hint as much.
+type SchemaError struct {
+	Message string
+}
+
+func (e *SchemaError) Error() string {
+	return e.Message
+}
+
+// IsSchemaError reports whether err, or any error it wraps, is a
+// SchemaError.
+func IsSchemaError(err error) bool {
+	var schemaErr *SchemaError
+	return errors.As(err, &schemaErr)
+}
+
+// Postgres checks that db answers a ping.
+func Postgres(db Pinger) Check {
+	return Check{Name: "database", Run: db.Ping}
+}
+
+// Schema checks that each table in required exists with at least the
+// listed columns. Every table is probed, so one failure lists every table
+// that needs a migration.
+func Schema(db Querier, required map[string][]string) Check {
+	tables := make([]string, 0, len(required))
+	for table := range required {
+		tables = append(tables, table)
+	}
+	sort.Strings(tables)
+
+	return Check{Name: "schema", Run: func(ctx context.Context) error {
+		var errs []error
+		for _, table := range tables {
+			columns := "1"
+			if len(required[table]) > 0 {
+				columns = strings.Join(required[table], ", ")
+			}
+			rows, err := db.Query(ctx, fmt.Sprintf("SELECT %s FROM %s LIMIT 0", columns, table))
+			if err == nil {
+				rows.Close()
+				err = rows.Err()
+			}
+			if err != nil {
+				errs = append(errs, Classify(err))
+			}
+		}
+		return errors.Join(errs...)
+	}}
+}
+
+// SchemaVersion checks that goose has applied at least migration expected.
+// A newer schema is fine: that is the normal state mid-rollout, once
+// migrations have run but older pods are still serving.
+func SchemaVersion(db RowQuerier, expected int64) Check {
+	return Check{Name: "schema version", Run: func(ctx context.Context) error {
+		var applied int64
+		err := db.QueryRow(ctx, "SELECT COALESCE(MAX(version_id), 0) FROM goose_db_version WHERE is_applied").Scan(&applied)
+		if err != nil {
+			return fmt.Errorf("database schema version unknown: %w", err)
+		}
+		if applied < expected {
+			return &SchemaError{Message: fmt.Sprintf("database schema at version %d, expected %d", applied, expected)}
+		}
+		return nil
+	}}
+}
+
+// NATS checks that conn is connected.
+func NATS(conn NATSConn) Check {
+	return Check{Name: "nats", Run: func(context.Context) error {
+		if status := conn.Status(); status != nats.CONNECTED {
+			return fmt.Errorf("nats connection %s", status)
+		}
+		return nil
+	}}
+}
+
+// S3 checks that client can reach bucket.
+func S3(client BucketHeader, bucket string) Check {
+	return Check{Name: "s3", Run: func(ctx context.Context) error {
+		if err := client.HeadBucket(ctx, bucket); err != nil {
+			return fmt.Errorf("head bucket %s: %w", bucket, err)
+		}
+		return nil
+	}}
+}
+
+// First runs checks in order and returns the first to fail with its error.
+func First(ctx context.Context, checks []Check) (Check, error) {
+	for _, check := range checks {
+		if err := check.Run(ctx); err != nil {
+			return check, err
+		}
+	}
+	return Check{}, nil
+}
+
+// Classify turns the Postgres errors for a missing table or column into a
+// SchemaError naming it. Other errors are returned unchanged.
+func Classify(err error) error {
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) {
+		return err
+	}
+	switch pgErr.Code {
+	case undefinedTable:
+		table := pgErr.TableName
+		if table == "" {
+			table = "required"
+		}
+		return &SchemaError{Message: fmt.Sprintf("database schema missing table %q", table)}
+	case undefinedColumn:
+		column := pgErr.ColumnName
+		if column == "" {
+			column = "required"
+		}
+		if pgErr.TableName != "" {
+			return &SchemaError{Message: fmt.Sprintf("database schema missing column %q on table %q", column, pgErr.TableName)}
+		}
+		return &SchemaError{Message: fmt.Sprintf("database schema missing column %q", column)}
+	}
+	return err
+}
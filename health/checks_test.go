@@ -0,0 +1,188 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/nats-io/nats.go"
+)
+
+func TestClassify(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		err              error
+		wantMessage      string
+		wantMigrationGap bool
+	}{
+		"undefined table": {
+			err: &pgconn.PgError{
+				Code:      undefinedTable,
+				TableName: "highlights",
+			},
+			wantMessage:      "database schema missing table \"highlights\"",
+			wantMigrationGap: true,
+		},
+		"undefined column": {
+			err: &pgconn.PgError{
+				Code:       undefinedColumn,
+				TableName:  "archives",
+				ColumnName: "title",
+			},
+			wantMessage:      "database schema missing column \"title\" on table \"archives\"",
+			wantMigrationGap: true,
+		},
+		"generic error": {
+			err:              errors.New("boom"),
+			wantMessage:      "boom",
+			wantMigrationGap: false,
+		},
+	}
+
+	for name, tc := range tests {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			err := Classify(tc.err)
+			if err.Error() != tc.wantMessage {
+				t.Fatalf("unexpected message: got %q want %q", err.Error(), tc.wantMessage)
+			}
+			if IsSchemaError(err) != tc.wantMigrationGap {
+				t.Fatalf("unexpected migration gap flag: got %t want %t", IsSchemaError(err), tc.wantMigrationGap)
+			}
+		})
+	}
+}
+
+func TestSchemaProbesEveryTable(t *testing.T) {
+	t.Parallel()
+
+	db := &probeQuerier{errs: map[string]error{
+		"archives":      &pgconn.PgError{Code: undefinedColumn, TableName: "archives", ColumnName: "lang"},
+		"link_captures": &pgconn.PgError{Code: undefinedTable, TableName: "link_captures"},
+	}}
+	check := Schema(db, map[string][]string{
+		"links":         {"id", "url"},
+		"archives":      {"link_id", "lang"},
+		"link_captures": nil,
+	})
+
+	err := check.Run(context.Background())
+	if !IsSchemaError(err) {
+		t.Fatalf("expected a schema error, got %v", err)
+	}
+	for _, want := range []string{`missing column "lang" on table "archives"`, `missing table "link_captures"`} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error %q does not mention %s", err, want)
+		}
+	}
+	wantQueries := []string{
+		"SELECT link_id, lang FROM archives LIMIT 0",
+		"SELECT 1 FROM link_captures LIMIT 0",
+		"SELECT id, url FROM links LIMIT 0",
+	}
+	if strings.Join(db.queries, "\n") != strings.Join(wantQueries, "\n") {
+		t.Fatalf("queries = %q, want %q", db.queries, wantQueries)
+	}
+}
+
+func TestSchemaVersion(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		applied int64
+		wantErr bool
+	}{
+		{name: "matches", applied: 18},
+		{name: "ahead", applied: 19},
+		{name: "behind", applied: 17, wantErr: true},
+	}
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			err := SchemaVersion(versionQuerier(tc.applied), 18).Run(context.Background())
+			if tc.wantErr != (err != nil) {
+				t.Fatalf("err = %v, want error %t", err, tc.wantErr)
+			}
+			if tc.wantErr && !IsSchemaError(err) {
+				t.Fatalf("expected a schema error, got %v", err)
+			}
+		})
+	}
+}
+
+func TestNATS(t *testing.T) {
+	t.Parallel()
+
+	if err := NATS(natsStatus(nats.CONNECTED)).Run(context.Background()); err != nil {
+		t.Fatalf("connected: %v", err)
+	}
+	if err := NATS(natsStatus(nats.RECONNECTING)).Run(context.Background()); err == nil {
+		t.Fatal("expected an error while reconnecting")
+	}
+}
+
+func TestFirstStopsAtFailure(t *testing.T) {
+	t.Parallel()
+
+	ran := 0
+	count := func(err error) func(context.Context) error {
+		return func(context.Context) error {
+			ran++
+			return err
+		}
+	}
+	failed, err := First(context.Background(), []Check{
+		{Name: "a", Run: count(nil)},
+		{Name: "b", Run: count(errors.New("down"))},
+		{Name: "c", Run: count(nil)},
+	})
+	if failed.Name != "b" || err == nil || ran != 2 {
+		t.Fatalf("First = %q, %v after %d checks", failed.Name, err, ran)
+	}
+}
+
+type probeQuerier struct {
+	errs    map[string]error
+	queries []string
+}
+
+func (q *probeQuerier) Query(_ context.Context, query string, _ ...any) (pgx.Rows, error) {
+	q.queries = append(q.queries, query)
+	for table, err := range q.errs {
+		if strings.HasSuffix(query, " FROM "+table+" LIMIT 0") {
+			return nil, err
+		}
+	}
+	return emptyRows{}, nil
+}
+
+type emptyRows struct{ pgx.Rows }
+
+func (emptyRows) Close()     {}
+func (emptyRows) Err() error { return nil }
+
+type versionQuerier int64
+
+func (v versionQuerier) QueryRow(context.Context, string, ...any) pgx.Row {
+	return versionRow(v)
+}
+
+type versionRow int64
+
+func (r versionRow) Scan(dest ...any) error {
+	*dest[0].(*int64) = int64(r)
+	return nil
+}
+
+type natsStatus nats.Status
+
+func (s natsStatus) Status() nats.Status {
+	return nats.Status(s)
+}
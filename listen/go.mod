@@ -0,0 +1,3 @@
+module github.com/example/keepstack/listen
+
+go 1.25
@@ -0,0 +1,133 @@
+// Package listen opens the listeners the API and worker HTTP servers serve
+// on. An address is a TCP address such as ":8080", "127.0.0.1:8080", or
+// "[::1]:8080", or a Unix domain socket such as
+// "unix:/run/keepstack/api.sock", so a server can sit behind a local reverse
+// proxy or expose metrics only to a sidecar sharing the socket's volume.
+package listen
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+)
+
+const unixPrefix = "unix:"
+
+// Parse splits addr into the network and address net.Listen takes.
+func Parse(addr string) (network, address string, err error) {
+	addr = strings.TrimSpace(addr)
+	if path, ok := strings.CutPrefix(addr, unixPrefix); ok {
+		if path == "" {
+			return "", "", fmt.Errorf("listen address %q: missing socket path", addr)
+		}
+		return "unix", path, nil
+	}
+	if _, _, err := net.SplitHostPort(addr); err != nil {
+		return "", "", fmt.Errorf("listen address %q: %w", addr, err)
+	}
+	return "tcp", addr, nil
+}
+
+// Open listens on every address in addrs. A socket file left behind by an
+// earlier run is removed first; any other file at the path is an error. If
+// one address fails, the listeners already opened are closed.
+func Open(addrs []string) ([]net.Listener, error) {
+	if len(addrs) == 0 {
+		return nil, errors.New("no listen addresses")
+	}
+	listeners := make([]net.Listener, 0, len(addrs))
+	for _, addr := range addrs {
+		listener, err := open(addr)
+		if err != nil {
+			for _, opened := range listeners {
+				opened.Close()
+			}
+			return nil, err
+		}
+		listeners = append(listeners, listener)
+	}
+	return listeners, nil
+}
+
+func open(addr string) (net.Listener, error) {
+	network, address, err := Parse(addr)
+	if err != nil {
+		return nil, err
+	}
+	if network == "unix" {
+		if err := removeStaleSocket(address); err != nil {
+			return nil, err
+		}
+	}
+	listener, err := net.Listen(network, address)
+	if err != nil {
+		return nil, fmt.Errorf("listen on %s: %w", addr, err)
+	}
+	return listener, nil
+}
+
+func removeStaleSocket(path string) error {
+	info, err := os.Lstat(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("stat socket %s: %w", path, err)
+	}
+	if info.Mode().Type() != fs.ModeSocket {
+		return fmt.Errorf("socket path %s exists and is not a socket", path)
+	}
+	if err := os.Remove(path); err != nil {
+		return fmt.Errorf("remove stale socket %s: %w", path, err)
+	}
+	return nil
+}
+
+// Serve serves srv on every listener and blocks until all of them stop,
+// which srv.Shutdown or srv.Close brings about. It returns the first error
+// other than http.ErrServerClosed; a listener that fails closes srv so the
+// caller sees the failure instead of serving on only some addresses.
+func Serve(srv *http.Server, listeners []net.Listener) error {
+	var (
+		wg       sync.WaitGroup
+		once     sync.Once
+		firstErr error
+	)
+	for _, listener := range listeners {
+		wg.Add(1)
+		go func(listener net.Listener) {
+			defer wg.Done()
+			if err := srv.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				once.Do(func() {
+					firstErr = fmt.Errorf("serve %s: %w", Name(listener), err)
+					srv.Close()
+				})
+			}
+		}(listener)
+	}
+	wg.Wait()
+	return firstErr
+}
+
+// Name formats the address of listener the way it was configured.
+func Name(listener net.Listener) string {
+	addr := listener.Addr()
+	if addr.Network() == "unix" {
+		return unixPrefix + addr.String()
+	}
+	return addr.String()
+}
+
+// Names formats the addresses of listeners for a log line.
+func Names(listeners []net.Listener) string {
+	names := make([]string, len(listeners))
+	for i, listener := range listeners {
+		names[i] = Name(listener)
+	}
+	return strings.Join(names, ", ")
+}
@@ -0,0 +1,102 @@
+package listen
+
+import (
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		network, address string
+		wantErr          bool
+	}{
+		":8080":                      {network: "tcp", address: ":8080"},
+		"[::1]:8080":                 {network: "tcp", address: "[::1]:8080"},
+		" 127.0.0.1:9090 ":           {network: "tcp", address: "127.0.0.1:9090"},
+		"unix:/run/keepstack/a.sock": {network: "unix", address: "/run/keepstack/a.sock"},
+		"unix:":                      {wantErr: true},
+		"8080":                       {wantErr: true},
+	}
+	for addr, tc := range tests {
+		network, address, err := Parse(addr)
+		if tc.wantErr {
+			if err == nil {
+				t.Errorf("Parse(%q) = %s %s, want an error", addr, network, address)
+			}
+			continue
+		}
+		if err != nil || network != tc.network || address != tc.address {
+			t.Errorf("Parse(%q) = %q, %q, %v; want %q, %q", addr, network, address, err, tc.network, tc.address)
+		}
+	}
+}
+
+func TestServeOnTCPAndUnixSocket(t *testing.T) {
+	t.Parallel()
+
+	socket := filepath.Join(t.TempDir(), "api.sock")
+	// A socket file left by a previous run must not block startup.
+	stale, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Fatalf("create stale socket: %v", err)
+	}
+	stale.(*net.UnixListener).SetUnlinkOnClose(false)
+	stale.Close()
+
+	listeners, err := Open([]string{"127.0.0.1:0", "unix:" + socket})
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	if names := Names(listeners); !strings.HasPrefix(names, "127.0.0.1:") || !strings.HasSuffix(names, ", unix:"+socket) {
+		t.Fatalf("Names = %q", names)
+	}
+
+	srv := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte("ok"))
+	})}
+	done := make(chan error, 1)
+	go func() { done <- Serve(srv, listeners) }()
+
+	get := func(client *http.Client, url string) {
+		t.Helper()
+		res, err := client.Get(url)
+		if err != nil {
+			t.Fatalf("GET %s: %v", url, err)
+		}
+		defer res.Body.Close()
+		if body, _ := io.ReadAll(res.Body); string(body) != "ok" {
+			t.Fatalf("GET %s = %q", url, body)
+		}
+	}
+	get(http.DefaultClient, "http://"+listeners[0].Addr().String()+"/")
+	get(&http.Client{Transport: &http.Transport{Dial: func(string, string) (net.Conn, error) {
+		return net.Dial("unix", socket)
+	}}}, "http://unix/")
+
+	srv.Close()
+	if err := <-done; err != nil {
+		t.Fatalf("Serve: %v", err)
+	}
+	if _, err := os.Stat(socket); !os.IsNotExist(err) {
+		t.Fatalf("expected the socket to be removed on shutdown, stat err = %v", err)
+	}
+}
+
+func TestOpenRefusesNonSocketFile(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "api.sock")
+	if err := os.WriteFile(path, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Open([]string{"unix:" + path}); err == nil || !strings.Contains(err.Error(), "not a socket") {
+		t.Fatalf("Open err = %v, want a not a socket error", err)
+	}
+}
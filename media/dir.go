@@ -0,0 +1,92 @@
+package media
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// DirStore keeps images as files under a directory, fanned out by the first
+// two characters of their hash. The content type is sniffed again on read.
+type DirStore struct {
+	dir string
+}
+
+// NewDirStore returns a DirStore rooted at dir, created on first Put.
+func NewDirStore(dir string) *DirStore {
+	return &DirStore{dir: dir}
+}
+
+func (d *DirStore) path(hash string) string {
+	return filepath.Join(d.dir, hash[:2], hash)
+}
+
+// Put writes body unless an image with hash is already stored. It writes
+// to a temporary file first so readers never see a partial image.
+func (d *DirStore) Put(_ context.Context, hash, _ string, body []byte) error {
+	if !ValidHash(hash) {
+		return fmt.Errorf("media: invalid hash %q", hash)
+	}
+	path := d.path(hash)
+	if _, err := os.Stat(path); err == nil {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("media: create directory: %w", err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), hash+".*")
+	if err != nil {
+		return fmt.Errorf("media: create file: %w", err)
+	}
+	if _, err := tmp.Write(body); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return fmt.Errorf("media: write file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("media: write file: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("media: store file: %w", err)
+	}
+	return nil
+}
+
+// Get opens the image stored under hash.
+func (d *DirStore) Get(_ context.Context, hash string) (Object, error) {
+	if !ValidHash(hash) {
+		return Object{}, ErrNotFound
+	}
+	file, err := os.Open(d.path(hash))
+	if errors.Is(err, fs.ErrNotExist) {
+		return Object{}, ErrNotFound
+	}
+	if err != nil {
+		return Object{}, fmt.Errorf("media: open file: %w", err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return Object{}, fmt.Errorf("media: stat file: %w", err)
+	}
+	head := make([]byte, 512)
+	n, _ := file.ReadAt(head, 0)
+	contentType, _ := Sniff(head[:n])
+	return Object{Body: file, ContentType: contentType, Size: info.Size()}, nil
+}
+
+// Delete removes the image stored under hash.
+func (d *DirStore) Delete(_ context.Context, hash string) error {
+	if !ValidHash(hash) {
+		return fmt.Errorf("media: invalid hash %q", hash)
+	}
+	if err := os.Remove(d.path(hash)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("media: remove file: %w", err)
+	}
+	return nil
+}
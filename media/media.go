@@ -0,0 +1,146 @@
+// Package media stores the images archived articles show, so readers load
+// them from keepstack instead of the sites they came from. The worker
+// downloads each image once and stores it under the SHA-256 of its bytes;
+// the API serves it back from /api/media/:hash. Both open the same Store
+// from Options, read from the MEDIA_* settings.
+package media
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+// Storage backends for Options.Storage.
+const (
+	// StorageNone keeps images where they are; nothing is mirrored.
+	StorageNone = ""
+	// StorageDir keeps images under a directory, for a single host or a
+	// volume shared by the API and worker.
+	StorageDir = "dir"
+	// StorageS3 keeps images in an S3-compatible bucket.
+	StorageS3 = "s3"
+)
+
+// PathPrefix is where the API serves stored images.
+const PathPrefix = "/api/media/"
+
+// ErrNotFound is returned by Store.Get for a hash that was never stored.
+var ErrNotFound = errors.New("media: not found")
+
+var hashPattern = regexp.MustCompile(`^[0-9a-f]{64}$`)
+
+// Object is a stored image. Callers close Body.
+type Object struct {
+	Body        io.ReadCloser
+	ContentType string
+	Size        int64
+}
+
+// Store keeps images by hash. Put with a hash already stored is a no-op,
+// as is Delete with one that is not.
+type Store interface {
+	Put(ctx context.Context, hash, contentType string, body []byte) error
+	Get(ctx context.Context, hash string) (Object, error)
+	Delete(ctx context.Context, hash string) error
+}
+
+// Options selects where images are kept.
+type Options struct {
+	Storage string
+	// Dir is the directory StorageDir writes to.
+	Dir string
+	// S3Bucket and the rest locate the bucket StorageS3 writes to. An empty
+	// S3Endpoint means AWS; set it for MinIO and other S3-compatible stores.
+	S3Bucket    string
+	S3AccessKey string
+	S3SecretKey string
+	S3Region    string
+	S3Endpoint  string
+	S3Prefix    string
+}
+
+// Enabled reports whether images are mirrored at all.
+func (o Options) Enabled() bool {
+	return o.Storage != StorageNone
+}
+
+// Validate reports an unknown backend or one missing its settings.
+func (o Options) Validate() error {
+	switch o.Storage {
+	case StorageNone:
+	case StorageDir:
+		if o.Dir == "" {
+			return fmt.Errorf("media storage %q requires a directory", o.Storage)
+		}
+	case StorageS3:
+		if o.S3Bucket == "" || o.S3AccessKey == "" || o.S3SecretKey == "" {
+			return fmt.Errorf("media storage %q requires a bucket, access key, and secret key", o.Storage)
+		}
+	default:
+		return fmt.Errorf("unknown media storage %q, want %q or %q", o.Storage, StorageDir, StorageS3)
+	}
+	return nil
+}
+
+// Open returns the Store o selects, or nil when mirroring is disabled.
+func Open(o Options) (Store, error) {
+	if err := o.Validate(); err != nil {
+		return nil, err
+	}
+	switch o.Storage {
+	case StorageDir:
+		return NewDirStore(o.Dir), nil
+	case StorageS3:
+		return newS3Store(o), nil
+	}
+	return nil, nil
+}
+
+// Hash returns the key body is stored under.
+func Hash(body []byte) string {
+	sum := sha256.Sum256(body)
+	return hex.EncodeToString(sum[:])
+}
+
+// ValidHash reports whether hash could have come from Hash, so it is safe
+// to use in a file name or object key.
+func ValidHash(hash string) bool {
+	return hashPattern.MatchString(hash)
+}
+
+// Path returns the API path an image with hash is served from.
+func Path(hash string) string {
+	return PathPrefix + hash
+}
+
+// HashFromPath returns the hash in a Path, or "" when src is not one.
+func HashFromPath(src string) string {
+	hash, ok := strings.CutPrefix(src, PathPrefix)
+	if !ok || !ValidHash(hash) {
+		return ""
+	}
+	return hash
+}
+
+// Sniff returns the content type of an image body, and false unless it is
+// a raster format browsers display. SVG is refused since it can carry
+// script.
+func Sniff(body []byte) (string, bool) {
+	if len(body) >= 12 && bytes.Equal(body[4:8], []byte("ftyp")) && (bytes.Equal(body[8:12], []byte("avif")) || bytes.Equal(body[8:12], []byte("avis"))) {
+		return "image/avif", true
+	}
+	switch contentType := http.DetectContentType(body); contentType {
+	case "image/png", "image/jpeg", "image/gif", "image/webp":
+		return contentType, true
+	default:
+		return contentType, false
+	}
+}
@@ -0,0 +1,131 @@
+package media
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+)
+
+// pngHeader is enough of a PNG for content sniffing.
+var pngHeader = []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+
+func TestDirStoreRoundTrip(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	store := NewDirStore(t.TempDir())
+	hash := Hash(pngHeader)
+
+	if _, err := store.Get(ctx, hash); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Get before Put = %v, want ErrNotFound", err)
+	}
+	for i := 0; i < 2; i++ {
+		if err := store.Put(ctx, hash, "image/png", pngHeader); err != nil {
+			t.Fatalf("Put #%d: %v", i+1, err)
+		}
+	}
+
+	object, err := store.Get(ctx, hash)
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	defer object.Body.Close()
+	body, err := io.ReadAll(object.Body)
+	if err != nil {
+		t.Fatalf("read body: %v", err)
+	}
+	if string(body) != string(pngHeader) || object.ContentType != "image/png" || object.Size != int64(len(pngHeader)) {
+		t.Fatalf("unexpected object %q %q %d", body, object.ContentType, object.Size)
+	}
+
+	if err := store.Put(ctx, "../../etc/passwd", "image/png", pngHeader); err == nil {
+		t.Fatal("expected Put to refuse a path as the hash")
+	}
+	if _, err := store.Get(ctx, "../"+hash); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Get with a path = %v, want ErrNotFound", err)
+	}
+
+	for i := 0; i < 2; i++ {
+		if err := store.Delete(ctx, hash); err != nil {
+			t.Fatalf("Delete #%d: %v", i+1, err)
+		}
+	}
+	if _, err := store.Get(ctx, hash); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Get after Delete = %v, want ErrNotFound", err)
+	}
+	if err := store.Delete(ctx, "../"+hash); err == nil {
+		t.Fatal("expected Delete to refuse a path as the hash")
+	}
+}
+
+func TestSniff(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name string
+		body []byte
+		want string
+		ok   bool
+	}{
+		{name: "png", body: pngHeader, want: "image/png", ok: true},
+		{name: "gif", body: []byte("GIF89a\x01\x00\x01\x00"), want: "image/gif", ok: true},
+		{name: "avif", body: []byte("\x00\x00\x00\x1cftypavif\x00\x00\x00\x00"), want: "image/avif", ok: true},
+		{name: "svg", body: []byte(`<svg xmlns="http://www.w3.org/2000/svg"><script>alert(1)</script></svg>`), ok: false},
+		{name: "html", body: []byte("<!DOCTYPE html><p>not an image"), ok: false},
+	}
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, ok := Sniff(tc.body)
+			if ok != tc.ok || (tc.ok && got != tc.want) {
+				t.Fatalf("Sniff = %q, %v; want %q, %v", got, ok, tc.want, tc.ok)
+			}
+		})
+	}
+}
+
+func TestHashFromPath(t *testing.T) {
+	t.Parallel()
+
+	hash := Hash(pngHeader)
+	if got := HashFromPath(Path(hash)); got != hash {
+		t.Fatalf("HashFromPath(Path) = %q, want %q", got, hash)
+	}
+	for _, src := range []string{"https://example.com/a.png", PathPrefix + "abc", "/api/media/" + hash + "/x"} {
+		if got := HashFromPath(src); got != "" {
+			t.Fatalf("HashFromPath(%q) = %q, want empty", src, got)
+		}
+	}
+}
+
+func TestOptionsValidate(t *testing.T) {
+	t.Parallel()
+
+	valid := []Options{
+		{},
+		{Storage: StorageDir, Dir: "/var/lib/keepstack/media"},
+		{Storage: StorageS3, S3Bucket: "media", S3AccessKey: "key", S3SecretKey: "secret"},
+	}
+	for _, options := range valid {
+		if err := options.Validate(); err != nil {
+			t.Errorf("Validate(%+v) = %v", options, err)
+		}
+	}
+	invalid := []Options{
+		{Storage: "gcs"},
+		{Storage: StorageDir},
+		{Storage: StorageS3, S3Bucket: "media"},
+	}
+	for _, options := range invalid {
+		if err := options.Validate(); err == nil {
+			t.Errorf("Validate(%+v) succeeded, want an error", options)
+		}
+	}
+
+	if store, err := Open(Options{}); store != nil || err != nil {
+		t.Fatalf("Open with no storage = %v, %v; want nil, nil", store, err)
+	}
+}
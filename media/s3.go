@@ -0,0 +1,102 @@
+package media
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// s3Store keeps images as objects named by hash under an optional prefix.
+type s3Store struct {
+	client *s3.Client
+	bucket string
+	prefix string
+}
+
+func newS3Store(o Options) *s3Store {
+	region := o.S3Region
+	if region == "" {
+		region = "us-east-1"
+	}
+	opts := s3.Options{
+		Region:       region,
+		Credentials:  credentials.NewStaticCredentialsProvider(o.S3AccessKey, o.S3SecretKey, ""),
+		UsePathStyle: true,
+	}
+	if o.S3Endpoint != "" {
+		opts.BaseEndpoint = aws.String(o.S3Endpoint)
+	}
+	return &s3Store{client: s3.New(opts), bucket: o.S3Bucket, prefix: strings.Trim(o.S3Prefix, "/")}
+}
+
+func (s *s3Store) key(hash string) string {
+	if s.prefix == "" {
+		return hash
+	}
+	return s.prefix + "/" + hash
+}
+
+// Put uploads body. Objects are immutable, so an existing one is simply
+// written again with the same bytes.
+func (s *s3Store) Put(ctx context.Context, hash, contentType string, body []byte) error {
+	if !ValidHash(hash) {
+		return fmt.Errorf("media: invalid hash %q", hash)
+	}
+	_, err := s.client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket:       aws.String(s.bucket),
+		Key:          aws.String(s.key(hash)),
+		Body:         bytes.NewReader(body),
+		ContentType:  aws.String(contentType),
+		CacheControl: aws.String("public, max-age=31536000, immutable"),
+	})
+	if err != nil {
+		return fmt.Errorf("media: upload %s: %w", hash, err)
+	}
+	return nil
+}
+
+// Get downloads the object stored under hash.
+func (s *s3Store) Get(ctx context.Context, hash string) (Object, error) {
+	if !ValidHash(hash) {
+		return Object{}, ErrNotFound
+	}
+	out, err := s.client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(s.key(hash)),
+	})
+	if err != nil {
+		var missing *types.NoSuchKey
+		if errors.As(err, &missing) {
+			return Object{}, ErrNotFound
+		}
+		return Object{}, fmt.Errorf("media: download %s: %w", hash, err)
+	}
+	return Object{
+		Body:        out.Body,
+		ContentType: aws.ToString(out.ContentType),
+		Size:        aws.ToInt64(out.ContentLength),
+	}, nil
+}
+
+// Delete removes the object stored under hash. S3 reports success for a
+// key that does not exist.
+func (s *s3Store) Delete(ctx context.Context, hash string) error {
+	if !ValidHash(hash) {
+		return fmt.Errorf("media: invalid hash %q", hash)
+	}
+	_, err := s.client.DeleteObject(ctx, &s3.DeleteObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(s.key(hash)),
+	})
+	if err != nil {
+		return fmt.Errorf("media: delete %s: %w", hash, err)
+	}
+	return nil
+}
@@ -0,0 +1,3 @@
+module github.com/example/keepstack/messages
+
+go 1.25
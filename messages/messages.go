@@ -0,0 +1,147 @@
+// Package messages is the contract for what the API and worker send each
+// other over NATS: the subjects and the JSON payload published on each.
+// Both apps use these types instead of their own copies, and the golden
+// payloads under testdata pin the wire format, so a change that would break
+// a peer still running the previous release fails this package's tests.
+package messages
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Subjects the API and worker exchange messages on.
+const (
+	// SubjectLinksSaved carries LinkSaved from the API to the worker.
+	SubjectLinksSaved = "keepstack.links.saved"
+	// SubjectLinksReparse carries LinkReparse to the worker.
+	SubjectLinksReparse = "keepstack.links.reparse"
+	// SubjectLinksDeleted carries LinkDeleted from the API to the worker.
+	SubjectLinksDeleted = "keepstack.links.deleted"
+	// SubjectLinksUnfurl is a request subject: the API sends UnfurlRequest
+	// and a worker answers with UnfurlReply.
+	SubjectLinksUnfurl = "keepstack.links.unfurl"
+	// SubjectLinksExpand is a request subject: the API sends ExpandRequest
+	// and a worker answers with ExpandReply.
+	SubjectLinksExpand = "keepstack.links.expand"
+	// SubjectRecommendationsRefresh carries RecommendationsRefresh between
+	// API replicas.
+	SubjectRecommendationsRefresh = "keepstack.recommendations.refresh"
+	// EventsSubjectPrefix prefixes live update subjects; the Event type
+	// follows it, e.g. "keepstack.events.link.ingested".
+	EventsSubjectPrefix = "keepstack.events."
+)
+
+// Live update event types.
+const (
+	EventLinkIngested          = "link.ingested"
+	EventLinkStatus            = "link.status"
+	EventLinkDeleted           = "link.deleted"
+	EventHighlightCreated      = "highlight.created"
+	EventRecommendationUpdated = "recommendation.updated"
+	EventDigestSent            = "digest.sent"
+	// EventBackupFailed concerns the whole instance rather than one user,
+	// so its UserID is empty and no live update stream receives it.
+	EventBackupFailed = "backup.failed"
+)
+
+// LinkSaved asks the worker to ingest a newly saved link.
+type LinkSaved struct {
+	LinkID string `json:"link_id"`
+	// EnqueuedAt is when the message was published, for measuring queue
+	// lag. Publishers that predate it, or that re-enqueue an old link,
+	// leave it zero.
+	EnqueuedAt time.Time `json:"enqueued_at"`
+}
+
+// LinkReparse asks the worker to ingest an already saved link again.
+type LinkReparse struct {
+	LinkID string `json:"link_id"`
+	// RequestedAt is when the reparse was asked for. Zero means now.
+	RequestedAt time.Time `json:"requested_at"`
+	// Force parses the page again even when the server reports it
+	// unchanged since the archive was taken.
+	Force bool `json:"force,omitempty"`
+}
+
+// LinkDeleted tells the worker a link is gone, so it can remove what the
+// link kept outside Postgres.
+type LinkDeleted struct {
+	LinkID string `json:"link_id"`
+	// MediaHashes are the mirrored images the link's archive showed. Other
+	// archives may show the same images, so the worker checks before
+	// removing any.
+	MediaHashes []string  `json:"media_hashes,omitempty"`
+	DeletedAt   time.Time `json:"deleted_at"`
+}
+
+// RecommendationsRefresh asks for one user's recommendations to be rebuilt.
+type RecommendationsRefresh struct {
+	UserID string `json:"user_id"`
+}
+
+// UnfurlRequest asks a worker to fetch and parse a page without saving it.
+type UnfurlRequest struct {
+	URL string `json:"url"`
+}
+
+// UnfurlReply is a worker's answer to an UnfurlRequest. Error is set, and
+// the other fields empty, when the page could not be fetched or parsed.
+type UnfurlReply struct {
+	URL         string `json:"url,omitempty"`
+	Title       string `json:"title,omitempty"`
+	Description string `json:"description,omitempty"`
+	Image       string `json:"image,omitempty"`
+	WordCount   int    `json:"word_count,omitempty"`
+	Error       string `json:"error,omitempty"`
+}
+
+// ExpandRequest asks a worker to list the article links on an index page,
+// such as a newsletter issue, without saving anything.
+type ExpandRequest struct {
+	URL string `json:"url"`
+}
+
+// ExpandReply is a worker's answer to an ExpandRequest, with links in page
+// order. Error is set, and the other fields empty, when the page could not
+// be fetched or parsed.
+type ExpandReply struct {
+	URL   string         `json:"url,omitempty"`
+	Title string         `json:"title,omitempty"`
+	Links []ExpandedLink `json:"links,omitempty"`
+	Error string         `json:"error,omitempty"`
+}
+
+// ExpandedLink is one article link found on an index page.
+type ExpandedLink struct {
+	URL   string `json:"url"`
+	Title string `json:"title"`
+}
+
+// Event is a live update for one user's clients, published on
+// EventsSubjectPrefix followed by Type. Data holds the type-specific
+// payload, if any.
+type Event struct {
+	Type   string          `json:"type"`
+	UserID string          `json:"user_id"`
+	LinkID string          `json:"link_id,omitempty"`
+	Data   json.RawMessage `json:"data,omitempty"`
+}
+
+// LinkStatus is the Data of an EventLinkStatus event.
+type LinkStatus struct {
+	Status      string `json:"status"`
+	FailedStage string `json:"failed_stage,omitempty"`
+	Error       string `json:"error,omitempty"`
+}
+
+// DigestSent is the Data of an EventDigestSent event.
+type DigestSent struct {
+	Links int `json:"links"`
+}
+
+// BackupFailed is the Data of an EventBackupFailed event.
+type BackupFailed struct {
+	Kind  string `json:"kind,omitempty"`
+	Error string `json:"error"`
+}
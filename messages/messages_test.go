@@ -0,0 +1,184 @@
+package messages
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+const (
+	linkID = "3f1c2a9e-8d4b-4c1e-9a57-2b6f0d8e4c13"
+	userID = "00000000-0000-0000-0000-000000000001"
+)
+
+// TestGoldenPayloads pins each message's JSON encoding. A failure means
+// the other app, or a replica on the previous release, would read the
+// message differently: keep the old field names and add new fields as
+// optional rather than updating the golden file.
+func TestGoldenPayloads(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		golden string
+		value  any
+	}{
+		"links saved": {
+			golden: "links.saved.json",
+			value:  &LinkSaved{LinkID: linkID, EnqueuedAt: time.Date(2024, 5, 1, 12, 30, 0, 0, time.UTC)},
+		},
+		"links reparse": {
+			golden: "links.reparse.json",
+			value:  &LinkReparse{LinkID: linkID, RequestedAt: time.Date(2024, 5, 2, 8, 0, 0, 0, time.UTC)},
+		},
+		"unfurl request": {
+			golden: "links.unfurl.request.json",
+			value:  &UnfurlRequest{URL: "https://example.com/article"},
+		},
+		"unfurl reply": {
+			golden: "links.unfurl.reply.json",
+			value: &UnfurlReply{
+				URL:         "https://example.com/article",
+				Title:       "An article",
+				Description: "What it is about",
+				Image:       "https://example.com/cover.png",
+				WordCount:   1200,
+			},
+		},
+		"unfurl reply error": {
+			golden: "links.unfurl.reply-error.json",
+			value:  &UnfurlReply{Error: "fetch: unexpected status 404"},
+		},
+		"expand request": {
+			golden: "links.expand.request.json",
+			value:  &ExpandRequest{URL: "https://example.com/issue-42"},
+		},
+		"expand reply": {
+			golden: "links.expand.reply.json",
+			value: &ExpandReply{
+				URL:   "https://example.com/issue-42",
+				Title: "Issue 42",
+				Links: []ExpandedLink{
+					{URL: "https://blog.example.org/a-post", Title: "A post"},
+					{URL: "https://news.example.net/2024/05/story", Title: "A story"},
+				},
+			},
+		},
+		"expand reply error": {
+			golden: "links.expand.reply-error.json",
+			value:  &ExpandReply{Error: "fetch: unexpected status 404"},
+		},
+		"recommendations refresh": {
+			golden: "recommendations.refresh.json",
+			value:  &RecommendationsRefresh{UserID: userID},
+		},
+		"link ingested event": {
+			golden: "events.link.ingested.json",
+			value:  &Event{Type: EventLinkIngested, UserID: userID, LinkID: linkID},
+		},
+		"link status event": {
+			golden: "events.link.status.json",
+			value: &Event{
+				Type:   EventLinkStatus,
+				UserID: userID,
+				LinkID: linkID,
+				Data:   json.RawMessage(`{"status":"failed","failed_stage":"fetch","error":"timeout"}`),
+			},
+		},
+		"digest sent event": {
+			golden: "events.digest.sent.json",
+			value: &Event{
+				Type:   EventDigestSent,
+				UserID: userID,
+				Data:   json.RawMessage(`{"links":12}`),
+			},
+		},
+		"backup failed event": {
+			golden: "events.backup.failed.json",
+			value: &Event{
+				Type: EventBackupFailed,
+				Data: json.RawMessage(`{"kind":"full","error":"pg_dump: connection refused"}`),
+			},
+		},
+	}
+
+	for name, tc := range tests {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			golden, err := os.ReadFile(filepath.Join("testdata", tc.golden))
+			if err != nil {
+				t.Fatal(err)
+			}
+			golden = bytes.TrimSpace(golden)
+
+			encoded, err := json.Marshal(tc.value)
+			if err != nil {
+				t.Fatalf("marshal: %v", err)
+			}
+			if !bytes.Equal(encoded, golden) {
+				t.Fatalf("encoding changed:\n got %s\nwant %s", encoded, golden)
+			}
+
+			decoded := reflect.New(reflect.TypeOf(tc.value).Elem()).Interface()
+			if err := json.Unmarshal(golden, decoded); err != nil {
+				t.Fatalf("unmarshal: %v", err)
+			}
+			if !reflect.DeepEqual(decoded, tc.value) {
+				t.Fatalf("round trip mismatch:\n got %+v\nwant %+v", decoded, tc.value)
+			}
+		})
+	}
+}
+
+func TestLinkSavedWithoutEnqueuedAt(t *testing.T) {
+	t.Parallel()
+
+	// Messages from API versions before enqueued_at existed must still
+	// decode, with a zero time the worker knows to ignore.
+	var msg LinkSaved
+	if err := json.Unmarshal([]byte(`{"link_id":"`+linkID+`"}`), &msg); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if msg.LinkID != linkID || !msg.EnqueuedAt.IsZero() {
+		t.Fatalf("unexpected message %+v", msg)
+	}
+}
+
+func TestLinkStatusData(t *testing.T) {
+	t.Parallel()
+
+	data, err := json.Marshal(LinkStatus{Status: "failed", FailedStage: "fetch", Error: "timeout"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := string(data), `{"status":"failed","failed_stage":"fetch","error":"timeout"}`; got != want {
+		t.Fatalf("encoding changed: got %s want %s", got, want)
+	}
+}
+
+func TestSubjects(t *testing.T) {
+	t.Parallel()
+
+	// Renaming a subject strands messages between releases; both sides
+	// must keep agreeing on these literal names.
+	for got, want := range map[string]string{
+		SubjectLinksSaved:                           "keepstack.links.saved",
+		SubjectLinksReparse:                         "keepstack.links.reparse",
+		SubjectLinksUnfurl:                          "keepstack.links.unfurl",
+		SubjectRecommendationsRefresh:               "keepstack.recommendations.refresh",
+		EventsSubjectPrefix + EventLinkIngested:     "keepstack.events.link.ingested",
+		EventsSubjectPrefix + EventLinkStatus:       "keepstack.events.link.status",
+		EventsSubjectPrefix + EventHighlightCreated: "keepstack.events.highlight.created",
+		EventsSubjectPrefix + EventDigestSent:       "keepstack.events.digest.sent",
+		EventsSubjectPrefix + EventBackupFailed:     "keepstack.events.backup.failed",
+	} {
+		if got != want {
+			t.Errorf("subject %q, want %q", got, want)
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package observe
+
+import (
+	"context"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// unnamedQuery labels statements that do not carry a sqlc name comment, such
+// as health checks and ad-hoc SQL, so the label set stays bounded.
+const unnamedQuery = "unnamed"
+
+// maxLoggedSQL caps how much of a slow statement is written to the log.
+const maxLoggedSQL = 200
+
+// QueryTracer implements pgx.QueryTracer. It records each query's duration
+// under its sqlc name and logs queries slower than the threshold. Arguments
+// are never logged.
+type QueryTracer struct {
+	duration      *prometheus.HistogramVec
+	slowThreshold time.Duration
+	logger        *log.Logger
+}
+
+type queryTraceKey struct{}
+
+type queryTrace struct {
+	name  string
+	sql   string
+	start time.Time
+}
+
+// NewQueryTracer builds a tracer that observes into duration, labelled by
+// query name and status. A zero slowThreshold disables slow query logging.
+func NewQueryTracer(duration *prometheus.HistogramVec, slowThreshold time.Duration, logger *log.Logger) *QueryTracer {
+	return &QueryTracer{duration: duration, slowThreshold: slowThreshold, logger: logger}
+}
+
+// TraceQueryStart stashes the query name and start time on the context.
+func (t *QueryTracer) TraceQueryStart(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryStartData) context.Context {
+	return context.WithValue(ctx, queryTraceKey{}, queryTrace{
+		name:  QueryName(data.SQL),
+		sql:   data.SQL,
+		start: time.Now(),
+	})
+}
+
+// TraceQueryEnd records the duration and logs the query when it was slow.
+func (t *QueryTracer) TraceQueryEnd(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryEndData) {
+	trace, ok := ctx.Value(queryTraceKey{}).(queryTrace)
+	if !ok {
+		return
+	}
+	elapsed := time.Since(trace.start)
+
+	status := "ok"
+	if data.Err != nil {
+		status = "error"
+	}
+	ObserveWithTrace(ctx, t.duration.WithLabelValues(trace.name, status), elapsed.Seconds())
+
+	if t.slowThreshold > 0 && elapsed >= t.slowThreshold && t.logger != nil {
+		t.logger.Printf("slow query %s took %s: %s", trace.name, elapsed.Round(time.Millisecond), summarizeSQL(trace.sql))
+	}
+}
+
+// QueryName extracts the sqlc query name from a "-- name: GetLink :one"
+// header, falling back to "unnamed".
+func QueryName(sql string) string {
+	sql = strings.TrimSpace(sql)
+	rest, ok := strings.CutPrefix(sql, "-- name:")
+	if !ok {
+		return unnamedQuery
+	}
+	fields := strings.Fields(rest)
+	if len(fields) == 0 {
+		return unnamedQuery
+	}
+	return fields[0]
+}
+
+// summarizeSQL drops sqlc name comments, collapses whitespace, and truncates
+// the statement so slow query log lines stay on one line.
+func summarizeSQL(sql string) string {
+	lines := strings.Split(sql, "\n")
+	kept := lines[:0]
+	for _, line := range lines {
+		if strings.HasPrefix(strings.TrimSpace(line), "--") {
+			continue
+		}
+		kept = append(kept, line)
+	}
+	summary := strings.Join(strings.Fields(strings.Join(kept, " ")), " ")
+	if len(summary) > maxLoggedSQL {
+		summary = summary[:maxLoggedSQL] + "..."
+	}
+	return summary
+}
@@ -0,0 +1,60 @@
+package observe
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log"
+	"strings"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestQueryName(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]string{
+		"-- name: GetLink :one\nSELECT 1":       "GetLink",
+		"\n  -- name: ListTags :many\nSELECT 1": "ListTags",
+		"SELECT 1":                              unnamedQuery,
+		"-- name:":                              unnamedQuery,
+	}
+	for sql, want := range cases {
+		if got := QueryName(sql); got != want {
+			t.Fatalf("QueryName(%q) = %q, want %q", sql, got, want)
+		}
+	}
+}
+
+func TestQueryTracerRecordsDurationAndSlowQueries(t *testing.T) {
+	t.Parallel()
+
+	histogram := prometheus.NewHistogramVec(prometheus.HistogramOpts{Name: "test_db_query_duration_seconds"}, []string{"query", "status"})
+	var buf bytes.Buffer
+	tracer := NewQueryTracer(histogram, 1, log.New(&buf, "", 0))
+
+	sql := "-- name: GetLink :one\nSELECT id,\n       url\nFROM links WHERE id = $1"
+	ctx := tracer.TraceQueryStart(context.Background(), nil, pgx.TraceQueryStartData{SQL: sql, Args: []any{"secret"}})
+	tracer.TraceQueryEnd(ctx, nil, pgx.TraceQueryEndData{})
+
+	ctx = tracer.TraceQueryStart(context.Background(), nil, pgx.TraceQueryStartData{SQL: "SELECT 1"})
+	tracer.TraceQueryEnd(ctx, nil, pgx.TraceQueryEndData{Err: errors.New("boom")})
+
+	if got := testutil.CollectAndCount(histogram); got != 2 {
+		t.Fatalf("expected 2 series, got %d", got)
+	}
+
+	logged := buf.String()
+	if !strings.Contains(logged, "slow query GetLink") {
+		t.Fatalf("expected slow query log for GetLink, got %q", logged)
+	}
+	if !strings.Contains(logged, "SELECT id, url FROM links WHERE id = $1") {
+		t.Fatalf("expected collapsed SQL in log, got %q", logged)
+	}
+	if strings.Contains(logged, "-- name") || strings.Contains(logged, "secret") {
+		t.Fatalf("expected name comment and arguments to be omitted, got %q", logged)
+	}
+}
@@ -0,0 +1,77 @@
+package observe
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/getsentry/sentry-go"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// ErrorReporter forwards errors and recovered panics to Sentry. A nil
+// *ErrorReporter drops everything, so callers do not need to check whether
+// reporting is configured.
+type ErrorReporter struct {
+	hub *sentry.Hub
+}
+
+// ErrorContext describes where an error happened.
+type ErrorContext struct {
+	// Request is attached with sensitive headers stripped and without the
+	// body.
+	Request *http.Request
+	// Tags are short, indexed values such as the route or pipeline stage.
+	Tags map[string]string
+	// Details holds longer values, such as URLs, shown on the event.
+	Details map[string]any
+	// Level overrides the default error level, e.g. for panics.
+	Level sentry.Level
+}
+
+// NewErrorReporter builds a reporter from opts. It returns nil when
+// opts.Dsn is empty, which disables reporting.
+func NewErrorReporter(opts sentry.ClientOptions) (*ErrorReporter, error) {
+	if opts.Dsn == "" {
+		return nil, nil
+	}
+	opts.AttachStacktrace = true
+	client, err := sentry.NewClient(opts)
+	if err != nil {
+		return nil, fmt.Errorf("create sentry client: %w", err)
+	}
+	return &ErrorReporter{hub: sentry.NewHub(client, sentry.NewScope())}, nil
+}
+
+// Capture reports err with the given context. The trace ID of a sampled span
+// in ctx is added as a tag so the event links back to the trace.
+func (r *ErrorReporter) Capture(ctx context.Context, err error, ec ErrorContext) {
+	if r == nil || err == nil {
+		return
+	}
+	hub := r.hub.Clone()
+	scope := hub.Scope()
+	if ec.Request != nil {
+		scope.SetRequest(ec.Request)
+	}
+	scope.SetTags(ec.Tags)
+	if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+		scope.SetTag("trace_id", sc.TraceID().String())
+	}
+	if len(ec.Details) > 0 {
+		scope.SetContext("details", sentry.Context(ec.Details))
+	}
+	if ec.Level != "" {
+		scope.SetLevel(ec.Level)
+	}
+	hub.CaptureException(err)
+}
+
+// Flush waits up to timeout for queued events to be sent.
+func (r *ErrorReporter) Flush(timeout time.Duration) {
+	if r == nil {
+		return
+	}
+	r.hub.Flush(timeout)
+}
@@ -0,0 +1,47 @@
+// Package observe holds the observability helpers the API and worker share:
+// tracing setup, observations that link to the trace they were recorded
+// under, the pgx tracer timing each sqlc query, the pgxpool statistics
+// collector, and the Sentry error reporter.
+package observe
+
+import (
+	"context"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// exemplarLabels returns the trace ID of a sampled span in ctx, or nil when
+// there is nothing worth linking to.
+func exemplarLabels(ctx context.Context) prometheus.Labels {
+	sc := trace.SpanContextFromContext(ctx)
+	if !sc.IsValid() || !sc.IsSampled() {
+		return nil
+	}
+	return prometheus.Labels{"trace_id": sc.TraceID().String()}
+}
+
+// ObserveWithTrace records v and, when ctx carries a sampled span, attaches
+// its trace ID as an exemplar so Grafana can jump from a latency spike to the
+// trace. Exemplars are only exposed in the OpenMetrics format.
+func ObserveWithTrace(ctx context.Context, observer prometheus.Observer, v float64) {
+	if labels := exemplarLabels(ctx); labels != nil {
+		if eo, ok := observer.(prometheus.ExemplarObserver); ok {
+			eo.ObserveWithExemplar(v, labels)
+			return
+		}
+	}
+	observer.Observe(v)
+}
+
+// IncWithTrace increments counter, attaching the trace ID as an exemplar
+// when ctx carries a sampled span.
+func IncWithTrace(ctx context.Context, counter prometheus.Counter) {
+	if labels := exemplarLabels(ctx); labels != nil {
+		if ea, ok := counter.(prometheus.ExemplarAdder); ok {
+			ea.AddWithExemplar(1, labels)
+			return
+		}
+	}
+	counter.Inc()
+}
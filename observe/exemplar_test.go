@@ -0,0 +1,57 @@
+package observe
+
+import (
+	"context"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestIncWithTraceAttachesSampledTraceID(t *testing.T) {
+	t.Parallel()
+
+	traceID := trace.TraceID{1, 2, 3}
+	sampled := trace.ContextWithSpanContext(context.Background(), trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    traceID,
+		SpanID:     trace.SpanID{4},
+		TraceFlags: trace.FlagsSampled,
+	}))
+	unsampled := trace.ContextWithSpanContext(context.Background(), trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID: traceID,
+		SpanID:  trace.SpanID{4},
+	}))
+
+	cases := map[string]struct {
+		ctx  context.Context
+		want string
+	}{
+		"sampled":   {ctx: sampled, want: traceID.String()},
+		"unsampled": {ctx: unsampled},
+		"no span":   {ctx: context.Background()},
+	}
+	for name, tc := range cases {
+		counter := prometheus.NewCounter(prometheus.CounterOpts{Name: "test_total"})
+		IncWithTrace(tc.ctx, counter)
+
+		var metric dto.Metric
+		if err := counter.Write(&metric); err != nil {
+			t.Fatalf("%s: write counter: %v", name, err)
+		}
+		if got := metric.GetCounter().GetValue(); got != 1 {
+			t.Fatalf("%s: counter = %v, want 1", name, got)
+		}
+		var got string
+		if exemplar := metric.GetCounter().GetExemplar(); exemplar != nil {
+			for _, label := range exemplar.GetLabel() {
+				if label.GetName() == "trace_id" {
+					got = label.GetValue()
+				}
+			}
+		}
+		if got != tc.want {
+			t.Fatalf("%s: exemplar trace_id = %q, want %q", name, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package observe
+
+import (
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// PoolStatsCollector exports pgxpool statistics at scrape time, so the
+// values are always current without a polling goroutine.
+type PoolStatsCollector struct {
+	stat func() *pgxpool.Stat
+
+	acquiredConns     *prometheus.Desc
+	idleConns         *prometheus.Desc
+	totalConns        *prometheus.Desc
+	maxConns          *prometheus.Desc
+	acquireCount      *prometheus.Desc
+	emptyAcquireCount *prometheus.Desc
+	canceledAcquires  *prometheus.Desc
+	acquireWait       *prometheus.Desc
+}
+
+// NewPoolStatsCollector builds a collector that reads stats from the given
+// function, typically pool.Stat. Metric names use the supplied namespace.
+func NewPoolStatsCollector(namespace string, stat func() *pgxpool.Stat) *PoolStatsCollector {
+	desc := func(name, help string) *prometheus.Desc {
+		return prometheus.NewDesc(prometheus.BuildFQName(namespace, "db_pool", name), help, nil, nil)
+	}
+	return &PoolStatsCollector{
+		stat:              stat,
+		acquiredConns:     desc("acquired_conns", "Number of connections currently checked out of the pool."),
+		idleConns:         desc("idle_conns", "Number of idle connections in the pool."),
+		totalConns:        desc("total_conns", "Total number of connections in the pool, including ones being established."),
+		maxConns:          desc("max_conns", "Maximum size of the pool."),
+		acquireCount:      desc("acquires_total", "Number of successful connection acquisitions."),
+		emptyAcquireCount: desc("empty_acquires_total", "Number of acquisitions that had to wait because the pool had no idle connection."),
+		canceledAcquires:  desc("canceled_acquires_total", "Number of acquisitions cancelled by their context."),
+		acquireWait:       desc("acquire_wait_seconds_total", "Cumulative time spent acquiring connections, including waits for a free one."),
+	}
+}
+
+// Describe implements prometheus.Collector.
+func (c *PoolStatsCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.acquiredConns
+	ch <- c.idleConns
+	ch <- c.totalConns
+	ch <- c.maxConns
+	ch <- c.acquireCount
+	ch <- c.emptyAcquireCount
+	ch <- c.canceledAcquires
+	ch <- c.acquireWait
+}
+
+// Collect implements prometheus.Collector.
+func (c *PoolStatsCollector) Collect(ch chan<- prometheus.Metric) {
+	stat := c.stat()
+	ch <- prometheus.MustNewConstMetric(c.acquiredConns, prometheus.GaugeValue, float64(stat.AcquiredConns()))
+	ch <- prometheus.MustNewConstMetric(c.idleConns, prometheus.GaugeValue, float64(stat.IdleConns()))
+	ch <- prometheus.MustNewConstMetric(c.totalConns, prometheus.GaugeValue, float64(stat.TotalConns()))
+	ch <- prometheus.MustNewConstMetric(c.maxConns, prometheus.GaugeValue, float64(stat.MaxConns()))
+	ch <- prometheus.MustNewConstMetric(c.acquireCount, prometheus.CounterValue, float64(stat.AcquireCount()))
+	ch <- prometheus.MustNewConstMetric(c.emptyAcquireCount, prometheus.CounterValue, float64(stat.EmptyAcquireCount()))
+	ch <- prometheus.MustNewConstMetric(c.canceledAcquires, prometheus.CounterValue, float64(stat.CanceledAcquireCount()))
+	ch <- prometheus.MustNewConstMetric(c.acquireWait, prometheus.CounterValue, stat.AcquireDuration().Seconds())
+}
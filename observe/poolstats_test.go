@@ -0,0 +1,38 @@
+package observe
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestPoolStatsCollector(t *testing.T) {
+	t.Parallel()
+
+	cfg, err := pgxpool.ParseConfig("postgres://keepstack@127.0.0.1:1/keepstack")
+	if err != nil {
+		t.Fatalf("parse config: %v", err)
+	}
+	cfg.MaxConns = 7
+	// The pool connects lazily, so no database is needed to read its stats.
+	pool, err := pgxpool.NewWithConfig(context.Background(), cfg)
+	if err != nil {
+		t.Fatalf("new pool: %v", err)
+	}
+	defer pool.Close()
+
+	collector := NewPoolStatsCollector("keepstack_test", pool.Stat)
+	if got := testutil.CollectAndCount(collector); got != 8 {
+		t.Fatalf("expected 8 pool metrics, got %d", got)
+	}
+	if err := testutil.CollectAndCompare(collector, strings.NewReader(`
+# HELP keepstack_test_db_pool_max_conns Maximum size of the pool.
+# TYPE keepstack_test_db_pool_max_conns gauge
+keepstack_test_db_pool_max_conns 7
+`), "keepstack_test_db_pool_max_conns"); err != nil {
+		t.Fatalf("unexpected max_conns: %v", err)
+	}
+}
@@ -0,0 +1,420 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.8
+// 	protoc        (unknown)
+// source: ingest/v1/ingest.proto
+
+package ingestv1
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	durationpb "google.golang.org/protobuf/types/known/durationpb"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type LinkStatus int32
+
+const (
+	LinkStatus_LINK_STATUS_UNSPECIFIED LinkStatus = 0
+	LinkStatus_LINK_STATUS_FETCHING    LinkStatus = 1
+	LinkStatus_LINK_STATUS_PARSING     LinkStatus = 2
+	LinkStatus_LINK_STATUS_PERSISTING  LinkStatus = 3
+	LinkStatus_LINK_STATUS_INGESTED    LinkStatus = 4
+	LinkStatus_LINK_STATUS_FAILED      LinkStatus = 5
+)
+
+// Enum value maps for LinkStatus.
+var (
+	LinkStatus_name = map[int32]string{
+		0: "LINK_STATUS_UNSPECIFIED",
+		1: "LINK_STATUS_FETCHING",
+		2: "LINK_STATUS_PARSING",
+		3: "LINK_STATUS_PERSISTING",
+		4: "LINK_STATUS_INGESTED",
+		5: "LINK_STATUS_FAILED",
+	}
+	LinkStatus_value = map[string]int32{
+		"LINK_STATUS_UNSPECIFIED": 0,
+		"LINK_STATUS_FETCHING":    1,
+		"LINK_STATUS_PARSING":     2,
+		"LINK_STATUS_PERSISTING":  3,
+		"LINK_STATUS_INGESTED":    4,
+		"LINK_STATUS_FAILED":      5,
+	}
+)
+
+func (x LinkStatus) Enum() *LinkStatus {
+	p := new(LinkStatus)
+	*p = x
+	return p
+}
+
+func (x LinkStatus) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (LinkStatus) Descriptor() protoreflect.EnumDescriptor {
+	return file_ingest_v1_ingest_proto_enumTypes[0].Descriptor()
+}
+
+func (LinkStatus) Type() protoreflect.EnumType {
+	return &file_ingest_v1_ingest_proto_enumTypes[0]
+}
+
+func (x LinkStatus) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use LinkStatus.Descriptor instead.
+func (LinkStatus) EnumDescriptor() ([]byte, []int) {
+	return file_ingest_v1_ingest_proto_rawDescGZIP(), []int{0}
+}
+
+type UpdateLinkStatusRequest struct {
+	state  protoimpl.MessageState `protogen:"open.v1"`
+	LinkId string                 `protobuf:"bytes,1,opt,name=link_id,json=linkId,proto3" json:"link_id,omitempty"`
+	Status LinkStatus             `protobuf:"varint,2,opt,name=status,proto3,enum=keepstack.ingest.v1.LinkStatus" json:"status,omitempty"`
+	// Set with LINK_STATUS_FAILED: the stage that failed and why.
+	FailedStage   string `protobuf:"bytes,3,opt,name=failed_stage,json=failedStage,proto3" json:"failed_stage,omitempty"`
+	Error         string `protobuf:"bytes,4,opt,name=error,proto3" json:"error,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UpdateLinkStatusRequest) Reset() {
+	*x = UpdateLinkStatusRequest{}
+	mi := &file_ingest_v1_ingest_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UpdateLinkStatusRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UpdateLinkStatusRequest) ProtoMessage() {}
+
+func (x *UpdateLinkStatusRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_ingest_v1_ingest_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UpdateLinkStatusRequest.ProtoReflect.Descriptor instead.
+func (*UpdateLinkStatusRequest) Descriptor() ([]byte, []int) {
+	return file_ingest_v1_ingest_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *UpdateLinkStatusRequest) GetLinkId() string {
+	if x != nil {
+		return x.LinkId
+	}
+	return ""
+}
+
+func (x *UpdateLinkStatusRequest) GetStatus() LinkStatus {
+	if x != nil {
+		return x.Status
+	}
+	return LinkStatus_LINK_STATUS_UNSPECIFIED
+}
+
+func (x *UpdateLinkStatusRequest) GetFailedStage() string {
+	if x != nil {
+		return x.FailedStage
+	}
+	return ""
+}
+
+func (x *UpdateLinkStatusRequest) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+type UpdateLinkStatusResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UpdateLinkStatusResponse) Reset() {
+	*x = UpdateLinkStatusResponse{}
+	mi := &file_ingest_v1_ingest_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UpdateLinkStatusResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UpdateLinkStatusResponse) ProtoMessage() {}
+
+func (x *UpdateLinkStatusResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_ingest_v1_ingest_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UpdateLinkStatusResponse.ProtoReflect.Descriptor instead.
+func (*UpdateLinkStatusResponse) Descriptor() ([]byte, []int) {
+	return file_ingest_v1_ingest_proto_rawDescGZIP(), []int{1}
+}
+
+type ReportDiagnosticsRequest struct {
+	state  protoimpl.MessageState `protogen:"open.v1"`
+	LinkId string                 `protobuf:"bytes,1,opt,name=link_id,json=linkId,proto3" json:"link_id,omitempty"`
+	// URL the fetch ended on after redirects.
+	FinalUrl      string               `protobuf:"bytes,2,opt,name=final_url,json=finalUrl,proto3" json:"final_url,omitempty"`
+	FetchBytes    int64                `protobuf:"varint,3,opt,name=fetch_bytes,json=fetchBytes,proto3" json:"fetch_bytes,omitempty"`
+	FetchDuration *durationpb.Duration `protobuf:"bytes,4,opt,name=fetch_duration,json=fetchDuration,proto3" json:"fetch_duration,omitempty"`
+	ParseDuration *durationpb.Duration `protobuf:"bytes,5,opt,name=parse_duration,json=parseDuration,proto3" json:"parse_duration,omitempty"`
+	Lang          string               `protobuf:"bytes,6,opt,name=lang,proto3" json:"lang,omitempty"`
+	LangDetected  bool                 `protobuf:"varint,7,opt,name=lang_detected,json=langDetected,proto3" json:"lang_detected,omitempty"`
+	WordCount     int32                `protobuf:"varint,8,opt,name=word_count,json=wordCount,proto3" json:"word_count,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ReportDiagnosticsRequest) Reset() {
+	*x = ReportDiagnosticsRequest{}
+	mi := &file_ingest_v1_ingest_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ReportDiagnosticsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ReportDiagnosticsRequest) ProtoMessage() {}
+
+func (x *ReportDiagnosticsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_ingest_v1_ingest_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ReportDiagnosticsRequest.ProtoReflect.Descriptor instead.
+func (*ReportDiagnosticsRequest) Descriptor() ([]byte, []int) {
+	return file_ingest_v1_ingest_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *ReportDiagnosticsRequest) GetLinkId() string {
+	if x != nil {
+		return x.LinkId
+	}
+	return ""
+}
+
+func (x *ReportDiagnosticsRequest) GetFinalUrl() string {
+	if x != nil {
+		return x.FinalUrl
+	}
+	return ""
+}
+
+func (x *ReportDiagnosticsRequest) GetFetchBytes() int64 {
+	if x != nil {
+		return x.FetchBytes
+	}
+	return 0
+}
+
+func (x *ReportDiagnosticsRequest) GetFetchDuration() *durationpb.Duration {
+	if x != nil {
+		return x.FetchDuration
+	}
+	return nil
+}
+
+func (x *ReportDiagnosticsRequest) GetParseDuration() *durationpb.Duration {
+	if x != nil {
+		return x.ParseDuration
+	}
+	return nil
+}
+
+func (x *ReportDiagnosticsRequest) GetLang() string {
+	if x != nil {
+		return x.Lang
+	}
+	return ""
+}
+
+func (x *ReportDiagnosticsRequest) GetLangDetected() bool {
+	if x != nil {
+		return x.LangDetected
+	}
+	return false
+}
+
+func (x *ReportDiagnosticsRequest) GetWordCount() int32 {
+	if x != nil {
+		return x.WordCount
+	}
+	return 0
+}
+
+type ReportDiagnosticsResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ReportDiagnosticsResponse) Reset() {
+	*x = ReportDiagnosticsResponse{}
+	mi := &file_ingest_v1_ingest_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ReportDiagnosticsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ReportDiagnosticsResponse) ProtoMessage() {}
+
+func (x *ReportDiagnosticsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_ingest_v1_ingest_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ReportDiagnosticsResponse.ProtoReflect.Descriptor instead.
+func (*ReportDiagnosticsResponse) Descriptor() ([]byte, []int) {
+	return file_ingest_v1_ingest_proto_rawDescGZIP(), []int{3}
+}
+
+var File_ingest_v1_ingest_proto protoreflect.FileDescriptor
+
+const file_ingest_v1_ingest_proto_rawDesc = "" +
+	"\n" +
+	"\x16ingest/v1/ingest.proto\x12\x13keepstack.ingest.v1\x1a\x1egoogle/protobuf/duration.proto\"\xa4\x01\n" +
+	"\x17UpdateLinkStatusRequest\x12\x17\n" +
+	"\alink_id\x18\x01 \x01(\tR\x06linkId\x127\n" +
+	"\x06status\x18\x02 \x01(\x0e2\x1f.keepstack.ingest.v1.LinkStatusR\x06status\x12!\n" +
+	"\ffailed_stage\x18\x03 \x01(\tR\vfailedStage\x12\x14\n" +
+	"\x05error\x18\x04 \x01(\tR\x05error\"\x1a\n" +
+	"\x18UpdateLinkStatusResponse\"\xcd\x02\n" +
+	"\x18ReportDiagnosticsRequest\x12\x17\n" +
+	"\alink_id\x18\x01 \x01(\tR\x06linkId\x12\x1b\n" +
+	"\tfinal_url\x18\x02 \x01(\tR\bfinalUrl\x12\x1f\n" +
+	"\vfetch_bytes\x18\x03 \x01(\x03R\n" +
+	"fetchBytes\x12@\n" +
+	"\x0efetch_duration\x18\x04 \x01(\v2\x19.google.protobuf.DurationR\rfetchDuration\x12@\n" +
+	"\x0eparse_duration\x18\x05 \x01(\v2\x19.google.protobuf.DurationR\rparseDuration\x12\x12\n" +
+	"\x04lang\x18\x06 \x01(\tR\x04lang\x12#\n" +
+	"\rlang_detected\x18\a \x01(\bR\flangDetected\x12\x1d\n" +
+	"\n" +
+	"word_count\x18\b \x01(\x05R\twordCount\"\x1b\n" +
+	"\x19ReportDiagnosticsResponse*\xaa\x01\n" +
+	"\n" +
+	"LinkStatus\x12\x1b\n" +
+	"\x17LINK_STATUS_UNSPECIFIED\x10\x00\x12\x18\n" +
+	"\x14LINK_STATUS_FETCHING\x10\x01\x12\x17\n" +
+	"\x13LINK_STATUS_PARSING\x10\x02\x12\x1a\n" +
+	"\x16LINK_STATUS_PERSISTING\x10\x03\x12\x18\n" +
+	"\x14LINK_STATUS_INGESTED\x10\x04\x12\x16\n" +
+	"\x12LINK_STATUS_FAILED\x10\x052\xf4\x01\n" +
+	"\rIngestService\x12o\n" +
+	"\x10UpdateLinkStatus\x12,.keepstack.ingest.v1.UpdateLinkStatusRequest\x1a-.keepstack.ingest.v1.UpdateLinkStatusResponse\x12r\n" +
+	"\x11ReportDiagnostics\x12-.keepstack.ingest.v1.ReportDiagnosticsRequest\x1a..keepstack.ingest.v1.ReportDiagnosticsResponseB7Z5github.com/example/keepstack/proto/ingest/v1;ingestv1b\x06proto3"
+
+var (
+	file_ingest_v1_ingest_proto_rawDescOnce sync.Once
+	file_ingest_v1_ingest_proto_rawDescData []byte
+)
+
+func file_ingest_v1_ingest_proto_rawDescGZIP() []byte {
+	file_ingest_v1_ingest_proto_rawDescOnce.Do(func() {
+		file_ingest_v1_ingest_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_ingest_v1_ingest_proto_rawDesc), len(file_ingest_v1_ingest_proto_rawDesc)))
+	})
+	return file_ingest_v1_ingest_proto_rawDescData
+}
+
+var file_ingest_v1_ingest_proto_enumTypes = make([]protoimpl.EnumInfo, 1)
+var file_ingest_v1_ingest_proto_msgTypes = make([]protoimpl.MessageInfo, 4)
+var file_ingest_v1_ingest_proto_goTypes = []any{
+	(LinkStatus)(0),                   // 0: keepstack.ingest.v1.LinkStatus
+	(*UpdateLinkStatusRequest)(nil),   // 1: keepstack.ingest.v1.UpdateLinkStatusRequest
+	(*UpdateLinkStatusResponse)(nil),  // 2: keepstack.ingest.v1.UpdateLinkStatusResponse
+	(*ReportDiagnosticsRequest)(nil),  // 3: keepstack.ingest.v1.ReportDiagnosticsRequest
+	(*ReportDiagnosticsResponse)(nil), // 4: keepstack.ingest.v1.ReportDiagnosticsResponse
+	(*durationpb.Duration)(nil),       // 5: google.protobuf.Duration
+}
+var file_ingest_v1_ingest_proto_depIdxs = []int32{
+	0, // 0: keepstack.ingest.v1.UpdateLinkStatusRequest.status:type_name -> keepstack.ingest.v1.LinkStatus
+	5, // 1: keepstack.ingest.v1.ReportDiagnosticsRequest.fetch_duration:type_name -> google.protobuf.Duration
+	5, // 2: keepstack.ingest.v1.ReportDiagnosticsRequest.parse_duration:type_name -> google.protobuf.Duration
+	1, // 3: keepstack.ingest.v1.IngestService.UpdateLinkStatus:input_type -> keepstack.ingest.v1.UpdateLinkStatusRequest
+	3, // 4: keepstack.ingest.v1.IngestService.ReportDiagnostics:input_type -> keepstack.ingest.v1.ReportDiagnosticsRequest
+	2, // 5: keepstack.ingest.v1.IngestService.UpdateLinkStatus:output_type -> keepstack.ingest.v1.UpdateLinkStatusResponse
+	4, // 6: keepstack.ingest.v1.IngestService.ReportDiagnostics:output_type -> keepstack.ingest.v1.ReportDiagnosticsResponse
+	5, // [5:7] is the sub-list for method output_type
+	3, // [3:5] is the sub-list for method input_type
+	3, // [3:3] is the sub-list for extension type_name
+	3, // [3:3] is the sub-list for extension extendee
+	0, // [0:3] is the sub-list for field type_name
+}
+
+func init() { file_ingest_v1_ingest_proto_init() }
+func file_ingest_v1_ingest_proto_init() {
+	if File_ingest_v1_ingest_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_ingest_v1_ingest_proto_rawDesc), len(file_ingest_v1_ingest_proto_rawDesc)),
+			NumEnums:      1,
+			NumMessages:   4,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_ingest_v1_ingest_proto_goTypes,
+		DependencyIndexes: file_ingest_v1_ingest_proto_depIdxs,
+		EnumInfos:         file_ingest_v1_ingest_proto_enumTypes,
+		MessageInfos:      file_ingest_v1_ingest_proto_msgTypes,
+	}.Build()
+	File_ingest_v1_ingest_proto = out.File
+	file_ingest_v1_ingest_proto_goTypes = nil
+	file_ingest_v1_ingest_proto_depIdxs = nil
+}
@@ -0,0 +1,169 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             (unknown)
+// source: ingest/v1/ingest.proto
+
+package ingestv1
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	IngestService_UpdateLinkStatus_FullMethodName  = "/keepstack.ingest.v1.IngestService/UpdateLinkStatus"
+	IngestService_ReportDiagnostics_FullMethodName = "/keepstack.ingest.v1.IngestService/ReportDiagnostics"
+)
+
+// IngestServiceClient is the client API for IngestService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// IngestService lets the worker report ingest progress to the API, which owns
+// the status records and relays changes to connected clients.
+type IngestServiceClient interface {
+	// UpdateLinkStatus records the pipeline stage a link has reached.
+	UpdateLinkStatus(ctx context.Context, in *UpdateLinkStatusRequest, opts ...grpc.CallOption) (*UpdateLinkStatusResponse, error)
+	// ReportDiagnostics records fetch and parse measurements for a job.
+	ReportDiagnostics(ctx context.Context, in *ReportDiagnosticsRequest, opts ...grpc.CallOption) (*ReportDiagnosticsResponse, error)
+}
+
+type ingestServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewIngestServiceClient(cc grpc.ClientConnInterface) IngestServiceClient {
+	return &ingestServiceClient{cc}
+}
+
+func (c *ingestServiceClient) UpdateLinkStatus(ctx context.Context, in *UpdateLinkStatusRequest, opts ...grpc.CallOption) (*UpdateLinkStatusResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(UpdateLinkStatusResponse)
+	err := c.cc.Invoke(ctx, IngestService_UpdateLinkStatus_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *ingestServiceClient) ReportDiagnostics(ctx context.Context, in *ReportDiagnosticsRequest, opts ...grpc.CallOption) (*ReportDiagnosticsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ReportDiagnosticsResponse)
+	err := c.cc.Invoke(ctx, IngestService_ReportDiagnostics_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// IngestServiceServer is the server API for IngestService service.
+// All implementations must embed UnimplementedIngestServiceServer
+// for forward compatibility.
+//
+// IngestService lets the worker report ingest progress to the API, which owns
+// the status records and relays changes to connected clients.
+type IngestServiceServer interface {
+	// UpdateLinkStatus records the pipeline stage a link has reached.
+	UpdateLinkStatus(context.Context, *UpdateLinkStatusRequest) (*UpdateLinkStatusResponse, error)
+	// ReportDiagnostics records fetch and parse measurements for a job.
+	ReportDiagnostics(context.Context, *ReportDiagnosticsRequest) (*ReportDiagnosticsResponse, error)
+	mustEmbedUnimplementedIngestServiceServer()
+}
+
+// UnimplementedIngestServiceServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedIngestServiceServer struct{}
+
+func (UnimplementedIngestServiceServer) UpdateLinkStatus(context.Context, *UpdateLinkStatusRequest) (*UpdateLinkStatusResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UpdateLinkStatus not implemented")
+}
+func (UnimplementedIngestServiceServer) ReportDiagnostics(context.Context, *ReportDiagnosticsRequest) (*ReportDiagnosticsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ReportDiagnostics not implemented")
+}
+func (UnimplementedIngestServiceServer) mustEmbedUnimplementedIngestServiceServer() {}
+func (UnimplementedIngestServiceServer) testEmbeddedByValue()                       {}
+
+// UnsafeIngestServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to IngestServiceServer will
+// result in compilation errors.
+type UnsafeIngestServiceServer interface {
+	mustEmbedUnimplementedIngestServiceServer()
+}
+
+func RegisterIngestServiceServer(s grpc.ServiceRegistrar, srv IngestServiceServer) {
+	// If the following call pancis, it indicates UnimplementedIngestServiceServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&IngestService_ServiceDesc, srv)
+}
+
+func _IngestService_UpdateLinkStatus_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UpdateLinkStatusRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(IngestServiceServer).UpdateLinkStatus(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: IngestService_UpdateLinkStatus_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(IngestServiceServer).UpdateLinkStatus(ctx, req.(*UpdateLinkStatusRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _IngestService_ReportDiagnostics_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ReportDiagnosticsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(IngestServiceServer).ReportDiagnostics(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: IngestService_ReportDiagnostics_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(IngestServiceServer).ReportDiagnostics(ctx, req.(*ReportDiagnosticsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// IngestService_ServiceDesc is the grpc.ServiceDesc for IngestService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var IngestService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "keepstack.ingest.v1.IngestService",
+	HandlerType: (*IngestServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "UpdateLinkStatus",
+			Handler:    _IngestService_UpdateLinkStatus_Handler,
+		},
+		{
+			MethodName: "ReportDiagnostics",
+			Handler:    _IngestService_ReportDiagnostics_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "ingest/v1/ingest.proto",
+}
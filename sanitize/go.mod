@@ -0,0 +1,11 @@
+module github.com/example/keepstack/sanitize
+
+go 1.25
+
+require github.com/microcosm-cc/bluemonday v1.0.26
+
+require (
+	github.com/aymerick/douceur v0.2.0 // indirect
+	github.com/gorilla/css v1.0.0 // indirect
+	golang.org/x/net v0.43.0 // indirect
+)
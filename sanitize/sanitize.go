@@ -0,0 +1,201 @@
+// Package sanitize builds the HTML policy archived pages are cleaned with.
+// The worker sanitizes extracted article HTML before storing it and the API
+// sanitizes it again before the reader renders it, so both build the policy
+// from the same Options, read from the SANITIZE_* settings.
+package sanitize
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/microcosm-cc/bluemonday"
+)
+
+// Features an instance can allow on top of text, links, and lists.
+const (
+	// FeatureTables keeps tables.
+	FeatureTables = "tables"
+	// FeatureCode keeps code blocks and their language classes, such as
+	// class="language-go", so the reader can highlight them.
+	FeatureCode = "code"
+	// FeatureImages keeps images, rewritten through ImageProxy when set.
+	FeatureImages = "images"
+	// FeatureEmbeds keeps iframes from EmbedHosts, such as video players.
+	FeatureEmbeds = "embeds"
+)
+
+// DefaultFeatures keeps what archives were sanitized with before the policy
+// was configurable.
+var DefaultFeatures = []string{FeatureTables, FeatureCode, FeatureImages}
+
+// DefaultEmbedHosts are the iframe hosts FeatureEmbeds allows when EmbedHosts
+// is empty.
+var DefaultEmbedHosts = []string{"www.youtube.com", "www.youtube-nocookie.com", "player.vimeo.com"}
+
+// ProxyPlaceholder is replaced in ImageProxy with the query-escaped image
+// URL.
+const ProxyPlaceholder = "{url}"
+
+var knownFeatures = []string{FeatureTables, FeatureCode, FeatureImages, FeatureEmbeds}
+
+// codeClass matches the language classes highlighters put on code blocks.
+var codeClass = regexp.MustCompile(`^(?:lang|language)-[\w+#-]+$`)
+
+// allowFullscreen matches the values players set allowfullscreen to.
+var allowFullscreen = regexp.MustCompile(`(?i)^(?:|allowfullscreen|true)$`)
+
+// Options selects what archived HTML keeps beyond text.
+type Options struct {
+	// Features lists the Feature constants to allow.
+	Features []string
+	// ImageProxy, when set, is a URL template such as
+	// "https://images.example.com/proxy?url={url}" that image sources are
+	// rewritten to, so readers never load images from the original site.
+	ImageProxy string
+	// EmbedHosts lists the hosts iframes may load over https when
+	// FeatureEmbeds is on. Empty means DefaultEmbedHosts.
+	EmbedHosts []string
+}
+
+// Validate reports unknown features and malformed proxy templates.
+func (o Options) Validate() error {
+	for _, feature := range o.Features {
+		if f := normalize(feature); f != "" && !contains(knownFeatures, f) {
+			return fmt.Errorf("unknown sanitize feature %q, want one of %s", feature, strings.Join(knownFeatures, ", "))
+		}
+	}
+	if o.ImageProxy != "" {
+		if !strings.Contains(o.ImageProxy, ProxyPlaceholder) {
+			return fmt.Errorf("image proxy %q must contain %s", o.ImageProxy, ProxyPlaceholder)
+		}
+		proxy, err := url.Parse(strings.ReplaceAll(o.ImageProxy, ProxyPlaceholder, ""))
+		if err != nil || (proxy.Scheme != "http" && proxy.Scheme != "https") || proxy.Host == "" {
+			return fmt.Errorf("image proxy %q must be an absolute http or https URL", o.ImageProxy)
+		}
+	}
+	for _, host := range o.EmbedHosts {
+		host = normalize(host)
+		if host == "" || strings.ContainsAny(host, "/:*") {
+			return fmt.Errorf("embed host %q must be a bare host name", host)
+		}
+	}
+	return nil
+}
+
+// Has reports whether feature is allowed.
+func (o Options) Has(feature string) bool {
+	for _, f := range o.Features {
+		if normalize(f) == feature {
+			return true
+		}
+	}
+	return false
+}
+
+// Hosts returns the hosts embeds may load from, or nil when embeds are off.
+func (o Options) Hosts() []string {
+	if !o.Has(FeatureEmbeds) {
+		return nil
+	}
+	hosts := make([]string, 0, len(o.EmbedHosts))
+	for _, host := range o.EmbedHosts {
+		if host = normalize(host); host != "" {
+			hosts = append(hosts, host)
+		}
+	}
+	if len(hosts) == 0 {
+		return DefaultEmbedHosts
+	}
+	return hosts
+}
+
+// ProxyOrigin returns the scheme and host of ImageProxy, or "" when images
+// are not proxied.
+func (o Options) ProxyOrigin() string {
+	if o.ImageProxy == "" || !o.Has(FeatureImages) {
+		return ""
+	}
+	proxy, err := url.Parse(strings.ReplaceAll(o.ImageProxy, ProxyPlaceholder, ""))
+	if err != nil || proxy.Host == "" {
+		return ""
+	}
+	return proxy.Scheme + "://" + proxy.Host
+}
+
+// Policy builds the bluemonday policy for o. Policies are safe for
+// concurrent use, so callers build one and keep it.
+func (o Options) Policy() *bluemonday.Policy {
+	p := bluemonday.NewPolicy()
+	p.AllowStandardAttributes()
+	p.AllowStandardURLs()
+
+	p.AllowElements("article", "aside", "figure", "figcaption", "section", "summary", "hgroup")
+	p.AllowAttrs("open").Matching(regexp.MustCompile(`(?i)^(|open)$`)).OnElements("details")
+	p.AllowElements("h1", "h2", "h3", "h4", "h5", "h6")
+	p.AllowAttrs("cite").OnElements("blockquote", "q")
+	p.AllowElements("br", "div", "hr", "p", "span", "wbr")
+	p.AllowAttrs("href").OnElements("a")
+	p.AllowElements("abbr", "acronym", "cite", "dfn", "em", "mark", "s", "strong", "sub", "sup", "var")
+	p.AllowAttrs("datetime").Matching(bluemonday.ISO8601).OnElements("time", "del", "ins")
+	p.AllowElements("b", "i", "small", "strike", "u", "rp", "rt", "ruby")
+	p.AllowAttrs("dir").Matching(bluemonday.Direction).OnElements("bdi", "bdo")
+	p.AllowLists()
+
+	if o.Has(FeatureTables) {
+		p.AllowTables()
+	}
+	if o.Has(FeatureCode) {
+		p.AllowElements("pre", "code", "kbd", "samp", "tt")
+		p.AllowAttrs("class").Matching(codeClass).OnElements("pre", "code")
+	}
+	if o.Has(FeatureImages) {
+		p.AllowImages()
+	}
+	hosts := o.Hosts()
+	if len(hosts) > 0 {
+		quoted := make([]string, len(hosts))
+		for i, host := range hosts {
+			quoted[i] = regexp.QuoteMeta(host)
+		}
+		p.AllowAttrs("src").Matching(regexp.MustCompile(`^https://(?:` + strings.Join(quoted, "|") + `)/`)).OnElements("iframe")
+		p.AllowAttrs("width", "height").Matching(bluemonday.NumberOrPercent).OnElements("iframe")
+		p.AllowAttrs("title").Matching(bluemonday.Paragraph).OnElements("iframe")
+		p.AllowAttrs("allowfullscreen").Matching(allowFullscreen).OnElements("iframe")
+	}
+	if origin := o.ProxyOrigin(); origin != "" {
+		p.RewriteSrc(o.proxyImage(origin, hosts))
+	}
+	return p
+}
+
+// proxyImage returns the RewriteSrc hook sending absolute image URLs through
+// ImageProxy. bluemonday runs it for every src, so iframes from embed hosts
+// are left alone, and URLs already on the proxy are too, which keeps
+// sanitizing stored HTML a second time a no-op.
+func (o Options) proxyImage(origin string, embedHosts []string) func(*url.URL) {
+	return func(u *url.URL) {
+		if !u.IsAbs() || u.Scheme+"://"+u.Host == origin || contains(embedHosts, strings.ToLower(u.Host)) {
+			return
+		}
+		proxied, err := url.Parse(strings.ReplaceAll(o.ImageProxy, ProxyPlaceholder, url.QueryEscape(u.String())))
+		if err != nil {
+			return
+		}
+		*u = *proxied
+	}
+}
+
+func normalize(s string) string {
+	return strings.ToLower(strings.TrimSpace(s))
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,136 @@
+package sanitize
+
+import (
+	"strings"
+	"testing"
+)
+
+const article = `<h1>Title</h1>
+<p>Intro with <a href="https://example.com/more">a link</a>.</p>
+<table><tr><td>cell</td></tr></table>
+<pre><code class="language-go">fmt.Println("hi")</code></pre>
+<img src="https://cdn.example.com/cat.png" alt="cat">
+<iframe src="https://www.youtube.com/embed/abc" width="560" height="315" allowfullscreen></iframe>
+<iframe src="https://tracker.example.net/frame"></iframe>
+<script>alert(1)</script>`
+
+func TestPolicyFeatures(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name     string
+		options  Options
+		want     []string
+		dontWant []string
+	}{
+		{
+			name:     "defaults",
+			options:  Options{Features: DefaultFeatures},
+			want:     []string{"<table>", `<code class="language-go">`, `<img src="https://cdn.example.com/cat.png"`},
+			dontWant: []string{"<iframe", "<script", "alert"},
+		},
+		{
+			name:     "text only",
+			options:  Options{},
+			want:     []string{"<h1>Title</h1>", `<a href="https://example.com/more"`, "cell", `fmt.Println(&#34;hi&#34;)`},
+			dontWant: []string{"<table>", "<pre>", "<img", "<iframe"},
+		},
+		{
+			name:     "embeds",
+			options:  Options{Features: []string{FeatureEmbeds}},
+			want:     []string{`<iframe src="https://www.youtube.com/embed/abc" width="560" height="315" allowfullscreen="">`},
+			dontWant: []string{"tracker.example.net", "<img"},
+		},
+		{
+			name:     "embeds from configured hosts",
+			options:  Options{Features: []string{" Embeds "}, EmbedHosts: []string{"tracker.example.net"}},
+			want:     []string{`<iframe src="https://tracker.example.net/frame">`},
+			dontWant: []string{"youtube"},
+		},
+		{
+			name: "proxied images",
+			options: Options{
+				Features:   []string{FeatureImages, FeatureEmbeds},
+				ImageProxy: "https://images.example.org/proxy?url={url}",
+			},
+			want: []string{
+				`<img src="https://images.example.org/proxy?url=https%3A%2F%2Fcdn.example.com%2Fcat.png"`,
+				`<iframe src="https://www.youtube.com/embed/abc"`,
+			},
+			dontWant: []string{`src="https://cdn.example.com`},
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			got := tc.options.Policy().Sanitize(article)
+			for _, want := range tc.want {
+				if !strings.Contains(got, want) {
+					t.Errorf("expected %q in %s", want, got)
+				}
+			}
+			for _, dontWant := range tc.dontWant {
+				if strings.Contains(got, dontWant) {
+					t.Errorf("did not expect %q in %s", dontWant, got)
+				}
+			}
+		})
+	}
+}
+
+func TestPolicyIsIdempotent(t *testing.T) {
+	t.Parallel()
+
+	policy := Options{
+		Features:   []string{FeatureTables, FeatureCode, FeatureImages, FeatureEmbeds},
+		ImageProxy: "https://images.example.org/proxy?url={url}",
+	}.Policy()
+
+	once := policy.Sanitize(article)
+	if twice := policy.Sanitize(once); twice != once {
+		t.Fatalf("sanitizing twice changed the html:\n%s\n%s", once, twice)
+	}
+}
+
+func TestOptionsValidate(t *testing.T) {
+	t.Parallel()
+
+	valid := []Options{
+		{Features: DefaultFeatures},
+		{Features: []string{"Tables", " embeds"}, EmbedHosts: []string{"player.example.com"}},
+		{ImageProxy: "http://imgproxy:8080/plain/{url}"},
+	}
+	for _, options := range valid {
+		if err := options.Validate(); err != nil {
+			t.Errorf("Validate(%+v) = %v", options, err)
+		}
+	}
+
+	invalid := []Options{
+		{Features: []string{"scripts"}},
+		{ImageProxy: "https://images.example.org/proxy"},
+		{ImageProxy: "/proxy?url={url}"},
+		{EmbedHosts: []string{"https://www.youtube.com"}},
+	}
+	for _, options := range invalid {
+		if err := options.Validate(); err == nil {
+			t.Errorf("Validate(%+v) succeeded, want an error", options)
+		}
+	}
+}
+
+func TestProxyOrigin(t *testing.T) {
+	t.Parallel()
+
+	options := Options{Features: []string{FeatureImages}, ImageProxy: "https://images.example.org/proxy?url={url}"}
+	if got := options.ProxyOrigin(); got != "https://images.example.org" {
+		t.Fatalf("ProxyOrigin = %q", got)
+	}
+	options.Features = nil
+	if got := options.ProxyOrigin(); got != "" {
+		t.Fatalf("ProxyOrigin without images = %q, want empty", got)
+	}
+}
@@ -9,7 +9,9 @@ fi
 BACKUP_PATH=${1:-}
 if [[ -z "$BACKUP_PATH" ]]; then
   BACKUP_DIR=${BACKUP_DIR:-/backups}
-  BACKUP_PATH=$(ls -1t "$BACKUP_DIR"/keepstack-*.sql.gz 2>/dev/null | head -n1 || true)
+  # Incremental backups only apply on top of their full backup; use
+  # `cron restore` to replay a chain.
+  BACKUP_PATH=$(ls -1t "$BACKUP_DIR"/keepstack-*.sql.gz 2>/dev/null | grep -v '\.incr\.sql\.gz$' | head -n1 || true)
 fi
 
 if [[ -z "$BACKUP_PATH" ]]; then
@@ -88,7 +88,7 @@ spec:
 YAML
 kubectl -n "${SRC_NS}" wait --for=condition=Ready "pod/${SRC_HELPER_POD}" --timeout=120s >/dev/null
 
-BACKUP_PATH=$(kubectl -n "${SRC_NS}" exec "${SRC_HELPER_POD}" -- sh -c "ls -1t ${BACKUP_DIR}/keepstack-*.sql.gz 2>/dev/null | head -n1" | tr -d '\r')
+BACKUP_PATH=$(kubectl -n "${SRC_NS}" exec "${SRC_HELPER_POD}" -- sh -c "ls -1t ${BACKUP_DIR}/keepstack-*.sql.gz 2>/dev/null | grep -v '\\.incr\\.sql\\.gz\$' | head -n1" | tr -d '\r')
 if [[ -z "${BACKUP_PATH}" ]]; then
   echo "no backup files found in ${SRC_NS}:${BACKUP_DIR}" >&2
   exit 1
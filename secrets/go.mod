@@ -0,0 +1,3 @@
+module github.com/example/keepstack/secrets
+
+go 1.25
@@ -0,0 +1,212 @@
+// Package secrets fills environment variables from the secret sources the
+// API and worker support, before their configuration is loaded: files named
+// by a _FILE variable, a dotenv file, and Vault.
+package secrets
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// defaultServiceAccountToken is the projected token Vault's Kubernetes auth
+// method verifies.
+const defaultServiceAccountToken = "/var/run/secrets/kubernetes.io/serviceaccount/token"
+
+// Load fills unset environment variables from secret sources, in order of
+// precedence:
+//
+//   - NAME_FILE for each of names, a file holding the value.
+//   - SECRETS_ENV_FILE, a dotenv file of NAME=value lines, such as the
+//     output of `sops -d --output-type dotenv` written to a shared volume.
+//   - VAULT_ADDR and VAULT_SECRET_PATH, a Vault KV v2 secret whose keys are
+//     variable names, read with VAULT_TOKEN (or VAULT_TOKEN_FILE) or by
+//     logging in as VAULT_KUBERNETES_ROLE.
+//
+// Variables set directly always win. The dotenv file and Vault secret may
+// set any variable; names only limits which ones are read from _FILE paths.
+func Load(ctx context.Context, names []string) error {
+	for _, name := range names {
+		path := os.Getenv(name + "_FILE")
+		if path == "" || os.Getenv(name) != "" {
+			continue
+		}
+		value, err := readSecretFile(path)
+		if err != nil {
+			return fmt.Errorf("%s_FILE: %w", name, err)
+		}
+		os.Setenv(name, value)
+	}
+
+	if path := os.Getenv("SECRETS_ENV_FILE"); path != "" {
+		values, err := readEnvFile(path)
+		if err != nil {
+			return fmt.Errorf("SECRETS_ENV_FILE: %w", err)
+		}
+		setUnset(values)
+	}
+
+	if os.Getenv("VAULT_ADDR") != "" && os.Getenv("VAULT_SECRET_PATH") != "" {
+		ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+		defer cancel()
+		values, err := readVaultSecret(ctx, http.DefaultClient)
+		if err != nil {
+			return fmt.Errorf("vault: %w", err)
+		}
+		setUnset(values)
+	}
+	return nil
+}
+
+// readSecretFile returns the file's contents without the trailing newline
+// editors and `kubectl create secret --from-file` tend to leave.
+func readSecretFile(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(string(data), "\r\n"), nil
+}
+
+// readEnvFile parses NAME=value lines, skipping blanks and # comments and
+// unquoting values wrapped in single or double quotes.
+func readEnvFile(path string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	values := make(map[string]string)
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		name, value, ok := strings.Cut(strings.TrimPrefix(text, "export "), "=")
+		if !ok || strings.TrimSpace(name) == "" {
+			return nil, fmt.Errorf("line %d: expected NAME=value", line)
+		}
+		value = strings.TrimSpace(value)
+		if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+			value = value[1 : len(value)-1]
+		}
+		values[strings.TrimSpace(name)] = value
+	}
+	return values, scanner.Err()
+}
+
+// readVaultSecret reads the KV v2 secret at VAULT_SECRET_PATH, e.g.
+// "secret/data/keepstack".
+func readVaultSecret(ctx context.Context, client *http.Client) (map[string]string, error) {
+	addr := strings.TrimRight(os.Getenv("VAULT_ADDR"), "/")
+
+	token := os.Getenv("VAULT_TOKEN")
+	if token == "" {
+		if path := os.Getenv("VAULT_TOKEN_FILE"); path != "" {
+			value, err := readSecretFile(path)
+			if err != nil {
+				return nil, fmt.Errorf("read VAULT_TOKEN_FILE: %w", err)
+			}
+			token = value
+		}
+	}
+	if token == "" {
+		role := os.Getenv("VAULT_KUBERNETES_ROLE")
+		if role == "" {
+			return nil, fmt.Errorf("set VAULT_TOKEN, VAULT_TOKEN_FILE, or VAULT_KUBERNETES_ROLE")
+		}
+		var err error
+		token, err = vaultKubernetesLogin(ctx, client, addr, role)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var secret struct {
+		Data struct {
+			Data map[string]any `json:"data"`
+		} `json:"data"`
+	}
+	path := strings.Trim(os.Getenv("VAULT_SECRET_PATH"), "/")
+	if err := vaultRequest(ctx, client, http.MethodGet, addr+"/v1/"+path, token, nil, &secret); err != nil {
+		return nil, fmt.Errorf("read %s: %w", path, err)
+	}
+	values := make(map[string]string, len(secret.Data.Data))
+	for name, value := range secret.Data.Data {
+		values[name] = fmt.Sprint(value)
+	}
+	return values, nil
+}
+
+// vaultKubernetesLogin exchanges the pod's service account token for a
+// Vault token through the Kubernetes auth method mounted at
+// VAULT_KUBERNETES_MOUNT (default "kubernetes").
+func vaultKubernetesLogin(ctx context.Context, client *http.Client, addr, role string) (string, error) {
+	tokenPath := os.Getenv("VAULT_KUBERNETES_TOKEN_PATH")
+	if tokenPath == "" {
+		tokenPath = defaultServiceAccountToken
+	}
+	jwt, err := readSecretFile(tokenPath)
+	if err != nil {
+		return "", fmt.Errorf("read service account token: %w", err)
+	}
+	mount := os.Getenv("VAULT_KUBERNETES_MOUNT")
+	if mount == "" {
+		mount = "kubernetes"
+	}
+
+	body, err := json.Marshal(map[string]string{"role": role, "jwt": jwt})
+	if err != nil {
+		return "", err
+	}
+	var login struct {
+		Auth struct {
+			ClientToken string `json:"client_token"`
+		} `json:"auth"`
+	}
+	if err := vaultRequest(ctx, client, http.MethodPost, addr+"/v1/auth/"+strings.Trim(mount, "/")+"/login", "", body, &login); err != nil {
+		return "", fmt.Errorf("kubernetes login as %s: %w", role, err)
+	}
+	if login.Auth.ClientToken == "" {
+		return "", fmt.Errorf("kubernetes login as %s returned no token", role)
+	}
+	return login.Auth.ClientToken, nil
+}
+
+func vaultRequest(ctx context.Context, client *http.Client, method, url, token string, body []byte, out any) error {
+	req, err := http.NewRequestWithContext(ctx, method, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	if token != "" {
+		req.Header.Set("X-Vault-Token", token)
+	}
+	if namespace := os.Getenv("VAULT_NAMESPACE"); namespace != "" {
+		req.Header.Set("X-Vault-Namespace", namespace)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+		return fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+func setUnset(values map[string]string) {
+	for name, value := range values {
+		if os.Getenv(name) == "" {
+			os.Setenv(name, value)
+		}
+	}
+}
@@ -16,13 +16,16 @@ import (
 	"testing"
 	"time"
 
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
 	"k8s.io/client-go/tools/portforward"
+	"k8s.io/client-go/tools/remotecommand"
 	"k8s.io/client-go/transport/spdy"
 )
 
@@ -221,6 +224,32 @@ func (c *Config) StartPortForward(ctx context.Context, kube *Kube, namespace, po
 	return &PortForwardHandle{stopCh: stopCh, errCh: errCh, once: &sync.Once{}}, nil
 }
 
+// Exec runs command in a container of the provided pod and returns its
+// standard output. A non-zero exit is returned as an error carrying stderr.
+func (c *Config) Exec(ctx context.Context, kube *Kube, namespace, pod, container string, command []string) (string, error) {
+	req := kube.Client.CoreV1().RESTClient().Post().
+		Resource("pods").
+		Namespace(namespace).
+		Name(pod).
+		SubResource("exec").
+		VersionedParams(&corev1.PodExecOptions{
+			Container: container,
+			Command:   command,
+			Stdout:    true,
+			Stderr:    true,
+		}, scheme.ParameterCodec)
+
+	executor, err := remotecommand.NewSPDYExecutor(kube.Config, "POST", req.URL())
+	if err != nil {
+		return "", fmt.Errorf("build exec request: %w", err)
+	}
+	var stdout, stderr strings.Builder
+	if err := executor.StreamWithContext(ctx, remotecommand.StreamOptions{Stdout: &stdout, Stderr: &stderr}); err != nil {
+		return stdout.String(), fmt.Errorf("exec in %s: %w: %s", pod, err, strings.TrimSpace(stderr.String()))
+	}
+	return stdout.String(), nil
+}
+
 // PortForwardHandle manages lifecycle of a port forward.
 type PortForwardHandle struct {
 	stopCh chan struct{}
@@ -29,6 +29,16 @@ func TestSmokeSuite(t *testing.T) {
 
 	ctx := context.Background()
 
+	keepFixtures := parseBoolEnv("SMOKE_KEEP_FIXTURES", false)
+	if !keepFixtures {
+		// Catches fixtures left behind when a subtest fails before teardown.
+		t.Cleanup(func() {
+			for _, err := range scenario.deleteFixtures(context.Background()) {
+				t.Logf("cleanup: %v", err)
+			}
+		})
+	}
+
 	t.Run("health and readiness", func(t *testing.T) {
 		checkHealthEndpoint(t, ctx, cfg, "/healthz")
 		checkHealthEndpoint(t, ctx, cfg, "/api/healthz")
@@ -62,10 +72,28 @@ func TestSmokeSuite(t *testing.T) {
 		scenario.runBackupTrigger(t, ctx)
 	})
 
+	t.Run("backup restore verification", func(t *testing.T) {
+		cfg.SkipUnlessTagged(t, "restore")
+		scenario.runRestoreVerification(t, ctx)
+	})
+
 	t.Run("resurfacer recommendations", func(t *testing.T) {
 		cfg.SkipUnlessTagged(t, "resurfacer")
 		scenario.runResurfacerChecks(t, ctx)
 	})
+
+	t.Run("queue durability", func(t *testing.T) {
+		cfg.SkipUnlessTagged(t, "queue")
+		scenario.runQueueDurability(t, ctx)
+	})
+
+	t.Run("fixture teardown", func(t *testing.T) {
+		if keepFixtures {
+			t.Skip("skipping teardown; SMOKE_KEEP_FIXTURES is set")
+		}
+		scenario.requireLink(t)
+		scenario.runTeardown(t, ctx)
+	})
 }
 
 type scenarioState struct {
@@ -80,6 +108,8 @@ type scenarioState struct {
 	linkTitle string
 	query     string
 
+	queueLinkURL string
+
 	tagPrimaryName   string
 	tagSecondaryName string
 	tagExtraName     string
@@ -111,6 +141,7 @@ func newScenario(cfg *smoke.Config) *scenarioState {
 		linkURL:          getenv("SMOKE_LINK_URL", fmt.Sprintf("https://example.com/keepstack/%s", slug)),
 		linkTitle:        getenv("SMOKE_LINK_TITLE", fmt.Sprintf("Keepstack Smoke %s", slug)),
 		query:            getenv("SMOKE_QUERY", slug),
+		queueLinkURL:     getenv("SMOKE_QUEUE_LINK_URL", fmt.Sprintf("https://example.com/?keepstack=%s-queue", slug)),
 		tagPrimaryName:   getenv("SMOKE_TAG_NAME_PRIMARY", fmt.Sprintf("Smoke Primary %s", slug)),
 		tagSecondaryName: getenv("SMOKE_TAG_NAME_SECONDARY", fmt.Sprintf("Smoke Secondary %s", slug)),
 		tagExtraName:     getenv("SMOKE_TAG_NAME_EXTRA", fmt.Sprintf("Smoke Extra %s", slug)),
@@ -422,6 +453,193 @@ func (s *scenarioState) runBackupTrigger(t *testing.T, ctx context.Context) {
 			t.Fatalf("stream backup logs: %v", err)
 		}
 	}
+
+	status, body, err := s.cfg.DoJSON(ctx, http.MethodGet, "/api/admin/backups", url.Values{"limit": {"1"}}, nil)
+	if err != nil {
+		t.Fatalf("list backups failed: %v", err)
+	}
+	if status != http.StatusOK {
+		t.Fatalf("list backups status %d: %s", status, string(body))
+	}
+	var runs struct {
+		Items []struct {
+			Status   string `json:"status"`
+			Checksum string `json:"checksum"`
+		} `json:"items"`
+	}
+	if err := json.Unmarshal(body, &runs); err != nil {
+		t.Fatalf("decode backups: %v", err)
+	}
+	if len(runs.Items) == 0 || runs.Items[0].Status != "success" || runs.Items[0].Checksum == "" {
+		t.Fatalf("latest backup run not recorded as successful: %s", string(body))
+	}
+}
+
+// restoreTables are compared between the live database and the restored
+// copy.
+var restoreTables = []string{"users", "links", "archives", "tags", "link_tags", "highlights"}
+
+// runRestoreVerification takes a fresh backup, restores it with the cron
+// restore subcommand into a scratch database on the same Postgres server,
+// and checks every table in restoreTables holds as many rows as the source,
+// proving the backups can actually be restored.
+func (s *scenarioState) runRestoreVerification(t *testing.T, ctx context.Context) {
+	kube := s.cfg.KubeOrSkip(t)
+
+	selector := fmt.Sprintf("app.kubernetes.io/instance=%s,app.kubernetes.io/component=backup", s.cfg.Release)
+	cron, err := firstCronJob(ctx, kube, s.cfg.Namespace, selector)
+	if err != nil {
+		t.Fatalf("backup CronJob lookup failed: %v", err)
+	}
+	timeout := durationEnv("KS_BACKUP_TIMEOUT", 10*time.Minute)
+	stamp := time.Now().Unix()
+
+	backupJob := s.runJob(t, ctx, kube, jobFromCronTemplate(fmt.Sprintf("%s-verify-%d", cron.Name, stamp), cron), timeout)
+	t.Logf("backup job %s completed", backupJob)
+
+	postgresSelector := fmt.Sprintf("app.kubernetes.io/instance=%s,app.kubernetes.io/component=postgres", s.cfg.Release)
+	pods, err := kube.Client.CoreV1().Pods(s.cfg.Namespace).List(ctx, metav1.ListOptions{LabelSelector: postgresSelector})
+	if err != nil {
+		t.Fatalf("list postgres pods failed: %v", err)
+	}
+	pgPod := pickRunningPod(t, pods.Items)
+	if pgPod == nil {
+		t.Fatalf("no running postgres pod for selector %s", postgresSelector)
+	}
+	// psql runs as the pod's own superuser against database, or the
+	// application database when it is empty.
+	psql := func(ctx context.Context, database, sql string) (string, error) {
+		return s.cfg.Exec(ctx, kube, s.cfg.Namespace, pgPod.Name, pgPod.Spec.Containers[0].Name, []string{
+			"sh", "-c", `psql -U "$POSTGRES_USER" -d "${1:-$POSTGRES_DB}" -At -v ON_ERROR_STOP=1 -c "$2"`,
+			"psql", database, sql,
+		})
+	}
+
+	want, err := countRows(ctx, psql, "")
+	if err != nil {
+		t.Fatalf("count source rows: %v", err)
+	}
+
+	scratch := fmt.Sprintf("keepstack_restore_%d", stamp)
+	if _, err := psql(ctx, "", "CREATE DATABASE "+scratch); err != nil {
+		t.Fatalf("create scratch database: %v", err)
+	}
+	t.Cleanup(func() {
+		if _, err := psql(context.Background(), "", "DROP DATABASE IF EXISTS "+scratch+" WITH (FORCE)"); err != nil {
+			t.Errorf("drop scratch database %s: %v", scratch, err)
+		}
+	})
+
+	restore := jobFromCronTemplate(fmt.Sprintf("%s-restore-%d", cron.Name, stamp), cron)
+	if err := s.pointRestoreAt(ctx, kube, restore, scratch); err != nil {
+		t.Fatalf("prepare restore job: %v", err)
+	}
+	s.runJob(t, ctx, kube, restore, timeout)
+
+	got, err := countRows(ctx, psql, scratch)
+	if err != nil {
+		t.Fatalf("count restored rows: %v", err)
+	}
+	for _, table := range restoreTables {
+		if got[table] != want[table] {
+			t.Errorf("table %s: restored %d rows, source has %d", table, got[table], want[table])
+		}
+	}
+}
+
+// pointRestoreAt turns a Job cloned from the backup CronJob into one that
+// runs the restore subcommand into database on the same server.
+func (s *scenarioState) pointRestoreAt(ctx context.Context, kube *smoke.Kube, job *batchv1.Job, database string) error {
+	spec := &job.Spec.Template.Spec
+	if len(spec.Containers) == 0 {
+		return fmt.Errorf("backup job template has no containers")
+	}
+	container := &spec.Containers[0]
+
+	var secretName string
+	for _, source := range container.EnvFrom {
+		if source.SecretRef != nil {
+			secretName = source.SecretRef.Name
+		}
+	}
+	if secretName == "" {
+		return fmt.Errorf("backup container does not load DATABASE_URL from a secret")
+	}
+	secret, err := kube.Client.CoreV1().Secrets(s.cfg.Namespace).Get(ctx, secretName, metav1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("read secret %s: %w", secretName, err)
+	}
+	target, err := url.Parse(string(secret.Data["DATABASE_URL"]))
+	if err != nil || target.Host == "" {
+		return fmt.Errorf("secret %s has no usable DATABASE_URL", secretName)
+	}
+	target.Path = "/" + database
+
+	container.Command = []string{"/app/cron", "restore"}
+	container.Args = nil
+	container.Env = append(container.Env, corev1.EnvVar{Name: "DATABASE_URL", Value: target.String()})
+
+	// Logical backups, which the image writes since it ships without
+	// pg_dump, carry data only and restore into a migrated schema.
+	if parseBoolEnv("KS_RESTORE_MIGRATE", true) {
+		migrate := container.DeepCopy()
+		migrate.Name = "migrate"
+		// Nothing serves from the scratch database, so unsafe steps are fine.
+		migrate.Command = []string{"/app/migrate", "up", "--allow-unsafe"}
+		migrate.VolumeMounts = nil
+		spec.InitContainers = append(spec.InitContainers, *migrate)
+	}
+	spec.RestartPolicy = corev1.RestartPolicyNever
+	backoff := int32(0)
+	job.Spec.BackoffLimit = &backoff
+	return nil
+}
+
+// runJob creates job, deletes it when the test ends, and waits for it to
+// complete, logging its output. It returns the created Job's name.
+func (s *scenarioState) runJob(t *testing.T, ctx context.Context, kube *smoke.Kube, job *batchv1.Job, timeout time.Duration) string {
+	t.Helper()
+	created, err := kube.Client.BatchV1().Jobs(s.cfg.Namespace).Create(ctx, job, metav1.CreateOptions{})
+	if err != nil {
+		t.Fatalf("create job %s failed: %v", job.Name, err)
+	}
+	t.Cleanup(func() {
+		propagation := metav1.DeletePropagationBackground
+		_ = kube.Client.BatchV1().Jobs(s.cfg.Namespace).Delete(context.Background(), created.Name, metav1.DeleteOptions{PropagationPolicy: &propagation})
+	})
+	waitErr := waitForJobCompletion(ctx, s.cfg, kube, created.Name, timeout)
+	if err := streamJobLogs(ctx, kube, s.cfg.Namespace, created.Name, t); err != nil {
+		t.Logf("stream logs for %s: %v", created.Name, err)
+	}
+	if waitErr != nil {
+		t.Fatalf("job %s did not complete: %v", created.Name, waitErr)
+	}
+	return created.Name
+}
+
+// countRows returns the row count of each table in restoreTables.
+func countRows(ctx context.Context, psql func(context.Context, string, string) (string, error), database string) (map[string]int64, error) {
+	parts := make([]string, 0, len(restoreTables))
+	for _, table := range restoreTables {
+		parts = append(parts, fmt.Sprintf("SELECT '%s', count(*) FROM %s", table, table))
+	}
+	out, err := psql(ctx, database, strings.Join(parts, " UNION ALL "))
+	if err != nil {
+		return nil, err
+	}
+	counts := make(map[string]int64, len(restoreTables))
+	for _, line := range strings.Split(strings.TrimSpace(out), "\n") {
+		table, raw, ok := strings.Cut(line, "|")
+		if !ok {
+			return nil, fmt.Errorf("unexpected psql output %q", line)
+		}
+		n, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("parse count for %s: %w", table, err)
+		}
+		counts[table] = n
+	}
+	return counts, nil
 }
 
 func (s *scenarioState) runResurfacerChecks(t *testing.T, ctx context.Context) {
@@ -472,6 +690,227 @@ func (s *scenarioState) runResurfacerChecks(t *testing.T, ctx context.Context) {
 	}
 }
 
+// runTeardown deletes the link and tags the scenario created and verifies
+// they no longer show up, so repeated runs leave the environment as they
+// found it.
+func (s *scenarioState) runTeardown(t *testing.T, ctx context.Context) {
+	linkID := s.linkID
+	tagIDs := s.tagIDs()
+
+	if errs := s.deleteFixtures(ctx); len(errs) > 0 {
+		for _, err := range errs {
+			t.Error(err)
+		}
+		t.FailNow()
+	}
+
+	status, body, err := s.cfg.DoJSON(ctx, http.MethodDelete, fmt.Sprintf("%s/%s", s.postPath, linkID), nil, nil)
+	if err != nil {
+		t.Fatalf("repeat link delete failed: %v", err)
+	}
+	if status != http.StatusNotFound {
+		t.Fatalf("repeat link delete status %d, want 404: %s", status, string(body))
+	}
+
+	query := url.Values{}
+	query.Set("q", s.query)
+	query.Set("limit", "5")
+	status, body, err = s.cfg.DoJSON(ctx, http.MethodGet, s.getPath, query, nil)
+	if err != nil {
+		t.Fatalf("post-delete search failed: %v", err)
+	}
+	if status != http.StatusOK {
+		t.Fatalf("post-delete search status %d: %s", status, string(body))
+	}
+	if present, err := linkPresent(body, linkID); err != nil {
+		t.Fatalf("post-delete search decode failed: %v -- %s", err, string(body))
+	} else if present {
+		t.Fatalf("deleted link %s still returned by search", linkID)
+	}
+
+	status, body, err = s.cfg.DoJSON(ctx, http.MethodGet, s.tagPath, nil, nil)
+	if err != nil {
+		t.Fatalf("post-delete tag list failed: %v", err)
+	}
+	if status != http.StatusOK {
+		t.Fatalf("post-delete tag list status %d: %s", status, string(body))
+	}
+	var tags []tagResponse
+	if err := json.Unmarshal(body, &tags); err != nil {
+		t.Fatalf("decode tag list: %v -- %s", err, string(body))
+	}
+	for _, tag := range tags {
+		for _, id := range tagIDs {
+			if tag.ID == id {
+				t.Fatalf("deleted tag %d (%s) still listed", tag.ID, tag.Name)
+			}
+		}
+	}
+}
+
+// deleteFixtures removes whatever the scenario has created so far and
+// forgets it, so calling it again is a no-op. A link or tag that is already
+// gone counts as deleted.
+func (s *scenarioState) deleteFixtures(ctx context.Context) []error {
+	var errs []error
+	if s.linkID != "" {
+		if err := s.deleteFixture(ctx, fmt.Sprintf("%s/%s", s.postPath, s.linkID)); err != nil {
+			errs = append(errs, fmt.Errorf("delete link %s: %w", s.linkID, err))
+		} else {
+			s.linkID = ""
+		}
+	}
+	for _, id := range []*int32{&s.tagPrimaryID, &s.tagSecondaryID, &s.tagExtraID} {
+		if *id == 0 {
+			continue
+		}
+		if err := s.deleteFixture(ctx, fmt.Sprintf("%s/%d", s.tagPath, *id)); err != nil {
+			errs = append(errs, fmt.Errorf("delete tag %d: %w", *id, err))
+		} else {
+			*id = 0
+		}
+	}
+	return errs
+}
+
+func (s *scenarioState) deleteFixture(ctx context.Context, path string) error {
+	status, body, err := s.cfg.DoJSON(ctx, http.MethodDelete, path, nil, nil)
+	if err != nil {
+		return err
+	}
+	if status != http.StatusNoContent && status != http.StatusNotFound {
+		return fmt.Errorf("status %d: %s", status, string(body))
+	}
+	return nil
+}
+
+func (s *scenarioState) tagIDs() []int32 {
+	var ids []int32
+	for _, id := range []int32{s.tagPrimaryID, s.tagSecondaryID, s.tagExtraID} {
+		if id != 0 {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// runQueueDurability saves a link while no worker is running and checks it
+// is archived once workers come back, proving a save is not lost when its
+// message is published with nobody subscribed.
+func (s *scenarioState) runQueueDurability(t *testing.T, ctx context.Context) {
+	kube := s.cfg.KubeOrSkip(t)
+
+	selector := fmt.Sprintf("app.kubernetes.io/instance=%s,app.kubernetes.io/component=worker", s.cfg.Release)
+	deployments := kube.Client.AppsV1().Deployments(s.cfg.Namespace)
+	list, err := deployments.List(ctx, metav1.ListOptions{LabelSelector: selector})
+	if err != nil {
+		t.Fatalf("list worker deployments failed: %v", err)
+	}
+	if len(list.Items) == 0 {
+		t.Fatalf("no worker deployment found for selector %s", selector)
+	}
+	name := list.Items[0].Name
+
+	scale, err := deployments.GetScale(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("get worker scale failed: %v", err)
+	}
+	replicas := scale.Spec.Replicas
+	if replicas == 0 {
+		t.Fatalf("worker deployment %s is already scaled to zero", name)
+	}
+
+	setReplicas := func(ctx context.Context, n int32) error {
+		scale, err := deployments.GetScale(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		scale.Spec.Replicas = n
+		_, err = deployments.UpdateScale(ctx, name, scale, metav1.UpdateOptions{})
+		return err
+	}
+	if err := setReplicas(ctx, 0); err != nil {
+		t.Fatalf("scale worker to zero failed: %v", err)
+	}
+	// Restores the worker even when an assertion below fails.
+	t.Cleanup(func() {
+		if err := setReplicas(context.Background(), replicas); err != nil {
+			t.Errorf("restore worker to %d replica(s): %v", replicas, err)
+		}
+	})
+
+	// Terminating pods keep their subscription until they exit, so wait for
+	// every pod to be gone before saving.
+	err = s.cfg.Poll(ctx, s.cfg.PollInterval, durationEnv("KS_QUEUE_SCALE_TIMEOUT", 2*time.Minute), func(ctx context.Context) (bool, error) {
+		pods, err := kube.Client.CoreV1().Pods(s.cfg.Namespace).List(ctx, metav1.ListOptions{LabelSelector: selector})
+		if err != nil {
+			return false, err
+		}
+		return len(pods.Items) == 0, nil
+	})
+	if err != nil {
+		t.Fatalf("worker pods did not stop: %v", err)
+	}
+
+	payload := map[string]any{"url": s.queueLinkURL, "title": fmt.Sprintf("%s (queue)", s.linkTitle)}
+	status, body, err := s.cfg.DoJSON(ctx, http.MethodPost, s.postPath, nil, payload)
+	if err != nil {
+		t.Fatalf("create link failed: %v", err)
+	}
+	if status != http.StatusCreated {
+		t.Fatalf("create link unexpected status %d: %s", status, string(body))
+	}
+	var created linkResponse
+	if err := json.Unmarshal(body, &created); err != nil || created.ID == "" {
+		t.Fatalf("decode link response: %v -- %s", err, string(body))
+	}
+	t.Cleanup(func() {
+		_ = s.deleteFixture(context.Background(), fmt.Sprintf("%s/%s", s.postPath, created.ID))
+	})
+
+	archived := func(ctx context.Context) (bool, error) {
+		query := url.Values{}
+		query.Set("q", s.queueLinkURL)
+		query.Set("limit", "5")
+		status, body, err := s.cfg.DoJSON(ctx, http.MethodGet, s.getPath, query, nil)
+		if err != nil {
+			return false, err
+		}
+		if status != http.StatusOK {
+			return false, fmt.Errorf("search returned %d: %s", status, string(body))
+		}
+		var list listLinksResponse
+		if err := json.Unmarshal(body, &list); err != nil {
+			return false, fmt.Errorf("decode search response: %w", err)
+		}
+		for _, item := range list.Items {
+			if item.ID == created.ID {
+				return item.ExtractedText != "" || item.WordCount > 0, nil
+			}
+		}
+		return false, fmt.Errorf("link %s missing from search", created.ID)
+	}
+
+	pendingFor := durationEnv("KS_QUEUE_PENDING_WINDOW", 15*time.Second)
+	for deadline := time.Now().Add(pendingFor); time.Now().Before(deadline); time.Sleep(s.cfg.PollInterval) {
+		done, err := archived(ctx)
+		if err != nil {
+			t.Fatalf("pending check failed: %v", err)
+		}
+		if done {
+			t.Fatalf("link %s archived with no worker running", created.ID)
+		}
+	}
+
+	if err := setReplicas(ctx, replicas); err != nil {
+		t.Fatalf("scale worker back to %d replica(s) failed: %v", replicas, err)
+	}
+	err = s.cfg.Poll(ctx, s.cfg.PollInterval, durationEnv("KS_QUEUE_RECOVERY_TIMEOUT", 5*time.Minute), archived)
+	if err != nil {
+		t.Fatalf("link %s not archived after the worker returned: %v", created.ID, err)
+	}
+}
+
 func (s *scenarioState) ensureTag(ctx context.Context, name string) (int32, error) {
 	payload := map[string]any{"name": name}
 	status, body, err := s.cfg.DoJSON(ctx, http.MethodPost, s.tagPath, nil, payload)
@@ -713,8 +1152,10 @@ func streamJobLogs(ctx context.Context, kube *smoke.Kube, namespace, jobName str
 }
 
 type linkResponse struct {
-	ID         string              `json:"id"`
-	Highlights []highlightResponse `json:"highlights"`
+	ID            string              `json:"id"`
+	ExtractedText string              `json:"extracted_text"`
+	WordCount     int                 `json:"word_count"`
+	Highlights    []highlightResponse `json:"highlights"`
 }
 
 type listLinksResponse struct {
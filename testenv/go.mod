@@ -0,0 +1,22 @@
+module github.com/example/keepstack/testenv
+
+go 1.25
+
+require (
+	github.com/example/keepstack/db v0.0.0
+	github.com/jackc/pgx/v5 v5.5.0
+	github.com/pressly/goose/v3 v3.16.0
+)
+
+require (
+	github.com/jackc/pgpassfile v1.0.0 // indirect
+	github.com/jackc/pgservicefile v0.0.0-20221227161230-091c0ba34f0a // indirect
+	github.com/jackc/puddle/v2 v2.2.1 // indirect
+	github.com/sethvargo/go-retry v0.2.4 // indirect
+	go.uber.org/multierr v1.11.0 // indirect
+	golang.org/x/crypto v0.15.0 // indirect
+	golang.org/x/sync v0.5.0 // indirect
+	golang.org/x/text v0.14.0 // indirect
+)
+
+replace github.com/example/keepstack/db => ../db
@@ -0,0 +1,183 @@
+// Package testenv starts throwaway Postgres and NATS containers for the
+// integration tests behind the "integration" build tag. Each container runs
+// through the docker CLI on a random host port and is removed when the test
+// that started it finishes. Postgres comes up with every migration in
+// db/migrations applied, so tests run against the schema the release ships.
+//
+// Tests skip when docker is not on PATH, so `go test -tags integration` stays
+// usable on machines without a container runtime.
+package testenv
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"database/sql"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	_ "github.com/jackc/pgx/v5/stdlib"
+	"github.com/pressly/goose/v3"
+
+	"github.com/example/keepstack/db/migrations"
+)
+
+// Images match the chart's defaults so tests see the server versions
+// deployments run. KEEPSTACK_TEST_POSTGRES_IMAGE and
+// KEEPSTACK_TEST_NATS_IMAGE override them.
+const (
+	PostgresImage = "postgres:15.6-alpine"
+	NATSImage     = "nats:2.10.10-alpine"
+)
+
+// StartTimeout bounds how long a container may take to accept connections.
+const StartTimeout = 90 * time.Second
+
+const (
+	postgresUser     = "keepstack"
+	postgresPassword = "keepstack"
+	postgresDB       = "keepstack"
+)
+
+// gooseMu serialises migrations, since goose keeps its settings in package
+// globals.
+var gooseMu sync.Mutex
+
+// Postgres starts a Postgres container, applies the migrations, and returns
+// its connection URL.
+func Postgres(t testing.TB) string {
+	t.Helper()
+
+	addr := start(t, image("KEEPSTACK_TEST_POSTGRES_IMAGE", PostgresImage), "5432/tcp",
+		"POSTGRES_USER="+postgresUser,
+		"POSTGRES_PASSWORD="+postgresPassword,
+		"POSTGRES_DB="+postgresDB,
+	)
+	url := fmt.Sprintf("postgres://%s:%s@%s/%s?sslmode=disable", postgresUser, postgresPassword, addr, postgresDB)
+
+	db, err := sql.Open("pgx", url)
+	if err != nil {
+		t.Fatalf("testenv: open postgres: %v", err)
+	}
+	defer db.Close()
+
+	// The image's entrypoint runs initdb against a server that only listens
+	// on its socket, so the first ping over TCP reaches the final server.
+	waitFor(t, "postgres", func(ctx context.Context) error {
+		return db.PingContext(ctx)
+	})
+
+	gooseMu.Lock()
+	defer gooseMu.Unlock()
+	goose.SetBaseFS(migrations.FS)
+	goose.SetLogger(goose.NopLogger())
+	if err := goose.SetDialect("postgres"); err != nil {
+		t.Fatalf("testenv: set goose dialect: %v", err)
+	}
+	if err := goose.Up(db, "."); err != nil {
+		t.Fatalf("testenv: apply migrations: %v", err)
+	}
+	return url
+}
+
+// NATS starts a NATS server container and returns its URL.
+func NATS(t testing.TB) string {
+	t.Helper()
+
+	addr := start(t, image("KEEPSTACK_TEST_NATS_IMAGE", NATSImage), "4222/tcp")
+	waitFor(t, "nats", func(ctx context.Context) error {
+		var dialer net.Dialer
+		conn, err := dialer.DialContext(ctx, "tcp", addr)
+		if err != nil {
+			return err
+		}
+		defer conn.Close()
+		_ = conn.SetReadDeadline(time.Now().Add(time.Second))
+		line, err := bufio.NewReader(conn).ReadString('\n')
+		if err != nil {
+			return err
+		}
+		if !strings.HasPrefix(line, "INFO ") {
+			return fmt.Errorf("unexpected greeting %q", line)
+		}
+		return nil
+	})
+	return "nats://" + addr
+}
+
+// start runs image detached with port published on a random host port and
+// returns the host address that reaches it. The container is removed when t
+// finishes.
+func start(t testing.TB, image, port string, env ...string) string {
+	t.Helper()
+
+	if _, err := exec.LookPath("docker"); err != nil {
+		t.Skip("testenv: docker is not available")
+	}
+
+	args := []string{"run", "--detach", "--rm", "--publish", "127.0.0.1::" + port}
+	for _, kv := range env {
+		args = append(args, "--env", kv)
+	}
+	args = append(args, image)
+	id, err := docker(args...)
+	if err != nil {
+		t.Fatalf("testenv: start %s: %v", image, err)
+	}
+	t.Cleanup(func() {
+		if _, err := docker("rm", "--force", "--volumes", id); err != nil {
+			t.Logf("testenv: remove %s container: %v", image, err)
+		}
+	})
+
+	mapping, err := docker("port", id, port)
+	if err != nil {
+		t.Fatalf("testenv: look up %s port: %v", image, err)
+	}
+	// docker prints one line per published address; the first is enough.
+	addr, _, _ := strings.Cut(mapping, "\n")
+	return strings.TrimSpace(addr)
+}
+
+// waitFor retries ready until it succeeds or StartTimeout passes.
+func waitFor(t testing.TB, name string, ready func(context.Context) error) {
+	t.Helper()
+
+	deadline := time.Now().Add(StartTimeout)
+	for {
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		err := ready(ctx)
+		cancel()
+		if err == nil {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("testenv: %s not ready after %s: %v", name, StartTimeout, err)
+		}
+		time.Sleep(250 * time.Millisecond)
+	}
+}
+
+func docker(args ...string) (string, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command("docker", args...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("docker %s: %w: %s", args[0], err, strings.TrimSpace(stderr.String()))
+	}
+	return strings.TrimSpace(stdout.String()), nil
+}
+
+func image(env, fallback string) string {
+	if v := os.Getenv(env); v != "" {
+		return v
+	}
+	return fallback
+}